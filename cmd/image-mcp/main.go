@@ -2,9 +2,12 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"strconv"
 
+	"github.com/ironsheep/image-tools-mcp/internal/accel"
 	"github.com/ironsheep/image-tools-mcp/internal/server"
 )
 
@@ -23,22 +26,41 @@ func main() {
 			fmt.Printf("image-tools-mcp %s\n", Version)
 			fmt.Printf("  Build time: %s\n", BuildTime)
 			fmt.Printf("  Git commit: %s\n", GitCommit)
+			fmt.Printf("  Backend:    %s\n", accel.Name())
 			return
 		case "--help", "-h", "help":
 			fmt.Println("image-tools-mcp - MCP server for image analysis")
 			fmt.Println()
 			fmt.Println("Usage: image-tools-mcp [options]")
+			fmt.Println("       image-tools-mcp replay <capture-file>")
 			fmt.Println()
 			fmt.Println("Options:")
 			fmt.Println("  --version, -v    Print version information")
 			fmt.Println("  --help, -h       Print this help message")
 			fmt.Println()
+			fmt.Println("Commands:")
+			fmt.Println("  replay <file>    Re-run a session captured with IMAGE_MCP_CAPTURE and")
+			fmt.Println("                   report responses that differ (exit status 1 if any)")
+			fmt.Println()
 			fmt.Println("Environment variables:")
 			fmt.Println("  IMAGE_MCP_LOG_LEVEL=debug    Enable debug logging")
+			fmt.Println("  IMAGE_MCP_SVG_SCALE=2.0      Render scale for SVG input (default 1.0)")
+			fmt.Println("  IMAGE_MCP_MAX_PIXELS=2e8     Largest image to load, in pixels (default 1e8, 0 = no limit)")
+			fmt.Println("  IMAGE_MCP_COLOR_CONVERT=0    Keep colors of images with an ICC profile as")
+			fmt.Println("                               stored (default 1: convert them to sRGB)")
+			fmt.Println("  IMAGE_MCP_CAPTURE=file.jsonl Append every request and response to a capture file")
+			fmt.Println("  IMAGE_MCP_CACHE_DIR=dir      Where persistent data such as baselines is kept")
+			fmt.Printf("                               (default %s)\n", server.DefaultCacheDir())
 			fmt.Println()
 			fmt.Println("This server communicates via MCP protocol over stdin/stdout.")
 			fmt.Println("Configure it in your MCP client (e.g., Claude Desktop).")
 			return
+		case "replay":
+			if len(os.Args) != 3 {
+				fmt.Fprintln(os.Stderr, "Usage: image-tools-mcp replay <capture-file>")
+				os.Exit(2)
+			}
+			os.Exit(replay(os.Args[2]))
 		}
 	}
 
@@ -48,11 +70,74 @@ func main() {
 
 	logLevel := os.Getenv("IMAGE_MCP_LOG_LEVEL")
 	if logLevel == "debug" {
-		log.Printf("Image MCP Server v%s (built %s, commit %s)", Version, BuildTime, GitCommit)
+		log.Printf("Image MCP Server v%s (built %s, commit %s, %s backend)", Version, BuildTime, GitCommit, accel.Name())
 	}
 
-	srv := server.New()
+	srv := newServer()
+	if path := os.Getenv("IMAGE_MCP_CAPTURE"); path != "" {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			log.Fatalf("Cannot open IMAGE_MCP_CAPTURE file: %v", err)
+		}
+		defer f.Close()
+		srv.SetCapture(f)
+	}
 	if err := srv.Run(); err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
 }
+
+// newServer creates a server configured from the environment.
+func newServer() *server.Server {
+	srv := server.New()
+	if v := os.Getenv("IMAGE_MCP_SVG_SCALE"); v != "" {
+		scale, err := strconv.ParseFloat(v, 64)
+		if err != nil || scale <= 0 {
+			log.Fatalf("Invalid IMAGE_MCP_SVG_SCALE %q: must be a positive number", v)
+		}
+		srv.SetSVGScale(scale)
+	}
+	if v := os.Getenv("IMAGE_MCP_MAX_PIXELS"); v != "" {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil || n < 0 {
+			log.Fatalf("Invalid IMAGE_MCP_MAX_PIXELS %q: must be a non-negative number", v)
+		}
+		srv.SetMaxPixels(int64(n))
+	}
+	if v := os.Getenv("IMAGE_MCP_COLOR_CONVERT"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			log.Fatalf("Invalid IMAGE_MCP_COLOR_CONVERT %q: must be 1, 0, true, or false", v)
+		}
+		srv.SetColorConversion(enabled)
+	}
+	if dir := os.Getenv("IMAGE_MCP_CACHE_DIR"); dir != "" {
+		srv.SetCacheDir(dir)
+	}
+	return srv
+}
+
+// replay re-runs the session in a capture file, printing differences to
+// stdout, and returns the exit status: 0 if every response matched, 1 if
+// any differed, 2 if the capture could not be read.
+func replay(path string) int {
+	log.SetOutput(io.Discard) // parse failures in the capture are expected
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "replay: %v\n", err)
+		return 2
+	}
+	defer f.Close()
+
+	summary, err := newServer().Replay(f, os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "replay: %v\n", err)
+		return 2
+	}
+	fmt.Printf("%d requests replayed, %d differed, %d input files missing or changed\n",
+		summary.Requests, summary.Differed, summary.InputsChanged)
+	if summary.Differed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -5,6 +5,14 @@ go 1.22
 require (
 	github.com/disintegration/imaging v1.6.2
 	github.com/otiai10/gosseract/v2 v2.4.1
+	github.com/srwiley/oksvg v0.0.0-20221011165216-be6e8873101c
+	github.com/srwiley/rasterx v0.0.0-20220730225603-2ab79fcdd4ef
+	gocv.io/x/gocv v0.43.0
 )
 
-require golang.org/x/image v0.15.0
+require (
+	golang.org/x/image v0.15.0
+	golang.org/x/text v0.14.0
+)
+
+require golang.org/x/net v0.0.0-20211118161319-6a13c67c3ce4 // indirect
@@ -0,0 +1,55 @@
+// Package accel is an optional bridge to OpenCV (through gocv) for the most
+// expensive steps of the image tools: Canny edge detection, the Hough line
+// and circle transforms, and template matching.
+//
+// By default the package is built without OpenCV. Enabled is false and
+// every function declines by returning ok == false, so callers run their
+// pure-Go implementation. Building with the opencv tag and cgo links gocv
+// instead:
+//
+//	CGO_ENABLED=1 go build -tags opencv ./cmd/image-mcp
+//
+// gocv is required in go.mod, but only files with the opencv tag import
+// it, so the default build neither compiles nor links it. OpenCV 4 and
+// its development headers must be installed; see the gocv documentation
+// for each platform.
+//
+// # Results
+//
+// Callers convert what this package returns into their own result structs
+// and apply their own filtering, so tool output has the same shape with
+// either backend. Values can differ slightly, since the OpenCV algorithms
+// differ in detail (border handling, gradient rounding, accumulator
+// resolution).
+//
+// Inputs and outputs are plain row-major slices, so this package does not
+// depend on the detection or imaging types.
+package accel
+
+// Backend names reported by Name.
+const (
+	BackendGo     = "go"
+	BackendOpenCV = "opencv"
+)
+
+// Name returns the backend in use: BackendOpenCV when built with the
+// opencv tag, BackendGo otherwise.
+func Name() string {
+	if Enabled {
+		return BackendOpenCV
+	}
+	return BackendGo
+}
+
+// LinePeak is a line found by HoughLines, in normal form:
+// x*cos(theta) + y*sin(theta) = rho.
+type LinePeak struct {
+	Rho   int // Signed distance from the origin in pixels
+	Theta int // Angle of the normal in degrees, 0-179
+}
+
+// CircleCandidate is a circle found by HoughCircles.
+type CircleCandidate struct {
+	X, Y   int // Center in pixels
+	Radius int // Radius in pixels
+}
@@ -0,0 +1,38 @@
+//go:build !opencv || !cgo
+
+package accel
+
+// Enabled reports whether OpenCV is linked in. It is false in this build.
+const Enabled = false
+
+// Canny runs Canny edge detection (5x5 Gaussian blur, Sobel gradients,
+// hysteresis between low and high) over a width×height grayscale image.
+// It returns the edge map, 255 for edges and 0 elsewhere, and true, or
+// false if OpenCV is not available.
+func Canny(gray []uint8, width, height, low, high int) ([]uint8, bool) {
+	return nil, false
+}
+
+// HoughLines runs the standard Hough line transform over a width×height
+// edge mask (non-zero is an edge) with 1 pixel and 1 degree resolution,
+// returning lines with at least threshold votes, strongest first. It
+// returns false if OpenCV is not available.
+func HoughLines(mask []uint8, width, height, threshold int) ([]LinePeak, bool) {
+	return nil, false
+}
+
+// HoughCircles runs the Hough gradient circle transform over a
+// width×height grayscale image, returning circles with radii in
+// [minRadius, maxRadius]. It returns false if OpenCV is not available.
+func HoughCircles(gray []uint8, width, height, minRadius, maxRadius int) ([]CircleCandidate, bool) {
+	return nil, false
+}
+
+// MatchTemplate scores every placement of a tw×th grayscale template over
+// a width×height grayscale image by zero-mean normalized cross-correlation
+// (OpenCV's TM_CCOEFF_NORMED), from -1 to 1, and 0 where the image is
+// flat. It returns the (width-tw+1)×(height-th+1) scores, row-major, and
+// true, or false if OpenCV is not available.
+func MatchTemplate(gray []uint8, width, height int, tmpl []uint8, tw, th int) ([]float32, bool) {
+	return nil, false
+}
@@ -0,0 +1,139 @@
+//go:build opencv && cgo
+
+package accel
+
+import (
+	"image"
+	"math"
+
+	"gocv.io/x/gocv"
+)
+
+// Enabled reports whether OpenCV is linked in. It is true in this build.
+const Enabled = true
+
+// newMat wraps a width×height single-channel buffer as a Mat. It returns
+// false if the buffer does not match the size.
+func newMat(data []uint8, width, height int) (gocv.Mat, bool) {
+	if width <= 0 || height <= 0 || len(data) != width*height {
+		return gocv.Mat{}, false
+	}
+	m, err := gocv.NewMatFromBytes(height, width, gocv.MatTypeCV8U, data)
+	if err != nil {
+		return gocv.Mat{}, false
+	}
+	return m, true
+}
+
+// Canny runs Canny edge detection; see the pure-Go build for details. The
+// blur matches the pure-Go kernel (sigma 1.4, replicated borders) and the
+// gradient magnitude is L2, as in EdgeDetect.
+func Canny(gray []uint8, width, height, low, high int) ([]uint8, bool) {
+	src, ok := newMat(gray, width, height)
+	if !ok {
+		return nil, false
+	}
+	defer src.Close()
+
+	blurred := gocv.NewMat()
+	defer blurred.Close()
+	gocv.GaussianBlur(src, &blurred, image.Pt(5, 5), 1.4, 1.4, gocv.BorderReplicate)
+
+	edges := gocv.NewMat()
+	defer edges.Close()
+	gocv.CannyWithParams(blurred, &edges, float32(low), float32(high), 3, true)
+	return edges.ToBytes(), true
+}
+
+// HoughLines runs the Hough line transform; see the pure-Go build for
+// details. OpenCV requires a positive threshold, so smaller values are
+// declined.
+func HoughLines(mask []uint8, width, height, threshold int) ([]LinePeak, bool) {
+	if threshold < 1 {
+		return nil, false
+	}
+	src, ok := newMat(mask, width, height)
+	if !ok {
+		return nil, false
+	}
+	defer src.Close()
+
+	lines := gocv.NewMat()
+	defer lines.Close()
+	gocv.HoughLines(src, &lines, 1, math.Pi/180, threshold)
+
+	// Rows are (rho, theta in radians), already ordered by votes
+	peaks := make([]LinePeak, 0, lines.Rows())
+	for i := 0; i < lines.Rows(); i++ {
+		v := lines.GetVecfAt(i, 0)
+		rho := int(math.Round(float64(v[0])))
+		theta := int(math.Round(float64(v[1]) * 180 / math.Pi))
+		if theta >= 180 {
+			// 180° is 0° with the normal reversed
+			theta -= 180
+			rho = -rho
+		}
+		peaks = append(peaks, LinePeak{Rho: rho, Theta: theta})
+	}
+	return peaks, true
+}
+
+// HoughCircles runs the Hough gradient circle transform; see the pure-Go
+// build for details. Centers closer than minRadius are merged by OpenCV.
+func HoughCircles(gray []uint8, width, height, minRadius, maxRadius int) ([]CircleCandidate, bool) {
+	src, ok := newMat(gray, width, height)
+	if !ok {
+		return nil, false
+	}
+	defer src.Close()
+
+	minDist := float64(minRadius)
+	if minDist < 1 {
+		minDist = 1
+	}
+	circles := gocv.NewMat()
+	defer circles.Close()
+	gocv.HoughCirclesWithParams(src, &circles, gocv.HoughGradient, 1, minDist, 100, 20, minRadius, maxRadius)
+
+	// One row of (x, y, radius)
+	found := make([]CircleCandidate, 0, circles.Cols())
+	for i := 0; i < circles.Cols(); i++ {
+		v := circles.GetVecfAt(0, i)
+		found = append(found, CircleCandidate{
+			X:      int(math.Round(float64(v[0]))),
+			Y:      int(math.Round(float64(v[1]))),
+			Radius: int(math.Round(float64(v[2]))),
+		})
+	}
+	return found, true
+}
+
+// MatchTemplate scores every placement of the template; see the pure-Go
+// build for details.
+func MatchTemplate(gray []uint8, width, height int, tmpl []uint8, tw, th int) ([]float32, bool) {
+	if tw > width || th > height {
+		return nil, false
+	}
+	src, ok := newMat(gray, width, height)
+	if !ok {
+		return nil, false
+	}
+	defer src.Close()
+	templ, ok := newMat(tmpl, tw, th)
+	if !ok {
+		return nil, false
+	}
+	defer templ.Close()
+
+	result := gocv.NewMat()
+	defer result.Close()
+	mask := gocv.NewMat()
+	defer mask.Close()
+	gocv.MatchTemplate(src, templ, &result, gocv.TmCcoeffNormed, mask)
+	scores, err := result.DataPtrFloat32()
+	if err != nil || len(scores) != (width-tw+1)*(height-th+1) {
+		return nil, false
+	}
+	// The Mat owns the scores; copy them out before it is closed
+	return append([]float32(nil), scores...), true
+}
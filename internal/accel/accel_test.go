@@ -0,0 +1,24 @@
+//go:build !opencv || !cgo
+
+package accel
+
+import "testing"
+
+func TestPureGoBuildDeclines(t *testing.T) {
+	if Name() != BackendGo {
+		t.Errorf("Name: got %q, want %q", Name(), BackendGo)
+	}
+	gray := make([]uint8, 16)
+	if _, ok := Canny(gray, 4, 4, 50, 150); ok {
+		t.Error("Canny: expected to decline without OpenCV")
+	}
+	if _, ok := HoughLines(gray, 4, 4, 1); ok {
+		t.Error("HoughLines: expected to decline without OpenCV")
+	}
+	if _, ok := HoughCircles(gray, 4, 4, 1, 2); ok {
+		t.Error("HoughCircles: expected to decline without OpenCV")
+	}
+	if _, ok := MatchTemplate(gray, 4, 4, gray[:4], 2, 2); ok {
+		t.Error("MatchTemplate: expected to decline without OpenCV")
+	}
+}
@@ -0,0 +1,416 @@
+// Package baseline keeps a registry of named baseline images for visual
+// regression checks: a screenshot is stored once under a name, and later
+// captures are compared against it.
+//
+// Each baseline is kept in the store directory as <name>.png, holding the
+// pixels, and <name>.json, holding its Record. Baselines are compared by
+// a hash of their decoded pixels, so re-encoding a file does not count as
+// a change, and when the hashes differ, pixel by pixel with the same
+// noise threshold as the side-by-side comparison tool.
+package baseline
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/ironsheep/image-tools-mcp/internal/detection"
+	"github.com/ironsheep/image-tools-mcp/internal/imaging"
+)
+
+// DefaultMaxDiffPercent is the share of differing pixels, in percent,
+// that Compare tolerates when no threshold is given. It absorbs a blinking
+// cursor or an anti-aliasing change, but not a changed word.
+const DefaultMaxDiffPercent = 0.1
+
+// validName matches baseline names: letters, digits, '.', '_', and '-',
+// not starting with '.', so a name cannot leave the store directory.
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]{0,127}$`)
+
+// ErrNotFound is returned by Compare and Get when no baseline has the
+// requested name.
+var ErrNotFound = errors.New("baseline not found")
+
+// Record describes a stored baseline.
+type Record struct {
+	// Name identifies the baseline.
+	Name string `json:"name"`
+
+	// Source is the path of the image the baseline was stored from.
+	Source string `json:"source"`
+
+	// Width and Height of the baseline in pixels.
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// Hash is the hex SHA-256 of the image size and its 8-bit RGBA
+	// pixels, independent of the file format.
+	Hash string `json:"hash"`
+
+	// Metrics are the quality metrics of the baseline (see
+	// imaging.MeasureQuality); nil for images under 3×3 pixels.
+	Metrics *imaging.QualityResult `json:"metrics,omitempty"`
+
+	// StoredAt is when the baseline was stored, in RFC 3339 format (UTC).
+	StoredAt string `json:"stored_at"`
+
+	// ImagePath is the stored copy of the baseline image.
+	ImagePath string `json:"image_path"`
+}
+
+// SaveResult reports a stored baseline.
+type SaveResult struct {
+	Record
+
+	// Replaced is true if a baseline of the same name was overwritten.
+	Replaced bool `json:"replaced"`
+
+	// Unchanged is true if the replaced baseline had the same pixels.
+	Unchanged bool `json:"unchanged"`
+}
+
+// CompareOptions controls Compare.
+type CompareOptions struct {
+	// MaxDiffPercent is the largest share of differing pixels, in
+	// percent (0-100), that still passes.
+	MaxDiffPercent float64
+
+	// IncludeDiff adds the side-by-side diff image to the result.
+	IncludeDiff bool
+}
+
+// CompareResult reports how a capture compares with its baseline.
+type CompareResult struct {
+	// Name of the baseline compared against.
+	Name string `json:"name"`
+
+	// Pass is true if the capture has the baseline's size and at most
+	// MaxDiffPercent of its pixels differ.
+	Pass bool `json:"pass"`
+
+	// Identical is true if the pixels are exactly the same.
+	Identical bool `json:"identical"`
+
+	// SameSize is true if the capture has the baseline's dimensions.
+	SameSize bool `json:"same_size"`
+
+	// DiffPixels is the number of pixels whose mean per-channel
+	// difference exceeds 10, over the area both images cover.
+	DiffPixels int `json:"diff_pixels"`
+
+	// DiffPercent is DiffPixels as a percentage of that area.
+	DiffPercent float64 `json:"diff_percent"`
+
+	// MaxDiffPercent is the threshold applied.
+	MaxDiffPercent float64 `json:"max_diff_percent"`
+
+	// ChangedRegions are the bounding boxes of the changed areas, one
+	// per cluster of changed pixels (see detection.ChangedRegions).
+	// Empty when the sizes differ.
+	ChangedRegions []detection.Bounds `json:"changed_regions"`
+
+	// Failures explains why the comparison failed; empty on a pass.
+	Failures []string `json:"failures"`
+
+	// Hash and Metrics describe the capture, as Record does the baseline.
+	Hash    string                 `json:"hash"`
+	Metrics *imaging.QualityResult `json:"metrics,omitempty"`
+
+	// Baseline is the record of the stored baseline.
+	Baseline Record `json:"baseline"`
+
+	// Diff is the baseline and the capture side by side with differing
+	// pixels tinted red, if requested and the images are not identical.
+	Diff *imaging.SideBySideResult `json:"diff,omitempty"`
+}
+
+// Store is a directory of named baselines. It is safe for concurrent use
+// within one process; separate processes sharing a directory should not
+// store the same name at the same time.
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewStore returns a store over dir. The directory is created when the
+// first baseline is saved.
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Dir returns the store's directory.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Save stores img as the baseline called name, replacing any baseline of
+// that name.
+//
+// Parameters:
+//   - name: Baseline name: 1 to 128 letters, digits, '.', '_', or '-',
+//     not starting with '.'.
+//   - img: The baseline image.
+//   - source: Path the image was loaded from, kept in the record.
+//
+// Returns:
+//   - *SaveResult: The stored record, and whether it replaced another.
+//   - error: Non-nil if the name is invalid, the image is empty, or the
+//     files cannot be written.
+func (s *Store) Save(name string, img image.Image, source string) (*SaveResult, error) {
+	if err := checkName(name); err != nil {
+		return nil, err
+	}
+	if img.Bounds().Empty() {
+		return nil, fmt.Errorf("image is empty")
+	}
+	pixels := toNRGBA(img)
+	rec := Record{
+		Name:      name,
+		Source:    source,
+		Width:     pixels.Rect.Dx(),
+		Height:    pixels.Rect.Dy(),
+		Hash:      pixelHash(pixels),
+		Metrics:   metrics(pixels),
+		StoredAt:  time.Now().UTC().Format(time.RFC3339),
+		ImagePath: s.imagePath(name),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := &SaveResult{Record: rec}
+	if old, err := s.get(name); err == nil {
+		result.Replaced = true
+		result.Unchanged = old.Hash == rec.Hash
+	} else if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create baseline directory: %w", err)
+	}
+	if err := writeFile(rec.ImagePath, func(f *os.File) error { return png.Encode(f, pixels) }); err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFile(s.recordPath(name), func(f *os.File) error {
+		_, err := f.Write(data)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Get returns the record of the baseline called name, or an error
+// wrapping ErrNotFound if there is none.
+func (s *Store) Get(name string) (*Record, error) {
+	if err := checkName(name); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(name)
+}
+
+// Compare compares img with the baseline called name.
+//
+// Parameters:
+//   - name: The baseline to compare against.
+//   - img: The new capture.
+//   - opts: Pass threshold and whether to return the diff image.
+//
+// Returns:
+//   - *CompareResult: Pass or fail, the difference statistics, and the
+//     reasons for a failure.
+//   - error: Non-nil if the name is invalid, the baseline does not exist
+//     (wrapping ErrNotFound) or cannot be read, or the threshold is
+//     outside 0-100.
+//
+// Identical pixel hashes pass without a pixel comparison. Otherwise a
+// capture fails if its size differs from the baseline's or more than
+// MaxDiffPercent of its pixels differ, with the same noise threshold as
+// imaging.SideBySide.
+func (s *Store) Compare(name string, img image.Image, opts CompareOptions) (*CompareResult, error) {
+	if opts.MaxDiffPercent < 0 || opts.MaxDiffPercent > 100 {
+		return nil, fmt.Errorf("max diff percent must be between 0 and 100, got %v", opts.MaxDiffPercent)
+	}
+	if err := checkName(name); err != nil {
+		return nil, err
+	}
+	if img.Bounds().Empty() {
+		return nil, fmt.Errorf("image is empty")
+	}
+
+	s.mu.Lock()
+	rec, err := s.get(name)
+	var stored image.Image
+	if err == nil {
+		stored, err = readPNG(rec.ImagePath)
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	current := toNRGBA(img)
+	result := &CompareResult{
+		Name:           name,
+		SameSize:       current.Rect.Dx() == rec.Width && current.Rect.Dy() == rec.Height,
+		MaxDiffPercent: opts.MaxDiffPercent,
+		ChangedRegions: []detection.Bounds{},
+		Failures:       []string{},
+		Hash:           pixelHash(current),
+		Metrics:        metrics(current),
+		Baseline:       *rec,
+	}
+	if result.Hash == rec.Hash {
+		result.Identical = true
+		result.Pass = true
+		return result, nil
+	}
+
+	diff, err := imaging.SideBySide(stored, current, "baseline", "current", false, true)
+	if err != nil {
+		return nil, err
+	}
+	result.DiffPixels = diff.PixelsDifferent
+	result.DiffPercent = diff.DiffPercentage
+	if opts.IncludeDiff {
+		result.Diff = diff
+	}
+
+	if result.SameSize {
+		regions, err := detection.ChangedRegions(stored, current)
+		if err != nil {
+			return nil, err
+		}
+		result.ChangedRegions = regions
+	} else {
+		result.Failures = append(result.Failures, fmt.Sprintf("size changed from %dx%d to %dx%d",
+			rec.Width, rec.Height, current.Rect.Dx(), current.Rect.Dy()))
+	}
+	if result.DiffPercent > opts.MaxDiffPercent {
+		result.Failures = append(result.Failures, fmt.Sprintf("%v%% of pixels differ, more than %v%%",
+			result.DiffPercent, opts.MaxDiffPercent))
+	}
+	result.Pass = len(result.Failures) == 0
+	return result, nil
+}
+
+// get reads the record of name; s.mu must be held.
+func (s *Store) get(name string) (*Record, error) {
+	data, err := os.ReadFile(s.recordPath(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %q", ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline %q: %w", name, err)
+	}
+	var rec Record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("failed to read baseline %q: %w", name, err)
+	}
+	// The store directory may have moved since the record was written
+	rec.ImagePath = s.imagePath(name)
+	return &rec, nil
+}
+
+func (s *Store) imagePath(name string) string {
+	return filepath.Join(s.dir, name+".png")
+}
+
+func (s *Store) recordPath(name string) string {
+	return filepath.Join(s.dir, name+".json")
+}
+
+// checkName validates a baseline name.
+func checkName(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid baseline name %q: use 1 to 128 letters, digits, '.', '_', or '-', not starting with '.'", name)
+	}
+	return nil
+}
+
+// writeFile writes a file through a temporary file in the same
+// directory, so readers never see it half written.
+func writeFile(path string, write func(*os.File) error) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// readPNG decodes a stored baseline image.
+func readPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline image: %w", err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline image: %w", err)
+	}
+	return img, nil
+}
+
+// toNRGBA converts an image to 8-bit non-premultiplied RGBA with its
+// origin at (0, 0), the form baselines are hashed and stored in.
+func toNRGBA(img image.Image) *image.NRGBA {
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Rect, img, b.Min, draw.Src)
+	return dst
+}
+
+// pixelHash returns the hex SHA-256 of an image's size and pixels.
+func pixelHash(img *image.NRGBA) string {
+	h := sha256.New()
+	var size [8]byte
+	binary.BigEndian.PutUint32(size[:4], uint32(img.Rect.Dx()))
+	binary.BigEndian.PutUint32(size[4:], uint32(img.Rect.Dy()))
+	h.Write(size[:])
+	for y := 0; y < img.Rect.Dy(); y++ {
+		row := img.Pix[y*img.Stride : y*img.Stride+img.Rect.Dx()*4]
+		h.Write(row)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// metrics returns the quality metrics of an image, or nil if it is too
+// small to measure.
+func metrics(img image.Image) *imaging.QualityResult {
+	q, err := imaging.MeasureQuality(img)
+	if err != nil {
+		return nil
+	}
+	return q
+}
@@ -0,0 +1,159 @@
+package baseline
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// screenshot returns a 200x100 white image with a black box at x.
+func screenshot(x int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	for y := 0; y < 100; y++ {
+		for px := 0; px < 200; px++ {
+			c := color.RGBA{255, 255, 255, 255}
+			if px >= x && px < x+40 && y >= 30 && y < 70 {
+				c = color.RGBA{0, 0, 0, 255}
+			}
+			img.SetRGBA(px, y, c)
+		}
+	}
+	return img
+}
+
+func TestStore_SaveAndCompare(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "baselines"))
+
+	saved, err := store.Save("home", screenshot(20), "/tmp/home.png")
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if saved.Replaced || saved.Width != 200 || saved.Height != 100 || len(saved.Hash) != 64 {
+		t.Errorf("unexpected record: %+v", saved)
+	}
+	if saved.Metrics == nil {
+		t.Error("expected quality metrics")
+	}
+	for _, p := range []string{saved.ImagePath, filepath.Join(store.Dir(), "home.json")} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("baseline file missing: %v", err)
+		}
+	}
+
+	// The same pixels pass, even from a differently laid out image
+	same := image.NewNRGBA(image.Rect(10, 10, 210, 110))
+	src := screenshot(20)
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 200; x++ {
+			same.Set(x+10, y+10, src.At(x, y))
+		}
+	}
+	result, err := store.Compare("home", same, CompareOptions{MaxDiffPercent: DefaultMaxDiffPercent, IncludeDiff: true})
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if !result.Pass || !result.Identical || result.Hash != saved.Hash || result.Diff != nil {
+		t.Errorf("identical capture: got %+v", result)
+	}
+
+	// A moved box fails, with its changed region and a diff image
+	result, err = store.Compare("home", screenshot(100), CompareOptions{MaxDiffPercent: DefaultMaxDiffPercent, IncludeDiff: true})
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if result.Pass || result.Identical || !result.SameSize {
+		t.Errorf("moved box: got pass=%v identical=%v same_size=%v", result.Pass, result.Identical, result.SameSize)
+	}
+	if result.DiffPixels != 2*40*40 || result.DiffPercent != 16 {
+		t.Errorf("diff: got %d pixels (%v%%), want 3200 (16%%)", result.DiffPixels, result.DiffPercent)
+	}
+	if len(result.ChangedRegions) == 0 || len(result.Failures) != 1 {
+		t.Errorf("got regions %v failures %v", result.ChangedRegions, result.Failures)
+	}
+	if result.Diff == nil || result.Diff.ImageBase64 == "" {
+		t.Error("expected a diff image")
+	}
+
+	// A generous threshold lets the same change pass
+	result, err = store.Compare("home", screenshot(100), CompareOptions{MaxDiffPercent: 20})
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if !result.Pass || result.Diff != nil {
+		t.Errorf("within threshold: got pass=%v diff=%v", result.Pass, result.Diff != nil)
+	}
+}
+
+func TestStore_SizeChange(t *testing.T) {
+	store := NewStore(t.TempDir())
+	if _, err := store.Save("page", screenshot(20), ""); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	taller := image.NewRGBA(image.Rect(0, 0, 200, 120))
+	for y := 0; y < 120; y++ {
+		for x := 0; x < 200; x++ {
+			taller.Set(x, y, screenshot(20).At(x, y))
+		}
+	}
+	result, err := store.Compare("page", taller, CompareOptions{MaxDiffPercent: 100})
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if result.Pass || result.SameSize || len(result.Failures) != 1 || len(result.ChangedRegions) != 0 {
+		t.Errorf("size change: got %+v", result)
+	}
+}
+
+func TestStore_Replace(t *testing.T) {
+	store := NewStore(t.TempDir())
+	if _, err := store.Save("a", screenshot(20), ""); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	again, err := store.Save("a", screenshot(20), "")
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if !again.Replaced || !again.Unchanged {
+		t.Errorf("same image: got replaced=%v unchanged=%v", again.Replaced, again.Unchanged)
+	}
+	moved, err := store.Save("a", screenshot(100), "")
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if !moved.Replaced || moved.Unchanged {
+		t.Errorf("new image: got replaced=%v unchanged=%v", moved.Replaced, moved.Unchanged)
+	}
+	result, err := store.Compare("a", screenshot(100), CompareOptions{})
+	if err != nil || !result.Identical {
+		t.Errorf("compare with replaced baseline: %v %+v", err, result)
+	}
+}
+
+func TestStore_Errors(t *testing.T) {
+	store := NewStore(t.TempDir())
+	img := screenshot(20)
+
+	for _, name := range []string{"", ".hidden", "../escape", "a/b", "has space"} {
+		if _, err := store.Save(name, img, ""); err == nil {
+			t.Errorf("Save(%q): expected error", name)
+		}
+	}
+	if _, err := store.Compare("missing", img, CompareOptions{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Compare missing: got %v, want ErrNotFound", err)
+	}
+	if _, err := store.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get missing: got %v, want ErrNotFound", err)
+	}
+	if _, err := store.Save("x", image.NewRGBA(image.Rect(0, 0, 0, 0)), ""); err == nil {
+		t.Error("Save empty image: expected error")
+	}
+	if _, err := store.Save("x", img, ""); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if _, err := store.Compare("x", img, CompareOptions{MaxDiffPercent: 101}); err == nil {
+		t.Error("Compare with threshold over 100: expected error")
+	}
+}
@@ -0,0 +1,123 @@
+// Package clipboard reads images from the system clipboard, where most
+// ad-hoc screenshots arrive, and saves them as files the image tools can
+// open.
+//
+// The clipboard is read with the tools each platform ships or commonly
+// installs, so no cgo is needed: osascript on macOS, wl-paste (Wayland)
+// or xclip (X11) on Linux, and PowerShell on Windows. On other platforms
+// Supported is false and ReadImage returns ErrUnsupported.
+//
+// Saved images are named by a hash of their contents, so reading an
+// unchanged clipboard again reuses the same file, and a path never names
+// two different images.
+package clipboard
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ErrUnsupported is returned by ReadImage on platforms without clipboard
+// support.
+var ErrUnsupported = errors.New("reading images from the clipboard is not supported on " + runtime.GOOS)
+
+// ErrNoImage is returned by ReadImage when the clipboard is empty or holds
+// something other than an image, such as text.
+var ErrNoImage = errors.New("the clipboard does not hold an image")
+
+// pngSignature starts every PNG file.
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// Supported reports whether images can be read from the clipboard on this
+// platform. The clipboard tools it needs may still be missing, which
+// ReadImage reports.
+func Supported() bool {
+	return supported
+}
+
+// ReadImage returns the image on the clipboard, encoded as PNG.
+func ReadImage() ([]byte, error) {
+	data, err := readImage()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, ErrNoImage
+	}
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, errors.New("the clipboard image is not in PNG format")
+	}
+	return data, nil
+}
+
+// Save writes a PNG image read from the clipboard to dir, creating it if
+// needed, as clipboard-<hash>.png, and returns the file's path. existed
+// is true if the file was already there, as when the same image was read
+// before.
+func Save(data []byte, dir string) (path string, existed bool, err error) {
+	sum := sha256.Sum256(data)
+	path = filepath.Join(dir, "clipboard-"+hex.EncodeToString(sum[:8])+".png")
+	if _, err := os.Stat(path); err == nil {
+		return path, true, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", false, fmt.Errorf("failed to create clipboard directory: %w", err)
+	}
+
+	// Write to a temporary file and rename it, so a concurrent read never
+	// sees a partial image under the final name
+	tmp, err := os.CreateTemp(dir, "clipboard-*.tmp")
+	if err != nil {
+		return "", false, fmt.Errorf("failed to save clipboard image: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", false, fmt.Errorf("failed to save clipboard image: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", false, fmt.Errorf("failed to save clipboard image: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", false, fmt.Errorf("failed to save clipboard image: %w", err)
+	}
+	return path, false, nil
+}
+
+// output runs a clipboard tool and returns its standard output. A failure
+// is reported with the tool's error message.
+func output(name string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s failed: %s", name, msg)
+		}
+		return nil, fmt.Errorf("%s failed: %w", name, err)
+	}
+	return out, nil
+}
+
+// parseAppleScriptData decodes the PNG data osascript prints for the
+// clipboard as PNG, a hex literal of the form «data PNGf89504E47…».
+func parseAppleScriptData(out []byte) ([]byte, error) {
+	s := strings.TrimSpace(string(out))
+	if !strings.HasPrefix(s, "«data PNGf") || !strings.HasSuffix(s, "»") {
+		return nil, ErrNoImage
+	}
+	data, err := hex.DecodeString(strings.TrimSuffix(strings.TrimPrefix(s, "«data PNGf"), "»"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode clipboard image: %w", err)
+	}
+	return data, nil
+}
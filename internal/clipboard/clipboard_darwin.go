@@ -0,0 +1,16 @@
+//go:build darwin
+
+package clipboard
+
+const supported = true
+
+// readImage reads the clipboard as PNG with AppleScript, which converts
+// the TIFF images some applications copy. osascript fails if the
+// clipboard holds no image.
+func readImage() ([]byte, error) {
+	out, err := output("osascript", "-e", "the clipboard as «class PNGf»")
+	if err != nil {
+		return nil, ErrNoImage
+	}
+	return parseAppleScriptData(out)
+}
@@ -0,0 +1,52 @@
+//go:build linux
+
+package clipboard
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const supported = true
+
+// readImage reads the clipboard as PNG with wl-paste under Wayland, or
+// with xclip under X11, after checking that it offers a PNG image.
+func readImage() ([]byte, error) {
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		if _, err := exec.LookPath("wl-paste"); err == nil {
+			types, err := output("wl-paste", "--list-types")
+			if err != nil {
+				// wl-paste fails on an empty clipboard
+				return nil, ErrNoImage
+			}
+			if !hasLine(types, "image/png") {
+				return nil, ErrNoImage
+			}
+			return output("wl-paste", "--no-newline", "--type", "image/png")
+		}
+	}
+	if _, err := exec.LookPath("xclip"); err == nil {
+		targets, err := output("xclip", "-selection", "clipboard", "-target", "TARGETS", "-out")
+		if err != nil {
+			return nil, err
+		}
+		if !hasLine(targets, "image/png") {
+			return nil, ErrNoImage
+		}
+		return output("xclip", "-selection", "clipboard", "-target", "image/png", "-out")
+	}
+	return nil, errors.New("reading the clipboard needs wl-paste (Wayland) or xclip (X11) to be installed")
+}
+
+// hasLine reports whether out, the output of a clipboard tool, has a line
+// reading s.
+func hasLine(out []byte, s string) bool {
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.TrimSpace(line) == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,9 @@
+//go:build !darwin && !linux && !windows
+
+package clipboard
+
+const supported = false
+
+func readImage() ([]byte, error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,53 @@
+package clipboard
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSave(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "clipboard")
+	first := append(append([]byte{}, pngSignature...), "first"...)
+	second := append(append([]byte{}, pngSignature...), "second"...)
+
+	path, existed, err := Save(first, dir)
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if existed || filepath.Dir(path) != dir || filepath.Ext(path) != ".png" {
+		t.Errorf("got path %q, existed %v", path, existed)
+	}
+	if data, err := os.ReadFile(path); err != nil || !bytes.Equal(data, first) {
+		t.Errorf("saved file holds %q (%v), want %q", data, err, first)
+	}
+
+	again, existed, err := Save(first, dir)
+	if err != nil || again != path || !existed {
+		t.Errorf("saving the same image again: got %q, existed %v, err %v; want %q, true", again, existed, err, path)
+	}
+	other, existed, err := Save(second, dir)
+	if err != nil || other == path || existed {
+		t.Errorf("saving another image: got %q, existed %v, err %v", other, existed, err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("got %d files in the clipboard directory, want 2 (no temporary files left)", len(entries))
+	}
+}
+
+func TestParseAppleScriptData(t *testing.T) {
+	data, err := parseAppleScriptData([]byte("«data PNGf89504E470D0A1A0A0102»\n"))
+	if err != nil || !bytes.Equal(data, append(append([]byte{}, pngSignature...), 1, 2)) {
+		t.Errorf("got %x, %v", data, err)
+	}
+	if _, err := parseAppleScriptData([]byte("«data TIFF4D4D»")); !errors.Is(err, ErrNoImage) {
+		t.Errorf("TIFF data: got %v, want ErrNoImage", err)
+	}
+	if _, err := parseAppleScriptData([]byte("«data PNGfZZ»")); err == nil {
+		t.Error("invalid hex: expected an error")
+	}
+}
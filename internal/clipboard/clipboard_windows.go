@@ -0,0 +1,39 @@
+//go:build windows
+
+package clipboard
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+const supported = true
+
+// readScript prints the clipboard image as base64-encoded PNG, or nothing
+// if the clipboard holds no image. The clipboard needs a single-threaded
+// apartment, hence -STA.
+const readScript = `Add-Type -AssemblyName System.Windows.Forms, System.Drawing
+$img = [System.Windows.Forms.Clipboard]::GetImage()
+if ($img -ne $null) {
+	$ms = New-Object System.IO.MemoryStream
+	$img.Save($ms, [System.Drawing.Imaging.ImageFormat]::Png)
+	[Convert]::ToBase64String($ms.ToArray())
+}`
+
+// readImage reads the clipboard as PNG with PowerShell.
+func readImage() ([]byte, error) {
+	out, err := output("powershell", "-NoProfile", "-NonInteractive", "-STA", "-Command", readScript)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimSpace(string(out))
+	if text == "" {
+		return nil, ErrNoImage
+	}
+	data, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode clipboard image: %w", err)
+	}
+	return data, nil
+}
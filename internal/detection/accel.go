@@ -0,0 +1,91 @@
+package detection
+
+import (
+	"image"
+
+	"github.com/ironsheep/image-tools-mcp/internal/accel"
+)
+
+// Hooks into package accel, which runs the Hough transforms in OpenCV when
+// the binary is built with the opencv tag. Each hook returns false when
+// OpenCV is not linked in (or declines the input), and the caller falls
+// back to its pure-Go transform. Edge maps always come from detectEdges,
+// so both backends see the same edges, and the pure-Go filtering that
+// follows each transform is shared.
+
+// acceleratedLinePeaks runs the Hough line transform over edges in
+// OpenCV. The peaks carry no vote counts; OpenCV returns them strongest
+// first.
+func acceleratedLinePeaks(edges *bitGrid, threshold int) ([]linePeak, bool) {
+	if !accel.Enabled {
+		return nil, false
+	}
+	found, ok := accel.HoughLines(edgeMask(edges), edges.width, edges.height, threshold)
+	if !ok {
+		return nil, false
+	}
+	peaks := make([]linePeak, len(found))
+	for i, p := range found {
+		peaks[i] = linePeak{rho: p.Rho, theta: p.Theta}
+	}
+	return peaks, true
+}
+
+// acceleratedCircles finds circle candidates in OpenCV, then scores each
+// one against edges with the same votes and threshold houghCircles uses
+// (circleVotes), so confidences mean the same with either backend.
+func acceleratedCircles(img image.Image, edges *bitGrid, minRadius, maxRadius int) ([]Circle, bool) {
+	if !accel.Enabled {
+		return nil, false
+	}
+	bounds := img.Bounds()
+	width, height := edges.width, edges.height
+	gray := make([]uint8, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			gray[y*width+x] = grayValue(img, x+bounds.Min.X, y+bounds.Min.Y)
+		}
+	}
+	found, ok := accel.HoughCircles(gray, width, height, minRadius, maxRadius)
+	if !ok {
+		return nil, false
+	}
+
+	circles := make([]Circle, 0, len(found))
+	for _, c := range found {
+		x, y, radius := c.X, c.Y, c.Radius
+		if radius < minRadius || radius > maxRadius ||
+			x < radius || x >= width-radius || y < radius || y >= height-radius {
+			continue
+		}
+		votes := circleVotes(edges, x, y, radius)
+		if votes < circleMinVotes {
+			continue
+		}
+		confidence := float64(votes) / circleSamples
+		circles = append(circles, Circle{
+			Center: Point{
+				X: x + bounds.Min.X,
+				Y: y + bounds.Min.Y,
+			},
+			Radius:     radius,
+			Diameter:   radius * 2,
+			FillColor:  sampleColorHex(img, x, y),
+			Confidence: confidence,
+		})
+	}
+	return circles, true
+}
+
+// edgeMask converts edges to a row-major byte mask, 255 for edges.
+func edgeMask(edges *bitGrid) []uint8 {
+	mask := make([]uint8, edges.width*edges.height)
+	for y := 0; y < edges.height; y++ {
+		for x := 0; x < edges.width; x++ {
+			if edges.Get(x, y) {
+				mask[y*edges.width+x] = 255
+			}
+		}
+	}
+	return mask
+}
@@ -0,0 +1,72 @@
+package detection
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/ironsheep/image-tools-mcp/internal/accel"
+)
+
+func TestCircleVotes_MatchesAccumulator(t *testing.T) {
+	img := createTestImage(200, 200, color.White)
+	for dy := -15; dy <= 15; dy++ {
+		for dx := -15; dx <= 15; dx++ {
+			if dx*dx+dy*dy <= 225 {
+				img.Set(100+dx, 100+dy, color.RGBA{0, 0, 200, 255})
+			}
+		}
+	}
+	edges := detectEdges(img, 200, 200)
+	defer edges.release()
+
+	circles := houghCircles(img, edges, 10, 20, nil)
+	if len(circles) == 0 {
+		t.Fatal("expected circle candidates")
+	}
+	// The OpenCV path scores its candidates with circleVotes, so it must
+	// agree with the accumulator
+	for _, c := range circles {
+		votes := circleVotes(edges, c.Center.X, c.Center.Y, c.Radius)
+		want := float64(votes) / circleSamples
+		if c.Confidence != want {
+			t.Errorf("circle at (%d,%d) r=%d: confidence %v, circleVotes gives %v",
+				c.Center.X, c.Center.Y, c.Radius, c.Confidence, want)
+		}
+	}
+}
+
+func TestEdgeMask(t *testing.T) {
+	g := newBitGrid(70, 2)
+	defer g.release()
+	g.Set(0, 0)
+	g.Set(69, 1)
+
+	mask := edgeMask(g)
+	if len(mask) != 140 {
+		t.Fatalf("len: got %d, want 140", len(mask))
+	}
+	for i, v := range mask {
+		want := uint8(0)
+		if i == 0 || i == 139 {
+			want = 255
+		}
+		if v != want {
+			t.Errorf("mask[%d]: got %d, want %d", i, v, want)
+		}
+	}
+}
+
+func TestAcceleratedHooks_FallBackWithoutOpenCV(t *testing.T) {
+	if accel.Enabled {
+		t.Skip("built with OpenCV")
+	}
+	img := createTestImage(50, 50, color.White)
+	edges := detectEdges(img, 50, 50)
+	defer edges.release()
+	if _, ok := acceleratedLinePeaks(edges, 10); ok {
+		t.Error("acceleratedLinePeaks: expected fallback")
+	}
+	if _, ok := acceleratedCircles(img, edges, 5, 10); ok {
+		t.Error("acceleratedCircles: expected fallback")
+	}
+}
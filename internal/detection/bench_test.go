@@ -0,0 +1,130 @@
+package detection
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// benchmarkSizes are the image sizes every pixel-loop benchmark runs at.
+// Content is tiled, so its density is the same at every size and time per
+// operation should grow with the pixel count; a benchmark that grows
+// faster than that has regressed.
+var benchmarkSizes = []struct {
+	name          string
+	width, height int
+}{
+	{"512", 512, 512},
+	{"1080p", 1920, 1080},
+	{"4K", 3840, 2160},
+}
+
+// benchmarkTile is the side of the square tile repeated by benchmarkScene.
+const benchmarkTile = 256
+
+// benchmarkScene draws a width x height diagram by tiling a 256x256 cell
+// holding a box outline, a filled circle of radius 30, a horizontal line,
+// and a block of text-like strokes, so every detector has work to do.
+func benchmarkScene(width, height int) *image.RGBA {
+	img := createTestImage(width, height, color.White)
+	black := color.RGBA{0, 0, 0, 255}
+	blue := color.RGBA{0, 0, 200, 255}
+	for ty := 0; ty < height; ty += benchmarkTile {
+		for tx := 0; tx < width; tx += benchmarkTile {
+			// Box outline
+			for x := tx + 16; x <= tx+136; x++ {
+				img.Set(x, ty+16, black)
+				img.Set(x, ty+96, black)
+			}
+			for y := ty + 16; y <= ty+96; y++ {
+				img.Set(tx+16, y, black)
+				img.Set(tx+136, y, black)
+			}
+			// Filled circle
+			for dy := -30; dy <= 30; dy++ {
+				for dx := -30; dx <= 30; dx++ {
+					if dx*dx+dy*dy <= 900 {
+						img.Set(tx+200+dx, ty+60+dy, blue)
+					}
+				}
+			}
+			// Horizontal line
+			for x := tx + 16; x < tx+240; x++ {
+				img.Set(x, ty+130, black)
+			}
+			// Text-like strokes
+			for row := 0; row < 4; row++ {
+				for c := 0; c < 36; c++ {
+					for k := 0; k < 8; k++ {
+						if (c+k+row)%3 != 0 {
+							img.Set(tx+16+c*6+k%4, ty+150+row*25+k, black)
+						}
+					}
+				}
+			}
+		}
+	}
+	return img
+}
+
+// runSizes runs fn as a sub-benchmark at each of benchmarkSizes, with the
+// scene built outside the timed region.
+func runSizes(b *testing.B, fn func(b *testing.B, img *image.RGBA)) {
+	for _, size := range benchmarkSizes {
+		b.Run(size.name, func(b *testing.B) {
+			img := benchmarkScene(size.width, size.height)
+			b.ReportAllocs()
+			b.ResetTimer()
+			fn(b, img)
+		})
+	}
+}
+
+func BenchmarkDetectEdges(b *testing.B) {
+	runSizes(b, func(b *testing.B, img *image.RGBA) {
+		bounds := img.Bounds()
+		for i := 0; i < b.N; i++ {
+			detectEdges(img, bounds.Dx(), bounds.Dy()).release()
+		}
+	})
+}
+
+func BenchmarkDetectRectangles(b *testing.B) {
+	runSizes(b, func(b *testing.B, img *image.RGBA) {
+		for i := 0; i < b.N; i++ {
+			if _, err := DetectRectangles(img, 100, 0.1); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkDetectCircles(b *testing.B) {
+	runSizes(b, func(b *testing.B, img *image.RGBA) {
+		for i := 0; i < b.N; i++ {
+			if _, err := DetectCircles(img, 25, 35); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkDetectLines(b *testing.B) {
+	runSizes(b, func(b *testing.B, img *image.RGBA) {
+		for i := 0; i < b.N; i++ {
+			if _, err := DetectLines(img, 50, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkDetectTextRegions(b *testing.B) {
+	runSizes(b, func(b *testing.B, img *image.RGBA) {
+		for i := 0; i < b.N; i++ {
+			if _, err := DetectTextRegions(img, 0.3); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,280 @@
+package detection
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+)
+
+// Debug overlay colors, by meaning.
+var (
+	debugEdgeColor     = color.RGBA{64, 160, 255, 255}
+	debugAcceptColor   = color.RGBA{0, 192, 0, 255}
+	debugRejectColor   = color.RGBA{255, 0, 0, 255}
+	debugNearMissColor = color.RGBA{255, 153, 0, 255}
+	debugPeakColor     = color.RGBA{255, 0, 255, 255}
+)
+
+// maxDebugRejections caps the rejections listed in a DebugOverlay. All of
+// them are still drawn.
+const maxDebugRejections = 100
+
+// DebugOverlay explains a detection run. The image shows the input faded
+// to gray, with the edge map and every candidate the detector considered
+// drawn over it, colored by outcome (see Legend).
+type DebugOverlay struct {
+	// ImageBase64 is the overlay encoded as base64 PNG, the same size as
+	// the input.
+	ImageBase64 string `json:"image_base64"`
+
+	// MimeType is always "image/png".
+	MimeType string `json:"mime_type"`
+
+	// Legend maps each color used (#RRGGBB) to its meaning.
+	Legend map[string]string `json:"legend"`
+
+	// EdgePixels is the number of pixels in the edge map.
+	EdgePixels int `json:"edge_pixels"`
+
+	// Peaks is the number of accumulator peaks (Hough detectors only).
+	Peaks int `json:"peaks,omitempty"`
+
+	// Accepted is the number of candidates in the result.
+	Accepted int `json:"accepted"`
+
+	// RejectedCount is the number of candidates rejected.
+	RejectedCount int `json:"rejected_count"`
+
+	// Rejected lists the first rejected candidates (at most 100) with the
+	// reason for each.
+	Rejected []DebugRejection `json:"rejected"`
+}
+
+// DebugRejection is one candidate a detector considered and dropped.
+type DebugRejection struct {
+	// Shape is the candidate, in image coordinates.
+	Shape Primitive `json:"shape"`
+
+	// Reason says which check failed, with the measured and required
+	// values.
+	Reason string `json:"reason"`
+}
+
+// debugMark is one shape to draw on the overlay. Accepted marks are
+// drawn last, over rejected ones.
+type debugMark struct {
+	shape    Primitive
+	color    color.RGBA
+	alpha    float64
+	accepted bool
+}
+
+// debugTrace records what a detector did, for DebugOverlay. Detectors
+// take a *debugTrace that is nil outside debug runs; every method is a
+// no-op on a nil trace.
+type debugTrace struct {
+	canvas   *image.RGBA
+	legend   map[string]string
+	edges    int
+	peaks    int
+	crosses  []Point
+	marks    []debugMark
+	accepted int
+	rejected []DebugRejection
+	rejects  int
+}
+
+// newDebugTrace starts a trace over img, whose faded copy is the overlay
+// background.
+func newDebugTrace(img image.Image) *debugTrace {
+	b := img.Bounds()
+	canvas := image.NewRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			g := 255 - (255-grayValue(img, x, y))/3
+			canvas.SetRGBA(x, y, color.RGBA{g, g, g, 255})
+		}
+	}
+	return &debugTrace{
+		canvas: canvas,
+		legend: map[string]string{hexColor(debugEdgeColor): "edge pixels"},
+	}
+}
+
+// edgeMap paints the edge map onto the overlay.
+func (t *debugTrace) edgeMap(edges *bitGrid) {
+	if t == nil {
+		return
+	}
+	min := t.canvas.Rect.Min
+	for y := 0; y < edges.height; y++ {
+		for x := 0; x < edges.width; x++ {
+			if edges.Get(x, y) {
+				t.canvas.SetRGBA(min.X+x, min.Y+y, debugEdgeColor)
+				t.edges++
+			}
+		}
+	}
+}
+
+// peak records an accumulator peak centered at p (image coordinates),
+// drawn as a cross.
+func (t *debugTrace) peak(p Point) {
+	if t == nil {
+		return
+	}
+	t.peaks++
+	t.crosses = append(t.crosses, p)
+	t.legend[hexColor(debugPeakColor)] = "accumulator peaks"
+}
+
+// countPeaks records n accumulator peaks that have no point to draw, such
+// as Hough lines.
+func (t *debugTrace) countPeaks(n int) {
+	if t == nil {
+		return
+	}
+	t.peaks += n
+}
+
+// accept records the shapes of the result.
+func (t *debugTrace) accept(shapes []Primitive) {
+	if t == nil {
+		return
+	}
+	for _, s := range shapes {
+		t.marks = append(t.marks, debugMark{shape: s, color: debugAcceptColor, alpha: 1, accepted: true})
+	}
+	t.accepted += len(shapes)
+	t.legend[hexColor(debugAcceptColor)] = "accepted"
+}
+
+// reject records a dropped candidate. Near misses, which failed only a
+// size or vote threshold, are drawn in their own color.
+func (t *debugTrace) reject(shape Primitive, nearMiss bool, reason string) {
+	if t == nil {
+		return
+	}
+	c, meaning, label := debugRejectColor, "rejected", "rejected"
+	if nearMiss {
+		c, meaning, label = debugNearMissColor, "near miss (below a threshold)", "near miss"
+	}
+	shape.Color, shape.Label = hexColor(c), label
+	t.marks = append(t.marks, debugMark{shape: shape, color: c, alpha: 0.8})
+	t.legend[hexColor(c)] = meaning
+	t.rejects++
+	if len(t.rejected) < maxDebugRejections {
+		t.rejected = append(t.rejected, DebugRejection{Shape: shape, Reason: reason})
+	}
+}
+
+// overlay draws the recorded shapes (rejected below accepted, peaks on
+// top) and encodes the result.
+func (t *debugTrace) overlay() (*DebugOverlay, error) {
+	for _, accepted := range []bool{false, true} {
+		for _, m := range t.marks {
+			if m.accepted == accepted {
+				t.drawPrimitive(m)
+			}
+		}
+	}
+	for _, p := range t.crosses {
+		for d := -3; d <= 3; d++ {
+			t.plot(p.X+d, p.Y, debugPeakColor, 1)
+			t.plot(p.X, p.Y+d, debugPeakColor, 1)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, t.canvas); err != nil {
+		return nil, fmt.Errorf("failed to encode debug overlay: %w", err)
+	}
+	rejected := t.rejected
+	if rejected == nil {
+		rejected = []DebugRejection{}
+	}
+	return &DebugOverlay{
+		ImageBase64:   base64.StdEncoding.EncodeToString(buf.Bytes()),
+		MimeType:      "image/png",
+		Legend:        t.legend,
+		EdgePixels:    t.edges,
+		Peaks:         t.peaks,
+		Accepted:      t.accepted,
+		RejectedCount: t.rejects,
+		Rejected:      rejected,
+	}, nil
+}
+
+// drawPrimitive outlines one mark on the canvas.
+func (t *debugTrace) drawPrimitive(m debugMark) {
+	s := m.shape
+	switch s.Type {
+	case PrimitiveRect:
+		b := s.Bounds
+		t.drawLine(Point{X: b.X1, Y: b.Y1}, Point{X: b.X2, Y: b.Y1}, m.color, m.alpha)
+		t.drawLine(Point{X: b.X2, Y: b.Y1}, Point{X: b.X2, Y: b.Y2}, m.color, m.alpha)
+		t.drawLine(Point{X: b.X2, Y: b.Y2}, Point{X: b.X1, Y: b.Y2}, m.color, m.alpha)
+		t.drawLine(Point{X: b.X1, Y: b.Y2}, Point{X: b.X1, Y: b.Y1}, m.color, m.alpha)
+	case PrimitiveCircle:
+		steps := maxInt(16, int(2*math.Pi*float64(s.Radius)))
+		for i := 0; i < steps; i++ {
+			a := 2 * math.Pi * float64(i) / float64(steps)
+			t.plot(s.Center.X+int(math.Round(float64(s.Radius)*math.Cos(a))),
+				s.Center.Y+int(math.Round(float64(s.Radius)*math.Sin(a))), m.color, m.alpha)
+		}
+	case PrimitiveLine:
+		t.drawLine(*s.Start, *s.End, m.color, m.alpha)
+	}
+}
+
+// drawLine draws a one-pixel line from a to b (Bresenham).
+func (t *debugTrace) drawLine(a, b Point, c color.RGBA, alpha float64) {
+	dx := int(math.Abs(float64(b.X - a.X)))
+	dy := -int(math.Abs(float64(b.Y - a.Y)))
+	sx, sy := 1, 1
+	if a.X > b.X {
+		sx = -1
+	}
+	if a.Y > b.Y {
+		sy = -1
+	}
+	err := dx + dy
+	x, y := a.X, a.Y
+	for {
+		t.plot(x, y, c, alpha)
+		if x == b.X && y == b.Y {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y += sy
+		}
+	}
+}
+
+// plot blends c into the canvas at (x, y) with the given opacity,
+// ignoring points outside the image.
+func (t *debugTrace) plot(x, y int, c color.RGBA, alpha float64) {
+	if !(image.Point{X: x, Y: y}).In(t.canvas.Rect) {
+		return
+	}
+	bg := t.canvas.RGBAAt(x, y)
+	mix := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a)*(1-alpha) + float64(b)*alpha))
+	}
+	t.canvas.SetRGBA(x, y, color.RGBA{mix(bg.R, c.R), mix(bg.G, c.G), mix(bg.B, c.B), 255})
+}
+
+// hexColor formats c as #RRGGBB.
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B)
+}
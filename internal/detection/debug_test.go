@@ -0,0 +1,170 @@
+package detection
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// decodeOverlay decodes a debug overlay image and checks its size.
+func decodeOverlay(t *testing.T, d *DebugOverlay, size image.Point) image.Image {
+	t.Helper()
+	if d == nil {
+		t.Fatal("expected a debug overlay")
+	}
+	data, err := base64.StdEncoding.DecodeString(d.ImageBase64)
+	if err != nil {
+		t.Fatalf("invalid base64: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("invalid PNG: %v", err)
+	}
+	if got := img.Bounds().Size(); got != size {
+		t.Errorf("overlay size: got %v, want %v", got, size)
+	}
+	if d.MimeType != "image/png" {
+		t.Errorf("MimeType: got %q", d.MimeType)
+	}
+	return img
+}
+
+func TestDetectRectanglesDebug(t *testing.T) {
+	img := createTestImage(200, 150, color.White)
+	fillBox(img, image.Rect(20, 20, 100, 80), color.RGBA{0, 0, 200, 255})
+	for dy := -20; dy <= 20; dy++ {
+		for dx := -20; dx <= 20; dx++ {
+			if dx*dx+dy*dy <= 400 {
+				img.Set(150+dx, 100+dy, color.Black)
+			}
+		}
+	}
+
+	want, _ := DetectRectangles(img, 100, 0.9)
+	got, err := DetectRectanglesDebug(img, 100, 0.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := got.Debug
+	got.Debug = nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("debug run changed the result: got %+v, want %+v", got, want)
+	}
+
+	overlay := decodeOverlay(t, d, image.Pt(200, 150))
+	if d.Accepted != want.Count || d.EdgePixels == 0 {
+		t.Errorf("got %d accepted and %d edge pixels", d.Accepted, d.EdgePixels)
+	}
+	if d.RejectedCount != 1 || !strings.Contains(d.Rejected[0].Reason, "rectangularity") {
+		t.Fatalf("expected the disk to be rejected as not rectangular, got %+v", d.Rejected)
+	}
+	if b := d.Rejected[0].Shape.Bounds; b == nil || b.X1 > 131 || b.X2 < 169 {
+		t.Errorf("rejected bounds %+v do not cover the disk", b)
+	}
+	for _, c := range []color.RGBA{debugEdgeColor, debugAcceptColor, debugRejectColor} {
+		if _, ok := d.Legend[hexColor(c)]; !ok {
+			t.Errorf("legend is missing %s: %v", hexColor(c), d.Legend)
+		}
+	}
+
+	// The accepted rectangle is drawn on top
+	r := want.Rectangles[0].Bounds
+	if c := color.RGBAModel.Convert(overlay.At(r.X1, (r.Y1+r.Y2)/2)).(color.RGBA); c != debugAcceptColor {
+		t.Errorf("left side of accepted rectangle: got %v, want %v", c, debugAcceptColor)
+	}
+}
+
+func TestDetectCirclesDebug(t *testing.T) {
+	img := createTestImage(200, 200, color.White)
+	for dy := -15; dy <= 15; dy++ {
+		for dx := -15; dx <= 15; dx++ {
+			if dx*dx+dy*dy <= 225 {
+				img.Set(100+dx, 100+dy, color.RGBA{0, 0, 200, 255})
+			}
+		}
+	}
+
+	want, _ := DetectCircles(img, 10, 20)
+	got, err := DetectCirclesDebug(img, 10, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := got.Debug
+	got.Debug = nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("debug run changed the result: got %+v, want %+v", got, want)
+	}
+
+	decodeOverlay(t, d, image.Pt(200, 200))
+	if want.Count == 0 || d.Accepted != want.Count {
+		t.Fatalf("got %d accepted, want %d (> 0)", d.Accepted, want.Count)
+	}
+	if d.Peaks < d.Accepted+d.RejectedCount {
+		t.Errorf("every candidate is a peak: got %d peaks for %d accepted and %d rejected",
+			d.Peaks, d.Accepted, d.RejectedCount)
+	}
+	for _, r := range d.Rejected {
+		if r.Shape.Type != PrimitiveCircle || r.Shape.Center == nil ||
+			!(strings.Contains(r.Reason, "duplicate") || strings.Contains(r.Reason, "votes")) {
+			t.Errorf("unexpected rejection: %+v", r)
+		}
+	}
+}
+
+func TestDetectLinesDebug(t *testing.T) {
+	img := createTestImage(200, 100, color.White)
+	fillBox(img, image.Rect(20, 50, 180, 52), color.Black)
+	fillBox(img, image.Rect(60, 80, 70, 82), color.Black)
+
+	want, _ := DetectLines(img, 40, false)
+	got, err := DetectLinesDebug(img, 40, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := got.Debug
+	got.Debug = nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("debug run changed the result: got %+v, want %+v", got, want)
+	}
+
+	decodeOverlay(t, d, image.Pt(200, 100))
+	if d.Peaks == 0 || d.Accepted != want.Count {
+		t.Errorf("got %d peaks and %d accepted, want peaks and %d accepted", d.Peaks, d.Accepted, want.Count)
+	}
+	if d.Accepted+d.RejectedCount > d.Peaks {
+		t.Errorf("more outcomes (%d) than peaks (%d)", d.Accepted+d.RejectedCount, d.Peaks)
+	}
+}
+
+func TestHoughLineEnds(t *testing.T) {
+	tests := []struct {
+		peak       linePeak
+		start, end Point
+	}{
+		{linePeak{rho: 10, theta: 90}, Point{X: 5, Y: 17}, Point{X: 104, Y: 17}},
+		{linePeak{rho: 5, theta: 0}, Point{X: 10, Y: 7}, Point{X: 10, Y: 56}},
+	}
+	for _, tt := range tests {
+		start, end := houghLineEnds(tt.peak, 100, 50, image.Pt(5, 7))
+		if start != tt.start || end != tt.end {
+			t.Errorf("houghLineEnds(%+v): got %v-%v, want %v-%v", tt.peak, start, end, tt.start, tt.end)
+		}
+	}
+}
+
+func TestDebugTrace_Nil(t *testing.T) {
+	// Detectors call the trace unconditionally; a nil trace must be inert
+	var trace *debugTrace
+	g := newBitGrid(4, 4)
+	defer g.release()
+	trace.edgeMap(g)
+	trace.peak(Point{})
+	trace.countPeaks(3)
+	trace.accept([]Primitive{{Type: PrimitiveRect, Bounds: &Bounds{}}})
+	trace.reject(Primitive{Type: PrimitiveRect, Bounds: &Bounds{}}, false, "test")
+}
@@ -11,7 +11,10 @@
 //   - Rectangles: Using edge detection and contour analysis
 //   - Circles: Using the Hough circle transform
 //   - Lines: Using the Hough line transform with arrow detection
-//   - Text regions: Using edge density heuristics
+//   - Text regions: Using edge density heuristics, each classified as printed
+//     or handwritten from the regularity of its strokes
+//   - Formula regions: Groups of symbols with the vertical spread, scripts,
+//     and operator bars of mathematical notation
 //
 // # Algorithm Overview
 //
@@ -44,12 +47,72 @@
 //   - Lines: Based on vote count in Hough space
 //   - Text regions: Based on edge density and horizontal structure
 //
+// # Drawing Results
+//
+// Every result type has a Primitives method that converts its detections
+// to rect, circle, and line primitives with fixed colors and numbered
+// labels, so the output of any detection tool can be handed to a drawing
+// step or an annotation export as is.
+//
+// # Masks
+//
+// The Restrict methods keep only the detections whose center (a line's
+// midpoint) is selected by a mask bitmap, for analyzing an irregular area.
+// Detection still runs on the whole image, so shapes crossing the mask's
+// edge are found as they are; painting out the unselected area instead
+// would add a false edge along the mask's outline.
+//
+// # Exporting Annotations
+//
+// Annotations turns primitives into labeled bounding boxes, and
+// ExportAnnotations writes them as a COCO JSON, YOLO txt, or Pascal VOC
+// XML annotation file, so detections can seed a training dataset. Class
+// ids come from the fixed AnnotationCategories list and do not depend on
+// the detector.
+//
 // # Performance Considerations
 //
 // Detection algorithms iterate over all pixels and may be computationally intensive
 // for large images. The Hough transforms have O(n²) or O(n³) complexity depending
 // on the parameter space searched.
 //
+// Window statistics (edge counts, edge runs, mean colors) are computed from
+// integral images (summed-area tables; see IntegralImage), so the sliding
+// window scan in DetectTextRegions costs O(1) per window position.
+//
+// Scratch buffers (edge maps, visited sets, Hough accumulators, and
+// integral image tables) are taken from size-classed pools and returned
+// when a call finishes, so repeated calls in a batch reuse memory instead
+// of allocating it afresh. Run the package benchmarks with
+// "go test -bench . -benchmem" to see allocations per call.
+//
+// When the binary is built with the opencv tag, the Hough transforms in
+// DetectLines and DetectCircles run in OpenCV (see package accel). Edge
+// maps and the filtering of peaks and candidates stay in Go, so results
+// use the same structs and confidence scales with either backend.
+//
+// For a sequence of similar frames, such as captures of a live UI, build a
+// Snapshot of the first frame and Update it with the regions that changed
+// (see ChangedRegions). Rectangle and text region results are identical to
+// a full detection, but only contours and windows near the changes are
+// recomputed.
+//
+// When suitable parameters are unknown, TuneRectangles, TuneLines, and
+// TuneCircles pick them by sweeping a small grid on a downscaled copy and
+// keeping the parameters whose detections are most stable across two sizes.
+//
+// To see why a shape was or was not found, DetectRectanglesDebug,
+// DetectLinesDebug, and DetectCirclesDebug return the same result with a
+// DebugOverlay: the edge map and every candidate drawn by outcome, and the
+// reason each rejected candidate was dropped.
+//
+// AuditStrokeWidths measures the width of every line and rectangle border
+// across the stroke, to check that a diagram keeps to one stroke width.
+//
+// ExtractFlowchart reads a flowchart as a graph: its nodes, found as the
+// background their outlines enclose and classified by shape, and the
+// connectors between them, with their arrowheads and paths.
+//
 // For large images, consider:
 //   - Cropping to regions of interest first
 //   - Using higher minimum size thresholds to reduce false positives
@@ -0,0 +1,284 @@
+package detection
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Annotation export formats accepted by ExportAnnotations.
+const (
+	ExportCOCO = "coco"
+	ExportYOLO = "yolo"
+	ExportVOC  = "voc"
+)
+
+// ExportFormats lists the annotation export formats.
+var ExportFormats = []string{ExportCOCO, ExportYOLO, ExportVOC}
+
+// Annotation categories. AnnotationCategories fixes their order, which
+// gives the class ids: 1-based in COCO, 0-based in YOLO. The ids are the
+// same for every tool, so exports of different detectors can be merged
+// into one dataset.
+const (
+	CategoryRectangle = "rectangle"
+	CategoryCircle    = "circle"
+	CategoryLine      = "line"
+	CategoryText      = "text"
+)
+
+// AnnotationCategories lists the annotation categories in class-id order.
+var AnnotationCategories = []string{CategoryRectangle, CategoryCircle, CategoryLine, CategoryText}
+
+// Annotation is one labeled bounding box.
+type Annotation struct {
+	// Category is one of AnnotationCategories.
+	Category string `json:"category"`
+
+	// Bounds is the box in image coordinates (X2, Y2 exclusive).
+	Bounds Bounds `json:"bounds"`
+}
+
+// AnnotationExport is a detection result serialized as a dataset
+// annotation file.
+type AnnotationExport struct {
+	// Format is "coco", "yolo", or "voc".
+	Format string `json:"format"`
+
+	// FileName is the conventional name for the annotation file: the
+	// image's base name with .json, .txt, or .xml.
+	FileName string `json:"file_name"`
+
+	// MimeType is "application/json", "text/plain", or "application/xml".
+	MimeType string `json:"mime_type"`
+
+	// Categories lists the category names in class-id order (YOLO's
+	// classes.txt).
+	Categories []string `json:"categories"`
+
+	// Count is the number of annotations written. Boxes are clipped to
+	// the image, and boxes entirely outside it are dropped.
+	Count int `json:"count"`
+
+	// Data is the content of the annotation file.
+	Data string `json:"data"`
+}
+
+// Annotations returns the bounding box of each primitive, labeled with
+// category. Circle boxes span the center plus and minus the radius; line
+// boxes span the endpoints and are at least one pixel wide and high.
+func Annotations(category string, prims []Primitive) []Annotation {
+	anns := make([]Annotation, 0, len(prims))
+	for _, p := range prims {
+		var b Bounds
+		switch p.Type {
+		case PrimitiveRect:
+			b = *p.Bounds
+		case PrimitiveCircle:
+			b = Bounds{X1: p.Center.X - p.Radius, Y1: p.Center.Y - p.Radius, X2: p.Center.X + p.Radius + 1, Y2: p.Center.Y + p.Radius + 1}
+		case PrimitiveLine:
+			b = Bounds{
+				X1: min(p.Start.X, p.End.X), Y1: min(p.Start.Y, p.End.Y),
+				X2: max(p.Start.X, p.End.X) + 1, Y2: max(p.Start.Y, p.End.Y) + 1,
+			}
+		default:
+			continue
+		}
+		anns = append(anns, Annotation{Category: category, Bounds: b})
+	}
+	return anns
+}
+
+// ExportAnnotations serializes annotations of one image in a standard
+// object-detection dataset format, so detections can bootstrap a labeled
+// training set.
+//
+// Parameters:
+//   - format: "coco" (COCO JSON), "yolo" (YOLO txt), or "voc" (Pascal
+//     VOC XML).
+//   - imageName: File name of the image, as recorded in COCO and VOC.
+//   - width, height: Image size in pixels.
+//   - anns: The annotations, in output order.
+//
+// Returns:
+//   - *AnnotationExport: The annotation file content and metadata.
+//   - error: Non-nil if the format is unknown, a category is not in
+//     AnnotationCategories, or the image size is not positive.
+//
+// # Coordinates
+//
+//   - COCO: bbox is [x, y, width, height] in pixels from the top-left.
+//   - YOLO: one "class x_center y_center width height" line per box,
+//     normalized to 0-1 by the image size.
+//   - VOC: xmin, ymin, xmax, ymax in 1-based inclusive pixels, so xmin is
+//     X1+1 and xmax is X2.
+func ExportAnnotations(format, imageName string, width, height int, anns []Annotation) (*AnnotationExport, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("image size must be positive, got %dx%d", width, height)
+	}
+	ids := make(map[string]int, len(AnnotationCategories))
+	for i, name := range AnnotationCategories {
+		ids[name] = i
+	}
+
+	clipped := make([]Annotation, 0, len(anns))
+	for _, a := range anns {
+		if _, ok := ids[a.Category]; !ok {
+			return nil, fmt.Errorf("unknown annotation category %q", a.Category)
+		}
+		b := Bounds{
+			X1: max(a.Bounds.X1, 0), Y1: max(a.Bounds.Y1, 0),
+			X2: min(a.Bounds.X2, width), Y2: min(a.Bounds.Y2, height),
+		}
+		if b.X2 > b.X1 && b.Y2 > b.Y1 {
+			clipped = append(clipped, Annotation{Category: a.Category, Bounds: b})
+		}
+	}
+
+	export := &AnnotationExport{
+		Format:     format,
+		Categories: AnnotationCategories,
+		Count:      len(clipped),
+	}
+	stem := strings.TrimSuffix(imageName, path.Ext(imageName))
+	switch format {
+	case ExportCOCO:
+		data, err := exportCOCO(imageName, width, height, clipped, ids)
+		if err != nil {
+			return nil, err
+		}
+		export.FileName, export.MimeType, export.Data = stem+".json", "application/json", data
+	case ExportYOLO:
+		export.FileName, export.MimeType = stem+".txt", "text/plain"
+		export.Data = exportYOLO(width, height, clipped, ids)
+	case ExportVOC:
+		data, err := exportVOC(imageName, width, height, clipped)
+		if err != nil {
+			return nil, err
+		}
+		export.FileName, export.MimeType, export.Data = stem+".xml", "application/xml", data
+	default:
+		return nil, fmt.Errorf("unknown export format %q (want %s)", format, strings.Join(ExportFormats, ", "))
+	}
+	return export, nil
+}
+
+// exportCOCO writes a COCO detection dataset holding the one image.
+func exportCOCO(imageName string, width, height int, anns []Annotation, ids map[string]int) (string, error) {
+	type cocoImage struct {
+		ID       int    `json:"id"`
+		FileName string `json:"file_name"`
+		Width    int    `json:"width"`
+		Height   int    `json:"height"`
+	}
+	type cocoAnnotation struct {
+		ID         int    `json:"id"`
+		ImageID    int    `json:"image_id"`
+		CategoryID int    `json:"category_id"`
+		BBox       [4]int `json:"bbox"`
+		Area       int    `json:"area"`
+		IsCrowd    int    `json:"iscrowd"`
+	}
+	type cocoCategory struct {
+		ID            int    `json:"id"`
+		Name          string `json:"name"`
+		Supercategory string `json:"supercategory"`
+	}
+
+	doc := struct {
+		Images      []cocoImage      `json:"images"`
+		Annotations []cocoAnnotation `json:"annotations"`
+		Categories  []cocoCategory   `json:"categories"`
+	}{
+		Images:      []cocoImage{{ID: 1, FileName: imageName, Width: width, Height: height}},
+		Annotations: make([]cocoAnnotation, len(anns)),
+		Categories:  make([]cocoCategory, len(AnnotationCategories)),
+	}
+	for i, a := range anns {
+		w, h := a.Bounds.X2-a.Bounds.X1, a.Bounds.Y2-a.Bounds.Y1
+		doc.Annotations[i] = cocoAnnotation{
+			ID:         i + 1,
+			ImageID:    1,
+			CategoryID: ids[a.Category] + 1,
+			BBox:       [4]int{a.Bounds.X1, a.Bounds.Y1, w, h},
+			Area:       w * h,
+		}
+	}
+	for i, name := range AnnotationCategories {
+		super := "shape"
+		if name == CategoryText {
+			super = "text"
+		}
+		doc.Categories[i] = cocoCategory{ID: i + 1, Name: name, Supercategory: super}
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode COCO annotations: %w", err)
+	}
+	return string(data) + "\n", nil
+}
+
+// exportYOLO writes one YOLO label line per annotation.
+func exportYOLO(width, height int, anns []Annotation, ids map[string]int) string {
+	var b strings.Builder
+	w, h := float64(width), float64(height)
+	for _, a := range anns {
+		fmt.Fprintf(&b, "%d %.6f %.6f %.6f %.6f\n", ids[a.Category],
+			float64(a.Bounds.X1+a.Bounds.X2)/2/w, float64(a.Bounds.Y1+a.Bounds.Y2)/2/h,
+			float64(a.Bounds.X2-a.Bounds.X1)/w, float64(a.Bounds.Y2-a.Bounds.Y1)/h)
+	}
+	return b.String()
+}
+
+// exportVOC writes a Pascal VOC annotation document.
+func exportVOC(imageName string, width, height int, anns []Annotation) (string, error) {
+	type vocBox struct {
+		XMin int `xml:"xmin"`
+		YMin int `xml:"ymin"`
+		XMax int `xml:"xmax"`
+		YMax int `xml:"ymax"`
+	}
+	type vocObject struct {
+		Name      string `xml:"name"`
+		Pose      string `xml:"pose"`
+		Truncated int    `xml:"truncated"`
+		Difficult int    `xml:"difficult"`
+		BndBox    vocBox `xml:"bndbox"`
+	}
+	type vocSize struct {
+		Width  int `xml:"width"`
+		Height int `xml:"height"`
+		Depth  int `xml:"depth"`
+	}
+
+	doc := struct {
+		XMLName   xml.Name    `xml:"annotation"`
+		Filename  string      `xml:"filename"`
+		Size      vocSize     `xml:"size"`
+		Segmented int         `xml:"segmented"`
+		Objects   []vocObject `xml:"object"`
+	}{
+		Filename: imageName,
+		Size:     vocSize{Width: width, Height: height, Depth: 3},
+		Objects:  make([]vocObject, len(anns)),
+	}
+	for i, a := range anns {
+		doc.Objects[i] = vocObject{
+			Name: a.Category,
+			Pose: "Unspecified",
+			BndBox: vocBox{
+				XMin: a.Bounds.X1 + 1, YMin: a.Bounds.Y1 + 1,
+				XMax: a.Bounds.X2, YMax: a.Bounds.Y2,
+			},
+		}
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode VOC annotations: %w", err)
+	}
+	return xml.Header + string(data) + "\n", nil
+}
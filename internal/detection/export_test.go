@@ -0,0 +1,123 @@
+package detection
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAnnotations(t *testing.T) {
+	rects := &RectanglesResult{Rectangles: []Rectangle{{Bounds: Bounds{X1: 10, Y1: 20, X2: 50, Y2: 40}}}}
+	circles := &CirclesResult{Circles: []Circle{{Center: Point{X: 30, Y: 30}, Radius: 5}}}
+	lines := &LinesResult{Lines: []Line{{Start: Point{X: 60, Y: 10}, End: Point{X: 20, Y: 10}}}}
+
+	got := append(Annotations(CategoryRectangle, rects.Primitives()), Annotations(CategoryCircle, circles.Primitives())...)
+	got = append(got, Annotations(CategoryLine, lines.Primitives())...)
+	want := []Annotation{
+		{Category: CategoryRectangle, Bounds: Bounds{X1: 10, Y1: 20, X2: 50, Y2: 40}},
+		{Category: CategoryCircle, Bounds: Bounds{X1: 25, Y1: 25, X2: 36, Y2: 36}},
+		{Category: CategoryLine, Bounds: Bounds{X1: 20, Y1: 10, X2: 61, Y2: 11}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestExportAnnotations(t *testing.T) {
+	anns := []Annotation{
+		{Category: CategoryRectangle, Bounds: Bounds{X1: 10, Y1: 20, X2: 50, Y2: 40}},
+		{Category: CategoryCircle, Bounds: Bounds{X1: -5, Y1: 90, X2: 15, Y2: 110}}, // Clipped to the image
+		{Category: CategoryLine, Bounds: Bounds{X1: 300, Y1: 0, X2: 310, Y2: 5}},    // Outside, dropped
+	}
+
+	t.Run("coco", func(t *testing.T) {
+		export, err := ExportAnnotations(ExportCOCO, "diagram.png", 200, 100, anns)
+		if err != nil {
+			t.Fatalf("ExportAnnotations failed: %v", err)
+		}
+		if export.FileName != "diagram.json" || export.MimeType != "application/json" || export.Count != 2 {
+			t.Errorf("metadata: got %+v", export)
+		}
+		var doc struct {
+			Images []struct {
+				FileName string `json:"file_name"`
+				Width    int    `json:"width"`
+			} `json:"images"`
+			Annotations []struct {
+				CategoryID int    `json:"category_id"`
+				BBox       [4]int `json:"bbox"`
+				Area       int    `json:"area"`
+			} `json:"annotations"`
+			Categories []struct {
+				ID   int    `json:"id"`
+				Name string `json:"name"`
+			} `json:"categories"`
+		}
+		if err := json.Unmarshal([]byte(export.Data), &doc); err != nil {
+			t.Fatalf("COCO data is not JSON: %v", err)
+		}
+		if len(doc.Images) != 1 || doc.Images[0].FileName != "diagram.png" || doc.Images[0].Width != 200 {
+			t.Errorf("images: got %+v", doc.Images)
+		}
+		if len(doc.Annotations) != 2 || doc.Annotations[0].BBox != [4]int{10, 20, 40, 20} || doc.Annotations[0].Area != 800 ||
+			doc.Annotations[1].CategoryID != 2 || doc.Annotations[1].BBox != [4]int{0, 90, 15, 10} {
+			t.Errorf("annotations: got %+v", doc.Annotations)
+		}
+		if len(doc.Categories) != len(AnnotationCategories) || doc.Categories[0].ID != 1 || doc.Categories[0].Name != CategoryRectangle {
+			t.Errorf("categories: got %+v", doc.Categories)
+		}
+	})
+
+	t.Run("yolo", func(t *testing.T) {
+		export, err := ExportAnnotations(ExportYOLO, "diagram.png", 200, 100, anns)
+		if err != nil {
+			t.Fatalf("ExportAnnotations failed: %v", err)
+		}
+		want := "0 0.150000 0.300000 0.200000 0.200000\n1 0.037500 0.950000 0.075000 0.100000\n"
+		if export.Data != want || export.FileName != "diagram.txt" {
+			t.Errorf("got %s %q, want %q", export.FileName, export.Data, want)
+		}
+	})
+
+	t.Run("voc", func(t *testing.T) {
+		export, err := ExportAnnotations(ExportVOC, "shots/diagram.v2.png", 200, 100, anns)
+		if err != nil {
+			t.Fatalf("ExportAnnotations failed: %v", err)
+		}
+		if export.FileName != "shots/diagram.v2.xml" || !strings.HasPrefix(export.Data, "<?xml") {
+			t.Errorf("got %s:\n%s", export.FileName, export.Data)
+		}
+		var doc struct {
+			Filename string `xml:"filename"`
+			Width    int    `xml:"size>width"`
+			Objects  []struct {
+				Name string `xml:"name"`
+				XMin int    `xml:"bndbox>xmin"`
+				YMin int    `xml:"bndbox>ymin"`
+				XMax int    `xml:"bndbox>xmax"`
+				YMax int    `xml:"bndbox>ymax"`
+			} `xml:"object"`
+		}
+		if err := xml.Unmarshal([]byte(export.Data), &doc); err != nil {
+			t.Fatalf("VOC data is not XML: %v", err)
+		}
+		if doc.Filename != "shots/diagram.v2.png" || doc.Width != 200 || len(doc.Objects) != 2 {
+			t.Fatalf("document: got %+v", doc)
+		}
+		if o := doc.Objects[0]; o.Name != CategoryRectangle || o.XMin != 11 || o.YMin != 21 || o.XMax != 50 || o.YMax != 40 {
+			t.Errorf("object: got %+v, want 1-based inclusive 11,21-50,40", o)
+		}
+	})
+
+	if _, err := ExportAnnotations("csv", "a.png", 10, 10, nil); err == nil {
+		t.Error("expected error for unknown format")
+	}
+	if _, err := ExportAnnotations(ExportCOCO, "a.png", 10, 10, []Annotation{{Category: "blob"}}); err == nil {
+		t.Error("expected error for unknown category")
+	}
+	if _, err := ExportAnnotations(ExportYOLO, "a.png", 0, 10, nil); err == nil {
+		t.Error("expected error for empty image size")
+	}
+}
@@ -0,0 +1,670 @@
+package detection
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+)
+
+// Flowchart node shapes (see FlowchartNode).
+const (
+	FlowchartProcess  = "process"
+	FlowchartDecision = "decision"
+	FlowchartTerminal = "terminal"
+)
+
+// Flowchart node outlines (see FlowchartNode).
+const (
+	OutlineRectangle = "rectangle"
+	OutlineDiamond   = "diamond"
+	OutlineCircle    = "circle"
+	OutlineEllipse   = "ellipse"
+	OutlineRounded   = "rounded"
+)
+
+// Arrowheads of a FlowchartEdge.
+const (
+	ArrowEnd  = "end"
+	ArrowBoth = "both"
+	ArrowNone = "none"
+)
+
+// Thresholds of flowchart extraction.
+const (
+	// flowchartSolid is the smallest share of the span of its rows that
+	// a node's inside fills, so the ring between a double border is not a
+	// node.
+	flowchartSolid = 0.6
+
+	// flowchartShared is the largest share of a node's outline that may
+	// border the inside of another node, as the areas closed off between
+	// nodes by connectors do.
+	flowchartShared = 0.1
+
+	// flowchartSymmetry is the largest difference between the widths of a
+	// node's inside near its top and bottom, and between its heights near
+	// its left and right, as a share of the widest and tallest.
+	flowchartSymmetry = 0.15
+
+	// flowchartMaxBorder is the thickest node border, in pixels; longer
+	// runs of ink out of a node are connectors.
+	flowchartMaxBorder = 16
+
+	// flowchartMargin is how far, in pixels, beyond its border a node
+	// reaches, to take in arrowhead tips that stop short of it.
+	flowchartMargin = 2
+
+	// flowchartPathTolerance is how far, in pixels, a connector's pixels
+	// may stray from its simplified path; 2 stroke widths for wider
+	// strokes.
+	flowchartPathTolerance = 2
+)
+
+// FlowchartOptions configures ExtractFlowchart.
+type FlowchartOptions struct {
+	// Threshold is the gray level (1-255) below which a pixel is ink, or
+	// 0 to choose it automatically (Otsu), ink being the less common
+	// side.
+	Threshold int
+
+	// MinNodeArea is the smallest inside of a node, in pixels.
+	MinNodeArea int
+}
+
+// FlowchartNode is one shape of a flowchart.
+type FlowchartNode struct {
+	// ID is the node's number (1-based), in reading order: top to bottom,
+	// and left to right within a row.
+	ID int `json:"id"`
+
+	// Shape is the node's role: "process" for a rectangle, "decision" for
+	// a diamond, or "terminal" for a circle, ellipse, or rounded
+	// rectangle.
+	Shape string `json:"shape"`
+
+	// Outline is the node's drawn shape: "rectangle", "diamond",
+	// "circle", "ellipse", or "rounded".
+	Outline string `json:"outline"`
+
+	// Bounds is the node's box, border included, and Inner the box of its
+	// inside, where its label is.
+	Bounds Bounds `json:"bounds"`
+	Inner  Bounds `json:"inner"`
+}
+
+// FlowchartEdge is a connector between two nodes.
+type FlowchartEdge struct {
+	// From and To are the IDs of the nodes connected. For an undirected
+	// connector, From is the node first in reading order.
+	From int `json:"from"`
+	To   int `json:"to"`
+
+	// Arrow is where the connector has arrowheads: "end" at To, "both",
+	// or "none".
+	Arrow string `json:"arrow"`
+
+	// Path is the connector's course from From to To, simplified to its
+	// corners.
+	Path []Point `json:"path"`
+}
+
+// FlowchartResult is the graph of a flowchart: its nodes and the
+// connectors between them.
+type FlowchartResult struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// StrokeWidth is the median width of the nodes' borders, in pixels.
+	StrokeWidth float64 `json:"stroke_width"`
+
+	// Nodes are the flowchart's shapes, in reading order.
+	Nodes []FlowchartNode `json:"nodes"`
+
+	// Edges are the connectors, by From and then To.
+	Edges []FlowchartEdge `json:"edges"`
+}
+
+// flowchartHole is an area of background enclosed by ink: a candidate
+// node's inside.
+type flowchartHole struct {
+	label   int32
+	area    int
+	bounds  Bounds
+	rows    [][2]int // first and last x of each row, from bounds.Y1
+	cols    [][2]int // first and last y of each column, from bounds.X1
+	outline string
+	border  int
+}
+
+// flowchart holds the pixels of an image being read as a flowchart.
+type flowchart struct {
+	w, h   int
+	ink    []bool
+	labels []int32 // background component of each pixel; -1 for ink
+}
+
+// ExtractFlowchart finds the nodes of a flowchart and the connectors
+// between them, for converting a diagram to text.
+//
+// Returns an error if Threshold is not 0-255 or MinNodeArea is below 1.
+//
+// # Method
+//
+// A node's inside is background enclosed by ink, of MinNodeArea or more,
+// whose rows fill the box of its inside as a rectangle's, a diamond's, an
+// ellipse's, or a rounded rectangle's do: filling it fully, by half and
+// narrowing to points, by about π/4, or by more but with rounded ends,
+// alike at the top and bottom and at the left and right. Areas holding
+// others, such as frames around a diagram, are dropped, and then areas
+// that connectors close off between nodes, largest first, while more
+// than flowchartShared of their outline borders other nodes.
+//
+// Connectors are the connected ink outside the nodes, each node taken
+// with flowchartMargin pixels around its border, that touches two or more
+// nodes. A connector has an arrowhead where it touches a node if it is at
+// least 2.5 times, and 4 pixels, wider than the stroke within 4 stroke
+// widths of the node. A connector touching more than two nodes, as where
+// branches join, connects each node without an arrowhead to each node
+// with one, or the first node to the others if none or all have one.
+func ExtractFlowchart(img image.Image, opts FlowchartOptions) (*FlowchartResult, error) {
+	if opts.Threshold < 0 || opts.Threshold > 255 {
+		return nil, fmt.Errorf("threshold must be 0-255, got %d", opts.Threshold)
+	}
+	if opts.MinNodeArea < 1 {
+		return nil, fmt.Errorf("min node area must be at least 1 pixel, got %d", opts.MinNodeArea)
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	result := &FlowchartResult{Width: w, Height: h, Nodes: []FlowchartNode{}, Edges: []FlowchartEdge{}}
+	if w == 0 || h == 0 {
+		return result, nil
+	}
+	gray := make([]uint8, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			gray[y*w+x] = grayValue(img, b.Min.X+x, b.Min.Y+y)
+		}
+	}
+	var ink []bool
+	if opts.Threshold > 0 {
+		ink = make([]bool, len(gray))
+		for i, v := range gray {
+			ink[i] = int(v) < opts.Threshold
+		}
+	} else {
+		ink = textInk(gray)
+	}
+
+	fc := &flowchart{w: w, h: h, ink: ink}
+	holes := fc.nodes(fc.holes(opts.MinNodeArea))
+	sort.SliceStable(holes, func(i, j int) bool {
+		a, b := holes[i].bounds, holes[j].bounds
+		if overlap := min(a.Y2, b.Y2) - max(a.Y1, b.Y1); 2*overlap >= min(a.Y2-a.Y1, b.Y2-b.Y1) {
+			return a.X1 < b.X1
+		}
+		return a.Y1 < b.Y1
+	})
+	var borders []float64
+	for i, hole := range holes {
+		shape := FlowchartTerminal
+		switch hole.outline {
+		case OutlineRectangle:
+			shape = FlowchartProcess
+		case OutlineDiamond:
+			shape = FlowchartDecision
+		}
+		t := hole.border
+		result.Nodes = append(result.Nodes, FlowchartNode{
+			ID:      i + 1,
+			Shape:   shape,
+			Outline: hole.outline,
+			Bounds:  Bounds{X1: b.Min.X + hole.bounds.X1 - t, Y1: b.Min.Y + hole.bounds.Y1 - t, X2: b.Min.X + hole.bounds.X2 + t, Y2: b.Min.Y + hole.bounds.Y2 + t},
+			Inner:   Bounds{X1: b.Min.X + hole.bounds.X1, Y1: b.Min.Y + hole.bounds.Y1, X2: b.Min.X + hole.bounds.X2, Y2: b.Min.Y + hole.bounds.Y2},
+		})
+		borders = append(borders, float64(t))
+	}
+	if len(borders) == 0 {
+		return result, nil
+	}
+	result.StrokeWidth = medianWidth(borders)
+
+	result.Edges = fc.edges(holes, result.StrokeWidth)
+	for i := range result.Edges {
+		for j := range result.Edges[i].Path {
+			result.Edges[i].Path[j].X += b.Min.X
+			result.Edges[i].Path[j].Y += b.Min.Y
+		}
+	}
+	return result, nil
+}
+
+// holes labels the background's 4-connected components and returns those
+// not touching the image's edge with at least minArea pixels.
+func (fc *flowchart) holes(minArea int) []*flowchartHole {
+	w, h := fc.w, fc.h
+	fc.labels = make([]int32, w*h)
+	for i := range fc.labels {
+		fc.labels[i] = -1
+	}
+	var holes []*flowchartHole
+	var stack []int
+	next := int32(0)
+	for start := range fc.ink {
+		if fc.ink[start] || fc.labels[start] >= 0 {
+			continue
+		}
+		hole := &flowchartHole{label: next, bounds: Bounds{X1: w, Y1: h}}
+		edge := false
+		fc.labels[start] = next
+		stack = append(stack[:0], start)
+		for len(stack) > 0 {
+			p := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x, y := p%w, p/w
+			hole.area++
+			hole.bounds = Bounds{X1: min(hole.bounds.X1, x), Y1: min(hole.bounds.Y1, y), X2: max(hole.bounds.X2, x+1), Y2: max(hole.bounds.Y2, y+1)}
+			if x == 0 || y == 0 || x == w-1 || y == h-1 {
+				edge = true
+			}
+			for _, d := range [4][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+				nx, ny := x+d[0], y+d[1]
+				if nx < 0 || ny < 0 || nx >= w || ny >= h {
+					continue
+				}
+				if q := ny*w + nx; !fc.ink[q] && fc.labels[q] < 0 {
+					fc.labels[q] = next
+					stack = append(stack, q)
+				}
+			}
+		}
+		next++
+		if !edge && hole.area >= minArea {
+			holes = append(holes, hole)
+		}
+	}
+
+	// The first and last pixel of each row and column
+	byLabel := make(map[int32]*flowchartHole, len(holes))
+	for _, hole := range holes {
+		hole.rows = make([][2]int, hole.bounds.Y2-hole.bounds.Y1)
+		for i := range hole.rows {
+			hole.rows[i] = [2]int{w, -1}
+		}
+		hole.cols = make([][2]int, hole.bounds.X2-hole.bounds.X1)
+		for i := range hole.cols {
+			hole.cols[i] = [2]int{h, -1}
+		}
+		byLabel[hole.label] = hole
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			hole := byLabel[fc.labels[y*w+x]]
+			if hole == nil {
+				continue
+			}
+			r, c := &hole.rows[y-hole.bounds.Y1], &hole.cols[x-hole.bounds.X1]
+			r[0], r[1] = min(r[0], x), max(r[1], x)
+			c[0], c[1] = min(c[0], y), max(c[1], y)
+		}
+	}
+	return holes
+}
+
+// nodes returns the holes that are nodes' insides, with their outlines
+// and borders.
+func (fc *flowchart) nodes(holes []*flowchartHole) []*flowchartHole {
+	var nodes []*flowchartHole
+	for _, hole := range holes {
+		if hole.outline = classifyOutline(hole); hole.outline != "" {
+			nodes = append(nodes, hole)
+		}
+	}
+
+	// Frames
+	var kept []*flowchartHole
+	for _, n := range nodes {
+		frame := false
+		for _, o := range nodes {
+			if o != n && boundsContain(n.bounds, o.bounds) {
+				frame = true
+			}
+		}
+		if !frame {
+			kept = append(kept, n)
+		}
+	}
+
+	// Areas closed off between nodes, largest first
+	for {
+		isNode := make(map[int32]bool, len(kept))
+		for _, n := range kept {
+			isNode[n.label] = true
+		}
+		largest := -1
+		for i, n := range kept {
+			share, border := fc.exterior(n, isNode)
+			n.border = border
+			if share > flowchartShared && (largest < 0 || n.area > kept[largest].area) {
+				largest = i
+			}
+		}
+		if largest < 0 {
+			return kept
+		}
+		kept = append(kept[:largest], kept[largest+1:]...)
+	}
+}
+
+// classifyOutline returns the outline whose inside hole is, or "" if it
+// is none of them.
+//
+// Its rows' spans fill the box of the hole by a share (fill), and are
+// compared, a tenth of the height in from the top and bottom, with the
+// widest (ends): a rectangle fills its box and keeps its width; a diamond
+// fills half of it and narrows to points; an ellipse fills π/4 of it and
+// narrows by 0.6; a rounded rectangle narrows less. Each is as wide near
+// its top as near its bottom, and as tall near its left as near its
+// right.
+func classifyOutline(hole *flowchartHole) string {
+	w, h := hole.bounds.X2-hole.bounds.X1, hole.bounds.Y2-hole.bounds.Y1
+	spans, widest, tallest := 0, 0, 0
+	width := func(r [2]int) int { return r[1] - r[0] + 1 }
+	for _, r := range hole.rows {
+		spans += width(r)
+		widest = max(widest, width(r))
+	}
+	for _, c := range hole.cols {
+		tallest = max(tallest, width(c))
+	}
+	if float64(hole.area) < flowchartSolid*float64(spans) {
+		return ""
+	}
+	k, j := (h-1)/10, (w-1)/10
+	top, bottom := width(hole.rows[k]), width(hole.rows[h-1-k])
+	left, right := width(hole.cols[j]), width(hole.cols[w-1-j])
+	if float64(absDiff(top, bottom)) > flowchartSymmetry*float64(widest) || float64(absDiff(left, right)) > flowchartSymmetry*float64(tallest) {
+		return ""
+	}
+	fill := float64(spans) / float64(w*h)
+	ends := float64(top+bottom) / 2 / float64(widest)
+	switch {
+	case ends >= 0.92 && fill >= 0.9:
+		return OutlineRectangle
+	case ends <= 0.45 && fill >= 0.4 && fill <= 0.62:
+		return OutlineDiamond
+	case ends > 0.45 && ends < 0.75 && fill >= 0.7 && fill <= 0.86:
+		if 5*max(w, h) <= 6*min(w, h) {
+			return OutlineCircle
+		}
+		return OutlineEllipse
+	case ends >= 0.75 && fill >= 0.8:
+		return OutlineRounded
+	}
+	return ""
+}
+
+// exterior steps out of hole from the ends of each of its rows and
+// columns, through the ink of its border, and returns the share of steps
+// that come out in another of nodes (by label), and the border's width:
+// the lower quartile of the other steps' lengths, as steps across a
+// slanting border are longer. Steps through more than flowchartMaxBorder
+// pixels of ink, along connectors, are left out.
+func (fc *flowchart) exterior(hole *flowchartHole, nodes map[int32]bool) (float64, int) {
+	var runs []int
+	shared := 0
+	step := func(x, y, dx, dy int) {
+		n := 0
+		for x >= 0 && y >= 0 && x < fc.w && y < fc.h && fc.ink[y*fc.w+x] {
+			if n++; n > flowchartMaxBorder {
+				return
+			}
+			x, y = x+dx, y+dy
+		}
+		if x >= 0 && y >= 0 && x < fc.w && y < fc.h {
+			if l := fc.labels[y*fc.w+x]; l != hole.label && nodes[l] {
+				shared++
+				return
+			}
+		}
+		runs = append(runs, n)
+	}
+	for i, r := range hole.rows {
+		y := hole.bounds.Y1 + i
+		step(r[0]-1, y, -1, 0)
+		step(r[1]+1, y, 1, 0)
+	}
+	for i, c := range hole.cols {
+		x := hole.bounds.X1 + i
+		step(x, c[0]-1, 0, -1)
+		step(x, c[1]+1, 0, 1)
+	}
+	if len(runs) == 0 {
+		return 1, 1
+	}
+	sort.Ints(runs)
+	return float64(shared) / float64(shared+len(runs)), max(1, runs[len(runs)/4])
+}
+
+// edges finds the connectors between nodes, whose borders are stroke
+// pixels wide on average.
+func (fc *flowchart) edges(nodes []*flowchartHole, stroke float64) []FlowchartEdge {
+	w, h := fc.w, fc.h
+	// Each node's inside, grown through its border and the margin
+	owner := make([]int32, w*h)
+	for i := range owner {
+		owner[i] = -1
+	}
+	for i, n := range nodes {
+		m := n.border + flowchartMargin
+		for r, span := range n.rows {
+			y := n.bounds.Y1 + r
+			for yy := max(0, y-m); yy <= min(h-1, y+m); yy++ {
+				for x := max(0, span[0]-m); x <= min(w-1, span[1]+m); x++ {
+					if owner[yy*w+x] < 0 {
+						owner[yy*w+x] = int32(i)
+					}
+				}
+			}
+		}
+	}
+
+	var edges []FlowchartEdge
+	comp := make([]int32, w*h)
+	for i := range comp {
+		comp[i] = -1
+	}
+	prev := make([]int32, w*h)
+	var stack []int
+	next := int32(0)
+	for start := range fc.ink {
+		if !fc.ink[start] || owner[start] >= 0 || comp[start] >= 0 {
+			continue
+		}
+		// The connector and the pixels where it touches each node
+		var pixels []int
+		contacts := make(map[int][]int)
+		comp[start] = next
+		stack = append(stack[:0], start)
+		for len(stack) > 0 {
+			p := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			pixels = append(pixels, p)
+			x, y := p%w, p/w
+			touched := -1
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := x+dx, y+dy
+					if nx < 0 || ny < 0 || nx >= w || ny >= h {
+						continue
+					}
+					q := ny*w + nx
+					if o := owner[q]; o >= 0 && int(o) != touched {
+						touched = int(o)
+						contacts[touched] = append(contacts[touched], p)
+					} else if o < 0 && fc.ink[q] && comp[q] < 0 {
+						comp[q] = next
+						stack = append(stack, q)
+					}
+				}
+			}
+		}
+		next++
+		if len(contacts) < 2 {
+			continue
+		}
+
+		touching := make([]int, 0, len(contacts))
+		for n := range contacts {
+			touching = append(touching, n)
+		}
+		sort.Ints(touching)
+		var heads, tails []int
+		for _, n := range touching {
+			if fc.arrowhead(pixels, contacts[n], stroke) {
+				heads = append(heads, n)
+			} else {
+				tails = append(tails, n)
+			}
+		}
+		path := func(from, to int, arrow string) {
+			edges = append(edges, FlowchartEdge{
+				From:  from + 1,
+				To:    to + 1,
+				Arrow: arrow,
+				Path:  fc.path(comp, prev, contacts[from], contacts[to], math.Max(flowchartPathTolerance, 2*stroke)),
+			})
+		}
+		switch {
+		case len(heads) > 0 && len(tails) > 0:
+			for _, t := range tails {
+				for _, hd := range heads {
+					path(t, hd, ArrowEnd)
+				}
+			}
+		case len(heads) > 0:
+			for _, n := range touching[1:] {
+				path(touching[0], n, ArrowBoth)
+			}
+		default:
+			for _, n := range touching[1:] {
+				path(touching[0], n, ArrowNone)
+			}
+		}
+	}
+	sort.SliceStable(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+	return edges
+}
+
+// arrowhead reports whether the connector of pixels widens into an
+// arrowhead where it touches a node at contact: whether, within 4 stroke
+// widths (at least 8 pixels) of the contact, it is at least 2.5 times,
+// and 4 pixels, wider across its direction than the stroke.
+func (fc *flowchart) arrowhead(pixels, contact []int, stroke float64) bool {
+	var cx, cy float64
+	for _, p := range contact {
+		cx += float64(p % fc.w)
+		cy += float64(p / fc.w)
+	}
+	cx, cy = cx/float64(len(contact)), cy/float64(len(contact))
+	reach := math.Max(8, 4*stroke)
+	var near [][2]float64
+	var mx, my float64
+	for _, p := range pixels {
+		x, y := float64(p%fc.w), float64(p/fc.w)
+		if math.Hypot(x-cx, y-cy) <= reach {
+			near = append(near, [2]float64{x, y})
+			mx += x
+			my += y
+		}
+	}
+	dx, dy := mx/float64(len(near))-cx, my/float64(len(near))-cy
+	length := math.Hypot(dx, dy)
+	if length < 1e-9 {
+		return false
+	}
+	dx, dy = dx/length, dy/length
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, p := range near {
+		across := (p[0]-cx)*-dy + (p[1]-cy)*dx
+		lo, hi = math.Min(lo, across), math.Max(hi, across)
+	}
+	return hi-lo+1 >= math.Max(2.5*stroke, stroke+4)
+}
+
+// path returns the shortest course through a connector (by comp) from
+// the pixels of from to those of to, simplified to within tolerance
+// pixels, as shortest courses wander across wide strokes and arrowheads.
+// prev is scratch space the size of the image.
+func (fc *flowchart) path(comp, prev []int32, from, to []int, tolerance float64) []Point {
+	label := comp[from[0]]
+	target := make(map[int]bool, len(to))
+	for _, p := range to {
+		target[p] = true
+	}
+	visited := make(map[int]bool)
+	queue := make([]int, 0, len(from))
+	for _, p := range from {
+		if !visited[p] {
+			visited[p] = true
+			prev[p] = -1
+			queue = append(queue, p)
+		}
+	}
+	end := -1
+	for i := 0; i < len(queue) && end < 0; i++ {
+		p := queue[i]
+		if target[p] {
+			end = p
+			break
+		}
+		x, y := p%fc.w, p/fc.w
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				nx, ny := x+dx, y+dy
+				if nx < 0 || ny < 0 || nx >= fc.w || ny >= fc.h {
+					continue
+				}
+				if q := ny*fc.w + nx; comp[q] == label && !visited[q] {
+					visited[q] = true
+					prev[q] = int32(p)
+					queue = append(queue, q)
+				}
+			}
+		}
+	}
+	var course []Point
+	for p := end; p >= 0; p = int(prev[p]) {
+		course = append(course, Point{X: p % fc.w, Y: p / fc.w})
+	}
+	for i, j := 0, len(course)-1; i < j; i, j = i+1, j-1 {
+		course[i], course[j] = course[j], course[i]
+	}
+	return simplifyCourse(course, tolerance)
+}
+
+// simplifyCourse drops the points of course within tolerance of the line
+// through the points kept around them (Douglas-Peucker).
+func simplifyCourse(course []Point, tolerance float64) []Point {
+	if len(course) <= 2 {
+		return append([]Point(nil), course...)
+	}
+	a := [2]float64{float64(course[0].X), float64(course[0].Y)}
+	b := [2]float64{float64(course[len(course)-1].X), float64(course[len(course)-1].Y)}
+	far, farthest := 0.0, 0
+	for i := 1; i < len(course)-1; i++ {
+		if d := segmentDistance([2]float64{float64(course[i].X), float64(course[i].Y)}, a, b); d > far {
+			far, farthest = d, i
+		}
+	}
+	if far <= tolerance {
+		return []Point{course[0], course[len(course)-1]}
+	}
+	left := simplifyCourse(course[:farthest+1], tolerance)
+	return append(left[:len(left)-1], simplifyCourse(course[farthest:], tolerance)...)
+}
@@ -0,0 +1,210 @@
+package detection
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// chartCanvas draws flowchart strokes in black on white.
+type chartCanvas struct {
+	*image.Gray
+}
+
+func newChartCanvas(w, h int) chartCanvas {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	return chartCanvas{img}
+}
+
+// paint blackens the pixels whose centers in is true for.
+func (c chartCanvas) paint(in func(x, y float64) bool) {
+	b := c.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if in(float64(x)+0.5, float64(y)+0.5) {
+				c.SetGray(x, y, color.Gray{})
+			}
+		}
+	}
+}
+
+// box outlines x1,y1-x2,y2 with a border 2 pixels wide.
+func (c chartCanvas) box(x1, y1, x2, y2 float64) {
+	c.paint(func(x, y float64) bool {
+		in := x >= x1 && x < x2 && y >= y1 && y < y2
+		inner := x >= x1+2 && x < x2-2 && y >= y1+2 && y < y2-2
+		return in && !inner
+	})
+}
+
+// ellipse outlines the ellipse at cx,cy with radii a and b.
+func (c chartCanvas) ellipse(cx, cy, a, b float64) {
+	c.paint(func(x, y float64) bool {
+		outer := math.Pow((x-cx)/a, 2)+math.Pow((y-cy)/b, 2) <= 1
+		inner := math.Pow((x-cx)/(a-2), 2)+math.Pow((y-cy)/(b-2), 2) <= 1
+		return outer && !inner
+	})
+}
+
+// diamond outlines the diamond at cx,cy reaching a across and b down.
+func (c chartCanvas) diamond(cx, cy, a, b float64) {
+	t := 2 * math.Hypot(a, b) / math.Max(a, b)
+	c.paint(func(x, y float64) bool {
+		d := math.Abs(x-cx)/a + math.Abs(y-cy)/b
+		return d <= 1 && d > 1-t/math.Min(a, b)
+	})
+}
+
+// line draws a stroke 2 pixels wide from x1,y1 to x2,y2.
+func (c chartCanvas) line(x1, y1, x2, y2 float64) {
+	c.paint(func(x, y float64) bool {
+		return segmentDistance([2]float64{x, y}, [2]float64{x1, y1}, [2]float64{x2, y2}) <= 1
+	})
+}
+
+// arrow draws a line ending in a filled arrowhead 10 pixels long and wide
+// at x2,y2.
+func (c chartCanvas) arrow(x1, y1, x2, y2 float64) {
+	l := math.Hypot(x2-x1, y2-y1)
+	dx, dy := (x2-x1)/l, (y2-y1)/l
+	c.line(x1, y1, x2-8*dx, y2-8*dy)
+	c.paint(func(x, y float64) bool {
+		along := (x2-x)*dx + (y2-y)*dy
+		across := math.Abs((x-x2)*-dy + (y-y2)*dx)
+		return along >= 0 && along <= 10 && across <= along/2
+	})
+}
+
+// chartPage draws a loop: Start (ellipse) → Process (rectangle) →
+// Decision (diamond) → End (circle), the decision's other exit looping
+// back to the process around the right.
+func chartPage() chartCanvas {
+	c := newChartCanvas(400, 460)
+	c.ellipse(200, 40, 60, 22)
+	c.box(140, 100, 260, 150)
+	c.diamond(200, 250, 70, 50)
+	c.ellipse(200, 400, 30, 30)
+	c.arrow(200, 62, 200, 100)
+	c.arrow(200, 150, 200, 200)
+	c.arrow(200, 300, 200, 370)
+	c.line(270, 250, 330, 250)
+	c.line(330, 250, 330, 125)
+	c.arrow(330, 125, 260, 125)
+	// Text in the nodes, and an edge label
+	for _, r := range [][4]float64{{180, 34, 220, 46}, {170, 118, 230, 132}, {185, 244, 215, 256}, {190, 394, 210, 406}, {290, 232, 310, 242}} {
+		c.paint(func(x, y float64) bool { return x >= r[0] && x < r[2] && y >= r[1] && y < r[3] })
+	}
+	return c
+}
+
+func TestExtractFlowchart(t *testing.T) {
+	result, err := ExtractFlowchart(chartPage(), FlowchartOptions{MinNodeArea: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		shape, outline string
+		inner          Bounds
+	}{
+		{FlowchartTerminal, OutlineEllipse, Bounds{X1: 142, Y1: 20, X2: 258, Y2: 60}},
+		{FlowchartProcess, OutlineRectangle, Bounds{X1: 142, Y1: 102, X2: 258, Y2: 148}},
+		{FlowchartDecision, OutlineDiamond, Bounds{X1: 133, Y1: 203, X2: 267, Y2: 297}},
+		{FlowchartTerminal, OutlineCircle, Bounds{X1: 172, Y1: 372, X2: 228, Y2: 428}},
+	}
+	if len(result.Nodes) != len(want) {
+		t.Fatalf("got %d nodes, want %d: %+v", len(result.Nodes), len(want), result.Nodes)
+	}
+	for i, w := range want {
+		n := result.Nodes[i]
+		if n.ID != i+1 || n.Shape != w.shape || n.Outline != w.outline || !nearBounds(n.Inner, w.inner, 3) {
+			t.Errorf("node %d: got %+v, want %s %s inside %+v", i+1, n, w.shape, w.outline, w.inner)
+		}
+	}
+	if result.StrokeWidth != 2 {
+		t.Errorf("stroke width: got %g, want 2", result.StrokeWidth)
+	}
+
+	wantEdges := [][2]int{{1, 2}, {2, 3}, {3, 2}, {3, 4}}
+	if len(result.Edges) != len(wantEdges) {
+		t.Fatalf("got %d edges, want %d: %+v", len(result.Edges), len(wantEdges), result.Edges)
+	}
+	for i, w := range wantEdges {
+		e := result.Edges[i]
+		if e.From != w[0] || e.To != w[1] || e.Arrow != ArrowEnd {
+			t.Errorf("edge %d: got %d→%d (%s), want %d→%d", i, e.From, e.To, e.Arrow, w[0], w[1])
+		}
+	}
+	// The loop back turns twice
+	if loop := result.Edges[2].Path; len(loop) != 4 {
+		t.Errorf("loop path: got %v, want 4 points", loop)
+	}
+}
+
+func TestExtractFlowchart_Undirected(t *testing.T) {
+	// Two boxes joined by a plain line, and a third joined to both by
+	// one branching line without arrowheads
+	c := newChartCanvas(300, 200)
+	c.box(20, 20, 100, 60)
+	c.box(200, 20, 280, 60)
+	c.box(110, 130, 190, 170)
+	c.line(100, 40, 200, 40)
+	c.line(150, 130, 150, 100)
+	c.line(60, 100, 240, 100)
+	c.line(60, 100, 60, 60)
+	c.line(240, 100, 240, 60)
+
+	result, err := ExtractFlowchart(c, FlowchartOptions{MinNodeArea: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Nodes) != 3 {
+		t.Fatalf("got %d nodes, want 3: %+v", len(result.Nodes), result.Nodes)
+	}
+	want := [][2]int{{1, 2}, {1, 2}, {1, 3}}
+	if len(result.Edges) != len(want) {
+		t.Fatalf("got %d edges, want %d: %+v", len(result.Edges), len(want), result.Edges)
+	}
+	for i, w := range want {
+		if e := result.Edges[i]; e.From != w[0] || e.To != w[1] || e.Arrow != ArrowNone {
+			t.Errorf("edge %d: got %d→%d (%s), want %d—%d", i, e.From, e.To, e.Arrow, w[0], w[1])
+		}
+	}
+}
+
+func TestExtractFlowchart_Frame(t *testing.T) {
+	// A frame around two boxes is not a node
+	c := newChartCanvas(300, 140)
+	c.box(5, 5, 295, 135)
+	c.box(30, 40, 110, 100)
+	c.box(190, 40, 270, 100)
+	c.arrow(110, 70, 190, 70)
+
+	result, err := ExtractFlowchart(c, FlowchartOptions{MinNodeArea: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Nodes) != 2 || len(result.Edges) != 1 || result.Edges[0].From != 1 || result.Edges[0].To != 2 {
+		t.Errorf("got nodes %+v, edges %+v", result.Nodes, result.Edges)
+	}
+}
+
+func TestExtractFlowchart_Invalid(t *testing.T) {
+	img := newChartCanvas(10, 10)
+	for _, opts := range []FlowchartOptions{{MinNodeArea: 0}, {Threshold: 256, MinNodeArea: 10}} {
+		if _, err := ExtractFlowchart(img, opts); err == nil {
+			t.Errorf("%+v: expected an error", opts)
+		}
+	}
+	result, err := ExtractFlowchart(img, FlowchartOptions{MinNodeArea: 10})
+	if err != nil || len(result.Nodes) != 0 || len(result.Edges) != 0 {
+		t.Errorf("blank image: got %+v, %v", result, err)
+	}
+}
+
+func nearBounds(a, b Bounds, tol int) bool {
+	return absDiff(a.X1, b.X1) <= tol && absDiff(a.Y1, b.Y1) <= tol && absDiff(a.X2, b.X2) <= tol && absDiff(a.Y2, b.Y2) <= tol
+}
@@ -0,0 +1,371 @@
+package detection
+
+import (
+	"image"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// formulaOverlayColor is the overlay color of formula regions.
+const formulaOverlayColor = "#FF8000"
+
+// formulaMinSymbols is the fewest ink shapes a group needs to be scored.
+const formulaMinSymbols = 3
+
+// FormulaOptions configures DetectFormulaRegions.
+type FormulaOptions struct {
+	// MinConfidence (0.0 to 1.0) is the confidence a region needs to be
+	// reported.
+	MinConfidence float64
+
+	// Words, if set, returns the words read in a region by OCR, for the
+	// dictionary word feature. It is called only for regions the other
+	// features already score at MinConfidence / 2 or more. Nil leaves the
+	// feature out.
+	Words func(b Bounds) []string
+}
+
+// FormulaRegion is a region that probably holds a mathematical formula.
+type FormulaRegion struct {
+	// Bounds is the bounding box of the formula's ink.
+	Bounds Bounds `json:"bounds"`
+
+	// Confidence (0.0 to 1.0) is the mean of the feature scores, scaled
+	// down by the fraction of common words if words were checked.
+	Confidence float64 `json:"confidence"`
+
+	// Symbols is the number of ink shapes in the region.
+	Symbols int `json:"symbols"`
+
+	// VerticalSpread is the region's height in typical symbol heights.
+	// A line of text spans about 1.5 (ascenders and descenders); stacked
+	// fractions, limits, and large operators spread further.
+	VerticalSpread float64 `json:"vertical_spread"`
+
+	// OffBaseline is the fraction (0.0 to 1.0) of shapes whose bottom is
+	// not on the region's most common baseline, as with superscripts and
+	// subscripts.
+	OffBaseline float64 `json:"off_baseline"`
+
+	// OperatorFraction is the fraction (0.0 to 1.0) of shapes that are
+	// thin horizontal bars: the strokes of =, −, and fraction bars.
+	OperatorFraction float64 `json:"operator_fraction"`
+
+	// WordFraction is the fraction (0.0 to 1.0) of the words read by OCR
+	// that are common English words. Omitted if words were not checked.
+	WordFraction *float64 `json:"word_fraction,omitempty"`
+}
+
+// FormulaRegionsResult contains the probable formula regions in an image.
+type FormulaRegionsResult struct {
+	// Regions is the list of formula regions, sorted by confidence
+	// (highest first).
+	Regions []FormulaRegion `json:"regions"`
+
+	// Count is the number of formula regions.
+	Count int `json:"count"`
+
+	// WordsChecked reports whether the dictionary word feature was used.
+	WordsChecked bool `json:"words_checked"`
+}
+
+// DetectFormulaRegions finds regions that probably hold mathematical
+// formulas, so they can be sent to a math recognizer instead of regular
+// OCR, which garbles them.
+//
+// Parameters:
+//   - img: Source image, typically a page or a screenshot of a document.
+//   - opts: Confidence threshold and an optional OCR word source.
+//
+// Returns:
+//   - *FormulaRegionsResult: The regions, sorted by confidence.
+//   - error: Currently always nil.
+//
+// # Algorithm
+//
+//  1. Ink: pixels on the less common side of the Otsu threshold, grouped
+//     into 8-connected shapes. The median shape height is the typical
+//     symbol height H. Shapes taller than a quarter of the image or
+//     wider than half of it (frames, rules, figures) are left out.
+//  2. Grouping: shapes overlapping vertically by at least 30% of the
+//     shorter one, with at most 1.5 H between them, join a group, which
+//     also takes in superscripts and subscripts. Groups just above and
+//     below a thin bar, and centered over it, join the bar's group, so a
+//     fraction is one region.
+//  3. Features, each scored from 0 (text) to 1 (formula), for groups of
+//     at least 3 shapes:
+//     - Vertical spread: 0 at 2 H or less, 1 at 3 H or more.
+//     - Off baseline: 0 at 15% of the shapes or less, 1 at 40% or more.
+//     A shape is off the baseline if its bottom is more than H/4 from
+//     the most common bottom.
+//     - Operators: 0 at 3% of the shapes or less, 1 at 15% or more. A
+//     thin bar is at least twice as wide as high and at most 0.35 H
+//     high.
+//  4. Confidence is the mean of the scores. With opts.Words, it is then
+//     multiplied by the dictionary word score: 1 at 10% common English
+//     words or less (or no words read), 0 at 50% or more, as running
+//     text is about half common words. Regions below opts.MinConfidence
+//     are dropped.
+//
+// # Limitations
+//
+// Formulas set inline in a line of text are found only if they make the
+// whole line look like one. Handwriting and scanned pages skewed by more
+// than a few degrees break the baseline features.
+func DetectFormulaRegions(img image.Image, opts FormulaOptions) (*FormulaRegionsResult, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	result := &FormulaRegionsResult{Regions: []FormulaRegion{}, WordsChecked: opts.Words != nil}
+	if w < 3 || h < 3 {
+		return result, nil
+	}
+	gray := make([]uint8, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			gray[y*w+x] = grayValue(img, b.Min.X+x, b.Min.Y+y)
+		}
+	}
+	var comps []Bounds
+	for _, c := range inkComponents(textInk(gray), w, h) {
+		if c.Y2-c.Y1 <= h/4 && c.X2-c.X1 <= w/2 {
+			comps = append(comps, c)
+		}
+	}
+	if len(comps) < formulaMinSymbols {
+		return result, nil
+	}
+	heights := make([]int, len(comps))
+	for i, c := range comps {
+		heights[i] = c.Y2 - c.Y1
+	}
+	sort.Ints(heights)
+	symbol := float64(heights[len(heights)/2])
+
+	for _, group := range groupFormulaSymbols(comps, symbol) {
+		if len(group) < formulaMinSymbols {
+			continue
+		}
+		region := scoreFormula(group, symbol)
+		if opts.Words != nil && region.Confidence >= opts.MinConfidence/2 {
+			fraction := commonWordFraction(opts.Words(region.Bounds))
+			region.WordFraction = &fraction
+			region.Confidence = math.Round(region.Confidence*formulaRamp(fraction, 0.5, 0.1)*1000) / 1000
+		}
+		if region.Confidence >= opts.MinConfidence {
+			region.Bounds = Bounds{X1: region.Bounds.X1 + b.Min.X, Y1: region.Bounds.Y1 + b.Min.Y, X2: region.Bounds.X2 + b.Min.X, Y2: region.Bounds.Y2 + b.Min.Y}
+			result.Regions = append(result.Regions, region)
+		}
+	}
+	sort.Slice(result.Regions, func(i, j int) bool {
+		return result.Regions[i].Confidence > result.Regions[j].Confidence
+	})
+	result.Count = len(result.Regions)
+	return result, nil
+}
+
+// isFormulaBar reports whether a shape is a thin horizontal bar, for
+// typical symbol height symbol.
+func isFormulaBar(c Bounds, symbol float64) bool {
+	bw, bh := c.X2-c.X1, c.Y2-c.Y1
+	return bw >= 2*bh && float64(bh) <= 0.35*symbol
+}
+
+// groupFormulaSymbols groups shapes into lines and joins the parts of
+// fractions through their bars.
+func groupFormulaSymbols(comps []Bounds, symbol float64) [][]Bounds {
+	parent := make([]int, len(comps))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(i, j int) { parent[find(i)] = find(j) }
+
+	sameLine := func(a, b Bounds) bool {
+		overlap := min(a.Y2, b.Y2) - max(a.Y1, b.Y1)
+		gap := max(a.X1, b.X1) - min(a.X2, b.X2)
+		return 10*overlap >= 3*min(a.Y2-a.Y1, b.Y2-b.Y1) && float64(gap) <= 1.5*symbol
+	}
+
+	// Shapes on the same line, sorted by left edge so the scan can stop
+	// once shapes are too far right
+	order := make([]int, len(comps))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return comps[order[a]].X1 < comps[order[b]].X1 })
+	for a := range order {
+		for c := a + 1; c < len(order) && float64(comps[order[c]].X1-comps[order[a]].X2) <= 1.5*symbol; c++ {
+			if sameLine(comps[order[a]], comps[order[c]]) {
+				union(order[a], order[c])
+			}
+		}
+	}
+
+	// Numerators and denominators join their fraction bar, and the
+	// fraction then joins the shapes on its line, until nothing changes
+	near := int(math.Ceil(0.6 * symbol))
+	for changed := true; changed; {
+		changed = false
+		gb := map[int]Bounds{}
+		for i, c := range comps {
+			r := find(i)
+			if g, ok := gb[r]; ok {
+				gb[r] = mergeBounds(g, c)
+			} else {
+				gb[r] = c
+			}
+		}
+		for i, bar := range comps {
+			if !isFormulaBar(bar, symbol) {
+				continue
+			}
+			for r, g := range gb {
+				if find(r) == find(i) {
+					continue
+				}
+				above := bar.Y1-g.Y2 >= 0 && bar.Y1-g.Y2 <= near
+				below := g.Y1-bar.Y2 >= 0 && g.Y1-bar.Y2 <= near
+				center := (g.X1 + g.X2) / 2
+				if (above || below) && center >= bar.X1 && center < bar.X2 && g.X2-g.X1 <= bar.X2-bar.X1+int(symbol) {
+					union(r, i)
+					changed = true
+				}
+			}
+		}
+		for r, g := range gb {
+			for s, h := range gb {
+				if find(r) != find(s) && sameLine(g, h) {
+					union(r, s)
+					changed = true
+				}
+			}
+		}
+	}
+
+	members := map[int][]Bounds{}
+	var roots []int
+	for i, c := range comps {
+		r := find(i)
+		if _, ok := members[r]; !ok {
+			roots = append(roots, r)
+		}
+		members[r] = append(members[r], c)
+	}
+	groups := make([][]Bounds, len(roots))
+	for k, r := range roots {
+		groups[k] = members[r]
+	}
+	return groups
+}
+
+// scoreFormula returns the formula region of a group of shapes, scored on
+// its shapes alone.
+func scoreFormula(group []Bounds, symbol float64) FormulaRegion {
+	bounds := group[0]
+	bottoms := map[int]int{}
+	bars := 0
+	for _, c := range group {
+		bounds = mergeBounds(bounds, c)
+		bottoms[c.Y2]++
+		if isFormulaBar(c, symbol) {
+			bars++
+		}
+	}
+
+	// Most common bottom, counting bottoms within H/4 as the same
+	tol := symbol / 4
+	baseline, best := 0, -1
+	for y := range bottoms {
+		n := 0
+		for other, count := range bottoms {
+			if math.Abs(float64(y-other)) <= tol {
+				n += count
+			}
+		}
+		if n > best || (n == best && y > baseline) {
+			baseline, best = y, n
+		}
+	}
+	off := 0
+	for _, c := range group {
+		if math.Abs(float64(c.Y2-baseline)) > tol {
+			off++
+		}
+	}
+
+	n := float64(len(group))
+	region := FormulaRegion{
+		Bounds:           bounds,
+		Symbols:          len(group),
+		VerticalSpread:   math.Round(float64(bounds.Y2-bounds.Y1)/symbol*100) / 100,
+		OffBaseline:      math.Round(float64(off)/n*1000) / 1000,
+		OperatorFraction: math.Round(float64(bars)/n*1000) / 1000,
+	}
+	score := (formulaRamp(region.VerticalSpread, 2, 3) +
+		formulaRamp(region.OffBaseline, 0.15, 0.4) +
+		formulaRamp(region.OperatorFraction, 0.03, 0.15)) / 3
+	region.Confidence = math.Round(score*1000) / 1000
+	return region
+}
+
+// formulaRamp scores v from 0 at text to 1 at formula, linearly between.
+func formulaRamp(v, text, formula float64) float64 {
+	return math.Min(math.Max((v-text)/(formula-text), 0), 1)
+}
+
+// commonWords are the most frequent English words, which make up about
+// half of running text but rarely appear in formulas.
+var commonWords = func() map[string]bool {
+	m := map[string]bool{}
+	for _, w := range strings.Fields(`the of and to in is that for it as was with be by on not he
+		this are or his from at which but have an they you were her she there been one all we
+		their has would when if so no will can more about out up into them some could other
+		than then its only any these two may first new also after our what who how where most
+		over such those each because between both under through while should very many same
+		used use given thus hence therefore since however`) {
+		m[w] = true
+	}
+	return m
+}()
+
+// commonWordFraction returns the fraction of the words that are common
+// English words, or 0 if there are none. Words are compared in lower case
+// without surrounding punctuation; tokens without letters, such as
+// numbers and operators, count as words that are not common.
+func commonWordFraction(words []string) float64 {
+	if len(words) == 0 {
+		return 0
+	}
+	common := 0
+	for _, w := range words {
+		if commonWords[strings.ToLower(strings.TrimFunc(w, func(r rune) bool { return !unicode.IsLetter(r) }))] {
+			common++
+		}
+	}
+	return math.Round(float64(common)/float64(len(words))*1000) / 1000
+}
+
+// Primitives converts the formula regions to "rect" primitives, in result
+// order.
+func (r *FormulaRegionsResult) Primitives() []Primitive {
+	prims := make([]Primitive, len(r.Regions))
+	for i, region := range r.Regions {
+		b := region.Bounds
+		prims[i] = Primitive{
+			Type:   PrimitiveRect,
+			Bounds: &b,
+			Color:  formulaOverlayColor,
+			Label:  "formula " + strconv.Itoa(i+1),
+		}
+	}
+	return prims
+}
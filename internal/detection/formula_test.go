@@ -0,0 +1,138 @@
+package detection
+
+import (
+	"image"
+	"image/draw"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// formulaPage renders a paragraph, the equation
+//
+//	       a + b     2
+//	f(x) = ----- + x  - 1
+//	       c - d
+//
+// with a fraction bar and a superscript, and another paragraph, in the
+// 7x13 bitmap font enlarged twice. It returns the equation's bounds.
+func formulaPage() (*image.RGBA, Bounds) {
+	small := image.NewRGBA(image.Rect(0, 0, 330, 150))
+	draw.Draw(small, small.Bounds(), image.White, image.Point{}, draw.Src)
+	d := &font.Drawer{Dst: small, Src: image.Black, Face: basicfont.Face7x13}
+	text := func(x, y int, s string) int {
+		d.Dot = fixed.P(x, y)
+		d.DrawString(s)
+		return d.Dot.X.Round()
+	}
+	text(4, 16, "The quick brown fox jumps over the lazy")
+	text(4, 32, "dog, and then it runs back to the barn.")
+
+	base := 78
+	x := text(40, base, "f(x) = ")
+	num := text(x, base-7, "a + b")
+	text(x, base+9, "c - d")
+	draw.Draw(small, image.Rect(x, base-4, num, base-3), image.Black, image.Point{}, draw.Src)
+	x = text(num, base, " + x")
+	x = text(x, base-6, "2")
+	text(x, base, " - 1")
+
+	text(4, 124, "Here the reader sees what it is and how it")
+	text(4, 140, "works, so that we can use it in the next one.")
+
+	img := image.NewRGBA(image.Rect(0, 0, 660, 300))
+	for y := 0; y < img.Rect.Dy(); y++ {
+		for x := 0; x < img.Rect.Dx(); x++ {
+			img.Set(x, y, small.At(x/2, y/2))
+		}
+	}
+	return img, Bounds{X1: 80, Y1: 2 * (base - 20), X2: 2 * (x + 28), Y2: 2 * (base + 14)}
+}
+
+func TestDetectFormulaRegions(t *testing.T) {
+	img, want := formulaPage()
+	result, err := DetectFormulaRegions(img, FormulaOptions{MinConfidence: 0.5})
+	if err != nil {
+		t.Fatalf("DetectFormulaRegions() error = %v", err)
+	}
+	if result.Count != 1 || len(result.Regions) != 1 {
+		t.Fatalf("Count = %d, regions = %+v, want 1 formula", result.Count, result.Regions)
+	}
+	r := result.Regions[0]
+	t.Logf("formula: %+v", r)
+	if r.Bounds.X1 < want.X1 || r.Bounds.Y1 < want.Y1 || r.Bounds.X2 > want.X2 || r.Bounds.Y2 > want.Y2 {
+		t.Errorf("Bounds = %+v, want within %+v", r.Bounds, want)
+	}
+	if r.VerticalSpread < 2.5 || r.OperatorFraction == 0 {
+		t.Errorf("features = %+v, want a wide vertical spread and operators", r)
+	}
+	if result.WordsChecked || r.WordFraction != nil {
+		t.Errorf("words checked without a word source: %+v", result)
+	}
+
+	// Text alone has no formulas
+	text := printedText(2, "The quick brown fox jumps over the lazy", "dog, and then it runs back to the barn.", "Invoice #4021 (due 2024-03-15): $1,250.")
+	result, err = DetectFormulaRegions(text, FormulaOptions{MinConfidence: 0.5})
+	if err != nil {
+		t.Fatalf("DetectFormulaRegions() error = %v", err)
+	}
+	if result.Count != 0 {
+		t.Errorf("text: Count = %d (%+v), want 0", result.Count, result.Regions)
+	}
+
+	if got := len(result.Primitives()); got != result.Count {
+		t.Errorf("Primitives() = %d, want %d", got, result.Count)
+	}
+}
+
+func TestDetectFormulaRegions_Words(t *testing.T) {
+	img, _ := formulaPage()
+	tests := []struct {
+		name  string
+		words string
+		want  int
+	}{
+		{"symbols", "f(x) = a+b c-d + x2 - 1", 1},
+		{"no words", "", 1},
+		{"prose", "this is the one that we can use and the other", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			words := func(Bounds) []string {
+				calls++
+				return strings.Fields(tt.words)
+			}
+			result, err := DetectFormulaRegions(img, FormulaOptions{MinConfidence: 0.5, Words: words})
+			if err != nil {
+				t.Fatalf("DetectFormulaRegions() error = %v", err)
+			}
+			if !result.WordsChecked || calls == 0 {
+				t.Errorf("WordsChecked = %v after %d calls, want checked", result.WordsChecked, calls)
+			}
+			if result.Count != tt.want {
+				t.Errorf("Count = %d (%+v), want %d", result.Count, result.Regions, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommonWordFraction(t *testing.T) {
+	tests := []struct {
+		words string
+		want  float64
+	}{
+		{"", 0},
+		{"The cat is on the mat.", 0.667},
+		{"x = (a + b) / 2", 0},
+		{"Hence, THEREFORE", 1},
+	}
+	for _, tt := range tests {
+		if got := commonWordFraction(strings.Fields(tt.words)); got != tt.want {
+			t.Errorf("commonWordFraction(%q) = %v, want %v", tt.words, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,519 @@
+package detection
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Golden tests run every detector on images whose content is known and
+// score the results by recall and precision, so an algorithm change can be
+// judged by numbers rather than by eye. Cases come from two places:
+//
+//   - goldenScenes renders synthetic diagrams and records what it drew.
+//   - testdata/golden/*.json describe real images with hand-checked
+//     expectations (see testdata/golden/README.md).
+//
+// Each case fails when a score drops below its minimum. Run with -v to see
+// all scores; raise a minimum when an improvement makes it stick.
+
+// goldenDir holds the fixture corpus, relative to this package.
+var goldenDir = filepath.Join("..", "..", "testdata", "golden")
+
+// goldenScore is the minimum recall and precision a detector must reach,
+// and the most detections it may report that match nothing. MaxExtra
+// catches new false positives where precision is already near zero; nil
+// allows any number.
+type goldenScore struct {
+	MinRecall    float64 `json:"min_recall"`
+	MinPrecision float64 `json:"min_precision"`
+	MaxExtra     *int    `json:"max_extra,omitempty"`
+}
+
+// maxExtra returns a MaxExtra of n.
+func maxExtra(n int) *int {
+	return &n
+}
+
+// goldenRectangles is the DetectRectangles part of a case.
+type goldenRectangles struct {
+	MinArea   int      `json:"min_area"`
+	Tolerance float64  `json:"tolerance"`
+	Expected  []Bounds `json:"expected"`
+	goldenScore
+}
+
+// goldenCircle is an expected circle.
+type goldenCircle struct {
+	Center Point `json:"center"`
+	Radius int   `json:"radius"`
+}
+
+// goldenCircles is the DetectCircles part of a case.
+type goldenCircles struct {
+	MinRadius int            `json:"min_radius"`
+	MaxRadius int            `json:"max_radius"`
+	Expected  []goldenCircle `json:"expected"`
+	goldenScore
+}
+
+// goldenLine is an expected line segment; its direction does not matter.
+type goldenLine struct {
+	Start Point `json:"start"`
+	End   Point `json:"end"`
+}
+
+// goldenLines is the DetectLines part of a case.
+type goldenLines struct {
+	MinLength int          `json:"min_length"`
+	Expected  []goldenLine `json:"expected"`
+	goldenScore
+}
+
+// goldenTextRegions is the DetectTextRegions part of a case.
+type goldenTextRegions struct {
+	MinConfidence float64  `json:"min_confidence"`
+	Expected      []Bounds `json:"expected"`
+	goldenScore
+}
+
+// goldenCase is one image with expectations for any of the detectors.
+// Detectors without a section are not run.
+type goldenCase struct {
+	Description string             `json:"description"`
+	Image       string             `json:"image"`
+	Rectangles  *goldenRectangles  `json:"rectangles,omitempty"`
+	Circles     *goldenCircles     `json:"circles,omitempty"`
+	Lines       *goldenLines       `json:"lines,omitempty"`
+	TextRegions *goldenTextRegions `json:"text_regions,omitempty"`
+}
+
+// goldenRectIoU is the overlap a detected rectangle needs with an
+// expected one to match.
+const goldenRectIoU = 0.8
+
+// textRegionMatches reports whether a detected text region covers at least
+// 80% of the expected ink box without being more than four times its
+// size. Text regions are padded to the detector's windows, so overlap
+// alone would be too strict.
+func textRegionMatches(expected, found Bounds) bool {
+	inter := Bounds{
+		X1: maxInt(expected.X1, found.X1), Y1: maxInt(expected.Y1, found.Y1),
+		X2: minInt(expected.X2, found.X2), Y2: minInt(expected.Y2, found.Y2),
+	}
+	if inter.X2 <= inter.X1 || inter.Y2 <= inter.Y1 {
+		return false
+	}
+	area := boundsArea(expected)
+	return float64(boundsArea(inter)) >= 0.8*float64(area) && boundsArea(found) <= 4*area
+}
+
+// matches reports whether a detected circle is close enough to an
+// expected one: center and radius within 20% of the radius, or 3 pixels.
+func (e goldenCircle) matches(c Circle) bool {
+	tol := math.Max(3, float64(e.Radius)/5)
+	return pointDistance(e.Center, c.Center) <= tol && math.Abs(float64(e.Radius-c.Radius)) <= tol
+}
+
+// matches reports whether a detected line has both endpoints, in either
+// order, within 5% of the expected length, or 5 pixels.
+func (e goldenLine) matches(l Line) bool {
+	tol := math.Max(5, pointDistance(e.Start, e.End)/20)
+	near := func(a, b Point) bool { return pointDistance(a, b) <= tol }
+	return (near(e.Start, l.Start) && near(e.End, l.End)) ||
+		(near(e.Start, l.End) && near(e.End, l.Start))
+}
+
+// goldenResult is the outcome of one detector on one case.
+type goldenResult struct {
+	recall, precision float64
+	missed            []int // indexes of unmatched expected shapes
+	extra             []int // indexes of unmatched detections
+}
+
+// scoreGolden matches expected shapes to detections one to one, taking
+// for each expected shape the first free detection that matches.
+func scoreGolden(expected, found int, match func(e, f int) bool) goldenResult {
+	used := make([]bool, found)
+	r := goldenResult{recall: 1, precision: 1}
+	hits := 0
+	for e := 0; e < expected; e++ {
+		hit := false
+		for f := 0; f < found && !hit; f++ {
+			if !used[f] && match(e, f) {
+				used[f], hit = true, true
+			}
+		}
+		if hit {
+			hits++
+		} else {
+			r.missed = append(r.missed, e)
+		}
+	}
+	for f, u := range used {
+		if !u {
+			r.extra = append(r.extra, f)
+		}
+	}
+	if expected > 0 {
+		r.recall = float64(hits) / float64(expected)
+	}
+	if found > 0 {
+		r.precision = float64(hits) / float64(found)
+	}
+	return r
+}
+
+// check logs a detector's scores and fails the test if either is below
+// the minimum, listing what was missed and what was extra.
+func (r goldenResult) check(t *testing.T, detector string, min goldenScore, describe func(missed bool, i int) string) {
+	t.Helper()
+	t.Logf("%s: recall %.3f precision %.3f extra %d", detector, r.recall, r.precision, len(r.extra))
+	if r.recall >= min.MinRecall && r.precision >= min.MinPrecision && (min.MaxExtra == nil || len(r.extra) <= *min.MaxExtra) {
+		return
+	}
+	var b strings.Builder
+	for _, i := range r.missed {
+		fmt.Fprintf(&b, "\n  missed %s", describe(true, i))
+	}
+	for _, i := range r.extra {
+		fmt.Fprintf(&b, "\n  extra  %s", describe(false, i))
+	}
+	limit := ""
+	if min.MaxExtra != nil {
+		limit = fmt.Sprintf(" with at most %d extra", *min.MaxExtra)
+	}
+	t.Errorf("%s: recall %.3f precision %.3f extra %d, want at least %.3f and %.3f%s%s",
+		detector, r.recall, r.precision, len(r.extra), min.MinRecall, min.MinPrecision, limit, b.String())
+}
+
+// runGolden runs the detectors a case asks for and checks their scores.
+func runGolden(t *testing.T, img image.Image, c *goldenCase) {
+	t.Helper()
+	if g := c.Rectangles; g != nil {
+		res, err := DetectRectangles(img, g.MinArea, g.Tolerance)
+		if err != nil {
+			t.Fatalf("DetectRectangles: %v", err)
+		}
+		scoreGolden(len(g.Expected), res.Count, func(e, f int) bool {
+			return boundsIoU(g.Expected[e], res.Rectangles[f].Bounds) >= goldenRectIoU
+		}).check(t, "rectangles", g.goldenScore, func(missed bool, i int) string {
+			if missed {
+				return fmt.Sprintf("%+v", g.Expected[i])
+			}
+			return fmt.Sprintf("%+v", res.Rectangles[i].Bounds)
+		})
+	}
+	if g := c.Circles; g != nil {
+		res, err := DetectCircles(img, g.MinRadius, g.MaxRadius)
+		if err != nil {
+			t.Fatalf("DetectCircles: %v", err)
+		}
+		scoreGolden(len(g.Expected), res.Count, func(e, f int) bool {
+			return g.Expected[e].matches(res.Circles[f])
+		}).check(t, "circles", g.goldenScore, func(missed bool, i int) string {
+			if missed {
+				return fmt.Sprintf("%+v", g.Expected[i])
+			}
+			return fmt.Sprintf("center %+v radius %d", res.Circles[i].Center, res.Circles[i].Radius)
+		})
+	}
+	if g := c.Lines; g != nil {
+		res, err := DetectLines(img, g.MinLength, false)
+		if err != nil {
+			t.Fatalf("DetectLines: %v", err)
+		}
+		scoreGolden(len(g.Expected), res.Count, func(e, f int) bool {
+			return g.Expected[e].matches(res.Lines[f])
+		}).check(t, "lines", g.goldenScore, func(missed bool, i int) string {
+			if missed {
+				return fmt.Sprintf("%+v", g.Expected[i])
+			}
+			return fmt.Sprintf("%+v - %+v", res.Lines[i].Start, res.Lines[i].End)
+		})
+	}
+	if g := c.TextRegions; g != nil {
+		res, err := DetectTextRegions(img, g.MinConfidence)
+		if err != nil {
+			t.Fatalf("DetectTextRegions: %v", err)
+		}
+		scoreGolden(len(g.Expected), len(res.Regions), func(e, f int) bool {
+			return textRegionMatches(g.Expected[e], res.Regions[f].Bounds)
+		}).check(t, "text regions", g.goldenScore, func(missed bool, i int) string {
+			if missed {
+				return fmt.Sprintf("%+v", g.Expected[i])
+			}
+			return fmt.Sprintf("%+v", res.Regions[i].Bounds)
+		})
+	}
+}
+
+// goldenCanvas renders a synthetic scene and records its ground truth.
+// Shapes are recorded in the form the detectors report them: rectangle
+// bounds span the outermost pixels inclusive, and text is one region per
+// block of lines.
+type goldenCanvas struct {
+	img  *image.RGBA
+	want goldenCase
+}
+
+func newGoldenCanvas(width, height int) *goldenCanvas {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	return &goldenCanvas{img: img}
+}
+
+// box fills r and records it as a rectangle.
+func (g *goldenCanvas) box(r image.Rectangle, c color.Color) {
+	draw.Draw(g.img, r, image.NewUniform(c), image.Point{}, draw.Src)
+	g.rectangle(r)
+}
+
+// frame draws the outline of r, thickness pixels wide, and records it as a
+// rectangle.
+func (g *goldenCanvas) frame(r image.Rectangle, thickness int, c color.Color) {
+	u := image.NewUniform(c)
+	for _, side := range []image.Rectangle{
+		{r.Min, image.Pt(r.Max.X, r.Min.Y+thickness)},
+		{image.Pt(r.Min.X, r.Max.Y-thickness), r.Max},
+		{r.Min, image.Pt(r.Min.X+thickness, r.Max.Y)},
+		{image.Pt(r.Max.X-thickness, r.Min.Y), r.Max},
+	} {
+		draw.Draw(g.img, side, u, image.Point{}, draw.Src)
+	}
+	g.rectangle(r)
+}
+
+func (g *goldenCanvas) rectangle(r image.Rectangle) {
+	if g.want.Rectangles == nil {
+		g.want.Rectangles = &goldenRectangles{}
+	}
+	g.want.Rectangles.Expected = append(g.want.Rectangles.Expected,
+		Bounds{X1: r.Min.X, Y1: r.Min.Y, X2: r.Max.X - 1, Y2: r.Max.Y - 1})
+}
+
+// disk fills a circle and records it.
+func (g *goldenCanvas) disk(center image.Point, radius int, c color.Color) {
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius; dx <= radius; dx++ {
+			if dx*dx+dy*dy <= radius*radius {
+				g.img.Set(center.X+dx, center.Y+dy, c)
+			}
+		}
+	}
+	if g.want.Circles == nil {
+		g.want.Circles = &goldenCircles{}
+	}
+	g.want.Circles.Expected = append(g.want.Circles.Expected,
+		goldenCircle{Center: Point{X: center.X, Y: center.Y}, Radius: radius})
+}
+
+// line draws a segment thickness pixels wide and records it.
+func (g *goldenCanvas) line(from, to image.Point, thickness int, c color.Color) {
+	dx, dy := float64(to.X-from.X), float64(to.Y-from.Y)
+	steps := int(math.Max(math.Abs(dx), math.Abs(dy)))
+	for i := 0; i <= steps; i++ {
+		t := float64(i) / float64(maxInt(steps, 1))
+		x := int(math.Round(float64(from.X) + t*dx))
+		y := int(math.Round(float64(from.Y) + t*dy))
+		draw.Draw(g.img, image.Rect(x, y, x+thickness, y+thickness), image.NewUniform(c), image.Point{}, draw.Src)
+	}
+	if g.want.Lines == nil {
+		g.want.Lines = &goldenLines{}
+	}
+	g.want.Lines.Expected = append(g.want.Lines.Expected,
+		goldenLine{Start: Point{X: from.X, Y: from.Y}, End: Point{X: to.X, Y: to.Y}})
+}
+
+// text draws lines of text with their tops at top, 16 pixels apart, and
+// records the block's ink bounds as one text region.
+func (g *goldenCanvas) text(left, top int, c color.Color, lines ...string) {
+	face := basicfont.Face7x13
+	d := &font.Drawer{Dst: g.img, Src: image.NewUniform(c), Face: face}
+	ink := image.Rectangle{}
+	for i, s := range lines {
+		d.Dot = fixed.P(left, top+i*16+face.Ascent)
+		b, _ := d.BoundString(s)
+		d.DrawString(s)
+		ink = ink.Union(image.Rect(b.Min.X.Floor(), b.Min.Y.Floor(), b.Max.X.Ceil(), b.Max.Y.Ceil()))
+	}
+	if g.want.TextRegions == nil {
+		g.want.TextRegions = &goldenTextRegions{}
+	}
+	g.want.TextRegions.Expected = append(g.want.TextRegions.Expected,
+		Bounds{X1: ink.Min.X, Y1: ink.Min.Y, X2: ink.Max.X - 1, Y2: ink.Max.Y - 1})
+}
+
+// goldenScene is a synthetic case. render draws the scene; tune sets
+// detector parameters and minimum scores on the recorded ground truth.
+//
+// Minimums are the scores the detectors are meant to reach on the scene;
+// the comments explain any allowance short of a perfect score.
+type goldenScene struct {
+	name   string
+	render func(g *goldenCanvas)
+	tune   func(c *goldenCase)
+}
+
+var (
+	goldenBlue  = color.RGBA{40, 90, 200, 255}
+	goldenGreen = color.RGBA{40, 160, 80, 255}
+	goldenInk   = color.RGBA{30, 30, 30, 255}
+)
+
+var goldenScenes = []goldenScene{
+	{
+		name: "boxes",
+		render: func(g *goldenCanvas) {
+			g.box(image.Rect(20, 20, 140, 90), goldenBlue)
+			g.box(image.Rect(180, 20, 230, 120), goldenGreen)
+			g.box(image.Rect(270, 150, 380, 260), color.Black)
+			g.frame(image.Rect(30, 140, 200, 250), 2, goldenInk)
+			g.frame(image.Rect(260, 20, 380, 110), 1, goldenBlue)
+		},
+		tune: func(c *goldenCase) {
+			// The 2px frame's inner edge is a nested rectangle of its own
+			c.Rectangles.MinArea, c.Rectangles.Tolerance = 100, 0.9
+			c.Rectangles.goldenScore = goldenScore{MinRecall: 1, MinPrecision: 0.83, MaxExtra: maxExtra(1)}
+		},
+	},
+	{
+		name: "circles",
+		render: func(g *goldenCanvas) {
+			g.disk(image.Pt(60, 60), 15, goldenBlue)
+			g.disk(image.Pt(180, 70), 25, goldenGreen)
+			g.disk(image.Pt(300, 80), 30, color.Black)
+			g.disk(image.Pt(120, 180), 20, goldenInk)
+		},
+		tune: func(c *goldenCase) {
+			c.Circles.MinRadius, c.Circles.MaxRadius = 10, 35
+			c.Circles.goldenScore = goldenScore{MinRecall: 1, MinPrecision: 1, MaxExtra: maxExtra(0)}
+		},
+	},
+	{
+		name: "lines",
+		render: func(g *goldenCanvas) {
+			g.line(image.Pt(20, 30), image.Pt(360, 30), 3, goldenInk)
+			g.line(image.Pt(40, 70), image.Pt(40, 260), 2, goldenInk)
+			g.line(image.Pt(100, 80), image.Pt(340, 250), 1, goldenBlue)
+		},
+		tune: func(c *goldenCase) {
+			c.Lines.MinLength = 40
+			c.Lines.goldenScore = goldenScore{MinRecall: 1, MinPrecision: 1, MaxExtra: maxExtra(0)}
+		},
+	},
+	{
+		name: "text",
+		render: func(g *goldenCanvas) {
+			g.text(20, 20, goldenInk, "The quick brown fox jumps", "over the lazy dog.")
+			g.text(20, 120, goldenInk, "Settings  Profile  Logout")
+			g.text(220, 200, goldenBlue, "Label 42")
+		},
+		tune: func(c *goldenCase) {
+			// The short label's best window scores just under 0.3
+			c.TextRegions.MinConfidence = 0.3
+			c.TextRegions.goldenScore = goldenScore{MinRecall: 0.66, MinPrecision: 1, MaxExtra: maxExtra(0)}
+		},
+	},
+	{
+		name: "diagram",
+		render: func(g *goldenCanvas) {
+			g.frame(image.Rect(20, 40, 150, 110), 2, goldenInk)
+			g.frame(image.Rect(250, 40, 380, 110), 2, goldenInk)
+			g.box(image.Rect(140, 190, 260, 250), goldenGreen)
+			g.line(image.Pt(150, 75), image.Pt(249, 75), 2, goldenInk)
+			g.disk(image.Pt(60, 210), 20, goldenBlue)
+			g.text(40, 65, goldenInk, "Client")
+			g.text(270, 65, goldenInk, "Server")
+		},
+		tune: func(c *goldenCase) {
+			// The sides of the three boxes are lines too, so only the
+			// connector is expected and the 12 sides are allowed as extra
+			c.Rectangles.MinArea, c.Rectangles.Tolerance = 100, 0.9
+			c.Rectangles.goldenScore = goldenScore{MinRecall: 1, MinPrecision: 1, MaxExtra: maxExtra(0)}
+			c.Circles.MinRadius, c.Circles.MaxRadius = 10, 30
+			c.Circles.goldenScore = goldenScore{MinRecall: 1, MinPrecision: 1, MaxExtra: maxExtra(0)}
+			c.Lines.MinLength = 60
+			c.Lines.goldenScore = goldenScore{MinRecall: 1, MaxExtra: maxExtra(12)}
+			c.TextRegions.MinConfidence = 0.3
+			c.TextRegions.goldenScore = goldenScore{MinRecall: 1, MinPrecision: 1, MaxExtra: maxExtra(0)}
+		},
+	},
+}
+
+func TestGolden_Synthetic(t *testing.T) {
+	for _, s := range goldenScenes {
+		t.Run(s.name, func(t *testing.T) {
+			g := newGoldenCanvas(400, 280)
+			s.render(g)
+			s.tune(&g.want)
+			runGolden(t, g.img, &g.want)
+		})
+	}
+}
+
+func TestGolden_Fixtures(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join(goldenDir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Skip("no fixtures in " + goldenDir)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var c goldenCase
+			if err := json.Unmarshal(data, &c); err != nil {
+				t.Fatalf("invalid fixture: %v", err)
+			}
+			f, err := os.Open(filepath.Join(goldenDir, c.Image))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			img, _, err := image.Decode(f)
+			if err != nil {
+				t.Fatalf("decoding %s: %v", c.Image, err)
+			}
+			runGolden(t, img, &c)
+		})
+	}
+}
+
+func TestScoreGolden(t *testing.T) {
+	// Three expected, three found; expected 0 and 2 both match found 1,
+	// which can only be used once
+	pairs := map[[2]int]bool{{0, 1}: true, {1, 2}: true, {2, 1}: true}
+	r := scoreGolden(3, 3, func(e, f int) bool { return pairs[[2]int{e, f}] })
+	if math.Abs(r.recall-2.0/3) > 1e-9 || math.Abs(r.precision-2.0/3) > 1e-9 {
+		t.Errorf("got recall %v precision %v, want 2/3 each", r.recall, r.precision)
+	}
+	if len(r.missed) != 1 || r.missed[0] != 2 || len(r.extra) != 1 || r.extra[0] != 0 {
+		t.Errorf("got missed %v extra %v, want [2] and [0]", r.missed, r.extra)
+	}
+
+	// Nothing expected and nothing found is a perfect score
+	if r := scoreGolden(0, 0, nil); r.recall != 1 || r.precision != 1 {
+		t.Errorf("empty: got recall %v precision %v", r.recall, r.precision)
+	}
+}
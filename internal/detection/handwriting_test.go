@@ -0,0 +1,132 @@
+package detection
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// printedText renders lines of the 7x13 bitmap font, enlarged scale
+// times, black on white.
+func printedText(scale int, lines ...string) *image.RGBA {
+	small := image.NewRGBA(image.Rect(0, 0, 330, 16*len(lines)+8))
+	draw.Draw(small, small.Bounds(), image.White, image.Point{}, draw.Src)
+	d := &font.Drawer{Dst: small, Src: image.Black, Face: basicfont.Face7x13}
+	for i, line := range lines {
+		d.Dot = fixed.P(4, 16*(i+1))
+		d.DrawString(line)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, small.Rect.Dx()*scale, small.Rect.Dy()*scale))
+	for y := 0; y < img.Rect.Dy(); y++ {
+		for x := 0; x < img.Rect.Dx(); x++ {
+			img.Set(x, y, small.At(x/scale, y/scale))
+		}
+	}
+	return img
+}
+
+// cursiveText draws two lines of five slanted, joined-up words of loops,
+// like handwritten "eeee", with a pen of the given radius. Loop heights
+// vary, and each word drifts off the line.
+func cursiveText(seed int64, pen float64) *image.RGBA {
+	rng := rand.New(rand.NewSource(seed))
+	img := image.NewRGBA(image.Rect(0, 0, 600, 160))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	for line := 0; line < 2; line++ {
+		x0, base := 20.0, 55.0+70*float64(line)
+		for word := 0; word < 5; word++ {
+			loops := 3 + rng.Intn(4)
+			drift := rng.Float64()*8 - 4
+			heights := make([]float64, loops)
+			for i := range heights {
+				heights[i] = 10 + rng.Float64()*14
+			}
+			for t := 0.0; t < 2*math.Pi*float64(loops); t += 0.02 {
+				x := x0 + 4*t - 7*math.Sin(t)
+				y := base + drift - heights[int(t/(2*math.Pi))]/2*(1-math.Cos(t)) + 3*math.Sin(x/40)
+				x += 0.35 * (base - y)
+				for dy := -pen; dy <= pen; dy++ {
+					for dx := -pen; dx <= pen; dx++ {
+						if dx*dx+dy*dy <= pen*pen+0.5 {
+							img.Set(int(x+dx), int(y+dy), color.Black)
+						}
+					}
+				}
+			}
+			x0 += 8*math.Pi*float64(loops) + 22
+		}
+	}
+	return img
+}
+
+func imageBounds(img image.Image) Bounds {
+	b := img.Bounds()
+	return Bounds{X1: b.Min.X, Y1: b.Min.Y, X2: b.Max.X, Y2: b.Max.Y}
+}
+
+func TestClassifyTextStyle(t *testing.T) {
+	tests := []struct {
+		name string
+		img  image.Image
+		want string
+	}{
+		{"small print", printedText(1, "The quick brown fox jumps over", "the lazy dog. Invoice #4021"), TextPrinted},
+		{"large print", printedText(3, "The quick brown fox", "jumps over the lazy"), TextPrinted},
+		{"cursive", cursiveText(1, 1.5), TextHandwritten},
+		{"fine cursive", cursiveText(2, 1), TextHandwritten},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ClassifyTextStyle(tt.img, imageBounds(tt.img))
+			if got.Style != tt.want {
+				t.Errorf("got %+v, want %s", got, tt.want)
+			}
+		})
+	}
+
+	// The features behind the call
+	printed := ClassifyTextStyle(printedText(3, "The quick brown fox"), imageBounds(printedText(3, "The quick brown fox")))
+	cursive := ClassifyTextStyle(cursiveText(1, 1.5), imageBounds(cursiveText(1, 1.5)))
+	if printed.AxisAlignment <= cursive.AxisAlignment || printed.ComponentAspect >= cursive.ComponentAspect ||
+		printed.BaselineAgreement <= cursive.BaselineAgreement || cursive.HandwritingScore < 0.6 || printed.HandwritingScore > 0.2 {
+		t.Errorf("printed %+v, cursive %+v", printed, cursive)
+	}
+
+	// White ink on black reads the same
+	inverted := printedText(3, "The quick brown fox")
+	for i := 0; i < len(inverted.Pix); i += 4 {
+		inverted.Pix[i], inverted.Pix[i+1], inverted.Pix[i+2] = 255-inverted.Pix[i], 255-inverted.Pix[i+1], 255-inverted.Pix[i+2]
+	}
+	if got := ClassifyTextStyle(inverted, imageBounds(inverted)); got.Style != TextPrinted {
+		t.Errorf("inverted: got %+v", got)
+	}
+
+	// Blank and tiny regions cannot be told
+	blank := image.NewRGBA(image.Rect(0, 0, 50, 20))
+	if got := ClassifyTextStyle(blank, imageBounds(blank)); got.Style != TextUnknown {
+		t.Errorf("blank: got %+v", got)
+	}
+	if got := ClassifyTextStyle(blank, Bounds{X1: 40, Y1: 10, X2: 80, Y2: 12}); got.Style != TextUnknown {
+		t.Errorf("tiny: got %+v", got)
+	}
+}
+
+func TestDetectTextRegions_Style(t *testing.T) {
+	img := printedText(2, "The quick brown fox jumps over", "the lazy dog. Invoice #4021")
+	result, err := DetectTextRegions(img, 0.3)
+	if err != nil || result.Count == 0 {
+		t.Fatalf("got %+v, %v", result, err)
+	}
+	for _, r := range result.Regions {
+		if r.Style == "" {
+			t.Errorf("region %+v has no style", r)
+		}
+	}
+}
@@ -0,0 +1,473 @@
+package detection
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+)
+
+// changeTileSize is the side length, in pixels, of the tiles ChangedRegions
+// groups differing pixels by.
+const changeTileSize = 16
+
+// changeThreshold is the mean per-channel difference (0-255) above which
+// ChangedRegions treats a pixel as changed, high enough to ignore
+// compression noise.
+const changeThreshold = 10
+
+// IncrementalOptions are the detection parameters a Snapshot is built with.
+type IncrementalOptions struct {
+	// MinArea is the minimum rectangle area in square pixels (see
+	// DetectRectangles).
+	MinArea int
+
+	// Tolerance is the minimum rectangularity, 0.0 to 1.0 (see
+	// DetectRectangles).
+	Tolerance float64
+
+	// MinConfidence is the minimum text region confidence, 0.0 to 1.0 (see
+	// DetectTextRegions).
+	MinConfidence float64
+}
+
+// Snapshot is the cached detection state of one image: its edge map plus
+// the per-contour rectangles and per-window text scores behind
+// DetectRectangles and DetectTextRegions. Update derives the snapshot of a
+// slightly changed version of the image by redoing only the work that the
+// changed regions affect, which makes repeated analysis of a live UI cheap.
+//
+// A Snapshot is never modified after creation and is safe for concurrent
+// use.
+type Snapshot struct {
+	bounds image.Rectangle
+	opts   IncrementalOptions
+	edges  *bitGrid
+
+	// img is the image analyzed, for classifying the style of the text
+	// regions.
+	img image.Image
+
+	// rects holds the rectangle found for each contour, keyed by the
+	// contour's first pixel in scan order (y × width + x).
+	rects map[int]Rectangle
+
+	// text holds the window scores for each text window size.
+	text []textWindowScores
+}
+
+// UpdateStats reports how much of a snapshot Update was able to reuse.
+type UpdateStats struct {
+	// DirtyRegions are the changed regions, clipped to the image.
+	DirtyRegions []Bounds `json:"dirty_regions"`
+
+	// DirtyFraction is the fraction of the image (0.0-1.0) covered by the
+	// dirty regions.
+	DirtyFraction float64 `json:"dirty_fraction"`
+
+	// EdgePixelsRecomputed is the number of edge map pixels re-evaluated.
+	EdgePixelsRecomputed int `json:"edge_pixels_recomputed"`
+
+	// ContoursRecomputed is the number of contours traced again.
+	ContoursRecomputed int `json:"contours_recomputed"`
+
+	// RectanglesReused is the number of rectangles carried over unchanged.
+	RectanglesReused int `json:"rectangles_reused"`
+
+	// WindowsRescored and WindowsReused count the text detection windows
+	// scored again and carried over.
+	WindowsRescored int `json:"windows_rescored"`
+	WindowsReused   int `json:"windows_reused"`
+}
+
+// IncrementalResult contains the detections of a snapshot and, if it was
+// derived from a previous snapshot, the statistics of that update.
+type IncrementalResult struct {
+	// Rectangles are the detected rectangles, as from DetectRectangles.
+	Rectangles *RectanglesResult `json:"rectangles"`
+
+	// TextRegions are the detected text regions, as from DetectTextRegions.
+	TextRegions *TextRegionsResult `json:"text_regions"`
+
+	// Incremental is true if only the changed regions were re-analyzed,
+	// false if the whole image was.
+	Incremental bool `json:"incremental"`
+
+	// Stats describes the update; nil for a full analysis.
+	Stats *UpdateStats `json:"stats,omitempty"`
+}
+
+// NewSnapshot analyzes the whole image and caches the intermediate state
+// needed for later incremental updates.
+//
+// Parameters:
+//   - img: Source image to analyze.
+//   - opts: Detection parameters, kept for all snapshots derived from this
+//     one.
+//
+// Returns:
+//   - *Snapshot: The cached state. Rectangles and TextRegions return the
+//     same results as DetectRectangles and DetectTextRegions.
+func NewSnapshot(img image.Image, opts IncrementalOptions) *Snapshot {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	// The edge map stays with the snapshot, so it is not released
+	edges := detectEdges(img, width, height)
+
+	rects := make(map[int]Rectangle)
+	for _, contour := range findContours(edges, width, height) {
+		if rect, ok := contourRectangle(img, contour, opts.MinArea, opts.Tolerance, nil); ok {
+			rects[contourKey(contour, width)] = rect
+		}
+	}
+
+	return &Snapshot{
+		bounds: bounds,
+		opts:   opts,
+		edges:  edges,
+		img:    img,
+		rects:  rects,
+		text:   scoreTextWindows(edges),
+	}
+}
+
+// Options returns the detection parameters the snapshot was built with.
+func (s *Snapshot) Options() IncrementalOptions {
+	return s.opts
+}
+
+// Update returns the snapshot of img, a changed version of the snapshot's
+// image, re-running detection only where the dirty regions require it.
+// The receiver is not modified.
+//
+// Parameters:
+//   - img: The new image. It must have the same size as the snapshot's.
+//   - dirty: Regions (X2 and Y2 exclusive) outside of which img is
+//     identical to the previous image, e.g. from ChangedRegions. Regions
+//     that are too small make the results stale; regions that are too large
+//     only cost time.
+//
+// Returns:
+//   - *Snapshot: The new snapshot. Its results equal those of NewSnapshot
+//     on img.
+//   - *UpdateStats: How much work was reused.
+//   - error: Non-nil if the image size changed.
+//
+// # Method
+//
+// An edge pixel depends on the pixel itself and its right and lower
+// neighbors, so edges are recomputed in each dirty region grown by one
+// pixel up and to the left. Contours that touch a changed edge, or whose
+// rectangle overlaps a dirty region (whose colors may have changed), are
+// traced and analyzed again; all other rectangles are reused. Text windows
+// that overlap a changed edge are rescored by scanning them; the others
+// keep their scores. Merging and sorting then run as in a full detection.
+func (s *Snapshot) Update(img image.Image, dirty []Bounds) (*Snapshot, *UpdateStats, error) {
+	bounds := img.Bounds()
+	if bounds.Size() != s.bounds.Size() {
+		return nil, nil, fmt.Errorf("image size changed from %dx%d to %dx%d; analyze it afresh",
+			s.bounds.Dx(), s.bounds.Dy(), bounds.Dx(), bounds.Dy())
+	}
+	width, height := bounds.Dx(), bounds.Dy()
+	stats := &UpdateStats{DirtyRegions: make([]Bounds, 0)}
+
+	// Clip the dirty regions to the image, in image-relative coordinates,
+	// and derive the regions whose edges may have changed
+	frame := image.Rect(0, 0, width, height)
+	interior := image.Rectangle{} // where detectEdges can mark edges
+	if width >= 3 && height >= 3 {
+		interior = image.Rect(1, 1, width-1, height-1)
+	}
+	var pixelRegions, edgeRegions []image.Rectangle
+	covered := newBitGrid(width, height)
+	defer covered.release()
+	dirtyPixels := 0
+	for _, d := range dirty {
+		r := image.Rect(d.X1, d.Y1, d.X2, d.Y2).Sub(bounds.Min).Intersect(frame)
+		if r.Empty() {
+			continue
+		}
+		pixelRegions = append(pixelRegions, r)
+		stats.DirtyRegions = append(stats.DirtyRegions, Bounds{
+			X1: r.Min.X + bounds.Min.X,
+			Y1: r.Min.Y + bounds.Min.Y,
+			X2: r.Max.X + bounds.Min.X,
+			Y2: r.Max.Y + bounds.Min.Y,
+		})
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				if !covered.Get(x, y) {
+					covered.Set(x, y)
+					dirtyPixels++
+				}
+			}
+		}
+		if e := image.Rect(r.Min.X-1, r.Min.Y-1, r.Max.X, r.Max.Y).Intersect(interior); !e.Empty() {
+			edgeRegions = append(edgeRegions, e)
+		}
+	}
+	if width*height > 0 {
+		stats.DirtyFraction = math.Round(float64(dirtyPixels)/float64(width*height)*10000) / 10000
+	}
+
+	// Recompute the edge map in the affected regions
+	edges := newBitGrid(width, height)
+	copy(edges.words, s.edges.words)
+	for _, e := range edgeRegions {
+		for y := e.Min.Y; y < e.Max.Y; y++ {
+			for x := e.Min.X; x < e.Max.X; x++ {
+				c := float64(grayValue(img, x+bounds.Min.X, y+bounds.Min.Y))
+				cx := float64(grayValue(img, x+1+bounds.Min.X, y+bounds.Min.Y))
+				cy := float64(grayValue(img, x+bounds.Min.X, y+1+bounds.Min.Y))
+				if math.Abs(c-cx) > edgeThreshold || math.Abs(c-cy) > edgeThreshold {
+					edges.Set(x, y)
+				} else {
+					edges.Clear(x, y)
+				}
+				stats.EdgePixelsRecomputed++
+			}
+		}
+	}
+
+	next := &Snapshot{
+		bounds: bounds,
+		opts:   s.opts,
+		edges:  edges,
+		img:    img,
+		rects:  s.updateRectangles(img, edges, pixelRegions, edgeRegions, stats),
+		text:   s.updateTextWindows(edges, edgeRegions, stats),
+	}
+	return next, stats, nil
+}
+
+// updateRectangles carries over the rectangles that no change can reach
+// and re-analyzes the contours that one might.
+func (s *Snapshot) updateRectangles(img image.Image, edges *bitGrid, pixelRegions, edgeRegions []image.Rectangle, stats *UpdateStats) map[int]Rectangle {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	frame := image.Rect(0, 0, width, height)
+
+	// A contour can only change if it touches (8-connected) a changed
+	// edge pixel; its colors only if its rectangle overlaps a dirty region
+	affected := append([]image.Rectangle(nil), pixelRegions...)
+	for _, e := range edgeRegions {
+		affected = append(affected, e.Inset(-1).Intersect(frame))
+	}
+	overlapsAffected := func(r image.Rectangle) bool {
+		for _, a := range affected {
+			if r.Overlaps(a) {
+				return true
+			}
+		}
+		return false
+	}
+
+	rects := make(map[int]Rectangle, len(s.rects))
+	seeds := affected
+	for key, rect := range s.rects {
+		area := image.Rect(rect.Bounds.X1, rect.Bounds.Y1, rect.Bounds.X2+1, rect.Bounds.Y2+1).Sub(bounds.Min)
+		if overlapsAffected(area) {
+			seeds = append(seeds, area)
+			continue
+		}
+		rects[key] = rect
+	}
+	kept := len(rects)
+
+	// Trace every contour with a pixel in a seed area. Contours are found
+	// once each, whichever seed reaches them first
+	visited := newBitGrid(width, height)
+	defer visited.release()
+	for _, area := range seeds {
+		for y := area.Min.Y; y < area.Max.Y; y++ {
+			for x := area.Min.X; x < area.Max.X; x++ {
+				if !edges.Get(x, y) || visited.Get(x, y) {
+					continue
+				}
+				contour := make([]Point, 0)
+				floodFill(edges, visited, x, y, width, height, &contour)
+				stats.ContoursRecomputed++
+
+				key := contourKey(contour, width)
+				if _, ok := rects[key]; ok {
+					kept--
+					delete(rects, key)
+				}
+				if len(contour) < 10 { // Minimum contour size, as in findContours
+					continue
+				}
+				if rect, ok := contourRectangle(img, contour, s.opts.MinArea, s.opts.Tolerance, nil); ok {
+					rects[key] = rect
+				}
+			}
+		}
+	}
+	stats.RectanglesReused = kept
+	return rects
+}
+
+// updateTextWindows copies the text window scores, rescoring the windows
+// that contain a changed edge pixel.
+func (s *Snapshot) updateTextWindows(edges *bitGrid, edgeRegions []image.Rectangle, stats *UpdateStats) []textWindowScores {
+	text := make([]textWindowScores, len(s.text))
+	for i, old := range s.text {
+		sc := old
+		sc.confidence = append([]float64(nil), old.confidence...)
+		for row := 0; row < sc.rows; row++ {
+			for col := 0; col < sc.cols; col++ {
+				x, y := col*(sc.w/2), row*(sc.h/2)
+				window := image.Rect(x, y, x+sc.w, y+sc.h)
+				changed := false
+				for _, e := range edgeRegions {
+					if window.Overlaps(e) {
+						changed = true
+						break
+					}
+				}
+				if !changed {
+					stats.WindowsReused++
+					continue
+				}
+				sc.confidence[row*sc.cols+col] = textWindowConfidence(
+					countEdges(edges, x, y, sc.w, sc.h), sc.w*sc.h,
+					func() float64 { return calculateHorizontalScore(edges, x, y, sc.w, sc.h) })
+				stats.WindowsRescored++
+			}
+		}
+		text[i] = sc
+	}
+	return text
+}
+
+// Rectangles returns the rectangles in the snapshot, sorted by area
+// (largest first), exactly as DetectRectangles would report them.
+func (s *Snapshot) Rectangles() *RectanglesResult {
+	// DetectRectangles sorts contours found in scan order; start from the
+	// same order so that ties in area break the same way
+	keys := make([]int, 0, len(s.rects))
+	for key := range s.rects {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	rectangles := make([]Rectangle, len(keys))
+	for i, key := range keys {
+		rectangles[i] = s.rects[key]
+	}
+
+	// Sort by area descending
+	sort.Slice(rectangles, func(i, j int) bool {
+		return rectangles[i].Area > rectangles[j].Area
+	})
+
+	return &RectanglesResult{
+		Rectangles: rectangles,
+		Count:      len(rectangles),
+	}
+}
+
+// TextRegions returns the text regions in the snapshot, merged and sorted
+// by confidence, exactly as DetectTextRegions would report them.
+func (s *Snapshot) TextRegions() *TextRegionsResult {
+	merged := mergeOverlappingRegions(textCandidates(s.text, s.opts.MinConfidence, s.bounds.Min))
+	trimTextRegions(s.edges, merged, s.bounds.Min)
+	tagTextStyles(s.img, merged)
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Confidence > merged[j].Confidence
+	})
+	return &TextRegionsResult{
+		Regions: merged,
+		Count:   len(merged),
+	}
+}
+
+// contourKey identifies a contour by its first pixel in scan order.
+func contourKey(contour []Point, width int) int {
+	key := contour[0].Y*width + contour[0].X
+	for _, p := range contour[1:] {
+		key = minInt(key, p.Y*width+p.X)
+	}
+	return key
+}
+
+// ChangedRegions finds the regions where two same-sized images differ,
+// for use as the dirty regions of Snapshot.Update.
+//
+// Parameters:
+//   - prev, cur: The previous and current images.
+//
+// Returns:
+//   - []Bounds: Bounding boxes (X2 and Y2 exclusive, in cur's coordinates)
+//     of the changed pixels, one per cluster of changed 16×16 tiles. Empty
+//     if the images are identical.
+//   - error: Non-nil if the image sizes differ.
+//
+// A pixel has changed when its mean per-channel difference exceeds 10,
+// which ignores compression noise. Changed tiles that touch (including
+// diagonally) form one cluster, so a moved or redrawn element gives one
+// region rather than many.
+func ChangedRegions(prev, cur image.Image) ([]Bounds, error) {
+	pb, cb := prev.Bounds(), cur.Bounds()
+	if pb.Size() != cb.Size() {
+		return nil, fmt.Errorf("image sizes differ: %dx%d and %dx%d", pb.Dx(), pb.Dy(), cb.Dx(), cb.Dy())
+	}
+	width, height := cb.Dx(), cb.Dy()
+	cols := (width + changeTileSize - 1) / changeTileSize
+	rows := (height + changeTileSize - 1) / changeTileSize
+
+	// Bounding box of the changed pixels in each tile; empty if unchanged
+	tiles := make([]image.Rectangle, cols*rows)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			a := rgb8(prev, x+pb.Min.X, y+pb.Min.Y)
+			b := rgb8(cur, x+cb.Min.X, y+cb.Min.Y)
+			diff := 0.0
+			for c := 0; c < 3; c++ {
+				diff += math.Abs(float64(a[c]) - float64(b[c]))
+			}
+			if diff/3 <= changeThreshold {
+				continue
+			}
+			t := &tiles[(y/changeTileSize)*cols+x/changeTileSize]
+			*t = t.Union(image.Rect(x, y, x+1, y+1))
+		}
+	}
+
+	// Group touching changed tiles and report each group's bounding box
+	regions := make([]Bounds, 0)
+	seen := make([]bool, len(tiles))
+	for start := range tiles {
+		if tiles[start].Empty() || seen[start] {
+			continue
+		}
+		box := image.Rectangle{}
+		stack := []int{start}
+		seen[start] = true
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			box = box.Union(tiles[i])
+			tx, ty := i%cols, i/cols
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := tx+dx, ty+dy
+					if nx < 0 || nx >= cols || ny < 0 || ny >= rows {
+						continue
+					}
+					j := ny*cols + nx
+					if !seen[j] && !tiles[j].Empty() {
+						seen[j] = true
+						stack = append(stack, j)
+					}
+				}
+			}
+		}
+		regions = append(regions, Bounds{
+			X1: box.Min.X + cb.Min.X,
+			Y1: box.Min.Y + cb.Min.Y,
+			X2: box.Max.X + cb.Min.X,
+			Y2: box.Max.Y + cb.Min.Y,
+		})
+	}
+	return regions, nil
+}
@@ -0,0 +1,159 @@
+package detection
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"reflect"
+	"testing"
+)
+
+// fillBox fills r with a solid color.
+func fillBox(img *image.RGBA, r image.Rectangle, c color.Color) {
+	draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Src)
+}
+
+// drawStrokes draws rows of short vertical strokes that read as text.
+func drawStrokes(img *image.RGBA, x, y, n int) {
+	black := color.RGBA{0, 0, 0, 255}
+	for row := 0; row < 3; row++ {
+		for c := 0; c < n; c++ {
+			for k := 0; k < 8; k++ {
+				if (c+k+row)%3 != 0 {
+					img.Set(x+c*6+k%4, y+row*16+k, black)
+				}
+			}
+		}
+	}
+}
+
+// incrementalScene draws a UI-like frame: panels, a button, and text.
+func incrementalScene() *image.RGBA {
+	img := createTestImage(480, 320, color.White)
+	fillBox(img, image.Rect(10, 10, 230, 150), color.RGBA{200, 200, 220, 255})
+	fillBox(img, image.Rect(250, 10, 470, 150), color.RGBA{220, 200, 200, 255})
+	fillBox(img, image.Rect(30, 40, 110, 70), color.RGBA{80, 80, 220, 255})
+	drawStrokes(img, 20, 180, 60)
+	fillBox(img, image.Rect(300, 200, 420, 280), color.RGBA{60, 60, 60, 255})
+	return img
+}
+
+func cloneRGBA(img *image.RGBA) *image.RGBA {
+	out := image.NewRGBA(img.Bounds())
+	copy(out.Pix, img.Pix)
+	return out
+}
+
+func TestSnapshot_MatchesFullDetection(t *testing.T) {
+	opts := IncrementalOptions{MinArea: 100, Tolerance: 0.8, MinConfidence: 0.3}
+	prev := incrementalScene()
+	snap := NewSnapshot(prev, opts)
+
+	rects, _ := DetectRectangles(prev, opts.MinArea, opts.Tolerance)
+	text, _ := DetectTextRegions(prev, opts.MinConfidence)
+	if rects.Count < 3 || text.Count == 0 {
+		t.Fatalf("scene should have rectangles and text, got %d and %d", rects.Count, text.Count)
+	}
+	if !reflect.DeepEqual(snap.Rectangles(), rects) {
+		t.Errorf("snapshot rectangles differ from DetectRectangles:\n got %+v\nwant %+v", snap.Rectangles(), rects)
+	}
+	if !reflect.DeepEqual(snap.TextRegions(), text) {
+		t.Errorf("snapshot text regions differ from DetectTextRegions:\n got %+v\nwant %+v", snap.TextRegions(), text)
+	}
+
+	edits := []struct {
+		name string
+		edit func(img *image.RGBA)
+	}{
+		{"button recolored", func(img *image.RGBA) {
+			fillBox(img, image.Rect(30, 40, 110, 70), color.RGBA{40, 160, 40, 255})
+		}},
+		{"button moved", func(img *image.RGBA) {
+			fillBox(img, image.Rect(30, 40, 110, 70), color.RGBA{200, 200, 220, 255})
+			fillBox(img, image.Rect(120, 90, 200, 120), color.RGBA{80, 80, 220, 255})
+		}},
+		{"panel edge notched", func(img *image.RGBA) {
+			fillBox(img, image.Rect(350, 5, 380, 15), color.White)
+		}},
+		{"text appended", func(img *image.RGBA) { drawStrokes(img, 20, 240, 30) }},
+		{"box added in empty area", func(img *image.RGBA) {
+			fillBox(img, image.Rect(440, 290, 478, 318), color.RGBA{250, 200, 0, 255})
+		}},
+		{"no change", func(img *image.RGBA) {}},
+	}
+
+	for _, tt := range edits {
+		t.Run(tt.name, func(t *testing.T) {
+			cur := cloneRGBA(prev)
+			tt.edit(cur)
+
+			dirty, err := ChangedRegions(prev, cur)
+			if err != nil {
+				t.Fatalf("ChangedRegions failed: %v", err)
+			}
+			next, stats, err := snap.Update(cur, dirty)
+			if err != nil {
+				t.Fatalf("Update failed: %v", err)
+			}
+
+			rects, _ := DetectRectangles(cur, opts.MinArea, opts.Tolerance)
+			text, _ := DetectTextRegions(cur, opts.MinConfidence)
+			if !reflect.DeepEqual(next.Rectangles(), rects) {
+				t.Errorf("rectangles differ from full detection:\n got %+v\nwant %+v", next.Rectangles(), rects)
+			}
+			if !reflect.DeepEqual(next.TextRegions(), text) {
+				t.Errorf("text regions differ from full detection:\n got %+v\nwant %+v", next.TextRegions(), text)
+			}
+			if len(dirty) == 0 {
+				if stats.WindowsRescored != 0 || stats.ContoursRecomputed != 0 {
+					t.Errorf("unchanged image redid work: %+v", stats)
+				}
+			} else if stats.WindowsReused == 0 {
+				t.Errorf("small edit reused no text windows: %+v", stats)
+			}
+		})
+	}
+
+	// The original snapshot is not modified by updates
+	if !reflect.DeepEqual(snap.Rectangles(), rects) {
+		t.Error("Update modified the original snapshot")
+	}
+}
+
+func TestSnapshot_Update_SizeChanged(t *testing.T) {
+	snap := NewSnapshot(createTestImage(50, 50, color.White), IncrementalOptions{MinArea: 100, Tolerance: 0.8})
+	if _, _, err := snap.Update(createTestImage(60, 50, color.White), nil); err == nil {
+		t.Error("Update should fail when the image size changes")
+	}
+}
+
+func TestChangedRegions(t *testing.T) {
+	prev := createTestImage(200, 100, color.White)
+	cur := cloneRGBA(prev)
+
+	regions, err := ChangedRegions(prev, cur)
+	if err != nil {
+		t.Fatalf("ChangedRegions failed: %v", err)
+	}
+	if len(regions) != 0 {
+		t.Errorf("identical images: got %v, want no regions", regions)
+	}
+
+	// Two separate edits, plus noise below the threshold
+	fillBox(cur, image.Rect(10, 10, 40, 30), color.Black)
+	fillBox(cur, image.Rect(150, 60, 155, 90), color.Black)
+	cur.Set(100, 50, color.RGBA{250, 250, 250, 255})
+
+	regions, err = ChangedRegions(prev, cur)
+	if err != nil {
+		t.Fatalf("ChangedRegions failed: %v", err)
+	}
+	want := []Bounds{{X1: 10, Y1: 10, X2: 40, Y2: 30}, {X1: 150, Y1: 60, X2: 155, Y2: 90}}
+	if !reflect.DeepEqual(regions, want) {
+		t.Errorf("got %v, want %v", regions, want)
+	}
+
+	if _, err := ChangedRegions(prev, createTestImage(100, 100, color.White)); err == nil {
+		t.Error("ChangedRegions should fail for different sizes")
+	}
+}
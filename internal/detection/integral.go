@@ -0,0 +1,109 @@
+package detection
+
+import "image"
+
+// IntegralImage is a summed-area table: after an O(width × height) build,
+// the sum of values over any axis-aligned rectangle is available in O(1).
+//
+// The table has one extra leading row and column of zeros, so entry
+// (x, y) holds the sum of all values above and to the left of (x, y),
+// exclusive.
+type IntegralImage struct {
+	width, height int
+	sum           []int64 // (width+1) × (height+1), row-major
+}
+
+// NewIntegralImage builds a summed-area table over a width×height grid,
+// calling value once for each cell.
+func NewIntegralImage(width, height int, value func(x, y int) int64) *IntegralImage {
+	return fillIntegralImage(width, height, make([]int64, (width+1)*(height+1)), value)
+}
+
+// newPooledIntegralImage is NewIntegralImage with the table taken from a
+// pool, for short-lived tables. Call release when done.
+func newPooledIntegralImage(width, height int, value func(x, y int) int64) *IntegralImage {
+	return fillIntegralImage(width, height, int64Pool.get((width+1)*(height+1)), value)
+}
+
+// fillIntegralImage builds the table into sum, which must be zeroed and
+// hold (width+1) × (height+1) entries.
+func fillIntegralImage(width, height int, sum []int64, value func(x, y int) int64) *IntegralImage {
+	stride := width + 1
+	ii := &IntegralImage{
+		width:  width,
+		height: height,
+		sum:    sum,
+	}
+	for y := 0; y < height; y++ {
+		var rowSum int64
+		above := y * stride
+		row := (y + 1) * stride
+		for x := 0; x < width; x++ {
+			rowSum += value(x, y)
+			ii.sum[row+x+1] = ii.sum[above+x+1] + rowSum
+		}
+	}
+	return ii
+}
+
+// release returns a pooled table to the pool. The image must not be used
+// afterwards.
+func (ii *IntegralImage) release() {
+	int64Pool.put(ii.sum)
+	ii.sum = nil
+}
+
+// Sum returns the sum over the rectangle [x1, x2) × [y1, y2). The
+// rectangle is clipped to the grid; an empty rectangle sums to 0.
+func (ii *IntegralImage) Sum(x1, y1, x2, y2 int) int64 {
+	x1, x2 = maxInt(x1, 0), minInt(x2, ii.width)
+	y1, y2 = maxInt(y1, 0), minInt(y2, ii.height)
+	if x1 >= x2 || y1 >= y2 {
+		return 0
+	}
+	stride := ii.width + 1
+	return ii.sum[y2*stride+x2] - ii.sum[y1*stride+x2] - ii.sum[y2*stride+x1] + ii.sum[y1*stride+x1]
+}
+
+// Mean returns the average value over the rectangle [x1, x2) × [y1, y2),
+// clipped to the grid, or 0 if the clipped rectangle is empty.
+func (ii *IntegralImage) Mean(x1, y1, x2, y2 int) float64 {
+	x1, x2 = maxInt(x1, 0), minInt(x2, ii.width)
+	y1, y2 = maxInt(y1, 0), minInt(y2, ii.height)
+	if x1 >= x2 || y1 >= y2 {
+		return 0
+	}
+	return float64(ii.Sum(x1, y1, x2, y2)) / float64((x2-x1)*(y2-y1))
+}
+
+// ColorIntegral holds one summed-area table per RGB channel, giving the
+// mean color of any rectangle in O(1).
+type ColorIntegral struct {
+	r, g, b *IntegralImage
+}
+
+// NewColorIntegral builds per-channel summed-area tables for img.
+// Coordinates passed to MeanColor are relative to img.Bounds().Min.
+func NewColorIntegral(img image.Image) *ColorIntegral {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	pixels := make([][3]uint8, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			pixels[y*width+x] = rgb8(img, x+bounds.Min.X, y+bounds.Min.Y)
+		}
+	}
+	channel := func(c int) *IntegralImage {
+		return NewIntegralImage(width, height, func(x, y int) int64 {
+			return int64(pixels[y*width+x][c])
+		})
+	}
+	return &ColorIntegral{r: channel(0), g: channel(1), b: channel(2)}
+}
+
+// MeanColor returns the mean 8-bit red, green, and blue values over the
+// rectangle [x1, x2) × [y1, y2), clipped to the image.
+func (ci *ColorIntegral) MeanColor(x1, y1, x2, y2 int) (r, g, b float64) {
+	return ci.r.Mean(x1, y1, x2, y2), ci.g.Mean(x1, y1, x2, y2), ci.b.Mean(x1, y1, x2, y2)
+}
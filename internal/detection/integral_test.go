@@ -0,0 +1,112 @@
+package detection
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+func TestIntegralImage_Sum(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	const w, h = 13, 9
+	values := make([]int64, w*h)
+	for i := range values {
+		values[i] = rng.Int63n(256)
+	}
+	ii := NewIntegralImage(w, h, func(x, y int) int64 { return values[y*w+x] })
+
+	for y1 := 0; y1 <= h; y1++ {
+		for y2 := y1; y2 <= h; y2++ {
+			for x1 := 0; x1 <= w; x1++ {
+				for x2 := x1; x2 <= w; x2++ {
+					var want int64
+					for y := y1; y < y2; y++ {
+						for x := x1; x < x2; x++ {
+							want += values[y*w+x]
+						}
+					}
+					if got := ii.Sum(x1, y1, x2, y2); got != want {
+						t.Fatalf("Sum(%d,%d,%d,%d): got %d, want %d", x1, y1, x2, y2, got, want)
+					}
+				}
+			}
+		}
+	}
+
+	// Rectangles are clipped to the grid
+	if got, want := ii.Sum(-5, -5, w+5, h+5), ii.Sum(0, 0, w, h); got != want {
+		t.Errorf("clipped Sum: got %d, want %d", got, want)
+	}
+	if got := ii.Sum(5, 5, 2, 8); got != 0 {
+		t.Errorf("empty Sum: got %d, want 0", got)
+	}
+	if got := ii.Mean(w, 0, w+3, h); got != 0 {
+		t.Errorf("Mean outside grid: got %v, want 0", got)
+	}
+}
+
+func TestColorIntegral_MeanColor(t *testing.T) {
+	// Left half red, right half blue, on an offset image
+	img := image.NewRGBA(image.Rect(10, 10, 30, 20))
+	for y := 10; y < 20; y++ {
+		for x := 10; x < 30; x++ {
+			if x < 20 {
+				img.Set(x, y, color.RGBA{255, 0, 0, 255})
+			} else {
+				img.Set(x, y, color.RGBA{0, 0, 255, 255})
+			}
+		}
+	}
+	ci := NewColorIntegral(img)
+
+	if r, g, b := ci.MeanColor(0, 0, 10, 10); r != 255 || g != 0 || b != 0 {
+		t.Errorf("left half: got (%v,%v,%v), want red", r, g, b)
+	}
+	if r, g, b := ci.MeanColor(5, 0, 15, 10); r != 127.5 || g != 0 || b != 127.5 {
+		t.Errorf("straddling: got (%v,%v,%v), want (127.5,0,127.5)", r, g, b)
+	}
+}
+
+func TestEdgeRunTables_MatchesDirectScore(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	const w, h = 40, 30
+	edges := newBitGrid(w, h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if rng.Intn(3) == 0 {
+				edges.Set(x, y)
+			}
+		}
+	}
+
+	for i := 0; i < 200; i++ {
+		x, y := rng.Intn(w), rng.Intn(h)
+		ww, wh := 1+rng.Intn(w-x), 1+rng.Intn(h-y)
+		tables := newEdgeRunTables(edges, y, wh)
+		want := calculateHorizontalScore(edges, x, y, ww, wh)
+		if got := tables.horizontalScore(x, ww); got != want {
+			t.Fatalf("window (%d,%d) %dx%d: got %v, want %v", x, y, ww, wh, got, want)
+		}
+		if got, want := tables.edges.Sum(x, 0, x+ww, wh), countEdges(edges, x, y, ww, wh); got != want {
+			t.Fatalf("window (%d,%d) %dx%d: edge count %d, want %d", x, y, ww, wh, got, want)
+		}
+	}
+}
+
+func TestBitGrid(t *testing.T) {
+	g := newBitGrid(130, 3)
+	g.Set(0, 0)
+	g.Set(63, 1)
+	g.Set(64, 1)
+	g.Set(129, 2)
+
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 130; x++ {
+			want := (x == 0 && y == 0) || (y == 1 && (x == 63 || x == 64)) || (x == 129 && y == 2)
+			if g.Get(x, y) != want {
+				t.Errorf("(%d,%d): got %v, want %v", x, y, g.Get(x, y), want)
+			}
+		}
+	}
+}
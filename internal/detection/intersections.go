@@ -0,0 +1,226 @@
+package detection
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// maxIntersectionLines caps the number of lines FindIntersections accepts,
+// since every pair is compared.
+const maxIntersectionLines = 500
+
+// IntersectionOptions configures FindIntersections.
+type IntersectionOptions struct {
+	// Tolerance is how far, in pixels, an intersection may lie beyond the
+	// end of a segment, how close to an end it must be for the line to end
+	// there, and how close intersections must be to merge into one
+	// junction. It absorbs gaps left by detection and stroke width.
+	Tolerance float64
+
+	// MinAngle is the smallest angle, in degrees, between two lines that
+	// are intersected. Nearly parallel lines, such as the two edges of one
+	// stroke, are not.
+	MinAngle float64
+}
+
+// JunctionLine is one line meeting at a junction.
+type JunctionLine struct {
+	// Line is the line's index in IntersectionsResult.Lines.
+	Line int `json:"line"`
+
+	// Ends is true if the line ends at the junction, and false if it
+	// passes through.
+	Ends bool `json:"ends"`
+}
+
+// Junction is a point where two or more lines meet.
+type Junction struct {
+	// X and Y are the junction's position, the mean of the pairwise
+	// intersections merged into it, rounded to 1 decimal place.
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+
+	// Kind is "corner" if every line ends at the junction, "cross" if
+	// every line passes through, and "tee" otherwise.
+	Kind string `json:"kind"`
+
+	// Degree is the number of branches leaving the junction: one for a
+	// line ending there, two for a line passing through.
+	Degree int `json:"degree"`
+
+	// Lines are the lines meeting at the junction, by index.
+	Lines []JunctionLine `json:"lines"`
+}
+
+// IntersectedLine is a line of IntersectionsResult with the junctions on
+// it.
+type IntersectedLine struct {
+	Start Point `json:"start"`
+	End   Point `json:"end"`
+
+	// Junctions are the indices of the junctions on the line, in order
+	// from Start to End.
+	Junctions []int `json:"junctions"`
+}
+
+// IntersectionsResult contains the junctions of a set of lines.
+type IntersectionsResult struct {
+	// Lines are the lines intersected, in input order.
+	Lines []IntersectedLine `json:"lines"`
+
+	// Junctions are the points where lines meet, top to bottom and left
+	// to right.
+	Junctions []Junction `json:"junctions"`
+
+	// Count is the number of junctions.
+	Count int `json:"count"`
+}
+
+// junctionCluster accumulates the pairwise intersections merged into one
+// junction.
+type junctionCluster struct {
+	sumX, sumY float64
+	n          int
+	lines      map[int]bool
+}
+
+func (c *junctionCluster) center() (float64, float64) {
+	return c.sumX / float64(c.n), c.sumY / float64(c.n)
+}
+
+// FindIntersections computes where line segments, such as those from
+// DetectLines, meet, and merges the crossings of nearby lines into
+// junctions. The result is the lines' topology: which lines meet where,
+// whether each ends there or passes through, and the order of junctions
+// along each line, for reconstructing tables and grids or the connections
+// of a diagram.
+//
+// Returns an error if there are more than 500 lines, Tolerance is
+// negative or above 50, or MinAngle is not above 0 and at most 90.
+//
+// # Algorithm
+//
+// Every pair of lines at least MinAngle apart is intersected as infinite
+// lines, and the crossing kept if it lies on both segments or within
+// Tolerance of their ends. Crossings are merged greedily into the first
+// junction whose center is within Tolerance, so the four crossings of two
+// strokes, each detected as a pair of edges, make one junction.
+func FindIntersections(lines []Line, opts IntersectionOptions) (*IntersectionsResult, error) {
+	if len(lines) > maxIntersectionLines {
+		return nil, fmt.Errorf("need at most %d lines, got %d", maxIntersectionLines, len(lines))
+	}
+	if opts.Tolerance < 0 || opts.Tolerance > 50 {
+		return nil, fmt.Errorf("tolerance must be between 0 and 50 pixels, got %g", opts.Tolerance)
+	}
+	if opts.MinAngle <= 0 || opts.MinAngle > 90 {
+		return nil, fmt.Errorf("min_angle must be above 0 and at most 90 degrees, got %g", opts.MinAngle)
+	}
+
+	var clusters []*junctionCluster
+	for i := 0; i < len(lines); i++ {
+		for j := i + 1; j < len(lines); j++ {
+			x, y, ok := intersectSegments(lines[i], lines[j], opts)
+			if !ok {
+				continue
+			}
+			var c *junctionCluster
+			for _, k := range clusters {
+				cx, cy := k.center()
+				if math.Hypot(x-cx, y-cy) <= opts.Tolerance {
+					c = k
+					break
+				}
+			}
+			if c == nil {
+				c = &junctionCluster{lines: make(map[int]bool)}
+				clusters = append(clusters, c)
+			}
+			c.sumX, c.sumY, c.n = c.sumX+x, c.sumY+y, c.n+1
+			c.lines[i], c.lines[j] = true, true
+		}
+	}
+
+	result := &IntersectionsResult{
+		Lines:     make([]IntersectedLine, len(lines)),
+		Junctions: make([]Junction, len(clusters)),
+		Count:     len(clusters),
+	}
+	for i, c := range clusters {
+		x, y := c.center()
+		j := Junction{X: math.Round(x*10) / 10, Y: math.Round(y*10) / 10, Lines: []JunctionLine{}}
+		ends := 0
+		for l := range c.lines {
+			s, e := lines[l].Start, lines[l].End
+			end := math.Min(math.Hypot(x-float64(s.X), y-float64(s.Y)), math.Hypot(x-float64(e.X), y-float64(e.Y))) <= opts.Tolerance
+			j.Lines = append(j.Lines, JunctionLine{Line: l, Ends: end})
+			if end {
+				ends++
+				j.Degree++
+			} else {
+				j.Degree += 2
+			}
+		}
+		sort.Slice(j.Lines, func(a, b int) bool { return j.Lines[a].Line < j.Lines[b].Line })
+		switch ends {
+		case len(j.Lines):
+			j.Kind = "corner"
+		case 0:
+			j.Kind = "cross"
+		default:
+			j.Kind = "tee"
+		}
+		result.Junctions[i] = j
+	}
+	sort.SliceStable(result.Junctions, func(a, b int) bool {
+		ja, jb := result.Junctions[a], result.Junctions[b]
+		if ja.Y != jb.Y {
+			return ja.Y < jb.Y
+		}
+		return ja.X < jb.X
+	})
+
+	for i, l := range lines {
+		result.Lines[i] = IntersectedLine{Start: l.Start, End: l.End, Junctions: []int{}}
+	}
+	for k, j := range result.Junctions {
+		for _, jl := range j.Lines {
+			result.Lines[jl.Line].Junctions = append(result.Lines[jl.Line].Junctions, k)
+		}
+	}
+	for i := range result.Lines {
+		l := &result.Lines[i]
+		dx, dy := float64(l.End.X-l.Start.X), float64(l.End.Y-l.Start.Y)
+		along := func(k int) float64 {
+			j := result.Junctions[l.Junctions[k]]
+			return (j.X-float64(l.Start.X))*dx + (j.Y-float64(l.Start.Y))*dy
+		}
+		sort.SliceStable(l.Junctions, func(a, b int) bool { return along(a) < along(b) })
+	}
+	return result, nil
+}
+
+// intersectSegments returns where the lines through a and b cross, if
+// they are at least opts.MinAngle apart and the crossing lies on both
+// segments, or within opts.Tolerance of their ends.
+func intersectSegments(a, b Line, opts IntersectionOptions) (float64, float64, bool) {
+	ax, ay := float64(a.End.X-a.Start.X), float64(a.End.Y-a.Start.Y)
+	bx, by := float64(b.End.X-b.Start.X), float64(b.End.Y-b.Start.Y)
+	la, lb := math.Hypot(ax, ay), math.Hypot(bx, by)
+	if la == 0 || lb == 0 {
+		return 0, 0, false
+	}
+	cross := ax*by - ay*bx
+	if math.Asin(math.Min(1, math.Abs(cross)/(la*lb)))*180/math.Pi < opts.MinAngle-1e-9 {
+		return 0, 0, false
+	}
+
+	// Start + t*(End - Start) on each line, t in [0, 1] on the segment
+	wx, wy := float64(b.Start.X-a.Start.X), float64(b.Start.Y-a.Start.Y)
+	t := (wx*by - wy*bx) / cross
+	u := (wx*ay - wy*ax) / cross
+	if t < -opts.Tolerance/la || t > 1+opts.Tolerance/la || u < -opts.Tolerance/lb || u > 1+opts.Tolerance/lb {
+		return 0, 0, false
+	}
+	return float64(a.Start.X) + t*ax, float64(a.Start.Y) + t*ay, true
+}
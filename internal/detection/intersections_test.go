@@ -0,0 +1,78 @@
+package detection
+
+import (
+	"testing"
+)
+
+func TestFindIntersections_Grid(t *testing.T) {
+	// A 2x2 table: three rows and three columns of rules
+	var lines []Line
+	for _, v := range []int{0, 50, 100} {
+		lines = append(lines, Line{Start: Point{X: 0, Y: v}, End: Point{X: 100, Y: v}})
+	}
+	for _, v := range []int{0, 50, 100} {
+		lines = append(lines, Line{Start: Point{X: v, Y: 100}, End: Point{X: v, Y: 0}})
+	}
+	result, err := FindIntersections(lines, IntersectionOptions{Tolerance: 3, MinAngle: 10})
+	if err != nil {
+		t.Fatalf("FindIntersections failed: %v", err)
+	}
+	if result.Count != 9 {
+		t.Fatalf("got %d junctions, want 9: %+v", result.Count, result.Junctions)
+	}
+
+	// Junctions run top to bottom, left to right
+	wantKinds := []string{"corner", "tee", "corner", "tee", "cross", "tee", "corner", "tee", "corner"}
+	wantDegrees := []int{2, 3, 2, 3, 4, 3, 2, 3, 2}
+	for i, j := range result.Junctions {
+		if j.X != float64(i%3*50) || j.Y != float64(i/3*50) || j.Kind != wantKinds[i] || j.Degree != wantDegrees[i] {
+			t.Errorf("junction %d = %+v, want %s at (%d, %d)", i, j, wantKinds[i], i%3*50, i/3*50)
+		}
+	}
+	if center := result.Junctions[4].Lines; len(center) != 2 || center[0] != (JunctionLine{Line: 1}) || center[1] != (JunctionLine{Line: 4}) {
+		t.Errorf("center lines = %+v", center)
+	}
+
+	// The columns run bottom to top
+	if got := result.Lines[3].Junctions; len(got) != 3 || got[0] != 6 || got[1] != 3 || got[2] != 0 {
+		t.Errorf("left column junctions = %v, want [6 3 0]", got)
+	}
+}
+
+func TestFindIntersections_Tolerance(t *testing.T) {
+	// A stem stopping 2 pixels short of a rule, a second rule just below
+	// the first, and a line 5 degrees off the first rule crossing it
+	lines := []Line{
+		{Start: Point{X: 0, Y: 50}, End: Point{X: 100, Y: 50}},
+		{Start: Point{X: 40, Y: 0}, End: Point{X: 40, Y: 48}},
+		{Start: Point{X: 0, Y: 51}, End: Point{X: 100, Y: 54}},
+		{Start: Point{X: 60, Y: 48}, End: Point{X: 100, Y: 51}},
+	}
+	result, err := FindIntersections(lines, IntersectionOptions{Tolerance: 5, MinAngle: 10})
+	if err != nil {
+		t.Fatalf("FindIntersections failed: %v", err)
+	}
+	if result.Count != 1 {
+		t.Fatalf("got %d junctions, want 1: %+v", result.Count, result.Junctions)
+	}
+	// The stem ends at both rules, whose crossings merge
+	j := result.Junctions[0]
+	if j.Kind != "tee" || j.Degree != 5 || len(j.Lines) != 3 || j.Lines[0].Ends || !j.Lines[1].Ends || j.Lines[2].Ends {
+		t.Errorf("junction = %+v", j)
+	}
+
+	result, _ = FindIntersections(lines, IntersectionOptions{Tolerance: 1, MinAngle: 10})
+	if result.Count != 0 || len(result.Lines[1].Junctions) != 0 {
+		t.Errorf("tolerance 1: got %+v", result.Junctions)
+	}
+	result, _ = FindIntersections(lines, IntersectionOptions{Tolerance: 1, MinAngle: 4})
+	if result.Count != 1 || result.Junctions[0].X != 86.7 || result.Junctions[0].Y != 50 {
+		t.Errorf("min angle 4: got %+v, want the lines 5 degrees apart to cross", result.Junctions)
+	}
+
+	for _, opts := range []IntersectionOptions{{Tolerance: -1, MinAngle: 10}, {Tolerance: 5}, {Tolerance: 5, MinAngle: 91}} {
+		if _, err := FindIntersections(lines, opts); err == nil {
+			t.Errorf("%+v: expected error", opts)
+		}
+	}
+}
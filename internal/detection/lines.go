@@ -1,11 +1,30 @@
 package detection
 
 import (
+	"fmt"
 	"image"
 	"math"
 	"sort"
 )
 
+// lineMaxGap is the widest gap, in pixels, between edge pixels along a
+// Hough line that still counts as one segment.
+const lineMaxGap = 4
+
+// lineDuplicateDist is how close, in pixels, an edge pixel must lie to a
+// segment already found to count toward that segment. A segment with half
+// its pixels that close is dropped as the same line.
+const lineDuplicateDist = 4
+
+// lineMaxClutter is the largest fraction of a segment's length that may
+// have edge pixels 3 to 8 pixels away on both sides, as along a row of
+// text.
+const lineMaxClutter = 0.25
+
+// lineMinCoverage is the least fraction of a segment's length that must
+// have edge pixels on the line.
+const lineMinCoverage = 0.95
+
 // Line represents a detected line segment with metadata.
 //
 // Lines are detected using the Hough line transform, which finds lines
@@ -49,6 +68,18 @@ type LinesResult struct {
 
 	// Count is the number of lines detected.
 	Count int `json:"count"`
+
+	// Tuning describes the automatic parameter search, if one was run
+	// (see TuneRectangles, TuneLines, and TuneCircles).
+	Tuning *TuningReport `json:"tuning,omitempty"`
+
+	// Debug explains the run, if requested (see the Debug variants of
+	// the detectors).
+	Debug *DebugOverlay `json:"debug,omitempty"`
+
+	// Export holds the detections as a dataset annotation file, if
+	// requested (see ExportAnnotations).
+	Export *AnnotationExport `json:"export,omitempty"`
 }
 
 // DetectLines finds line segments in an image using the Hough line transform.
@@ -78,8 +109,13 @@ type LinesResult struct {
 //  3. Peak Detection: Find local maxima in the accumulator with votes >= threshold
 //  4. Line Extraction: For each peak (rho, theta):
 //     - Find all edge pixels within 2 pixels of the line
-//     - Determine endpoints from the extreme points
-//  5. Length Filtering: Remove lines shorter than minLength
+//     - Split them into segments wherever the gap between neighbors along
+//     the line is wider than 4 pixels
+//  5. Segment Filtering: Remove segments that are shorter than minLength,
+//     have edge pixels along less than 95% of their length, lie along a
+//     segment already found (half their pixels within 4 pixels of it), or
+//     have edge pixels 3-8 pixels away on both sides along more than a
+//     quarter of their length (a row of text rather than a drawn line)
 //  6. Arrow Detection (optional): Check endpoints for arrow head patterns
 //
 // # Arrow Detection
@@ -98,28 +134,274 @@ type LinesResult struct {
 //   - Maximum 50 lines returned (strongest by vote count)
 //   - Curved lines are not detected
 //   - Very thick lines may be detected as multiple parallel lines
-//   - Dashed/dotted lines may be detected as multiple segments
+//   - Dashed/dotted lines are split at gaps over 4 pixels, and dashes
+//     shorter than minLength are dropped
+//   - A line drawn through or right beside text may be dropped as clutter
 //   - Arrow detection only works for ~45° arrow heads
 func DetectLines(img image.Image, minLength int, detectArrows bool) (*LinesResult, error) {
+	return detectLines(img, minLength, detectArrows, nil), nil
+}
+
+// DetectLinesDebug is DetectLines with a DebugOverlay attached to the
+// result, showing the edge map, the lines found, and the Hough peaks
+// rejected for having too few edge pixels along them (drawn across the
+// image) or a segment shorter than minLength. Peaks beyond the 50-line
+// limit are not examined and not shown.
+func DetectLinesDebug(img image.Image, minLength int, detectArrows bool) (*LinesResult, error) {
+	trace := newDebugTrace(img)
+	result := detectLines(img, minLength, detectArrows, trace)
+	trace.accept(result.Primitives())
+	overlay, err := trace.overlay()
+	if err != nil {
+		return nil, err
+	}
+	result.Debug = overlay
+	return result, nil
+}
+
+// detectLines implements DetectLines, recording its steps in trace if
+// non-nil.
+func detectLines(img image.Image, minLength int, detectArrows bool, trace *debugTrace) *LinesResult {
 	bounds := img.Bounds()
 	width := bounds.Dx()
 	height := bounds.Dy()
 
 	// Detect edges
 	edges := detectEdges(img, width, height)
+	defer edges.release()
+	trace.edgeMap(edges)
+
+	// Find (rho, theta) peaks in Hough space, with OpenCV when available
+	threshold := minLength / 2
+	peaks, ok := acceleratedLinePeaks(edges, threshold)
+	if !ok {
+		peaks = houghLinePeaks(edges, threshold)
+	}
+	trace.countPeaks(len(peaks))
+
+	// Convert peaks to line segments
+	lines := make([]Line, 0)
+	var linePoints []Point
+
+	for _, peak := range peaks {
+		if len(lines) >= 50 { // Limit number of lines
+			break
+		}
+
+		angle := float64(peak.theta) * math.Pi / 180.0
+		rho := float64(peak.rho)
+
+		cosA := math.Cos(angle)
+		sinA := math.Sin(angle)
+
+		// Find points on this line in the edge image
+		linePoints = linePixels(linePoints[:0], edges, cosA, sinA, rho)
+
+		if len(linePoints) < minLength {
+			if trace != nil {
+				start, end := houghLineEnds(peak, width, height, bounds.Min)
+				trace.reject(Primitive{Type: PrimitiveLine, Start: &start, End: &end}, false,
+					fmt.Sprintf("%d edge pixels along the line, min_length %d needed", len(linePoints), minLength))
+			}
+			continue
+		}
+
+		// Each run of those points along the line (direction (-sin, cos))
+		// without a gap wider than lineMaxGap is a segment
+		along := func(p Point) float64 { return float64(p.Y)*cosA - float64(p.X)*sinA }
+		sort.Slice(linePoints, func(i, j int) bool {
+			a, b := linePoints[i], linePoints[j]
+			if da, db := along(a), along(b); da != db {
+				return da < db
+			}
+			return a.Y < b.Y || (a.Y == b.Y && a.X < b.X)
+		})
+		for first := 0; first < len(linePoints) && len(lines) < 50; {
+			last := first
+			for last+1 < len(linePoints) && along(linePoints[last+1])-along(linePoints[last]) <= lineMaxGap {
+				last++
+			}
+			run := linePoints[first : last+1]
+			first = last + 1
+			if len(run) < minLength {
+				continue
+			}
+			if line, reason := lineSegment(img, edges, run, along, minLength, lines, detectArrows); reason != "" {
+				if trace != nil && line.Length > 0 {
+					nearMiss := line.Length < float64(minLength)
+					trace.reject(Primitive{Type: PrimitiveLine, Start: &line.Start, End: &line.End}, nearMiss, reason)
+				}
+			} else {
+				lines = append(lines, line)
+			}
+		}
+	}
+
+	return &LinesResult{
+		Lines: lines,
+		Count: len(lines),
+	}
+}
+
+// linePixels appends to points the edge pixels less than 2 pixels from
+// the line x*cosA + y*sinA = rho. It steps along the axis the line is
+// closer to and checks only the few pixels across it at each step.
+func linePixels(points []Point, edges *bitGrid, cosA, sinA, rho float64) []Point {
+	check := func(x, y int) {
+		if x >= 0 && x < edges.width && y >= 0 && y < edges.height && edges.Get(x, y) &&
+			math.Abs(float64(x)*cosA+float64(y)*sinA-rho) < 2.0 {
+			points = append(points, Point{X: x, Y: y})
+		}
+	}
+	if math.Abs(sinA) >= math.Abs(cosA) {
+		for x := 0; x < edges.width; x++ {
+			mid := (rho - float64(x)*cosA) / sinA
+			half := 2 / math.Abs(sinA)
+			for y := int(math.Ceil(mid - half)); y <= int(math.Floor(mid+half)); y++ {
+				check(x, y)
+			}
+		}
+	} else {
+		for y := 0; y < edges.height; y++ {
+			mid := (rho - float64(y)*sinA) / cosA
+			half := 2 / math.Abs(cosA)
+			for x := int(math.Ceil(mid - half)); x <= int(math.Floor(mid+half)); x++ {
+				check(x, y)
+			}
+		}
+	}
+	return points
+}
+
+// lineSegment measures the segment spanned by run, edge pixels sorted by
+// their position along a Hough line, and returns it. If the segment is
+// shorter than minLength, has edge pixels along less than lineMinCoverage
+// of its length, lies along one of found, or runs through clutter (see
+// lineMaxClutter), it returns the reason instead, with the segment's ends
+// set.
+func lineSegment(img image.Image, edges *bitGrid, run []Point, along func(Point) float64, minLength int, found []Line, detectArrows bool) (Line, string) {
+	bounds := img.Bounds()
+	startX, startY := run[0].X, run[0].Y
+	endX, endY := run[len(run)-1].X, run[len(run)-1].Y
+	dx := float64(endX - startX)
+	dy := float64(endY - startY)
+	length := math.Sqrt(dx*dx + dy*dy)
+	line := Line{
+		Start:  Point{X: startX + bounds.Min.X, Y: startY + bounds.Min.Y},
+		End:    Point{X: endX + bounds.Min.X, Y: endY + bounds.Min.Y},
+		Length: math.Round(length*10) / 10,
+	}
+	if length < float64(minLength) {
+		return line, fmt.Sprintf("segment length %.1f below min_length %d", length, minLength)
+	}
+
+	// A drawn line has edge pixels all along it. Text lined up on a
+	// baseline or cap height leaves short gaps between letters
+	covered := make(map[int]bool, len(run))
+	for _, p := range run {
+		covered[int(math.Round(along(p)))] = true
+	}
+	steps := int(math.Round(along(run[len(run)-1]))) - int(math.Round(along(run[0]))) + 1
+	if coverage := float64(len(covered)) / float64(steps); coverage < lineMinCoverage {
+		return line, fmt.Sprintf("edge pixels along only %.0f%% of the segment", coverage*100)
+	}
+
+	// Both edges of a stroke, and neighboring peaks, trace the same
+	// segment again, or lean on it for most of their pixels
+	near := 0
+	for _, p := range run {
+		q := [2]float64{float64(p.X + bounds.Min.X), float64(p.Y + bounds.Min.Y)}
+		for _, l := range found {
+			a := [2]float64{float64(l.Start.X), float64(l.Start.Y)}
+			b := [2]float64{float64(l.End.X), float64(l.End.Y)}
+			if segmentDistance(q, a, b) <= lineDuplicateDist {
+				near++
+				break
+			}
+		}
+	}
+	if 2*near >= len(run) {
+		return line, "lies along a line already found"
+	}
+
+	// A drawn line has open space beside it on at least one side for most
+	// of its length; a row of text has glyphs on both
+	width, height := edges.width, edges.height
+	ux, uy := dx/length, dy/length
+	cluttered := 0
+	for t := 0.0; t <= length; t++ {
+		x, y := float64(startX)+t*ux, float64(startY)+t*uy
+		var sides [2]bool
+		for d := 3.0; d <= 8; d++ {
+			for i, sign := range []float64{1, -1} {
+				px, py := int(math.Round(x-sign*d*uy)), int(math.Round(y+sign*d*ux))
+				if px >= 0 && px < width && py >= 0 && py < height && edges.Get(px, py) {
+					sides[i] = true
+				}
+			}
+		}
+		if sides[0] && sides[1] {
+			cluttered++
+		}
+	}
+	if clutter := float64(cluttered) / (math.Floor(length) + 1); clutter > lineMaxClutter {
+		return line, fmt.Sprintf("edge pixels close on both sides along %.0f%% of the segment", clutter*100)
+	}
+
+	line.AngleDegrees = math.Round(math.Atan2(dy, dx)*180/math.Pi*10) / 10
+	line.Color = sampleColorHex(img, (startX+endX)/2+bounds.Min.X, (startY+endY)/2+bounds.Min.Y)
+	line.ThicknessApprox = estimateLineThickness(edges, startX, startY, endX, endY, width, height)
+	if detectArrows {
+		line.HasArrowStart = detectArrowHead(edges, startX, startY, endX, endY, width, height)
+		line.HasArrowEnd = detectArrowHead(edges, endX, endY, startX, startY, width, height)
+	}
+	return line, ""
+}
 
-	// Hough transform parameters
+// houghLineEnds returns where the peak's line crosses the edges of a
+// width×height image, offset by origin, for drawing.
+func houghLineEnds(peak linePeak, width, height int, origin image.Point) (Point, Point) {
+	angle := float64(peak.theta) * math.Pi / 180.0
+	cosA, sinA := math.Cos(angle), math.Sin(angle)
+	rho := float64(peak.rho)
+	var x1, y1, x2, y2 float64
+	if math.Abs(sinA) > math.Abs(cosA) {
+		// Closer to horizontal: solve for y at the left and right edges
+		x1, x2 = 0, float64(width-1)
+		y1, y2 = (rho-x1*cosA)/sinA, (rho-x2*cosA)/sinA
+	} else {
+		y1, y2 = 0, float64(height-1)
+		x1, x2 = (rho-y1*sinA)/cosA, (rho-y2*sinA)/cosA
+	}
+	return Point{X: origin.X + int(math.Round(x1)), Y: origin.Y + int(math.Round(y1))},
+		Point{X: origin.X + int(math.Round(x2)), Y: origin.Y + int(math.Round(y2))}
+}
+
+// linePeak is a line in Hough space: x*cos(theta) + y*sin(theta) = rho,
+// with theta in degrees (0-179).
+type linePeak struct {
+	rho   int
+	theta int
+	votes int
+}
+
+// houghLinePeaks votes every edge pixel into a (rho, theta) accumulator
+// and returns the local maxima with at least threshold votes, strongest
+// first.
+func houghLinePeaks(edges *bitGrid, threshold int) []linePeak {
+	width, height := edges.width, edges.height
+
+	// Hough transform parameters. The accumulator is a pooled, row-major
+	// (rho × theta) buffer
 	maxDist := int(math.Sqrt(float64(width*width + height*height)))
 	numAngles := 180
-	accumulator := make([][]int, maxDist*2)
-	for i := range accumulator {
-		accumulator[i] = make([]int, numAngles)
-	}
+	accumulator := int32Pool.get(maxDist * 2 * numAngles)
+	defer int32Pool.put(accumulator)
 
 	// Vote in Hough space
 	for y := 0; y < height; y++ {
 		for x := 0; x < width; x++ {
-			if !edges[y][x] {
+			if !edges.Get(x, y) {
 				continue
 			}
 			for theta := 0; theta < numAngles; theta++ {
@@ -127,24 +409,18 @@ func DetectLines(img image.Image, minLength int, detectArrows bool) (*LinesResul
 				rho := float64(x)*math.Cos(angle) + float64(y)*math.Sin(angle)
 				rhoIdx := int(rho) + maxDist
 				if rhoIdx >= 0 && rhoIdx < maxDist*2 {
-					accumulator[rhoIdx][theta]++
+					accumulator[rhoIdx*numAngles+theta]++
 				}
 			}
 		}
 	}
 
 	// Find peaks in accumulator
-	type Peak struct {
-		rho   int
-		theta int
-		votes int
-	}
-	peaks := make([]Peak, 0)
-	threshold := minLength / 2
+	peaks := make([]linePeak, 0)
 
 	for rhoIdx := 0; rhoIdx < maxDist*2; rhoIdx++ {
 		for theta := 0; theta < numAngles; theta++ {
-			if accumulator[rhoIdx][theta] >= threshold {
+			if int(accumulator[rhoIdx*numAngles+theta]) >= threshold {
 				// Check if local maximum
 				isMax := true
 				for dr := -2; dr <= 2 && isMax; dr++ {
@@ -155,17 +431,17 @@ func DetectLines(img image.Image, minLength int, detectArrows bool) (*LinesResul
 						nr := rhoIdx + dr
 						nt := (theta + dt + numAngles) % numAngles
 						if nr >= 0 && nr < maxDist*2 {
-							if accumulator[nr][nt] > accumulator[rhoIdx][theta] {
+							if accumulator[nr*numAngles+nt] > accumulator[rhoIdx*numAngles+theta] {
 								isMax = false
 							}
 						}
 					}
 				}
 				if isMax {
-					peaks = append(peaks, Peak{
+					peaks = append(peaks, linePeak{
 						rho:   rhoIdx - maxDist,
 						theta: theta,
-						votes: accumulator[rhoIdx][theta],
+						votes: int(accumulator[rhoIdx*numAngles+theta]),
 					})
 				}
 			}
@@ -177,114 +453,14 @@ func DetectLines(img image.Image, minLength int, detectArrows bool) (*LinesResul
 		return peaks[i].votes > peaks[j].votes
 	})
 
-	// Convert peaks to line segments
-	lines := make([]Line, 0)
-
-	for _, peak := range peaks {
-		if len(lines) >= 50 { // Limit number of lines
-			break
-		}
-
-		angle := float64(peak.theta) * math.Pi / 180.0
-		rho := float64(peak.rho)
-
-		// Find actual line endpoints by tracing along the line
-		var startX, startY, endX, endY int
-		found := false
-
-		cosA := math.Cos(angle)
-		sinA := math.Sin(angle)
-
-		// Find points on this line in the edge image
-		linePoints := make([]Point, 0)
-		for y := 0; y < height; y++ {
-			for x := 0; x < width; x++ {
-				if !edges[y][x] {
-					continue
-				}
-				// Check if point is on the line (within tolerance)
-				dist := math.Abs(float64(x)*cosA + float64(y)*sinA - rho)
-				if dist < 2.0 {
-					linePoints = append(linePoints, Point{X: x, Y: y})
-				}
-			}
-		}
-
-		if len(linePoints) < minLength {
-			continue
-		}
-
-		// Find endpoints
-		minDist := math.MaxFloat64
-		maxDist := 0.0
-		for _, p := range linePoints {
-			d := float64(p.X)*cosA + float64(p.Y)*sinA
-			if d < minDist {
-				minDist = d
-				startX, startY = p.X, p.Y
-				found = true
-			}
-			if d > maxDist {
-				maxDist = d
-				endX, endY = p.X, p.Y
-			}
-		}
-
-		if !found {
-			continue
-		}
-
-		// Calculate length
-		dx := float64(endX - startX)
-		dy := float64(endY - startY)
-		length := math.Sqrt(dx*dx + dy*dy)
-
-		if length < float64(minLength) {
-			continue
-		}
-
-		// Calculate angle in degrees
-		angleDeg := math.Atan2(dy, dx) * 180 / math.Pi
-
-		// Sample color at midpoint
-		midX := (startX + endX) / 2
-		midY := (startY + endY) / 2
-		color := sampleColorHex(img, midX+bounds.Min.X, midY+bounds.Min.Y)
-
-		// Estimate thickness
-		thickness := estimateLineThickness(edges, startX, startY, endX, endY, width, height)
-
-		// Detect arrows if requested
-		hasArrowStart := false
-		hasArrowEnd := false
-		if detectArrows {
-			hasArrowStart = detectArrowHead(edges, startX, startY, endX, endY, width, height)
-			hasArrowEnd = detectArrowHead(edges, endX, endY, startX, startY, width, height)
-		}
-
-		lines = append(lines, Line{
-			Start:          Point{X: startX + bounds.Min.X, Y: startY + bounds.Min.Y},
-			End:            Point{X: endX + bounds.Min.X, Y: endY + bounds.Min.Y},
-			Length:         math.Round(length*10) / 10,
-			AngleDegrees:   math.Round(angleDeg*10) / 10,
-			Color:          color,
-			ThicknessApprox: thickness,
-			HasArrowStart:  hasArrowStart,
-			HasArrowEnd:    hasArrowEnd,
-		})
-	}
-
-	return &LinesResult{
-		Lines: lines,
-		Count: len(lines),
-	}, nil
+	return peaks
 }
 
 // estimateLineThickness estimates line thickness by sampling perpendicular to the line.
 //
 // At the line's midpoint, samples perpendicular to the line direction for ±10 pixels,
 // counting edge pixels. Returns a minimum of 1 even if no edges are found.
-func estimateLineThickness(edges [][]bool, x1, y1, x2, y2, width, height int) int {
+func estimateLineThickness(edges *bitGrid, x1, y1, x2, y2, width, height int) int {
 	dx := float64(x2 - x1)
 	dy := float64(y2 - y1)
 	length := math.Sqrt(dx*dx + dy*dy)
@@ -305,7 +481,7 @@ func estimateLineThickness(edges [][]bool, x1, y1, x2, y2, width, height int) in
 	for d := -10; d <= 10; d++ {
 		px := int(midX + float64(d)*perpX)
 		py := int(midY + float64(d)*perpY)
-		if px >= 0 && px < width && py >= 0 && py < height && edges[py][px] {
+		if px >= 0 && px < width && py >= 0 && py < height && edges.Get(px, py) {
 			thickness++
 		}
 	}
@@ -327,7 +503,7 @@ func estimateLineThickness(edges [][]bool, x1, y1, x2, y2, width, height int) in
 //
 // Returns true if both left and right wings have at least 3 edge pixels
 // within 10 pixels of the endpoint.
-func detectArrowHead(edges [][]bool, endX, endY, otherX, otherY, width, height int) bool {
+func detectArrowHead(edges *bitGrid, endX, endY, otherX, otherY, width, height int) bool {
 	// Direction from other end to this end
 	dx := float64(endX - otherX)
 	dy := float64(endY - otherY)
@@ -362,13 +538,13 @@ func detectArrowHead(edges [][]bool, endX, endY, otherX, otherY, width, height i
 	for d := 1; d <= checkDist; d++ {
 		px := endX - int(float64(d)*leftX)
 		py := endY - int(float64(d)*leftY)
-		if px >= 0 && px < width && py >= 0 && py < height && edges[py][px] {
+		if px >= 0 && px < width && py >= 0 && py < height && edges.Get(px, py) {
 			leftCount++
 		}
 
 		px = endX - int(float64(d)*rightX)
 		py = endY - int(float64(d)*rightY)
-		if px >= 0 && px < width && py >= 0 && py < height && edges[py][px] {
+		if px >= 0 && px < width && py >= 0 && py < height && edges.Get(px, py) {
 			rightCount++
 		}
 	}
@@ -200,16 +200,13 @@ func TestDetectLines_MaxLines(t *testing.T) {
 
 func TestEstimateLineThickness(t *testing.T) {
 	// Create edge array with thick line
-	edges := make([][]bool, 50)
-	for y := 0; y < 50; y++ {
-		edges[y] = make([]bool, 50)
-	}
+	edges := newBitGrid(50, 50)
 
 	// Thick horizontal line (3 pixels)
 	for x := 0; x < 50; x++ {
-		edges[24][x] = true
-		edges[25][x] = true
-		edges[26][x] = true
+		edges.Set(x, 24)
+		edges.Set(x, 25)
+		edges.Set(x, 26)
 	}
 
 	thickness := estimateLineThickness(edges, 0, 25, 49, 25, 50, 50)
@@ -221,14 +218,11 @@ func TestEstimateLineThickness(t *testing.T) {
 }
 
 func TestEstimateLineThickness_SinglePixel(t *testing.T) {
-	edges := make([][]bool, 50)
-	for y := 0; y < 50; y++ {
-		edges[y] = make([]bool, 50)
-	}
+	edges := newBitGrid(50, 50)
 
 	// Single pixel line
 	for x := 0; x < 50; x++ {
-		edges[25][x] = true
+		edges.Set(x, 25)
 	}
 
 	thickness := estimateLineThickness(edges, 0, 25, 49, 25, 50, 50)
@@ -240,10 +234,7 @@ func TestEstimateLineThickness_SinglePixel(t *testing.T) {
 }
 
 func TestEstimateLineThickness_ZeroLength(t *testing.T) {
-	edges := make([][]bool, 10)
-	for y := 0; y < 10; y++ {
-		edges[y] = make([]bool, 10)
-	}
+	edges := newBitGrid(10, 10)
 
 	// Zero length line (same point)
 	thickness := estimateLineThickness(edges, 5, 5, 5, 5, 10, 10)
@@ -255,22 +246,19 @@ func TestEstimateLineThickness_ZeroLength(t *testing.T) {
 }
 
 func TestDetectArrowHead(t *testing.T) {
-	edges := make([][]bool, 50)
-	for y := 0; y < 50; y++ {
-		edges[y] = make([]bool, 50)
-	}
+	edges := newBitGrid(50, 50)
 
 	// Create arrow head pattern at (40, 25)
 	// Line going from left to right
 	endX, endY := 40, 25
 	for x := 10; x <= endX; x++ {
-		edges[25][x] = true
+		edges.Set(x, 25)
 	}
 
 	// Arrow wings at 45 degrees
 	for i := 1; i <= 5; i++ {
-		edges[endY-i][endX-i] = true // top wing
-		edges[endY+i][endX-i] = true // bottom wing
+		edges.Set(endX-i, endY-i) // top wing
+		edges.Set(endX-i, endY+i) // bottom wing
 	}
 
 	hasArrow := detectArrowHead(edges, endX, endY, 10, 25, 50, 50)
@@ -279,14 +267,11 @@ func TestDetectArrowHead(t *testing.T) {
 }
 
 func TestDetectArrowHead_NoArrow(t *testing.T) {
-	edges := make([][]bool, 50)
-	for y := 0; y < 50; y++ {
-		edges[y] = make([]bool, 50)
-	}
+	edges := newBitGrid(50, 50)
 
 	// Just a line, no arrow head
 	for x := 10; x <= 40; x++ {
-		edges[25][x] = true
+		edges.Set(x, 25)
 	}
 
 	hasArrow := detectArrowHead(edges, 40, 25, 10, 25, 50, 50)
@@ -297,10 +282,7 @@ func TestDetectArrowHead_NoArrow(t *testing.T) {
 }
 
 func TestDetectArrowHead_ZeroLength(t *testing.T) {
-	edges := make([][]bool, 10)
-	for y := 0; y < 10; y++ {
-		edges[y] = make([]bool, 10)
-	}
+	edges := newBitGrid(10, 10)
 
 	// Same point (zero length)
 	hasArrow := detectArrowHead(edges, 5, 5, 5, 5, 10, 10)
@@ -0,0 +1,270 @@
+package detection
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// borderMatchThreshold is the maximum mean per-channel difference (0-255)
+// between a border pixel and the border's reference color for the border to
+// count as visible at that point. It is loose enough to accept anti-aliasing.
+const borderMatchThreshold = 24
+
+// maxOverlapBoxes caps the number of boxes AnalyzeOverlaps accepts, since
+// every pair is compared.
+const maxOverlapBoxes = 200
+
+// LabeledBox is a bounding box with an optional caller-supplied label.
+//
+// Box edges should lie on the element's drawn border, as in the bounds
+// returned by DetectRectangles.
+type LabeledBox struct {
+	Bounds Bounds `json:"bounds"`
+	Label  string `json:"label,omitempty"`
+}
+
+// Overlap describes two boxes that intersect.
+type Overlap struct {
+	// A and B are indices into the input boxes (A < B).
+	A int `json:"a"`
+	B int `json:"b"`
+
+	// Relation is "overlap" for a partial intersection, "a_contains_b" or
+	// "b_contains_a" when one box lies entirely within the other.
+	Relation string `json:"relation"`
+
+	// Intersection is the shared area.
+	Intersection Bounds `json:"intersection"`
+
+	// IoU is the intersection area divided by the union area (0.0-1.0).
+	IoU float64 `json:"iou"`
+
+	// BorderContinuityA is the fraction (0.0-1.0) of A's border inside B
+	// that is still visible. Nil if no part of A's border lies inside B.
+	BorderContinuityA *float64 `json:"border_continuity_a,omitempty"`
+
+	// BorderContinuityB is the same measure for B's border inside A.
+	BorderContinuityB *float64 `json:"border_continuity_b,omitempty"`
+
+	// OnTop is the z-order hint: "a", "b", or "unknown". The element whose
+	// border stays continuous through the intersection is drawn over the
+	// other.
+	OnTop string `json:"on_top"`
+}
+
+// OverlapResult contains all intersecting pairs among a set of boxes.
+type OverlapResult struct {
+	// Boxes are the boxes that were analyzed, in input order, clipped to
+	// the image.
+	Boxes []LabeledBox `json:"boxes"`
+
+	// Overlaps lists every intersecting pair.
+	Overlaps []Overlap `json:"overlaps"`
+
+	// Count is the number of overlapping pairs.
+	Count int `json:"count"`
+}
+
+// AnalyzeOverlaps reports which boxes intersect, by how much, and which
+// element appears to be drawn on top.
+//
+// Parameters:
+//   - img: The image the boxes refer to, used for z-order hints.
+//   - boxes: Bounding boxes to compare (at most 200). Each is clipped to
+//     the image first.
+//
+// Returns:
+//   - *OverlapResult: Every intersecting pair with IoU and z-order hint.
+//   - error: Non-nil if there are too many boxes or a box is inverted or
+//     lies outside the image.
+//
+// # Z-Order Hints
+//
+// Where two elements overlap, the one on top keeps its whole border while
+// the other's border disappears beneath it. For each box, the reference
+// border color is the most common color along its edges outside the other
+// box; continuity is the fraction of its edge pixels inside the other box
+// that still match that color (allowing a 1px offset for anti-aliasing).
+// A clear difference (0.3 or more) between the two continuities decides the
+// hint. For nested boxes only the inner border can be measured (against its
+// own most common color), and the inner element is reported on top if its
+// border is at least 70% consistent.
+func AnalyzeOverlaps(img image.Image, boxes []LabeledBox) (*OverlapResult, error) {
+	if len(boxes) > maxOverlapBoxes {
+		return nil, fmt.Errorf("too many boxes: %d (max %d)", len(boxes), maxOverlapBoxes)
+	}
+	// Clipped, a box's border has at most a few pixels per row and column
+	// of the image, however far out of range it was given
+	ib := img.Bounds()
+	clipped := make([]LabeledBox, len(boxes))
+	for i, b := range boxes {
+		if b.Bounds.X2 <= b.Bounds.X1 || b.Bounds.Y2 <= b.Bounds.Y1 {
+			return nil, fmt.Errorf("box %d is empty or inverted: %+v", i, b.Bounds)
+		}
+		b.Bounds = Bounds{
+			X1: maxInt(b.Bounds.X1, ib.Min.X),
+			Y1: maxInt(b.Bounds.Y1, ib.Min.Y),
+			X2: minInt(b.Bounds.X2, ib.Max.X),
+			Y2: minInt(b.Bounds.Y2, ib.Max.Y),
+		}
+		if b.Bounds.X2 <= b.Bounds.X1 || b.Bounds.Y2 <= b.Bounds.Y1 {
+			return nil, fmt.Errorf("box %d lies outside the %dx%d image", i, ib.Dx(), ib.Dy())
+		}
+		clipped[i] = b
+	}
+	boxes = clipped
+
+	result := &OverlapResult{Boxes: boxes, Overlaps: []Overlap{}}
+	for i := 0; i < len(boxes); i++ {
+		for j := i + 1; j < len(boxes); j++ {
+			a, b := boxes[i].Bounds, boxes[j].Bounds
+			inter := Bounds{
+				X1: maxInt(a.X1, b.X1),
+				Y1: maxInt(a.Y1, b.Y1),
+				X2: minInt(a.X2, b.X2),
+				Y2: minInt(a.Y2, b.Y2),
+			}
+			if inter.X2 <= inter.X1 || inter.Y2 <= inter.Y1 {
+				continue
+			}
+
+			o := Overlap{A: i, B: j, Relation: "overlap", Intersection: inter}
+			switch {
+			case boundsContain(a, b):
+				o.Relation = "a_contains_b"
+			case boundsContain(b, a):
+				o.Relation = "b_contains_a"
+			}
+
+			interArea := boundsArea(inter)
+			union := boundsArea(a) + boundsArea(b) - interArea
+			o.IoU = math.Round(float64(interArea)/float64(union)*1000) / 1000
+
+			o.BorderContinuityA = borderContinuity(img, a, b)
+			o.BorderContinuityB = borderContinuity(img, b, a)
+			o.OnTop = zOrderHint(o.BorderContinuityA, o.BorderContinuityB)
+
+			result.Overlaps = append(result.Overlaps, o)
+		}
+	}
+	result.Count = len(result.Overlaps)
+
+	return result, nil
+}
+
+// zOrderHint decides which element is on top from the two border
+// continuity measurements.
+func zOrderHint(ca, cb *float64) string {
+	switch {
+	case ca != nil && cb != nil:
+		if *ca-*cb >= 0.3 {
+			return "a"
+		}
+		if *cb-*ca >= 0.3 {
+			return "b"
+		}
+	case ca != nil && *ca >= 0.7:
+		return "a"
+	case cb != nil && *cb >= 0.7:
+		return "b"
+	}
+	return "unknown"
+}
+
+// borderContinuity returns the fraction of a's border pixels lying strictly
+// inside other that match a's border color, or nil if none lie inside.
+func borderContinuity(img image.Image, a, other Bounds) *float64 {
+	ib := img.Bounds()
+	inImage := func(x, y int) bool {
+		return x >= ib.Min.X && y >= ib.Min.Y && x < ib.Max.X && y < ib.Max.Y
+	}
+	inside := func(x, y int) bool {
+		return x > other.X1 && x < other.X2 && y > other.Y1 && y < other.Y2
+	}
+
+	// Each border pixel with the direction perpendicular to its edge
+	type edgePixel struct{ x, y, dx, dy int }
+	var pixels []edgePixel
+	for x := a.X1; x <= a.X2; x++ {
+		pixels = append(pixels, edgePixel{x, a.Y1, 0, 1}, edgePixel{x, a.Y2, 0, 1})
+	}
+	for y := a.Y1 + 1; y < a.Y2; y++ {
+		pixels = append(pixels, edgePixel{a.X1, y, 1, 0}, edgePixel{a.X2, y, 1, 0})
+	}
+
+	// Reference color: most common border color outside the other box, or
+	// along the whole border if a lies entirely inside it
+	mostCommon := func(skipInside bool) ([3]uint8, bool) {
+		counts := make(map[[3]uint8]int)
+		var best [3]uint8
+		for _, p := range pixels {
+			if !inImage(p.x, p.y) || (skipInside && inside(p.x, p.y)) {
+				continue
+			}
+			c := rgb8(img, p.x, p.y)
+			counts[c]++
+			if counts[c] > counts[best] {
+				best = c
+			}
+		}
+		return best, len(counts) > 0
+	}
+	ref, ok := mostCommon(true)
+	if !ok {
+		if ref, ok = mostCommon(false); !ok {
+			return nil
+		}
+	}
+
+	total, visible := 0, 0
+	for _, p := range pixels {
+		if !inImage(p.x, p.y) || !inside(p.x, p.y) {
+			continue
+		}
+		total++
+		for d := -1; d <= 1; d++ {
+			x, y := p.x+d*p.dx, p.y+d*p.dy
+			if inImage(x, y) && rgbDistance(rgb8(img, x, y), ref) <= borderMatchThreshold {
+				visible++
+				break
+			}
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+
+	v := math.Round(float64(visible)/float64(total)*1000) / 1000
+	return &v
+}
+
+// boundsContain reports whether outer fully contains inner.
+func boundsContain(outer, inner Bounds) bool {
+	return inner.X1 >= outer.X1 && inner.Y1 >= outer.Y1 && inner.X2 <= outer.X2 && inner.Y2 <= outer.Y2
+}
+
+// boundsArea returns the area of b using the same convention as
+// Rectangle.Area: (X2-X1) × (Y2-Y1).
+func boundsArea(b Bounds) int {
+	return (b.X2 - b.X1) * (b.Y2 - b.Y1)
+}
+
+// rgb8 returns the 8-bit RGB channels of a pixel.
+func rgb8(img image.Image, x, y int) [3]uint8 {
+	r, g, b, _ := img.At(x, y).RGBA()
+	return [3]uint8{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8)}
+}
+
+// rgbDistance returns the mean absolute per-channel difference of two colors.
+func rgbDistance(a, b [3]uint8) float64 {
+	sum := 0
+	for i := 0; i < 3; i++ {
+		d := int(a[i]) - int(b[i])
+		if d < 0 {
+			d = -d
+		}
+		sum += d
+	}
+	return float64(sum) / 3
+}
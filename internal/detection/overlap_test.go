@@ -0,0 +1,115 @@
+package detection
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// drawFilledBox draws a box with a 1px border on its bounds lines and a
+// solid interior, covering whatever was there before.
+func drawFilledBox(img *image.RGBA, b Bounds, border, fill color.Color) {
+	for y := b.Y1; y <= b.Y2; y++ {
+		for x := b.X1; x <= b.X2; x++ {
+			if x == b.X1 || x == b.X2 || y == b.Y1 || y == b.Y2 {
+				img.Set(x, y, border)
+			} else {
+				img.Set(x, y, fill)
+			}
+		}
+	}
+}
+
+func TestAnalyzeOverlaps_ZOrder(t *testing.T) {
+	img := createTestImage(100, 80, color.White)
+	a := Bounds{X1: 10, Y1: 10, X2: 50, Y2: 40}
+	b := Bounds{X1: 30, Y1: 20, X2: 70, Y2: 60}
+	drawFilledBox(img, a, color.RGBA{255, 0, 0, 255}, color.RGBA{255, 230, 230, 255})
+	drawFilledBox(img, b, color.RGBA{0, 0, 255, 255}, color.RGBA{230, 230, 255, 255}) // b drawn last
+
+	result, err := AnalyzeOverlaps(img, []LabeledBox{{Bounds: a, Label: "card"}, {Bounds: b, Label: "popup"}})
+	if err != nil {
+		t.Fatalf("AnalyzeOverlaps failed: %v", err)
+	}
+
+	if result.Count != 1 {
+		t.Fatalf("Count: got %d, want 1", result.Count)
+	}
+	o := result.Overlaps[0]
+	if o.Relation != "overlap" {
+		t.Errorf("Relation: got %s, want overlap", o.Relation)
+	}
+	if o.Intersection != (Bounds{X1: 30, Y1: 20, X2: 50, Y2: 40}) {
+		t.Errorf("Intersection: got %+v", o.Intersection)
+	}
+	// 400 / (1200 + 1600 - 400)
+	if o.IoU != 0.167 {
+		t.Errorf("IoU: got %v, want 0.167", o.IoU)
+	}
+	if o.OnTop != "b" {
+		t.Errorf("OnTop: got %s, want b (continuity a=%v b=%v)", o.OnTop, *o.BorderContinuityA, *o.BorderContinuityB)
+	}
+	if *o.BorderContinuityB < 0.9 || *o.BorderContinuityA > 0.1 {
+		t.Errorf("continuity: got a=%v b=%v", *o.BorderContinuityA, *o.BorderContinuityB)
+	}
+}
+
+func TestAnalyzeOverlaps_Containment(t *testing.T) {
+	img := createTestImage(100, 100, color.White)
+	outer := Bounds{X1: 5, Y1: 5, X2: 90, Y2: 90}
+	inner := Bounds{X1: 30, Y1: 30, X2: 60, Y2: 60}
+	drawFilledBox(img, outer, color.Black, color.RGBA{240, 240, 240, 255})
+	drawFilledBox(img, inner, color.RGBA{0, 128, 0, 255}, color.White)
+
+	result, err := AnalyzeOverlaps(img, []LabeledBox{{Bounds: inner}, {Bounds: outer}})
+	if err != nil {
+		t.Fatalf("AnalyzeOverlaps failed: %v", err)
+	}
+
+	o := result.Overlaps[0]
+	if o.Relation != "b_contains_a" {
+		t.Errorf("Relation: got %s, want b_contains_a", o.Relation)
+	}
+	if o.BorderContinuityB != nil {
+		t.Errorf("outer border never enters inner box, got continuity %v", *o.BorderContinuityB)
+	}
+	if o.OnTop != "a" {
+		t.Errorf("OnTop: got %s, want a", o.OnTop)
+	}
+}
+
+func TestAnalyzeOverlaps_Disjoint(t *testing.T) {
+	img := createTestImage(50, 50, color.White)
+	boxes := []LabeledBox{
+		{Bounds: Bounds{X1: 0, Y1: 0, X2: 10, Y2: 10}},
+		{Bounds: Bounds{X1: 20, Y1: 20, X2: 30, Y2: 30}},
+		{Bounds: Bounds{X1: 10, Y1: 0, X2: 15, Y2: 10}}, // touches first box edge only
+	}
+
+	result, err := AnalyzeOverlaps(img, boxes)
+	if err != nil {
+		t.Fatalf("AnalyzeOverlaps failed: %v", err)
+	}
+	if result.Count != 0 {
+		t.Errorf("Count: got %d, want 0", result.Count)
+	}
+}
+
+func TestAnalyzeOverlaps_Errors(t *testing.T) {
+	img := createTestImage(10, 10, color.White)
+
+	if _, err := AnalyzeOverlaps(img, []LabeledBox{{Bounds: Bounds{X1: 5, Y1: 0, X2: 2, Y2: 5}}}); err == nil {
+		t.Error("should fail for an inverted box")
+	}
+	if _, err := AnalyzeOverlaps(img, []LabeledBox{{Bounds: Bounds{X1: 10, Y1: 0, X2: 20, Y2: 5}}}); err == nil {
+		t.Error("should fail for a box outside the image")
+	}
+
+	tooMany := make([]LabeledBox, maxOverlapBoxes+1)
+	for i := range tooMany {
+		tooMany[i] = LabeledBox{Bounds: Bounds{X1: 0, Y1: 0, X2: 1, Y2: 1}}
+	}
+	if _, err := AnalyzeOverlaps(img, tooMany); err == nil {
+		t.Error("should fail with too many boxes")
+	}
+}
@@ -0,0 +1,56 @@
+package detection
+
+import (
+	"math/bits"
+	"sync"
+)
+
+// slicePool recycles scratch slices between detection calls so batch
+// workloads do not allocate a fresh edge map or accumulator per image.
+//
+// Slices are kept in size classes by capacity (powers of two), one
+// sync.Pool per class, so a request is served by any earlier slice of the
+// same class. The pools are safe for concurrent use; the garbage collector
+// may still drop idle slices at any time.
+type slicePool[T any] struct {
+	classes [bits.UintSize]sync.Pool
+}
+
+// sizeClass returns the class of a slice of length n: the smallest c with
+// 1<<c >= n.
+func sizeClass(n int) int {
+	if n <= 1 {
+		return 0
+	}
+	return bits.Len(uint(n - 1))
+}
+
+// get returns a zeroed slice of length n. Pass it to put when done.
+func (p *slicePool[T]) get(n int) []T {
+	c := sizeClass(n)
+	if v, ok := p.classes[c].Get().(*[]T); ok {
+		s := (*v)[:n]
+		clear(s)
+		return s
+	}
+	return make([]T, n, 1<<c)
+}
+
+// put returns s to the pool. s must not be used afterwards. Slices that
+// did not come from get are ignored.
+func (p *slicePool[T]) put(s []T) {
+	c := sizeClass(cap(s))
+	if cap(s) == 0 || cap(s) != 1<<c {
+		return
+	}
+	s = s[:0]
+	p.classes[c].Put(&s)
+}
+
+// Scratch buffer pools shared by the detectors.
+var (
+	uint8Pool slicePool[uint8]  // grayscale rows
+	wordPool  slicePool[uint64] // bitGrid storage
+	int32Pool slicePool[int32]  // Hough accumulators
+	int64Pool slicePool[int64]  // integral image tables
+)
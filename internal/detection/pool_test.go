@@ -0,0 +1,66 @@
+package detection
+
+import "testing"
+
+func TestSizeClass(t *testing.T) {
+	tests := []struct{ n, want int }{
+		{0, 0}, {1, 0}, {2, 1}, {3, 2}, {4, 2}, {5, 3}, {1024, 10}, {1025, 11},
+	}
+	for _, tt := range tests {
+		if got := sizeClass(tt.n); got != tt.want {
+			t.Errorf("sizeClass(%d): got %d, want %d", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestSlicePool_ReturnsZeroedSlices(t *testing.T) {
+	var p slicePool[int32]
+
+	s := p.get(100)
+	if len(s) != 100 || cap(s) != 128 {
+		t.Fatalf("get(100): got len %d cap %d, want len 100 cap 128", len(s), cap(s))
+	}
+	for i := range s {
+		s[i] = int32(i + 1)
+	}
+	p.put(s)
+
+	// Any length in the same class may reuse the slice; it must be zeroed
+	// either way, including the part beyond the earlier length
+	s = p.get(120)
+	if len(s) != 120 {
+		t.Fatalf("get(120): got len %d", len(s))
+	}
+	for i, v := range s {
+		if v != 0 {
+			t.Fatalf("get(120)[%d] = %d, want 0", i, v)
+		}
+	}
+	p.put(s)
+
+	// Slices not from the pool are ignored
+	p.put(make([]int32, 10))
+	if s := p.get(10); len(s) != 10 || cap(s) != 16 {
+		t.Errorf("get(10): got len %d cap %d, want len 10 cap 16", len(s), cap(s))
+	}
+}
+
+func TestBitGrid_ReleaseAndReuse(t *testing.T) {
+	g := newBitGrid(100, 50)
+	for y := 0; y < 50; y++ {
+		for x := 0; x < 100; x++ {
+			g.Set(x, y)
+		}
+	}
+	g.release()
+
+	g = newBitGrid(100, 50)
+	defer g.release()
+	for y := 0; y < 50; y++ {
+		for x := 0; x < 100; x++ {
+			if g.Get(x, y) {
+				t.Fatalf("reused grid has (%d,%d) set", x, y)
+			}
+		}
+	}
+}
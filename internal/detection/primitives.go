@@ -0,0 +1,114 @@
+package detection
+
+import "strconv"
+
+// Primitive types produced by the Primitives conversions.
+const (
+	PrimitiveRect   = "rect"
+	PrimitiveCircle = "circle"
+	PrimitiveLine   = "line"
+)
+
+// Overlay colors, one per kind of detection, so a drawing of several
+// results stays readable.
+const (
+	rectangleOverlayColor  = "#FF0000"
+	circleOverlayColor     = "#0080FF"
+	lineOverlayColor       = "#00B000"
+	textRegionOverlayColor = "#FF00FF"
+)
+
+// Primitive is one shape to draw over an image, converted from a
+// detection result. Only the fields for its Type are set.
+type Primitive struct {
+	// Type is "rect", "circle", or "line".
+	Type string `json:"type"`
+
+	// Bounds is the box of a "rect".
+	Bounds *Bounds `json:"bounds,omitempty"`
+
+	// Center and Radius describe a "circle".
+	Center *Point `json:"center,omitempty"`
+	Radius int    `json:"radius,omitempty"`
+
+	// Start and End are the endpoints of a "line"; ArrowStart and
+	// ArrowEnd mark arrow heads.
+	Start      *Point `json:"start,omitempty"`
+	End        *Point `json:"end,omitempty"`
+	ArrowStart bool   `json:"arrow_start,omitempty"`
+	ArrowEnd   bool   `json:"arrow_end,omitempty"`
+
+	// Color is the stroke color as hex (#RRGGBB), fixed per kind of
+	// detection.
+	Color string `json:"color"`
+
+	// Label names the detection, e.g. "rectangle 2", numbered from 1 in
+	// result order.
+	Label string `json:"label"`
+}
+
+// Primitives converts the rectangles to "rect" primitives, in result order.
+func (r *RectanglesResult) Primitives() []Primitive {
+	prims := make([]Primitive, len(r.Rectangles))
+	for i, rect := range r.Rectangles {
+		b := rect.Bounds
+		prims[i] = Primitive{
+			Type:   PrimitiveRect,
+			Bounds: &b,
+			Color:  rectangleOverlayColor,
+			Label:  "rectangle " + strconv.Itoa(i+1),
+		}
+	}
+	return prims
+}
+
+// Primitives converts the circles to "circle" primitives, in result order.
+func (r *CirclesResult) Primitives() []Primitive {
+	prims := make([]Primitive, len(r.Circles))
+	for i, c := range r.Circles {
+		center := c.Center
+		prims[i] = Primitive{
+			Type:   PrimitiveCircle,
+			Center: &center,
+			Radius: c.Radius,
+			Color:  circleOverlayColor,
+			Label:  "circle " + strconv.Itoa(i+1),
+		}
+	}
+	return prims
+}
+
+// Primitives converts the lines to "line" primitives, in result order,
+// keeping detected arrow heads.
+func (r *LinesResult) Primitives() []Primitive {
+	prims := make([]Primitive, len(r.Lines))
+	for i, l := range r.Lines {
+		start, end := l.Start, l.End
+		prims[i] = Primitive{
+			Type:       PrimitiveLine,
+			Start:      &start,
+			End:        &end,
+			ArrowStart: l.HasArrowStart,
+			ArrowEnd:   l.HasArrowEnd,
+			Color:      lineOverlayColor,
+			Label:      "line " + strconv.Itoa(i+1),
+		}
+	}
+	return prims
+}
+
+// Primitives converts the text regions to "rect" primitives, in result
+// order.
+func (r *TextRegionsResult) Primitives() []Primitive {
+	prims := make([]Primitive, len(r.Regions))
+	for i, region := range r.Regions {
+		b := region.Bounds
+		prims[i] = Primitive{
+			Type:   PrimitiveRect,
+			Bounds: &b,
+			Color:  textRegionOverlayColor,
+			Label:  "text " + strconv.Itoa(i+1),
+		}
+	}
+	return prims
+}
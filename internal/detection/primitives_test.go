@@ -0,0 +1,27 @@
+package detection
+
+import "testing"
+
+func TestPrimitives_Fields(t *testing.T) {
+	lines := &LinesResult{
+		Lines: []Line{{Start: Point{X: 1, Y: 2}, End: Point{X: 3, Y: 4}, HasArrowStart: true}},
+	}
+	p := lines.Primitives()[0]
+	if p.Type != PrimitiveLine || *p.Start != (Point{X: 1, Y: 2}) || *p.End != (Point{X: 3, Y: 4}) {
+		t.Errorf("unexpected line primitive: %+v", p)
+	}
+	if !p.ArrowStart || p.ArrowEnd {
+		t.Errorf("arrow flags: got start=%v end=%v", p.ArrowStart, p.ArrowEnd)
+	}
+	if p.Label != "line 1" || p.Color != lineOverlayColor {
+		t.Errorf("label/color: got %q %q", p.Label, p.Color)
+	}
+
+	// Text regions are rects, told apart from rectangles by label and color
+	regions := &TextRegionsResult{Regions: []TextRegion{{Bounds: Bounds{X2: 5, Y2: 5}}}}
+	rects := &RectanglesResult{Rectangles: []Rectangle{{Bounds: Bounds{X2: 5, Y2: 5}}}}
+	tp, rp := regions.Primitives()[0], rects.Primitives()[0]
+	if tp.Type != PrimitiveRect || tp.Label != "text 1" || tp.Color == rp.Color {
+		t.Errorf("text primitive %+v should be a rect distinct from %+v", tp, rp)
+	}
+}
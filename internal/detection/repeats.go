@@ -0,0 +1,427 @@
+package detection
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+)
+
+// repeatBackgroundThreshold is the maximum mean per-channel difference
+// (0-255) from the background color for a pixel to count as background.
+const repeatBackgroundThreshold = 16
+
+// maxRepeatCandidates caps the number of elements compared pairwise by
+// FindRepeats. When more are found, the largest are kept.
+const maxRepeatCandidates = 500
+
+// repeatSignatureSize is the side length of the grayscale thumbnail used to
+// compare elements.
+const repeatSignatureSize = 16
+
+// RepeatCluster is a group of visually matching elements.
+type RepeatCluster struct {
+	// Instances are the bounding boxes of the matching elements in reading
+	// order (top to bottom, then left to right). X2/Y2 are exclusive.
+	Instances []Bounds `json:"instances"`
+
+	// Count is the number of instances.
+	Count int `json:"count"`
+
+	// Exemplar is the instance most similar to all the others, suitable for
+	// cropping as the representative of the cluster.
+	Exemplar Bounds `json:"exemplar"`
+
+	// ExemplarIndex is the exemplar's index in Instances.
+	ExemplarIndex int `json:"exemplar_index"`
+
+	// Width and Height are the exemplar's dimensions in pixels.
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// Similarity is the mean similarity (0.0-1.0) of the other instances to
+	// the exemplar.
+	Similarity float64 `json:"similarity"`
+}
+
+// RepeatsResult contains the clusters of repeated elements found in an image.
+type RepeatsResult struct {
+	// Clusters are sorted by instance count (most first), then by element
+	// area (largest first).
+	Clusters []RepeatCluster `json:"clusters"`
+
+	// Count is the number of clusters.
+	Count int `json:"count"`
+
+	// ElementsAnalyzed is the number of candidate elements that were compared.
+	ElementsAnalyzed int `json:"elements_analyzed"`
+
+	// Background is the detected background color as hex (#RRGGBB).
+	Background string `json:"background"`
+}
+
+// repeatCandidate is a segmented element and its comparison signature.
+type repeatCandidate struct {
+	bounds    Bounds
+	signature []float64 // zero-mean, unit-norm; nil for flat elements
+	mean      float64
+}
+
+// FindRepeats discovers elements that appear more than once, such as list
+// rows, cards, or icons, and groups them into clusters.
+//
+// Parameters:
+//   - img: Source image to analyze.
+//   - minSize: Minimum width and height in pixels for an element.
+//   - mergeDistance: Foreground pixels closer than this many pixels are
+//     merged into one element (e.g. an icon and its caption). Use 0 to keep
+//     every connected shape separate.
+//   - minSimilarity: Minimum similarity (0.0-1.0) for two elements to match.
+//     Typical: 0.85-0.95.
+//
+// Returns:
+//   - *RepeatsResult: Clusters with two or more instances.
+//   - error: Non-nil if a parameter is out of range.
+//
+// # Algorithm
+//
+//  1. Segmentation: Pixels that differ from the most common color are
+//     foreground. The foreground is dilated by mergeDistance and split into
+//     8-connected components; each element's bounds are the tight bounds of
+//     its original foreground pixels.
+//  2. Signature: Each element is reduced to a 16x16 grayscale thumbnail,
+//     normalized to zero mean and unit length.
+//  3. Matching: Two elements match if their widths and heights agree within
+//     10% (plus 2px) and the correlation of their signatures is at least
+//     minSimilarity. Elements of a single flat color match if their gray
+//     levels are within 10.
+//  4. Clustering: Matches are joined transitively; the exemplar is the
+//     instance with the highest total similarity to the rest.
+//
+// # Limitations
+//
+//   - Elements that touch each other, or sit on a non-uniform background,
+//     are segmented as one element
+//   - Correlation ignores overall brightness, so the same shape in different
+//     colors may match
+//   - Rotated or scaled instances are not matched
+func FindRepeats(img image.Image, minSize, mergeDistance int, minSimilarity float64) (*RepeatsResult, error) {
+	if minSize < 1 {
+		return nil, fmt.Errorf("min_size must be at least 1, got %d", minSize)
+	}
+	if mergeDistance < 0 {
+		return nil, fmt.Errorf("merge_distance must not be negative, got %d", mergeDistance)
+	}
+	if minSimilarity <= 0 || minSimilarity > 1 {
+		return nil, fmt.Errorf("similarity must be in (0, 1], got %v", minSimilarity)
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	bg := mostCommonRGB(img)
+	fg := make([]bool, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			fg[y*width+x] = rgbDistance(rgb8(img, x+bounds.Min.X, y+bounds.Min.Y), bg) > repeatBackgroundThreshold
+		}
+	}
+
+	var candidates []repeatCandidate
+	for _, b := range segmentForeground(fg, width, height, mergeDistance) {
+		if b.X2-b.X1 < minSize || b.Y2-b.Y1 < minSize {
+			continue
+		}
+		candidates = append(candidates, repeatCandidate{bounds: b})
+	}
+	if len(candidates) > maxRepeatCandidates {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return boundsArea(candidates[i].bounds) > boundsArea(candidates[j].bounds)
+		})
+		candidates = candidates[:maxRepeatCandidates]
+	}
+	gray := NewIntegralImage(width, height, func(x, y int) int64 {
+		return int64(grayValue(img, x+bounds.Min.X, y+bounds.Min.Y))
+	})
+	for i := range candidates {
+		candidates[i].signature, candidates[i].mean = repeatSignature(gray, candidates[i].bounds)
+		b := &candidates[i].bounds
+		b.X1 += bounds.Min.X
+		b.X2 += bounds.Min.X
+		b.Y1 += bounds.Min.Y
+		b.Y2 += bounds.Min.Y
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i].bounds, candidates[j].bounds
+		if a.Y1 != b.Y1 {
+			return a.Y1 < b.Y1
+		}
+		return a.X1 < b.X1
+	})
+
+	// Pairwise similarity, joined with union-find
+	n := len(candidates)
+	parent := make([]int, n)
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			if s, ok := repeatSimilarity(candidates[i], candidates[j]); ok && s >= minSimilarity {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+
+	groups := make(map[int][]int)
+	var roots []int
+	for i := 0; i < n; i++ {
+		r := find(i)
+		if _, seen := groups[r]; !seen {
+			roots = append(roots, r)
+		}
+		groups[r] = append(groups[r], i)
+	}
+
+	clusters := make([]RepeatCluster, 0)
+	for _, r := range roots {
+		members := groups[r]
+		if len(members) < 2 {
+			continue
+		}
+
+		best, bestTotal := 0, -1.0
+		for k, i := range members {
+			total := 0.0
+			for _, j := range members {
+				if i != j {
+					s, _ := repeatSimilarity(candidates[i], candidates[j])
+					total += s
+				}
+			}
+			if total > bestTotal {
+				best, bestTotal = k, total
+			}
+		}
+
+		c := RepeatCluster{
+			Instances:     make([]Bounds, len(members)),
+			Count:         len(members),
+			ExemplarIndex: best,
+			Similarity:    math.Round(bestTotal/float64(len(members)-1)*1000) / 1000,
+		}
+		for k, i := range members {
+			c.Instances[k] = candidates[i].bounds
+		}
+		c.Exemplar = c.Instances[best]
+		c.Width = c.Exemplar.X2 - c.Exemplar.X1
+		c.Height = c.Exemplar.Y2 - c.Exemplar.Y1
+		clusters = append(clusters, c)
+	}
+
+	sort.SliceStable(clusters, func(i, j int) bool {
+		if clusters[i].Count != clusters[j].Count {
+			return clusters[i].Count > clusters[j].Count
+		}
+		return boundsArea(clusters[i].Exemplar) > boundsArea(clusters[j].Exemplar)
+	})
+
+	return &RepeatsResult{
+		Clusters:         clusters,
+		Count:            len(clusters),
+		ElementsAnalyzed: n,
+		Background:       fmt.Sprintf("#%02X%02X%02X", bg[0], bg[1], bg[2]),
+	}, nil
+}
+
+// mostCommonRGB returns the most frequent 8-bit RGB color in the image.
+func mostCommonRGB(img image.Image) [3]uint8 {
+	b := img.Bounds()
+	counts := make(map[[3]uint8]int)
+	var best [3]uint8
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := rgb8(img, x, y)
+			counts[c]++
+			if counts[c] > counts[best] {
+				best = c
+			}
+		}
+	}
+	return best
+}
+
+// segmentForeground dilates the foreground mask by dist pixels, labels its
+// 8-connected components, and returns the tight bounds of the original
+// foreground pixels in each component (X2/Y2 exclusive, image-relative).
+func segmentForeground(fg []bool, width, height, dist int) []Bounds {
+	mask := dilateMask(fg, width, height, dist)
+
+	labels := make([]bool, width*height)
+	var result []Bounds
+	stack := make([]int, 0, 64)
+	for start := range mask {
+		if !mask[start] || labels[start] {
+			continue
+		}
+		// Dilation only grows from foreground, so every component has some
+		// original foreground pixel and the bounds below are always set
+		b := Bounds{X1: width, Y1: height}
+		labels[start] = true
+		stack = append(stack[:0], start)
+		for len(stack) > 0 {
+			p := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x, y := p%width, p/width
+			if fg[p] {
+				b.X1, b.Y1 = minInt(b.X1, x), minInt(b.Y1, y)
+				b.X2, b.Y2 = maxInt(b.X2, x+1), maxInt(b.Y2, y+1)
+			}
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := x+dx, y+dy
+					if nx < 0 || ny < 0 || nx >= width || ny >= height {
+						continue
+					}
+					q := ny*width + nx
+					if mask[q] && !labels[q] {
+						labels[q] = true
+						stack = append(stack, q)
+					}
+				}
+			}
+		}
+		result = append(result, b)
+	}
+	return result
+}
+
+// dilateMask grows the mask by dist pixels in every direction (a square
+// structuring element), using separable running counts.
+func dilateMask(mask []bool, width, height, dist int) []bool {
+	if dist == 0 {
+		return mask
+	}
+	horiz := make([]bool, len(mask))
+	for y := 0; y < height; y++ {
+		row := y * width
+		count := 0
+		for x := 0; x < minInt(dist, width); x++ {
+			if mask[row+x] {
+				count++
+			}
+		}
+		for x := 0; x < width; x++ {
+			if x+dist < width && mask[row+x+dist] {
+				count++
+			}
+			if x-dist-1 >= 0 && mask[row+x-dist-1] {
+				count--
+			}
+			horiz[row+x] = count > 0
+		}
+	}
+
+	out := make([]bool, len(mask))
+	for x := 0; x < width; x++ {
+		count := 0
+		for y := 0; y < minInt(dist, height); y++ {
+			if horiz[y*width+x] {
+				count++
+			}
+		}
+		for y := 0; y < height; y++ {
+			if y+dist < height && horiz[(y+dist)*width+x] {
+				count++
+			}
+			if y-dist-1 >= 0 && horiz[(y-dist-1)*width+x] {
+				count--
+			}
+			out[y*width+x] = count > 0
+		}
+	}
+	return out
+}
+
+// repeatSignature reduces the element at b (image-relative) to an
+// area-averaged grayscale thumbnail, normalized to zero mean and unit length,
+// reading cell averages from the image's gray integral. It returns a nil
+// signature for elements of a single flat tone, along with the mean gray
+// level.
+func repeatSignature(gray *IntegralImage, b Bounds) ([]float64, float64) {
+	const n = repeatSignatureSize
+	w, h := b.X2-b.X1, b.Y2-b.Y1
+
+	// Each cell covers at least one pixel, so small elements repeat pixels
+	cellRange := func(c, size int) (int, int) {
+		lo := c * size / n
+		return lo, maxInt(lo+1, (c+1)*size/n)
+	}
+
+	sig := make([]float64, n*n)
+	mean := 0.0
+	for cy := 0; cy < n; cy++ {
+		y0, y1 := cellRange(cy, h)
+		for cx := 0; cx < n; cx++ {
+			x0, x1 := cellRange(cx, w)
+			v := gray.Mean(b.X1+x0, b.Y1+y0, b.X1+x1, b.Y1+y1)
+			sig[cy*n+cx] = v
+			mean += v
+		}
+	}
+	mean /= n * n
+
+	norm := 0.0
+	for i := range sig {
+		sig[i] -= mean
+		norm += sig[i] * sig[i]
+	}
+	norm = math.Sqrt(norm)
+	if norm < 1 {
+		return nil, mean
+	}
+	for i := range sig {
+		sig[i] /= norm
+	}
+	return sig, mean
+}
+
+// repeatSimilarity compares two candidates. The second return value is false
+// if their sizes are too different to compare.
+func repeatSimilarity(a, b repeatCandidate) (float64, bool) {
+	wa, ha := a.bounds.X2-a.bounds.X1, a.bounds.Y2-a.bounds.Y1
+	wb, hb := b.bounds.X2-b.bounds.X1, b.bounds.Y2-b.bounds.Y1
+	sizeClose := func(p, q int) bool {
+		d := p - q
+		if d < 0 {
+			d = -d
+		}
+		return float64(d) <= 0.1*float64(maxInt(p, q))+2
+	}
+	if !sizeClose(wa, wb) || !sizeClose(ha, hb) {
+		return 0, false
+	}
+
+	switch {
+	case a.signature == nil && b.signature == nil:
+		if math.Abs(a.mean-b.mean) <= 10 {
+			return 1, true
+		}
+		return 0, true
+	case a.signature == nil || b.signature == nil:
+		return 0, true
+	}
+
+	dot := 0.0
+	for i := range a.signature {
+		dot += a.signature[i] * b.signature[i]
+	}
+	return math.Max(0, math.Min(1, dot)), true
+}
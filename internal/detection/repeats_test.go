@@ -0,0 +1,117 @@
+package detection
+
+import (
+	"image/color"
+	"testing"
+)
+
+// drawListRow draws a list row: a square icon followed by a text bar.
+func drawListRow(img interface{ Set(x, y int, c color.Color) }, x, y int) {
+	for dy := 0; dy < 12; dy++ {
+		for dx := 0; dx < 12; dx++ {
+			img.Set(x+dx, y+dy, color.RGBA{0, 120, 255, 255})
+		}
+	}
+	for dy := 3; dy < 9; dy++ {
+		for dx := 16; dx < 70; dx++ {
+			img.Set(x+dx, y+dy, color.RGBA{40, 40, 40, 255})
+		}
+	}
+}
+
+func TestFindRepeats_ListRows(t *testing.T) {
+	img := createTestImage(200, 160, color.White)
+	for i := 0; i < 4; i++ {
+		drawListRow(img, 10, 10+i*25)
+	}
+	// A one-off element that should not form a cluster
+	for y := 120; y < 150; y++ {
+		for x := 120; x < 190; x++ {
+			if x == 120 || y == 120 || x == 189 || y == 149 || x == y+10 {
+				img.Set(x, y, color.Black)
+			}
+		}
+	}
+
+	result, err := FindRepeats(img, 8, 6, 0.9)
+	if err != nil {
+		t.Fatalf("FindRepeats failed: %v", err)
+	}
+
+	if result.Count != 1 {
+		t.Fatalf("Count: got %d, want 1 (clusters %+v)", result.Count, result.Clusters)
+	}
+	c := result.Clusters[0]
+	if c.Count != 4 {
+		t.Fatalf("instances: got %d, want 4", c.Count)
+	}
+	// The icon and text bar are merged into one 70x12 row
+	if c.Width != 70 || c.Height != 12 {
+		t.Errorf("exemplar size: got %dx%d, want 70x12", c.Width, c.Height)
+	}
+	for i, b := range c.Instances {
+		want := Bounds{X1: 10, Y1: 10 + i*25, X2: 80, Y2: 22 + i*25}
+		if b != want {
+			t.Errorf("instance %d: got %+v, want %+v", i, b, want)
+		}
+	}
+	if c.Exemplar != c.Instances[c.ExemplarIndex] {
+		t.Errorf("exemplar %+v does not match instance %d", c.Exemplar, c.ExemplarIndex)
+	}
+	if c.Similarity < 0.99 {
+		t.Errorf("Similarity: got %v, want ~1", c.Similarity)
+	}
+	if result.Background != "#FFFFFF" {
+		t.Errorf("Background: got %s, want #FFFFFF", result.Background)
+	}
+}
+
+func TestFindRepeats_NoMerge(t *testing.T) {
+	img := createTestImage(200, 100, color.White)
+	for i := 0; i < 3; i++ {
+		drawListRow(img, 10, 10+i*25)
+	}
+
+	// Without merging, icons and text bars form separate clusters
+	result, err := FindRepeats(img, 4, 0, 0.9)
+	if err != nil {
+		t.Fatalf("FindRepeats failed: %v", err)
+	}
+	if result.Count != 2 || result.ElementsAnalyzed != 6 {
+		t.Fatalf("got %d clusters from %d elements, want 2 from 6", result.Count, result.ElementsAnalyzed)
+	}
+	// Equal counts sort by area: text bars (54x6) before icons (12x12)
+	if result.Clusters[0].Width != 54 || result.Clusters[1].Width != 12 {
+		t.Errorf("cluster widths: got %d and %d, want 54 and 12",
+			result.Clusters[0].Width, result.Clusters[1].Width)
+	}
+}
+
+func TestFindRepeats_Errors(t *testing.T) {
+	img := createTestImage(10, 10, color.White)
+
+	if _, err := FindRepeats(img, 0, 0, 0.9); err == nil {
+		t.Error("FindRepeats should fail with min size 0")
+	}
+	if _, err := FindRepeats(img, 4, -1, 0.9); err == nil {
+		t.Error("FindRepeats should fail with negative merge distance")
+	}
+	if _, err := FindRepeats(img, 4, 0, 1.5); err == nil {
+		t.Error("FindRepeats should fail with similarity above 1")
+	}
+}
+
+func TestDilateMask(t *testing.T) {
+	mask := make([]bool, 5*5)
+	mask[2*5+2] = true
+
+	out := dilateMask(mask, 5, 5, 1)
+	for y := 0; y < 5; y++ {
+		for x := 0; x < 5; x++ {
+			want := x >= 1 && x <= 3 && y >= 1 && y <= 3
+			if out[y*5+x] != want {
+				t.Errorf("(%d,%d): got %v, want %v", x, y, out[y*5+x], want)
+			}
+		}
+	}
+}
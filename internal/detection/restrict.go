@@ -0,0 +1,41 @@
+package detection
+
+import "image"
+
+// inMask reports whether mask selects the pixel at (x, y).
+func inMask(mask *image.Alpha, x, y int) bool {
+	return mask.AlphaAt(x, y).A != 0
+}
+
+// Restrict drops the rectangles whose center is not selected by mask.
+func (r *RectanglesResult) Restrict(mask *image.Alpha) {
+	kept := r.Rectangles[:0]
+	for _, rect := range r.Rectangles {
+		if inMask(mask, rect.Center.X, rect.Center.Y) {
+			kept = append(kept, rect)
+		}
+	}
+	r.Rectangles, r.Count = kept, len(kept)
+}
+
+// Restrict drops the circles whose center is not selected by mask.
+func (r *CirclesResult) Restrict(mask *image.Alpha) {
+	kept := r.Circles[:0]
+	for _, c := range r.Circles {
+		if inMask(mask, c.Center.X, c.Center.Y) {
+			kept = append(kept, c)
+		}
+	}
+	r.Circles, r.Count = kept, len(kept)
+}
+
+// Restrict drops the lines whose midpoint is not selected by mask.
+func (r *LinesResult) Restrict(mask *image.Alpha) {
+	kept := r.Lines[:0]
+	for _, l := range r.Lines {
+		if inMask(mask, (l.Start.X+l.End.X)/2, (l.Start.Y+l.End.Y)/2) {
+			kept = append(kept, l)
+		}
+	}
+	r.Lines, r.Count = kept, len(kept)
+}
@@ -0,0 +1,44 @@
+package detection
+
+import (
+	"image"
+	"testing"
+)
+
+func TestRestrict(t *testing.T) {
+	// Selects the left half of a 100x100 image
+	mask := image.NewAlpha(image.Rect(0, 0, 100, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 50; x++ {
+			mask.Pix[mask.PixOffset(x, y)] = 255
+		}
+	}
+
+	rects := &RectanglesResult{Count: 2, Rectangles: []Rectangle{
+		{Center: Point{X: 20, Y: 50}},
+		{Center: Point{X: 80, Y: 50}},
+	}}
+	rects.Restrict(mask)
+	if rects.Count != 1 || rects.Rectangles[0].Center.X != 20 {
+		t.Errorf("rectangles: got %+v", rects)
+	}
+
+	circles := &CirclesResult{Count: 2, Circles: []Circle{
+		{Center: Point{X: 70, Y: 10}},
+		{Center: Point{X: 10, Y: 10}},
+	}}
+	circles.Restrict(mask)
+	if circles.Count != 1 || circles.Circles[0].Center.X != 10 {
+		t.Errorf("circles: got %+v", circles)
+	}
+
+	// A line crossing into the mask is kept only if its midpoint is inside
+	lines := &LinesResult{Count: 2, Lines: []Line{
+		{Start: Point{X: 0, Y: 5}, End: Point{X: 90, Y: 5}},
+		{Start: Point{X: 30, Y: 5}, End: Point{X: 99, Y: 5}},
+	}}
+	lines.Restrict(mask)
+	if lines.Count != 1 || lines.Lines[0].Start.X != 0 {
+		t.Errorf("lines: got %+v", lines)
+	}
+}
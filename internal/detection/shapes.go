@@ -65,6 +65,18 @@ type RectanglesResult struct {
 
 	// Count is the number of rectangles detected.
 	Count int `json:"count"`
+
+	// Tuning describes the automatic parameter search, if one was run
+	// (see TuneRectangles, TuneLines, and TuneCircles).
+	Tuning *TuningReport `json:"tuning,omitempty"`
+
+	// Debug explains the run, if requested (see the Debug variants of
+	// the detectors).
+	Debug *DebugOverlay `json:"debug,omitempty"`
+
+	// Export holds the detections as a dataset annotation file, if
+	// requested (see ExportAnnotations).
+	Export *AnnotationExport `json:"export,omitempty"`
 }
 
 // DetectRectangles finds rectangular shapes in an image using edge and contour analysis.
@@ -88,16 +100,19 @@ type RectanglesResult struct {
 //  1. Edge Detection: Compute gradients and threshold to find edge pixels
 //  2. Contour Finding: Use flood-fill to group connected edge pixels
 //  3. Bounding Box: Calculate the bounding rectangle of each contour
-//  4. Rectangularity Check: Compare contour perimeter to expected rectangle
-//     perimeter. Score = 1 - |contour_length - expected_perimeter| / expected_perimeter
-//  5. Filtering: Remove shapes below minArea or with score < tolerance
+//  4. Rectangularity Check: Compare the contour to the bounding box's
+//     border (see below)
+//  5. Filtering: Remove shapes below minArea, narrower than 3 pixels
+//     (rules, which DetectLines reports), or with score < tolerance
 //  6. Color Sampling: Sample fill color at center, border color at corner
 //
 // # Rectangularity Score
 //
-// A perfect rectangle has a contour length exactly equal to 2*(width + height).
-// The rectangularity score measures deviation from this:
-//   - 1.0 = Perfect rectangle (contour matches perimeter exactly)
+// The score is the fraction of the bounding box's border that the contour
+// covers, times the fraction of contour pixels that lie on that border,
+// both measured within a 2-pixel band so that the two-pixel edge of a 1px
+// outline counts once:
+//   - 1.0 = Perfect rectangle (contour traces the whole border and nothing else)
 //   - Lower values indicate non-rectangular shapes (circles, irregular polygons)
 //
 // # Limitations
@@ -107,12 +122,36 @@ type RectanglesResult struct {
 //   - Rounded corners reduce rectangularity score
 //   - Very thin rectangles may have low confidence
 func DetectRectangles(img image.Image, minArea int, tolerance float64) (*RectanglesResult, error) {
+	return detectRectangles(img, minArea, tolerance, nil), nil
+}
+
+// DetectRectanglesDebug is DetectRectangles with a DebugOverlay attached
+// to the result, showing the edge map, the rectangles found, and the
+// contours rejected as not rectangular (red) or slightly too small
+// (orange, at least a quarter of minArea). Smaller contours are omitted.
+func DetectRectanglesDebug(img image.Image, minArea int, tolerance float64) (*RectanglesResult, error) {
+	trace := newDebugTrace(img)
+	result := detectRectangles(img, minArea, tolerance, trace)
+	trace.accept(result.Primitives())
+	overlay, err := trace.overlay()
+	if err != nil {
+		return nil, err
+	}
+	result.Debug = overlay
+	return result, nil
+}
+
+// detectRectangles implements DetectRectangles, recording its steps in
+// trace if non-nil.
+func detectRectangles(img image.Image, minArea int, tolerance float64, trace *debugTrace) *RectanglesResult {
 	bounds := img.Bounds()
 	width := bounds.Dx()
 	height := bounds.Dy()
 
 	// Convert to grayscale and detect edges
 	edges := detectEdges(img, width, height)
+	defer edges.release()
+	trace.edgeMap(edges)
 
 	// Find contours (connected components of edge pixels)
 	contours := findContours(edges, width, height)
@@ -121,81 +160,157 @@ func DetectRectangles(img image.Image, minArea int, tolerance float64) (*Rectang
 	rectangles := make([]Rectangle, 0)
 
 	for _, contour := range contours {
-		if len(contour) < 4 {
-			continue
+		if rect, ok := contourRectangle(img, contour, minArea, tolerance, trace); ok {
+			rectangles = append(rectangles, rect)
 		}
+	}
 
-		// Get bounding box of contour
-		minX, minY := width, height
-		maxX, maxY := 0, 0
-		for _, p := range contour {
-			if p.X < minX {
-				minX = p.X
-			}
-			if p.X > maxX {
-				maxX = p.X
-			}
-			if p.Y < minY {
-				minY = p.Y
-			}
-			if p.Y > maxY {
-				maxY = p.Y
-			}
-		}
+	// Sort by area descending
+	sort.Slice(rectangles, func(i, j int) bool {
+		return rectangles[i].Area > rectangles[j].Area
+	})
 
-		rectWidth := maxX - minX
-		rectHeight := maxY - minY
-		area := rectWidth * rectHeight
+	return &RectanglesResult{
+		Rectangles: rectangles,
+		Count:      len(rectangles),
+	}
+}
 
-		if area < minArea {
-			continue
+// contourRectangle analyzes one contour as a candidate rectangle. It
+// returns false if the contour's bounding box is smaller than minArea or
+// its rectangularity is below tolerance, recording why in trace if
+// non-nil. Contour points are relative to img.Bounds().Min.
+func contourRectangle(img image.Image, contour []Point, minArea int, tolerance float64, trace *debugTrace) (Rectangle, bool) {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+
+	if len(contour) < 4 {
+		return Rectangle{}, false
+	}
+
+	// Get bounding box of contour
+	minX, minY := width, height
+	maxX, maxY := 0, 0
+	for _, p := range contour {
+		if p.X < minX {
+			minX = p.X
+		}
+		if p.X > maxX {
+			maxX = p.X
+		}
+		if p.Y < minY {
+			minY = p.Y
+		}
+		if p.Y > maxY {
+			maxY = p.Y
 		}
+	}
 
-		// Calculate how rectangular the shape is
-		contourArea := len(contour)
-		expectedPerimeter := 2 * (rectWidth + rectHeight)
-		rectangularity := 1.0 - math.Abs(float64(contourArea-expectedPerimeter))/float64(expectedPerimeter)
+	rectWidth := maxX - minX
+	rectHeight := maxY - minY
+	area := rectWidth * rectHeight
 
-		if rectangularity < tolerance {
-			continue
+	box := func() Primitive {
+		return Primitive{Type: PrimitiveRect, Bounds: &Bounds{
+			X1: minX + bounds.Min.X, Y1: minY + bounds.Min.Y,
+			X2: maxX + bounds.Min.X, Y2: maxY + bounds.Min.Y,
+		}}
+	}
+
+	if area < minArea {
+		if trace != nil && area*4 >= minArea {
+			trace.reject(box(), true, fmt.Sprintf("area %d below min_area %d", area, minArea))
 		}
+		return Rectangle{}, false
+	}
 
-		// Sample colors
-		centerX := (minX + maxX) / 2
-		centerY := (minY + maxY) / 2
-
-		fillColor := sampleColorHex(img, centerX, centerY)
-		borderColor := sampleColorHex(img, minX, minY)
-
-		rectangles = append(rectangles, Rectangle{
-			Bounds: Bounds{
-				X1: minX + bounds.Min.X,
-				Y1: minY + bounds.Min.Y,
-				X2: maxX + bounds.Min.X,
-				Y2: maxY + bounds.Min.Y,
-			},
-			Center: Point{
-				X: centerX + bounds.Min.X,
-				Y: centerY + bounds.Min.Y,
-			},
-			Width:       rectWidth,
-			Height:      rectHeight,
-			Area:        area,
-			FillColor:   fillColor,
-			BorderColor: borderColor,
-			Confidence:  rectangularity,
-		})
+	if rectWidth < minRectSide || rectHeight < minRectSide {
+		if trace != nil {
+			trace.reject(box(), false, fmt.Sprintf("%dx%d is a rule, not a rectangle", rectWidth, rectHeight))
+		}
+		return Rectangle{}, false
 	}
 
-	// Sort by area descending
-	sort.Slice(rectangles, func(i, j int) bool {
-		return rectangles[i].Area > rectangles[j].Area
-	})
+	// Calculate how rectangular the shape is
+	rectangularity := borderFit(contour, minX, minY, maxX, maxY)
 
-	return &RectanglesResult{
-		Rectangles: rectangles,
-		Count:      len(rectangles),
-	}, nil
+	if rectangularity < tolerance {
+		if trace != nil {
+			trace.reject(box(), false, fmt.Sprintf("rectangularity %.2f below tolerance %.2f", rectangularity, tolerance))
+		}
+		return Rectangle{}, false
+	}
+
+	// Sample colors
+	centerX := (minX + maxX) / 2
+	centerY := (minY + maxY) / 2
+
+	fillColor := sampleColorHex(img, centerX, centerY)
+	borderColor := sampleColorHex(img, minX, minY)
+
+	return Rectangle{
+		Bounds: Bounds{
+			X1: minX + bounds.Min.X,
+			Y1: minY + bounds.Min.Y,
+			X2: maxX + bounds.Min.X,
+			Y2: maxY + bounds.Min.Y,
+		},
+		Center: Point{
+			X: centerX + bounds.Min.X,
+			Y: centerY + bounds.Min.Y,
+		},
+		Width:       rectWidth,
+		Height:      rectHeight,
+		Area:        area,
+		FillColor:   fillColor,
+		BorderColor: borderColor,
+		Confidence:  rectangularity,
+	}, true
+}
+
+// minRectSide is the smallest bounding box side, in pixels, of a
+// rectangle. A 1px rule's edges span two pixels.
+const minRectSide = 3
+
+// borderFit scores how well a contour traces the border of its bounding
+// box: the fraction of border positions (columns along the top and bottom,
+// rows along the sides) with a contour pixel within 2 pixels inside the
+// border, times the fraction of contour pixels that lie that close to it.
+func borderFit(contour []Point, minX, minY, maxX, maxY int) float64 {
+	w, h := maxX-minX+1, maxY-minY+1
+	top, bottom := make([]bool, w), make([]bool, w)
+	left, right := make([]bool, h), make([]bool, h)
+	onBorder := 0
+	for _, p := range contour {
+		x, y := p.X-minX, p.Y-minY
+		near := false
+		if y <= 1 {
+			top[x], near = true, true
+		}
+		if y >= h-2 {
+			bottom[x], near = true, true
+		}
+		if x <= 1 {
+			left[y], near = true, true
+		}
+		if x >= w-2 {
+			right[y], near = true, true
+		}
+		if near {
+			onBorder++
+		}
+	}
+
+	covered := 0
+	for _, side := range [][]bool{top, bottom, left, right} {
+		for _, c := range side {
+			if c {
+				covered++
+			}
+		}
+	}
+	return float64(covered) / float64(2*(w+h)) * float64(onBorder) / float64(len(contour))
 }
 
 // Circle represents a detected circular shape with metadata.
@@ -227,6 +342,18 @@ type CirclesResult struct {
 
 	// Count is the number of circles detected.
 	Count int `json:"count"`
+
+	// Tuning describes the automatic parameter search, if one was run
+	// (see TuneRectangles, TuneLines, and TuneCircles).
+	Tuning *TuningReport `json:"tuning,omitempty"`
+
+	// Debug explains the run, if requested (see the Debug variants of
+	// the detectors).
+	Debug *DebugOverlay `json:"debug,omitempty"`
+
+	// Export holds the detections as a dataset annotation file, if
+	// requested (see ExportAnnotations).
+	Export *AnnotationExport `json:"export,omitempty"`
 }
 
 // DetectCircles finds circular shapes in an image using the Hough circle transform.
@@ -252,19 +379,19 @@ type CirclesResult struct {
 //     - For each edge pixel, vote for potential centers by drawing a
 //     voting circle around the pixel
 //     - Votes are cast every 10° around the edge pixel
-//  3. Peak Detection: Find local maxima in the accumulator that exceed
-//     threshold (60% of expected circumference points)
-//  4. Duplicate Removal: Merge circles with overlapping centers
+//  3. Peak Detection: Find local maxima in the accumulator, and check 36
+//     points evenly spaced around each for an edge pixel within a pixel
+//     of the circumference; at least 27 (75%) must have one
+//  4. Duplicate Removal: Merge circles with overlapping centers, keeping
+//     the best scored
 //  5. Color Sampling: Sample fill color at detected center
 //
 // # Confidence Score
 //
-// Confidence is calculated as: votes / (2 × radius)
-//
-// This represents the fraction of the circumference where edge pixels voted
-// for this center. Capped at 1.0.
-//   - 1.0 = Every expected edge point voted for this center
-//   - 0.6 = Threshold for detection (sparse but detectable circle)
+// Confidence is the fraction of the 36 points around the circumference
+// with an edge pixel:
+//   - 1.0 = Edge pixels all the way around
+//   - 0.75 = Threshold for detection (a circle with gaps)
 //
 // # Performance
 //
@@ -278,45 +405,115 @@ type CirclesResult struct {
 //   - Ellipses are not detected (only true circles)
 //   - Large maxRadius values slow detection significantly
 func DetectCircles(img image.Image, minRadius, maxRadius int) (*CirclesResult, error) {
+	return detectCircles(img, minRadius, maxRadius, nil), nil
+}
+
+// DetectCirclesDebug is DetectCircles with a DebugOverlay attached to the
+// result, showing the edge map, the accumulator peaks, the circles found,
+// and the peaks rejected as duplicates of a stronger circle (red) or with
+// 30-60% of the votes needed (orange).
+func DetectCirclesDebug(img image.Image, minRadius, maxRadius int) (*CirclesResult, error) {
+	trace := newDebugTrace(img)
+	result := detectCircles(img, minRadius, maxRadius, trace)
+	trace.accept(result.Primitives())
+	overlay, err := trace.overlay()
+	if err != nil {
+		return nil, err
+	}
+	result.Debug = overlay
+	return result, nil
+}
+
+// detectCircles implements DetectCircles, recording its steps in trace if
+// non-nil.
+func detectCircles(img image.Image, minRadius, maxRadius int, trace *debugTrace) *CirclesResult {
 	bounds := img.Bounds()
 	width := bounds.Dx()
 	height := bounds.Dy()
 
 	// Detect edges
 	edges := detectEdges(img, width, height)
+	defer edges.release()
+	trace.edgeMap(edges)
+
+	// Find candidate circles, with OpenCV when available
+	circles, ok := acceleratedCircles(img, edges, minRadius, maxRadius)
+	if !ok {
+		circles = houghCircles(img, edges, minRadius, maxRadius, trace)
+	}
+
+	// Remove duplicate detections (circles with very close centers),
+	// keeping the best scored, and of those the largest, since a ring's
+	// inner edge scores as well as its outer one
+	sort.SliceStable(circles, func(i, j int) bool {
+		if circles[i].Confidence != circles[j].Confidence {
+			return circles[i].Confidence > circles[j].Confidence
+		}
+		return circles[i].Radius > circles[j].Radius
+	})
+	filtered := filterDuplicateCircles(circles)
+	if trace != nil {
+		kept := make(map[Circle]bool, len(filtered))
+		for _, c := range filtered {
+			kept[c] = true
+		}
+		for _, c := range circles {
+			trace.peak(c.Center)
+			if !kept[c] {
+				center := c.Center
+				trace.reject(Primitive{Type: PrimitiveCircle, Center: &center, Radius: c.Radius}, false,
+					"duplicate of a stronger circle with a nearby center")
+			}
+		}
+	}
+
+	return &CirclesResult{
+		Circles: filtered,
+		Count:   len(filtered),
+	}
+}
+
+// houghCircles finds circle candidates by voting each edge pixel into a
+// center accumulator, one radius at a time, and keeps the local maxima
+// with edge pixels around three quarters of their circumference (see
+// circleVotes). If trace is non-nil, local maxima with half as many are
+// recorded there as near misses.
+func houghCircles(img image.Image, edges *bitGrid, minRadius, maxRadius int, trace *debugTrace) []Circle {
+	bounds := img.Bounds()
+	width, height := edges.width, edges.height
 
 	// Simple circle detection using accumulator
 	circles := make([]Circle, 0)
 
-	// For each radius, accumulate votes
+	// For each radius, accumulate votes into a row-major width×height
+	// buffer. One pooled buffer is reused across radii
+	accumulator := int32Pool.get(width * height)
+	defer int32Pool.put(accumulator)
 	for radius := minRadius; radius <= maxRadius; radius++ {
-		accumulator := make([][]int, height)
-		for y := 0; y < height; y++ {
-			accumulator[y] = make([]int, width)
-		}
+		clear(accumulator)
 
 		// Vote for circle centers
 		for y := 0; y < height; y++ {
 			for x := 0; x < width; x++ {
-				if edges[y][x] {
+				if edges.Get(x, y) {
 					// Vote in a circle around this edge point
 					for angle := 0; angle < 360; angle += 10 {
 						rad := float64(angle) * math.Pi / 180
 						cx := x - int(float64(radius)*math.Cos(rad))
 						cy := y - int(float64(radius)*math.Sin(rad))
 						if cx >= 0 && cx < width && cy >= 0 && cy < height {
-							accumulator[cy][cx]++
+							accumulator[cy*width+cx]++
 						}
 					}
 				}
 			}
 		}
 
-		// Find local maxima in accumulator
-		threshold := int(float64(2*radius) * 0.6) // Require ~60% of circumference
+		// Local maxima in the accumulator are candidates, scored by how
+		// much of their circumference has edge pixels
 		for y := radius; y < height-radius; y++ {
 			for x := radius; x < width-radius; x++ {
-				if accumulator[y][x] >= threshold {
+				if int(accumulator[y*width+x]) >= circleMinCandidateVotes {
 					// Check if local maximum
 					isMax := true
 					for dy := -5; dy <= 5 && isMax; dy++ {
@@ -326,15 +523,26 @@ func DetectCircles(img image.Image, minRadius, maxRadius int) (*CirclesResult, e
 							}
 							ny, nx := y+dy, x+dx
 							if ny >= 0 && ny < height && nx >= 0 && nx < width {
-								if accumulator[ny][nx] > accumulator[y][x] {
+								if accumulator[ny*width+nx] > accumulator[y*width+x] {
 									isMax = false
 								}
 							}
 						}
 					}
 
-					if isMax {
-						confidence := float64(accumulator[y][x]) / float64(2*radius)
+					if !isMax {
+						continue
+					}
+					votes := circleVotes(edges, x, y, radius)
+					if votes < circleMinVotes {
+						if trace != nil && 2*votes >= circleMinVotes {
+							center := Point{X: x + bounds.Min.X, Y: y + bounds.Min.Y}
+							trace.peak(center)
+							trace.reject(Primitive{Type: PrimitiveCircle, Center: &center, Radius: radius}, true,
+								fmt.Sprintf("%d votes, %d needed", votes, circleMinVotes))
+						}
+					} else {
+						confidence := float64(votes) / circleSamples
 						fillColor := sampleColorHex(img, x, y)
 
 						circles = append(circles, Circle{
@@ -353,51 +561,95 @@ func DetectCircles(img image.Image, minRadius, maxRadius int) (*CirclesResult, e
 		}
 	}
 
-	// Remove duplicate detections (circles with very close centers)
-	filtered := filterDuplicateCircles(circles)
+	return circles
+}
 
-	// Sort by confidence descending
-	sort.Slice(filtered, func(i, j int) bool {
-		return filtered[i].Confidence > filtered[j].Confidence
-	})
+// Circle scoring: candidates are checked at circleSamples points around
+// their circumference.
+const (
+	circleSamples = 36
 
-	return &CirclesResult{
-		Circles: filtered,
-		Count:   len(filtered),
-	}, nil
+	// circleMinVotes is how many of the points a circle needs edge pixels
+	// at, three quarters of its circumference.
+	circleMinVotes = 27
+
+	// circleMinCandidateVotes is the least accumulator count for which a
+	// center is checked at all.
+	circleMinCandidateVotes = 9
+)
+
+// circleVotes scores the circle around (cx, cy) with the given radius:
+// the number of its circleSamples evenly spaced points with an edge pixel
+// on the circle or a pixel inside or outside it. Unless circleMinVotes of
+// the points also have no edge pixels 2 and 3 pixels further out, the
+// circle does not stand apart from its surroundings, as a loop of text
+// does not, and scores 0.
+func circleVotes(edges *bitGrid, cx, cy, radius int) int {
+	edgeAt := func(r, rad float64) bool {
+		x := cx + int(math.Round(r*math.Cos(rad)))
+		y := cy + int(math.Round(r*math.Sin(rad)))
+		return x >= 0 && x < edges.width && y >= 0 && y < edges.height && edges.Get(x, y)
+	}
+	votes, clear := 0, 0
+	for i := 0; i < circleSamples; i++ {
+		rad := float64(i) * 2 * math.Pi / circleSamples
+		r := float64(radius)
+		if edgeAt(r, rad) || edgeAt(r-1, rad) || edgeAt(r+1, rad) {
+			votes++
+		}
+		if !edgeAt(r+2, rad) && !edgeAt(r+3, rad) {
+			clear++
+		}
+	}
+	if clear < circleMinVotes {
+		return 0
+	}
+	return votes
 }
 
+// edgeThreshold is the grayscale step between neighboring pixels above
+// which detectEdges marks an edge.
+const edgeThreshold = 30.0
+
 // detectEdges performs simple gradient-based edge detection.
 //
 // Uses a simple gradient threshold: pixels where |current - neighbor| > 30
 // (in grayscale) are marked as edges. Checks both horizontal and vertical
 // neighbors.
 //
-// Returns a 2D boolean array where true indicates an edge pixel.
+// Returns a packed bit grid where set cells are edge pixels. The grid is
+// pooled; call release when done.
 // Border pixels (x=0, y=0, x=width-1, y=height-1) are never edges.
-func detectEdges(img image.Image, width, height int) [][]bool {
+func detectEdges(img image.Image, width, height int) *bitGrid {
 	bounds := img.Bounds()
-	edges := make([][]bool, height)
-	threshold := 30.0
-
-	for y := 0; y < height; y++ {
-		edges[y] = make([]bool, width)
-		for x := 0; x < width; x++ {
-			if x == 0 || y == 0 || x == width-1 || y == height-1 {
-				continue
-			}
+	edges := newBitGrid(width, height)
+	if width < 3 || height < 3 {
+		return edges
+	}
 
-			// Get grayscale values
-			c := grayValue(img, x+bounds.Min.X, y+bounds.Min.Y)
-			cx := grayValue(img, x+1+bounds.Min.X, y+bounds.Min.Y)
-			cy := grayValue(img, x+bounds.Min.X, y+1+bounds.Min.Y)
+	// Keep grayscale values for the current row and the one below it, so
+	// each pixel is read once
+	row := uint8Pool.get(width)
+	below := uint8Pool.get(width)
+	defer uint8Pool.put(row)
+	defer uint8Pool.put(below)
+	readRow := func(dst []uint8, y int) {
+		for x := range dst {
+			dst[x] = grayValue(img, x+bounds.Min.X, y+bounds.Min.Y)
+		}
+	}
 
+	readRow(below, 1)
+	for y := 1; y < height-1; y++ {
+		row, below = below, row
+		readRow(below, y+1)
+		for x := 1; x < width-1; x++ {
 			// Simple gradient
-			dx := math.Abs(float64(c) - float64(cx))
-			dy := math.Abs(float64(c) - float64(cy))
+			dx := math.Abs(float64(row[x]) - float64(row[x+1]))
+			dy := math.Abs(float64(row[x]) - float64(below[x]))
 
-			if dx > threshold || dy > threshold {
-				edges[y][x] = true
+			if dx > edgeThreshold || dy > edgeThreshold {
+				edges.Set(x, y)
 			}
 		}
 	}
@@ -412,17 +664,15 @@ func detectEdges(img image.Image, width, height int) [][]bool {
 //
 // Contours smaller than 10 pixels are discarded as noise.
 // Returns a slice of contours, where each contour is a slice of Points.
-func findContours(edges [][]bool, width, height int) [][]Point {
-	visited := make([][]bool, height)
-	for y := 0; y < height; y++ {
-		visited[y] = make([]bool, width)
-	}
+func findContours(edges *bitGrid, width, height int) [][]Point {
+	visited := newBitGrid(width, height)
+	defer visited.release()
 
 	contours := make([][]Point, 0)
 
 	for y := 0; y < height; y++ {
 		for x := 0; x < width; x++ {
-			if edges[y][x] && !visited[y][x] {
+			if edges.Get(x, y) && !visited.Get(x, y) {
 				contour := make([]Point, 0)
 				floodFill(edges, visited, x, y, width, height, &contour)
 				if len(contour) >= 10 { // Minimum contour size
@@ -440,7 +690,7 @@ func findContours(edges [][]bool, width, height int) [][]Point {
 // Uses a stack-based approach (not recursive) to avoid stack overflow
 // on large contours. Marks visited pixels and appends them to the contour.
 // Uses 8-connectivity (includes diagonal neighbors).
-func floodFill(edges, visited [][]bool, startX, startY, width, height int, contour *[]Point) {
+func floodFill(edges, visited *bitGrid, startX, startY, width, height int, contour *[]Point) {
 	stack := []Point{{X: startX, Y: startY}}
 
 	for len(stack) > 0 {
@@ -450,11 +700,11 @@ func floodFill(edges, visited [][]bool, startX, startY, width, height int, conto
 		if p.X < 0 || p.X >= width || p.Y < 0 || p.Y >= height {
 			continue
 		}
-		if visited[p.Y][p.X] || !edges[p.Y][p.X] {
+		if visited.Get(p.X, p.Y) || !edges.Get(p.X, p.Y) {
 			continue
 		}
 
-		visited[p.Y][p.X] = true
+		visited.Set(p.X, p.Y)
 		*contour = append(*contour, p)
 
 		// 8-connected neighbors
@@ -176,7 +176,7 @@ func TestDetectEdges(t *testing.T) {
 	edgeFound := false
 	for y := 1; y < 49; y++ {
 		for x := 23; x <= 26; x++ {
-			if edges[y][x] {
+			if edges.Get(x, y) {
 				edgeFound = true
 				break
 			}
@@ -197,7 +197,7 @@ func TestDetectEdges_UniformImage(t *testing.T) {
 	edgeCount := 0
 	for y := 0; y < 50; y++ {
 		for x := 0; x < 50; x++ {
-			if edges[y][x] {
+			if edges.Get(x, y) {
 				edgeCount++
 			}
 		}
@@ -210,19 +210,16 @@ func TestDetectEdges_UniformImage(t *testing.T) {
 
 func TestFindContours(t *testing.T) {
 	// Create a simple edge pattern
-	edges := make([][]bool, 20)
-	for y := 0; y < 20; y++ {
-		edges[y] = make([]bool, 20)
-	}
+	edges := newBitGrid(20, 20)
 
 	// Create a connected contour (small square)
 	for x := 5; x <= 15; x++ {
-		edges[5][x] = true
-		edges[15][x] = true
+		edges.Set(x, 5)
+		edges.Set(x, 15)
 	}
 	for y := 5; y <= 15; y++ {
-		edges[y][5] = true
-		edges[y][15] = true
+		edges.Set(5, y)
+		edges.Set(15, y)
 	}
 
 	contours := findContours(edges, 20, 20)
@@ -234,10 +231,7 @@ func TestFindContours(t *testing.T) {
 }
 
 func TestFindContours_Empty(t *testing.T) {
-	edges := make([][]bool, 20)
-	for y := 0; y < 20; y++ {
-		edges[y] = make([]bool, 20)
-	}
+	edges := newBitGrid(20, 20)
 
 	contours := findContours(edges, 20, 20)
 
@@ -247,18 +241,14 @@ func TestFindContours_Empty(t *testing.T) {
 }
 
 func TestFloodFill(t *testing.T) {
-	edges := make([][]bool, 10)
-	visited := make([][]bool, 10)
-	for y := 0; y < 10; y++ {
-		edges[y] = make([]bool, 10)
-		visited[y] = make([]bool, 10)
-	}
+	edges := newBitGrid(10, 10)
+	visited := newBitGrid(10, 10)
 
 	// Create a small connected region
-	edges[5][5] = true
-	edges[5][6] = true
-	edges[6][5] = true
-	edges[6][6] = true
+	edges.Set(5, 5)
+	edges.Set(6, 5)
+	edges.Set(5, 6)
+	edges.Set(6, 6)
 
 	var contour []Point
 	floodFill(edges, visited, 5, 5, 10, 10, &contour)
@@ -268,7 +258,7 @@ func TestFloodFill(t *testing.T) {
 	}
 
 	// Check visited was marked
-	if !visited[5][5] || !visited[5][6] || !visited[6][5] || !visited[6][6] {
+	if !visited.Get(5, 5) || !visited.Get(6, 5) || !visited.Get(5, 6) || !visited.Get(6, 6) {
 		t.Error("Flood fill should mark all visited points")
 	}
 }
@@ -0,0 +1,462 @@
+package detection
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+)
+
+// Stroke width audit: a line is measured on strokeLineSamples profiles
+// across it and a rectangle on strokeSideSamples profiles across each of
+// its sides, each reaching strokeReach pixels either side. Pixels within
+// strokeInkTolerance gray levels of the stroke's darkest-contrast pixel
+// belong to the stroke.
+const (
+	strokeLineSamples  = 5
+	strokeSideSamples  = 3
+	strokeReach        = 16
+	strokeInkTolerance = 30
+)
+
+// Kinds of StrokeMeasurement.
+const (
+	StrokeLine      = "line"
+	StrokeRectangle = "rectangle"
+)
+
+// StrokeAuditOptions configures AuditStrokeWidths.
+type StrokeAuditOptions struct {
+	// MinLength is the shortest line measured, in pixels (see DetectLines).
+	MinLength int
+
+	// MinArea is the smallest rectangle measured, in square pixels (see
+	// DetectRectangles).
+	MinArea int
+
+	// ExpectedWidth, if positive, is the width every stroke should have,
+	// in pixels. Otherwise strokes are compared with the most common
+	// width.
+	ExpectedWidth float64
+
+	// Tolerance is the largest difference from the reference width, in
+	// pixels, of a stroke that is not an outlier.
+	Tolerance float64
+}
+
+// StrokeMeasurement is the measured width of one line or rectangle border.
+type StrokeMeasurement struct {
+	// Kind is "line" or "rectangle".
+	Kind string `json:"kind"`
+
+	// Bounds is the line's or rectangle's bounding box.
+	Bounds Bounds `json:"bounds"`
+
+	// Start and End are a line's endpoints; unset for rectangles.
+	Start *Point `json:"start,omitempty"`
+	End   *Point `json:"end,omitempty"`
+
+	// Width is the median of the widths measured across the stroke, in
+	// pixels, and WidthMin and WidthMax the extremes; a rectangle whose
+	// sides differ shows as a range.
+	Width    float64 `json:"width"`
+	WidthMin int     `json:"width_min"`
+	WidthMax int     `json:"width_max"`
+
+	// Samples is the number of profiles across the stroke that found it.
+	Samples int `json:"samples"`
+
+	// Color is the hex color (#RRGGBB) of the stroke.
+	Color string `json:"color"`
+
+	// Deviation is the difference between Width and the reference width,
+	// and Outlier is true if it exceeds the tolerance.
+	Deviation float64 `json:"deviation"`
+	Outlier   bool    `json:"outlier"`
+}
+
+// StrokeWidthCount is one bar of the stroke width histogram.
+type StrokeWidthCount struct {
+	// Width is the stroke width in whole pixels.
+	Width int `json:"width"`
+
+	// Count is the number of strokes of that width, rounded.
+	Count int `json:"count"`
+}
+
+// StrokeAuditResult is the distribution of stroke widths across a diagram
+// and the strokes that stand out from it.
+type StrokeAuditResult struct {
+	// Strokes are the measured lines and rectangle borders, the largest
+	// deviation first.
+	Strokes []StrokeMeasurement `json:"strokes"`
+
+	// Count is the number of strokes measured.
+	Count int `json:"count"`
+
+	// Unmeasured is the number of lines and rectangles detected whose
+	// width could not be measured: filled shapes without a border, and
+	// strokes about 30 pixels or wider.
+	Unmeasured int `json:"unmeasured"`
+
+	// Histogram counts the strokes of each width, thinnest first.
+	Histogram []StrokeWidthCount `json:"histogram"`
+
+	// DominantWidth is the most common width (the thinnest if tied), and
+	// MedianWidth the median; both 0 if no stroke was measured.
+	DominantWidth int     `json:"dominant_width"`
+	MedianWidth   float64 `json:"median_width"`
+
+	// ReferenceWidth is the width strokes were compared with: the
+	// expected width if given, otherwise DominantWidth.
+	ReferenceWidth float64 `json:"reference_width"`
+
+	// Tolerance is the largest deviation allowed.
+	Tolerance float64 `json:"tolerance"`
+
+	// Outliers is the number of strokes deviating by more than Tolerance.
+	Outliers int `json:"outliers"`
+
+	// Passed is true if no stroke is an outlier.
+	Passed bool `json:"passed"`
+}
+
+// AuditStrokeWidths measures the stroke width of every line and rectangle
+// border in a diagram and reports the distribution and the outliers, to
+// check a style guide such as "all connectors are 2px".
+//
+// Parameters:
+//   - img: Source image to analyze.
+//   - opts: The detection thresholds, and the width and tolerance strokes
+//     are checked against.
+//
+// Returns:
+//   - *StrokeAuditResult: The measured strokes, the width histogram, and
+//     the outliers.
+//   - error: Non-nil if an option is out of range.
+//
+// # Measurement
+//
+// Lines and rectangles are found with DetectLines and DetectRectangles.
+// Their edges lie beside the stroke rather than along its middle, so each
+// is measured on profiles across it: five along a line, away from its ends
+// and any arrowheads, and three along each side of a rectangle. The pixel
+// near the profile's middle contrasting most with both its ends is taken
+// as the stroke, and the width is the run of pixels within 30 gray levels
+// of it. Antialiased pixels at the stroke's sides are not counted. A
+// profile whose run reaches 16 pixels either side is a filled area, not a
+// stroke, and is ignored.
+//
+// # Duplicates
+//
+// The edge detectors find both sides of a stroke: a rectangle border
+// gives an outer and an inner rectangle, and a thick line two parallel
+// lines, and every rectangle side is a line as well. A rectangle within a
+// larger one's border width of it on every side, and a line whose measured
+// stretch lies along a stroke already measured, are the same stroke and
+// are not counted again. A straight line thicker than a pixel or two is
+// also found as a thin rectangle; one no thicker than its border is
+// reported as a line.
+func AuditStrokeWidths(img image.Image, opts StrokeAuditOptions) (*StrokeAuditResult, error) {
+	if opts.MinLength < 1 {
+		return nil, fmt.Errorf("min_length must be positive, got %d", opts.MinLength)
+	}
+	if opts.MinArea < 1 {
+		return nil, fmt.Errorf("min_area must be positive, got %d", opts.MinArea)
+	}
+	if opts.ExpectedWidth < 0 {
+		return nil, fmt.Errorf("expected_width must be non-negative, got %g", opts.ExpectedWidth)
+	}
+	if opts.Tolerance < 0 {
+		return nil, fmt.Errorf("tolerance must be non-negative, got %g", opts.Tolerance)
+	}
+	rects := detectRectangles(img, opts.MinArea, 0.9, nil)
+	lines := detectLines(img, opts.MinLength, false, nil)
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	gray := make([]uint8, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			gray[y*w+x] = grayValue(img, x+bounds.Min.X, y+bounds.Min.Y)
+		}
+	}
+	m := strokeMeter{img: img, gray: gray, w: w, h: h, origin: bounds.Min}
+
+	result := &StrokeAuditResult{Strokes: []StrokeMeasurement{}, Histogram: []StrokeWidthCount{}, Tolerance: opts.Tolerance}
+	// Segments already measured, relative to the image origin, with the
+	// distance from them within which a line is the same stroke
+	type measured struct {
+		a, b  [2]float64
+		reach float64
+	}
+	var done []measured
+	var kept []StrokeMeasurement
+	for _, r := range rects.Rectangles {
+		b := r.Bounds
+		b.X1, b.Y1, b.X2, b.Y2 = b.X1-bounds.Min.X, b.Y1-bounds.Min.Y, b.X2-bounds.Min.X, b.Y2-bounds.Min.Y
+		inner := false
+		for _, k := range kept {
+			if k.Kind == StrokeRectangle && nestedWithin(r.Bounds, k.Bounds, k.WidthMax+2) {
+				inner = true
+				break
+			}
+		}
+		if inner {
+			continue
+		}
+		var widths []int
+		var cx, cy int
+		for _, side := range [4][4]float64{
+			{float64(b.X1), float64(b.Y1), float64(b.X2), float64(b.Y1)},
+			{float64(b.X1), float64(b.Y2), float64(b.X2), float64(b.Y2)},
+			{float64(b.X1), float64(b.Y1), float64(b.X1), float64(b.Y2)},
+			{float64(b.X2), float64(b.Y1), float64(b.X2), float64(b.Y2)},
+		} {
+			for i := 1; i <= strokeSideSamples; i++ {
+				t := float64(i) / (strokeSideSamples + 1)
+				x, y := side[0]+t*(side[2]-side[0]), side[1]+t*(side[3]-side[1])
+				// Across a horizontal side is down, across a vertical one right
+				dx, dy := 0.0, 1.0
+				if side[0] == side[2] {
+					dx, dy = 1, 0
+				}
+				if width, px, py, ok := m.widthAt(x, y, dx, dy); ok {
+					widths = append(widths, width)
+					cx, cy = px, py
+				}
+			}
+		}
+		if len(widths) < 2*strokeSideSamples {
+			result.Unmeasured++
+			continue
+		}
+		s := m.measurement(StrokeRectangle, r.Bounds, widths, cx, cy)
+		if bw, bh := b.X2-b.X1, b.Y2-b.Y1; minInt(bw, bh) <= s.WidthMax+2 {
+			// A box no thicker than its border is a straight line
+			start, end := Point{X: r.Bounds.X1, Y: r.Center.Y}, Point{X: r.Bounds.X2, Y: r.Center.Y}
+			if bh > bw {
+				start, end = Point{X: r.Center.X, Y: r.Bounds.Y1}, Point{X: r.Center.X, Y: r.Bounds.Y2}
+			}
+			s.Kind, s.Start, s.End = StrokeLine, &start, &end
+		}
+		kept = append(kept, s)
+		reach := float64(s.WidthMax + 2)
+		corners := [4][2]float64{{float64(b.X1), float64(b.Y1)}, {float64(b.X2), float64(b.Y1)}, {float64(b.X2), float64(b.Y2)}, {float64(b.X1), float64(b.Y2)}}
+		for i := range corners {
+			done = append(done, measured{corners[i], corners[(i+1)%4], reach})
+		}
+	}
+
+	for _, l := range lines.Lines {
+		a := [2]float64{float64(l.Start.X - bounds.Min.X), float64(l.Start.Y - bounds.Min.Y)}
+		b := [2]float64{float64(l.End.X - bounds.Min.X), float64(l.End.Y - bounds.Min.Y)}
+		length := math.Hypot(b[0]-a[0], b[1]-a[1])
+		if length == 0 {
+			continue
+		}
+		// The points measured, away from the ends
+		var points [strokeLineSamples][2]float64
+		for i := range points {
+			t := 0.2 + 0.6*float64(i)/(strokeLineSamples-1)
+			points[i] = [2]float64{a[0] + t*(b[0]-a[0]), a[1] + t*(b[1]-a[1])}
+		}
+		same := false
+		for _, d := range done {
+			same = true
+			for _, p := range points {
+				if segmentDistance(p, d.a, d.b) > d.reach {
+					same = false
+					break
+				}
+			}
+			if same {
+				break
+			}
+		}
+		if same {
+			continue
+		}
+		// Perpendicular to the line
+		dx, dy := -(b[1]-a[1])/length, (b[0]-a[0])/length
+		var widths []int
+		var cx, cy int
+		for _, p := range points {
+			if width, px, py, ok := m.widthAt(p[0], p[1], dx, dy); ok {
+				widths = append(widths, width)
+				cx, cy = px, py
+			}
+		}
+		if len(widths) < (strokeLineSamples+1)/2 {
+			result.Unmeasured++
+			continue
+		}
+		lb := Bounds{
+			X1: minInt(l.Start.X, l.End.X), Y1: minInt(l.Start.Y, l.End.Y),
+			X2: maxInt(l.Start.X, l.End.X), Y2: maxInt(l.Start.Y, l.End.Y),
+		}
+		s := m.measurement(StrokeLine, lb, widths, cx, cy)
+		start, end := l.Start, l.End
+		s.Start, s.End = &start, &end
+		kept = append(kept, s)
+		done = append(done, measured{a, b, float64(s.WidthMax + 2)})
+	}
+
+	result.Count = len(kept)
+	if len(kept) == 0 {
+		result.Passed = true
+		return result, nil
+	}
+	counts := make(map[int]int)
+	widths := make([]float64, len(kept))
+	for i, s := range kept {
+		counts[int(math.Round(s.Width))]++
+		widths[i] = s.Width
+	}
+	for width, n := range counts {
+		result.Histogram = append(result.Histogram, StrokeWidthCount{Width: width, Count: n})
+	}
+	sort.Slice(result.Histogram, func(i, j int) bool { return result.Histogram[i].Width < result.Histogram[j].Width })
+	best := 0
+	for _, bar := range result.Histogram {
+		if bar.Count > best {
+			best, result.DominantWidth = bar.Count, bar.Width
+		}
+	}
+	result.MedianWidth = medianWidth(widths)
+	result.ReferenceWidth = float64(result.DominantWidth)
+	if opts.ExpectedWidth > 0 {
+		result.ReferenceWidth = opts.ExpectedWidth
+	}
+	for i := range kept {
+		s := &kept[i]
+		s.Deviation = math.Round(math.Abs(s.Width-result.ReferenceWidth)*10) / 10
+		// Compare unrounded, so a deviation equal to the tolerance passes
+		if math.Abs(s.Width-result.ReferenceWidth) > opts.Tolerance+1e-9 {
+			s.Outlier = true
+			result.Outliers++
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].Deviation > kept[j].Deviation })
+	result.Strokes = kept
+	result.Passed = result.Outliers == 0
+	return result, nil
+}
+
+// strokeMeter measures strokes in the grayscale copy of img, w×h pixels
+// with img's origin at (0, 0).
+type strokeMeter struct {
+	img    image.Image
+	gray   []uint8
+	w, h   int
+	origin image.Point
+}
+
+// widthAt measures the stroke crossing (x, y) along the unit vector
+// (dx, dy), returning its width and the pixel it was found at. It returns
+// false if no pixel within 2 of (x, y) contrasts with both ends of the
+// profile, or if the stroke runs to a profile end.
+func (m strokeMeter) widthAt(x, y, dx, dy float64) (int, int, int, bool) {
+	var profile [2*strokeReach + 1]int
+	var px, py [2*strokeReach + 1]int
+	first, last := -1, -1
+	for d := -strokeReach; d <= strokeReach; d++ {
+		i := d + strokeReach
+		px[i] = int(math.Round(x + float64(d)*dx))
+		py[i] = int(math.Round(y + float64(d)*dy))
+		profile[i] = -1
+		if px[i] >= 0 && px[i] < m.w && py[i] >= 0 && py[i] < m.h {
+			profile[i] = int(m.gray[py[i]*m.w+px[i]])
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 {
+		return 0, 0, 0, false
+	}
+
+	center, contrast := -1, strokeInkTolerance
+	for i := strokeReach - 2; i <= strokeReach+2; i++ {
+		if profile[i] < 0 {
+			continue
+		}
+		c := minInt(absDiff(profile[i], profile[first]), absDiff(profile[i], profile[last]))
+		if c > contrast {
+			center, contrast = i, c
+		}
+	}
+	if center < 0 {
+		return 0, 0, 0, false
+	}
+	ink := func(i int) bool {
+		return profile[i] >= 0 && absDiff(profile[i], profile[center]) <= strokeInkTolerance
+	}
+	lo, hi := center, center
+	for lo > first && ink(lo-1) {
+		lo--
+	}
+	for hi < last && ink(hi+1) {
+		hi++
+	}
+	if lo == first || hi == last {
+		return 0, 0, 0, false
+	}
+	return hi - lo + 1, px[center], py[center], true
+}
+
+// measurement summarizes the widths measured across one stroke, whose
+// color is sampled at (cx, cy).
+func (m strokeMeter) measurement(kind string, b Bounds, widths []int, cx, cy int) StrokeMeasurement {
+	sort.Ints(widths)
+	values := make([]float64, len(widths))
+	for i, w := range widths {
+		values[i] = float64(w)
+	}
+	return StrokeMeasurement{
+		Kind:     kind,
+		Bounds:   b,
+		Width:    medianWidth(values),
+		WidthMin: widths[0],
+		WidthMax: widths[len(widths)-1],
+		Samples:  len(widths),
+		Color:    sampleColorHex(m.img, cx+m.origin.X, cy+m.origin.Y),
+	}
+}
+
+// nestedWithin reports whether inner lies inside outer with every side
+// within reach pixels of outer's.
+func nestedWithin(inner, outer Bounds, reach int) bool {
+	return inner.X1 >= outer.X1 && inner.Y1 >= outer.Y1 && inner.X2 <= outer.X2 && inner.Y2 <= outer.Y2 &&
+		inner.X1-outer.X1 <= reach && inner.Y1-outer.Y1 <= reach &&
+		outer.X2-inner.X2 <= reach && outer.Y2-inner.Y2 <= reach
+}
+
+// segmentDistance returns the distance from p to the segment from a to b.
+func segmentDistance(p, a, b [2]float64) float64 {
+	vx, vy := b[0]-a[0], b[1]-a[1]
+	t := 0.0
+	if l2 := vx*vx + vy*vy; l2 > 0 {
+		t = math.Max(0, math.Min(1, ((p[0]-a[0])*vx+(p[1]-a[1])*vy)/l2))
+	}
+	return math.Hypot(p[0]-a[0]-t*vx, p[1]-a[1]-t*vy)
+}
+
+// medianWidth returns the median of widths, reordering them.
+func medianWidth(widths []float64) float64 {
+	sort.Float64s(widths)
+	n := len(widths)
+	if n%2 == 1 {
+		return widths[n/2]
+	}
+	return (widths[n/2-1] + widths[n/2]) / 2
+}
+
+// absDiff returns |a - b|.
+func absDiff(a, b int) int {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
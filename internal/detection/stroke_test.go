@@ -0,0 +1,87 @@
+package detection
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// strokeScene draws a diagram on white: two boxes with 2-pixel borders,
+// a third with a 4-pixel border, a 2-pixel connector between the first
+// two, and a filled box without a border.
+func strokeScene() *image.RGBA {
+	g := newGoldenCanvas(400, 300)
+	black := color.RGBA{0, 0, 0, 255}
+	g.frame(image.Rect(20, 20, 120, 100), 2, black)
+	g.frame(image.Rect(220, 20, 320, 100), 2, black)
+	g.frame(image.Rect(20, 160, 120, 260), 4, color.RGBA{200, 40, 40, 255})
+	g.line(image.Pt(130, 60), image.Pt(210, 60), 2, black)
+	g.box(image.Rect(220, 160, 320, 260), color.RGBA{90, 120, 200, 255})
+	return g.img
+}
+
+func TestAuditStrokeWidths(t *testing.T) {
+	result, err := AuditStrokeWidths(strokeScene(), StrokeAuditOptions{MinLength: 20, MinArea: 100, Tolerance: 0.5})
+	if err != nil {
+		t.Fatalf("AuditStrokeWidths failed: %v", err)
+	}
+	if result.Count != 4 {
+		t.Fatalf("measured %d strokes, want 4: %+v", result.Count, result.Strokes)
+	}
+	if result.DominantWidth != 2 || result.ReferenceWidth != 2 || result.Outliers != 1 || result.Passed {
+		t.Errorf("got dominant %d, reference %g, %d outliers", result.DominantWidth, result.ReferenceWidth, result.Outliers)
+	}
+	first := result.Strokes[0]
+	if !first.Outlier || first.Kind != StrokeRectangle || first.Width != 4 || first.Color != "#C82828" {
+		t.Errorf("first stroke = %+v, want the 4-pixel red box", first)
+	}
+	lines := 0
+	for _, s := range result.Strokes[1:] {
+		if s.Outlier || s.Width != 2 {
+			t.Errorf("stroke %+v: want 2 pixels wide", s)
+		}
+		if s.Kind == StrokeLine {
+			lines++
+		}
+	}
+	if lines != 1 {
+		t.Errorf("got %d lines, want the connector alone", lines)
+	}
+	if len(result.Histogram) != 2 || result.Histogram[0] != (StrokeWidthCount{Width: 2, Count: 3}) {
+		t.Errorf("histogram = %+v", result.Histogram)
+	}
+}
+
+func TestAuditStrokeWidths_ExpectedWidth(t *testing.T) {
+	result, err := AuditStrokeWidths(strokeScene(), StrokeAuditOptions{MinLength: 20, MinArea: 100, ExpectedWidth: 4})
+	if err != nil {
+		t.Fatalf("AuditStrokeWidths failed: %v", err)
+	}
+	if result.ReferenceWidth != 4 || result.Outliers != 3 {
+		t.Errorf("got reference %g, %d outliers, want 4 and 3", result.ReferenceWidth, result.Outliers)
+	}
+}
+
+func TestAuditStrokeWidths_Blank(t *testing.T) {
+	result, err := AuditStrokeWidths(createTestImage(100, 100, color.White), StrokeAuditOptions{MinLength: 20, MinArea: 100})
+	if err != nil {
+		t.Fatalf("AuditStrokeWidths failed: %v", err)
+	}
+	if result.Count != 0 || !result.Passed || result.DominantWidth != 0 {
+		t.Errorf("got %+v", result)
+	}
+}
+
+func TestAuditStrokeWidths_InvalidOptions(t *testing.T) {
+	img := createTestImage(50, 50, color.White)
+	for _, opts := range []StrokeAuditOptions{
+		{MinLength: 0, MinArea: 100},
+		{MinLength: 20, MinArea: 0},
+		{MinLength: 20, MinArea: 100, ExpectedWidth: -1},
+		{MinLength: 20, MinArea: 100, Tolerance: -1},
+	} {
+		if _, err := AuditStrokeWidths(img, opts); err == nil {
+			t.Errorf("expected error for %+v", opts)
+		}
+	}
+}
@@ -20,6 +20,14 @@ type TextRegion struct {
 
 	// Area is the region size in square pixels.
 	Area int `json:"area"`
+
+	// Style is "printed", "handwritten", or "unknown" (see
+	// ClassifyTextStyle). OCR confidence will be low on handwriting.
+	Style string `json:"style"`
+
+	// HandwritingScore (0.0 to 1.0) is how much the region's strokes look
+	// like handwriting; Style is "handwritten" from 0.5.
+	HandwritingScore float64 `json:"handwriting_score"`
 }
 
 // TextRegionsResult contains all text regions detected in an image.
@@ -48,7 +56,10 @@ type TextRegionsResult struct {
 //
 // # Algorithm
 //
-//  1. Edge Detection: Find edge pixels using gradient thresholds
+//  1. Edge Detection: Find edge pixels using gradient thresholds. For each
+//     row of windows, integral images of edges and edge-run starts are
+//     built over just that band of rows, so each window is scored in O(1)
+//     without whole-image tables
 //  2. Sliding Window: Scan the image with multiple window sizes:
 //     - 100×30 (small text)
 //     - 150×40 (medium text)
@@ -62,7 +73,11 @@ type TextRegionsResult struct {
 //  5. Confidence Calculation:
 //     confidence = horizontalScore × (1 - |density - 0.2| / 0.2)
 //     This peaks when density is ~20% and horizontal score is high
-//  6. Region Merging: Combine overlapping regions, keeping highest confidence
+//  6. Region Merging: Combine overlapping regions, keeping highest
+//     confidence, then trim each region to the edge pixels inside it so
+//     that it fits the ink rather than the windows
+//  7. Style: Classify each region as printed or handwritten from the
+//     regularity of its strokes (see ClassifyTextStyle)
 //
 // # Edge Density for Text
 //
@@ -89,63 +104,16 @@ func DetectTextRegions(img image.Image, minConfidence float64) (*TextRegionsResu
 
 	// Detect edges
 	edges := detectEdges(img, width, height)
+	defer edges.release()
 
-	// Use sliding window to find regions with high edge density
-	windowSizes := []struct{ w, h int }{
-		{100, 30}, // Small text
-		{150, 40}, // Medium text
-		{200, 50}, // Large text
-		{80, 25},  // Very small text
-	}
-
-	candidates := make([]TextRegion, 0)
-
-	for _, ws := range windowSizes {
-		stepX := ws.w / 2
-		stepY := ws.h / 2
-
-		for y := 0; y <= height-ws.h; y += stepY {
-			for x := 0; x <= width-ws.w; x += stepX {
-				// Count edge pixels in window
-				edgeCount := 0
-				for wy := 0; wy < ws.h; wy++ {
-					for wx := 0; wx < ws.w; wx++ {
-						if edges[y+wy][x+wx] {
-							edgeCount++
-						}
-					}
-				}
-
-				// Calculate edge density
-				area := ws.w * ws.h
-				density := float64(edgeCount) / float64(area)
-
-				// Text typically has medium edge density (not too sparse, not too dense)
-				if density >= 0.05 && density <= 0.4 {
-					// Check horizontal edge distribution (text is usually horizontal)
-					horizontalScore := calculateHorizontalScore(edges, x, y, ws.w, ws.h)
-
-					confidence := horizontalScore * (1.0 - math.Abs(density-0.2)/0.2)
-
-					if confidence >= minConfidence {
-						candidates = append(candidates, TextRegion{
-							Bounds: Bounds{
-								X1: x + bounds.Min.X,
-								Y1: y + bounds.Min.Y,
-								X2: x + ws.w + bounds.Min.X,
-								Y2: y + ws.h + bounds.Min.Y,
-							},
-							Confidence: math.Round(confidence*1000) / 1000,
-							Area:       area,
-						})
-					}
-				}
-			}
-		}
-	}
+	// Score every window position, then keep the confident ones
+	scores := scoreTextWindows(edges)
+	candidates := textCandidates(scores, minConfidence, bounds.Min)
 
 	// Merge overlapping regions
 	merged := mergeOverlappingRegions(candidates)
+	trimTextRegions(edges, merged, bounds.Min)
+	tagTextStyles(img, merged)
 
 	// Sort by confidence
 	sort.Slice(merged, func(i, j int) bool {
@@ -158,13 +126,165 @@ func DetectTextRegions(img image.Image, minConfidence float64) (*TextRegionsResu
 	}, nil
 }
 
+// textWindowSizes are the sliding window sizes DetectTextRegions scans
+// with. Each window steps by half its size in both directions.
+var textWindowSizes = []struct{ w, h int }{
+	{100, 30}, // Small text
+	{150, 40}, // Medium text
+	{200, 50}, // Large text
+	{80, 25},  // Very small text
+}
+
+// textWindowScores holds the confidence of every window position for one
+// window size. Window (col, row) covers w×h pixels starting at
+// (col×w/2, row×h/2). Windows whose edge density is outside the range
+// typical of text score -1.
+type textWindowScores struct {
+	w, h       int
+	cols, rows int
+	confidence []float64 // row-major, cols × rows
+}
+
+// scoreTextWindows scores every window position of every window size.
+func scoreTextWindows(edges *bitGrid) []textWindowScores {
+	scores := make([]textWindowScores, len(textWindowSizes))
+	for i, ws := range textWindowSizes {
+		sc := newTextWindowScores(ws.w, ws.h, edges.width, edges.height)
+		for row := 0; row < sc.rows; row++ {
+			y := row * (ws.h / 2)
+			tables := newEdgeRunTables(edges, y, ws.h)
+			for col := 0; col < sc.cols; col++ {
+				x := col * (ws.w / 2)
+				sc.confidence[row*sc.cols+col] = textWindowConfidence(
+					tables.edges.Sum(x, 0, x+ws.w, ws.h), ws.w*ws.h,
+					func() float64 { return tables.horizontalScore(x, ws.w) })
+			}
+			tables.release()
+		}
+		scores[i] = sc
+	}
+	return scores
+}
+
+// newTextWindowScores sizes the window grid for a w×h window sliding over
+// a width×height image.
+func newTextWindowScores(w, h, width, height int) textWindowScores {
+	sc := textWindowScores{w: w, h: h}
+	if width >= w && height >= h {
+		sc.cols = (width-w)/(w/2) + 1
+		sc.rows = (height-h)/(h/2) + 1
+	}
+	sc.confidence = make([]float64, sc.cols*sc.rows)
+	return sc
+}
+
+// textWindowConfidence scores one window from its edge count and area.
+// The horizontal score is only computed for windows whose edge density is
+// typical of text; other windows score -1.
+func textWindowConfidence(edgeCount int64, area int, horizontalScore func() float64) float64 {
+	density := float64(edgeCount) / float64(area)
+
+	// Text typically has medium edge density (not too sparse, not too dense)
+	if density < 0.05 || density > 0.4 {
+		return -1
+	}
+
+	// Check horizontal edge distribution (text is usually horizontal)
+	return horizontalScore() * (1.0 - math.Abs(density-0.2)/0.2)
+}
+
+// textCandidates returns the windows scoring at least minConfidence, in
+// scan order (window size, then row, then column), offset by origin.
+func textCandidates(scores []textWindowScores, minConfidence float64, origin image.Point) []TextRegion {
+	candidates := make([]TextRegion, 0)
+	for _, sc := range scores {
+		for row := 0; row < sc.rows; row++ {
+			for col := 0; col < sc.cols; col++ {
+				confidence := sc.confidence[row*sc.cols+col]
+				if confidence < 0 || confidence < minConfidence {
+					continue
+				}
+				x, y := col*(sc.w/2), row*(sc.h/2)
+				candidates = append(candidates, TextRegion{
+					Bounds: Bounds{
+						X1: x + origin.X,
+						Y1: y + origin.Y,
+						X2: x + sc.w + origin.X,
+						Y2: y + sc.h + origin.Y,
+					},
+					Confidence: math.Round(confidence*1000) / 1000,
+					Area:       sc.w * sc.h,
+				})
+			}
+		}
+	}
+	return candidates
+}
+
+// edgeRunTables holds integral images over one horizontal band of an edge
+// map for O(1) window statistics: the edge count and the number of
+// horizontal and vertical edge runs. Band coordinates start at row 0.
+//
+// A run starts at an edge pixel whose left (or upper) neighbor is not an
+// edge. Clipping a run at a window's left edge starts a new run there, so
+// the runs in a window are the run starts inside it plus the continuation
+// pixels (edges that are not run starts) in its first column. Vertical runs
+// work the same way with the band's first row.
+type edgeRunTables struct {
+	edges, hStart, vStart *IntegralImage
+}
+
+// newEdgeRunTables builds the integral images for rows y0 to y0+rows of
+// the edge map. Run starts look at the row above the band, so runs that
+// enter the band from above are counted as clipped, not as starts. The
+// tables are pooled; call release when done.
+func newEdgeRunTables(edges *bitGrid, y0, rows int) *edgeRunTables {
+	table := func(f func(x, y int) bool) *IntegralImage {
+		return newPooledIntegralImage(edges.width, rows, func(x, y int) int64 {
+			if edges.Get(x, y0+y) && f(x, y0+y) {
+				return 1
+			}
+			return 0
+		})
+	}
+	return &edgeRunTables{
+		edges:  table(func(x, y int) bool { return true }),
+		hStart: table(func(x, y int) bool { return x == 0 || !edges.Get(x-1, y) }),
+		vStart: table(func(x, y int) bool { return y == 0 || !edges.Get(x, y-1) }),
+	}
+}
+
+// release returns the tables to the pool.
+func (t *edgeRunTables) release() {
+	t.edges.release()
+	t.hStart.release()
+	t.vStart.release()
+}
+
+// horizontalScore is the O(1) equivalent of calculateHorizontalScore for
+// the window spanning columns x to x+w and the full height of the band.
+func (t *edgeRunTables) horizontalScore(x, w int) float64 {
+	h := t.edges.height
+	hCont := t.edges.Sum(x, 0, x+1, h) - t.hStart.Sum(x, 0, x+1, h)
+	vCont := t.edges.Sum(x, 0, x+w, 1) - t.vStart.Sum(x, 0, x+w, 1)
+	horizontalRuns := t.hStart.Sum(x, 0, x+w, h) + hCont
+	verticalRuns := t.vStart.Sum(x, 0, x+w, h) + vCont
+	if horizontalRuns+verticalRuns == 0 {
+		return 0
+	}
+	return float64(horizontalRuns) / float64(horizontalRuns+verticalRuns)
+}
+
 // calculateHorizontalScore measures how horizontally oriented the edge distribution is.
 //
 // Counts horizontal and vertical "runs" of consecutive edge pixels.
 // Returns the ratio of horizontal runs to total runs.
 // A higher score (closer to 1.0) indicates more horizontal structure, typical of text.
 // Returns 0 if no edge runs are found.
-func calculateHorizontalScore(edges [][]bool, x, y, w, h int) float64 {
+//
+// This scans the whole window; DetectTextRegions uses the equivalent
+// edgeRunTables.horizontalScore, which is O(1) per window.
+func calculateHorizontalScore(edges *bitGrid, x, y, w, h int) float64 {
 	horizontalRuns := 0
 	verticalRuns := 0
 
@@ -172,7 +292,7 @@ func calculateHorizontalScore(edges [][]bool, x, y, w, h int) float64 {
 	for row := y; row < y+h; row++ {
 		inRun := false
 		for col := x; col < x+w; col++ {
-			if edges[row][col] {
+			if edges.Get(col, row) {
 				if !inRun {
 					horizontalRuns++
 					inRun = true
@@ -187,7 +307,7 @@ func calculateHorizontalScore(edges [][]bool, x, y, w, h int) float64 {
 	for col := x; col < x+w; col++ {
 		inRun := false
 		for row := y; row < y+h; row++ {
-			if edges[row][col] {
+			if edges.Get(col, row) {
 				if !inRun {
 					verticalRuns++
 					inRun = true
@@ -205,6 +325,19 @@ func calculateHorizontalScore(edges [][]bool, x, y, w, h int) float64 {
 	return float64(horizontalRuns) / float64(horizontalRuns+verticalRuns)
 }
 
+// countEdges counts the set cells in a window by scanning it.
+func countEdges(edges *bitGrid, x, y, w, h int) int64 {
+	var n int64
+	for row := y; row < y+h; row++ {
+		for col := x; col < x+w; col++ {
+			if edges.Get(col, row) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
 // mergeOverlappingRegions combines overlapping text regions into larger regions.
 //
 // When two regions overlap, they are merged into a single region with:
@@ -241,6 +374,34 @@ func mergeOverlappingRegions(regions []TextRegion) []TextRegion {
 	return merged
 }
 
+// trimTextRegions shrinks each region to the bounding box of the edge
+// pixels inside it, recalculating Area. Region bounds are offset by origin
+// from edge map coordinates; a region without edge pixels is left as is.
+func trimTextRegions(edges *bitGrid, regions []TextRegion, origin image.Point) {
+	for i := range regions {
+		b := regions[i].Bounds
+		x1, y1 := maxInt(b.X1-origin.X, 0), maxInt(b.Y1-origin.Y, 0)
+		x2, y2 := minInt(b.X2-origin.X, edges.width), minInt(b.Y2-origin.Y, edges.height)
+		minX, minY, maxX, maxY := x2, y2, -1, -1
+		for y := y1; y < y2; y++ {
+			for x := x1; x < x2; x++ {
+				if edges.Get(x, y) {
+					minX, minY = minInt(minX, x), minInt(minY, y)
+					maxX, maxY = maxInt(maxX, x), maxInt(maxY, y)
+				}
+			}
+		}
+		if maxX < 0 {
+			continue
+		}
+		regions[i].Bounds = Bounds{
+			X1: minX + origin.X, Y1: minY + origin.Y,
+			X2: maxX + 1 + origin.X, Y2: maxY + 1 + origin.Y,
+		}
+		regions[i].Area = (maxX + 1 - minX) * (maxY + 1 - minY)
+	}
+}
+
 // regionsOverlap checks if two bounding boxes overlap (share any area).
 func regionsOverlap(a, b Bounds) bool {
 	return a.X1 < b.X2 && a.X2 > b.X1 && a.Y1 < b.Y2 && a.Y2 > b.Y1
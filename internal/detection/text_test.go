@@ -112,17 +112,14 @@ func TestDetectTextRegions_SortedByConfidence(t *testing.T) {
 }
 
 func TestCalculateHorizontalScore(t *testing.T) {
-	edges := make([][]bool, 50)
-	for y := 0; y < 50; y++ {
-		edges[y] = make([]bool, 50)
-	}
+	edges := newBitGrid(50, 50)
 
 	// Create horizontal lines - these have one horizontal run per row
 	// but many vertical runs (each column has multiple interrupted runs)
 	// The algorithm counts runs, not line orientations
 	for y := 10; y < 40; y += 5 {
 		for x := 5; x < 45; x++ {
-			edges[y][x] = true
+			edges.Set(x, y)
 		}
 	}
 
@@ -136,16 +133,13 @@ func TestCalculateHorizontalScore(t *testing.T) {
 }
 
 func TestCalculateHorizontalScore_Vertical(t *testing.T) {
-	edges := make([][]bool, 50)
-	for y := 0; y < 50; y++ {
-		edges[y] = make([]bool, 50)
-	}
+	edges := newBitGrid(50, 50)
 
 	// Create vertical lines - these have one vertical run per column
 	// but many horizontal runs (each row has multiple interrupted runs)
 	for x := 10; x < 40; x += 5 {
 		for y := 5; y < 45; y++ {
-			edges[y][x] = true
+			edges.Set(x, y)
 		}
 	}
 
@@ -159,10 +153,7 @@ func TestCalculateHorizontalScore_Vertical(t *testing.T) {
 }
 
 func TestCalculateHorizontalScore_Empty(t *testing.T) {
-	edges := make([][]bool, 50)
-	for y := 0; y < 50; y++ {
-		edges[y] = make([]bool, 50)
-	}
+	edges := newBitGrid(50, 50)
 
 	score := calculateHorizontalScore(edges, 0, 0, 50, 50)
 
@@ -0,0 +1,304 @@
+package detection
+
+import (
+	"image"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+// Parameter tuning sizes.
+const (
+	// tuneMaxSize is the longest side, in pixels, of the image the
+	// parameter sweep runs on. Larger images are downscaled first.
+	tuneMaxSize = 400
+
+	// tuneSecondScale is the size of the second sweep image relative to
+	// the first. Detections are compared between the two.
+	tuneSecondScale = 0.75
+)
+
+// Parameter grids, relative to the image size so they suit any resolution.
+var (
+	// tuneRectAreaFractions are minimum areas as fractions of the image area.
+	tuneRectAreaFractions = []float64{0.0002, 0.001, 0.005}
+
+	// tuneRectTolerances are rectangularity tolerances.
+	tuneRectTolerances = []float64{0.95, 0.9, 0.8, 0.7}
+
+	// tuneLineLengthFractions are minimum line lengths as fractions of the
+	// longer image side.
+	tuneLineLengthFractions = []float64{0.02, 0.04, 0.08, 0.15, 0.3}
+
+	// tuneCircleRadiusBands are radius ranges as fractions of the shorter
+	// image side.
+	tuneCircleRadiusBands = [][2]float64{{0.01, 0.04}, {0.03, 0.08}, {0.06, 0.16}, {0.12, 0.3}}
+)
+
+// RectangleParams are the DetectRectangles parameters.
+type RectangleParams struct {
+	MinArea   int
+	Tolerance float64
+}
+
+// LineParams are the DetectLines parameters.
+type LineParams struct {
+	MinLength int
+}
+
+// CircleParams are the DetectCircles parameters.
+type CircleParams struct {
+	MinRadius int
+	MaxRadius int
+}
+
+// TuningReport describes an automatic parameter search.
+type TuningReport struct {
+	// Tuned is true if a parameter set gave detections; the caller's
+	// defaults were used otherwise.
+	Tuned bool `json:"tuned"`
+
+	// Params are the chosen parameters, by tool argument name, in
+	// full-resolution pixels. Omitted when Tuned is false.
+	Params map[string]float64 `json:"params,omitempty"`
+
+	// Stability is the agreement (0.0-1.0) between the detections at the
+	// two sweep sizes for the chosen parameters: twice the matched count
+	// over the total count.
+	Stability float64 `json:"stability"`
+
+	// Candidates is the number of parameter sets tried.
+	Candidates int `json:"candidates"`
+
+	// SweepWidth and SweepHeight are the size of the (downscaled) image
+	// the sweep ran on.
+	SweepWidth  int `json:"sweep_width"`
+	SweepHeight int `json:"sweep_height"`
+}
+
+// tuneScale is one downscaled copy of the image used in a sweep.
+type tuneScale struct {
+	img    image.Image
+	sx, sy float64 // size relative to the original, per axis
+}
+
+// newTuneScales returns the two sweep images: the source scaled to at most
+// tuneMaxSize on its longer side, and a copy tuneSecondScale times that.
+//
+// Box filtering keeps thin lines visible, which suits the Hough
+// detectors. Contour tracing needs the crisp boundaries of nearest
+// neighbor sampling instead: a blended boundary pixel gives a two-pixel
+// edge band, which doubles the traced perimeter.
+func newTuneScales(img image.Image, filter imaging.ResampleFilter) [2]tuneScale {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	s := math.Min(1, float64(tuneMaxSize)/float64(maxInt(w, h)))
+
+	scaled := func(f float64) tuneScale {
+		if f >= 1 {
+			return tuneScale{img: img, sx: 1, sy: 1}
+		}
+		sw := maxInt(1, int(math.Round(float64(w)*f)))
+		sh := maxInt(1, int(math.Round(float64(h)*f)))
+		return tuneScale{
+			img: imaging.Resize(img, sw, sh, filter),
+			sx:  float64(sw) / float64(w),
+			sy:  float64(sh) / float64(h),
+		}
+	}
+	return [2]tuneScale{scaled(s), scaled(s * tuneSecondScale)}
+}
+
+// scale returns a full-resolution length at this scale, at least min.
+func (ts tuneScale) scale(v float64, min int) int {
+	return maxInt(min, int(math.Round(v*(ts.sx+ts.sy)/2)))
+}
+
+// point maps a point at this scale back to full resolution.
+func (ts tuneScale) point(p Point, origin image.Point) Point {
+	return Point{
+		X: origin.X + int(math.Round(float64(p.X-origin.X)/ts.sx)),
+		Y: origin.Y + int(math.Round(float64(p.Y-origin.Y)/ts.sy)),
+	}
+}
+
+// tolerance is the matching slack, in full-resolution pixels, for
+// detections made at this scale: two of its pixels.
+func (ts tuneScale) tolerance() float64 {
+	return 2 / math.Min(ts.sx, ts.sy)
+}
+
+// sweep runs detect at both scales for each of n parameter sets and returns
+// the index of the most stable set, its stability, and whether any set
+// gave detections that agree. Ties go to the set with more matched
+// detections, then to the earlier set.
+func sweep[D any](scales [2]tuneScale, n int, detect func(ts tuneScale, i int) []D, match func(a, b D, tol float64) bool) (int, float64, bool) {
+	tol := scales[1].tolerance()
+	best, bestStability, bestMatched := -1, 0.0, 0
+	for i := 0; i < n; i++ {
+		a, b := detect(scales[0], i), detect(scales[1], i)
+		if len(a)+len(b) == 0 {
+			continue
+		}
+		matched := 0
+		used := make([]bool, len(b))
+		for _, da := range a {
+			for j, db := range b {
+				if !used[j] && match(da, db, tol) {
+					used[j] = true
+					matched++
+					break
+				}
+			}
+		}
+		stability := 2 * float64(matched) / float64(len(a)+len(b))
+		if matched > 0 && (stability > bestStability || (stability == bestStability && matched > bestMatched)) {
+			best, bestStability, bestMatched = i, stability, matched
+		}
+	}
+	return best, math.Round(bestStability*1000) / 1000, best >= 0
+}
+
+// newTuningReport starts a report for a sweep over n candidates.
+func newTuningReport(scales [2]tuneScale, n int) *TuningReport {
+	b := scales[0].img.Bounds()
+	return &TuningReport{Candidates: n, SweepWidth: b.Dx(), SweepHeight: b.Dy()}
+}
+
+// TuneRectangles picks DetectRectangles parameters for img by sweeping a
+// grid of minimum areas and tolerances on a downscaled copy.
+//
+// Each parameter set is run at two sizes (see TuningReport), and the set
+// whose rectangles agree best between them, by overlap, wins: real boxes
+// survive a change of scale, while noise and fragments of text do not.
+// If no set finds matching rectangles, the report has Tuned false and the
+// returned parameters are zero; use the defaults instead.
+func TuneRectangles(img image.Image) (RectangleParams, *TuningReport) {
+	b := img.Bounds()
+	area := float64(b.Dx() * b.Dy())
+	var grid []RectangleParams
+	for _, f := range tuneRectAreaFractions {
+		for _, tol := range tuneRectTolerances {
+			grid = append(grid, RectangleParams{MinArea: maxInt(16, int(area*f)), Tolerance: tol})
+		}
+	}
+
+	scales := newTuneScales(img, imaging.NearestNeighbor)
+	report := newTuningReport(scales, len(grid))
+	best, stability, ok := sweep(scales, len(grid),
+		func(ts tuneScale, i int) []Bounds {
+			minArea := maxInt(4, int(math.Round(float64(grid[i].MinArea)*ts.sx*ts.sy)))
+			res, _ := DetectRectangles(ts.img, minArea, grid[i].Tolerance)
+			boxes := make([]Bounds, len(res.Rectangles))
+			origin := ts.img.Bounds().Min
+			for j, r := range res.Rectangles {
+				p1 := ts.point(Point{X: r.Bounds.X1, Y: r.Bounds.Y1}, origin)
+				p2 := ts.point(Point{X: r.Bounds.X2, Y: r.Bounds.Y2}, origin)
+				boxes[j] = Bounds{X1: p1.X, Y1: p1.Y, X2: p2.X, Y2: p2.Y}
+			}
+			return boxes
+		},
+		func(a, b Bounds, _ float64) bool {
+			return boundsIoU(a, b) >= 0.5
+		})
+	if !ok {
+		return RectangleParams{}, report
+	}
+	p := grid[best]
+	report.Tuned, report.Stability = true, stability
+	report.Params = map[string]float64{"min_area": float64(p.MinArea), "tolerance": p.Tolerance}
+	return p, report
+}
+
+// TuneLines picks the DetectLines minimum length for img by sweeping
+// lengths on a downscaled copy, keeping the one whose lines agree best
+// (by endpoints) between two sizes. See TuneRectangles.
+func TuneLines(img image.Image) (LineParams, *TuningReport) {
+	b := img.Bounds()
+	longSide := float64(maxInt(b.Dx(), b.Dy()))
+	var grid []LineParams
+	for _, f := range tuneLineLengthFractions {
+		grid = append(grid, LineParams{MinLength: maxInt(10, int(longSide*f))})
+	}
+
+	type segment struct{ start, end Point }
+	scales := newTuneScales(img, imaging.Box)
+	report := newTuningReport(scales, len(grid))
+	best, stability, ok := sweep(scales, len(grid),
+		func(ts tuneScale, i int) []segment {
+			res, _ := DetectLines(ts.img, ts.scale(float64(grid[i].MinLength), 4), false)
+			segs := make([]segment, len(res.Lines))
+			origin := ts.img.Bounds().Min
+			for j, l := range res.Lines {
+				segs[j] = segment{ts.point(l.Start, origin), ts.point(l.End, origin)}
+			}
+			return segs
+		},
+		func(a, b segment, tol float64) bool {
+			tol += 0.05 * pointDistance(a.start, a.end)
+			return (pointDistance(a.start, b.start) <= tol && pointDistance(a.end, b.end) <= tol) ||
+				(pointDistance(a.start, b.end) <= tol && pointDistance(a.end, b.start) <= tol)
+		})
+	if !ok {
+		return LineParams{}, report
+	}
+	p := grid[best]
+	report.Tuned, report.Stability = true, stability
+	report.Params = map[string]float64{"min_length": float64(p.MinLength)}
+	return p, report
+}
+
+// TuneCircles picks the DetectCircles radius range for img by sweeping
+// radius bands on a downscaled copy, keeping the band whose circles agree
+// best (by center and radius) between two sizes. See TuneRectangles.
+func TuneCircles(img image.Image) (CircleParams, *TuningReport) {
+	b := img.Bounds()
+	shortSide := float64(minInt(b.Dx(), b.Dy()))
+	var grid []CircleParams
+	for _, band := range tuneCircleRadiusBands {
+		minR := maxInt(3, int(shortSide*band[0]))
+		grid = append(grid, CircleParams{MinRadius: minR, MaxRadius: maxInt(minR+1, int(shortSide*band[1]))})
+	}
+
+	scales := newTuneScales(img, imaging.Box)
+	report := newTuningReport(scales, len(grid))
+	best, stability, ok := sweep(scales, len(grid),
+		func(ts tuneScale, i int) []Circle {
+			minR := ts.scale(float64(grid[i].MinRadius), 2)
+			maxR := maxInt(minR, ts.scale(float64(grid[i].MaxRadius), 2))
+			res, _ := DetectCircles(ts.img, minR, maxR)
+			origin := ts.img.Bounds().Min
+			for j := range res.Circles {
+				c := &res.Circles[j]
+				c.Center = ts.point(c.Center, origin)
+				c.Radius = int(math.Round(float64(c.Radius) * 2 / (ts.sx + ts.sy)))
+			}
+			return res.Circles
+		},
+		func(a, b Circle, tol float64) bool {
+			tol += 0.25 * float64(a.Radius)
+			return pointDistance(a.Center, b.Center) <= tol && math.Abs(float64(a.Radius-b.Radius)) <= tol
+		})
+	if !ok {
+		return CircleParams{}, report
+	}
+	p := grid[best]
+	report.Tuned, report.Stability = true, stability
+	report.Params = map[string]float64{"min_radius": float64(p.MinRadius), "max_radius": float64(p.MaxRadius)}
+	return p, report
+}
+
+// boundsIoU returns the intersection over union of two boxes.
+func boundsIoU(a, b Bounds) float64 {
+	inter := Bounds{X1: maxInt(a.X1, b.X1), Y1: maxInt(a.Y1, b.Y1), X2: minInt(a.X2, b.X2), Y2: minInt(a.Y2, b.Y2)}
+	if inter.X2 <= inter.X1 || inter.Y2 <= inter.Y1 {
+		return 0
+	}
+	interArea := boundsArea(inter)
+	return float64(interArea) / float64(boundsArea(a)+boundsArea(b)-interArea)
+}
+
+// pointDistance returns the Euclidean distance between two points.
+func pointDistance(a, b Point) float64 {
+	return math.Hypot(float64(a.X-b.X), float64(a.Y-b.Y))
+}
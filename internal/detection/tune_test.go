@@ -0,0 +1,125 @@
+package detection
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+func TestNewTuneScales(t *testing.T) {
+	tests := []struct {
+		w, h          int
+		first, second image.Point
+		firstUnscaled bool
+	}{
+		{800, 600, image.Pt(400, 300), image.Pt(300, 225), false},
+		{200, 100, image.Pt(200, 100), image.Pt(150, 75), true},
+	}
+	for _, tt := range tests {
+		img := createTestImage(tt.w, tt.h, color.White)
+		scales := newTuneScales(img, imaging.Box)
+		if got := scales[0].img.Bounds().Size(); got != tt.first {
+			t.Errorf("%dx%d first scale: got %v, want %v", tt.w, tt.h, got, tt.first)
+		}
+		if got := scales[1].img.Bounds().Size(); got != tt.second {
+			t.Errorf("%dx%d second scale: got %v, want %v", tt.w, tt.h, got, tt.second)
+		}
+		if (scales[0].img == image.Image(img)) != tt.firstUnscaled {
+			t.Errorf("%dx%d: first scale reuses source = %v, want %v", tt.w, tt.h, !tt.firstUnscaled, tt.firstUnscaled)
+		}
+	}
+}
+
+func TestTuneRectangles(t *testing.T) {
+	img := createTestImage(800, 600, color.White)
+	boxes := []image.Rectangle{
+		image.Rect(60, 60, 300, 200),
+		image.Rect(420, 80, 720, 260),
+		image.Rect(100, 340, 380, 540),
+	}
+	for _, r := range boxes {
+		fillBox(img, r, color.RGBA{0, 90, 200, 255})
+	}
+
+	params, report := TuneRectangles(img)
+	if !report.Tuned {
+		t.Fatalf("expected tuning to succeed: %+v", report)
+	}
+	if report.Candidates != len(tuneRectAreaFractions)*len(tuneRectTolerances) {
+		t.Errorf("Candidates: got %d", report.Candidates)
+	}
+	if report.SweepWidth != 400 || report.SweepHeight != 300 {
+		t.Errorf("sweep size: got %dx%d, want 400x300", report.SweepWidth, report.SweepHeight)
+	}
+	if report.Stability <= 0 || report.Stability > 1 {
+		t.Errorf("Stability out of range: %v", report.Stability)
+	}
+	if report.Params["min_area"] != float64(params.MinArea) || report.Params["tolerance"] != params.Tolerance {
+		t.Errorf("report params %v do not match %+v", report.Params, params)
+	}
+
+	result, err := DetectRectangles(img, params.MinArea, params.Tolerance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range boxes {
+		found := false
+		for _, r := range result.Rectangles {
+			got := Bounds{X1: r.Bounds.X1, Y1: r.Bounds.Y1, X2: r.Bounds.X2, Y2: r.Bounds.Y2}
+			if boundsIoU(got, Bounds{X1: want.Min.X, Y1: want.Min.Y, X2: want.Max.X, Y2: want.Max.Y}) >= 0.8 {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("box %v not detected with tuned params %+v", want, params)
+		}
+	}
+}
+
+func TestTune_BlankImage(t *testing.T) {
+	img := createTestImage(300, 200, color.White)
+	if _, report := TuneRectangles(img); report.Tuned || report.Params != nil {
+		t.Errorf("rectangles: expected no tuning on a blank image, got %+v", report)
+	}
+	if _, report := TuneLines(img); report.Tuned {
+		t.Errorf("lines: expected no tuning on a blank image, got %+v", report)
+	}
+	if _, report := TuneCircles(img); report.Tuned {
+		t.Errorf("circles: expected no tuning on a blank image, got %+v", report)
+	}
+}
+
+func TestTuneLines(t *testing.T) {
+	img := createTestImage(600, 400, color.White)
+	black := color.RGBA{0, 0, 0, 255}
+	for _, y := range []int{100, 200, 300} {
+		fillBox(img, image.Rect(50, y, 550, y+2), black)
+	}
+
+	params, report := TuneLines(img)
+	if !report.Tuned {
+		t.Fatalf("expected tuning to succeed: %+v", report)
+	}
+	if params.MinLength < 10 || params.MinLength > 500 {
+		t.Errorf("MinLength out of range: %d", params.MinLength)
+	}
+}
+
+func TestBoundsIoU(t *testing.T) {
+	a := Bounds{X1: 0, Y1: 0, X2: 10, Y2: 10}
+	tests := []struct {
+		b    Bounds
+		want float64
+	}{
+		{a, 1},
+		{Bounds{X1: 5, Y1: 0, X2: 15, Y2: 10}, 50.0 / 150.0},
+		{Bounds{X1: 10, Y1: 0, X2: 20, Y2: 10}, 0},
+	}
+	for _, tt := range tests {
+		if got := boundsIoU(a, tt.b); got != tt.want {
+			t.Errorf("boundsIoU(%v, %v): got %v, want %v", a, tt.b, got, tt.want)
+		}
+	}
+}
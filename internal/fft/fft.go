@@ -0,0 +1,134 @@
+// Package fft implements the fast Fourier transform and the spectrum
+// helpers behind the frequency analyses of the image tools:
+// phase-correlation alignment, moiré detection, and periodicity
+// detection.
+//
+// Transforms are iterative radix-2 and work in place, so lengths must be
+// powers of two. Two-dimensional data is an n×n square in row-major
+// order, with the horizontal frequency along each row. Spectrum index k of
+// an n-point transform holds frequency Freq(k, n)/n cycles per sample.
+//
+// The package works on plain slices, so it does not depend on the imaging
+// types.
+package fft
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// IsPowerOfTwo reports whether n is a positive power of two.
+func IsPowerOfTwo(n int) bool {
+	return n > 0 && n&(n-1) == 0
+}
+
+// Transform performs an in-place FFT of a, or the inverse transform if
+// inverse is true. Neither direction is scaled, so a round trip multiplies
+// by len(a). len(a) must be a power of two.
+func Transform(a []complex128, inverse bool) {
+	n := len(a)
+
+	// Bit-reversal permutation
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			a[i], a[j] = a[j], a[i]
+		}
+	}
+
+	sign := -1.0
+	if inverse {
+		sign = 1.0
+	}
+	for size := 2; size <= n; size <<= 1 {
+		w := cmplx.Rect(1, sign*2*math.Pi/float64(size))
+		for start := 0; start < n; start += size {
+			wk := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				u := a[start+k]
+				v := a[start+k+size/2] * wk
+				a[start+k] = u + v
+				a[start+k+size/2] = u - v
+				wk *= w
+			}
+		}
+	}
+}
+
+// Transform2D performs an in-place 2D FFT on an n×n row-major matrix, where
+// n is a power of two. If inverse is true, the inverse transform is
+// computed and the result is scaled by 1/n², so a round trip returns the
+// input.
+func Transform2D(data []complex128, n int, inverse bool) {
+	col := make([]complex128, n)
+	for y := 0; y < n; y++ {
+		Transform(data[y*n:(y+1)*n], inverse)
+	}
+	for x := 0; x < n; x++ {
+		for y := 0; y < n; y++ {
+			col[y] = data[y*n+x]
+		}
+		Transform(col, inverse)
+		for y := 0; y < n; y++ {
+			data[y*n+x] = col[y]
+		}
+	}
+	if inverse {
+		scale := complex(1/float64(n*n), 0)
+		for i := range data {
+			data[i] *= scale
+		}
+	}
+}
+
+// Hann returns the n weights of a Hann window, which tapers data to zero
+// at both ends so the transform does not see the edges as a step.
+func Hann(n int) []float64 {
+	w := make([]float64, n)
+	for i := range w {
+		w[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n-1))
+	}
+	return w
+}
+
+// Power returns the power |X|² of each value of a spectrum.
+func Power(spectrum []complex128) []float64 {
+	p := make([]float64, len(spectrum))
+	for i, c := range spectrum {
+		p[i] = real(c)*real(c) + imag(c)*imag(c)
+	}
+	return p
+}
+
+// Freq returns the signed frequency of spectrum index k of an n-point
+// transform, in cycles per n samples: k for k < n/2, otherwise k-n.
+func Freq(k, n int) int {
+	if k >= n/2 {
+		return k - n
+	}
+	return k
+}
+
+// Index returns the spectrum index of signed frequency f of an n-point
+// transform, wrapping around; it is the inverse of Freq.
+func Index(f, n int) int {
+	return ((f % n) + n) % n
+}
+
+// RefinePeak estimates where between spectrum samples a peak lies, from
+// the power at the peak sample (mid) and its two neighbors. It fits a
+// parabola through the log power, which is exact for a Gaussian peak and
+// close for a Hann-windowed sinusoid, and returns the offset from mid in
+// samples, between -0.5 and 0.5.
+func RefinePeak(lo, mid, hi float64) float64 {
+	lo, mid, hi = math.Log(lo+1e-12), math.Log(mid+1e-12), math.Log(hi+1e-12)
+	den := lo - 2*mid + hi
+	if den >= 0 {
+		return 0
+	}
+	return math.Max(-0.5, math.Min(0.5, 0.5*(lo-hi)/den))
+}
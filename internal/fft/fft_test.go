@@ -0,0 +1,103 @@
+package fft
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestTransform_RoundTrip(t *testing.T) {
+	orig := []complex128{1, 2, 3, 4, 0, -1, -2, 5}
+	data := append([]complex128(nil), orig...)
+
+	Transform(data, false)
+	Transform(data, true)
+
+	for i := range data {
+		if cmplx.Abs(data[i]/complex(float64(len(data)), 0)-orig[i]) > 1e-9 {
+			t.Errorf("index %d: got %v, want %v", i, data[i]/8, orig[i])
+		}
+	}
+}
+
+func TestTransform2D(t *testing.T) {
+	// A cosine with 3 cycles across and 5 down peaks at (3, 5) and its
+	// mirror image (-3, -5)
+	const n = 16
+	data := make([]complex128, n*n)
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			data[y*n+x] = complex(math.Cos(2*math.Pi*(3*float64(x)+5*float64(y))/n), 0)
+		}
+	}
+	orig := append([]complex128(nil), data...)
+
+	Transform2D(data, n, false)
+	power := Power(data)
+	for i, p := range power {
+		u, v := Freq(i%n, n), Freq(i/n, n)
+		peak := (u == 3 && v == 5) || (u == -3 && v == -5)
+		if peak && math.Abs(p-n*n*n*n/4) > 1e-6 {
+			t.Errorf("peak (%d, %d): got power %v, want %v", u, v, p, n*n*n*n/4)
+		}
+		if !peak && p > 1e-6 {
+			t.Errorf("(%d, %d): got power %v, want 0", u, v, p)
+		}
+	}
+
+	Transform2D(data, n, true)
+	for i := range data {
+		if cmplx.Abs(data[i]-orig[i]) > 1e-9 {
+			t.Fatalf("round trip index %d: got %v, want %v", i, data[i], orig[i])
+		}
+	}
+}
+
+func TestFreqIndex(t *testing.T) {
+	for k := 0; k < 8; k++ {
+		f := Freq(k, 8)
+		if f < -4 || f >= 4 {
+			t.Errorf("Freq(%d, 8) = %d, out of range", k, f)
+		}
+		if got := Index(f, 8); got != k {
+			t.Errorf("Index(Freq(%d)) = %d", k, got)
+		}
+	}
+	if Freq(5, 8) != -3 || Index(-1, 8) != 7 {
+		t.Error("negative frequencies should wrap to the upper half")
+	}
+}
+
+func TestRefinePeak(t *testing.T) {
+	// A Hann-windowed sinusoid between spectrum samples
+	const n = 64
+	hann := Hann(n)
+	for _, f := range []float64{10, 10.25, 10.5, 17.8} {
+		data := make([]complex128, n)
+		for i := range data {
+			data[i] = complex(math.Sin(2*math.Pi*f*float64(i)/n)*hann[i], 0)
+		}
+		Transform(data, false)
+		power := Power(data)
+		k := int(math.Round(f))
+		if power[k-1] > power[k] || power[k+1] > power[k] {
+			k = int(f)
+		}
+		got := float64(k) + RefinePeak(power[k-1], power[k], power[k+1])
+		if math.Abs(got-f) > 0.05 {
+			t.Errorf("frequency %v: got %v", f, got)
+		}
+	}
+
+	if got := RefinePeak(1, 1, 1); got != 0 {
+		t.Errorf("flat: got %v, want 0", got)
+	}
+}
+
+func TestIsPowerOfTwo(t *testing.T) {
+	for n, want := range map[int]bool{0: false, 1: true, 2: true, 6: false, 64: true, -4: false} {
+		if got := IsPowerOfTwo(n); got != want {
+			t.Errorf("IsPowerOfTwo(%d) = %v, want %v", n, got, want)
+		}
+	}
+}
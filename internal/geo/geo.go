@@ -0,0 +1,193 @@
+// Package geo converts between the pixel coordinates of a map image and
+// geographic coordinates, for measuring on map screenshots and scanned
+// maps.
+//
+// A Transform is fitted to control points, pixels whose latitude and
+// longitude are known. The map is assumed to be drawn in one projection
+// with the same scale across and down, but may be rotated, as a scanned
+// sheet often is: pixel coordinates and projected coordinates are then
+// related by a similarity (scale, rotation, and offset), which two control
+// points determine. Web map tiles and most screenshots of online maps use
+// Web Mercator; world maps and many scanned maps use the equirectangular
+// (plate carrée) projection.
+//
+// Distances are great-circle distances on a sphere of the Earth's mean
+// radius, within about 0.5% of the ellipsoid.
+package geo
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+)
+
+// Projections of a map image.
+const (
+	Mercator        = "mercator"        // Web Mercator, as used by map tiles
+	Equirectangular = "equirectangular" // Plate carrée: degrees plotted evenly
+)
+
+// mercatorRadius is the radius of the Web Mercator sphere, maxMercatorLat
+// the latitude at which its square world map ends, and meanEarthRadius the
+// radius distances are measured on, all in meters.
+const (
+	mercatorRadius  = 6378137.0
+	maxMercatorLat  = 85.05112878
+	meanEarthRadius = 6371008.8
+)
+
+// ControlPoint is a pixel of a map image whose geographic coordinates are
+// known.
+type ControlPoint struct {
+	X   float64 `json:"x"`
+	Y   float64 `json:"y"`
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// Transform converts between the pixels of a map image and geographic
+// coordinates. Pixels are complex numbers x - iy, so that the imaginary
+// axis points up, and projected coordinates easting + i·northing; they
+// are related by projected = m·pixel + c.
+type Transform struct {
+	projection string
+	m, c       complex128
+	lon0       float64
+	residual   float64
+}
+
+// NewTransform fits a Transform to control points.
+//
+// Parameters:
+//   - points: At least two control points, at distinct pixels and
+//     distinct coordinates.
+//   - projection: Mercator or Equirectangular.
+//
+// Returns:
+//   - *Transform: The fitted transform.
+//   - error: Non-nil if the projection is unknown, a coordinate is out of
+//     range, or the points coincide.
+//
+// With two points the fit is exact. With more, it is the least-squares
+// similarity, and Residual reports how well the points agree with it.
+// Longitudes are taken within 180 degrees of the first point's, so maps
+// across the antimeridian work.
+func NewTransform(points []ControlPoint, projection string) (*Transform, error) {
+	if projection != Mercator && projection != Equirectangular {
+		return nil, fmt.Errorf("projection must be %q or %q, got %q", Mercator, Equirectangular, projection)
+	}
+	if len(points) < 2 {
+		return nil, fmt.Errorf("need at least 2 control points, got %d", len(points))
+	}
+	t := &Transform{projection: projection, lon0: points[0].Lon}
+	pix := make([]complex128, len(points))
+	proj := make([]complex128, len(points))
+	var pixMean, projMean complex128
+	for i, p := range points {
+		if math.Abs(p.Lat) > t.maxLat() || math.Abs(p.Lon) > 180 {
+			return nil, fmt.Errorf("control point %d: latitude must be within ±%g and longitude within ±180, got %g, %g", i+1, t.maxLat(), p.Lat, p.Lon)
+		}
+		pix[i] = complex(p.X, -p.Y)
+		proj[i] = t.project(p.Lat, p.Lon)
+		pixMean += pix[i]
+		projMean += proj[i]
+	}
+	n := complex(float64(len(points)), 0)
+	pixMean, projMean = pixMean/n, projMean/n
+
+	var num complex128
+	var den, spread float64
+	for i := range points {
+		dz, dw := pix[i]-pixMean, proj[i]-projMean
+		num += dw * cmplx.Conj(dz)
+		den += real(dz)*real(dz) + imag(dz)*imag(dz)
+		spread += real(dw)*real(dw) + imag(dw)*imag(dw)
+	}
+	if den < 1e-12 || spread < 1e-18 {
+		return nil, fmt.Errorf("control points must be at distinct pixels and distinct coordinates")
+	}
+	t.m = num / complex(den, 0)
+	t.c = projMean - t.m*pixMean
+
+	var sq float64
+	for i := range points {
+		d := cmplx.Abs((proj[i]-t.c)/t.m - pix[i])
+		sq += d * d
+	}
+	t.residual = math.Sqrt(sq / float64(len(points)))
+	return t, nil
+}
+
+// maxLat returns the largest latitude the projection can show.
+func (t *Transform) maxLat() float64 {
+	if t.projection == Mercator {
+		return maxMercatorLat
+	}
+	return 90
+}
+
+// project returns the projected coordinates of (lat, lon): meters for
+// Mercator, degrees for equirectangular.
+func (t *Transform) project(lat, lon float64) complex128 {
+	lon = t.lon0 + math.Remainder(lon-t.lon0, 360)
+	if t.projection == Equirectangular {
+		return complex(lon, lat)
+	}
+	lat = math.Max(-maxMercatorLat, math.Min(maxMercatorLat, lat))
+	phi := lat * math.Pi / 180
+	return complex(mercatorRadius*lon*math.Pi/180, mercatorRadius*math.Log(math.Tan(math.Pi/4+phi/2)))
+}
+
+// ToGeo returns the latitude and longitude of pixel (x, y). Longitudes
+// are in [-180, 180).
+func (t *Transform) ToGeo(x, y float64) (lat, lon float64) {
+	w := t.m*complex(x, -y) + t.c
+	if t.projection == Equirectangular {
+		lat, lon = imag(w), real(w)
+	} else {
+		lat = (2*math.Atan(math.Exp(imag(w)/mercatorRadius)) - math.Pi/2) * 180 / math.Pi
+		lon = real(w) / mercatorRadius * 180 / math.Pi
+	}
+	return lat, math.Mod(math.Mod(lon+180, 360)+360, 360) - 180
+}
+
+// ToPixel returns the pixel at latitude lat and longitude lon, which may
+// lie outside the image. Mercator latitudes beyond ±85.05 are clamped.
+func (t *Transform) ToPixel(lat, lon float64) (x, y float64) {
+	z := (t.project(lat, lon) - t.c) / t.m
+	return real(z), -imag(z)
+}
+
+// NorthDegrees returns the direction of north in the image, in degrees
+// clockwise from up: 0 for a north-up map, 90 if north points right.
+func (t *Transform) NorthDegrees() float64 {
+	return cmplx.Phase(t.m) * 180 / math.Pi
+}
+
+// Residual returns the root-mean-square distance, in pixels, between the
+// control points and where the transform puts their coordinates; 0 for
+// two points.
+func (t *Transform) Residual() float64 {
+	return t.residual
+}
+
+// MetersPerPixel returns the ground distance, in meters, one pixel spans
+// at pixel (x, y): the mean of a step right and a step down. On a
+// Mercator map it grows toward the equator; on an equirectangular map a
+// step right is shorter than a step down away from the equator.
+func (t *Transform) MetersPerPixel(x, y float64) float64 {
+	lat, lon := t.ToGeo(x, y)
+	rLat, rLon := t.ToGeo(x+1, y)
+	dLat, dLon := t.ToGeo(x, y+1)
+	return (Distance(lat, lon, rLat, rLon) + Distance(lat, lon, dLat, dLon)) / 2
+}
+
+// Distance returns the great-circle distance, in meters, between two
+// points given in degrees (the haversine formula).
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1, phi2 := lat1*math.Pi/180, lat2*math.Pi/180
+	dPhi := phi2 - phi1
+	dLambda := (lon2 - lon1) * math.Pi / 180
+	h := math.Sin(dPhi/2)*math.Sin(dPhi/2) + math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+	return 2 * meanEarthRadius * math.Asin(math.Sqrt(math.Min(1, h)))
+}
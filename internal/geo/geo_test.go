@@ -0,0 +1,134 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+// tilePixel returns the global pixel of (lat, lon) on Web Mercator map
+// tiles of 256 pixels at zoom level z.
+func tilePixel(lat, lon float64, z int) (x, y float64) {
+	size := 256 * math.Exp2(float64(z))
+	phi := lat * math.Pi / 180
+	x = (lon + 180) / 360 * size
+	y = (1 - math.Log(math.Tan(phi)+1/math.Cos(phi))/math.Pi) / 2 * size
+	return x, y
+}
+
+// controlPoint returns the control point of (lat, lon) on tiles at zoom
+// level z, relative to pixel (x0, y0).
+func controlPoint(lat, lon float64, z int, x0, y0 float64) ControlPoint {
+	x, y := tilePixel(lat, lon, z)
+	return ControlPoint{X: x - x0, Y: y - y0, Lat: lat, Lon: lon}
+}
+
+func TestTransform_Mercator(t *testing.T) {
+	// A 1024-pixel screenshot of central Paris at zoom 14
+	x0, y0 := tilePixel(48.87, 2.30, 14)
+	tr, err := NewTransform([]ControlPoint{
+		controlPoint(48.8584, 2.2945, 14, x0, y0), // Eiffel Tower
+		controlPoint(48.8530, 2.3499, 14, x0, y0), // Notre-Dame
+	}, Mercator)
+	if err != nil {
+		t.Fatalf("NewTransform failed: %v", err)
+	}
+
+	// The Arc de Triomphe lies where the tiles put it
+	want := controlPoint(48.8738, 2.2950, 14, x0, y0)
+	lat, lon := tr.ToGeo(want.X, want.Y)
+	if math.Abs(lat-want.Lat) > 1e-6 || math.Abs(lon-want.Lon) > 1e-6 {
+		t.Errorf("ToGeo = %g, %g, want %g, %g", lat, lon, want.Lat, want.Lon)
+	}
+	x, y := tr.ToPixel(want.Lat, want.Lon)
+	if math.Abs(x-want.X) > 1e-3 || math.Abs(y-want.Y) > 1e-3 {
+		t.Errorf("ToPixel = %g, %g, want %g, %g", x, y, want.X, want.Y)
+	}
+	if n := tr.NorthDegrees(); math.Abs(n) > 1e-9 || tr.Residual() > 1e-9 {
+		t.Errorf("north %g, residual %g, want 0 and 0", n, tr.Residual())
+	}
+
+	// Zoom 14 tiles are 9.55 m per pixel at the equator, times cos(lat)
+	if mpp := tr.MetersPerPixel(512, 512); math.Abs(mpp-9.5546*math.Cos(48.87*math.Pi/180)) > 0.05 {
+		t.Errorf("MetersPerPixel = %g", mpp)
+	}
+}
+
+func TestTransform_EquirectangularRotated(t *testing.T) {
+	// A world map turned a quarter clockwise: north points right, and
+	// east down
+	tr, err := NewTransform([]ControlPoint{
+		{X: 90, Y: 180, Lat: 0, Lon: 0},
+		{X: 150, Y: 60, Lat: 60, Lon: -120},
+	}, Equirectangular)
+	if err != nil {
+		t.Fatalf("NewTransform failed: %v", err)
+	}
+	if n := tr.NorthDegrees(); math.Abs(n-90) > 1e-9 {
+		t.Errorf("north = %g degrees, want 90", n)
+	}
+	if lat, lon := tr.ToGeo(10, 300); math.Abs(lat+80) > 1e-9 || math.Abs(lon-120) > 1e-9 {
+		t.Errorf("ToGeo = %g, %g, want -80, 120", lat, lon)
+	}
+}
+
+func TestTransform_Antimeridian(t *testing.T) {
+	tr, err := NewTransform([]ControlPoint{
+		{X: 0, Y: 0, Lat: -16, Lon: 178},
+		{X: 400, Y: 200, Lat: -18, Lon: -178},
+	}, Equirectangular)
+	if err != nil {
+		t.Fatalf("NewTransform failed: %v", err)
+	}
+	if x, y := tr.ToPixel(-17, 180); math.Abs(x-200) > 1e-9 || math.Abs(y-100) > 1e-9 {
+		t.Errorf("ToPixel(-17, 180) = %g, %g, want the middle", x, y)
+	}
+	if _, lon := tr.ToGeo(300, 150); math.Abs(lon+179) > 1e-9 {
+		t.Errorf("longitude = %g, want -179", lon)
+	}
+}
+
+func TestTransform_Residual(t *testing.T) {
+	tr, err := NewTransform([]ControlPoint{
+		{X: 0, Y: 0, Lat: 10, Lon: 0},
+		{X: 100, Y: 0, Lat: 10, Lon: 10},
+		{X: 100, Y: 100, Lat: 0, Lon: 10},
+		{X: 0, Y: 104, Lat: 0, Lon: 0},
+	}, Equirectangular)
+	if err != nil {
+		t.Fatalf("NewTransform failed: %v", err)
+	}
+	if r := tr.Residual(); r < 1 || r > 3 {
+		t.Errorf("residual = %g pixels, want about 2 for a point 4 pixels off", r)
+	}
+}
+
+func TestNewTransform_Invalid(t *testing.T) {
+	a := ControlPoint{X: 0, Y: 0, Lat: 10, Lon: 10}
+	b := ControlPoint{X: 100, Y: 100, Lat: 0, Lon: 20}
+	for _, tc := range []struct {
+		name       string
+		points     []ControlPoint
+		projection string
+	}{
+		{"unknown projection", []ControlPoint{a, b}, "lambert"},
+		{"one point", []ControlPoint{a}, Mercator},
+		{"same pixel", []ControlPoint{a, {X: 0, Y: 0, Lat: 0, Lon: 20}}, Mercator},
+		{"same coordinates", []ControlPoint{a, {X: 100, Y: 100, Lat: 10, Lon: 10}}, Mercator},
+		{"beyond Mercator", []ControlPoint{a, {X: 100, Y: 100, Lat: 89, Lon: 20}}, Mercator},
+		{"longitude", []ControlPoint{a, {X: 100, Y: 100, Lat: 0, Lon: 200}}, Equirectangular},
+	} {
+		if _, err := NewTransform(tc.points, tc.projection); err == nil {
+			t.Errorf("%s: expected error", tc.name)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	if d := Distance(0, 0, 1, 0); math.Abs(d-111195) > 1 {
+		t.Errorf("one degree of latitude = %g m", d)
+	}
+	// Paris to London
+	if d := Distance(48.8566, 2.3522, 51.5074, -0.1278); math.Abs(d-343.5e3) > 1e3 {
+		t.Errorf("Paris to London = %g m", d)
+	}
+}
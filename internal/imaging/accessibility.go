@@ -0,0 +1,370 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+)
+
+// Thresholds for ColorVisionConflicts.
+const (
+	// visionConflictColors is the number of dominant colors compared.
+	visionConflictColors = 12
+
+	// visionNormalDistance is the smallest CIEDE2000 difference at which
+	// two colors are clearly distinct with normal color vision.
+	visionNormalDistance = 20
+
+	// visionSimulatedDistance is the CIEDE2000 difference below which two
+	// colors are hard to tell apart under a simulated deficiency.
+	visionSimulatedDistance = 8
+)
+
+// colorVisionDeficiencies are the simulated deficiencies, in report order,
+// each as a linear-RGB matrix for full severity from Machado, Oliveira,
+// and Fernandes (2009).
+var colorVisionDeficiencies = []struct {
+	name   string
+	matrix [3][3]float64
+}{
+	{"protanopia", [3][3]float64{
+		{0.152286, 1.052583, -0.204868},
+		{0.114503, 0.786281, 0.099216},
+		{-0.003882, -0.048116, 1.051998},
+	}},
+	{"deuteranopia", [3][3]float64{
+		{0.367322, 0.860646, -0.227968},
+		{0.280085, 0.672501, 0.047413},
+		{-0.011820, 0.042940, 0.968881},
+	}},
+	{"tritanopia", [3][3]float64{
+		{1.255528, -0.076749, -0.178779},
+		{-0.078411, 0.930809, 0.147602},
+		{0.004733, 0.691367, 0.303900},
+	}},
+}
+
+// TextContrastResult describes the text colors and size within a region.
+type TextContrastResult struct {
+	// Foreground and Background are the colors of the text and of what
+	// surrounds it, as "#RRGGBB".
+	Foreground string `json:"foreground"`
+	Background string `json:"background"`
+
+	// Ratio is the WCAG 2 contrast ratio between them (1 to 21).
+	Ratio float64 `json:"ratio"`
+
+	// InkShare is the fraction (0.0-1.0) of the region's pixels that
+	// belong to the text.
+	InkShare float64 `json:"ink_share"`
+
+	// Lines is the number of text lines found: runs of pixel rows that
+	// contain text, separated by rows that do not.
+	Lines int `json:"lines"`
+
+	// LineHeight is the median height in pixels of those runs, from the
+	// top of the tallest glyph to the bottom of the lowest.
+	LineHeight int `json:"line_height"`
+}
+
+// ColorVisionConflict is a pair of colors that differ clearly with normal
+// color vision but look alike under a color vision deficiency.
+type ColorVisionConflict struct {
+	// Deficiency is "protanopia", "deuteranopia", or "tritanopia": the one
+	// under which the colors are hardest to tell apart.
+	Deficiency string `json:"deficiency"`
+
+	// Colors are the two colors, more common first, as by DominantColors.
+	Colors [2]ColorFrequency `json:"colors"`
+
+	// Bounds are the bounding boxes of the pixels of each color.
+	Bounds [2]Region `json:"bounds"`
+
+	// NormalDistance and SimulatedDistance are the CIEDE2000 differences
+	// between the colors with normal vision and under the deficiency.
+	NormalDistance    float64 `json:"normal_distance"`
+	SimulatedDistance float64 `json:"simulated_distance"`
+}
+
+// ContrastRatio returns the WCAG 2 contrast ratio between two colors:
+// (L1 + 0.05) / (L2 + 0.05), where L1 and L2 are the relative luminances
+// of the lighter and darker color. It ranges from 1 (identical
+// luminance) to 21 (black on white).
+func ContrastRatio(a, b RGBColor) float64 {
+	la, lb := relativeLuminance(a), relativeLuminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// TextContrast measures the text in a region: its color, the background
+// color, their contrast ratio, and the height of its lines.
+//
+// Parameters:
+//   - img: Source image.
+//   - r: Region holding the text, such as one found by text region
+//     detection, with some background around it. It is clipped to the
+//     image.
+//
+// Returns:
+//   - *TextContrastResult: The colors, contrast ratio, and line metrics.
+//   - error: Non-nil if the region is smaller than 2x2 pixels inside the
+//     image, or holds a single luminance, so no text can be told apart.
+//
+// # Method
+//
+// The region's luminance is split into two classes by Otsu's method; the
+// larger class is the background and the smaller the text. The
+// background color is the mean of its class. Anti-aliased glyph edges
+// blend toward the background, so the text color is the mean of only the
+// text pixels at least as far from the background luminance as the class
+// mean, which approximates the color the text was drawn in.
+//
+// A row belongs to a text line if it holds any text pixel. Runs of a
+// single row, such as underlines, are not counted as lines.
+func TextContrast(img image.Image, r Region) (*TextContrastResult, error) {
+	rect := image.Rect(r.X1, r.Y1, r.X2, r.Y2).Intersect(img.Bounds())
+	w, h := rect.Dx(), rect.Dy()
+	if w < 2 || h < 2 {
+		return nil, fmt.Errorf("region %+v must cover at least 2x2 pixels of the image", r)
+	}
+
+	pix := make([][3]uint8, w*h)
+	lum := make([]uint8, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			cr, cg, cb, _ := img.At(rect.Min.X+x, rect.Min.Y+y).RGBA()
+			i := y*w + x
+			pix[i] = [3]uint8{uint8(cr >> 8), uint8(cg >> 8), uint8(cb >> 8)}
+			lum[i] = uint8(math.Round((0.299*float64(cr) + 0.587*float64(cg) + 0.114*float64(cb)) / 257))
+		}
+	}
+	t := otsuThreshold(lum)
+	dark := 0
+	for _, v := range lum {
+		if int(v) < t {
+			dark++
+		}
+	}
+	if dark == 0 || dark == len(lum) {
+		return nil, fmt.Errorf("region %+v has a single luminance", r)
+	}
+	// Text is the smaller class: dark text on a light background or the
+	// reverse
+	darkText := dark*2 <= len(lum)
+	isText := func(v uint8) bool { return (int(v) < t) == darkText }
+
+	var bgSum, fgSum [3]float64
+	var bgLum, fgLum float64
+	bgN, fgN := 0, 0
+	for i, v := range lum {
+		if isText(v) {
+			fgLum += float64(v)
+			fgN++
+		} else {
+			for c := 0; c < 3; c++ {
+				bgSum[c] += float64(pix[i][c])
+			}
+			bgLum += float64(v)
+			bgN++
+		}
+	}
+	bgLum /= float64(bgN)
+	fgLum /= float64(fgN)
+	core := 0
+	for i, v := range lum {
+		if isText(v) && math.Abs(float64(v)-bgLum) >= math.Abs(fgLum-bgLum) {
+			for c := 0; c < 3; c++ {
+				fgSum[c] += float64(pix[i][c])
+			}
+			core++
+		}
+	}
+	mean := func(sum [3]float64, n int) RGBColor {
+		return RGBColor{
+			R: uint8(math.Round(sum[0] / float64(n))),
+			G: uint8(math.Round(sum[1] / float64(n))),
+			B: uint8(math.Round(sum[2] / float64(n))),
+		}
+	}
+	fg, bg := mean(fgSum, core), mean(bgSum, bgN)
+
+	// Text lines are runs of rows holding text pixels
+	var heights []int
+	run := 0
+	for y := 0; y <= h; y++ {
+		inked := false
+		for x := 0; y < h && x < w && !inked; x++ {
+			inked = isText(lum[y*w+x])
+		}
+		if inked {
+			run++
+			continue
+		}
+		if run > 1 {
+			heights = append(heights, run)
+		}
+		run = 0
+	}
+	result := &TextContrastResult{
+		Foreground: fmt.Sprintf("#%02X%02X%02X", fg.R, fg.G, fg.B),
+		Background: fmt.Sprintf("#%02X%02X%02X", bg.R, bg.G, bg.B),
+		Ratio:      math.Round(ContrastRatio(fg, bg)*100) / 100,
+		InkShare:   math.Round(float64(fgN)/float64(len(lum))*10000) / 10000,
+		Lines:      len(heights),
+	}
+	if len(heights) > 0 {
+		sort.Ints(heights)
+		result.LineHeight = heights[len(heights)/2]
+	}
+	return result, nil
+}
+
+// ColorVisionConflicts finds pairs of an image's dominant colors that are
+// clearly distinct with normal color vision but hard to tell apart under
+// protanopia, deuteranopia, or tritanopia, such as the red and green of
+// status indicators.
+//
+// Parameters:
+//   - img: Source image.
+//   - minPercentage: Share of the image (0-100) a color must cover to be
+//     compared, so anti-aliasing blends are ignored. Typical: 0.5.
+//
+// Returns:
+//   - []ColorVisionConflict: The conflicting pairs, hardest to tell apart
+//     first. Empty if there are none.
+//   - error: Non-nil if minPercentage is outside 0-100.
+//
+// # Method
+//
+// The 12 most common colors (quantized as by DominantColors) covering at
+// least minPercentage are compared in pairs. A pair conflicts when its
+// CIEDE2000 difference is at least 20 with normal vision but below 8 once
+// both colors are passed through the full-severity simulation matrices of
+// Machado, Oliveira, and Fernandes (2009), applied in linear RGB. Colors
+// that differ in lightness stay apart under every simulation, so only
+// pairs told apart by hue alone are reported.
+func ColorVisionConflicts(img image.Image, minPercentage float64) ([]ColorVisionConflict, error) {
+	if minPercentage < 0 || minPercentage > 100 {
+		return nil, fmt.Errorf("min percentage must be between 0 and 100, got %v", minPercentage)
+	}
+	dominant, err := DominantColors(img, visionConflictColors, nil)
+	if err != nil {
+		return nil, err
+	}
+	var colors []ColorFrequency
+	for _, c := range dominant.Colors {
+		if c.Percentage >= minPercentage {
+			colors = append(colors, c)
+		}
+	}
+	// Order colors of equal share by hex, so pairs are reported the same
+	// way on every call
+	sort.SliceStable(colors, func(i, j int) bool {
+		if colors[i].Percentage != colors[j].Percentage {
+			return colors[i].Percentage > colors[j].Percentage
+		}
+		return colors[i].Hex < colors[j].Hex
+	})
+
+	conflicts := []ColorVisionConflict{}
+	for i := 0; i < len(colors); i++ {
+		for j := i + 1; j < len(colors); j++ {
+			a, b := colors[i].RGB, colors[j].RGB
+			normal := deltaE2000(rgbToLab(a.R, a.G, a.B), rgbToLab(b.R, b.G, b.B))
+			if normal < visionNormalDistance {
+				continue
+			}
+			best, bestName := math.Inf(1), ""
+			for _, d := range colorVisionDeficiencies {
+				sa, sb := simulateDeficiency(a, &d.matrix), simulateDeficiency(b, &d.matrix)
+				if dist := deltaE2000(rgbToLab(sa.R, sa.G, sa.B), rgbToLab(sb.R, sb.G, sb.B)); dist < best {
+					best, bestName = dist, d.name
+				}
+			}
+			if best >= visionSimulatedDistance {
+				continue
+			}
+			ca, cb := colors[i], colors[j]
+			ca.Percentage = math.Round(ca.Percentage*100) / 100
+			cb.Percentage = math.Round(cb.Percentage*100) / 100
+			conflicts = append(conflicts, ColorVisionConflict{
+				Deficiency:        bestName,
+				Colors:            [2]ColorFrequency{ca, cb},
+				NormalDistance:    math.Round(normal*100) / 100,
+				SimulatedDistance: math.Round(best*100) / 100,
+			})
+		}
+	}
+	if len(conflicts) == 0 {
+		return conflicts, nil
+	}
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		return conflicts[i].SimulatedDistance < conflicts[j].SimulatedDistance
+	})
+
+	// Locate each conflicting color in one pass over the image
+	boxes := make(map[RGBColor]*image.Rectangle)
+	for _, c := range conflicts {
+		for _, col := range c.Colors {
+			boxes[col.RGB] = &image.Rectangle{}
+		}
+	}
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			cr, cg, cb, _ := img.At(x, y).RGBA()
+			q := RGBColor{R: uint8((cr >> 8) / 16 * 16), G: uint8((cg >> 8) / 16 * 16), B: uint8((cb >> 8) / 16 * 16)}
+			if box, ok := boxes[q]; ok {
+				*box = box.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	for i := range conflicts {
+		for k, col := range conflicts[i].Colors {
+			box := boxes[col.RGB]
+			conflicts[i].Bounds[k] = Region{X1: box.Min.X, Y1: box.Min.Y, X2: box.Max.X, Y2: box.Max.Y}
+		}
+	}
+	return conflicts, nil
+}
+
+// relativeLuminance returns the WCAG 2 relative luminance of a color
+// (0 for black to 1 for white).
+func relativeLuminance(c RGBColor) float64 {
+	return 0.2126*srgbToLinear(c.R) + 0.7152*srgbToLinear(c.G) + 0.0722*srgbToLinear(c.B)
+}
+
+// simulateDeficiency returns c as seen with the color vision deficiency
+// whose linear-RGB simulation matrix is m.
+func simulateDeficiency(c RGBColor, m *[3][3]float64) RGBColor {
+	in := [3]float64{srgbToLinear(c.R), srgbToLinear(c.G), srgbToLinear(c.B)}
+	var out [3]uint8
+	for i := 0; i < 3; i++ {
+		out[i] = linearToSRGB(m[i][0]*in[0] + m[i][1]*in[1] + m[i][2]*in[2])
+	}
+	return RGBColor{R: out[0], G: out[1], B: out[2]}
+}
+
+// srgbToLinear converts an 8-bit sRGB component to linear light (0-1).
+func srgbToLinear(v uint8) float64 {
+	c := float64(v) / 255
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return math.Pow((c+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB converts linear light to an 8-bit sRGB component, clamping
+// values outside 0-1.
+func linearToSRGB(v float64) uint8 {
+	v = math.Max(0, math.Min(1, v))
+	if v <= 0.0031308 {
+		v *= 12.92
+	} else {
+		v = 1.055*math.Pow(v, 1/2.4) - 0.055
+	}
+	return uint8(math.Round(v * 255))
+}
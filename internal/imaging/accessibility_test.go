@@ -0,0 +1,126 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestContrastRatio(t *testing.T) {
+	tests := []struct {
+		a, b RGBColor
+		want float64
+	}{
+		{RGBColor{0, 0, 0}, RGBColor{255, 255, 255}, 21},
+		{RGBColor{255, 255, 255}, RGBColor{0, 0, 0}, 21},
+		{RGBColor{119, 119, 119}, RGBColor{255, 255, 255}, 4.48},
+		{RGBColor{0, 0, 255}, RGBColor{255, 255, 255}, 8.59},
+		{RGBColor{100, 100, 100}, RGBColor{100, 100, 100}, 1},
+	}
+	for _, tt := range tests {
+		if got := ContrastRatio(tt.a, tt.b); math.Abs(got-tt.want) > 0.01 {
+			t.Errorf("ContrastRatio(%v, %v) = %.3f, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// textBlock draws two lines of blocky "glyphs" of the given height in fg
+// on a bg background, with an anti-aliased column at each glyph edge.
+func textBlock(fg, bg color.RGBA, glyphHeight int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 200, 20+3*glyphHeight))
+	fillRect(img, 0, 0, 200, img.Bounds().Dy(), bg)
+	mid := color.RGBA{uint8((int(fg.R) + int(bg.R)) / 2), uint8((int(fg.G) + int(bg.G)) / 2), uint8((int(fg.B) + int(bg.B)) / 2), 255}
+	for line := 0; line < 2; line++ {
+		y := 10 + line*(glyphHeight*3/2)
+		for x := 10; x+6 < 190; x += 10 {
+			fillRect(img, x, y, x+5, y+glyphHeight, fg)
+			fillRect(img, x+5, y, x+6, y+glyphHeight, mid)
+		}
+	}
+	return img
+}
+
+func TestTextContrast(t *testing.T) {
+	img := textBlock(color.RGBA{118, 118, 118, 255}, color.RGBA{255, 255, 255, 255}, 12)
+	result, err := TextContrast(img, Region{X1: 0, Y1: 0, X2: 200, Y2: 200})
+	if err != nil {
+		t.Fatalf("TextContrast failed: %v", err)
+	}
+	if result.Foreground != "#767676" || result.Background != "#FFFFFF" {
+		t.Errorf("colors: got %s on %s, want #767676 on #FFFFFF", result.Foreground, result.Background)
+	}
+	if result.Ratio != 4.54 {
+		t.Errorf("ratio: got %v, want 4.54", result.Ratio)
+	}
+	if result.Lines != 2 || result.LineHeight != 12 {
+		t.Errorf("lines: got %d of height %d, want 2 of 12", result.Lines, result.LineHeight)
+	}
+
+	// Light text on a dark background
+	img = textBlock(color.RGBA{255, 255, 0, 255}, color.RGBA{0, 0, 128, 255}, 20)
+	result, err = TextContrast(img, Region{X1: 0, Y1: 0, X2: 200, Y2: 80})
+	if err != nil {
+		t.Fatalf("TextContrast failed: %v", err)
+	}
+	if result.Foreground != "#FFFF00" || result.Background != "#000080" || result.LineHeight != 20 {
+		t.Errorf("got %+v, want #FFFF00 on #000080, height 20", result)
+	}
+}
+
+func TestTextContrast_Errors(t *testing.T) {
+	img := createInMemoryImage(50, 50, color.White)
+	if _, err := TextContrast(img, Region{X1: 0, Y1: 0, X2: 50, Y2: 50}); err == nil {
+		t.Error("expected error for a flat region")
+	}
+	if _, err := TextContrast(img, Region{X1: 49, Y1: 0, X2: 80, Y2: 50}); err == nil {
+		t.Error("expected error for a region mostly outside the image")
+	}
+}
+
+func TestColorVisionConflicts(t *testing.T) {
+	// Red and green status dots on white: distinct, except with
+	// deuteranopia; the dark title stays distinct from both
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	fillRect(img, 0, 0, 200, 100, color.White)
+	fillRect(img, 20, 40, 40, 60, color.RGBA{208, 48, 48, 255})
+	fillRect(img, 120, 40, 140, 60, color.RGBA{48, 160, 48, 255})
+	fillRect(img, 20, 5, 180, 20, color.RGBA{16, 16, 16, 255})
+
+	conflicts, err := ColorVisionConflicts(img, 0.5)
+	if err != nil {
+		t.Fatalf("ColorVisionConflicts failed: %v", err)
+	}
+	if len(conflicts) != 1 {
+		t.Fatalf("got %d conflicts, want 1: %+v", len(conflicts), conflicts)
+	}
+	c := conflicts[0]
+	if c.Deficiency != "deuteranopia" {
+		t.Errorf("deficiency: got %s, want deuteranopia", c.Deficiency)
+	}
+	hexes := map[string]bool{c.Colors[0].Hex: true, c.Colors[1].Hex: true}
+	if !hexes["#D03030"] || !hexes["#30A030"] {
+		t.Errorf("colors: got %s and %s", c.Colors[0].Hex, c.Colors[1].Hex)
+	}
+	for k, col := range c.Colors {
+		want := Region{X1: 20, Y1: 40, X2: 40, Y2: 60}
+		if col.Hex == "#30A030" {
+			want = Region{X1: 120, Y1: 40, X2: 140, Y2: 60}
+		}
+		if c.Bounds[k] != want {
+			t.Errorf("bounds of %s: got %+v, want %+v", col.Hex, c.Bounds[k], want)
+		}
+	}
+	if c.NormalDistance < visionNormalDistance || c.SimulatedDistance >= visionSimulatedDistance {
+		t.Errorf("distances: normal %v simulated %v", c.NormalDistance, c.SimulatedDistance)
+	}
+
+	// Below the share threshold, the dots are ignored
+	conflicts, err = ColorVisionConflicts(img, 5)
+	if err != nil || len(conflicts) != 0 {
+		t.Errorf("with min 5%%: got %v, %v; want none", conflicts, err)
+	}
+	if _, err := ColorVisionConflicts(img, 101); err == nil {
+		t.Error("expected error for min percentage above 100")
+	}
+}
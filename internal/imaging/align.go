@@ -0,0 +1,185 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"math/cmplx"
+
+	"github.com/ironsheep/image-tools-mcp/internal/fft"
+)
+
+// maxAlignWindow is the largest square window (in pixels, a power of two)
+// used for phase correlation. Larger windows are more robust but cost
+// O(n² log n) time and memory.
+const maxAlignWindow = 512
+
+// AlignResult contains the estimated translation between two images and the
+// differences that remain after compensating for it.
+type AlignResult struct {
+	// OffsetX and OffsetY are the translation (in pixels) that maps a point in
+	// the reference image to the matching point in the target image:
+	// target(x+OffsetX, y+OffsetY) ≈ reference(x, y).
+	OffsetX int `json:"offset_x"`
+	OffsetY int `json:"offset_y"`
+
+	// Confidence is the height of the phase correlation peak (0.0-1.0).
+	// Values near 1.0 indicate a clean pure translation; values below ~0.1
+	// suggest the images share little content.
+	Confidence float64 `json:"confidence"`
+
+	// OverlapWidth and OverlapHeight are the dimensions of the area shared by
+	// both images once the offset is applied.
+	OverlapWidth  int `json:"overlap_width"`
+	OverlapHeight int `json:"overlap_height"`
+
+	// UnalignedPixelsDifferent is the number of differing pixels when the
+	// images are compared without any offset, for reference.
+	UnalignedPixelsDifferent int `json:"unaligned_pixels_different"`
+
+	// PixelsDifferent is the number of differing pixels in the aligned overlap.
+	PixelsDifferent int `json:"pixels_different"`
+
+	// DiffPercentage is PixelsDifferent as a percentage of the aligned overlap.
+	DiffPercentage float64 `json:"diff_percentage"`
+
+	// DiffBounds is the bounding box (in reference image coordinates) of the
+	// residual differences. Nil if the aligned images match.
+	DiffBounds *Region `json:"diff_bounds,omitempty"`
+}
+
+// Align estimates the translation between two nearly identical images using
+// phase correlation, then compares them with that offset applied.
+//
+// Parameters:
+//   - reference: The baseline image.
+//   - target: The image to align against the reference.
+//   - maxOffset: Largest translation (in pixels, per axis) to consider.
+//
+// Returns:
+//   - *AlignResult: Estimated offset and residual difference statistics.
+//   - error: Non-nil if maxOffset is negative or the images are too small.
+//
+// # Method
+//
+// A centered square window (up to 512×512) is taken from both images,
+// converted to grayscale and tapered with a Hann window. The normalized
+// cross-power spectrum of the two windows is inverse transformed, and the
+// location of its peak gives the integer translation. Only peaks within
+// maxOffset of the origin are considered, which rejects spurious matches in
+// repetitive content.
+//
+// Residual differences use the same per-pixel threshold as CompareRegions,
+// so a scrolled screenshot with no real changes reports zero differences.
+func Align(reference, target image.Image, maxOffset int) (*AlignResult, error) {
+	if maxOffset < 0 {
+		return nil, fmt.Errorf("max offset must be non-negative, got %d", maxOffset)
+	}
+
+	rb := reference.Bounds()
+	tb := target.Bounds()
+	minW := minInt(rb.Dx(), tb.Dx())
+	minH := minInt(rb.Dy(), tb.Dy())
+
+	n := 1
+	for n*2 <= minInt(minW, minH) && n*2 <= maxAlignWindow {
+		n *= 2
+	}
+	if n < 8 {
+		return nil, fmt.Errorf("images too small to align: %dx%d", minW, minH)
+	}
+	if maxOffset > n/2-1 {
+		maxOffset = n/2 - 1
+	}
+
+	// Use the same window position in both images so the correlation peak
+	// measures the content shift rather than a difference in window origin.
+	ox := (minW - n) / 2
+	oy := (minH - n) / 2
+	f1 := grayWindow(reference, rb.Min.X+ox, rb.Min.Y+oy, n)
+	f2 := grayWindow(target, tb.Min.X+ox, tb.Min.Y+oy, n)
+	fft.Transform2D(f1, n, false)
+	fft.Transform2D(f2, n, false)
+
+	// Normalized cross-power spectrum: conj(F1)·F2 / |conj(F1)·F2|
+	for i := range f1 {
+		p := cmplx.Conj(f1[i]) * f2[i]
+		if m := cmplx.Abs(p); m > 1e-12 {
+			f1[i] = p / complex(m, 0)
+		} else {
+			f1[i] = 0
+		}
+	}
+	fft.Transform2D(f1, n, true)
+
+	bestX, bestY := 0, 0
+	best := math.Inf(-1)
+	for dy := -maxOffset; dy <= maxOffset; dy++ {
+		for dx := -maxOffset; dx <= maxOffset; dx++ {
+			v := real(f1[((dy+n)%n)*n+(dx+n)%n])
+			if v > best {
+				best, bestX, bestY = v, dx, dy
+			}
+		}
+	}
+
+	result := &AlignResult{
+		OffsetX:    bestX,
+		OffsetY:    bestY,
+		Confidence: math.Round(math.Max(0, math.Min(best, 1))*1000) / 1000,
+	}
+
+	result.UnalignedPixelsDifferent, _, _ = countOffsetDiff(reference, target, 0, 0)
+
+	diff, total, bounds := countOffsetDiff(reference, target, bestX, bestY)
+	result.PixelsDifferent = diff
+	result.DiffBounds = bounds
+	if total > 0 {
+		result.DiffPercentage = math.Round(float64(diff)/float64(total)*10000) / 100
+	}
+	result.OverlapWidth, result.OverlapHeight = overlapSize(rb, tb, bestX, bestY)
+
+	return result, nil
+}
+
+// overlapSize returns the dimensions of the area where reference(x, y) and
+// target(x+dx, y+dy) are both defined.
+func overlapSize(rb, tb image.Rectangle, dx, dy int) (int, int) {
+	w := minInt(rb.Dx(), tb.Dx()-dx) - maxInt(0, -dx)
+	h := minInt(rb.Dy(), tb.Dy()-dy) - maxInt(0, -dy)
+	return maxInt(w, 0), maxInt(h, 0)
+}
+
+// countOffsetDiff compares reference(x, y) with target(x+dx, y+dy) over
+// their overlap. It returns the number of differing pixels, the number of
+// pixels compared, and the bounding box of the differences in reference
+// coordinates (nil if none).
+func countOffsetDiff(reference, target image.Image, dx, dy int) (int, int, *Region) {
+	rb := reference.Bounds()
+	tb := target.Bounds()
+	w, h := overlapSize(rb, tb, dx, dy)
+	x0 := maxInt(0, -dx)
+	y0 := maxInt(0, -dy)
+
+	diff := 0
+	var bounds *Region
+	for y := y0; y < y0+h; y++ {
+		for x := x0; x < x0+w; x++ {
+			c1 := reference.At(rb.Min.X+x, rb.Min.Y+y)
+			c2 := target.At(tb.Min.X+x+dx, tb.Min.Y+y+dy)
+			if pixelDiff(c1, c2) <= pixelDiffThreshold {
+				continue
+			}
+			diff++
+			if bounds == nil {
+				bounds = &Region{X1: x, Y1: y, X2: x + 1, Y2: y + 1}
+			} else {
+				bounds.X1 = minInt(bounds.X1, x)
+				bounds.Y1 = minInt(bounds.Y1, y)
+				bounds.X2 = maxInt(bounds.X2, x+1)
+				bounds.Y2 = maxInt(bounds.Y2, y+1)
+			}
+		}
+	}
+	return diff, w * h, bounds
+}
@@ -0,0 +1,121 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// createTexturedImage creates an image with non-repeating content whose
+// pixel at (x, y) depends only on (x+shiftX, y+shiftY), so two calls with
+// different shifts produce translated copies of the same scene.
+func createTexturedImage(width, height, shiftX, shiftY int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			sx, sy := x+shiftX, y+shiftY
+			v := uint8((sx*sx*7 + sy*13 + (sx*sy)%97) % 256)
+			img.Set(x, y, color.RGBA{v, v / 2, 255 - v, 255})
+		}
+	}
+	return img
+}
+
+func TestAlign_Identical(t *testing.T) {
+	img := createTexturedImage(128, 96, 0, 0)
+
+	result, err := Align(img, img, 16)
+	if err != nil {
+		t.Fatalf("Align failed: %v", err)
+	}
+
+	if result.OffsetX != 0 || result.OffsetY != 0 {
+		t.Errorf("offset: got (%d,%d), want (0,0)", result.OffsetX, result.OffsetY)
+	}
+	if result.PixelsDifferent != 0 {
+		t.Errorf("PixelsDifferent: got %d, want 0", result.PixelsDifferent)
+	}
+	if result.DiffBounds != nil {
+		t.Errorf("DiffBounds: got %+v, want nil", result.DiffBounds)
+	}
+	if result.Confidence < 0.9 {
+		t.Errorf("Confidence: got %v, want >= 0.9", result.Confidence)
+	}
+}
+
+func TestAlign_Translation(t *testing.T) {
+	tests := []struct {
+		name   string
+		dx, dy int
+	}{
+		{"right", 2, 0},
+		{"down", 0, 1},
+		{"up-left", -3, -2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reference := createTexturedImage(160, 120, 0, 0)
+			// target(x+dx, y+dy) == reference(x, y)
+			target := createTexturedImage(160, 120, -tt.dx, -tt.dy)
+
+			result, err := Align(reference, target, 16)
+			if err != nil {
+				t.Fatalf("Align failed: %v", err)
+			}
+
+			if result.OffsetX != tt.dx || result.OffsetY != tt.dy {
+				t.Errorf("offset: got (%d,%d), want (%d,%d)", result.OffsetX, result.OffsetY, tt.dx, tt.dy)
+			}
+			if result.PixelsDifferent != 0 {
+				t.Errorf("PixelsDifferent: got %d, want 0", result.PixelsDifferent)
+			}
+			if result.UnalignedPixelsDifferent == 0 {
+				t.Error("UnalignedPixelsDifferent should be non-zero for shifted images")
+			}
+			if result.OverlapWidth != 160-abs(tt.dx) || result.OverlapHeight != 120-abs(tt.dy) {
+				t.Errorf("overlap: got %dx%d, want %dx%d", result.OverlapWidth, result.OverlapHeight,
+					160-abs(tt.dx), 120-abs(tt.dy))
+			}
+		})
+	}
+}
+
+func TestAlign_ResidualDifferences(t *testing.T) {
+	reference := createTexturedImage(128, 128, 0, 0)
+	target := createTexturedImage(128, 128, -1, -2)
+
+	// Paint a block in the target at what is reference (20..30, 40..45)
+	for y := 40; y < 45; y++ {
+		for x := 20; x < 30; x++ {
+			target.Set(x+1, y+2, color.RGBA{0, 255, 0, 255})
+		}
+	}
+
+	result, err := Align(reference, target, 8)
+	if err != nil {
+		t.Fatalf("Align failed: %v", err)
+	}
+
+	if result.OffsetX != 1 || result.OffsetY != 2 {
+		t.Fatalf("offset: got (%d,%d), want (1,2)", result.OffsetX, result.OffsetY)
+	}
+	if result.DiffBounds == nil {
+		t.Fatal("DiffBounds should not be nil")
+	}
+	if *result.DiffBounds != (Region{X1: 20, Y1: 40, X2: 30, Y2: 45}) {
+		t.Errorf("DiffBounds: got %+v, want {20 40 30 45}", *result.DiffBounds)
+	}
+}
+
+func TestAlign_Errors(t *testing.T) {
+	img := createTexturedImage(64, 64, 0, 0)
+	tiny := createTexturedImage(4, 4, 0, 0)
+
+	if _, err := Align(img, img, -1); err == nil {
+		t.Error("Align should fail with negative max offset")
+	}
+	if _, err := Align(tiny, tiny, 2); err == nil {
+		t.Error("Align should fail for images that are too small")
+	}
+}
@@ -0,0 +1,127 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// Alpha modes for CompareRegionsAlpha and DominantColorsAlpha.
+const (
+	// AlphaIgnore reads the color channels as stored and ignores alpha,
+	// so transparent pixels count by their (usually black) color.
+	AlphaIgnore = "ignore"
+
+	// AlphaContent treats fully transparent pixels as no content: they
+	// are left out of the analysis. Other pixels count by their color
+	// without alpha applied.
+	AlphaContent = "content"
+
+	// AlphaComposite composites every pixel over a background color
+	// first, as the image would appear on it.
+	AlphaComposite = "composite"
+)
+
+// AlphaOptions selects how an analysis treats transparency. The zero value
+// ignores alpha.
+type AlphaOptions struct {
+	// Mode is AlphaIgnore, AlphaContent, or AlphaComposite; "" is
+	// AlphaIgnore.
+	Mode string
+
+	// Background is the hex color (#RRGGBB) composited under the image in
+	// AlphaComposite mode; "" is white. Ignored in other modes.
+	Background string
+}
+
+// AlphaSummary describes the transparency of the pixels analyzed.
+type AlphaSummary struct {
+	// TransparentPixels have alpha 0.
+	TransparentPixels int `json:"transparent_pixels"`
+
+	// TranslucentPixels have alpha from 1 to 254.
+	TranslucentPixels int `json:"translucent_pixels"`
+
+	// OpaquePixels have alpha 255.
+	OpaquePixels int `json:"opaque_pixels"`
+
+	// MeanAlpha is the mean alpha (0-255) of all the pixels.
+	MeanAlpha float64 `json:"mean_alpha"`
+}
+
+// alphaReader reads pixels for analysis under AlphaOptions.
+type alphaReader struct {
+	mode    string
+	r, g, b uint32 // Background, 16-bit
+
+	// Alpha counts and sum (16-bit) of the pixels read
+	transparent, translucent, opaque int
+	alphaSum                         uint64
+}
+
+// reader validates o and returns a reader for it.
+func (o AlphaOptions) reader() (*alphaReader, error) {
+	ar := &alphaReader{mode: o.Mode}
+	switch o.Mode {
+	case "", AlphaIgnore:
+		ar.mode = AlphaIgnore
+	case AlphaContent:
+	case AlphaComposite:
+		ar.r, ar.g, ar.b = 0xffff, 0xffff, 0xffff
+		if o.Background != "" {
+			bg, err := parseHexColor(o.Background)
+			if err != nil {
+				return nil, fmt.Errorf("invalid background color: %w", err)
+			}
+			ar.r, ar.g, ar.b = uint32(bg.R)*0x101, uint32(bg.G)*0x101, uint32(bg.B)*0x101
+		}
+	default:
+		return nil, fmt.Errorf("invalid alpha mode %q: must be %s, %s, or %s", o.Mode, AlphaIgnore, AlphaContent, AlphaComposite)
+	}
+	return ar, nil
+}
+
+// at returns the 16-bit color of a pixel to analyze, and whether it is
+// content. It also counts the pixel's alpha toward summary.
+func (ar *alphaReader) at(img image.Image, x, y int) (r, g, b uint32, content bool) {
+	r, g, b, a := img.At(x, y).RGBA()
+	ar.alphaSum += uint64(a)
+	switch a {
+	case 0:
+		ar.transparent++
+	case 0xffff:
+		ar.opaque++
+	default:
+		ar.translucent++
+	}
+
+	switch ar.mode {
+	case AlphaContent:
+		if a == 0 {
+			return 0, 0, 0, false
+		}
+		if a != 0xffff {
+			r, g, b = r*0xffff/a, g*0xffff/a, b*0xffff/a
+		}
+	case AlphaComposite:
+		r += ar.r * (0xffff - a) / 0xffff
+		g += ar.g * (0xffff - a) / 0xffff
+		b += ar.b * (0xffff - a) / 0xffff
+	}
+	return r, g, b, true
+}
+
+// summary returns the transparency of the pixels read, or nil if they were
+// all opaque.
+func (ar *alphaReader) summary() *AlphaSummary {
+	n := ar.transparent + ar.translucent + ar.opaque
+	if n == ar.opaque {
+		return nil
+	}
+	return &AlphaSummary{
+		TransparentPixels: ar.transparent,
+		TranslucentPixels: ar.translucent,
+		OpaquePixels:      ar.opaque,
+		MeanAlpha:         math.Round(float64(ar.alphaSum)/float64(n)/257*100) / 100,
+	}
+}
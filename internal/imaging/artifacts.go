@@ -0,0 +1,562 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+)
+
+// Tunables for DetectArtifacts.
+const (
+	maxArtifactExamples = 5
+
+	jpegBlockSize       = 8  // JPEG DCT block size
+	blockinessTile      = 64 // Tile size for blockiness examples
+	blockinessEdgeDiff  = 20 // Luminance steps above this are content edges
+	blockinessEdgeReach = 3  // Steps this close to a content edge are skipped
+
+	bandingMinRange = 6  // Smallest luminance rise of a gradient
+	bandingMinSteps = 3  // Fewest steps in a gradient
+	bandingMaxStep  = 24 // Larger steps are content edges
+	bandingTile     = 32 // Tile size for banding examples
+
+	ringingEdgeDiff  = 64 // Luminance change across 2 pixels marking a strong edge
+	ringingNearMin   = 2  // Band around edges checked for ringing, in pixels
+	ringingNearMax   = 4
+	ringingFarMin    = 8 // Pixels at least this far from edges give the baseline
+	ringingSmallDiff = 16
+	ringingTile      = 32
+)
+
+// ArtifactScore rates one kind of artifact.
+type ArtifactScore struct {
+	// Severity is 0.0 (none) to 1.0 (severe).
+	Severity float64 `json:"severity"`
+
+	// Level is "none" (below 0.1), "mild" (below 0.35), "moderate"
+	// (below 0.65), or "severe".
+	Level string `json:"level"`
+
+	// Examples are up to 5 areas where the artifact is strongest.
+	Examples []Region `json:"examples"`
+}
+
+// BlockinessScore rates JPEG blockiness: steps at the borders of the 8x8
+// blocks JPEG compresses independently.
+type BlockinessScore struct {
+	ArtifactScore
+
+	// GridOffset is the position of the block grid, 0-7 on each axis; it
+	// is not 0 when the image was cropped after compression.
+	GridOffset Point `json:"grid_offset"`
+
+	// BoundaryRatio compares the mean luminance step across block borders
+	// with the step elsewhere, taking the weaker axis; about 1 means no
+	// block structure.
+	BoundaryRatio float64 `json:"boundary_ratio"`
+}
+
+// BandingScore rates color banding: gradients rendered as visible steps.
+type BandingScore struct {
+	ArtifactScore
+
+	// GradientPercent is the share of the image (0-100) covered by smooth
+	// gradients.
+	GradientPercent float64 `json:"gradient_percent"`
+
+	// BandedPercent is the share of the image (0-100) covered by banded
+	// gradients.
+	BandedPercent float64 `json:"banded_percent"`
+}
+
+// RingingScore rates ringing: ripples in flat areas beside sharp edges
+// (mosquito noise).
+type RingingScore struct {
+	// ArtifactScore rates the excess of EdgeActivity over FlatActivity.
+	ArtifactScore
+
+	// EdgeActivity is the mean small luminance variation 2-4 pixels from
+	// strong edges.
+	EdgeActivity float64 `json:"edge_activity"`
+
+	// FlatActivity is the same variation at least 8 pixels from any
+	// strong edge, the image's baseline texture and noise.
+	FlatActivity float64 `json:"flat_activity"`
+}
+
+// ArtifactsResult reports compression and quantization artifacts.
+type ArtifactsResult struct {
+	Blockiness BlockinessScore `json:"blockiness"`
+	Banding    BandingScore    `json:"banding"`
+	Ringing    RingingScore    `json:"ringing"`
+
+	// Severity and Level are those of the worst artifact.
+	Severity float64 `json:"severity"`
+	Level    string  `json:"level"`
+
+	// Reexport is true when any artifact is moderate or severe, and the
+	// asset should be exported again from its source.
+	Reexport bool `json:"reexport"`
+
+	// Advice explains each artifact found and how to avoid it.
+	Advice []string `json:"advice"`
+}
+
+// DetectArtifacts looks for JPEG blockiness, color banding in gradients,
+// and ringing near edges, and advises whether the image should be
+// re-exported at higher quality.
+//
+// Parameters:
+//   - img: Source image.
+//
+// Returns:
+//   - *ArtifactsResult: A severity score per artifact with example areas,
+//     and advice.
+//   - error: Non-nil if the image is smaller than 16x16 pixels.
+//
+// # Method
+//
+// All measures use luminance (ITU-R BT.601 weights).
+//
+//   - Blockiness: the mean step between horizontally and vertically
+//     adjacent pixels is computed for each of the 8 positions relative to
+//     an 8-pixel grid, skipping steps within 3 pixels of content edges.
+//     A JPEG shows larger steps at one position, its block borders.
+//     Severity grows from a border to interior ratio of 1.15 to 1.75,
+//     taking the weaker axis, so grids of UI elements drawn in one
+//     direction do not count.
+//
+//   - Banding: each row and column is split into ramps, runs where
+//     luminance changes in one direction without edges. A ramp rising 6
+//     or more levels in 3 or more steps is a gradient; it is banded when
+//     its steps average 2 or more levels and come at least 3 pixels
+//     apart. Severity is the banded share of the gradient pixels.
+//
+//   - Ringing: pixels 2-4 pixels from a strong edge should be as flat as
+//     the rest of the image. Severity grows as their small variations
+//     exceed those of pixels far from edges by 0.5 to 2.5 levels.
+func DetectArtifacts(img image.Image) (*ArtifactsResult, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w < 16 || h < 16 {
+		return nil, fmt.Errorf("image must be at least 16x16 pixels to detect artifacts, got %dx%d", w, h)
+	}
+
+	lum := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, bl, _ := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			lum[y*w+x] = (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)) / 257
+		}
+	}
+	offset := func(r Region) Region {
+		return Region{X1: b.Min.X + r.X1, Y1: b.Min.Y + r.Y1, X2: b.Min.X + r.X2, Y2: b.Min.Y + r.Y2}
+	}
+
+	result := &ArtifactsResult{
+		Blockiness: measureBlockiness(lum, w, h),
+		Banding:    measureBanding(lum, w, h),
+		Ringing:    measureRinging(lum, w, h),
+		Advice:     []string{},
+	}
+	scores := []*ArtifactScore{&result.Blockiness.ArtifactScore, &result.Banding.ArtifactScore, &result.Ringing.ArtifactScore}
+	for _, s := range scores {
+		for i, r := range s.Examples {
+			s.Examples[i] = offset(r)
+		}
+		s.Severity = math.Round(s.Severity*100) / 100
+		s.Level = artifactLevel(s.Severity)
+		result.Severity = math.Max(result.Severity, s.Severity)
+	}
+	result.Level = artifactLevel(result.Severity)
+	result.Reexport = result.Severity >= 0.35
+
+	if s := result.Blockiness; s.Level != "none" {
+		result.Advice = append(result.Advice, fmt.Sprintf("Blockiness is %s: the image was saved as a heavily compressed JPEG. Re-export at JPEG quality 90 or higher, or as PNG.", s.Level))
+	}
+	if s := result.Banding; s.Level != "none" {
+		result.Advice = append(result.Advice, fmt.Sprintf("Banding is %s: gradients show visible steps. Export with more color depth (no palette reduction) or enable dithering.", s.Level))
+	}
+	if s := result.Ringing; s.Level != "none" {
+		result.Advice = append(result.Advice, fmt.Sprintf("Ringing is %s: edges are surrounded by compression noise. Export line art, text, and UI as PNG.", s.Level))
+	}
+	if len(result.Advice) == 0 {
+		result.Advice = append(result.Advice, "No visible compression artifacts.")
+	}
+	return result, nil
+}
+
+// artifactLevel names a severity.
+func artifactLevel(severity float64) string {
+	switch {
+	case severity < 0.1:
+		return "none"
+	case severity < 0.35:
+		return "mild"
+	case severity < 0.65:
+		return "moderate"
+	default:
+		return "severe"
+	}
+}
+
+// rampSeverity maps v linearly from lo (0) to hi (1), clamped.
+func rampSeverity(v, lo, hi float64) float64 {
+	return math.Max(0, math.Min(1, (v-lo)/(hi-lo)))
+}
+
+// blockSteps accumulates luminance steps by position relative to the
+// 8-pixel grid, for one axis.
+type blockSteps struct {
+	sum   [jpegBlockSize]float64
+	count [jpegBlockSize]int
+}
+
+// addLine counts the steps between pixels from to to-1 of a line, read
+// through at. Steps within blockinessEdgeReach pixels of a content edge
+// are skipped; ringing beside edges would otherwise add to one phase.
+func (s *blockSteps) addLine(at func(int) float64, from, to int) {
+	n := to - from - 1
+	if n <= 0 {
+		return
+	}
+	d := make([]float64, n)
+	lastEdge := -blockinessEdgeReach - 1
+	near := make([]bool, n)
+	for k := range d {
+		d[k] = math.Abs(at(from+k+1) - at(from+k))
+		if d[k] > blockinessEdgeDiff {
+			lastEdge = k
+		}
+		near[k] = k-lastEdge <= blockinessEdgeReach
+	}
+	nextEdge := n + blockinessEdgeReach
+	for k := n - 1; k >= 0; k-- {
+		if d[k] > blockinessEdgeDiff {
+			nextEdge = k
+		}
+		if !near[k] && nextEdge-k > blockinessEdgeReach {
+			phase := (from + k + 1) % jpegBlockSize
+			s.sum[phase] += d[k]
+			s.count[phase]++
+		}
+	}
+}
+
+// ratio returns the phase with the largest mean step and how much it
+// exceeds the mean of the others. Half a level is added to both means,
+// so flat areas give a ratio near 1.
+func (s *blockSteps) ratio() (int, float64) {
+	var means [jpegBlockSize]float64
+	best := 0
+	for p := range means {
+		if s.count[p] > 0 {
+			means[p] = s.sum[p] / float64(s.count[p])
+		}
+		if means[p] > means[best] {
+			best = p
+		}
+	}
+	others := 0.0
+	for p, m := range means {
+		if p != best {
+			others += m
+		}
+	}
+	others /= jpegBlockSize - 1
+	return best, (means[best] + 0.5) / (others + 0.5)
+}
+
+// measureBlockiness rates JPEG block structure.
+func measureBlockiness(lum []float64, w, h int) BlockinessScore {
+	steps := func(x0, y0, x1, y1 int) (hs, vs blockSteps) {
+		for y := y0; y < y1; y++ {
+			hs.addLine(func(x int) float64 { return lum[y*w+x] }, x0, x1)
+		}
+		for x := x0; x < x1; x++ {
+			vs.addLine(func(y int) float64 { return lum[y*w+x] }, y0, y1)
+		}
+		return hs, vs
+	}
+	severity := func(ratio float64) float64 {
+		return rampSeverity(ratio, 1.15, 1.75)
+	}
+
+	hs, vs := steps(0, 0, w, h)
+	px, rx := hs.ratio()
+	py, ry := vs.ratio()
+	ratio := math.Min(rx, ry)
+	score := BlockinessScore{
+		ArtifactScore: ArtifactScore{Severity: severity(ratio), Examples: []Region{}},
+		GridOffset:    Point{X: px, Y: py},
+		BoundaryRatio: math.Round(ratio*100) / 100,
+	}
+	if score.Severity == 0 {
+		return score
+	}
+
+	// The tiles with the strongest block structure on the same grid
+	var tiles []scoredRegion
+	for y := 0; y < h; y += blockinessTile {
+		for x := 0; x < w; x += blockinessTile {
+			r := Region{X1: x, Y1: y, X2: min(x+blockinessTile, w), Y2: min(y+blockinessTile, h)}
+			ths, tvs := steps(r.X1, r.Y1, r.X2, r.Y2)
+			if ths.count[px] == 0 || tvs.count[py] == 0 {
+				continue
+			}
+			tx := (ths.sum[px]/float64(ths.count[px]) + 0.5) / (meanExcept(ths, px) + 0.5)
+			ty := (tvs.sum[py]/float64(tvs.count[py]) + 0.5) / (meanExcept(tvs, py) + 0.5)
+			if s := severity(math.Min(tx, ty)); s >= 0.1 {
+				tiles = append(tiles, scoredRegion{r, s})
+			}
+		}
+	}
+	score.Examples = topRegions(tiles)
+	return score
+}
+
+// meanExcept returns the mean step of every phase but skip.
+func meanExcept(s blockSteps, skip int) float64 {
+	sum, n := 0.0, 0
+	for p := range s.sum {
+		if p != skip {
+			sum += s.sum[p]
+			n += s.count[p]
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
+// measureBanding rates banding in gradients.
+func measureBanding(lum []float64, w, h int) BandingScore {
+	score := BandingScore{ArtifactScore: ArtifactScore{Examples: []Region{}}}
+	level := make([]int, len(lum))
+	for i, v := range lum {
+		level[i] = int(math.Round(v))
+	}
+
+	// Ramps along rows, then columns
+	mark := make([]uint8, len(lum))
+	for y := 0; y < h; y++ {
+		markRamps(level, mark, y*w, 1, w)
+	}
+	for x := 0; x < w; x++ {
+		markRamps(level, mark, x, w, h)
+	}
+
+	tilesX := (w + bandingTile - 1) / bandingTile
+	tileBanded := make([]int, tilesX*((h+bandingTile-1)/bandingTile))
+	gradient, banded := 0, 0
+	for i, m := range mark {
+		if m == 0 {
+			continue
+		}
+		gradient++
+		if m == rampBanded {
+			banded++
+			tileBanded[(i/w/bandingTile)*tilesX+(i%w)/bandingTile]++
+		}
+	}
+	if gradient > 0 {
+		score.Severity = float64(banded) / float64(gradient)
+	}
+	score.GradientPercent = math.Round(float64(gradient)/float64(w*h)*10000) / 100
+	score.BandedPercent = math.Round(float64(banded)/float64(w*h)*10000) / 100
+
+	// Tiles at least a quarter covered by banding
+	var tiles []scoredRegion
+	for t, n := range tileBanded {
+		x, y := (t%tilesX)*bandingTile, (t/tilesX)*bandingTile
+		r := Region{X1: x, Y1: y, X2: min(x+bandingTile, w), Y2: min(y+bandingTile, h)}
+		if area := (r.X2 - r.X1) * (r.Y2 - r.Y1); n*4 >= area {
+			tiles = append(tiles, scoredRegion{r, float64(n) / float64(area)})
+		}
+	}
+	score.Examples = topRegions(tiles)
+	return score
+}
+
+// Ramp marks set by markRamps.
+const (
+	rampSmooth = 1
+	rampBanded = 2
+)
+
+// markRamps finds the ramps along one line of n pixels, starting at index
+// start and stride apart: runs where luminance changes in one direction
+// without edges. Pixels of a ramp rising at least bandingMinRange levels
+// in at least bandingMinSteps steps are marked rampSmooth, or rampBanded
+// when the steps average 2 or more levels and come at least 3 pixels
+// apart. A pixel keeps the stronger mark of its row and column.
+func markRamps(level []int, mark []uint8, start, stride, n int) {
+	at := func(k int) int { return level[start+k*stride] }
+	for i := 0; i < n-1; {
+		d := at(i+1) - at(i)
+		if d == 0 || absInt(d) > bandingMaxStep {
+			i++
+			continue
+		}
+
+		// Extend the ramp while steps are small and keep their direction;
+		// last is the pixel after its final step
+		rising := d > 0
+		last, steps, rise := i, 0, 0
+		for k := i; k < n-1; k++ {
+			d := at(k+1) - at(k)
+			if absInt(d) > bandingMaxStep || (d != 0 && (d > 0) != rising) {
+				break
+			}
+			if d != 0 {
+				steps++
+				rise += absInt(d)
+				last = k + 1
+			}
+		}
+
+		if rise >= bandingMinRange && steps >= bandingMinSteps {
+			var m uint8 = rampSmooth
+			if rise >= 2*steps && last-i >= 3*steps {
+				m = rampBanded
+			}
+			for k := i; k <= last; k++ {
+				p := start + k*stride
+				mark[p] = max(mark[p], m)
+			}
+		}
+		i = last
+	}
+}
+
+// measureRinging rates ringing beside strong edges.
+func measureRinging(lum []float64, w, h int) RingingScore {
+	score := RingingScore{ArtifactScore: ArtifactScore{Examples: []Region{}}}
+
+	// Chebyshev distance to the nearest strong edge, capped at the far
+	// distance
+	dist := make([]int, w*h)
+	var queue []int
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*w + x
+			dist[i] = ringingFarMin
+			gx, gy := 0.0, 0.0
+			if x > 0 && x+1 < w {
+				gx = math.Abs(lum[i+1] - lum[i-1])
+			}
+			if y > 0 && y+1 < h {
+				gy = math.Abs(lum[i+w] - lum[i-w])
+			}
+			if math.Max(gx, gy) >= ringingEdgeDiff {
+				dist[i] = 0
+				queue = append(queue, i)
+			}
+		}
+	}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		x, y := i%w, i/w
+		for ny := max(y-1, 0); ny <= min(y+1, h-1); ny++ {
+			for nx := max(x-1, 0); nx <= min(x+1, w-1); nx++ {
+				if j := ny*w + nx; dist[j] > dist[i]+1 {
+					dist[j] = dist[i] + 1
+					queue = append(queue, j)
+				}
+			}
+		}
+	}
+
+	// activity is the mean small step from a pixel to its 4 neighbors
+	activity := func(x, y int) float64 {
+		i := y*w + x
+		sum, n := 0.0, 0
+		for _, j := range [4]int{i - 1, i + 1, i - w, i + w} {
+			if d := math.Abs(lum[j] - lum[i]); d < ringingSmallDiff {
+				sum += d
+				n++
+			}
+		}
+		if n == 0 {
+			return 0
+		}
+		return sum / float64(n)
+	}
+
+	tilesX := (w + ringingTile - 1) / ringingTile
+	tileSum := make([]float64, tilesX*((h+ringingTile-1)/ringingTile))
+	tileCount := make([]int, len(tileSum))
+	var nearSum, farSum float64
+	var nearN, farN int
+	for y := 1; y+1 < h; y++ {
+		for x := 1; x+1 < w; x++ {
+			d := dist[y*w+x]
+			switch {
+			case d >= ringingNearMin && d <= ringingNearMax:
+				a := activity(x, y)
+				nearSum += a
+				nearN++
+				t := (y/ringingTile)*tilesX + x/ringingTile
+				tileSum[t] += a
+				tileCount[t]++
+			case d >= ringingFarMin:
+				farSum += activity(x, y)
+				farN++
+			}
+		}
+	}
+	if nearN > 0 {
+		score.EdgeActivity = math.Round(nearSum/float64(nearN)*100) / 100
+	}
+	if farN > 0 {
+		score.FlatActivity = math.Round(farSum/float64(farN)*100) / 100
+	}
+	if nearN < 50 {
+		return score
+	}
+	severity := func(near float64) float64 {
+		return rampSeverity(near-farSum/math.Max(float64(farN), 1), 0.5, 2.5)
+	}
+	score.Severity = severity(nearSum / float64(nearN))
+
+	var tiles []scoredRegion
+	for t, n := range tileCount {
+		if n < 16 {
+			continue
+		}
+		if s := severity(tileSum[t] / float64(n)); s >= 0.1 {
+			x, y := (t%tilesX)*ringingTile, (t/tilesX)*ringingTile
+			tiles = append(tiles, scoredRegion{Region{X1: x, Y1: y, X2: min(x+ringingTile, w), Y2: min(y+ringingTile, h)}, s})
+		}
+	}
+	score.Examples = topRegions(tiles)
+	return score
+}
+
+// scoredRegion is a candidate example area.
+type scoredRegion struct {
+	region Region
+	score  float64
+}
+
+// topRegions returns the regions with the highest scores, up to
+// maxArtifactExamples, highest first.
+func topRegions(regions []scoredRegion) []Region {
+	sort.SliceStable(regions, func(i, j int) bool { return regions[i].score > regions[j].score })
+	out := []Region{}
+	for _, r := range regions[:min(len(regions), maxArtifactExamples)] {
+		out = append(out, r.region)
+	}
+	return out
+}
+
+// absInt returns the absolute value of x.
+func absInt(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
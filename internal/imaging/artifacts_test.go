@@ -0,0 +1,140 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"testing"
+)
+
+// jpegRoundTrip compresses an image as JPEG at the given quality and
+// decodes it again.
+func jpegRoundTrip(t *testing.T, img image.Image, quality int) image.Image {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		t.Fatalf("jpeg.Encode failed: %v", err)
+	}
+	out, err := jpeg.Decode(&buf)
+	if err != nil {
+		t.Fatalf("jpeg.Decode failed: %v", err)
+	}
+	return out
+}
+
+// smoothScene draws soft diagonal shading with gentle waves, like a
+// photographed sky.
+func smoothScene(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := 60 + 120*float64(x+y)/float64(w+h) + 25*math.Sin(float64(x)/9)*math.Cos(float64(y)/13)
+			img.Set(x, y, color.RGBA{uint8(v), uint8(v * 0.9), uint8(v*0.7 + 40), 255})
+		}
+	}
+	return img
+}
+
+func TestDetectArtifacts(t *testing.T) {
+	// A lossless smooth scene is clean
+	scene := smoothScene(192, 160)
+	clean, err := DetectArtifacts(scene)
+	if err != nil {
+		t.Fatalf("DetectArtifacts failed: %v", err)
+	}
+	if clean.Level != "none" || clean.Reexport || len(clean.Advice) != 1 {
+		t.Errorf("clean scene: got %+v", clean)
+	}
+
+	// Heavy JPEG compression makes it blocky
+	blocky, err := DetectArtifacts(jpegRoundTrip(t, scene, 10))
+	if err != nil {
+		t.Fatalf("DetectArtifacts failed: %v", err)
+	}
+	if blocky.Blockiness.Severity < 0.35 || !blocky.Reexport {
+		t.Errorf("blocky: got blockiness %+v", blocky.Blockiness)
+	}
+	if blocky.Blockiness.GridOffset != (Point{X: 0, Y: 0}) || len(blocky.Blockiness.Examples) == 0 {
+		t.Errorf("blocky: got grid %v, examples %v", blocky.Blockiness.GridOffset, blocky.Blockiness.Examples)
+	}
+
+	// Cropping after compression moves the grid
+	cropped := jpegRoundTrip(t, scene, 10).(interface {
+		SubImage(image.Rectangle) image.Image
+	}).SubImage(image.Rect(3, 5, 192, 160))
+	shifted, err := DetectArtifacts(cropped)
+	if err != nil {
+		t.Fatalf("DetectArtifacts failed: %v", err)
+	}
+	if shifted.Blockiness.GridOffset != (Point{X: 5, Y: 3}) {
+		t.Errorf("cropped: got grid %v, want (5, 3)", shifted.Blockiness.GridOffset)
+	}
+	if ex := shifted.Blockiness.Examples; len(ex) == 0 || ex[0].X1 < 3 || ex[0].Y1 < 5 {
+		t.Errorf("cropped: examples should be in image coordinates, got %v", ex)
+	}
+
+	// A gradient reduced to 12 levels is banded; the full gradient is not
+	smooth := image.NewRGBA(image.Rect(0, 0, 256, 64))
+	banded := image.NewRGBA(image.Rect(0, 0, 256, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 256; x++ {
+			v := uint8(40 + x*3/4)
+			smooth.Set(x, y, color.RGBA{v, v, v, 255})
+			q := uint8(40 + (x/16)*12)
+			banded.Set(x, y, color.RGBA{q, q, q, 255})
+		}
+	}
+	res, err := DetectArtifacts(smooth)
+	if err != nil {
+		t.Fatalf("DetectArtifacts failed: %v", err)
+	}
+	if res.Banding.Severity != 0 || res.Banding.GradientPercent < 99 {
+		t.Errorf("smooth gradient: got %+v", res.Banding)
+	}
+	res, err = DetectArtifacts(banded)
+	if err != nil {
+		t.Fatalf("DetectArtifacts failed: %v", err)
+	}
+	if res.Banding.Level != "severe" || res.Banding.BandedPercent < 80 || len(res.Banding.Examples) != maxArtifactExamples {
+		t.Errorf("banded gradient: got %+v", res.Banding)
+	}
+	if res.Blockiness.Severity != 0 || res.Ringing.Severity != 0 {
+		t.Errorf("banded gradient: got blockiness %v, ringing %v", res.Blockiness.Severity, res.Ringing.Severity)
+	}
+
+	// Sharp text-like shapes ring once compressed
+	art := image.NewRGBA(image.Rect(0, 0, 160, 128))
+	fillRect(art, 0, 0, 160, 128, color.White)
+	for i := 0; i < 6; i++ {
+		fillRect(art, 10+i*24, 20, 18+i*24, 108, color.Black)
+		fillRect(art, 10+i*24, 60, 28+i*24, 66, color.Black)
+	}
+	res, err = DetectArtifacts(art)
+	if err != nil {
+		t.Fatalf("DetectArtifacts failed: %v", err)
+	}
+	if res.Ringing.Severity != 0 || res.Level != "none" {
+		t.Errorf("crisp art: got %+v", res)
+	}
+	res, err = DetectArtifacts(jpegRoundTrip(t, art, 30))
+	if err != nil {
+		t.Fatalf("DetectArtifacts failed: %v", err)
+	}
+	if res.Ringing.Severity < 0.35 || res.Ringing.EdgeActivity <= res.Ringing.FlatActivity || len(res.Ringing.Examples) == 0 {
+		t.Errorf("compressed art: got ringing %+v", res.Ringing)
+	}
+
+	if _, err := DetectArtifacts(image.NewRGBA(image.Rect(0, 0, 15, 40))); err == nil {
+		t.Error("expected error for an image smaller than 16x16")
+	}
+}
+
+func TestArtifactLevel(t *testing.T) {
+	for severity, want := range map[float64]string{0: "none", 0.09: "none", 0.1: "mild", 0.34: "mild", 0.35: "moderate", 0.65: "severe", 1: "severe"} {
+		if got := artifactLevel(severity); got != want {
+			t.Errorf("artifactLevel(%v) = %q, want %q", severity, got, want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// benchmarkSizes are the image sizes every pixel-loop benchmark runs at.
+// Content is tiled, so time per operation should grow with the pixel
+// count; a benchmark that grows faster than that has regressed.
+var benchmarkSizes = []struct {
+	name          string
+	width, height int
+}{
+	{"512", 512, 512},
+	{"1080p", 1920, 1080},
+	{"4K", 3840, 2160},
+}
+
+// benchmarkScene draws a width x height image by tiling a 256x256 cell: a
+// diagonal gradient (many distinct colors) with a red box, a blue box,
+// and a black outline on top (sharp edges).
+func benchmarkScene(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			tx, ty := x%256, y%256
+			var c color.RGBA
+			switch {
+			case tx >= 24 && tx < 104 && ty >= 24 && ty < 104:
+				c = color.RGBA{220, 40, 40, 255}
+			case tx >= 152 && tx < 232 && ty >= 40 && ty < 88:
+				c = color.RGBA{40, 80, 220, 255}
+			case (tx == 16 || tx == 240) && ty >= 136 && ty <= 232,
+				(ty == 136 || ty == 232) && tx >= 16 && tx <= 240:
+				c = color.RGBA{0, 0, 0, 255}
+			default:
+				c = color.RGBA{uint8(tx), uint8(ty), uint8((tx + ty) / 2), 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// runSizes runs fn as a sub-benchmark at each of benchmarkSizes, with the
+// scene built outside the timed region.
+func runSizes(b *testing.B, fn func(b *testing.B, img *image.RGBA)) {
+	for _, size := range benchmarkSizes {
+		b.Run(size.name, func(b *testing.B) {
+			img := benchmarkScene(size.width, size.height)
+			b.ReportAllocs()
+			b.ResetTimer()
+			fn(b, img)
+		})
+	}
+}
+
+func BenchmarkEdgeDetect(b *testing.B) {
+	runSizes(b, func(b *testing.B, img *image.RGBA) {
+		for i := 0; i < b.N; i++ {
+			if _, err := EdgeDetect(img, 50, 150); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkDominantColors(b *testing.B) {
+	runSizes(b, func(b *testing.B, img *image.RGBA) {
+		for i := 0; i < b.N; i++ {
+			if _, err := DominantColors(img, 5, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkCompareRegions compares the left and right halves of the image.
+func BenchmarkCompareRegions(b *testing.B) {
+	runSizes(b, func(b *testing.B, img *image.RGBA) {
+		w, h := img.Bounds().Dx(), img.Bounds().Dy()
+		left := Region{X1: 0, Y1: 0, X2: w / 2, Y2: h}
+		right := Region{X1: w / 2, Y1: 0, X2: w, Y2: h}
+		for i := 0; i < b.N; i++ {
+			if _, err := CompareRegions(img, left, right); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,560 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+)
+
+// Blob counting: at most maxListedBlobs blobs are listed, and the size
+// histogram has blobHistogramBins bins of equivalent diameter. Clumps are
+// estimated only among at least blobClumpMinCount blobs, against their
+// median area.
+const (
+	maxListedBlobs    = 2000
+	blobHistogramBins = 10
+	blobClumpMinCount = 5
+)
+
+// Polarities of the blobs CountBlobs looks for.
+const (
+	BlobsAuto  = "auto"  // Whichever side of the threshold is the minority
+	BlobsDark  = "dark"  // Dark blobs on a light background
+	BlobsLight = "light" // Light blobs on a dark background, as in fluorescence
+)
+
+// BlobOptions configures CountBlobs.
+type BlobOptions struct {
+	// Threshold is the luma (1-255) separating blobs from the background,
+	// or 0 to choose it with Otsu's method.
+	Threshold int
+
+	// Polarity is BlobsAuto, BlobsDark, or BlobsLight.
+	Polarity string
+
+	// MinArea and MaxArea bound the area of counted blobs in pixels,
+	// holes included; MaxArea 0 sets no upper bound.
+	MinArea int
+	MaxArea int
+
+	// MinRoundness (0-1) drops blobs whose minor axis is shorter than
+	// this fraction of their major axis.
+	MinRoundness float64
+
+	// ExcludeEdges drops blobs touching the image's edge, which are cut
+	// off and would skew the sizes.
+	ExcludeEdges bool
+
+	// Shapes adds each listed blob's shape descriptors, in Blob.Shape.
+	Shapes bool
+}
+
+// Blob is one blob counted by CountBlobs.
+type Blob struct {
+	// ID is the blob's 1-based index in BlobResult.Blobs.
+	ID int `json:"id"`
+
+	// Bounds is the blob's bounding box.
+	Bounds Region `json:"bounds"`
+
+	// Area is the number of pixels in the blob, holes included.
+	Area int `json:"area"`
+
+	// CentroidX and CentroidY are the mean position of the blob's pixels,
+	// to a tenth of a pixel.
+	CentroidX float64 `json:"centroid_x"`
+	CentroidY float64 `json:"centroid_y"`
+
+	// Diameter is the diameter of the circle of the same area, in pixels.
+	Diameter float64 `json:"diameter"`
+
+	// Roundness is the ratio of the blob's minor to major axis, from its
+	// second moments: 1 for a disc, lower for elongated blobs and touching
+	// pairs.
+	Roundness float64 `json:"roundness"`
+
+	// Count is the number of blobs this one is estimated to hold: its
+	// area over the median area, rounded, and more than 1 for a clump of
+	// touching blobs.
+	Count int `json:"count"`
+
+	// Shape is the blob's shape descriptors, if BlobOptions.Shapes is set.
+	Shape *BlobShape `json:"shape,omitempty"`
+}
+
+// BlobSizeBin is one bin of the blob size histogram.
+type BlobSizeBin struct {
+	// MinDiameter and MaxDiameter bound the bin, in pixels. The last bin
+	// includes its maximum.
+	MinDiameter float64 `json:"min_diameter"`
+	MaxDiameter float64 `json:"max_diameter"`
+
+	// Count is the number of blobs in the bin.
+	Count int `json:"count"`
+}
+
+// BlobSizes is the size distribution of the counted blobs, in pixels.
+type BlobSizes struct {
+	MinArea    int     `json:"min_area"`
+	MaxArea    int     `json:"max_area"`
+	MeanArea   float64 `json:"mean_area"`
+	MedianArea float64 `json:"median_area"`
+	StdDevArea float64 `json:"stddev_area"`
+
+	// Diameters are equivalent diameters, as in Blob.
+	MinDiameter    float64 `json:"min_diameter"`
+	MaxDiameter    float64 `json:"max_diameter"`
+	MeanDiameter   float64 `json:"mean_diameter"`
+	MedianDiameter float64 `json:"median_diameter"`
+
+	// Histogram counts the blobs in equal bins of diameter from the
+	// smallest to the largest.
+	Histogram []BlobSizeBin `json:"histogram"`
+}
+
+// CalibratedBlobSizes is the size distribution in the units of a scale
+// bar calibration.
+type CalibratedBlobSizes struct {
+	Unit          string  `json:"unit"`
+	UnitsPerPixel float64 `json:"units_per_pixel"`
+
+	MinDiameter    float64 `json:"min_diameter"`
+	MaxDiameter    float64 `json:"max_diameter"`
+	MeanDiameter   float64 `json:"mean_diameter"`
+	MedianDiameter float64 `json:"median_diameter"`
+
+	// Areas are in square units.
+	MeanArea   float64 `json:"mean_area"`
+	MedianArea float64 `json:"median_area"`
+}
+
+// BlobRejections counts the blobs found but not counted, by reason. A blob
+// is counted under the first reason that applies, in this order.
+type BlobRejections struct {
+	Edge     int `json:"edge"`
+	TooSmall int `json:"too_small"`
+	TooLarge int `json:"too_large"`
+	NotRound int `json:"not_round"`
+}
+
+// BlobResult contains the blobs counted by CountBlobs.
+type BlobResult struct {
+	// Count is the number of blobs counted.
+	Count int `json:"count"`
+
+	// EstimatedCount is the sum of the blobs' Count: the number of
+	// objects, with clumps of touching blobs counted by their area.
+	EstimatedCount int `json:"estimated_count"`
+
+	// Threshold is the luma that separated blobs from the background.
+	Threshold int `json:"threshold"`
+
+	// Polarity is BlobsDark or BlobsLight: the blobs counted.
+	Polarity string `json:"polarity"`
+
+	// Sizes is the size distribution; nil if no blob was counted.
+	Sizes *BlobSizes `json:"sizes,omitempty"`
+
+	// Calibrated is the size distribution in the units of the image's
+	// scale bar, once a ScaleCalibration is known. Set by Calibrate.
+	Calibrated *CalibratedBlobSizes `json:"calibrated,omitempty"`
+
+	// Rejected counts the blobs found but not counted.
+	Rejected BlobRejections `json:"rejected"`
+
+	// Blobs are the counted blobs, top to bottom, then left to right.
+	Blobs []Blob `json:"blobs"`
+
+	// Truncated is true if more than 2000 blobs were counted and only the
+	// first 2000 are listed. Counts and sizes cover them all.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// blobStats accumulates the pixels of one connected component.
+type blobStats struct {
+	area                  int
+	sx, sy, sxx, syy, sxy float64
+	x1, y1, x2, y2        int
+	edge                  bool
+
+	// The third moments, Σx³, Σx²y, Σxy², and Σy³, from the first pixel
+	// added, (ox, oy), for precision, and each row's leftmost x and
+	// rightmost x+1, from row y1 down
+	ox, oy int
+	s3     [4]float64
+	rows   [][2]int
+}
+
+func (s *blobStats) add(x, y int) {
+	fx, fy := float64(x), float64(y)
+	switch {
+	case s.area == 0:
+		s.ox, s.oy = x, y
+		s.rows = append(s.rows[:0], [2]int{x, x + 1})
+	case y < s.y1:
+		// A pixel above the top row, such as a hole's, grows the rows
+		// upward
+		above := make([][2]int, s.y1-y, s.y1-y+len(s.rows))
+		for i := range above {
+			above[i] = [2]int{x, x + 1}
+		}
+		s.rows = append(above, s.rows...)
+	}
+	s.area++
+	s.sx, s.sy = s.sx+fx, s.sy+fy
+	s.sxx, s.syy, s.sxy = s.sxx+fx*fx, s.syy+fy*fy, s.sxy+fx*fy
+	s.x1, s.y1 = min(s.x1, x), min(s.y1, y)
+	s.x2, s.y2 = max(s.x2, x+1), max(s.y2, y+1)
+
+	dx, dy := float64(x-s.ox), float64(y-s.oy)
+	s.s3[0] += dx * dx * dx
+	s.s3[1] += dx * dx * dy
+	s.s3[2] += dx * dy * dy
+	s.s3[3] += dy * dy * dy
+	for len(s.rows) <= y-s.y1 {
+		s.rows = append(s.rows, [2]int{x, x + 1})
+	}
+	row := &s.rows[y-s.y1]
+	row[0], row[1] = min(row[0], x), max(row[1], x+1)
+}
+
+// CountBlobs counts roughly circular blobs, such as particles or cells in
+// a micrograph, on a uniform background.
+//
+// Parameters:
+//   - img: Source image.
+//   - opts: Threshold, polarity, and filters.
+//
+// Returns:
+//   - *BlobResult: The count, the size distribution, and each blob's
+//     bounds, centroid, and size.
+//   - error: Non-nil if an option is out of range.
+//
+// # Algorithm
+//
+//  1. Smooth: the luma (Rec. 601) is averaged over 3x3 pixels, so noise
+//     does not break blobs apart or speckle the background.
+//
+//  2. Threshold: the smoothed luma against opts.Threshold or Otsu's
+//     threshold. With BlobsAuto, the blobs are the side of the threshold
+//     with fewer pixels.
+//
+//  3. Label: blob pixels are joined into 8-connected components. Holes,
+//     4-connected background not connected to the image's edge and
+//     bordered by one component only, are filled, so cells with bright
+//     centers count whole. Background around a blob inside another blob
+//     is left unfilled.
+//
+//  4. Filter and measure: components are dropped by edge contact, area,
+//     and roundness. A blob of several times the median area is counted
+//     as a clump of that many. With opts.Shapes, each blob's moments and
+//     convex hull give its shape descriptors.
+//
+// Touching blobs are not split; their Count estimates how many they hold.
+func CountBlobs(img image.Image, opts BlobOptions) (*BlobResult, error) {
+	result, _, err := countBlobs(img, opts)
+	return result, err
+}
+
+// countBlobs implements CountBlobs, also returning the stats of the
+// counted blobs in the order of their IDs.
+func countBlobs(img image.Image, opts BlobOptions) (*BlobResult, []*blobStats, error) {
+	if opts.Threshold < 0 || opts.Threshold > 255 {
+		return nil, nil, fmt.Errorf("threshold must be 0 (automatic) to 255, got %d", opts.Threshold)
+	}
+	if opts.Polarity == "" {
+		opts.Polarity = BlobsAuto
+	}
+	if opts.Polarity != BlobsAuto && opts.Polarity != BlobsDark && opts.Polarity != BlobsLight {
+		return nil, nil, fmt.Errorf("polarity must be %q, %q, or %q, got %q", BlobsAuto, BlobsDark, BlobsLight, opts.Polarity)
+	}
+	if opts.MinArea < 0 || opts.MaxArea < 0 || (opts.MaxArea > 0 && opts.MaxArea < opts.MinArea) {
+		return nil, nil, fmt.Errorf("min_area and max_area must be positive with min_area <= max_area, got %d and %d", opts.MinArea, opts.MaxArea)
+	}
+	if opts.MinRoundness < 0 || opts.MinRoundness > 1 {
+		return nil, nil, fmt.Errorf("min_roundness must be 0 to 1, got %g", opts.MinRoundness)
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	lum := make([]uint8, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			lum[y*w+x] = luma(nrgbaAt(img, b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	smooth := boxSmooth3(lum, w, h)
+
+	result := &BlobResult{Threshold: opts.Threshold, Polarity: opts.Polarity, Blobs: []Blob{}}
+	if result.Threshold == 0 {
+		result.Threshold = otsuThreshold(smooth)
+	}
+	t := uint8(result.Threshold)
+	if result.Polarity == BlobsAuto {
+		dark := 0
+		for _, v := range smooth {
+			if v < t {
+				dark++
+			}
+		}
+		result.Polarity = BlobsLight
+		if dark*2 <= len(smooth) {
+			result.Polarity = BlobsDark
+		}
+	}
+	in := make([]bool, w*h)
+	for i, v := range smooth {
+		in[i] = (v < t) == (result.Polarity == BlobsDark)
+	}
+
+	stats := labelBlobs(in, w, h)
+	var kept []*blobStats
+	for _, s := range stats {
+		switch {
+		case opts.ExcludeEdges && s.edge:
+			result.Rejected.Edge++
+		case s.area < opts.MinArea:
+			result.Rejected.TooSmall++
+		case opts.MaxArea > 0 && s.area > opts.MaxArea:
+			result.Rejected.TooLarge++
+		case blobRoundness(s) < opts.MinRoundness:
+			result.Rejected.NotRound++
+		default:
+			kept = append(kept, s)
+		}
+	}
+	if len(kept) == 0 {
+		return result, nil, nil
+	}
+	sort.SliceStable(kept, func(i, j int) bool {
+		if kept[i].y1 != kept[j].y1 {
+			return kept[i].y1 < kept[j].y1
+		}
+		return kept[i].x1 < kept[j].x1
+	})
+
+	areas := make([]float64, len(kept))
+	diameters := make([]float64, len(kept))
+	for i, s := range kept {
+		areas[i] = float64(s.area)
+		diameters[i] = 2 * math.Sqrt(float64(s.area)/math.Pi)
+	}
+	median := medianFloat(areas)
+	for i, s := range kept {
+		n := float64(s.area)
+		count := 1
+		if len(kept) >= blobClumpMinCount {
+			count = max(1, int(math.Round(n/median)))
+		}
+		result.Count++
+		result.EstimatedCount += count
+		if len(result.Blobs) == maxListedBlobs {
+			result.Truncated = true
+			continue
+		}
+		result.Blobs = append(result.Blobs, Blob{
+			ID:        len(result.Blobs) + 1,
+			Bounds:    Region{X1: b.Min.X + s.x1, Y1: b.Min.Y + s.y1, X2: b.Min.X + s.x2, Y2: b.Min.Y + s.y2},
+			Area:      s.area,
+			CentroidX: math.Round((float64(b.Min.X)+s.sx/n)*10) / 10,
+			CentroidY: math.Round((float64(b.Min.Y)+s.sy/n)*10) / 10,
+			Diameter:  math.Round(diameters[i]*100) / 100,
+			Roundness: math.Round(blobRoundness(s)*100) / 100,
+			Count:     count,
+		})
+		if opts.Shapes {
+			result.Blobs[len(result.Blobs)-1].Shape = blobShape(s)
+		}
+	}
+	result.Sizes = blobSizes(areas, diameters)
+	return result, kept, nil
+}
+
+// Calibrate sets r.Calibrated from a scale bar calibration of the image.
+func (r *BlobResult) Calibrate(c *ScaleCalibration) {
+	if r.Sizes == nil || c == nil {
+		return
+	}
+	u := c.UnitsPerPixel
+	r.Calibrated = &CalibratedBlobSizes{
+		Unit:           c.Unit,
+		UnitsPerPixel:  u,
+		MinDiameter:    significant(r.Sizes.MinDiameter*u, 4),
+		MaxDiameter:    significant(r.Sizes.MaxDiameter*u, 4),
+		MeanDiameter:   significant(r.Sizes.MeanDiameter*u, 4),
+		MedianDiameter: significant(r.Sizes.MedianDiameter*u, 4),
+		MeanArea:       significant(r.Sizes.MeanArea*u*u, 4),
+		MedianArea:     significant(r.Sizes.MedianArea*u*u, 4),
+	}
+}
+
+// boxSmooth3 returns the mean of every pixel's 3x3 neighborhood, clipped
+// to the image.
+func boxSmooth3(lum []uint8, w, h int) []uint8 {
+	out := make([]uint8, len(lum))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			sum, n := 0, 0
+			for yy := max(0, y-1); yy <= min(h-1, y+1); yy++ {
+				for xx := max(0, x-1); xx <= min(w-1, x+1); xx++ {
+					sum += int(lum[yy*w+xx])
+					n++
+				}
+			}
+			out[y*w+x] = uint8((sum + n/2) / n)
+		}
+	}
+	return out
+}
+
+// labelBlobs returns the 8-connected components of the pixels set in in,
+// an image w pixels wide, with their holes filled: 4-connected runs of
+// unset pixels not connected to the image's edge and bordered by only one
+// component. A run between two components, such as the gap between nested
+// rings, is background and belongs to neither.
+func labelBlobs(in []bool, w, h int) []*blobStats {
+	label := make([]int32, w*h)
+	var stats []*blobStats
+	var stack []int
+	for start, set := range in {
+		if !set || label[start] != 0 {
+			continue
+		}
+		s := &blobStats{x1: w, y1: h}
+		stats = append(stats, s)
+		id := int32(len(stats))
+		label[start] = id
+		stack = append(stack[:0], start)
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x, y := i%w, i/w
+			s.add(x, y)
+			if x == 0 || y == 0 || x == w-1 || y == h-1 {
+				s.edge = true
+			}
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := x+dx, y+dy
+					if nx < 0 || ny < 0 || nx >= w || ny >= h {
+						continue
+					}
+					if j := ny*w + nx; in[j] && label[j] == 0 {
+						label[j] = id
+						stack = append(stack, j)
+					}
+				}
+			}
+		}
+	}
+
+	// Background runs: a run touching the edge is the background proper;
+	// any other is a hole of the component around it, if there is only one
+	seen := make([]bool, w*h)
+	var run []int
+	for start, set := range in {
+		if set || seen[start] {
+			continue
+		}
+		seen[start] = true
+		run, stack = run[:0], append(stack[:0], start)
+		edge, owner, shared := false, int32(0), false
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			run = append(run, i)
+			x, y := i%w, i/w
+			if x == 0 || y == 0 || x == w-1 || y == h-1 {
+				edge = true
+			}
+			for _, j := range [4]int{i - w, i + w, i - 1, i + 1} {
+				if j < 0 || j >= len(in) || (j == i-1 && x == 0) || (j == i+1 && x == w-1) {
+					continue
+				}
+				if in[j] {
+					if owner != 0 && label[j] != owner {
+						shared = true
+					}
+					owner = label[j]
+				} else if !seen[j] {
+					seen[j] = true
+					stack = append(stack, j)
+				}
+			}
+		}
+		if !edge && !shared && owner != 0 {
+			s := stats[owner-1]
+			for _, i := range run {
+				s.add(i%w, i/w)
+			}
+		}
+	}
+	return stats
+}
+
+// blobRoundness returns the ratio of a blob's minor to major axis, from
+// the eigenvalues of its covariance.
+func blobRoundness(s *blobStats) float64 {
+	n := float64(s.area)
+	mx, my := s.sx/n, s.sy/n
+	cxx, cyy, cxy := s.sxx/n-mx*mx, s.syy/n-my*my, s.sxy/n-mx*my
+	mean := (cxx + cyy) / 2
+	d := math.Sqrt((cxx-cyy)*(cxx-cyy)/4 + cxy*cxy)
+	if mean+d <= 0 {
+		return 1
+	}
+	return math.Sqrt(math.Max(0, mean-d) / (mean + d))
+}
+
+// blobSizes returns the size distribution of blobs with the given areas
+// and equivalent diameters.
+func blobSizes(areas, diameters []float64) *BlobSizes {
+	n := float64(len(areas))
+	var sum, sumSq, dSum float64
+	for i, a := range areas {
+		sum += a
+		sumSq += a * a
+		dSum += diameters[i]
+	}
+	mean := sum / n
+	minD, maxD := diameters[0], diameters[0]
+	minA, maxA := areas[0], areas[0]
+	for i, d := range diameters {
+		minD, maxD = math.Min(minD, d), math.Max(maxD, d)
+		minA, maxA = math.Min(minA, areas[i]), math.Max(maxA, areas[i])
+	}
+	sizes := &BlobSizes{
+		MinArea:        int(minA),
+		MaxArea:        int(maxA),
+		MeanArea:       math.Round(mean*10) / 10,
+		MedianArea:     medianFloat(areas),
+		StdDevArea:     math.Round(math.Sqrt(math.Max(0, sumSq/n-mean*mean))*10) / 10,
+		MinDiameter:    math.Round(minD*100) / 100,
+		MaxDiameter:    math.Round(maxD*100) / 100,
+		MeanDiameter:   math.Round(dSum/n*100) / 100,
+		MedianDiameter: math.Round(medianFloat(diameters)*100) / 100,
+	}
+
+	bins := blobHistogramBins
+	if maxD-minD < 1e-9 {
+		bins = 1
+	}
+	width := (maxD - minD) / float64(bins)
+	for i := 0; i < bins; i++ {
+		lo := minD + float64(i)*width
+		sizes.Histogram = append(sizes.Histogram, BlobSizeBin{
+			MinDiameter: math.Round(lo*100) / 100,
+			MaxDiameter: math.Round((lo+width)*100) / 100,
+		})
+	}
+	sizes.Histogram[bins-1].MaxDiameter = sizes.MaxDiameter
+	for _, d := range diameters {
+		i := bins - 1
+		if width > 0 {
+			i = min(bins-1, int((d-minD)/width))
+		}
+		sizes.Histogram[i].Count++
+	}
+	return sizes
+}
@@ -0,0 +1,279 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// paintDisk fills the pixels within r of (cx, cy) with c.
+func paintDisk(img *image.RGBA, cx, cy, r int, c color.RGBA) {
+	for y := cy - r; y <= cy+r; y++ {
+		for x := cx - r; x <= cx+r; x++ {
+			if (x-cx)*(x-cx)+(y-cy)*(y-cy) <= r*r {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+}
+
+// cellImage returns a 300x200 noisy light background with dark cells of
+// radius 8: five apart, one with a light center, two touching, one cut
+// off by the left edge, and a speck.
+func cellImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 300, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 300; x++ {
+			v := uint8(200 + (x*7+y*13)%20)
+			img.SetRGBA(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+	dark := color.RGBA{60, 60, 90, 255}
+	for _, c := range [][2]int{{40, 40}, {100, 40}, {160, 40}, {220, 40}, {40, 120}, {100, 120}, {200, 120}, {215, 120}, {3, 170}} {
+		paintDisk(img, c[0], c[1], 8, dark)
+	}
+	paintDisk(img, 100, 120, 3, color.RGBA{210, 210, 210, 255})
+	paintDisk(img, 260, 170, 1, dark)
+	return img
+}
+
+func TestCountBlobs(t *testing.T) {
+	result, err := CountBlobs(cellImage(), BlobOptions{MinArea: 20, ExcludeEdges: true})
+	if err != nil {
+		t.Fatalf("CountBlobs failed: %v", err)
+	}
+	if result.Polarity != BlobsDark || result.Count != 7 || result.EstimatedCount != 8 {
+		t.Fatalf("got %s count %d, estimated %d, want dark 7 and 8", result.Polarity, result.Count, result.EstimatedCount)
+	}
+	if result.Rejected != (BlobRejections{Edge: 1, TooSmall: 1}) {
+		t.Errorf("rejected = %+v", result.Rejected)
+	}
+
+	first := result.Blobs[0]
+	if first.CentroidX != 40 || first.CentroidY != 40 || first.Area != 193 || first.Count != 1 || first.Roundness < 0.95 {
+		t.Errorf("first blob = %+v", first)
+	}
+	// The light center is a hole, filled
+	if ring := result.Blobs[5]; ring.CentroidX != 100 || ring.Area != first.Area {
+		t.Errorf("ring cell = %+v, want area %d", ring, first.Area)
+	}
+	clump := result.Blobs[6]
+	if clump.Count != 2 || clump.Roundness > 0.7 || clump.Bounds != (Region{X1: 193, Y1: 113, X2: 223, Y2: 128}) {
+		t.Errorf("clump = %+v", clump)
+	}
+
+	s := result.Sizes
+	if s.MinArea != 193 || s.MedianArea != 193 || s.MaxArea <= 300 || math.Abs(s.MedianDiameter-15.68) > 0.01 {
+		t.Errorf("sizes = %+v", s)
+	}
+	if len(s.Histogram) != 10 || s.Histogram[0].Count != 6 || s.Histogram[9].Count != 1 || s.Histogram[9].MaxDiameter != s.MaxDiameter {
+		t.Errorf("histogram = %+v", s.Histogram)
+	}
+
+	result.Calibrate(&ScaleCalibration{Unit: "µm", UnitsPerPixel: 0.5})
+	if c := result.Calibrated; c == nil || c.MedianDiameter != 7.84 || c.MedianArea != 48.25 || c.Unit != "µm" {
+		t.Errorf("calibrated = %+v", c)
+	}
+}
+
+func TestCountBlobs_LightAndFilters(t *testing.T) {
+	// Fluorescence: bright spots on black, one elongated
+	img := image.NewRGBA(image.Rect(0, 0, 120, 80))
+	for i := range img.Pix {
+		img.Pix[i] = 255 * uint8((i%4)/3)
+	}
+	bright := color.RGBA{240, 250, 120, 255}
+	paintDisk(img, 30, 40, 6, bright)
+	paintDisk(img, 60, 40, 10, bright)
+	for x := 80; x < 110; x++ {
+		for y := 36; y < 42; y++ {
+			img.SetRGBA(x, y, bright)
+		}
+	}
+
+	result, err := CountBlobs(img, BlobOptions{MinRoundness: 0.5})
+	if err != nil {
+		t.Fatalf("CountBlobs failed: %v", err)
+	}
+	if result.Polarity != BlobsLight || result.Count != 2 || result.Rejected.NotRound != 1 {
+		t.Errorf("got %s count %d, rejected %+v", result.Polarity, result.Count, result.Rejected)
+	}
+	// Fewer than five blobs are not split into clumps
+	if result.EstimatedCount != 2 || result.Blobs[1].Count != 1 {
+		t.Errorf("estimated %d, blobs %+v", result.EstimatedCount, result.Blobs)
+	}
+
+	result, _ = CountBlobs(img, BlobOptions{Polarity: BlobsLight, MaxArea: 200})
+	if result.Count != 2 || result.Rejected.TooLarge != 1 {
+		t.Errorf("max area: count %d, rejected %+v", result.Count, result.Rejected)
+	}
+
+	// Dark blobs on black are the background
+	result, _ = CountBlobs(img, BlobOptions{Polarity: BlobsDark, ExcludeEdges: true})
+	if result.Count != 0 || result.Sizes != nil || len(result.Blobs) != 0 {
+		t.Errorf("dark: got %+v", result)
+	}
+}
+
+func TestCountBlobs_Invalid(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for _, opts := range []BlobOptions{
+		{Threshold: 256},
+		{Polarity: "grey"},
+		{MinArea: 50, MaxArea: 10},
+		{MinRoundness: 1.5},
+	} {
+		if _, err := CountBlobs(img, opts); err == nil {
+			t.Errorf("%+v: expected error", opts)
+		}
+	}
+}
+
+func TestCountBlobs_Shapes(t *testing.T) {
+	// A disc, a 48x12 bar, the bar half as large again and turned 30
+	// degrees, and an L
+	img := image.NewRGBA(image.Rect(0, 0, 320, 160))
+	fillRect(img, 0, 0, 320, 160, color.RGBA{255, 255, 255, 255})
+	dark := color.RGBA{0, 0, 0, 255}
+	paintDisk(img, 40, 40, 20, dark)
+	fillRect(img, 80, 34, 128, 46, dark)
+	sin, cos := math.Sincos(30 * math.Pi / 180)
+	for y := 0; y < 160; y++ {
+		for x := 150; x < 260; x++ {
+			u := (float64(x)-205)*cos + (float64(y)-60)*sin
+			v := -(float64(x)-205)*sin + (float64(y)-60)*cos
+			if math.Abs(u) <= 36 && math.Abs(v) <= 9 {
+				img.SetRGBA(x, y, dark)
+			}
+		}
+	}
+	fillRect(img, 40, 100, 50, 150, dark)
+	fillRect(img, 40, 140, 90, 150, dark)
+
+	result, err := CountBlobs(img, BlobOptions{Shapes: true})
+	if err != nil {
+		t.Fatalf("CountBlobs failed: %v", err)
+	}
+	if result.Count != 4 {
+		t.Fatalf("count = %d, want 4", result.Count)
+	}
+	disc, bar, turned, ell := result.Blobs[0].Shape, result.Blobs[1].Shape, result.Blobs[2].Shape, result.Blobs[3].Shape
+	if disc == nil || disc.Eccentricity > 0.2 || disc.Solidity < 0.95 || disc.AspectRatio > 1.1 {
+		t.Errorf("disc = %+v", disc)
+	}
+	if bar.Eccentricity < 0.95 || bar.Solidity < 0.99 || bar.AspectRatio != 4 || bar.Orientation != 0 {
+		t.Errorf("bar = %+v", bar)
+	}
+	if math.Abs(turned.AspectRatio-4) > 0.2 || turned.Solidity < 0.95 || math.Abs(turned.Orientation-30) > 1 {
+		t.Errorf("turned bar = %+v", turned)
+	}
+	// Hu moments hold through scaling and turning
+	for i := 0; i < 2; i++ {
+		if math.Abs(turned.HuMoments[i]-bar.HuMoments[i]) > 0.03*bar.HuMoments[i] {
+			t.Errorf("hu[%d]: turned %g, bar %g", i, turned.HuMoments[i], bar.HuMoments[i])
+		}
+	}
+	if ell.Solidity > 0.6 || ell.AspectRatio != 1 || math.Abs(ell.Orientation-45) > 1 {
+		t.Errorf("L = %+v", ell)
+	}
+
+	result, _ = CountBlobs(img, BlobOptions{})
+	if result.Blobs[0].Shape != nil {
+		t.Errorf("shape without Shapes: %+v", result.Blobs[0].Shape)
+	}
+}
+
+// checkBlobRows fails unless every component's rows span its bounds.
+func checkBlobRows(t *testing.T, stats []*blobStats) {
+	t.Helper()
+	for n, s := range stats {
+		if len(s.rows) != s.y2-s.y1 {
+			t.Fatalf("component %d: %d rows for bounds %d-%d", n, len(s.rows), s.y1, s.y2)
+		}
+		for i, r := range s.rows {
+			if r[0] < s.x1 || r[1] > s.x2 || r[0] >= r[1] {
+				t.Fatalf("component %d: row %d spans %v, outside %d-%d", n, s.y1+i, r, s.x1, s.x2)
+			}
+		}
+	}
+}
+
+func TestLabelBlobs_Rows(t *testing.T) {
+	// Random noise: holes of every shape, beside and above the first row
+	// of the components around them
+	rng := rand.New(rand.NewSource(1))
+	noise := make([]bool, 64*48)
+	for i := range noise {
+		noise[i] = rng.Intn(2) == 0
+	}
+	checkBlobRows(t, labelBlobs(noise, 64, 48))
+
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			v := uint8(rng.Intn(256))
+			img.SetRGBA(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+	if _, err := CountBlobs(img, BlobOptions{Shapes: true}); err != nil {
+		t.Fatalf("CountBlobs on noise: %v", err)
+	}
+
+	// A dot in a ring in a ring, and a square abutting a bar
+	const w, h = 40, 24
+	mask := make([]bool, w*h)
+	set := func(x1, y1, x2, y2 int, v bool) {
+		for y := y1; y < y2; y++ {
+			for x := x1; x < x2; x++ {
+				mask[y*w+x] = v
+			}
+		}
+	}
+	set(2, 2, 20, 20, true)
+	set(4, 4, 18, 18, false)
+	set(6, 6, 16, 16, true)
+	set(8, 8, 14, 14, false)
+	set(10, 10, 12, 12, true)
+	set(24, 4, 30, 10, true)
+	set(30, 6, 38, 8, true)
+	stats := labelBlobs(mask, w, h)
+	checkBlobRows(t, stats)
+	if len(stats) != 4 || stats[1].area != 36+16 {
+		t.Fatalf("got %d components, want 4 with the square and bar as one", len(stats))
+	}
+	// Each gap borders two components, so neither ring fills it
+	if stats[0].area != 18*18-14*14 || stats[2].area != 10*10-6*6 || stats[3].area != 4 {
+		t.Errorf("ring and dot areas %d %d %d, want 128 64 4", stats[0].area, stats[2].area, stats[3].area)
+	}
+}
+
+func TestLabelBlobs_SharedGap(t *testing.T) {
+	// Two rings side by side in a frame, with a one-pixel gap between
+	// them: the background inside the frame, gap included, borders all
+	// three, so it is a hole of none; each ring's inside is its own
+	const w, h = 34, 18
+	mask := make([]bool, w*h)
+	ring := func(x1, y1, x2, y2 int) {
+		for y := y1; y < y2; y++ {
+			for x := x1; x < x2; x++ {
+				if y == y1 || y == y2-1 || x == x1 || x == x2-1 {
+					mask[y*w+x] = true
+				}
+			}
+		}
+	}
+	ring(1, 1, 33, 17)
+	ring(4, 4, 14, 14)
+	ring(15, 4, 25, 14)
+	stats := labelBlobs(mask, w, h)
+	checkBlobRows(t, stats)
+	if len(stats) != 3 {
+		t.Fatalf("got %d components, want 3", len(stats))
+	}
+	want := [3]int{32*16 - 30*14, 100, 100}
+	if got := [3]int{stats[0].area, stats[1].area, stats[2].area}; got != want {
+		t.Errorf("areas %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,148 @@
+package imaging
+
+import (
+	"math"
+	"sort"
+)
+
+// BlobShape describes the shape of a blob in numbers that do not change
+// when it is moved, scaled, or turned, so shapes can be classified and
+// matched across images.
+type BlobShape struct {
+	// HuMoments are the seven Hu moment invariants, to 4 significant
+	// digits. The seventh changes sign when the shape is mirrored.
+	// Compare shapes by -sign(h)·log10|h| of each, as their magnitudes
+	// span many orders.
+	HuMoments [7]float64 `json:"hu_moments"`
+
+	// Eccentricity (0-1) is that of the ellipse with the blob's second
+	// moments: 0 for a disc or square, near 1 for a line.
+	Eccentricity float64 `json:"eccentricity"`
+
+	// Solidity (0-1) is the blob's area over that of its convex hull, in
+	// pixels: 1 for a convex shape, lower for stars, crescents, and
+	// clumps.
+	Solidity float64 `json:"solidity"`
+
+	// AspectRatio (1 or more) is the long side over the short side of the
+	// smallest rectangle, at any angle, around the blob.
+	AspectRatio float64 `json:"aspect_ratio"`
+
+	// Orientation is the angle of the blob's major axis, in degrees
+	// clockwise from horizontal (0-180); 0 for a disc.
+	Orientation float64 `json:"orientation"`
+}
+
+// blobShape returns the shape descriptors of the blob s.
+//
+// The moments are those of the blob's pixels as points, central and
+// normalized for scale before Hu's seven combinations are taken. The
+// convex hull is that of the centers of each row's first and last
+// pixels, and its area the number of pixel centers within it, by Pick's
+// theorem, so a convex blob, upright or turned, has solidity 1. The
+// smallest rectangle around the hull is grown by half a pixel all round.
+func blobShape(s *blobStats) *BlobShape {
+	n := float64(s.area)
+	ox, oy := float64(s.ox), float64(s.oy)
+	// Moments about the first pixel, then about the centroid
+	mx, my := s.sx/n-ox, s.sy/n-oy
+	s20 := s.sxx - 2*ox*s.sx + n*ox*ox
+	s02 := s.syy - 2*oy*s.sy + n*oy*oy
+	s11 := s.sxy - ox*s.sy - oy*s.sx + n*ox*oy
+	mu20 := s20 - n*mx*mx
+	mu02 := s02 - n*my*my
+	mu11 := s11 - n*mx*my
+	mu30 := s.s3[0] - 3*mx*s20 + 2*n*mx*mx*mx
+	mu21 := s.s3[1] - 2*mx*s11 - my*s20 + 2*n*mx*mx*my
+	mu12 := s.s3[2] - 2*my*s11 - mx*s02 + 2*n*mx*my*my
+	mu03 := s.s3[3] - 3*my*s02 + 2*n*my*my*my
+
+	n2, n3 := math.Pow(n, 2), math.Pow(n, 2.5)
+	e20, e02, e11 := mu20/n2, mu02/n2, mu11/n2
+	e30, e21, e12, e03 := mu30/n3, mu21/n3, mu12/n3, mu03/n3
+	a, b := e30+e12, e21+e03
+	c, d := e30-3*e12, 3*e21-e03
+	hu := [7]float64{
+		e20 + e02,
+		(e20-e02)*(e20-e02) + 4*e11*e11,
+		c*c + d*d,
+		a*a + b*b,
+		c*a*(a*a-3*b*b) + d*b*(3*a*a-b*b),
+		(e20-e02)*(a*a-b*b) + 4*e11*a*b,
+		d*a*(a*a-3*b*b) - c*b*(3*a*a-b*b),
+	}
+
+	shape := &BlobShape{}
+	for i, h := range hu {
+		shape.HuMoments[i] = significant(h, 4)
+	}
+	roundness := blobRoundness(s)
+	shape.Eccentricity = math.Round(math.Sqrt(math.Max(0, 1-roundness*roundness))*1000) / 1000
+	if roundness < 1 {
+		angle := math.Atan2(2*mu11, mu20-mu02) / 2 * 180 / math.Pi
+		shape.Orientation = math.Round(math.Mod(angle+180, 180)*10) / 10
+	}
+
+	var ends [][2]float64
+	for i, r := range s.rows {
+		y := float64(s.y1 + i)
+		ends = append(ends, [2]float64{float64(r[0]), y}, [2]float64{float64(r[1] - 1), y})
+	}
+	hull := convexHull(ends)
+	// Pick: the pixels within are the area plus half those on the
+	// boundary, plus 1
+	area, boundary := 0.0, 0
+	for i, p := range hull {
+		q := hull[(i+1)%len(hull)]
+		area += (p[0]*q[1] - q[0]*p[1]) / 2
+		boundary += gcd(int(math.Abs(q[0]-p[0])), int(math.Abs(q[1]-p[1])))
+	}
+	area = math.Abs(area) + float64(boundary)/2 + 1
+	shape.Solidity = math.Round(math.Min(1, n/area)*1000) / 1000
+	rect := minAreaRect(hull, 1)
+	shape.AspectRatio = math.Round(rect.Width/rect.Height*100) / 100
+	return shape
+}
+
+// convexHull returns the vertices of the convex hull of points, clockwise
+// on screen from the leftmost, by Andrew's monotone chain. It reorders
+// points.
+func convexHull(points [][2]float64) [][2]float64 {
+	sort.Slice(points, func(i, j int) bool {
+		return points[i][0] < points[j][0] || (points[i][0] == points[j][0] && points[i][1] < points[j][1])
+	})
+	unique := points[:0]
+	for _, p := range points {
+		if len(unique) == 0 || p != unique[len(unique)-1] {
+			unique = append(unique, p)
+		}
+	}
+	points = unique
+	cross := func(o, a, b [2]float64) float64 {
+		return (a[0]-o[0])*(b[1]-o[1]) - (a[1]-o[1])*(b[0]-o[0])
+	}
+	hull := make([][2]float64, 0, 2*len(points))
+	for _, p := range points {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	lower := len(hull) + 1
+	for i := len(points) - 2; i >= 0; i-- {
+		p := points[i]
+		for len(hull) >= lower && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	return hull[:max(1, len(hull)-1)]
+}
+
+// gcd returns the greatest common divisor of a and b, 0 if both are 0.
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
@@ -0,0 +1,448 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// Board reading constants.
+const (
+	// maxBoardCells is the most cells ReadBoard classifies.
+	maxBoardCells = 1024
+
+	// maxBoardTemplates and maxPieceColors limit the templates and named
+	// piece colors ReadBoard accepts.
+	maxBoardTemplates = 32
+	maxPieceColors    = 16
+
+	// boardInset is the fraction of a cell's size trimmed from each side
+	// before classifying it, clear of grid lines and their anti-aliasing.
+	boardInset = 0.08
+
+	// boardForeground is the mean per-channel difference (0-255) from a
+	// cell's own color above which a pixel belongs to a piece or mark.
+	boardForeground = 20
+
+	// minPieceFill is the fraction of a cell's middle a piece or mark must
+	// cover; less, such as a coordinate label in a corner, leaves the cell
+	// empty.
+	minPieceFill = 0.04
+
+	// boardMaskSize is the side of the grid masks are sampled onto for
+	// template matching.
+	boardMaskSize = 24
+
+	// minTemplateMatch is the smallest overlap (intersection over union)
+	// of a piece's mask with a template's for the piece to take the
+	// template's label.
+	minTemplateMatch = 0.6
+)
+
+// PieceColor names a color of piece, such as "white" and "black" in chess
+// or "red" in checkers.
+type PieceColor struct {
+	Label string `json:"label"`
+	Color string `json:"color"` // Hex color (#RRGGBB)
+}
+
+// BoardTemplate is an image of one kind of piece, such as a chess knight,
+// on a plain background.
+type BoardTemplate struct {
+	Label string
+	Image image.Image
+}
+
+// BoardOptions configures ReadBoard.
+type BoardOptions struct {
+	// Region is the board's area, or nil for the whole image.
+	Region *Region
+
+	// Rows and Columns, if both set, split Region evenly into cells. If
+	// both are zero the grid is found as by DetectGrid.
+	Rows    int
+	Columns int
+
+	// PieceColors, if any, name each piece by the nearest of these colors
+	// instead of "light" or "dark".
+	PieceColors []PieceColor
+
+	// Templates, if any, name each piece by the template whose shape it
+	// matches best instead of "disc" or "piece".
+	Templates []BoardTemplate
+}
+
+// BoardCell is one cell classified by ReadBoard.
+type BoardCell struct {
+	Row    int    `json:"row"`
+	Col    int    `json:"col"`
+	Bounds Region `json:"bounds"`
+
+	// Background is the cell's own color as hex (#RRGGBB): the square's
+	// color on a checkered board.
+	Background string `json:"background"`
+
+	// Empty is true if nothing covers at least 4% of the cell's middle.
+	Empty bool `json:"empty"`
+
+	// Fill is the fraction (0-1) of the cell's middle the piece or mark
+	// covers.
+	Fill float64 `json:"fill"`
+
+	// Shape is "x", "o", "disc" (a round piece, as in checkers or go),
+	// "piece" (any other shape), or the label of the matching template.
+	// Empty for an empty cell.
+	Shape string `json:"shape,omitempty"`
+
+	// Match is the overlap (0-1) of the piece's shape with the matching
+	// template's, if any.
+	Match float64 `json:"match,omitempty"`
+
+	// Color is the piece's median color as hex (#RRGGBB), and ColorLabel
+	// the nearest PieceColors label, or "light" or "dark" by luma.
+	Color      string `json:"color,omitempty"`
+	ColorLabel string `json:"color_label,omitempty"`
+
+	// Label names the cell's content: "X" or "O" for marks, or the color
+	// label and the shape for pieces, as in "dark disc" or "white knight".
+	// Empty for an empty cell.
+	Label string `json:"label,omitempty"`
+}
+
+// BoardResult contains the state of a game board read by ReadBoard.
+type BoardResult struct {
+	// Region is the area that was analyzed.
+	Region Region `json:"region"`
+
+	// Layout is "lines" if the grid was found, or "even" if the region was
+	// split into the given rows and columns.
+	Layout string `json:"layout"`
+
+	// Rows and Columns are the bands of cells, top to bottom and left to
+	// right.
+	Rows        []GridBand `json:"rows"`
+	Columns     []GridBand `json:"columns"`
+	RowCount    int        `json:"row_count"`
+	ColumnCount int        `json:"column_count"`
+
+	// Cells are the cells row by row, left to right.
+	Cells []BoardCell `json:"cells"`
+
+	// Board holds each cell's label, Board[row][col], "" where it is
+	// empty.
+	Board [][]string `json:"board"`
+
+	// Occupied and Empty count the cells with and without content.
+	Occupied int `json:"occupied"`
+	Empty    int `json:"empty"`
+
+	// Counts is the number of cells with each label.
+	Counts map[string]int `json:"counts"`
+}
+
+// boardMask is a piece's shape sampled onto a boardMaskSize grid.
+type boardMask [boardMaskSize * boardMaskSize]bool
+
+// ReadBoard recovers the state of a game board, such as tic-tac-toe,
+// checkers, chess, or go, by finding its cells and classifying what is in
+// each: nothing, an X or O mark, or a piece of some color and shape.
+//
+// Returns an error if the region is invalid, only one of Rows and Columns
+// is set or they exceed the region's size, no grid is found, there are
+// more than 1024 cells, a piece color is invalid or unlabeled, a template
+// is unlabeled or blank, or there are more than 16 piece colors or 32
+// templates.
+//
+// # Method
+//
+// The grid is found as by DetectGrid; a board drawn without outer lines,
+// such as tic-tac-toe, gains an outer row or column where the region's
+// edge lies about one pitch beyond the outermost line.
+//
+// Each cell's middle, trimmed by 8% on each side, is compared to the
+// cell's own color, the most common on its outline, so pieces are found
+// on either color of a checkered board. Pixels differing by more than 20
+// levels form the piece's mask. Rays cast from the mask's center look for
+// it in the outer half of its bounding box: a mask hollow in the middle
+// that nearly every ray reaches is an O; one reached along the diagonals
+// but not the axes is an X; a solid, round one every ray reaches is a
+// disc; anything else is a piece. Templates are matched by the overlap of
+// their masks with the piece's, both scaled to their bounding boxes, so
+// size and the square's color do not matter.
+func ReadBoard(img image.Image, opts BoardOptions) (*BoardResult, error) {
+	if (opts.Rows > 0) != (opts.Columns > 0) || opts.Rows < 0 || opts.Columns < 0 {
+		return nil, fmt.Errorf("rows and columns must both be positive, or both 0 to find the grid")
+	}
+	if len(opts.PieceColors) > maxPieceColors {
+		return nil, fmt.Errorf("need at most %d piece colors, got %d", maxPieceColors, len(opts.PieceColors))
+	}
+	pieceColors := make([]color.RGBA, len(opts.PieceColors))
+	for i, pc := range opts.PieceColors {
+		if pc.Label == "" {
+			return nil, fmt.Errorf("piece color %d needs a label", i)
+		}
+		c, err := parseHexColor(pc.Color)
+		if err != nil {
+			return nil, fmt.Errorf("piece color %q: invalid color %q: %w", pc.Label, pc.Color, err)
+		}
+		pieceColors[i] = c
+	}
+	if len(opts.Templates) > maxBoardTemplates {
+		return nil, fmt.Errorf("need at most %d templates, got %d", maxBoardTemplates, len(opts.Templates))
+	}
+	templates := make([]boardMask, len(opts.Templates))
+	for i, t := range opts.Templates {
+		if t.Label == "" {
+			return nil, fmt.Errorf("template %d needs a label", i)
+		}
+		tb := t.Image.Bounds()
+		r := Region{X1: 0, Y1: 0, X2: tb.Dx(), Y2: tb.Dy()}
+		mask, _, n := pieceMask(t.Image, r, outlineColor(t.Image, r))
+		if n == 0 {
+			return nil, fmt.Errorf("template %q is blank", t.Label)
+		}
+		templates[i] = mask
+	}
+
+	b := img.Bounds()
+	r := Region{X1: 0, Y1: 0, X2: b.Dx(), Y2: b.Dy()}
+	if opts.Region != nil {
+		r = *opts.Region
+		if err := validateRegion(r, b.Dx(), b.Dy()); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Rows > r.Y2-r.Y1 || opts.Columns > r.X2-r.X1 {
+		return nil, fmt.Errorf("%dx%d cells do not fit the %dx%d region", opts.Rows, opts.Columns, r.Y2-r.Y1, r.X2-r.X1)
+	}
+
+	result := &BoardResult{Region: r, Counts: map[string]int{}}
+	if opts.Rows > 0 {
+		result.Layout = "even"
+		result.Rows = evenBands(r.Y1, r.Y2, opts.Rows)
+		result.Columns = evenBands(r.X1, r.X2, opts.Columns)
+	} else {
+		grid, err := DetectGrid(img, &r, 0.6)
+		if err != nil {
+			return nil, err
+		}
+		if !grid.Found {
+			return nil, fmt.Errorf("no grid found; give rows and columns to split the board evenly")
+		}
+		result.Layout = "lines"
+		result.Rows = openBands(grid.Rows, grid.HorizontalLines, grid.RowPitch, r.Y1, r.Y2)
+		result.Columns = openBands(grid.Columns, grid.VerticalLines, grid.ColumnPitch, r.X1, r.X2)
+	}
+	result.RowCount, result.ColumnCount = len(result.Rows), len(result.Columns)
+	if n := result.RowCount * result.ColumnCount; n > maxBoardCells {
+		return nil, fmt.Errorf("found %d cells, more than %d", n, maxBoardCells)
+	}
+
+	result.Cells = make([]BoardCell, 0, result.RowCount*result.ColumnCount)
+	result.Board = make([][]string, result.RowCount)
+	for _, row := range result.Rows {
+		result.Board[row.Index] = make([]string, result.ColumnCount)
+		for _, col := range result.Columns {
+			bounds := Region{X1: col.Start, Y1: row.Start, X2: col.End, Y2: row.End}
+			cell := classifyCell(img, bounds, opts, pieceColors, templates)
+			cell.Row, cell.Col = row.Index, col.Index
+			result.Cells = append(result.Cells, cell)
+			result.Board[row.Index][col.Index] = cell.Label
+			if cell.Empty {
+				result.Empty++
+			} else {
+				result.Occupied++
+				result.Counts[cell.Label]++
+			}
+		}
+	}
+	return result, nil
+}
+
+// openBands returns bands with the outer bands of a board drawn without
+// outer lines, as in tic-tac-toe, added where the region's edge, start or
+// end, lies about one pitch beyond the outermost line.
+func openBands(bands []GridBand, lines []GridLine, pitch float64, start, end int) []GridBand {
+	if len(lines) < 2 || pitch == 0 {
+		return bands
+	}
+	near := func(d float64) bool { return d >= 0.6*pitch && d <= 1.25*pitch }
+	var out []GridBand
+	if first := lines[0]; first.Kind == "line" && near(first.Position-float64(start)) {
+		out = append(out, GridBand{Start: start, End: first.Start})
+	}
+	out = append(out, bands...)
+	if last := lines[len(lines)-1]; last.Kind == "line" && near(float64(end)-last.Position) {
+		out = append(out, GridBand{Start: last.End, End: end})
+	}
+	for i := range out {
+		out[i].Index, out[i].Size = i, out[i].End-out[i].Start
+	}
+	return out
+}
+
+// classifyCell classifies the content of the cell at bounds.
+func classifyCell(img image.Image, bounds Region, opts BoardOptions, pieceColors []color.RGBA, templates []boardMask) BoardCell {
+	dx := int(math.Round(boardInset * float64(bounds.X2-bounds.X1)))
+	dy := int(math.Round(boardInset * float64(bounds.Y2-bounds.Y1)))
+	inner := Region{X1: bounds.X1 + dx, Y1: bounds.Y1 + dy, X2: bounds.X2 - dx, Y2: bounds.Y2 - dy}
+	bg := outlineColor(img, inner)
+	cell := BoardCell{Bounds: bounds, Background: fmt.Sprintf("#%02X%02X%02X", bg.R, bg.G, bg.B)}
+
+	mask, c, n := pieceMask(img, inner, bg)
+	cell.Fill = math.Round(float64(n)/float64((inner.X2-inner.X1)*(inner.Y2-inner.Y1))*1000) / 1000
+	if cell.Fill < minPieceFill {
+		cell.Empty = true
+		return cell
+	}
+
+	cell.Color = fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B)
+	if len(pieceColors) > 0 {
+		best := math.Inf(1)
+		for i, pc := range pieceColors {
+			dr, dg, db := float64(c.R)-float64(pc.R), float64(c.G)-float64(pc.G), float64(c.B)-float64(pc.B)
+			if d := dr*dr + dg*dg + db*db; d < best {
+				best, cell.ColorLabel = d, opts.PieceColors[i].Label
+			}
+		}
+	} else if luma(color.NRGBA{c.R, c.G, c.B, 255}) >= 128 {
+		cell.ColorLabel = "light"
+	} else {
+		cell.ColorLabel = "dark"
+	}
+
+	for i, t := range templates {
+		if m := maskOverlap(mask, t); m >= minTemplateMatch && m > cell.Match {
+			cell.Shape, cell.Match = opts.Templates[i].Label, math.Round(m*1000)/1000
+		}
+	}
+	if cell.Shape == "" {
+		cell.Shape = maskShape(mask)
+	}
+	switch cell.Shape {
+	case "x", "o":
+		cell.Label = map[string]string{"x": "X", "o": "O"}[cell.Shape]
+	default:
+		cell.Label = cell.ColorLabel + " " + cell.Shape
+	}
+	return cell
+}
+
+// pieceMask returns the pixels of region r differing from bg, sampled onto
+// a boardMaskSize grid spanning their bounding box, their per-channel
+// median color, and their count.
+func pieceMask(img image.Image, r Region, bg color.RGBA) (boardMask, color.RGBA, int) {
+	b := img.Bounds()
+	w, h := r.X2-r.X1, r.Y2-r.Y1
+	on := make([]bool, w*h)
+	x1, y1, x2, y2 := w, h, 0, 0
+	var rs, gs, bs []float64
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := nrgbaAt(img, b.Min.X+r.X1+x, b.Min.Y+r.Y1+y)
+			if pixelDiff(c, bg) <= boardForeground {
+				continue
+			}
+			on[y*w+x] = true
+			x1, y1, x2, y2 = min(x1, x), min(y1, y), max(x2, x+1), max(y2, y+1)
+			rs, gs, bs = append(rs, float64(c.R)), append(gs, float64(c.G)), append(bs, float64(c.B))
+		}
+	}
+
+	var mask boardMask
+	if len(rs) == 0 {
+		return mask, color.RGBA{}, 0
+	}
+	for my := 0; my < boardMaskSize; my++ {
+		for mx := 0; mx < boardMaskSize; mx++ {
+			x := x1 + (2*mx+1)*(x2-x1)/(2*boardMaskSize)
+			y := y1 + (2*my+1)*(y2-y1)/(2*boardMaskSize)
+			mask[my*boardMaskSize+mx] = on[y*w+x]
+		}
+	}
+	fg := color.RGBA{
+		uint8(math.Round(medianFloat(rs))),
+		uint8(math.Round(medianFloat(gs))),
+		uint8(math.Round(medianFloat(bs))),
+		255,
+	}
+	return mask, fg, len(rs)
+}
+
+// maskOverlap returns the intersection over union of two masks.
+func maskOverlap(a, b boardMask) float64 {
+	var both, either int
+	for i := range a {
+		if a[i] && b[i] {
+			both++
+		}
+		if a[i] || b[i] {
+			either++
+		}
+	}
+	if either == 0 {
+		return 0
+	}
+	return float64(both) / float64(either)
+}
+
+// maskShape classifies a mask as "x", "o", "disc", or "piece" from how
+// much of its center is filled and which of 16 rays from the center reach
+// it in the outer half of its bounding box.
+func maskShape(mask boardMask) string {
+	half := float64(boardMaskSize) / 2
+	at := func(u, v float64) bool {
+		x := min(boardMaskSize-1, max(0, int(half+u*half)))
+		y := min(boardMaskSize-1, max(0, int(half+v*half)))
+		return mask[y*boardMaskSize+x]
+	}
+
+	var center, centerOn, filled int
+	for y := 0; y < boardMaskSize; y++ {
+		for x := 0; x < boardMaskSize; x++ {
+			on := mask[y*boardMaskSize+x]
+			if on {
+				filled++
+			}
+			if math.Hypot(float64(x)+0.5-half, float64(y)+0.5-half) < 0.35*half {
+				center++
+				if on {
+					centerOn++
+				}
+			}
+		}
+	}
+
+	// Rays at multiples of 22.5°: even ones along the axes and diagonals
+	var hits, axes, diagonals int
+	for k := 0; k < 16; k++ {
+		a := float64(k) * math.Pi / 8
+		hit := false
+		for rho := 0.5; rho <= 1 && !hit; rho += 0.05 {
+			hit = at(rho*math.Cos(a), rho*math.Sin(a))
+		}
+		if !hit {
+			continue
+		}
+		hits++
+		switch k % 4 {
+		case 0:
+			axes++
+		case 2:
+			diagonals++
+		}
+	}
+
+	centerFill := float64(centerOn) / float64(center)
+	fill := float64(filled) / float64(len(mask))
+	switch {
+	case centerFill < 0.2 && hits >= 14:
+		return "o"
+	case diagonals == 4 && axes == 0 && centerFill > 0.3:
+		return "x"
+	case centerFill > 0.9 && hits == 16 && fill > 0.7 && fill < 0.88:
+		return "disc"
+	}
+	return "piece"
+}
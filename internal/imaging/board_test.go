@@ -0,0 +1,156 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// paintStroke paints a line from (x1, y1) to (x2, y2), width pixels wide.
+func paintStroke(img *image.RGBA, x1, y1, x2, y2, width float64, c color.RGBA) {
+	dx, dy := x2-x1, y2-y1
+	length2 := dx*dx + dy*dy
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			px, py := float64(x)+0.5, float64(y)+0.5
+			t := math.Max(0, math.Min(1, ((px-x1)*dx+(py-y1)*dy)/length2))
+			if math.Hypot(px-x1-t*dx, py-y1-t*dy) <= width/2 {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+}
+
+func TestReadBoard_TicTacToe(t *testing.T) {
+	// X O _ / _ X O / _ _ X on a board of four 3-pixel lines, without an
+	// outer frame
+	black := color.RGBA{0, 0, 0, 255}
+	img := image.NewRGBA(image.Rect(0, 0, 126, 126))
+	fillRect(img, 0, 0, 126, 126, color.White)
+	for _, p := range []int{40, 83} {
+		fillRect(img, p, 0, p+3, 126, black)
+		fillRect(img, 0, p, 126, p+3, black)
+	}
+	for _, c := range []int{0, 1, 2} {
+		x, y := float64(c*43), float64(c*43)
+		paintStroke(img, x+8, y+8, x+32, y+32, 4, black)
+		paintStroke(img, x+32, y+8, x+8, y+32, 4, black)
+	}
+	paintRing(img, 63, 20, 9, 13, 360, black)
+	paintRing(img, 106, 63, 9, 13, 360, black)
+
+	result, err := ReadBoard(img, BoardOptions{})
+	if err != nil {
+		t.Fatalf("ReadBoard failed: %v", err)
+	}
+	if result.Layout != "lines" || result.RowCount != 3 || result.ColumnCount != 3 {
+		t.Fatalf("got %s layout, %dx%d", result.Layout, result.RowCount, result.ColumnCount)
+	}
+	want := [][]string{{"X", "O", ""}, {"", "X", "O"}, {"", "", "X"}}
+	for r := range want {
+		for c := range want[r] {
+			if result.Board[r][c] != want[r][c] {
+				t.Fatalf("board = %q, want %q", result.Board, want)
+			}
+		}
+	}
+	if result.Occupied != 5 || result.Empty != 4 || result.Counts["X"] != 3 || result.Counts["O"] != 2 {
+		t.Errorf("occupied %d, empty %d, counts %v", result.Occupied, result.Empty, result.Counts)
+	}
+}
+
+func TestReadBoard_Checkers(t *testing.T) {
+	// A 4x4 checkered board of 30-pixel squares with red and black discs
+	// on the dark squares
+	light, dark := color.RGBA{240, 217, 181, 255}, color.RGBA{181, 136, 99, 255}
+	red, black := color.RGBA{200, 30, 30, 255}, color.RGBA{20, 20, 20, 255}
+	img := image.NewRGBA(image.Rect(0, 0, 120, 120))
+	for r := 0; r < 4; r++ {
+		for c := 0; c < 4; c++ {
+			sq := light
+			if (r+c)%2 == 1 {
+				sq = dark
+			}
+			fillRect(img, c*30, r*30, c*30+30, r*30+30, sq)
+		}
+	}
+	paintRing(img, 45, 15, 0, 11, 360, red)
+	paintRing(img, 15, 45, 0, 11, 360, red)
+	paintRing(img, 75, 105, 0, 11, 360, black)
+
+	result, err := ReadBoard(img, BoardOptions{PieceColors: []PieceColor{{"red", "#C81E1E"}, {"black", "#141414"}}})
+	if err != nil {
+		t.Fatalf("ReadBoard failed: %v", err)
+	}
+	if result.RowCount != 4 || result.ColumnCount != 4 {
+		t.Fatalf("got %dx%d", result.RowCount, result.ColumnCount)
+	}
+	if result.Board[0][1] != "red disc" || result.Board[1][0] != "red disc" || result.Board[3][2] != "black disc" {
+		t.Errorf("board = %q", result.Board)
+	}
+	if result.Occupied != 3 || result.Counts["red disc"] != 2 {
+		t.Errorf("occupied %d, counts %v", result.Occupied, result.Counts)
+	}
+	if c := result.Cells[1]; c.Background != "#B58863" || c.Color != "#C81E1E" {
+		t.Errorf("cell (0, 1) = %+v", c)
+	}
+}
+
+func TestReadBoard_Templates(t *testing.T) {
+	// A triangle and an L on two squares of one row, and templates of both
+	// at other sizes
+	triangle := func(img *image.RGBA, x, y, size int, c color.RGBA) {
+		for dy := 0; dy < size; dy++ {
+			w := dy / 2
+			fillRect(img, x+size/2-w, y+dy, x+size/2+w+1, y+dy+1, c)
+		}
+	}
+	ell := func(img *image.RGBA, x, y, size int, c color.RGBA) {
+		fillRect(img, x, y, x+size/3, y+size, c)
+		fillRect(img, x, y+2*size/3, x+size, y+size, c)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 100, 50))
+	fillRect(img, 0, 0, 100, 50, color.RGBA{200, 200, 200, 255})
+	triangle(img, 10, 10, 30, color.RGBA{255, 255, 255, 255})
+	ell(img, 60, 10, 30, color.RGBA{10, 10, 10, 255})
+
+	small := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	fillRect(small, 0, 0, 20, 20, color.White)
+	triangle(small, 2, 2, 16, color.RGBA{0, 0, 255, 255})
+	large := image.NewRGBA(image.Rect(0, 0, 60, 60))
+	ell(large, 5, 5, 50, color.RGBA{255, 0, 0, 255})
+
+	result, err := ReadBoard(img, BoardOptions{
+		Rows:      1,
+		Columns:   2,
+		Templates: []BoardTemplate{{"pawn", small}, {"rook", large}},
+	})
+	if err != nil {
+		t.Fatalf("ReadBoard failed: %v", err)
+	}
+	if result.Layout != "even" || result.Board[0][0] != "light pawn" || result.Board[0][1] != "dark rook" {
+		t.Errorf("got %s layout, board %q", result.Layout, result.Board)
+	}
+	if m := result.Cells[0].Match; m < 0.8 {
+		t.Errorf("pawn match = %v", m)
+	}
+}
+
+func TestReadBoard_Invalid(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 40))
+	blank := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for _, opts := range []BoardOptions{
+		{Rows: 2},
+		{Rows: 2, Columns: 50},
+		{},
+		{Rows: 2, Columns: 2, PieceColors: []PieceColor{{"", "#000000"}}},
+		{Rows: 2, Columns: 2, PieceColors: []PieceColor{{"red", "red"}}},
+		{Rows: 2, Columns: 2, Templates: []BoardTemplate{{"pawn", blank}}},
+	} {
+		if _, err := ReadBoard(img, opts); err == nil {
+			t.Errorf("%+v: expected error", opts)
+		}
+	}
+}
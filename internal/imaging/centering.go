@@ -0,0 +1,195 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// CenteringResult reports how far an element is from the center of its
+// container.
+type CenteringResult struct {
+	// Container is the box the element should be centered in.
+	Container Region `json:"container"`
+
+	// Element is the element's bounding box.
+	Element Region `json:"element"`
+
+	// ElementDetected is true if Element was found automatically rather
+	// than supplied by the caller.
+	ElementDetected bool `json:"element_detected"`
+
+	// OffsetX and OffsetY are the element center minus the container center,
+	// in pixels. Positive values mean the element sits right of / below
+	// center. Half-pixel values occur when the spare space is odd.
+	OffsetX float64 `json:"offset_x"`
+	OffsetY float64 `json:"offset_y"`
+
+	// OffsetXPercent and OffsetYPercent express the offsets as a percentage
+	// of the container's width and height.
+	OffsetXPercent float64 `json:"offset_x_percent"`
+	OffsetYPercent float64 `json:"offset_y_percent"`
+
+	// Margins are the gaps between the element and each container edge.
+	Margins Insets `json:"margins"`
+
+	// HorizontallyCentered and VerticallyCentered are true if the absolute
+	// offset on that axis is within Tolerance.
+	HorizontallyCentered bool `json:"horizontally_centered"`
+	VerticallyCentered   bool `json:"vertically_centered"`
+
+	// Centered is true if the element is centered on both axes.
+	Centered bool `json:"centered"`
+
+	// Tolerance is the allowed offset in pixels.
+	Tolerance float64 `json:"tolerance"`
+}
+
+// CheckCentering measures how well an element is centered in a container.
+//
+// Parameters:
+//   - img: Source image (used for auto-detection and bounds checks).
+//   - container: The container box, or nil for the whole image.
+//   - element: The element box, or nil to detect it automatically.
+//   - tolerance: Maximum absolute offset in pixels that still counts as
+//     centered.
+//
+// Returns:
+//   - *CenteringResult: Offsets, margins, and pass/fail per axis.
+//   - error: Non-nil if a box is empty or outside the image, the element is
+//     not inside the container, tolerance is negative, or no element can
+//     be detected.
+//
+// # Element Detection
+//
+// The container's most common color is taken as its background. Pixels
+// that differ from it are foreground; foreground connected to the container
+// edge (a border or frame) is ignored. The element is the bounding box of
+// the remaining foreground.
+func CheckCentering(img image.Image, container, element *Region, tolerance float64) (*CenteringResult, error) {
+	if tolerance < 0 {
+		return nil, fmt.Errorf("tolerance must be non-negative, got %v", tolerance)
+	}
+
+	b := img.Bounds()
+	c := Region{X1: 0, Y1: 0, X2: b.Dx(), Y2: b.Dy()}
+	if container != nil {
+		c = *container
+		if err := validateRegion(c, b.Dx(), b.Dy()); err != nil {
+			return nil, fmt.Errorf("container: %w", err)
+		}
+	}
+
+	result := &CenteringResult{Container: c, Tolerance: tolerance}
+	if element != nil {
+		if err := validateRegion(*element, b.Dx(), b.Dy()); err != nil {
+			return nil, fmt.Errorf("element: %w", err)
+		}
+		result.Element = *element
+	} else {
+		e, ok := detectCenteredElement(img, c)
+		if !ok {
+			return nil, fmt.Errorf("no element found inside container")
+		}
+		result.Element = e
+		result.ElementDetected = true
+	}
+
+	e := result.Element
+	if e.X1 < c.X1 || e.Y1 < c.Y1 || e.X2 > c.X2 || e.Y2 > c.Y2 {
+		return nil, fmt.Errorf("element %+v is not inside container %+v", e, c)
+	}
+
+	result.Margins = Insets{
+		Top:    e.Y1 - c.Y1,
+		Left:   e.X1 - c.X1,
+		Bottom: c.Y2 - e.Y2,
+		Right:  c.X2 - e.X2,
+	}
+	result.OffsetX = float64(result.Margins.Left-result.Margins.Right) / 2
+	result.OffsetY = float64(result.Margins.Top-result.Margins.Bottom) / 2
+	result.OffsetXPercent = math.Round(result.OffsetX/float64(c.X2-c.X1)*10000) / 100
+	result.OffsetYPercent = math.Round(result.OffsetY/float64(c.Y2-c.Y1)*10000) / 100
+	result.HorizontallyCentered = math.Abs(result.OffsetX) <= tolerance
+	result.VerticallyCentered = math.Abs(result.OffsetY) <= tolerance
+	result.Centered = result.HorizontallyCentered && result.VerticallyCentered
+
+	return result, nil
+}
+
+// validateRegion checks that r is non-empty and lies within a w×h image.
+func validateRegion(r Region, w, h int) error {
+	if r.X1 < 0 || r.Y1 < 0 || r.X2 > w || r.Y2 > h {
+		return fmt.Errorf("region (%d,%d)-(%d,%d) outside image bounds (%dx%d)", r.X1, r.Y1, r.X2, r.Y2, w, h)
+	}
+	if r.X2 <= r.X1 || r.Y2 <= r.Y1 {
+		return fmt.Errorf("region (%d,%d)-(%d,%d) is empty", r.X1, r.Y1, r.X2, r.Y2)
+	}
+	return nil
+}
+
+// detectCenteredElement finds the bounding box of foreground pixels inside
+// c that are not connected to c's edge.
+func detectCenteredElement(img image.Image, c Region) (Region, bool) {
+	b := img.Bounds()
+	w, h := c.X2-c.X1, c.Y2-c.Y1
+
+	// Most common color in the container is the background
+	bg := mostCommonColor(img, image.Rect(c.X1, c.Y1, c.X2, c.Y2).Add(b.Min))
+
+	fg := make([]bool, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			fg[y*w+x] = pixelDiff(img.At(b.Min.X+c.X1+x, b.Min.Y+c.Y1+y), bg) > pixelDiffThreshold
+		}
+	}
+
+	// Clear foreground reachable from the container edge (borders, frames)
+	var stack []int
+	push := func(x, y int) {
+		if x >= 0 && y >= 0 && x < w && y < h && fg[y*w+x] {
+			fg[y*w+x] = false
+			stack = append(stack, y*w+x)
+		}
+	}
+	for x := 0; x < w; x++ {
+		push(x, 0)
+		push(x, h-1)
+	}
+	for y := 0; y < h; y++ {
+		push(0, y)
+		push(w-1, y)
+	}
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		x, y := i%w, i/w
+		push(x+1, y)
+		push(x-1, y)
+		push(x, y+1)
+		push(x, y-1)
+	}
+
+	var e Region
+	found := false
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if !fg[y*w+x] {
+				continue
+			}
+			if !found {
+				e = Region{X1: x, Y1: y, X2: x + 1, Y2: y + 1}
+				found = true
+				continue
+			}
+			e.X1 = minInt(e.X1, x)
+			e.Y1 = minInt(e.Y1, y)
+			e.X2 = maxInt(e.X2, x+1)
+			e.Y2 = maxInt(e.Y2, y+1)
+		}
+	}
+	if !found {
+		return Region{}, false
+	}
+	return Region{X1: c.X1 + e.X1, Y1: c.Y1 + e.Y1, X2: c.X1 + e.X2, Y2: c.Y1 + e.Y2}, true
+}
@@ -0,0 +1,117 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// createFramedElement draws a 100×60 white canvas with a 2px black frame and
+// a blue element at the given box.
+func createFramedElement(e Region) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 60))
+	for y := 0; y < 60; y++ {
+		for x := 0; x < 100; x++ {
+			c := color.RGBA{255, 255, 255, 255}
+			if x < 2 || y < 2 || x >= 98 || y >= 58 {
+				c = color.RGBA{0, 0, 0, 255}
+			} else if x >= e.X1 && x < e.X2 && y >= e.Y1 && y < e.Y2 {
+				c = color.RGBA{0, 0, 255, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestCheckCentering_Explicit(t *testing.T) {
+	img := createInMemoryImage(100, 100, color.RGBA{255, 255, 255, 255})
+	container := &Region{X1: 0, Y1: 0, X2: 100, Y2: 50}
+	element := &Region{X1: 40, Y1: 20, X2: 64, Y2: 30}
+
+	result, err := CheckCentering(img, container, element, 1)
+	if err != nil {
+		t.Fatalf("CheckCentering failed: %v", err)
+	}
+
+	// Left margin 40, right 36 -> 2px right of center
+	if result.OffsetX != 2 || result.OffsetY != 0 {
+		t.Errorf("offset: got (%v,%v), want (2,0)", result.OffsetX, result.OffsetY)
+	}
+	if result.OffsetXPercent != 2 {
+		t.Errorf("OffsetXPercent: got %v, want 2", result.OffsetXPercent)
+	}
+	if result.Margins != (Insets{Top: 20, Left: 40, Bottom: 20, Right: 36}) {
+		t.Errorf("Margins: got %+v", result.Margins)
+	}
+	if result.HorizontallyCentered || !result.VerticallyCentered || result.Centered {
+		t.Errorf("centered flags: got h=%v v=%v all=%v, want false/true/false",
+			result.HorizontallyCentered, result.VerticallyCentered, result.Centered)
+	}
+	if result.ElementDetected {
+		t.Error("ElementDetected should be false for an explicit element")
+	}
+}
+
+func TestCheckCentering_Detected(t *testing.T) {
+	// Element 30x10 in a 100x60 frame: 35 left/right, 25 top/bottom -> centered
+	img := createFramedElement(Region{X1: 35, Y1: 25, X2: 65, Y2: 35})
+
+	result, err := CheckCentering(img, nil, nil, 0)
+	if err != nil {
+		t.Fatalf("CheckCentering failed: %v", err)
+	}
+
+	if !result.ElementDetected {
+		t.Error("ElementDetected should be true")
+	}
+	if result.Element != (Region{X1: 35, Y1: 25, X2: 65, Y2: 35}) {
+		t.Errorf("Element: got %+v, want {35 25 65 35} (frame should be ignored)", result.Element)
+	}
+	if !result.Centered {
+		t.Errorf("should be centered, got offset (%v,%v)", result.OffsetX, result.OffsetY)
+	}
+}
+
+func TestCheckCentering_HalfPixel(t *testing.T) {
+	img := createInMemoryImage(11, 11, color.RGBA{255, 255, 255, 255})
+	element := &Region{X1: 5, Y1: 4, X2: 7, Y2: 6}
+
+	result, err := CheckCentering(img, nil, element, 0.5)
+	if err != nil {
+		t.Fatalf("CheckCentering failed: %v", err)
+	}
+
+	// Margins 5/4 horizontally, 4/5 vertically -> +0.5, -0.5
+	if result.OffsetX != 0.5 || result.OffsetY != -0.5 {
+		t.Errorf("offset: got (%v,%v), want (0.5,-0.5)", result.OffsetX, result.OffsetY)
+	}
+	if !result.Centered {
+		t.Error("half-pixel offsets should pass with tolerance 0.5")
+	}
+}
+
+func TestCheckCentering_Errors(t *testing.T) {
+	img := createInMemoryImage(50, 50, color.RGBA{255, 255, 255, 255})
+
+	tests := []struct {
+		name      string
+		container *Region
+		element   *Region
+		tolerance float64
+	}{
+		{"negative tolerance", nil, &Region{X1: 10, Y1: 10, X2: 20, Y2: 20}, -1},
+		{"container out of bounds", &Region{X1: 0, Y1: 0, X2: 60, Y2: 50}, &Region{X1: 10, Y1: 10, X2: 20, Y2: 20}, 1},
+		{"empty element", nil, &Region{X1: 10, Y1: 10, X2: 10, Y2: 20}, 1},
+		{"element outside container", &Region{X1: 0, Y1: 0, X2: 20, Y2: 20}, &Region{X1: 10, Y1: 10, X2: 30, Y2: 20}, 1},
+		{"nothing to detect", nil, nil, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := CheckCentering(img, tt.container, tt.element, tt.tolerance); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// channelChangeThreshold is the per-channel difference (0-255) above which
+// ChannelDiff counts a channel of a pixel as changed. It allows for the
+// rounding of re-encoded images.
+const channelChangeThreshold = 2
+
+// channelCurveTolerance is how far (0-255) a changed channel may be from
+// the value the tone curve predicts and still be explained by it.
+const channelCurveTolerance = 3
+
+// channelDiffGain scales differences in the ChannelDiff heat maps, so the
+// few levels of a gamma change are visible.
+const channelDiffGain = 4
+
+// Change types reported by ChannelDiff.
+const (
+	ChangeNone       = "none"
+	ChangeColorShift = "color_shift"
+	ChangeLayout     = "layout"
+	ChangeMixed      = "mixed"
+)
+
+// ChannelDifference describes how one channel differs between two images.
+type ChannelDifference struct {
+	// Channel is "r", "g", "b", "a", or "luminance" (Rec. 601 luma of the
+	// color channels).
+	Channel string `json:"channel"`
+
+	// MeanAbsDiff is the mean absolute difference (0-255).
+	MeanAbsDiff float64 `json:"mean_abs_diff"`
+
+	// MeanSignedDiff is the mean of the second image minus the first:
+	// positive if the second is brighter (or more opaque) on average.
+	MeanSignedDiff float64 `json:"mean_signed_diff"`
+
+	// MaxDiff is the largest absolute difference (0-255).
+	MaxDiff int `json:"max_diff"`
+
+	// PixelsChanged is the number of pixels whose difference in this
+	// channel exceeds 2, and ChangedPercentage that as a percentage of
+	// the compared area.
+	PixelsChanged     int     `json:"pixels_changed"`
+	ChangedPercentage float64 `json:"changed_percentage"`
+
+	// ImageBase64 is a heat map of the difference as base64 PNG: red where
+	// the second image is higher, blue where it is lower, black where
+	// they match, with differences scaled 4 times.
+	ImageBase64 string `json:"image_base64"`
+}
+
+// ChannelDiffResult contains per-channel differences between two images.
+type ChannelDiffResult struct {
+	// Width and Height of the compared area: the overlap of the two
+	// images aligned at their top-left corners.
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// Channels has the differences of r, g, b, a, and luminance, in that
+	// order.
+	Channels []ChannelDifference `json:"channels"`
+
+	// PixelsChanged is the number of pixels changed in any of r, g, b, or
+	// a. ShiftedPixels of them are explained by a per-channel tone curve,
+	// and ContentPixels are not.
+	PixelsChanged int `json:"pixels_changed"`
+	ShiftedPixels int `json:"shifted_pixels"`
+	ContentPixels int `json:"content_pixels"`
+
+	// ChangeType is "none", "color_shift" (at least 99% of the changed
+	// pixels are explained by a tone curve, as with a gamma, brightness,
+	// or color-balance change), "layout" (at most half are, as with moved
+	// or changed content), or "mixed" (a color shift that hides some
+	// content change).
+	ChangeType string `json:"change_type"`
+
+	// Reason explains the change type in a sentence.
+	Reason string `json:"reason"`
+
+	// MimeType is always "image/png" for the heat maps.
+	MimeType string `json:"mime_type"`
+}
+
+// ChannelDiff compares two images channel by channel, so that a change of
+// color, such as a gamma or color-profile regression, can be told apart
+// from a change of layout.
+//
+// Parameters:
+//   - first, second: The images to compare, aligned at their top-left
+//     corners; only the area both cover is compared.
+//
+// Returns:
+//   - *ChannelDiffResult: Statistics and a heat map for each channel, and
+//     the type of the change.
+//   - error: Non-nil if PNG encoding fails.
+//
+// # Change Type
+//
+// A color change maps every pixel of a given value to the same new value,
+// whatever its position. For each channel, the tone curve from the first
+// image's values to the second's is estimated as the most common second
+// value of each first value. A changed pixel is shifted if each of its
+// channels is within 3 of the curve, and content otherwise: a moved
+// element changes only some of the pixels of each value, which the curve
+// does not follow. A color used only by the changed content, such as a
+// moved element's own color, can still fit a curve, so a layout change
+// may have some shifted pixels.
+func ChannelDiff(first, second image.Image) (*ChannelDiffResult, error) {
+	fb, sb := first.Bounds(), second.Bounds()
+	w := min(fb.Dx(), sb.Dx())
+	h := min(fb.Dy(), sb.Dy())
+	n := w * h
+
+	// Pixels of both images, 4 channels each, and the luminance
+	a := make([]uint8, 5*n)
+	b := make([]uint8, 5*n)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := 5 * (y*w + x)
+			c1 := nrgbaAt(first, fb.Min.X+x, fb.Min.Y+y)
+			c2 := nrgbaAt(second, sb.Min.X+x, sb.Min.Y+y)
+			a[i], a[i+1], a[i+2], a[i+3], a[i+4] = c1.R, c1.G, c1.B, c1.A, luma(c1)
+			b[i], b[i+1], b[i+2], b[i+3], b[i+4] = c2.R, c2.G, c2.B, c2.A, luma(c2)
+		}
+	}
+
+	result := &ChannelDiffResult{
+		Width:    w,
+		Height:   h,
+		Channels: make([]ChannelDifference, 5),
+		MimeType: "image/png",
+	}
+	var curves [4][256]uint8
+	for ch, name := range []string{"r", "g", "b", "a", "luminance"} {
+		cd, err := channelDifference(a, b, ch, w, h)
+		if err != nil {
+			return nil, err
+		}
+		cd.Channel = name
+		result.Channels[ch] = *cd
+		if ch < 4 {
+			curves[ch] = toneCurveFit(a, b, ch)
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		changed, shifted := false, true
+		for ch := 0; ch < 4; ch++ {
+			v1, v2 := a[5*i+ch], b[5*i+ch]
+			if absDiff(v1, v2) > channelChangeThreshold {
+				changed = true
+			}
+			if absDiff(curves[ch][v1], v2) > channelCurveTolerance {
+				shifted = false
+			}
+		}
+		if !changed {
+			continue
+		}
+		result.PixelsChanged++
+		if shifted {
+			result.ShiftedPixels++
+		} else {
+			result.ContentPixels++
+		}
+	}
+	classifyChange(result)
+	return result, nil
+}
+
+// luma returns the Rec. 601 luma of a color, ignoring alpha.
+func luma(c color.NRGBA) uint8 {
+	return uint8(math.Round(0.299*float64(c.R) + 0.587*float64(c.G) + 0.114*float64(c.B)))
+}
+
+// channelDifference returns the statistics and heat map of channel ch of
+// the interleaved pixels a and b.
+func channelDifference(a, b []uint8, ch, w, h int) (*ChannelDifference, error) {
+	heat := image.NewNRGBA(image.Rect(0, 0, w, h))
+	cd := &ChannelDifference{}
+	var absSum, signedSum int
+	for i := 0; i < w*h; i++ {
+		d := int(b[5*i+ch]) - int(a[5*i+ch])
+		ad := max(d, -d)
+		absSum += ad
+		signedSum += d
+		cd.MaxDiff = max(cd.MaxDiff, ad)
+		if ad > channelChangeThreshold {
+			cd.PixelsChanged++
+		}
+		v := uint8(min(ad*channelDiffGain, 255))
+		c := color.NRGBA{A: 255}
+		if d > 0 {
+			c.R = v
+		} else {
+			c.B = v
+		}
+		heat.SetNRGBA(i%w, i/w, c)
+	}
+	if n := float64(w * h); n > 0 {
+		cd.MeanAbsDiff = math.Round(float64(absSum)/n*100) / 100
+		cd.MeanSignedDiff = math.Round(float64(signedSum)/n*100) / 100
+		cd.ChangedPercentage = math.Round(float64(cd.PixelsChanged)/n*10000) / 100
+	}
+	encoded, err := encodePNGBase64(heat)
+	if err != nil {
+		return nil, err
+	}
+	cd.ImageBase64 = encoded
+	return cd, nil
+}
+
+// toneCurveFit returns, for each value of channel ch in a, the most common
+// value of the same pixels in b. Values absent from a map to themselves.
+func toneCurveFit(a, b []uint8, ch int) [256]uint8 {
+	var hist [256][256]int
+	for i := ch; i < len(a); i += 5 {
+		hist[a[i]][b[i]]++
+	}
+	var curve [256]uint8
+	for v := range hist {
+		curve[v] = uint8(v)
+		best := 0
+		for u, count := range hist[v] {
+			if count > best {
+				curve[v], best = uint8(u), count
+			}
+		}
+	}
+	return curve
+}
+
+// classifyChange sets the change type and reason of r from its pixel
+// counts.
+func classifyChange(r *ChannelDiffResult) {
+	if r.PixelsChanged == 0 {
+		r.ChangeType = ChangeNone
+		r.Reason = "no pixel differs by more than 2 in any channel"
+		return
+	}
+	shifted := float64(r.ShiftedPixels) / float64(r.PixelsChanged)
+	percent := math.Round(shifted*1000) / 10
+	switch {
+	case shifted >= 0.99:
+		r.ChangeType = ChangeColorShift
+		r.Reason = fmt.Sprintf("%v%% of the changed pixels follow a per-channel tone curve, as with a gamma, brightness, or color-balance change", percent)
+	case shifted <= 0.5:
+		r.ChangeType = ChangeLayout
+		r.Reason = fmt.Sprintf("only %v%% of the changed pixels follow a per-channel tone curve; the rest changed with their position, as when content moves or changes", percent)
+	default:
+		r.ChangeType = ChangeMixed
+		r.Reason = fmt.Sprintf("%v%% of the changed pixels follow a per-channel tone curve, but %d do not: both colors and content changed", percent, r.ContentPixels)
+	}
+}
@@ -0,0 +1,94 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// gammaAdjust returns img with gamma applied to its color channels.
+func gammaAdjust(img *image.NRGBA, gamma float64) *image.NRGBA {
+	out := image.NewNRGBA(img.Rect)
+	for i := range img.Pix {
+		v := img.Pix[i]
+		if i%4 != 3 {
+			v = uint8(math.Round(255 * math.Pow(float64(v)/255, gamma)))
+		}
+		out.Pix[i] = v
+	}
+	return out
+}
+
+// moveBlock returns img with a 20x20 block copied 60 pixels to the right.
+func moveBlock(img *image.NRGBA) *image.NRGBA {
+	out := image.NewNRGBA(img.Rect)
+	copy(out.Pix, img.Pix)
+	for y := 150; y < 170; y++ {
+		for x := 20; x < 40; x++ {
+			out.SetNRGBA(x+60, y, img.NRGBAAt(x, y))
+			out.SetNRGBA(x, y, color.NRGBA{250, 250, 250, 255})
+		}
+	}
+	return out
+}
+
+func TestChannelDiff(t *testing.T) {
+	// A screenshot with a gradient panel and a dark block
+	base := uiScreenshot()
+	for y := 140; y < 180; y++ {
+		for x := 0; x < 256; x++ {
+			base.SetNRGBA(x, y, color.NRGBA{uint8(x), uint8(255 - x), 128, 255})
+		}
+	}
+	for y := 150; y < 170; y++ {
+		for x := 20; x < 40; x++ {
+			base.SetNRGBA(x, y, color.NRGBA{10, 10, 10, 255})
+		}
+	}
+
+	tests := []struct {
+		name   string
+		second *image.NRGBA
+		want   string
+	}{
+		{"identical", base, ChangeNone},
+		{"gamma", gammaAdjust(base, 0.8), ChangeColorShift},
+		{"moved", moveBlock(base), ChangeLayout},
+		{"both", gammaAdjust(moveBlock(base), 0.8), ChangeMixed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ChannelDiff(base, tt.second)
+			if err != nil {
+				t.Fatalf("ChannelDiff failed: %v", err)
+			}
+			if result.ChangeType != tt.want {
+				t.Errorf("got %s (%d shifted, %d content): %s", result.ChangeType, result.ShiftedPixels, result.ContentPixels, result.Reason)
+			}
+			if len(result.Channels) != 5 || result.Channels[4].Channel != "luminance" || result.Channels[0].ImageBase64 == "" {
+				t.Fatalf("channels: got %d", len(result.Channels))
+			}
+			if result.ShiftedPixels+result.ContentPixels != result.PixelsChanged {
+				t.Errorf("counts: got %+v", result)
+			}
+		})
+	}
+
+	// A gamma below 1 brightens every channel and leaves alpha alone
+	result, _ := ChannelDiff(base, gammaAdjust(base, 0.8))
+	for _, ch := range result.Channels[:3] {
+		if ch.MeanSignedDiff <= 0 || ch.MeanSignedDiff != ch.MeanAbsDiff {
+			t.Errorf("%s: got %+v", ch.Channel, ch)
+		}
+	}
+	if a := result.Channels[3]; a.MaxDiff != 0 || a.PixelsChanged != 0 {
+		t.Errorf("alpha: got %+v", a)
+	}
+
+	// Only the overlap of different sizes is compared
+	result, err := ChannelDiff(base, base.SubImage(image.Rect(0, 0, 100, 50)))
+	if err != nil || result.Width != 100 || result.Height != 50 || result.ChangeType != ChangeNone {
+		t.Errorf("overlap: got %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,499 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// Tunables for InspectChannels.
+const (
+	channelTile = 32 // Tile side for locating LSB anomalies
+
+	// A tile's content is structured if its bit 1 plane agrees with the
+	// neighboring pixel this often; its LSB plane is noise if it agrees
+	// less often than channelNoisyAgreement (0.5 is random).
+	channelStructuredAgreement = 0.9
+	channelNoisyAgreement      = 0.6
+
+	// A channel is anomalous if this share of its structured tiles, and
+	// at least channelMinNoisyTiles of them, have a noise LSB plane.
+	channelNoisyShare    = 0.1
+	channelMinNoisyTiles = 2
+
+	// channelEmbeddingThreshold is the pairs-of-values probability from
+	// which a channel is anomalous, if the same test on the value pairs
+	// shifted by one stays below channelShiftedThreshold; channelMinPairs
+	// is the fewest value pairs for the test to count.
+	channelEmbeddingThreshold = 0.95
+	channelShiftedThreshold   = 0.5
+	channelMinPairs           = 8
+
+	maxChannelExamples = 5
+)
+
+// channelNames are the channels InspectChannels accepts, in NRGBA order.
+var channelNames = []string{"r", "g", "b", "a"}
+
+// ChannelInspection reports the bit planes of an image's channels and
+// signs of data hidden in them.
+type ChannelInspection struct {
+	// Width and Height of the image in pixels.
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// HasAlpha is true if any pixel is not fully opaque.
+	HasAlpha bool `json:"has_alpha"`
+
+	// Channels holds the statistics of each channel inspected.
+	Channels []ChannelStats `json:"channels"`
+
+	// Alpha describes the alpha channel; nil if every pixel is opaque.
+	Alpha *AlphaStats `json:"alpha,omitempty"`
+
+	// Planes are the requested bit planes as images.
+	Planes []BitPlane `json:"planes"`
+
+	// SuspiciousRegions are up to 5 tiles, most anomalous first, where a
+	// channel's LSB plane is noise although its content is structured.
+	SuspiciousRegions []Region `json:"suspicious_regions"`
+
+	// Suspicious is true if any channel is anomalous or transparent
+	// pixels hide color.
+	Suspicious bool `json:"suspicious"`
+
+	// Findings explains each anomaly, and caveats about the image.
+	Findings []string `json:"findings"`
+}
+
+// ChannelStats are the least significant bit (LSB) statistics of one
+// channel.
+type ChannelStats struct {
+	// Channel is "r", "g", "b", or "a".
+	Channel string `json:"channel"`
+
+	// LSBOnes is the fraction (0.0-1.0) of pixels whose LSB is 1, and
+	// LSBEntropy its Shannon entropy in bits (0.0-1.0).
+	LSBOnes    float64 `json:"lsb_ones"`
+	LSBEntropy float64 `json:"lsb_entropy"`
+
+	// LSBAgreement and Bit1Agreement are the fractions (0.0-1.0) of
+	// horizontally and vertically adjacent pixel pairs whose bit 0 and
+	// bit 1 are equal. Random bits agree half the time; flat content
+	// nearly always.
+	LSBAgreement  float64 `json:"lsb_agreement"`
+	Bit1Agreement float64 `json:"bit1_agreement"`
+
+	// EmbeddingProbability is the probability (0.0-1.0), from the
+	// pairs-of-values chi-square test, that the channel's histogram has
+	// the equalized value pairs that LSB embedding leaves. 0 if there are
+	// too few pairs of values to test.
+	EmbeddingProbability float64 `json:"embedding_probability"`
+
+	// StructuredTiles is the number of 32x32 tiles whose bit 1 plane is
+	// structured, and NoisyTiles how many of those have a noise LSB
+	// plane.
+	StructuredTiles int `json:"structured_tiles"`
+	NoisyTiles      int `json:"noisy_tiles"`
+
+	// Anomaly is true if the embedding probability reaches 0.95 (see
+	// InspectChannels), or at least 2 and 10% of the structured tiles are
+	// noisy.
+	Anomaly bool `json:"anomaly"`
+}
+
+// AlphaStats describes an image's alpha channel.
+type AlphaStats struct {
+	// TransparentPixels is the number of fully transparent pixels, and
+	// HiddenColorPixels how many of those have a color other than black,
+	// which is invisible but kept in the file.
+	TransparentPixels int `json:"transparent_pixels"`
+	HiddenColorPixels int `json:"hidden_color_pixels"`
+
+	// DistinctValues is the number of distinct alpha values.
+	DistinctValues int `json:"distinct_values"`
+
+	// ImageBase64 is the alpha channel as a grayscale PNG, white where
+	// opaque. Empty unless images are requested.
+	ImageBase64 string `json:"image_base64,omitempty"`
+
+	// RevealedBase64 is the image with every pixel made opaque, showing
+	// any color hidden under transparent pixels, as PNG. Empty unless
+	// images are requested and HiddenColorPixels is above 0.
+	RevealedBase64 string `json:"revealed_base64,omitempty"`
+}
+
+// BitPlane is one bit of one channel as an image.
+type BitPlane struct {
+	// Channel is "r", "g", "b", or "a", and Bit the bit, 0 (least
+	// significant) to 7.
+	Channel string `json:"channel"`
+	Bit     int    `json:"bit"`
+
+	// ImageBase64 is the plane as a black and white PNG, white where the
+	// bit is 1.
+	ImageBase64 string `json:"image_base64"`
+
+	// MimeType is always "image/png".
+	MimeType string `json:"mime_type"`
+}
+
+// InspectChannels extracts bit planes and the alpha channel of an image
+// and looks for data hidden in their least significant bits, for
+// investigating suspicious images.
+//
+// Parameters:
+//   - img: Source image.
+//   - channels: Channels to inspect, of "r", "g", "b", and "a". Empty
+//     means "r", "g", and "b", plus "a" if the image has transparency.
+//   - bits: Bit planes (0-7) of each channel to return as images.
+//   - includeImages: Whether to return the bit planes and alpha images.
+//
+// Returns:
+//   - *ChannelInspection: Per-channel LSB statistics, the alpha channel,
+//     the bit plane images, and any anomalies.
+//   - error: Non-nil if the image is smaller than 2x2 pixels, a channel or
+//     bit is invalid, or PNG encoding fails.
+//
+// # Method
+//
+// LSB steganography replaces the lowest bit of pixel values with message
+// bits, which look random. Two tests detect it:
+//
+//   - Pairs of values: embedding equalizes the counts of the values 2k and
+//     2k+1, which a chi-square test against their mean detects. It is
+//     strongest when most pixels carry message bits. Smooth histograms,
+//     as of gradients, have equal counts for all neighboring values, so
+//     the test only counts if the pairs 2k+1 and 2k+2 are not also equal.
+//   - Plane structure: in flat or smooth content, such as screenshots,
+//     adjacent pixels share their low bits. A tile whose bit 1 plane is
+//     structured but whose LSB plane is noise is a likely embedding site,
+//     even when only part of the image carries a message.
+//
+// Photographs have noisy low bits throughout, so the plane structure test
+// finds nothing in them, and JPEG images hide data in their DCT
+// coefficients rather than pixel bits; neither is examined here.
+func InspectChannels(img image.Image, channels []string, bits []int, includeImages bool) (*ChannelInspection, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w < 2 || h < 2 {
+		return nil, fmt.Errorf("image must be at least 2x2 pixels, got %dx%d", w, h)
+	}
+	for _, bit := range bits {
+		if bit < 0 || bit > 7 {
+			return nil, fmt.Errorf("invalid bit %d: must be 0 to 7", bit)
+		}
+	}
+
+	// Split the image into non-premultiplied channel planes, so colors
+	// under transparent pixels are kept
+	planes := make([][]uint8, 4)
+	for c := range planes {
+		planes[c] = make([]uint8, w*h)
+	}
+	hasAlpha := false
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA)
+			i := y*w + x
+			planes[0][i], planes[1][i], planes[2][i], planes[3][i] = c.R, c.G, c.B, c.A
+			hasAlpha = hasAlpha || c.A != 255
+		}
+	}
+
+	if len(channels) == 0 {
+		channels = channelNames[:3]
+		if hasAlpha {
+			channels = channelNames
+		}
+	}
+	indexes := make([]int, len(channels))
+	for k, name := range channels {
+		indexes[k] = -1
+		for c, known := range channelNames {
+			if name == known {
+				indexes[k] = c
+			}
+		}
+		if indexes[k] < 0 {
+			return nil, fmt.Errorf("invalid channel %q: must be r, g, b, or a", name)
+		}
+	}
+
+	result := &ChannelInspection{
+		Width:             w,
+		Height:            h,
+		HasAlpha:          hasAlpha,
+		Channels:          make([]ChannelStats, 0, len(channels)),
+		Planes:            []BitPlane{},
+		SuspiciousRegions: []Region{},
+		Findings:          []string{},
+	}
+	var suspicious []scoredRegion
+	for k, name := range channels {
+		stats, tiles, embedded := channelStats(planes[indexes[k]], w, h)
+		stats.Channel = name
+		result.Channels = append(result.Channels, stats)
+		if !stats.Anomaly {
+			continue
+		}
+		result.Suspicious = true
+		if embedded {
+			result.Findings = append(result.Findings, fmt.Sprintf(
+				"Channel %s: the counts of values 2k and 2k+1 are equalized (embedding probability %.2f), as LSB embedding leaves them", name, stats.EmbeddingProbability))
+		}
+		if len(tiles) > 0 {
+			result.Findings = append(result.Findings, fmt.Sprintf(
+				"Channel %s: the LSB plane is noise in %d of %d tiles with structured content, a sign of hidden data; see suspicious_regions", name, stats.NoisyTiles, stats.StructuredTiles))
+			suspicious = append(suspicious, tiles...)
+		}
+	}
+	result.SuspiciousRegions = mergeSuspiciousTiles(suspicious)
+
+	if hasAlpha {
+		alpha := alphaStats(planes)
+		result.Alpha = alpha
+		if alpha.HiddenColorPixels > 0 {
+			result.Suspicious = true
+			result.Findings = append(result.Findings, fmt.Sprintf(
+				"%d fully transparent pixels have a color other than black, invisible but kept in the file; see the revealed image", alpha.HiddenColorPixels))
+		}
+		if includeImages {
+			gray := &image.Gray{Pix: planes[3], Stride: w, Rect: image.Rect(0, 0, w, h)}
+			var err error
+			if alpha.ImageBase64, err = encodePNGBase64(gray); err != nil {
+				return nil, err
+			}
+			if alpha.HiddenColorPixels > 0 {
+				opaque := image.NewNRGBA(image.Rect(0, 0, w, h))
+				for i := 0; i < w*h; i++ {
+					copy(opaque.Pix[i*4:], []uint8{planes[0][i], planes[1][i], planes[2][i], 255})
+				}
+				if alpha.RevealedBase64, err = encodePNGBase64(opaque); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if _, ok := img.(*image.YCbCr); ok {
+		result.Findings = append(result.Findings,
+			"The image is JPEG-compressed: its low bits are compression noise, and JPEG steganography hides data in DCT coefficients, which are not examined")
+	}
+
+	if includeImages {
+		for k, name := range channels {
+			for _, bit := range bits {
+				encoded, err := encodePNGBase64(bitPlaneImage(planes[indexes[k]], w, h, bit))
+				if err != nil {
+					return nil, err
+				}
+				result.Planes = append(result.Planes, BitPlane{Channel: name, Bit: bit, ImageBase64: encoded, MimeType: "image/png"})
+			}
+		}
+	}
+	return result, nil
+}
+
+// channelStats measures one channel's LSB plane. If the channel is
+// anomalous, it also returns the noisy tiles, if they make it so, scored
+// by how much more their bit 1 plane agrees than their LSB plane, and
+// whether the pairs-of-values test does.
+func channelStats(plane []uint8, w, h int) (stats ChannelStats, noisy []scoredRegion, embedded bool) {
+	ones := 0
+	var hist [256]int
+	for _, v := range plane {
+		ones += int(v & 1)
+		hist[v]++
+	}
+	p := float64(ones) / float64(len(plane))
+	stats.LSBOnes = math.Round(p*10000) / 10000
+	if p > 0 && p < 1 {
+		stats.LSBEntropy = math.Round(-(p*math.Log2(p)+(1-p)*math.Log2(1-p))*10000) / 10000
+	}
+	agree0, agree1, pairs := planeAgreement(plane, w, image.Rect(0, 0, w, h))
+	stats.LSBAgreement = math.Round(float64(agree0)/float64(pairs)*10000) / 10000
+	stats.Bit1Agreement = math.Round(float64(agree1)/float64(pairs)*10000) / 10000
+	stats.EmbeddingProbability = math.Round(pairsOfValues(hist, 0)*10000) / 10000
+	embedded = stats.EmbeddingProbability >= channelEmbeddingThreshold && pairsOfValues(hist, 1) < channelShiftedThreshold
+
+	for ty := 0; ty+channelTile/2 <= h; ty += channelTile {
+		for tx := 0; tx+channelTile/2 <= w; tx += channelTile {
+			tile := image.Rect(tx, ty, tx+channelTile, ty+channelTile).Intersect(image.Rect(0, 0, w, h))
+			a0, a1, n := planeAgreement(plane, w, tile)
+			structure := float64(a1) / float64(n)
+			if structure < channelStructuredAgreement {
+				continue
+			}
+			stats.StructuredTiles++
+			if float64(a0)/float64(n) < channelNoisyAgreement {
+				stats.NoisyTiles++
+				noisy = append(noisy, scoredRegion{
+					region: Region{X1: tile.Min.X, Y1: tile.Min.Y, X2: tile.Max.X, Y2: tile.Max.Y},
+					score:  structure - float64(a0)/float64(n),
+				})
+			}
+		}
+	}
+	if stats.NoisyTiles < channelMinNoisyTiles || float64(stats.NoisyTiles) < channelNoisyShare*float64(stats.StructuredTiles) {
+		noisy = nil
+	}
+	stats.Anomaly = embedded || len(noisy) > 0
+	return stats, noisy, embedded
+}
+
+// planeAgreement counts, within r, the horizontally and vertically
+// adjacent pixel pairs whose bit 0 and whose bit 1 are equal, and the
+// pairs compared.
+func planeAgreement(plane []uint8, w int, r image.Rectangle) (agree0, agree1, pairs int) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			v := plane[y*w+x]
+			if x+1 < r.Max.X {
+				d := v ^ plane[y*w+x+1]
+				agree0 += int(1 - d&1)
+				agree1 += int(1 - d>>1&1)
+				pairs++
+			}
+			if y+1 < r.Max.Y {
+				d := v ^ plane[(y+1)*w+x]
+				agree0 += int(1 - d&1)
+				agree1 += int(1 - d>>1&1)
+				pairs++
+			}
+		}
+	}
+	return agree0, agree1, pairs
+}
+
+// pairsOfValues is the chi-square attack of Westfeld and Pfitzmann
+// (1999): the probability that the counts of each pair of values
+// 2k+offset and 2k+offset+1 are as equal as LSB embedding makes them for
+// offset 0. Pairs with fewer than 10 pixels are left out; with fewer than
+// channelMinPairs pairs left, it returns 0.
+func pairsOfValues(hist [256]int, offset int) float64 {
+	chi2 := 0.0
+	n := 0
+	for v := offset; v+1 < 256; v += 2 {
+		expected := float64(hist[v]+hist[v+1]) / 2
+		if expected < 5 {
+			continue
+		}
+		d := float64(hist[v]) - expected
+		chi2 += d * d / expected
+		n++
+	}
+	if n < channelMinPairs {
+		return 0
+	}
+	return chiSquareSurvival(chi2, float64(n-1))
+}
+
+// chiSquareSurvival returns the probability that a chi-square variable
+// with df degrees of freedom exceeds x: the regularized upper incomplete
+// gamma function Q(df/2, x/2).
+func chiSquareSurvival(x, df float64) float64 {
+	a, z := df/2, x/2
+	if z <= 0 {
+		return 1
+	}
+	lnPrefix := a*math.Log(z) - z
+	lg, _ := math.Lgamma(a)
+	lnPrefix -= lg
+	if z < a+1 {
+		// Series for the lower function P
+		sum, term := 1/a, 1/a
+		for n := 1.0; n < 500; n++ {
+			term *= z / (a + n)
+			sum += term
+			if term < sum*1e-14 {
+				break
+			}
+		}
+		return 1 - sum*math.Exp(lnPrefix)
+	}
+	// Continued fraction for Q (modified Lentz)
+	const tiny = 1e-300
+	bn := z + 1 - a
+	c, d := 1/tiny, 1/bn
+	f := d
+	for i := 1.0; i < 500; i++ {
+		an := -i * (i - a)
+		bn += 2
+		d = an*d + bn
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = bn + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		f *= delta
+		if math.Abs(delta-1) < 1e-14 {
+			break
+		}
+	}
+	return math.Exp(lnPrefix) * f
+}
+
+// alphaStats describes the alpha plane, planes[3], and the colors under
+// its transparent pixels.
+func alphaStats(planes [][]uint8) *AlphaStats {
+	stats := &AlphaStats{}
+	var seen [256]bool
+	for i, a := range planes[3] {
+		if !seen[a] {
+			seen[a] = true
+			stats.DistinctValues++
+		}
+		if a == 0 {
+			stats.TransparentPixels++
+			if planes[0][i]|planes[1][i]|planes[2][i] != 0 {
+				stats.HiddenColorPixels++
+			}
+		}
+	}
+	return stats
+}
+
+// bitPlaneImage returns one bit of a channel plane as a two-color image,
+// white where the bit is 1.
+func bitPlaneImage(plane []uint8, w, h, bit int) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, w, h), color.Palette{color.Black, color.White})
+	for i, v := range plane {
+		img.Pix[i] = v >> uint(bit) & 1
+	}
+	return img
+}
+
+// mergeSuspiciousTiles returns the distinct tiles of several channels,
+// keeping each tile's highest score, most anomalous first, up to
+// maxChannelExamples.
+func mergeSuspiciousTiles(tiles []scoredRegion) []Region {
+	best := make(map[Region]float64)
+	for _, t := range tiles {
+		if s, ok := best[t.region]; !ok || t.score > s {
+			best[t.region] = t.score
+		}
+	}
+	merged := make([]scoredRegion, 0, len(best))
+	for r, s := range best {
+		merged = append(merged, scoredRegion{region: r, score: s})
+	}
+	// Order ties by position, so the result does not depend on map order
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].score != merged[j].score {
+			return merged[i].score > merged[j].score
+		}
+		a, b := merged[i].region, merged[j].region
+		return a.Y1 < b.Y1 || (a.Y1 == b.Y1 && a.X1 < b.X1)
+	})
+	regions := make([]Region, 0, maxChannelExamples)
+	for i := 0; i < len(merged) && i < maxChannelExamples; i++ {
+		regions = append(regions, merged[i].region)
+	}
+	return regions
+}
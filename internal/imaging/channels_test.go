@@ -0,0 +1,171 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// uiScreenshot returns a 256x256 flat-color image like a screenshot.
+func uiScreenshot() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 256, 256))
+	for y := 0; y < 256; y++ {
+		for x := 0; x < 256; x++ {
+			c := color.NRGBA{250, 250, 250, 255}
+			switch {
+			case y < 40:
+				c = color.NRGBA{32, 64, 160, 255}
+			case x >= 40 && x < 200 && y >= 80 && y < 120:
+				c = color.NRGBA{230, 230, 230, 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestInspectChannels_Clean(t *testing.T) {
+	result, err := InspectChannels(uiScreenshot(), nil, []int{0, 7}, true)
+	if err != nil {
+		t.Fatalf("InspectChannels failed: %v", err)
+	}
+	if result.Suspicious || result.HasAlpha || result.Alpha != nil || len(result.Findings) != 0 {
+		t.Errorf("clean screenshot: got suspicious=%v findings=%v", result.Suspicious, result.Findings)
+	}
+	if len(result.Channels) != 3 || result.Channels[0].Channel != "r" || result.Channels[0].LSBAgreement < 0.99 {
+		t.Errorf("channels: got %+v", result.Channels)
+	}
+	if len(result.Planes) != 6 || result.Planes[1].Channel != "r" || result.Planes[1].Bit != 7 || result.Planes[1].ImageBase64 == "" {
+		t.Errorf("planes: got %d", len(result.Planes))
+	}
+
+	// A smooth gradient has equal counts for every pair of values, but is
+	// not an embedding
+	gradient := image.NewGray(image.Rect(0, 0, 256, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 256; x++ {
+			gradient.SetGray(x, y, color.Gray{uint8(x)})
+		}
+	}
+	result, err = InspectChannels(gradient, []string{"r"}, nil, false)
+	if err != nil {
+		t.Fatalf("InspectChannels failed: %v", err)
+	}
+	if result.Suspicious || result.Channels[0].EmbeddingProbability != 1 {
+		t.Errorf("gradient: got %+v", result.Channels[0])
+	}
+}
+
+func TestInspectChannels_LSBEmbedding(t *testing.T) {
+	// Message bits in the red LSBs of the top-left 96x96 pixels
+	rng := rand.New(rand.NewSource(1))
+	img := uiScreenshot()
+	for y := 0; y < 96; y++ {
+		for x := 0; x < 96; x++ {
+			c := img.NRGBAAt(x, y)
+			c.R = c.R&^1 | uint8(rng.Intn(2))
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	result, err := InspectChannels(img, nil, []int{0}, false)
+	if err != nil {
+		t.Fatalf("InspectChannels failed: %v", err)
+	}
+	r := result.Channels[0]
+	if !result.Suspicious || !r.Anomaly || r.NoisyTiles != 9 || result.Channels[1].Anomaly {
+		t.Errorf("got suspicious=%v red=%+v green anomaly=%v", result.Suspicious, r, result.Channels[1].Anomaly)
+	}
+	if len(result.SuspiciousRegions) != 5 || len(result.Planes) != 0 {
+		t.Errorf("got %d regions, %d planes", len(result.SuspiciousRegions), len(result.Planes))
+	}
+	for _, reg := range result.SuspiciousRegions {
+		if reg.X2 > 96 || reg.Y2 > 96 {
+			t.Errorf("region %+v outside the embedding", reg)
+		}
+	}
+
+	// A noisy image of even values, 4k three times as often as 4k+2,
+	// before and after embedding in every pixel: only the pairs-of-values
+	// test can tell
+	noisy := image.NewGray(image.Rect(0, 0, 256, 256))
+	for i := range noisy.Pix {
+		noisy.Pix[i] = uint8(rng.Intn(48) * 4)
+		if rng.Intn(4) == 0 {
+			noisy.Pix[i] += 2
+		}
+	}
+	result, err = InspectChannels(noisy, []string{"r"}, nil, false)
+	if err != nil {
+		t.Fatalf("InspectChannels failed: %v", err)
+	}
+	if result.Suspicious || result.Channels[0].EmbeddingProbability > 0.01 {
+		t.Errorf("noisy clean: got %+v", result.Channels[0])
+	}
+	for i := range noisy.Pix {
+		noisy.Pix[i] |= uint8(rng.Intn(2))
+	}
+	result, err = InspectChannels(noisy, []string{"r"}, nil, false)
+	if err != nil {
+		t.Fatalf("InspectChannels failed: %v", err)
+	}
+	if !result.Suspicious || result.Channels[0].EmbeddingProbability < 0.95 || len(result.Findings) != 1 {
+		t.Errorf("noisy embedded: got %+v, findings %v", result.Channels[0], result.Findings)
+	}
+}
+
+func TestInspectChannels_HiddenAlphaColor(t *testing.T) {
+	img := uiScreenshot()
+	for y := 200; y < 256; y++ {
+		for x := 0; x < 256; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{0, 0, 0, 0})
+		}
+	}
+	// Invisible text under the transparent strip
+	for x := 10; x < 100; x++ {
+		img.SetNRGBA(x, 220, color.NRGBA{255, 0, 0, 0})
+	}
+	result, err := InspectChannels(img, nil, nil, true)
+	if err != nil {
+		t.Fatalf("InspectChannels failed: %v", err)
+	}
+	if !result.HasAlpha || len(result.Channels) != 4 || result.Channels[3].Channel != "a" {
+		t.Fatalf("got has_alpha=%v, %d channels", result.HasAlpha, len(result.Channels))
+	}
+	a := result.Alpha
+	if a == nil || a.TransparentPixels != 56*256 || a.HiddenColorPixels != 90 || a.DistinctValues != 2 {
+		t.Fatalf("alpha: got %+v", a)
+	}
+	if !result.Suspicious || a.ImageBase64 == "" || a.RevealedBase64 == "" {
+		t.Errorf("got suspicious=%v, images %v %v", result.Suspicious, a.ImageBase64 != "", a.RevealedBase64 != "")
+	}
+}
+
+func TestInspectChannels_Errors(t *testing.T) {
+	img := uiScreenshot()
+	if _, err := InspectChannels(img, []string{"x"}, nil, false); err == nil {
+		t.Error("expected error for an unknown channel")
+	}
+	if _, err := InspectChannels(img, nil, []int{8}, false); err == nil {
+		t.Error("expected error for bit 8")
+	}
+	if _, err := InspectChannels(image.NewGray(image.Rect(0, 0, 1, 5)), nil, nil, false); err == nil {
+		t.Error("expected error for a 1-pixel-wide image")
+	}
+}
+
+func TestChiSquareSurvival(t *testing.T) {
+	tests := []struct{ x, df, want float64 }{
+		{3.841, 1, 0.05},
+		{18.307, 10, 0.05},
+		{10, 10, 0.4405},
+		{124.342, 100, 0.05},
+		{0, 5, 1},
+	}
+	for _, tt := range tests {
+		if got := chiSquareSurvival(tt.x, tt.df); math.Abs(got-tt.want) > 0.001 {
+			t.Errorf("chiSquareSurvival(%v, %v) = %.4f, want %v", tt.x, tt.df, got, tt.want)
+		}
+	}
+}
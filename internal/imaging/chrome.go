@@ -0,0 +1,393 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// Window chrome platforms (see WindowChromeOptions.Platform).
+const (
+	ChromeMacOS   = "macos"
+	ChromeWindows = "windows"
+)
+
+// Title bar methods (see WindowChromeResult.TitleBarMethod).
+const (
+	// TitleBarStandard is a title bar whose bottom edge was found at the
+	// height of a standard title bar of the platform.
+	TitleBarStandard = "standard_height"
+
+	// TitleBarDetected is a title bar whose bottom edge was found at
+	// another height, as for custom title bars.
+	TitleBarDetected = "detected"
+
+	// TitleBarAssumed is a title bar of the platform's default height,
+	// assumed because no edge was found, as when the title bar and the
+	// content have the same background.
+	TitleBarAssumed = "assumed"
+)
+
+// chromeTitleHeights are the heights, in points, of standard title bars,
+// the first being the default: on macOS, title bars alone and with a
+// compact or regular unified toolbar; on Windows, Windows 11 and Windows
+// 10 title bars.
+var chromeTitleHeights = map[string][]int{
+	ChromeMacOS:   {28, 38, 52},
+	ChromeWindows: {32, 30, 31},
+}
+
+// chromeScales are the display scales (pixels per point) tried when the
+// scale is not known.
+var chromeScales = []float64{1, 1.25, 1.5, 2}
+
+// Window chrome detection thresholds: a window pixel has at least
+// chromeOpaque alpha; two rows meet at an edge if at least chromeEdgeShare
+// of their pixels differ by more than chromeEdgeDiff in luma; a title bar
+// has at least chromeBarShare of its pixels within chromeEdgeDiff of its
+// background; and it is at most chromeMaxBar points tall.
+const (
+	chromeOpaque    = 250
+	chromeEdgeDiff  = 8
+	chromeEdgeShare = 0.9
+	chromeBarShare  = 0.7
+	chromeMaxBar    = 80
+)
+
+// trafficLights are the colors of the close, minimize, and zoom buttons
+// at the left of an active macOS title bar.
+var trafficLights = []color.NRGBA{
+	{R: 255, G: 95, B: 87, A: 255},
+	{R: 254, G: 188, B: 46, A: 255},
+	{R: 40, G: 200, B: 64, A: 255},
+}
+
+// WindowChromeOptions configures DetectWindowChrome.
+type WindowChromeOptions struct {
+	// Platform is ChromeMacOS or ChromeWindows if known, so only its
+	// title bar heights are tried, and its default height is assumed if
+	// no title bar edge is found; empty to detect it.
+	Platform string
+
+	// Scale is the display scale the screenshot was taken at (pixels
+	// per point, 1 to 4); 0 to detect it.
+	Scale float64
+
+	// IncludeImage returns the content area as a PNG image.
+	IncludeImage bool
+}
+
+// WindowChromeResult describes the window chrome of a screenshot of a
+// window: its drop shadow and title bar, and the content area left
+// without them.
+type WindowChromeResult struct {
+	// Detected is true if a shadow or a title bar was found.
+	Detected bool `json:"detected"`
+
+	// Platform is ChromeMacOS if the traffic light buttons were found,
+	// the platform given otherwise, or empty if unknown.
+	Platform string `json:"platform,omitempty"`
+
+	// Scale is the display scale (pixels per point), if known: given,
+	// from the size of the traffic light buttons, or from the height of
+	// a standard title bar.
+	Scale float64 `json:"scale,omitempty"`
+
+	// Window is the window without its shadow.
+	Window Region `json:"window"`
+
+	// Shadow holds the widths of the shadow, or other transparent
+	// margin, around the window.
+	Shadow Insets `json:"shadow"`
+
+	// TitleBar is the title bar, including any separator line below it;
+	// nil if none was found or assumed.
+	TitleBar *Region `json:"title_bar,omitempty"`
+
+	// TitleBarMethod is how the title bar was found: TitleBarStandard,
+	// TitleBarDetected, or TitleBarAssumed.
+	TitleBarMethod string `json:"title_bar_method,omitempty"`
+
+	// Content is the window below its title bar: the app's content.
+	Content Region `json:"content"`
+
+	// Offset is the top left corner of Content. Subtract it from image
+	// coordinates to get content coordinates.
+	Offset Point `json:"offset"`
+
+	// ImageBase64 is the content area encoded as base64 PNG, if
+	// requested.
+	ImageBase64 string `json:"image_base64,omitempty"`
+
+	// MimeType is "image/png" when ImageBase64 is set.
+	MimeType string `json:"mime_type,omitempty"`
+}
+
+// DetectWindowChrome finds the window chrome of a window screenshot, such
+// as a macOS screenshot of a window or a Windows Alt+Print Screen capture,
+// so measurements can refer to the app's content rather than the image.
+//
+// Parameters:
+//   - img: The screenshot.
+//   - opts: The platform and scale, if known, and whether to return the
+//     content image.
+//
+// Returns:
+//   - *WindowChromeResult: The window, shadow, title bar, and content.
+//   - error: Non-nil if the options are invalid, or the image is smaller
+//     than 16×16 pixels or fully transparent.
+//
+// # Method
+//
+// The window is the bounding box of the opaque pixels; the transparent
+// margin around it, with the drop shadow macOS adds, is the shadow.
+//
+// The title bar is a band across the top of the window, mostly of one
+// color, ending at a row that differs from the next across most of the
+// width: a color change or a separator line. Its bottom edge is looked
+// for at the standard title bar heights of each platform and scale
+// first, then at any height up to 80 points. If none is found and the
+// platform is known, a title bar of its default height is assumed.
+//
+// The macOS traffic light buttons identify macOS screenshots, and their
+// size the display scale. Windows title bars are not identified, so the
+// platform of a Windows screenshot is empty unless given.
+func DetectWindowChrome(img image.Image, opts WindowChromeOptions) (*WindowChromeResult, error) {
+	if _, ok := chromeTitleHeights[opts.Platform]; !ok && opts.Platform != "" {
+		return nil, fmt.Errorf("invalid platform: %s (use %s or %s)", opts.Platform, ChromeMacOS, ChromeWindows)
+	}
+	if opts.Scale != 0 && (opts.Scale < 1 || opts.Scale > 4) {
+		return nil, fmt.Errorf("scale must be between 1 and 4, got %g", opts.Scale)
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w < 16 || h < 16 {
+		return nil, fmt.Errorf("image too small for window chrome detection: %dx%d", w, h)
+	}
+	px := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			px.SetNRGBA(x, y, nrgbaAt(img, b.Min.X+x, b.Min.Y+y))
+		}
+	}
+
+	window, ok := windowBounds(px)
+	if !ok {
+		return nil, fmt.Errorf("image is fully transparent")
+	}
+	result := &WindowChromeResult{
+		Platform: opts.Platform,
+		Scale:    opts.Scale,
+		Window:   window,
+		Shadow:   Insets{Top: window.Y1, Left: window.X1, Bottom: h - window.Y2, Right: w - window.X2},
+	}
+	if scale, found := findTrafficLights(px, window); found {
+		result.Platform = ChromeMacOS
+		if result.Scale == 0 {
+			result.Scale = scale
+		}
+	}
+
+	top := window.Y1
+	contentTop := top
+	if bottom, scale := standardTitleBar(px, window, result.Platform, result.Scale); bottom > top {
+		contentTop, result.TitleBarMethod = bottom, TitleBarStandard
+		if result.Scale == 0 {
+			result.Scale = scale
+		}
+	} else if bottom := anyTitleBar(px, window, result.Scale); bottom > top {
+		contentTop, result.TitleBarMethod = bottom, TitleBarDetected
+	} else if result.Platform != "" {
+		scale := result.Scale
+		if scale == 0 {
+			scale = 1
+		}
+		contentTop = min(top+int(math.Round(float64(chromeTitleHeights[result.Platform][0])*scale)), window.Y2)
+		result.TitleBarMethod = TitleBarAssumed
+	}
+	if contentTop > top {
+		result.TitleBar = &Region{X1: window.X1, Y1: top, X2: window.X2, Y2: contentTop}
+	}
+
+	result.Content = Region{X1: window.X1, Y1: contentTop, X2: window.X2, Y2: window.Y2}
+	result.Offset = Point{X: result.Content.X1, Y: result.Content.Y1}
+	result.Detected = result.Shadow != (Insets{}) || result.TitleBar != nil
+
+	if opts.IncludeImage {
+		content := px.SubImage(image.Rect(result.Content.X1, result.Content.Y1, result.Content.X2, result.Content.Y2))
+		encoded, err := encodePNGBase64(content)
+		if err != nil {
+			return nil, err
+		}
+		result.ImageBase64, result.MimeType = encoded, "image/png"
+	}
+	return result, nil
+}
+
+// windowBounds returns the bounding box of the pixels of img with at
+// least chromeOpaque alpha, leaving out a shadow, and false if there are
+// none.
+func windowBounds(img *image.NRGBA) (Region, bool) {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	r := Region{X1: w, Y1: h}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if img.Pix[img.PixOffset(x, y)+3] >= chromeOpaque {
+				r.X1, r.Y1 = min(r.X1, x), min(r.Y1, y)
+				r.X2, r.Y2 = max(r.X2, x+1), max(r.Y2, y+1)
+			}
+		}
+	}
+	return r, r.X2 > r.X1
+}
+
+// findTrafficLights looks for the macOS traffic light buttons at the top
+// left of the window, and returns the display scale from their width (12
+// points), and whether all three were found.
+func findTrafficLights(img *image.NRGBA, window Region) (float64, bool) {
+	area := Region{X1: window.X1, Y1: window.Y1, X2: min(window.X2, window.X1+240), Y2: min(window.Y2, window.Y1+160)}
+	widths := make([]int, len(trafficLights))
+	for i, light := range trafficLights {
+		x1, x2, count := area.X2, area.X1, 0
+		for y := area.Y1; y < area.Y2; y++ {
+			for x := area.X1; x < area.X2; x++ {
+				c := img.NRGBAAt(x, y)
+				if c.A >= chromeOpaque && absDiff(c.R, light.R)+absDiff(c.G, light.G)+absDiff(c.B, light.B) <= 60 {
+					x1, x2 = min(x1, x), max(x2, x+1)
+					count++
+				}
+			}
+		}
+		if count < 20 {
+			return 0, false
+		}
+		widths[i] = x2 - x1
+	}
+	return nearestScale(float64(widths[0]) / 12), true
+}
+
+// nearestScale returns the scale of chromeScales nearest to s.
+func nearestScale(s float64) float64 {
+	best := chromeScales[0]
+	for _, c := range chromeScales {
+		if math.Abs(c-s) < math.Abs(best-s) {
+			best = c
+		}
+	}
+	return best
+}
+
+// standardTitleBar looks for the bottom edge of a title bar at the
+// standard heights of platform (of both platforms if empty) at scale (at
+// each of chromeScales if 0). It returns the first row of the content,
+// and the scale of the height found; the window's top if none is found.
+// Of several heights, the one with the strongest edge wins.
+func standardTitleBar(img *image.NRGBA, window Region, platform string, scale float64) (int, float64) {
+	platforms := []string{ChromeMacOS, ChromeWindows}
+	if platform != "" {
+		platforms = []string{platform}
+	}
+	scales := chromeScales
+	if scale != 0 {
+		scales = []float64{scale}
+	}
+	bestRow, bestScale, bestEdge := window.Y1, 0.0, 0.0
+	for _, p := range platforms {
+		for _, height := range chromeTitleHeights[p] {
+			for _, s := range scales {
+				at := window.Y1 + int(math.Round(float64(height)*s))
+				// The edge may be a row off the nominal height, and a
+				// separator line makes two edges: the content starts
+				// after the last
+				row, edge := window.Y1, 0.0
+				for y := at - 2; y <= at+2; y++ {
+					if e := rowEdge(img, window, y); e >= chromeEdgeShare {
+						row, edge = y, max(edge, e)
+					}
+				}
+				if edge > bestEdge && uniformBar(img, window, row) {
+					bestRow, bestScale, bestEdge = row, s, edge
+				}
+			}
+		}
+	}
+	return bestRow, bestScale
+}
+
+// anyTitleBar looks for the bottom edge of a title bar at any height up
+// to chromeMaxBar points at scale (2 if 0), and at least 16 pixels, and
+// returns the first row of the content; the window's top if none is
+// found.
+func anyTitleBar(img *image.NRGBA, window Region, scale float64) int {
+	if scale == 0 {
+		scale = 2
+	}
+	limit := min(window.Y1+int(float64(chromeMaxBar)*scale), window.Y1+(window.Y2-window.Y1)/3)
+	for y := window.Y1 + 16; y < limit; y++ {
+		if rowEdge(img, window, y) < chromeEdgeShare || !uniformBar(img, window, y) {
+			continue
+		}
+		// Step over a separator line
+		for y+1 < limit && rowEdge(img, window, y+1) >= chromeEdgeShare {
+			y++
+		}
+		return y
+	}
+	return window.Y1
+}
+
+// rowEdge returns the share of the opaque pixels of row y of the window
+// whose luma differs by more than chromeEdgeDiff from the pixel above.
+func rowEdge(img *image.NRGBA, window Region, y int) float64 {
+	if y <= window.Y1 || y >= window.Y2 {
+		return 0
+	}
+	edges, total := 0, 0
+	for x := window.X1; x < window.X2; x++ {
+		above, c := img.NRGBAAt(x, y-1), img.NRGBAAt(x, y)
+		if above.A < chromeOpaque || c.A < chromeOpaque {
+			continue
+		}
+		total++
+		if absDiff(luma(above), luma(c)) > chromeEdgeDiff {
+			edges++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(edges) / float64(total)
+}
+
+// uniformBar reports whether the rows of the window above bottom are
+// mostly of one color, as a title bar's background: at least
+// chromeBarShare of their opaque pixels have a luma within chromeEdgeDiff
+// of the most common luma. A separator line at the bottom is left out.
+func uniformBar(img *image.NRGBA, window Region, bottom int) bool {
+	var counts [256]int
+	total := 0
+	for y := window.Y1; y < bottom; y++ {
+		for x := window.X1; x < window.X2; x++ {
+			if c := img.NRGBAAt(x, y); c.A >= chromeOpaque {
+				counts[luma(c)]++
+				total++
+			}
+		}
+	}
+	if total == 0 {
+		return false
+	}
+	mode := 0
+	for l := range counts {
+		if counts[l] > counts[mode] {
+			mode = l
+		}
+	}
+	near := 0
+	for l := max(0, mode-chromeEdgeDiff); l <= min(255, mode+chromeEdgeDiff); l++ {
+		near += counts[l]
+	}
+	// A separator line is one row of window width
+	return float64(near+(window.X2-window.X1)) >= chromeBarShare*float64(total)
+}
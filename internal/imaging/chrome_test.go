@@ -0,0 +1,162 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"testing"
+)
+
+// macWindowShot draws a macOS screenshot of a window at scale 2: a 20 px
+// shadow fading out around a 360×260 window with rounded top corners, a
+// 56 px title bar with traffic lights and a title, a separator line, and
+// white content with a toolbar of buttons.
+func macWindowShot() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 400, 300))
+	window := image.Rect(20, 14, 380, 274)
+	for y := 0; y < 300; y++ {
+		for x := 0; x < 400; x++ {
+			dx := max(window.Min.X-x, x-window.Max.X+1, 0)
+			dy := max(window.Min.Y-y, y-window.Max.Y+1, 0)
+			if d := max(dx, dy); d > 0 && d <= 20 {
+				img.SetNRGBA(x, y, color.NRGBA{A: uint8(120 - 6*d)})
+			}
+		}
+	}
+	fill := func(r image.Rectangle, c color.NRGBA) {
+		draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Src)
+	}
+	fill(image.Rect(20, 14, 380, 69), color.NRGBA{236, 236, 236, 255})
+	fill(image.Rect(20, 69, 380, 70), color.NRGBA{200, 200, 200, 255})
+	fill(image.Rect(20, 70, 380, 274), color.NRGBA{255, 255, 255, 255})
+	fill(image.Rect(160, 34, 240, 48), color.NRGBA{60, 60, 60, 255})
+	for i := 0; i < 3; i++ {
+		fill(image.Rect(60+40*i, 110, 90+40*i, 130), color.NRGBA{0, 122, 255, 255})
+	}
+	// Traffic lights: 12 pt across, 20 pt apart, 14 pt down
+	for i, light := range trafficLights {
+		cx, cy := 20+26+40*i, 14+28
+		for y := cy - 12; y <= cy+12; y++ {
+			for x := cx - 12; x <= cx+12; x++ {
+				if (x-cx)*(x-cx)+(y-cy)*(y-cy) <= 144 {
+					img.SetNRGBA(x, y, light)
+				}
+			}
+		}
+	}
+	// Rounded top corners
+	for y := 14; y < 18; y++ {
+		for x := 20; x < 20+18-y; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{A: 100})
+			img.SetNRGBA(399-x, y, color.NRGBA{A: 100})
+		}
+	}
+	return img
+}
+
+// windowsWindowShot draws a 300×200 Windows 11 window capture at scale 1:
+// a 32 px light gray title bar with caption buttons, over content of
+// barColor with a text field.
+func windowsWindowShot(barColor color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 300, 200))
+	fill := func(r image.Rectangle, c color.NRGBA) {
+		draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Src)
+	}
+	fill(img.Bounds(), color.NRGBA{255, 255, 255, 255})
+	fill(image.Rect(0, 0, 300, 32), barColor)
+	for i := 0; i < 3; i++ {
+		fill(image.Rect(170+46*i+18, 15, 170+46*i+28, 16), color.NRGBA{30, 30, 30, 255})
+	}
+	fill(image.Rect(12, 10, 70, 22), color.NRGBA{30, 30, 30, 255})
+	fill(image.Rect(20, 60, 200, 62), color.NRGBA{120, 120, 120, 255})
+	return img
+}
+
+func TestDetectWindowChrome_MacOS(t *testing.T) {
+	result, err := DetectWindowChrome(macWindowShot(), WindowChromeOptions{IncludeImage: true})
+	if err != nil {
+		t.Fatalf("DetectWindowChrome failed: %v", err)
+	}
+	if !result.Detected || result.Platform != ChromeMacOS || result.Scale != 2 {
+		t.Errorf("got detected %v, platform %q, scale %g; want true, macos, 2", result.Detected, result.Platform, result.Scale)
+	}
+	if result.Window != (Region{X1: 20, Y1: 14, X2: 380, Y2: 274}) || result.Shadow != (Insets{Top: 14, Left: 20, Bottom: 26, Right: 20}) {
+		t.Errorf("got window %+v, shadow %+v", result.Window, result.Shadow)
+	}
+	if result.TitleBarMethod != TitleBarStandard || result.TitleBar == nil || *result.TitleBar != (Region{X1: 20, Y1: 14, X2: 380, Y2: 70}) {
+		t.Errorf("got title bar %+v (%s), want 20,14-380,70 at the standard height", result.TitleBar, result.TitleBarMethod)
+	}
+	if result.Content != (Region{X1: 20, Y1: 70, X2: 380, Y2: 274}) || result.Offset != (Point{X: 20, Y: 70}) {
+		t.Errorf("got content %+v, offset %+v", result.Content, result.Offset)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(result.ImageBase64)
+	if err != nil {
+		t.Fatalf("invalid base64: %v", err)
+	}
+	content, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("invalid PNG: %v", err)
+	}
+	if content.Bounds().Dx() != 360 || content.Bounds().Dy() != 204 || result.MimeType != "image/png" {
+		t.Errorf("content image is %v (%s), want 360×204", content.Bounds(), result.MimeType)
+	}
+	// The first toolbar button is at 40,40 in content coordinates
+	if r, g, b, _ := content.At(content.Bounds().Min.X+45, content.Bounds().Min.Y+45).RGBA(); r>>8 != 0 || g>>8 != 122 || b>>8 != 255 {
+		t.Errorf("content pixel 45,45 is %d,%d,%d, want the blue button", r>>8, g>>8, b>>8)
+	}
+}
+
+func TestDetectWindowChrome_Windows(t *testing.T) {
+	for _, platform := range []string{"", ChromeWindows} {
+		result, err := DetectWindowChrome(windowsWindowShot(color.NRGBA{243, 243, 243, 255}), WindowChromeOptions{Platform: platform})
+		if err != nil {
+			t.Fatalf("DetectWindowChrome failed: %v", err)
+		}
+		if result.Platform != platform || result.Shadow != (Insets{}) {
+			t.Errorf("platform %q: got platform %q, shadow %+v", platform, result.Platform, result.Shadow)
+		}
+		if result.TitleBarMethod != TitleBarStandard || result.Offset != (Point{X: 0, Y: 32}) || result.Content != (Region{X1: 0, Y1: 32, X2: 300, Y2: 200}) {
+			t.Errorf("platform %q: got %s title bar, content %+v, offset %+v", platform, result.TitleBarMethod, result.Content, result.Offset)
+		}
+	}
+}
+
+func TestDetectWindowChrome_AssumedTitleBar(t *testing.T) {
+	// A title bar the color of the content has no edge
+	img := windowsWindowShot(color.NRGBA{255, 255, 255, 255})
+
+	result, err := DetectWindowChrome(img, WindowChromeOptions{Platform: ChromeWindows, Scale: 1.5})
+	if err != nil {
+		t.Fatalf("DetectWindowChrome failed: %v", err)
+	}
+	if result.TitleBarMethod != TitleBarAssumed || result.Offset.Y != 48 || !result.Detected {
+		t.Errorf("got %s title bar, offset %+v; want assumed, 48 px", result.TitleBarMethod, result.Offset)
+	}
+
+	result, err = DetectWindowChrome(img, WindowChromeOptions{})
+	if err != nil {
+		t.Fatalf("DetectWindowChrome failed: %v", err)
+	}
+	if result.Detected || result.TitleBar != nil || result.Content != (Region{X1: 0, Y1: 0, X2: 300, Y2: 200}) {
+		t.Errorf("unknown platform: got detected %v, title bar %+v, content %+v; want no chrome", result.Detected, result.TitleBar, result.Content)
+	}
+}
+
+func TestDetectWindowChrome_Errors(t *testing.T) {
+	img := windowsWindowShot(color.NRGBA{243, 243, 243, 255})
+	for _, opts := range []WindowChromeOptions{{Platform: "linux"}, {Scale: 0.5}, {Scale: 5}} {
+		if _, err := DetectWindowChrome(img, opts); err == nil {
+			t.Errorf("%+v: expected an error", opts)
+		}
+	}
+	if _, err := DetectWindowChrome(image.NewNRGBA(image.Rect(0, 0, 8, 8)), WindowChromeOptions{}); err == nil {
+		t.Error("expected an error for a tiny image")
+	}
+	if _, err := DetectWindowChrome(image.NewNRGBA(image.Rect(0, 0, 40, 40)), WindowChromeOptions{}); err == nil {
+		t.Error("expected an error for a transparent image")
+	}
+}
@@ -0,0 +1,322 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// Content classes reported by ClassifyContent.
+const (
+	ContentPhotograph = "photograph"
+	ContentDiagram    = "diagram"
+	ContentText       = "text"
+	ContentScreenshot = "screenshot"
+	ContentBlank      = "blank"
+)
+
+// Thresholds for the per-pixel statistics gathered by ClassifyContent.
+const (
+	// classifyShadingMax is the largest gray step (0-255) between adjacent
+	// pixels that still counts as smooth shading rather than an edge.
+	classifyShadingMax = 16
+
+	// classifyEdgeMin is the smallest gray step between adjacent pixels
+	// that counts as a strong edge.
+	classifyEdgeMin = 48
+
+	// classifyStrokeMax is the longest horizontal run of foreground (in
+	// pixels) that counts as a glyph stroke.
+	classifyStrokeMax = 8
+)
+
+// suggestedTools maps each content class to the tools that usually work
+// best on it.
+var suggestedTools = map[string][]string{
+	ContentPhotograph: {"image_dominant_colors", "image_sample_color", "image_crop_quadrant"},
+	ContentDiagram:    {"image_detect_rectangles", "image_detect_lines", "image_detect_circles", "image_detect_text_regions"},
+	ContentText:       {"image_ocr_full", "image_detect_rows"},
+	ContentScreenshot: {"image_detect_text_regions", "image_ocr_region", "image_detect_rectangles", "image_find_repeats"},
+	ContentBlank:      {},
+}
+
+// ContentFeatures are the statistics ClassifyContent bases its decision on.
+type ContentFeatures struct {
+	// UniqueColors is the number of distinct 8-bit RGB colors.
+	UniqueColors int `json:"unique_colors"`
+
+	// TopColorsCoverage is the fraction of pixels (0.0-1.0) covered by the
+	// eight most common colors. Flat graphics score near 1.
+	TopColorsCoverage float64 `json:"top_colors_coverage"`
+
+	// BackgroundFraction is the fraction of pixels within a small tolerance
+	// of the most common color.
+	BackgroundFraction float64 `json:"background_fraction"`
+
+	// FlatColorAreas is the number of colors, other than the background,
+	// that each cover at least 2% of the pixels (panels, fills, toolbars).
+	FlatColorAreas int `json:"flat_color_areas"`
+
+	// EdgeDensity is the fraction of adjacent pixel pairs with a strong
+	// brightness step.
+	EdgeDensity float64 `json:"edge_density"`
+
+	// ShadingFraction is the fraction of adjacent pixel pairs with a small,
+	// non-zero brightness step, as in gradients and photographic texture.
+	ShadingFraction float64 `json:"shading_fraction"`
+
+	// TextDensity is the fraction of pixel rows that look like a line of
+	// text: several short foreground runs that change from the row above.
+	TextDensity float64 `json:"text_density"`
+}
+
+// ContentClassification is the classification of an image or one part of it.
+type ContentClassification struct {
+	// Name identifies the part ("full", "top-left", "top-right",
+	// "bottom-left", or "bottom-right").
+	Name string `json:"name"`
+
+	// Region is the classified area in image coordinates.
+	Region Region `json:"region"`
+
+	// Class is one of "photograph", "diagram", "text", "screenshot", or
+	// "blank".
+	Class string `json:"class"`
+
+	// Reason briefly explains which features decided the class.
+	Reason string `json:"reason"`
+
+	// SuggestedTools lists tools that usually work well on this class.
+	SuggestedTools []string `json:"suggested_tools"`
+
+	// Features are the measured statistics.
+	Features ContentFeatures `json:"features"`
+}
+
+// ClassifyContentResult contains the classification of a whole image and,
+// optionally, of each quadrant.
+type ClassifyContentResult struct {
+	ContentClassification
+
+	// Quadrants holds the per-quadrant classifications, if requested.
+	Quadrants []ContentClassification `json:"quadrants,omitempty"`
+}
+
+// ClassifyContent decides whether an image is a photograph, a line-art
+// diagram, dense text, or a UI screenshot, so the caller can pick suitable
+// downstream tools.
+//
+// Parameters:
+//   - img: Source image.
+//   - quadrants: If true, also classify each quadrant separately (useful
+//     for mixed content such as a screenshot containing a photo).
+//
+// Returns:
+//   - *ClassifyContentResult: The class, reasoning, and features of the
+//     whole image, plus each quadrant if requested.
+//   - error: Non-nil if the image is too small to classify.
+//
+// # Decision Rules
+//
+// Rules are applied in order; the first match wins:
+//
+//  1. blank: at least 99.5% of pixels are background.
+//  2. photograph: shading fraction at least 0.2 and the top eight colors
+//     cover less than 60% of the image.
+//  3. text: text density at least 0.25, at most one flat color area, and
+//     at least half the image is background.
+//  4. screenshot: text density at least 0.05 and either two or more flat
+//     color areas or less than half the image is background.
+//  5. diagram: everything else (mostly background with lines and shapes).
+//
+// # Limitations
+//
+// The rules are heuristics tuned for typical content. Heavily compressed
+// screenshots can look like photographs, and diagrams with many labels or
+// colored fills can look like screenshots; check the features when the
+// result matters.
+func ClassifyContent(img image.Image, quadrants bool) (*ClassifyContentResult, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w < 2 || h < 2 {
+		return nil, fmt.Errorf("image too small to classify: %dx%d", w, h)
+	}
+
+	result := &ClassifyContentResult{
+		ContentClassification: classifyRegion(img, "full", Region{X1: 0, Y1: 0, X2: w, Y2: h}),
+	}
+	if quadrants {
+		if w < 4 || h < 4 {
+			return nil, fmt.Errorf("image too small to split into quadrants: %dx%d", w, h)
+		}
+		midX, midY := w/2, h/2
+		result.Quadrants = []ContentClassification{
+			classifyRegion(img, "top-left", Region{X1: 0, Y1: 0, X2: midX, Y2: midY}),
+			classifyRegion(img, "top-right", Region{X1: midX, Y1: 0, X2: w, Y2: midY}),
+			classifyRegion(img, "bottom-left", Region{X1: 0, Y1: midY, X2: midX, Y2: h}),
+			classifyRegion(img, "bottom-right", Region{X1: midX, Y1: midY, X2: w, Y2: h}),
+		}
+	}
+	return result, nil
+}
+
+// classifyRegion measures and classifies one region (at least 2x2).
+func classifyRegion(img image.Image, name string, r Region) ContentClassification {
+	f := measureContent(img, r)
+	c := ContentClassification{Name: name, Region: r, Features: f}
+
+	switch {
+	case f.BackgroundFraction >= 0.995:
+		c.Class = ContentBlank
+		c.Reason = "almost every pixel is background"
+	case f.ShadingFraction >= 0.2 && f.TopColorsCoverage < 0.6:
+		c.Class = ContentPhotograph
+		c.Reason = "smooth shading throughout and no small set of dominant colors"
+	case f.TextDensity >= 0.25 && f.FlatColorAreas <= 1 && f.BackgroundFraction >= 0.5:
+		c.Class = ContentText
+		c.Reason = "many rows of glyph-like strokes on a plain background"
+	case f.TextDensity >= 0.05 && (f.FlatColorAreas >= 2 || f.BackgroundFraction < 0.5):
+		c.Class = ContentScreenshot
+		c.Reason = "text among several flat-colored panels"
+	default:
+		c.Class = ContentDiagram
+		c.Reason = "flat colors, mostly background, with little text"
+	}
+	c.SuggestedTools = suggestedTools[c.Class]
+	return c
+}
+
+// measureContent computes the classification features of region r.
+//
+// The region is read twice: once for the color histogram, which gives the
+// background, and once row by row for the neighbor and text statistics.
+// Only two rows of derived data are kept, so memory does not grow with the
+// image height.
+func measureContent(img image.Image, r Region) ContentFeatures {
+	b := img.Bounds()
+	w, h := r.X2-r.X1, r.Y2-r.Y1
+	n := w * h
+	at := func(x, y int) color.RGBA {
+		cr, cg, cb, _ := img.At(b.Min.X+r.X1+x, b.Min.Y+r.Y1+y).RGBA()
+		return color.RGBA{uint8(cr >> 8), uint8(cg >> 8), uint8(cb >> 8), 255}
+	}
+
+	counts := make(map[color.RGBA]int)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			counts[at(x, y)]++
+		}
+	}
+
+	type colorCount struct {
+		c color.RGBA
+		n int
+	}
+	sorted := make([]colorCount, 0, len(counts))
+	for c, k := range counts {
+		sorted = append(sorted, colorCount{c, k})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].n != sorted[j].n {
+			return sorted[i].n > sorted[j].n
+		}
+		return rgbLess(sorted[i].c, sorted[j].c)
+	})
+	bg := sorted[0].c
+
+	f := ContentFeatures{UniqueColors: len(counts)}
+	top := 0
+	for i := 0; i < len(sorted) && i < 8; i++ {
+		top += sorted[i].n
+	}
+	f.TopColorsCoverage = float64(top) / float64(n)
+	for _, cc := range sorted[1:] {
+		if float64(cc.n) < 0.02*float64(n) {
+			break
+		}
+		if pixelDiff(cc.c, bg) > pixelDiffThreshold {
+			f.FlatColorAreas++
+		}
+	}
+
+	// Neighbor steps, background, and text rows, one row at a time
+	background, edges, shading, pairs, textRows := 0, 0, 0, 0, 0
+	step := func(a, b float64) {
+		d := math.Abs(a - b)
+		pairs++
+		if d >= classifyEdgeMin {
+			edges++
+		} else if d >= 1 && d <= classifyShadingMax {
+			shading++
+		}
+	}
+	gray, grayAbove := make([]float64, w), make([]float64, w)
+	fg, fgAbove := make([]bool, w), make([]bool, w)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := at(x, y)
+			gray[x] = 0.299*float64(c.R) + 0.587*float64(c.G) + 0.114*float64(c.B)
+			fg[x] = pixelDiff(c, bg) > pixelDiffThreshold
+			if !fg[x] {
+				background++
+			}
+			if x > 0 {
+				step(gray[x-1], gray[x])
+			}
+			if y > 0 {
+				step(grayAbove[x], gray[x])
+			}
+		}
+
+		// A text row has several short foreground runs that differ from
+		// the row above; straight vertical lines repeat unchanged and do
+		// not count
+		if y > 0 {
+			strokes, changed, run := 0, 0, 0
+			for x := 0; x <= w; x++ {
+				if x < w && fg[x] {
+					run++
+				} else {
+					if run > 0 && run <= classifyStrokeMax {
+						strokes++
+					}
+					run = 0
+				}
+				if x < w && fg[x] != fgAbove[x] {
+					changed++
+				}
+			}
+			if strokes >= 4 && changed >= strokes {
+				textRows++
+			}
+		}
+
+		gray, grayAbove = grayAbove, gray
+		fg, fgAbove = fgAbove, fg
+	}
+	f.BackgroundFraction = float64(background) / float64(n)
+	f.EdgeDensity = float64(edges) / float64(pairs)
+	f.ShadingFraction = float64(shading) / float64(pairs)
+	f.TextDensity = float64(textRows) / float64(h)
+
+	f.TopColorsCoverage = math.Round(f.TopColorsCoverage*1000) / 1000
+	f.BackgroundFraction = math.Round(f.BackgroundFraction*1000) / 1000
+	f.EdgeDensity = math.Round(f.EdgeDensity*1000) / 1000
+	f.ShadingFraction = math.Round(f.ShadingFraction*1000) / 1000
+	f.TextDensity = math.Round(f.TextDensity*1000) / 1000
+	return f
+}
+
+// rgbLess orders colors by R, then G, then B, to break count ties
+// deterministically.
+func rgbLess(a, b color.RGBA) bool {
+	if a.R != b.R {
+		return a.R < b.R
+	}
+	if a.G != b.G {
+		return a.G < b.G
+	}
+	return a.B < b.B
+}
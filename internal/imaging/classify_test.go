@@ -0,0 +1,127 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// fillRect fills x1..x2, y1..y2 (exclusive) with c.
+func fillRect(img *image.RGBA, x1, y1, x2, y2 int, c color.Color) {
+	for y := y1; y < y2; y++ {
+		for x := x1; x < x2; x++ {
+			img.Set(x, y, c)
+		}
+	}
+}
+
+// drawFakeText draws lines of glyph-like dot patterns in x1..x2, starting
+// at y1, with 10px lines every 16px until y2.
+func drawFakeText(img *image.RGBA, x1, y1, x2, y2 int) {
+	for line := y1; line+10 <= y2; line += 16 {
+		for y := line; y < line+10; y++ {
+			for x := x1; x < x2; x++ {
+				if (x*7+y*3)%5 == 0 && (x/6)%5 != 4 {
+					img.Set(x, y, color.Black)
+				}
+			}
+		}
+	}
+}
+
+func TestClassifyContent(t *testing.T) {
+	white := color.RGBA{255, 255, 255, 255}
+
+	photo := image.NewRGBA(image.Rect(0, 0, 120, 120))
+	for y := 0; y < 120; y++ {
+		for x := 0; x < 120; x++ {
+			n := uint8((x*31 + y*17) % 5)
+			photo.Set(x, y, color.RGBA{uint8(x*2) + n, uint8(y*2) + n, uint8(x+y) + n, 255})
+		}
+	}
+
+	text := image.NewRGBA(image.Rect(0, 0, 120, 120))
+	fillRect(text, 0, 0, 120, 120, white)
+	drawFakeText(text, 10, 10, 110, 110)
+
+	diagram := image.NewRGBA(image.Rect(0, 0, 120, 120))
+	fillRect(diagram, 0, 0, 120, 120, white)
+	for _, r := range [][4]int{{10, 10, 50, 40}, {70, 10, 110, 40}, {40, 70, 80, 110}} {
+		fillRect(diagram, r[0], r[1], r[2], r[1]+2, color.Black)
+		fillRect(diagram, r[0], r[3]-2, r[2], r[3], color.Black)
+		fillRect(diagram, r[0], r[1], r[0]+2, r[3], color.Black)
+		fillRect(diagram, r[2]-2, r[1], r[2], r[3], color.Black)
+	}
+	fillRect(diagram, 50, 24, 70, 26, color.Black)
+	fillRect(diagram, 59, 40, 61, 70, color.Black)
+
+	screenshot := image.NewRGBA(image.Rect(0, 0, 120, 120))
+	fillRect(screenshot, 0, 0, 120, 120, white)
+	fillRect(screenshot, 0, 0, 120, 20, color.RGBA{220, 220, 220, 255})
+	fillRect(screenshot, 0, 20, 30, 120, color.RGBA{40, 60, 120, 255})
+	drawFakeText(screenshot, 40, 30, 115, 110)
+
+	blank := image.NewRGBA(image.Rect(0, 0, 50, 50))
+	fillRect(blank, 0, 0, 50, 50, white)
+
+	tests := []struct {
+		name string
+		img  image.Image
+		want string
+	}{
+		{"photo", photo, ContentPhotograph},
+		{"text", text, ContentText},
+		{"diagram", diagram, ContentDiagram},
+		{"screenshot", screenshot, ContentScreenshot},
+		{"blank", blank, ContentBlank},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ClassifyContent(tt.img, false)
+			if err != nil {
+				t.Fatalf("ClassifyContent failed: %v", err)
+			}
+			if result.Class != tt.want {
+				t.Errorf("Class: got %s (%s), want %s; features %+v",
+					result.Class, result.Reason, tt.want, result.Features)
+			}
+			if result.Quadrants != nil {
+				t.Error("Quadrants should be omitted when not requested")
+			}
+		})
+	}
+}
+
+func TestClassifyContent_Quadrants(t *testing.T) {
+	// Text in the top-left quadrant only
+	img := image.NewRGBA(image.Rect(0, 0, 200, 200))
+	fillRect(img, 0, 0, 200, 200, color.White)
+	drawFakeText(img, 5, 5, 95, 95)
+
+	result, err := ClassifyContent(img, true)
+	if err != nil {
+		t.Fatalf("ClassifyContent failed: %v", err)
+	}
+	if len(result.Quadrants) != 4 {
+		t.Fatalf("Quadrants: got %d, want 4", len(result.Quadrants))
+	}
+	tl := result.Quadrants[0]
+	if tl.Name != "top-left" || tl.Region != (Region{X1: 0, Y1: 0, X2: 100, Y2: 100}) {
+		t.Errorf("first quadrant: got %s %+v", tl.Name, tl.Region)
+	}
+	if tl.Class != ContentText {
+		t.Errorf("top-left: got %s, want text", tl.Class)
+	}
+	if br := result.Quadrants[3]; br.Class != ContentBlank || len(br.SuggestedTools) != 0 {
+		t.Errorf("bottom-right: got %s with tools %v, want blank with none", br.Class, br.SuggestedTools)
+	}
+}
+
+func TestClassifyContent_TooSmall(t *testing.T) {
+	if _, err := ClassifyContent(image.NewRGBA(image.Rect(0, 0, 1, 5)), false); err == nil {
+		t.Error("ClassifyContent should fail on a 1px wide image")
+	}
+	if _, err := ClassifyContent(image.NewRGBA(image.Rect(0, 0, 3, 3)), true); err == nil {
+		t.Error("ClassifyContent should fail to split a 3x3 image into quadrants")
+	}
+}
@@ -0,0 +1,314 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+)
+
+// Clock reading constants.
+const (
+	// clockHandInner is the radius, as a fraction of the dial's radius,
+	// from which hands are traced: beyond the hub.
+	clockHandInner = 0.15
+
+	// minHandReach is the shortest a hand may reach, as a fraction of the
+	// dial's radius, so tails and hubs are not taken for hands.
+	minHandReach = 0.3
+
+	// minHandProminence is the least a hand must reach beyond where it
+	// parts from another, as a fraction of the dial's radius.
+	minHandProminence = 0.1
+)
+
+// ClockOptions configures ReadClock.
+type ClockOptions struct {
+	// Region is the area searched for the clock face, or nil for the whole
+	// image. It is ignored when Center is set.
+	Region *Region
+
+	// Center and Radius, if Center is set, give the face: the hands' pivot
+	// and the radius of the face. Otherwise the face is found as the
+	// largest circle around a center.
+	Center *Point
+	Radius int
+}
+
+// ClockHand is a hand of a clock.
+type ClockHand struct {
+	// Name is "hour", "minute", or "second".
+	Name string `json:"name"`
+
+	// Angle is the hand's direction in degrees clockwise from 12 o'clock
+	// (0-360), and Tip its end.
+	Angle float64 `json:"angle"`
+	Tip   Point   `json:"tip"`
+
+	// Length is how far the hand reaches, as a fraction of the face's
+	// radius, and Width its thickness in pixels, estimated from the angle
+	// its rays span.
+	Length float64 `json:"length"`
+	Width  float64 `json:"width"`
+}
+
+// ClockResult contains the time read from an analog clock by ReadClock.
+type ClockResult struct {
+	Dial GaugeDial `json:"dial"`
+
+	// Found is true if the hands were found.
+	Found bool `json:"found"`
+
+	// Time is the time shown, as "H:MM", or "H:MM:SS" with a second hand,
+	// on a 12-hour clock.
+	Time string `json:"time,omitempty"`
+
+	// Hour (1-12), Minute, and Second, if there is a second hand, are the
+	// parts of Time.
+	Hour   int  `json:"hour"`
+	Minute int  `json:"minute"`
+	Second *int `json:"second,omitempty"`
+
+	// Confidence (0-1) is how well the hour hand agrees with the minute
+	// hand: 1 if it is exactly as far past the hour as the minutes say.
+	Confidence float64 `json:"confidence"`
+
+	// Hands are the hands found: hour, minute, then second.
+	Hands []ClockHand `json:"hands,omitempty"`
+
+	// Warnings note a face or hands that could not be found, or hands that
+	// overlap.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// ReadClock reads the time from an analog clock: it finds the face and
+// the angles of its hands, tells the hands apart, and converts their
+// angles to a time.
+//
+// Parameters:
+//   - img: Source image.
+//   - opts: The face, if known, or the region to search for it.
+//
+// Returns:
+//   - *ClockResult: The face, the hands, and the time they show. Found is
+//     false if no hand was found; Warnings say why.
+//   - error: Non-nil if the region, center, or radius is invalid.
+//
+// # Method
+//
+// The face is found as by ReadGauge, and ink is what differs from the
+// face's color by 80 or more. Each ray, in steps of 0.5 degrees, is traced
+// outward from 15% of the radius while on ink; runs of rays reaching past
+// 30% of the radius are hands, pointing where their rays reach furthest.
+// A run parts into two hands where the rays going on from it are much
+// thinner, as where a thin hand crosses a thick one, but not within its
+// thickness, so a hand touching a tick stays whole. Of three hands, the
+// thinnest is the second hand; of two, one under a third as thick as the
+// other is. Of the rest, the longer is the minute hand, or the thinner of
+// two as long; a lone one is the hour and minute hands overlapping. The
+// minutes are the minute hand's angle over 6 degrees, and the hour the
+// hour hand's angle over 30 degrees less the minutes' share of an hour,
+// rounded, so a hand crossing a tick or another hand moves it little.
+func ReadClock(img image.Image, opts ClockOptions) (*ClockResult, error) {
+	result := &ClockResult{}
+	dial, ok, err := locateDial(img, opts.Region, opts.Center, opts.Radius)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		result.Warnings = append(result.Warnings, "no clock face found; pass its center and radius")
+		return result, nil
+	}
+	result.Dial = dial
+
+	hands := findHands(img, dial)
+	if len(hands) == 0 {
+		result.Warnings = append(result.Warnings, "no hands found")
+		return result, nil
+	}
+	if len(hands) > 3 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("ignored %d shorter marks", len(hands)-3))
+		hands = hands[:3]
+	}
+
+	var hour, minute ClockHand
+	var second *ClockHand
+	narrowest := 0
+	for i, hand := range hands {
+		if hand.Width < hands[narrowest].Width {
+			narrowest = i
+		}
+	}
+	rest := append(append([]ClockHand{}, hands[:narrowest]...), hands[narrowest+1:]...)
+	switch {
+	case len(hands) == 1:
+		hour, minute = hands[0], hands[0]
+	case len(hands) == 2 && 3*hands[narrowest].Width < rest[0].Width:
+		second = &hands[narrowest]
+		hour, minute = rest[0], rest[0]
+	case len(hands) == 2:
+		minute, hour = minuteHour(hands[0], hands[1])
+	default:
+		second = &hands[narrowest]
+		minute, hour = minuteHour(rest[0], rest[1])
+	}
+	if hour.Angle == minute.Angle {
+		result.Warnings = append(result.Warnings, "hour and minute hands overlap; the hour is read from the minute hand")
+	}
+	hour.Name, minute.Name = "hour", "minute"
+	result.Hands = []ClockHand{hour, minute}
+
+	m := int(math.Round(minute.Angle/6)) % 60
+	hf := hour.Angle/30 - float64(m)/60
+	h := (int(math.Round(hf))%12 + 12) % 12
+	if h == 0 {
+		h = 12
+	}
+	result.Found = true
+	result.Hour, result.Minute = h, m
+	result.Time = fmt.Sprintf("%d:%02d", h, m)
+	result.Confidence = math.Round((1-2*math.Abs(hf-math.Round(hf)))*100) / 100
+	if second != nil {
+		second.Name = "second"
+		result.Hands = append(result.Hands, *second)
+		s := int(math.Round(second.Angle/6)) % 60
+		result.Second = &s
+		result.Time += fmt.Sprintf(":%02d", s)
+	}
+	return result, nil
+}
+
+// minuteHour returns the minute and hour hands of a and b, a the longer:
+// the longer, or the thinner of two as long.
+func minuteHour(a, b ClockHand) (ClockHand, ClockHand) {
+	if a.Length-b.Length < 0.05 && b.Width < a.Width {
+		return b, a
+	}
+	return a, b
+}
+
+// findHands returns the hands of the clock on dial, unnamed, longest
+// first.
+func findHands(img image.Image, dial GaugeDial) []ClockHand {
+	ink, ok := dialInk(img, dial, nil)
+	if !ok {
+		return nil
+	}
+	radius := float64(dial.Radius)
+	const steps = 720
+	reach := make([]float64, steps)
+	first := -1
+	for k := range reach {
+		reach[k] = inkReach(ink, float64(k)*360/steps, clockHandInner*radius, radius)
+		if reach[k] < minHandReach*radius && first < 0 {
+			first = k
+		}
+	}
+	if first < 0 {
+		return nil
+	}
+	// Start after a short ray so no run wraps
+	reach = append(reach[first:], reach[:first]...)
+
+	var spans []handSpan
+	for _, run := range reachRuns(reach, 0, steps, minHandReach*radius) {
+		splitHands(reach, run, minHandReach*radius, minHandProminence*radius, &spans)
+	}
+	// Each hand points where its rays reach its end, where overlapping
+	// hands part too
+	hands := make([]ClockHand, len(spans))
+	for i, sp := range spans {
+		var sum, n float64
+		for k := 0; k < sp.n; k++ {
+			if math.Min(reach[sp.from+k], sp.end) >= sp.end-math.Max(2, radius/40) {
+				sum += float64(k)
+				n++
+			}
+		}
+		angle := math.Mod(float64(first+sp.from)*360/steps+sum/n*360/steps, 360)
+		tip := inkReach(ink, angle, clockHandInner*radius, radius)
+		hands[i] = ClockHand{
+			Angle:  math.Round(angle*10) / 10,
+			Tip:    dialPoint(dial, angle, tip),
+			Length: math.Round(tip/radius*100) / 100,
+			Width:  math.Round(sp.level*float64(sp.n)*2*math.Pi/steps*10) / 10,
+		}
+	}
+	sort.SliceStable(hands, func(i, j int) bool { return hands[i].Length > hands[j].Length })
+	return hands
+}
+
+// handSpan is a run of n rays from index from that belong to one hand,
+// taken at the reach level where the hand parts from the rest, and the
+// reach end at which it ends.
+type handSpan struct {
+	from, n    int
+	level, end float64
+}
+
+// reachRuns returns the runs, as [from, n], of the n rays from index from
+// that reach level or beyond.
+func reachRuns(reach []float64, from, n int, level float64) [][2]int {
+	var runs [][2]int
+	for k := from; k < from+n; k++ {
+		if reach[k] < level {
+			continue
+		}
+		if len(runs) > 0 && runs[len(runs)-1][0]+runs[len(runs)-1][1] == k {
+			runs[len(runs)-1][1]++
+		} else {
+			runs = append(runs, [2]int{k, 1})
+		}
+	}
+	return runs
+}
+
+// splitHands adds to spans the hands within run of rays reaching level,
+// raising the level a pixel at a time until the run parts into runs rising
+// prominence or more above it. Two such runs are hands of their own. So is
+// one if the run thins by half at once, as where a thin hand crossing a
+// thick one goes on past its end, the thickness, the angle spanned times
+// the level, otherwise holding along a hand; but not if it goes on within
+// the run's thickness, as a tick the hand points at does.
+func splitHands(reach []float64, run [2]int, level, prominence float64, spans *[]handSpan) {
+	base := level * float64(run[1])
+	for l := level + 1; ; l++ {
+		subs := reachRuns(reach, run[0], run[1], l)
+		if len(subs) == 0 {
+			*spans = append(*spans, handSpan{from: run[0], n: run[1], level: level, end: l - 1})
+			return
+		}
+		var parts [][2]int
+		for _, sub := range subs {
+			top := 0.0
+			for _, r := range reach[sub[0] : sub[0]+sub[1]] {
+				top = math.Max(top, r)
+			}
+			if top-l >= prominence {
+				parts = append(parts, sub)
+			}
+		}
+		if len(parts) == 1 {
+			offset := float64(2*parts[0][0]+parts[0][1]-2*run[0]-run[1]) / 2
+			if 2*l*float64(parts[0][1]) >= base || 2*l*math.Abs(offset) <= base {
+				continue
+			}
+			*spans = append(*spans, handSpan{from: run[0], n: run[1], level: level, end: l - 1})
+		}
+		if len(parts) >= 2 {
+			total := 0
+			for _, sub := range subs {
+				total += sub[1]
+			}
+			if 2*l*float64(total) < base {
+				*spans = append(*spans, handSpan{from: run[0], n: run[1], level: level, end: l - 1})
+			}
+		}
+		for _, part := range parts {
+			splitHands(reach, part, l, prominence, spans)
+		}
+		if len(parts) > 0 {
+			return
+		}
+	}
+}
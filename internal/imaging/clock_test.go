@@ -0,0 +1,130 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// clockHand is a hand drawn by clockFace: its angle clockwise from 12
+// o'clock, length, half-width, and color.
+type clockHand struct {
+	angle, length float64
+	half          int
+	c             color.RGBA
+}
+
+// clockFace draws a clock on gray: a white face of radius 80 at (100, 100)
+// in a black rim, with hour ticks, and hands.
+func clockFace(hands ...clockHand) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 200, 200))
+	fillRect(img, 0, 0, 200, 200, color.RGBA{150, 150, 150, 255})
+	paintRing(img, 100.5, 100.5, 0, 84, 360, color.RGBA{0, 0, 0, 255})
+	paintRing(img, 100.5, 100.5, 0, 80, 360, color.RGBA{255, 255, 255, 255})
+	ray := func(a, to float64, half int, c color.RGBA, from float64) {
+		rad := a * math.Pi / 180
+		for r := from; r <= to; r += 0.5 {
+			x, y := int(100.5+r*math.Sin(rad)), int(100.5-r*math.Cos(rad))
+			fillRect(img, x-half, y-half, x+half+1, y+half+1, c)
+		}
+	}
+	for i := 0; i < 12; i++ {
+		ray(float64(i)*30, 77, 1, color.RGBA{0, 0, 0, 255}, 68)
+	}
+	for _, h := range hands {
+		ray(h.angle, h.length, h.half, h.c, 0)
+	}
+	paintRing(img, 100.5, 100.5, 0, 5, 360, color.RGBA{0, 0, 0, 255})
+	return img
+}
+
+func TestReadClock(t *testing.T) {
+	black := color.RGBA{20, 20, 20, 255}
+	// 3:50:20, the thin red second hand crossing the thick hour hand
+	img := clockFace(
+		clockHand{115, 42, 3, black},
+		clockHand{300, 60, 2, black},
+		clockHand{122, 62, 0, color.RGBA{220, 30, 30, 255}},
+	)
+	result, err := ReadClock(img, ClockOptions{})
+	if err != nil {
+		t.Fatalf("ReadClock failed: %v", err)
+	}
+	if d := result.Dial; !d.Detected || math.Abs(float64(d.Center.X-100)) > 2 || math.Abs(float64(d.Center.Y-100)) > 2 {
+		t.Errorf("dial = %+v", d)
+	}
+	if !result.Found || result.Time != "3:50:20" || result.Hour != 3 || result.Minute != 50 || *result.Second != 20 {
+		t.Fatalf("read %q: %+v %v", result.Time, result.Hands, result.Warnings)
+	}
+	if result.Confidence < 0.9 || len(result.Warnings) != 0 {
+		t.Errorf("confidence %g, warnings %v", result.Confidence, result.Warnings)
+	}
+	for i, want := range []struct {
+		name   string
+		angle  float64
+		length float64
+	}{{"hour", 115, 0.55}, {"minute", 300, 0.78}, {"second", 122, 0.79}} {
+		h := result.Hands[i]
+		if h.Name != want.name || math.Abs(h.Angle-want.angle) > 1 || math.Abs(h.Length-want.length) > 0.06 {
+			t.Errorf("hand %d = %+v, want %s at %g", i, h, want.name, want.angle)
+		}
+	}
+	if h, m := result.Hands[0], result.Hands[1]; h.Width <= m.Width || m.Width <= result.Hands[2].Width {
+		t.Errorf("widths %g, %g, %g", h.Width, m.Width, result.Hands[2].Width)
+	}
+}
+
+func TestReadClock_Overlapping(t *testing.T) {
+	black := color.RGBA{20, 20, 20, 255}
+	img := clockFace(clockHand{0, 42, 3, black}, clockHand{0, 64, 2, black})
+	result, err := ReadClock(img, ClockOptions{})
+	if err != nil {
+		t.Fatalf("ReadClock failed: %v", err)
+	}
+	if result.Time != "12:00" || result.Second != nil || len(result.Hands) != 2 || len(result.Warnings) != 1 {
+		t.Errorf("read %q: %+v %v", result.Time, result.Hands, result.Warnings)
+	}
+}
+
+func TestReadClock_GivenFace(t *testing.T) {
+	// A face without a rim, given: 9:15, the hour hand a quarter past 9
+	img := image.NewRGBA(image.Rect(0, 0, 120, 120))
+	fillRect(img, 0, 0, 120, 120, color.RGBA{240, 240, 230, 255})
+	fillRect(img, 60, 57, 105, 63, color.RGBA{0, 0, 80, 255})
+	rad := 277.5 * math.Pi / 180
+	for r := 0.0; r <= 30; r += 0.5 {
+		x, y := int(60.5+r*math.Sin(rad)), int(60.5-r*math.Cos(rad))
+		fillRect(img, x-3, y-3, x+4, y+4, color.RGBA{0, 0, 80, 255})
+	}
+	result, err := ReadClock(img, ClockOptions{Center: &Point{X: 60, Y: 60}, Radius: 50})
+	if err != nil {
+		t.Fatalf("ReadClock failed: %v", err)
+	}
+	if result.Dial.Detected || result.Time != "9:15" {
+		t.Errorf("read %q: %+v %v", result.Time, result.Hands, result.Warnings)
+	}
+}
+
+func TestReadClock_NoHands(t *testing.T) {
+	result, err := ReadClock(clockFace(), ClockOptions{})
+	if err != nil {
+		t.Fatalf("ReadClock failed: %v", err)
+	}
+	if !result.Dial.Detected || result.Found || result.Time != "" || len(result.Warnings) != 1 {
+		t.Errorf("handless clock = %+v", result)
+	}
+}
+
+func TestReadClock_Invalid(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 40))
+	for _, opts := range []ClockOptions{
+		{Center: &Point{X: 20, Y: 20}, Radius: 4},
+		{Center: &Point{X: 50, Y: 20}, Radius: 10},
+		{Region: &Region{X1: 0, Y1: 0, X2: 50, Y2: 40}},
+	} {
+		if _, err := ReadClock(img, opts); err == nil {
+			t.Errorf("%+v: expected error", opts)
+		}
+	}
+}
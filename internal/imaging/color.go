@@ -29,6 +29,21 @@ type RGBAColor struct {
 	A uint8 `json:"a"` // Alpha/opacity component (0-255)
 }
 
+// RGB16Color represents an RGB color with 16-bit components (0-65535).
+type RGB16Color struct {
+	R uint16 `json:"r"` // Red component (0-65535)
+	G uint16 `json:"g"` // Green component (0-65535)
+	B uint16 `json:"b"` // Blue component (0-65535)
+}
+
+// RGBA16Color represents an RGBA color with 16-bit components (0-65535).
+type RGBA16Color struct {
+	R uint16 `json:"r"` // Red component (0-65535)
+	G uint16 `json:"g"` // Green component (0-65535)
+	B uint16 `json:"b"` // Blue component (0-65535)
+	A uint16 `json:"a"` // Alpha/opacity component (0-65535)
+}
+
 // HSLColor represents a color in HSL (Hue, Saturation, Lightness) color space.
 //
 // HSL is often more intuitive for color manipulation than RGB:
@@ -48,11 +63,29 @@ type HSLColor struct {
 //   - RGB: Standard 8-bit components without alpha
 //   - RGBA: 8-bit components with alpha for transparency
 //   - HSL: Perceptual color space for intuitive color operations
+//
+// Sampling at bit depth 16 also fills Hex16 and RGBA16 with the full
+// precision of 16-bit images.
 type ColorResult struct {
 	Hex  string    `json:"hex"`  // Hex format "#RRGGBB" (no alpha)
 	RGB  RGBColor  `json:"rgb"`  // RGB components
 	RGBA RGBAColor `json:"rgba"` // RGBA components with alpha
 	HSL  HSLColor  `json:"hsl"`  // HSL representation
+
+	// Hex16 is the color as "#RRRRGGGGBBBB" (bit depth 16 only).
+	Hex16 string `json:"hex16,omitempty"`
+
+	// RGBA16 holds the 16-bit components (bit depth 16 only). For 8-bit
+	// images each is the 8-bit value times 257.
+	RGBA16 *RGBA16Color `json:"rgba16,omitempty"`
+}
+
+// checkBitDepth returns an error unless bitDepth is 8 or 16.
+func checkBitDepth(bitDepth int) error {
+	if bitDepth != 8 && bitDepth != 16 {
+		return fmt.Errorf("invalid bit depth %d: must be 8 or 16", bitDepth)
+	}
+	return nil
 }
 
 // SampleColor extracts the color value at a specific pixel coordinate.
@@ -78,6 +111,16 @@ type ColorResult struct {
 // components. For 16-bit images, values are scaled down by right-shifting 8 bits.
 // The Hex format excludes alpha; use RGBA.A to get transparency information.
 func SampleColor(img image.Image, x, y int) (*ColorResult, error) {
+	return SampleColorDepth(img, x, y, 8)
+}
+
+// SampleColorDepth is SampleColor at a bit depth of 8 or 16. At 16, the
+// result also carries the 16-bit components, which keep the precision of
+// 16-bit PNG and TIFF images.
+func SampleColorDepth(img image.Image, x, y, bitDepth int) (*ColorResult, error) {
+	if err := checkBitDepth(bitDepth); err != nil {
+		return nil, err
+	}
 	bounds := img.Bounds()
 	if x < bounds.Min.X || x >= bounds.Max.X || y < bounds.Min.Y || y >= bounds.Max.Y {
 		return nil, fmt.Errorf("coordinates (%d,%d) outside image bounds", x, y)
@@ -87,12 +130,17 @@ func SampleColor(img image.Image, x, y int) (*ColorResult, error) {
 	// Convert from 16-bit to 8-bit
 	r8, g8, b8, a8 := uint8(r>>8), uint8(g>>8), uint8(b>>8), uint8(a>>8)
 
-	return &ColorResult{
+	result := &ColorResult{
 		Hex:  fmt.Sprintf("#%02X%02X%02X", r8, g8, b8),
 		RGB:  RGBColor{R: r8, G: g8, B: b8},
 		RGBA: RGBAColor{R: r8, G: g8, B: b8, A: a8},
 		HSL:  rgbToHSL(r8, g8, b8),
-	}, nil
+	}
+	if bitDepth == 16 {
+		result.Hex16 = fmt.Sprintf("#%04X%04X%04X", r, g, b)
+		result.RGBA16 = &RGBA16Color{R: uint16(r), G: uint16(g), B: uint16(b), A: uint16(a)}
+	}
+	return result, nil
 }
 
 // LabeledPoint represents a pixel coordinate with an optional descriptive label.
@@ -143,10 +191,19 @@ type MultiColorResult struct {
 //	}
 //	result, err := imaging.SampleColorsMulti(img, points)
 func SampleColorsMulti(img image.Image, points []LabeledPoint) (*MultiColorResult, error) {
+	return SampleColorsMultiDepth(img, points, 8)
+}
+
+// SampleColorsMultiDepth is SampleColorsMulti at a bit depth of 8 or 16
+// (see SampleColorDepth).
+func SampleColorsMultiDepth(img image.Image, points []LabeledPoint, bitDepth int) (*MultiColorResult, error) {
+	if err := checkBitDepth(bitDepth); err != nil {
+		return nil, err
+	}
 	results := make([]LabeledColorResult, 0, len(points))
 
 	for _, p := range points {
-		color, err := SampleColor(img, p.X, p.Y)
+		color, err := SampleColorDepth(img, p.X, p.Y, bitDepth)
 		if err != nil {
 			return nil, fmt.Errorf("failed to sample point (%d,%d): %w", p.X, p.Y, err)
 		}
@@ -168,10 +225,10 @@ func SampleColorsMulti(img image.Image, points []LabeledPoint) (*MultiColorResul
 //   - (X2, Y2) is the bottom-right corner (exclusive)
 //   - Width = X2 - X1, Height = Y2 - Y1
 type Region struct {
-	X1 int // Left edge X coordinate (inclusive)
-	Y1 int // Top edge Y coordinate (inclusive)
-	X2 int // Right edge X coordinate (exclusive)
-	Y2 int // Bottom edge Y coordinate (exclusive)
+	X1 int `json:"x1"` // Left edge X coordinate (inclusive)
+	Y1 int `json:"y1"` // Top edge Y coordinate (inclusive)
+	X2 int `json:"x2"` // Right edge X coordinate (exclusive)
+	Y2 int `json:"y2"` // Bottom edge Y coordinate (exclusive)
 }
 
 // ColorFrequency represents a color and its occurrence frequency in an image.
@@ -179,6 +236,10 @@ type ColorFrequency struct {
 	Hex        string   `json:"hex"`        // Hex color "#RRGGBB" (quantized)
 	Percentage float64  `json:"percentage"` // Percentage of pixels with this color (0-100)
 	RGB        RGBColor `json:"rgb"`        // RGB components (quantized)
+
+	// RGB16 is the mean 16-bit color of the pixels grouped under Hex, not
+	// quantized (bit depth 16 only).
+	RGB16 *RGB16Color `json:"rgb16,omitempty"`
 }
 
 // DominantColorsResult contains the most frequently occurring colors in an image.
@@ -186,6 +247,10 @@ type ColorFrequency struct {
 // Colors are sorted by frequency in descending order (most common first).
 type DominantColorsResult struct {
 	Colors []ColorFrequency `json:"colors"` // Colors sorted by frequency (descending)
+
+	// Alpha describes the transparency of the analyzed pixels. Nil if
+	// they are all opaque.
+	Alpha *AlphaSummary `json:"alpha,omitempty"`
 }
 
 // DominantColors extracts the N most common colors from an image or region.
@@ -219,39 +284,100 @@ type DominantColorsResult struct {
 // The function iterates over every pixel in the region, so large images may
 // take longer to process. Consider using a smaller region for quick analysis.
 func DominantColors(img image.Image, count int, region *Region) (*DominantColorsResult, error) {
+	return DominantColorsMasked(img, count, region, nil)
+}
+
+// DominantColorsMasked is DominantColors restricted to the pixels selected
+// by mask (see Mask.Rasterize), in image coordinates. If region is also
+// given, only selected pixels inside it count. A nil mask selects every
+// pixel.
+//
+// Returns an error if the mask selects no pixels in the analyzed area.
+func DominantColorsMasked(img image.Image, count int, region *Region, mask *image.Alpha) (*DominantColorsResult, error) {
+	return DominantColorsDepth(img, count, region, mask, 8)
+}
+
+// colorSums accumulates the pixels grouped under one quantized color.
+type colorSums struct {
+	count   int
+	r, g, b uint64 // 16-bit component sums
+}
+
+// DominantColorsDepth is DominantColorsMasked at a bit depth of 8 or 16.
+// Colors are grouped the same way at both depths; at 16, each also
+// carries the mean 16-bit color of its pixels.
+func DominantColorsDepth(img image.Image, count int, region *Region, mask *image.Alpha, bitDepth int) (*DominantColorsResult, error) {
+	return DominantColorsAlpha(img, count, region, mask, bitDepth, AlphaOptions{})
+}
+
+// DominantColorsAlpha is DominantColorsDepth with transparency handled as
+// alpha selects (see AlphaOptions). Alpha describes the transparency of
+// the analyzed pixels. In AlphaContent mode, percentages are of the pixels
+// that are not fully transparent, and Colors is empty if there are none.
+func DominantColorsAlpha(img image.Image, count int, region *Region, mask *image.Alpha, bitDepth int, alpha AlphaOptions) (*DominantColorsResult, error) {
+	if err := checkBitDepth(bitDepth); err != nil {
+		return nil, err
+	}
+	read, err := alpha.reader()
+	if err != nil {
+		return nil, err
+	}
 	bounds := img.Bounds()
 	if region != nil {
 		bounds = image.Rect(region.X1, region.Y1, region.X2, region.Y2)
 	}
 
-	colorCounts := make(map[string]int)
+	colorCounts := make(map[string]*colorSums)
+	selected := 0
 	totalPixels := 0
 
 	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
 		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			r, g, b, _ := img.At(x, y).RGBA()
+			if !maskSelects(mask, x, y) {
+				continue
+			}
+			selected++
+			r, g, b, content := read.at(img, x, y)
+			if !content {
+				continue
+			}
 			// Quantize to reduce color space (group similar colors)
 			r8 := uint8((r >> 8) / 16 * 16)
 			g8 := uint8((g >> 8) / 16 * 16)
 			b8 := uint8((b >> 8) / 16 * 16)
 			key := fmt.Sprintf("#%02X%02X%02X", r8, g8, b8)
-			colorCounts[key]++
+			sums := colorCounts[key]
+			if sums == nil {
+				sums = &colorSums{}
+				colorCounts[key] = sums
+			}
+			sums.count++
+			sums.r, sums.g, sums.b = sums.r+uint64(r), sums.g+uint64(g), sums.b+uint64(b)
 			totalPixels++
 		}
 	}
 
+	if mask != nil && selected == 0 {
+		return nil, fmt.Errorf("mask selects no pixels in %v", bounds)
+	}
+
 	// Convert to slice and sort by frequency
 	colors := make([]ColorFrequency, 0, len(colorCounts))
-	for hex, cnt := range colorCounts {
+	for hex, sums := range colorCounts {
 		// Parse hex back to RGB
 		var r, g, b uint8
 		_, _ = fmt.Sscanf(hex, "#%02X%02X%02X", &r, &g, &b)
 
-		colors = append(colors, ColorFrequency{
+		c := ColorFrequency{
 			Hex:        hex,
-			Percentage: float64(cnt) / float64(totalPixels) * 100,
+			Percentage: float64(sums.count) / float64(totalPixels) * 100,
 			RGB:        RGBColor{R: r, G: g, B: b},
-		})
+		}
+		if bitDepth == 16 {
+			n := uint64(sums.count)
+			c.RGB16 = &RGB16Color{R: uint16((sums.r + n/2) / n), G: uint16((sums.g + n/2) / n), B: uint16((sums.b + n/2) / n)}
+		}
+		colors = append(colors, c)
 	}
 
 	sort.Slice(colors, func(i, j int) bool {
@@ -262,7 +388,7 @@ func DominantColors(img image.Image, count int, region *Region) (*DominantColors
 		colors = colors[:count]
 	}
 
-	return &DominantColorsResult{Colors: colors}, nil
+	return &DominantColorsResult{Colors: colors, Alpha: read.summary()}, nil
 }
 
 // rgbToHSL converts 8-bit RGB values to HSL color space.
@@ -3,6 +3,7 @@ package imaging
 import (
 	"image"
 	"image/color"
+	"reflect"
 	"testing"
 )
 
@@ -273,6 +274,122 @@ func TestDominantColors_SingleColor(t *testing.T) {
 	}
 }
 
+func TestSampleColorDepth(t *testing.T) {
+	img := image.NewNRGBA64(image.Rect(0, 0, 4, 4))
+	img.SetNRGBA64(1, 2, color.NRGBA64{0x1234, 0xABCD, 0x00FF, 0xFFFF})
+
+	result, err := SampleColorDepth(img, 1, 2, 16)
+	if err != nil {
+		t.Fatalf("SampleColorDepth failed: %v", err)
+	}
+	if result.Hex != "#12AB00" || result.Hex16 != "#1234ABCD00FF" {
+		t.Errorf("hex: got %s, %s", result.Hex, result.Hex16)
+	}
+	if got := result.RGBA16; got == nil || *got != (RGBA16Color{R: 0x1234, G: 0xABCD, B: 0x00FF, A: 0xFFFF}) {
+		t.Errorf("rgba16: got %+v", got)
+	}
+
+	// 8-bit reporting leaves the 16-bit fields out
+	result, err = SampleColorDepth(img, 1, 2, 8)
+	if err != nil {
+		t.Fatalf("SampleColorDepth failed: %v", err)
+	}
+	if result.Hex16 != "" || result.RGBA16 != nil {
+		t.Errorf("depth 8: got %s, %+v", result.Hex16, result.RGBA16)
+	}
+
+	if _, err := SampleColorDepth(img, 1, 2, 12); err == nil {
+		t.Error("expected error for bit depth 12")
+	}
+	multi, err := SampleColorsMultiDepth(img, []LabeledPoint{{X: 1, Y: 2, Label: "a"}}, 16)
+	if err != nil || multi.Samples[0].Color.RGBA16 == nil {
+		t.Errorf("SampleColorsMultiDepth: got %+v, %v", multi, err)
+	}
+}
+
+func TestDominantColorsDepth(t *testing.T) {
+	// Two shades in the same quantization group
+	img := image.NewNRGBA64(image.Rect(0, 0, 10, 10))
+	for i := 0; i < 100; i++ {
+		c := color.NRGBA64{0x8000, 0x4000, 0x2000, 0xFFFF}
+		if i%2 == 1 {
+			c = color.NRGBA64{0x8100, 0x4100, 0x2100, 0xFFFF}
+		}
+		img.SetNRGBA64(i%10, i/10, c)
+	}
+	result, err := DominantColorsDepth(img, 5, nil, nil, 16)
+	if err != nil {
+		t.Fatalf("DominantColorsDepth failed: %v", err)
+	}
+	if len(result.Colors) != 1 || result.Colors[0].Hex != "#804020" {
+		t.Fatalf("got %+v", result.Colors)
+	}
+	if got := result.Colors[0].RGB16; got == nil || *got != (RGB16Color{R: 0x8080, G: 0x4080, B: 0x2080}) {
+		t.Errorf("rgb16: got %+v", got)
+	}
+	if _, err := DominantColorsDepth(img, 5, nil, nil, 32); err == nil {
+		t.Error("expected error for bit depth 32")
+	}
+}
+
+func TestDominantColorsAlpha(t *testing.T) {
+	// Half transparent, a quarter red, a quarter half-transparent blue
+	img := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	for y := 5; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			c := color.NRGBA{255, 0, 0, 255}
+			if x >= 5 {
+				c = color.NRGBA{0, 0, 255, 128}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	tests := []struct {
+		alpha AlphaOptions
+		want  map[string]float64
+	}{
+		{AlphaOptions{}, map[string]float64{"#000000": 50, "#F00000": 25, "#000080": 25}},
+		{AlphaOptions{Mode: AlphaContent}, map[string]float64{"#F00000": 50, "#0000F0": 50}},
+		{AlphaOptions{Mode: AlphaComposite}, map[string]float64{"#F0F0F0": 50, "#F00000": 25, "#7070F0": 25}},
+		{AlphaOptions{Mode: AlphaComposite, Background: "#00FF00"}, map[string]float64{"#00F000": 50, "#F00000": 25, "#007080": 25}},
+	}
+	for _, tt := range tests {
+		result, err := DominantColorsAlpha(img, 5, nil, nil, 8, tt.alpha)
+		if err != nil {
+			t.Fatalf("%+v: DominantColorsAlpha failed: %v", tt.alpha, err)
+		}
+		got := map[string]float64{}
+		for _, c := range result.Colors {
+			got[c.Hex] = c.Percentage
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%+v: got %v, want %v", tt.alpha, got, tt.want)
+		}
+		want := AlphaSummary{TransparentPixels: 50, TranslucentPixels: 25, OpaquePixels: 25, MeanAlpha: 95.75}
+		if result.Alpha == nil || *result.Alpha != want {
+			t.Errorf("%+v: alpha: got %+v", tt.alpha, result.Alpha)
+		}
+	}
+
+	// Nothing but transparency is no content
+	result, err := DominantColorsAlpha(img, 5, &Region{X1: 0, Y1: 0, X2: 10, Y2: 5}, nil, 8, AlphaOptions{Mode: AlphaContent})
+	if err != nil || len(result.Colors) != 0 || result.Alpha.TransparentPixels != 50 {
+		t.Errorf("transparent region: got %+v, %v", result, err)
+	}
+	// Opaque pixels report no alpha summary
+	result, _ = DominantColorsAlpha(img, 5, &Region{X1: 0, Y1: 5, X2: 5, Y2: 10}, nil, 8, AlphaOptions{})
+	if result.Alpha != nil {
+		t.Errorf("opaque region: got alpha %+v", result.Alpha)
+	}
+
+	if _, err := DominantColorsAlpha(img, 5, nil, nil, 8, AlphaOptions{Mode: "blend"}); err == nil {
+		t.Error("expected error for an unknown alpha mode")
+	}
+	if _, err := DominantColorsAlpha(img, 5, nil, nil, 8, AlphaOptions{Mode: AlphaComposite, Background: "#12"}); err == nil {
+		t.Error("expected error for an invalid background")
+	}
+}
+
 func TestRgbToHSL(t *testing.T) {
 	tests := []struct {
 		name     string
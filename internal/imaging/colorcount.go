@@ -0,0 +1,233 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"sort"
+)
+
+// Limits for CountColors.
+const (
+	maxCountedColors       = 4096 // Colors listed
+	maxNearDuplicateSearch = 1024 // Distinct colors above which near duplicates are not searched
+	maxNearDuplicates      = 50   // Near-duplicate pairs listed
+	maxNearDistance        = 32   // Largest NearDistance
+)
+
+// ColorCountOptions configures CountColors.
+type ColorCountOptions struct {
+	// Region, if set, restricts the count to a rectangle.
+	Region *Region
+
+	// Mask, if set, restricts the count to the selected pixels (see
+	// Mask.Rasterize), in image coordinates.
+	Mask *image.Alpha
+
+	// Limit is the number of colors listed, most common first (1-4096).
+	Limit int
+
+	// MaxColors, if positive, is the largest palette allowed; the result
+	// reports whether the image is within it.
+	MaxColors int
+
+	// IgnoreTransparent leaves fully transparent pixels out of the count,
+	// as a transparent background usually is of an icon's palette.
+	IgnoreTransparent bool
+
+	// NearDistance, if positive, is the largest difference in any channel
+	// between two colors reported as near duplicates (1-32).
+	NearDistance int
+}
+
+// CountedColor is one exact color of an image and how many pixels have it.
+type CountedColor struct {
+	// Hex is the color as #RRGGBB, or #RRGGBBAA if it is not opaque.
+	// Fully transparent pixels are one color, #00000000, whatever their
+	// RGB values.
+	Hex string `json:"hex"`
+
+	// RGBA is the color's components, not premultiplied.
+	RGBA RGBAColor `json:"rgba"`
+
+	// Pixels is the number of counted pixels of this color.
+	Pixels int `json:"pixels"`
+
+	// Percent is the share of the counted pixels (0-100).
+	Percent float64 `json:"percent"`
+}
+
+// NearDuplicateColors are two distinct colors that differ so little that
+// they are probably one color, split by resampling, compression, or a
+// gradient.
+type NearDuplicateColors struct {
+	// A is the more common color and B the rarer, as hex.
+	A string `json:"a"`
+	B string `json:"b"`
+
+	// Difference is the largest difference between their channels.
+	Difference int `json:"difference"`
+
+	// PixelsB is the number of pixels of the rarer color.
+	PixelsB int `json:"pixels_b"`
+}
+
+// ColorCountResult is the exact color count of an image.
+type ColorCountResult struct {
+	// DistinctColors is the number of distinct colors counted.
+	DistinctColors int `json:"distinct_colors"`
+
+	// Pixels is the number of pixels counted.
+	Pixels int `json:"pixels"`
+
+	// TransparentPixels is the number of fully transparent pixels, counted
+	// as one color unless ignored.
+	TransparentPixels int `json:"transparent_pixels"`
+
+	// Colors are the most common colors, most common first.
+	Colors []CountedColor `json:"colors"`
+
+	// MaxColors is the palette size checked against, and WithinLimit
+	// whether DistinctColors is at most MaxColors; both unset if no
+	// limit was given.
+	MaxColors   int   `json:"max_colors,omitempty"`
+	WithinLimit *bool `json:"within_limit,omitempty"`
+
+	// NearDuplicates are the pairs of colors within NearDistance of each
+	// other in every channel, the rarest first; searched only if
+	// NearDistance is set and there are at most 1024 distinct colors.
+	NearDuplicates []NearDuplicateColors `json:"near_duplicates,omitempty"`
+}
+
+// CountColors counts the exact distinct colors of an image, without
+// quantization, and the pixels of each, to verify the palette of pixel
+// art and icons.
+//
+// Parameters:
+//   - img: The image.
+//   - opts: The area counted, the colors listed, and the checks.
+//
+// Returns:
+//   - *ColorCountResult: The distinct color count, the most common colors
+//     with their pixel counts, and the checks' results.
+//   - error: Non-nil if an option is out of range or the mask selects no
+//     pixels.
+//
+// Colors are compared at 8 bits per channel, not premultiplied, so a
+// 16-bit image's colors are those it shows on an 8-bit display. Colors
+// differing only in alpha are distinct.
+//
+// Near duplicates reveal the stray shades that a palette limit is most
+// often exceeded by: a sprite resized with smoothing, saved as JPEG, or
+// dithered gains colors a level or two away from its own. Every pair of
+// colors is compared, so the search is limited to images with at most
+// 1024 distinct colors, far more than any palette-limited asset.
+func CountColors(img image.Image, opts ColorCountOptions) (*ColorCountResult, error) {
+	if opts.Limit < 1 || opts.Limit > maxCountedColors {
+		return nil, fmt.Errorf("limit must be between 1 and %d, got %d", maxCountedColors, opts.Limit)
+	}
+	if opts.MaxColors < 0 {
+		return nil, fmt.Errorf("max_colors must be non-negative, got %d", opts.MaxColors)
+	}
+	if opts.NearDistance < 0 || opts.NearDistance > maxNearDistance {
+		return nil, fmt.Errorf("near_distance must be between 0 and %d, got %d", maxNearDistance, opts.NearDistance)
+	}
+	bounds := img.Bounds()
+	if opts.Region != nil {
+		bounds = image.Rect(opts.Region.X1, opts.Region.Y1, opts.Region.X2, opts.Region.Y2).Intersect(bounds)
+	}
+
+	// Colors are keyed as 0xRRGGBBAA; fully transparent pixels are 0
+	counts := make(map[uint32]int)
+	result := &ColorCountResult{}
+	selected := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if !maskSelects(opts.Mask, x, y) {
+				continue
+			}
+			selected++
+			c := nrgbaAt(img, x, y)
+			if c.A == 0 {
+				result.TransparentPixels++
+				if opts.IgnoreTransparent {
+					continue
+				}
+				counts[0]++
+			} else {
+				counts[uint32(c.R)<<24|uint32(c.G)<<16|uint32(c.B)<<8|uint32(c.A)]++
+			}
+			result.Pixels++
+		}
+	}
+	if opts.Mask != nil && selected == 0 {
+		return nil, fmt.Errorf("mask selects no pixels in %v", bounds)
+	}
+
+	keys := make([]uint32, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	// Most common first, ties by color, for a stable order
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	result.DistinctColors = len(keys)
+	result.Colors = make([]CountedColor, 0, min(len(keys), opts.Limit))
+	for _, k := range keys[:min(len(keys), opts.Limit)] {
+		result.Colors = append(result.Colors, countedColor(k, counts[k], result.Pixels))
+	}
+	if opts.MaxColors > 0 {
+		within := result.DistinctColors <= opts.MaxColors
+		result.MaxColors, result.WithinLimit = opts.MaxColors, &within
+	}
+	if opts.NearDistance > 0 && len(keys) <= maxNearDuplicateSearch {
+		result.NearDuplicates = nearDuplicateColors(keys, counts, opts.NearDistance)
+	}
+	return result, nil
+}
+
+// countedColor returns the color of key k, with n of total pixels.
+func countedColor(k uint32, n, total int) CountedColor {
+	c := RGBAColor{R: uint8(k >> 24), G: uint8(k >> 16), B: uint8(k >> 8), A: uint8(k)}
+	hex := fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B)
+	if c.A != 255 {
+		hex += fmt.Sprintf("%02X", c.A)
+	}
+	percent := 0.0
+	if total > 0 {
+		percent = float64(n) / float64(total) * 100
+	}
+	return CountedColor{Hex: hex, RGBA: c, Pixels: n, Percent: percent}
+}
+
+// nearDuplicateColors returns the pairs of colors, keyed as in
+// CountColors and sorted most common first, that differ by at most
+// distance in every channel, the rarest first.
+func nearDuplicateColors(keys []uint32, counts map[uint32]int, distance int) []NearDuplicateColors {
+	var pairs []NearDuplicateColors
+	for i, a := range keys {
+		for _, b := range keys[i+1:] {
+			diff := 0
+			for shift := 0; shift < 32; shift += 8 {
+				diff = max(diff, absInt(int(a>>shift&0xFF)-int(b>>shift&0xFF)))
+			}
+			if diff > distance {
+				continue
+			}
+			pairs = append(pairs, NearDuplicateColors{
+				A:          countedColor(a, 0, 0).Hex,
+				B:          countedColor(b, 0, 0).Hex,
+				Difference: diff,
+				PixelsB:    counts[b],
+			})
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].PixelsB < pairs[j].PixelsB })
+	if len(pairs) > maxNearDuplicates {
+		pairs = pairs[:maxNearDuplicates]
+	}
+	return pairs
+}
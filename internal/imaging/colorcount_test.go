@@ -0,0 +1,98 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// spriteImage draws a 16×16 sprite: a transparent background, an 8×8
+// red body with a 2×2 half-transparent highlight, and a black outline
+// pixel one level off black.
+func spriteImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for y := 4; y < 12; y++ {
+		for x := 4; x < 12; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{200, 30, 30, 255})
+		}
+	}
+	for y := 5; y < 7; y++ {
+		for x := 5; x < 7; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{255, 255, 255, 128})
+		}
+	}
+	for x := 4; x < 12; x++ {
+		img.SetNRGBA(x, 3, color.NRGBA{0, 0, 0, 255})
+	}
+	img.SetNRGBA(11, 3, color.NRGBA{1, 0, 0, 255})
+	// A transparent pixel with leftover RGB is still transparent
+	img.SetNRGBA(0, 0, color.NRGBA{90, 90, 90, 0})
+	return img
+}
+
+func TestCountColors(t *testing.T) {
+	result, err := CountColors(spriteImage(), ColorCountOptions{Limit: 256, MaxColors: 4, NearDistance: 2})
+	if err != nil {
+		t.Fatalf("CountColors failed: %v", err)
+	}
+	if result.DistinctColors != 5 || result.Pixels != 256 || result.TransparentPixels != 184 {
+		t.Errorf("got %d colors, %d pixels, %d transparent", result.DistinctColors, result.Pixels, result.TransparentPixels)
+	}
+	want := []struct {
+		hex    string
+		pixels int
+	}{{"#00000000", 184}, {"#C81E1E", 60}, {"#000000", 7}, {"#FFFFFF80", 4}, {"#010000", 1}}
+	if len(result.Colors) != len(want) {
+		t.Fatalf("got %d colors, want %d", len(result.Colors), len(want))
+	}
+	for i, w := range want {
+		if c := result.Colors[i]; c.Hex != w.hex || c.Pixels != w.pixels {
+			t.Errorf("color %d = %s × %d, want %s × %d", i, c.Hex, c.Pixels, w.hex, w.pixels)
+		}
+	}
+	if result.WithinLimit == nil || *result.WithinLimit {
+		t.Error("5 colors: expected to exceed a palette of 4")
+	}
+	if len(result.NearDuplicates) != 1 || result.NearDuplicates[0] != (NearDuplicateColors{A: "#000000", B: "#010000", Difference: 1, PixelsB: 1}) {
+		t.Errorf("near duplicates = %+v", result.NearDuplicates)
+	}
+}
+
+func TestCountColors_IgnoreTransparent(t *testing.T) {
+	result, err := CountColors(spriteImage(), ColorCountOptions{Limit: 2, MaxColors: 4, IgnoreTransparent: true})
+	if err != nil {
+		t.Fatalf("CountColors failed: %v", err)
+	}
+	if result.DistinctColors != 4 || result.Pixels != 72 || len(result.Colors) != 2 {
+		t.Errorf("got %d colors (%d listed), %d pixels", result.DistinctColors, len(result.Colors), result.Pixels)
+	}
+	if result.WithinLimit == nil || !*result.WithinLimit {
+		t.Error("4 colors: expected to fit a palette of 4")
+	}
+	if result.NearDuplicates != nil {
+		t.Errorf("near duplicates searched without a distance: %+v", result.NearDuplicates)
+	}
+}
+
+func TestCountColors_Region(t *testing.T) {
+	result, err := CountColors(spriteImage(), ColorCountOptions{Limit: 10, Region: &Region{X1: 4, Y1: 4, X2: 12, Y2: 12}})
+	if err != nil {
+		t.Fatalf("CountColors failed: %v", err)
+	}
+	if result.DistinctColors != 2 || result.Pixels != 64 || result.WithinLimit != nil {
+		t.Errorf("got %d colors, %d pixels", result.DistinctColors, result.Pixels)
+	}
+}
+
+func TestCountColors_InvalidOptions(t *testing.T) {
+	for _, opts := range []ColorCountOptions{
+		{Limit: 0},
+		{Limit: 5000},
+		{Limit: 10, MaxColors: -1},
+		{Limit: 10, NearDistance: 33},
+	} {
+		if _, err := CountColors(spriteImage(), opts); err == nil {
+			t.Errorf("expected error for %+v", opts)
+		}
+	}
+}
@@ -0,0 +1,383 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// ContactSheetItem is a single image to be placed on a contact sheet.
+//
+// The image may be a full image or a sub-image cropped by the caller.
+// Label is drawn beneath the thumbnail when labels are enabled.
+type ContactSheetItem struct {
+	Image image.Image // Image (or cropped region) to render as a thumbnail
+	Label string      // Caption drawn under the thumbnail (may be empty)
+}
+
+// ContactSheetCell describes where one item was placed on the contact sheet.
+type ContactSheetCell struct {
+	// Index is the position of the item in the input list (0-based).
+	Index int `json:"index"`
+
+	// Label is the caption drawn for this cell.
+	Label string `json:"label,omitempty"`
+
+	// Row and Column are the grid position of the cell (0-based).
+	Row    int `json:"row"`
+	Column int `json:"column"`
+
+	// Bounds is the thumbnail's location within the contact sheet image.
+	Bounds Region `json:"bounds"`
+
+	// SourceWidth and SourceHeight are the dimensions of the original image
+	// (or region) before thumbnailing.
+	SourceWidth  int `json:"source_width"`
+	SourceHeight int `json:"source_height"`
+}
+
+// ContactSheetResult contains a composed grid of thumbnails as base64 PNG.
+type ContactSheetResult struct {
+	// Width of the contact sheet image in pixels.
+	Width int `json:"width"`
+
+	// Height of the contact sheet image in pixels.
+	Height int `json:"height"`
+
+	// ImageBase64 is the contact sheet encoded as base64 PNG.
+	ImageBase64 string `json:"image_base64"`
+
+	// MimeType is always "image/png" for contact sheets.
+	MimeType string `json:"mime_type"`
+
+	// Columns and Rows are the dimensions of the thumbnail grid.
+	Columns int `json:"columns"`
+	Rows    int `json:"rows"`
+
+	// Cells lists the placement of each input item, in input order.
+	Cells []ContactSheetCell `json:"cells"`
+}
+
+// Contact sheet layout constants.
+const (
+	contactSheetPadding     = 8  // Gap around each thumbnail in pixels
+	contactSheetLabelHeight = 16 // Height reserved for a caption row
+	maxContactSheetItems    = 100
+	maxContactSheetThumb    = 1024 // Largest thumbSize, bounding the sheet size
+)
+
+// ContactSheet composes thumbnails of several images into a single labeled grid.
+//
+// This is useful for reviewing many images (or many regions of one image) in a
+// single client render instead of transferring each one separately.
+//
+// Parameters:
+//   - items: Images to place on the sheet, in reading order (left-to-right,
+//     top-to-bottom). Must contain between 1 and 100 items.
+//   - columns: Number of thumbnails per row. If <= 0, a near-square grid is
+//     chosen automatically (ceil(sqrt(n)) columns).
+//   - thumbSize: Maximum width and height of each thumbnail in pixels. Images
+//     are scaled down to fit, preserving aspect ratio; smaller images are not
+//     enlarged. Must be between 1 and 1024.
+//   - showLabels: If true, each item's Label is drawn beneath its thumbnail.
+//
+// Returns:
+//   - *ContactSheetResult: The composed sheet and per-cell placement data.
+//   - error: Non-nil if no items are given, too many items are given,
+//     thumbSize is invalid, or PNG encoding fails.
+//
+// # Layout
+//
+// Every cell is thumbSize × thumbSize (plus a caption row when labels are
+// shown) with 8 pixels of padding. Thumbnails are centered within their cell
+// on a light gray background so differently shaped images line up.
+func ContactSheet(items []ContactSheetItem, columns, thumbSize int, showLabels bool) (*ContactSheetResult, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("contact sheet requires at least one image")
+	}
+	if len(items) > maxContactSheetItems {
+		return nil, fmt.Errorf("contact sheet supports at most %d images, got %d", maxContactSheetItems, len(items))
+	}
+	if thumbSize <= 0 || thumbSize > maxContactSheetThumb {
+		return nil, fmt.Errorf("thumb_size must be between 1 and %d, got %d", maxContactSheetThumb, thumbSize)
+	}
+
+	if columns <= 0 {
+		columns = int(math.Ceil(math.Sqrt(float64(len(items)))))
+	}
+	if columns > len(items) {
+		columns = len(items)
+	}
+	rows := (len(items) + columns - 1) / columns
+
+	labelHeight := 0
+	if showLabels {
+		labelHeight = contactSheetLabelHeight
+	}
+	cellW := thumbSize + contactSheetPadding
+	cellH := thumbSize + labelHeight + contactSheetPadding
+
+	sheetW := columns*cellW + contactSheetPadding
+	sheetH := rows*cellH + contactSheetPadding
+
+	sheet := image.NewRGBA(image.Rect(0, 0, sheetW, sheetH))
+	draw.Draw(sheet, sheet.Bounds(), &image.Uniform{color.RGBA{240, 240, 240, 255}}, image.Point{}, draw.Src)
+
+	cells := make([]ContactSheetCell, 0, len(items))
+	for i, item := range items {
+		row := i / columns
+		col := i % columns
+		srcBounds := item.Image.Bounds()
+
+		thumb := imaging.Fit(item.Image, thumbSize, thumbSize, imaging.Lanczos)
+		tw := thumb.Bounds().Dx()
+		th := thumb.Bounds().Dy()
+
+		// Center the thumbnail within its cell
+		cellX := contactSheetPadding + col*cellW
+		cellY := contactSheetPadding + row*cellH
+		x := cellX + (thumbSize-tw)/2
+		y := cellY + (thumbSize-th)/2
+
+		dst := image.Rect(x, y, x+tw, y+th)
+		draw.Draw(sheet, dst, thumb, thumb.Bounds().Min, draw.Over)
+
+		if showLabels && item.Label != "" {
+			drawText(sheet, cellX, cellY+thumbSize+2, thumbSize, item.Label, color.RGBA{0, 0, 0, 255})
+		}
+
+		cells = append(cells, ContactSheetCell{
+			Index:        i,
+			Label:        item.Label,
+			Row:          row,
+			Column:       col,
+			Bounds:       Region{X1: x, Y1: y, X2: x + tw, Y2: y + th},
+			SourceWidth:  srcBounds.Dx(),
+			SourceHeight: srcBounds.Dy(),
+		})
+	}
+
+	encoded, err := encodePNGBase64(sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ContactSheetResult{
+		Width:       sheetW,
+		Height:      sheetH,
+		ImageBase64: encoded,
+		MimeType:    "image/png",
+		Columns:     columns,
+		Rows:        rows,
+		Cells:       cells,
+	}, nil
+}
+
+// SideBySideResult contains two images composed next to each other as base64 PNG.
+type SideBySideResult struct {
+	// Width of the composite image in pixels.
+	Width int `json:"width"`
+
+	// Height of the composite image in pixels.
+	Height int `json:"height"`
+
+	// ImageBase64 is the composite encoded as base64 PNG.
+	ImageBase64 string `json:"image_base64"`
+
+	// MimeType is always "image/png" for side-by-side results.
+	MimeType string `json:"mime_type"`
+
+	// LeftBounds and RightBounds locate each input image within the composite.
+	// For vertical layouts, "left" is the top image and "right" the bottom one.
+	LeftBounds  Region `json:"left_bounds"`
+	RightBounds Region `json:"right_bounds"`
+
+	// DiffHighlighted is true if difference highlighting was requested.
+	// PixelsDifferent and DiffPercentage are only meaningful when it is set.
+	DiffHighlighted bool `json:"diff_highlighted"`
+
+	// PixelsDifferent is the number of overlapping pixels whose mean channel
+	// difference exceeds the noise threshold.
+	PixelsDifferent int `json:"pixels_different"`
+
+	// DiffPercentage is PixelsDifferent as a percentage of the compared area.
+	DiffPercentage float64 `json:"diff_percentage"`
+
+	// ChannelDiff has the per-channel differences, if requested (see
+	// ChannelDiff).
+	ChannelDiff *ChannelDiffResult `json:"channel_diff,omitempty"`
+
+	// FontDiff compares the glyph rendering of the text regions, if
+	// requested (see TextRenderingDiff).
+	FontDiff *TextRenderingDiffResult `json:"font_diff,omitempty"`
+}
+
+// SideBySide places two images next to each other in a single composite image.
+//
+// This produces the visual artifact typically assembled by hand for regression
+// reports: "before" on one side, "after" on the other, optionally with the
+// pixels that changed tinted red on both sides.
+//
+// Parameters:
+//   - left, right: The two images to compose. They may differ in size.
+//   - leftLabel, rightLabel: Captions drawn above each image. Empty labels
+//     are not drawn; if both are empty no caption row is reserved.
+//   - vertical: If true, stack the images top-to-bottom instead of left-to-right.
+//   - highlightDiff: If true, pixels that differ between the two images
+//     (compared from their top-left corners over the overlapping area) are
+//     tinted red in both halves of the composite.
+//
+// Returns:
+//   - *SideBySideResult: The composite image, the placement of each half and,
+//     when highlighting, difference statistics.
+//   - error: Non-nil if PNG encoding fails.
+//
+// # Difference Highlighting
+//
+// A pixel is considered different when its mean per-channel difference
+// exceeds 10 (the same threshold as CompareRegions), which ignores minor
+// compression and anti-aliasing noise. Differing pixels are blended 50% with
+// pure red so the underlying content remains visible.
+func SideBySide(left, right image.Image, leftLabel, rightLabel string, vertical, highlightDiff bool) (*SideBySideResult, error) {
+	lb := left.Bounds()
+	rb := right.Bounds()
+
+	labelHeight := 0
+	if leftLabel != "" || rightLabel != "" {
+		labelHeight = contactSheetLabelHeight
+	}
+	pad := contactSheetPadding
+
+	var leftOrigin, rightOrigin image.Point
+	var width, height int
+	if vertical {
+		width = maxInt(lb.Dx(), rb.Dx()) + 2*pad
+		leftOrigin = image.Pt(pad, pad+labelHeight)
+		rightOrigin = image.Pt(pad, leftOrigin.Y+lb.Dy()+pad+labelHeight)
+		height = rightOrigin.Y + rb.Dy() + pad
+	} else {
+		height = maxInt(lb.Dy(), rb.Dy()) + labelHeight + 2*pad
+		leftOrigin = image.Pt(pad, pad+labelHeight)
+		rightOrigin = image.Pt(leftOrigin.X+lb.Dx()+pad, pad+labelHeight)
+		width = rightOrigin.X + rb.Dx() + pad
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(canvas, canvas.Bounds(), &image.Uniform{color.RGBA{240, 240, 240, 255}}, image.Point{}, draw.Src)
+
+	leftRect := image.Rectangle{Min: leftOrigin, Max: leftOrigin.Add(lb.Size())}
+	rightRect := image.Rectangle{Min: rightOrigin, Max: rightOrigin.Add(rb.Size())}
+	draw.Draw(canvas, leftRect, left, lb.Min, draw.Over)
+	draw.Draw(canvas, rightRect, right, rb.Min, draw.Over)
+
+	if leftLabel != "" {
+		drawText(canvas, leftOrigin.X, leftOrigin.Y-labelHeight+1, lb.Dx(), leftLabel, color.RGBA{0, 0, 0, 255})
+	}
+	if rightLabel != "" {
+		drawText(canvas, rightOrigin.X, rightOrigin.Y-labelHeight+1, rb.Dx(), rightLabel, color.RGBA{0, 0, 0, 255})
+	}
+
+	result := &SideBySideResult{
+		Width:       width,
+		Height:      height,
+		MimeType:    "image/png",
+		LeftBounds:  Region{X1: leftRect.Min.X, Y1: leftRect.Min.Y, X2: leftRect.Max.X, Y2: leftRect.Max.Y},
+		RightBounds: Region{X1: rightRect.Min.X, Y1: rightRect.Min.Y, X2: rightRect.Max.X, Y2: rightRect.Max.Y},
+	}
+
+	if highlightDiff {
+		minW := minInt(lb.Dx(), rb.Dx())
+		minH := minInt(lb.Dy(), rb.Dy())
+		different := 0
+		for dy := 0; dy < minH; dy++ {
+			for dx := 0; dx < minW; dx++ {
+				if pixelDiff(left.At(lb.Min.X+dx, lb.Min.Y+dy), right.At(rb.Min.X+dx, rb.Min.Y+dy)) > pixelDiffThreshold {
+					different++
+					tintRed(canvas, leftOrigin.X+dx, leftOrigin.Y+dy)
+					tintRed(canvas, rightOrigin.X+dx, rightOrigin.Y+dy)
+				}
+			}
+		}
+		result.DiffHighlighted = true
+		result.PixelsDifferent = different
+		if minW*minH > 0 {
+			result.DiffPercentage = math.Round(float64(different)/float64(minW*minH)*10000) / 100
+		}
+	}
+
+	encoded, err := encodePNGBase64(canvas)
+	if err != nil {
+		return nil, err
+	}
+	result.ImageBase64 = encoded
+
+	return result, nil
+}
+
+// tintRed blends a pixel 50% with pure red to highlight it.
+func tintRed(img *image.RGBA, x, y int) {
+	c := img.RGBAAt(x, y)
+	img.SetRGBA(x, y, color.RGBA{
+		R: uint8((int(c.R) + 255) / 2),
+		G: c.G / 2,
+		B: c.B / 2,
+		A: 255,
+	})
+}
+
+// drawText renders a single line of text using the 7x13 basic bitmap font.
+//
+// The text is clipped to maxWidth pixels (truncated with "..." when it does
+// not fit). (x, y) is the top-left corner of the text line.
+func drawText(img draw.Image, x, y, maxWidth int, text string, fg color.Color) {
+	face := basicfont.Face7x13
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(fg),
+		Face: face,
+	}
+
+	if maxWidth > 0 && d.MeasureString(text).Ceil() > maxWidth {
+		runes := []rune(text)
+		for len(runes) > 0 && d.MeasureString(string(runes)+"...").Ceil() > maxWidth {
+			runes = runes[:len(runes)-1]
+		}
+		text = string(runes) + "..."
+	}
+
+	d.Dot = fixed.P(x, y+face.Ascent)
+	d.DrawString(text)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// encodePNGBase64 encodes an image as PNG and returns it as a base64 string.
+func encodePNGBase64(img image.Image) (string, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", fmt.Errorf("failed to encode image: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
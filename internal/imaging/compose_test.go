@@ -0,0 +1,230 @@
+package imaging
+
+import (
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestContactSheet(t *testing.T) {
+	items := []ContactSheetItem{
+		{Image: createInMemoryImage(100, 50, color.RGBA{255, 0, 0, 255}), Label: "red"},
+		{Image: createInMemoryImage(50, 100, color.RGBA{0, 255, 0, 255}), Label: "green"},
+		{Image: createInMemoryImage(80, 80, color.RGBA{0, 0, 255, 255}), Label: "blue"},
+	}
+
+	result, err := ContactSheet(items, 0, 64, true)
+	if err != nil {
+		t.Fatalf("ContactSheet failed: %v", err)
+	}
+
+	// 3 items -> ceil(sqrt(3)) = 2 columns, 2 rows
+	if result.Columns != 2 || result.Rows != 2 {
+		t.Errorf("grid: got %dx%d, want 2x2", result.Columns, result.Rows)
+	}
+	if len(result.Cells) != 3 {
+		t.Fatalf("cells: got %d, want 3", len(result.Cells))
+	}
+	if result.MimeType != "image/png" {
+		t.Errorf("MimeType: got %s, want image/png", result.MimeType)
+	}
+
+	// First thumbnail is 100x50 scaled to fit 64 -> 64x32
+	b := result.Cells[0].Bounds
+	if b.X2-b.X1 != 64 || b.Y2-b.Y1 != 32 {
+		t.Errorf("cell 0 size: got %dx%d, want 64x32", b.X2-b.X1, b.Y2-b.Y1)
+	}
+	if result.Cells[0].SourceWidth != 100 || result.Cells[0].SourceHeight != 50 {
+		t.Errorf("cell 0 source: got %dx%d, want 100x50",
+			result.Cells[0].SourceWidth, result.Cells[0].SourceHeight)
+	}
+	if result.Cells[2].Row != 1 || result.Cells[2].Column != 0 {
+		t.Errorf("cell 2 position: got row %d col %d, want row 1 col 0",
+			result.Cells[2].Row, result.Cells[2].Column)
+	}
+
+	// Verify the thumbnail pixels landed where the cell says they are
+	decoded, err := base64.StdEncoding.DecodeString(result.ImageBase64)
+	if err != nil {
+		t.Fatalf("failed to decode base64: %v", err)
+	}
+	sheet, err := png.Decode(strings.NewReader(string(decoded)))
+	if err != nil {
+		t.Fatalf("failed to decode PNG: %v", err)
+	}
+	if sheet.Bounds().Dx() != result.Width || sheet.Bounds().Dy() != result.Height {
+		t.Errorf("decoded size: got %dx%d, want %dx%d",
+			sheet.Bounds().Dx(), sheet.Bounds().Dy(), result.Width, result.Height)
+	}
+
+	cx := (b.X1 + b.X2) / 2
+	cy := (b.Y1 + b.Y2) / 2
+	r, g, bl, _ := sheet.At(cx, cy).RGBA()
+	if r>>8 != 255 || g>>8 != 0 || bl>>8 != 0 {
+		t.Errorf("cell 0 center color: got (%d,%d,%d), want (255,0,0)", r>>8, g>>8, bl>>8)
+	}
+}
+
+func TestContactSheet_ExplicitColumns(t *testing.T) {
+	items := make([]ContactSheetItem, 5)
+	for i := range items {
+		items[i] = ContactSheetItem{Image: createInMemoryImage(20, 20, color.RGBA{128, 128, 128, 255})}
+	}
+
+	result, err := ContactSheet(items, 5, 20, false)
+	if err != nil {
+		t.Fatalf("ContactSheet failed: %v", err)
+	}
+
+	if result.Columns != 5 || result.Rows != 1 {
+		t.Errorf("grid: got %dx%d, want 5x1", result.Columns, result.Rows)
+	}
+
+	// Without labels: 5 cells of (20+8) plus 8 padding
+	if result.Width != 5*28+8 || result.Height != 28+8 {
+		t.Errorf("size: got %dx%d, want %dx%d", result.Width, result.Height, 5*28+8, 28+8)
+	}
+}
+
+func TestContactSheet_ColumnsClampedToItemCount(t *testing.T) {
+	items := []ContactSheetItem{
+		{Image: createInMemoryImage(10, 10, color.RGBA{0, 0, 0, 255})},
+		{Image: createInMemoryImage(10, 10, color.RGBA{0, 0, 0, 255})},
+	}
+
+	result, err := ContactSheet(items, 10, 10, false)
+	if err != nil {
+		t.Fatalf("ContactSheet failed: %v", err)
+	}
+
+	if result.Columns != 2 {
+		t.Errorf("Columns: got %d, want 2", result.Columns)
+	}
+}
+
+func TestContactSheet_Errors(t *testing.T) {
+	img := createInMemoryImage(10, 10, color.RGBA{0, 0, 0, 255})
+
+	if _, err := ContactSheet(nil, 0, 100, true); err == nil {
+		t.Error("ContactSheet should fail with no items")
+	}
+
+	if _, err := ContactSheet([]ContactSheetItem{{Image: img}}, 0, 0, true); err == nil {
+		t.Error("ContactSheet should fail with zero thumb size")
+	}
+
+	if _, err := ContactSheet([]ContactSheetItem{{Image: img}}, 0, maxContactSheetThumb+1, true); err == nil {
+		t.Error("ContactSheet should fail with an oversized thumb size")
+	}
+
+	tooMany := make([]ContactSheetItem, maxContactSheetItems+1)
+	for i := range tooMany {
+		tooMany[i] = ContactSheetItem{Image: img}
+	}
+	if _, err := ContactSheet(tooMany, 0, 10, false); err == nil {
+		t.Error("ContactSheet should fail with too many items")
+	}
+}
+
+func TestDrawText_Truncates(t *testing.T) {
+	sheet := createPatternImage(40, 20)
+
+	// Should not panic and should stay within maxWidth
+	drawText(sheet, 0, 0, 30, "a very long label that cannot fit", color.RGBA{0, 0, 0, 255})
+}
+
+func TestSideBySide(t *testing.T) {
+	left := createInMemoryImage(40, 30, color.RGBA{255, 255, 255, 255})
+	right := createInMemoryImage(50, 20, color.RGBA{255, 255, 255, 255})
+
+	result, err := SideBySide(left, right, "", "", false, false)
+	if err != nil {
+		t.Fatalf("SideBySide failed: %v", err)
+	}
+
+	// No labels: 8 + 40 + 8 + 50 + 8 wide, 8 + 30 + 8 high
+	if result.Width != 114 || result.Height != 46 {
+		t.Errorf("size: got %dx%d, want 114x46", result.Width, result.Height)
+	}
+	if result.LeftBounds != (Region{X1: 8, Y1: 8, X2: 48, Y2: 38}) {
+		t.Errorf("LeftBounds: got %+v", result.LeftBounds)
+	}
+	if result.RightBounds != (Region{X1: 56, Y1: 8, X2: 106, Y2: 28}) {
+		t.Errorf("RightBounds: got %+v", result.RightBounds)
+	}
+	if result.DiffHighlighted {
+		t.Error("DiffHighlighted should be false when not requested")
+	}
+}
+
+func TestSideBySide_Vertical(t *testing.T) {
+	top := createInMemoryImage(40, 30, color.RGBA{0, 0, 0, 255})
+	bottom := createInMemoryImage(20, 10, color.RGBA{0, 0, 0, 255})
+
+	result, err := SideBySide(top, bottom, "before", "after", true, false)
+	if err != nil {
+		t.Fatalf("SideBySide failed: %v", err)
+	}
+
+	if result.RightBounds.Y1 <= result.LeftBounds.Y2 {
+		t.Errorf("bottom image should be below top image: %+v vs %+v", result.RightBounds, result.LeftBounds)
+	}
+	if result.LeftBounds.X1 != result.RightBounds.X1 {
+		t.Errorf("vertical layout should left-align images: %d vs %d", result.LeftBounds.X1, result.RightBounds.X1)
+	}
+	if result.Width != 40+16 {
+		t.Errorf("Width: got %d, want %d", result.Width, 40+16)
+	}
+}
+
+func TestSideBySide_HighlightDiff(t *testing.T) {
+	left := createInMemoryImage(20, 20, color.RGBA{0, 0, 0, 255})
+	right := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 20; x++ {
+			right.Set(x, y, color.RGBA{0, 0, 0, 255})
+		}
+	}
+	// Change a 5x4 block
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 5; x++ {
+			right.Set(x, y, color.RGBA{255, 255, 255, 255})
+		}
+	}
+
+	result, err := SideBySide(left, right, "", "", false, true)
+	if err != nil {
+		t.Fatalf("SideBySide failed: %v", err)
+	}
+
+	if !result.DiffHighlighted {
+		t.Error("DiffHighlighted should be true")
+	}
+	if result.PixelsDifferent != 20 {
+		t.Errorf("PixelsDifferent: got %d, want 20", result.PixelsDifferent)
+	}
+	if result.DiffPercentage != 5 {
+		t.Errorf("DiffPercentage: got %v, want 5", result.DiffPercentage)
+	}
+
+	decoded, _ := base64.StdEncoding.DecodeString(result.ImageBase64)
+	composite, err := png.Decode(strings.NewReader(string(decoded)))
+	if err != nil {
+		t.Fatalf("failed to decode PNG: %v", err)
+	}
+
+	// Changed pixel on the left half should be tinted red
+	r, g, _, _ := composite.At(result.LeftBounds.X1, result.LeftBounds.Y1).RGBA()
+	if r>>8 < 100 || g>>8 != 0 {
+		t.Errorf("changed pixel should be tinted red, got r=%d g=%d", r>>8, g>>8)
+	}
+
+	// Unchanged pixel stays black
+	r, _, _, _ = composite.At(result.LeftBounds.X1+10, result.LeftBounds.Y1+10).RGBA()
+	if r>>8 != 0 {
+		t.Errorf("unchanged pixel should stay black, got r=%d", r>>8)
+	}
+}
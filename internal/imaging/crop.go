@@ -27,8 +27,16 @@ type CropResult struct {
 
 	// MimeType is always "image/png" for crop results.
 	MimeType string `json:"mime_type"`
+
+	// ToneMapped reports whether a dim 16-bit region was brightened to fill
+	// the 8-bit range of the preview (see ToneMap).
+	ToneMapped bool `json:"tone_mapped,omitempty"`
 }
 
+// maxScaledDimension caps the width and height of a scaled crop, so a large
+// scale factor cannot request an arbitrarily large image.
+const maxScaledDimension = 16384
+
 // Crop extracts a rectangular region from an image and returns it as base64 PNG.
 //
 // This function is useful for zooming into specific areas of an image for
@@ -40,12 +48,14 @@ type CropResult struct {
 //   - x2, y2: Bottom-right corner of the crop region (exclusive).
 //   - scale: Scaling factor to apply after cropping. Use 1.0 for no scaling,
 //     2.0 to double the size, 0.5 to halve it, etc. Must be > 0.
+//     The scaled image may be at most 16384 pixels on each side.
 //
 // Returns:
 //   - *CropResult: The cropped image data with dimensions and base64 encoding.
 //   - error: Non-nil if:
 //   - Crop region is outside image bounds
 //   - Crop region is invalid (x1 >= x2 or y1 >= y2)
+//   - The scaled image would exceed 16384 pixels on a side
 //   - PNG encoding fails
 //
 // # Coordinate System
@@ -63,24 +73,25 @@ type CropResult struct {
 //
 //	finalWidth = int(cropWidth * scale)
 //	finalHeight = int(cropHeight * scale)
+//
+// # 16-bit Images
+//
+// The returned PNG has 8 bits per channel. 16-bit regions are rounded to 8
+// bits and, if dim, brightened; see ToneMap.
 func Crop(img image.Image, x1, y1, x2, y2 int, scale float64) (*CropResult, error) {
-	bounds := img.Bounds()
-
-	// Validate coordinates
-	if x1 < bounds.Min.X || y1 < bounds.Min.Y || x2 > bounds.Max.X || y2 > bounds.Max.Y {
-		return nil, fmt.Errorf("crop region (%d,%d)-(%d,%d) outside image bounds (%d,%d)-(%d,%d)",
-			x1, y1, x2, y2, bounds.Min.X, bounds.Min.Y, bounds.Max.X, bounds.Max.Y)
-	}
-	if x1 >= x2 || y1 >= y2 {
-		return nil, fmt.Errorf("invalid crop region: x1 must be < x2, y1 must be < y2")
+	src, toneMapped := previewSource(img, image.Rect(x1, y1, x2, y2))
+	cropped, err := CropRegion(src, Region{X1: x1, Y1: y1, X2: x2, Y2: y2})
+	if err != nil {
+		return nil, err
 	}
 
-	cropped := imaging.Crop(img, image.Rect(x1, y1, x2, y2))
-
 	if scale != 1.0 && scale > 0 {
-		newWidth := int(float64(cropped.Bounds().Dx()) * scale)
-		newHeight := int(float64(cropped.Bounds().Dy()) * scale)
-		cropped = imaging.Resize(cropped, newWidth, newHeight, imaging.Lanczos)
+		w := float64(cropped.Bounds().Dx()) * scale
+		h := float64(cropped.Bounds().Dy()) * scale
+		if w > maxScaledDimension || h > maxScaledDimension {
+			return nil, fmt.Errorf("scaled crop too large: %.0fx%.0f (max %d per side); use a smaller scale", w, h, maxScaledDimension)
+		}
+		cropped = imaging.Resize(cropped, int(w), int(h), imaging.Lanczos)
 	}
 
 	var buf bytes.Buffer
@@ -93,9 +104,37 @@ func Crop(img image.Image, x1, y1, x2, y2 int, scale float64) (*CropResult, erro
 		Height:      cropped.Bounds().Dy(),
 		ImageBase64: base64.StdEncoding.EncodeToString(buf.Bytes()),
 		MimeType:    "image/png",
+		ToneMapped:  toneMapped,
 	}, nil
 }
 
+// CropRegion extracts a rectangular region from an image as a new image.
+//
+// Unlike Crop, the result is returned as an in-memory image rather than
+// base64 PNG, for use by operations that compose or analyze sub-images.
+//
+// Parameters:
+//   - img: Source image to crop from.
+//   - r: Region to extract. (X1, Y1) is inclusive, (X2, Y2) is exclusive.
+//
+// Returns:
+//   - *image.NRGBA: The cropped pixels, with bounds starting at (0, 0).
+//   - error: Non-nil if the region is outside the image bounds or empty.
+func CropRegion(img image.Image, r Region) (*image.NRGBA, error) {
+	bounds := img.Bounds()
+
+	// Validate coordinates
+	if r.X1 < bounds.Min.X || r.Y1 < bounds.Min.Y || r.X2 > bounds.Max.X || r.Y2 > bounds.Max.Y {
+		return nil, fmt.Errorf("crop region (%d,%d)-(%d,%d) outside image bounds (%d,%d)-(%d,%d)",
+			r.X1, r.Y1, r.X2, r.Y2, bounds.Min.X, bounds.Min.Y, bounds.Max.X, bounds.Max.Y)
+	}
+	if r.X1 >= r.X2 || r.Y1 >= r.Y2 {
+		return nil, fmt.Errorf("invalid crop region: x1 must be < x2, y1 must be < y2")
+	}
+
+	return imaging.Crop(img, image.Rect(r.X1, r.Y1, r.X2, r.Y2)), nil
+}
+
 // CropQuadrant extracts a named region from an image using predefined positions.
 //
 // This function provides a convenient way to extract common image regions without
@@ -59,6 +59,15 @@ func TestCrop_ScaleDown(t *testing.T) {
 	}
 }
 
+func TestCrop_ScaleTooLarge(t *testing.T) {
+	img := createInMemoryImage(100, 100, color.RGBA{255, 0, 0, 255})
+
+	// 50 * 1e6 pixels per side must be refused before anything is allocated
+	if _, err := Crop(img, 0, 0, 50, 50, 1e6); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("Crop with scale 1e6: got %v, want a size error", err)
+	}
+}
+
 func TestCrop_OutOfBounds(t *testing.T) {
 	img := createInMemoryImage(100, 100, color.RGBA{255, 0, 0, 255})
 
@@ -265,3 +274,32 @@ func TestCropQuadrant_OddDimensions(t *testing.T) {
 		t.Errorf("dimensions: got %dx%d, want 50x50", result.Width, result.Height)
 	}
 }
+
+func TestCropRegion(t *testing.T) {
+	img := createPatternImage(100, 100)
+
+	cropped, err := CropRegion(img, Region{X1: 50, Y1: 0, X2: 100, Y2: 50})
+	if err != nil {
+		t.Fatalf("CropRegion failed: %v", err)
+	}
+
+	if cropped.Bounds().Min.X != 0 || cropped.Bounds().Min.Y != 0 {
+		t.Errorf("bounds should start at origin, got %v", cropped.Bounds().Min)
+	}
+	if cropped.Bounds().Dx() != 50 || cropped.Bounds().Dy() != 50 {
+		t.Errorf("dimensions: got %dx%d, want 50x50", cropped.Bounds().Dx(), cropped.Bounds().Dy())
+	}
+
+	// Top-right quadrant of the pattern is green
+	r, g, b, _ := cropped.At(25, 25).RGBA()
+	if r>>8 != 0 || g>>8 != 255 || b>>8 != 0 {
+		t.Errorf("color: got (%d,%d,%d), want (0,255,0)", r>>8, g>>8, b>>8)
+	}
+
+	if _, err := CropRegion(img, Region{X1: 0, Y1: 0, X2: 101, Y2: 50}); err == nil {
+		t.Error("CropRegion should fail for out-of-bounds region")
+	}
+	if _, err := CropRegion(img, Region{X1: 10, Y1: 10, X2: 10, Y2: 50}); err == nil {
+		t.Error("CropRegion should fail for empty region")
+	}
+}
@@ -0,0 +1,78 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// noisyScreen returns a 128x96 gray panel with a black bar, plus Gaussian
+// noise of standard deviation sigma.
+func noisyScreen(sigma float64) *image.NRGBA {
+	rng := rand.New(rand.NewSource(1))
+	img := image.NewNRGBA(image.Rect(0, 0, 128, 96))
+	for y := 0; y < 96; y++ {
+		for x := 0; x < 128; x++ {
+			v := 180.0
+			if y >= 40 && y < 56 {
+				v = 20
+			}
+			v += rng.NormFloat64() * sigma
+			g := uint8(math.Round(min(max(v, 0), 255)))
+			img.SetNRGBA(x, y, color.NRGBA{g, g, g, 255})
+		}
+	}
+	return img
+}
+
+func TestEstimateNoise(t *testing.T) {
+	if got := EstimateNoise(uiScreenshot()); got != 0 {
+		t.Errorf("clean screenshot: got %v", got)
+	}
+	for _, sigma := range []float64{3, 10, 20} {
+		if got := EstimateNoise(noisyScreen(sigma)); math.Abs(got-sigma) > sigma*0.15 {
+			t.Errorf("sigma %v: got %v", sigma, got)
+		}
+	}
+	if got := EstimateNoise(image.NewGray(image.Rect(0, 0, 2, 2))); got != 0 {
+		t.Errorf("2x2: got %v", got)
+	}
+}
+
+func TestDenoise(t *testing.T) {
+	img := noisyScreen(10)
+	for _, method := range []string{DenoiseBilateral, DenoiseNLM} {
+		t.Run(method, func(t *testing.T) {
+			result, err := Denoise(img, method, 1)
+			if err != nil {
+				t.Fatalf("Denoise failed: %v", err)
+			}
+			if result.NoiseSigma < 8 || result.ResidualSigma > result.NoiseSigma/2 || result.ImageBase64 == "" {
+				t.Errorf("got noise %v, residual %v", result.NoiseSigma, result.ResidualSigma)
+			}
+
+			// The bar's edges stay sharp
+			out, _, _ := DenoiseImage(img, method, 1)
+			above, inside := out.NRGBAAt(64, 38).R, out.NRGBAAt(64, 41).R
+			if above < 150 || inside > 50 {
+				t.Errorf("edge blurred: %d above, %d inside", above, inside)
+			}
+		})
+	}
+
+	// Without noise, nothing changes
+	clean := uiScreenshot()
+	out, sigma, err := DenoiseImage(clean, DenoiseBilateral, 1)
+	if err != nil || sigma != 0 || out.NRGBAAt(10, 10) != clean.NRGBAAt(10, 10) {
+		t.Errorf("clean: got sigma %v, %v", sigma, err)
+	}
+
+	if _, err := Denoise(img, "median", 1); err == nil {
+		t.Error("expected error for an unknown method")
+	}
+	if _, err := Denoise(img, DenoiseNLM, 0); err == nil {
+		t.Error("expected error for strength 0")
+	}
+}
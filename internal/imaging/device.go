@@ -0,0 +1,480 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// Device platforms (see DeviceChromeOptions.Platform).
+const (
+	DeviceIOS     = "ios"
+	DeviceAndroid = "android"
+)
+
+// Device chrome methods (see DeviceChromeResult.Method).
+const (
+	// DeviceScreenSize is chrome sized from the known screen of the
+	// device the screenshot's size identifies.
+	DeviceScreenSize = "screen_size"
+
+	// DeviceDetected is a status bar whose bottom edge was found at a
+	// standard height.
+	DeviceDetected = "detected"
+
+	// DeviceAssumed is chrome of the platform's default size, assumed
+	// because neither the screen nor an edge was recognized.
+	DeviceAssumed = "assumed"
+)
+
+// Navigation bar kinds (see DeviceChromeResult.NavigationKind).
+const (
+	// NavigationHomeIndicator is the area of the iOS home indicator.
+	NavigationHomeIndicator = "home_indicator"
+
+	// NavigationButtons is an Android three-button navigation bar.
+	NavigationButtons = "buttons"
+
+	// NavigationGesture is the area of the Android gesture handle.
+	NavigationGesture = "gesture"
+)
+
+// iosScreen is the screen of an iPhone or iPad model, and its safe area
+// insets in points: the status bar and home indicator in portrait, and
+// the status bar, the sides (the notch or Dynamic Island and its mirror),
+// and the home indicator in landscape, where iPhones hide the status bar.
+type iosScreen struct {
+	width, height                                   int
+	status, bottom                                  int
+	landscapeStatus, landscapeSide, landscapeBottom int
+}
+
+// iosHomeButtonScreen and iosNotchScreen are the screens assumed for iOS
+// screenshots of unknown sizes.
+var (
+	iosHomeButtonScreen = iosScreen{375, 667, 20, 0, 0, 0, 0}
+	iosNotchScreen      = iosScreen{390, 844, 47, 34, 0, 47, 21}
+)
+
+// iosScreens are the portrait screen sizes, in points, of iPhones and
+// iPads, which take screenshots at scale 2 or 3.
+var iosScreens = []iosScreen{
+	// iPhones with a home button
+	{320, 568, 20, 0, 0, 0, 0},
+	iosHomeButtonScreen,
+	{414, 736, 20, 0, 0, 0, 0},
+	// iPhones with a notch
+	{375, 812, 44, 34, 0, 44, 21},
+	{414, 896, 44, 34, 0, 44, 21},
+	iosNotchScreen,
+	{428, 926, 47, 34, 0, 47, 21},
+	// iPhones with the Dynamic Island
+	{393, 852, 54, 34, 0, 59, 21},
+	{430, 932, 54, 34, 0, 59, 21},
+	{402, 874, 62, 34, 0, 62, 21},
+	{440, 956, 62, 34, 0, 62, 21},
+	// iPads with a home button, then without
+	{768, 1024, 20, 0, 20, 0, 0},
+	{810, 1080, 20, 0, 20, 0, 0},
+	{744, 1133, 24, 20, 24, 0, 20},
+	{820, 1180, 24, 20, 24, 0, 20},
+	{834, 1194, 24, 20, 24, 0, 20},
+	{1024, 1366, 24, 20, 24, 0, 20},
+}
+
+// iosScales are the scales of iOS screenshots.
+var iosScales = []float64{2, 3}
+
+// androidDensities are the common Android display densities (pixels per
+// dp).
+var androidDensities = []float64{1, 1.5, 2, 2.625, 2.75, 3, 3.5, 4}
+
+// Android chrome sizes in dp: status bars are androidStatusHeights tall,
+// the first being the default, and navigation bars androidButtonsHeight
+// with buttons, or androidGestureHeight with a gesture handle.
+var androidStatusHeights = []int{24, 28, 32, 36, 40, 48, 52}
+
+const (
+	androidButtonsHeight = 48
+	androidGestureHeight = 24
+	androidPhoneWidth    = 411
+)
+
+// DeviceChromeOptions configures StripDeviceChrome.
+type DeviceChromeOptions struct {
+	// Platform is DeviceIOS or DeviceAndroid if known; empty to detect
+	// it: screenshots the size of an iOS screen are taken as iOS, others
+	// as Android.
+	Platform string
+
+	// Scale is the scale the screenshot was taken at (pixels per point
+	// or dp, 1 to 4); 0 to detect it.
+	Scale float64
+
+	// StatusBar removes the status bar, with the notch or Dynamic Island
+	// in it, and in landscape the sides an iPhone's notch covers.
+	StatusBar bool
+
+	// NavigationBar removes the home indicator area or navigation bar.
+	NavigationBar bool
+}
+
+// DeviceChromeResult describes the system chrome of a mobile screenshot
+// and the app content left without it.
+type DeviceChromeResult struct {
+	// Platform is DeviceIOS or DeviceAndroid: given, or detected.
+	Platform string `json:"platform"`
+
+	// Scale is the screenshot's scale (pixels per point or dp): given,
+	// from the screen size, or from the image width.
+	Scale float64 `json:"scale"`
+
+	// Orientation is "portrait" or "landscape".
+	Orientation string `json:"orientation"`
+
+	// Method is how the chrome was sized: DeviceScreenSize,
+	// DeviceDetected, or DeviceAssumed.
+	Method string `json:"method"`
+
+	// StatusBar is the status bar; nil if there is none, as on iPhones
+	// in landscape.
+	StatusBar *Region `json:"status_bar,omitempty"`
+
+	// Cutout is the notch or Dynamic Island, if the screenshot shows it
+	// as a black shape at the top center of the status bar, as device
+	// frames and photos of screens do.
+	Cutout *Region `json:"cutout,omitempty"`
+
+	// NavigationBar is the home indicator area or navigation bar; nil if
+	// there is none.
+	NavigationBar *Region `json:"navigation_bar,omitempty"`
+
+	// NavigationKind is NavigationHomeIndicator, NavigationButtons, or
+	// NavigationGesture when NavigationBar is set.
+	NavigationKind string `json:"navigation_kind,omitempty"`
+
+	// Removed holds the widths removed from each side of the image.
+	Removed Insets `json:"removed"`
+
+	// Content is the app content left: the image without the removed
+	// chrome.
+	Content Region `json:"content"`
+
+	// Offset is the top left corner of Content. Subtract it from image
+	// coordinates to get content coordinates.
+	Offset Point `json:"offset"`
+
+	// Width and Height of the content image in pixels.
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// ImageBase64 is the content encoded as base64 PNG.
+	ImageBase64 string `json:"image_base64"`
+
+	// MimeType is always "image/png".
+	MimeType string `json:"mime_type"`
+}
+
+// StripDeviceChrome removes the system chrome of an iOS or Android
+// screenshot, its status bar and home indicator or navigation bar, and
+// returns the app content, so screenshots of different devices can be
+// compared.
+//
+// Parameters:
+//   - img: The screenshot, of the whole screen.
+//   - opts: The platform and scale, if known, and the chrome to remove.
+//
+// Returns:
+//   - *DeviceChromeResult: The chrome found and the content image.
+//   - error: Non-nil if the options are invalid, the image is smaller
+//     than 64×64 pixels, or the chrome would leave no content.
+//
+// # Method
+//
+// iOS chrome has a fixed size for each screen, so an iOS screenshot the
+// size of a known iPhone or iPad screen at scale 2 or 3 has the status
+// bar and home indicator area of that screen: its safe area insets. An
+// iOS screenshot of another size is assumed to be a phone with a notch
+// if it is over twice as tall as wide, and one with a home button
+// otherwise.
+//
+// Android chrome varies by device and version. The status bar's bottom
+// edge is looked for at the standard heights (24 to 52 dp), at the
+// density nearest to the width of a 411 dp phone unless the scale is
+// given, and a 24 dp status bar is assumed if none is found. A navigation
+// bar with buttons is found by its 48 dp edge (at the right in
+// landscape), and a gesture navigation area by the handle centered in
+// the bottom 24 dp; apps drawn behind the navigation bar have neither.
+func StripDeviceChrome(img image.Image, opts DeviceChromeOptions) (*DeviceChromeResult, error) {
+	if opts.Platform != "" && opts.Platform != DeviceIOS && opts.Platform != DeviceAndroid {
+		return nil, fmt.Errorf("invalid platform: %s (use %s or %s)", opts.Platform, DeviceIOS, DeviceAndroid)
+	}
+	if opts.Scale != 0 && (opts.Scale < 1 || opts.Scale > 4) {
+		return nil, fmt.Errorf("scale must be between 1 and 4, got %g", opts.Scale)
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w < 64 || h < 64 {
+		return nil, fmt.Errorf("image too small for device chrome detection: %dx%d", w, h)
+	}
+	px := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			px.SetNRGBA(x, y, nrgbaAt(img, b.Min.X+x, b.Min.Y+y))
+		}
+	}
+
+	result := &DeviceChromeResult{Platform: opts.Platform, Scale: opts.Scale, Orientation: "portrait"}
+	landscape := w > h
+	if landscape {
+		result.Orientation = "landscape"
+	}
+	screen, scale, known := findIOSScreen(w, h, opts.Scale)
+	if result.Platform == "" {
+		result.Platform = DeviceAndroid
+		if known {
+			result.Platform = DeviceIOS
+		}
+	}
+
+	// The chrome's insets, before choosing which to remove
+	var status, nav Insets
+	full := Region{X2: w, Y2: h}
+	if result.Platform == DeviceIOS {
+		result.Method = DeviceScreenSize
+		if !known {
+			screen, scale = assumedIOSScreen(w, h, opts.Scale)
+			result.Method = DeviceAssumed
+		}
+		result.Scale = scale
+		pt := func(v int) int { return int(math.Round(float64(v) * scale)) }
+		if landscape {
+			status = Insets{Top: pt(screen.landscapeStatus), Left: pt(screen.landscapeSide), Right: pt(screen.landscapeSide)}
+			nav.Bottom = pt(screen.landscapeBottom)
+		} else {
+			status.Top = pt(screen.status)
+			nav.Bottom = pt(screen.bottom)
+		}
+		if nav.Bottom > 0 {
+			result.NavigationKind = NavigationHomeIndicator
+		}
+	} else {
+		if result.Scale == 0 {
+			result.Scale = nearestDensity(float64(min(w, h)) / androidPhoneWidth)
+		}
+		dp := func(v int) int { return int(math.Round(float64(v) * result.Scale)) }
+		status.Top, result.Method = dp(androidStatusHeights[0]), DeviceAssumed
+		if top := androidStatusBar(px, full, result.Scale); top > 0 {
+			status.Top, result.Method = top, DeviceDetected
+		}
+		if at := h - dp(androidButtonsHeight); !landscape && edgeNear(px, full, at, false) {
+			nav.Bottom, result.NavigationKind = h-at, NavigationButtons
+		} else if at := w - dp(androidButtonsHeight); landscape && edgeNear(px, full, at, true) {
+			nav.Right, result.NavigationKind = w-at, NavigationButtons
+		} else if findHandle(px, Region{X2: w, Y1: h - dp(androidGestureHeight), Y2: h}, result.Scale) {
+			nav.Bottom, result.NavigationKind = dp(androidGestureHeight), NavigationGesture
+		}
+	}
+
+	if status.Top > 0 {
+		result.StatusBar = &Region{X2: w, Y2: status.Top}
+		result.Cutout = findCutout(px, *result.StatusBar)
+	}
+	if nav != (Insets{}) {
+		bar := Region{X1: w - nav.Right, Y2: h}
+		if nav.Bottom > 0 {
+			bar = Region{Y1: h - nav.Bottom, X2: w, Y2: h}
+		}
+		result.NavigationBar = &bar
+	}
+	if opts.StatusBar {
+		result.Removed = status
+	}
+	if opts.NavigationBar {
+		result.Removed.Bottom += nav.Bottom
+		result.Removed.Right = max(result.Removed.Right, nav.Right)
+	}
+	result.Content = Region{
+		X1: result.Removed.Left, Y1: result.Removed.Top,
+		X2: w - result.Removed.Right, Y2: h - result.Removed.Bottom,
+	}
+	if result.Content.X2 <= result.Content.X1 || result.Content.Y2 <= result.Content.Y1 {
+		return nil, fmt.Errorf("device chrome leaves no content in a %dx%d image", w, h)
+	}
+	result.Offset = Point{X: result.Content.X1, Y: result.Content.Y1}
+	result.Width, result.Height = result.Content.X2-result.Content.X1, result.Content.Y2-result.Content.Y1
+
+	content := px.SubImage(image.Rect(result.Content.X1, result.Content.Y1, result.Content.X2, result.Content.Y2))
+	encoded, err := encodePNGBase64(content)
+	if err != nil {
+		return nil, err
+	}
+	result.ImageBase64, result.MimeType = encoded, "image/png"
+	return result, nil
+}
+
+// findIOSScreen returns the iOS screen a w×h screenshot shows, in either
+// orientation, at scale (at each of iosScales if 0), and its scale, and
+// false if it is the size of none.
+func findIOSScreen(w, h int, scale float64) (iosScreen, float64, bool) {
+	scales := iosScales
+	if scale != 0 {
+		scales = []float64{scale}
+	}
+	short, long := min(w, h), max(w, h)
+	for _, s := range scales {
+		for _, screen := range iosScreens {
+			if absInt(short-int(math.Round(float64(screen.width)*s))) <= 1 &&
+				absInt(long-int(math.Round(float64(screen.height)*s))) <= 1 {
+				return screen, s, true
+			}
+		}
+	}
+	return iosScreen{}, 0, false
+}
+
+// assumedIOSScreen returns the screen assumed for an iOS screenshot of
+// an unknown size: a phone with a notch if it is over twice as tall as
+// wide, and one with a home button otherwise, at scale (3 if 0 and the
+// short side is at least 1000 pixels, 2 otherwise).
+func assumedIOSScreen(w, h int, scale float64) (iosScreen, float64) {
+	short, long := min(w, h), max(w, h)
+	if scale == 0 {
+		scale = 2
+		if short >= 1000 {
+			scale = 3
+		}
+	}
+	if float64(long) > 2*float64(short) {
+		return iosNotchScreen, scale
+	}
+	return iosHomeButtonScreen, scale
+}
+
+// nearestDensity returns the density of androidDensities nearest to d.
+func nearestDensity(d float64) float64 {
+	best := androidDensities[0]
+	for _, c := range androidDensities {
+		if math.Abs(c-d) < math.Abs(best-d) {
+			best = c
+		}
+	}
+	return best
+}
+
+// androidStatusBar looks for the bottom edge of an Android status bar at
+// the standard heights at scale, and returns the first row below it; 0
+// if none is found. The lowest edge wins, as an app bar below the status
+// bar may share its color.
+func androidStatusBar(img *image.NRGBA, full Region, scale float64) int {
+	for i := len(androidStatusHeights) - 1; i >= 0; i-- {
+		at := int(math.Round(float64(androidStatusHeights[i]) * scale))
+		for y := at + 2; y >= at-2; y-- {
+			if rowEdge(img, full, y) >= chromeEdgeShare {
+				return y
+			}
+		}
+	}
+	return 0
+}
+
+// edgeNear reports whether there is an edge across the image within 2
+// pixels of row at, or of column at if vertical.
+func edgeNear(img *image.NRGBA, full Region, at int, vertical bool) bool {
+	for d := -2; d <= 2; d++ {
+		if vertical && columnEdge(img, full, at+d) >= chromeEdgeShare {
+			return true
+		}
+		if !vertical && rowEdge(img, full, at+d) >= chromeEdgeShare {
+			return true
+		}
+	}
+	return false
+}
+
+// columnEdge returns the share of the opaque pixels of column x of r
+// whose luma differs by more than chromeEdgeDiff from the pixel left of
+// it.
+func columnEdge(img *image.NRGBA, r Region, x int) float64 {
+	if x <= r.X1 || x >= r.X2 {
+		return 0
+	}
+	edges, total := 0, 0
+	for y := r.Y1; y < r.Y2; y++ {
+		left, c := img.NRGBAAt(x-1, y), img.NRGBAAt(x, y)
+		if left.A < chromeOpaque || c.A < chromeOpaque {
+			continue
+		}
+		total++
+		if absDiff(luma(left), luma(c)) > chromeEdgeDiff {
+			edges++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(edges) / float64(total)
+}
+
+// findHandle reports whether band holds a gesture handle: a bar through
+// the center column, 60 to 160 dp wide at scale and centered, that
+// differs from the band's background.
+func findHandle(img *image.NRGBA, band Region, scale float64) bool {
+	cx := (band.X1 + band.X2) / 2
+	bg := luma(img.NRGBAAt(band.X1, band.Y1))
+	differs := func(x, y int) bool { return absDiff(luma(img.NRGBAAt(x, y)), bg) > 3*chromeEdgeDiff }
+	for y := band.Y1; y < band.Y2; y++ {
+		if !differs(cx, y) {
+			continue
+		}
+		x1, x2 := cx, cx+1
+		for x1 > band.X1 && differs(x1-1, y) {
+			x1--
+		}
+		for x2 < band.X2 && differs(x2, y) {
+			x2++
+		}
+		width := float64(x2-x1) / scale
+		if width >= 60 && width <= 160 && absInt(x1+x2-2*cx) <= 4 {
+			return true
+		}
+	}
+	return false
+}
+
+// findCutout returns the black shape at the top center of the status
+// bar, the notch or Dynamic Island, if the screenshot shows one: nil
+// unless the center column has near-black pixels, spanning 15-70% of
+// the width, in the status bar.
+func findCutout(img *image.NRGBA, bar Region) *Region {
+	cx := (bar.X1 + bar.X2) / 2
+	dark := func(x, y int) bool { return luma(img.NRGBAAt(x, y)) < 24 }
+	var cutout *Region
+	for y := bar.Y1; y < bar.Y2; y++ {
+		if !dark(cx, y) {
+			if cutout != nil {
+				break
+			}
+			continue
+		}
+		x1, x2 := cx, cx+1
+		for x1 > bar.X1 && dark(x1-1, y) {
+			x1--
+		}
+		for x2 < bar.X2 && dark(x2, y) {
+			x2++
+		}
+		if cutout == nil {
+			cutout = &Region{X1: x1, Y1: y, X2: x2, Y2: y + 1}
+			continue
+		}
+		cutout.X1, cutout.X2, cutout.Y2 = min(cutout.X1, x1), max(cutout.X2, x2), y+1
+	}
+	if cutout == nil {
+		return nil
+	}
+	if share := float64(cutout.X2-cutout.X1) / float64(bar.X2-bar.X1); share < 0.15 || share > 0.7 {
+		return nil
+	}
+	return cutout
+}
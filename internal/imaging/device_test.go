@@ -0,0 +1,101 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// deviceShot draws a w×h screenshot of white app content with a status
+// bar of statusColor, status pixels tall, at the top, and a navigation
+// bar of navColor, nav pixels tall, at the bottom.
+func deviceShot(w, h, status int, statusColor color.NRGBA, nav int, navColor color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.NRGBA{255, 255, 255, 255}), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 0, w, status), image.NewUniform(statusColor), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, h-nav, w, h), image.NewUniform(navColor), image.Point{}, draw.Src)
+	return img
+}
+
+func TestStripDeviceChrome_IOS(t *testing.T) {
+	// An iPhone XR screenshot (414×896 points at scale 2) with the notch
+	// drawn, as in a device frame
+	white := color.NRGBA{255, 255, 255, 255}
+	img := deviceShot(828, 1792, 0, white, 0, white)
+	draw.Draw(img, image.Rect(205, 0, 623, 60), image.NewUniform(color.NRGBA{A: 255}), image.Point{}, draw.Src)
+
+	result, err := StripDeviceChrome(img, DeviceChromeOptions{StatusBar: true, NavigationBar: true})
+	if err != nil {
+		t.Fatalf("StripDeviceChrome failed: %v", err)
+	}
+	if result.Platform != DeviceIOS || result.Scale != 2 || result.Method != DeviceScreenSize || result.Orientation != "portrait" {
+		t.Errorf("got %s at scale %g by %s, %s; want ios at scale 2 by screen size, portrait", result.Platform, result.Scale, result.Method, result.Orientation)
+	}
+	if result.Removed != (Insets{Top: 88, Bottom: 68}) || result.Width != 828 || result.Height != 1636 || result.Offset != (Point{X: 0, Y: 88}) {
+		t.Errorf("got removed %+v, %d×%d content at %+v", result.Removed, result.Width, result.Height, result.Offset)
+	}
+	if result.NavigationKind != NavigationHomeIndicator || result.Cutout == nil || *result.Cutout != (Region{X1: 205, Y1: 0, X2: 623, Y2: 60}) {
+		t.Errorf("got navigation %q, cutout %+v", result.NavigationKind, result.Cutout)
+	}
+
+	// In landscape, the status bar is hidden and the notch's side and its
+	// mirror are removed
+	result, err = StripDeviceChrome(deviceShot(1792, 828, 0, white, 0, white), DeviceChromeOptions{StatusBar: true, NavigationBar: true})
+	if err != nil {
+		t.Fatalf("StripDeviceChrome failed: %v", err)
+	}
+	if result.Orientation != "landscape" || result.StatusBar != nil || result.Removed != (Insets{Left: 88, Right: 88, Bottom: 42}) {
+		t.Errorf("landscape: got %s, status bar %+v, removed %+v", result.Orientation, result.StatusBar, result.Removed)
+	}
+
+	// Keeping the home indicator area
+	result, err = StripDeviceChrome(img, DeviceChromeOptions{StatusBar: true})
+	if err != nil {
+		t.Fatalf("StripDeviceChrome failed: %v", err)
+	}
+	if result.Removed != (Insets{Top: 88}) || result.NavigationBar == nil {
+		t.Errorf("status bar only: got removed %+v, navigation bar %+v", result.Removed, result.NavigationBar)
+	}
+}
+
+func TestStripDeviceChrome_Android(t *testing.T) {
+	// 1080 pixels wide is a 411 dp phone at density 2.625: a 24 dp status
+	// bar is 63 pixels, and a 48 dp navigation bar 126
+	img := deviceShot(1080, 1920, 63, color.NRGBA{25, 118, 210, 255}, 126, color.NRGBA{0, 0, 0, 255})
+	result, err := StripDeviceChrome(img, DeviceChromeOptions{StatusBar: true, NavigationBar: true})
+	if err != nil {
+		t.Fatalf("StripDeviceChrome failed: %v", err)
+	}
+	if result.Platform != DeviceAndroid || result.Scale != 2.625 || result.Method != DeviceDetected || result.NavigationKind != NavigationButtons {
+		t.Errorf("got %s at scale %g by %s, navigation %q", result.Platform, result.Scale, result.Method, result.NavigationKind)
+	}
+	if result.Removed != (Insets{Top: 63, Bottom: 126}) || result.Content != (Region{X1: 0, Y1: 63, X2: 1080, Y2: 1794}) {
+		t.Errorf("got removed %+v, content %+v", result.Removed, result.Content)
+	}
+
+	// A status bar the color of the app is assumed, and a gesture handle
+	// found
+	white := color.NRGBA{255, 255, 255, 255}
+	img = deviceShot(1080, 1920, 0, white, 0, white)
+	draw.Draw(img, image.Rect(398, 1890, 682, 1900), image.NewUniform(color.NRGBA{60, 60, 60, 255}), image.Point{}, draw.Src)
+	result, err = StripDeviceChrome(img, DeviceChromeOptions{Platform: DeviceAndroid, StatusBar: true, NavigationBar: true})
+	if err != nil {
+		t.Fatalf("StripDeviceChrome failed: %v", err)
+	}
+	if result.Method != DeviceAssumed || result.NavigationKind != NavigationGesture || result.Removed != (Insets{Top: 63, Bottom: 63}) {
+		t.Errorf("got %s status bar, navigation %q, removed %+v", result.Method, result.NavigationKind, result.Removed)
+	}
+}
+
+func TestStripDeviceChrome_Errors(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 200, 400))
+	for _, opts := range []DeviceChromeOptions{{Platform: "windows"}, {Scale: 0.5}, {Scale: 5}} {
+		if _, err := StripDeviceChrome(img, opts); err == nil {
+			t.Errorf("%+v: expected an error", opts)
+		}
+	}
+	if _, err := StripDeviceChrome(image.NewNRGBA(image.Rect(0, 0, 32, 32)), DeviceChromeOptions{}); err == nil {
+		t.Error("expected an error for a tiny image")
+	}
+}
@@ -0,0 +1,836 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Display reading constants.
+const (
+	// minDisplayContrast is the smallest difference (0-255) between the
+	// mean luma of lit and unlit pixels for a display to be read.
+	minDisplayContrast = 40
+
+	// minDigitHeight is the shortest digit, in pixels, that is read.
+	minDigitHeight = 8
+
+	// segmentLit is the fraction (0-1) of a segment's zone that must be
+	// lit for the segment to count as on.
+	segmentLit = 0.2
+
+	// maxDisplaySlant is the largest slant tried for italic digits, as the
+	// horizontal shift per pixel of height (about 17 degrees).
+	maxDisplaySlant = 0.3
+)
+
+// sevenSegmentZones are the areas of a digit cell sampled for segments a
+// to g (top, upper right, lower right, bottom, lower left, upper left, and
+// middle), as fractions of the cell's width and height: x1, y1, x2, y2.
+var sevenSegmentZones = [7][4]float64{
+	{0.3, 0, 0.7, 0.2},
+	{0.65, 0.15, 1, 0.42},
+	{0.65, 0.58, 1, 0.85},
+	{0.3, 0.8, 0.7, 1},
+	{0, 0.58, 0.35, 0.85},
+	{0, 0.15, 0.35, 0.42},
+	{0.3, 0.4, 0.7, 0.6},
+}
+
+// sevenSegmentDigits maps the lit segments of a character to the
+// character, including the variants of 6, 7, and 9 with and without their
+// tails.
+var sevenSegmentDigits = map[string]string{
+	"abcdef": "0", "bc": "1", "abdeg": "2", "abcdg": "3", "bcfg": "4",
+	"acdfg": "5", "acdefg": "6", "cdefg": "6", "abc": "7", "abcf": "7",
+	"abcdefg": "8", "abcdfg": "9", "abcfg": "9", "g": "-",
+}
+
+// sevenSegmentLetters maps the lit segments of the letters seven-segment
+// displays show, in hexadecimal values and error codes, to the letter.
+// They take precedence over the digit variants they share segments with.
+var sevenSegmentLetters = map[string]string{
+	"abcefg": "A", "cdefg": "b", "adef": "C", "deg": "c", "bcdeg": "d",
+	"adefg": "E", "aefg": "F", "bcefg": "H", "def": "L", "ceg": "n",
+	"cdeg": "o", "abefg": "P", "eg": "r", "defg": "t", "bcdef": "U",
+}
+
+// dotMatrixFont is the 5x7 font of the characters read from dot-matrix
+// displays, one row per element, its high bit the leftmost dot.
+var dotMatrixFont = map[string][7]uint8{
+	"0": {0b01110, 0b10001, 0b10011, 0b10101, 0b11001, 0b10001, 0b01110},
+	"1": {0b00100, 0b01100, 0b00100, 0b00100, 0b00100, 0b00100, 0b01110},
+	"2": {0b01110, 0b10001, 0b00001, 0b00010, 0b00100, 0b01000, 0b11111},
+	"3": {0b11111, 0b00010, 0b00100, 0b00010, 0b00001, 0b10001, 0b01110},
+	"4": {0b00010, 0b00110, 0b01010, 0b10010, 0b11111, 0b00010, 0b00010},
+	"5": {0b11111, 0b10000, 0b11110, 0b00001, 0b00001, 0b10001, 0b01110},
+	"6": {0b00110, 0b01000, 0b10000, 0b11110, 0b10001, 0b10001, 0b01110},
+	"7": {0b11111, 0b00001, 0b00010, 0b00100, 0b01000, 0b01000, 0b01000},
+	"8": {0b01110, 0b10001, 0b10001, 0b01110, 0b10001, 0b10001, 0b01110},
+	"9": {0b01110, 0b10001, 0b10001, 0b01111, 0b00001, 0b00010, 0b01100},
+	"-": {0, 0, 0, 0b11111, 0, 0, 0},
+	".": {0, 0, 0, 0, 0, 0b01100, 0b01100},
+	":": {0, 0b01100, 0b01100, 0, 0b01100, 0b01100, 0},
+}
+
+// DisplayOptions configures ReadDisplay.
+type DisplayOptions struct {
+	// Region is the area of the digits, or nil for the whole image. Frame
+	// the digits inside the display's glass, leaving out its bezel, labels,
+	// and units.
+	Region *Region
+
+	// Kind is "seven_segment", "dot_matrix", or "auto" to tell them apart
+	// by their shapes: a dot-matrix display lights separate round dots.
+	Kind string
+
+	// Polarity is "light" for lit segments brighter than the background,
+	// as on LED and backlit displays, "dark" for darker ones, as on
+	// reflective LCDs, or "auto" to take the smaller class as lit.
+	Polarity string
+
+	// Letters also reads the letters seven-segment displays show, such as
+	// in hexadecimal values and error codes like "E01" or "Err".
+	Letters bool
+}
+
+// DisplayChar is one character read from a display.
+type DisplayChar struct {
+	// Char is the character, "?" if it could not be recognized, or " " for
+	// an unlit cell between characters of a dot-matrix display.
+	Char string `json:"char"`
+
+	// Bounds is the character's cell.
+	Bounds Region `json:"bounds"`
+
+	// Confidence (0-1) is how clearly the character was read: for
+	// seven-segment digits, the margin of the least certain segment; for
+	// dot-matrix characters, the margin of the best match over the next.
+	Confidence float64 `json:"confidence"`
+
+	// Segments are the lit segments of a seven-segment digit, "a" (top)
+	// to "g" (middle), clockwise from the top.
+	Segments *string `json:"segments,omitempty"`
+
+	// Dots are the rows of a dot-matrix character, "#" for a lit dot and
+	// "." for an unlit one.
+	Dots []string `json:"dots,omitempty"`
+}
+
+// DisplayResult contains the reading of a display by ReadDisplay.
+type DisplayResult struct {
+	// Region is the area that was analyzed.
+	Region Region `json:"region"`
+
+	// Kind is the kind of display read: "seven_segment" or "dot_matrix".
+	Kind string `json:"kind"`
+
+	// Polarity is "light" if lit pixels were brighter than the background,
+	// "dark" if darker. Empty if the region has too little contrast.
+	Polarity string `json:"polarity,omitempty"`
+
+	// Found is true if at least one character was read.
+	Found bool `json:"found"`
+
+	// Text is the characters read, left to right.
+	Text string `json:"text"`
+
+	// Value is Text as a number, such as -12.5, if it is one.
+	Value *float64 `json:"value,omitempty"`
+
+	// Confidence is the lowest confidence of any character; 0 if none was
+	// read.
+	Confidence float64 `json:"confidence"`
+
+	// DigitHeight is the height of the characters in pixels.
+	DigitHeight int `json:"digit_height"`
+
+	// Slant is the slant of italic seven-segment digits, in degrees
+	// clockwise from upright.
+	Slant float64 `json:"slant"`
+
+	// Characters are the characters read, left to right.
+	Characters []DisplayChar `json:"characters"`
+}
+
+// ReadDisplay reads the digits of a seven-segment or dot-matrix display,
+// such as on a photo of a multimeter, an oven, or a clock, which general
+// OCR reads poorly.
+//
+// Parameters:
+//   - img: Source image.
+//   - opts: The region of the digits, the display's kind and polarity,
+//     and whether to read letters.
+//
+// Returns:
+//   - *DisplayResult: The text and numeric value read, with each
+//     character's cell and confidence. Found is false if no character was
+//     read.
+//   - error: Non-nil if the region is invalid or the kind or polarity is
+//     unknown.
+//
+// # Method
+//
+// The region is split into lit and unlit pixels by Otsu's threshold and
+// its 8-connected components found. Seven-segment digits are straightened
+// by the slant that packs their lit columns tightest and split into cells
+// at the gaps between lit columns; a zone in each cell is sampled per
+// segment, and the lit segments give the digit. Small dots on the baseline
+// are decimal points, and stacked pairs are colons. For dot-matrix
+// displays, the centers of the dots are snapped to a grid, the grid is cut
+// into 5x7 cells spaced one or two columns apart, and each cell is matched
+// against a 5x7 font of digits, "-", ".", and ":".
+func ReadDisplay(img image.Image, opts DisplayOptions) (*DisplayResult, error) {
+	kind := opts.Kind
+	if kind == "" {
+		kind = "auto"
+	}
+	if kind != "auto" && kind != "seven_segment" && kind != "dot_matrix" {
+		return nil, fmt.Errorf("kind must be \"seven_segment\", \"dot_matrix\", or \"auto\", got %q", opts.Kind)
+	}
+	polarity := opts.Polarity
+	if polarity == "" {
+		polarity = "auto"
+	}
+	if polarity != "auto" && polarity != "light" && polarity != "dark" {
+		return nil, fmt.Errorf("polarity must be \"light\", \"dark\", or \"auto\", got %q", opts.Polarity)
+	}
+
+	b := img.Bounds()
+	r := Region{X1: 0, Y1: 0, X2: b.Dx(), Y2: b.Dy()}
+	if opts.Region != nil {
+		r = *opts.Region
+		if err := validateRegion(r, b.Dx(), b.Dy()); err != nil {
+			return nil, err
+		}
+	}
+	w, h := r.X2-r.X1, r.Y2-r.Y1
+	lum := make([]uint8, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			lum[y*w+x] = luma(nrgbaAt(img, b.Min.X+r.X1+x, b.Min.Y+r.Y1+y))
+		}
+	}
+
+	result := &DisplayResult{Region: r, Kind: kind, Characters: []DisplayChar{}}
+	lit, polarity, ok := displayMask(lum, polarity)
+	if !ok {
+		if kind == "auto" {
+			result.Kind = "seven_segment"
+		}
+		return result, nil
+	}
+	result.Polarity = polarity
+
+	// Specks of noise are dropped
+	minArea := max(3, w*h/5000)
+	var blobs []*blobStats
+	for _, s := range labelBlobs(lit, w, h) {
+		if s.area >= minArea {
+			blobs = append(blobs, s)
+			continue
+		}
+		for y := s.y1; y < s.y2; y++ {
+			for x := s.x1; x < s.x2; x++ {
+				lit[y*w+x] = false
+			}
+		}
+	}
+	if kind == "auto" {
+		kind = "seven_segment"
+		if isDotMatrix(blobs) {
+			kind = "dot_matrix"
+		}
+		result.Kind = kind
+	}
+
+	var chars []DisplayChar
+	if kind == "dot_matrix" {
+		chars, result.DigitHeight = readDotMatrix(blobs, w, h)
+	} else {
+		var slant float64
+		chars, result.DigitHeight, slant = readSevenSegment(lit, w, h, blobs, opts.Letters)
+		result.Slant = math.Round(math.Atan(slant)*180/math.Pi*10) / 10
+	}
+
+	var text strings.Builder
+	for i := range chars {
+		c := &chars[i]
+		c.Bounds.X1 += r.X1
+		c.Bounds.X2 += r.X1
+		c.Bounds.Y1 += r.Y1
+		c.Bounds.Y2 += r.Y1
+		text.WriteString(c.Char)
+		if i == 0 || c.Confidence < result.Confidence {
+			result.Confidence = c.Confidence
+		}
+	}
+	if chars != nil {
+		result.Characters = chars
+	}
+	result.Found = len(chars) > 0
+	result.Text = text.String()
+	result.Value = displayValue(result.Text)
+	return result, nil
+}
+
+// displayMask splits pixels of luma lum into lit and unlit by Otsu's
+// threshold, lit pixels being the brighter class for polarity "light",
+// the darker for "dark", or the smaller for "auto". It returns the mask,
+// the polarity found, and false if the classes differ too little.
+func displayMask(lum []uint8, polarity string) ([]bool, string, bool) {
+	t := otsuThreshold(lum)
+	var nLight int
+	var sumLight, sumDark float64
+	for _, v := range lum {
+		if int(v) >= t {
+			nLight++
+			sumLight += float64(v)
+		} else {
+			sumDark += float64(v)
+		}
+	}
+	nDark := len(lum) - nLight
+	if nLight == 0 || nDark == 0 || sumLight/float64(nLight)-sumDark/float64(nDark) < minDisplayContrast {
+		return nil, "", false
+	}
+	if polarity == "auto" {
+		polarity = "light"
+		if nLight > nDark {
+			polarity = "dark"
+		}
+	}
+	lit := make([]bool, len(lum))
+	for i, v := range lum {
+		lit[i] = (int(v) >= t) == (polarity == "light")
+	}
+	return lit, polarity, true
+}
+
+// isDotMatrix reports whether blobs look like the dots of a dot-matrix
+// display: at least 10, nearly all small and round next to the height of
+// the text they make up.
+func isDotMatrix(blobs []*blobStats) bool {
+	if len(blobs) < 10 {
+		return false
+	}
+	top, bottom := math.MaxInt, 0
+	for _, s := range blobs {
+		top, bottom = min(top, s.y1), max(bottom, s.y2)
+	}
+	limit := 0.2 * float64(bottom-top)
+	round := 0
+	for _, s := range blobs {
+		bw, bh := float64(s.x2-s.x1), float64(s.y2-s.y1)
+		if bw <= limit && bh <= limit && bw <= 2*bh && bh <= 2*bw {
+			round++
+		}
+	}
+	return float64(round) >= 0.8*float64(len(blobs))
+}
+
+// displayValue returns text as a number if it is one, such as "-12.5".
+func displayValue(text string) *float64 {
+	if !strings.ContainsAny(text, "0123456789") || strings.Trim(text, "0123456789.-") != "" {
+		return nil
+	}
+	v, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return nil
+	}
+	return &v
+}
+
+// readSevenSegment reads the seven-segment characters in the lit mask of a
+// w×h region, whose significant components are blobs. It returns the
+// characters in region coordinates, the digit height, and the slant as the
+// horizontal shift per pixel of height.
+func readSevenSegment(lit []bool, w, h int, blobs []*blobStats, letters bool) ([]DisplayChar, int, float64) {
+	top, bottom := h, 0
+	for _, s := range blobs {
+		top, bottom = min(top, s.y1), max(bottom, s.y2)
+	}
+	height := bottom - top
+	if height < minDigitHeight {
+		return nil, max(0, height), 0
+	}
+	fh := float64(height)
+
+	// Decimal points and colons are small in both directions; the digits'
+	// segments are not
+	body := make([]bool, len(lit))
+	copy(body, lit)
+	var dots []*blobStats
+	for _, s := range blobs {
+		if float64(s.x2-s.x1) <= 0.22*fh && float64(s.y2-s.y1) <= 0.22*fh {
+			dots = append(dots, s)
+			for y := s.y1; y < s.y2; y++ {
+				for x := s.x1; x < s.x2; x++ {
+					body[y*w+x] = false
+				}
+			}
+		}
+	}
+
+	// Straighten italic digits: each row shifts by slant per pixel from
+	// the middle, into a mask margin pixels wider on each side
+	slant := displaySlant(body, w, top, bottom)
+	mid := float64(top+bottom) / 2
+	margin := int(math.Ceil(maxDisplaySlant*fh/2)) + 1
+	sw := w + 2*margin
+	shift := func(y int) int { return margin + int(math.Round(slant*(float64(y)-mid))) }
+	straight := make([]bool, sw*h)
+	colLit := make([]bool, sw)
+	for y := top; y < bottom; y++ {
+		d := shift(y)
+		for x := 0; x < w; x++ {
+			if body[y*w+x] {
+				straight[y*sw+x+d] = true
+				colLit[x+d] = true
+			}
+		}
+	}
+	// bounds returns the region covered by straightened columns x1 to x2
+	// of rows y1 to y2
+	bounds := func(x1, x2, y1, y2 int) Region {
+		a, b := shift(y1), shift(y2-1)
+		return Region{
+			X1: max(0, min(x1-a, x1-b)), Y1: y1,
+			X2: min(w, max(x2-a, x2-b)), Y2: y2,
+		}
+	}
+
+	// Cells are runs of lit columns; a digit's segments may leave a
+	// sliver of a gap
+	maxGap := int(0.06 * fh)
+	var runs [][2]int
+	for x := 0; x < sw; x++ {
+		if !colLit[x] {
+			continue
+		}
+		if n := len(runs); n > 0 && x-runs[n-1][1] <= maxGap {
+			runs[n-1][1] = x + 1
+			continue
+		}
+		runs = append(runs, [2]int{x, x + 1})
+	}
+	var widths []float64
+	for _, run := range runs {
+		if rw := float64(run[1] - run[0]); rw >= 0.35*fh {
+			widths = append(widths, rw)
+		}
+	}
+	digitWidth := 0.55 * fh
+	if len(widths) > 0 {
+		digitWidth = medianFloat(widths)
+	}
+
+	type placed struct {
+		x    float64
+		char DisplayChar
+	}
+	var out []placed
+	addCell := func(x1, x2, clip1, clip2 int) {
+		f := segmentFractions(straight, sw, x1, x2, clip1, clip2, top, height)
+		char, segs, conf := classifySegments(f, letters)
+		out = append(out, placed{
+			x: float64(x1+x2) / 2,
+			char: DisplayChar{
+				Char:       char,
+				Bounds:     bounds(max(x1, clip1), min(x2, clip2), top, bottom),
+				Confidence: conf,
+				Segments:   &segs,
+			},
+		})
+	}
+	dw := int(math.Round(digitWidth))
+	for _, run := range runs {
+		rw := float64(run[1] - run[0])
+		switch {
+		case rw >= 1.5*digitWidth:
+			// Digits run together: split evenly
+			n := int(math.Round(rw / digitWidth))
+			for i := 0; i < n; i++ {
+				x1 := run[0] + int(math.Round(float64(i)*rw/float64(n)))
+				x2 := run[0] + int(math.Round(float64(i+1)*rw/float64(n)))
+				addCell(x1, x2, x1, x2)
+			}
+		case rw < 0.7*digitWidth:
+			// A narrow cell is a 1, its segments on the right of a full
+			// cell, if tall; else centered, as a minus sign
+			y1, y2 := bottom, top
+			for y := top; y < bottom; y++ {
+				for x := run[0]; x < run[1]; x++ {
+					if straight[y*sw+x] {
+						y1, y2 = min(y1, y), max(y2, y+1)
+						break
+					}
+				}
+			}
+			if float64(y2-y1) >= 0.6*fh {
+				addCell(run[1]-dw, run[1], run[0], run[1])
+			} else {
+				c := (run[0] + run[1]) / 2
+				addCell(c-dw/2, c-dw/2+dw, run[0], run[1])
+			}
+		default:
+			addCell(run[0], run[1], run[0], run[1])
+		}
+	}
+
+	// Dots on the baseline are decimal points; dots stacked in the same
+	// columns are colons
+	used := make([]bool, len(dots))
+	for i, s := range dots {
+		if used[i] {
+			continue
+		}
+		y := float64(s.y1+s.y2) / 2
+		x := float64(s.x1+s.x2)/2 + float64(shift(int(y)))
+		switch {
+		case float64(s.y2) >= float64(bottom)-0.1*fh:
+			used[i] = true
+			out = append(out, placed{x: x, char: DisplayChar{Char: ".", Bounds: Region{X1: s.x1, Y1: s.y1, X2: s.x2, Y2: s.y2}, Confidence: 1}})
+		case y < mid:
+			for j := i + 1; j < len(dots); j++ {
+				o := dots[j]
+				if used[j] || float64(o.y1+o.y2)/2 <= mid || o.x2+shift(o.y1) <= s.x1+shift(s.y1) || o.x1+shift(o.y1) >= s.x2+shift(s.y1) {
+					continue
+				}
+				used[i], used[j] = true, true
+				out = append(out, placed{x: x, char: DisplayChar{
+					Char:       ":",
+					Bounds:     Region{X1: min(s.x1, o.x1), Y1: s.y1, X2: max(s.x2, o.x2), Y2: o.y2},
+					Confidence: 1,
+				}})
+				break
+			}
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].x < out[j].x })
+	chars := make([]DisplayChar, len(out))
+	for i, p := range out {
+		chars[i] = p.char
+	}
+	return chars, height, slant
+}
+
+// displaySlant returns the slant, as the horizontal shift per pixel of
+// height, that straightens the lit pixels of rows top to bottom of a mask
+// w pixels wide: the one leaving the fewest lit columns, as upright
+// vertical segments overlap. Smaller slants win ties.
+func displaySlant(lit []bool, w, top, bottom int) float64 {
+	mid := float64(top+bottom) / 2
+	margin := int(math.Ceil(maxDisplaySlant*float64(bottom-top)/2)) + 1
+	cols := make([]bool, w+2*margin)
+	best, bestCount := 0.0, math.MaxInt
+	for k := 0; k <= 2*int(maxDisplaySlant*100); k++ {
+		// 0, -0.01, 0.01, -0.02, 0.02, ...
+		s := float64((k+1)/2) * 0.01
+		if k%2 == 1 {
+			s = -s
+		}
+		clear(cols)
+		count := 0
+		for y := top; y < bottom; y++ {
+			d := margin + int(math.Round(s*(float64(y)-mid)))
+			for x := 0; x < w; x++ {
+				if lit[y*w+x] && !cols[x+d] {
+					cols[x+d] = true
+					count++
+				}
+			}
+		}
+		if count < bestCount {
+			best, bestCount = s, count
+		}
+	}
+	return best
+}
+
+// segmentFractions returns the fraction of each segment's zone lit in the
+// digit cell spanning columns x1 to x2 and height rows from top of a mask
+// stride pixels wide. Only columns clip1 to clip2 count as lit, so a
+// narrow digit's cell does not take in its neighbor.
+func segmentFractions(lit []bool, stride, x1, x2, clip1, clip2, top, height int) [7]float64 {
+	var f [7]float64
+	cw, ch := float64(x2-x1), float64(height)
+	for i, z := range sevenSegmentZones {
+		zx1 := x1 + int(math.Round(z[0]*cw))
+		zx2 := max(zx1+1, x1+int(math.Round(z[2]*cw)))
+		zy1 := top + int(math.Round(z[1]*ch))
+		zy2 := max(zy1+1, top+int(math.Round(z[3]*ch)))
+		on, n := 0, 0
+		for y := zy1; y < zy2; y++ {
+			for x := zx1; x < zx2; x++ {
+				n++
+				if x >= clip1 && x < clip2 && x >= 0 && x < stride && lit[y*stride+x] {
+					on++
+				}
+			}
+		}
+		f[i] = float64(on) / float64(n)
+	}
+	return f
+}
+
+// classifySegments returns the character shown by segments lit to the
+// fractions f, the lit segments, and the confidence: the least margin of
+// any segment from the lit threshold, relative to it. A pattern one
+// segment from a single character reads as it at half the confidence;
+// any other reads as "?".
+func classifySegments(f [7]float64, letters bool) (string, string, float64) {
+	var segs []byte
+	conf := 1.0
+	for i, v := range f {
+		if v >= segmentLit {
+			segs = append(segs, byte('a'+i))
+		}
+		conf = math.Min(conf, math.Abs(v-segmentLit)/segmentLit)
+	}
+	conf = math.Round(conf*100) / 100
+	key := string(segs)
+
+	lookup := func(k string) (string, bool) {
+		if letters {
+			if c, ok := sevenSegmentLetters[k]; ok {
+				return c, true
+			}
+		}
+		c, ok := sevenSegmentDigits[k]
+		return c, ok
+	}
+	if c, ok := lookup(key); ok {
+		return c, key, conf
+	}
+
+	mask := func(k string) int {
+		m := 0
+		for _, c := range k {
+			m |= 1 << (c - 'a')
+		}
+		return m
+	}
+	tables := []map[string]string{sevenSegmentDigits}
+	if letters {
+		tables = append(tables, sevenSegmentLetters)
+	}
+	near := ""
+	for _, table := range tables {
+		for k := range table {
+			if bits.OnesCount(uint(mask(k)^mask(key))) != 1 {
+				continue
+			}
+			c, _ := lookup(k)
+			if near != "" && near != c {
+				return "?", key, 0
+			}
+			near = c
+		}
+	}
+	if near == "" {
+		return "?", key, 0
+	}
+	return near, key, math.Round(conf*50) / 100
+}
+
+// readDotMatrix reads the 5x7 dot-matrix characters whose dots are blobs
+// in a w×h region. It returns the characters in region coordinates and
+// the character height.
+func readDotMatrix(blobs []*blobStats, w, h int) ([]DisplayChar, int) {
+	if len(blobs) == 0 {
+		return nil, 0
+	}
+	sizes := make([]float64, len(blobs))
+	xs, ys := make([]float64, len(blobs)), make([]float64, len(blobs))
+	for i, s := range blobs {
+		sizes[i] = float64(max(s.x2-s.x1, s.y2-s.y1))
+		xs[i], ys[i] = float64(s.x1+s.x2)/2, float64(s.y1+s.y2)/2
+	}
+	dot := medianFloat(sizes)
+	cols, rows := dotPositions(xs, dot), dotPositions(ys, dot)
+	px, py := dotPitch(cols), dotPitch(rows)
+	switch {
+	case px == 0 && py == 0:
+		px, py = 1.5*dot, 1.5*dot
+	case px == 0:
+		px = py
+	case py == 0:
+		py = px
+	}
+
+	// Snap each dot to the grid; rows below the seventh, such as a cursor
+	// line, are ignored
+	lit := make(map[[2]int]bool)
+	lastCol := 0
+	for i := range blobs {
+		row := int(math.Round((ys[i] - rows[0]) / py))
+		col := int(math.Round((xs[i] - cols[0]) / px))
+		if row < 7 {
+			lit[[2]int{row, col}] = true
+			lastCol = max(lastCol, col)
+		}
+	}
+
+	// Characters are 5 columns wide with 1 or 2 unlit columns between:
+	// take the spacing and phase that leave the fewest dots between
+	// characters, then the closest matches to the font
+	var best []dotChar
+	bestGaps, bestDistance := math.MaxInt, math.MaxInt
+	for _, period := range []int{6, 7} {
+		for phase := 0; phase < period; phase++ {
+			gaps := 0
+			for k := range lit {
+				if ((k[1]-phase)%period+period)%period >= 5 {
+					gaps++
+				}
+			}
+			if gaps > bestGaps {
+				continue
+			}
+			chars, distance := dotMatrixChars(lit, lastCol, period, phase)
+			if gaps < bestGaps || distance < bestDistance {
+				best, bestGaps, bestDistance = chars, gaps, distance
+			}
+		}
+	}
+
+	out := make([]DisplayChar, len(best))
+	for i, c := range best {
+		dots := make([]string, 7)
+		for row, bits := range c.rows {
+			var line [5]byte
+			for col := range line {
+				line[col] = '.'
+				if bits&(1<<(4-col)) != 0 {
+					line[col] = '#'
+				}
+			}
+			dots[row] = string(line[:])
+		}
+		out[i] = DisplayChar{
+			Char: c.char,
+			Bounds: Region{
+				X1: max(0, int(math.Round(cols[0]+(float64(c.start)-0.5)*px))),
+				Y1: max(0, int(math.Round(rows[0]-0.5*py))),
+				X2: min(w, int(math.Round(cols[0]+(float64(c.start)+4.5)*px))),
+				Y2: min(h, int(math.Round(rows[0]+6.5*py))),
+			},
+			Confidence: c.confidence,
+			Dots:       dots,
+		}
+	}
+	return out, int(math.Round(7 * py))
+}
+
+// dotChar is a cell of a dot-matrix display starting at grid column start.
+type dotChar struct {
+	start      int
+	rows       [7]uint8
+	char       string
+	confidence float64
+}
+
+// dotMatrixChars cuts the grid columns 0 to lastCol of the lit dots into
+// 5-column cells, one every period columns starting at phase, and matches
+// each to the font. Unlit cells before the first character and after the
+// last are dropped. It returns the cells and their total distance, in
+// dots, from the characters matched.
+func dotMatrixChars(lit map[[2]int]bool, lastCol, period, phase int) ([]dotChar, int) {
+	start := phase
+	if start > 0 {
+		start -= period
+	}
+	var chars []dotChar
+	total := 0
+	for ; start <= lastCol; start += period {
+		c := dotChar{start: start}
+		blank := true
+		for row := 0; row < 7; row++ {
+			for col := 0; col < 5; col++ {
+				if lit[[2]int{row, start + col}] {
+					c.rows[row] |= 1 << (4 - col)
+					blank = false
+				}
+			}
+		}
+		if blank {
+			if len(chars) > 0 {
+				chars = append(chars, dotChar{start: start, char: " ", confidence: 1})
+			}
+			continue
+		}
+
+		best, second := math.MaxInt, math.MaxInt
+		for name, glyph := range dotMatrixFont {
+			d := 0
+			for row := range glyph {
+				d += bits.OnesCount8(glyph[row] ^ c.rows[row])
+			}
+			if d < best || d == best && name < c.char {
+				second = min(second, best)
+				best, c.char = d, name
+			} else {
+				second = min(second, d)
+			}
+		}
+		total += best
+		if best > 7 {
+			c.char = "?"
+		} else {
+			c.confidence = math.Round(float64(second-best)/float64(second)*100) / 100
+		}
+		chars = append(chars, c)
+	}
+	for len(chars) > 0 && chars[len(chars)-1].char == " " {
+		chars = chars[:len(chars)-1]
+	}
+	return chars, total
+}
+
+// dotPositions clusters the dot centers v, sorted, into rows or columns:
+// centers within half a dot of the previous join it. It returns the mean
+// of each cluster, in order.
+func dotPositions(v []float64, dot float64) []float64 {
+	sorted := append([]float64(nil), v...)
+	sort.Float64s(sorted)
+	var out []float64
+	sum, n := sorted[0], 1
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i]-sorted[i-1] <= dot/2 {
+			sum += sorted[i]
+			n++
+			continue
+		}
+		out = append(out, sum/float64(n))
+		sum, n = sorted[i], 1
+	}
+	return append(out, sum/float64(n))
+}
+
+// dotPitch returns the spacing of grid positions p: the median of the gaps
+// between consecutive positions up to 1.5 times the smallest, so gaps
+// spanning unlit rows or columns do not count. Zero for fewer than two.
+func dotPitch(p []float64) float64 {
+	if len(p) < 2 {
+		return 0
+	}
+	gaps := make([]float64, len(p)-1)
+	smallest := math.MaxFloat64
+	for i := range gaps {
+		gaps[i] = p[i+1] - p[i]
+		smallest = math.Min(smallest, gaps[i])
+	}
+	var near []float64
+	for _, g := range gaps {
+		if g <= 1.5*smallest {
+			near = append(near, g)
+		}
+	}
+	return medianFloat(near)
+}
@@ -0,0 +1,161 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// paintDigit draws the seven-segment character with lit segments segs in a
+// w×h cell at (x, y), its segments t pixels thick, slanted by shifting each
+// row slant pixels per pixel above the middle.
+func paintDigit(img *image.RGBA, x, y, w, h, t int, segs string, slant float64, c color.RGBA) {
+	zones := map[rune][4]int{
+		'a': {t + 1, 0, w - t - 1, t},
+		'b': {w - t, t + 1, w, h/2 - 1},
+		'c': {w - t, h/2 + 1, w, h - t - 1},
+		'd': {t + 1, h - t, w - t - 1, h},
+		'e': {0, h/2 + 1, t, h - t - 1},
+		'f': {0, t + 1, t, h/2 - 1},
+		'g': {t + 1, h/2 - t/2, w - t - 1, h/2 - t/2 + t},
+	}
+	for _, s := range segs {
+		paintSlanted(img, x, y, h, zones[s], slant, c)
+	}
+}
+
+// paintSlanted fills the area z of a cell at (x, y) of height h, slanted.
+func paintSlanted(img *image.RGBA, x, y, h int, z [4]int, slant float64, c color.RGBA) {
+	for v := z[1]; v < z[3]; v++ {
+		d := int(math.Round(slant * float64(h/2-v)))
+		for u := z[0]; u < z[2]; u++ {
+			img.SetRGBA(x+u+d, y+v, c)
+		}
+	}
+}
+
+func TestReadDisplay_SevenSegment(t *testing.T) {
+	// A red LED meter reading -12.50, its unlit segments faintly visible
+	img := image.NewRGBA(image.Rect(0, 0, 200, 60))
+	fillRect(img, 0, 0, 200, 60, color.RGBA{20, 20, 20, 255})
+	on, off := color.RGBA{255, 40, 40, 255}, color.RGBA{45, 22, 22, 255}
+	for i, segs := range []string{"g", "bc", "abdeg", "acdfg", "abcdef"} {
+		paintDigit(img, 10+i*34, 10, 24, 40, 5, "abcdefg", 0, off)
+		paintDigit(img, 10+i*34, 10, 24, 40, 5, segs, 0, on)
+	}
+	fillRect(img, 10+2*34+27, 45, 10+2*34+32, 50, on)
+
+	result, err := ReadDisplay(img, DisplayOptions{})
+	if err != nil {
+		t.Fatalf("ReadDisplay failed: %v", err)
+	}
+	if result.Kind != "seven_segment" || result.Polarity != "light" || result.DigitHeight != 40 || result.Slant != 0 {
+		t.Errorf("got %s, %s, height %d, slant %g", result.Kind, result.Polarity, result.DigitHeight, result.Slant)
+	}
+	if result.Text != "-12.50" || result.Value == nil || *result.Value != -12.5 || result.Confidence < 0.9 {
+		t.Fatalf("read %q (%v) at %g: %+v", result.Text, result.Value, result.Confidence, result.Characters)
+	}
+	if c := result.Characters[2]; *c.Segments != "abdeg" || c.Bounds != (Region{X1: 78, Y1: 10, X2: 102, Y2: 50}) {
+		t.Errorf("digit 2 = %+v", c)
+	}
+}
+
+func TestReadDisplay_SlantedLCD(t *testing.T) {
+	// A reflective LCD clock reading 03:47 in italics, dark on gray
+	img := image.NewRGBA(image.Rect(0, 0, 180, 70))
+	fillRect(img, 0, 0, 180, 70, color.RGBA{170, 180, 160, 255})
+	ink := color.RGBA{30, 35, 30, 255}
+	const slant = 0.15
+	for i, segs := range []string{"abcdef", "abcdg", "bcfg", "abc"} {
+		x := 15 + i*34
+		if i >= 2 {
+			x += 16
+		}
+		paintDigit(img, x, 10, 26, 50, 6, segs, slant, ink)
+	}
+	paintSlanted(img, 15+2*34-3, 10, 50, [4]int{0, 14, 6, 20}, slant, ink)
+	paintSlanted(img, 15+2*34-3, 10, 50, [4]int{0, 32, 6, 38}, slant, ink)
+
+	result, err := ReadDisplay(img, DisplayOptions{Kind: "seven_segment"})
+	if err != nil {
+		t.Fatalf("ReadDisplay failed: %v", err)
+	}
+	if result.Polarity != "dark" || result.Slant < 6 || result.Slant > 11 {
+		t.Errorf("polarity %s, slant %g", result.Polarity, result.Slant)
+	}
+	if result.Text != "03:47" || result.Value != nil {
+		t.Errorf("read %q (%v): %+v", result.Text, result.Value, result.Characters)
+	}
+}
+
+func TestReadDisplay_Letters(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 120, 60))
+	fillRect(img, 0, 0, 120, 60, color.RGBA{0, 0, 0, 255})
+	for i, segs := range []string{"adefg", "eg", "eg"} {
+		paintDigit(img, 10+i*34, 10, 24, 40, 5, segs, 0, color.RGBA{80, 255, 120, 255})
+	}
+	result, err := ReadDisplay(img, DisplayOptions{Letters: true})
+	if err != nil {
+		t.Fatalf("ReadDisplay failed: %v", err)
+	}
+	if result.Text != "Err" || result.Value != nil {
+		t.Errorf("read %q: %+v", result.Text, result.Characters)
+	}
+}
+
+func TestReadDisplay_DotMatrix(t *testing.T) {
+	// A green 5x7 display reading 12.5: 3-pixel dots 5 pixels apart, one
+	// unlit column between characters
+	img := image.NewRGBA(image.Rect(0, 0, 140, 50))
+	fillRect(img, 0, 0, 140, 50, color.RGBA{10, 20, 10, 255})
+	for i, ch := range []string{"1", "2", ".", "5"} {
+		for row, bits := range dotMatrixFont[ch] {
+			for col := 0; col < 5; col++ {
+				if bits&(1<<(4-col)) != 0 {
+					x, y := 8+(i*6+col)*5, 8+row*5
+					fillRect(img, x, y, x+3, y+3, color.RGBA{90, 255, 90, 255})
+				}
+			}
+		}
+	}
+
+	result, err := ReadDisplay(img, DisplayOptions{})
+	if err != nil {
+		t.Fatalf("ReadDisplay failed: %v", err)
+	}
+	if result.Kind != "dot_matrix" || result.DigitHeight != 35 {
+		t.Errorf("got %s, height %d", result.Kind, result.DigitHeight)
+	}
+	if result.Text != "12.5" || result.Value == nil || *result.Value != 12.5 || result.Confidence != 1 {
+		t.Fatalf("read %q (%v) at %g: %+v", result.Text, result.Value, result.Confidence, result.Characters)
+	}
+	if c := result.Characters[1]; c.Dots[6] != "#####" || c.Bounds.X1 != 37 {
+		t.Errorf("character 1 = %+v", c)
+	}
+}
+
+func TestReadDisplay_Blank(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	fillRect(img, 0, 0, 40, 20, color.RGBA{40, 40, 40, 255})
+	result, err := ReadDisplay(img, DisplayOptions{})
+	if err != nil {
+		t.Fatalf("ReadDisplay failed: %v", err)
+	}
+	if result.Found || result.Text != "" || result.Polarity != "" || len(result.Characters) != 0 {
+		t.Errorf("blank display = %+v", result)
+	}
+}
+
+func TestReadDisplay_Invalid(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for _, opts := range []DisplayOptions{
+		{Kind: "lcd"},
+		{Polarity: "bright"},
+		{Region: &Region{X1: 10, Y1: 0, X2: 50, Y2: 10}},
+	} {
+		if _, err := ReadDisplay(img, opts); err == nil {
+			t.Errorf("%+v: expected error", opts)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Edge comparison constants.
+const (
+	// compareEdgeLow and compareEdgeHigh are the Canny thresholds of the
+	// edges compared, EdgeDetect's defaults for clean images.
+	compareEdgeLow  = 50
+	compareEdgeHigh = 150
+
+	// edgeMatchDistance is the farthest, in pixels, an edge pixel may be
+	// from the other region's edges to count as matched.
+	edgeMatchDistance = 2
+)
+
+// EdgeComparison compares the edges of two regions, which, unlike their
+// pixels, stay put when fill colors change, as between a light and a dark
+// theme of the same layout.
+type EdgeComparison struct {
+	// Similarity (0-1) is the share of each region's edge pixels within 2
+	// pixels of the other's edges, the mean of the two shares. 1 if
+	// neither region has edges.
+	Similarity float64 `json:"similarity"`
+
+	// ChamferDistance is the mean distance, in pixels, from each region's
+	// edge pixels to the nearest edge of the other, averaged both ways.
+	// Nil if only one region has edges.
+	ChamferDistance *float64 `json:"chamfer_distance,omitempty"`
+
+	// Edges1 and Edges2 are the numbers of edge pixels in each region.
+	Edges1 int `json:"edges1"`
+	Edges2 int `json:"edges2"`
+}
+
+// compareEdges compares the edges of the pixels of r1 and r2 that
+// CompareRegionsAlpha compares. Each region's compared area is read as
+// alpha selects, its edges found by Canny edge detection with thresholds
+// 50 and 150, and the distance from every pixel to the nearest edge
+// estimated by a 3-4 chamfer distance transform.
+func compareEdges(img image.Image, r1, r2 Region, mask *image.Alpha, alpha AlphaOptions) *EdgeComparison {
+	w := min(r1.X2-r1.X1, r2.X2-r2.X1)
+	h := min(r1.Y2-r1.Y1, r2.Y2-r2.Y1)
+	var edges [2][]bool
+	var counts [2]int
+	for i, r := range []Region{r1, r2} {
+		read, _ := alpha.reader()
+		area := image.NewNRGBA(image.Rect(0, 0, w, h))
+		for dy := 0; dy < h; dy++ {
+			for dx := 0; dx < w; dx++ {
+				cr, cg, cb, _ := read.at(img, r.X1+dx, r.Y1+dy)
+				area.SetNRGBA(dx, dy, color.NRGBA{R: uint8(cr >> 8), G: uint8(cg >> 8), B: uint8(cb >> 8), A: 255})
+			}
+		}
+		gray, ok := acceleratedCanny(area, compareEdgeLow, compareEdgeHigh)
+		if !ok {
+			gray = cannyEdges(area, compareEdgeLow, compareEdgeHigh)
+		}
+		edges[i] = make([]bool, w*h)
+		for dy := 0; dy < h; dy++ {
+			for dx := 0; dx < w; dx++ {
+				if maskSelects(mask, dx, dy) && gray.GrayAt(dx, dy).Y != 0 {
+					edges[i][dy*w+dx] = true
+					counts[i]++
+				}
+			}
+		}
+	}
+
+	result := &EdgeComparison{Similarity: 1, Edges1: counts[0], Edges2: counts[1]}
+	switch {
+	case counts[0] == 0 && counts[1] == 0:
+		zero := 0.0
+		result.ChamferDistance = &zero
+	case counts[0] == 0 || counts[1] == 0:
+		result.Similarity = 0
+	default:
+		var distance, matched float64
+		for i := 0; i < 2; i++ {
+			other := chamferTransform(edges[1-i], w, h)
+			sum, near := 0.0, 0
+			for k, e := range edges[i] {
+				if e {
+					sum += other[k]
+					if other[k] <= edgeMatchDistance {
+						near++
+					}
+				}
+			}
+			distance += sum / float64(counts[i]) / 2
+			matched += float64(near) / float64(counts[i]) / 2
+		}
+		distance = math.Round(distance*100) / 100
+		result.Similarity = math.Round(matched*1000) / 1000
+		result.ChamferDistance = &distance
+	}
+	return result
+}
+
+// chamferTransform returns the distance, in pixels, from each of the w×h
+// pixels to the nearest of edges, estimated in two passes with steps of 3
+// across and 4 diagonally, divided by 3.
+func chamferTransform(edges []bool, w, h int) []float64 {
+	const far = math.MaxInt32 / 2
+	d := make([]int, w*h)
+	for k, e := range edges {
+		if !e {
+			d[k] = far
+		}
+	}
+	relax := func(x, y, nx, ny, step int) {
+		if nx >= 0 && ny >= 0 && nx < w && ny < h {
+			if v := d[ny*w+nx] + step; v < d[y*w+x] {
+				d[y*w+x] = v
+			}
+		}
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			relax(x, y, x-1, y, 3)
+			relax(x, y, x-1, y-1, 4)
+			relax(x, y, x, y-1, 3)
+			relax(x, y, x+1, y-1, 4)
+		}
+	}
+	for y := h - 1; y >= 0; y-- {
+		for x := w - 1; x >= 0; x-- {
+			relax(x, y, x+1, y, 3)
+			relax(x, y, x+1, y+1, 4)
+			relax(x, y, x, y+1, 3)
+			relax(x, y, x-1, y+1, 4)
+		}
+	}
+	out := make([]float64, len(d))
+	for k, v := range d {
+		out[k] = float64(v) / 3
+	}
+	return out
+}
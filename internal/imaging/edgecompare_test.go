@@ -0,0 +1,54 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// themedLayout draws a card with a header bar and two lines of text at
+// (x, y) in a 100x80 area, its bars lowered by drop pixels.
+func themedLayout(img *image.RGBA, x, y, drop int, bg, card, ink color.RGBA) {
+	fillRect(img, x, y, x+100, y+80, bg)
+	fillRect(img, x+10, y+10, x+90, y+70, card)
+	fillRect(img, x+15, y+15+drop, x+85, y+25+drop, ink)
+	fillRect(img, x+15, y+35+drop, x+70, y+40+drop, ink)
+	fillRect(img, x+15, y+48+drop, x+60, y+53+drop, ink)
+}
+
+func TestCompareRegionsMethod_Edges(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 300, 80))
+	themedLayout(img, 0, 0, 0, color.RGBA{240, 240, 240, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{30, 30, 30, 255})
+	themedLayout(img, 100, 0, 0, color.RGBA{18, 18, 18, 255}, color.RGBA{50, 50, 60, 255}, color.RGBA{230, 230, 230, 255})
+	themedLayout(img, 200, 0, 8, color.RGBA{18, 18, 18, 255}, color.RGBA{50, 50, 60, 255}, color.RGBA{230, 230, 230, 255})
+	light, dark, moved := Region{X1: 0, Y1: 0, X2: 100, Y2: 80}, Region{X1: 100, Y1: 0, X2: 200, Y2: 80}, Region{X1: 200, Y1: 0, X2: 300, Y2: 80}
+
+	result, err := CompareRegionsMethod(img, light, dark, nil, AlphaOptions{}, "edges")
+	if err != nil {
+		t.Fatalf("CompareRegionsMethod failed: %v", err)
+	}
+	e := result.Edges
+	if result.SimilarityScore > 0.1 || e == nil || e.Similarity < 0.9 || e.ChamferDistance == nil || *e.ChamferDistance > 1 {
+		t.Fatalf("themes: pixel %g, edges %+v", result.SimilarityScore, e)
+	}
+	if e.Edges1 == 0 || e.Edges2 == 0 {
+		t.Errorf("edge counts %d and %d", e.Edges1, e.Edges2)
+	}
+
+	result, _ = CompareRegionsMethod(img, light, moved, nil, AlphaOptions{}, "edges")
+	if e := result.Edges; e.Similarity > 0.7 || *e.ChamferDistance < 2 {
+		t.Errorf("moved: edges %+v, chamfer %g", e, *e.ChamferDistance)
+	}
+}
+
+func TestChamferTransform(t *testing.T) {
+	edges := make([]bool, 25)
+	edges[12] = true
+	d := chamferTransform(edges, 5, 5)
+	for k, want := range map[int]float64{12: 0, 13: 1, 14: 2, 18: 4.0 / 3, 0: 8.0 / 3} {
+		if math.Abs(d[k]-want) > 1e-9 {
+			t.Errorf("d[%d] = %g, want %g", k, d[k], want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// maxEmptyRegions limits how many regions FindEmptyRegions will return.
+const maxEmptyRegions = 50
+
+// EmptyRegion is a rectangle containing only background pixels.
+type EmptyRegion struct {
+	// Bounds is the rectangle in image coordinates.
+	Bounds Region `json:"bounds"`
+
+	// Width and Height are the rectangle dimensions in pixels.
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// AreaPercent is the rectangle's area as a percentage of the image.
+	AreaPercent float64 `json:"area_percent"`
+}
+
+// EmptyRegionsResult contains the empty rectangles found in an image.
+type EmptyRegionsResult struct {
+	// Background is the color treated as empty, as hex (#RRGGBB).
+	Background string `json:"background"`
+
+	// Regions lists non-overlapping empty rectangles, largest first.
+	Regions []EmptyRegion `json:"regions"`
+
+	// Count is the number of regions returned.
+	Count int `json:"count"`
+}
+
+// FindEmptyRegions finds the largest rectangles that contain only
+// background color, e.g. to place callouts without covering content.
+//
+// Parameters:
+//   - img: Source image.
+//   - background: Background color as hex, or "" to use the image's most
+//     common color.
+//   - tolerance: Maximum mean per-channel difference (0-255) from the
+//     background for a pixel to count as empty.
+//   - count: Maximum number of rectangles to return (1-50).
+//   - minWidth, minHeight: Smallest rectangle size worth reporting.
+//
+// Returns:
+//   - *EmptyRegionsResult: Up to count non-overlapping rectangles, largest
+//     first. May be empty if no rectangle meets the minimum size.
+//   - error: Non-nil if a parameter is out of range or the color is invalid.
+//
+// # Method
+//
+// The largest all-background rectangle is found with the maximal-rectangle
+// histogram algorithm (O(width × height)). It is then marked as used and the
+// search repeats, so later results never overlap earlier ones.
+func FindEmptyRegions(img image.Image, background string, tolerance float64, count, minWidth, minHeight int) (*EmptyRegionsResult, error) {
+	if count < 1 || count > maxEmptyRegions {
+		return nil, fmt.Errorf("count must be between 1 and %d, got %d", maxEmptyRegions, count)
+	}
+	if tolerance < 0 || tolerance > 255 {
+		return nil, fmt.Errorf("tolerance must be between 0 and 255, got %v", tolerance)
+	}
+	if minWidth < 1 {
+		minWidth = 1
+	}
+	if minHeight < 1 {
+		minHeight = 1
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	var bg color.RGBA
+	if background != "" {
+		c, err := parseHexColor(background)
+		if err != nil {
+			return nil, fmt.Errorf("invalid background color: %w", err)
+		}
+		bg = c
+	} else {
+		bg = mostCommonColor(img, b)
+	}
+
+	empty := make([]bool, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			empty[y*w+x] = pixelDiff(img.At(b.Min.X+x, b.Min.Y+y), bg) <= tolerance
+		}
+	}
+
+	result := &EmptyRegionsResult{
+		Background: fmt.Sprintf("#%02X%02X%02X", bg.R, bg.G, bg.B),
+		Regions:    []EmptyRegion{},
+	}
+	for len(result.Regions) < count {
+		r, ok := largestEmptyRect(empty, w, h, minWidth, minHeight)
+		if !ok {
+			break
+		}
+		for y := r.Y1; y < r.Y2; y++ {
+			for x := r.X1; x < r.X2; x++ {
+				empty[y*w+x] = false
+			}
+		}
+		rw, rh := r.X2-r.X1, r.Y2-r.Y1
+		result.Regions = append(result.Regions, EmptyRegion{
+			Bounds:      r,
+			Width:       rw,
+			Height:      rh,
+			AreaPercent: math.Round(float64(rw*rh)/float64(w*h)*10000) / 100,
+		})
+	}
+	result.Count = len(result.Regions)
+
+	return result, nil
+}
+
+// largestEmptyRect returns the largest-area rectangle of true cells in the
+// w×h mask that is at least minWidth×minHeight.
+func largestEmptyRect(mask []bool, w, h, minWidth, minHeight int) (Region, bool) {
+	heights := make([]int, w)
+	stack := make([]int, 0, w+1)
+	var best Region
+	bestArea := 0
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if mask[y*w+x] {
+				heights[x]++
+			} else {
+				heights[x] = 0
+			}
+		}
+
+		// Largest rectangle in histogram; each popped bar yields the maximal
+		// rectangle of its height ending at row y.
+		stack = stack[:0]
+		for x := 0; x <= w; x++ {
+			cur := 0
+			if x < w {
+				cur = heights[x]
+			}
+			for len(stack) > 0 && heights[stack[len(stack)-1]] >= cur {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				bh := heights[top]
+				left := 0
+				if len(stack) > 0 {
+					left = stack[len(stack)-1] + 1
+				}
+				bw := x - left
+				if bh >= minHeight && bw >= minWidth && bw*bh > bestArea {
+					bestArea = bw * bh
+					best = Region{X1: left, Y1: y - bh + 1, X2: x, Y2: y + 1}
+				}
+			}
+			stack = append(stack, x)
+		}
+	}
+	return best, bestArea > 0
+}
+
+// mostCommonColor returns the most frequent 8-bit RGB color within rect
+// (in the image's own coordinate space).
+func mostCommonColor(img image.Image, b image.Rectangle) color.RGBA {
+	counts := make(map[color.RGBA]int)
+	var best color.RGBA
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			c := color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(bl >> 8), 255}
+			counts[c]++
+			if counts[c] > counts[best] {
+				best = c
+			}
+		}
+	}
+	return best
+}
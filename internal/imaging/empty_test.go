@@ -0,0 +1,96 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFindEmptyRegions(t *testing.T) {
+	// 100x60 white image with a black block covering x 0-60, y 0-40
+	img := image.NewRGBA(image.Rect(0, 0, 100, 60))
+	for y := 0; y < 60; y++ {
+		for x := 0; x < 100; x++ {
+			c := color.RGBA{255, 255, 255, 255}
+			if x < 60 && y < 40 {
+				c = color.RGBA{0, 0, 0, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+
+	result, err := FindEmptyRegions(img, "", 10, 2, 1, 1)
+	if err != nil {
+		t.Fatalf("FindEmptyRegions failed: %v", err)
+	}
+
+	if result.Background != "#FFFFFF" {
+		t.Errorf("Background: got %s, want #FFFFFF", result.Background)
+	}
+	if result.Count != 2 {
+		t.Fatalf("Count: got %d, want 2", result.Count)
+	}
+
+	// Largest: the bottom strip (100x20 = 2000) vs right strip (40x60 = 2400)
+	first := result.Regions[0]
+	if first.Bounds != (Region{X1: 60, Y1: 0, X2: 100, Y2: 60}) {
+		t.Errorf("first region: got %+v, want {60 0 100 60}", first.Bounds)
+	}
+	if first.AreaPercent != 40 {
+		t.Errorf("AreaPercent: got %v, want 40", first.AreaPercent)
+	}
+
+	// Second must not overlap the first
+	second := result.Regions[1]
+	if second.Bounds != (Region{X1: 0, Y1: 40, X2: 60, Y2: 60}) {
+		t.Errorf("second region: got %+v, want {0 40 60 60}", second.Bounds)
+	}
+}
+
+func TestFindEmptyRegions_MinSize(t *testing.T) {
+	// Black image with a single 30x5 white strip
+	img := image.NewRGBA(image.Rect(0, 0, 50, 50))
+	for y := 0; y < 50; y++ {
+		for x := 0; x < 50; x++ {
+			img.Set(x, y, color.RGBA{0, 0, 0, 255})
+		}
+	}
+	for y := 10; y < 15; y++ {
+		for x := 10; x < 40; x++ {
+			img.Set(x, y, color.RGBA{255, 255, 255, 255})
+		}
+	}
+
+	result, err := FindEmptyRegions(img, "#FFFFFF", 0, 5, 1, 10)
+	if err != nil {
+		t.Fatalf("FindEmptyRegions failed: %v", err)
+	}
+	if result.Count != 0 {
+		t.Errorf("Count: got %d, want 0 (strip is shorter than min height)", result.Count)
+	}
+
+	result, err = FindEmptyRegions(img, "#FFFFFF", 0, 5, 20, 5)
+	if err != nil {
+		t.Fatalf("FindEmptyRegions failed: %v", err)
+	}
+	if result.Count != 1 || result.Regions[0].Bounds != (Region{X1: 10, Y1: 10, X2: 40, Y2: 15}) {
+		t.Errorf("regions: got %+v, want the 30x5 strip", result.Regions)
+	}
+}
+
+func TestFindEmptyRegions_Errors(t *testing.T) {
+	img := createInMemoryImage(10, 10, color.RGBA{255, 255, 255, 255})
+
+	if _, err := FindEmptyRegions(img, "", 10, 0, 1, 1); err == nil {
+		t.Error("should fail with count 0")
+	}
+	if _, err := FindEmptyRegions(img, "", 10, 51, 1, 1); err == nil {
+		t.Error("should fail with count > 50")
+	}
+	if _, err := FindEmptyRegions(img, "", -1, 5, 1, 1); err == nil {
+		t.Error("should fail with negative tolerance")
+	}
+	if _, err := FindEmptyRegions(img, "not-a-color", 10, 5, 1, 1); err == nil {
+		t.Error("should fail with invalid background color")
+	}
+}
@@ -0,0 +1,258 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"math/rand"
+)
+
+// maxEnclosedPoints limits how many points EnclosePoints accepts.
+const maxEnclosedPoints = 10000
+
+// EnclosingCircle is the smallest circle around a set of points.
+type EnclosingCircle struct {
+	Center PointF  `json:"center"`
+	Radius float64 `json:"radius"`
+}
+
+// RotatedRectangle is a rectangle at any angle.
+type RotatedRectangle struct {
+	Center PointF `json:"center"`
+
+	// Width is the long side and Height the short side, in pixels.
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+
+	// Angle is the direction of the long side, in degrees clockwise from
+	// horizontal (0-180).
+	Angle float64 `json:"angle"`
+
+	// Corners are the rectangle's corners, clockwise on screen.
+	Corners [4]PointF `json:"corners"`
+}
+
+// EnclosingShapes contains the convex hull of a set of points and the
+// smallest circle and rectangle around them.
+type EnclosingShapes struct {
+	// Points is the number of distinct points enclosed.
+	Points int `json:"points"`
+
+	// Blob is the blob enclosed, with its shape descriptors, if the points
+	// are a blob's.
+	Blob *Blob `json:"blob,omitempty"`
+
+	// Hull is the vertices of the convex hull, clockwise on screen from the
+	// leftmost, and HullArea and HullPerimeter its size.
+	Hull          []Point `json:"hull"`
+	HullArea      float64 `json:"hull_area"`
+	HullPerimeter float64 `json:"hull_perimeter"`
+
+	// Circle is the smallest circle around the points.
+	Circle EnclosingCircle `json:"circle"`
+
+	// Rectangle is the smallest-area rectangle, at any angle, around the
+	// points.
+	Rectangle RotatedRectangle `json:"rectangle"`
+}
+
+// EnclosePoints returns the convex hull of points and the smallest circle
+// and rotated rectangle around them, such as the corners of a detected
+// shape or a set of landmarks.
+//
+// Returns an error if there are no points or more than 10000.
+//
+// # Method
+//
+// The hull is Andrew's monotone chain. The circle is Welzl's, run
+// incrementally over the hull's vertices in a shuffled order, so its
+// expected time is linear. The rectangle has a side along one of the
+// hull's edges, so each edge is tried, by rotating calipers.
+func EnclosePoints(points []Point) (*EnclosingShapes, error) {
+	if len(points) == 0 || len(points) > maxEnclosedPoints {
+		return nil, fmt.Errorf("need 1 to %d points, got %d", maxEnclosedPoints, len(points))
+	}
+	pts := make([][2]float64, len(points))
+	distinct := make(map[Point]bool)
+	for i, p := range points {
+		pts[i] = [2]float64{float64(p.X), float64(p.Y)}
+		distinct[p] = true
+	}
+	shapes := enclose(pts)
+	shapes.Points = len(distinct)
+	return shapes, nil
+}
+
+// EncloseBlob is EnclosePoints for the pixels of a blob: the blob with ID
+// id (1-based) among those CountBlobs counts with opts. The shapes enclose
+// the blob's pixels whole, through their corners, so a blob filling a
+// 48×12 box has a 48×12 rectangle. Blob holds the blob and its shape
+// descriptors.
+//
+// Returns an error if the options are invalid or no blob has ID id.
+func EncloseBlob(img image.Image, opts BlobOptions, id int) (*EnclosingShapes, error) {
+	opts.Shapes = true
+	result, kept, err := countBlobs(img, opts)
+	if err != nil {
+		return nil, err
+	}
+	if id < 1 || id > len(result.Blobs) {
+		return nil, fmt.Errorf("no blob %d: %d blobs counted", id, len(result.Blobs))
+	}
+	s := kept[id-1]
+	b := img.Bounds()
+	var pts [][2]float64
+	for i, r := range s.rows {
+		y := float64(b.Min.Y + s.y1 + i)
+		for _, x := range []int{b.Min.X + r[0], b.Min.X + r[1]} {
+			pts = append(pts, [2]float64{float64(x), y}, [2]float64{float64(x), y + 1})
+		}
+	}
+	shapes := enclose(pts)
+	shapes.Points = s.area
+	shapes.Blob = &result.Blobs[id-1]
+	return shapes, nil
+}
+
+// enclose returns the shapes enclosing pts, which it reorders, but for
+// Points.
+func enclose(pts [][2]float64) *EnclosingShapes {
+	hull := convexHull(pts)
+	shapes := &EnclosingShapes{Hull: make([]Point, len(hull))}
+	area, perimeter := 0.0, 0.0
+	for i, p := range hull {
+		q := hull[(i+1)%len(hull)]
+		area += (p[0]*q[1] - q[0]*p[1]) / 2
+		perimeter += math.Hypot(q[0]-p[0], q[1]-p[1])
+		shapes.Hull[i] = Point{X: int(p[0]), Y: int(p[1])}
+	}
+	shapes.HullArea = math.Abs(area)
+	shapes.HullPerimeter = math.Round(perimeter*100) / 100
+
+	c := enclosingCircle(hull)
+	shapes.Circle = EnclosingCircle{
+		Center: PointF{X: math.Round(c.Center.X*100) / 100, Y: math.Round(c.Center.Y*100) / 100},
+		Radius: math.Round(c.Radius*100) / 100,
+	}
+	r := minAreaRect(hull, 0)
+	round := func(p PointF) PointF { return PointF{X: math.Round(p.X*100) / 100, Y: math.Round(p.Y*100) / 100} }
+	r.Center = round(r.Center)
+	r.Width, r.Height = math.Round(r.Width*100)/100, math.Round(r.Height*100)/100
+	r.Angle = math.Round(r.Angle*10) / 10
+	for i := range r.Corners {
+		r.Corners[i] = round(r.Corners[i])
+	}
+	shapes.Rectangle = r
+	return shapes
+}
+
+// enclosingCircle returns the smallest circle around pts by Welzl's
+// algorithm: each point outside the circle so far must lie on the
+// circle around it and the points before it, found by the same search
+// with it fixed. The points are taken in an order shuffled from a fixed
+// seed.
+func enclosingCircle(pts [][2]float64) EnclosingCircle {
+	pts = append([][2]float64{}, pts...)
+	rng := rand.New(rand.NewSource(1))
+	rng.Shuffle(len(pts), func(i, j int) { pts[i], pts[j] = pts[j], pts[i] })
+
+	inside := func(c EnclosingCircle, p [2]float64) bool {
+		return math.Hypot(p[0]-c.Center.X, p[1]-c.Center.Y) <= c.Radius+1e-7
+	}
+	two := func(a, b [2]float64) EnclosingCircle {
+		return EnclosingCircle{
+			Center: PointF{X: (a[0] + b[0]) / 2, Y: (a[1] + b[1]) / 2},
+			Radius: math.Hypot(a[0]-b[0], a[1]-b[1]) / 2,
+		}
+	}
+	three := func(a, b, c [2]float64) EnclosingCircle {
+		bx, by := b[0]-a[0], b[1]-a[1]
+		cx, cy := c[0]-a[0], c[1]-a[1]
+		d := 2 * (bx*cy - by*cx)
+		if math.Abs(d) < 1e-12 {
+			// Collinear: the circle across the farthest two
+			best := two(a, b)
+			for _, e := range []EnclosingCircle{two(a, c), two(b, c)} {
+				if e.Radius > best.Radius {
+					best = e
+				}
+			}
+			return best
+		}
+		ux := (cy*(bx*bx+by*by) - by*(cx*cx+cy*cy)) / d
+		uy := (bx*(cx*cx+cy*cy) - cx*(bx*bx+by*by)) / d
+		return EnclosingCircle{Center: PointF{X: a[0] + ux, Y: a[1] + uy}, Radius: math.Hypot(ux, uy)}
+	}
+
+	c := EnclosingCircle{Center: PointF{X: pts[0][0], Y: pts[0][1]}}
+	for i := 1; i < len(pts); i++ {
+		if inside(c, pts[i]) {
+			continue
+		}
+		c = EnclosingCircle{Center: PointF{X: pts[i][0], Y: pts[i][1]}}
+		for j := 0; j < i; j++ {
+			if inside(c, pts[j]) {
+				continue
+			}
+			c = two(pts[i], pts[j])
+			for k := 0; k < j; k++ {
+				if !inside(c, pts[k]) {
+					c = three(pts[i], pts[j], pts[k])
+				}
+			}
+		}
+	}
+	return c
+}
+
+// minAreaRect returns the smallest-area rectangle around the convex
+// polygon hull, its width and height grown by grow. Such a rectangle has
+// a side along one of the hull's edges.
+func minAreaRect(hull [][2]float64, grow float64) RotatedRectangle {
+	best := math.Inf(1)
+	rect := RotatedRectangle{Center: PointF{X: hull[0][0], Y: hull[0][1]}, Width: grow, Height: grow}
+	for i, p := range hull {
+		q := hull[(i+1)%len(hull)]
+		ex, ey := q[0]-p[0], q[1]-p[1]
+		length := math.Hypot(ex, ey)
+		if length == 0 {
+			continue
+		}
+		ex, ey = ex/length, ey/length
+		u1, u2, v1, v2 := math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)
+		for _, r := range hull {
+			u, v := r[0]*ex+r[1]*ey, r[1]*ex-r[0]*ey
+			u1, u2 = math.Min(u1, u), math.Max(u2, u)
+			v1, v2 = math.Min(v1, v), math.Max(v2, v)
+		}
+		w, h := u2-u1+grow, v2-v1+grow
+		if w*h >= best {
+			continue
+		}
+		best = w * h
+		// Along the edge (ex, ey) and across it (-ey, ex)
+		mu, mv := (u1+u2)/2, (v1+v2)/2
+		center := PointF{X: mu*ex - mv*ey, Y: mu*ey + mv*ex}
+		lx, ly := ex, ey
+		if h > w {
+			w, h = h, w
+			lx, ly = -ey, ex
+		}
+		angle := math.Mod(math.Atan2(ly, lx)*180/math.Pi+360, 180)
+		rect = RotatedRectangle{Center: center, Width: w, Height: h, Angle: angle}
+	}
+
+	rect.setCorners()
+	return rect
+}
+
+// setCorners sets the corners of r from its center, size, and angle: from
+// the long side's direction and the short side's, a quarter turn
+// clockwise on screen.
+func (r *RotatedRectangle) setCorners() {
+	sin, cos := math.Sincos(r.Angle * math.Pi / 180)
+	for i, s := range [4][2]float64{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}} {
+		a, b := s[0]*r.Width/2, s[1]*r.Height/2
+		r.Corners[i] = PointF{X: r.Center.X + a*cos - b*sin, Y: r.Center.Y + a*sin + b*cos}
+	}
+}
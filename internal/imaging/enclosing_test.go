@@ -0,0 +1,118 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestEnclosePoints(t *testing.T) {
+	// A square's corners, a point inside, and a repeat
+	result, err := EnclosePoints([]Point{{10, 10}, {30, 10}, {30, 30}, {10, 30}, {20, 20}, {30, 30}})
+	if err != nil {
+		t.Fatalf("EnclosePoints failed: %v", err)
+	}
+	if result.Points != 5 || result.HullArea != 400 || result.HullPerimeter != 80 {
+		t.Errorf("points %d, hull area %g, perimeter %g", result.Points, result.HullArea, result.HullPerimeter)
+	}
+	want := []Point{{10, 10}, {30, 10}, {30, 30}, {10, 30}}
+	if len(result.Hull) != 4 {
+		t.Fatalf("hull = %v, want %v", result.Hull, want)
+	}
+	for i := range want {
+		if result.Hull[i] != want[i] {
+			t.Errorf("hull = %v, want %v", result.Hull, want)
+			break
+		}
+	}
+	if c := result.Circle; c.Center != (PointF{X: 20, Y: 20}) || math.Abs(c.Radius-14.14) > 0.01 {
+		t.Errorf("circle = %+v", c)
+	}
+	if r := result.Rectangle; r.Width != 20 || r.Height != 20 || r.Center != (PointF{X: 20, Y: 20}) {
+		t.Errorf("rectangle = %+v", r)
+	}
+}
+
+func TestEnclosePoints_Turned(t *testing.T) {
+	// A 40x10 rectangle turned 30 degrees, by its corners and the points
+	// along its sides
+	sin, cos := math.Sincos(30 * math.Pi / 180)
+	var points []Point
+	for _, c := range [][2]float64{{-20, -5}, {0, -5}, {20, -5}, {20, 5}, {0, 5}, {-20, 5}} {
+		points = append(points, Point{X: int(math.Round(100 + c[0]*cos - c[1]*sin)), Y: int(math.Round(100 + c[0]*sin + c[1]*cos))})
+	}
+	result, err := EnclosePoints(points)
+	if err != nil {
+		t.Fatalf("EnclosePoints failed: %v", err)
+	}
+	r := result.Rectangle
+	if math.Abs(r.Width-40) > 1 || math.Abs(r.Height-10) > 1 || math.Abs(r.Angle-30) > 2 {
+		t.Errorf("rectangle = %+v", r)
+	}
+	if math.Abs(r.Center.X-100) > 0.5 || math.Abs(r.Center.Y-100) > 0.5 {
+		t.Errorf("rectangle center = %+v", r.Center)
+	}
+	// The circle passes through the farthest corners
+	if c := result.Circle; math.Abs(c.Radius-math.Hypot(20, 5)) > 1 {
+		t.Errorf("circle = %+v", c)
+	}
+	for _, p := range points {
+		if math.Hypot(float64(p.X)-result.Circle.Center.X, float64(p.Y)-result.Circle.Center.Y) > result.Circle.Radius+0.01 {
+			t.Errorf("%v outside circle %+v", p, result.Circle)
+		}
+	}
+}
+
+func TestEnclosePoints_Degenerate(t *testing.T) {
+	result, err := EnclosePoints([]Point{{5, 5}})
+	if err != nil {
+		t.Fatalf("EnclosePoints failed: %v", err)
+	}
+	if len(result.Hull) != 1 || result.Circle.Radius != 0 || result.Rectangle.Width != 0 {
+		t.Errorf("one point: %+v", result)
+	}
+
+	result, err = EnclosePoints([]Point{{0, 0}, {10, 0}, {5, 0}})
+	if err != nil {
+		t.Fatalf("EnclosePoints failed: %v", err)
+	}
+	if len(result.Hull) != 2 || result.Circle.Radius != 5 || result.Rectangle.Width != 10 || result.Rectangle.Height != 0 {
+		t.Errorf("collinear: %+v", result)
+	}
+
+	if _, err := EnclosePoints(nil); err == nil {
+		t.Error("expected error for no points")
+	}
+}
+
+func TestEncloseBlob(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 120, 80))
+	fillRect(img, 0, 0, 120, 80, color.RGBA{255, 255, 255, 255})
+	paintDisk(img, 30, 40, 12, color.RGBA{0, 0, 0, 255})
+	fillRect(img, 60, 30, 108, 42, color.RGBA{0, 0, 0, 255})
+
+	result, err := EncloseBlob(img, BlobOptions{}, 2)
+	if err != nil {
+		t.Fatalf("EncloseBlob failed: %v", err)
+	}
+	if result.Blob == nil || result.Blob.Shape == nil || result.Blob.Bounds.X1 != 60 {
+		t.Fatalf("blob = %+v", result.Blob)
+	}
+	// Around the bar's pixels whole
+	if r := result.Rectangle; r.Width != 48 || r.Height != 12 || r.Angle != 0 || r.Center != (PointF{X: 84, Y: 36}) {
+		t.Errorf("rectangle = %+v", r)
+	}
+
+	disc, err := EncloseBlob(img, BlobOptions{}, 1)
+	if err != nil {
+		t.Fatalf("EncloseBlob failed: %v", err)
+	}
+	if c := disc.Circle; math.Abs(c.Center.X-30.5) > 0.5 || math.Abs(c.Center.Y-40.5) > 0.5 || math.Abs(c.Radius-13) > 1 {
+		t.Errorf("disc circle = %+v", c)
+	}
+
+	if _, err := EncloseBlob(img, BlobOptions{}, 3); err == nil {
+		t.Error("expected error for blob 3 of 2")
+	}
+}
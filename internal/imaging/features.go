@@ -0,0 +1,615 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"math/bits"
+	"math/cmplx"
+	"math/rand"
+	"sort"
+)
+
+// Feature matching constants.
+const (
+	// featurePatch is the radius, in pixels of a keypoint's level, of the
+	// patch its orientation and descriptor are taken from.
+	featurePatch = 15
+
+	// featureBorder is how near a level's edges keypoints may be, so their
+	// patches, however turned, fit inside it.
+	featureBorder = featurePatch + 1
+
+	// featureLevels is the most levels of the pyramid, each featureScaleStep
+	// times smaller than the last, so regions up to 3.6 times larger or
+	// smaller match.
+	featureLevels    = 8
+	featureScaleStep = 1.2
+
+	// maxFeatureSide is the largest side, in pixels, of the first level;
+	// larger images are shrunk to it first.
+	maxFeatureSide = 1024
+
+	// fastThreshold is how much brighter or darker than a pixel, in levels
+	// (0-255), the ring around it must be to make it a corner.
+	fastThreshold = 20
+
+	// maxDescriptorDistance is the most bits of 256 two descriptors may
+	// differ by to match, and matchRatio how much closer than the second
+	// best a match must be.
+	maxDescriptorDistance = 64
+	matchRatio            = 0.8
+
+	// ransacIterations is the number of transforms tried, and
+	// ransacTolerance how near, in pixels, a transform must carry a match
+	// for the match to agree with it.
+	ransacIterations = 1000
+	ransacTolerance  = 3.0
+
+	// minFeatureInliers is the fewest matches that must agree on a
+	// transform for it to be reported.
+	minFeatureInliers = 8
+
+	// maxReportedMatches is the most inlier matches listed.
+	maxReportedMatches = 20
+)
+
+// fastCircle is the ring of 16 pixels, 3 from the center, that FAST
+// compares a pixel with, clockwise from straight up.
+var fastCircle = [16][2]int{
+	{0, -3}, {1, -3}, {2, -2}, {3, -1}, {3, 0}, {3, 1}, {2, 2}, {1, 3},
+	{0, 3}, {-1, 3}, {-2, 2}, {-3, 1}, {-3, 0}, {-3, -1}, {-2, -2}, {-1, -3},
+}
+
+// briefPattern is the 256 pairs of points, within featurePatch of a
+// keypoint, whose brightness each bit of its descriptor compares. The
+// pairs are drawn once, from a fixed seed, so descriptors are the same
+// from run to run.
+var briefPattern = func() [256][2][2]float64 {
+	var pattern [256][2][2]float64
+	rng := rand.New(rand.NewSource(1))
+	point := func() [2]float64 {
+		for {
+			x := rng.NormFloat64() * (2*featurePatch + 1) / 5
+			y := rng.NormFloat64() * (2*featurePatch + 1) / 5
+			if x*x+y*y <= featurePatch*featurePatch {
+				return [2]float64{x, y}
+			}
+		}
+	}
+	for i := range pattern {
+		pattern[i] = [2][2]float64{point(), point()}
+	}
+	return pattern
+}()
+
+// FeatureTransform is a similarity transform: a scale, a rotation, and a
+// translation, carrying reference points to target points.
+type FeatureTransform struct {
+	// Scale is how many times larger the target shows the reference.
+	Scale float64 `json:"scale"`
+
+	// Rotation is how far the target turns the reference, in degrees
+	// clockwise (-180 to 180).
+	Rotation float64 `json:"rotation"`
+
+	// TranslateX and TranslateY are where the reference's origin lands in
+	// the target.
+	TranslateX float64 `json:"translate_x"`
+	TranslateY float64 `json:"translate_y"`
+
+	// Matrix is the transform as a 2×3 affine matrix: reference (x, y)
+	// lands at target (m[0][0]·x + m[0][1]·y + m[0][2],
+	// m[1][0]·x + m[1][1]·y + m[1][2]).
+	Matrix [2][3]float64 `json:"matrix"`
+}
+
+// FeatureMatch is a keypoint of the reference matched to one of the target.
+type FeatureMatch struct {
+	Reference Point `json:"reference"`
+	Target    Point `json:"target"`
+
+	// Distance is how many of the 256 bits of their descriptors differ.
+	Distance int `json:"distance"`
+}
+
+// FeatureMatchResult contains the transform between two images estimated
+// from their matching keypoints by MatchFeatures. Coordinates are relative
+// to each image's top-left corner.
+type FeatureMatchResult struct {
+	// Found is true if enough matches agreed on a transform.
+	Found bool `json:"found"`
+
+	// Transform carries reference points to where they appear in the
+	// target. Nil if not found.
+	Transform *FeatureTransform `json:"transform,omitempty"`
+
+	// Corners are the reference's corners in the target: top-left,
+	// top-right, bottom-right, bottom-left.
+	Corners []Point `json:"corners,omitempty"`
+
+	// ReferenceKeypoints and TargetKeypoints are the numbers of keypoints
+	// found in each image.
+	ReferenceKeypoints int `json:"reference_keypoints"`
+	TargetKeypoints    int `json:"target_keypoints"`
+
+	// Matches is the number of keypoints matched, and Inliers the number
+	// of those that agree with the transform, InlierRatio their share.
+	Matches     int     `json:"matches"`
+	Inliers     int     `json:"inliers"`
+	InlierRatio float64 `json:"inlier_ratio"`
+
+	// MeanError is how far, in pixels, the transform carries the inliers
+	// from their matches on average.
+	MeanError float64 `json:"mean_error"`
+
+	// InlierMatches are up to 20 inliers, the closest descriptors first.
+	InlierMatches []FeatureMatch `json:"inlier_matches,omitempty"`
+
+	// Warnings note images with too few keypoints or matches.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// MatchFeatures estimates how a region is scaled, rotated, and moved
+// between two images by matching keypoints, so, unlike Align, it finds
+// content that has turned or changed size.
+//
+// Parameters:
+//   - reference: The image or region to find.
+//   - target: The image to find it in.
+//   - maxFeatures: Most keypoints to take from each image.
+//
+// Returns:
+//   - *FeatureMatchResult: The transform and the matches behind it. Found
+//     is false if too few matches agree; Warnings say why.
+//   - error: Non-nil if maxFeatures is not positive or an image is smaller
+//     than 33×33 pixels.
+//
+// # Method
+//
+// Like ORB, each image is shrunk to at most 1024 pixels a side and built
+// into a pyramid of up to 8 levels, each 1.2 times smaller. Keypoints are
+// FAST corners, pixels with 9 of the 16 on a ring of radius 3 around them
+// all 20 levels brighter or all darker, ranked by Harris response, the
+// strongest of each 3×3 neighborhood kept and each level's share of
+// maxFeatures taken in proportion to its area. A keypoint's orientation
+// points to the intensity centroid of the 31-pixel patch around it, and
+// its descriptor is 256 comparisons of pairs of points in the smoothed
+// patch, turned to that orientation, so descriptors survive rotation and,
+// across levels, scaling. Keypoints match the target keypoint nearest by
+// Hamming distance if each is the other's nearest, within 64 bits, and
+// closer than 0.8 of the second nearest. RANSAC then tries 1000
+// similarity transforms through random pairs of matches, keeps the one
+// that carries the most matches within 3 pixels of their partners, and
+// refits it to them by least squares.
+func MatchFeatures(reference, target image.Image, maxFeatures int) (*FeatureMatchResult, error) {
+	if maxFeatures < 1 {
+		return nil, fmt.Errorf("max features must be positive, got %d", maxFeatures)
+	}
+	for _, img := range []image.Image{reference, target} {
+		if b := img.Bounds(); b.Dx() < 2*featureBorder+1 || b.Dy() < 2*featureBorder+1 {
+			return nil, fmt.Errorf("image too small to match features: %dx%d", b.Dx(), b.Dy())
+		}
+	}
+
+	refPoints := findKeypoints(reference, maxFeatures)
+	tgtPoints := findKeypoints(target, maxFeatures)
+	result := &FeatureMatchResult{ReferenceKeypoints: len(refPoints), TargetKeypoints: len(tgtPoints)}
+	for i, n := range []int{len(refPoints), len(tgtPoints)} {
+		if n < minFeatureInliers {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("only %d keypoints in the %s; it may be too plain", n, []string{"reference", "target"}[i]))
+		}
+	}
+
+	matches := matchKeypoints(refPoints, tgtPoints)
+	result.Matches = len(matches)
+	if len(matches) < minFeatureInliers {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("only %d keypoints match", len(matches)))
+		return result, nil
+	}
+	ps := make([]complex128, len(matches))
+	qs := make([]complex128, len(matches))
+	for i, m := range matches {
+		ps[i], qs[i] = refPoints[m.ref].pos, tgtPoints[m.tgt].pos
+	}
+	tolerance := ransacTolerance * math.Max(1, tgtPoints[0].shrink)
+	model, inliers := ransacSimilarity(ps, qs, tolerance)
+	result.Inliers = len(inliers)
+	result.InlierRatio = math.Round(float64(len(inliers))/float64(len(matches))*1000) / 1000
+	if len(inliers) < minFeatureInliers {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("only %d of %d matches agree on a transform", len(inliers), len(matches)))
+		return result, nil
+	}
+
+	result.Found = true
+	a, b := real(model.z), imag(model.z)
+	tx, ty := real(model.t), imag(model.t)
+	result.Transform = &FeatureTransform{
+		Scale:      math.Round(cmplx.Abs(model.z)*1000) / 1000,
+		Rotation:   math.Round(cmplx.Phase(model.z)*180/math.Pi*10) / 10,
+		TranslateX: math.Round(tx*10) / 10,
+		TranslateY: math.Round(ty*10) / 10,
+		Matrix: [2][3]float64{
+			{math.Round(a*10000) / 10000, math.Round(-b*10000) / 10000, math.Round(tx*10) / 10},
+			{math.Round(b*10000) / 10000, math.Round(a*10000) / 10000, math.Round(ty*10) / 10},
+		},
+	}
+	rb := reference.Bounds()
+	w, h := float64(rb.Dx()), float64(rb.Dy())
+	for _, c := range []complex128{0, complex(w, 0), complex(w, h), complex(0, h)} {
+		result.Corners = append(result.Corners, complexPoint(model.apply(c)))
+	}
+
+	sum := 0.0
+	for _, i := range inliers {
+		sum += cmplx.Abs(model.apply(ps[i]) - qs[i])
+	}
+	result.MeanError = math.Round(sum/float64(len(inliers))*100) / 100
+	sort.SliceStable(inliers, func(i, j int) bool { return matches[inliers[i]].dist < matches[inliers[j]].dist })
+	for _, i := range inliers[:min(len(inliers), maxReportedMatches)] {
+		result.InlierMatches = append(result.InlierMatches, FeatureMatch{
+			Reference: complexPoint(ps[i]),
+			Target:    complexPoint(qs[i]),
+			Distance:  matches[i].dist,
+		})
+	}
+	return result, nil
+}
+
+// complexPoint returns the pixel nearest p, x + yi.
+func complexPoint(p complex128) Point {
+	return Point{X: int(math.Round(real(p))), Y: int(math.Round(imag(p)))}
+}
+
+// lumaLevel is a level of a feature pyramid: the luma of w×h pixels, each
+// scale pixels of the image wide.
+type lumaLevel struct {
+	w, h  int
+	v     []float64
+	scale float64
+}
+
+// keypoint is a corner found by findKeypoints: where it is in the image,
+// x + yi, how much the first level of the pyramid shrank the image, and
+// its descriptor.
+type keypoint struct {
+	pos    complex128
+	shrink float64
+	desc   [4]uint64
+}
+
+// findKeypoints returns up to maxFeatures keypoints of img, over the levels
+// of its pyramid, with their descriptors.
+func findKeypoints(img image.Image, maxFeatures int) []keypoint {
+	b := img.Bounds()
+	first := lumaLevel{w: b.Dx(), h: b.Dy(), v: make([]float64, b.Dx()*b.Dy()), scale: 1}
+	for y := 0; y < first.h; y++ {
+		for x := 0; x < first.w; x++ {
+			first.v[y*first.w+x] = float64(luma(nrgbaAt(img, b.Min.X+x, b.Min.Y+y)))
+		}
+	}
+	if f := float64(max(first.w, first.h)) / maxFeatureSide; f > 1 {
+		first = shrinkLuma(first, f)
+	}
+	levels := []lumaLevel{first}
+	for len(levels) < featureLevels {
+		next := shrinkLuma(levels[len(levels)-1], featureScaleStep)
+		if min(next.w, next.h) < 2*featureBorder+1 {
+			break
+		}
+		levels = append(levels, next)
+	}
+
+	// Each level's share of maxFeatures, in proportion to its area
+	area := 1 / (featureScaleStep * featureScaleStep)
+	share := float64(maxFeatures) * (1 - area) / (1 - math.Pow(area, float64(len(levels))))
+	var points []keypoint
+	for _, l := range levels {
+		quota := max(1, int(math.Round(share)))
+		share *= area
+		corners := fastCorners(l, fastThreshold)
+		if len(corners) < quota {
+			corners = fastCorners(l, fastThreshold/2)
+		}
+		sort.SliceStable(corners, func(i, j int) bool { return corners[i].score > corners[j].score })
+		corners = corners[:min(len(corners), quota)]
+
+		smooth := boxBlur3(boxBlur3(l.v, l.w, l.h), l.w, l.h)
+		for _, c := range corners {
+			points = append(points, keypoint{
+				pos:    complex((float64(c.x)+0.5)*l.scale-0.5, (float64(c.y)+0.5)*l.scale-0.5),
+				shrink: first.scale,
+				desc:   briefDescriptor(smooth, l.w, c.x, c.y, centroidAngle(l, c.x, c.y)),
+			})
+		}
+	}
+	return points
+}
+
+// shrinkLuma returns l shrunk f times, each pixel the mean of bilinear
+// samples spread over the pixels it covers.
+func shrinkLuma(l lumaLevel, f float64) lumaLevel {
+	w, h := int(float64(l.w)/f), int(float64(l.h)/f)
+	out := lumaLevel{w: w, h: h, v: make([]float64, w*h), scale: l.scale * f}
+	k := int(math.Ceil(f))
+	sample := func(sx, sy float64) float64 {
+		sx = math.Max(0, math.Min(float64(l.w-1), sx))
+		sy = math.Max(0, math.Min(float64(l.h-1), sy))
+		x0, y0 := min(int(sx), l.w-2), min(int(sy), l.h-2)
+		fx, fy := sx-float64(x0), sy-float64(y0)
+		top := l.v[y0*l.w+x0]*(1-fx) + l.v[y0*l.w+x0+1]*fx
+		bottom := l.v[(y0+1)*l.w+x0]*(1-fx) + l.v[(y0+1)*l.w+x0+1]*fx
+		return top*(1-fy) + bottom*fy
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			sum := 0.0
+			for j := 0; j < k; j++ {
+				for i := 0; i < k; i++ {
+					sum += sample((float64(x)+(float64(i)+0.5)/float64(k))*f-0.5, (float64(y)+(float64(j)+0.5)/float64(k))*f-0.5)
+				}
+			}
+			out.v[y*w+x] = sum / float64(k*k)
+		}
+	}
+	return out
+}
+
+// levelCorner is a FAST corner of a pyramid level and its Harris score.
+type levelCorner struct {
+	x, y  int
+	score float64
+}
+
+// fastCorners returns the FAST corners of l at threshold, the strongest
+// by Harris score of each 3×3 neighborhood, at least featureBorder from
+// its edges.
+func fastCorners(l lumaLevel, threshold float64) []levelCorner {
+	scores := make(map[int]float64)
+	for y := featureBorder; y < l.h-featureBorder; y++ {
+		for x := featureBorder; x < l.w-featureBorder; x++ {
+			p := l.v[y*l.w+x]
+			var ring [16]int
+			brighter, darker := 0, 0
+			for k, o := range fastCircle {
+				q := l.v[(y+o[1])*l.w+x+o[0]]
+				if q > p+threshold {
+					ring[k] = 1
+				} else if q < p-threshold {
+					ring[k] = -1
+				}
+				if k%4 == 0 && ring[k] > 0 {
+					brighter++
+				} else if k%4 == 0 && ring[k] < 0 {
+					darker++
+				}
+			}
+			// An arc of 9 takes in at least 2 of the 4 compass points
+			if (brighter >= 2 && fastArc(ring, 1)) || (darker >= 2 && fastArc(ring, -1)) {
+				scores[y*l.w+x] = harrisScore(l, x, y)
+			}
+		}
+	}
+
+	var corners []levelCorner
+	for k, s := range scores {
+		x, y := k%l.w, k/l.w
+		strongest := true
+		for dy := -1; dy <= 1 && strongest; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				n := k + dy*l.w + dx
+				if o, ok := scores[n]; ok && n != k && (o > s || (o == s && n < k)) {
+					strongest = false
+					break
+				}
+			}
+		}
+		if strongest {
+			corners = append(corners, levelCorner{x: x, y: y, score: s})
+		}
+	}
+	// Map order is random; start from a fixed one
+	sort.Slice(corners, func(i, j int) bool {
+		return corners[i].y < corners[j].y || (corners[i].y == corners[j].y && corners[i].x < corners[j].x)
+	})
+	return corners
+}
+
+// fastArc reports whether 9 or more contiguous pixels of ring are sign.
+func fastArc(ring [16]int, sign int) bool {
+	run := 0
+	for k := 0; k < 16+8; k++ {
+		if ring[k%16] != sign {
+			run = 0
+		} else if run++; run >= 9 {
+			return true
+		}
+	}
+	return false
+}
+
+// harrisScore returns the Harris corner response at (x, y) of l, from the
+// Sobel gradients of the 7×7 pixels around it.
+func harrisScore(l lumaLevel, x, y int) float64 {
+	var xx, yy, xy float64
+	for j := y - 3; j <= y+3; j++ {
+		for i := x - 3; i <= x+3; i++ {
+			at := func(dx, dy int) float64 { return l.v[(j+dy)*l.w+i+dx] }
+			gx := at(1, -1) + 2*at(1, 0) + at(1, 1) - at(-1, -1) - 2*at(-1, 0) - at(-1, 1)
+			gy := at(-1, 1) + 2*at(0, 1) + at(1, 1) - at(-1, -1) - 2*at(0, -1) - at(1, -1)
+			xx, yy, xy = xx+gx*gx, yy+gy*gy, xy+gx*gy
+		}
+	}
+	return xx*yy - xy*xy - 0.04*(xx+yy)*(xx+yy)
+}
+
+// centroidAngle returns the direction, in radians, from (x, y) to the
+// intensity centroid of the pixels of l within featurePatch of it.
+func centroidAngle(l lumaLevel, x, y int) float64 {
+	var mx, my float64
+	for dy := -featurePatch; dy <= featurePatch; dy++ {
+		for dx := -featurePatch; dx <= featurePatch; dx++ {
+			if dx*dx+dy*dy > featurePatch*featurePatch {
+				continue
+			}
+			v := l.v[(y+dy)*l.w+x+dx]
+			mx += float64(dx) * v
+			my += float64(dy) * v
+		}
+	}
+	return math.Atan2(my, mx)
+}
+
+// briefDescriptor returns the descriptor of the keypoint at (x, y) of the
+// smoothed w-wide level v: bit i is set if the first point of pair i of
+// briefPattern, turned by angle, is darker than the second.
+func briefDescriptor(v []float64, w, x, y int, angle float64) [4]uint64 {
+	sin, cos := math.Sincos(angle)
+	at := func(p [2]float64) float64 {
+		px := int(math.Round(cos*p[0] - sin*p[1]))
+		py := int(math.Round(sin*p[0] + cos*p[1]))
+		return v[(y+py)*w+x+px]
+	}
+	var desc [4]uint64
+	for i, pair := range briefPattern {
+		if at(pair[0]) < at(pair[1]) {
+			desc[i/64] |= 1 << (i % 64)
+		}
+	}
+	return desc
+}
+
+// keypointMatch is reference keypoint ref matched to target keypoint tgt,
+// dist bits apart.
+type keypointMatch struct {
+	ref, tgt, dist int
+}
+
+// matchKeypoints returns the matches between ref and tgt: pairs nearest
+// each other by Hamming distance, within maxDescriptorDistance, and
+// nearer than matchRatio of ref's second nearest.
+func matchKeypoints(ref, tgt []keypoint) []keypointMatch {
+	if len(ref) == 0 || len(tgt) == 0 {
+		return nil
+	}
+	nearestRef := make([]int, len(tgt))
+	nearestRefDist := make([]int, len(tgt))
+	for j := range nearestRefDist {
+		nearestRefDist[j] = math.MaxInt
+	}
+	type nearest struct{ tgt, dist, second int }
+	best := make([]nearest, len(ref))
+	for i, r := range ref {
+		best[i] = nearest{tgt: -1, dist: math.MaxInt, second: math.MaxInt}
+		for j, t := range tgt {
+			d := 0
+			for k := range r.desc {
+				d += bits.OnesCount64(r.desc[k] ^ t.desc[k])
+			}
+			if d < best[i].dist {
+				best[i] = nearest{tgt: j, dist: d, second: best[i].dist}
+			} else if d < best[i].second {
+				best[i].second = d
+			}
+			if d < nearestRefDist[j] {
+				nearestRef[j], nearestRefDist[j] = i, d
+			}
+		}
+	}
+
+	var matches []keypointMatch
+	for i, n := range best {
+		if n.dist > maxDescriptorDistance || nearestRef[n.tgt] != i {
+			continue
+		}
+		if n.second != math.MaxInt && float64(n.dist) >= matchRatio*float64(n.second) {
+			continue
+		}
+		matches = append(matches, keypointMatch{ref: i, tgt: n.tgt, dist: n.dist})
+	}
+	return matches
+}
+
+// similarity is the transform carrying p to z·p + t, z turning and
+// scaling it.
+type similarity struct {
+	z, t complex128
+}
+
+func (s similarity) apply(p complex128) complex128 {
+	return s.z*p + s.t
+}
+
+// ransacSimilarity returns the similarity carrying the most of ps within
+// tolerance of their partners in qs, refit to them, and the indexes of
+// those it carries there. Each of ransacIterations tries is the transform
+// through a random pair of matches, drawn from a fixed seed so results
+// repeat.
+func ransacSimilarity(ps, qs []complex128, tolerance float64) (similarity, []int) {
+	inliersOf := func(s similarity) []int {
+		var in []int
+		for i := range ps {
+			if cmplx.Abs(s.apply(ps[i])-qs[i]) <= tolerance {
+				in = append(in, i)
+			}
+		}
+		return in
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	var best similarity
+	var bestIn []int
+	for iter := 0; iter < ransacIterations; iter++ {
+		i, j := rng.Intn(len(ps)), rng.Intn(len(ps))
+		dp, dq := ps[j]-ps[i], qs[j]-qs[i]
+		if cmplx.Abs(dp) < 1 || cmplx.Abs(dq) < 1 {
+			continue
+		}
+		z := dq / dp
+		if scale := cmplx.Abs(z); scale < 1.0/16 || scale > 16 {
+			continue
+		}
+		s := similarity{z: z, t: qs[i] - z*ps[i]}
+		if in := inliersOf(s); len(in) > len(bestIn) {
+			best, bestIn = s, in
+		}
+	}
+	// Refit to the inliers, which may take in more
+	for pass := 0; pass < 2 && len(bestIn) >= 2; pass++ {
+		s, ok := fitSimilarity(ps, qs, bestIn)
+		if !ok {
+			break
+		}
+		in := inliersOf(s)
+		if len(in) < len(bestIn) {
+			break
+		}
+		best, bestIn = s, in
+	}
+	return best, bestIn
+}
+
+// fitSimilarity returns the similarity carrying the points of ps indexed
+// by in nearest, by least squares, to their partners in qs, or false if
+// the points all coincide.
+func fitSimilarity(ps, qs []complex128, in []int) (similarity, bool) {
+	var pm, qm complex128
+	for _, i := range in {
+		pm += ps[i]
+		qm += qs[i]
+	}
+	pm /= complex(float64(len(in)), 0)
+	qm /= complex(float64(len(in)), 0)
+	var num complex128
+	den := 0.0
+	for _, i := range in {
+		dp, dq := ps[i]-pm, qs[i]-qm
+		num += dq * cmplx.Conj(dp)
+		den += real(dp)*real(dp) + imag(dp)*imag(dp)
+	}
+	if den == 0 {
+		return similarity{}, false
+	}
+	z := num / complex(den, 0)
+	return similarity{z: z, t: qm - z*pm}, true
+}
@@ -0,0 +1,162 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// featureScene returns a w×h scene of overlapping rectangles and discs in
+// random grays, drawn from seed.
+func featureScene(w, h int, seed int64) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	fillRect(img, 0, 0, w, h, color.RGBA{128, 128, 128, 255})
+	rng := rand.New(rand.NewSource(seed))
+	for i := 0; i < 40; i++ {
+		c := color.RGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)), 255}
+		x, y := rng.Intn(w), rng.Intn(h)
+		size := 8 + rng.Intn(w/5)
+		if i%2 == 0 {
+			fillRect(img, x, y, min(w, x+size), min(h, y+size*2/3), c)
+			continue
+		}
+		for dy := -size / 2; dy <= size/2; dy++ {
+			for dx := -size / 2; dx <= size/2; dx++ {
+				if dx*dx+dy*dy <= size*size/4 && image.Pt(x+dx, y+dy).In(img.Rect) {
+					img.SetRGBA(x+dx, y+dy, c)
+				}
+			}
+		}
+	}
+	return img
+}
+
+// transformScene returns a w×h image of src scaled, turned clockwise by
+// degrees, and moved by (tx, ty), sampled bilinearly, gray where src does
+// not reach.
+func transformScene(src *image.RGBA, w, h int, scale, degrees, tx, ty float64) *image.RGBA {
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	sin, cos := math.Sincos(degrees * math.Pi / 180)
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			// Invert target = scale·R·p + t
+			qx, qy := float64(x)-tx, float64(y)-ty
+			px := (cos*qx + sin*qy) / scale
+			py := (-sin*qx + cos*qy) / scale
+			if px < 0 || py < 0 || px >= float64(sw-1) || py >= float64(sh-1) {
+				out.SetRGBA(x, y, color.RGBA{128, 128, 128, 255})
+				continue
+			}
+			x0, y0 := int(px), int(py)
+			fx, fy := px-float64(x0), py-float64(y0)
+			var c [3]float64
+			for k, wgt := range map[[2]int]float64{{0, 0}: (1 - fx) * (1 - fy), {1, 0}: fx * (1 - fy), {0, 1}: (1 - fx) * fy, {1, 1}: fx * fy} {
+				p := src.RGBAAt(x0+k[0], y0+k[1])
+				c[0] += float64(p.R) * wgt
+				c[1] += float64(p.G) * wgt
+				c[2] += float64(p.B) * wgt
+			}
+			out.SetRGBA(x, y, color.RGBA{uint8(math.Round(c[0])), uint8(math.Round(c[1])), uint8(math.Round(c[2])), 255})
+		}
+	}
+	return out
+}
+
+func TestMatchFeatures_Identical(t *testing.T) {
+	img := featureScene(200, 160, 1)
+	result, err := MatchFeatures(img, img, 500)
+	if err != nil {
+		t.Fatalf("MatchFeatures failed: %v", err)
+	}
+	if !result.Found {
+		t.Fatalf("not found: %+v", result)
+	}
+	tr := result.Transform
+	if tr.Scale != 1 || tr.Rotation != 0 || tr.TranslateX != 0 || tr.TranslateY != 0 {
+		t.Errorf("transform: got %+v, want identity", tr)
+	}
+	if result.Inliers != result.Matches {
+		t.Errorf("inliers: got %d of %d matches, want all", result.Inliers, result.Matches)
+	}
+}
+
+func TestMatchFeatures_RotatedScaled(t *testing.T) {
+	tests := []struct {
+		name           string
+		scale, degrees float64
+		tx, ty         float64
+	}{
+		{"rotated", 1, 30, 90, -20},
+		{"scaled", 1.5, 0, 20, 15},
+		{"shrunk and rotated", 0.7, -45, 60, 150},
+	}
+	reference := featureScene(200, 160, 2)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := transformScene(reference, 340, 300, tt.scale, tt.degrees, tt.tx, tt.ty)
+			result, err := MatchFeatures(reference, target, 500)
+			if err != nil {
+				t.Fatalf("MatchFeatures failed: %v", err)
+			}
+			if !result.Found {
+				t.Fatalf("not found: %+v", result)
+			}
+			tr := result.Transform
+			if math.Abs(tr.Scale-tt.scale) > 0.05*tt.scale {
+				t.Errorf("scale: got %v, want %v", tr.Scale, tt.scale)
+			}
+			if math.Abs(tr.Rotation-tt.degrees) > 2 {
+				t.Errorf("rotation: got %v, want %v", tr.Rotation, tt.degrees)
+			}
+			if math.Abs(tr.TranslateX-tt.tx) > 4 || math.Abs(tr.TranslateY-tt.ty) > 4 {
+				t.Errorf("translation: got (%v,%v), want (%v,%v)", tr.TranslateX, tr.TranslateY, tt.tx, tt.ty)
+			}
+			if result.Inliers < 2*minFeatureInliers {
+				t.Errorf("inliers: got %d, want >= %d", result.Inliers, 2*minFeatureInliers)
+			}
+			if len(result.Corners) != 4 || math.Abs(float64(result.Corners[0].X)-tt.tx) > 4 || math.Abs(float64(result.Corners[0].Y)-tt.ty) > 4 {
+				t.Errorf("corners: got %+v, want the first at (%v,%v)", result.Corners, tt.tx, tt.ty)
+			}
+		})
+	}
+}
+
+func TestMatchFeatures_NoMatch(t *testing.T) {
+	reference := featureScene(200, 160, 3)
+	blank := image.NewRGBA(image.Rect(0, 0, 200, 160))
+	fillRect(blank, 0, 0, 200, 160, color.RGBA{255, 255, 255, 255})
+
+	result, err := MatchFeatures(reference, blank, 500)
+	if err != nil {
+		t.Fatalf("MatchFeatures failed: %v", err)
+	}
+	if result.Found || result.Transform != nil {
+		t.Errorf("found a transform in a blank image: %+v", result.Transform)
+	}
+	if result.TargetKeypoints != 0 || len(result.Warnings) == 0 {
+		t.Errorf("want no target keypoints and warnings, got %d, %v", result.TargetKeypoints, result.Warnings)
+	}
+
+	other := featureScene(200, 160, 4)
+	result, err = MatchFeatures(reference, other, 500)
+	if err != nil {
+		t.Fatalf("MatchFeatures failed: %v", err)
+	}
+	if result.Found {
+		t.Errorf("found a transform between unrelated scenes: %+v (%d of %d matches)", result.Transform, result.Inliers, result.Matches)
+	}
+}
+
+func TestMatchFeatures_Errors(t *testing.T) {
+	img := featureScene(100, 100, 1)
+	if _, err := MatchFeatures(img, img, 0); err == nil {
+		t.Error("expected error for max features 0")
+	}
+	small := image.NewRGBA(image.Rect(0, 0, 20, 100))
+	if _, err := MatchFeatures(img, small, 500); err == nil {
+		t.Error("expected error for a 20-pixel-wide image")
+	}
+}
@@ -0,0 +1,117 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/image/tiff"
+)
+
+// fuzzMaxPixels bounds decoding during fuzzing, so headers claiming huge
+// images exercise the pixel limit instead of exhausting memory.
+const fuzzMaxPixels = 1 << 20
+
+// decoderSeeds returns small valid images in every raster format the
+// loader accepts, each also truncated to half its length.
+func decoderSeeds(f *testing.F) [][]byte {
+	f.Helper()
+	img := image.NewPaletted(image.Rect(0, 0, 8, 6), []color.Color{color.White, color.Black, color.RGBA{255, 0, 0, 255}})
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i % 3)
+	}
+	var p, j, g, tf bytes.Buffer
+	if err := png.Encode(&p, img); err != nil {
+		f.Fatal(err)
+	}
+	if err := jpeg.Encode(&j, img, nil); err != nil {
+		f.Fatal(err)
+	}
+	if err := gif.Encode(&g, img, nil); err != nil {
+		f.Fatal(err)
+	}
+	if err := tiff.Encode(&tf, img, nil); err != nil {
+		f.Fatal(err)
+	}
+	red := color.NRGBA{255, 0, 0, 255}
+	ico := buildICO([][]byte{pngBytes(f, 16, 2, red), dib24(8, red)}, []int{32, 24})
+	icns := buildICNS(map[string][]byte{"icp4": pngBytes(f, 16, 0, red)}, []string{"icp4"})
+
+	var seeds [][]byte
+	for _, s := range [][]byte{p.Bytes(), j.Bytes(), g.Bytes(), tf.Bytes(), ico, icns} {
+		seeds = append(seeds, s, s[:len(s)/2])
+	}
+	return seeds
+}
+
+func FuzzImageCacheLoad(f *testing.F) {
+	for _, s := range decoderSeeds(f) {
+		f.Add(s)
+	}
+	path := filepath.Join(f.TempDir(), "image")
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cache := NewImageCache()
+		cache.SetMaxPixels(fuzzMaxPixels)
+		img, err := cache.Load(path)
+		if err != nil {
+			return
+		}
+		b := img.Bounds()
+		if b.Dx() <= 0 || b.Dy() <= 0 || int64(b.Dx())*int64(b.Dy()) > fuzzMaxPixels {
+			t.Fatalf("Load returned an image with bounds %v", b)
+		}
+		// Every pixel of a decoded image must be readable
+		img.At(b.Min.X, b.Min.Y)
+		img.At(b.Max.X-1, b.Max.Y-1)
+	})
+}
+
+func FuzzReadIcon(f *testing.F) {
+	for _, s := range decoderSeeds(f) {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		icon, err := ReadIcon(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		for _, e := range icon.Entries {
+			b := e.Image.Bounds()
+			if b.Dx() != e.Width || b.Dy() != e.Height {
+				t.Fatalf("entry %d: size %dx%d does not match image bounds %v", e.Index, e.Width, e.Height, b)
+			}
+			if e.Width > maxIconDimension || e.Height > maxIconDimension {
+				t.Fatalf("entry %d: %dx%d exceeds the icon size limit", e.Index, e.Width, e.Height)
+			}
+		}
+		if _, err := icon.Select(0); err != nil {
+			t.Fatalf("Select(0) on a decoded icon: %v", err)
+		}
+	})
+}
+
+func FuzzParseICCProfile(f *testing.F) {
+	f.Add(rgbProfile(2, descTag("Display P3"), displayP3Colorants, srgbCurve()))
+	f.Add(rgbProfile(4, mlucTag("Adobe RGB (1998)"), adobeRGBColorants, gammaCurve(2.2)))
+	f.Add(buildICC("GRAY", 2, []iccTag{{"kTRC", gammaCurve(1.8)}}))
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// A profile either fails to parse or converts without panicking
+		if _, err := parseICCProfile(data); err != nil {
+			return
+		}
+		out, profile := applyColorProfile(img, data, false, true)
+		if profile == nil || out.Bounds() != img.Bounds() {
+			t.Fatalf("got profile %+v, bounds %v", profile, out.Bounds())
+		}
+	})
+}
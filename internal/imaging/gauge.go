@@ -0,0 +1,687 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Gauge reading constants.
+const (
+	// dialSearchSize is the largest side, in pixels, of the downsampled
+	// image searched for a dial.
+	dialSearchSize = 300
+
+	// minDialCoverage is the fraction (0-1) of a circle's circumference
+	// that must show an edge for it to be taken as the dial.
+	minDialCoverage = 0.6
+
+	// needleInk is the smallest RGB distance from the dial's face color
+	// of a needle pixel, or the largest from the needle color, if given.
+	needleInk = 80
+
+	// needleInner and needleOuter bound the radii, as fractions of the
+	// dial's radius, scanned for the needle: beyond the hub and short of
+	// the ticks and labels.
+	needleInner = 0.2
+	needleOuter = 0.65
+
+	// minNeedleScore is the smallest fraction (0-1) of a ray's samples
+	// that must be needle pixels for the needle to be found.
+	minNeedleScore = 0.5
+)
+
+// GaugeMark is a point of a gauge's scale: the value at an angle, in
+// degrees clockwise from 12 o'clock.
+type GaugeMark struct {
+	Angle float64 `json:"angle"`
+	Value float64 `json:"value"`
+}
+
+// GaugeLabel is a word read from a gauge's face, such as a scale number.
+type GaugeLabel struct {
+	Text   string `json:"text"`
+	Bounds Region `json:"bounds"`
+}
+
+// GaugeOptions configures ReadGauge.
+type GaugeOptions struct {
+	// Region is the area searched for the dial, or nil for the whole
+	// image. It is ignored when Center is set.
+	Region *Region
+
+	// Center and Radius, if Center is set, give the dial: the needle's
+	// pivot and the radius of its face. Otherwise the dial is found as the
+	// largest circle around a center.
+	Center *Point
+	Radius int
+
+	// Marks are at least two points of the scale, in order from its start,
+	// such as its minimum and maximum. Values between them are
+	// interpolated linearly by angle.
+	Marks []GaugeMark
+
+	// Counterclockwise is true for scales whose values increase
+	// counterclockwise.
+	Counterclockwise bool
+
+	// NeedleColor, if set, is the needle's color as hex (#RRGGBB);
+	// otherwise the needle is whatever differs from the face.
+	NeedleColor string
+
+	// ReadLabels returns the words within a region of the image, such as
+	// by OCR, to calibrate the scale from its printed numbers when no marks
+	// are given; nil reads no labels.
+	ReadLabels func(r Region) []GaugeLabel
+}
+
+// GaugeDial is the dial of a gauge.
+type GaugeDial struct {
+	Center Point `json:"center"`
+	Radius int   `json:"radius"`
+
+	// Detected is true if the dial was found in the image rather than
+	// given.
+	Detected bool `json:"detected"`
+}
+
+// GaugeResult contains a gauge's reading by ReadGauge.
+type GaugeResult struct {
+	Dial GaugeDial `json:"dial"`
+
+	// Found is true if a needle was found.
+	Found bool `json:"found"`
+
+	// NeedleAngle is the needle's direction in degrees clockwise from 12
+	// o'clock (0-360), and NeedleTip the end of the needle.
+	NeedleAngle float64 `json:"needle_angle"`
+	NeedleTip   Point   `json:"needle_tip"`
+
+	// NeedleConfidence (0-1) is how much more of the needle's ray than of
+	// any other direction is needle-colored.
+	NeedleConfidence float64 `json:"needle_confidence"`
+
+	// Value is the reading, if the needle was found and the scale is known.
+	Value *float64 `json:"value,omitempty"`
+
+	// Fraction is the needle's position along the scale, from 0 at its
+	// first mark to 1 at its last.
+	Fraction *float64 `json:"fraction,omitempty"`
+
+	// OutOfRange is true if the needle is outside the scale's marks, so
+	// Value is extrapolated.
+	OutOfRange bool `json:"out_of_range"`
+
+	// Calibration is "marks" for a scale given by marks, "labels" for one
+	// read from the printed numbers, or empty if the scale is unknown.
+	Calibration string `json:"calibration,omitempty"`
+
+	// Marks are the scale's points, in order from its start.
+	Marks []GaugeMark `json:"marks,omitempty"`
+
+	// Labels are the scale numbers read, in order along the scale, for a
+	// scale calibrated from labels.
+	Labels []GaugeLabel `json:"labels,omitempty"`
+
+	// Warnings note a dial or scale that could not be found.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// ReadGauge reads an analog gauge, such as a pressure gauge, a
+// thermometer, or a meter with a needle: it finds the dial, the angle of
+// its needle, and the value the needle points to on the scale.
+//
+// Parameters:
+//   - img: Source image.
+//   - opts: The dial, if known, the scale's marks or a way to read its
+//     labels, and the needle's color.
+//
+// Returns:
+//   - *GaugeResult: The dial, the needle's angle and tip, and the value
+//     read. Found is false if no needle was found, and Value is nil if the
+//     scale is unknown; Warnings say why.
+//   - error: Non-nil if the region, center, radius, marks, or needle color
+//     is invalid.
+//
+// # Method
+//
+// Unless given, the dial is found on the image downsampled to at most 300
+// pixels and blurred: each edge pixel votes for centers along its
+// gradient, the radius is the largest around the winning center at which
+// edges cover 60% of the circumference, and a circle fit to those edges
+// refines both. The face color is the median of the dial
+// between 20% and 65% of its radius, and the needle is the direction,
+// in steps of 0.5 degrees, whose ray over those radii has the most pixels
+// differing from the face by 80 or more, refined by the weighted mean of
+// its neighbors. The value is interpolated between the marks on either
+// side of the needle; past the last mark, the needle counts from whichever
+// end of the scale is closer. Labels are read within the dial and kept if
+// they are numbers beyond a third of its radius. The scale starts at the
+// label from which the most values increase along it, the others dropped
+// as misreads.
+func ReadGauge(img image.Image, opts GaugeOptions) (*GaugeResult, error) {
+	var needle *RGBColor
+	if opts.NeedleColor != "" {
+		c, err := parseHexColor(opts.NeedleColor)
+		if err != nil {
+			return nil, fmt.Errorf("invalid needle color %q: %w", opts.NeedleColor, err)
+		}
+		needle = &RGBColor{R: c.R, G: c.G, B: c.B}
+	}
+	if len(opts.Marks) == 1 {
+		return nil, fmt.Errorf("need at least 2 marks, got 1")
+	}
+	marks, err := orderMarks(opts.Marks, opts.Counterclockwise)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &GaugeResult{}
+	dial, ok, err := locateDial(img, opts.Region, opts.Center, opts.Radius)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		result.Warnings = append(result.Warnings, "no dial found; pass its center and radius")
+		return result, nil
+	}
+	result.Dial = dial
+
+	angle, tip, confidence, ok := findNeedle(img, result.Dial, needle)
+	if !ok {
+		result.Warnings = append(result.Warnings, "no needle found; pass its color as needle_color")
+	} else {
+		result.Found = true
+		result.NeedleAngle = math.Round(angle*10) / 10
+		result.NeedleTip = tip
+		result.NeedleConfidence = confidence
+	}
+
+	switch {
+	case len(marks) > 0:
+		result.Calibration = "marks"
+	case opts.ReadLabels != nil:
+		marks, result.Labels = labelMarks(result.Dial, opts.ReadLabels, opts.Counterclockwise)
+		if len(marks) < 2 {
+			result.Warnings = append(result.Warnings, "fewer than 2 scale numbers read; pass the scale's marks")
+			marks = nil
+		} else {
+			result.Calibration = "labels"
+		}
+	default:
+		result.Warnings = append(result.Warnings, "scale unknown; pass its marks to read a value")
+	}
+	result.Marks = marks
+	if result.Found && len(marks) > 0 {
+		v, f, out := gaugeValue(angle, marks, opts.Counterclockwise)
+		v, f = significant(v, 4), math.Round(f*1000)/1000
+		result.Value, result.Fraction, result.OutOfRange = &v, &f, out
+	}
+	return result, nil
+}
+
+// locateDial returns the dial with center and radius if center is set,
+// or else the one found in region of img, the whole image if nil, and
+// false if none is found.
+func locateDial(img image.Image, region *Region, center *Point, radius int) (GaugeDial, bool, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if center != nil {
+		c := *center
+		if c.X < 0 || c.Y < 0 || c.X >= w || c.Y >= h {
+			return GaugeDial{}, false, fmt.Errorf("center (%d, %d) is outside the %dx%d image", c.X, c.Y, w, h)
+		}
+		if radius < 8 {
+			return GaugeDial{}, false, fmt.Errorf("radius must be at least 8 with a center, got %d", radius)
+		}
+		return GaugeDial{Center: c, Radius: radius}, true, nil
+	}
+	r := Region{X1: 0, Y1: 0, X2: w, Y2: h}
+	if region != nil {
+		r = *region
+		if err := validateRegion(r, w, h); err != nil {
+			return GaugeDial{}, false, err
+		}
+	}
+	cx, cy, rr, ok := findDial(img, r)
+	if !ok {
+		return GaugeDial{}, false, nil
+	}
+	return GaugeDial{
+		Center:   Point{X: int(math.Round(cx)), Y: int(math.Round(cy))},
+		Radius:   int(math.Round(rr)),
+		Detected: true,
+	}, true, nil
+}
+
+// sweep returns the angle a, in degrees, past from along the direction of
+// the scale, in [0, 360).
+func sweep(from, a float64, counterclockwise bool) float64 {
+	d := a - from
+	if counterclockwise {
+		d = -d
+	}
+	return math.Mod(math.Mod(d, 360)+360, 360)
+}
+
+// orderMarks validates marks and returns them in order along the scale
+// from the first, their angles normalized to [0, 360).
+func orderMarks(marks []GaugeMark, counterclockwise bool) ([]GaugeMark, error) {
+	if len(marks) == 0 {
+		return nil, nil
+	}
+	out := make([]GaugeMark, len(marks))
+	seen := make(map[float64]bool)
+	for i, m := range marks {
+		if math.IsNaN(m.Angle) || math.IsInf(m.Angle, 0) || math.IsNaN(m.Value) || math.IsInf(m.Value, 0) {
+			return nil, fmt.Errorf("mark %d: angle and value must be finite", i)
+		}
+		a := math.Mod(math.Mod(m.Angle, 360)+360, 360)
+		if seen[a] {
+			return nil, fmt.Errorf("mark %d: two marks at angle %g", i, m.Angle)
+		}
+		seen[a] = true
+		out[i] = GaugeMark{Angle: a, Value: m.Value}
+	}
+	from := out[0].Angle
+	sort.SliceStable(out, func(i, j int) bool {
+		return sweep(from, out[i].Angle, counterclockwise) < sweep(from, out[j].Angle, counterclockwise)
+	})
+	return out, nil
+}
+
+// gaugeValue returns the value at angle on the scale of marks, ordered
+// from its start, the fraction of the scale the angle lies along, and
+// whether it lies outside the marks.
+func gaugeValue(angle float64, marks []GaugeMark, counterclockwise bool) (float64, float64, bool) {
+	from := marks[0].Angle
+	s := sweep(from, angle, counterclockwise)
+	end := sweep(from, marks[len(marks)-1].Angle, counterclockwise)
+	// Past the end, the needle is nearer one end or the other
+	if s > end && s-end > 360-s {
+		s -= 360
+	}
+	i := 0
+	for i < len(marks)-2 && s > sweep(from, marks[i+1].Angle, counterclockwise) {
+		i++
+	}
+	s0, s1 := sweep(from, marks[i].Angle, counterclockwise), sweep(from, marks[i+1].Angle, counterclockwise)
+	v := marks[i].Value + (s-s0)/(s1-s0)*(marks[i+1].Value-marks[i].Value)
+	out := s < -0.5 || s > end+0.5
+	return v, s / end, out
+}
+
+// findDial finds the largest circle of a dial in region r of img. It
+// returns the circle's center and radius in image coordinates, and false
+// if no circle is closed enough.
+func findDial(img image.Image, r Region) (cx, cy, radius float64, ok bool) {
+	b := img.Bounds()
+	f := max(1, (max(r.X2-r.X1, r.Y2-r.Y1)+dialSearchSize-1)/dialSearchSize)
+	dw, dh := (r.X2-r.X1)/f, (r.Y2-r.Y1)/f
+	if dw < 16 || dh < 16 {
+		return 0, 0, 0, false
+	}
+	lum := make([]float64, dw*dh)
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			sum := 0
+			for j := 0; j < f; j++ {
+				for i := 0; i < f; i++ {
+					sum += int(luma(nrgbaAt(img, b.Min.X+r.X1+x*f+i, b.Min.Y+r.Y1+y*f+j)))
+				}
+			}
+			lum[y*dw+x] = float64(sum) / float64(f*f)
+		}
+	}
+
+	// Sobel gradients of the blurred image, whose directions are truer;
+	// the strongest edges vote for centers along their
+	// gradient, either way, at every radius
+	type edge struct {
+		x, y   int
+		ux, uy float64
+	}
+	var edges []edge
+	lum = boxBlur3(lum, dw, dh)
+	mags := make([]float64, dw*dh)
+	gxs, gys := make([]float64, dw*dh), make([]float64, dw*dh)
+	maxMag := 0.0
+	at := func(x, y int) float64 { return lum[y*dw+x] }
+	for y := 1; y < dh-1; y++ {
+		for x := 1; x < dw-1; x++ {
+			gx := at(x+1, y-1) + 2*at(x+1, y) + at(x+1, y+1) - at(x-1, y-1) - 2*at(x-1, y) - at(x-1, y+1)
+			gy := at(x-1, y+1) + 2*at(x, y+1) + at(x+1, y+1) - at(x-1, y-1) - 2*at(x, y-1) - at(x+1, y-1)
+			i := y*dw + x
+			gxs[i], gys[i], mags[i] = gx, gy, math.Hypot(gx, gy)
+			maxMag = math.Max(maxMag, mags[i])
+		}
+	}
+	threshold := math.Max(40, 0.2*maxMag)
+	rMin := max(4, int(0.1*float64(min(dw, dh))))
+	rMax := int(0.55 * float64(min(dw, dh)))
+	acc := make([]float64, dw*dh)
+	for y := 1; y < dh-1; y++ {
+		for x := 1; x < dw-1; x++ {
+			i := y*dw + x
+			if mags[i] < threshold {
+				continue
+			}
+			e := edge{x: x, y: y, ux: gxs[i] / mags[i], uy: gys[i] / mags[i]}
+			edges = append(edges, e)
+			for rr := rMin; rr <= rMax; rr++ {
+				for _, sign := range [2]float64{-1, 1} {
+					px := int(math.Round(float64(x) + sign*float64(rr)*e.ux))
+					py := int(math.Round(float64(y) + sign*float64(rr)*e.uy))
+					if px >= 0 && py >= 0 && px < dw && py < dh {
+						acc[py*dw+px]++
+					}
+				}
+			}
+		}
+	}
+
+	// The center is the accumulator's peak, summed over 5x5 to gather
+	// votes scattered by rounding, refined to the centroid around it
+	best, bx, by := -1.0, 0, 0
+	for y := 2; y < dh-2; y++ {
+		for x := 2; x < dw-2; x++ {
+			sum := 0.0
+			for j := -2; j <= 2; j++ {
+				for i := -2; i <= 2; i++ {
+					sum += acc[(y+j)*dw+x+i]
+				}
+			}
+			if sum > best {
+				best, bx, by = sum, x, y
+			}
+		}
+	}
+	if best <= 0 {
+		return 0, 0, 0, false
+	}
+	var sx, sy float64
+	for j := -2; j <= 2; j++ {
+		for i := -2; i <= 2; i++ {
+			v := acc[(by+j)*dw+bx+i]
+			sx, sy = sx+v*float64(bx+i), sy+v*float64(by+j)
+		}
+	}
+	cx, cy = sx/best, sy/best
+
+	// The radius is the largest at which edges facing the center cover
+	// most of the circumference, in 5-degree bins
+	const bins = 72
+	cover := make([][bins]bool, rMax+2)
+	for _, e := range edges {
+		dx, dy := float64(e.x)-cx, float64(e.y)-cy
+		d := math.Hypot(dx, dy)
+		ri := int(math.Round(d))
+		if ri < rMin-1 || ri > rMax+1 || math.Abs(e.ux*dx+e.uy*dy) < 0.9*d {
+			continue
+		}
+		bin := int((math.Atan2(dy, dx)+math.Pi)/(2*math.Pi)*bins) % bins
+		cover[ri][bin] = true
+	}
+	found := -1
+	for rr := rMax; rr >= rMin && found < 0; rr-- {
+		n := 0
+		for bin := 0; bin < bins; bin++ {
+			if cover[rr-1][bin] || cover[rr][bin] || cover[rr+1][bin] {
+				n++
+			}
+		}
+		if float64(n) >= minDialCoverage*bins {
+			found = rr
+		}
+	}
+	if found < 0 {
+		return 0, 0, 0, false
+	}
+	// Refine by a circle fit to the edges at that radius
+	var xs, ys []float64
+	for _, e := range edges {
+		dx, dy := float64(e.x)-cx, float64(e.y)-cy
+		if d := math.Hypot(dx, dy); math.Abs(d-float64(found)) <= 2 && math.Abs(e.ux*dx+e.uy*dy) >= 0.9*d {
+			xs, ys = append(xs, float64(e.x)), append(ys, float64(e.y))
+		}
+	}
+	radius = float64(found)
+	if fx, fy, fr, ok := fitCircle(xs, ys); ok {
+		cx, cy, radius = fx, fy, fr
+	}
+	scale := float64(f)
+	offset := float64(f-1) / 2
+	return float64(r.X1) + cx*scale + offset, float64(r.Y1) + cy*scale + offset, radius * scale, true
+}
+
+// findNeedle finds the needle of dial, of color needle if set, as the
+// direction from the center most covered by pixels unlike the face. It
+// returns the needle's angle, its tip, the confidence, and false if no
+// direction is covered enough.
+func findNeedle(img image.Image, dial GaugeDial, needle *RGBColor) (float64, Point, float64, bool) {
+	ink, ok := dialInk(img, dial, needle)
+	if !ok {
+		return 0, Point{}, 0, false
+	}
+	radius := float64(dial.Radius)
+
+	const steps = 720
+	scores := make([]float64, steps)
+	peak := 0
+	for k := range scores {
+		a := float64(k) * 360 / steps
+		on, n := 0, 0
+		for r := needleInner * radius; r <= needleOuter*radius; r++ {
+			n++
+			if ink(a, r) {
+				on++
+			}
+		}
+		scores[k] = float64(on) / float64(n)
+		if scores[k] > scores[peak] {
+			peak = k
+		}
+	}
+	if scores[peak] < minNeedleScore {
+		return 0, Point{}, 0, false
+	}
+
+	// Refine by the weighted mean within 4 degrees, and compare with the
+	// best direction 20 degrees or more away
+	var sum, weight, second float64
+	for d := -steps / 2; d < steps/2; d++ {
+		s := scores[((peak+d)%steps+steps)%steps]
+		if abs := math.Abs(float64(d) * 360 / steps); abs <= 4 {
+			if s >= scores[peak]/2 {
+				sum += float64(d) * s
+				weight += s
+			}
+		} else if abs >= 20 {
+			second = math.Max(second, s)
+		}
+	}
+	angle := math.Mod(float64(peak)*360/steps+sum/weight*360/steps+360, 360)
+	tipR := inkReach(ink, angle, needleInner*radius, radius)
+	return angle, dialPoint(dial, angle, tipR), math.Round((scores[peak]-second)*100) / 100, true
+}
+
+// dialInk returns a test of whether the pixel at an angle, in degrees
+// clockwise from 12 o'clock, and a radius from the center of dial is ink:
+// of color ink if set, or unlike the face, the median color between 20%
+// and 65% of the dial's radius. It returns false if the dial is outside
+// the image.
+func dialInk(img image.Image, dial GaugeDial, ink *RGBColor) (func(angle, r float64) bool, bool) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	radius := float64(dial.Radius)
+	pixel := func(angle, r float64) (RGBColor, bool) {
+		p := dialPoint(dial, angle, r)
+		if p.X < 0 || p.Y < 0 || p.X >= w || p.Y >= h {
+			return RGBColor{}, false
+		}
+		c := nrgbaAt(img, b.Min.X+p.X, b.Min.Y+p.Y)
+		return RGBColor{R: c.R, G: c.G, B: c.B}, true
+	}
+	distance := func(a, c RGBColor) float64 {
+		dr, dg, db := float64(a.R)-float64(c.R), float64(a.G)-float64(c.G), float64(a.B)-float64(c.B)
+		return math.Sqrt(dr*dr + dg*dg + db*db)
+	}
+
+	// The face is most of what the scanned radii cover
+	var rs, gs, bs []float64
+	for r := needleInner * radius; r <= needleOuter*radius; r += math.Max(1, radius/40) {
+		for a := 0.0; a < 360; a += 3 {
+			if c, ok := pixel(a, r); ok {
+				rs, gs, bs = append(rs, float64(c.R)), append(gs, float64(c.G)), append(bs, float64(c.B))
+			}
+		}
+	}
+	if len(rs) == 0 {
+		return nil, false
+	}
+	face := RGBColor{
+		R: uint8(math.Round(medianFloat(rs))),
+		G: uint8(math.Round(medianFloat(gs))),
+		B: uint8(math.Round(medianFloat(bs))),
+	}
+	return func(angle, r float64) bool {
+		c, ok := pixel(angle, r)
+		if !ok {
+			return false
+		}
+		if ink != nil {
+			return distance(c, *ink) <= needleInk
+		}
+		return distance(c, face) >= needleInk
+	}, true
+}
+
+// inkReach returns the radius, up to limit, at which ink ends along the
+// ray at angle starting from radius from, across gaps of a few pixels, or
+// from if the ray starts off ink.
+func inkReach(ink func(angle, r float64) bool, angle, from, limit float64) float64 {
+	gap := math.Max(2, limit/50)
+	reach, miss := from, 0.0
+	for r := from; r <= limit; r++ {
+		if ink(angle, r) {
+			reach, miss = r, 0
+		} else if miss++; miss > gap {
+			break
+		}
+	}
+	return reach
+}
+
+// dialPoint returns the pixel at an angle, in degrees clockwise from 12
+// o'clock, and a radius from the center of dial.
+func dialPoint(dial GaugeDial, angle, r float64) Point {
+	rad := angle * math.Pi / 180
+	return Point{
+		X: int(math.Round(float64(dial.Center.X) + r*math.Sin(rad))),
+		Y: int(math.Round(float64(dial.Center.Y) - r*math.Cos(rad))),
+	}
+}
+
+// labelMarks reads the words within dial with read and returns marks for
+// the scale numbers among them, in order along the scale, and the labels
+// they came from.
+func labelMarks(dial GaugeDial, read func(Region) []GaugeLabel, counterclockwise bool) ([]GaugeMark, []GaugeLabel) {
+	c, r := dial.Center, dial.Radius
+	words := read(Region{X1: max(0, c.X-r), Y1: max(0, c.Y-r), X2: c.X + r + 1, Y2: c.Y + r + 1})
+
+	type label struct {
+		mark GaugeMark
+		word GaugeLabel
+	}
+	var labels []label
+	for _, word := range words {
+		v, err := strconv.ParseFloat(strings.TrimSpace(word.Text), 64)
+		if err != nil || math.IsInf(v, 0) || math.IsNaN(v) {
+			continue
+		}
+		dx := float64(word.Bounds.X1+word.Bounds.X2)/2 - float64(c.X)
+		dy := float64(word.Bounds.Y1+word.Bounds.Y2)/2 - float64(c.Y)
+		if d := math.Hypot(dx, dy); d < float64(r)/3 || d > 1.05*float64(r) {
+			continue
+		}
+		angle := math.Mod(math.Atan2(dx, -dy)*180/math.Pi+360, 360)
+		labels = append(labels, label{GaugeMark{Angle: math.Round(angle*10) / 10, Value: v}, word})
+	}
+	if len(labels) < 2 {
+		return nil, nil
+	}
+
+	// The scale starts at the label from which the most values increase
+	// along it, dropping misreads; of equals, the one after the widest gap
+	sort.Slice(labels, func(i, j int) bool {
+		return sweep(0, labels[i].mark.Angle, counterclockwise) < sweep(0, labels[j].mark.Angle, counterclockwise)
+	})
+	n := len(labels)
+	var keep []int
+	widest := -1.0
+	for start := 0; start < n; start++ {
+		values := make([]float64, n)
+		for k := range values {
+			values[k] = labels[(start+k)%n].mark.Value
+		}
+		run := increasingRun(values)
+		gap := sweep(labels[(start+n-1)%n].mark.Angle, labels[start].mark.Angle, counterclockwise)
+		if len(run) > len(keep) || len(run) == len(keep) && gap > widest {
+			keep, widest = run, gap
+			for k := range keep {
+				keep[k] = (start + keep[k]) % n
+			}
+		}
+	}
+	marks := make([]GaugeMark, len(keep))
+	used := make([]GaugeLabel, len(keep))
+	for k, i := range keep {
+		marks[k], used[k] = labels[i].mark, labels[i].word
+	}
+	return marks, used
+}
+
+// increasingRun returns the indices of the longest strictly increasing
+// subsequence of values, the first such if there are several.
+func increasingRun(values []float64) []int {
+	length, prev := make([]int, len(values)), make([]int, len(values))
+	best := 0
+	for i := range values {
+		length[i], prev[i] = 1, -1
+		for j := 0; j < i; j++ {
+			if values[j] < values[i] && length[j]+1 > length[i] {
+				length[i], prev[i] = length[j]+1, j
+			}
+		}
+		if length[i] > length[best] {
+			best = i
+		}
+	}
+	run := make([]int, length[best])
+	for i, k := best, len(run)-1; i >= 0; i, k = prev[i], k-1 {
+		run[k] = i
+	}
+	return run
+}
+
+// boxBlur3 returns the w×h values v averaged over 3x3 neighborhoods,
+// clipped at the edges.
+func boxBlur3(v []float64, w, h int) []float64 {
+	out := make([]float64, len(v))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			sum, n := 0.0, 0
+			for j := max(0, y-1); j <= min(h-1, y+1); j++ {
+				for i := max(0, x-1); i <= min(w-1, x+1); i++ {
+					sum += v[j*w+i]
+					n++
+				}
+			}
+			out[y*w+x] = sum / float64(n)
+		}
+	}
+	return out
+}
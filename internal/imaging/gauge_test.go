@@ -0,0 +1,136 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// gaugeFace draws a gauge on gray: a white face of radius 80 at (100, 100)
+// in a black rim, with ticks every 27 degrees clockwise from 225 to 135,
+// and a needle of color c pointing at angle, clockwise from 12 o'clock.
+func gaugeFace(angle float64, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 200, 200))
+	fillRect(img, 0, 0, 200, 200, color.RGBA{150, 150, 150, 255})
+	paintRing(img, 100.5, 100.5, 0, 84, 360, color.RGBA{0, 0, 0, 255})
+	paintRing(img, 100.5, 100.5, 0, 80, 360, color.RGBA{255, 255, 255, 255})
+	ray := func(a, from, to float64, c color.RGBA) {
+		rad := a * math.Pi / 180
+		for r := from; r <= to; r += 0.5 {
+			x, y := int(100.5+r*math.Sin(rad)), int(100.5-r*math.Cos(rad))
+			fillRect(img, x-1, y-1, x+2, y+2, c)
+		}
+	}
+	for i := 0; i <= 10; i++ {
+		ray(225+float64(i)*27, 68, 77, color.RGBA{0, 0, 0, 255})
+	}
+	ray(angle, 0, 70, c)
+	paintRing(img, 100.5, 100.5, 0, 6, 360, c)
+	return img
+}
+
+func TestReadGauge(t *testing.T) {
+	img := gaugeFace(90, color.RGBA{20, 20, 20, 255})
+	result, err := ReadGauge(img, GaugeOptions{Marks: []GaugeMark{{Angle: 225, Value: 0}, {Angle: 135, Value: 100}}})
+	if err != nil {
+		t.Fatalf("ReadGauge failed: %v", err)
+	}
+	d := result.Dial
+	if !d.Detected || math.Abs(float64(d.Center.X-100)) > 2 || math.Abs(float64(d.Center.Y-100)) > 2 || d.Radius < 78 || d.Radius > 85 {
+		t.Errorf("dial = %+v", d)
+	}
+	if !result.Found || math.Abs(result.NeedleAngle-90) > 1 || result.NeedleConfidence < 0.8 {
+		t.Fatalf("needle at %g (%g): %v", result.NeedleAngle, result.NeedleConfidence, result.Warnings)
+	}
+	if result.Value == nil || math.Abs(*result.Value-83.33) > 0.5 || result.OutOfRange || result.Calibration != "marks" {
+		t.Errorf("value %v, fraction %v, out of range %v", result.Value, result.Fraction, result.OutOfRange)
+	}
+	if tip := result.NeedleTip; math.Abs(float64(tip.X-170)) > 2 || math.Abs(float64(tip.Y-100)) > 2 {
+		t.Errorf("tip = %+v", tip)
+	}
+
+	// Without marks, the needle is read but not the value
+	result, _ = ReadGauge(img, GaugeOptions{})
+	if !result.Found || result.Value != nil || len(result.Warnings) != 1 {
+		t.Errorf("no scale = %+v", result)
+	}
+}
+
+func TestReadGauge_GivenDial(t *testing.T) {
+	// A red needle in the dead zone, nearer the start of the scale
+	img := gaugeFace(200, color.RGBA{220, 30, 30, 255})
+	result, err := ReadGauge(img, GaugeOptions{
+		Center:      &Point{X: 100, Y: 100},
+		Radius:      80,
+		Marks:       []GaugeMark{{Angle: -135, Value: 0}, {Angle: 135, Value: 100}},
+		NeedleColor: "#DC1E1E",
+	})
+	if err != nil {
+		t.Fatalf("ReadGauge failed: %v", err)
+	}
+	if result.Dial.Detected || !result.Found || math.Abs(result.NeedleAngle-200) > 1 {
+		t.Fatalf("dial %+v, needle at %g", result.Dial, result.NeedleAngle)
+	}
+	if result.Value == nil || math.Abs(*result.Value+9.26) > 0.5 || !result.OutOfRange || *result.Fraction > 0 {
+		t.Errorf("value %v, fraction %v, out of range %v", result.Value, result.Fraction, result.OutOfRange)
+	}
+
+	// Counterclockwise, the same marks span the other way around
+	result, _ = ReadGauge(img, GaugeOptions{
+		Center:           &Point{X: 100, Y: 100},
+		Radius:           80,
+		Marks:            []GaugeMark{{Angle: 225, Value: 0}, {Angle: 135, Value: 100}},
+		Counterclockwise: true,
+	})
+	if result.Value == nil || math.Abs(*result.Value-27.8) > 0.5 || result.OutOfRange {
+		t.Errorf("counterclockwise value %v", result.Value)
+	}
+}
+
+func TestReadGauge_Labels(t *testing.T) {
+	img := gaugeFace(90, color.RGBA{20, 20, 20, 255})
+	// label places text at angle, 55 pixels from the center
+	label := func(text string, angle float64) GaugeLabel {
+		rad := angle * math.Pi / 180
+		x, y := int(100+55*math.Sin(rad)), int(100-55*math.Cos(rad))
+		return GaugeLabel{Text: text, Bounds: Region{X1: x - 6, Y1: y - 4, X2: x + 6, Y2: y + 4}}
+	}
+	read := func(Region) []GaugeLabel {
+		// The unit, and a misread 5 between 50 and 100
+		return []GaugeLabel{
+			label("100", 135), label("PSI", 180), label("0", 225), label("50", 0), label("5", 30),
+		}
+	}
+	result, err := ReadGauge(img, GaugeOptions{ReadLabels: read})
+	if err != nil {
+		t.Fatalf("ReadGauge failed: %v", err)
+	}
+	if result.Calibration != "labels" || len(result.Marks) != 3 || result.Marks[0].Value != 0 || result.Labels[2].Text != "100" {
+		t.Fatalf("calibration %q: %+v", result.Calibration, result.Marks)
+	}
+	if result.Value == nil || math.Abs(*result.Value-83.33) > 0.5 {
+		t.Errorf("value %v", result.Value)
+	}
+
+	result, _ = ReadGauge(img, GaugeOptions{ReadLabels: func(Region) []GaugeLabel { return []GaugeLabel{label("0", 225)} }})
+	if result.Value != nil || result.Calibration != "" || len(result.Warnings) != 1 {
+		t.Errorf("one label = %+v", result)
+	}
+}
+
+func TestReadGauge_Invalid(t *testing.T) {
+	img := gaugeFace(0, color.RGBA{0, 0, 0, 255})
+	for _, opts := range []GaugeOptions{
+		{Marks: []GaugeMark{{Angle: 0, Value: 1}}},
+		{Marks: []GaugeMark{{Angle: 10, Value: 0}, {Angle: 370, Value: 1}}},
+		{NeedleColor: "red"},
+		{Center: &Point{X: 300, Y: 10}, Radius: 20},
+		{Center: &Point{X: 100, Y: 100}, Radius: 2},
+		{Region: &Region{X1: 0, Y1: 0, X2: 300, Y2: 10}},
+	} {
+		if _, err := ReadGauge(img, opts); err == nil {
+			t.Errorf("%+v: expected error", opts)
+		}
+	}
+}
@@ -0,0 +1,399 @@
+package imaging
+
+import (
+	"fmt"
+	"math"
+)
+
+// maxPolygonPoints limits how many vertices MeasurePolygon accepts.
+const maxPolygonPoints = 1000
+
+// PointF is a point with fractional pixel coordinates.
+type PointF struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// LineSegment is a straight line between two pixels.
+type LineSegment struct {
+	X1 int `json:"x1"`
+	Y1 int `json:"y1"`
+	X2 int `json:"x2"`
+	Y2 int `json:"y2"`
+}
+
+// length returns the segment's length in pixels.
+func (l LineSegment) length() float64 {
+	return math.Hypot(float64(l.X2-l.X1), float64(l.Y2-l.Y1))
+}
+
+// orientation returns the direction of the line through the segment, in
+// degrees from 0 up to 180: 0 is horizontal, 90 vertical, and angles grow
+// clockwise on screen.
+func (l LineSegment) orientation() float64 {
+	a := math.Atan2(float64(l.Y2-l.Y1), float64(l.X2-l.X1)) * 180 / math.Pi
+	if a < 0 {
+		a += 180
+	}
+	if a >= 180 {
+		a -= 180
+	}
+	return a
+}
+
+// distanceTo returns the distance of (x, y) from the line through the
+// segment, extended beyond its ends.
+func (l LineSegment) distanceTo(x, y float64) float64 {
+	dx, dy := float64(l.X2-l.X1), float64(l.Y2-l.Y1)
+	return math.Abs(dx*(y-float64(l.Y1))-dy*(x-float64(l.X1))) / math.Hypot(dx, dy)
+}
+
+// AngleResult contains the angle at a vertex between two arms.
+type AngleResult struct {
+	// AngleDegrees is the angle between the arms, from 0 to 180, and
+	// ReflexDegrees the angle on the other side, 360 - AngleDegrees. Both
+	// are rounded to 2 decimal places.
+	AngleDegrees  float64 `json:"angle_degrees"`
+	ReflexDegrees float64 `json:"reflex_degrees"`
+
+	// Turn is the direction on screen, "clockwise" or "counterclockwise",
+	// from the first arm to the second through AngleDegrees, or
+	// "collinear" if the arms lie on one line.
+	Turn string `json:"turn"`
+
+	// Arm1Degrees and Arm2Degrees are the directions of the arms from the
+	// vertex, as DistanceResult.AngleDegrees: 0° right, 90° down.
+	Arm1Degrees float64 `json:"arm1_degrees"`
+	Arm2Degrees float64 `json:"arm2_degrees"`
+
+	// Arm1Pixels and Arm2Pixels are the lengths of the arms.
+	Arm1Pixels float64 `json:"arm1_pixels"`
+	Arm2Pixels float64 `json:"arm2_pixels"`
+}
+
+// MeasureAngle measures the angle at vertex between the arms to p1 and p2,
+// such as the angle of a joint, a corner of a drawn shape, or a slope
+// against a reference line.
+//
+// Returns an error if either arm has zero length.
+func MeasureAngle(p1, vertex, p2 Point) (*AngleResult, error) {
+	ax, ay := float64(p1.X-vertex.X), float64(p1.Y-vertex.Y)
+	bx, by := float64(p2.X-vertex.X), float64(p2.Y-vertex.Y)
+	if ax == 0 && ay == 0 {
+		return nil, fmt.Errorf("the first point is the vertex (%d, %d)", vertex.X, vertex.Y)
+	}
+	if bx == 0 && by == 0 {
+		return nil, fmt.Errorf("the second point is the vertex (%d, %d)", vertex.X, vertex.Y)
+	}
+
+	// With Y down, a positive cross product turns clockwise on screen
+	cross := ax*by - ay*bx
+	angle := math.Atan2(math.Abs(cross), ax*bx+ay*by) * 180 / math.Pi
+	turn := "collinear"
+	if cross > 0 {
+		turn = "clockwise"
+	} else if cross < 0 {
+		turn = "counterclockwise"
+	}
+	return &AngleResult{
+		AngleDegrees:  math.Round(angle*100) / 100,
+		ReflexDegrees: math.Round((360-angle)*100) / 100,
+		Turn:          turn,
+		Arm1Degrees:   math.Round(math.Atan2(ay, ax)*180/math.Pi*100) / 100,
+		Arm2Degrees:   math.Round(math.Atan2(by, bx)*180/math.Pi*100) / 100,
+		Arm1Pixels:    math.Round(math.Hypot(ax, ay)*100) / 100,
+		Arm2Pixels:    math.Round(math.Hypot(bx, by)*100) / 100,
+	}, nil
+}
+
+// PolygonResult contains the measurements of a polygon.
+type PolygonResult struct {
+	// Vertices is the number of vertices measured, after dropping repeats
+	// of the previous point and a closing repeat of the first.
+	Vertices int `json:"vertices"`
+
+	// PerimeterPixels is the length of the closed outline, rounded to 2
+	// decimal places.
+	PerimeterPixels float64 `json:"perimeter_pixels"`
+
+	// AreaPixels is the enclosed area in square pixels, by the shoelace
+	// formula. For a self-intersecting outline, loops of opposite
+	// direction cancel out.
+	AreaPixels float64 `json:"area_pixels"`
+
+	// Centroid is the center of mass of the enclosed area.
+	Centroid PointF `json:"centroid"`
+
+	// Bounds is the pixel box covering the vertices (X2 and Y2
+	// exclusive).
+	Bounds Region `json:"bounds"`
+
+	// Orientation is the direction on screen in which the vertices go
+	// around: "clockwise" or "counterclockwise".
+	Orientation string `json:"orientation"`
+
+	// Convex is true if every interior angle is at most 180°.
+	Convex bool `json:"convex"`
+
+	// SelfIntersecting is true if two sides cross.
+	SelfIntersecting bool `json:"self_intersecting"`
+
+	// InteriorAngles are the angles inside the polygon at each vertex, in
+	// degrees. Omitted for a self-intersecting outline.
+	InteriorAngles []float64 `json:"interior_angles,omitempty"`
+
+	// Calibrated is the perimeter and area in the units of the image's
+	// scale bar, once a ScaleCalibration is known. Set by Calibrate.
+	Calibrated *CalibratedPolygon `json:"calibrated,omitempty"`
+}
+
+// CalibratedPolygon is a polygon's size in the units of a ScaleCalibration.
+type CalibratedPolygon struct {
+	Perimeter float64 `json:"perimeter"`
+	Area      float64 `json:"area"` // In square units
+	Unit      string  `json:"unit"`
+}
+
+// MeasurePolygon measures the perimeter, area, and shape of the polygon
+// through points, in order, such as the outline of a room on a floor
+// plan, a plot of land on a map, or a region traced in a micrograph. The
+// polygon closes from the last point back to the first.
+//
+// Returns an error if there are fewer than 3 or more than 1000 points,
+// or the points enclose no area.
+func MeasurePolygon(points []Point) (*PolygonResult, error) {
+	if len(points) > maxPolygonPoints {
+		return nil, fmt.Errorf("need at most %d points, got %d", maxPolygonPoints, len(points))
+	}
+	var pts []Point
+	for _, p := range points {
+		if len(pts) == 0 || p != pts[len(pts)-1] {
+			pts = append(pts, p)
+		}
+	}
+	if len(pts) > 1 && pts[0] == pts[len(pts)-1] {
+		pts = pts[:len(pts)-1]
+	}
+	n := len(pts)
+	if n < 3 {
+		return nil, fmt.Errorf("need at least 3 distinct points, got %d", n)
+	}
+
+	var perimeter, twiceArea, cx, cy float64
+	bounds := Region{X1: pts[0].X, Y1: pts[0].Y, X2: pts[0].X + 1, Y2: pts[0].Y + 1}
+	for i, p := range pts {
+		q := pts[(i+1)%n]
+		perimeter += math.Hypot(float64(q.X-p.X), float64(q.Y-p.Y))
+		cross := float64(p.X*q.Y - q.X*p.Y)
+		twiceArea += cross
+		cx += float64(p.X+q.X) * cross
+		cy += float64(p.Y+q.Y) * cross
+		bounds.X1, bounds.Y1 = min(bounds.X1, p.X), min(bounds.Y1, p.Y)
+		bounds.X2, bounds.Y2 = max(bounds.X2, p.X+1), max(bounds.Y2, p.Y+1)
+	}
+	if twiceArea == 0 {
+		return nil, fmt.Errorf("the points enclose no area")
+	}
+
+	// With Y down, a positive signed area goes clockwise on screen
+	orientation := "counterclockwise"
+	if twiceArea > 0 {
+		orientation = "clockwise"
+	}
+	result := &PolygonResult{
+		Vertices:         n,
+		PerimeterPixels:  math.Round(perimeter*100) / 100,
+		AreaPixels:       math.Abs(twiceArea) / 2,
+		Centroid:         PointF{X: math.Round(cx/(3*twiceArea)*100) / 100, Y: math.Round(cy/(3*twiceArea)*100) / 100},
+		Bounds:           bounds,
+		Orientation:      orientation,
+		SelfIntersecting: selfIntersecting(pts),
+	}
+
+	// A vertex turning against the orientation is a reflex angle
+	convex := !result.SelfIntersecting
+	angles := make([]float64, n)
+	for i, v := range pts {
+		a, err := MeasureAngle(pts[(i+n-1)%n], v, pts[(i+1)%n])
+		if err != nil {
+			return nil, err
+		}
+		angles[i] = a.AngleDegrees
+		if a.Turn != "collinear" && (a.Turn == "clockwise") == (orientation == "clockwise") {
+			angles[i] = a.ReflexDegrees
+			convex = false
+		}
+	}
+	result.Convex = convex
+	if !result.SelfIntersecting {
+		result.InteriorAngles = angles
+	}
+	return result, nil
+}
+
+// Calibrate sets r.Calibrated to the perimeter and area in c's units,
+// keeping four significant digits. A nil c leaves r unchanged.
+func (r *PolygonResult) Calibrate(c *ScaleCalibration) {
+	if c == nil {
+		return
+	}
+	u := c.UnitsPerPixel
+	r.Calibrated = &CalibratedPolygon{
+		Perimeter: significant(r.PerimeterPixels*u, 4),
+		Area:      significant(r.AreaPixels*u*u, 4),
+		Unit:      c.Unit,
+	}
+}
+
+// selfIntersecting reports whether two non-adjacent sides of the closed
+// polygon through pts touch or cross.
+func selfIntersecting(pts []Point) bool {
+	n := len(pts)
+	orient := func(a, b, c Point) int {
+		v := (b.X-a.X)*(c.Y-a.Y) - (b.Y-a.Y)*(c.X-a.X)
+		if v > 0 {
+			return 1
+		} else if v < 0 {
+			return -1
+		}
+		return 0
+	}
+	onSegment := func(a, b, p Point) bool {
+		return min(a.X, b.X) <= p.X && p.X <= max(a.X, b.X) && min(a.Y, b.Y) <= p.Y && p.Y <= max(a.Y, b.Y)
+	}
+	for i := 0; i < n; i++ {
+		a, b := pts[i], pts[(i+1)%n]
+		for j := i + 2; j < n; j++ {
+			if i == 0 && j == n-1 {
+				continue // Adjacent through the closing side
+			}
+			c, d := pts[j], pts[(j+1)%n]
+			o1, o2, o3, o4 := orient(a, b, c), orient(a, b, d), orient(c, d, a), orient(c, d, b)
+			if o1 != o2 && o3 != o4 {
+				return true
+			}
+			if (o1 == 0 && onSegment(a, b, c)) || (o2 == 0 && onSegment(a, b, d)) ||
+				(o3 == 0 && onSegment(c, d, a)) || (o4 == 0 && onSegment(c, d, b)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ParallelResult contains the comparison of two lines' directions.
+type ParallelResult struct {
+	// Line1Degrees and Line2Degrees are the directions of the lines, from
+	// 0 up to 180: 0 is horizontal, 90 vertical, and angles grow clockwise
+	// on screen.
+	Line1Degrees float64 `json:"line1_degrees"`
+	Line2Degrees float64 `json:"line2_degrees"`
+
+	// AngleBetween is the acute angle between the lines, from 0 to 90.
+	AngleBetween float64 `json:"angle_between"`
+
+	// Parallel is true if AngleBetween is within the tolerance of 0, and
+	// Perpendicular if it is within the tolerance of 90.
+	Parallel      bool `json:"parallel"`
+	Perpendicular bool `json:"perpendicular"`
+
+	// SeparationPixels is the mean distance of each line's ends from the
+	// other line, extended: the gap between parallel lines. Only set when
+	// Parallel.
+	SeparationPixels *float64 `json:"separation_pixels,omitempty"`
+
+	// OverlapPercent is how much of the shorter line lies alongside the
+	// longer, measured along the first line's direction. Only set when
+	// Parallel.
+	OverlapPercent *float64 `json:"overlap_percent,omitempty"`
+
+	// Intersection is where the lines, extended, cross. Only set when
+	// they are not parallel.
+	Intersection *PointF `json:"intersection,omitempty"`
+
+	// Calibrated is SeparationPixels in the units of the image's scale
+	// bar, once a ScaleCalibration is known. Set by Calibrate.
+	Calibrated *CalibratedSeparation `json:"calibrated,omitempty"`
+}
+
+// CalibratedSeparation is the gap between parallel lines in the units of
+// a ScaleCalibration.
+type CalibratedSeparation struct {
+	Separation float64 `json:"separation"`
+	Unit       string  `json:"unit"`
+}
+
+// CheckParallel compares the directions of two lines, such as edges of a
+// drawing, rails of a layout, or sides of a photographed document, to
+// tell whether they are parallel or perpendicular within tolerance
+// degrees.
+//
+// Returns an error if either line has zero length or tolerance is not
+// above 0 and below 45.
+func CheckParallel(line1, line2 LineSegment, tolerance float64) (*ParallelResult, error) {
+	if tolerance <= 0 || tolerance >= 45 {
+		return nil, fmt.Errorf("tolerance must be above 0 and below 45 degrees, got %g", tolerance)
+	}
+	for i, l := range []LineSegment{line1, line2} {
+		if l.length() == 0 {
+			return nil, fmt.Errorf("line %d has zero length", i+1)
+		}
+	}
+
+	o1, o2 := line1.orientation(), line2.orientation()
+	between := math.Abs(o1 - o2)
+	if between > 90 {
+		between = 180 - between
+	}
+	result := &ParallelResult{
+		Line1Degrees:  math.Round(o1*100) / 100,
+		Line2Degrees:  math.Round(o2*100) / 100,
+		AngleBetween:  math.Round(between*100) / 100,
+		Parallel:      between <= tolerance,
+		Perpendicular: 90-between <= tolerance,
+	}
+
+	if result.Parallel {
+		sep := (line1.distanceTo(float64(line2.X1), float64(line2.Y1)) + line1.distanceTo(float64(line2.X2), float64(line2.Y2)) +
+			line2.distanceTo(float64(line1.X1), float64(line1.Y1)) + line2.distanceTo(float64(line1.X2), float64(line1.Y2))) / 4
+		sep = math.Round(sep*100) / 100
+		result.SeparationPixels = &sep
+
+		// Project both lines onto the first's direction
+		ux, uy := float64(line1.X2-line1.X1)/line1.length(), float64(line1.Y2-line1.Y1)/line1.length()
+		project := func(x, y int) float64 { return float64(x)*ux + float64(y)*uy }
+		a1, a2 := project(line1.X1, line1.Y1), project(line1.X2, line1.Y2)
+		b1, b2 := project(line2.X1, line2.Y1), project(line2.X2, line2.Y2)
+		lo, hi := math.Max(math.Min(a1, a2), math.Min(b1, b2)), math.Min(math.Max(a1, a2), math.Max(b1, b2))
+		overlap := 0.0
+		if shorter := math.Min(math.Abs(a2-a1), math.Abs(b2-b1)); hi > lo && shorter > 0 {
+			overlap = math.Round((hi-lo)/shorter*1000) / 10
+		}
+		result.OverlapPercent = &overlap
+		return result, nil
+	}
+
+	dx1, dy1 := float64(line1.X2-line1.X1), float64(line1.Y2-line1.Y1)
+	dx2, dy2 := float64(line2.X2-line2.X1), float64(line2.Y2-line2.Y1)
+	if denom := dx1*dy2 - dy1*dx2; denom != 0 {
+		t := (float64(line2.X1-line1.X1)*dy2 - float64(line2.Y1-line1.Y1)*dx2) / denom
+		result.Intersection = &PointF{
+			X: math.Round((float64(line1.X1)+t*dx1)*100) / 100,
+			Y: math.Round((float64(line1.Y1)+t*dy1)*100) / 100,
+		}
+	}
+	return result, nil
+}
+
+// Calibrate sets r.Calibrated to the separation in c's units, keeping
+// four significant digits. A nil c, or lines that are not parallel, leave
+// r unchanged.
+func (r *ParallelResult) Calibrate(c *ScaleCalibration) {
+	if c == nil || r.SeparationPixels == nil {
+		return
+	}
+	r.Calibrated = &CalibratedSeparation{
+		Separation: significant(*r.SeparationPixels*c.UnitsPerPixel, 4),
+		Unit:       c.Unit,
+	}
+}
@@ -0,0 +1,149 @@
+package imaging
+
+import (
+	"testing"
+)
+
+func TestMeasureAngle(t *testing.T) {
+	tests := []struct {
+		name       string
+		p1, v, p2  Point
+		wantAngle  float64
+		wantTurn   string
+		wantArm1   float64
+		wantLength float64
+	}{
+		{"right angle", Point{10, 0}, Point{0, 0}, Point{0, 10}, 90, "clockwise", 0, 10},
+		{"reversed", Point{0, 10}, Point{0, 0}, Point{10, 0}, 90, "counterclockwise", 90, 10},
+		{"acute", Point{110, 100}, Point{100, 100}, Point{110, 90}, 45, "counterclockwise", 0, 10},
+		{"straight", Point{0, 5}, Point{5, 5}, Point{10, 5}, 180, "collinear", 180, 5},
+		{"same direction", Point{3, 4}, Point{0, 0}, Point{6, 8}, 0, "collinear", 53.13, 5},
+	}
+	for _, tt := range tests {
+		result, err := MeasureAngle(tt.p1, tt.v, tt.p2)
+		if err != nil {
+			t.Fatalf("%s: MeasureAngle failed: %v", tt.name, err)
+		}
+		if result.AngleDegrees != tt.wantAngle || result.ReflexDegrees != 360-tt.wantAngle || result.Turn != tt.wantTurn {
+			t.Errorf("%s: got %v° (%v° reflex) %s, want %v° %s", tt.name, result.AngleDegrees, result.ReflexDegrees, result.Turn, tt.wantAngle, tt.wantTurn)
+		}
+		if result.Arm1Degrees != tt.wantArm1 || result.Arm1Pixels != tt.wantLength {
+			t.Errorf("%s: arm 1 %v° %v px, want %v° %v px", tt.name, result.Arm1Degrees, result.Arm1Pixels, tt.wantArm1, tt.wantLength)
+		}
+	}
+
+	if _, err := MeasureAngle(Point{5, 5}, Point{5, 5}, Point{0, 0}); err == nil {
+		t.Error("expected error for a zero-length arm")
+	}
+}
+
+func TestMeasurePolygon(t *testing.T) {
+	// An L shape, clockwise on screen, closed by repeating the first point
+	l := []Point{{0, 0}, {40, 0}, {40, 10}, {10, 10}, {10, 30}, {0, 30}, {0, 0}}
+	result, err := MeasurePolygon(l)
+	if err != nil {
+		t.Fatalf("MeasurePolygon failed: %v", err)
+	}
+	if result.Vertices != 6 || result.PerimeterPixels != 140 || result.AreaPixels != 600 || result.Orientation != "clockwise" {
+		t.Errorf("got %+v", result)
+	}
+	if result.Centroid != (PointF{X: 15, Y: 10}) || result.Bounds != (Region{X1: 0, Y1: 0, X2: 41, Y2: 31}) {
+		t.Errorf("centroid %+v, bounds %+v", result.Centroid, result.Bounds)
+	}
+	if result.Convex || result.SelfIntersecting {
+		t.Errorf("convex %v, self-intersecting %v", result.Convex, result.SelfIntersecting)
+	}
+	want := []float64{90, 90, 90, 270, 90, 90}
+	for i, a := range result.InteriorAngles {
+		if a != want[i] {
+			t.Errorf("interior angles %v, want %v", result.InteriorAngles, want)
+			break
+		}
+	}
+
+	// A triangle the other way round
+	result, err = MeasurePolygon([]Point{{0, 0}, {0, 30}, {40, 0}})
+	if err != nil {
+		t.Fatalf("MeasurePolygon failed: %v", err)
+	}
+	if result.AreaPixels != 600 || result.PerimeterPixels != 120 || result.Orientation != "counterclockwise" || !result.Convex {
+		t.Errorf("triangle: got %+v", result)
+	}
+	if sum := result.InteriorAngles[0] + result.InteriorAngles[1] + result.InteriorAngles[2]; sum < 179.99 || sum > 180.01 {
+		t.Errorf("triangle: interior angles %v sum to %v", result.InteriorAngles, sum)
+	}
+
+	result.Calibrate(&ScaleCalibration{Unit: "m", UnitsPerPixel: 0.5})
+	if c := result.Calibrated; c == nil || c.Perimeter != 60 || c.Area != 150 || c.Unit != "m" {
+		t.Errorf("calibrated = %+v", c)
+	}
+}
+
+func TestMeasurePolygon_SelfIntersecting(t *testing.T) {
+	// A bow tie: its two loops cancel out
+	result, err := MeasurePolygon([]Point{{0, 0}, {20, 20}, {20, 0}, {0, 30}})
+	if err != nil {
+		t.Fatalf("MeasurePolygon failed: %v", err)
+	}
+	if !result.SelfIntersecting || result.Convex || result.InteriorAngles != nil {
+		t.Errorf("got %+v", result)
+	}
+
+	for _, pts := range [][]Point{
+		{{0, 0}, {10, 10}},
+		{{0, 0}, {5, 5}, {10, 10}},
+		{{0, 0}, {0, 0}, {10, 10}, {10, 10}},
+	} {
+		if _, err := MeasurePolygon(pts); err == nil {
+			t.Errorf("%v: expected error", pts)
+		}
+	}
+}
+
+func TestCheckParallel(t *testing.T) {
+	// Two nearly horizontal rails about 20 pixels apart, overlapping by half
+	result, err := CheckParallel(LineSegment{0, 10, 100, 10}, LineSegment{50, 30, 150, 31}, 1)
+	if err != nil {
+		t.Fatalf("CheckParallel failed: %v", err)
+	}
+	if !result.Parallel || result.Perpendicular || result.AngleBetween != 0.57 || result.Intersection != nil {
+		t.Errorf("got %+v", result)
+	}
+	if result.SeparationPixels == nil || result.OverlapPercent == nil {
+		t.Fatalf("parallel lines without separation or overlap: %+v", result)
+	}
+	if *result.SeparationPixels != 20.25 || *result.OverlapPercent != 50 {
+		t.Errorf("separation %v, overlap %v", *result.SeparationPixels, *result.OverlapPercent)
+	}
+	result.Calibrate(&ScaleCalibration{Unit: "mm", UnitsPerPixel: 0.1})
+	if c := result.Calibrated; c == nil || c.Separation != 2.025 || c.Unit != "mm" {
+		t.Errorf("calibrated = %+v", c)
+	}
+
+	// Direction does not matter, only the line
+	result, _ = CheckParallel(LineSegment{0, 0, 10, 10}, LineSegment{30, 10, 20, 0}, 1)
+	if !result.Parallel || result.Line1Degrees != 45 || result.Line2Degrees != 45 {
+		t.Errorf("reversed: got %+v", result)
+	}
+
+	result, _ = CheckParallel(LineSegment{0, 50, 100, 50}, LineSegment{40, 0, 41, 100}, 1)
+	if result.Parallel || !result.Perpendicular || result.SeparationPixels != nil || result.Calibrated != nil {
+		t.Errorf("perpendicular: got %+v", result)
+	}
+	if result.Intersection == nil || *result.Intersection != (PointF{X: 40.5, Y: 50}) {
+		t.Errorf("intersection = %v", result.Intersection)
+	}
+
+	for _, tc := range []struct {
+		line      LineSegment
+		tolerance float64
+	}{
+		{LineSegment{5, 5, 5, 5}, 1},
+		{LineSegment{0, 0, 10, 0}, 0},
+		{LineSegment{0, 0, 10, 0}, 45},
+	} {
+		if _, err := CheckParallel(LineSegment{0, 0, 10, 0}, tc.line, tc.tolerance); err == nil {
+			t.Errorf("%+v: expected error", tc)
+		}
+	}
+}
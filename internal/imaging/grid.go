@@ -32,6 +32,11 @@ type GridOverlayResult struct {
 
 	// GridSpacing is the distance between grid lines in pixels.
 	GridSpacing int `json:"grid_spacing"`
+
+	// Viewport is the browser viewport the grid was drawn on, in image
+	// coordinates, when coordinates are relative to it; the image is then
+	// the viewport alone. Set by the caller.
+	Viewport *Region `json:"viewport,omitempty"`
 }
 
 // GridOverlay adds a coordinate grid overlay to an image for positioning reference.
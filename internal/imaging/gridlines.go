@@ -0,0 +1,309 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// Grid detection constants.
+const (
+	// gridEdgeContrast is the smallest luma step (0-255) between adjacent
+	// pixels that counts toward a grid line's edge.
+	gridEdgeContrast = 24
+
+	// maxGridLineWidth is the widest drawn line, in pixels, whose two
+	// edges are paired into one line.
+	maxGridLineWidth = 8
+
+	// minGridCell is the smallest gap, in pixels, between two lines that
+	// makes a row or column; closer lines, such as a double rule, merge.
+	minGridCell = 4
+)
+
+// GridLine is a line of a grid found by DetectGrid, or a boundary between
+// cells filled with different colors.
+type GridLine struct {
+	// Position is the line's center: a Y coordinate for horizontal lines,
+	// an X coordinate for vertical ones.
+	Position float64 `json:"position"`
+
+	// Start and End are the first pixel of the line and the pixel after
+	// it. They are equal for an edge or border, which has no thickness.
+	Start int `json:"start"`
+	End   int `json:"end"`
+
+	// Thickness is End - Start.
+	Thickness int `json:"thickness"`
+
+	// Coverage is the fraction (0-1) of the analyzed region's width (or
+	// height, for vertical lines) along which the line is seen. Zero for a
+	// border.
+	Coverage float64 `json:"coverage"`
+
+	// Kind is "line" for a drawn line, "edge" for a boundary between
+	// differently colored cells, or "border" for an edge of the region
+	// assumed to close the outermost cells.
+	Kind string `json:"kind"`
+}
+
+// GridBand is one row or column of a grid found by DetectGrid.
+type GridBand struct {
+	// Index is the row's or column's position, from 0 at the top or left.
+	Index int `json:"index"`
+
+	// Start and End are the first pixel inside the band and the pixel
+	// after it, between the lines on either side.
+	Start int `json:"start"`
+	End   int `json:"end"`
+
+	// Size is End - Start.
+	Size int `json:"size"`
+}
+
+// GridCell is the pixel area of one cell of a grid.
+type GridCell struct {
+	Row    int    `json:"row"`
+	Col    int    `json:"col"`
+	Bounds Region `json:"bounds"`
+	Center Point  `json:"center"`
+}
+
+// GridResult contains the grid found by DetectGrid.
+type GridResult struct {
+	// Region is the area that was analyzed.
+	Region Region `json:"region"`
+
+	// Found is true if at least one row and one column were found.
+	Found bool `json:"found"`
+
+	// Rows and Columns are the grid's bands, top to bottom and left to
+	// right. Cell (row, col) spans Columns[col] by Rows[row].
+	Rows        []GridBand `json:"rows"`
+	Columns     []GridBand `json:"columns"`
+	RowCount    int        `json:"row_count"`
+	ColumnCount int        `json:"column_count"`
+
+	// HorizontalLines and VerticalLines are the lines between and around
+	// the bands, in order.
+	HorizontalLines []GridLine `json:"horizontal_lines"`
+	VerticalLines   []GridLine `json:"vertical_lines"`
+
+	// RowPitch and ColumnPitch are the median distances between the
+	// centers of consecutive lines. Zero with fewer than two lines.
+	RowPitch    float64 `json:"row_pitch"`
+	ColumnPitch float64 `json:"column_pitch"`
+
+	// RegularRows and RegularColumns are true if there are at least two
+	// bands and every band's size is within 15% of the median.
+	RegularRows    bool `json:"regular_rows"`
+	RegularColumns bool `json:"regular_columns"`
+
+	// Bounds is the grid's extent, outer lines included. Nil if the grid
+	// was not found.
+	Bounds *Region `json:"bounds,omitempty"`
+
+	// Cells are the cells looked up by the caller with Cell.
+	Cells []GridCell `json:"cells,omitempty"`
+}
+
+// Cell returns the pixel area of the cell at row and col, both counted
+// from 0 at the top left, between the lines around it.
+//
+// Returns an error if the cell is outside the grid.
+func (r *GridResult) Cell(row, col int) (GridCell, error) {
+	if row < 0 || row >= r.RowCount || col < 0 || col >= r.ColumnCount {
+		return GridCell{}, fmt.Errorf("cell (%d, %d) is outside the %dx%d grid", row, col, r.RowCount, r.ColumnCount)
+	}
+	rb, cb := r.Rows[row], r.Columns[col]
+	return GridCell{
+		Row:    row,
+		Col:    col,
+		Bounds: Region{X1: cb.Start, Y1: rb.Start, X2: cb.End, Y2: rb.End},
+		Center: Point{X: (cb.Start + cb.End) / 2, Y: (rb.Start + rb.End) / 2},
+	}, nil
+}
+
+// DetectGrid finds the horizontal and vertical lines of a grid, such as a
+// game board, a calendar, a spreadsheet, or a table with ruling lines,
+// and the rows and columns between them.
+//
+// Parameters:
+//   - img: Source image.
+//   - region: The area to analyze, or nil for the whole image.
+//   - minCoverage: The fraction (0-1) of the region's width a horizontal
+//     line must span, or of its height a vertical one, to count. Text and
+//     other content rarely span more than half.
+//
+// Returns:
+//   - *GridResult: The rows and columns, their lines, and whether they are
+//     regular. Found is false if no row or no column was found.
+//   - error: Non-nil if the region is invalid or minCoverage is not
+//     above 0 and at most 1.
+//
+// # Method
+//
+// For each boundary between two pixel rows, the columns where luma steps
+// by at least 24 are counted, and boundaries whose count reaches
+// minCoverage become edges, adjacent ones of the same direction merged.
+// An edge darkening and one lightening again within 8 pixels are the two
+// sides of a drawn line; an edge of mixed direction, as between the
+// squares of a checkerboard, is a boundary on its own. Lines closer than
+// 4 pixels, such as a double rule, merge. Where the region's edge lies
+// about one pitch from an outermost edge, as on a board of filled squares
+// filling the image, it is taken as a border closing the outer cells.
+// Vertical lines are found the same way across columns.
+func DetectGrid(img image.Image, region *Region, minCoverage float64) (*GridResult, error) {
+	if minCoverage <= 0 || minCoverage > 1 {
+		return nil, fmt.Errorf("min_coverage must be above 0 and at most 1, got %g", minCoverage)
+	}
+	b := img.Bounds()
+	r := Region{X1: 0, Y1: 0, X2: b.Dx(), Y2: b.Dy()}
+	if region != nil {
+		r = *region
+		if err := validateRegion(r, b.Dx(), b.Dy()); err != nil {
+			return nil, err
+		}
+	}
+	w, h := r.X2-r.X1, r.Y2-r.Y1
+	lum := make([]uint8, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			lum[y*w+x] = luma(nrgbaAt(img, b.Min.X+r.X1+x, b.Min.Y+r.Y1+y))
+		}
+	}
+
+	result := &GridResult{Region: r}
+	result.HorizontalLines = gridLines(h, w, func(u, v int) uint8 { return lum[u*w+v] }, minCoverage, r.Y1)
+	result.VerticalLines = gridLines(w, h, func(u, v int) uint8 { return lum[v*w+u] }, minCoverage, r.X1)
+	result.Rows, result.RowPitch, result.RegularRows = gridBands(result.HorizontalLines)
+	result.Columns, result.ColumnPitch, result.RegularColumns = gridBands(result.VerticalLines)
+	result.RowCount, result.ColumnCount = len(result.Rows), len(result.Columns)
+	result.Found = result.RowCount > 0 && result.ColumnCount > 0
+	if result.Found {
+		hl, vl := result.HorizontalLines, result.VerticalLines
+		result.Bounds = &Region{X1: vl[0].Start, Y1: hl[0].Start, X2: vl[len(vl)-1].End, Y2: hl[len(hl)-1].End}
+	}
+	return result, nil
+}
+
+// gridEdge is a run of adjacent boundaries between pixel rows (or
+// columns) where luma steps along most of the region.
+type gridEdge struct {
+	first, last int // Boundary u lies between pixel rows u-1 and u
+	sign        int // -1 darkening, 1 lightening, 0 mixed
+	coverage    float64
+}
+
+// gridLines returns the lines across a length×breadth region whose luma
+// at (u, v) is at, u running across the lines. Positions are offset by
+// origin.
+func gridLines(length, breadth int, at func(u, v int) uint8, minCoverage float64, origin int) []GridLine {
+	var edges []gridEdge
+	for u := 1; u < length; u++ {
+		pos, neg := 0, 0
+		for v := 0; v < breadth; v++ {
+			d := int(at(u, v)) - int(at(u-1, v))
+			if d >= gridEdgeContrast {
+				pos++
+			} else if d <= -gridEdgeContrast {
+				neg++
+			}
+		}
+		coverage := float64(pos+neg) / float64(breadth)
+		if coverage < minCoverage {
+			continue
+		}
+		sign := 0
+		if float64(pos) >= 0.7*float64(pos+neg) {
+			sign = 1
+		} else if float64(neg) >= 0.7*float64(pos+neg) {
+			sign = -1
+		}
+		if n := len(edges); n > 0 && edges[n-1].last == u-1 && edges[n-1].sign == sign {
+			edges[n-1].last = u
+			edges[n-1].coverage = math.Max(edges[n-1].coverage, coverage)
+			continue
+		}
+		edges = append(edges, gridEdge{first: u, last: u, sign: sign, coverage: coverage})
+	}
+
+	var lines []GridLine
+	add := func(start, end int, coverage float64, kind string) {
+		if n := len(lines); n > 0 && start-lines[n-1].End < minGridCell {
+			prev := &lines[n-1]
+			prev.End = end
+			prev.Coverage = math.Min(prev.Coverage, coverage)
+			prev.Kind = "line"
+			return
+		}
+		lines = append(lines, GridLine{Start: start, End: end, Coverage: coverage, Kind: kind})
+	}
+	for i := 0; i < len(edges); i++ {
+		e := edges[i]
+		if i+1 < len(edges) {
+			next := edges[i+1]
+			if e.sign != 0 && next.sign == -e.sign && next.first-e.last <= maxGridLineWidth {
+				add(e.first, next.last, math.Min(e.coverage, next.coverage), "line")
+				i++
+				continue
+			}
+		}
+		add(e.first, e.last, e.coverage, "edge")
+	}
+
+	// Close outer filled cells at the region's edges, about one pitch
+	// away; drawn grids draw their outer lines
+	if len(lines) >= 2 {
+		center := func(l GridLine) float64 { return float64(l.Start+l.End) / 2 }
+		gaps := make([]float64, len(lines)-1)
+		for i := range gaps {
+			gaps[i] = center(lines[i+1]) - center(lines[i])
+		}
+		pitch := medianFloat(gaps)
+		near := func(d float64) bool { return d >= 0.6*pitch && d <= 1.25*pitch }
+		if lines[0].Kind == "edge" && near(center(lines[0])) {
+			lines = append([]GridLine{{Kind: "border"}}, lines...)
+		}
+		if last := lines[len(lines)-1]; last.Kind == "edge" && near(float64(length)-center(last)) {
+			lines = append(lines, GridLine{Start: length, End: length, Kind: "border"})
+		}
+	}
+
+	for i := range lines {
+		l := &lines[i]
+		l.Start += origin
+		l.End += origin
+		l.Thickness = l.End - l.Start
+		l.Position = float64(l.Start+l.End) / 2
+		l.Coverage = math.Round(l.Coverage*1000) / 1000
+	}
+	return lines
+}
+
+// gridBands returns the bands between consecutive lines, the median
+// distance between line centers, and whether the bands are regular.
+func gridBands(lines []GridLine) ([]GridBand, float64, bool) {
+	bands := []GridBand{}
+	if len(lines) < 2 {
+		return bands, 0, false
+	}
+	gaps := make([]float64, len(lines)-1)
+	sizes := make([]float64, len(lines)-1)
+	for i := 0; i+1 < len(lines); i++ {
+		start, end := lines[i].End, lines[i+1].Start
+		bands = append(bands, GridBand{Index: i, Start: start, End: end, Size: end - start})
+		gaps[i] = lines[i+1].Position - lines[i].Position
+		sizes[i] = float64(end - start)
+	}
+	pitch := math.Round(medianFloat(gaps)*100) / 100
+
+	median := medianFloat(sizes)
+	regular := len(bands) >= 2
+	for _, s := range sizes {
+		if math.Abs(s-median) > 0.15*median {
+			regular = false
+		}
+	}
+	return bands, pitch, regular
+}
@@ -0,0 +1,117 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// boardImage returns a 160x160 checkerboard of 20-pixel squares.
+func boardImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 160, 160))
+	for y := 0; y < 160; y++ {
+		for x := 0; x < 160; x++ {
+			c := color.RGBA{240, 217, 181, 255}
+			if (x/20+y/20)%2 == 1 {
+				c = color.RGBA{181, 136, 99, 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// sheetImage returns a 300x200 spreadsheet: a toolbar band, then 1-pixel
+// gray rules every 20 pixels from y 40 and at uneven columns, with dark
+// marks standing in for text.
+func sheetImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 300, 200))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 0, 300, 30), image.NewUniform(color.RGBA{60, 60, 70, 255}), image.Point{}, draw.Src)
+	rule := image.NewUniform(color.RGBA{190, 190, 190, 255})
+	for y := 40; y <= 180; y += 20 {
+		draw.Draw(img, image.Rect(10, y, 290, y+1), rule, image.Point{}, draw.Src)
+	}
+	for _, x := range []int{10, 50, 150, 210, 289} {
+		draw.Draw(img, image.Rect(x, 40, x+1, 181), rule, image.Point{}, draw.Src)
+	}
+	for y := 45; y < 180; y += 20 {
+		draw.Draw(img, image.Rect(55, y, 120, y+10), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	}
+	return img
+}
+
+func TestDetectGrid_Board(t *testing.T) {
+	result, err := DetectGrid(boardImage(), nil, 0.6)
+	if err != nil {
+		t.Fatalf("DetectGrid failed: %v", err)
+	}
+	if !result.Found || result.RowCount != 8 || result.ColumnCount != 8 || !result.RegularRows || !result.RegularColumns {
+		t.Fatalf("got %dx%d, regular %v %v", result.RowCount, result.ColumnCount, result.RegularRows, result.RegularColumns)
+	}
+	if result.RowPitch != 20 || *result.Bounds != (Region{X1: 0, Y1: 0, X2: 160, Y2: 160}) {
+		t.Errorf("pitch %v, bounds %+v", result.RowPitch, *result.Bounds)
+	}
+	// The squares meet at edges, closed by the image's borders
+	if l := result.HorizontalLines; l[0].Kind != "border" || l[1].Kind != "edge" || l[1].Position != 20 || l[8].Kind != "border" {
+		t.Errorf("horizontal lines = %+v", l)
+	}
+	cell, err := result.Cell(2, 7)
+	if err != nil {
+		t.Fatalf("Cell failed: %v", err)
+	}
+	if cell.Bounds != (Region{X1: 140, Y1: 40, X2: 160, Y2: 60}) || cell.Center != (Point{X: 150, Y: 50}) {
+		t.Errorf("cell = %+v", cell)
+	}
+	if _, err := result.Cell(8, 0); err == nil {
+		t.Error("expected error for a cell outside the grid")
+	}
+}
+
+func TestDetectGrid_Sheet(t *testing.T) {
+	result, err := DetectGrid(sheetImage(), nil, 0.6)
+	if err != nil {
+		t.Fatalf("DetectGrid failed: %v", err)
+	}
+	// The toolbar's lower edge closes an extra, short row
+	if result.RowCount != 8 || result.ColumnCount != 4 || result.RegularRows || result.HorizontalLines[0].Kind != "edge" {
+		t.Fatalf("whole image: got %dx%d, regular rows %v", result.RowCount, result.ColumnCount, result.RegularRows)
+	}
+
+	// Below the toolbar, the rows are regular
+	result, err = DetectGrid(sheetImage(), &Region{X1: 0, Y1: 35, X2: 300, Y2: 200}, 0.6)
+	if err != nil {
+		t.Fatalf("DetectGrid failed: %v", err)
+	}
+	if !result.Found || result.RowCount != 7 || result.ColumnCount != 4 || !result.RegularRows || result.RegularColumns {
+		t.Fatalf("sheet: got %dx%d, regular %v %v", result.RowCount, result.ColumnCount, result.RegularRows, result.RegularColumns)
+	}
+	r := result.Rows[0]
+	if r.Start != 41 || r.End != 60 || r.Size != 19 || result.RowPitch != 20 {
+		t.Errorf("first row = %+v, pitch %v", r, result.RowPitch)
+	}
+	if c := result.Columns[1]; c.Start != 51 || c.End != 150 {
+		t.Errorf("second column = %+v", c)
+	}
+	if l := result.VerticalLines[0]; l.Kind != "line" || l.Thickness != 1 || l.Position != 10.5 {
+		t.Errorf("first vertical line = %+v", l)
+	}
+}
+
+func TestDetectGrid_Invalid(t *testing.T) {
+	img := boardImage()
+	if _, err := DetectGrid(img, nil, 0); err == nil {
+		t.Error("expected error for min coverage 0")
+	}
+	if _, err := DetectGrid(img, &Region{X1: 0, Y1: 0, X2: 200, Y2: 100}, 0.6); err == nil {
+		t.Error("expected error for a region outside the image")
+	}
+
+	// A blank image has no grid
+	blank := image.NewRGBA(image.Rect(0, 0, 50, 50))
+	result, err := DetectGrid(blank, nil, 0.6)
+	if err != nil || result.Found || result.Bounds != nil || len(result.Rows) != 0 {
+		t.Errorf("blank: got %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,323 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"sort"
+)
+
+// maxGrowSeeds limits how many seeds GrowRegions accepts.
+const maxGrowSeeds = 50
+
+// growColors are the tints of grown regions in GrowRegions' image, in
+// seed order, repeating after the last.
+var growColors = []color.RGBA{
+	{230, 25, 75, 255}, {60, 180, 75, 255}, {0, 130, 200, 255}, {245, 130, 48, 255},
+	{145, 30, 180, 255}, {70, 240, 240, 255}, {240, 50, 230, 255}, {210, 245, 60, 255},
+}
+
+// GrowSeed is a pixel from which GrowRegions grows a region.
+type GrowSeed struct {
+	X     int    // X coordinate (0-based)
+	Y     int    // Y coordinate (0-based)
+	Label string // Optional descriptive label for the region
+
+	// Tolerance, if positive, replaces the shared tolerance for this
+	// region.
+	Tolerance float64
+}
+
+// GrownRegion is one region grown by GrowRegions.
+type GrownRegion struct {
+	// ID is the region's index in GrowResult.Regions, the same as its
+	// seed's index.
+	ID int `json:"id"`
+
+	// Label is the seed's label, if any.
+	Label string `json:"label,omitempty"`
+
+	// Seed is the pixel the region grew from.
+	Seed Point `json:"seed"`
+
+	// Area is the number of pixels in the region.
+	Area int `json:"area"`
+
+	// AreaPercent is the area as a percentage of the image.
+	AreaPercent float64 `json:"area_percent"`
+
+	// Perimeter is the number of pixel edges between the region and
+	// other pixels or the image's edge, holes included.
+	Perimeter int `json:"perimeter"`
+
+	// Bounds is the region's bounding box.
+	Bounds Region `json:"bounds"`
+
+	// Centroid is the mean position of the region's pixels.
+	Centroid Point `json:"centroid"`
+
+	// Color is the region's mean color as hex (#RRGGBB).
+	Color string `json:"color"`
+
+	// RGB is the region's mean color.
+	RGB RGBColor `json:"rgb"`
+
+	// ColorStdDev is the root-mean-square RGB distance of the region's
+	// pixels from its mean color: 0 for a flat area.
+	ColorStdDev float64 `json:"color_std_dev"`
+
+	// Neighbors lists the IDs of the regions that share a border with
+	// this one.
+	Neighbors []int `json:"neighbors"`
+}
+
+// GrowResult contains the regions grown by GrowRegions.
+type GrowResult struct {
+	// Regions are the grown regions, in seed order.
+	Regions []GrownRegion `json:"regions"`
+
+	// UnassignedPixels is the number of pixels no region reached.
+	UnassignedPixels int `json:"unassigned_pixels"`
+
+	// ImageBase64 shows the image with every region tinted and outlined
+	// in its own color, as base64 PNG. Only set when requested.
+	ImageBase64 string `json:"image_base64,omitempty"`
+
+	// MimeType is "image/png" when ImageBase64 is set.
+	MimeType string `json:"mime_type,omitempty"`
+}
+
+// growEntry is a pixel a region may claim.
+type growEntry struct {
+	pixel, region int32
+}
+
+// growStats accumulates the pixels of one region.
+type growStats struct {
+	area           int
+	r, g, b, sq    float64
+	sumX, sumY     int
+	x1, y1, x2, y2 int
+	tolerance      float64
+}
+
+func (s *growStats) add(x, y int, c color.RGBA) {
+	r, g, b := float64(c.R), float64(c.G), float64(c.B)
+	s.area++
+	s.r, s.g, s.b = s.r+r, s.g+g, s.b+b
+	s.sq += r*r + g*g + b*b
+	s.sumX, s.sumY = s.sumX+x, s.sumY+y
+	s.x1, s.y1 = min(s.x1, x), min(s.y1, y)
+	s.x2, s.y2 = max(s.x2, x+1), max(s.y2, y+1)
+}
+
+// distance returns the RGB distance of c from the region's mean color.
+func (s *growStats) distance(c color.RGBA) float64 {
+	n := float64(s.area)
+	dr, dg, db := float64(c.R)-s.r/n, float64(c.G)-s.g/n, float64(c.B)-s.b/n
+	return math.Sqrt(dr*dr + dg*dg + db*db)
+}
+
+// GrowRegions grows a region from each seed at once, for measuring and
+// comparing several areas of one image, such as samples in a gel, wells
+// of a plate, or parts of a photographed object.
+//
+// Parameters:
+//   - img: Source image.
+//   - seeds: 1 to 50 seed pixels, at distinct positions.
+//   - tolerance: How far (1-442) in RGB distance a pixel may be from a
+//     region's mean color to join it, unless a seed sets its own.
+//   - includeImage: Also return the image with the regions marked.
+//
+// Returns:
+//   - *GrowResult: Each region's area, perimeter, bounds, centroid, mean
+//     color, and neighbors.
+//   - error: Non-nil if a seed lies outside the image, two seeds share a
+//     pixel, a tolerance is out of range, or PNG encoding fails.
+//
+// # Algorithm
+//
+// Seeded region growing (Adams and Bischof, 1994): every pixel bordering
+// a region is queued with its RGB distance from the region's mean color,
+// and the closest queued pixel is always taken next, joining its region
+// and updating the mean. The regions thus grow together, and where two
+// meet, each pixel goes to the one it matches better. A pixel farther
+// than its region's tolerance is not taken. Regions are 4-connected;
+// distances are bucketed to whole units.
+func GrowRegions(img image.Image, seeds []GrowSeed, tolerance float64, includeImage bool) (*GrowResult, error) {
+	if len(seeds) < 1 || len(seeds) > maxGrowSeeds {
+		return nil, fmt.Errorf("need 1 to %d seeds, got %d", maxGrowSeeds, len(seeds))
+	}
+	if tolerance < 1 || tolerance > maxColorDistance {
+		return nil, fmt.Errorf("tolerance must be between 1 and %d, got %g", maxColorDistance, tolerance)
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	pix := make([]color.RGBA, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := nrgbaAt(img, b.Min.X+x, b.Min.Y+y)
+			pix[y*w+x] = color.RGBA{c.R, c.G, c.B, 255}
+		}
+	}
+
+	// label holds region index + 1, or 0 for unassigned pixels
+	label := make([]int32, w*h)
+	stats := make([]growStats, len(seeds))
+	buckets := make([][]growEntry, maxColorDistance+1)
+	lowest := len(buckets)
+	push := func(i int, k int32) {
+		s := &stats[k]
+		d := s.distance(pix[i])
+		if d > s.tolerance {
+			return
+		}
+		q := int(math.Round(d))
+		buckets[q] = append(buckets[q], growEntry{int32(i), k})
+		lowest = min(lowest, q)
+	}
+	neighbors := func(i int, visit func(j int)) {
+		x := i % w
+		if i >= w {
+			visit(i - w)
+		}
+		if i+w < len(pix) {
+			visit(i + w)
+		}
+		if x > 0 {
+			visit(i - 1)
+		}
+		if x < w-1 {
+			visit(i + 1)
+		}
+	}
+
+	for k, seed := range seeds {
+		x, y := seed.X-b.Min.X, seed.Y-b.Min.Y
+		if x < 0 || y < 0 || x >= w || y >= h {
+			return nil, fmt.Errorf("seed %d (%d, %d) is outside the image", k+1, seed.X, seed.Y)
+		}
+		if seed.Tolerance < 0 || seed.Tolerance > maxColorDistance {
+			return nil, fmt.Errorf("seed %d: tolerance must be between 1 and %d, got %g", k+1, maxColorDistance, seed.Tolerance)
+		}
+		i := y*w + x
+		if label[i] != 0 {
+			return nil, fmt.Errorf("seeds %d and %d are at the same pixel (%d, %d)", label[i], k+1, seed.X, seed.Y)
+		}
+		label[i] = int32(k + 1)
+		stats[k] = growStats{x1: w, y1: h, tolerance: tolerance}
+		if seed.Tolerance > 0 {
+			stats[k].tolerance = seed.Tolerance
+		}
+		stats[k].add(x, y, pix[i])
+	}
+	for k, seed := range seeds {
+		i := (seed.Y-b.Min.Y)*w + seed.X - b.Min.X
+		neighbors(i, func(j int) {
+			if label[j] == 0 {
+				push(j, int32(k))
+			}
+		})
+	}
+
+	for lowest < len(buckets) {
+		if len(buckets[lowest]) == 0 {
+			buckets[lowest] = nil
+			lowest++
+			continue
+		}
+		e := buckets[lowest][0]
+		buckets[lowest] = buckets[lowest][1:]
+		i, k := int(e.pixel), e.region
+		if label[i] != 0 || stats[k].distance(pix[i]) > stats[k].tolerance {
+			continue
+		}
+		label[i] = k + 1
+		stats[k].add(i%w, i/w, pix[i])
+		neighbors(i, func(j int) {
+			if label[j] == 0 {
+				push(j, k)
+			}
+		})
+	}
+
+	result := &GrowResult{Regions: make([]GrownRegion, len(seeds))}
+	perimeter := make([]int, len(seeds))
+	adjacent := make(map[[2]int]bool)
+	for i, l := range label {
+		if l == 0 {
+			result.UnassignedPixels++
+			continue
+		}
+		k := int(l - 1)
+		perimeter[k] += 4
+		neighbors(i, func(j int) {
+			if m := int(label[j]) - 1; m == k {
+				perimeter[k]--
+			} else if m >= 0 {
+				adjacent[[2]int{min(k, m), max(k, m)}] = true
+			}
+		})
+	}
+	for k, seed := range seeds {
+		s := stats[k]
+		n := float64(s.area)
+		mr, mg, mb := s.r/n, s.g/n, s.b/n
+		c := color.RGBA{uint8(math.Round(mr)), uint8(math.Round(mg)), uint8(math.Round(mb)), 255}
+		variance := s.sq/n - (mr*mr + mg*mg + mb*mb)
+		result.Regions[k] = GrownRegion{
+			ID:          k,
+			Label:       seed.Label,
+			Seed:        Point{X: seed.X, Y: seed.Y},
+			Area:        s.area,
+			AreaPercent: math.Round(n/float64(w*h)*10000) / 100,
+			Perimeter:   perimeter[k],
+			Bounds:      Region{X1: b.Min.X + s.x1, Y1: b.Min.Y + s.y1, X2: b.Min.X + s.x2, Y2: b.Min.Y + s.y2},
+			Centroid: Point{
+				X: b.Min.X + int(math.Round(float64(s.sumX)/n)),
+				Y: b.Min.Y + int(math.Round(float64(s.sumY)/n)),
+			},
+			Color:       fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B),
+			RGB:         RGBColor{R: c.R, G: c.G, B: c.B},
+			ColorStdDev: math.Round(math.Sqrt(math.Max(0, variance))*100) / 100,
+			Neighbors:   []int{},
+		}
+	}
+	for pair := range adjacent {
+		result.Regions[pair[0]].Neighbors = append(result.Regions[pair[0]].Neighbors, pair[1])
+		result.Regions[pair[1]].Neighbors = append(result.Regions[pair[1]].Neighbors, pair[0])
+	}
+	for i := range result.Regions {
+		sort.Ints(result.Regions[i].Neighbors)
+	}
+
+	if includeImage {
+		out := image.NewRGBA(image.Rect(0, 0, w, h))
+		for i, l := range label {
+			c := pix[i]
+			if l != 0 {
+				tint := growColors[int(l-1)%len(growColors)]
+				edge := false
+				neighbors(i, func(j int) { edge = edge || label[j] != l })
+				if edge || i%w == 0 || i%w == w-1 || i < w || i >= len(pix)-w {
+					c = tint
+				} else {
+					c = color.RGBA{uint8((int(c.R) + int(tint.R)) / 2), uint8((int(c.G) + int(tint.G)) / 2), uint8((int(c.B) + int(tint.B)) / 2), 255}
+				}
+			}
+			out.SetRGBA(i%w, i/w, c)
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, out); err != nil {
+			return nil, fmt.Errorf("failed to encode region image: %w", err)
+		}
+		result.ImageBase64 = base64.StdEncoding.EncodeToString(buf.Bytes())
+		result.MimeType = "image/png"
+	}
+	return result, nil
+}
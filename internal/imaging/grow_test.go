@@ -0,0 +1,94 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// growImage returns a 200x100 image: a red band 60 pixels wide, a blue
+// band with a slight gradient beside it, and white beyond, with a gray
+// 20x20 square within the white.
+func growImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 0, 60, 100), image.NewUniform(color.RGBA{200, 30, 30, 255}), image.Point{}, draw.Src)
+	for y := 0; y < 100; y++ {
+		for x := 60; x < 120; x++ {
+			img.SetRGBA(x, y, color.RGBA{20, 40, uint8(160 + (x-60)/3), 255})
+		}
+	}
+	draw.Draw(img, image.Rect(150, 40, 170, 60), image.NewUniform(color.RGBA{128, 128, 128, 255}), image.Point{}, draw.Src)
+	return img
+}
+
+func TestGrowRegions(t *testing.T) {
+	seeds := []GrowSeed{{X: 10, Y: 10, Label: "red"}, {X: 100, Y: 50, Label: "blue"}, {X: 160, Y: 50}}
+	result, err := GrowRegions(growImage(), seeds, 30, true)
+	if err != nil {
+		t.Fatalf("GrowRegions failed: %v", err)
+	}
+	red, blue, gray := result.Regions[0], result.Regions[1], result.Regions[2]
+	if red.Area != 6000 || red.Perimeter != 320 || red.Color != "#C81E1E" || red.ColorStdDev != 0 || red.Label != "red" {
+		t.Errorf("red = %+v", red)
+	}
+	if red.Bounds != (Region{X1: 0, Y1: 0, X2: 60, Y2: 100}) || red.Centroid != (Point{X: 30, Y: 50}) || red.AreaPercent != 30 {
+		t.Errorf("red = %+v", red)
+	}
+	// The gradient is within tolerance of the blue band's mean
+	if blue.Area != 6000 || blue.ColorStdDev < 5 || blue.ColorStdDev > 6 || len(blue.Neighbors) != 1 || blue.Neighbors[0] != 0 {
+		t.Errorf("blue = %+v", blue)
+	}
+	if gray.Area != 400 || gray.Perimeter != 80 || len(gray.Neighbors) != 0 {
+		t.Errorf("gray = %+v", gray)
+	}
+	if result.UnassignedPixels != 200*100-12400 || result.MimeType != "image/png" || result.ImageBase64 == "" {
+		t.Errorf("unassigned %d, mime type %q", result.UnassignedPixels, result.MimeType)
+	}
+}
+
+func TestGrowRegions_Competing(t *testing.T) {
+	// Two seeds in one flat area split it between them
+	img := image.NewRGBA(image.Rect(0, 0, 100, 40))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{90, 90, 90, 255}), image.Point{}, draw.Src)
+	result, err := GrowRegions(img, []GrowSeed{{X: 10, Y: 20}, {X: 90, Y: 20}}, 10, false)
+	if err != nil {
+		t.Fatalf("GrowRegions failed: %v", err)
+	}
+	a, b := result.Regions[0], result.Regions[1]
+	if a.Area+b.Area != 4000 || a.Area < 1800 || b.Area < 1800 || result.UnassignedPixels != 0 {
+		t.Errorf("areas %d and %d", a.Area, b.Area)
+	}
+	if len(a.Neighbors) != 1 || a.Neighbors[0] != 1 || result.ImageBase64 != "" {
+		t.Errorf("neighbors %v", a.Neighbors)
+	}
+
+	// A seed's own tolerance lets it cross into the white
+	result, err = GrowRegions(growImage(), []GrowSeed{{X: 160, Y: 50, Tolerance: 250}}, 30, false)
+	if err != nil {
+		t.Fatalf("GrowRegions failed: %v", err)
+	}
+	if r := result.Regions[0]; r.Area != 80*100 {
+		t.Errorf("area = %d, want the gray square and the white", r.Area)
+	}
+}
+
+func TestGrowRegions_Invalid(t *testing.T) {
+	img := growImage()
+	for _, tc := range []struct {
+		name      string
+		seeds     []GrowSeed
+		tolerance float64
+	}{
+		{"no seeds", nil, 30},
+		{"outside", []GrowSeed{{X: 200, Y: 0}}, 30},
+		{"same pixel", []GrowSeed{{X: 5, Y: 5}, {X: 5, Y: 5}}, 30},
+		{"tolerance", []GrowSeed{{X: 5, Y: 5}}, 0},
+		{"seed tolerance", []GrowSeed{{X: 5, Y: 5, Tolerance: 500}}, 30},
+	} {
+		if _, err := GrowRegions(img, tc.seeds, tc.tolerance, false); err == nil {
+			t.Errorf("%s: expected error", tc.name)
+		}
+	}
+}
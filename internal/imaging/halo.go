@@ -0,0 +1,260 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+)
+
+// Halo detection: pixels at least haloOpaqueAlpha are opaque content, and
+// the edge pixels beside them are compared with the opaque pixels within
+// haloRadius. At most maxHaloRegions clusters are listed, and the
+// visualization is scaled up to haloViewSize pixels on its longer side, by
+// at most haloMaxZoom.
+const (
+	haloOpaqueAlpha = 250
+	haloRadius      = 2
+	maxHaloRegions  = 20
+	haloViewSize    = 256
+	haloMaxZoom     = 16
+)
+
+// haloMark is the color halo pixels are painted in the visualization.
+var haloMark = color.NRGBA{R: 255, G: 0, B: 255, A: 255}
+
+// HaloOptions configures DetectHalo.
+type HaloOptions struct {
+	// Threshold is the smallest difference, in any channel (0-255),
+	// between an edge pixel and every opaque pixel near it for the edge
+	// pixel to count as a halo.
+	Threshold int
+
+	// MinAlpha is the lowest alpha of an edge pixel checked; fainter
+	// pixels are barely visible whatever their color.
+	MinAlpha int
+
+	// IncludeImage adds a visualization of the halo pixels.
+	IncludeImage bool
+}
+
+// HaloRegion is a cluster of halo pixels.
+type HaloRegion struct {
+	// Bounds is the cluster's bounding box.
+	Bounds Region `json:"bounds"`
+
+	// Pixels is the number of halo pixels in the cluster.
+	Pixels int `json:"pixels"`
+}
+
+// HaloResult describes the semi-transparent fringe around the opaque
+// content of an image.
+type HaloResult struct {
+	// HasAlpha is false if every pixel of the image is opaque, leaving no
+	// fringe to check.
+	HasAlpha bool `json:"has_alpha"`
+
+	// EdgePixels is the number of semi-transparent pixels checked: those
+	// with alpha from MinAlpha to 249 within 2 pixels of opaque content.
+	EdgePixels int `json:"edge_pixels"`
+
+	// HaloPixels is the number of edge pixels whose color differs from
+	// all the opaque content near them by at least Threshold, and
+	// HaloPercent their share of EdgePixels.
+	HaloPixels  int     `json:"halo_pixels"`
+	HaloPercent float64 `json:"halo_percent"`
+
+	// LightPixels and DarkPixels split HaloPixels into those lighter than
+	// the content near them, left by a white or light matte, and those
+	// darker, left by a black or dark matte.
+	LightPixels int `json:"light_pixels"`
+	DarkPixels  int `json:"dark_pixels"`
+
+	// HaloColor is the mean color of the halo pixels (#RRGGBB), close to
+	// the matte the image was exported against; empty if there are none.
+	HaloColor string `json:"halo_color,omitempty"`
+
+	// DetachedPixels is the number of semi-transparent pixels, from
+	// MinAlpha, with no opaque pixel within 2 pixels: soft shadows and
+	// glows, translucent fills, or stray alpha noise. They are not
+	// checked for halos.
+	DetachedPixels int `json:"detached_pixels"`
+
+	// Regions are the largest clusters of halo pixels (8-connected), most
+	// pixels first.
+	Regions []HaloRegion `json:"regions"`
+
+	// Passed is true if there are no halo pixels.
+	Passed bool `json:"passed"`
+
+	// ImageBase64 is the image over a background contrasting with the
+	// halo (black for light halos, white for dark ones), with halo pixels
+	// painted magenta and small images scaled up, as base64 PNG. Empty
+	// unless requested.
+	ImageBase64 string `json:"image_base64,omitempty"`
+
+	// MimeType is "image/png" when ImageBase64 is set.
+	MimeType string `json:"mime_type,omitempty"`
+}
+
+// DetectHalo finds matte halos around the opaque content of an icon or
+// sprite: semi-transparent edge pixels whose color is not that of the
+// content they fringe.
+//
+// Parameters:
+//   - img: The image.
+//   - opts: The thresholds, and whether to visualize the halo.
+//
+// Returns:
+//   - *HaloResult: The halo pixel counts, their clusters, and the
+//     visualization.
+//   - error: Non-nil if an option is out of range or PNG encoding fails.
+//
+// # Method
+//
+// An anti-aliased edge of straight (unpremultiplied) alpha keeps the
+// content's color and fades its alpha. An image exported against a matte,
+// or premultiplied and saved as straight alpha, mixes the matte's color
+// into its edge pixels instead, which then show as a light or dark ring
+// on any other background. Each semi-transparent pixel within 2 pixels
+// of an opaque one is compared with every opaque pixel in that reach; it
+// is a halo pixel if it differs from the nearest in color of them by at
+// least Threshold in some channel. Comparing with the nearest color
+// keeps the edges of outlined and multicolored content, which match one
+// of their neighbors, from counting.
+func DetectHalo(img image.Image, opts HaloOptions) (*HaloResult, error) {
+	if opts.Threshold < 1 || opts.Threshold > 255 {
+		return nil, fmt.Errorf("threshold must be between 1 and 255, got %d", opts.Threshold)
+	}
+	if opts.MinAlpha < 1 || opts.MinAlpha >= haloOpaqueAlpha {
+		return nil, fmt.Errorf("min_alpha must be between 1 and %d, got %d", haloOpaqueAlpha-1, opts.MinAlpha)
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	pix := make([]color.NRGBA, w*h)
+	result := &HaloResult{Regions: []HaloRegion{}}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			pix[y*w+x] = nrgbaAt(img, b.Min.X+x, b.Min.Y+y)
+			if pix[y*w+x].A < 255 {
+				result.HasAlpha = true
+			}
+		}
+	}
+
+	halo := make([]bool, w*h)
+	var sumR, sumG, sumB int
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			p := pix[y*w+x]
+			if int(p.A) < opts.MinAlpha || p.A >= haloOpaqueAlpha {
+				continue
+			}
+			nearest, found := 256, false
+			var near color.NRGBA
+			for ny := max(0, y-haloRadius); ny <= min(h-1, y+haloRadius); ny++ {
+				for nx := max(0, x-haloRadius); nx <= min(w-1, x+haloRadius); nx++ {
+					q := pix[ny*w+nx]
+					if q.A < haloOpaqueAlpha {
+						continue
+					}
+					found = true
+					d := max(absInt(int(p.R)-int(q.R)), absInt(int(p.G)-int(q.G)), absInt(int(p.B)-int(q.B)))
+					if d < nearest {
+						nearest, near = d, q
+					}
+				}
+			}
+			if !found {
+				result.DetachedPixels++
+				continue
+			}
+			result.EdgePixels++
+			if nearest < opts.Threshold {
+				continue
+			}
+			halo[y*w+x] = true
+			result.HaloPixels++
+			if luma(p) > luma(near) {
+				result.LightPixels++
+			} else {
+				result.DarkPixels++
+			}
+			sumR, sumG, sumB = sumR+int(p.R), sumG+int(p.G), sumB+int(p.B)
+		}
+	}
+	result.Passed = result.HaloPixels == 0
+	if result.HaloPixels > 0 {
+		n := result.HaloPixels
+		result.HaloPercent = float64(n) / float64(result.EdgePixels) * 100
+		result.HaloColor = fmt.Sprintf("#%02X%02X%02X", (sumR+n/2)/n, (sumG+n/2)/n, (sumB+n/2)/n)
+		result.Regions = haloRegions(halo, w, h)
+		for i := range result.Regions {
+			r := &result.Regions[i].Bounds
+			r.X1, r.Y1, r.X2, r.Y2 = r.X1+b.Min.X, r.Y1+b.Min.Y, r.X2+b.Min.X, r.Y2+b.Min.Y
+		}
+	}
+
+	if opts.IncludeImage {
+		bg := color.NRGBA{A: 255}
+		if result.DarkPixels > result.LightPixels {
+			bg = color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+		}
+		zoom := max(1, min(haloMaxZoom, haloViewSize/max(1, w, h)))
+		view := image.NewNRGBA(image.Rect(0, 0, w*zoom, h*zoom))
+		for y := 0; y < h*zoom; y++ {
+			for x := 0; x < w*zoom; x++ {
+				i := (y/zoom)*w + x/zoom
+				c := haloMark
+				if !halo[i] {
+					c = blendNRGBA(bg, pix[i])
+				}
+				view.SetNRGBA(x, y, c)
+			}
+		}
+		encoded, err := encodePNGBase64(view)
+		if err != nil {
+			return nil, err
+		}
+		result.ImageBase64, result.MimeType = encoded, "image/png"
+	}
+	return result, nil
+}
+
+// haloRegions returns the 8-connected clusters of the set pixels of mask,
+// a w×h image, most pixels first, up to maxHaloRegions.
+func haloRegions(mask []bool, w, h int) []HaloRegion {
+	seen := make([]bool, w*h)
+	var regions []HaloRegion
+	var stack []int
+	for start := range mask {
+		if !mask[start] || seen[start] {
+			continue
+		}
+		r := HaloRegion{Bounds: Region{X1: w, Y1: h}}
+		seen[start] = true
+		stack = append(stack[:0], start)
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x, y := i%w, i/w
+			r.Pixels++
+			r.Bounds.X1, r.Bounds.Y1 = min(r.Bounds.X1, x), min(r.Bounds.Y1, y)
+			r.Bounds.X2, r.Bounds.Y2 = max(r.Bounds.X2, x+1), max(r.Bounds.Y2, y+1)
+			for ny := max(0, y-1); ny <= min(h-1, y+1); ny++ {
+				for nx := max(0, x-1); nx <= min(w-1, x+1); nx++ {
+					if j := ny*w + nx; mask[j] && !seen[j] {
+						seen[j] = true
+						stack = append(stack, j)
+					}
+				}
+			}
+		}
+		regions = append(regions, r)
+	}
+	sort.SliceStable(regions, func(i, j int) bool { return regions[i].Pixels > regions[j].Pixels })
+	if len(regions) > maxHaloRegions {
+		regions = regions[:maxHaloRegions]
+	}
+	return regions
+}
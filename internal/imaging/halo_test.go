@@ -0,0 +1,102 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// haloIcon draws a 32×32 icon on a transparent background: an opaque red
+// square from 8 to 24 with a one-pixel edge of alpha 128 around it in
+// edge, and a faint detached pixel.
+func haloIcon(edge color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+	for y := 7; y < 25; y++ {
+		for x := 7; x < 25; x++ {
+			img.SetNRGBA(x, y, edge)
+		}
+	}
+	for y := 8; y < 24; y++ {
+		for x := 8; x < 24; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{200, 30, 30, 255})
+		}
+	}
+	img.SetNRGBA(2, 2, color.NRGBA{200, 30, 30, 60})
+	return img
+}
+
+var haloOptions = HaloOptions{Threshold: 48, MinAlpha: 16}
+
+func TestDetectHalo_Clean(t *testing.T) {
+	result, err := DetectHalo(haloIcon(color.NRGBA{200, 30, 30, 128}), haloOptions)
+	if err != nil {
+		t.Fatalf("DetectHalo failed: %v", err)
+	}
+	if !result.HasAlpha || !result.Passed || result.HaloPixels != 0 || result.EdgePixels != 68 || result.DetachedPixels != 1 {
+		t.Errorf("got %+v", result)
+	}
+}
+
+func TestDetectHalo_Mattes(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		edge  color.NRGBA // red mixed half and half with the matte
+		light bool
+	}{
+		{"white matte", color.NRGBA{228, 143, 143, 128}, true},
+		{"black matte", color.NRGBA{100, 15, 15, 128}, false},
+	} {
+		opts := haloOptions
+		opts.IncludeImage = true
+		result, err := DetectHalo(haloIcon(tc.edge), opts)
+		if err != nil {
+			t.Fatalf("%s: DetectHalo failed: %v", tc.name, err)
+		}
+		if result.Passed || result.HaloPixels != 68 || result.HaloPercent != 100 {
+			t.Errorf("%s: got %d halo pixels (%.1f%%)", tc.name, result.HaloPixels, result.HaloPercent)
+		}
+		if (result.LightPixels == 68) != tc.light || result.LightPixels+result.DarkPixels != 68 {
+			t.Errorf("%s: got %d light, %d dark", tc.name, result.LightPixels, result.DarkPixels)
+		}
+		if len(result.Regions) != 1 || result.Regions[0].Bounds != (Region{X1: 7, Y1: 7, X2: 25, Y2: 25}) {
+			t.Errorf("%s: regions = %+v", tc.name, result.Regions)
+		}
+
+		data, err := base64.StdEncoding.DecodeString(result.ImageBase64)
+		if err != nil {
+			t.Fatalf("%s: invalid base64: %v", tc.name, err)
+		}
+		view, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: invalid PNG: %v", tc.name, err)
+		}
+		// 32×32 is shown 8 times larger, the halo in magenta
+		if view.Bounds().Dx() != 256 {
+			t.Errorf("%s: view is %v, want 256×256", tc.name, view.Bounds())
+		}
+		if r, g, b, _ := view.At(7*8+3, 16*8).RGBA(); r>>8 != 255 || g>>8 != 0 || b>>8 != 255 {
+			t.Errorf("%s: halo pixel shown as %d,%d,%d", tc.name, r>>8, g>>8, b>>8)
+		}
+	}
+}
+
+func TestDetectHalo_Opaque(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	result, err := DetectHalo(img, haloOptions)
+	if err != nil {
+		t.Fatalf("DetectHalo failed: %v", err)
+	}
+	// image.RGBA's zero pixels are transparent
+	if !result.HasAlpha || !result.Passed {
+		t.Errorf("got %+v", result)
+	}
+
+	for _, opts := range []HaloOptions{{Threshold: 0, MinAlpha: 16}, {Threshold: 48, MinAlpha: 250}} {
+		if _, err := DetectHalo(img, opts); err == nil {
+			t.Errorf("expected error for %+v", opts)
+		}
+	}
+}
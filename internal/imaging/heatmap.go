@@ -0,0 +1,428 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// Heatmap limits.
+const (
+	// maxHeatmapStops is the most color stops a heatmap scale may have.
+	maxHeatmapStops = 32
+
+	// maxHeatmapCells is the most cells ReadHeatmap reads.
+	maxHeatmapCells = 10000
+)
+
+// ScaleStop is one color of a heatmap's scale and the value it stands
+// for.
+type ScaleStop struct {
+	Color string  `json:"color"` // Hex color (#RRGGBB)
+	Value float64 `json:"value"`
+}
+
+// HeatmapScale is a heatmap's color scale.
+type HeatmapScale struct {
+	// Stops are the scale's colors in order, from one end to the other.
+	Stops []ScaleStop
+
+	// Discrete is true if the scale has distinct levels, so each cell
+	// takes the value of its nearest stop, rather than a continuous
+	// gradient interpolated between stops.
+	Discrete bool
+}
+
+// heatmapPresets are the named scales ReadHeatmap callers may use in
+// place of their own.
+var heatmapPresets = map[string]HeatmapScale{
+	// GitHub contribution graph, light theme: no contributions, then four
+	// levels of activity.
+	"github": {Stops: []ScaleStop{
+		{"#EBEDF0", 0}, {"#9BE9A8", 1}, {"#40C463", 2}, {"#30A14E", 3}, {"#216E39", 4},
+	}, Discrete: true},
+
+	// GitHub contribution graph, dark theme.
+	"github_dark": {Stops: []ScaleStop{
+		{"#161B22", 0}, {"#0E4429", 1}, {"#006D32", 2}, {"#26A641", 3}, {"#39D353", 4},
+	}, Discrete: true},
+
+	// Viridis, the default colormap of matplotlib and many plotting
+	// libraries, from 0 to 1.
+	"viridis": {Stops: []ScaleStop{
+		{"#440154", 0}, {"#3B528B", 0.25}, {"#21918C", 0.5}, {"#5EC962", 0.75}, {"#FDE725", 1},
+	}},
+}
+
+// HeatmapPreset returns the named preset scale, and false if there is
+// none.
+func HeatmapPreset(name string) (HeatmapScale, bool) {
+	s, ok := heatmapPresets[name]
+	return s, ok
+}
+
+// HeatmapPresetNames returns the names of the preset scales, sorted.
+func HeatmapPresetNames() []string {
+	names := make([]string, 0, len(heatmapPresets))
+	for name := range heatmapPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HeatmapOptions configures ReadHeatmap.
+type HeatmapOptions struct {
+	// Region is the area holding the cells, or nil for the whole image.
+	// Labels and legends inside it are taken for cells.
+	Region *Region
+
+	// Scale maps cell colors to values.
+	Scale HeatmapScale
+
+	// Rows and Columns, if both set, split Region evenly into cells, for
+	// heatmaps whose cells touch. If both are zero the cells are found.
+	Rows    int
+	Columns int
+
+	// Tolerance is the largest RGB distance (1-442) of a cell's color from
+	// the scale for the cell to be given a value.
+	Tolerance float64
+}
+
+// HeatmapCell is one cell read by ReadHeatmap.
+type HeatmapCell struct {
+	Row    int    `json:"row"`
+	Col    int    `json:"col"`
+	Bounds Region `json:"bounds"`
+
+	// Color is the median color of the cell's middle as hex (#RRGGBB).
+	Color string `json:"color"`
+
+	// Value is the value the color stands for on the scale. Nil if the
+	// cell is empty or its color is off the scale.
+	Value *float64 `json:"value"`
+
+	// Distance is the RGB distance of Color from the nearest point of the
+	// scale: a stop for a discrete scale, anywhere between two consecutive
+	// stops otherwise.
+	Distance float64 `json:"distance"`
+
+	// Empty is true if the cell has the background color, as for the days
+	// missing at the ends of a contribution graph.
+	Empty bool `json:"empty,omitempty"`
+}
+
+// HeatmapSummary summarizes the values read by ReadHeatmap.
+type HeatmapSummary struct {
+	// Valued, Empty, and OffScale count the cells with a value, of the
+	// background color, and too far from the scale.
+	Valued   int `json:"valued"`
+	Empty    int `json:"empty"`
+	OffScale int `json:"off_scale"`
+
+	// Min, Max, Mean, and Sum are over the cells with a value. Zero if
+	// there are none.
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	Mean float64 `json:"mean"`
+	Sum  float64 `json:"sum"`
+}
+
+// HeatmapResult contains the values read from a heatmap.
+type HeatmapResult struct {
+	// Region is the area that was analyzed.
+	Region Region `json:"region"`
+
+	// Layout is how the cells were found: "gaps" between cells of the
+	// background color, grid "lines", or an "even" split into the given
+	// rows and columns.
+	Layout string `json:"layout"`
+
+	// Background is the color around the cells as hex (#RRGGBB), for the
+	// "gaps" layout.
+	Background string `json:"background,omitempty"`
+
+	// Rows and Columns are the bands of cells, top to bottom and left to
+	// right.
+	Rows        []GridBand `json:"rows"`
+	Columns     []GridBand `json:"columns"`
+	RowCount    int        `json:"row_count"`
+	ColumnCount int        `json:"column_count"`
+
+	// Cells are the cells row by row, left to right.
+	Cells []HeatmapCell `json:"cells"`
+
+	// Values are the cells' values, Values[row][col], null where a cell
+	// has none.
+	Values [][]*float64 `json:"values"`
+
+	Summary HeatmapSummary `json:"summary"`
+}
+
+// ReadHeatmap recovers approximate values from a heatmap, such as a
+// contribution graph, a calendar heatmap, or a correlation matrix, by
+// finding its cells and mapping each cell's color onto a color scale.
+//
+// Returns an error if the region is invalid, the scale has fewer than 2
+// or more than 32 stops or an invalid color, Tolerance is out of range,
+// only one of Rows and Columns is set or they exceed the region's size,
+// no cells are found, or there are more than 10000 cells.
+//
+// # Method
+//
+// Unless Rows and Columns are given, the color most common on the
+// region's outline is taken as background, and pixel rows and columns
+// holding only background separate the bands of cells. If that finds
+// fewer than two cells, the lines of a grid are found as by DetectGrid.
+// Each cell's color is the per-channel median of its middle half, clear
+// of borders and rounded corners. In the "gaps" layout, a cell within 10
+// levels of the background is empty.
+func ReadHeatmap(img image.Image, opts HeatmapOptions) (*HeatmapResult, error) {
+	stops, err := parseScale(opts.Scale.Stops)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Tolerance < 1 || opts.Tolerance > maxColorDistance {
+		return nil, fmt.Errorf("tolerance must be between 1 and %d, got %g", maxColorDistance, opts.Tolerance)
+	}
+	if (opts.Rows > 0) != (opts.Columns > 0) || opts.Rows < 0 || opts.Columns < 0 {
+		return nil, fmt.Errorf("rows and columns must both be positive, or both 0 to find the cells")
+	}
+
+	b := img.Bounds()
+	r := Region{X1: 0, Y1: 0, X2: b.Dx(), Y2: b.Dy()}
+	if opts.Region != nil {
+		r = *opts.Region
+		if err := validateRegion(r, b.Dx(), b.Dy()); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Rows > r.Y2-r.Y1 || opts.Columns > r.X2-r.X1 {
+		return nil, fmt.Errorf("%dx%d cells do not fit the %dx%d region", opts.Rows, opts.Columns, r.Y2-r.Y1, r.X2-r.X1)
+	}
+
+	result := &HeatmapResult{Region: r}
+	var bg color.RGBA
+	if opts.Rows > 0 {
+		result.Layout = "even"
+		result.Rows = evenBands(r.Y1, r.Y2, opts.Rows)
+		result.Columns = evenBands(r.X1, r.X2, opts.Columns)
+	} else {
+		bg = outlineColor(img, r)
+		result.Rows, result.Columns = gapBands(img, r, bg)
+		result.Layout = "gaps"
+		result.Background = fmt.Sprintf("#%02X%02X%02X", bg.R, bg.G, bg.B)
+	}
+	if result.Layout == "gaps" && len(result.Rows)*len(result.Columns) < 2 {
+		grid, err := DetectGrid(img, &r, 0.6)
+		if err != nil {
+			return nil, err
+		}
+		if !grid.Found {
+			return nil, fmt.Errorf("no cells found; give rows and columns for a heatmap whose cells touch")
+		}
+		result.Layout, result.Background = "lines", ""
+		result.Rows, result.Columns = grid.Rows, grid.Columns
+	}
+	result.RowCount, result.ColumnCount = len(result.Rows), len(result.Columns)
+	if n := result.RowCount * result.ColumnCount; n > maxHeatmapCells {
+		return nil, fmt.Errorf("found %d cells, more than %d", n, maxHeatmapCells)
+	}
+
+	result.Cells = make([]HeatmapCell, 0, result.RowCount*result.ColumnCount)
+	result.Values = make([][]*float64, result.RowCount)
+	s := &result.Summary
+	for _, row := range result.Rows {
+		result.Values[row.Index] = make([]*float64, result.ColumnCount)
+		for _, col := range result.Columns {
+			bounds := Region{X1: col.Start, Y1: row.Start, X2: col.End, Y2: row.End}
+			c := cellColor(img, bounds)
+			cell := HeatmapCell{
+				Row:    row.Index,
+				Col:    col.Index,
+				Bounds: bounds,
+				Color:  fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B),
+			}
+			if result.Layout == "gaps" && pixelDiff(c, bg) <= pixelDiffThreshold {
+				cell.Empty = true
+				s.Empty++
+				result.Cells = append(result.Cells, cell)
+				continue
+			}
+
+			value, distance := scaleValue(stops, opts.Scale.Discrete, c)
+			cell.Distance = math.Round(distance*10) / 10
+			if distance > opts.Tolerance {
+				s.OffScale++
+				result.Cells = append(result.Cells, cell)
+				continue
+			}
+			value = significant(value, 4)
+			cell.Value = &value
+			result.Values[row.Index][col.Index] = &value
+			if s.Valued == 0 || value < s.Min {
+				s.Min = value
+			}
+			if s.Valued == 0 || value > s.Max {
+				s.Max = value
+			}
+			s.Valued++
+			s.Sum += value
+			result.Cells = append(result.Cells, cell)
+		}
+	}
+	if s.Valued > 0 {
+		s.Mean = significant(s.Sum/float64(s.Valued), 4)
+		s.Sum = significant(s.Sum, 6)
+	}
+	return result, nil
+}
+
+// scaleStop is a parsed ScaleStop.
+type scaleStop struct {
+	c     [3]float64
+	value float64
+}
+
+// parseScale parses the stops of a heatmap scale.
+func parseScale(stops []ScaleStop) ([]scaleStop, error) {
+	if len(stops) < 2 || len(stops) > maxHeatmapStops {
+		return nil, fmt.Errorf("scale needs 2 to %d stops, got %d", maxHeatmapStops, len(stops))
+	}
+	parsed := make([]scaleStop, len(stops))
+	for i, s := range stops {
+		c, err := parseHexColor(s.Color)
+		if err != nil {
+			return nil, fmt.Errorf("scale stop %d: invalid color %q: %w", i, s.Color, err)
+		}
+		parsed[i] = scaleStop{c: [3]float64{float64(c.R), float64(c.G), float64(c.B)}, value: s.Value}
+	}
+	return parsed, nil
+}
+
+// scaleValue returns the value c stands for on the scale, and its RGB
+// distance from the scale: from the nearest stop if discrete, or from the
+// nearest point on the path through consecutive stops otherwise.
+func scaleValue(stops []scaleStop, discrete bool, c color.RGBA) (float64, float64) {
+	p := [3]float64{float64(c.R), float64(c.G), float64(c.B)}
+	dist := func(q [3]float64) float64 {
+		return math.Sqrt((p[0]-q[0])*(p[0]-q[0]) + (p[1]-q[1])*(p[1]-q[1]) + (p[2]-q[2])*(p[2]-q[2]))
+	}
+
+	best, bestDist := stops[0].value, dist(stops[0].c)
+	for _, s := range stops[1:] {
+		if d := dist(s.c); d < bestDist {
+			best, bestDist = s.value, d
+		}
+	}
+	if discrete {
+		return best, bestDist
+	}
+	for i := 0; i+1 < len(stops); i++ {
+		a, z := stops[i], stops[i+1]
+		var dot, len2 float64
+		for k := 0; k < 3; k++ {
+			dot += (p[k] - a.c[k]) * (z.c[k] - a.c[k])
+			len2 += (z.c[k] - a.c[k]) * (z.c[k] - a.c[k])
+		}
+		if len2 == 0 {
+			continue
+		}
+		t := math.Max(0, math.Min(1, dot/len2))
+		var q [3]float64
+		for k := 0; k < 3; k++ {
+			q[k] = a.c[k] + t*(z.c[k]-a.c[k])
+		}
+		if d := dist(q); d < bestDist {
+			best, bestDist = a.value+t*(z.value-a.value), d
+		}
+	}
+	return best, bestDist
+}
+
+// outlineColor returns the most common color on the outline of region r.
+func outlineColor(img image.Image, r Region) color.RGBA {
+	b := img.Bounds()
+	counts := make(map[color.RGBA]int)
+	var best color.RGBA
+	add := func(x, y int) {
+		c := nrgbaAt(img, b.Min.X+x, b.Min.Y+y)
+		k := color.RGBA{c.R, c.G, c.B, 255}
+		counts[k]++
+		if counts[k] > counts[best] {
+			best = k
+		}
+	}
+	for x := r.X1; x < r.X2; x++ {
+		add(x, r.Y1)
+		add(x, r.Y2-1)
+	}
+	for y := r.Y1 + 1; y < r.Y2-1; y++ {
+		add(r.X1, y)
+		add(r.X2-1, y)
+	}
+	return best
+}
+
+// gapBands returns the rows and columns of region r holding pixels other
+// than the background, separated by pixel rows and columns of background.
+func gapBands(img image.Image, r Region, bg color.RGBA) ([]GridBand, []GridBand) {
+	b := img.Bounds()
+	w, h := r.X2-r.X1, r.Y2-r.Y1
+	rowFilled, colFilled := make([]bool, h), make([]bool, w)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if pixelDiff(img.At(b.Min.X+r.X1+x, b.Min.Y+r.Y1+y), bg) > pixelDiffThreshold {
+				rowFilled[y], colFilled[x] = true, true
+			}
+		}
+	}
+	runs := func(filled []bool, origin int) []GridBand {
+		bands := []GridBand{}
+		for i := 0; i < len(filled); i++ {
+			if !filled[i] {
+				continue
+			}
+			start := i
+			for i < len(filled) && filled[i] {
+				i++
+			}
+			bands = append(bands, GridBand{Index: len(bands), Start: origin + start, End: origin + i, Size: i - start})
+		}
+		return bands
+	}
+	return runs(rowFilled, r.Y1), runs(colFilled, r.X1)
+}
+
+// evenBands splits start to end into n bands of nearly equal size.
+func evenBands(start, end, n int) []GridBand {
+	bands := make([]GridBand, n)
+	for i := range bands {
+		s, e := start+i*(end-start)/n, start+(i+1)*(end-start)/n
+		bands[i] = GridBand{Index: i, Start: s, End: e, Size: e - s}
+	}
+	return bands
+}
+
+// cellColor returns the per-channel median color of the middle half of
+// cell r.
+func cellColor(img image.Image, r Region) color.RGBA {
+	b := img.Bounds()
+	dx, dy := (r.X2-r.X1)/4, (r.Y2-r.Y1)/4
+	var rs, gs, bs []float64
+	for y := r.Y1 + dy; y < r.Y2-dy; y++ {
+		for x := r.X1 + dx; x < r.X2-dx; x++ {
+			c := nrgbaAt(img, b.Min.X+x, b.Min.Y+y)
+			rs, gs, bs = append(rs, float64(c.R)), append(gs, float64(c.G)), append(bs, float64(c.B))
+		}
+	}
+	return color.RGBA{
+		uint8(math.Round(medianFloat(rs))),
+		uint8(math.Round(medianFloat(gs))),
+		uint8(math.Round(medianFloat(bs))),
+		255,
+	}
+}
@@ -0,0 +1,145 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// contributionGraph draws a 7-row contribution graph of 10-pixel cells 3
+// pixels apart on white, cycling through the GitHub levels. The last week
+// has only three days, and one day is red.
+func contributionGraph() *image.RGBA {
+	levels := []color.RGBA{
+		{0xEB, 0xED, 0xF0, 255}, {0x9B, 0xE9, 0xA8, 255}, {0x40, 0xC4, 0x63, 255},
+		{0x30, 0xA1, 0x4E, 255}, {0x21, 0x6E, 0x39, 255},
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 75, 101))
+	fillRect(img, 0, 0, 75, 101, color.RGBA{255, 255, 255, 255})
+	for col := 0; col < 5; col++ {
+		for row := 0; row < 7; row++ {
+			if col == 4 && row >= 3 {
+				break
+			}
+			c := levels[(col*7+row)%5]
+			if col == 2 && row == 2 {
+				c = color.RGBA{220, 30, 30, 255}
+			}
+			x, y := 5+col*13, 5+row*13
+			fillRect(img, x, y, x+10, y+10, c)
+		}
+	}
+	return img
+}
+
+func TestReadHeatmap_Gaps(t *testing.T) {
+	scale, _ := HeatmapPreset("github")
+	result, err := ReadHeatmap(contributionGraph(), HeatmapOptions{Scale: scale, Tolerance: 40})
+	if err != nil {
+		t.Fatalf("ReadHeatmap failed: %v", err)
+	}
+	if result.Layout != "gaps" || result.Background != "#FFFFFF" || result.RowCount != 7 || result.ColumnCount != 5 {
+		t.Fatalf("got %s layout on %s, %dx%d", result.Layout, result.Background, result.RowCount, result.ColumnCount)
+	}
+	if c := result.Columns[1]; c.Start != 18 || c.End != 28 {
+		t.Errorf("column 1 = %+v", c)
+	}
+
+	for _, cell := range result.Cells {
+		v := result.Values[cell.Row][cell.Col]
+		switch {
+		case cell.Col == 4 && cell.Row >= 3:
+			if !cell.Empty || v != nil {
+				t.Errorf("missing day (%d, %d) = %+v", cell.Row, cell.Col, cell)
+			}
+		case cell.Col == 2 && cell.Row == 2:
+			if cell.Empty || v != nil || cell.Distance < 40 {
+				t.Errorf("red day = %+v", cell)
+			}
+		default:
+			if want := float64((cell.Col*7 + cell.Row) % 5); v == nil || *v != want || cell.Distance != 0 {
+				t.Errorf("day (%d, %d) = %+v, want %v", cell.Row, cell.Col, cell, want)
+			}
+		}
+	}
+	s := result.Summary
+	if s.Valued != 30 || s.Empty != 4 || s.OffScale != 1 || s.Min != 0 || s.Max != 4 || s.Sum != 59 {
+		t.Errorf("summary = %+v", s)
+	}
+}
+
+func TestReadHeatmap_Even(t *testing.T) {
+	// Four touching cells: viridis stops and a color halfway between the
+	// first two
+	img := image.NewRGBA(image.Rect(0, 0, 80, 20))
+	for i, c := range []color.RGBA{{0x44, 0x01, 0x54, 255}, {0x21, 0x91, 0x8C, 255}, {0xFD, 0xE7, 0x25, 255}, {0x40, 0x2A, 0x70, 255}} {
+		fillRect(img, i*20, 0, i*20+20, 20, c)
+	}
+	scale, _ := HeatmapPreset("viridis")
+	result, err := ReadHeatmap(img, HeatmapOptions{Scale: scale, Rows: 1, Columns: 4, Tolerance: 40})
+	if err != nil {
+		t.Fatalf("ReadHeatmap failed: %v", err)
+	}
+	if result.Layout != "even" || result.Background != "" {
+		t.Errorf("layout %s, background %q", result.Layout, result.Background)
+	}
+	want := []float64{0, 0.5, 1, 0.125}
+	for i, v := range result.Values[0] {
+		if v == nil || *v < want[i]-0.01 || *v > want[i]+0.01 {
+			t.Errorf("values %v, want %v", result.Cells, want)
+			break
+		}
+	}
+
+	// As discrete levels, the last is far from any stop and snaps to the
+	// nearest, the second, once the tolerance allows
+	scale.Discrete = true
+	result, _ = ReadHeatmap(img, HeatmapOptions{Scale: scale, Rows: 1, Columns: 4, Tolerance: 40})
+	if result.Values[0][3] != nil || result.Summary.OffScale != 1 {
+		t.Errorf("discrete = %+v", result.Cells[3])
+	}
+	result, _ = ReadHeatmap(img, HeatmapOptions{Scale: scale, Rows: 1, Columns: 4, Tolerance: 60})
+	if v := result.Values[0][3]; v == nil || *v != 0.25 {
+		t.Errorf("discrete = %+v", result.Cells[3])
+	}
+}
+
+func TestReadHeatmap_Lines(t *testing.T) {
+	// A 2x2 table ruled in black, its cells filled with GitHub levels
+	img := image.NewRGBA(image.Rect(0, 0, 70, 70))
+	fillRect(img, 0, 0, 70, 70, color.RGBA{255, 255, 255, 255})
+	fillRect(img, 5, 5, 66, 66, color.RGBA{0, 0, 0, 255})
+	fillRect(img, 6, 6, 35, 35, color.RGBA{0x9B, 0xE9, 0xA8, 255})
+	fillRect(img, 36, 6, 65, 35, color.RGBA{0x21, 0x6E, 0x39, 255})
+	fillRect(img, 6, 36, 35, 65, color.RGBA{0xEB, 0xED, 0xF0, 255})
+	fillRect(img, 36, 36, 65, 65, color.RGBA{0x40, 0xC4, 0x63, 255})
+
+	scale, _ := HeatmapPreset("github")
+	result, err := ReadHeatmap(img, HeatmapOptions{Scale: scale, Tolerance: 40})
+	if err != nil {
+		t.Fatalf("ReadHeatmap failed: %v", err)
+	}
+	if result.Layout != "lines" || result.RowCount != 2 || result.ColumnCount != 2 {
+		t.Fatalf("got %s layout, %dx%d", result.Layout, result.RowCount, result.ColumnCount)
+	}
+	if result.Summary.Sum != 7 || *result.Values[0][1] != 4 || *result.Values[1][0] != 0 {
+		t.Errorf("cells = %+v", result.Cells)
+	}
+}
+
+func TestReadHeatmap_Invalid(t *testing.T) {
+	img := contributionGraph()
+	scale, _ := HeatmapPreset("github")
+	for _, opts := range []HeatmapOptions{
+		{Scale: HeatmapScale{Stops: scale.Stops[:1]}, Tolerance: 40},
+		{Scale: HeatmapScale{Stops: []ScaleStop{{"#000000", 0}, {"blue", 1}}}, Tolerance: 40},
+		{Scale: scale, Tolerance: 0},
+		{Scale: scale, Rows: 3, Tolerance: 40},
+		{Scale: scale, Rows: 1, Columns: 500, Tolerance: 40},
+		{Scale: scale, Region: &Region{X1: 0, Y1: 0, X2: 4, Y2: 4}, Tolerance: 40},
+	} {
+		if _, err := ReadHeatmap(img, opts); err == nil {
+			t.Errorf("%+v: expected error", opts)
+		}
+	}
+}
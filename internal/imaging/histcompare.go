@@ -0,0 +1,140 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// histogramLevels is the number of bins per channel of the color
+// histograms intersected by compareDistributions: 8, each 32 levels wide.
+const histogramLevels = 8
+
+// DistributionComparison compares the color distributions of two regions,
+// which, unlike their pixels, hardly change when content shifts by a few
+// pixels or picks up noise.
+type DistributionComparison struct {
+	// Similarity (0-1) is the intersection of the regions' color
+	// histograms: the share of pixels whose colors both regions have.
+	// Noise of a few levels lowers it by a few percent.
+	Similarity float64 `json:"similarity"`
+
+	// EarthMoversDistance is how far, in levels (0-255), one region's
+	// channel values must move on average to match the other's, the mean
+	// over R, G, and B. Near 0 for the same colors, shifted or noisy.
+	EarthMoversDistance float64 `json:"earth_movers_distance"`
+}
+
+// CompareRegionsMethod is CompareRegionsAlpha by method: "pixel" (or
+// empty) compares pixel by pixel; "histogram" also compares the color
+// distributions of the same pixels, in Distribution, so content that is
+// the same but slightly shifted scores low on SimilarityScore and high on
+// Distribution.Similarity; and "edges" also compares their edges, in
+// Edges, so the same layout in other colors, such as a light and a dark
+// theme, matches.
+//
+// Returns an error if method is unknown, or as CompareRegionsAlpha does.
+func CompareRegionsMethod(img image.Image, r1, r2 Region, mask *image.Alpha, alpha AlphaOptions, method string) (*CompareRegionsResult, error) {
+	switch method {
+	case "", "pixel", "histogram", "edges":
+	default:
+		return nil, fmt.Errorf("method must be \"pixel\", \"histogram\", or \"edges\", got %q", method)
+	}
+	result, err := CompareRegionsAlpha(img, r1, r2, mask, alpha)
+	if err != nil {
+		return nil, err
+	}
+	switch method {
+	case "histogram":
+		result.Distribution = compareDistributions(img, r1, r2, mask, alpha)
+	case "edges":
+		result.Edges = compareEdges(img, r1, r2, mask, alpha)
+	}
+	return result, nil
+}
+
+// compareDistributions compares the color distributions of the pixels of
+// r1 and r2 that CompareRegionsAlpha compares.
+//
+// The histograms have 8 bins a channel, 512 in all, each pixel shared
+// between the two nearest bins of each channel in proportion, so noise
+// that carries a color across a bin's edge moves little of it. The distance
+// is the 1-D Earth Mover's Distance between each channel's 256-level
+// histograms, the area between their cumulative distributions.
+func compareDistributions(img image.Image, r1, r2 Region, mask *image.Alpha, alpha AlphaOptions) *DistributionComparison {
+	// The same pixels, read again without counting their alpha twice
+	w := min(r1.X2-r1.X1, r2.X2-r2.X1)
+	h := min(r1.Y2-r1.Y1, r2.Y2-r2.Y1)
+	var colors [2][histogramLevels * histogramLevels * histogramLevels]float64
+	var levels [2][3][256]float64
+	var counts [2]float64
+	for i, r := range []Region{r1, r2} {
+		read, _ := alpha.reader()
+		for dy := 0; dy < h; dy++ {
+			for dx := 0; dx < w; dx++ {
+				if !maskSelects(mask, dx, dy) {
+					continue
+				}
+				cr, cg, cb, content := read.at(img, r.X1+dx, r.Y1+dy)
+				if !content {
+					continue
+				}
+				v := [3]uint8{uint8(cr >> 8), uint8(cg >> 8), uint8(cb >> 8)}
+				for c := range v {
+					levels[i][c][v[c]]++
+				}
+				addSoftBins(&colors[i], v)
+				counts[i]++
+			}
+		}
+	}
+
+	d := &DistributionComparison{Similarity: 1}
+	if counts[0] > 0 && counts[1] > 0 {
+		intersection := 0.0
+		for k := range colors[0] {
+			intersection += math.Min(colors[0][k]/counts[0], colors[1][k]/counts[1])
+		}
+		emd := 0.0
+		for c := 0; c < 3; c++ {
+			var c1, c2 float64
+			for v := 0; v < 256; v++ {
+				c1 += levels[0][c][v] / counts[0]
+				c2 += levels[1][c][v] / counts[1]
+				emd += math.Abs(c1 - c2)
+			}
+		}
+		d.Similarity = math.Round(math.Min(1, intersection)*1000) / 1000
+		d.EarthMoversDistance = math.Round(emd/3*100) / 100
+	} else if counts[0] != counts[1] {
+		// Content in one region only
+		d.Similarity, d.EarthMoversDistance = 0, 255
+	}
+	return d
+}
+
+// addSoftBins adds a pixel of color v to the histogram bins, shared
+// between the two bins nearest each channel's value in proportion to
+// their closeness.
+func addSoftBins(bins *[histogramLevels * histogramLevels * histogramLevels]float64, v [3]uint8) {
+	var lo [3]int
+	var frac [3]float64
+	for c := range v {
+		p := (float64(v[c])+0.5)/(256/histogramLevels) - 0.5
+		p = math.Max(0, math.Min(histogramLevels-1, p))
+		lo[c] = min(int(p), histogramLevels-2)
+		frac[c] = p - float64(lo[c])
+	}
+	for corner := 0; corner < 8; corner++ {
+		weight, index := 1.0, 0
+		for c := 0; c < 3; c++ {
+			bin, f := lo[c], 1-frac[c]
+			if corner&(1<<c) != 0 {
+				bin, f = lo[c]+1, frac[c]
+			}
+			weight *= f
+			index = index*histogramLevels + bin
+		}
+		bins[index] += weight
+	}
+}
@@ -0,0 +1,61 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestCompareRegionsMethod_Histogram(t *testing.T) {
+	// Two copies of striped content, the second shifted 3 pixels right and
+	// speckled with noise, and a third in other colors
+	img := image.NewRGBA(image.Rect(0, 0, 180, 40))
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 60; x++ {
+			c := color.RGBA{240, 240, 240, 255}
+			if (x/4)%2 == 0 {
+				c = color.RGBA{30, 60, 200, 255}
+			}
+			img.SetRGBA(x, y, c)
+			n := uint8((x*7 + y*13) % 4)
+			shifted := color.RGBA{240 - n, 240 - n, 240 - n, 255}
+			if ((x+3)/4)%2 == 0 {
+				shifted = color.RGBA{30 + n, 60 + n, 200 - n, 255}
+			}
+			img.SetRGBA(60+x, y, shifted)
+			other := color.RGBA{200, 40, 40, 255}
+			if (x/4)%2 == 0 {
+				other = color.RGBA{20, 20, 20, 255}
+			}
+			img.SetRGBA(120+x, y, other)
+		}
+	}
+	a, b, c := Region{X1: 0, Y1: 0, X2: 60, Y2: 40}, Region{X1: 60, Y1: 0, X2: 120, Y2: 40}, Region{X1: 120, Y1: 0, X2: 180, Y2: 40}
+
+	result, err := CompareRegionsMethod(img, a, b, nil, AlphaOptions{}, "histogram")
+	if err != nil {
+		t.Fatalf("CompareRegionsMethod failed: %v", err)
+	}
+	d := result.Distribution
+	if result.SimilarityScore > 0.5 || d == nil || d.Similarity < 0.9 || d.EarthMoversDistance > 12 {
+		t.Errorf("shifted: pixel %g, distribution %+v", result.SimilarityScore, d)
+	}
+
+	result, _ = CompareRegionsMethod(img, a, c, nil, AlphaOptions{}, "histogram")
+	if d := result.Distribution; d.Similarity > 0.2 || d.EarthMoversDistance < 50 {
+		t.Errorf("different: distribution %+v", d)
+	}
+
+	result, _ = CompareRegionsMethod(img, a, a, nil, AlphaOptions{}, "histogram")
+	if d := result.Distribution; d.Similarity != 1 || d.EarthMoversDistance != 0 {
+		t.Errorf("same: distribution %+v", d)
+	}
+
+	result, _ = CompareRegionsMethod(img, a, b, nil, AlphaOptions{}, "")
+	if result.Distribution != nil {
+		t.Errorf("pixel method reported a distribution: %+v", result.Distribution)
+	}
+	if _, err := CompareRegionsMethod(img, a, b, nil, AlphaOptions{}, "emd"); err == nil {
+		t.Error("expected error for an unknown method")
+	}
+}
@@ -0,0 +1,673 @@
+package imaging
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf16"
+)
+
+// ColorProfile describes the color profile embedded in an image file, and
+// whether the image's pixels were converted from it to sRGB on load.
+//
+// Images without a profile are assumed to be sRGB and have no
+// ColorProfile.
+type ColorProfile struct {
+	// Name is the profile's description, such as "Display P3" or
+	// "sRGB IEC61966-2.1". It is empty if the profile has none.
+	Name string `json:"name"`
+
+	// Source is where the profile came from: "icc" for an embedded ICC
+	// profile, or "png_srgb" for a PNG sRGB chunk.
+	Source string `json:"source"`
+
+	// ColorSpace is the color space of the profile's data: "rgb", "gray",
+	// "cmyk", or another ICC color space signature in lower case.
+	ColorSpace string `json:"color_space"`
+
+	// Version is the ICC specification version of the profile, such as
+	// "2.1" or "4.3". It is empty for a PNG sRGB chunk.
+	Version string `json:"version,omitempty"`
+
+	// IsSRGB reports whether the profile is sRGB, or so close to it that
+	// no conversion is needed.
+	IsSRGB bool `json:"is_srgb"`
+
+	// Converted reports whether the pixels were converted from the profile
+	// to sRGB on load. When true, every tool sees sRGB values; colors
+	// outside the sRGB gamut are clipped to its edge.
+	Converted bool `json:"converted"`
+
+	// Note explains why a profile other than sRGB was not converted.
+	Note string `json:"note,omitempty"`
+
+	// opaque is set when the image had no alpha channel before conversion
+	opaque bool
+}
+
+// maxICCProfileSize bounds the size of an embedded ICC profile. Real
+// profiles are a few kilobytes; lookup-table profiles rarely exceed 1 MB.
+const maxICCProfileSize = 4 << 20
+
+// iccProfile is the part of an ICC profile needed to convert to sRGB.
+type iccProfile struct {
+	name       string
+	colorSpace string // Header signature, lower case and trimmed
+	version    string
+
+	// matrix holds the rXYZ, gXYZ, and bXYZ colorants as columns,
+	// mapping linear RGB to the D50 profile connection space. It is nil
+	// for profiles without colorant tags.
+	matrix *[3][3]float64
+
+	// curves are the rTRC, gTRC, and bTRC tone curves, or the kTRC curve
+	// in curves[0] for a gray profile. nil if absent.
+	curves [3]toneCurve
+}
+
+// toneCurve maps an encoded channel value in [0, 1] to linear light.
+type toneCurve func(float64) float64
+
+// srgbToXYZD50 maps linear sRGB to CIE XYZ, Bradford-adapted to D50, the
+// white point of the ICC profile connection space.
+var srgbToXYZD50 = [3][3]float64{
+	{0.4360747, 0.3850649, 0.1430804},
+	{0.2225045, 0.7168786, 0.0606169},
+	{0.0139322, 0.0971045, 0.7141733},
+}
+
+// xyzD50ToSRGB is the inverse of srgbToXYZD50.
+var xyzD50ToSRGB = [3][3]float64{
+	{3.1338561, -1.6168667, -0.4906146},
+	{-0.9787684, 1.9161415, 0.0334540},
+	{0.0719453, -0.2289914, 1.4052427},
+}
+
+// srgbLinear is the sRGB tone curve.
+func srgbLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// srgbEncode is the inverse of srgbLinear.
+func srgbEncode(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+var (
+	srgbEncodeOnce  sync.Once
+	srgbEncodeTable []uint16
+)
+
+// srgbEncode16 returns the 16-bit sRGB encoding of a linear value,
+// clipped to [0, 1], from a table built on first use.
+func srgbEncode16(v float64) uint16 {
+	srgbEncodeOnce.Do(func() {
+		srgbEncodeTable = make([]uint16, 65536)
+		for i := range srgbEncodeTable {
+			srgbEncodeTable[i] = uint16(math.Round(srgbEncode(float64(i)/65535) * 65535))
+		}
+	})
+	switch {
+	case !(v > 0): // Also NaN, from a degenerate curve
+		return 0
+	case v >= 1:
+		return 65535
+	}
+	return srgbEncodeTable[int(v*65535+0.5)]
+}
+
+// readEmbeddedProfile returns the color profile stored in a PNG, JPEG, or
+// TIFF file: the ICC profile, or srgb set for a PNG sRGB chunk. Both are
+// empty for other formats and for files without a profile.
+func readEmbeddedProfile(r io.ReaderAt) (icc []byte, srgb bool, err error) {
+	magic := make([]byte, 8)
+	if n, _ := r.ReadAt(magic, 0); n < len(magic) {
+		return nil, false, nil
+	}
+	br := bufio.NewReader(io.NewSectionReader(r, 0, math.MaxInt64))
+	switch {
+	case bytes.HasPrefix(magic, []byte("\x89PNG\r\n\x1a\n")):
+		return readPNGProfile(br)
+	case bytes.HasPrefix(magic, []byte{0xff, 0xd8}):
+		icc, err = readJPEGProfile(br)
+		return icc, false, err
+	case bytes.HasPrefix(magic, []byte("II*\x00")), bytes.HasPrefix(magic, []byte("MM\x00*")):
+		icc, err = readTIFFProfile(r, magic)
+		return icc, false, err
+	}
+	return nil, false, nil
+}
+
+// readPNGProfile reads the iCCP or sRGB chunk of a PNG file, stopping at
+// the first IDAT chunk, after which neither may appear.
+func readPNGProfile(r io.Reader) (icc []byte, srgb bool, err error) {
+	if _, err := io.CopyN(io.Discard, r, 8); err != nil {
+		return nil, false, err
+	}
+	var header [8]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return nil, false, err
+		}
+		length := binary.BigEndian.Uint32(header[:4])
+		switch string(header[4:]) {
+		case "IDAT", "IEND":
+			return nil, srgb, nil
+		case "sRGB":
+			srgb = true
+		case "iCCP":
+			if length > maxICCProfileSize {
+				return nil, false, fmt.Errorf("iCCP chunk too large")
+			}
+			data := make([]byte, length)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return nil, false, err
+			}
+			// Profile name, NUL, compression method (0 = zlib), profile
+			name := bytes.IndexByte(data, 0)
+			if name < 0 || name+2 > len(data) || data[name+1] != 0 {
+				return nil, false, fmt.Errorf("malformed iCCP chunk")
+			}
+			zr, err := zlib.NewReader(bytes.NewReader(data[name+2:]))
+			if err != nil {
+				return nil, false, err
+			}
+			icc, err = io.ReadAll(io.LimitReader(zr, maxICCProfileSize))
+			return icc, false, err
+		}
+		if _, err := io.CopyN(io.Discard, r, int64(length)+4); err != nil { // Data and CRC
+			return nil, false, err
+		}
+	}
+}
+
+// readJPEGProfile reads the ICC profile from the APP2 segments of a JPEG
+// file, stopping at the start of scan. A profile too large for one
+// segment is split across several, numbered from 1.
+func readJPEGProfile(br *bufio.Reader) ([]byte, error) {
+	if _, err := br.Discard(2); err != nil {
+		return nil, err
+	}
+	chunks := map[int][]byte{}
+	total := 0
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b != 0xff {
+			return nil, fmt.Errorf("malformed JPEG marker")
+		}
+		marker, err := br.ReadByte()
+		for err == nil && marker == 0xff { // Fill bytes
+			marker, err = br.ReadByte()
+		}
+		if err != nil {
+			return nil, err
+		}
+		if marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7) { // No length
+			continue
+		}
+		if marker == 0xda || marker == 0xd9 { // Start of scan, end of image
+			break
+		}
+		var length [2]byte
+		if _, err := io.ReadFull(br, length[:]); err != nil {
+			return nil, err
+		}
+		n := int(binary.BigEndian.Uint16(length[:])) - 2
+		if n < 0 {
+			return nil, fmt.Errorf("malformed JPEG segment")
+		}
+		data := make([]byte, n)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return nil, err
+		}
+		const iccTag = "ICC_PROFILE\x00"
+		if marker != 0xe2 || len(data) < len(iccTag)+2 || string(data[:len(iccTag)]) != iccTag {
+			continue
+		}
+		total += len(data) - len(iccTag) - 2
+		if total > maxICCProfileSize {
+			return nil, fmt.Errorf("ICC profile too large")
+		}
+		chunks[int(data[len(iccTag)])] = data[len(iccTag)+2:]
+	}
+	if len(chunks) == 0 {
+		return nil, nil
+	}
+	seqs := make([]int, 0, len(chunks))
+	for seq := range chunks {
+		seqs = append(seqs, seq)
+	}
+	sort.Ints(seqs)
+	var icc []byte
+	for _, seq := range seqs {
+		icc = append(icc, chunks[seq]...)
+	}
+	return icc, nil
+}
+
+// tiffICCProfileTag is the TIFF tag holding an embedded ICC profile.
+const tiffICCProfileTag = 34675
+
+// readTIFFProfile reads the ICC profile tag of the first image of a TIFF
+// file, given the file's 8-byte header.
+func readTIFFProfile(r io.ReaderAt, header []byte) ([]byte, error) {
+	var order binary.ByteOrder = binary.LittleEndian
+	if header[0] == 'M' {
+		order = binary.BigEndian
+	}
+	ifd := int64(order.Uint32(header[4:8]))
+	var count [2]byte
+	if _, err := r.ReadAt(count[:], ifd); err != nil {
+		return nil, err
+	}
+	entries := make([]byte, 12*int(order.Uint16(count[:])))
+	if _, err := r.ReadAt(entries, ifd+2); err != nil {
+		return nil, err
+	}
+	for e := entries; len(e) >= 12; e = e[12:] {
+		if order.Uint16(e) != tiffICCProfileTag {
+			continue
+		}
+		// An UNDEFINED (7) or BYTE (1) array, too long to be stored inline
+		size := order.Uint32(e[4:8])
+		if typ := order.Uint16(e[2:4]); (typ != 7 && typ != 1) || size <= 4 || size > maxICCProfileSize {
+			return nil, fmt.Errorf("malformed TIFF ICC profile tag")
+		}
+		icc := make([]byte, size)
+		if _, err := r.ReadAt(icc, int64(order.Uint32(e[8:12]))); err != nil {
+			return nil, err
+		}
+		return icc, nil
+	}
+	return nil, nil
+}
+
+// parseICCProfile reads the header, description, colorants, and tone
+// curves of an ICC profile.
+func parseICCProfile(data []byte) (*iccProfile, error) {
+	if len(data) < 132 || string(data[36:40]) != "acsp" {
+		return nil, errors.New("not an ICC profile")
+	}
+	p := &iccProfile{
+		colorSpace: strings.ToLower(strings.TrimSpace(string(data[16:20]))),
+		version:    fmt.Sprintf("%d.%d", data[8], data[9]>>4),
+	}
+	count := int(binary.BigEndian.Uint32(data[128:132]))
+	if count > (len(data)-132)/12 {
+		return nil, errors.New("truncated ICC tag table")
+	}
+	tags := make(map[string][]byte, count)
+	for i := 0; i < count; i++ {
+		entry := data[132+12*i:]
+		offset := int64(binary.BigEndian.Uint32(entry[4:8]))
+		size := int64(binary.BigEndian.Uint32(entry[8:12]))
+		if offset+size > int64(len(data)) || size < 8 {
+			return nil, fmt.Errorf("ICC tag %q out of range", entry[:4])
+		}
+		tags[string(entry[:4])] = data[offset : offset+size]
+	}
+
+	if desc, ok := tags["desc"]; ok {
+		p.name = iccText(desc)
+	}
+	colorants := []string{"rXYZ", "gXYZ", "bXYZ"}
+	var m [3][3]float64
+	found := 0
+	for col, sig := range colorants {
+		tag, ok := tags[sig]
+		if !ok || len(tag) < 20 || string(tag[:4]) != "XYZ " {
+			continue
+		}
+		for row := 0; row < 3; row++ {
+			m[row][col] = s15Fixed16(tag[8+4*row:])
+		}
+		found++
+	}
+	if found == 3 {
+		p.matrix = &m
+	}
+	curveTags := []string{"rTRC", "gTRC", "bTRC"}
+	if p.colorSpace == "gray" {
+		curveTags = []string{"kTRC"}
+	}
+	for i, sig := range curveTags {
+		if tag, ok := tags[sig]; ok {
+			curve, err := parseToneCurve(tag)
+			if err != nil {
+				return nil, fmt.Errorf("ICC tag %s: %w", sig, err)
+			}
+			p.curves[i] = curve
+		}
+	}
+	return p, nil
+}
+
+// s15Fixed16 decodes an ICC signed 15.16 fixed-point number.
+func s15Fixed16(b []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(b))) / 65536
+}
+
+// iccText decodes a textDescriptionType (ICC v2) or multiLocalizedUnicode
+// (v4) tag, preferring the English record of the latter.
+func iccText(tag []byte) string {
+	switch string(tag[:4]) {
+	case "desc":
+		if len(tag) < 12 {
+			return ""
+		}
+		n := int(binary.BigEndian.Uint32(tag[8:12]))
+		if n > len(tag)-12 {
+			return ""
+		}
+		return strings.TrimRight(string(tag[12:12+n]), "\x00")
+	case "mluc":
+		if len(tag) < 16 {
+			return ""
+		}
+		records := int(binary.BigEndian.Uint32(tag[8:12]))
+		size := int(binary.BigEndian.Uint32(tag[12:16]))
+		text := ""
+		for i := 0; i < records && size >= 12 && 16+(i+1)*size <= len(tag); i++ {
+			rec := tag[16+i*size:]
+			n := int(binary.BigEndian.Uint32(rec[4:8]))
+			offset := int(binary.BigEndian.Uint32(rec[8:12]))
+			if offset+n > len(tag) || n%2 != 0 {
+				continue
+			}
+			units := make([]uint16, n/2)
+			for j := range units {
+				units[j] = binary.BigEndian.Uint16(tag[offset+2*j:])
+			}
+			s := strings.TrimRight(string(utf16.Decode(units)), "\x00")
+			if text == "" || string(rec[:2]) == "en" {
+				text = s
+			}
+			if string(rec[:2]) == "en" {
+				break
+			}
+		}
+		return text
+	}
+	return ""
+}
+
+// parseToneCurve decodes a curveType or parametricCurveType tag.
+func parseToneCurve(tag []byte) (toneCurve, error) {
+	switch string(tag[:4]) {
+	case "curv":
+		if len(tag) < 12 {
+			return nil, errors.New("truncated curve")
+		}
+		n := int(binary.BigEndian.Uint32(tag[8:12]))
+		if n > (len(tag)-12)/2 {
+			return nil, errors.New("truncated curve")
+		}
+		switch n {
+		case 0:
+			return func(v float64) float64 { return v }, nil
+		case 1:
+			gamma := float64(binary.BigEndian.Uint16(tag[12:14])) / 256
+			return func(v float64) float64 { return math.Pow(v, gamma) }, nil
+		}
+		table := make([]float64, n)
+		for i := range table {
+			table[i] = float64(binary.BigEndian.Uint16(tag[12+2*i:])) / 65535
+		}
+		return func(v float64) float64 {
+			pos := v * float64(n-1)
+			i := min(int(pos), n-2)
+			frac := pos - float64(i)
+			return table[i]*(1-frac) + table[i+1]*frac
+		}, nil
+
+	case "para":
+		if len(tag) < 12 {
+			return nil, errors.New("truncated curve")
+		}
+		fn := binary.BigEndian.Uint16(tag[8:10])
+		counts := []int{1, 3, 4, 5, 7}
+		if int(fn) >= len(counts) || len(tag) < 12+4*counts[fn] {
+			return nil, fmt.Errorf("unsupported parametric curve type %d", fn)
+		}
+		// Parameters g, a, b, c, d, e, f; those not stored default to
+		// values that reduce the general form to the stored one
+		prm := [7]float64{1, 1, 0, 0, 0, 0, 0}
+		for i := 0; i < counts[fn]; i++ {
+			prm[i] = s15Fixed16(tag[12+4*i:])
+		}
+		g, a, b, c, d, e, f := prm[0], prm[1], prm[2], prm[3], prm[4], prm[5], prm[6]
+		switch fn {
+		case 1:
+			d = -b / a
+		case 2:
+			d, e, f = -b/a, c, c
+			c = 0
+		}
+		return func(v float64) float64 {
+			if v >= d {
+				if base := a*v + b; base > 0 {
+					return math.Pow(base, g) + e
+				}
+				return e
+			}
+			return c*v + f
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported curve type %q", tag[:4])
+}
+
+// isSRGB reports whether a matrix/TRC profile matches sRGB: colorants
+// within 0.002 and tone curves within half an 8-bit level of it.
+func (p *iccProfile) isSRGB() bool {
+	if p.matrix == nil || p.curves[0] == nil || p.curves[1] == nil || p.curves[2] == nil {
+		return false
+	}
+	for row := range p.matrix {
+		for col := range p.matrix[row] {
+			if math.Abs(p.matrix[row][col]-srgbToXYZD50[row][col]) > 0.002 {
+				return false
+			}
+		}
+	}
+	for _, curve := range p.curves {
+		for i := 0; i <= 32; i++ {
+			v := float64(i) / 32
+			if math.Abs(srgbEncode(curve(v))-v) > 0.5/255 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// applyColorProfile describes an image's embedded profile and, if convert
+// is set, converts the image from it to sRGB. It returns the image to use
+// and nil if the file has no profile.
+func applyColorProfile(img image.Image, icc []byte, srgb, convert bool) (image.Image, *ColorProfile) {
+	if icc == nil {
+		if !srgb {
+			return img, nil
+		}
+		return img, &ColorProfile{Name: "sRGB", Source: "png_srgb", ColorSpace: "rgb", IsSRGB: true}
+	}
+	info := &ColorProfile{Source: "icc"}
+	p, err := parseICCProfile(icc)
+	if err != nil {
+		info.Note = fmt.Sprintf("unreadable profile (%v); colors were not converted", err)
+		return img, info
+	}
+	info.Name, info.ColorSpace, info.Version = p.name, p.colorSpace, p.version
+
+	_, gray := img.(*image.Gray)
+	_, gray16 := img.(*image.Gray16)
+	switch {
+	case p.colorSpace == "rgb" && p.isSRGB():
+		info.IsSRGB = true
+		return img, info
+	case p.colorSpace == "rgb" && (p.matrix == nil || p.curves[0] == nil || p.curves[1] == nil || p.curves[2] == nil):
+		info.Note = "profiles built on lookup tables are not supported; colors were not converted"
+		return img, info
+	case p.colorSpace == "gray" && p.curves[0] == nil:
+		info.Note = "profile has no gray tone curve; colors were not converted"
+		return img, info
+	case p.colorSpace == "gray" && !gray && !gray16:
+		info.Note = "gray profile on a color image; colors were not converted"
+		return img, info
+	case p.colorSpace != "rgb" && p.colorSpace != "gray":
+		info.Note = fmt.Sprintf("%s profiles are not supported; colors were not converted", strings.ToUpper(p.colorSpace))
+		return img, info
+	case !convert:
+		info.Note = "conversion to sRGB is disabled (IMAGE_MCP_COLOR_CONVERT)"
+		return img, info
+	}
+
+	info.Converted = true
+	info.opaque = img.ColorModel() == color.YCbCrModel || gray || gray16
+	if p.colorSpace == "gray" {
+		return convertGray(img, p.curves[0]), info
+	}
+	return convertRGB(img, p), info
+}
+
+// rgbConverter converts colors from a matrix/TRC profile to sRGB.
+type rgbConverter struct {
+	m      [3][3]float64 // Linear profile RGB to linear sRGB
+	curves [3]toneCurve
+	lut8   [3][]float64 // Linear values of the 256 8-bit levels
+}
+
+func newRGBConverter(p *iccProfile) *rgbConverter {
+	cv := &rgbConverter{curves: p.curves}
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			for k := 0; k < 3; k++ {
+				cv.m[row][col] += xyzD50ToSRGB[row][k] * p.matrix[k][col]
+			}
+		}
+	}
+	for ch := range cv.lut8 {
+		cv.lut8[ch] = make([]float64, 256)
+		for i := range cv.lut8[ch] {
+			cv.lut8[ch][i] = p.curves[ch](float64(i) / 255)
+		}
+	}
+	return cv
+}
+
+// convert maps linear profile RGB to 16-bit sRGB.
+func (cv *rgbConverter) convert(r, g, b float64) (uint16, uint16, uint16) {
+	return srgbEncode16(cv.m[0][0]*r + cv.m[0][1]*g + cv.m[0][2]*b),
+		srgbEncode16(cv.m[1][0]*r + cv.m[1][1]*g + cv.m[1][2]*b),
+		srgbEncode16(cv.m[2][0]*r + cv.m[2][1]*g + cv.m[2][2]*b)
+}
+
+// convert8 converts an 8-bit color.
+func (cv *rgbConverter) convert8(c color.NRGBA) color.NRGBA {
+	r, g, b := cv.convert(cv.lut8[0][c.R], cv.lut8[1][c.G], cv.lut8[2][c.B])
+	return color.NRGBA{to8(r), to8(g), to8(b), c.A}
+}
+
+// to8 rounds a 16-bit channel value to 8 bits.
+func to8(v uint16) uint8 {
+	return uint8((uint32(v)*255 + 32767) / 65535)
+}
+
+// convertRGB converts an RGB image to sRGB. Palette images keep their
+// palette; 16-bit images stay 16-bit; all others become *image.NRGBA.
+func convertRGB(img image.Image, p *iccProfile) image.Image {
+	cv := newRGBConverter(p)
+	b := img.Bounds()
+	switch src := img.(type) {
+	case *image.Paletted:
+		palette := make(color.Palette, len(src.Palette))
+		for i, c := range src.Palette {
+			palette[i] = cv.convert8(color.NRGBAModel.Convert(c).(color.NRGBA))
+		}
+		out := image.NewPaletted(b, palette)
+		copy(out.Pix, src.Pix)
+		return out
+
+	case *image.NRGBA64, *image.RGBA64, *image.Gray16:
+		out := image.NewNRGBA64(b)
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				c := color.NRGBA64Model.Convert(img.At(x, y)).(color.NRGBA64)
+				r, g, bl := cv.convert(cv.curves[0](float64(c.R)/65535), cv.curves[1](float64(c.G)/65535), cv.curves[2](float64(c.B)/65535))
+				out.SetNRGBA64(x, y, color.NRGBA64{r, g, bl, c.A})
+			}
+		}
+		return out
+	}
+
+	out := image.NewNRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			out.SetNRGBA(x, y, cv.convert8(nrgbaAt(img, x, y)))
+		}
+	}
+	return out
+}
+
+// nrgbaAt returns the non-premultiplied 8-bit color of a pixel, reading
+// the common decoded image types directly.
+func nrgbaAt(img image.Image, x, y int) color.NRGBA {
+	switch src := img.(type) {
+	case *image.NRGBA:
+		return src.NRGBAAt(x, y)
+	case *image.YCbCr:
+		c := src.YCbCrAt(x, y)
+		r, g, b := color.YCbCrToRGB(c.Y, c.Cb, c.Cr)
+		return color.NRGBA{r, g, b, 255}
+	case *image.RGBA:
+		if c := src.RGBAAt(x, y); c.A == 255 {
+			return color.NRGBA{c.R, c.G, c.B, 255}
+		}
+	}
+	return color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+}
+
+// convertGray converts a gray image through a gray tone curve to the
+// sRGB tone curve.
+func convertGray(img image.Image, curve toneCurve) image.Image {
+	b := img.Bounds()
+	if src, ok := img.(*image.Gray16); ok {
+		out := image.NewGray16(b)
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				out.SetGray16(x, y, color.Gray16{srgbEncode16(curve(float64(src.Gray16At(x, y).Y) / 65535))})
+			}
+		}
+		return out
+	}
+	src := img.(*image.Gray)
+	var lut [256]uint8
+	for i := range lut {
+		lut[i] = to8(srgbEncode16(curve(float64(i) / 255)))
+	}
+	out := image.NewGray(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			out.SetGray(x, y, color.Gray{lut[src.GrayAt(x, y).Y]})
+		}
+	}
+	return out
+}
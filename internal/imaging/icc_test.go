@@ -0,0 +1,442 @@
+package imaging
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+
+	"golang.org/x/image/tiff"
+)
+
+// D50-adapted colorants (columns) of two common wide-gamut profiles
+var (
+	displayP3Colorants = [3][3]float64{
+		{0.5151, 0.2920, 0.1571},
+		{0.2412, 0.6922, 0.0666},
+		{-0.0011, 0.0419, 0.7841},
+	}
+	adobeRGBColorants = [3][3]float64{
+		{0.6097, 0.2053, 0.1492},
+		{0.3111, 0.6257, 0.0632},
+		{0.0195, 0.0609, 0.7446},
+	}
+)
+
+// iccTag is one tag of a test ICC profile.
+type iccTag struct {
+	sig  string
+	data []byte
+}
+
+// buildICC returns an ICC profile with the given header fields and tags.
+func buildICC(space string, major byte, tags []iccTag) []byte {
+	header := make([]byte, 128)
+	header[8] = major
+	copy(header[12:], "mntr")
+	copy(header[16:], space)
+	copy(header[20:], "XYZ ")
+	copy(header[36:], "acsp")
+	table := binary.BigEndian.AppendUint32(nil, uint32(len(tags)))
+	var data []byte
+	offset := 128 + 4 + 12*len(tags)
+	for _, t := range tags {
+		table = append(table, t.sig...)
+		table = binary.BigEndian.AppendUint32(table, uint32(offset+len(data)))
+		table = binary.BigEndian.AppendUint32(table, uint32(len(t.data)))
+		data = append(data, t.data...)
+		for len(data)%4 != 0 {
+			data = append(data, 0)
+		}
+	}
+	profile := append(append(header, table...), data...)
+	binary.BigEndian.PutUint32(profile, uint32(len(profile)))
+	return profile
+}
+
+func fixed16(v float64) []byte {
+	return binary.BigEndian.AppendUint32(nil, uint32(int32(math.Round(v*65536))))
+}
+
+func descTag(name string) []byte {
+	tag := append([]byte("desc\x00\x00\x00\x00"), binary.BigEndian.AppendUint32(nil, uint32(len(name)+1))...)
+	return append(append(tag, name...), 0)
+}
+
+func mlucTag(name string) []byte {
+	tag := []byte("mluc\x00\x00\x00\x00")
+	tag = binary.BigEndian.AppendUint32(tag, 1)
+	tag = binary.BigEndian.AppendUint32(tag, 12)
+	tag = append(tag, "enUS"...)
+	tag = binary.BigEndian.AppendUint32(tag, uint32(2*len(name)))
+	tag = binary.BigEndian.AppendUint32(tag, 28)
+	for _, u := range utf16.Encode([]rune(name)) {
+		tag = binary.BigEndian.AppendUint16(tag, u)
+	}
+	return tag
+}
+
+func xyzTag(x, y, z float64) []byte {
+	tag := []byte("XYZ \x00\x00\x00\x00")
+	for _, v := range []float64{x, y, z} {
+		tag = append(tag, fixed16(v)...)
+	}
+	return tag
+}
+
+func gammaCurve(gamma float64) []byte {
+	tag := binary.BigEndian.AppendUint32([]byte("curv\x00\x00\x00\x00"), 1)
+	return binary.BigEndian.AppendUint16(tag, uint16(math.Round(gamma*256)))
+}
+
+// srgbCurve is the sRGB tone curve as a type 3 parametric curve.
+func srgbCurve() []byte {
+	tag := []byte("para\x00\x00\x00\x00\x00\x03\x00\x00")
+	for _, v := range []float64{2.4, 1 / 1.055, 0.055 / 1.055, 1 / 12.92, 0.04045} {
+		tag = append(tag, fixed16(v)...)
+	}
+	return tag
+}
+
+// rgbProfile returns a matrix/TRC RGB profile with one curve for all
+// three channels.
+func rgbProfile(major byte, desc []byte, colorants [3][3]float64, curve []byte) []byte {
+	tags := []iccTag{{"desc", desc}}
+	for col, sig := range []string{"rXYZ", "gXYZ", "bXYZ"} {
+		tags = append(tags, iccTag{sig, xyzTag(colorants[0][col], colorants[1][col], colorants[2][col])})
+	}
+	for _, sig := range []string{"rTRC", "gTRC", "bTRC"} {
+		tags = append(tags, iccTag{sig, curve})
+	}
+	return buildICC("RGB ", major, tags)
+}
+
+// pngChunk returns a PNG chunk with its length and CRC.
+func pngChunk(typ string, data []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(append(chunk, typ...), data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+// writePNGWithChunk encodes img as a PNG with an extra chunk after IHDR.
+func writePNGWithChunk(t *testing.T, img image.Image, typ string, data []byte) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+	out := append(append(append([]byte(nil), encoded[:33]...), pngChunk(typ, data)...), encoded[33:]...)
+	path := filepath.Join(t.TempDir(), "profile.png")
+	if err := os.WriteFile(path, out, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writePNGWithProfile encodes img as a PNG with an iCCP chunk.
+func writePNGWithProfile(t *testing.T, img image.Image, icc []byte) string {
+	t.Helper()
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	zw.Write(icc)
+	zw.Close()
+	return writePNGWithChunk(t, img, "iCCP", append([]byte("test\x00\x00"), z.Bytes()...))
+}
+
+// writeJPEGWithProfile encodes img as a JPEG with the ICC profile split
+// across APP2 segments of at most chunk bytes.
+func writeJPEGWithProfile(t *testing.T, img image.Image, icc []byte, chunk int) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+	out := append([]byte(nil), encoded[:2]...)
+	count := (len(icc) + chunk - 1) / chunk
+	for i := 0; i < count; i++ {
+		part := icc[i*chunk : min(len(icc), (i+1)*chunk)]
+		segment := append([]byte("ICC_PROFILE\x00"), byte(i+1), byte(count))
+		segment = append(segment, part...)
+		out = append(out, 0xff, 0xe2)
+		out = binary.BigEndian.AppendUint16(out, uint16(len(segment)+2))
+		out = append(out, segment...)
+	}
+	out = append(out, encoded[2:]...)
+	path := filepath.Join(t.TempDir(), "profile.jpg")
+	if err := os.WriteFile(path, out, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writeTIFFWithProfile encodes img as a TIFF and, if icc is not nil,
+// adds an ICC profile tag to its image directory.
+func writeTIFFWithProfile(t *testing.T, img image.Image, icc []byte) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := tiff.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.Bytes()
+	if icc != nil {
+		// Append the profile and a copy of the directory with the tag
+		// added last, as it has the highest number, and point to it
+		order := binary.LittleEndian
+		ifd := order.Uint32(out[4:8])
+		n := int(order.Uint16(out[ifd:]))
+		entries := append([]byte(nil), out[ifd+2:int(ifd)+2+12*n]...)
+		iccOffset := len(out)
+		out = append(out, icc...)
+		entry := order.AppendUint16(nil, tiffICCProfileTag)
+		entry = order.AppendUint16(entry, 7)
+		entry = order.AppendUint32(entry, uint32(len(icc)))
+		entry = order.AppendUint32(entry, uint32(iccOffset))
+		order.PutUint32(out[4:8], uint32(len(out)))
+		out = order.AppendUint16(out, uint16(n+1))
+		out = append(append(out, entries...), entry...)
+		out = order.AppendUint32(out, 0)
+	}
+	path := filepath.Join(t.TempDir(), "image.tiff")
+	if err := os.WriteFile(path, out, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// assertColor fails if a pixel differs from want by more than tolerance
+// in any channel.
+func assertColor(t *testing.T, img image.Image, x, y int, want color.NRGBA, tolerance int) {
+	t.Helper()
+	got := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+	diff := func(a, b uint8) int { return max(int(a)-int(b), int(b)-int(a)) }
+	if diff(got.R, want.R) > tolerance || diff(got.G, want.G) > tolerance || diff(got.B, want.B) > tolerance || got.A != want.A {
+		t.Errorf("pixel (%d, %d): got %v, want %v ±%d", x, y, got, want, tolerance)
+	}
+}
+
+func TestLoad_DisplayP3PNG(t *testing.T) {
+	// sRGB red in Display P3 coordinates, mid gray, and translucent blue
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{234, 51, 35, 255})
+	img.SetNRGBA(1, 0, color.NRGBA{128, 128, 128, 255})
+	img.SetNRGBA(2, 0, color.NRGBA{0, 0, 255, 100})
+	path := writePNGWithProfile(t, img, rgbProfile(2, descTag("Display P3"), displayP3Colorants, srgbCurve()))
+
+	cache := NewImageCache()
+	info, err := LoadImageInfo(cache, path)
+	if err != nil {
+		t.Fatalf("LoadImageInfo failed: %v", err)
+	}
+	p := info.ColorProfile
+	if p == nil || p.Name != "Display P3" || p.Source != "icc" || p.ColorSpace != "rgb" || p.Version != "2.0" || p.IsSRGB || !p.Converted || p.Note != "" {
+		t.Fatalf("profile: got %+v", p)
+	}
+	if !info.HasAlpha || info.ColorDepth != "8-bit" {
+		t.Errorf("got has_alpha=%v, depth %s", info.HasAlpha, info.ColorDepth)
+	}
+	loaded, _ := cache.Load(path)
+	assertColor(t, loaded, 0, 0, color.NRGBA{255, 0, 0, 255}, 2)
+	assertColor(t, loaded, 1, 0, color.NRGBA{128, 128, 128, 255}, 1)
+	// P3 blue is outside sRGB, so it is clipped; alpha is kept
+	assertColor(t, loaded, 2, 0, color.NRGBA{0, 0, 255, 100}, 1)
+
+	// With conversion off, the profile is reported and pixels kept
+	cache = NewImageCache()
+	cache.SetColorConversion(false)
+	info, err = LoadImageInfo(cache, path)
+	if err != nil {
+		t.Fatalf("LoadImageInfo failed: %v", err)
+	}
+	if p := info.ColorProfile; p == nil || p.Converted || p.Note == "" {
+		t.Errorf("unconverted profile: got %+v", p)
+	}
+	loaded, _ = cache.Load(path)
+	assertColor(t, loaded, 0, 0, color.NRGBA{234, 51, 35, 255}, 0)
+}
+
+func TestLoad_AdobeRGBJPEG(t *testing.T) {
+	// Adobe RGB green, outside sRGB, and neutral gray over a profile
+	// split across three APP2 segments
+	img := image.NewRGBA(image.Rect(0, 0, 32, 16))
+	fillRect(img, 0, 0, 16, 16, color.RGBA{0, 255, 0, 255})
+	fillRect(img, 16, 0, 32, 16, color.RGBA{128, 128, 128, 255})
+	icc := rgbProfile(4, mlucTag("Adobe RGB (1998)"), adobeRGBColorants, gammaCurve(2.2))
+	path := writeJPEGWithProfile(t, img, icc, 200)
+
+	cache := NewImageCache()
+	info, err := LoadImageInfo(cache, path)
+	if err != nil {
+		t.Fatalf("LoadImageInfo failed: %v", err)
+	}
+	if p := info.ColorProfile; p == nil || p.Name != "Adobe RGB (1998)" || p.Version != "4.0" || !p.Converted {
+		t.Fatalf("profile: got %+v", p)
+	}
+	if info.HasAlpha {
+		t.Error("converted JPEG reports an alpha channel")
+	}
+	loaded, _ := cache.Load(path)
+	assertColor(t, loaded, 8, 8, color.NRGBA{0, 255, 0, 255}, 3)
+	// Gamma 2.2 gray 128 is slightly lighter in sRGB
+	assertColor(t, loaded, 24, 8, color.NRGBA{129, 129, 129, 255}, 2)
+}
+
+func TestLoad_SRGBProfiles(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	fillRect(img, 0, 0, 4, 4, color.RGBA{200, 100, 50, 255})
+
+	// An embedded sRGB profile is recognized and needs no conversion
+	path := writePNGWithProfile(t, img, rgbProfile(2, descTag("sRGB IEC61966-2.1"), srgbToXYZD50, srgbCurve()))
+	cache := NewImageCache()
+	info, err := LoadImageInfo(cache, path)
+	if err != nil {
+		t.Fatalf("LoadImageInfo failed: %v", err)
+	}
+	if p := info.ColorProfile; p == nil || !p.IsSRGB || p.Converted || p.Note != "" {
+		t.Errorf("sRGB profile: got %+v", p)
+	}
+	if loaded, _ := cache.Load(path); loaded.(*image.RGBA).Pix[0] != 200 {
+		t.Error("sRGB image was modified")
+	}
+
+	// As is a PNG sRGB chunk
+	path = writePNGWithChunk(t, img, "sRGB", []byte{0})
+	info, err = LoadImageInfo(cache, path)
+	if err != nil {
+		t.Fatalf("LoadImageInfo failed: %v", err)
+	}
+	if p := info.ColorProfile; p == nil || p.Source != "png_srgb" || !p.IsSRGB || p.Converted {
+		t.Errorf("sRGB chunk: got %+v", p)
+	}
+
+	// A file without a profile has none
+	info, err = LoadImageInfo(cache, createTestImage(t, 4, 4, color.White))
+	if err != nil {
+		t.Fatalf("LoadImageInfo failed: %v", err)
+	}
+	if info.ColorProfile != nil {
+		t.Errorf("no profile: got %+v", info.ColorProfile)
+	}
+}
+
+func TestLoad_GrayProfile(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 2, 2))
+	for i := range img.Pix {
+		img.Pix[i] = 50
+	}
+	icc := buildICC("GRAY", 2, []iccTag{{"desc", descTag("Linear Gray")}, {"kTRC", gammaCurve(1)}})
+	path := writePNGWithProfile(t, img, icc)
+
+	cache := NewImageCache()
+	info, err := LoadImageInfo(cache, path)
+	if err != nil {
+		t.Fatalf("LoadImageInfo failed: %v", err)
+	}
+	if p := info.ColorProfile; p == nil || p.ColorSpace != "gray" || !p.Converted {
+		t.Fatalf("profile: got %+v", p)
+	}
+	loaded, _ := cache.Load(path)
+	// Linear 50/255 is 122 in sRGB
+	if g, ok := loaded.(*image.Gray); !ok || g.Pix[0] != 122 {
+		t.Errorf("got %T %v, want gray 122", loaded, loaded.At(0, 0))
+	}
+}
+
+func TestLoad_UnsupportedProfiles(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	fillRect(img, 0, 0, 4, 4, color.RGBA{10, 20, 30, 255})
+	tests := []struct {
+		name string
+		icc  []byte
+	}{
+		{"cmyk", buildICC("CMYK", 2, []iccTag{{"desc", descTag("Coated FOGRA39")}})},
+		{"lut", buildICC("RGB ", 4, []iccTag{{"desc", descTag("LUT RGB")}, {"A2B0", []byte("mAB \x00\x00\x00\x00")}})},
+		{"malformed", []byte("not a profile")},
+	}
+	for _, tt := range tests {
+		cache := NewImageCache()
+		path := writePNGWithProfile(t, img, tt.icc)
+		info, err := LoadImageInfo(cache, path)
+		if err != nil {
+			t.Fatalf("%s: LoadImageInfo failed: %v", tt.name, err)
+		}
+		if p := info.ColorProfile; p == nil || p.Converted || p.Note == "" {
+			t.Errorf("%s: got %+v", tt.name, p)
+		}
+		loaded, _ := cache.Load(path)
+		assertColor(t, loaded, 0, 0, color.NRGBA{10, 20, 30, 255}, 0)
+	}
+}
+
+func TestParseToneCurve(t *testing.T) {
+	table := binary.BigEndian.AppendUint32([]byte("curv\x00\x00\x00\x00"), 3)
+	for _, v := range []uint16{0, 16384, 65535} {
+		table = binary.BigEndian.AppendUint16(table, v)
+	}
+	tests := []struct {
+		name string
+		tag  []byte
+		in   float64
+		want float64
+	}{
+		{"identity", binary.BigEndian.AppendUint32([]byte("curv\x00\x00\x00\x00"), 0), 0.3, 0.3},
+		{"gamma", gammaCurve(2), 0.5, 0.25},
+		{"table", table, 0.25, 0.125},
+		{"srgb", srgbCurve(), 0.5, srgbLinear(0.5)},
+		{"srgb toe", srgbCurve(), 0.02, 0.02 / 12.92},
+	}
+	for _, tt := range tests {
+		curve, err := parseToneCurve(tt.tag)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := curve(tt.in); math.Abs(got-tt.want) > 0.001 {
+			t.Errorf("%s: curve(%v) = %.4f, want %.4f", tt.name, tt.in, got, tt.want)
+		}
+	}
+	if _, err := parseToneCurve([]byte("para\x00\x00\x00\x00\x00\x09\x00\x00")); err == nil {
+		t.Error("expected error for parametric curve type 9")
+	}
+}
+
+func TestLoad_TIFF16(t *testing.T) {
+	img := image.NewNRGBA64(image.Rect(0, 0, 4, 4))
+	for i := 0; i < 16; i++ {
+		img.SetNRGBA64(i%4, i/4, color.NRGBA64{0x1234, 0x5678, 0x9ABC, 0xFFFF})
+	}
+
+	// Without a profile, every 16-bit value is kept
+	cache := NewImageCache()
+	path := writeTIFFWithProfile(t, img, nil)
+	info, err := LoadImageInfo(cache, path)
+	if err != nil {
+		t.Fatalf("LoadImageInfo failed: %v", err)
+	}
+	if info.Format != "tiff" || info.ColorDepth != "16-bit" || info.ColorProfile != nil {
+		t.Errorf("got format %s, depth %s, profile %+v", info.Format, info.ColorDepth, info.ColorProfile)
+	}
+	loaded, _ := cache.Load(path)
+	if got := color.NRGBA64Model.Convert(loaded.At(2, 2)).(color.NRGBA64); got != (color.NRGBA64{0x1234, 0x5678, 0x9ABC, 0xFFFF}) {
+		t.Errorf("got %v", got)
+	}
+
+	// A profile in the directory is converted, still at 16 bits
+	path = writeTIFFWithProfile(t, img, rgbProfile(2, descTag("Display P3"), displayP3Colorants, srgbCurve()))
+	info, err = LoadImageInfo(cache, path)
+	if err != nil {
+		t.Fatalf("LoadImageInfo failed: %v", err)
+	}
+	if p := info.ColorProfile; p == nil || p.Name != "Display P3" || !p.Converted || info.ColorDepth != "16-bit" {
+		t.Errorf("got profile %+v, depth %s", p, info.ColorDepth)
+	}
+}
@@ -0,0 +1,441 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+func init() {
+	image.RegisterFormat("ico", "\x00\x00\x01\x00", decodeLargestIcon, decodeLargestIconConfig)
+	image.RegisterFormat("icns", "icns", decodeLargestIcon, decodeLargestIconConfig)
+}
+
+// maxIconDimension is the largest width or height of an icon entry that is
+// decoded. The largest standard size is 1024 (ICNS ic10); entries claiming
+// more are skipped before any pixels are allocated, since the cache's
+// pixel limit only sees the icon after it is decoded.
+const maxIconDimension = 1024
+
+// maxIconEntries is the most entries decoded from one container. Real
+// icons have a dozen or so; the cap stops a small file whose directory
+// points many entries at the same large bitmap from decoding it over and
+// over. Entries that fail to decode count too, since a truncated PNG
+// allocates its full image before failing.
+const maxIconEntries = 32
+
+// IconEntry describes one sub-image stored in an icon container.
+type IconEntry struct {
+	// Index is the position of the entry within the container.
+	Index int `json:"index"`
+
+	// Width and Height are the decoded dimensions in pixels.
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// BitsPerPixel is the color depth declared in the ICO directory.
+	// Zero for ICNS entries, which are always 32-bit PNG.
+	BitsPerPixel int `json:"bits_per_pixel,omitempty"`
+
+	// Encoding is how the entry is stored: "png" or "bmp".
+	Encoding string `json:"encoding"`
+
+	// Image is the decoded sub-image.
+	Image image.Image `json:"-"`
+}
+
+// Icon is a decoded icon container (.ico or .icns).
+type Icon struct {
+	// Format is "ico" or "icns".
+	Format string
+
+	// Entries are the decoded sub-images, sorted by width (smallest first).
+	Entries []IconEntry
+}
+
+// ReadIcon decodes every supported sub-image in an ICO or ICNS container.
+//
+// ICO entries may be PNG or BMP (1, 4, 8, 24, or 32 bits per pixel, with the
+// AND transparency mask applied). ICNS entries stored as PNG are decoded;
+// legacy RLE and JPEG 2000 entries are skipped, as are entries larger than
+// 1024x1024. At most 32 entries are read.
+//
+// Returns an error if the data is not a recognized container or no entries
+// could be decoded.
+func ReadIcon(r io.Reader) (*Icon, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read icon: %w", err)
+	}
+
+	var icon *Icon
+	switch {
+	case bytes.HasPrefix(data, []byte("\x00\x00\x01\x00")):
+		icon, err = readICO(data)
+	case bytes.HasPrefix(data, []byte("icns")):
+		icon, err = readICNS(data)
+	default:
+		return nil, fmt.Errorf("not an ICO or ICNS file")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(icon.Entries) == 0 {
+		return nil, fmt.Errorf("%s file contains no supported images", icon.Format)
+	}
+
+	sort.SliceStable(icon.Entries, func(i, j int) bool {
+		return icon.Entries[i].Width < icon.Entries[j].Width
+	})
+	return icon, nil
+}
+
+// Select returns the entry whose width equals size, or the largest entry if
+// size is 0. If several entries share the size, the one with the greatest
+// color depth wins.
+func (ic *Icon) Select(size int) (*IconEntry, error) {
+	if size == 0 {
+		return &ic.Entries[len(ic.Entries)-1], nil
+	}
+
+	var best *IconEntry
+	for i := range ic.Entries {
+		e := &ic.Entries[i]
+		if e.Width == size && (best == nil || e.BitsPerPixel > best.BitsPerPixel) {
+			best = e
+		}
+	}
+	if best == nil {
+		sizes := make([]string, len(ic.Entries))
+		for i, e := range ic.Entries {
+			sizes[i] = fmt.Sprintf("%dx%d", e.Width, e.Height)
+		}
+		return nil, fmt.Errorf("no %dpx image in icon (available: %s)", size, strings.Join(sizes, ", "))
+	}
+	return best, nil
+}
+
+// IconExtractResult contains one sub-image of an icon container and a
+// summary of all the sizes it provides.
+type IconExtractResult struct {
+	// Format is the container format: "ico" or "icns".
+	Format string `json:"format"`
+
+	// Available lists every decoded entry, smallest first.
+	Available []IconEntry `json:"available"`
+
+	// Selected is the entry that was extracted.
+	Selected IconEntry `json:"selected"`
+
+	// ContentBounds is the bounding box of non-transparent pixels in the
+	// selected image. The gap to the image edges is the icon's padding.
+	// Nil if the image is fully transparent.
+	ContentBounds *Region `json:"content_bounds,omitempty"`
+
+	// ImageBase64 is the selected sub-image encoded as base64 PNG.
+	ImageBase64 string `json:"image_base64"`
+
+	// MimeType is always "image/png".
+	MimeType string `json:"mime_type"`
+}
+
+// ExtractIcon reads an ICO or ICNS file and returns the sub-image of the
+// requested size.
+//
+// Parameters:
+//   - path: Path to the .ico or .icns file.
+//   - size: Desired width in pixels, or 0 for the largest entry.
+//
+// Returns:
+//   - *IconExtractResult: The selected image, its content bounds, and the
+//     list of available sizes.
+//   - error: Non-nil if the file cannot be read or decoded, or no entry of
+//     the requested size exists.
+func ExtractIcon(path string, size int) (*IconExtractResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open icon: %w", err)
+	}
+	defer f.Close()
+
+	icon, err := ReadIcon(f)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := icon.Select(size)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := encodePNGBase64(entry.Image)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IconExtractResult{
+		Format:        icon.Format,
+		Available:     icon.Entries,
+		Selected:      *entry,
+		ContentBounds: opaqueBounds(entry.Image),
+		ImageBase64:   encoded,
+		MimeType:      "image/png",
+	}, nil
+}
+
+// opaqueBounds returns the bounding box of pixels with non-zero alpha, or
+// nil if there are none.
+func opaqueBounds(img image.Image) *Region {
+	b := img.Bounds()
+	var r *Region
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a == 0 {
+				continue
+			}
+			px, py := x-b.Min.X, y-b.Min.Y
+			if r == nil {
+				r = &Region{X1: px, Y1: py, X2: px + 1, Y2: py + 1}
+				continue
+			}
+			r.X1 = minInt(r.X1, px)
+			r.Y1 = minInt(r.Y1, py)
+			r.X2 = maxInt(r.X2, px+1)
+			r.Y2 = maxInt(r.Y2, py+1)
+		}
+	}
+	return r
+}
+
+// decodeLargestIcon is the image.Decode hook for icon containers; it returns
+// the largest sub-image so icons can be used with every other tool.
+func decodeLargestIcon(r io.Reader) (image.Image, error) {
+	icon, err := ReadIcon(r)
+	if err != nil {
+		return nil, err
+	}
+	entry, _ := icon.Select(0)
+	return entry.Image, nil
+}
+
+func decodeLargestIconConfig(r io.Reader) (image.Config, error) {
+	img, err := decodeLargestIcon(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{
+		ColorModel: img.ColorModel(),
+		Width:      img.Bounds().Dx(),
+		Height:     img.Bounds().Dy(),
+	}, nil
+}
+
+// readICO parses a Windows icon file: a 6-byte header followed by 16-byte
+// directory entries pointing at PNG or headerless BMP (DIB) data.
+func readICO(data []byte) (*Icon, error) {
+	if len(data) < 6 {
+		return nil, fmt.Errorf("truncated ICO header")
+	}
+	count := int(binary.LittleEndian.Uint16(data[4:6]))
+	if len(data) < 6+16*count {
+		return nil, fmt.Errorf("truncated ICO directory")
+	}
+
+	icon := &Icon{Format: "ico"}
+	for i := 0; i < count && i < maxIconEntries; i++ {
+		dir := data[6+16*i : 6+16*(i+1)]
+		bpp := int(binary.LittleEndian.Uint16(dir[6:8]))
+		size := int(binary.LittleEndian.Uint32(dir[8:12]))
+		offset := int(binary.LittleEndian.Uint32(dir[12:16]))
+		if offset < 0 || size < 0 || offset+size > len(data) || offset+size < offset {
+			continue
+		}
+		blob := data[offset : offset+size]
+
+		entry := IconEntry{Index: i, BitsPerPixel: bpp}
+		var img image.Image
+		var err error
+		if bytes.HasPrefix(blob, []byte("\x89PNG")) {
+			entry.Encoding = "png"
+			img, err = decodeIconPNG(blob)
+		} else {
+			entry.Encoding = "bmp"
+			img, err = decodeDIB(blob)
+		}
+		if err != nil {
+			continue
+		}
+		entry.Image = img
+		entry.Width = img.Bounds().Dx()
+		entry.Height = img.Bounds().Dy()
+		icon.Entries = append(icon.Entries, entry)
+	}
+	return icon, nil
+}
+
+// readICNS parses an Apple icon file: an 8-byte header followed by
+// (type, length, data) elements. Only PNG-encoded elements are decoded.
+func readICNS(data []byte) (*Icon, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("truncated ICNS header")
+	}
+	total := int(binary.BigEndian.Uint32(data[4:8]))
+	if total > len(data) || total < 8 {
+		total = len(data)
+	}
+
+	icon := &Icon{Format: "icns"}
+	decoded := 0
+	for pos, i := 8, 0; pos+8 <= total && decoded < maxIconEntries; i++ {
+		length := int(binary.BigEndian.Uint32(data[pos+4 : pos+8]))
+		if length < 8 || pos+length > total {
+			break
+		}
+		blob := data[pos+8 : pos+length]
+		pos += length
+
+		if !bytes.HasPrefix(blob, []byte("\x89PNG")) {
+			continue
+		}
+		decoded++
+		img, err := decodeIconPNG(blob)
+		if err != nil {
+			continue
+		}
+		icon.Entries = append(icon.Entries, IconEntry{
+			Index:    i,
+			Width:    img.Bounds().Dx(),
+			Height:   img.Bounds().Dy(),
+			Encoding: "png",
+			Image:    img,
+		})
+	}
+	return icon, nil
+}
+
+// decodeIconPNG decodes a PNG icon entry after checking its header
+// dimensions against maxIconDimension.
+func decodeIconPNG(data []byte) (image.Image, error) {
+	cfg, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width > maxIconDimension || cfg.Height > maxIconDimension {
+		return nil, fmt.Errorf("unsupported PNG icon size %dx%d", cfg.Width, cfg.Height)
+	}
+	return png.Decode(bytes.NewReader(data))
+}
+
+// decodeDIB decodes the headerless bitmap used inside ICO files. The
+// declared height covers both the color (XOR) bitmap and the 1-bit AND
+// transparency mask, so the image height is half of it.
+func decodeDIB(data []byte) (image.Image, error) {
+	if len(data) < 40 {
+		return nil, fmt.Errorf("truncated BMP header")
+	}
+	headerSize := int(binary.LittleEndian.Uint32(data[0:4]))
+	width := int(int32(binary.LittleEndian.Uint32(data[4:8])))
+	height := int(int32(binary.LittleEndian.Uint32(data[8:12]))) / 2
+	bpp := int(binary.LittleEndian.Uint16(data[14:16]))
+	compression := binary.LittleEndian.Uint32(data[16:20])
+	colorsUsed := int(binary.LittleEndian.Uint32(data[32:36]))
+
+	if width <= 0 || height <= 0 || width > maxIconDimension || height > maxIconDimension {
+		return nil, fmt.Errorf("unsupported BMP size %dx%d", width, height)
+	}
+	if compression != 0 {
+		return nil, fmt.Errorf("unsupported BMP compression %d", compression)
+	}
+
+	pos := headerSize
+	var palette []color.NRGBA
+	if bpp <= 8 {
+		n := colorsUsed
+		if n == 0 {
+			n = 1 << bpp
+		}
+		if pos+4*n > len(data) {
+			return nil, fmt.Errorf("truncated BMP palette")
+		}
+		palette = make([]color.NRGBA, n)
+		for i := range palette {
+			p := data[pos+4*i:]
+			palette[i] = color.NRGBA{R: p[2], G: p[1], B: p[0], A: 255}
+		}
+		pos += 4 * n
+	}
+
+	switch bpp {
+	case 1, 4, 8, 24, 32:
+	default:
+		return nil, fmt.Errorf("unsupported BMP depth %d", bpp)
+	}
+
+	stride := ((width*bpp + 31) / 32) * 4
+	maskStride := ((width + 31) / 32) * 4
+	if pos+stride*height > len(data) {
+		return nil, fmt.Errorf("truncated BMP pixel data")
+	}
+	maskPos := pos + stride*height
+	hasMask := maskPos+maskStride*height <= len(data)
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	hasAlpha := false
+	for row := 0; row < height; row++ {
+		// Rows are stored bottom-up
+		y := height - 1 - row
+		line := data[pos+row*stride:]
+		for x := 0; x < width; x++ {
+			var c color.NRGBA
+			switch bpp {
+			case 32:
+				p := line[x*4:]
+				c = color.NRGBA{R: p[2], G: p[1], B: p[0], A: p[3]}
+				if p[3] != 0 {
+					hasAlpha = true
+				}
+			case 24:
+				p := line[x*3:]
+				c = color.NRGBA{R: p[2], G: p[1], B: p[0], A: 255}
+			default:
+				perByte := 8 / bpp
+				shift := uint(8 - bpp*(x%perByte+1))
+				idx := int(line[x/perByte]>>shift) & (1<<bpp - 1)
+				if idx < len(palette) {
+					c = palette[idx]
+				}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+
+	// 32-bit images carry their own alpha; the AND mask only applies when
+	// the alpha channel is unused (all zero).
+	if bpp == 32 && hasAlpha {
+		return img, nil
+	}
+	for row := 0; row < height; row++ {
+		y := height - 1 - row
+		for x := 0; x < width; x++ {
+			transparent := false
+			if hasMask {
+				b := data[maskPos+row*maskStride+x/8]
+				transparent = b&(0x80>>uint(x%8)) != 0
+			}
+			c := img.NRGBAAt(x, y)
+			if transparent {
+				c.A = 0
+			} else {
+				c.A = 255
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img, nil
+}
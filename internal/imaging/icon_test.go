@@ -0,0 +1,265 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// pngBytes encodes a solid square image with a transparent border of pad
+// pixels on every side.
+func pngBytes(t testing.TB, size, pad int, c color.NRGBA) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for y := pad; y < size-pad; y++ {
+		for x := pad; x < size-pad; x++ {
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// dib24 builds a headerless 24-bit ICO bitmap of a solid color whose left
+// half is marked transparent in the AND mask.
+func dib24(size int, c color.NRGBA) []byte {
+	var buf bytes.Buffer
+	hdr := make([]byte, 40)
+	binary.LittleEndian.PutUint32(hdr[0:], 40)
+	binary.LittleEndian.PutUint32(hdr[4:], uint32(size))
+	binary.LittleEndian.PutUint32(hdr[8:], uint32(size*2))
+	binary.LittleEndian.PutUint16(hdr[12:], 1)
+	binary.LittleEndian.PutUint16(hdr[14:], 24)
+	buf.Write(hdr)
+
+	stride := ((size*24 + 31) / 32) * 4
+	for y := 0; y < size; y++ {
+		row := make([]byte, stride)
+		for x := 0; x < size; x++ {
+			row[x*3], row[x*3+1], row[x*3+2] = c.B, c.G, c.R
+		}
+		buf.Write(row)
+	}
+
+	maskStride := ((size + 31) / 32) * 4
+	for y := 0; y < size; y++ {
+		row := make([]byte, maskStride)
+		for x := 0; x < size/2; x++ {
+			row[x/8] |= 0x80 >> uint(x%8)
+		}
+		buf.Write(row)
+	}
+	return buf.Bytes()
+}
+
+func buildICO(entries [][]byte, bpps []int) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, []uint16{0, 1, uint16(len(entries))})
+	offset := 6 + 16*len(entries)
+	for i, e := range entries {
+		dir := make([]byte, 16)
+		binary.LittleEndian.PutUint16(dir[4:], 1)
+		binary.LittleEndian.PutUint16(dir[6:], uint16(bpps[i]))
+		binary.LittleEndian.PutUint32(dir[8:], uint32(len(e)))
+		binary.LittleEndian.PutUint32(dir[12:], uint32(offset))
+		buf.Write(dir)
+		offset += len(e)
+	}
+	for _, e := range entries {
+		buf.Write(e)
+	}
+	return buf.Bytes()
+}
+
+func buildICNS(elements map[string][]byte, order []string) []byte {
+	var body bytes.Buffer
+	for _, typ := range order {
+		data := elements[typ]
+		body.WriteString(typ)
+		binary.Write(&body, binary.BigEndian, uint32(len(data)+8))
+		body.Write(data)
+	}
+	var buf bytes.Buffer
+	buf.WriteString("icns")
+	binary.Write(&buf, binary.BigEndian, uint32(body.Len()+8))
+	buf.Write(body.Bytes())
+	return buf.Bytes()
+}
+
+func TestReadIcon_ICO(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 255}
+	data := buildICO([][]byte{
+		pngBytes(t, 32, 4, red),
+		dib24(16, red),
+		pngBytes(t, 48, 0, red),
+	}, []int{32, 24, 32})
+
+	icon, err := ReadIcon(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadIcon failed: %v", err)
+	}
+
+	if icon.Format != "ico" {
+		t.Errorf("Format: got %s, want ico", icon.Format)
+	}
+	if len(icon.Entries) != 3 {
+		t.Fatalf("entries: got %d, want 3", len(icon.Entries))
+	}
+	wantWidths := []int{16, 32, 48}
+	for i, w := range wantWidths {
+		if icon.Entries[i].Width != w {
+			t.Errorf("entry %d width: got %d, want %d", i, icon.Entries[i].Width, w)
+		}
+	}
+	if icon.Entries[0].Encoding != "bmp" || icon.Entries[1].Encoding != "png" {
+		t.Errorf("encodings: got %s, %s", icon.Entries[0].Encoding, icon.Entries[1].Encoding)
+	}
+
+	// BMP entry: AND mask makes the left half transparent
+	bmp := icon.Entries[0].Image
+	if _, _, _, a := bmp.At(2, 5).RGBA(); a != 0 {
+		t.Errorf("masked pixel alpha: got %d, want 0", a>>8)
+	}
+	if r, _, _, a := bmp.At(12, 5).RGBA(); r>>8 != 255 || a>>8 != 255 {
+		t.Errorf("opaque pixel: got r=%d a=%d, want 255,255", r>>8, a>>8)
+	}
+}
+
+func TestReadIcon_ICNS(t *testing.T) {
+	blue := color.NRGBA{0, 0, 255, 255}
+	data := buildICNS(map[string][]byte{
+		"ic07": pngBytes(t, 128, 0, blue),
+		"icp4": pngBytes(t, 16, 0, blue),
+		"is32": {1, 2, 3, 4}, // legacy RLE entry, skipped
+	}, []string{"ic07", "is32", "icp4"})
+
+	icon, err := ReadIcon(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadIcon failed: %v", err)
+	}
+
+	if icon.Format != "icns" {
+		t.Errorf("Format: got %s, want icns", icon.Format)
+	}
+	if len(icon.Entries) != 2 {
+		t.Fatalf("entries: got %d, want 2", len(icon.Entries))
+	}
+	if icon.Entries[0].Width != 16 || icon.Entries[1].Width != 128 {
+		t.Errorf("widths: got %d, %d, want 16, 128", icon.Entries[0].Width, icon.Entries[1].Width)
+	}
+}
+
+func TestReadIcon_Invalid(t *testing.T) {
+	if _, err := ReadIcon(bytes.NewReader([]byte("not an icon"))); err == nil {
+		t.Error("ReadIcon should fail for non-icon data")
+	}
+	if _, err := ReadIcon(bytes.NewReader(buildICO(nil, nil))); err == nil {
+		t.Error("ReadIcon should fail for an icon with no entries")
+	}
+}
+
+func TestReadIcon_Limits(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 255}
+
+	// A PNG entry whose header claims 50000x50000 is skipped before its
+	// pixels are allocated
+	huge := pngBytes(t, 16, 0, red)
+	binary.BigEndian.PutUint32(huge[16:], 50000)
+	binary.BigEndian.PutUint32(huge[20:], 50000)
+	binary.BigEndian.PutUint32(huge[29:], crc32.ChecksumIEEE(huge[12:29]))
+	icon, err := ReadIcon(bytes.NewReader(buildICO([][]byte{huge, pngBytes(t, 16, 0, red)}, []int{32, 32})))
+	if err != nil {
+		t.Fatalf("ReadIcon failed: %v", err)
+	}
+	if len(icon.Entries) != 1 || icon.Entries[0].Index != 1 {
+		t.Errorf("got %d entries, want only the valid one", len(icon.Entries))
+	}
+
+	// A directory listing the same bitmap many times decodes it at most
+	// maxIconEntries times
+	entries := make([][]byte, 1000)
+	bpps := make([]int, len(entries))
+	for i := range entries {
+		entries[i], bpps[i] = dib24(8, red), 24
+	}
+	icon, err = ReadIcon(bytes.NewReader(buildICO(entries, bpps)))
+	if err != nil {
+		t.Fatalf("ReadIcon failed: %v", err)
+	}
+	if len(icon.Entries) != maxIconEntries {
+		t.Errorf("got %d entries, want %d", len(icon.Entries), maxIconEntries)
+	}
+}
+
+func TestIcon_Select(t *testing.T) {
+	c := color.NRGBA{0, 255, 0, 255}
+	data := buildICO([][]byte{
+		dib24(32, c),
+		pngBytes(t, 32, 0, c),
+		pngBytes(t, 64, 0, c),
+	}, []int{24, 32, 32})
+	icon, err := ReadIcon(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadIcon failed: %v", err)
+	}
+
+	e, err := icon.Select(0)
+	if err != nil || e.Width != 64 {
+		t.Errorf("Select(0): got %+v, %v, want the 64px entry", e, err)
+	}
+
+	e, err = icon.Select(32)
+	if err != nil || e.BitsPerPixel != 32 {
+		t.Errorf("Select(32): got %+v, %v, want the 32-bit entry", e, err)
+	}
+
+	if _, err := icon.Select(256); err == nil {
+		t.Error("Select(256) should fail when no 256px entry exists")
+	}
+}
+
+func TestExtractIcon(t *testing.T) {
+	c := color.NRGBA{255, 128, 0, 255}
+	path := filepath.Join(t.TempDir(), "app.ico")
+	data := buildICO([][]byte{pngBytes(t, 16, 0, c), pngBytes(t, 32, 4, c)}, []int{32, 32})
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to write icon: %v", err)
+	}
+
+	result, err := ExtractIcon(path, 32)
+	if err != nil {
+		t.Fatalf("ExtractIcon failed: %v", err)
+	}
+
+	if len(result.Available) != 2 {
+		t.Errorf("Available: got %d entries, want 2", len(result.Available))
+	}
+	if result.Selected.Width != 32 {
+		t.Errorf("Selected width: got %d, want 32", result.Selected.Width)
+	}
+	if result.ContentBounds == nil || *result.ContentBounds != (Region{X1: 4, Y1: 4, X2: 28, Y2: 28}) {
+		t.Errorf("ContentBounds: got %+v, want {4 4 28 28}", result.ContentBounds)
+	}
+	if result.ImageBase64 == "" || result.MimeType != "image/png" {
+		t.Error("expected base64 PNG output")
+	}
+
+	// The cache loads the largest entry through the registered decoder
+	cache := NewImageCache()
+	img, err := cache.Load(path)
+	if err != nil {
+		t.Fatalf("cache Load failed: %v", err)
+	}
+	if img.Bounds().Dx() != 32 {
+		t.Errorf("cached width: got %d, want 32", img.Bounds().Dx())
+	}
+}
@@ -0,0 +1,279 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+)
+
+// Indicator reading limits.
+const (
+	// maxIndicators and maxIndicatorStates limit the indicators and states
+	// ReadIndicators accepts.
+	maxIndicators      = 200
+	maxIndicatorStates = 32
+
+	// maxIndicatorRadius is the largest radius of a point indicator.
+	maxIndicatorRadius = 100
+
+	// defaultStateTolerance is the RGB distance within which a state's
+	// reference color matches when the state gives no tolerance.
+	defaultStateTolerance = 60
+)
+
+// Indicator is a status light or other indicator read by ReadIndicators:
+// a disc of Radius pixels around (X, Y), or Region if set.
+type Indicator struct {
+	Name   string  `json:"name"`
+	X      int     `json:"x"`
+	Y      int     `json:"y"`
+	Radius int     `json:"radius,omitempty"` // 0 samples the pixel alone
+	Region *Region `json:"region,omitempty"`
+}
+
+// IndicatorState is a state an indicator may be in, such as "on", "off",
+// or "fault", defined by color. An indicator is in the state if its color
+// meets every condition given.
+type IndicatorState struct {
+	Name string `json:"name"`
+
+	// Color, if set, is a reference color as hex (#RRGGBB), and Tolerance
+	// the largest RGB distance (1-442) from it, 60 if zero.
+	Color     string  `json:"color,omitempty"`
+	Tolerance float64 `json:"tolerance,omitempty"`
+
+	// Hue, Saturation, and Lightness, if set, are [min, max] ranges of the
+	// color's HSL components, inclusive. Hue is in degrees (0-360) and
+	// wraps around when min is above max, as in [340, 20] for red.
+	// Saturation and lightness are percentages (0-100).
+	Hue        []int `json:"hue,omitempty"`
+	Saturation []int `json:"saturation,omitempty"`
+	Lightness  []int `json:"lightness,omitempty"`
+}
+
+// IndicatorReading is the color and state of one indicator.
+type IndicatorReading struct {
+	Name string `json:"name"`
+
+	// Bounds is the area sampled.
+	Bounds Region `json:"bounds"`
+
+	// Color is the indicator's sampled color.
+	Color string   `json:"color"` // Hex color (#RRGGBB)
+	RGB   RGBColor `json:"rgb"`
+	HSL   HSLColor `json:"hsl"`
+
+	// State is the first state whose conditions the color meets, or
+	// "unknown" if none does.
+	State string `json:"state"`
+
+	// Distance is the RGB distance of Color from the state's reference
+	// color, if the state has one.
+	Distance *float64 `json:"distance,omitempty"`
+}
+
+// IndicatorsResult contains the states of indicators read by
+// ReadIndicators.
+type IndicatorsResult struct {
+	// Indicators are the readings, in input order.
+	Indicators []IndicatorReading `json:"indicators"`
+
+	// States maps each indicator's name to its state.
+	States map[string]string `json:"states"`
+
+	// Counts is the number of indicators in each state, "unknown"
+	// included.
+	Counts map[string]int `json:"counts"`
+}
+
+// ReadIndicators samples the color of each indicator, such as the status
+// LEDs of a device or the lights of a dashboard, and classifies it into
+// the first of states whose conditions it meets.
+//
+// Parameters:
+//   - img: Source image.
+//   - indicators: 1 to 200 indicators with unique, non-empty names.
+//   - states: 1 to 32 states with unique, non-empty names, each with a
+//     reference color or at least one HSL range. List narrower states
+//     first: an indicator takes the first that matches.
+//   - sample: "median" for the per-channel median of the area, or
+//     "brightest" for the median of its brightest quarter by strongest
+//     channel, which picks out the lit part of an LED in a photo, where a
+//     saturated color counts as bright as white.
+//
+// Returns:
+//   - *IndicatorsResult: Each indicator's color and state, a map of names
+//     to states, and counts per state.
+//   - error: Non-nil if an indicator or state is invalid or outside the
+//     image, names repeat, or sample is unknown.
+func ReadIndicators(img image.Image, indicators []Indicator, states []IndicatorState, sample string) (*IndicatorsResult, error) {
+	if len(indicators) == 0 || len(indicators) > maxIndicators {
+		return nil, fmt.Errorf("need 1 to %d indicators, got %d", maxIndicators, len(indicators))
+	}
+	if len(states) == 0 || len(states) > maxIndicatorStates {
+		return nil, fmt.Errorf("need 1 to %d states, got %d", maxIndicatorStates, len(states))
+	}
+	if sample != "median" && sample != "brightest" {
+		return nil, fmt.Errorf("sample must be \"median\" or \"brightest\", got %q", sample)
+	}
+
+	refs := make([]*RGBColor, len(states))
+	seen := make(map[string]bool)
+	for i, s := range states {
+		if s.Name == "" || s.Name == "unknown" || seen[s.Name] {
+			return nil, fmt.Errorf("state %d needs a unique name other than \"unknown\", got %q", i, s.Name)
+		}
+		seen[s.Name] = true
+		if s.Color == "" && s.Hue == nil && s.Saturation == nil && s.Lightness == nil {
+			return nil, fmt.Errorf("state %q needs a color or an HSL range", s.Name)
+		}
+		if s.Color != "" {
+			c, err := parseHexColor(s.Color)
+			if err != nil {
+				return nil, fmt.Errorf("state %q: invalid color %q: %w", s.Name, s.Color, err)
+			}
+			refs[i] = &RGBColor{R: c.R, G: c.G, B: c.B}
+		}
+		if s.Tolerance < 0 || s.Tolerance > maxColorDistance {
+			return nil, fmt.Errorf("state %q: tolerance must be between 1 and %d, got %g", s.Name, maxColorDistance, s.Tolerance)
+		}
+		for _, rng := range []struct {
+			name  string
+			r     []int
+			limit int
+		}{{"hue", s.Hue, 360}, {"saturation", s.Saturation, 100}, {"lightness", s.Lightness, 100}} {
+			if rng.r == nil {
+				continue
+			}
+			if len(rng.r) != 2 || rng.r[0] < 0 || rng.r[1] < 0 || rng.r[0] > rng.limit || rng.r[1] > rng.limit {
+				return nil, fmt.Errorf("state %q: %s must be [min, max] within 0-%d, got %v", s.Name, rng.name, rng.limit, rng.r)
+			}
+			if rng.name != "hue" && rng.r[0] > rng.r[1] {
+				return nil, fmt.Errorf("state %q: %s min %d is above max %d", s.Name, rng.name, rng.r[0], rng.r[1])
+			}
+		}
+	}
+
+	b := img.Bounds()
+	result := &IndicatorsResult{
+		Indicators: make([]IndicatorReading, len(indicators)),
+		States:     make(map[string]string, len(indicators)),
+		Counts:     make(map[string]int),
+	}
+	for i, ind := range indicators {
+		if ind.Name == "" || result.States[ind.Name] != "" {
+			return nil, fmt.Errorf("indicator %d needs a unique name, got %q", i, ind.Name)
+		}
+		area, err := indicatorArea(ind, b.Dx(), b.Dy())
+		if err != nil {
+			return nil, fmt.Errorf("indicator %q: %w", ind.Name, err)
+		}
+
+		var pixels []RGBColor
+		for y := area.Y1; y < area.Y2; y++ {
+			for x := area.X1; x < area.X2; x++ {
+				if ind.Region == nil && (x-ind.X)*(x-ind.X)+(y-ind.Y)*(y-ind.Y) > ind.Radius*ind.Radius {
+					continue
+				}
+				c := nrgbaAt(img, b.Min.X+x, b.Min.Y+y)
+				pixels = append(pixels, RGBColor{R: c.R, G: c.G, B: c.B})
+			}
+		}
+		if sample == "brightest" {
+			sort.SliceStable(pixels, func(a, b int) bool { return indicatorBrightness(pixels[a]) > indicatorBrightness(pixels[b]) })
+			pixels = pixels[:max(1, len(pixels)/4)]
+		}
+		rs, gs, bs := make([]float64, len(pixels)), make([]float64, len(pixels)), make([]float64, len(pixels))
+		for k, p := range pixels {
+			rs[k], gs[k], bs[k] = float64(p.R), float64(p.G), float64(p.B)
+		}
+		c := RGBColor{
+			R: uint8(math.Round(medianFloat(rs))),
+			G: uint8(math.Round(medianFloat(gs))),
+			B: uint8(math.Round(medianFloat(bs))),
+		}
+
+		reading := IndicatorReading{
+			Name:   ind.Name,
+			Bounds: area,
+			Color:  fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B),
+			RGB:    c,
+			HSL:    rgbToHSL(c.R, c.G, c.B),
+			State:  "unknown",
+		}
+		for k, s := range states {
+			if ok, d := stateMatches(s, refs[k], c, reading.HSL); ok {
+				reading.State, reading.Distance = s.Name, d
+				break
+			}
+		}
+		result.Indicators[i] = reading
+		result.States[ind.Name] = reading.State
+		result.Counts[reading.State]++
+	}
+	return result, nil
+}
+
+// indicatorArea returns the area sampled for ind: its region, or the
+// bounding box of its disc, clipped to the image.
+func indicatorArea(ind Indicator, w, h int) (Region, error) {
+	if ind.Region != nil {
+		if err := validateRegion(*ind.Region, w, h); err != nil {
+			return Region{}, err
+		}
+		return *ind.Region, nil
+	}
+	if ind.Radius < 0 || ind.Radius > maxIndicatorRadius {
+		return Region{}, fmt.Errorf("radius must be between 0 and %d, got %d", maxIndicatorRadius, ind.Radius)
+	}
+	if ind.X < 0 || ind.Y < 0 || ind.X >= w || ind.Y >= h {
+		return Region{}, fmt.Errorf("point (%d, %d) is outside the %dx%d image", ind.X, ind.Y, w, h)
+	}
+	return Region{
+		X1: max(0, ind.X-ind.Radius),
+		Y1: max(0, ind.Y-ind.Radius),
+		X2: min(w, ind.X+ind.Radius+1),
+		Y2: min(h, ind.Y+ind.Radius+1),
+	}, nil
+}
+
+// indicatorBrightness orders pixels by their strongest channel, then by
+// saturation, so the colored glow of an LED ranks with its white core.
+func indicatorBrightness(c RGBColor) int {
+	hi := max(c.R, c.G, c.B)
+	lo := min(c.R, c.G, c.B)
+	return int(hi)*256 + int(hi-lo)
+}
+
+// stateMatches reports whether color c, with HSL components hsl, meets
+// every condition of state s, whose parsed reference color is ref, and
+// the distance from ref if there is one.
+func stateMatches(s IndicatorState, ref *RGBColor, c RGBColor, hsl HSLColor) (bool, *float64) {
+	var distance *float64
+	if ref != nil {
+		dr, dg, db := float64(c.R)-float64(ref.R), float64(c.G)-float64(ref.G), float64(c.B)-float64(ref.B)
+		d := math.Round(math.Sqrt(dr*dr+dg*dg+db*db)*10) / 10
+		tolerance := s.Tolerance
+		if tolerance == 0 {
+			tolerance = defaultStateTolerance
+		}
+		if d > tolerance {
+			return false, nil
+		}
+		distance = &d
+	}
+	if s.Hue != nil {
+		lo, hi := s.Hue[0], s.Hue[1]
+		if lo <= hi && (hsl.H < lo || hsl.H > hi) || lo > hi && hsl.H < lo && hsl.H > hi {
+			return false, nil
+		}
+	}
+	if s.Saturation != nil && (hsl.S < s.Saturation[0] || hsl.S > s.Saturation[1]) {
+		return false, nil
+	}
+	if s.Lightness != nil && (hsl.L < s.Lightness[0] || hsl.L > s.Lightness[1]) {
+		return false, nil
+	}
+	return true, distance
+}
@@ -0,0 +1,92 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// ledStates are the states of a status LED: off when dark, then red,
+// amber, or green when lit.
+var ledStates = []IndicatorState{
+	{Name: "off", Lightness: []int{0, 20}},
+	{Name: "red", Hue: []int{345, 15}, Saturation: []int{50, 100}},
+	{Name: "amber", Hue: []int{25, 55}, Saturation: []int{50, 100}},
+	{Name: "green", Color: "#20C040"},
+}
+
+func TestReadIndicators(t *testing.T) {
+	// Four LEDs on a gray panel: red with a white core, amber, green, and
+	// one off, plus a blue one in a region
+	img := image.NewRGBA(image.Rect(0, 0, 100, 40))
+	fillRect(img, 0, 0, 100, 40, color.RGBA{90, 90, 90, 255})
+	paintRing(img, 10, 10, 0, 5, 360, color.RGBA{230, 20, 30, 255})
+	paintRing(img, 10, 10, 0, 2, 360, color.RGBA{255, 255, 255, 255})
+	paintRing(img, 30, 10, 0, 5, 360, color.RGBA{250, 170, 0, 255})
+	paintRing(img, 50, 10, 0, 5, 360, color.RGBA{40, 200, 60, 255})
+	paintRing(img, 70, 10, 0, 5, 360, color.RGBA{25, 25, 25, 255})
+	fillRect(img, 80, 25, 90, 35, color.RGBA{30, 60, 230, 255})
+
+	indicators := []Indicator{
+		{Name: "power", X: 10, Y: 10, Radius: 6},
+		{Name: "disk", X: 30, Y: 10, Radius: 4},
+		{Name: "network", X: 50, Y: 10, Radius: 4},
+		{Name: "fault", X: 70, Y: 10, Radius: 4},
+		{Name: "link", Region: &Region{X1: 78, Y1: 23, X2: 92, Y2: 37}},
+	}
+	result, err := ReadIndicators(img, indicators, ledStates, "brightest")
+	if err != nil {
+		t.Fatalf("ReadIndicators failed: %v", err)
+	}
+	want := map[string]string{"power": "red", "disk": "amber", "network": "green", "fault": "off", "link": "unknown"}
+	for name, state := range want {
+		if result.States[name] != state {
+			t.Errorf("states = %v, want %v", result.States, want)
+			break
+		}
+	}
+	if n := result.Indicators[2]; n.Distance == nil || *n.Distance > 20 || n.Bounds != (Region{X1: 46, Y1: 6, X2: 55, Y2: 15}) {
+		t.Errorf("network = %+v", n)
+	}
+	if result.Counts["unknown"] != 1 || result.Counts["red"] != 1 {
+		t.Errorf("counts = %v", result.Counts)
+	}
+
+	// The median of a wide disc is the panel around the red LED, while its
+	// brightest quarter is still the LED
+	wide := []Indicator{{Name: "power", X: 10, Y: 10, Radius: 12}}
+	result, _ = ReadIndicators(img, wide, ledStates, "brightest")
+	if result.States["power"] != "red" {
+		t.Errorf("brightest: %+v", result.Indicators[0])
+	}
+	result, _ = ReadIndicators(img, wide, ledStates, "median")
+	if result.States["power"] != "unknown" || result.Indicators[0].Color != "#5A5A5A" {
+		t.Errorf("median: %+v", result.Indicators[0])
+	}
+}
+
+func TestReadIndicators_Invalid(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	led := []Indicator{{Name: "a", X: 5, Y: 5}}
+	for _, tc := range []struct {
+		indicators []Indicator
+		states     []IndicatorState
+		sample     string
+	}{
+		{nil, ledStates, "median"},
+		{led, nil, "median"},
+		{led, ledStates, "mean"},
+		{[]Indicator{{Name: "a", X: 5, Y: 5}, {Name: "a", X: 6, Y: 6}}, ledStates, "median"},
+		{[]Indicator{{Name: "a", X: 25, Y: 5}}, ledStates, "median"},
+		{[]Indicator{{Name: "a", X: 5, Y: 5, Radius: -1}}, ledStates, "median"},
+		{led, []IndicatorState{{Name: "on"}}, "median"},
+		{led, []IndicatorState{{Name: "unknown", Color: "#FF0000"}}, "median"},
+		{led, []IndicatorState{{Name: "on", Hue: []int{10}}}, "median"},
+		{led, []IndicatorState{{Name: "on", Lightness: []int{60, 40}}}, "median"},
+		{led, []IndicatorState{{Name: "on", Color: "#FF0000", Tolerance: 500}}, "median"},
+	} {
+		if _, err := ReadIndicators(img, tc.indicators, tc.states, tc.sample); err == nil {
+			t.Errorf("%+v: expected error", tc)
+		}
+	}
+}
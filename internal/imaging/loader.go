@@ -1,16 +1,26 @@
 package imaging
 
 import (
+	"bytes"
 	"fmt"
 	"image"
 	_ "image/gif"  // Register GIF format decoder
 	_ "image/jpeg" // Register JPEG format decoder
 	_ "image/png"  // Register PNG format decoder
+	"io"
 	"os"
 	"path/filepath"
 	"sync"
+
+	"golang.org/x/image/tiff" // Also registers the TIFF format decoder
 )
 
+// DefaultMaxPixels is the largest image, in pixels, that ImageCache loads
+// when no limit is configured: 100 megapixels, about 400 MB once decoded.
+// Larger scans are rejected before decoding rather than risking running
+// out of memory in the analysis tools.
+const DefaultMaxPixels = 100_000_000
+
 // ImageCache provides thread-safe caching of loaded images to avoid redundant disk reads.
 //
 // The cache stores decoded image.Image objects keyed by their file path. Once an image
@@ -26,6 +36,19 @@ import (
 // For long-running processes handling many images, consider periodic cleanup to
 // prevent unbounded memory growth.
 //
+// Images larger than the pixel limit (DefaultMaxPixels unless changed with
+// SetMaxPixels) are refused. Raster formats are checked from the file header,
+// before any pixel data is decoded.
+//
+// # Color Profiles
+//
+// PNG, JPEG, and TIFF files with an embedded ICC profile other than sRGB, such as
+// the Display P3 profile of many screenshots, are converted to sRGB on
+// load, so sampled colors match what a browser shows. Matrix/TRC RGB
+// profiles and gray profiles are supported; others are loaded unconverted.
+// SetColorConversion turns conversion off. The profile found is reported
+// in ImageInfo.ColorProfile.
+//
 // # Example Usage
 //
 //	cache := imaging.NewImageCache()
@@ -36,8 +59,12 @@ import (
 //	// Use img...
 //	cache.Evict("/path/to/image.png") // Optional: free memory
 type ImageCache struct {
-	mu     sync.RWMutex
-	images map[string]image.Image
+	mu        sync.RWMutex
+	images    map[string]image.Image
+	profiles  map[string]*ColorProfile // Embedded profiles of cached images
+	svgScale  float64
+	maxPixels int64
+	noConvert bool // Keep pixel values in the embedded profile's color space
 }
 
 // NewImageCache creates and initializes a new empty image cache.
@@ -45,15 +72,72 @@ type ImageCache struct {
 // The returned cache is ready for immediate use and is safe for concurrent access.
 func NewImageCache() *ImageCache {
 	return &ImageCache{
-		images: make(map[string]image.Image),
+		images:    make(map[string]image.Image),
+		profiles:  make(map[string]*ColorProfile),
+		svgScale:  DefaultSVGScale,
+		maxPixels: DefaultMaxPixels,
+	}
+}
+
+// SetSVGScale sets the render scale (output pixels per SVG user unit) used
+// when loading SVG files.
+//
+// The scale applies to SVG files loaded after the call; images already in the
+// cache are not re-rendered. Call this once at startup, or Clear() the cache
+// after changing it. Non-positive values are ignored.
+func (c *ImageCache) SetSVGScale(scale float64) {
+	if scale <= 0 {
+		return
+	}
+	c.mu.Lock()
+	c.svgScale = scale
+	c.mu.Unlock()
+}
+
+// SetMaxPixels sets the largest image size, in pixels (width × height),
+// that Load accepts. Zero or a negative value removes the limit.
+//
+// The limit applies to images loaded after the call; images already in the
+// cache are kept.
+func (c *ImageCache) SetMaxPixels(n int64) {
+	c.mu.Lock()
+	c.maxPixels = n
+	c.mu.Unlock()
+}
+
+// SetColorConversion sets whether images with an embedded color profile
+// are converted to sRGB on load (the default). With conversion off, pixel
+// values are kept as stored in the file, and tools read them as sRGB.
+//
+// The setting applies to images loaded after the call; images already in
+// the cache are kept.
+func (c *ImageCache) SetColorConversion(enabled bool) {
+	c.mu.Lock()
+	c.noConvert = !enabled
+	c.mu.Unlock()
+}
+
+// checkPixelLimit returns an error if a width×height image exceeds the
+// cache's pixel limit.
+func (c *ImageCache) checkPixelLimit(width, height int) error {
+	c.mu.RLock()
+	limit := c.maxPixels
+	c.mu.RUnlock()
+	if limit <= 0 || int64(width)*int64(height) <= limit {
+		return nil
 	}
+	return fmt.Errorf("image too large: %dx%d is %.1f megapixels, limit is %.1f; crop or downscale it first, or raise IMAGE_MCP_MAX_PIXELS",
+		width, height, float64(width)*float64(height)/1e6, float64(limit)/1e6)
 }
 
 // Load retrieves an image from the cache or loads it from disk if not cached.
 //
 // Parameters:
 //   - path: Absolute or relative file path to the image. Supported formats are
-//     PNG, JPEG, and GIF.
+//     PNG, JPEG, GIF, TIFF, SVG, ICO, and ICNS. 16-bit PNG and TIFF images
+//     keep their 16 bits per channel. SVG files are rasterized at the
+//     cache's SVG scale (see SetSVGScale and RasterizeSVG). Icon containers
+//     load their largest sub-image (see ExtractIcon for other sizes).
 //
 // Returns:
 //   - image.Image: The decoded image. The concrete type depends on the image format
@@ -66,31 +150,131 @@ func NewImageCache() *ImageCache {
 // # Errors
 //
 //   - Returns error if the file does not exist or cannot be read
-//   - Returns error if the file is not a valid PNG, JPEG, or GIF image
+//   - Returns error if the file is not a valid PNG, JPEG, GIF, TIFF, SVG, ICO, or ICNS image
+//   - Returns error if the image exceeds the pixel limit (see SetMaxPixels)
 func (c *ImageCache) Load(path string) (image.Image, error) {
+	img, _, err := c.load(path)
+	return img, err
+}
+
+// load is Load, also returning the image's embedded color profile, or nil
+// if it has none.
+func (c *ImageCache) load(path string) (image.Image, *ColorProfile, error) {
+	img, profile, cached, err := c.decode(path)
+	if err != nil || cached {
+		return img, profile, err
+	}
+
+	c.mu.Lock()
+	c.images[path] = img
+	if profile != nil {
+		c.profiles[path] = profile
+	}
+	c.mu.Unlock()
+
+	return img, profile, nil
+}
+
+// Decode returns the image at path like Load, but does not add a newly
+// decoded image to the cache. It suits one-off reads of many files, such
+// as generating thumbnails, that should not keep every full-size image in
+// memory. An image already in the cache is returned from it.
+func (c *ImageCache) Decode(path string) (image.Image, error) {
+	img, _, _, err := c.decode(path)
+	return img, err
+}
+
+// decode returns the cached image at path, or decodes it from disk, with
+// its embedded color profile. cached reports whether the image came from
+// the cache.
+func (c *ImageCache) decode(path string) (img image.Image, profile *ColorProfile, cached bool, err error) {
 	c.mu.RLock()
 	if img, ok := c.images[path]; ok {
+		profile := c.profiles[path]
 		c.mu.RUnlock()
-		return img, nil
+		return img, profile, true, nil
 	}
 	c.mu.RUnlock()
 
 	f, err := os.Open(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open image: %w", err)
+		return nil, nil, false, fmt.Errorf("failed to open image: %w", err)
 	}
 	defer f.Close()
 
-	img, _, err := image.Decode(f)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode image: %w", err)
+	if IsSVG(path) {
+		c.mu.RLock()
+		scale := c.svgScale
+		c.mu.RUnlock()
+		img, err = RasterizeSVG(f, scale)
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("failed to decode image: %w", err)
+		}
+		// The SVG size is only known once rendered; maxSVGDimension bounds
+		// the allocation until then
+		if err := c.checkPixelLimit(img.Bounds().Dx(), img.Bounds().Dy()); err != nil {
+			return nil, nil, false, err
+		}
+		return img, nil, false, nil
 	}
+	img, profile, err = c.decodeRaster(f)
+	return img, profile, false, err
+}
 
-	c.mu.Lock()
-	c.images[path] = img
-	c.mu.Unlock()
+// decodeRaster decodes a raster image after checking its header dimensions
+// against the pixel limit, and converts it to sRGB if it has an embedded
+// color profile.
+//
+// TIFF files are decoded straight from f. Through image.Decode's buffered
+// reader, the TIFF decoder would buffer the stream up to every offset the
+// file names. A crafted 60-byte file could then allocate gigabytes before
+// the pixel limit is checked. Given f, which has ReadAt, it reads only
+// what is there.
+func (c *ImageCache) decodeRaster(f *os.File) (image.Image, *ColorProfile, error) {
+	magic := make([]byte, 4)
+	n, _ := f.ReadAt(magic, 0)
+	isTIFF := n == len(magic) && (bytes.Equal(magic, []byte("II*\x00")) || bytes.Equal(magic, []byte("MM\x00*")))
+
+	var cfg image.Config
+	var err error
+	if isTIFF {
+		cfg, err = tiff.DecodeConfig(f)
+	} else {
+		cfg, _, err = image.DecodeConfig(f)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if err := c.checkPixelLimit(cfg.Width, cfg.Height); err != nil {
+		return nil, nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	var img image.Image
+	if isTIFF {
+		img, err = tiff.Decode(f)
+	} else {
+		img, _, err = image.Decode(f)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode image: %w", err)
+	}
 
-	return img, nil
+	// The image decoded, so a damaged profile is ignored rather than
+	// failing the load
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return img, nil, nil
+	}
+	icc, srgb, err := readEmbeddedProfile(f)
+	if err != nil {
+		return img, nil, nil
+	}
+	c.mu.RLock()
+	convert := !c.noConvert
+	c.mu.RUnlock()
+	img, profile := applyColorProfile(img, icc, srgb, convert)
+	return img, profile, nil
 }
 
 // Clear removes all images from the cache, freeing the associated memory.
@@ -101,6 +285,7 @@ func (c *ImageCache) Load(path string) (image.Image, error) {
 func (c *ImageCache) Clear() {
 	c.mu.Lock()
 	c.images = make(map[string]image.Image)
+	c.profiles = make(map[string]*ColorProfile)
 	c.mu.Unlock()
 }
 
@@ -114,6 +299,7 @@ func (c *ImageCache) Clear() {
 func (c *ImageCache) Evict(path string) {
 	c.mu.Lock()
 	delete(c.images, path)
+	delete(c.profiles, path)
 	c.mu.Unlock()
 }
 
@@ -128,7 +314,8 @@ type ImageInfo struct {
 	// Height is the image height in pixels.
 	Height int `json:"height"`
 
-	// Format is the detected image format: "png", "jpeg", "gif", or "unknown".
+	// Format is the detected image format: "png", "jpeg", "gif", "tiff", "svg",
+	// "ico", "icns", or "unknown".
 	// Detection is based on file extension, not file contents.
 	Format string `json:"format"`
 
@@ -140,6 +327,11 @@ type ImageInfo struct {
 
 	// FileSizeBytes is the size of the image file on disk in bytes.
 	FileSizeBytes int64 `json:"file_size_bytes"`
+
+	// ColorProfile describes the file's embedded color profile, and
+	// whether the pixels were converted from it to sRGB. It is nil for
+	// files without one, which are treated as sRGB.
+	ColorProfile *ColorProfile `json:"color_profile,omitempty"`
 }
 
 // LoadImageInfo loads an image and returns comprehensive metadata about it.
@@ -162,6 +354,10 @@ type ImageInfo struct {
 //   - ".png" -> "png"
 //   - ".jpg", ".jpeg" -> "jpeg"
 //   - ".gif" -> "gif"
+//   - ".tif", ".tiff" -> "tiff"
+//   - ".svg" -> "svg"
+//   - ".ico" -> "ico"
+//   - ".icns" -> "icns"
 //   - Other extensions -> "unknown"
 //
 // # Color Depth Detection
@@ -169,8 +365,11 @@ type ImageInfo struct {
 // Color depth is determined by the Go image type:
 //   - *image.RGBA64, *image.NRGBA64, *image.Gray16 -> "16-bit"
 //   - All other types -> "8-bit"
+//
+// An image converted from its color profile reports the alpha channel of
+// the file, not of the converted image.
 func LoadImageInfo(cache *ImageCache, path string) (*ImageInfo, error) {
-	img, err := cache.Load(path)
+	img, profile, err := cache.load(path)
 	if err != nil {
 		return nil, err
 	}
@@ -193,6 +392,14 @@ func LoadImageInfo(cache *ImageCache, path string) (*ImageInfo, error) {
 		format = "jpeg"
 	case ".gif":
 		format = "gif"
+	case ".tif", ".tiff":
+		format = "tiff"
+	case ".svg":
+		format = "svg"
+	case ".ico":
+		format = "ico"
+	case ".icns":
+		format = "icns"
 	}
 
 	// Check for alpha channel
@@ -207,6 +414,9 @@ func LoadImageInfo(cache *ImageCache, path string) (*ImageInfo, error) {
 	case *image.Gray16:
 		colorDepth = "16-bit"
 	}
+	if profile != nil && profile.opaque {
+		hasAlpha = false
+	}
 
 	return &ImageInfo{
 		Width:         bounds.Dx(),
@@ -215,6 +425,7 @@ func LoadImageInfo(cache *ImageCache, path string) (*ImageInfo, error) {
 		ColorDepth:    colorDepth,
 		HasAlpha:      hasAlpha,
 		FileSizeBytes: stat.Size(),
+		ColorProfile:  profile,
 	}, nil
 }
 
@@ -6,6 +6,8 @@ import (
 	"image/png"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"sync"
 	"testing"
 )
@@ -101,6 +103,45 @@ func TestImageCache_Load_InvalidImage(t *testing.T) {
 	}
 }
 
+func TestImageCache_Load_PixelLimit(t *testing.T) {
+	imgPath := createTestImage(t, 100, 100, color.RGBA{255, 0, 0, 255})
+	defer os.Remove(imgPath)
+
+	cache := NewImageCache()
+	cache.SetMaxPixels(5000)
+	_, err := cache.Load(imgPath)
+	if err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Fatalf("Load should reject a 10000-pixel image with limit 5000, got %v", err)
+	}
+
+	// A non-positive limit disables the check
+	cache.SetMaxPixels(0)
+	if _, err := cache.Load(imgPath); err != nil {
+		t.Errorf("Load with limit disabled failed: %v", err)
+	}
+}
+
+func TestImageCache_Load_TIFFOffsetPastEnd(t *testing.T) {
+	// A 60-byte TIFF whose first IFD is 1.5 GB in: the decoder must not
+	// buffer up to it
+	data := append([]byte("II*\x008\x00\x00Y\x01\x02\x00"), make([]byte, 48)...)
+	path := filepath.Join(t.TempDir(), "crafted.tif")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err := NewImageCache().Load(path)
+	runtime.ReadMemStats(&after)
+	if err == nil {
+		t.Error("Load should fail for a truncated TIFF")
+	}
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 16<<20 {
+		t.Errorf("Load allocated %d MB for a 60-byte file", alloc>>20)
+	}
+}
+
 func TestImageCache_Clear(t *testing.T) {
 	cache := NewImageCache()
 	imgPath := createTestImage(t, 50, 50, color.RGBA{0, 255, 0, 255})
@@ -149,6 +190,39 @@ func TestImageCache_Evict(t *testing.T) {
 	}
 }
 
+func TestImageCache_Decode(t *testing.T) {
+	cache := NewImageCache()
+	imgPath := createTestImage(t, 40, 30, color.RGBA{255, 0, 255, 255})
+	defer os.Remove(imgPath)
+
+	img, err := cache.Decode(imgPath)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if img.Bounds().Dx() != 40 || img.Bounds().Dy() != 30 {
+		t.Errorf("dimensions: got %v, want 40x30", img.Bounds())
+	}
+
+	// Decode does not cache, but returns an image Load cached
+	cache.mu.RLock()
+	_, exists := cache.images[imgPath]
+	cache.mu.RUnlock()
+	if exists {
+		t.Error("Decode added the image to the cache")
+	}
+	loaded, err := cache.Load(imgPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if again, _ := cache.Decode(imgPath); again != loaded {
+		t.Error("Decode should return the cached image")
+	}
+
+	if _, err := cache.Decode("/nonexistent/image.png"); err == nil {
+		t.Error("expected error for nonexistent file")
+	}
+}
+
 func TestImageCache_Evict_NonExistent(t *testing.T) {
 	cache := NewImageCache()
 	// Should not panic
@@ -0,0 +1,602 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// Loading indicator detection: neighboring pixels belong to one shape if
+// no channel differs by more than loadingChainDiff, and at most
+// maxLoadingIndicators indicators are listed. Spinner arcs are measured in
+// loadingAngleBins bins around their circle, and cover from
+// spinnerMinBins to spinnerMaxBins of them. A ring of dots has
+// dotRingMin to dotRingMax dots of at most dotMaxSide pixels. A skeleton
+// placeholder is a flat shape of at least skeletonMinArea pixels, at
+// most skeletonMaxChroma from gray, whose luma differs from its
+// surroundings by skeletonMinContrast to skeletonMaxContrast; it shimmers
+// if the mean luma of its columns or rows ranges over skeletonShimmer.
+const (
+	loadingChainDiff     = 3
+	maxLoadingIndicators = 50
+	loadingAngleBins     = 72
+	spinnerMinBins       = 9
+	spinnerMaxBins       = 68
+	dotRingMin           = 5
+	dotRingMax           = 16
+	dotMaxSide           = 24
+	skeletonMinArea      = 60
+	skeletonMinSide      = 6
+	skeletonMaxChroma    = 24
+	skeletonMinContrast  = 4
+	skeletonMaxContrast  = 64
+	skeletonShimmer      = 6
+)
+
+// Kinds of LoadingIndicator.
+const (
+	LoadingSpinner  = "spinner"  // A rotating arc
+	LoadingDots     = "dots"     // A ring of dots or petals
+	LoadingSkeleton = "skeleton" // A placeholder standing in for content
+)
+
+// Shapes of skeleton placeholders.
+const (
+	PlaceholderBar    = "bar"    // A line of text
+	PlaceholderBlock  = "block"  // An image, card, or paragraph
+	PlaceholderCircle = "circle" // An avatar or icon
+)
+
+// LoadingIndicator is a spinner or skeleton placeholder found by
+// DetectLoading.
+type LoadingIndicator struct {
+	// Kind is LoadingSpinner, LoadingDots, or LoadingSkeleton.
+	Kind string `json:"kind"`
+
+	// Bounds is the indicator's bounding box.
+	Bounds Region `json:"bounds"`
+
+	// Color is the mean color (#RRGGBB) of the indicator, over all the
+	// dots of a ring.
+	Color string `json:"color"`
+
+	// Center and Radius are the circle a spinner's arc or a ring's dots
+	// lie on; unset for skeleton placeholders.
+	Center *Point  `json:"center,omitempty"`
+	Radius float64 `json:"radius,omitempty"`
+
+	// ArcDegrees is the part of the circle a spinner's arc covers, to 5
+	// degrees.
+	ArcDegrees float64 `json:"arc_degrees,omitempty"`
+
+	// Dots is the number of dots or petals in a ring.
+	Dots int `json:"dots,omitempty"`
+
+	// Shape is PlaceholderBar, PlaceholderBlock, or PlaceholderCircle for
+	// a skeleton placeholder.
+	Shape string `json:"shape,omitempty"`
+
+	// Shimmer is true if a skeleton placeholder has a gradient across it:
+	// the highlight of a shimmer animation caught mid-sweep.
+	Shimmer bool `json:"shimmer,omitempty"`
+}
+
+// LoadingResult reports the loading indicators on a screen.
+type LoadingResult struct {
+	// Loading is true if the screen has a spinner or ring of dots, or at
+	// least the requested number of skeleton placeholders: the page had
+	// not finished rendering.
+	Loading bool `json:"loading"`
+
+	// Spinners is the number of spinners and rings of dots.
+	Spinners int `json:"spinners"`
+
+	// SkeletonBlocks is the number of skeleton placeholders.
+	SkeletonBlocks int `json:"skeleton_blocks"`
+
+	// SkeletonPercent is the share of the image, in percent, covered by
+	// the bounding boxes of skeleton placeholders.
+	SkeletonPercent float64 `json:"skeleton_percent"`
+
+	// Shimmer is true if any skeleton placeholder shimmers.
+	Shimmer bool `json:"shimmer"`
+
+	// Indicators are the spinners and rings of dots, largest first, then
+	// the skeleton placeholders from top to bottom, up to 50.
+	Indicators []LoadingIndicator `json:"indicators"`
+}
+
+// loadingShape is a flat shape: 4-connected pixels whose neighbors differ
+// by at most loadingChainDiff in every channel. Bounds are exclusive.
+type loadingShape struct {
+	bounds     Region
+	area       int
+	sx, sy     int
+	sr, sg, sb int
+}
+
+// loadingPlane holds the pixels of an image and their shapes.
+type loadingPlane struct {
+	w, h   int
+	pix    []color.NRGBA
+	label  []int32
+	shapes []loadingShape
+}
+
+// DetectLoading finds the loading indicators of a screenshot, so that a
+// pipeline can tell a page that has not finished rendering before
+// analyzing its content: spinners (a rotating arc, or a ring of dots or
+// petals) and skeleton screens (gray placeholder bars, blocks, and
+// circles standing in for text, images, and avatars).
+//
+// Parameters:
+//   - img: The screenshot.
+//   - minSkeletonBlocks: The fewest skeleton placeholders that make a
+//     skeleton screen. A single flat gray block is as likely to be an
+//     empty field or panel.
+//
+// Returns:
+//   - *LoadingResult: The indicators found and whether the screen is
+//     loading.
+//   - error: Non-nil if minSkeletonBlocks is less than 1.
+//
+// # Method
+//
+// The image is split into flat shapes, joining neighboring pixels whose
+// channels differ by at most 3, so that antialiased edges and text
+// separate from the shapes they border. A shape is a spinner if a circle
+// fitted to its pixels (Kåsa's least-squares fit) runs through all of
+// them, so that it is a thin ring, and it covers one stretch of 45 to 340
+// degrees of the circle: a full ring, such as a radio button, does not
+// spin. Shapes beside two or more shapes of their height in a row are
+// letters, such as a "C", and are skipped. Five to 16 small shapes of
+// similar size whose centers lie evenly on a circle are a ring of dots.
+//
+// A skeleton placeholder is a near-gray shape that fills its bounding
+// box (a bar or block, with rounded corners at most) or the circle in it,
+// lies on one flat color it differs from by 4 to 64 levels of luma, and
+// contains nothing else, such as the label of a button. It shimmers if
+// its luma changes gradually across it by 6 levels or more.
+func DetectLoading(img image.Image, minSkeletonBlocks int) (*LoadingResult, error) {
+	if minSkeletonBlocks < 1 {
+		return nil, fmt.Errorf("min_skeleton_blocks must be at least 1, got %d", minSkeletonBlocks)
+	}
+	b := img.Bounds()
+	p := newLoadingPlane(img)
+
+	result := &LoadingResult{Indicators: []LoadingIndicator{}}
+	var spinners, skeletons []LoadingIndicator
+	var dots []int
+	skeletonArea := 0
+	for id := range p.shapes {
+		if ind, ok := p.spinner(id); ok {
+			spinners = append(spinners, ind)
+		} else if ind, ok := p.placeholder(id); ok {
+			skeletons = append(skeletons, ind)
+			skeletonArea += regionArea(ind.Bounds)
+			result.Shimmer = result.Shimmer || ind.Shimmer
+		} else if p.dotCandidate(id) {
+			dots = append(dots, id)
+		}
+	}
+	spinners = append(spinners, p.dotRings(dots)...)
+	sort.SliceStable(spinners, func(i, j int) bool {
+		return regionArea(spinners[i].Bounds) > regionArea(spinners[j].Bounds)
+	})
+	sort.SliceStable(skeletons, func(i, j int) bool {
+		if skeletons[i].Bounds.Y1 != skeletons[j].Bounds.Y1 {
+			return skeletons[i].Bounds.Y1 < skeletons[j].Bounds.Y1
+		}
+		return skeletons[i].Bounds.X1 < skeletons[j].Bounds.X1
+	})
+
+	result.Spinners = len(spinners)
+	result.SkeletonBlocks = len(skeletons)
+	if p.w > 0 && p.h > 0 {
+		result.SkeletonPercent = math.Round(float64(skeletonArea)/float64(p.w*p.h)*1000) / 10
+	}
+	result.Loading = result.Spinners > 0 || result.SkeletonBlocks >= minSkeletonBlocks
+	for _, ind := range append(spinners, skeletons...) {
+		if len(result.Indicators) == maxLoadingIndicators {
+			break
+		}
+		r := &ind.Bounds
+		r.X1, r.Y1, r.X2, r.Y2 = r.X1+b.Min.X, r.Y1+b.Min.Y, r.X2+b.Min.X, r.Y2+b.Min.Y
+		if ind.Center != nil {
+			ind.Center = &Point{X: ind.Center.X + b.Min.X, Y: ind.Center.Y + b.Min.Y}
+		}
+		result.Indicators = append(result.Indicators, ind)
+	}
+	return result, nil
+}
+
+// newLoadingPlane reads img and labels its flat shapes.
+func newLoadingPlane(img image.Image) *loadingPlane {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	p := &loadingPlane{w: w, h: h, pix: make([]color.NRGBA, w*h), label: make([]int32, w*h)}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			p.pix[y*w+x] = nrgbaAt(img, b.Min.X+x, b.Min.Y+y)
+			p.label[y*w+x] = -1
+		}
+	}
+	var stack []int
+	for start := range p.pix {
+		if p.label[start] >= 0 {
+			continue
+		}
+		id := int32(len(p.shapes))
+		s := loadingShape{bounds: Region{X1: w, Y1: h}}
+		p.label[start] = id
+		stack = append(stack[:0], start)
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x, y, c := i%w, i/w, p.pix[i]
+			s.area++
+			s.sx, s.sy = s.sx+x, s.sy+y
+			s.sr, s.sg, s.sb = s.sr+int(c.R), s.sg+int(c.G), s.sb+int(c.B)
+			s.bounds.X1, s.bounds.Y1 = min(s.bounds.X1, x), min(s.bounds.Y1, y)
+			s.bounds.X2, s.bounds.Y2 = max(s.bounds.X2, x+1), max(s.bounds.Y2, y+1)
+			for _, j := range [4]int{i - w, i + w, i - 1, i + 1} {
+				if j < 0 || j >= len(p.pix) || (j == i-1 && x == 0) || (j == i+1 && x == w-1) {
+					continue
+				}
+				if p.label[j] < 0 && colorDiff(c, p.pix[j]) <= loadingChainDiff {
+					p.label[j] = id
+					stack = append(stack, j)
+				}
+			}
+		}
+		p.shapes = append(p.shapes, s)
+	}
+	return p
+}
+
+// colorDiff returns the largest difference between the channels of a and
+// b, ignoring alpha.
+func colorDiff(a, b color.NRGBA) int {
+	return max(absInt(int(a.R)-int(b.R)), absInt(int(a.G)-int(b.G)), absInt(int(a.B)-int(b.B)))
+}
+
+// meanColor returns the mean color of shape s.
+func (s loadingShape) meanColor() color.NRGBA {
+	n := s.area
+	return color.NRGBA{R: uint8((s.sr + n/2) / n), G: uint8((s.sg + n/2) / n), B: uint8((s.sb + n/2) / n), A: 255}
+}
+
+// spinner reports whether shape id is the arc of a spinner.
+func (p *loadingPlane) spinner(id int) (LoadingIndicator, bool) {
+	s := p.shapes[id]
+	bw, bh := s.bounds.X2-s.bounds.X1, s.bounds.Y2-s.bounds.Y1
+	if s.area < 24 || max(bw, bh) < 10 || max(bw, bh) > 200 {
+		return LoadingIndicator{}, false
+	}
+	xs := make([]float64, 0, s.area)
+	ys := make([]float64, 0, s.area)
+	for y := s.bounds.Y1; y < s.bounds.Y2; y++ {
+		for x := s.bounds.X1; x < s.bounds.X2; x++ {
+			if p.label[y*p.w+x] == int32(id) {
+				xs, ys = append(xs, float64(x)+0.5), append(ys, float64(y)+0.5)
+			}
+		}
+	}
+	cx, cy, r, ok := fitCircle(xs, ys)
+	if !ok || r < 5 || r > 100 || r > float64(max(bw, bh)) {
+		return LoadingIndicator{}, false
+	}
+
+	// Every pixel lies in a thin band around the circle, and the arc is
+	// round: each bin's pixels are at the circle's radius on average.
+	band := math.Max(2, 0.3*r)
+	var sum, count [loadingAngleBins]float64
+	for i := range xs {
+		dx, dy := xs[i]-cx, ys[i]-cy
+		d := math.Hypot(dx, dy)
+		if math.Abs(d-r) > band {
+			return LoadingIndicator{}, false
+		}
+		a := math.Atan2(dy, dx)
+		bin := int((a + math.Pi) / (2 * math.Pi) * loadingAngleBins)
+		bin = min(bin, loadingAngleBins-1)
+		sum[bin] += d
+		count[bin]++
+	}
+	occupied, runs := 0, 0
+	for i := range count {
+		if count[i] == 0 {
+			continue
+		}
+		occupied++
+		if count[(i+loadingAngleBins-1)%loadingAngleBins] == 0 {
+			runs++
+		}
+		if math.Abs(sum[i]/count[i]-r) > math.Max(1.5, 0.12*r) {
+			return LoadingIndicator{}, false
+		}
+	}
+	if occupied < spinnerMinBins || occupied > spinnerMaxBins || runs != 1 || p.inTextRow(id) {
+		return LoadingIndicator{}, false
+	}
+	return LoadingIndicator{
+		Kind:       LoadingSpinner,
+		Bounds:     s.bounds,
+		Color:      hexNRGBA(s.meanColor()),
+		Center:     &Point{X: int(math.Floor(cx)), Y: int(math.Floor(cy))},
+		Radius:     math.Round(r*10) / 10,
+		ArcDegrees: float64(occupied * 360 / loadingAngleBins),
+	}, true
+}
+
+// inTextRow reports whether shape id has two or more shapes of about its
+// height beside it in a row, as the letters of a word do.
+func (p *loadingPlane) inTextRow(id int) bool {
+	s := p.shapes[id]
+	h := s.bounds.Y2 - s.bounds.Y1
+	mid := s.bounds.Y1 + s.bounds.Y2
+	neighbors := 0
+	for j, o := range p.shapes {
+		oh := o.bounds.Y2 - o.bounds.Y1
+		if j == id || absInt(oh-h) > h/4 || absInt(o.bounds.Y1+o.bounds.Y2-mid) > h/2 {
+			continue
+		}
+		if gap := max(o.bounds.X1-s.bounds.X2, s.bounds.X1-o.bounds.X2); gap <= h {
+			neighbors++
+		}
+	}
+	return neighbors >= 2
+}
+
+// dotCandidate reports whether shape id is small and compact enough to be
+// a dot or petal of a ring.
+func (p *loadingPlane) dotCandidate(id int) bool {
+	s := p.shapes[id]
+	bw, bh := s.bounds.X2-s.bounds.X1, s.bounds.Y2-s.bounds.Y1
+	return s.area >= 6 && max(bw, bh) <= dotMaxSide && max(bw, bh) <= 4*min(bw, bh) &&
+		s.area*4 >= bw*bh
+}
+
+// dotRings groups the dot candidates ids into rings of dots.
+func (p *loadingPlane) dotRings(ids []int) []LoadingIndicator {
+	side := func(id int) int {
+		b := p.shapes[id].bounds
+		return max(b.X2-b.X1, b.Y2-b.Y1)
+	}
+	center := func(id int) (float64, float64) {
+		s := p.shapes[id]
+		return float64(s.sx)/float64(s.area) + 0.5, float64(s.sy)/float64(s.area) + 0.5
+	}
+
+	// Link dots closer than three times their size, sweeping in order of x.
+	sort.Slice(ids, func(i, j int) bool { return p.shapes[ids[i]].bounds.X1 < p.shapes[ids[j]].bounds.X1 })
+	parent := make([]int, len(ids))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i, a := range ids {
+		ax, ay := center(a)
+		for j := i + 1; j < len(ids); j++ {
+			bx, by := center(ids[j])
+			reach := float64(3 * max(side(a), side(ids[j])))
+			if bx-ax > 3*dotMaxSide+1 {
+				break
+			}
+			if math.Hypot(bx-ax, by-ay) <= reach {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+	groups := make(map[int][]int)
+	var roots []int
+	for i, id := range ids {
+		root := find(i)
+		if groups[root] == nil {
+			roots = append(roots, root)
+		}
+		groups[root] = append(groups[root], id)
+	}
+
+	var rings []LoadingIndicator
+	for _, root := range roots {
+		group := groups[root]
+		n := len(group)
+		if n < dotRingMin || n > dotRingMax {
+			continue
+		}
+		minSide, maxSide := dotMaxSide, 0
+		xs, ys := make([]float64, n), make([]float64, n)
+		for i, id := range group {
+			minSide, maxSide = min(minSide, side(id)), max(maxSide, side(id))
+			xs[i], ys[i] = center(id)
+		}
+		if maxSide > 2*minSide {
+			continue
+		}
+		cx, cy, r, ok := fitCircle(xs, ys)
+		if !ok || r < 4 || r > 80 {
+			continue
+		}
+		angles := make([]float64, n)
+		round := true
+		for i := range xs {
+			if math.Abs(math.Hypot(xs[i]-cx, ys[i]-cy)-r) > math.Max(1.5, 0.2*r) {
+				round = false
+				break
+			}
+			angles[i] = math.Atan2(ys[i]-cy, xs[i]-cx) * 180 / math.Pi
+		}
+		if !round {
+			continue
+		}
+		sort.Float64s(angles)
+		step := 360 / float64(n)
+		minGap, maxGap := angles[0]+360-angles[n-1], angles[0]+360-angles[n-1]
+		for i := 1; i < n; i++ {
+			minGap, maxGap = math.Min(minGap, angles[i]-angles[i-1]), math.Max(maxGap, angles[i]-angles[i-1])
+		}
+		if minGap < 0.4*step || maxGap > 2.5*step || maxGap > 120 {
+			continue
+		}
+
+		ring := loadingShape{bounds: Region{X1: p.w, Y1: p.h}}
+		for _, id := range group {
+			s := p.shapes[id]
+			ring.area += s.area
+			ring.sr, ring.sg, ring.sb = ring.sr+s.sr, ring.sg+s.sg, ring.sb+s.sb
+			ring.bounds.X1, ring.bounds.Y1 = min(ring.bounds.X1, s.bounds.X1), min(ring.bounds.Y1, s.bounds.Y1)
+			ring.bounds.X2, ring.bounds.Y2 = max(ring.bounds.X2, s.bounds.X2), max(ring.bounds.Y2, s.bounds.Y2)
+		}
+		rings = append(rings, LoadingIndicator{
+			Kind:   LoadingDots,
+			Bounds: ring.bounds,
+			Color:  hexNRGBA(ring.meanColor()),
+			Center: &Point{X: int(math.Floor(cx)), Y: int(math.Floor(cy))},
+			Radius: math.Round(r*10) / 10,
+			Dots:   n,
+		})
+	}
+	return rings
+}
+
+// placeholder reports whether shape id is a skeleton placeholder.
+func (p *loadingPlane) placeholder(id int) (LoadingIndicator, bool) {
+	s := p.shapes[id]
+	r := s.bounds
+	bw, bh := r.X2-r.X1, r.Y2-r.Y1
+	if s.area < skeletonMinArea || bw < skeletonMinSide || bh < skeletonMinSide || bw*bh*4 > p.w*p.h {
+		return LoadingIndicator{}, false
+	}
+	fill := float64(s.area) / float64(bw*bh)
+	aspect := float64(bw) / float64(bh)
+	var shape string
+	switch {
+	case aspect >= 0.85 && aspect <= 1.18 && fill >= 0.7 && fill <= 0.84:
+		shape = PlaceholderCircle
+	case fill < 0.85:
+		return LoadingIndicator{}, false
+	case bw >= 3*bh && bh <= 40:
+		shape = PlaceholderBar
+	default:
+		shape = PlaceholderBlock
+	}
+	c := s.meanColor()
+	if int(max(c.R, c.G, c.B))-int(min(c.R, c.G, c.B)) > skeletonMaxChroma {
+		return LoadingIndicator{}, false
+	}
+
+	// The shape lies on one flat color: most of the pixels 2 outside its
+	// box share it.
+	around := make(map[color.NRGBA]int)
+	total := 0
+	for y := r.Y1 - 2; y < r.Y2+2; y++ {
+		for x := r.X1 - 2; x < r.X2+2; x++ {
+			if x < 0 || y < 0 || x >= p.w || y >= p.h || (x >= r.X1-1 && x <= r.X2 && y >= r.Y1-1 && y <= r.Y2) {
+				continue
+			}
+			around[p.pix[y*p.w+x]]++
+			total++
+		}
+	}
+	var bg color.NRGBA
+	for k, n := range around {
+		if n > around[bg] || (n == around[bg] && luma(k) > luma(bg)) {
+			bg = k
+		}
+	}
+	contrast := absInt(int(luma(c)) - int(luma(bg)))
+	if total == 0 || around[bg]*5 < total*4 || contrast < skeletonMinContrast || contrast > skeletonMaxContrast {
+		return LoadingIndicator{}, false
+	}
+
+	// Nothing else lies inside: the rest of the box is antialiasing or
+	// background. Column and row means of luma show a shimmer.
+	tolerance := colorDiff(c, bg)/2 + 8
+	foreign := 0
+	colSum, colN := make([]int, bw), make([]int, bw)
+	rowSum, rowN := make([]int, bh), make([]int, bh)
+	for y := r.Y1; y < r.Y2; y++ {
+		for x := r.X1; x < r.X2; x++ {
+			q := p.pix[y*p.w+x]
+			if p.label[y*p.w+x] != int32(id) {
+				if min(colorDiff(q, c), colorDiff(q, bg)) > tolerance {
+					foreign++
+				}
+				continue
+			}
+			l := int(luma(q))
+			colSum[x-r.X1], colN[x-r.X1] = colSum[x-r.X1]+l, colN[x-r.X1]+1
+			rowSum[y-r.Y1], rowN[y-r.Y1] = rowSum[y-r.Y1]+l, rowN[y-r.Y1]+1
+		}
+	}
+	if foreign*100 > bw*bh || foreign > max(2, bw*bh/100) {
+		return LoadingIndicator{}, false
+	}
+	return LoadingIndicator{
+		Kind:    LoadingSkeleton,
+		Bounds:  r,
+		Color:   hexNRGBA(c),
+		Shape:   shape,
+		Shimmer: meanRange(colSum, colN) >= skeletonShimmer || meanRange(rowSum, rowN) >= skeletonShimmer,
+	}, true
+}
+
+// meanRange returns the range of sum[i]/n[i] over the entries with n[i] > 0.
+func meanRange(sum, n []int) float64 {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for i := range sum {
+		if n[i] == 0 {
+			continue
+		}
+		m := float64(sum[i]) / float64(n[i])
+		lo, hi = math.Min(lo, m), math.Max(hi, m)
+	}
+	if hi < lo {
+		return 0
+	}
+	return hi - lo
+}
+
+// fitCircle fits a circle to the points (xs[i], ys[i]) by least squares
+// on the circle's algebraic equation (Kåsa's method), solved about the
+// points' mean. ok is false for fewer than 3 points or collinear ones.
+func fitCircle(xs, ys []float64) (cx, cy, r float64, ok bool) {
+	n := float64(len(xs))
+	if len(xs) < 3 {
+		return 0, 0, 0, false
+	}
+	var mx, my float64
+	for i := range xs {
+		mx, my = mx+xs[i], my+ys[i]
+	}
+	mx, my = mx/n, my/n
+	var suu, svv, suv, suuu, svvv, suvv, svuu float64
+	for i := range xs {
+		u, v := xs[i]-mx, ys[i]-my
+		suu, svv, suv = suu+u*u, svv+v*v, suv+u*v
+		suuu, svvv = suuu+u*u*u, svvv+v*v*v
+		suvv, svuu = suvv+u*v*v, svuu+v*u*u
+	}
+	det := suu*svv - suv*suv
+	if math.Abs(det) < 1e-9*math.Max(1, suu*svv) {
+		return 0, 0, 0, false
+	}
+	bu, bv := (suuu+suvv)/2, (svvv+svuu)/2
+	uc := (bu*svv - bv*suv) / det
+	vc := (bv*suu - bu*suv) / det
+	return uc + mx, vc + my, math.Sqrt(uc*uc + vc*vc + (suu+svv)/n), true
+}
+
+// hexNRGBA formats c as #RRGGBB.
+func hexNRGBA(c color.NRGBA) string {
+	return fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B)
+}
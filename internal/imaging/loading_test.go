@@ -0,0 +1,120 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+)
+
+// loadingCanvas returns a white w×h image.
+func loadingCanvas(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	return img
+}
+
+// paintRing paints the pixels from inner to outer radius around (cx, cy)
+// whose angle, clockwise from the right, is below degrees.
+func paintRing(img *image.RGBA, cx, cy, inner, outer, degrees float64, c color.RGBA) {
+	for y := int(cy - outer - 1); y <= int(cy+outer+1); y++ {
+		for x := int(cx - outer - 1); x <= int(cx+outer+1); x++ {
+			dx, dy := float64(x)+0.5-cx, float64(y)+0.5-cy
+			a := math.Atan2(dy, dx) * 180 / math.Pi
+			if a < 0 {
+				a += 360
+			}
+			if d := math.Hypot(dx, dy); d >= inner && d <= outer && a < degrees {
+				img.Set(x, y, c)
+			}
+		}
+	}
+}
+
+// paintBar fills r with a gray ramp from start to end luma, left to right.
+func paintBar(img *image.RGBA, r image.Rectangle, start, end int) {
+	for x := r.Min.X; x < r.Max.X; x++ {
+		v := uint8(start + (end-start)*(x-r.Min.X)/max(1, r.Dx()-1))
+		draw.Draw(img, image.Rect(x, r.Min.Y, x+1, r.Max.Y), image.NewUniform(color.RGBA{v, v, v, 255}), image.Point{}, draw.Src)
+	}
+}
+
+// loadingScene draws a loading screen: an arc spinner, a ring of eight
+// fading dots, and a skeleton card with an avatar, two text bars (one
+// shimmering), and an image block. A radio button and a labeled gray
+// button are not loading indicators.
+func loadingScene() *image.RGBA {
+	img := loadingCanvas(400, 300)
+	paintRing(img, 60, 60, 14, 18, 270, color.RGBA{25, 118, 210, 255})
+	for i := 0; i < 8; i++ {
+		a := float64(i) * math.Pi / 4
+		v := uint8(40 + 20*i)
+		paintRing(img, 160+16*math.Cos(a), 60+16*math.Sin(a), 0, 3.5, 360, color.RGBA{v, v, v, 255})
+	}
+	paintRing(img, 260, 60, 9, 12, 360, color.RGBA{80, 80, 80, 255})
+	draw.Draw(img, image.Rect(300, 45, 380, 75), image.NewUniform(color.RGBA{224, 224, 224, 255}), image.Point{}, draw.Src)
+	drawText(img, 312, 54, 60, "Submit", color.Black)
+
+	paintRing(img, 60, 180, 0, 20, 360, color.RGBA{224, 224, 224, 255})
+	paintBar(img, image.Rect(100, 165, 300, 177), 224, 224)
+	paintBar(img, image.Rect(100, 185, 260, 197), 216, 238)
+	paintBar(img, image.Rect(20, 220, 380, 290), 224, 224)
+	return img
+}
+
+func TestDetectLoading(t *testing.T) {
+	result, err := DetectLoading(loadingScene(), 3)
+	if err != nil {
+		t.Fatalf("DetectLoading failed: %v", err)
+	}
+	if !result.Loading || result.Spinners != 2 || result.SkeletonBlocks != 4 || !result.Shimmer {
+		t.Fatalf("got %+v", result)
+	}
+	dots, arc := result.Indicators[0], result.Indicators[1]
+	if arc.Kind != LoadingSpinner || arc.ArcDegrees < 260 || arc.ArcDegrees > 280 || math.Abs(arc.Radius-16) > 1 || arc.Color != "#1976D2" {
+		t.Errorf("arc = %+v", arc)
+	}
+	if dots.Kind != LoadingDots || dots.Dots != 8 || *dots.Center != (Point{X: 160, Y: 60}) || math.Abs(dots.Radius-16) > 1 {
+		t.Errorf("dots = %+v", dots)
+	}
+	wantShapes := []string{PlaceholderCircle, PlaceholderBar, PlaceholderBar, PlaceholderBlock}
+	for i, ind := range result.Indicators[2:] {
+		if ind.Kind != LoadingSkeleton || ind.Shape != wantShapes[i] || ind.Shimmer != (i == 2) {
+			t.Errorf("placeholder %d = %+v, want a %s", i, ind, wantShapes[i])
+		}
+	}
+	if result.Indicators[5].Bounds != (Region{X1: 20, Y1: 220, X2: 380, Y2: 290}) {
+		t.Errorf("block bounds = %+v", result.Indicators[5].Bounds)
+	}
+}
+
+func TestDetectLoading_Rendered(t *testing.T) {
+	img := loadingCanvas(400, 300)
+	paintRing(img, 260, 60, 9, 12, 360, color.RGBA{80, 80, 80, 255})
+	draw.Draw(img, image.Rect(300, 45, 380, 75), image.NewUniform(color.RGBA{224, 224, 224, 255}), image.Point{}, draw.Src)
+	drawText(img, 312, 54, 60, "Submit", color.Black)
+	for i, line := range []string{"Order #1042 shipped", "Arrives on Thursday", "Carrier: Parcel Co."} {
+		drawText(img, 20, 120+20*i, 360, line, color.Black)
+	}
+	draw.Draw(img, image.Rect(20, 220, 200, 290), image.NewUniform(color.RGBA{224, 224, 224, 255}), image.Point{}, draw.Src)
+
+	result, err := DetectLoading(img, 3)
+	if err != nil {
+		t.Fatalf("DetectLoading failed: %v", err)
+	}
+	if result.Loading || result.Spinners != 0 || result.SkeletonBlocks != 1 {
+		t.Errorf("got %+v, want one lone gray block and no loading", result)
+	}
+
+	result, _ = DetectLoading(img, 1)
+	if !result.Loading {
+		t.Error("a single placeholder should count when min_skeleton_blocks is 1")
+	}
+}
+
+func TestDetectLoading_Invalid(t *testing.T) {
+	if _, err := DetectLoading(loadingCanvas(10, 10), 0); err == nil {
+		t.Error("expected error for min_skeleton_blocks 0")
+	}
+}
@@ -0,0 +1,167 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// maxMaskVertices caps the vertices of a polygon mask.
+const maxMaskVertices = 10000
+
+// Mask selects an irregular area of an image, such as a circular avatar
+// or a rounded panel, for tools that would otherwise only take a
+// rectangle. Exactly one of Polygon and RLE is set.
+type Mask struct {
+	// Polygon lists the vertices of a simple or self-intersecting polygon
+	// in order; the last vertex connects back to the first. A pixel is
+	// inside if its center is, by the even-odd rule.
+	Polygon []Point `json:"polygon,omitempty"`
+
+	// RLE is a run-length encoded bitmap.
+	RLE *RLE `json:"rle,omitempty"`
+}
+
+// RLE is a bitmap in the uncompressed run-length encoding of COCO
+// segmentation masks: Counts alternates runs of unselected and selected
+// pixels, starting with unselected (possibly a run of 0), in column-major
+// order (down each column, then left to right).
+type RLE struct {
+	// Size is [height, width] of the bitmap.
+	Size [2]int `json:"size"`
+
+	// Counts are the run lengths; they must add up to height × width.
+	Counts []int `json:"counts"`
+}
+
+// Rasterize renders the mask over bounds as an image.Alpha with the same
+// bounds, 255 where selected and 0 elsewhere. Polygon vertices are in the
+// coordinates of bounds; an RLE bitmap covers bounds exactly, from
+// bounds.Min.
+//
+// Returns an error if the mask is malformed, if an RLE bitmap's size
+// differs from that of bounds, or if no pixel in bounds is selected.
+func (m *Mask) Rasterize(bounds image.Rectangle) (*image.Alpha, error) {
+	switch {
+	case (m.Polygon == nil) == (m.RLE == nil):
+		return nil, fmt.Errorf("mask must have exactly one of polygon and rle")
+	case m.Polygon != nil:
+		return rasterizePolygon(m.Polygon, bounds)
+	default:
+		return rasterizeRLE(m.RLE, bounds)
+	}
+}
+
+// rasterizePolygon fills a polygon by the even-odd rule, sampling pixel
+// centers.
+func rasterizePolygon(poly []Point, bounds image.Rectangle) (*image.Alpha, error) {
+	if len(poly) < 3 || len(poly) > maxMaskVertices {
+		return nil, fmt.Errorf("mask polygon must have 3 to %d vertices, got %d", maxMaskVertices, len(poly))
+	}
+
+	mask := image.NewAlpha(bounds)
+	selected := 0
+	var xs []float64
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		cy := float64(y) + 0.5
+		xs = xs[:0]
+		for i, p := range poly {
+			q := poly[(i+1)%len(poly)]
+			y1, y2 := float64(p.Y), float64(q.Y)
+			if (y1 <= cy) == (y2 <= cy) {
+				continue
+			}
+			xs = append(xs, float64(p.X)+(cy-y1)/(y2-y1)*float64(q.X-p.X))
+		}
+		sort.Float64s(xs)
+		for i := 0; i+1 < len(xs); i += 2 {
+			// Pixels whose centers lie between the crossings
+			x1 := max(bounds.Min.X, int(math.Ceil(xs[i]-0.5)))
+			x2 := min(bounds.Max.X, int(math.Ceil(xs[i+1]-0.5)))
+			for x := x1; x < x2; x++ {
+				mask.Pix[mask.PixOffset(x, y)] = 255
+				selected++
+			}
+		}
+	}
+	if selected == 0 {
+		return nil, fmt.Errorf("mask polygon selects no pixels in %v", bounds)
+	}
+	return mask, nil
+}
+
+// rasterizeRLE decodes a column-major RLE bitmap.
+func rasterizeRLE(rle *RLE, bounds image.Rectangle) (*image.Alpha, error) {
+	h, w := rle.Size[0], rle.Size[1]
+	if w != bounds.Dx() || h != bounds.Dy() {
+		return nil, fmt.Errorf("mask rle size must be [%d, %d] (height, width), got [%d, %d]", bounds.Dy(), bounds.Dx(), h, w)
+	}
+
+	mask := image.NewAlpha(bounds)
+	total := w * h
+	pos, selected := 0, 0
+	for i, n := range rle.Counts {
+		if n < 0 || n > total-pos {
+			return nil, fmt.Errorf("mask rle counts must be non-negative and add up to %d", total)
+		}
+		if i%2 == 1 {
+			for j := pos; j < pos+n; j++ {
+				mask.Pix[mask.PixOffset(bounds.Min.X+j/h, bounds.Min.Y+j%h)] = 255
+			}
+			selected += n
+		}
+		pos += n
+	}
+	if pos != total {
+		return nil, fmt.Errorf("mask rle counts must add up to %d, got %d", total, pos)
+	}
+	if selected == 0 {
+		return nil, fmt.Errorf("mask rle selects no pixels")
+	}
+	return mask, nil
+}
+
+// MaskBounds returns the smallest rectangle containing every selected
+// pixel of mask, or the empty rectangle if there are none.
+func MaskBounds(mask *image.Alpha) image.Rectangle {
+	b := mask.Bounds()
+	r := image.Rectangle{Min: b.Max, Max: b.Min}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if mask.Pix[mask.PixOffset(x, y)] != 0 {
+				r.Min.X, r.Min.Y = min(r.Min.X, x), min(r.Min.Y, y)
+				r.Max.X, r.Max.Y = max(r.Max.X, x+1), max(r.Max.Y, y+1)
+			}
+		}
+	}
+	if r.Empty() {
+		return image.Rectangle{}
+	}
+	return r
+}
+
+// MaskImage copies the part of img within the bounds of mask, replacing
+// unselected pixels with fill, so tools that only take rectangles (such
+// as OCR) see the masked area alone. The result keeps img's coordinates.
+func MaskImage(img image.Image, mask *image.Alpha, fill color.Color) *image.RGBA {
+	b := MaskBounds(mask).Intersect(img.Bounds())
+	out := image.NewRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if mask.Pix[mask.PixOffset(x, y)] != 0 {
+				out.Set(x, y, img.At(x, y))
+			} else {
+				out.Set(x, y, fill)
+			}
+		}
+	}
+	return out
+}
+
+// maskSelects reports whether mask selects the pixel at (x, y); a nil
+// mask selects every pixel.
+func maskSelects(mask *image.Alpha, x, y int) bool {
+	return mask == nil || mask.AlphaAt(x, y).A != 0
+}
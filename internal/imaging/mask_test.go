@@ -0,0 +1,136 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// maskString renders a mask as rows of '#' (selected) and '.'.
+func maskString(m *image.Alpha) string {
+	b := m.Bounds()
+	s := ""
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if m.AlphaAt(x, y).A != 0 {
+				s += "#"
+			} else {
+				s += "."
+			}
+		}
+		s += "\n"
+	}
+	return s
+}
+
+func TestMaskRasterize(t *testing.T) {
+	bounds := image.Rect(0, 0, 6, 4)
+
+	// A rectangle polygon selects exactly the pixels it encloses
+	square := &Mask{Polygon: []Point{{1, 1}, {4, 1}, {4, 3}, {1, 3}}}
+	m, err := square.Rasterize(bounds)
+	if err != nil {
+		t.Fatalf("Rasterize failed: %v", err)
+	}
+	if got, want := maskString(m), "......\n.###..\n.###..\n......\n"; got != want {
+		t.Errorf("square:\n%swant:\n%s", got, want)
+	}
+	if got := MaskBounds(m); got != image.Rect(1, 1, 4, 3) {
+		t.Errorf("MaskBounds: got %v", got)
+	}
+
+	// A triangle, clipped by the bounds
+	triangle := &Mask{Polygon: []Point{{0, 0}, {8, 0}, {0, 8}}}
+	m, err = triangle.Rasterize(bounds)
+	if err != nil {
+		t.Fatalf("Rasterize failed: %v", err)
+	}
+	if got, want := maskString(m), "######\n######\n#####.\n####..\n"; got != want {
+		t.Errorf("triangle:\n%swant:\n%s", got, want)
+	}
+
+	// RLE runs go down the columns: 2 off, 3 on, 7 off, 1 on, 11 off
+	rle := &Mask{RLE: &RLE{Size: [2]int{4, 6}, Counts: []int{2, 3, 7, 1, 11}}}
+	m, err = rle.Rasterize(image.Rect(10, 20, 16, 24))
+	if err != nil {
+		t.Fatalf("Rasterize failed: %v", err)
+	}
+	if got, want := maskString(m), ".#.#..\n......\n#.....\n#.....\n"; got != want {
+		t.Errorf("rle:\n%swant:\n%s", got, want)
+	}
+	if m.AlphaAt(11, 20).A == 0 {
+		t.Error("rle should be placed at the origin of the bounds")
+	}
+
+	for name, bad := range map[string]*Mask{
+		"empty":          {},
+		"both":           {Polygon: square.Polygon, RLE: rle.RLE},
+		"two vertices":   {Polygon: []Point{{0, 0}, {5, 5}}},
+		"outside":        {Polygon: []Point{{10, 10}, {20, 10}, {20, 20}}},
+		"rle size":       {RLE: &RLE{Size: [2]int{6, 4}, Counts: []int{24}}},
+		"rle short":      {RLE: &RLE{Size: [2]int{4, 6}, Counts: []int{2, 3}}},
+		"rle long":       {RLE: &RLE{Size: [2]int{4, 6}, Counts: []int{20, 10}}},
+		"rle negative":   {RLE: &RLE{Size: [2]int{4, 6}, Counts: []int{-1, 25}}},
+		"rle unselected": {RLE: &RLE{Size: [2]int{4, 6}, Counts: []int{24}}},
+	} {
+		if _, err := bad.Rasterize(bounds); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestMaskedOperations(t *testing.T) {
+	// A red disk on a blue square on white, like an avatar in a frame
+	img := image.NewRGBA(image.Rect(0, 0, 40, 40))
+	fillRect(img, 0, 0, 40, 40, color.White)
+	fillRect(img, 10, 10, 30, 30, color.RGBA{0, 0, 255, 255})
+	for y := 10; y < 30; y++ {
+		for x := 10; x < 30; x++ {
+			if dx, dy := float64(x)+0.5-20, float64(y)+0.5-20; dx*dx+dy*dy < 64 {
+				img.Set(x, y, color.RGBA{255, 0, 0, 255})
+			}
+		}
+	}
+	// An octagon inside the disk
+	octagon := &Mask{Polygon: []Point{{17, 13}, {23, 13}, {27, 17}, {27, 23}, {23, 27}, {17, 27}, {13, 23}, {13, 17}}}
+	mask, err := octagon.Rasterize(img.Bounds())
+	if err != nil {
+		t.Fatalf("Rasterize failed: %v", err)
+	}
+
+	colors, err := DominantColorsMasked(img, 5, nil, mask)
+	if err != nil {
+		t.Fatalf("DominantColorsMasked failed: %v", err)
+	}
+	if len(colors.Colors) != 1 || colors.Colors[0].Hex != "#F00000" {
+		t.Errorf("masked colors: got %+v, want only red", colors.Colors)
+	}
+	if _, err := DominantColorsMasked(img, 5, &Region{X1: 0, Y1: 0, X2: 5, Y2: 5}, mask); err == nil {
+		t.Error("expected error when the region excludes the mask")
+	}
+
+	masked := MaskImage(img, mask, color.White)
+	if masked.Bounds() != image.Rect(13, 13, 27, 27) {
+		t.Errorf("MaskImage bounds: got %v", masked.Bounds())
+	}
+	if masked.RGBAAt(13, 13) != (color.RGBA{255, 255, 255, 255}) || masked.RGBAAt(20, 20) != (color.RGBA{255, 0, 0, 255}) {
+		t.Error("MaskImage should keep masked pixels and fill the rest")
+	}
+
+	// The frame corners differ from the disk, but not under a disk mask
+	r1 := Region{X1: 10, Y1: 10, X2: 30, Y2: 30}
+	r2 := Region{X1: 12, Y1: 12, X2: 32, Y2: 32}
+	local := &Mask{Polygon: []Point{{7, 3}, {13, 3}, {17, 7}, {17, 13}, {13, 17}, {7, 17}, {3, 13}, {3, 7}}}
+	localMask, err := local.Rasterize(image.Rect(0, 0, 20, 20))
+	if err != nil {
+		t.Fatalf("Rasterize failed: %v", err)
+	}
+	whole, _ := CompareRegions(img, r1, r2)
+	inner, err := CompareRegionsMasked(img, r1, r2, localMask)
+	if err != nil {
+		t.Fatalf("CompareRegionsMasked failed: %v", err)
+	}
+	if inner.TotalPixels >= whole.TotalPixels || inner.SimilarityScore <= whole.SimilarityScore {
+		t.Errorf("masked comparison: got %+v, whole %+v", inner, whole)
+	}
+}
@@ -1,7 +1,9 @@
 package imaging
 
 import (
+	"fmt"
 	"image"
+	"image/color"
 	"math"
 )
 
@@ -42,6 +44,15 @@ type DistanceResult struct {
 
 	// DistancePercentHeight is the distance as a percentage of image height.
 	DistancePercentHeight float64 `json:"distance_percent_height"`
+
+	// Viewport is the browser viewport the points are relative to, in
+	// image coordinates, when coordinates are relative to it; percentages
+	// are then of its width and height. Set by the caller.
+	Viewport *Region `json:"viewport,omitempty"`
+
+	// Calibrated is the distance in the units of the image's scale bar,
+	// once a ScaleCalibration is known for the image. Set by the caller.
+	Calibrated *CalibratedDistance `json:"calibrated,omitempty"`
 }
 
 // MeasureDistance calculates the Euclidean distance and angle between two points.
@@ -210,7 +221,8 @@ type CompareRegionsResult struct {
 	PixelsDifferent int `json:"pixels_different"`
 
 	// TotalPixels is the number of pixels compared.
-	// For different-sized regions, this is min(width1,width2) * min(height1,height2).
+	// For different-sized regions, this is min(width1,width2) * min(height1,height2),
+	// less any pixels transparent in both regions in alpha mode "content".
 	TotalPixels int `json:"total_pixels"`
 
 	// SameSize is true if both regions have identical dimensions.
@@ -227,6 +239,23 @@ type CompareRegionsResult struct {
 	// Calculated as average of: (|r1-r2| + |g1-g2| + |b1-b2|) / 3
 	// Range: 0 (identical) to 255 (maximum difference).
 	AverageColorDiff float64 `json:"average_color_diff"`
+
+	// Region1Alpha and Region2Alpha describe the transparency of the
+	// compared pixels of each region. Nil if they are all opaque.
+	Region1Alpha *AlphaSummary `json:"region1_alpha,omitempty"`
+	Region2Alpha *AlphaSummary `json:"region2_alpha,omitempty"`
+
+	// TransparencyMismatches is the number of pixels fully transparent in
+	// one region but not the other (alpha mode "content" only).
+	TransparencyMismatches int `json:"transparency_mismatches,omitempty"`
+
+	// Distribution compares the regions' color distributions, with method
+	// "histogram" (see CompareRegionsMethod). Nil otherwise.
+	Distribution *DistributionComparison `json:"distribution,omitempty"`
+
+	// Edges compares the regions' edges, with method "edges". Nil
+	// otherwise.
+	Edges *EdgeComparison `json:"edges,omitempty"`
 }
 
 // CompareRegions compares two rectangular regions of an image for similarity.
@@ -241,7 +270,7 @@ type CompareRegionsResult struct {
 //
 // Returns:
 //   - *CompareRegionsResult: Detailed comparison statistics.
-//   - error: Currently always nil (no bounds validation).
+//   - error: Non-nil if either region is empty.
 //
 // # Comparison Method
 //
@@ -261,12 +290,48 @@ type CompareRegionsResult struct {
 // Time complexity is O(width × height) for the smaller region dimensions.
 // Large regions may take noticeable time to compare.
 func CompareRegions(img image.Image, r1, r2 Region) (*CompareRegionsResult, error) {
+	return CompareRegionsMasked(img, r1, r2, nil)
+}
+
+// CompareRegionsMasked is CompareRegions restricted to the pixels selected
+// by mask (see Mask.Rasterize). The mask is in coordinates relative to the
+// top-left of each region, so the same shape is compared in both;
+// TotalPixels counts the selected pixels of the compared area. A nil mask
+// selects every pixel.
+//
+// Returns an error if either region is empty or the mask selects no
+// pixels of the compared area.
+func CompareRegionsMasked(img image.Image, r1, r2 Region, mask *image.Alpha) (*CompareRegionsResult, error) {
+	return CompareRegionsAlpha(img, r1, r2, mask, AlphaOptions{})
+}
+
+// CompareRegionsAlpha is CompareRegionsMasked with transparency handled as
+// alpha selects (see AlphaOptions). Region1Alpha and Region2Alpha describe
+// the transparency of the compared pixels of each region.
+//
+// In AlphaContent mode, pixels transparent in both regions are not
+// compared, and pixels transparent in only one are different, with a color
+// difference of 255, and counted in TransparencyMismatches. Two regions
+// with no content at all are identical.
+//
+// Returns an error if either region is empty, the mask selects no pixels
+// of the compared area, or alpha is invalid.
+func CompareRegionsAlpha(img image.Image, r1, r2 Region, mask *image.Alpha, alpha AlphaOptions) (*CompareRegionsResult, error) {
 	// Calculate region sizes
 	w1 := r1.X2 - r1.X1
 	h1 := r1.Y2 - r1.Y1
 	w2 := r2.X2 - r2.X1
 	h2 := r2.Y2 - r2.Y1
 
+	if w1 <= 0 || h1 <= 0 || w2 <= 0 || h2 <= 0 {
+		return nil, fmt.Errorf("regions must not be empty: region1 is %dx%d, region2 is %dx%d", w1, h1, w2, h2)
+	}
+	read1, err := alpha.reader()
+	if err != nil {
+		return nil, err
+	}
+	read2, _ := alpha.reader()
+
 	sameSize := w1 == w2 && h1 == h2
 
 	// For comparison, use the smaller dimensions
@@ -279,14 +344,30 @@ func CompareRegions(img image.Image, r1, r2 Region) (*CompareRegionsResult, erro
 		minH = h2
 	}
 
-	totalPixels := minW * minH
+	selected := 0
+	totalPixels := 0
 	pixelsDifferent := 0
+	mismatches := 0
 	var totalColorDiff float64
 
 	for dy := 0; dy < minH; dy++ {
 		for dx := 0; dx < minW; dx++ {
-			r1c, g1c, b1c, _ := img.At(r1.X1+dx, r1.Y1+dy).RGBA()
-			r2c, g2c, b2c, _ := img.At(r2.X1+dx, r2.Y1+dy).RGBA()
+			if !maskSelects(mask, dx, dy) {
+				continue
+			}
+			selected++
+			r1c, g1c, b1c, content1 := read1.at(img, r1.X1+dx, r1.Y1+dy)
+			r2c, g2c, b2c, content2 := read2.at(img, r2.X1+dx, r2.Y1+dy)
+			if !content1 && !content2 {
+				continue
+			}
+			totalPixels++
+			if content1 != content2 {
+				mismatches++
+				pixelsDifferent++
+				totalColorDiff += 255
+				continue
+			}
 
 			// Convert to 8-bit
 			r1v, g1v, b1v := uint8(r1c>>8), uint8(g1c>>8), uint8(b1c>>8)
@@ -301,26 +382,52 @@ func CompareRegions(img image.Image, r1, r2 Region) (*CompareRegionsResult, erro
 			totalColorDiff += diff
 
 			// Count as different if difference exceeds threshold
-			if diff > 10 {
+			if diff > pixelDiffThreshold {
 				pixelsDifferent++
 			}
 		}
 	}
 
-	similarity := 1.0 - float64(pixelsDifferent)/float64(totalPixels)
-	avgColorDiff := totalColorDiff / float64(totalPixels)
+	if selected == 0 {
+		return nil, fmt.Errorf("mask selects no pixels of the compared %dx%d area", minW, minH)
+	}
+
+	similarity, avgColorDiff := 1.0, 0.0
+	if totalPixels > 0 {
+		similarity = 1.0 - float64(pixelsDifferent)/float64(totalPixels)
+		avgColorDiff = totalColorDiff / float64(totalPixels)
+	}
 
 	return &CompareRegionsResult{
-		SimilarityScore:  math.Round(similarity*1000) / 1000,
-		PixelsDifferent:  pixelsDifferent,
-		TotalPixels:      totalPixels,
-		SameSize:         sameSize,
-		Region1Size:      Point{X: w1, Y: h1},
-		Region2Size:      Point{X: w2, Y: h2},
-		AverageColorDiff: math.Round(avgColorDiff*100) / 100,
+		SimilarityScore:        math.Round(similarity*1000) / 1000,
+		PixelsDifferent:        pixelsDifferent,
+		TotalPixels:            totalPixels,
+		SameSize:               sameSize,
+		Region1Size:            Point{X: w1, Y: h1},
+		Region2Size:            Point{X: w2, Y: h2},
+		AverageColorDiff:       math.Round(avgColorDiff*100) / 100,
+		Region1Alpha:           read1.summary(),
+		Region2Alpha:           read2.summary(),
+		TransparencyMismatches: mismatches,
 	}, nil
 }
 
+// pixelDiffThreshold is the mean per-channel difference (0-255) above which
+// two pixels are considered different. It filters out minor compression
+// artifacts and anti-aliasing noise.
+const pixelDiffThreshold = 10
+
+// pixelDiff returns the mean absolute per-channel RGB difference (0-255)
+// between two colors, ignoring alpha.
+func pixelDiff(c1, c2 color.Color) float64 {
+	r1, g1, b1, _ := c1.RGBA()
+	r2, g2, b2, _ := c2.RGBA()
+	dr := absDiff(uint8(r1>>8), uint8(r2>>8))
+	dg := absDiff(uint8(g1>>8), uint8(g2>>8))
+	db := absDiff(uint8(b1>>8), uint8(b2>>8))
+	return float64(dr+dg+db) / 3.0
+}
+
 // absDiff returns the absolute difference between two uint8 values.
 // Used for color channel comparison without overflow issues.
 func absDiff(a, b uint8) int {
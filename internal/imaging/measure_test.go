@@ -1,6 +1,7 @@
 package imaging
 
 import (
+	"image"
 	"image/color"
 	"math"
 	"testing"
@@ -255,6 +256,75 @@ func TestCompareRegions_RegionSizes(t *testing.T) {
 	}
 }
 
+func TestCompareRegions_Empty(t *testing.T) {
+	img := createInMemoryImage(100, 100, color.RGBA{255, 0, 0, 255})
+
+	// Zero-size regions have no pixels to compare and would score NaN
+	_, err := CompareRegions(img, Region{}, Region{X1: 0, Y1: 0, X2: 10, Y2: 10})
+	if err == nil {
+		t.Error("expected error for empty region1")
+	}
+	_, err = CompareRegions(img,
+		Region{X1: 0, Y1: 0, X2: 10, Y2: 10},
+		Region{X1: 20, Y1: 20, X2: 10, Y2: 10},
+	)
+	if err == nil {
+		t.Error("expected error for inverted region2")
+	}
+}
+
+func TestCompareRegionsAlpha(t *testing.T) {
+	// Two icons on a transparent background, the second shifted right by
+	// one pixel
+	img := image.NewNRGBA(image.Rect(0, 0, 20, 10))
+	for y := 2; y < 8; y++ {
+		for x := 2; x < 8; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{255, 0, 0, 255})
+			img.SetNRGBA(x+11, y, color.NRGBA{255, 0, 0, 255})
+		}
+	}
+	r1 := Region{X1: 0, Y1: 0, X2: 10, Y2: 10}
+	r2 := Region{X1: 10, Y1: 0, X2: 20, Y2: 10}
+
+	result, err := CompareRegionsAlpha(img, r1, r2, nil, AlphaOptions{})
+	if err != nil {
+		t.Fatalf("CompareRegionsAlpha failed: %v", err)
+	}
+	if result.TotalPixels != 100 || result.PixelsDifferent != 12 || result.SimilarityScore != 0.88 || result.TransparencyMismatches != 0 {
+		t.Errorf("ignore: got %+v", result)
+	}
+	want := AlphaSummary{TransparentPixels: 64, OpaquePixels: 36, MeanAlpha: 91.8}
+	if result.Region1Alpha == nil || *result.Region1Alpha != want || result.Region2Alpha == nil || *result.Region2Alpha != want {
+		t.Errorf("alpha: got %+v and %+v", result.Region1Alpha, result.Region2Alpha)
+	}
+
+	// Only the icons are compared
+	result, err = CompareRegionsAlpha(img, r1, r2, nil, AlphaOptions{Mode: AlphaContent})
+	if err != nil {
+		t.Fatalf("CompareRegionsAlpha failed: %v", err)
+	}
+	if result.TotalPixels != 42 || result.PixelsDifferent != 12 || result.TransparencyMismatches != 12 || result.AverageColorDiff != 72.86 {
+		t.Errorf("content: got %+v", result)
+	}
+
+	// Two empty areas are identical
+	empty := Region{X1: 0, Y1: 0, X2: 2, Y2: 2}
+	result, err = CompareRegionsAlpha(img, empty, Region{X1: 18, Y1: 8, X2: 20, Y2: 10}, nil, AlphaOptions{Mode: AlphaContent})
+	if err != nil || result.TotalPixels != 0 || result.SimilarityScore != 1 {
+		t.Errorf("empty: got %+v, %v", result, err)
+	}
+
+	// Over a red background, the icons disappear
+	result, err = CompareRegionsAlpha(img, r1, r2, nil, AlphaOptions{Mode: AlphaComposite, Background: "#FF0000"})
+	if err != nil || result.PixelsDifferent != 0 {
+		t.Errorf("composite: got %+v, %v", result, err)
+	}
+
+	if _, err := CompareRegionsAlpha(img, r1, r2, nil, AlphaOptions{Mode: "blend"}); err == nil {
+		t.Error("expected error for an unknown alpha mode")
+	}
+}
+
 func TestAbsDiff(t *testing.T) {
 	tests := []struct {
 		a, b uint8
@@ -0,0 +1,237 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+
+	"github.com/ironsheep/image-tools-mcp/internal/fft"
+)
+
+// Tunables for DetectMoire.
+const (
+	// moireMaxPeriod is the longest pattern period, in pixels, checked for
+	// aliasing. Coarser patterns are sampled cleanly at any zoom.
+	moireMaxPeriod = 6
+
+	// moirePeriodTolerance is how far, in pixels, a period may be from a
+	// whole number and still count as drawn on the pixel grid.
+	moirePeriodTolerance = 0.1
+
+	// moireMinRMS is the smallest RMS luminance variation of a tapered tile
+	// worth analyzing; flatter tiles are skipped.
+	moireMinRMS = 2
+)
+
+// MoireRegion is an area showing an aliased fine pattern.
+type MoireRegion struct {
+	// Bounds covers the affected tiles.
+	Bounds Region `json:"bounds"`
+
+	// Score is the share (0.0-1.0) of the luminance variation carried by
+	// the aliased pattern, in the strongest tile.
+	Score float64 `json:"score"`
+
+	// Period is the pattern's period in pixels, measured across its
+	// stripes; not a whole number of pixels on either axis.
+	Period float64 `json:"period"`
+
+	// Angle is the direction of the stripes in degrees (0-180): 0 is
+	// horizontal, 90 vertical.
+	Angle float64 `json:"angle"`
+
+	// Tiles is the number of tiles merged into the region.
+	Tiles int `json:"tiles"`
+}
+
+// MoireResult reports aliasing and moiré patterns.
+type MoireResult struct {
+	// Detected is true when any region was found.
+	Detected bool `json:"detected"`
+
+	// Regions lists the affected areas, highest score first.
+	Regions []MoireRegion `json:"regions"`
+
+	// Count is the number of regions.
+	Count int `json:"count"`
+
+	// AffectedPercent is the share of the image (0-100) in affected tiles.
+	AffectedPercent float64 `json:"affected_percent"`
+
+	// TileSize is the side of the analyzed tiles, in pixels.
+	TileSize int `json:"tile_size"`
+
+	// TilesAnalyzed is the number of tiles with enough detail to analyze.
+	TilesAnalyzed int `json:"tiles_analyzed"`
+}
+
+// moireTile is the analysis of one tile.
+type moireTile struct {
+	rect    Region
+	flagged bool
+	score   float64
+	period  float64
+	angle   float64
+}
+
+// DetectMoire finds areas where a fine periodic pattern has been resampled
+// off the pixel grid, as when a screenshot is taken at a browser zoom or
+// display scale that is not a whole number. Such aliasing shows as moiré
+// and beat patterns that corrupt OCR and line detection.
+//
+// Parameters:
+//   - img: Source image.
+//   - tileSize: Side of the analyzed tiles: 32, 64, or 128 pixels.
+//   - minStrength: Smallest share (0-1) of a tile's luminance variation
+//     the aliased pattern must carry to be reported. Typical value: 0.25.
+//
+// Returns:
+//   - *MoireResult: The affected regions with pattern period and angle.
+//   - error: Non-nil if a parameter is out of range or the image is
+//     smaller than one tile.
+//
+// # Method
+//
+// The image is divided into tiles (the last row and column aligned to the
+// image edges). Each tile is converted to luminance, tapered with a Hann
+// window, and transformed with a 2D FFT. The strongest peak with a period
+// of 6 pixels or less is located, and its frequency refined by
+// interpolation. The peak's power, with its mirror image, as a share of
+// all the tile's power is its strength.
+//
+// A fine pattern rendered on the pixel grid, such as a dither or 1-pixel
+// hatching, has a period of a whole number of pixels along each axis. A
+// pattern resampled at another scale does not: its lines fall alternately
+// 2 and 3 pixels apart, say, producing a visible beat. Tiles whose
+// strongest fine pattern is at least minStrength and off the grid by more
+// than 0.1 pixel are flagged, and 4-adjacent flagged tiles are merged
+// into regions.
+func DetectMoire(img image.Image, tileSize int, minStrength float64) (*MoireResult, error) {
+	if tileSize != 32 && tileSize != 64 && tileSize != 128 {
+		return nil, fmt.Errorf("tile size must be 32, 64, or 128, got %d", tileSize)
+	}
+	if minStrength <= 0 || minStrength > 1 {
+		return nil, fmt.Errorf("min strength must be between 0 and 1, got %v", minStrength)
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w < tileSize || h < tileSize {
+		return nil, fmt.Errorf("image (%dx%d) is smaller than one %dx%d tile", w, h, tileSize, tileSize)
+	}
+
+	cols, rows := (w+tileSize-1)/tileSize, (h+tileSize-1)/tileSize
+	tiles := make([]moireTile, cols*rows)
+	result := &MoireResult{Regions: []MoireRegion{}, TileSize: tileSize}
+	affected := 0
+	for ty := 0; ty < rows; ty++ {
+		for tx := 0; tx < cols; tx++ {
+			x0, y0 := min(tx*tileSize, w-tileSize), min(ty*tileSize, h-tileSize)
+			t := analyzeMoireTile(img, b.Min.X+x0, b.Min.Y+y0, tileSize, minStrength)
+			if t == nil {
+				continue
+			}
+			result.TilesAnalyzed++
+			t.rect = Region{X1: b.Min.X + x0, Y1: b.Min.Y + y0, X2: b.Min.X + x0 + tileSize, Y2: b.Min.Y + y0 + tileSize}
+			tiles[ty*cols+tx] = *t
+			if t.flagged {
+				// The area this tile owns in the grid; the shifted last
+				// tiles overlap their neighbors
+				affected += (min((tx+1)*tileSize, w) - tx*tileSize) * (min((ty+1)*tileSize, h) - ty*tileSize)
+			}
+		}
+	}
+
+	// Merge 4-adjacent flagged tiles
+	seen := make([]bool, len(tiles))
+	for start := range tiles {
+		if !tiles[start].flagged || seen[start] {
+			continue
+		}
+		seen[start] = true
+		region := MoireRegion{Bounds: tiles[start].rect}
+		best := -1.0
+		for stack := []int{start}; len(stack) > 0; {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			t := tiles[i]
+			region.Tiles++
+			region.Bounds = Region{
+				X1: min(region.Bounds.X1, t.rect.X1), Y1: min(region.Bounds.Y1, t.rect.Y1),
+				X2: max(region.Bounds.X2, t.rect.X2), Y2: max(region.Bounds.Y2, t.rect.Y2),
+			}
+			if t.score > best {
+				best = t.score
+				region.Score = math.Round(t.score*100) / 100
+				region.Period = math.Round(t.period*100) / 100
+				region.Angle = math.Round(t.angle*10) / 10
+			}
+			tx, ty := i%cols, i/cols
+			for _, n := range [4][2]int{{tx - 1, ty}, {tx + 1, ty}, {tx, ty - 1}, {tx, ty + 1}} {
+				if n[0] < 0 || n[0] >= cols || n[1] < 0 || n[1] >= rows {
+					continue
+				}
+				if j := n[1]*cols + n[0]; tiles[j].flagged && !seen[j] {
+					seen[j] = true
+					stack = append(stack, j)
+				}
+			}
+		}
+		result.Regions = append(result.Regions, region)
+	}
+	sort.SliceStable(result.Regions, func(i, j int) bool { return result.Regions[i].Score > result.Regions[j].Score })
+
+	result.Count = len(result.Regions)
+	result.Detected = result.Count > 0
+	result.AffectedPercent = math.Round(float64(affected)/float64(w*h)*10000) / 100
+	return result, nil
+}
+
+// analyzeMoireTile measures the strongest fine pattern in the n×n tile at
+// (x0, y0). It returns nil for a tile too flat to analyze.
+func analyzeMoireTile(img image.Image, x0, y0, n int, minStrength float64) *moireTile {
+	power, total := windowSpectrum(img, x0, y0, n)
+	if spectrumRMS(total, n) < moireMinRMS {
+		return nil
+	}
+
+	// The strongest peak with a period of moireMaxPeriod or less
+	minRadius := float64(n) / moireMaxPeriod
+	peak, peakPower := -1, 0.0
+	for v := 0; v < n; v++ {
+		for u := 0; u < n; u++ {
+			fu, fv := float64(fft.Freq(u, n)), float64(fft.Freq(v, n))
+			if math.Hypot(fu, fv) >= minRadius && power[v*n+u] > peakPower {
+				peak, peakPower = v*n+u, power[v*n+u]
+			}
+		}
+	}
+	if peak < 0 {
+		return &moireTile{}
+	}
+	pu, pv := peak%n, peak/n
+	share := peakShare(power, n, pu, pv, total)
+	fx, fy := peakFrequency(power, n, pu, pv)
+
+	// On the pixel grid when each axis with a component has a whole
+	// number period
+	onGrid := true
+	for _, f := range []float64{fx, fy} {
+		if math.Abs(f) < 1.5/float64(n) {
+			continue
+		}
+		p := 1 / math.Abs(f)
+		if math.Abs(p-math.Round(p)) > moirePeriodTolerance {
+			onGrid = false
+		}
+	}
+
+	// Stripes run perpendicular to the direction of variation
+	angle := math.Mod(math.Atan2(fy, fx)*180/math.Pi+90+360, 180)
+	return &moireTile{
+		flagged: !onGrid && share >= minStrength,
+		score:   share,
+		period:  1 / math.Hypot(fx, fy),
+		angle:   angle,
+	}
+}
@@ -0,0 +1,86 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// drawStripes fills a rectangle with vertical stripes of the given period,
+// sampled as a sinusoid, the way a resampled fine pattern looks.
+func drawStripes(img *image.RGBA, x1, y1, x2, y2 int, period float64) {
+	for y := y1; y < y2; y++ {
+		for x := x1; x < x2; x++ {
+			v := uint8(128 + 100*math.Sin(2*math.Pi*float64(x)/period))
+			img.Set(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+}
+
+func TestDetectMoire(t *testing.T) {
+	// Stripes every 2.7 pixels in the lower left quarter-and-a-bit
+	img := image.NewRGBA(image.Rect(0, 0, 256, 192))
+	fillRect(img, 0, 0, 256, 192, color.White)
+	drawStripes(img, 0, 64, 128, 192, 2.7)
+
+	result, err := DetectMoire(img, 64, 0.25)
+	if err != nil {
+		t.Fatalf("DetectMoire failed: %v", err)
+	}
+	if !result.Detected || result.Count != 1 || result.TilesAnalyzed != 4 {
+		t.Fatalf("got %+v", result)
+	}
+	r := result.Regions[0]
+	if r.Bounds != (Region{X1: 0, Y1: 64, X2: 128, Y2: 192}) || r.Tiles != 4 {
+		t.Errorf("region: got %+v", r)
+	}
+	if math.Abs(r.Period-2.7) > 0.05 || r.Angle != 90 || r.Score < 0.9 {
+		t.Errorf("pattern: got period %v, angle %v, score %v", r.Period, r.Angle, r.Score)
+	}
+	if result.AffectedPercent != 33.33 {
+		t.Errorf("affected: got %v%%, want 33.33%%", result.AffectedPercent)
+	}
+
+	// Patterns drawn on the pixel grid are not aliased: 1-pixel lines
+	// every 3 pixels, and a checkerboard
+	grid := image.NewRGBA(image.Rect(0, 0, 128, 128))
+	fillRect(grid, 0, 0, 128, 128, color.White)
+	for y := 0; y < 128; y++ {
+		for x := 0; x < 128; x++ {
+			if (y < 64 && x%3 == 0) || (y >= 64 && (x+y)%2 == 0) {
+				grid.Set(x, y, color.Black)
+			}
+		}
+	}
+	result, err = DetectMoire(grid, 32, 0.25)
+	if err != nil {
+		t.Fatalf("DetectMoire failed: %v", err)
+	}
+	if result.Detected || result.TilesAnalyzed != 16 {
+		t.Errorf("pixel-grid patterns: got %+v", result)
+	}
+
+	// Smooth shading has no fine pattern; a partial last tile overlaps
+	result, err = DetectMoire(smoothScene(200, 100), 64, 0.25)
+	if err != nil {
+		t.Fatalf("DetectMoire failed: %v", err)
+	}
+	if result.Detected || result.TilesAnalyzed != 8 {
+		t.Errorf("smooth scene: got %+v", result)
+	}
+
+	for name, args := range map[string]struct {
+		tile     int
+		strength float64
+	}{
+		"tile size":  {48, 0.25},
+		"strength":   {64, 0},
+		"too small":  {128, 0.25},
+		"strength 2": {64, 2},
+	} {
+		if _, err := DetectMoire(smoothScene(100, 100), args.tile, args.strength); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// Span is a half-open range [Start, End) along one axis.
+type Span struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Insets are distances from each edge of an image, in pixels.
+type Insets struct {
+	Top    int `json:"top"`
+	Left   int `json:"left"`
+	Bottom int `json:"bottom"`
+	Right  int `json:"right"`
+}
+
+// NinePatchResult describes the inferred nine-patch layout of a UI asset.
+type NinePatchResult struct {
+	// Width and Height are the asset dimensions.
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// StretchX is the longest run of identical columns, which can be
+	// repeated to widen the asset without distortion. Nil if no two
+	// adjacent columns match.
+	StretchX *Span `json:"stretch_x,omitempty"`
+
+	// StretchY is the longest run of identical rows. Nil if no two adjacent
+	// rows match.
+	StretchY *Span `json:"stretch_y,omitempty"`
+
+	// Padding holds the fixed (non-stretchable) border on each side: the
+	// cap insets for iOS/CSS border-image, and the content padding for an
+	// Android nine-patch. An axis with no stretch region is fully fixed,
+	// so its insets are 0.
+	Padding Insets `json:"padding"`
+
+	// ContentRegion is the area left after removing Padding, where a
+	// label or icon would be placed.
+	ContentRegion Region `json:"content_region"`
+}
+
+// InferNinePatch finds the stretchable bands of a button or panel asset.
+//
+// Parameters:
+//   - img: The asset image (typically with rounded corners or a border).
+//   - tolerance: Maximum per-channel difference (0-255, including alpha)
+//     for two pixels to count as equal. 0 requires an exact match.
+//
+// Returns:
+//   - *NinePatchResult: Stretch spans and the derived padding.
+//   - error: Non-nil if tolerance is out of range or the image is smaller
+//     than 2×2.
+//
+// # Method
+//
+// Two adjacent columns are equal if every pixel in them matches within
+// tolerance. The longest run of columns that each equal their neighbor is
+// the horizontal stretch region; rows are handled the same way. Gradients that vary only
+// across the other axis (e.g. a vertical gradient fill) do not break a run.
+func InferNinePatch(img image.Image, tolerance int) (*NinePatchResult, error) {
+	if tolerance < 0 || tolerance > 255 {
+		return nil, fmt.Errorf("tolerance must be between 0 and 255, got %d", tolerance)
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w < 2 || h < 2 {
+		return nil, fmt.Errorf("image too small for nine-patch inference: %dx%d", w, h)
+	}
+
+	at := func(x, y int) color.NRGBA {
+		return color.NRGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA)
+	}
+
+	stretchX := longestEqualRun(w, func(i int) bool {
+		for y := 0; y < h; y++ {
+			if !nrgbaEqual(at(i, y), at(i+1, y), tolerance) {
+				return false
+			}
+		}
+		return true
+	})
+	stretchY := longestEqualRun(h, func(i int) bool {
+		for x := 0; x < w; x++ {
+			if !nrgbaEqual(at(x, i), at(x, i+1), tolerance) {
+				return false
+			}
+		}
+		return true
+	})
+
+	result := &NinePatchResult{
+		Width:    w,
+		Height:   h,
+		StretchX: stretchX,
+		StretchY: stretchY,
+	}
+	if stretchX != nil {
+		result.Padding.Left = stretchX.Start
+		result.Padding.Right = w - stretchX.End
+	}
+	if stretchY != nil {
+		result.Padding.Top = stretchY.Start
+		result.Padding.Bottom = h - stretchY.End
+	}
+	result.ContentRegion = Region{
+		X1: result.Padding.Left,
+		Y1: result.Padding.Top,
+		X2: w - result.Padding.Right,
+		Y2: h - result.Padding.Bottom,
+	}
+
+	return result, nil
+}
+
+// longestEqualRun returns the longest span of positions [0, n) where each
+// position i in the span (except the last) satisfies same(i), meaning i and
+// i+1 are equal. Returns nil if same is never true.
+func longestEqualRun(n int, same func(i int) bool) *Span {
+	var best *Span
+	start := -1
+	for i := 0; i < n-1; i++ {
+		if same(i) {
+			if start < 0 {
+				start = i
+			}
+			if best == nil || i+2-start > best.End-best.Start {
+				best = &Span{Start: start, End: i + 2}
+			}
+		} else {
+			start = -1
+		}
+	}
+	return best
+}
+
+// nrgbaEqual reports whether every channel of a and b differs by at most
+// tolerance.
+func nrgbaEqual(a, b color.NRGBA, tolerance int) bool {
+	return absDiff(a.R, b.R) <= tolerance &&
+		absDiff(a.G, b.G) <= tolerance &&
+		absDiff(a.B, b.B) <= tolerance &&
+		absDiff(a.A, b.A) <= tolerance
+}
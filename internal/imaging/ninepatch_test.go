@@ -0,0 +1,106 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// createButtonAsset draws a w×h button with 3×3 transparent corners, a 1px
+// dark border, and a fill. If gradient is true the fill darkens row by row.
+func createButtonAsset(w, h int, gradient bool) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			switch {
+			case (x < 3 || x >= w-3) && (y < 3 || y >= h-3):
+				// transparent corner
+			case x == 0 || x == w-1 || y == 0 || y == h-1:
+				img.SetNRGBA(x, y, color.NRGBA{40, 40, 40, 255})
+			case gradient:
+				img.SetNRGBA(x, y, color.NRGBA{uint8(200 - 5*y), 120, 255, 255})
+			default:
+				img.SetNRGBA(x, y, color.NRGBA{200, 120, 255, 255})
+			}
+		}
+	}
+	return img
+}
+
+func TestInferNinePatch(t *testing.T) {
+	img := createButtonAsset(40, 20, false)
+
+	result, err := InferNinePatch(img, 0)
+	if err != nil {
+		t.Fatalf("InferNinePatch failed: %v", err)
+	}
+
+	if result.StretchX == nil || *result.StretchX != (Span{Start: 3, End: 37}) {
+		t.Errorf("StretchX: got %+v, want {3 37}", result.StretchX)
+	}
+	if result.StretchY == nil || *result.StretchY != (Span{Start: 3, End: 17}) {
+		t.Errorf("StretchY: got %+v, want {3 17}", result.StretchY)
+	}
+	if result.Padding != (Insets{Top: 3, Left: 3, Bottom: 3, Right: 3}) {
+		t.Errorf("Padding: got %+v, want 3 on all sides", result.Padding)
+	}
+	if result.ContentRegion != (Region{X1: 3, Y1: 3, X2: 37, Y2: 17}) {
+		t.Errorf("ContentRegion: got %+v", result.ContentRegion)
+	}
+}
+
+func TestInferNinePatch_Gradient(t *testing.T) {
+	img := createButtonAsset(40, 20, true)
+
+	result, err := InferNinePatch(img, 0)
+	if err != nil {
+		t.Fatalf("InferNinePatch failed: %v", err)
+	}
+
+	// Vertical gradient: columns still repeat, rows never do
+	if result.StretchX == nil || *result.StretchX != (Span{Start: 3, End: 37}) {
+		t.Errorf("StretchX: got %+v, want {3 37}", result.StretchX)
+	}
+	if result.StretchY != nil {
+		t.Errorf("StretchY: got %+v, want nil", result.StretchY)
+	}
+	if result.Padding.Top != 0 || result.Padding.Bottom != 0 {
+		t.Errorf("vertical padding: got %d/%d, want 0/0", result.Padding.Top, result.Padding.Bottom)
+	}
+
+	// A tolerance above the per-row step merges the gradient rows
+	result, err = InferNinePatch(img, 5)
+	if err != nil {
+		t.Fatalf("InferNinePatch failed: %v", err)
+	}
+	if result.StretchY == nil || *result.StretchY != (Span{Start: 3, End: 17}) {
+		t.Errorf("StretchY with tolerance: got %+v, want {3 17}", result.StretchY)
+	}
+}
+
+func TestInferNinePatch_Errors(t *testing.T) {
+	img := createButtonAsset(40, 20, false)
+
+	if _, err := InferNinePatch(img, -1); err == nil {
+		t.Error("InferNinePatch should fail with negative tolerance")
+	}
+	if _, err := InferNinePatch(img, 256); err == nil {
+		t.Error("InferNinePatch should fail with tolerance > 255")
+	}
+	if _, err := InferNinePatch(createInMemoryImage(1, 10, color.RGBA{0, 0, 0, 255}), 0); err == nil {
+		t.Error("InferNinePatch should fail for a 1px wide image")
+	}
+}
+
+func TestLongestEqualRun(t *testing.T) {
+	// Positions 0..9; equal pairs at 1-2, 4-5-6-7
+	same := map[int]bool{1: true, 4: true, 5: true, 6: true}
+	got := longestEqualRun(10, func(i int) bool { return same[i] })
+	if got == nil || *got != (Span{Start: 4, End: 8}) {
+		t.Errorf("got %+v, want {4 8}", got)
+	}
+
+	if got := longestEqualRun(10, func(int) bool { return false }); got != nil {
+		t.Errorf("got %+v, want nil", got)
+	}
+}
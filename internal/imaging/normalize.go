@@ -0,0 +1,230 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// normalizeMaxGain limits the gray-world channel gains, so an image that
+// really is mostly one color is not turned gray.
+const normalizeMaxGain = 2.0
+
+// normalizeMinRange is the smallest brightness range (0-255) between the
+// black and white points that exposure normalization stretches; flatter
+// images are left as they are.
+const normalizeMinRange = 16
+
+// NormalizeOptions selects the corrections Normalize applies.
+type NormalizeOptions struct {
+	// WhiteBalance removes a color cast with the gray-world method.
+	WhiteBalance bool
+
+	// Exposure stretches brightness so the black point becomes black and
+	// the white point white.
+	Exposure bool
+
+	// ClipPercent is the percentage (0-10) of the darkest and of the
+	// brightest pixels clipped by the exposure stretch, so a few specular
+	// highlights or deep shadows do not set the points.
+	ClipPercent float64
+}
+
+// ChannelGains are the factors applied to each color channel.
+type ChannelGains struct {
+	R float64 `json:"r"`
+	G float64 `json:"g"`
+	B float64 `json:"b"`
+}
+
+// NormalizeResult contains a normalized image encoded as base64 PNG.
+type NormalizeResult struct {
+	// Width and Height of the image in pixels, unchanged.
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// ImageBase64 is the normalized image encoded as base64 PNG.
+	ImageBase64 string `json:"image_base64"`
+
+	// MimeType is always "image/png" for normalization results.
+	MimeType string `json:"mime_type"`
+
+	// Gains are the white balance factors applied; all 1 if white
+	// balance was not requested.
+	Gains ChannelGains `json:"gains"`
+
+	// BlackPoint and WhitePoint are the brightness levels (0-255), after
+	// white balance, stretched to 0 and 255. 0 and 255 if exposure was not
+	// normalized, or the image was too flat to stretch.
+	BlackPoint int `json:"black_point"`
+	WhitePoint int `json:"white_point"`
+
+	// MeanBefore and MeanAfter are the mean colors as hex (#RRGGBB).
+	MeanBefore string `json:"mean_before"`
+	MeanAfter  string `json:"mean_after"`
+}
+
+// Normalize corrects the color cast and exposure of an image, such as a
+// photo of a whiteboard or a document under warm, dim light, so the
+// other tools see white paper and dark ink.
+//
+// Parameters:
+//   - img: Source image. It is not modified.
+//   - opts: The corrections to apply.
+//
+// Returns:
+//   - *NormalizeResult: The normalized image and the corrections applied.
+//   - error: Non-nil if ClipPercent is out of range or PNG encoding fails.
+//
+// # Method
+//
+// Gray-world white balance assumes the scene averages to gray: each
+// channel is scaled so its mean matches the mean of all three, by a
+// factor from 0.5 to 2. Pixels clipped at 0 or 255 in any channel, and
+// transparent pixels, are left out of the means.
+//
+// Exposure normalization finds the brightness levels below which
+// ClipPercent of the pixels fall (the black point) and above which as
+// many rise (the white point), and stretches all channels linearly so
+// they become 0 and 255. Images whose points are less than 16 levels
+// apart are not stretched.
+func Normalize(img image.Image, opts NormalizeOptions) (*NormalizeResult, error) {
+	out, result, err := NormalizeImage(img, opts)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := encodePNGBase64(out)
+	if err != nil {
+		return nil, err
+	}
+	result.ImageBase64 = encoded
+	return result, nil
+}
+
+// NormalizeImage is Normalize returning the normalized image, with its
+// origin at (0, 0), and a result without the encoded image. Alpha is kept
+// as is.
+func NormalizeImage(img image.Image, opts NormalizeOptions) (*image.NRGBA, *NormalizeResult, error) {
+	if opts.ClipPercent < 0 || opts.ClipPercent > 10 {
+		return nil, nil, fmt.Errorf("clip percent must be between 0 and 10, got %v", opts.ClipPercent)
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	out := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			out.SetNRGBA(x, y, nrgbaAt(img, b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	result := &NormalizeResult{
+		Width:      w,
+		Height:     h,
+		MimeType:   "image/png",
+		Gains:      ChannelGains{R: 1, G: 1, B: 1},
+		WhitePoint: 255,
+		MeanBefore: meanHex(out),
+	}
+
+	if opts.WhiteBalance {
+		result.Gains = grayWorldGains(out)
+		g := [3]float64{result.Gains.R, result.Gains.G, result.Gains.B}
+		var lut [3][256]uint8
+		for c := range lut {
+			for v := range lut[c] {
+				lut[c][v] = uint8(math.Round(min(float64(v)*g[c], 255)))
+			}
+		}
+		for i := 0; i < len(out.Pix); i += 4 {
+			for c := 0; c < 3; c++ {
+				out.Pix[i+c] = lut[c][out.Pix[i+c]]
+			}
+		}
+	}
+
+	if opts.Exposure {
+		black, white := brightnessPoints(out, opts.ClipPercent)
+		if white-black >= normalizeMinRange {
+			result.BlackPoint, result.WhitePoint = black, white
+			var lut [256]uint8
+			for v := range lut {
+				s := float64(v-black) * 255 / float64(white-black)
+				lut[v] = uint8(math.Round(min(max(s, 0), 255)))
+			}
+			for i := 0; i < len(out.Pix); i += 4 {
+				out.Pix[i], out.Pix[i+1], out.Pix[i+2] = lut[out.Pix[i]], lut[out.Pix[i+1]], lut[out.Pix[i+2]]
+			}
+		}
+	}
+
+	result.MeanAfter = meanHex(out)
+	return out, result, nil
+}
+
+// grayWorldGains returns the channel factors that make the mean of the
+// unclipped, visible pixels of img gray.
+func grayWorldGains(img *image.NRGBA) ChannelGains {
+	var sum [3]float64
+	n := 0
+	for i := 0; i < len(img.Pix); i += 4 {
+		p := img.Pix[i : i+4 : i+4]
+		if p[3] == 0 || min(p[0], p[1], p[2]) == 0 || max(p[0], p[1], p[2]) == 255 {
+			continue
+		}
+		sum[0] += float64(p[0])
+		sum[1] += float64(p[1])
+		sum[2] += float64(p[2])
+		n++
+	}
+	if n == 0 || sum[0] == 0 || sum[1] == 0 || sum[2] == 0 {
+		return ChannelGains{R: 1, G: 1, B: 1}
+	}
+	gray := (sum[0] + sum[1] + sum[2]) / 3
+	gain := func(s float64) float64 {
+		return math.Round(min(max(gray/s, 1/normalizeMaxGain), normalizeMaxGain)*1000) / 1000
+	}
+	return ChannelGains{R: gain(sum[0]), G: gain(sum[1]), B: gain(sum[2])}
+}
+
+// brightnessPoints returns the brightness levels below and above which
+// clipPercent of the visible pixels of img fall.
+func brightnessPoints(img *image.NRGBA, clipPercent float64) (black, white int) {
+	var hist [256]int
+	n := 0
+	for i := 0; i < len(img.Pix); i += 4 {
+		if img.Pix[i+3] == 0 {
+			continue
+		}
+		p := img.Pix[i : i+4 : i+4]
+		hist[luma(color.NRGBA{p[0], p[1], p[2], p[3]})]++
+		n++
+	}
+	clip := int(float64(n) * clipPercent / 100)
+	black, white = 0, 255
+	for count := hist[0]; black < 255 && count <= clip; count += hist[black] {
+		black++
+	}
+	for count := hist[255]; white > 0 && count <= clip; count += hist[white] {
+		white--
+	}
+	return black, white
+}
+
+// meanHex returns the mean color of the visible pixels of img as hex.
+func meanHex(img *image.NRGBA) string {
+	var sum [3]float64
+	n := 0
+	for i := 0; i < len(img.Pix); i += 4 {
+		if img.Pix[i+3] == 0 {
+			continue
+		}
+		sum[0] += float64(img.Pix[i])
+		sum[1] += float64(img.Pix[i+1])
+		sum[2] += float64(img.Pix[i+2])
+		n++
+	}
+	if n == 0 {
+		return "#000000"
+	}
+	return fmt.Sprintf("#%02X%02X%02X", int(math.Round(sum[0]/float64(n))), int(math.Round(sum[1]/float64(n))), int(math.Round(sum[2]/float64(n))))
+}
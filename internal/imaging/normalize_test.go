@@ -0,0 +1,84 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// whiteboardPhoto returns a 120x80 photo of a whiteboard under warm, dim
+// light: yellowish paper, shaded toward the right, with dark ink strokes.
+func whiteboardPhoto() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 120, 80))
+	for y := 0; y < 80; y++ {
+		for x := 0; x < 120; x++ {
+			shade := uint8(x / 8)
+			c := color.NRGBA{170 - shade, 150 - shade, 110 - shade, 255}
+			if (y >= 20 && y < 24) || (x >= 60 && x < 63 && y >= 30) {
+				c = color.NRGBA{50, 45, 40, 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestNormalize(t *testing.T) {
+	img := whiteboardPhoto()
+	result, err := Normalize(img, NormalizeOptions{WhiteBalance: true, Exposure: true, ClipPercent: 1})
+	if err != nil {
+		t.Fatalf("Normalize failed: %v", err)
+	}
+	if result.Gains.R >= 1 || result.Gains.B <= 1 || result.BlackPoint == 0 || result.WhitePoint == 255 || result.ImageBase64 == "" {
+		t.Errorf("got %+v", result)
+	}
+
+	// The paper becomes near-white and neutral, the ink stays dark
+	out, _, _ := NormalizeImage(img, NormalizeOptions{WhiteBalance: true, Exposure: true, ClipPercent: 1})
+	paper, ink := out.NRGBAAt(5, 5), out.NRGBAAt(5, 21)
+	if min(paper.R, paper.G, paper.B) < 235 || absDiff(paper.R, paper.B) > 12 {
+		t.Errorf("paper: got %v", paper)
+	}
+	if max(ink.R, ink.G, ink.B) > 40 {
+		t.Errorf("ink: got %v", ink)
+	}
+
+	// Each correction can be turned off
+	out, result, _ = NormalizeImage(img, NormalizeOptions{Exposure: true})
+	if result.Gains != (ChannelGains{R: 1, G: 1, B: 1}) || out.NRGBAAt(5, 5).B >= out.NRGBAAt(5, 5).R {
+		t.Errorf("exposure only: got %+v, paper %v", result, out.NRGBAAt(5, 5))
+	}
+	out, result, _ = NormalizeImage(img, NormalizeOptions{WhiteBalance: true})
+	if result.BlackPoint != 0 || result.WhitePoint != 255 || out.NRGBAAt(5, 5).G > 160 {
+		t.Errorf("white balance only: got %+v, paper %v", result, out.NRGBAAt(5, 5))
+	}
+
+	if _, err := Normalize(img, NormalizeOptions{ClipPercent: 11}); err == nil {
+		t.Error("expected error for clip percent above 10")
+	}
+}
+
+func TestNormalize_Limits(t *testing.T) {
+	// A mostly red image keeps its color: the gains are limited
+	red := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	for i := 0; i < len(red.Pix); i += 4 {
+		copy(red.Pix[i:], []uint8{200, 20, 20, 255})
+	}
+	_, result, err := NormalizeImage(red, NormalizeOptions{WhiteBalance: true})
+	if err != nil {
+		t.Fatalf("NormalizeImage failed: %v", err)
+	}
+	if result.Gains != (ChannelGains{R: 0.5, G: 2, B: 2}) || result.MeanAfter != "#642828" {
+		t.Errorf("got %+v", result)
+	}
+
+	// Transparent pixels are left out and keep their alpha
+	img := whiteboardPhoto()
+	for x := 0; x < 120; x++ {
+		img.SetNRGBA(x, 0, color.NRGBA{})
+	}
+	out, _, _ := NormalizeImage(img, NormalizeOptions{WhiteBalance: true, Exposure: true, ClipPercent: 1})
+	if out.NRGBAAt(5, 0).A != 0 || out.NRGBAAt(5, 5).A != 255 {
+		t.Errorf("alpha: got %v, %v", out.NRGBAAt(5, 0), out.NRGBAAt(5, 5))
+	}
+}
@@ -0,0 +1,494 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// Limits for CheckPalette.
+const (
+	maxPaletteColors       = 64 // Colors in the allowed palette
+	maxPaletteViolations   = 20 // Off-palette colors reported
+	maxViolationLocations  = 5  // Locations reported per off-palette color
+	paletteEdgeChannelDiff = 8  // Channel difference that marks a pixel as an edge
+)
+
+// PaletteOptions configures CheckPalette.
+type PaletteOptions struct {
+	// Tolerance is the largest CIEDE2000 color difference from a palette
+	// color that still counts as that color. About 1 is just noticeable;
+	// 2-3 allows for compression and rendering differences.
+	Tolerance float64
+
+	// MinPercent is the smallest share of the analyzed pixels (0-100) an
+	// off-palette color must cover to be reported.
+	MinPercent float64
+
+	// IgnoreEdges skips pixels whose 3x3 neighborhood is not flat, so the
+	// blended colors of anti-aliased edges and text are not reported.
+	IgnoreEdges bool
+
+	// Region, if set, restricts the check to a rectangle.
+	Region *Region
+
+	// Mask, if set, restricts the check to the selected pixels (see
+	// Mask.Rasterize), in image coordinates.
+	Mask *image.Alpha
+
+	// Points are sampled individually and checked regardless of the other
+	// options.
+	Points []Point
+}
+
+// PaletteUsage is the share of the analyzed pixels matching one palette
+// color.
+type PaletteUsage struct {
+	// Hex is the palette color (#RRGGBB).
+	Hex string `json:"hex"`
+
+	// Percent is the share of analyzed pixels (0-100) whose nearest
+	// palette color this is, within tolerance.
+	Percent float64 `json:"percent"`
+}
+
+// PaletteViolation is a color found in the image that is not in the
+// palette.
+type PaletteViolation struct {
+	// Hex is the most common exact color of the group (#RRGGBB). Colors
+	// are grouped by quantizing each channel to 16 levels, as in
+	// DominantColors.
+	Hex string `json:"hex"`
+
+	// RGB is the same color as components.
+	RGB RGBColor `json:"rgb"`
+
+	// Nearest is the closest palette color, and DeltaE its CIEDE2000
+	// difference from Hex.
+	Nearest string  `json:"nearest"`
+	DeltaE  float64 `json:"delta_e"`
+
+	// Pixels is the number of analyzed pixels in the group, and Percent
+	// their share of all analyzed pixels (0-100).
+	Pixels  int     `json:"pixels"`
+	Percent float64 `json:"percent"`
+
+	// Locations are the bounding boxes of the largest connected areas of
+	// the group, largest first, up to 5.
+	Locations []Region `json:"locations"`
+
+	// LocationCount is the number of connected areas of the group.
+	LocationCount int `json:"location_count"`
+}
+
+// PaletteSample is the check of one sampled point.
+type PaletteSample struct {
+	// Point is the sampled pixel.
+	Point Point `json:"point"`
+
+	// Hex is the pixel's color (#RRGGBB).
+	Hex string `json:"hex"`
+
+	// Nearest is the closest palette color, and DeltaE its CIEDE2000
+	// difference from Hex.
+	Nearest string  `json:"nearest"`
+	DeltaE  float64 `json:"delta_e"`
+
+	// InPalette is true when DeltaE is within the tolerance.
+	InPalette bool `json:"in_palette"`
+}
+
+// PaletteResult reports how well an image keeps to a color palette.
+type PaletteResult struct {
+	// Compliant is true when no off-palette color was reported and every
+	// sampled point is in the palette.
+	Compliant bool `json:"compliant"`
+
+	// Tolerance is the CIEDE2000 tolerance applied.
+	Tolerance float64 `json:"tolerance"`
+
+	// AnalyzedPixels is the number of pixels checked, after the region,
+	// mask, and edge filters.
+	AnalyzedPixels int `json:"analyzed_pixels"`
+
+	// CompliantPercent is the share of analyzed pixels (0-100) within
+	// tolerance of a palette color.
+	CompliantPercent float64 `json:"compliant_percent"`
+
+	// Palette lists each palette color's share of the analyzed pixels, in
+	// the order given.
+	Palette []PaletteUsage `json:"palette"`
+
+	// Violations lists the off-palette colors covering at least the
+	// minimum share, most common first, up to 20.
+	Violations []PaletteViolation `json:"violations"`
+
+	// Samples are the checks of the requested points, in order.
+	Samples []PaletteSample `json:"samples,omitempty"`
+}
+
+// CheckPalette compares the colors of an image against an allowed
+// palette, such as a design system's brand colors, and reports every
+// significant color outside it and where it occurs.
+//
+// Parameters:
+//   - img: Source image.
+//   - palette: Allowed colors as hex (#RRGGBB or #RRGGBBAA; alpha is
+//     ignored), 1 to 64 of them.
+//   - opts: Tolerance, reporting threshold, and what to check.
+//
+// Returns:
+//   - *PaletteResult: Palette usage, off-palette colors with their
+//     locations, and sampled points.
+//   - error: Non-nil if the palette or an option is invalid, a point is
+//     outside the image, or the mask selects no pixels.
+//
+// # Method
+//
+// Colors are compared in CIELAB (sRGB, D65 white) with the CIEDE2000
+// formula, which tracks perceived difference far better than RGB
+// distance. Each pixel matches its nearest palette color if within
+// Tolerance. Off-palette pixels are grouped like DominantColors groups
+// colors, and each group of at least MinPercent is reported with its
+// 8-connected areas.
+//
+// With IgnoreEdges, pixels whose 3x3 neighborhood differs by more than 8
+// levels in any channel are skipped. This leaves flat fills, where brand
+// colors matter most, and drops anti-aliasing, gradients, and photos;
+// check thin text and icons with Points instead.
+func CheckPalette(img image.Image, palette []string, opts PaletteOptions) (*PaletteResult, error) {
+	if len(palette) == 0 || len(palette) > maxPaletteColors {
+		return nil, fmt.Errorf("palette must have 1 to %d colors, got %d", maxPaletteColors, len(palette))
+	}
+	if opts.Tolerance < 0 || opts.Tolerance > 100 {
+		return nil, fmt.Errorf("tolerance must be between 0 and 100, got %v", opts.Tolerance)
+	}
+	if opts.MinPercent < 0 || opts.MinPercent > 100 {
+		return nil, fmt.Errorf("min_percent must be between 0 and 100, got %v", opts.MinPercent)
+	}
+
+	pal := make([]labColor, len(palette))
+	result := &PaletteResult{
+		Tolerance:  opts.Tolerance,
+		Palette:    make([]PaletteUsage, len(palette)),
+		Violations: []PaletteViolation{},
+	}
+	for i, hex := range palette {
+		c, err := parseHexColor(hex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid palette color %q: %w", hex, err)
+		}
+		pal[i] = rgbToLab(c.R, c.G, c.B)
+		result.Palette[i].Hex = fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B)
+	}
+
+	// nearest matches a color against the palette, caching by color
+	type match struct {
+		index  int
+		deltaE float64
+	}
+	matches := make(map[uint32]match)
+	nearest := func(r, g, b uint8) match {
+		key := uint32(r)<<16 | uint32(g)<<8 | uint32(b)
+		if m, ok := matches[key]; ok {
+			return m
+		}
+		lab := rgbToLab(r, g, b)
+		m := match{deltaE: math.Inf(1)}
+		for i, p := range pal {
+			if d := deltaE2000(lab, p); d < m.deltaE {
+				m = match{index: i, deltaE: d}
+			}
+		}
+		matches[key] = m
+		return m
+	}
+
+	imgBounds := img.Bounds()
+	for _, p := range opts.Points {
+		if !image.Pt(p.X, p.Y).In(imgBounds) {
+			return nil, fmt.Errorf("point (%d, %d) is outside the image bounds %v", p.X, p.Y, imgBounds)
+		}
+		r, g, b, _ := img.At(p.X, p.Y).RGBA()
+		r8, g8, b8 := uint8(r>>8), uint8(g>>8), uint8(b>>8)
+		m := nearest(r8, g8, b8)
+		result.Samples = append(result.Samples, PaletteSample{
+			Point:     p,
+			Hex:       fmt.Sprintf("#%02X%02X%02X", r8, g8, b8),
+			Nearest:   result.Palette[m.index].Hex,
+			DeltaE:    math.Round(m.deltaE*100) / 100,
+			InPalette: m.deltaE <= opts.Tolerance,
+		})
+	}
+
+	bounds := imgBounds
+	if opts.Region != nil {
+		bounds = image.Rect(opts.Region.X1, opts.Region.Y1, opts.Region.X2, opts.Region.Y2).Intersect(imgBounds)
+	}
+	w, h := bounds.Dx(), bounds.Dy()
+	pix := make([]color.RGBA, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			pix[y*w+x] = color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), 255}
+		}
+	}
+
+	// Classify the pixels; label holds the off-palette group of each
+	// pixel, or -1
+	label := make([]int32, w*h)
+	groupPixels := make(map[int32]int)
+	exact := make(map[uint32]int)
+	usage := make([]int, len(pal))
+	selected, analyzed, compliant := 0, 0, 0
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*w + x
+			label[i] = -1
+			if !maskSelects(opts.Mask, bounds.Min.X+x, bounds.Min.Y+y) {
+				continue
+			}
+			selected++
+			if opts.IgnoreEdges && !flatPixel(pix, w, h, x, y) {
+				continue
+			}
+			analyzed++
+			c := pix[i]
+			m := nearest(c.R, c.G, c.B)
+			if m.deltaE <= opts.Tolerance {
+				usage[m.index]++
+				compliant++
+				continue
+			}
+			group := int32(c.R>>4)<<8 | int32(c.G>>4)<<4 | int32(c.B>>4)
+			label[i] = group
+			groupPixels[group]++
+			exact[uint32(c.R)<<16|uint32(c.G)<<8|uint32(c.B)]++
+		}
+	}
+	if opts.Mask != nil && selected == 0 {
+		return nil, fmt.Errorf("mask selects no pixels in %v", bounds)
+	}
+
+	result.AnalyzedPixels = analyzed
+	percent := func(n int) float64 {
+		if analyzed == 0 {
+			return 0
+		}
+		return math.Round(float64(n)/float64(analyzed)*10000) / 100
+	}
+	result.CompliantPercent = percent(compliant)
+	for i, n := range usage {
+		result.Palette[i].Percent = percent(n)
+	}
+
+	// The reported groups, most common first
+	var groups []int32
+	for g, n := range groupPixels {
+		if percent(n) >= opts.MinPercent {
+			groups = append(groups, g)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groupPixels[groups[i]] != groupPixels[groups[j]] {
+			return groupPixels[groups[i]] > groupPixels[groups[j]]
+		}
+		return groups[i] < groups[j]
+	})
+	if len(groups) > maxPaletteViolations {
+		groups = groups[:maxPaletteViolations]
+	}
+
+	// Each group's most common exact color
+	reported := make(map[int32]int, len(groups))
+	for k, g := range groups {
+		reported[g] = k
+	}
+	best := make([]uint32, len(groups))
+	bestCount := make([]int, len(groups))
+	for c, n := range exact {
+		g := int32(c>>20&0xF)<<8 | int32(c>>12&0xF)<<4 | int32(c>>4&0xF)
+		if k, ok := reported[g]; ok && (n > bestCount[k] || n == bestCount[k] && c < best[k]) {
+			best[k], bestCount[k] = c, n
+		}
+	}
+
+	areas := paletteAreas(label, w, h, reported, len(groups))
+	for k, g := range groups {
+		r, gr, b := uint8(best[k]>>16), uint8(best[k]>>8), uint8(best[k])
+		m := nearest(r, gr, b)
+		v := PaletteViolation{
+			Hex:           fmt.Sprintf("#%02X%02X%02X", r, gr, b),
+			RGB:           RGBColor{R: r, G: gr, B: b},
+			Nearest:       result.Palette[m.index].Hex,
+			DeltaE:        math.Round(m.deltaE*100) / 100,
+			Pixels:        groupPixels[g],
+			Percent:       percent(groupPixels[g]),
+			Locations:     []Region{},
+			LocationCount: len(areas[k]),
+		}
+		for _, a := range areas[k][:min(len(areas[k]), maxViolationLocations)] {
+			v.Locations = append(v.Locations, Region{
+				X1: bounds.Min.X + a.bounds.X1, Y1: bounds.Min.Y + a.bounds.Y1,
+				X2: bounds.Min.X + a.bounds.X2, Y2: bounds.Min.Y + a.bounds.Y2,
+			})
+		}
+		result.Violations = append(result.Violations, v)
+	}
+
+	result.Compliant = len(result.Violations) == 0
+	for _, s := range result.Samples {
+		result.Compliant = result.Compliant && s.InPalette
+	}
+	return result, nil
+}
+
+// flatPixel reports whether no pixel in the 3x3 neighborhood of (x, y)
+// differs from it by more than paletteEdgeChannelDiff in any channel.
+func flatPixel(pix []color.RGBA, w, h, x, y int) bool {
+	c := pix[y*w+x]
+	for ny := max(y-1, 0); ny <= min(y+1, h-1); ny++ {
+		for nx := max(x-1, 0); nx <= min(x+1, w-1); nx++ {
+			n := pix[ny*w+nx]
+			if absDiff(c.R, n.R) > paletteEdgeChannelDiff || absDiff(c.G, n.G) > paletteEdgeChannelDiff || absDiff(c.B, n.B) > paletteEdgeChannelDiff {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// paletteArea is one connected area of an off-palette group.
+type paletteArea struct {
+	bounds Region
+	pixels int
+}
+
+// paletteAreas finds the 8-connected areas of the groups in reported
+// (group label to index), largest first for each group.
+func paletteAreas(label []int32, w, h int, reported map[int32]int, n int) [][]paletteArea {
+	areas := make([][]paletteArea, n)
+	seen := make([]bool, len(label))
+	var queue []int
+	for start, g := range label {
+		k, ok := reported[g]
+		if !ok || seen[start] {
+			continue
+		}
+		x0, y0 := start%w, start/w
+		a := paletteArea{bounds: Region{X1: x0, Y1: y0, X2: x0 + 1, Y2: y0 + 1}}
+		seen[start] = true
+		queue = append(queue[:0], start)
+		for len(queue) > 0 {
+			i := queue[len(queue)-1]
+			queue = queue[:len(queue)-1]
+			x, y := i%w, i/w
+			a.pixels++
+			a.bounds.X1, a.bounds.Y1 = min(a.bounds.X1, x), min(a.bounds.Y1, y)
+			a.bounds.X2, a.bounds.Y2 = max(a.bounds.X2, x+1), max(a.bounds.Y2, y+1)
+			for ny := max(y-1, 0); ny <= min(y+1, h-1); ny++ {
+				for nx := max(x-1, 0); nx <= min(x+1, w-1); nx++ {
+					j := ny*w + nx
+					if !seen[j] && label[j] == g {
+						seen[j] = true
+						queue = append(queue, j)
+					}
+				}
+			}
+		}
+		areas[k] = append(areas[k], a)
+	}
+	for _, list := range areas {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].pixels > list[j].pixels })
+	}
+	return areas
+}
+
+// labColor is a color in CIELAB.
+type labColor struct {
+	L, A, B float64
+}
+
+// rgbToLab converts an sRGB color to CIELAB under the D65 white point.
+func rgbToLab(r, g, b uint8) labColor {
+	rl, gl, bl := srgbToLinear(r), srgbToLinear(g), srgbToLinear(b)
+	x := (0.4124564*rl + 0.3575761*gl + 0.1804375*bl) / 0.95047
+	y := 0.2126729*rl + 0.7151522*gl + 0.0721750*bl
+	z := (0.0193339*rl + 0.1191920*gl + 0.9503041*bl) / 1.08883
+
+	f := func(t float64) float64 {
+		if t > 216.0/24389 {
+			return math.Cbrt(t)
+		}
+		return (24389.0/27*t + 16) / 116
+	}
+	fx, fy, fz := f(x), f(y), f(z)
+	return labColor{L: 116*fy - 16, A: 500 * (fx - fy), B: 200 * (fy - fz)}
+}
+
+// deltaE2000 returns the CIEDE2000 color difference between two colors,
+// following Sharma, Wu, and Dalal (2005).
+func deltaE2000(c1, c2 labColor) float64 {
+	const pow25to7 = 6103515625 // 25^7
+	rad := math.Pi / 180
+
+	cBar := (math.Hypot(c1.A, c1.B) + math.Hypot(c2.A, c2.B)) / 2
+	cBar7 := math.Pow(cBar, 7)
+	g := 0.5 * (1 - math.Sqrt(cBar7/(cBar7+pow25to7)))
+	a1, a2 := (1+g)*c1.A, (1+g)*c2.A
+	cp1, cp2 := math.Hypot(a1, c1.B), math.Hypot(a2, c2.B)
+
+	hue := func(b, a float64) float64 {
+		if a == 0 && b == 0 {
+			return 0
+		}
+		h := math.Atan2(b, a) / rad
+		if h < 0 {
+			h += 360
+		}
+		return h
+	}
+	hp1, hp2 := hue(c1.B, a1), hue(c2.B, a2)
+
+	dL := c2.L - c1.L
+	dC := cp2 - cp1
+	var dh float64
+	if cp1*cp2 != 0 {
+		dh = hp2 - hp1
+		if dh > 180 {
+			dh -= 360
+		} else if dh < -180 {
+			dh += 360
+		}
+	}
+	dH := 2 * math.Sqrt(cp1*cp2) * math.Sin(dh/2*rad)
+
+	lBar := (c1.L + c2.L) / 2
+	cpBar := (cp1 + cp2) / 2
+	hBar := hp1 + hp2
+	if cp1*cp2 != 0 {
+		switch {
+		case math.Abs(hp1-hp2) <= 180:
+			hBar /= 2
+		case hBar < 360:
+			hBar = (hBar + 360) / 2
+		default:
+			hBar = (hBar - 360) / 2
+		}
+	}
+
+	t := 1 - 0.17*math.Cos((hBar-30)*rad) + 0.24*math.Cos(2*hBar*rad) +
+		0.32*math.Cos((3*hBar+6)*rad) - 0.20*math.Cos((4*hBar-63)*rad)
+	dTheta := 30 * math.Exp(-math.Pow((hBar-275)/25, 2))
+	cpBar7 := math.Pow(cpBar, 7)
+	rc := 2 * math.Sqrt(cpBar7/(cpBar7+pow25to7))
+	l50 := (lBar - 50) * (lBar - 50)
+	sl := 1 + 0.015*l50/math.Sqrt(20+l50)
+	sc := 1 + 0.045*cpBar
+	sh := 1 + 0.015*cpBar*t
+	rt := -math.Sin(2*dTheta*rad) * rc
+
+	return math.Sqrt(math.Pow(dL/sl, 2) + math.Pow(dC/sc, 2) + math.Pow(dH/sh, 2) + rt*(dC/sc)*(dH/sh))
+}
@@ -0,0 +1,116 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestDeltaE2000(t *testing.T) {
+	// Test pairs from Sharma, Wu, and Dalal (2005)
+	tests := []struct {
+		c1, c2 labColor
+		want   float64
+	}{
+		{labColor{50, 2.6772, -79.7751}, labColor{50, 0, -82.7485}, 2.0425},
+		{labColor{50, 0, 0}, labColor{50, -1, 2}, 2.3669},
+		{labColor{50, 2.5, 0}, labColor{73, 25, -18}, 27.1492},
+		{labColor{60.2574, -34.0099, 36.2677}, labColor{60.4626, -34.1751, 39.4387}, 1.2644},
+		{labColor{2.0776, 0.0795, -1.1350}, labColor{0.9033, -0.0636, -0.5514}, 0.9082},
+	}
+	for _, tt := range tests {
+		if got := deltaE2000(tt.c1, tt.c2); math.Abs(got-tt.want) > 0.0001 {
+			t.Errorf("deltaE2000(%v, %v) = %.4f, want %.4f", tt.c1, tt.c2, got, tt.want)
+		}
+		if got := deltaE2000(tt.c2, tt.c1); math.Abs(got-tt.want) > 0.0001 {
+			t.Errorf("deltaE2000 is not symmetric for %v, %v: %.4f", tt.c1, tt.c2, got)
+		}
+	}
+
+	red := rgbToLab(255, 0, 0)
+	if math.Abs(red.L-53.24) > 0.01 || math.Abs(red.A-80.09) > 0.01 || math.Abs(red.B-67.20) > 0.01 {
+		t.Errorf("rgbToLab(red) = %+v, want {53.24 80.09 67.20}", red)
+	}
+	if white := rgbToLab(255, 255, 255); math.Abs(white.L-100) > 0.01 || math.Abs(white.A) > 0.01 || math.Abs(white.B) > 0.01 {
+		t.Errorf("rgbToLab(white) = %+v, want {100 0 0}", white)
+	}
+}
+
+func TestCheckPalette(t *testing.T) {
+	// A brand-blue button and an off-brand orange badge on white, with a
+	// column blending the button into the background
+	img := image.NewRGBA(image.Rect(0, 0, 100, 60))
+	fillRect(img, 0, 0, 100, 60, color.White)
+	fillRect(img, 10, 10, 40, 30, color.RGBA{0, 82, 204, 255})
+	fillRect(img, 40, 10, 41, 30, color.RGBA{128, 168, 230, 255})
+	fillRect(img, 60, 40, 70, 50, color.RGBA{255, 140, 0, 255})
+	fillRect(img, 80, 40, 84, 44, color.RGBA{255, 140, 0, 255})
+	// Slightly off the brand blue, within tolerance
+	fillRect(img, 10, 40, 20, 50, color.RGBA{2, 84, 205, 255})
+
+	palette := []string{"#FFFFFF", "#0052cc"}
+	opts := PaletteOptions{
+		Tolerance:   3,
+		MinPercent:  0.1,
+		IgnoreEdges: true,
+		Points:      []Point{{X: 25, Y: 20}, {X: 65, Y: 45}},
+	}
+	result, err := CheckPalette(img, palette, opts)
+	if err != nil {
+		t.Fatalf("CheckPalette failed: %v", err)
+	}
+	if result.Compliant || len(result.Violations) != 1 {
+		t.Fatalf("got compliant %v, violations %+v; want the orange badge only", result.Compliant, result.Violations)
+	}
+	v := result.Violations[0]
+	if v.Hex != "#FF8C00" || v.Nearest != "#FFFFFF" || v.DeltaE < 20 || v.LocationCount != 2 {
+		t.Errorf("violation: got %+v", v)
+	}
+	// Only the interiors of the flat areas are analyzed
+	if len(v.Locations) != 2 || v.Locations[0] != (Region{X1: 61, Y1: 41, X2: 69, Y2: 49}) || v.Locations[1] != (Region{X1: 81, Y1: 41, X2: 83, Y2: 43}) {
+		t.Errorf("locations: got %v", v.Locations)
+	}
+	if result.Palette[1].Hex != "#0052CC" || result.Palette[1].Percent <= 0 || result.CompliantPercent >= 100 {
+		t.Errorf("usage: got %+v, compliant %v%%", result.Palette, result.CompliantPercent)
+	}
+	if len(result.Samples) != 2 || !result.Samples[0].InPalette || result.Samples[0].DeltaE != 0 || result.Samples[1].InPalette {
+		t.Errorf("samples: got %+v", result.Samples)
+	}
+
+	// Without edge filtering the blended column is reported too
+	opts.IgnoreEdges = false
+	opts.Points = nil
+	result, err = CheckPalette(img, palette, opts)
+	if err != nil {
+		t.Fatalf("CheckPalette failed: %v", err)
+	}
+	if len(result.Violations) != 2 || result.Violations[1].Hex != "#80A8E6" || result.AnalyzedPixels != 6000 {
+		t.Errorf("violations: got %+v", result.Violations)
+	}
+
+	// A region around the button is compliant
+	opts.Region = &Region{X1: 0, Y1: 0, X2: 40, Y2: 60}
+	result, err = CheckPalette(img, palette, opts)
+	if err != nil {
+		t.Fatalf("CheckPalette failed: %v", err)
+	}
+	if !result.Compliant || result.CompliantPercent != 100 || result.AnalyzedPixels != 2400 {
+		t.Errorf("region: got %+v", result)
+	}
+
+	for name, bad := range map[string]struct {
+		palette []string
+		opts    PaletteOptions
+	}{
+		"empty palette": {nil, PaletteOptions{Tolerance: 3}},
+		"bad color":     {[]string{"#12345"}, PaletteOptions{Tolerance: 3}},
+		"tolerance":     {palette, PaletteOptions{Tolerance: -1}},
+		"min percent":   {palette, PaletteOptions{Tolerance: 3, MinPercent: 101}},
+		"point":         {palette, PaletteOptions{Tolerance: 3, Points: []Point{{X: 100, Y: 0}}}},
+	} {
+		if _, err := CheckPalette(img, bad.palette, bad.opts); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+
+	"github.com/disintegration/imaging"
+)
+
+// Limits for ComparePalettes.
+const (
+	maxPaletteImages        = 32  // Images compared in one call
+	maxComparePaletteColors = 32  // Dominant colors per image
+	paletteAnalysisSize     = 512 // Longest side images are reduced to
+)
+
+// PaletteSource is one image to compare with ComparePalettes.
+type PaletteSource struct {
+	Image image.Image // Image (or cropped region) to analyze
+	Label string      // Name reported for the image (may be empty)
+}
+
+// PaletteImage is the palette of one compared image.
+type PaletteImage struct {
+	// Index is the position of the image in the input list (0-based).
+	Index int `json:"index"`
+
+	// Label is the name given for the image.
+	Label string `json:"label,omitempty"`
+
+	// Colors are the image's dominant colors, most common first, as
+	// returned by DominantColors.
+	Colors []ColorFrequency `json:"colors"`
+
+	// MedianDistance is the median palette distance from this image to
+	// the others (the lower of the two middle values for an even count),
+	// so one outlier does not raise it for every other image.
+	MedianDistance float64 `json:"median_distance"`
+
+	// Outlier is true when MedianDistance exceeds the threshold.
+	Outlier bool `json:"outlier"`
+}
+
+// PalettePair is the palette distance between two images.
+type PalettePair struct {
+	// A and B are the indexes of the two images, A < B.
+	A int `json:"a"`
+	B int `json:"b"`
+
+	// Distance is the palette distance, in CIEDE2000 units.
+	Distance float64 `json:"distance"`
+}
+
+// PaletteComparisonResult reports how consistent the colors of a set of
+// images are.
+type PaletteComparisonResult struct {
+	// Consistent is true when every pair of images is within the
+	// threshold.
+	Consistent bool `json:"consistent"`
+
+	// Threshold is the palette distance above which images differ.
+	Threshold float64 `json:"threshold"`
+
+	// MeanDistance and MaxDistance summarize the pairwise distances.
+	MeanDistance float64 `json:"mean_distance"`
+	MaxDistance  float64 `json:"max_distance"`
+
+	// Images lists each image's palette, in input order.
+	Images []PaletteImage `json:"images"`
+
+	// Pairs lists the distance between every pair of images, most
+	// different first.
+	Pairs []PalettePair `json:"pairs"`
+
+	// Outliers are the indexes of the images whose median distance to
+	// the others exceeds the threshold. With only two images an outlier
+	// cannot be told apart, so none is reported.
+	Outliers []int `json:"outliers"`
+}
+
+// ComparePalettes extracts the dominant colors of several images and
+// compares them pairwise, to check that a set of assets, such as
+// marketing images or screenshots of one product, share a consistent
+// color grading.
+//
+// Parameters:
+//   - sources: The images to compare, 2 to 32 of them.
+//   - count: Dominant colors extracted per image (1-32).
+//   - threshold: Palette distance (CIEDE2000) above which two images
+//     count as differently graded. Typical value: 15.
+//
+// Returns:
+//   - *PaletteComparisonResult: Each image's palette, the pairwise
+//     distances, and the outliers.
+//   - error: Non-nil if the number of images, count, or threshold is out
+//     of range, or an image is empty.
+//
+// # Method
+//
+// Each image is reduced to at most 512 pixels on its longest side and its
+// dominant colors extracted as by DominantColors, with their shares
+// renormalized to sum to 1. The distance between two palettes averages,
+// over the colors of each weighted by share, the CIEDE2000 difference to
+// the nearest color of the other palette, and takes the mean of the two
+// directions. It is 0 for identical palettes and grows with the color
+// difference of the pixels involved; a uniform cast or shift in
+// brightness raises it for every color.
+//
+// Images of different subjects have different palettes even when graded
+// alike, so the threshold should allow for content; compare crops of
+// shared elements, such as backgrounds or brand areas, for a stricter
+// check.
+func ComparePalettes(sources []PaletteSource, count int, threshold float64) (*PaletteComparisonResult, error) {
+	if len(sources) < 2 || len(sources) > maxPaletteImages {
+		return nil, fmt.Errorf("need between 2 and %d images, got %d", maxPaletteImages, len(sources))
+	}
+	if count < 1 || count > maxComparePaletteColors {
+		return nil, fmt.Errorf("count must be between 1 and %d, got %d", maxComparePaletteColors, count)
+	}
+	if threshold <= 0 {
+		return nil, fmt.Errorf("threshold must be positive, got %v", threshold)
+	}
+
+	type weightedLab struct {
+		lab    labColor
+		weight float64
+	}
+	result := &PaletteComparisonResult{
+		Threshold: threshold,
+		Images:    make([]PaletteImage, len(sources)),
+		Pairs:     []PalettePair{},
+		Outliers:  []int{},
+	}
+	palettes := make([][]weightedLab, len(sources))
+	for i, src := range sources {
+		b := src.Image.Bounds()
+		if b.Empty() {
+			return nil, fmt.Errorf("image %d is empty", i)
+		}
+		img := src.Image
+		if b.Dx() > paletteAnalysisSize || b.Dy() > paletteAnalysisSize {
+			img = imaging.Fit(img, paletteAnalysisSize, paletteAnalysisSize, imaging.Box)
+		}
+		dominant, err := DominantColors(img, count, nil)
+		if err != nil {
+			return nil, err
+		}
+		total := 0.0
+		for _, c := range dominant.Colors {
+			total += c.Percentage
+		}
+		for j, c := range dominant.Colors {
+			palettes[i] = append(palettes[i], weightedLab{rgbToLab(c.RGB.R, c.RGB.G, c.RGB.B), c.Percentage / total})
+			dominant.Colors[j].Percentage = math.Round(c.Percentage*100) / 100
+		}
+		result.Images[i] = PaletteImage{Index: i, Label: src.Label, Colors: dominant.Colors}
+	}
+
+	// One direction of the palette distance: how far, on average, the
+	// colors of p are from their nearest color in q
+	nearest := func(p, q []weightedLab) float64 {
+		sum := 0.0
+		for _, a := range p {
+			best := math.Inf(1)
+			for _, b := range q {
+				best = math.Min(best, deltaE2000(a.lab, b.lab))
+			}
+			sum += a.weight * best
+		}
+		return sum
+	}
+	n := len(sources)
+	dist := make([][]float64, n)
+	for i := range dist {
+		dist[i] = make([]float64, n)
+	}
+	sum := 0.0
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			d := (nearest(palettes[i], palettes[j]) + nearest(palettes[j], palettes[i])) / 2
+			dist[i][j], dist[j][i] = d, d
+			sum += d
+			result.MaxDistance = math.Max(result.MaxDistance, d)
+			result.Pairs = append(result.Pairs, PalettePair{A: i, B: j, Distance: math.Round(d*100) / 100})
+		}
+	}
+	sort.SliceStable(result.Pairs, func(i, j int) bool { return result.Pairs[i].Distance > result.Pairs[j].Distance })
+	result.Consistent = result.MaxDistance <= threshold
+	result.MeanDistance = math.Round(sum/float64(len(result.Pairs))*100) / 100
+	result.MaxDistance = math.Round(result.MaxDistance*100) / 100
+
+	for i := range result.Images {
+		others := make([]float64, 0, n-1)
+		for j := 0; j < n; j++ {
+			if j != i {
+				others = append(others, dist[i][j])
+			}
+		}
+		sort.Float64s(others)
+		median := others[(len(others)-1)/2]
+		result.Images[i].MedianDistance = math.Round(median*100) / 100
+		if n > 2 && median > threshold {
+			result.Images[i].Outlier = true
+			result.Outliers = append(result.Outliers, i)
+		}
+	}
+	return result, nil
+}
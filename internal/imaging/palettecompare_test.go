@@ -0,0 +1,144 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// brandAsset draws a white canvas with a navy panel and an orange accent,
+// laid out differently for each variant, with every color shifted by
+// (dr, dg, db) to simulate a different grading.
+func brandAsset(variant, dr, dg, db int) *image.RGBA {
+	shift := func(r, g, b int) color.RGBA {
+		c := func(v int) uint8 { return uint8(max(0, min(255, v))) }
+		return color.RGBA{c(r + dr), c(g + dg), c(b + db), 255}
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 200, 120))
+	fillRect(img, 0, 0, 200, 120, shift(250, 250, 250))
+	x := 10 + variant*20
+	fillRect(img, x, 10, x+100, 70, shift(20, 40, 110))
+	fillRect(img, 150-variant*10, 80, 190-variant*10, 110, shift(240, 120, 30))
+	return img
+}
+
+func TestComparePalettes(t *testing.T) {
+	sources := []PaletteSource{
+		{Image: brandAsset(0, 0, 0, 0), Label: "hero"},
+		{Image: brandAsset(1, 0, 0, 0), Label: "banner"},
+		{Image: brandAsset(2, 0, 0, 0), Label: "card"},
+		{Image: brandAsset(3, 60, 20, -60), Label: "warm"},
+	}
+	result, err := ComparePalettes(sources, 8, 15)
+	if err != nil {
+		t.Fatalf("ComparePalettes failed: %v", err)
+	}
+
+	if len(result.Images) != 4 || len(result.Pairs) != 6 {
+		t.Fatalf("got %d images, %d pairs; want 4, 6", len(result.Images), len(result.Pairs))
+	}
+	if result.Images[0].Label != "hero" || len(result.Images[0].Colors) != 3 {
+		t.Errorf("image 0: got %+v", result.Images[0])
+	}
+	if result.Consistent {
+		t.Error("a set with a differently graded image should not be consistent")
+	}
+	if len(result.Outliers) != 1 || result.Outliers[0] != 3 || !result.Images[3].Outlier {
+		t.Errorf("outliers: got %v, want [3]", result.Outliers)
+	}
+
+	// The same palette in different layouts is close; the most different
+	// pairs all involve the warm image
+	for _, p := range result.Pairs {
+		if p.B == 3 {
+			if p.Distance <= 15 {
+				t.Errorf("pair %d-%d: got %v, want above 15", p.A, p.B, p.Distance)
+			}
+		} else if p.Distance > 1 {
+			t.Errorf("pair %d-%d: got %v, want near 0", p.A, p.B, p.Distance)
+		}
+	}
+	if result.Pairs[0].B != 3 || result.MaxDistance != result.Pairs[0].Distance {
+		t.Errorf("pairs should be sorted most different first: %+v", result.Pairs)
+	}
+	for i := 0; i < 3; i++ {
+		if result.Images[i].Outlier || result.Images[i].MedianDistance > 1 {
+			t.Errorf("image %d: got %+v", i, result.Images[i])
+		}
+	}
+}
+
+func TestComparePalettes_Consistent(t *testing.T) {
+	sources := []PaletteSource{{Image: brandAsset(0, 0, 0, 0)}, {Image: brandAsset(2, 0, 0, 0)}}
+	result, err := ComparePalettes(sources, 8, 15)
+	if err != nil {
+		t.Fatalf("ComparePalettes failed: %v", err)
+	}
+	if !result.Consistent || len(result.Outliers) != 0 || result.MeanDistance > 1 {
+		t.Errorf("got %+v", result)
+	}
+}
+
+func TestComparePalettes_TwoImagesNoOutlier(t *testing.T) {
+	// Two differing images: inconsistent, but neither is the outlier
+	sources := []PaletteSource{{Image: brandAsset(0, 0, 0, 0)}, {Image: brandAsset(0, 60, 20, -60)}}
+	result, err := ComparePalettes(sources, 8, 15)
+	if err != nil {
+		t.Fatalf("ComparePalettes failed: %v", err)
+	}
+	if result.Consistent || len(result.Outliers) != 0 {
+		t.Errorf("got consistent %v, outliers %v; want false, none", result.Consistent, result.Outliers)
+	}
+	if math.Abs(result.Images[0].MedianDistance-result.MaxDistance) > 1e-9 {
+		t.Errorf("median distance: got %v, want %v", result.Images[0].MedianDistance, result.MaxDistance)
+	}
+}
+
+func TestComparePalettes_LargeImage(t *testing.T) {
+	// Large images are reduced before extraction without changing flat colors
+	big := image.NewRGBA(image.Rect(0, 0, 2000, 1000))
+	fillRect(big, 0, 0, 1000, 1000, color.RGBA{200, 30, 30, 255})
+	fillRect(big, 1000, 0, 2000, 1000, color.RGBA{30, 30, 200, 255})
+	small := image.NewRGBA(image.Rect(0, 0, 20, 10))
+	fillRect(small, 0, 0, 10, 10, color.RGBA{200, 30, 30, 255})
+	fillRect(small, 10, 0, 20, 10, color.RGBA{30, 30, 200, 255})
+
+	result, err := ComparePalettes([]PaletteSource{{Image: big}, {Image: small}}, 4, 15)
+	if err != nil {
+		t.Fatalf("ComparePalettes failed: %v", err)
+	}
+	if result.MaxDistance > 1 {
+		t.Errorf("distance: got %v, want near 0", result.MaxDistance)
+	}
+}
+
+func TestComparePalettes_Errors(t *testing.T) {
+	one := []PaletteSource{{Image: brandAsset(0, 0, 0, 0)}}
+	two := append(one, PaletteSource{Image: brandAsset(1, 0, 0, 0)})
+	many := make([]PaletteSource, 33)
+	for i := range many {
+		many[i] = one[0]
+	}
+	empty := append(one, PaletteSource{Image: image.NewRGBA(image.Rect(0, 0, 0, 0))})
+	tests := []struct {
+		name      string
+		sources   []PaletteSource
+		count     int
+		threshold float64
+	}{
+		{"one image", one, 8, 15},
+		{"too many images", many, 8, 15},
+		{"zero count", two, 0, 15},
+		{"count too large", two, 33, 15},
+		{"zero threshold", two, 8, 0},
+		{"empty image", empty, 8, 15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ComparePalettes(tt.sources, tt.count, tt.threshold); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+
+	"github.com/ironsheep/image-tools-mcp/internal/fft"
+)
+
+// Tunables for DetectPeriodicity.
+const (
+	// maxPeriodicityWindow is the largest window analyzed, in pixels (a
+	// power of two).
+	maxPeriodicityWindow = 512
+
+	// maxPeriodicityPeaks limits how many periods DetectPeriodicity returns.
+	maxPeriodicityPeaks = 20
+
+	// periodicityMinCycles is the fewest repetitions across the window for
+	// a pattern to count; longer periods are single large shapes.
+	periodicityMinCycles = 3
+
+	// periodicityMinShare is the smallest share of the window's variation
+	// a spectrum peak must carry to be considered.
+	periodicityMinShare = 0.01
+
+	// periodicityMinStrength is the strength of the strongest period above
+	// which an image counts as periodic.
+	periodicityMinStrength = 0.15
+
+	// periodicityMaxOrder is the highest multiple of a fundamental checked
+	// when grouping harmonics.
+	periodicityMaxOrder = 8
+)
+
+// Periodicity is one repeating pattern: a dominant spatial frequency with
+// its harmonics.
+type Periodicity struct {
+	// Period is the repeat distance in pixels, measured in the direction
+	// given by Angle.
+	Period float64 `json:"period"`
+
+	// PeriodX and PeriodY are the repeat distances along each axis, or 0
+	// if the pattern does not change along that axis.
+	PeriodX float64 `json:"period_x"`
+	PeriodY float64 `json:"period_y"`
+
+	// Frequency is 1/Period, in cycles per pixel.
+	Frequency float64 `json:"frequency"`
+
+	// Angle is the direction in which the pattern repeats, in degrees
+	// (0-180): 0 for side by side, 90 for stacked, like list rows.
+	Angle float64 `json:"angle"`
+
+	// Strength is the share (0.0-1.0) of the window's brightness variation
+	// carried by the pattern, including its harmonics.
+	Strength float64 `json:"strength"`
+
+	// Harmonics is the number of multiples and combinations of the
+	// fundamental frequencies found and counted in Strength.
+	Harmonics int `json:"harmonics"`
+}
+
+// PeriodicityResult contains the dominant repeating patterns of an image.
+type PeriodicityResult struct {
+	// Periodic is true when the strongest pattern has a strength of 0.15
+	// or more.
+	Periodic bool `json:"periodic"`
+
+	// Peaks lists the patterns, strongest first.
+	Peaks []Periodicity `json:"peaks"`
+
+	// Count is the number of patterns returned.
+	Count int `json:"count"`
+
+	// Window is the square area analyzed.
+	Window Region `json:"window"`
+}
+
+// periodicityPeak is a candidate spectrum peak.
+type periodicityPeak struct {
+	u, v   int     // spectrum index
+	fx, fy float64 // refined frequency, cycles per pixel
+	share  float64
+}
+
+// DetectPeriodicity finds the dominant spatial frequencies of an image:
+// the spacing and direction of repeated elements such as list rows, grid
+// cells, table rules, or hatching.
+//
+// Parameters:
+//   - img: Source image.
+//   - region: The area to analyze, or nil for the whole image.
+//   - maxPeaks: Maximum number of patterns to return (1-20).
+//
+// Returns:
+//   - *PeriodicityResult: The repeating patterns, strongest first.
+//   - error: Non-nil if the region is invalid, maxPeaks is out of range,
+//     or the region is smaller than 16x16 pixels.
+//
+// # Method
+//
+// The largest centered square window whose side is a power of two (at
+// most 512) is taken from the region, converted to luminance, tapered
+// with a Hann window, and transformed with a 2D FFT. Local maxima of the
+// power spectrum that repeat at least 3 times across the window and carry
+// at least 1% of its variation are candidates, and their frequencies are
+// refined by interpolation.
+//
+// Repeated elements produce a fundamental frequency and its multiples, and
+// a 2D grid of elements also produces sums of its two fundamentals.
+// Candidates are taken in order of increasing frequency; one that is such
+// a multiple or sum of the fundamentals already found, allowing for
+// wrap-around above the Nyquist frequency, is counted as a harmonic of
+// the strongest fundamental involved. The rest are new fundamentals.
+func DetectPeriodicity(img image.Image, region *Region, maxPeaks int) (*PeriodicityResult, error) {
+	if maxPeaks < 1 || maxPeaks > maxPeriodicityPeaks {
+		return nil, fmt.Errorf("max_peaks must be between 1 and %d, got %d", maxPeriodicityPeaks, maxPeaks)
+	}
+	b := img.Bounds()
+	r := Region{X1: 0, Y1: 0, X2: b.Dx(), Y2: b.Dy()}
+	if region != nil {
+		r = *region
+		if err := validateRegion(r, b.Dx(), b.Dy()); err != nil {
+			return nil, err
+		}
+	}
+	w, h := r.X2-r.X1, r.Y2-r.Y1
+	n := 1
+	for n*2 <= min(w, h) && n*2 <= maxPeriodicityWindow {
+		n *= 2
+	}
+	if n < 16 {
+		return nil, fmt.Errorf("region too small for periodicity analysis: %dx%d, need at least 16x16", w, h)
+	}
+
+	x0, y0 := r.X1+(w-n)/2, r.Y1+(h-n)/2
+	result := &PeriodicityResult{
+		Peaks:  []Periodicity{},
+		Window: Region{X1: b.Min.X + x0, Y1: b.Min.Y + y0, X2: b.Min.X + x0 + n, Y2: b.Min.Y + y0 + n},
+	}
+	power, total := windowSpectrum(img, b.Min.X+x0, b.Min.Y+y0, n)
+	if spectrumRMS(total, n) < 0.5 {
+		return result, nil
+	}
+
+	// Local maxima, strongest first, each once with its mirror image
+	var candidates []periodicityPeak
+	for v := 0; v < n; v++ {
+		for u := 0; u < n; u++ {
+			fu, fv := fft.Freq(u, n), fft.Freq(v, n)
+			if math.Hypot(float64(fu), float64(fv)) < periodicityMinCycles {
+				continue
+			}
+			p := power[v*n+u]
+			local := true
+			for dv := -1; dv <= 1 && local; dv++ {
+				for du := -1; du <= 1; du++ {
+					if (du != 0 || dv != 0) && power[fft.Index(v+dv, n)*n+fft.Index(u+du, n)] > p {
+						local = false
+						break
+					}
+				}
+			}
+			if !local {
+				continue
+			}
+			if share := peakShare(power, n, fu, fv, total); share >= periodicityMinShare {
+				fx, fy := peakFrequency(power, n, u, v)
+				candidates = append(candidates, periodicityPeak{u: fu, v: fv, fx: fx, fy: fy, share: share})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].share > candidates[j].share })
+	taken := make(map[[2]int]bool)
+	var peaks []periodicityPeak
+	for _, c := range candidates {
+		if taken[[2]int{c.u, c.v}] {
+			continue
+		}
+		taken[[2]int{c.u, c.v}] = true
+		taken[[2]int{-c.u, -c.v}] = true
+		peaks = append(peaks, c)
+	}
+
+	// Group harmonics with their fundamentals, lowest frequencies first
+	sort.SliceStable(peaks, func(i, j int) bool {
+		return math.Hypot(peaks[i].fx, peaks[i].fy) < math.Hypot(peaks[j].fx, peaks[j].fy)
+	})
+	type fundamental struct {
+		peak      periodicityPeak
+		strength  float64
+		harmonics int
+	}
+	var found []*fundamental
+	tolerance := 1.5 / float64(n)
+	matches := func(c periodicityPeak, fx, fy float64) bool {
+		dx, dy := c.fx-fx, c.fy-fy
+		dx, dy = dx-math.Round(dx), dy-math.Round(dy)
+		return math.Hypot(dx, dy) <= tolerance
+	}
+	for _, c := range peaks {
+		var owner *fundamental
+		for i, f := range found {
+			for j := i; j < len(found) && owner == nil; j++ {
+				g := found[j]
+				for a := -periodicityMaxOrder; a <= periodicityMaxOrder && owner == nil; a++ {
+					for k := -periodicityMaxOrder; k <= periodicityMaxOrder; k++ {
+						if (i == j && k != 0) || (a == 0 && k == 0) {
+							continue
+						}
+						fx := float64(a)*f.peak.fx + float64(k)*g.peak.fx
+						fy := float64(a)*f.peak.fy + float64(k)*g.peak.fy
+						if matches(c, fx, fy) || matches(c, -fx, -fy) {
+							owner = f
+							if g.peak.share > f.peak.share {
+								owner = g
+							}
+							break
+						}
+					}
+				}
+			}
+			if owner != nil {
+				break
+			}
+		}
+		if owner != nil {
+			owner.strength += c.share
+			owner.harmonics++
+			continue
+		}
+		found = append(found, &fundamental{peak: c, strength: c.share})
+	}
+
+	sort.SliceStable(found, func(i, j int) bool { return found[i].strength > found[j].strength })
+	for _, f := range found[:min(len(found), maxPeaks)] {
+		fx, fy := f.peak.fx, f.peak.fy
+		freq := math.Hypot(fx, fy)
+		axisPeriod := func(f float64) float64 {
+			if math.Abs(f) < 0.5/float64(n) {
+				return 0
+			}
+			return math.Round(100/math.Abs(f)) / 100
+		}
+		result.Peaks = append(result.Peaks, Periodicity{
+			Period:    math.Round(100/freq) / 100,
+			PeriodX:   axisPeriod(fx),
+			PeriodY:   axisPeriod(fy),
+			Frequency: math.Round(freq*10000) / 10000,
+			Angle:     math.Round(math.Mod(math.Atan2(fy, fx)*180/math.Pi+360, 180)*10) / 10,
+			Strength:  math.Round(math.Min(f.strength, 1)*1000) / 1000,
+			Harmonics: f.harmonics,
+		})
+	}
+	result.Count = len(result.Peaks)
+	result.Periodic = result.Count > 0 && result.Peaks[0].Strength >= periodicityMinStrength
+	return result, nil
+}
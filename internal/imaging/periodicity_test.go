@@ -0,0 +1,80 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestDetectPeriodicity(t *testing.T) {
+	// List rows every 48 pixels: an icon, a label, and a divider
+	list := image.NewRGBA(image.Rect(0, 0, 400, 600))
+	fillRect(list, 0, 0, 400, 600, color.White)
+	for y := 10; y < 600; y += 48 {
+		fillRect(list, 12, y, 40, y+28, color.RGBA{40, 90, 200, 255})
+		fillRect(list, 50, y+8, 300, y+20, color.RGBA{60, 60, 60, 255})
+		fillRect(list, 0, y+40, 400, y+41, color.RGBA{220, 220, 220, 255})
+	}
+	result, err := DetectPeriodicity(list, nil, 5)
+	if err != nil {
+		t.Fatalf("DetectPeriodicity failed: %v", err)
+	}
+	if !result.Periodic || result.Window != (Region{X1: 72, Y1: 172, X2: 328, Y2: 428}) {
+		t.Fatalf("list: got %+v", result)
+	}
+	p := result.Peaks[0]
+	if math.Abs(p.Period-48) > 0.5 || p.PeriodX != 0 || p.Angle != 90 || p.Strength < 0.8 || p.Harmonics == 0 {
+		t.Errorf("list: got %+v", p)
+	}
+
+	// Cards in a grid, 72 pixels apart across and 56 down; the
+	// combinations of the two are harmonics, not more patterns
+	grid := image.NewRGBA(image.Rect(0, 0, 600, 500))
+	fillRect(grid, 0, 0, 600, 500, color.RGBA{240, 240, 240, 255})
+	for y := 8; y < 500; y += 56 {
+		for x := 8; x < 600; x += 72 {
+			fillRect(grid, x, y, x+60, y+44, color.White)
+			fillRect(grid, x+6, y+6, x+26, y+26, color.RGBA{200, 80, 40, 255})
+		}
+	}
+	result, err = DetectPeriodicity(grid, nil, 5)
+	if err != nil {
+		t.Fatalf("DetectPeriodicity failed: %v", err)
+	}
+	if result.Count != 2 {
+		t.Fatalf("grid: got %+v", result.Peaks)
+	}
+	periods := map[float64]bool{}
+	for _, p := range result.Peaks {
+		periods[math.Round(p.PeriodX)+1000*math.Round(p.PeriodY)] = true
+	}
+	if !periods[72] || !periods[56000] {
+		t.Errorf("grid: got %+v, want periods 72 across and 56 down", result.Peaks)
+	}
+
+	// A region restricts the analysis; one shape does not repeat
+	single := image.NewRGBA(image.Rect(0, 0, 300, 300))
+	fillRect(single, 0, 0, 300, 300, color.White)
+	fillRect(single, 60, 80, 220, 200, color.RGBA{30, 30, 30, 255})
+	result, err = DetectPeriodicity(single, &Region{X1: 20, Y1: 40, X2: 280, Y2: 240}, 5)
+	if err != nil {
+		t.Fatalf("DetectPeriodicity failed: %v", err)
+	}
+	if result.Periodic || result.Window != (Region{X1: 86, Y1: 76, X2: 214, Y2: 204}) {
+		t.Errorf("single shape: got %+v", result)
+	}
+
+	// A flat image has no patterns
+	result, err = DetectPeriodicity(image.NewRGBA(image.Rect(0, 0, 64, 64)), nil, 5)
+	if err != nil || result.Periodic || result.Count != 0 {
+		t.Errorf("flat: got %+v, %v", result, err)
+	}
+
+	if _, err := DetectPeriodicity(single, nil, 0); err == nil {
+		t.Error("expected error for max_peaks 0")
+	}
+	if _, err := DetectPeriodicity(single, &Region{X1: 0, Y1: 0, X2: 10, Y2: 300}, 5); err == nil {
+		t.Error("expected error for a region narrower than 16 pixels")
+	}
+}
@@ -0,0 +1,215 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"math/bits"
+)
+
+// duplicateThumbSize is the side length of the grayscale thumbnails
+// compared by NearDuplicate.
+const duplicateThumbSize = 16
+
+// BlankResult reports whether an image is blank (a single flat color).
+type BlankResult struct {
+	// IsBlank is true if StdDev is at most MaxStdDev.
+	IsBlank bool `json:"is_blank"`
+
+	// MeanColor is the average color as hex (#RRGGBB).
+	MeanColor string `json:"mean_color"`
+
+	// StdDev is the standard deviation of pixel brightness (0-255).
+	StdDev float64 `json:"std_dev"`
+
+	// ContentFraction is the fraction of pixels (0.0-1.0) that differ
+	// noticeably from the mean color.
+	ContentFraction float64 `json:"content_fraction"`
+
+	// MaxStdDev is the threshold that was applied.
+	MaxStdDev float64 `json:"max_std_dev"`
+}
+
+// IsBlank checks whether an image is a single flat color, such as an empty
+// frame or a screen that has not rendered yet.
+//
+// Parameters:
+//   - img: Source image.
+//   - maxStdDev: Largest brightness standard deviation (0-255) that still
+//     counts as blank. Typical: 2-5 to tolerate compression noise.
+//
+// Returns:
+//   - *BlankResult: The verdict and the statistics behind it.
+//   - error: Non-nil if maxStdDev is negative.
+//
+// A single small mark is enough to lift the deviation above a few units
+// (one 20x20 black square on a white 800x600 frame gives about 7), so
+// frames with any real content are not reported as blank.
+func IsBlank(img image.Image, maxStdDev float64) (*BlankResult, error) {
+	if maxStdDev < 0 {
+		return nil, fmt.Errorf("max_std_dev must be non-negative, got %v", maxStdDev)
+	}
+
+	b := img.Bounds()
+	n := float64(b.Dx() * b.Dy())
+	var sumR, sumG, sumB, sumGray, sumSq float64
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			rf, gf, bf := float64(r>>8), float64(g>>8), float64(bl>>8)
+			gray := 0.299*rf + 0.587*gf + 0.114*bf
+			sumR += rf
+			sumG += gf
+			sumB += bf
+			sumGray += gray
+			sumSq += gray * gray
+		}
+	}
+
+	meanGray := sumGray / n
+	stdDev := math.Sqrt(math.Max(0, sumSq/n-meanGray*meanGray))
+	mean := color.RGBA{uint8(math.Round(sumR / n)), uint8(math.Round(sumG / n)), uint8(math.Round(sumB / n)), 255}
+
+	content := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if pixelDiff(img.At(x, y), mean) > pixelDiffThreshold {
+				content++
+			}
+		}
+	}
+
+	return &BlankResult{
+		IsBlank:         stdDev <= maxStdDev,
+		MeanColor:       fmt.Sprintf("#%02X%02X%02X", mean.R, mean.G, mean.B),
+		StdDev:          math.Round(stdDev*100) / 100,
+		ContentFraction: math.Round(float64(content)/n*10000) / 10000,
+		MaxStdDev:       maxStdDev,
+	}, nil
+}
+
+// NearDuplicateResult reports how similar two images are.
+type NearDuplicateResult struct {
+	// IsDuplicate is true if HammingDistance is at most MaxDistance and
+	// MeanDiff is at most MaxMeanDiff, or if the thumbnails are practically
+	// identical (MeanDiff at most 1).
+	IsDuplicate bool `json:"is_duplicate"`
+
+	// HashA and HashB are the 64-bit difference hashes as hex.
+	HashA string `json:"hash_a"`
+	HashB string `json:"hash_b"`
+
+	// HammingDistance is the number of differing hash bits (0-64).
+	HammingDistance int `json:"hamming_distance"`
+
+	// MaxDistance is the threshold that was applied.
+	MaxDistance int `json:"max_distance"`
+
+	// MeanDiff is the mean absolute brightness difference (0-255) between
+	// 16x16 grayscale thumbnails of the two images.
+	MeanDiff float64 `json:"mean_diff"`
+
+	// MaxMeanDiff is the MeanDiff threshold that was applied.
+	MaxMeanDiff float64 `json:"max_mean_diff"`
+
+	// SameDimensions is true if both images have the same width and height.
+	SameDimensions bool `json:"same_dimensions"`
+}
+
+// NearDuplicate checks whether two images show practically the same
+// content, e.g. consecutive frames of a screen recording.
+//
+// Parameters:
+//   - a, b: The images to compare. They may differ in size.
+//   - maxDistance: Largest hash Hamming distance (0-64) that still counts
+//     as a duplicate. Typical: 3-10.
+//   - maxMeanDiff: Largest thumbnail brightness difference (0-255) that
+//     still counts as a duplicate. Typical: 4-10.
+//
+// Returns:
+//   - *NearDuplicateResult: The verdict, hashes, and distances.
+//   - error: Non-nil if a threshold is out of range.
+//
+// # Method
+//
+// Each image is reduced to a 9x8 area-averaged grayscale thumbnail; each
+// hash bit records whether a cell is brighter than its right neighbor
+// (dHash). The hash captures structure and ignores scaling and small
+// color shifts, while the 16x16 thumbnail difference catches changes the
+// coarse hash misses, such as a panel changing color or moving. Both must
+// pass. Because the hash of a near-flat image is dominated by noise, a
+// thumbnail difference of at most 1 counts as a duplicate on its own.
+func NearDuplicate(a, b image.Image, maxDistance int, maxMeanDiff float64) (*NearDuplicateResult, error) {
+	if maxDistance < 0 || maxDistance > 64 {
+		return nil, fmt.Errorf("max_distance must be between 0 and 64, got %d", maxDistance)
+	}
+	if maxMeanDiff < 0 || maxMeanDiff > 255 {
+		return nil, fmt.Errorf("max_mean_diff must be between 0 and 255, got %v", maxMeanDiff)
+	}
+
+	hashA, hashB := differenceHash(a), differenceHash(b)
+	distance := bits.OnesCount64(hashA ^ hashB)
+
+	thumbA := grayThumbnail(a, duplicateThumbSize, duplicateThumbSize)
+	thumbB := grayThumbnail(b, duplicateThumbSize, duplicateThumbSize)
+	meanDiff := 0.0
+	for i := range thumbA {
+		meanDiff += math.Abs(thumbA[i] - thumbB[i])
+	}
+	meanDiff /= float64(len(thumbA))
+
+	return &NearDuplicateResult{
+		IsDuplicate:     (distance <= maxDistance && meanDiff <= maxMeanDiff) || meanDiff <= 1,
+		HashA:           fmt.Sprintf("%016x", hashA),
+		HashB:           fmt.Sprintf("%016x", hashB),
+		HammingDistance: distance,
+		MaxDistance:     maxDistance,
+		MeanDiff:        math.Round(meanDiff*100) / 100,
+		MaxMeanDiff:     maxMeanDiff,
+		SameDimensions:  a.Bounds().Size() == b.Bounds().Size(),
+	}, nil
+}
+
+// differenceHash computes the 64-bit dHash of img.
+func differenceHash(img image.Image) uint64 {
+	thumb := grayThumbnail(img, 9, 8)
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if thumb[y*9+x] > thumb[y*9+x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// grayThumbnail reduces img to a tw×th grid of area-averaged brightness
+// values (0-255), row-major. Images smaller than the grid repeat pixels.
+func grayThumbnail(img image.Image, tw, th int) []float64 {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	cellRange := func(c, size, n int) (int, int) {
+		lo := c * size / n
+		return lo, maxInt(lo+1, (c+1)*size/n)
+	}
+
+	thumb := make([]float64, tw*th)
+	for cy := 0; cy < th; cy++ {
+		y0, y1 := cellRange(cy, h, th)
+		for cx := 0; cx < tw; cx++ {
+			x0, x1 := cellRange(cx, w, tw)
+			sum := 0.0
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					r, g, bl, _ := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+					sum += (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)) / 257
+				}
+			}
+			thumb[cy*tw+cx] = sum / float64((x1-x0)*(y1-y0))
+		}
+	}
+	return thumb
+}
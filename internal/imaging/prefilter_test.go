@@ -0,0 +1,110 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestIsBlank(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	fillRect(img, 0, 0, 100, 100, color.RGBA{200, 100, 50, 255})
+
+	result, err := IsBlank(img, 3)
+	if err != nil {
+		t.Fatalf("IsBlank failed: %v", err)
+	}
+	if !result.IsBlank || result.StdDev != 0 || result.ContentFraction != 0 {
+		t.Errorf("flat image: got %+v, want blank with zero deviation", result)
+	}
+	if result.MeanColor != "#C86432" {
+		t.Errorf("MeanColor: got %s, want #C86432", result.MeanColor)
+	}
+
+	// A 10x10 black mark covers 1% of the image
+	fillRect(img, 40, 40, 50, 50, color.Black)
+	result, err = IsBlank(img, 3)
+	if err != nil {
+		t.Fatalf("IsBlank failed: %v", err)
+	}
+	if result.IsBlank {
+		t.Errorf("image with a mark should not be blank, std dev %v", result.StdDev)
+	}
+	if result.ContentFraction != 0.01 {
+		t.Errorf("ContentFraction: got %v, want 0.01", result.ContentFraction)
+	}
+
+	if _, err := IsBlank(img, -1); err == nil {
+		t.Error("IsBlank should fail with negative max std dev")
+	}
+}
+
+func TestNearDuplicate(t *testing.T) {
+	base := image.NewRGBA(image.Rect(0, 0, 90, 80))
+	fillRect(base, 0, 0, 90, 80, color.White)
+	drawFakeText(base, 5, 5, 85, 75)
+	fillRect(base, 60, 40, 85, 75, color.RGBA{0, 0, 200, 255})
+
+	// Same content at half size (2x2 box filter), slightly darker
+	small := image.NewRGBA(image.Rect(0, 0, 45, 40))
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 45; x++ {
+			var sum [3]uint32
+			for _, p := range [][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+				r, g, b, _ := base.At(x*2+p[0], y*2+p[1]).RGBA()
+				sum[0], sum[1], sum[2] = sum[0]+r>>8, sum[1]+g>>8, sum[2]+b>>8
+			}
+			small.Set(x, y, color.RGBA{uint8(sum[0]/4) &^ 3, uint8(sum[1]/4) &^ 3, uint8(sum[2]/4) &^ 3, 255})
+		}
+	}
+
+	result, err := NearDuplicate(base, small, 5, 8)
+	if err != nil {
+		t.Fatalf("NearDuplicate failed: %v", err)
+	}
+	if !result.IsDuplicate || result.SameDimensions {
+		t.Errorf("scaled copy: got %+v, want duplicate with different dimensions", result)
+	}
+
+	// Move the blue block to the other side
+	changed := image.NewRGBA(image.Rect(0, 0, 90, 80))
+	fillRect(changed, 0, 0, 90, 80, color.White)
+	drawFakeText(changed, 5, 5, 85, 75)
+	fillRect(changed, 5, 40, 30, 75, color.RGBA{0, 0, 200, 255})
+
+	result, err = NearDuplicate(base, changed, 5, 8)
+	if err != nil {
+		t.Fatalf("NearDuplicate failed: %v", err)
+	}
+	if result.IsDuplicate {
+		t.Errorf("changed layout: got duplicate with distance %d, mean diff %v",
+			result.HammingDistance, result.MeanDiff)
+	}
+
+	if _, err := NearDuplicate(base, base, 65, 4); err == nil {
+		t.Error("NearDuplicate should fail with max distance above 64")
+	}
+	if _, err := NearDuplicate(base, base, 5, -1); err == nil {
+		t.Error("NearDuplicate should fail with negative max mean diff")
+	}
+}
+
+func TestNearDuplicate_FlatImages(t *testing.T) {
+	// Hashes of near-flat images are noise; the thumbnail check catches them
+	a := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	b := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			a.Set(x, y, color.Gray{uint8(128 + (x+y)%2)})
+			b.Set(x, y, color.Gray{uint8(128 + (x+y+1)%2)})
+		}
+	}
+
+	result, err := NearDuplicate(a, b, 0, 0)
+	if err != nil {
+		t.Fatalf("NearDuplicate failed: %v", err)
+	}
+	if !result.IsDuplicate || result.MeanDiff > 1 {
+		t.Errorf("near-flat images: got %+v, want duplicate", result)
+	}
+}
@@ -0,0 +1,279 @@
+package imaging
+
+import "image"
+
+// Print mark detection thresholds: crop marks are lines of pixels darker
+// than printDarkLuma between pixels lighter than printLightLuma, at most
+// printMaxMark pixels thick; they point at the trim box's corners within
+// printMarkTol pixels, from at least printMinOffset pixels away. Bleed
+// rows and columns have at least printBleedShare of their pixels more
+// than printEdgeDiff from the slug's luma.
+const (
+	printDarkLuma   = 128
+	printLightLuma  = 192
+	printMaxMark    = 4
+	printMarkTol    = 2
+	printMinOffset  = 2
+	printBleedShare = 0.01
+	printEdgeDiff   = 16
+)
+
+// PrintMarksResult is the trim and bleed boxes found from the crop marks
+// of a print-ready export.
+type PrintMarksResult struct {
+	// Found is true if crop marks were found at three or four corners of
+	// a trim box.
+	Found bool `json:"found"`
+
+	// TrimBox is the finished page, where the paper is cut: the lines the
+	// crop marks point along. Nil if not found.
+	TrimBox *Region `json:"trim_box,omitempty"`
+
+	// BleedBox is the artwork's extent beyond the trim box, to be cut
+	// off; the trim box if the artwork stops at it. Nil if not found.
+	BleedBox *Region `json:"bleed_box,omitempty"`
+
+	// Bleed is the width of the bleed beyond each side of the trim box,
+	// in pixels.
+	Bleed Insets `json:"bleed"`
+
+	// Corners is the number of the trim box's four corners with marks.
+	Corners int `json:"corners"`
+
+	// Marks are the crop marks of the trim box.
+	Marks []Region `json:"marks"`
+
+	// SlugLuma is the luma of the slug, the blank paper beyond the bleed
+	// that holds the marks.
+	SlugLuma int `json:"slug_luma"`
+}
+
+// trimCorner is a corner of a trim box, at x, y, and its crop marks.
+type trimCorner struct {
+	x, y int
+	h, v separator
+}
+
+// DetectPrintMarks finds the crop marks of a print-ready export, such as a
+// PDF page rasterized with marks and bleed, and the trim and bleed boxes
+// they show.
+//
+// Parameters:
+//   - img: The export.
+//
+// Returns:
+//   - *PrintMarksResult: The trim box, the bleed beyond it, and the
+//     marks; Found is false if no marks were found.
+//
+// # Method
+//
+// A crop mark is a short, thin dark line on light paper, in line with an
+// edge of the trim box and pointing at one of its corners from outside,
+// offset to clear the bleed. Each pair of a horizontal and a vertical
+// line that both end short of the point where their extensions cross is
+// a candidate corner, of the kind (top-left, top-right, bottom-left, or
+// bottom-right) their sides give. Opposite corners form a candidate trim
+// box, supported by the corners found at its four positions; the box
+// with the most support, and then the largest, wins, with at least three
+// corners: lines in the artwork rarely line up so.
+//
+// The bleed on each side is the rows (or columns) outward from the trim
+// box, until one with under 1% of its pixels differing from the slug,
+// the most common luma at the image border.
+func DetectPrintMarks(img image.Image) *PrintMarksResult {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	lum := make([]uint8, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			lum[y*w+x] = luma(nrgbaAt(img, b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	dark := func(x, y int) bool { return lum[y*w+x] < printDarkLuma }
+	light := func(x, y int) bool { return lum[y*w+x] >= printLightLuma }
+
+	minLen, maxLen := max(8, min(w, h)/100), max(w, h)/4
+	hMarks := findMarkLines(w, h, minLen, maxLen, dark, light)
+	vMarks := findMarkLines(h, w, minLen, maxLen,
+		func(along, across int) bool { return dark(across, along) },
+		func(along, across int) bool { return light(across, along) })
+
+	// Corners by kind: top-left, top-right, bottom-left, bottom-right
+	maxGap := max(w, h) / 8
+	gap := func(g int) bool { return g >= printMinOffset && g <= maxGap }
+	var corners [4][]trimCorner
+	for _, hm := range hMarks {
+		y := hm.at + hm.thick/2
+		for _, vm := range vMarks {
+			x := vm.at + vm.thick/2
+			left, right := gap(x-hm.end), gap(hm.start-x-1)
+			above, below := gap(y-vm.end), gap(vm.start-y-1)
+			c := trimCorner{x: x, y: y, h: hm, v: vm}
+			switch {
+			case left && above:
+				corners[0] = append(corners[0], c)
+			case right && above:
+				corners[1] = append(corners[1], c)
+			case left && below:
+				corners[2] = append(corners[2], c)
+			case right && below:
+				corners[3] = append(corners[3], c)
+			}
+		}
+	}
+
+	result := &PrintMarksResult{Marks: []Region{}, SlugLuma: int(borderLuma(lum, w, h))}
+	var best []trimCorner
+	bestArea := 0
+	try := func(x1, y1, x2, y2 int) {
+		if x2-x1 < minLen || y2-y1 < minLen {
+			return
+		}
+		var found []trimCorner
+		for i, at := range [4]Point{{X: x1, Y: y1}, {X: x2, Y: y1}, {X: x1, Y: y2}, {X: x2, Y: y2}} {
+			for _, c := range corners[i] {
+				if absInt(c.x-at.X) <= printMarkTol && absInt(c.y-at.Y) <= printMarkTol {
+					found = append(found, c)
+					break
+				}
+			}
+		}
+		area := (x2 - x1) * (y2 - y1)
+		if len(found) > len(best) || len(found) == len(best) && area > bestArea {
+			best, bestArea = found, area
+			result.TrimBox = &Region{X1: x1, Y1: y1, X2: x2, Y2: y2}
+		}
+	}
+	for _, tl := range corners[0] {
+		for _, br := range corners[3] {
+			try(tl.x, tl.y, br.x, br.y)
+		}
+	}
+	for _, tr := range corners[1] {
+		for _, bl := range corners[2] {
+			try(bl.x, tr.y, tr.x, bl.y)
+		}
+	}
+	if len(best) < 3 {
+		result.TrimBox = nil
+		return result
+	}
+
+	result.Found = true
+	result.Corners = len(best)
+	for _, c := range best {
+		result.Marks = append(result.Marks,
+			Region{X1: c.h.start, Y1: c.h.at, X2: c.h.end, Y2: c.h.at + c.h.thick},
+			Region{X1: c.v.at, Y1: c.v.start, X2: c.v.at + c.v.thick, Y2: c.v.end})
+	}
+	trim := *result.TrimBox
+	result.Bleed = measureBleed(lum, w, h, trim, uint8(result.SlugLuma))
+	result.BleedBox = &Region{
+		X1: trim.X1 - result.Bleed.Left,
+		Y1: trim.Y1 - result.Bleed.Top,
+		X2: trim.X2 + result.Bleed.Right,
+		Y2: trim.Y2 + result.Bleed.Bottom,
+	}
+	return result
+}
+
+// findMarkLines finds the thin dark lines of an image along one axis: for
+// each position across the axis and each thickness up to printMaxMark,
+// the runs of minLen to maxLen positions along it that are dark across
+// the thickness and light on both sides. n is the length along the axis
+// and m across it.
+func findMarkLines(n, m, minLen, maxLen int, dark, light func(along, across int) bool) []separator {
+	var lines []separator
+	isLine := func(along, across, thick int) bool {
+		if !light(along, across-1) || !light(along, across+thick) {
+			return false
+		}
+		for i := 0; i < thick; i++ {
+			if !dark(along, across+i) {
+				return false
+			}
+		}
+		return true
+	}
+	for across := 1; across < m; across++ {
+		for thick := 1; thick <= printMaxMark && across+thick < m; thick++ {
+			start := -1
+			for along := 0; along <= n; along++ {
+				if along < n && isLine(along, across, thick) {
+					if start < 0 {
+						start = along
+					}
+					continue
+				}
+				if start >= 0 && along-start >= minLen && along-start <= maxLen {
+					lines = append(lines, separator{at: across, thick: thick, start: start, end: along})
+				}
+				start = -1
+			}
+		}
+	}
+	return lines
+}
+
+// borderLuma returns the most common luma of the image border.
+func borderLuma(lum []uint8, w, h int) uint8 {
+	var counts [256]int
+	for x := 0; x < w; x++ {
+		counts[lum[x]]++
+		counts[lum[(h-1)*w+x]]++
+	}
+	for y := 0; y < h; y++ {
+		counts[lum[y*w]]++
+		counts[lum[y*w+w-1]]++
+	}
+	mode := 0
+	for l := range counts {
+		if counts[l] > counts[mode] {
+			mode = l
+		}
+	}
+	return uint8(mode)
+}
+
+// measureBleed returns the bleed beyond each side of trim: the rows or
+// columns outward from it with at least printBleedShare of their pixels,
+// along the side and clear of the marks at its ends, differing from the
+// slug's luma.
+func measureBleed(lum []uint8, w, h int, trim Region, slug uint8) Insets {
+	inset := printMaxMark + printMarkTol
+	content := func(x, y int) bool { return absInt(int(lum[y*w+x])-int(slug)) > printEdgeDiff }
+	row := func(y int) bool {
+		n, total := 0, 0
+		for x := max(0, trim.X1+inset); x < min(w, trim.X2-inset); x++ {
+			if content(x, y) {
+				n++
+			}
+			total++
+		}
+		return total > 0 && float64(n) >= printBleedShare*float64(total)
+	}
+	col := func(x int) bool {
+		n, total := 0, 0
+		for y := max(0, trim.Y1+inset); y < min(h, trim.Y2-inset); y++ {
+			if content(x, y) {
+				n++
+			}
+			total++
+		}
+		return total > 0 && float64(n) >= printBleedShare*float64(total)
+	}
+	var bleed Insets
+	for y := trim.Y1 - 1; y >= 0 && row(y); y-- {
+		bleed.Top++
+	}
+	for y := trim.Y2; y < h && row(y); y++ {
+		bleed.Bottom++
+	}
+	for x := trim.X1 - 1; x >= 0 && col(x); x-- {
+		bleed.Left++
+	}
+	for x := trim.X2; x < w && col(x); x++ {
+		bleed.Right++
+	}
+	return bleed
+}
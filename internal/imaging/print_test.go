@@ -0,0 +1,76 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// printExport draws a 600×800 print export on white paper: a 480×680
+// trim box at 60,60, artwork extending bleed pixels beyond it, and crop
+// marks 24 pixels out from each corner listed in corners (0 top-left, 1
+// top-right, 2 bottom-left, 3 bottom-right).
+func printExport(bleed int, corners ...int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 600, 800))
+	fill := func(r image.Rectangle, c color.NRGBA) {
+		draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Src)
+	}
+	fill(img.Bounds(), color.NRGBA{255, 255, 255, 255})
+	fill(image.Rect(60-bleed, 60-bleed, 540+bleed, 740+bleed), color.NRGBA{40, 90, 200, 255})
+	fill(image.Rect(120, 200, 480, 260), color.NRGBA{250, 250, 250, 255})
+	black := color.NRGBA{0, 0, 0, 255}
+	for _, c := range corners {
+		x, y, dx, dy := 60, 60, -1, -1
+		if c%2 == 1 {
+			x, dx = 540, 1
+		}
+		if c >= 2 {
+			y, dy = 740, 1
+		}
+		// The horizontal mark runs out from x+24*dx, the vertical from y+24*dy
+		hx := min(x+24*dx, x+54*dx)
+		vy := min(y+24*dy, y+54*dy)
+		fill(image.Rect(hx, y, hx+31, y+1), black)
+		fill(image.Rect(x, vy, x+1, vy+31), black)
+	}
+	return img
+}
+
+func TestDetectPrintMarks(t *testing.T) {
+	result := DetectPrintMarks(printExport(18, 0, 1, 2, 3))
+	if !result.Found || result.Corners != 4 || len(result.Marks) != 8 {
+		t.Fatalf("got found %v, %d corners, %d marks", result.Found, result.Corners, len(result.Marks))
+	}
+	if *result.TrimBox != (Region{X1: 60, Y1: 60, X2: 540, Y2: 740}) {
+		t.Errorf("trim box = %+v", *result.TrimBox)
+	}
+	if result.Bleed != (Insets{Top: 18, Left: 18, Bottom: 18, Right: 18}) || *result.BleedBox != (Region{X1: 42, Y1: 42, X2: 558, Y2: 758}) {
+		t.Errorf("bleed = %+v, bleed box %+v", result.Bleed, *result.BleedBox)
+	}
+	if result.SlugLuma != 255 {
+		t.Errorf("slug luma = %d, want 255", result.SlugLuma)
+	}
+}
+
+func TestDetectPrintMarks_ThreeCorners(t *testing.T) {
+	result := DetectPrintMarks(printExport(0, 0, 1, 3))
+	if !result.Found || result.Corners != 3 || *result.TrimBox != (Region{X1: 60, Y1: 60, X2: 540, Y2: 740}) {
+		t.Fatalf("got found %v, %d corners, trim box %+v", result.Found, result.Corners, result.TrimBox)
+	}
+	if result.Bleed != (Insets{}) || *result.BleedBox != *result.TrimBox {
+		t.Errorf("artwork stopping at the trim box: bleed = %+v", result.Bleed)
+	}
+}
+
+func TestDetectPrintMarks_NotFound(t *testing.T) {
+	for name, img := range map[string]*image.NRGBA{
+		"no marks":    printExport(18),
+		"two corners": printExport(18, 0, 3),
+	} {
+		result := DetectPrintMarks(img)
+		if result.Found || result.TrimBox != nil || len(result.Marks) != 0 {
+			t.Errorf("%s: got found %v, trim box %+v", name, result.Found, result.TrimBox)
+		}
+	}
+}
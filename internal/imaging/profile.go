@@ -0,0 +1,411 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strconv"
+)
+
+// Line profiles: profiles are at most maxProfileWidth pixels wide, and at
+// most maxProfileEdges edges are listed. Within an edge, steps of at most
+// profileFlatStep luma against its direction are taken as noise. The plot
+// is profilePlotWidth by profilePlotHeight pixels.
+const (
+	maxProfileWidth   = 51
+	maxProfileEdges   = 100
+	profileFlatStep   = 1.0
+	profilePlotWidth  = 640
+	profilePlotHeight = 320
+)
+
+// Colors of the line profile plot.
+var (
+	profileAxisColor = color.RGBA{160, 160, 160, 255}
+	profileGridColor = color.RGBA{232, 232, 232, 255}
+	profileEdgeColor = color.RGBA{255, 170, 60, 255}
+	profileLumaColor = color.RGBA{0, 0, 0, 255}
+)
+
+// ProfileOptions configures LineProfile.
+type ProfileOptions struct {
+	// Width is the number of parallel lines (odd, 1-51) averaged into the
+	// profile, to smooth noise and texture.
+	Width int
+
+	// RGB also returns the red, green, and blue profiles.
+	RGB bool
+
+	// EdgeThreshold is the smallest change of luma (1-255) reported as an
+	// edge.
+	EdgeThreshold float64
+
+	// IncludePlot also renders the profile as a plot.
+	IncludePlot bool
+}
+
+// ProfileEdge is a rise or fall of intensity along a line profile.
+type ProfileEdge struct {
+	// Position is the distance along the line, in pixels, at which the
+	// edge crosses halfway between From and To.
+	Position float64 `json:"position"`
+
+	// X and Y are the image coordinates at Position.
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+
+	// Direction is "rising" or "falling".
+	Direction string `json:"direction"`
+
+	// From and To are the luma before and after the edge, and Contrast
+	// their difference.
+	From     float64 `json:"from"`
+	To       float64 `json:"to"`
+	Contrast float64 `json:"contrast"`
+
+	// RiseDistance is the distance, in pixels, over which the edge goes
+	// from 10% to 90% of the way from From to To: under 1 for a sharp
+	// edge, more for a blurred or antialiased one.
+	RiseDistance float64 `json:"rise_distance"`
+}
+
+// ProfileStats summarizes the luma of a line profile.
+type ProfileStats struct {
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stddev"`
+
+	// MinPosition and MaxPosition are where the minimum and maximum first
+	// occur, as distances along the line in pixels.
+	MinPosition float64 `json:"min_position"`
+	MaxPosition float64 `json:"max_position"`
+}
+
+// LineProfileResult contains the intensity sampled along a line.
+type LineProfileResult struct {
+	// Start and End are the line's end points.
+	Start Point `json:"start"`
+	End   Point `json:"end"`
+
+	// Length is the line's length in pixels.
+	Length float64 `json:"length"`
+
+	// Spacing is the distance between consecutive samples in pixels, at
+	// most 1.
+	Spacing float64 `json:"spacing"`
+
+	// Width is the number of parallel lines averaged.
+	Width int `json:"width"`
+
+	// Luma is the Rec. 601 luma (0-255) at each sample, from Start to End.
+	Luma []float64 `json:"luma"`
+
+	// Red, Green, and Blue are the channel profiles, when requested.
+	Red   []float64 `json:"red,omitempty"`
+	Green []float64 `json:"green,omitempty"`
+	Blue  []float64 `json:"blue,omitempty"`
+
+	// Stats summarizes Luma.
+	Stats ProfileStats `json:"stats"`
+
+	// Edges are the rises and falls of luma of at least the edge
+	// threshold, in order along the line.
+	Edges []ProfileEdge `json:"edges"`
+
+	// ImageBase64 is the profile plotted against distance along the line,
+	// with luma from 0 to 255 up the side and edges marked, as base64 PNG.
+	// Only set when requested.
+	ImageBase64 string `json:"image_base64,omitempty"`
+
+	// MimeType is "image/png" when ImageBase64 is set.
+	MimeType string `json:"mime_type,omitempty"`
+}
+
+// LineProfile samples intensity along the line from (x1, y1) to (x2, y2),
+// for inspecting edges, gradients, and banding quantitatively.
+//
+// Parameters:
+//   - img: Source image.
+//   - x1, y1, x2, y2: The line's end points, within the image and distinct.
+//   - opts: Width, channels, edge threshold, and plot.
+//
+// Returns:
+//   - *LineProfileResult: The profile, its statistics and edges, and the
+//     optional plot.
+//   - error: Non-nil if an end point is outside the image, the end points
+//     coincide, an option is out of range, or PNG encoding fails.
+//
+// Samples are taken at most 1 pixel apart, from the start to the end
+// inclusive, by bilinear interpolation; with a width over 1,
+// each sample averages parallel lines one pixel apart, clamped to the
+// image. An edge is a run of steps in one direction, ignoring steps of up
+// to one luma level against it, whose total change reaches the threshold.
+func LineProfile(img image.Image, x1, y1, x2, y2 int, opts ProfileOptions) (*LineProfileResult, error) {
+	b := img.Bounds()
+	for _, p := range []image.Point{{x1, y1}, {x2, y2}} {
+		if !p.In(b) {
+			return nil, fmt.Errorf("point (%d, %d) is outside the image %v", p.X, p.Y, b)
+		}
+	}
+	if x1 == x2 && y1 == y2 {
+		return nil, fmt.Errorf("start and end points must differ")
+	}
+	if opts.Width < 1 || opts.Width > maxProfileWidth || opts.Width%2 == 0 {
+		return nil, fmt.Errorf("width must be odd, from 1 to %d, got %d", maxProfileWidth, opts.Width)
+	}
+	if opts.EdgeThreshold < 1 || opts.EdgeThreshold > 255 {
+		return nil, fmt.Errorf("edge_threshold must be between 1 and 255, got %g", opts.EdgeThreshold)
+	}
+
+	dx, dy := float64(x2-x1), float64(y2-y1)
+	length := math.Hypot(dx, dy)
+	n := int(math.Ceil(length)) + 1
+	spacing := length / float64(n-1)
+	ux, uy := dx/length, dy/length
+
+	result := &LineProfileResult{
+		Start:   Point{X: x1, Y: y1},
+		End:     Point{X: x2, Y: y2},
+		Length:  math.Round(length*100) / 100,
+		Spacing: math.Round(spacing*10000) / 10000,
+		Width:   opts.Width,
+		Luma:    make([]float64, n),
+		Edges:   []ProfileEdge{},
+	}
+	var red, green, blue []float64
+	if opts.RGB {
+		red, green, blue = make([]float64, n), make([]float64, n), make([]float64, n)
+	}
+	half := opts.Width / 2
+	for i := 0; i < n; i++ {
+		t := float64(i) * spacing
+		var r, g, bl float64
+		for k := -half; k <= half; k++ {
+			x := float64(x1) + ux*t - uy*float64(k)
+			y := float64(y1) + uy*t + ux*float64(k)
+			cr, cg, cb := profileSample(img, b, x, y)
+			r, g, bl = r+cr, g+cg, bl+cb
+		}
+		r, g, bl = r/float64(opts.Width), g/float64(opts.Width), bl/float64(opts.Width)
+		result.Luma[i] = math.Round((0.299*r+0.587*g+0.114*bl)*100) / 100
+		if opts.RGB {
+			red[i], green[i], blue[i] = math.Round(r*100)/100, math.Round(g*100)/100, math.Round(bl*100)/100
+		}
+	}
+	result.Red, result.Green, result.Blue = red, green, blue
+	result.Stats = profileStats(result.Luma, spacing)
+
+	for _, run := range profileRuns(result.Luma) {
+		if len(result.Edges) == maxProfileEdges {
+			break
+		}
+		from, to := result.Luma[run[0]], result.Luma[run[1]]
+		if math.Abs(to-from) < opts.EdgeThreshold {
+			continue
+		}
+		at := func(f float64) float64 {
+			return profileCrossing(result.Luma, run[0], run[1], from+f*(to-from)) * spacing
+		}
+		pos := at(0.5)
+		e := ProfileEdge{
+			Position:     math.Round(pos*100) / 100,
+			X:            math.Round((float64(x1)+ux*pos)*100) / 100,
+			Y:            math.Round((float64(y1)+uy*pos)*100) / 100,
+			Direction:    "rising",
+			From:         from,
+			To:           to,
+			Contrast:     math.Round(math.Abs(to-from)*100) / 100,
+			RiseDistance: math.Round((at(0.9)-at(0.1))*100) / 100,
+		}
+		if to < from {
+			e.Direction = "falling"
+		}
+		result.Edges = append(result.Edges, e)
+	}
+
+	if opts.IncludePlot {
+		plot := profilePlot(result, spacing)
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, plot); err != nil {
+			return nil, fmt.Errorf("failed to encode profile plot: %w", err)
+		}
+		result.ImageBase64 = base64.StdEncoding.EncodeToString(buf.Bytes())
+		result.MimeType = "image/png"
+	}
+	return result, nil
+}
+
+// profileSample returns the color of img at the fractional pixel position
+// (x, y), interpolated between the four nearest pixels and clamped to the
+// bounds b.
+func profileSample(img image.Image, b image.Rectangle, x, y float64) (r, g, bl float64) {
+	x = math.Max(float64(b.Min.X), math.Min(float64(b.Max.X-1), x))
+	y = math.Max(float64(b.Min.Y), math.Min(float64(b.Max.Y-1), y))
+	x0, y0 := int(math.Floor(x)), int(math.Floor(y))
+	fx, fy := x-float64(x0), y-float64(y0)
+	for _, s := range [4]struct {
+		dx, dy int
+		wt     float64
+	}{{0, 0, (1 - fx) * (1 - fy)}, {1, 0, fx * (1 - fy)}, {0, 1, (1 - fx) * fy}, {1, 1, fx * fy}} {
+		if s.wt == 0 {
+			continue
+		}
+		c := nrgbaAt(img, min(x0+s.dx, b.Max.X-1), min(y0+s.dy, b.Max.Y-1))
+		r, g, bl = r+s.wt*float64(c.R), g+s.wt*float64(c.G), bl+s.wt*float64(c.B)
+	}
+	return r, g, bl
+}
+
+// profileStats returns the statistics of a profile with the given sample
+// spacing.
+func profileStats(luma []float64, spacing float64) ProfileStats {
+	s := ProfileStats{Min: luma[0], Max: luma[0]}
+	var sum, sq float64
+	for i, v := range luma {
+		if v < s.Min {
+			s.Min, s.MinPosition = v, float64(i)*spacing
+		}
+		if v > s.Max {
+			s.Max, s.MaxPosition = v, float64(i)*spacing
+		}
+		sum += v
+		sq += v * v
+	}
+	n := float64(len(luma))
+	mean := sum / n
+	s.Mean = math.Round(mean*100) / 100
+	s.StdDev = math.Round(math.Sqrt(math.Max(0, sq/n-mean*mean))*100) / 100
+	s.MinPosition = math.Round(s.MinPosition*100) / 100
+	s.MaxPosition = math.Round(s.MaxPosition*100) / 100
+	return s
+}
+
+// profileRuns returns the first and last sample index of each run of a
+// profile in one direction, tolerating steps of up to profileFlatStep
+// against it, and trimmed of flat ends.
+func profileRuns(luma []float64) [][2]int {
+	var runs [][2]int
+	start, dir := 0, 0.0
+	end := func(i int) {
+		s, e := start, i
+		for s < e && math.Abs(luma[s+1]-luma[s]) <= profileFlatStep && (luma[s+1]-luma[s])*dir <= 0 {
+			s++
+		}
+		for e > s && math.Abs(luma[e]-luma[e-1]) <= profileFlatStep && (luma[e]-luma[e-1])*dir <= 0 {
+			e--
+		}
+		if e > s {
+			runs = append(runs, [2]int{s, e})
+		}
+	}
+	for i := 1; i < len(luma); i++ {
+		d := luma[i] - luma[i-1]
+		switch {
+		case dir == 0 && math.Abs(d) > profileFlatStep:
+			dir = math.Copysign(1, d)
+		case dir != 0 && d*dir < -profileFlatStep:
+			end(i - 1)
+			start, dir = i-1, math.Copysign(1, d)
+		}
+	}
+	if dir != 0 {
+		end(len(luma) - 1)
+	}
+	return runs
+}
+
+// profileCrossing returns the fractional sample index within [s, e] at
+// which the profile first reaches level, interpolating linearly.
+func profileCrossing(luma []float64, s, e int, level float64) float64 {
+	up := luma[e] > luma[s]
+	for i := s + 1; i <= e; i++ {
+		if (up && luma[i] >= level) || (!up && luma[i] <= level) {
+			a, c := luma[i-1], luma[i]
+			if a == c {
+				return float64(i)
+			}
+			return float64(i-1) + (level-a)/(c-a)
+		}
+	}
+	return float64(e)
+}
+
+// profilePlot renders a profile against distance along its line.
+func profilePlot(r *LineProfileResult, spacing float64) *image.RGBA {
+	const left, right, top, bottom = 40, 12, 10, 24
+	plot := image.NewRGBA(image.Rect(0, 0, profilePlotWidth, profilePlotHeight))
+	for i := range plot.Pix {
+		plot.Pix[i] = 255
+	}
+	pw, ph := profilePlotWidth-left-right, profilePlotHeight-top-bottom
+	px := func(d float64) int { return left + int(math.Round(d/r.Length*float64(pw))) }
+	py := func(v float64) int { return top + ph - int(math.Round(v/255*float64(ph))) }
+
+	for _, v := range []float64{0, 64, 128, 192, 255} {
+		plotLine(plot, left, py(v), left+pw, py(v), profileGridColor)
+		label := strconv.Itoa(int(v))
+		drawText(plot, left-6-7*len(label), py(v)-7, 0, label, profileAxisColor)
+	}
+	for _, e := range r.Edges {
+		for y := top; y < top+ph; y += 4 {
+			plotLine(plot, px(e.Position), y, px(e.Position), y+1, profileEdgeColor)
+		}
+	}
+	plotLine(plot, left, top, left, top+ph, profileAxisColor)
+	plotLine(plot, left, top+ph, left+pw, top+ph, profileAxisColor)
+	for _, d := range []float64{0, r.Length / 2, r.Length} {
+		label := strconv.FormatFloat(math.Round(d*10)/10, 'f', -1, 64)
+		drawText(plot, px(d)-7*len(label)/2, top+ph+6, 0, label, profileAxisColor)
+	}
+
+	series := []struct {
+		values []float64
+		c      color.RGBA
+	}{
+		{r.Red, color.RGBA{220, 40, 40, 255}},
+		{r.Green, color.RGBA{40, 170, 60, 255}},
+		{r.Blue, color.RGBA{40, 80, 220, 255}},
+		{r.Luma, profileLumaColor},
+	}
+	for _, s := range series {
+		for i := 1; i < len(s.values); i++ {
+			plotLine(plot, px(float64(i-1)*spacing), py(s.values[i-1]), px(float64(i)*spacing), py(s.values[i]), s.c)
+		}
+	}
+	return plot
+}
+
+// plotLine draws a one-pixel line from (x0, y0) to (x1, y1) (Bresenham),
+// clipped to img.
+func plotLine(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA) {
+	dx, dy, sx, sy := x1-x0, y0-y1, 1, 1
+	if dx < 0 {
+		dx, sx = -dx, -1
+	}
+	if dy > 0 {
+		dy, sy = -dy, -1
+	}
+	e := dx + dy
+	for {
+		if (image.Point{x0, y0}).In(img.Rect) {
+			img.SetRGBA(x0, y0, c)
+		}
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"testing"
+)
+
+// profileImage returns a 100x20 image: black to x 29, a ramp from black
+// to white over x 30-39, white to x 69, and a hard step to mid gray.
+func profileImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 20))
+	for x := 0; x < 100; x++ {
+		var v uint8
+		switch {
+		case x < 30:
+			v = 0
+		case x < 40:
+			v = uint8((x - 29) * 255 / 11)
+		case x < 70:
+			v = 255
+		default:
+			v = 128
+		}
+		for y := 0; y < 20; y++ {
+			img.SetRGBA(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+	return img
+}
+
+func TestLineProfile(t *testing.T) {
+	result, err := LineProfile(profileImage(), 0, 10, 99, 10, ProfileOptions{Width: 3, EdgeThreshold: 20, IncludePlot: true})
+	if err != nil {
+		t.Fatalf("LineProfile failed: %v", err)
+	}
+	if len(result.Luma) != 100 || result.Spacing != 1 || result.Length != 99 || result.Luma[10] != 0 || result.Luma[50] != 255 || result.Luma[80] != 128 {
+		t.Fatalf("got %d samples, spacing %g, luma %v", len(result.Luma), result.Spacing, result.Luma)
+	}
+	if s := result.Stats; s.Min != 0 || s.Max != 255 || s.MinPosition != 0 || s.MaxPosition != 40 {
+		t.Errorf("stats = %+v", s)
+	}
+	if len(result.Edges) != 2 {
+		t.Fatalf("edges = %+v, want the ramp and the step", result.Edges)
+	}
+	ramp, step := result.Edges[0], result.Edges[1]
+	if ramp.Direction != "rising" || ramp.Contrast != 255 || math.Abs(ramp.Position-34.5) > 0.6 || math.Abs(ramp.RiseDistance-8.8) > 0.5 {
+		t.Errorf("ramp = %+v", ramp)
+	}
+	if step.Direction != "falling" || step.From != 255 || step.To != 128 || step.RiseDistance > 1 || step.Y != 10 || math.Abs(step.X-69.5) > 0.01 {
+		t.Errorf("step = %+v", step)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(result.ImageBase64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plot, err := png.Decode(bytes.NewReader(data))
+	if err != nil || plot.Bounds().Dx() != profilePlotWidth || result.MimeType != "image/png" {
+		t.Errorf("plot: %v, %v", err, plot.Bounds())
+	}
+}
+
+func TestLineProfile_DiagonalRGB(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 40))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = 200, 100, 50, 255
+	}
+	result, err := LineProfile(img, 0, 0, 30, 39, ProfileOptions{Width: 1, RGB: true, EdgeThreshold: 10})
+	if err != nil {
+		t.Fatalf("LineProfile failed: %v", err)
+	}
+	n := len(result.Luma)
+	if n != 51 || result.Spacing > 1 || len(result.Red) != n || result.Red[7] != 200 || result.Green[7] != 100 || result.Blue[7] != 50 {
+		t.Errorf("got %d samples, spacing %g, red %v", n, result.Spacing, result.Red)
+	}
+	if len(result.Edges) != 0 || result.Stats.StdDev != 0 || result.ImageBase64 != "" {
+		t.Errorf("flat image: edges %v, stats %+v", result.Edges, result.Stats)
+	}
+}
+
+func TestLineProfile_Invalid(t *testing.T) {
+	img := profileImage()
+	opts := ProfileOptions{Width: 1, EdgeThreshold: 20}
+	if _, err := LineProfile(img, 0, 0, 100, 0, opts); err == nil {
+		t.Error("expected error for a point outside the image")
+	}
+	if _, err := LineProfile(img, 5, 5, 5, 5, opts); err == nil {
+		t.Error("expected error for a zero-length line")
+	}
+	if _, err := LineProfile(img, 0, 0, 10, 0, ProfileOptions{Width: 2, EdgeThreshold: 20}); err == nil {
+		t.Error("expected error for an even width")
+	}
+	if _, err := LineProfile(img, 0, 0, 10, 0, ProfileOptions{Width: 1}); err == nil {
+		t.Error("expected error for edge threshold 0")
+	}
+}
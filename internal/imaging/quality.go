@@ -0,0 +1,122 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// Thresholds behind the issues reported by MeasureQuality.
+const (
+	lowContrastStdDev = 10  // Brightness standard deviation below this is low contrast
+	blurrySharpness   = 100 // Laplacian variance below this is blurry
+	darkBrightness    = 40  // Mean brightness below this is dark
+	clippedLevel      = 5   // Brightness within this of 0 or 255 is clipped
+)
+
+// QualityResult contains basic image quality metrics.
+type QualityResult struct {
+	// Brightness is the mean pixel brightness (0-255).
+	Brightness float64 `json:"brightness"`
+
+	// Contrast is the standard deviation of pixel brightness (0-255).
+	Contrast float64 `json:"contrast"`
+
+	// Sharpness is the variance of the Laplacian of the brightness. Crisp
+	// edges give values in the thousands; below about 100 the image is
+	// blurry, unless it has little content at all.
+	Sharpness float64 `json:"sharpness"`
+
+	// ShadowsClipped is the fraction of pixels (0.0-1.0) that are black
+	// or nearly so.
+	ShadowsClipped float64 `json:"shadows_clipped"`
+
+	// HighlightsClipped is the fraction of pixels (0.0-1.0) that are
+	// white or nearly so. Diagrams and screenshots on white backgrounds
+	// score high here; that is not a defect.
+	HighlightsClipped float64 `json:"highlights_clipped"`
+
+	// Issues lists detected problems: "low_contrast", "blurry", "dark".
+	// Empty if none were found.
+	Issues []string `json:"issues"`
+}
+
+// MeasureQuality computes brightness, contrast, sharpness, and clipping
+// statistics for an image and flags common capture problems.
+//
+// Parameters:
+//   - img: Source image.
+//
+// Returns:
+//   - *QualityResult: The metrics and any issues found.
+//   - error: Non-nil if the image is smaller than 3x3 pixels.
+//
+// # Issues
+//
+//   - low_contrast: brightness standard deviation below 10.
+//   - blurry: Laplacian variance below 100 on an image that is not low
+//     contrast (a flat image has no edges to be sharp).
+//   - dark: mean brightness below 40.
+//
+// Brightness uses ITU-R BT.601 weights (0.299*R + 0.587*G + 0.114*B). The
+// Laplacian is the 4-neighbor kernel, evaluated on interior pixels.
+func MeasureQuality(img image.Image) (*QualityResult, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w < 3 || h < 3 {
+		return nil, fmt.Errorf("image must be at least 3x3 pixels to measure quality, got %dx%d", w, h)
+	}
+
+	gray := make([]float64, w*h)
+	var sum, sumSq float64
+	shadows, highlights := 0, 0
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, bl, _ := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			v := 0.299*float64(r>>8) + 0.587*float64(g>>8) + 0.114*float64(bl>>8)
+			gray[y*w+x] = v
+			sum += v
+			sumSq += v * v
+			if v <= clippedLevel {
+				shadows++
+			} else if v >= 255-clippedLevel {
+				highlights++
+			}
+		}
+	}
+	n := float64(w * h)
+	mean := sum / n
+	stdDev := math.Sqrt(math.Max(0, sumSq/n-mean*mean))
+
+	var lapSum, lapSq float64
+	for y := 1; y < h-1; y++ {
+		for x := 1; x < w-1; x++ {
+			i := y*w + x
+			lap := gray[i-w] + gray[i+w] + gray[i-1] + gray[i+1] - 4*gray[i]
+			lapSum += lap
+			lapSq += lap * lap
+		}
+	}
+	m := float64((w - 2) * (h - 2))
+	lapMean := lapSum / m
+	sharpness := math.Max(0, lapSq/m-lapMean*lapMean)
+
+	issues := []string{}
+	if stdDev < lowContrastStdDev {
+		issues = append(issues, "low_contrast")
+	} else if sharpness < blurrySharpness {
+		issues = append(issues, "blurry")
+	}
+	if mean < darkBrightness {
+		issues = append(issues, "dark")
+	}
+
+	return &QualityResult{
+		Brightness:        math.Round(mean*100) / 100,
+		Contrast:          math.Round(stdDev*100) / 100,
+		Sharpness:         math.Round(sharpness*100) / 100,
+		ShadowsClipped:    math.Round(float64(shadows)/n*10000) / 10000,
+		HighlightsClipped: math.Round(float64(highlights)/n*10000) / 10000,
+		Issues:            issues,
+	}, nil
+}
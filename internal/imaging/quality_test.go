@@ -0,0 +1,53 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+func TestMeasureQuality(t *testing.T) {
+	// Crisp black box on white: sharp, no issues
+	sharp := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	fillRect(sharp, 0, 0, 100, 100, color.White)
+	fillRect(sharp, 25, 25, 75, 75, color.Black)
+	result, err := MeasureQuality(sharp)
+	if err != nil {
+		t.Fatalf("MeasureQuality failed: %v", err)
+	}
+	if len(result.Issues) != 0 || result.Sharpness < blurrySharpness {
+		t.Errorf("sharp image: got %+v, want no issues", result)
+	}
+	if result.ShadowsClipped != 0.25 || result.HighlightsClipped != 0.75 {
+		t.Errorf("clipping: got %v/%v, want 0.25/0.75", result.ShadowsClipped, result.HighlightsClipped)
+	}
+
+	// A smooth gradient has contrast but no edges
+	gradient := image.NewRGBA(image.Rect(0, 0, 128, 20))
+	for x := 0; x < 128; x++ {
+		fillRect(gradient, x, 0, x+1, 20, color.Gray{uint8(x * 2)})
+	}
+	result, err = MeasureQuality(gradient)
+	if err != nil {
+		t.Fatalf("MeasureQuality failed: %v", err)
+	}
+	if !reflect.DeepEqual(result.Issues, []string{"blurry"}) {
+		t.Errorf("gradient: got issues %v, want [blurry]", result.Issues)
+	}
+
+	// A flat dark frame is low contrast rather than blurry
+	dark := image.NewRGBA(image.Rect(0, 0, 50, 50))
+	fillRect(dark, 0, 0, 50, 50, color.RGBA{20, 20, 20, 255})
+	result, err = MeasureQuality(dark)
+	if err != nil {
+		t.Fatalf("MeasureQuality failed: %v", err)
+	}
+	if !reflect.DeepEqual(result.Issues, []string{"low_contrast", "dark"}) || result.Brightness != 20 {
+		t.Errorf("dark frame: got %+v, want low_contrast and dark at brightness 20", result)
+	}
+
+	if _, err := MeasureQuality(image.NewRGBA(image.Rect(0, 0, 2, 10))); err == nil {
+		t.Error("MeasureQuality should fail for images smaller than 3x3")
+	}
+}
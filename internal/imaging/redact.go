@@ -0,0 +1,148 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Redaction styles for Redact.
+const (
+	// RedactBlack fills each region with opaque black.
+	RedactBlack = "black"
+
+	// RedactPixelate replaces each region with blocks of its average
+	// colors.
+	RedactPixelate = "pixelate"
+)
+
+// Redaction is one area hidden by Redact.
+type Redaction struct {
+	// Bounds is the area hidden, after padding and clipping to the image.
+	Bounds Region `json:"bounds"`
+
+	// Source is why it was hidden: "region" for an explicitly given box,
+	// or the pattern whose match it covers.
+	Source string `json:"source"`
+}
+
+// RedactResult contains a redacted image encoded as base64 PNG.
+type RedactResult struct {
+	// Width and Height of the image in pixels, unchanged by redaction.
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// ImageBase64 is the redacted image encoded as base64 PNG.
+	ImageBase64 string `json:"image_base64"`
+
+	// MimeType is always "image/png" for redaction results.
+	MimeType string `json:"mime_type"`
+
+	// Style is the redaction style applied: "black" or "pixelate".
+	Style string `json:"style"`
+
+	// Redactions lists the areas hidden, in the order given.
+	Redactions []Redaction `json:"redactions"`
+
+	// Count is the number of areas hidden.
+	Count int `json:"count"`
+}
+
+// Redact hides regions of an image, so it can be shared without the
+// sensitive content they hold.
+//
+// Parameters:
+//   - img: Source image. It is not modified.
+//   - redactions: Areas to hide, with the reason for each. Each is grown
+//     by padding on every side and clipped to the image.
+//   - style: RedactBlack or RedactPixelate.
+//   - blockSize: Side of the pixelation blocks in pixels (at least 2);
+//     ignored for RedactBlack.
+//   - padding: Pixels added around each area (0 or more), so anti-aliased
+//     glyph edges just outside a tight text box are hidden too.
+//
+// Returns:
+//   - *RedactResult: The redacted image and the areas hidden.
+//   - error: Non-nil if the style or block size is invalid, an area is
+//     empty or lies entirely outside the image, or PNG encoding fails.
+//
+// # Pixelation
+//
+// Blocks are aligned to each area's top-left corner, and each is filled
+// with the mean color of its pixels. Blocks smaller than the text they
+// cover can leave it legible, and short strings such as numbers can be
+// guessed from their pixelation; use RedactBlack for anything that must
+// not be recovered.
+func Redact(img image.Image, redactions []Redaction, style string, blockSize, padding int) (*RedactResult, error) {
+	if style != RedactBlack && style != RedactPixelate {
+		return nil, fmt.Errorf("invalid style %q: must be %s or %s", style, RedactBlack, RedactPixelate)
+	}
+	if style == RedactPixelate && blockSize < 2 {
+		return nil, fmt.Errorf("block size must be at least 2, got %d", blockSize)
+	}
+	if padding < 0 {
+		return nil, fmt.Errorf("padding must be non-negative, got %d", padding)
+	}
+
+	b := img.Bounds()
+	out := image.NewRGBA(b)
+	draw.Draw(out, b, img, b.Min, draw.Src)
+
+	applied := make([]Redaction, 0, len(redactions))
+	for _, r := range redactions {
+		if r.Bounds.X1 >= r.Bounds.X2 || r.Bounds.Y1 >= r.Bounds.Y2 {
+			return nil, fmt.Errorf("invalid redaction region %+v: x1 must be less than x2 and y1 less than y2", r.Bounds)
+		}
+		rect := image.Rect(r.Bounds.X1-padding, r.Bounds.Y1-padding, r.Bounds.X2+padding, r.Bounds.Y2+padding).Intersect(b)
+		if rect.Empty() {
+			return nil, fmt.Errorf("redaction region %+v is outside the image (%dx%d)", r.Bounds, b.Dx(), b.Dy())
+		}
+		if style == RedactBlack {
+			draw.Draw(out, rect, image.NewUniform(color.RGBA{0, 0, 0, 255}), image.Point{}, draw.Src)
+		} else {
+			pixelate(out, rect, blockSize)
+		}
+		applied = append(applied, Redaction{
+			Bounds: Region{X1: rect.Min.X, Y1: rect.Min.Y, X2: rect.Max.X, Y2: rect.Max.Y},
+			Source: r.Source,
+		})
+	}
+
+	encoded, err := encodePNGBase64(out)
+	if err != nil {
+		return nil, err
+	}
+	return &RedactResult{
+		Width:       b.Dx(),
+		Height:      b.Dy(),
+		ImageBase64: encoded,
+		MimeType:    "image/png",
+		Style:       style,
+		Redactions:  applied,
+		Count:       len(applied),
+	}, nil
+}
+
+// pixelate fills each block of rect, aligned to its top-left corner, with
+// the block's mean color.
+func pixelate(img *image.RGBA, rect image.Rectangle, blockSize int) {
+	for by := rect.Min.Y; by < rect.Max.Y; by += blockSize {
+		for bx := rect.Min.X; bx < rect.Max.X; bx += blockSize {
+			block := image.Rect(bx, by, bx+blockSize, by+blockSize).Intersect(rect)
+			var sum [4]int
+			for y := block.Min.Y; y < block.Max.Y; y++ {
+				for x := block.Min.X; x < block.Max.X; x++ {
+					c := img.RGBAAt(x, y)
+					sum[0] += int(c.R)
+					sum[1] += int(c.G)
+					sum[2] += int(c.B)
+					sum[3] += int(c.A)
+				}
+			}
+			n := block.Dx() * block.Dy()
+			mean := color.RGBA{uint8(sum[0] / n), uint8(sum[1] / n), uint8(sum[2] / n), uint8(sum[3] / n)}
+			draw.Draw(img, block, image.NewUniform(mean), image.Point{}, draw.Src)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// decodeRedacted decodes the image of a RedactResult.
+func decodeRedacted(t *testing.T, result *RedactResult) image.Image {
+	t.Helper()
+	data, err := base64.StdEncoding.DecodeString(result.ImageBase64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func TestRedact_Black(t *testing.T) {
+	img := createInMemoryImage(100, 50, color.White)
+	result, err := Redact(img, []Redaction{
+		{Bounds: Region{X1: 10, Y1: 10, X2: 30, Y2: 20}, Source: "region"},
+		{Bounds: Region{X1: 90, Y1: 40, X2: 120, Y2: 60}, Source: "email"},
+	}, RedactBlack, 0, 2)
+	if err != nil {
+		t.Fatalf("Redact failed: %v", err)
+	}
+	if result.Width != 100 || result.Height != 50 || result.Count != 2 || result.Style != RedactBlack {
+		t.Errorf("got %dx%d, %d redactions, style %s", result.Width, result.Height, result.Count, result.Style)
+	}
+	// Padded, and the second clipped to the image
+	if got := result.Redactions[0].Bounds; got != (Region{X1: 8, Y1: 8, X2: 32, Y2: 22}) {
+		t.Errorf("first bounds: got %+v", got)
+	}
+	if got := result.Redactions[1]; got.Bounds != (Region{X1: 88, Y1: 38, X2: 100, Y2: 50}) || got.Source != "email" {
+		t.Errorf("second redaction: got %+v", got)
+	}
+
+	out := decodeRedacted(t, result)
+	for _, p := range []image.Point{{8, 8}, {31, 21}, {99, 49}} {
+		if r, g, b, _ := out.At(p.X, p.Y).RGBA(); r|g|b != 0 {
+			t.Errorf("pixel %v not black", p)
+		}
+	}
+	for _, p := range []image.Point{{7, 8}, {32, 21}, {50, 25}} {
+		if r, _, _, _ := out.At(p.X, p.Y).RGBA(); r != 0xffff {
+			t.Errorf("pixel %v changed", p)
+		}
+	}
+	// The source image is untouched
+	if r, _, _, _ := img.At(10, 10).RGBA(); r != 0xffff {
+		t.Error("source image was modified")
+	}
+}
+
+func TestRedact_Pixelate(t *testing.T) {
+	// Alternating black and white columns average to gray in each block
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	fillRect(img, 0, 0, 40, 20, color.White)
+	for x := 0; x < 40; x += 2 {
+		fillRect(img, x, 0, x+1, 20, color.Black)
+	}
+	result, err := Redact(img, []Redaction{{Bounds: Region{X1: 0, Y1: 0, X2: 20, Y2: 20}, Source: "region"}}, RedactPixelate, 4, 0)
+	if err != nil {
+		t.Fatalf("Redact failed: %v", err)
+	}
+	out := decodeRedacted(t, result)
+	for x := 0; x < 20; x++ {
+		if c := color.RGBAModel.Convert(out.At(x, 5)).(color.RGBA); c.R != 127 {
+			t.Fatalf("pixel (%d, 5): got %v, want gray 127", x, c)
+		}
+	}
+	if r, _, _, _ := out.At(21, 5).RGBA(); r != 0xffff {
+		t.Error("pixel outside the region changed")
+	}
+}
+
+func TestRedact_Errors(t *testing.T) {
+	img := createInMemoryImage(50, 50, color.White)
+	box := []Redaction{{Bounds: Region{X1: 0, Y1: 0, X2: 10, Y2: 10}}}
+	tests := []struct {
+		name       string
+		redactions []Redaction
+		style      string
+		blockSize  int
+		padding    int
+	}{
+		{"unknown style", box, "blur", 8, 0},
+		{"small block", box, RedactPixelate, 1, 0},
+		{"negative padding", box, RedactBlack, 0, -1},
+		{"empty region", []Redaction{{Bounds: Region{X1: 10, Y1: 0, X2: 10, Y2: 10}}}, RedactBlack, 0, 0},
+		{"outside", []Redaction{{Bounds: Region{X1: 60, Y1: 60, X2: 70, Y2: 70}}}, RedactBlack, 0, 0},
+	}
+	for _, tt := range tests {
+		if _, err := Redact(img, tt.redactions, tt.style, tt.blockSize, tt.padding); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
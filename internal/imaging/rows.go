@@ -0,0 +1,219 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+)
+
+// RowBand is one row (or column) of content found by DetectRows.
+type RowBand struct {
+	// Bounds is the tight bounding box of the row's foreground pixels.
+	Bounds Region `json:"bounds"`
+
+	// Slot spans the full width of the analyzed region (full height for
+	// columns) and extends halfway into the gaps on either side, so
+	// consecutive slots tile the region. Useful as a padded OCR crop.
+	Slot Region `json:"slot"`
+
+	// Size is the row's height (or the column's width) in pixels.
+	Size int `json:"size"`
+
+	// SizeMatches is true if Size is close to the median size. Headers,
+	// separators, and wrapped multi-line rows usually report false.
+	SizeMatches bool `json:"size_matches"`
+}
+
+// RowsResult contains the rows or columns found in a region.
+type RowsResult struct {
+	// Direction is "rows" or "columns".
+	Direction string `json:"direction"`
+
+	// Region is the area that was analyzed.
+	Region Region `json:"region"`
+
+	// Background is the region's most common color as hex (#RRGGBB).
+	Background string `json:"background"`
+
+	// Rows lists the bands in order (top to bottom, or left to right).
+	Rows []RowBand `json:"rows"`
+
+	// Count is the number of rows found.
+	Count int `json:"count"`
+
+	// Pitch is the median distance in pixels from the start of one row to
+	// the start of the next. Zero if fewer than two rows were found.
+	Pitch float64 `json:"pitch"`
+
+	// MedianSize is the median row size in pixels.
+	MedianSize float64 `json:"median_size"`
+
+	// Regular is true if at least two rows were found, every pitch is
+	// within 15% of the median pitch, and every row size matches.
+	Regular bool `json:"regular"`
+}
+
+// DetectRows finds a repeating rhythm of rows or columns in a region, such
+// as the entries of a list, the rows of a table without ruling lines, or
+// the items of a menu.
+//
+// Parameters:
+//   - img: Source image.
+//   - region: The area to analyze, or nil for the whole image.
+//   - direction: "rows" to split top to bottom, or "columns" to split left
+//     to right.
+//   - minGap: Minimum run of empty lines (pixels) that separates two rows.
+//     Shorter gaps, such as between lines of a wrapped entry, are merged.
+//
+// Returns:
+//   - *RowsResult: The rows in order, with rhythm statistics. Rows may be
+//     empty if the region contains only background.
+//   - error: Non-nil if the region is invalid, direction is unknown, or
+//     minGap is less than 1.
+//
+// # Method
+//
+// The region's most common color is taken as background. For each line
+// along the direction (each pixel row for "rows"), the foreground pixels
+// are counted; runs of lines with content form bands, and bands separated
+// by fewer than minGap empty lines are merged.
+func DetectRows(img image.Image, region *Region, direction string, minGap int) (*RowsResult, error) {
+	if direction != "rows" && direction != "columns" {
+		return nil, fmt.Errorf("direction must be \"rows\" or \"columns\", got %q", direction)
+	}
+	if minGap < 1 {
+		return nil, fmt.Errorf("min_gap must be at least 1, got %d", minGap)
+	}
+
+	b := img.Bounds()
+	r := Region{X1: 0, Y1: 0, X2: b.Dx(), Y2: b.Dy()}
+	if region != nil {
+		r = *region
+		if err := validateRegion(r, b.Dx(), b.Dy()); err != nil {
+			return nil, err
+		}
+	}
+	w, h := r.X2-r.X1, r.Y2-r.Y1
+
+	bg := mostCommonColor(img, image.Rect(r.X1, r.Y1, r.X2, r.Y2).Add(b.Min))
+	fg := make([]bool, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			fg[y*w+x] = pixelDiff(img.At(b.Min.X+r.X1+x, b.Min.Y+r.Y1+y), bg) > pixelDiffThreshold
+		}
+	}
+
+	// Work in (u, v) coordinates: u runs along the direction, v across it
+	rows := direction == "rows"
+	length, breadth := h, w
+	if !rows {
+		length, breadth = w, h
+	}
+	at := func(u, v int) bool {
+		if rows {
+			return fg[u*w+v]
+		}
+		return fg[v*w+u]
+	}
+
+	type span struct{ start, end int } // end exclusive
+	var bands []span
+	for u := 0; u < length; u++ {
+		filled := false
+		for v := 0; v < breadth && !filled; v++ {
+			filled = at(u, v)
+		}
+		if !filled {
+			continue
+		}
+		if n := len(bands); n > 0 && u-bands[n-1].end < minGap {
+			bands[n-1].end = u + 1
+		} else {
+			bands = append(bands, span{u, u + 1})
+		}
+	}
+
+	result := &RowsResult{
+		Direction:  direction,
+		Region:     r,
+		Background: fmt.Sprintf("#%02X%02X%02X", bg.R, bg.G, bg.B),
+		Rows:       make([]RowBand, len(bands)),
+		Count:      len(bands),
+	}
+	if len(bands) == 0 {
+		return result, nil
+	}
+
+	sizes := make([]float64, len(bands))
+	for i, s := range bands {
+		vMin, vMax := breadth, 0
+		for u := s.start; u < s.end; u++ {
+			for v := 0; v < breadth; v++ {
+				if at(u, v) {
+					if v < vMin {
+						vMin = v
+					}
+					if v+1 > vMax {
+						vMax = v + 1
+					}
+				}
+			}
+		}
+
+		// Slots meet at the middle of each gap
+		slotStart, slotEnd := 0, length
+		if i > 0 {
+			slotStart = (bands[i-1].end + s.start) / 2
+		}
+		if i < len(bands)-1 {
+			slotEnd = (s.end + bands[i+1].start) / 2
+		}
+
+		band := RowBand{Size: s.end - s.start}
+		if rows {
+			band.Bounds = Region{X1: r.X1 + vMin, Y1: r.Y1 + s.start, X2: r.X1 + vMax, Y2: r.Y1 + s.end}
+			band.Slot = Region{X1: r.X1, Y1: r.Y1 + slotStart, X2: r.X2, Y2: r.Y1 + slotEnd}
+		} else {
+			band.Bounds = Region{X1: r.X1 + s.start, Y1: r.Y1 + vMin, X2: r.X1 + s.end, Y2: r.Y1 + vMax}
+			band.Slot = Region{X1: r.X1 + slotStart, Y1: r.Y1, X2: r.X1 + slotEnd, Y2: r.Y2}
+		}
+		result.Rows[i] = band
+		sizes[i] = float64(band.Size)
+	}
+
+	result.MedianSize = medianFloat(sizes)
+	sizesMatch := true
+	for i := range result.Rows {
+		d := math.Abs(float64(result.Rows[i].Size) - result.MedianSize)
+		result.Rows[i].SizeMatches = d <= math.Max(2, 0.25*result.MedianSize)
+		sizesMatch = sizesMatch && result.Rows[i].SizeMatches
+	}
+
+	if len(bands) >= 2 {
+		pitches := make([]float64, len(bands)-1)
+		for i := range pitches {
+			pitches[i] = float64(bands[i+1].start - bands[i].start)
+		}
+		result.Pitch = medianFloat(pitches)
+		result.Regular = sizesMatch
+		for _, p := range pitches {
+			if math.Abs(p-result.Pitch) > math.Max(1, 0.15*result.Pitch) {
+				result.Regular = false
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// medianFloat returns the median of values without modifying the slice.
+func medianFloat(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
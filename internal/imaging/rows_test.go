@@ -0,0 +1,124 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// newListImage returns a white 100x100 image with a dark bar of the given
+// height starting at each y, spanning x 10-80.
+func newListImage(starts []int, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 100; x++ {
+			img.Set(x, y, color.RGBA{255, 255, 255, 255})
+		}
+	}
+	for _, y0 := range starts {
+		for y := y0; y < y0+height; y++ {
+			for x := 10; x < 80; x++ {
+				img.Set(x, y, color.RGBA{30, 30, 30, 255})
+			}
+		}
+	}
+	return img
+}
+
+func TestDetectRows(t *testing.T) {
+	img := newListImage([]int{10, 30, 50, 70}, 8)
+
+	result, err := DetectRows(img, nil, "rows", 3)
+	if err != nil {
+		t.Fatalf("DetectRows failed: %v", err)
+	}
+
+	if result.Count != 4 {
+		t.Fatalf("Count: got %d, want 4", result.Count)
+	}
+	if result.Pitch != 20 || result.MedianSize != 8 {
+		t.Errorf("rhythm: got pitch %v size %v, want 20 and 8", result.Pitch, result.MedianSize)
+	}
+	if !result.Regular {
+		t.Error("evenly spaced rows should be regular")
+	}
+
+	first := result.Rows[0]
+	if first.Bounds != (Region{X1: 10, Y1: 10, X2: 80, Y2: 18}) {
+		t.Errorf("first bounds: got %+v", first.Bounds)
+	}
+	// Slots tile the image, meeting halfway through each 12px gap
+	if first.Slot != (Region{X1: 0, Y1: 0, X2: 100, Y2: 24}) {
+		t.Errorf("first slot: got %+v", first.Slot)
+	}
+	if last := result.Rows[3].Slot; last != (Region{X1: 0, Y1: 64, X2: 100, Y2: 100}) {
+		t.Errorf("last slot: got %+v", last)
+	}
+}
+
+func TestDetectRows_MergesSmallGaps(t *testing.T) {
+	// The second entry wraps onto two lines 2px apart
+	img := newListImage([]int{10, 30, 40, 60}, 8)
+
+	result, err := DetectRows(img, nil, "rows", 3)
+	if err != nil {
+		t.Fatalf("DetectRows failed: %v", err)
+	}
+	if result.Count != 3 {
+		t.Fatalf("Count: got %d, want 3", result.Count)
+	}
+	if result.Rows[1].Size != 18 || result.Rows[1].SizeMatches {
+		t.Errorf("wrapped row: got size %d matches %v, want 18 and false",
+			result.Rows[1].Size, result.Rows[1].SizeMatches)
+	}
+	if result.Regular {
+		t.Error("rows with a wrapped entry should not be regular")
+	}
+}
+
+func TestDetectRows_ColumnsInRegion(t *testing.T) {
+	img := newListImage([]int{10, 30}, 8)
+
+	// Only look at the first bar; split it into two columns
+	for x := 40; x < 45; x++ {
+		for y := 10; y < 18; y++ {
+			img.Set(x, y, color.RGBA{255, 255, 255, 255})
+		}
+	}
+	region := &Region{X1: 0, Y1: 5, X2: 100, Y2: 25}
+	result, err := DetectRows(img, region, "columns", 3)
+	if err != nil {
+		t.Fatalf("DetectRows failed: %v", err)
+	}
+	if result.Count != 2 {
+		t.Fatalf("Count: got %d, want 2", result.Count)
+	}
+	if got := result.Rows[1].Bounds; got != (Region{X1: 45, Y1: 10, X2: 80, Y2: 18}) {
+		t.Errorf("second column bounds: got %+v", got)
+	}
+	if got := result.Rows[0].Slot; got != (Region{X1: 0, Y1: 5, X2: 42, Y2: 25}) {
+		t.Errorf("first column slot: got %+v", got)
+	}
+}
+
+func TestDetectRows_Errors(t *testing.T) {
+	img := newListImage(nil, 0)
+
+	if _, err := DetectRows(img, nil, "diagonal", 3); err == nil {
+		t.Error("DetectRows should fail with unknown direction")
+	}
+	if _, err := DetectRows(img, nil, "rows", 0); err == nil {
+		t.Error("DetectRows should fail with min gap 0")
+	}
+	if _, err := DetectRows(img, &Region{X1: 0, Y1: 0, X2: 200, Y2: 10}, "rows", 3); err == nil {
+		t.Error("DetectRows should fail with region outside image")
+	}
+
+	result, err := DetectRows(img, nil, "rows", 3)
+	if err != nil {
+		t.Fatalf("DetectRows failed on blank image: %v", err)
+	}
+	if result.Count != 0 || result.Regular {
+		t.Errorf("blank image: got %d rows, regular %v", result.Count, result.Regular)
+	}
+}
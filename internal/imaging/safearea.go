@@ -0,0 +1,77 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// Safe area overlay colors: the area outside the safe area is blended
+// with safeAreaShade, the safe area's edge drawn in safeAreaGuide, and
+// flagged regions outlined in safeAreaFlag.
+var (
+	safeAreaShade = color.NRGBA{R: 0, G: 0, B: 0, A: 110}
+	safeAreaGuide = color.NRGBA{R: 0, G: 200, B: 255, A: 255}
+	safeAreaFlag  = color.NRGBA{R: 255, G: 40, B: 40, A: 255}
+)
+
+// SafeAreaOverlay draws safe area guides on an image, for checking that
+// its key content stays clear of the areas a platform crops or covers.
+//
+// Parameters:
+//   - img: The image.
+//   - safe: The safe area, in image coordinates.
+//   - flagged: Regions to outline, such as content outside the safe area.
+//
+// Returns:
+//   - string: The image, with the area outside safe shaded, the edge of
+//     safe drawn, and flagged outlined, as base64 PNG.
+//   - error: Non-nil if safe is empty or PNG encoding fails.
+//
+// Lines are 2 pixels wide, or more on large images (1/400 of the longer
+// side), so they stay visible when the image is shown scaled down.
+func SafeAreaOverlay(img image.Image, safe Region, flagged []Region) (string, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if safe.X2 <= safe.X1 || safe.Y2 <= safe.Y1 {
+		return "", fmt.Errorf("empty safe area: %+v", safe)
+	}
+	out := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := nrgbaAt(img, b.Min.X+x, b.Min.Y+y)
+			if x < safe.X1 || x >= safe.X2 || y < safe.Y1 || y >= safe.Y2 {
+				c = blendNRGBA(c, safeAreaShade)
+			}
+			out.SetNRGBA(x, y, c)
+		}
+	}
+	width := max(2, max(w, h)/400)
+	outlineRegion(out, safe, width, safeAreaGuide)
+	for _, r := range flagged {
+		outlineRegion(out, r, width, safeAreaFlag)
+	}
+	return encodePNGBase64(out)
+}
+
+// blendNRGBA returns c with over composited on top, keeping c's alpha.
+func blendNRGBA(c, over color.NRGBA) color.NRGBA {
+	a := uint32(over.A)
+	mix := func(under, top uint8) uint8 {
+		return uint8((uint32(under)*(255-a) + uint32(top)*a + 127) / 255)
+	}
+	return color.NRGBA{R: mix(c.R, over.R), G: mix(c.G, over.G), B: mix(c.B, over.B), A: max(c.A, over.A)}
+}
+
+// outlineRegion draws the edge of r on img, width pixels wide on the
+// inside of r, clipped to the image.
+func outlineRegion(img *image.NRGBA, r Region, width int, c color.NRGBA) {
+	bounds := img.Bounds()
+	for y := max(r.Y1, bounds.Min.Y); y < min(r.Y2, bounds.Max.Y); y++ {
+		for x := max(r.X1, bounds.Min.X); x < min(r.X2, bounds.Max.X); x++ {
+			if x < r.X1+width || x >= r.X2-width || y < r.Y1+width || y >= r.Y2-width {
+				img.SetNRGBA(x, y, c)
+			}
+		}
+	}
+}
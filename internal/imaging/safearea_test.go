@@ -0,0 +1,52 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"testing"
+)
+
+func TestSafeAreaOverlay(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	encoded, err := SafeAreaOverlay(img, Region{X1: 20, Y1: 10, X2: 180, Y2: 90}, []Region{{X1: 150, Y1: 40, X2: 195, Y2: 60}})
+	if err != nil {
+		t.Fatalf("SafeAreaOverlay failed: %v", err)
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("invalid base64: %v", err)
+	}
+	out, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("invalid PNG: %v", err)
+	}
+	if out.Bounds() != image.Rect(0, 0, 200, 100) {
+		t.Fatalf("overlay is %v, want 200×100", out.Bounds())
+	}
+
+	for _, tc := range []struct {
+		name    string
+		x, y    int
+		r, g, b uint32
+	}{
+		{"outside, shaded", 5, 5, 145, 145, 145},
+		{"safe area edge", 20, 50, 0, 200, 255},
+		{"inside, unchanged", 100, 50, 255, 255, 255},
+		{"flagged edge", 150, 50, 255, 40, 40},
+		{"flagged edge in the margin", 194, 50, 255, 40, 40},
+	} {
+		r, g, b, _ := out.At(tc.x, tc.y).RGBA()
+		if r>>8 != tc.r || g>>8 != tc.g || b>>8 != tc.b {
+			t.Errorf("%s: pixel %d,%d is %d,%d,%d, want %d,%d,%d", tc.name, tc.x, tc.y, r>>8, g>>8, b>>8, tc.r, tc.g, tc.b)
+		}
+	}
+
+	if _, err := SafeAreaOverlay(img, Region{X1: 50, Y1: 10, X2: 50, Y2: 90}, nil); err == nil {
+		t.Error("expected error for an empty safe area")
+	}
+}
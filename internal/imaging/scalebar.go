@@ -0,0 +1,369 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Scale bar detection: bars are runs of pixels at most scaleBarDark or at
+// least scaleBarLight in luma, at least scaleBarMinLength pixels long and
+// at most scaleBarMaxShare of the image's width. A bar is at least three
+// times as long as it is thick, and its full rows cover
+// scaleBarSpineShare of its length. At most maxScaleBars candidates are
+// listed.
+const (
+	scaleBarDark       = 40
+	scaleBarLight      = 215
+	scaleBarMinLength  = 16
+	scaleBarMaxShare   = 0.6
+	scaleBarSpineShare = 0.9
+	maxScaleBars       = 5
+)
+
+// scaleUnits maps the units a scale bar label may use to their length in
+// meters. The micro sign, the Greek mu, and "u" all read as micrometers.
+var scaleUnits = map[string]float64{
+	"pm": 1e-12,
+	"Å":  1e-10,
+	"nm": 1e-9,
+	"µm": 1e-6,
+	"mm": 1e-3,
+	"cm": 1e-2,
+	"m":  1,
+	"km": 1e3,
+	"in": 0.0254,
+	"ft": 0.3048,
+	"mi": 1609.344,
+}
+
+// scaleLabelPattern matches a length such as "10 µm", "0.5mm", or "1,000 nm".
+var scaleLabelPattern = regexp.MustCompile(`(\d+(?:[.,]\d+)*)\s*(pm|Å|nm|[µμu]m|mm|cm|km|m|in|ft|mi)(?:[^A-Za-z]|$)`)
+
+// ScaleBarOptions configures DetectScaleBars.
+type ScaleBarOptions struct {
+	// Label, if set, is the length of the bar, such as "10 µm", given
+	// rather than read from the image. It applies to the first bar.
+	Label string
+
+	// Read returns the text within a region of the image, such as by OCR,
+	// to find each bar's label; nil reads no labels.
+	Read func(r Region) string
+}
+
+// ScaleCalibration converts pixels to the units of a scale bar.
+type ScaleCalibration struct {
+	// Unit is the unit of the bar's label, such as "µm".
+	Unit string `json:"unit"`
+
+	// UnitsPerPixel is the length one pixel spans, in Unit, and
+	// PixelsPerUnit its inverse.
+	UnitsPerPixel float64 `json:"units_per_pixel"`
+	PixelsPerUnit float64 `json:"pixels_per_unit"`
+
+	// MetersPerPixel is UnitsPerPixel in meters.
+	MetersPerPixel float64 `json:"meters_per_pixel"`
+
+	// Source is the label the calibration comes from.
+	Source string `json:"source"`
+}
+
+// CalibratedDistance is a distance in the units of a ScaleCalibration.
+type CalibratedDistance struct {
+	// Distance is the distance between the points, and DeltaX and DeltaY
+	// its horizontal and vertical components, in Unit.
+	Distance float64 `json:"distance"`
+	DeltaX   float64 `json:"delta_x"`
+	DeltaY   float64 `json:"delta_y"`
+	Unit     string  `json:"unit"`
+}
+
+// Measure converts a displacement of dx by dy pixels to the calibration's
+// units. Values keep four significant digits.
+func (c *ScaleCalibration) Measure(dx, dy int) *CalibratedDistance {
+	return &CalibratedDistance{
+		Distance: significant(math.Hypot(float64(dx), float64(dy))*c.UnitsPerPixel, 4),
+		DeltaX:   significant(float64(dx)*c.UnitsPerPixel, 4),
+		DeltaY:   significant(float64(dy)*c.UnitsPerPixel, 4),
+		Unit:     c.Unit,
+	}
+}
+
+// ScaleBar is a scale bar candidate.
+type ScaleBar struct {
+	// Bounds is the bar's bounding box, end ticks included.
+	Bounds Region `json:"bounds"`
+
+	// LengthPixels is the bar's length: the width of Bounds.
+	LengthPixels int `json:"length_pixels"`
+
+	// ThicknessPixels is the thickness of the bar, without its ticks.
+	ThicknessPixels int `json:"thickness_pixels"`
+
+	// Light is true for a light bar (on a dark image), false for a dark
+	// one.
+	Light bool `json:"light"`
+
+	// Label is the text of the bar's label, and LabelBounds the region it
+	// was read from; empty if none was found.
+	Label       string  `json:"label,omitempty"`
+	LabelBounds *Region `json:"label_bounds,omitempty"`
+
+	// Value and Unit are the length the label gives, such as 10 and "µm".
+	Value float64 `json:"value,omitempty"`
+	Unit  string  `json:"unit,omitempty"`
+}
+
+// ScaleBarResult holds the scale bars found in an image and the
+// calibration of the best one.
+type ScaleBarResult struct {
+	// Found is true if any bar was found.
+	Found bool `json:"found"`
+
+	// ScaleBars are the candidates: those with a label first, then the
+	// lowest in the image, up to 5.
+	ScaleBars []ScaleBar `json:"scale_bars"`
+
+	// Calibration converts pixels to the units of the first bar with a
+	// label; nil if no bar has one.
+	Calibration *ScaleCalibration `json:"calibration,omitempty"`
+
+	// Warnings note bars whose label could not be read.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// DetectScaleBars finds the scale bar of a micrograph, map, or figure and
+// calibrates pixels to its units.
+//
+// Parameters:
+//   - img: The image.
+//   - opts: The bar's length, if known, or how to read labels.
+//
+// Returns:
+//   - *ScaleBarResult: The bars found, and the calibration of the best.
+//   - error: Non-nil if opts.Label is not a length.
+//
+// # Method
+//
+// A scale bar is a solid black or white (at most 40 or at least 215 in
+// luma) horizontal bar, with or without ticks at its ends. Each connected
+// run of such pixels is a candidate if its full rows (90% of its length
+// or more) form one band at most a third of its length thick, and all its
+// other pixels, the ticks, lie within a tenth of its length of either
+// end. Candidates are at least 16 pixels long and at most 60% of the
+// image's width, leaving out frames and axes.
+//
+// The label is sought above the bar, then below, right, and left of it,
+// in a reach of half the bar's length (24 to 120 pixels), as a number
+// followed by a unit of length: pm, Å, nm, µm (or "um"), mm, cm, m, km,
+// in, ft, or mi. The bar's length runs from the outer edges of its ends,
+// so a bar whose ends are antialiased may measure a pixel short.
+func DetectScaleBars(img image.Image, opts ScaleBarOptions) (*ScaleBarResult, error) {
+	var given ScaleBar
+	if opts.Label != "" {
+		value, unit, ok := parseScaleLabel(opts.Label)
+		if !ok {
+			return nil, fmt.Errorf("label %q is not a length such as \"10 µm\"", opts.Label)
+		}
+		given = ScaleBar{Label: opts.Label, Value: value, Unit: unit}
+	}
+
+	b := img.Bounds()
+	bars := scaleBarCandidates(img)
+	for i := range bars {
+		r := &bars[i].Bounds
+		r.X1, r.Y1, r.X2, r.Y2 = r.X1+b.Min.X, r.Y1+b.Min.Y, r.X2+b.Min.X, r.Y2+b.Min.Y
+	}
+	sort.SliceStable(bars, func(i, j int) bool { return bars[i].Bounds.Y2 > bars[j].Bounds.Y2 })
+	if len(bars) > maxScaleBars {
+		bars = bars[:maxScaleBars]
+	}
+
+	result := &ScaleBarResult{ScaleBars: []ScaleBar{}, Found: len(bars) > 0}
+	switch {
+	case len(bars) == 0:
+	case opts.Label != "":
+		given.Bounds, given.LengthPixels = bars[0].Bounds, bars[0].LengthPixels
+		given.ThicknessPixels, given.Light = bars[0].ThicknessPixels, bars[0].Light
+		bars[0] = given
+	case opts.Read != nil:
+		for i := range bars {
+			readScaleLabel(&bars[i], b, opts.Read)
+		}
+		sort.SliceStable(bars, func(i, j int) bool { return bars[i].Unit != "" && bars[j].Unit == "" })
+	}
+	result.ScaleBars = append(result.ScaleBars, bars...)
+
+	if len(bars) > 0 && bars[0].Unit != "" {
+		bar := bars[0]
+		perPixel := bar.Value / float64(bar.LengthPixels)
+		result.Calibration = &ScaleCalibration{
+			Unit:           bar.Unit,
+			UnitsPerPixel:  significant(perPixel, 6),
+			PixelsPerUnit:  significant(1/perPixel, 6),
+			MetersPerPixel: significant(perPixel*scaleUnits[bar.Unit], 6),
+			Source:         bar.Label,
+		}
+	} else if len(bars) > 0 {
+		result.Warnings = append(result.Warnings, "no bar has a readable label such as \"10 µm\"; pass the bar's length as label")
+	}
+	return result, nil
+}
+
+// scaleBarCandidates returns the dark and light bars of img, in
+// coordinates relative to its top left corner.
+func scaleBarCandidates(img image.Image) []ScaleBar {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	lum := make([]uint8, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			lum[y*w+x] = luma(nrgbaAt(img, b.Min.X+x, b.Min.Y+y))
+		}
+	}
+
+	var bars []ScaleBar
+	var pixels, stack []int
+	for _, light := range []bool{false, true} {
+		in := func(i int) bool { return lum[i] <= scaleBarDark }
+		if light {
+			in = func(i int) bool { return lum[i] >= scaleBarLight }
+		}
+		seen := make([]bool, w*h)
+		for start := range lum {
+			if seen[start] || !in(start) {
+				continue
+			}
+			seen[start] = true
+			pixels, stack = pixels[:0], append(stack[:0], start)
+			for len(stack) > 0 {
+				i := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				pixels = append(pixels, i)
+				x := i % w
+				for _, j := range [4]int{i - w, i + w, i - 1, i + 1} {
+					if j < 0 || j >= len(lum) || (j == i-1 && x == 0) || (j == i+1 && x == w-1) {
+						continue
+					}
+					if !seen[j] && in(j) {
+						seen[j] = true
+						stack = append(stack, j)
+					}
+				}
+			}
+			if bar, ok := scaleBarShape(pixels, w); ok && float64(bar.LengthPixels) <= scaleBarMaxShare*float64(w) {
+				bar.Light = light
+				bars = append(bars, bar)
+			}
+		}
+	}
+	return bars
+}
+
+// scaleBarShape reports whether the pixels of a connected run, indices
+// into an image w pixels wide, form a horizontal bar with optional end
+// ticks.
+func scaleBarShape(pixels []int, w int) (ScaleBar, bool) {
+	r := Region{X1: w, Y1: math.MaxInt}
+	for _, i := range pixels {
+		x, y := i%w, i/w
+		r.X1, r.Y1 = min(r.X1, x), min(r.Y1, y)
+		r.X2, r.Y2 = max(r.X2, x+1), max(r.Y2, y+1)
+	}
+	length, height := r.X2-r.X1, r.Y2-r.Y1
+	if length < scaleBarMinLength || height*3 > length*2 {
+		return ScaleBar{}, false
+	}
+	rows := make([]int, height)
+	for _, i := range pixels {
+		rows[i/w-r.Y1]++
+	}
+	first, last := -1, -1
+	for y, n := range rows {
+		if float64(n) >= scaleBarSpineShare*float64(length) {
+			if first < 0 {
+				first = y
+			} else if last != y-1 {
+				return ScaleBar{}, false
+			}
+			last = y
+		}
+	}
+	thickness := last - first + 1
+	if first < 0 || thickness*3 > length {
+		return ScaleBar{}, false
+	}
+	ticks := max(3, length/10)
+	for _, i := range pixels {
+		x, y := i%w, i/w-r.Y1
+		if (y < first || y > last) && x-r.X1 >= ticks && r.X2-1-x >= ticks {
+			return ScaleBar{}, false
+		}
+	}
+	return ScaleBar{Bounds: r, LengthPixels: length, ThicknessPixels: thickness}, true
+}
+
+// readScaleLabel reads the label of bar beside it, within bounds, and
+// sets its Label, LabelBounds, Value, and Unit if one is found.
+func readScaleLabel(bar *ScaleBar, bounds image.Rectangle, read func(Region) string) {
+	r := bar.Bounds
+	length := r.X2 - r.X1
+	reach := max(24, min(120, length/2))
+	mid := (r.Y1 + r.Y2) / 2
+	for _, area := range []Region{
+		{X1: r.X1 - length/4, Y1: r.Y1 - reach, X2: r.X2 + length/4, Y2: r.Y1},
+		{X1: r.X1 - length/4, Y1: r.Y2, X2: r.X2 + length/4, Y2: r.Y2 + reach},
+		{X1: r.X2, Y1: mid - reach/2, X2: r.X2 + 2*length, Y2: mid + reach/2},
+		{X1: r.X1 - 2*length, Y1: mid - reach/2, X2: r.X1, Y2: mid + reach/2},
+	} {
+		area.X1, area.Y1 = max(area.X1, bounds.Min.X), max(area.Y1, bounds.Min.Y)
+		area.X2, area.Y2 = min(area.X2, bounds.Max.X), min(area.Y2, bounds.Max.Y)
+		if area.X2-area.X1 < 8 || area.Y2-area.Y1 < 8 {
+			continue
+		}
+		text := strings.TrimSpace(read(area))
+		if value, unit, ok := parseScaleLabel(text); ok {
+			bar.Label, bar.Value, bar.Unit = text, value, unit
+			bar.LabelBounds = &area
+			return
+		}
+	}
+}
+
+// parseScaleLabel returns the first length in text, such as 10 and "µm"
+// for "10 µm". A comma followed by three digits separates thousands;
+// otherwise it is a decimal point.
+func parseScaleLabel(text string) (float64, string, bool) {
+	m := scaleLabelPattern.FindStringSubmatch(text)
+	if m == nil {
+		return 0, "", false
+	}
+	number := m[1]
+	if i := strings.LastIndex(number, ","); i >= 0 && len(number)-i-1 == 3 {
+		number = strings.ReplaceAll(number, ",", "")
+	} else {
+		number = strings.ReplaceAll(number, ",", ".")
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value <= 0 {
+		return 0, "", false
+	}
+	unit := m[2]
+	if unit == "μm" || unit == "um" {
+		unit = "µm"
+	}
+	return value, unit, true
+}
+
+// significant rounds v to the given number of significant digits.
+func significant(v float64, digits int) float64 {
+	if v == 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+		return v
+	}
+	scale := math.Pow(10, float64(digits)-math.Ceil(math.Log10(math.Abs(v))))
+	return math.Round(v*scale) / scale
+}
@@ -0,0 +1,128 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// micrograph returns a 400x300 mid-gray image with a white scale bar,
+// 80 pixels long with 18-pixel end ticks, at the bottom right, and a
+// white border 2 pixels wide.
+func micrograph() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 300))
+	for y := 0; y < 300; y++ {
+		for x := 0; x < 400; x++ {
+			v := uint8(70 + (x*7+y*13)%90)
+			img.Set(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+	white := image.NewUniform(color.White)
+	for _, r := range []image.Rectangle{
+		image.Rect(0, 0, 400, 2), image.Rect(0, 298, 400, 300), image.Rect(0, 0, 2, 300), image.Rect(398, 0, 400, 300),
+		image.Rect(300, 268, 380, 274), image.Rect(300, 262, 303, 280), image.Rect(377, 262, 380, 280),
+	} {
+		draw.Draw(img, r, white, image.Point{}, draw.Src)
+	}
+	return img
+}
+
+func TestDetectScaleBars(t *testing.T) {
+	var asked []Region
+	read := func(r Region) string {
+		asked = append(asked, r)
+		if r.Y2 == 262 {
+			return "20 µm"
+		}
+		return ""
+	}
+	result, err := DetectScaleBars(micrograph(), ScaleBarOptions{Read: read})
+	if err != nil {
+		t.Fatalf("DetectScaleBars failed: %v", err)
+	}
+	if !result.Found || len(result.ScaleBars) != 1 {
+		t.Fatalf("got %+v, want the bar alone", result)
+	}
+	bar := result.ScaleBars[0]
+	if bar.Bounds != (Region{X1: 300, Y1: 262, X2: 380, Y2: 280}) || bar.LengthPixels != 80 || bar.ThicknessPixels != 6 || !bar.Light {
+		t.Errorf("bar = %+v", bar)
+	}
+	if bar.Value != 20 || bar.Unit != "µm" || *bar.LabelBounds != (Region{X1: 280, Y1: 222, X2: 400, Y2: 262}) || len(asked) != 1 {
+		t.Errorf("label = %+v, read %v", bar, asked)
+	}
+	c := result.Calibration
+	if c == nil || c.Unit != "µm" || c.UnitsPerPixel != 0.25 || c.PixelsPerUnit != 4 || c.MetersPerPixel != 2.5e-7 {
+		t.Fatalf("calibration = %+v", c)
+	}
+	if d := c.Measure(30, -40); d.Distance != 12.5 || d.DeltaX != 7.5 || d.DeltaY != -10 || d.Unit != "µm" {
+		t.Errorf("Measure = %+v", d)
+	}
+}
+
+func TestDetectScaleBars_DarkWithRule(t *testing.T) {
+	// A figure: a black rule under the title, and a plain black bar with
+	// its label below it
+	img := image.NewRGBA(image.Rect(0, 0, 300, 200))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(20, 30, 160, 32), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(40, 120, 140, 124), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	read := func(r Region) string {
+		if r.Y1 == 124 {
+			return "500 nm"
+		}
+		return ""
+	}
+	result, err := DetectScaleBars(img, ScaleBarOptions{Read: read})
+	if err != nil {
+		t.Fatalf("DetectScaleBars failed: %v", err)
+	}
+	if len(result.ScaleBars) != 2 || result.ScaleBars[0].Bounds.Y1 != 120 || result.ScaleBars[0].Light {
+		t.Fatalf("got %+v, want the labeled bar first", result.ScaleBars)
+	}
+	if c := result.Calibration; c == nil || c.UnitsPerPixel != 5 || c.Unit != "nm" || c.Source != "500 nm" {
+		t.Errorf("calibration = %+v", c)
+	}
+
+	// Without labels the lowest bar comes first, uncalibrated
+	result, _ = DetectScaleBars(img, ScaleBarOptions{})
+	if result.Calibration != nil || len(result.Warnings) != 1 || result.ScaleBars[0].Bounds.Y1 != 120 {
+		t.Errorf("got %+v", result)
+	}
+
+	// A given label calibrates the lowest bar
+	result, err = DetectScaleBars(img, ScaleBarOptions{Label: "1 µm"})
+	if err != nil {
+		t.Fatalf("DetectScaleBars failed: %v", err)
+	}
+	if c := result.Calibration; c == nil || c.UnitsPerPixel != 0.01 || c.Unit != "µm" {
+		t.Errorf("calibration = %+v", c)
+	}
+	if _, err := DetectScaleBars(img, ScaleBarOptions{Label: "ten microns"}); err == nil {
+		t.Error("expected error for a label without a length")
+	}
+}
+
+func TestParseScaleLabel(t *testing.T) {
+	for _, tc := range []struct {
+		text  string
+		value float64
+		unit  string
+	}{
+		{"10 µm", 10, "µm"},
+		{"10μm", 10, "µm"},
+		{"100 um", 100, "µm"},
+		{"0.5 mm", 0.5, "mm"},
+		{"2,5 cm", 2.5, "cm"},
+		{"1,000 nm", 1000, "nm"},
+		{"5 Å", 5, "Å"},
+		{"Scale: 2 km\n", 2, "km"},
+		{"8 min", 0, ""},
+		{"x500", 0, ""},
+	} {
+		value, unit, ok := parseScaleLabel(tc.text)
+		if ok != (tc.unit != "") || value != tc.value || unit != tc.unit {
+			t.Errorf("parseScaleLabel(%q) = %g %q %v, want %g %q", tc.text, value, unit, ok, tc.value, tc.unit)
+		}
+	}
+}
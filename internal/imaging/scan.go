@@ -0,0 +1,568 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// Cleanup steps reported by ScanCleanup, in the order they run.
+const (
+	ScanPerspective   = "perspective"
+	ScanShadowRemoval = "shadow_removal"
+	ScanDeskew        = "deskew"
+	ScanBinarize      = "binarize"
+)
+
+// Page detection works on a copy of the photo at most this many pixels
+// on its longer side.
+const scanDetectSize = 400
+
+// scanMinPageArea is the smallest fraction of the photo the page may
+// cover; smaller bright areas are not taken for the page.
+const scanMinPageArea = 0.2
+
+// Deskewing searches angles up to scanMaxSkew degrees either way, first
+// in steps of a degree, then of a tenth around the best, and leaves pages
+// skewed less than a tenth of a degree as they are.
+const scanMaxSkew = 15
+
+// Sauvola binarization parameters (Sauvola and Pietikäinen, 2000): the
+// threshold rises with local contrast by scanSauvolaK against the
+// dynamic range of the standard deviation, scanSauvolaR.
+const (
+	scanSauvolaK = 0.2
+	scanSauvolaR = 128
+)
+
+// ScanCleanupOptions selects the steps ScanCleanup applies.
+type ScanCleanupOptions struct {
+	// Perspective finds the page against the background and warps it to
+	// a rectangle.
+	Perspective bool
+
+	// RemoveShadows evens out the lighting, so shadows and shading across
+	// the page become white paper.
+	RemoveShadows bool
+
+	// Deskew rotates the page so its lines of text are horizontal.
+	Deskew bool
+
+	// Binarize turns the page into black ink on white with a threshold
+	// adapted to each neighborhood.
+	Binarize bool
+
+	// Window is the side in pixels of the neighborhood Binarize adapts
+	// to: an odd number from 3 to 201, larger than the text strokes.
+	Window int
+}
+
+// ScanCleanupResult contains a cleaned-up page encoded as base64 PNG.
+type ScanCleanupResult struct {
+	// Width and Height of the cleaned page in pixels.
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// ImageBase64 is the cleaned page encoded as base64 PNG: grayscale if
+	// binarized, otherwise color.
+	ImageBase64 string `json:"image_base64"`
+
+	// MimeType is always "image/png" for scan cleanup results.
+	MimeType string `json:"mime_type"`
+
+	// Steps lists the steps applied, in order.
+	Steps []string `json:"steps"`
+
+	// Corners are the page corners found in the photo: top-left,
+	// top-right, bottom-right, bottom-left. Empty unless the perspective
+	// was corrected.
+	Corners []Point `json:"corners,omitempty"`
+
+	// SkewAngle is the rotation corrected, in degrees: positive when the
+	// lines of text ran down to the right. 0 unless deskewed.
+	SkewAngle float64 `json:"skew_angle"`
+
+	// Notes explain why requested steps were not applied.
+	Notes []string `json:"notes,omitempty"`
+}
+
+// ScanCleanup turns a photo of a document into a clean, upright page for
+// OCR: it corrects the perspective, removes shadows, straightens the
+// text, and binarizes.
+//
+// Parameters:
+//   - img: Source photo. It is not modified.
+//   - opts: The steps to apply.
+//
+// Returns:
+//   - *ScanCleanupResult: The cleaned page and the corrections applied.
+//   - error: Non-nil if the binarization window is invalid or PNG
+//     encoding fails.
+//
+// # Steps
+//
+// Perspective: the page is taken to be the largest connected area
+// brighter than the Otsu threshold, if it covers at least a fifth of the
+// photo but does not fill it. Its corners are the points of the area
+// furthest toward each corner of the photo, which holds for pages turned
+// up to about 30 degrees, and the page is warped to a rectangle as wide
+// and high as its longer opposite edges.
+//
+// Shadow removal: the paper brightness is estimated as the 90th
+// percentile of the brightness in blocks of about a 40th of the page,
+// smoothed and interpolated, and each pixel is divided by it.
+//
+// Deskew: the angle at which the rows of dark pixels line up best (the
+// projection of the dark pixels onto the vertical axis is most peaked)
+// is found, and the page rotated about its center by it, filling the
+// corners with white.
+//
+// Binarize: each pixel becomes black if its brightness is below the
+// Sauvola threshold of its Window x Window neighborhood, and white
+// otherwise.
+//
+// Steps that do not apply, such as perspective correction of a photo
+// with no background around the page, are skipped with a note.
+func ScanCleanup(img image.Image, opts ScanCleanupOptions) (*ScanCleanupResult, error) {
+	out, result, err := ScanCleanupImage(img, opts)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := encodePNGBase64(out)
+	if err != nil {
+		return nil, err
+	}
+	result.ImageBase64 = encoded
+	return result, nil
+}
+
+// ScanCleanupImage is ScanCleanup returning the cleaned page, with its
+// origin at (0, 0), and a result without the encoded image. The page is
+// an *image.Gray if binarized and an *image.NRGBA otherwise.
+func ScanCleanupImage(img image.Image, opts ScanCleanupOptions) (image.Image, *ScanCleanupResult, error) {
+	if opts.Binarize && (opts.Window < 3 || opts.Window > 201 || opts.Window%2 == 0) {
+		return nil, nil, fmt.Errorf("window must be an odd number from 3 to 201, got %d", opts.Window)
+	}
+	b := img.Bounds()
+	page := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			page.SetNRGBA(x, y, nrgbaAt(img, b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	result := &ScanCleanupResult{MimeType: "image/png", Steps: []string{}}
+
+	if opts.Perspective {
+		if corners, note := findPage(page); corners == nil {
+			result.Notes = append(result.Notes, "perspective: "+note)
+		} else {
+			page = warpPage(page, corners)
+			for _, c := range corners {
+				result.Corners = append(result.Corners, Point{X: int(math.Round(c[0])), Y: int(math.Round(c[1]))})
+			}
+			result.Steps = append(result.Steps, ScanPerspective)
+		}
+	}
+	if opts.RemoveShadows {
+		removeShadows(page)
+		result.Steps = append(result.Steps, ScanShadowRemoval)
+	}
+	if opts.Deskew {
+		if angle, note := skewAngle(page); note != "" {
+			result.Notes = append(result.Notes, "deskew: "+note)
+		} else if math.Abs(angle) >= 0.1 {
+			page = rotatePage(page, angle)
+			result.SkewAngle = angle
+			result.Steps = append(result.Steps, ScanDeskew)
+		}
+	}
+
+	var out image.Image = page
+	if opts.Binarize {
+		out = sauvolaBinarize(page, opts.Window)
+		result.Steps = append(result.Steps, ScanBinarize)
+	}
+	result.Width, result.Height = page.Rect.Dx(), page.Rect.Dy()
+	return out, result, nil
+}
+
+// lumaPlane returns the Rec. 601 luma of every pixel of img.
+func lumaPlane(img *image.NRGBA) []uint8 {
+	lum := make([]uint8, len(img.Pix)/4)
+	for i := range lum {
+		p := img.Pix[4*i : 4*i+4 : 4*i+4]
+		lum[i] = luma(color.NRGBA{p[0], p[1], p[2], p[3]})
+	}
+	return lum
+}
+
+// findPage returns the corners of the page in img, top-left, top-right,
+// bottom-right, bottom-left, or nil and the reason none was found.
+func findPage(img *image.NRGBA) ([][2]float64, string) {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	f := max((max(w, h)+scanDetectSize-1)/scanDetectSize, 1)
+	sw, sh := w/f, h/f
+	if sw < 8 || sh < 8 {
+		return nil, "the photo is too small"
+	}
+
+	// Box-downscaled luma, and the pixels brighter than its Otsu threshold
+	full := lumaPlane(img)
+	small := make([]uint8, sw*sh)
+	for y := 0; y < sh; y++ {
+		for x := 0; x < sw; x++ {
+			sum := 0
+			for dy := 0; dy < f; dy++ {
+				for dx := 0; dx < f; dx++ {
+					sum += int(full[(y*f+dy)*w+x*f+dx])
+				}
+			}
+			small[y*sw+x] = uint8(sum / (f * f))
+		}
+	}
+	t := uint8(otsuThreshold(small))
+
+	// Largest 4-connected bright area
+	label := make([]int32, sw*sh)
+	best, bestSize := int32(0), 0
+	var stack []int
+	next := int32(0)
+	for start := range small {
+		if small[start] < t || label[start] != 0 {
+			continue
+		}
+		next++
+		label[start] = next
+		stack = append(stack[:0], start)
+		size := 0
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			size++
+			x := i % sw
+			for _, j := range [4]int{i - 1, i + 1, i - sw, i + sw} {
+				if (j == i-1 && x == 0) || (j == i+1 && x == sw-1) || j < 0 || j >= sw*sh {
+					continue
+				}
+				if small[j] >= t && label[j] == 0 {
+					label[j] = next
+					stack = append(stack, j)
+				}
+			}
+		}
+		if size > bestSize {
+			best, bestSize = next, size
+		}
+	}
+	if float64(bestSize) < scanMinPageArea*float64(sw*sh) {
+		return nil, "no bright page found against the background"
+	}
+
+	// Extreme points toward each corner
+	var corners [4][2]float64
+	scores := [4]int{math.MaxInt, math.MinInt, math.MinInt, math.MaxInt}
+	minX, minY, maxX, maxY := sw, sh, 0, 0
+	for i, l := range label {
+		if l != best {
+			continue
+		}
+		x, y := i%sw, i/sw
+		minX, minY, maxX, maxY = min(minX, x), min(minY, y), max(maxX, x), max(maxY, y)
+		p := [2]float64{(float64(x) + 0.5) * float64(f), (float64(y) + 0.5) * float64(f)}
+		if s := x + y; s < scores[0] {
+			scores[0], corners[0] = s, p
+		}
+		if s := x - y; s > scores[1] {
+			scores[1], corners[1] = s, p
+		}
+		if s := x + y; s > scores[2] {
+			scores[2], corners[2] = s, p
+		}
+		if s := x - y; s < scores[3] {
+			scores[3], corners[3] = s, p
+		}
+	}
+	if minX == 0 && minY == 0 && maxX == sw-1 && maxY == sh-1 {
+		return nil, "the page fills the photo, so there is no background to find its edges against"
+	}
+
+	// The corners must make a convex quadrilateral of a reasonable size
+	area := 0.0
+	for i := range corners {
+		a, b, c := corners[i], corners[(i+1)%4], corners[(i+2)%4]
+		if (b[0]-a[0])*(c[1]-b[1])-(b[1]-a[1])*(c[0]-b[0]) <= 0 {
+			return nil, "the page outline is not a convex quadrilateral"
+		}
+		area += a[0]*b[1] - b[0]*a[1]
+	}
+	if area/2 < scanMinPageArea*float64(w*h) {
+		return nil, "the page outline is too small"
+	}
+	return corners[:], ""
+}
+
+// warpPage returns the quadrilateral of img with the given corners
+// (top-left, top-right, bottom-right, bottom-left) warped to a rectangle
+// as wide and high as its longer opposite edges.
+func warpPage(img *image.NRGBA, corners [][2]float64) *image.NRGBA {
+	dist := func(a, b [2]float64) float64 { return math.Hypot(a[0]-b[0], a[1]-b[1]) }
+	w := int(math.Round(max(dist(corners[0], corners[1]), dist(corners[3], corners[2]))))
+	h := int(math.Round(max(dist(corners[0], corners[3]), dist(corners[1], corners[2]))))
+	w, h = max(w, 1), max(h, 1)
+	H := homography([4][2]float64{{0, 0}, {float64(w), 0}, {float64(w), float64(h)}, {0, float64(h)}},
+		[4][2]float64{corners[0], corners[1], corners[2], corners[3]})
+
+	out := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			px, py := float64(x)+0.5, float64(y)+0.5
+			d := H[6]*px + H[7]*py + 1
+			sx := (H[0]*px + H[1]*py + H[2]) / d
+			sy := (H[3]*px + H[4]*py + H[5]) / d
+			out.SetNRGBA(x, y, bilinearAt(img, sx-0.5, sy-0.5, color.NRGBA{255, 255, 255, 255}))
+		}
+	}
+	return out
+}
+
+// homography returns the projective transform, as the first 8 entries of
+// a 3x3 matrix whose last is 1, that maps each point of from to the point
+// of to.
+func homography(from, to [4][2]float64) [8]float64 {
+	// Two equations per point pair, solved by Gaussian elimination with
+	// partial pivoting
+	var m [8][9]float64
+	for i := 0; i < 4; i++ {
+		x, y, u, v := from[i][0], from[i][1], to[i][0], to[i][1]
+		m[2*i] = [9]float64{x, y, 1, 0, 0, 0, -u * x, -u * y, u}
+		m[2*i+1] = [9]float64{0, 0, 0, x, y, 1, -v * x, -v * y, v}
+	}
+	for col := 0; col < 8; col++ {
+		pivot := col
+		for r := col + 1; r < 8; r++ {
+			if math.Abs(m[r][col]) > math.Abs(m[pivot][col]) {
+				pivot = r
+			}
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		for r := 0; r < 8; r++ {
+			if r == col || m[col][col] == 0 {
+				continue
+			}
+			k := m[r][col] / m[col][col]
+			for c := col; c < 9; c++ {
+				m[r][c] -= k * m[col][c]
+			}
+		}
+	}
+	var h [8]float64
+	for i := range h {
+		if m[i][i] != 0 {
+			h[i] = m[i][8] / m[i][i]
+		}
+	}
+	return h
+}
+
+// bilinearAt returns the color of img at the fractional pixel position
+// (x, y), interpolated between the four nearest pixels, or fill outside
+// the image.
+func bilinearAt(img *image.NRGBA, x, y float64, fill color.NRGBA) color.NRGBA {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	if x < -0.5 || y < -0.5 || x > float64(w)-0.5 || y > float64(h)-0.5 {
+		return fill
+	}
+	x0, y0 := int(math.Floor(x)), int(math.Floor(y))
+	fx, fy := x-float64(x0), y-float64(y0)
+	var c [4]float64
+	for _, s := range [4]struct {
+		dx, dy int
+		wt     float64
+	}{{0, 0, (1 - fx) * (1 - fy)}, {1, 0, fx * (1 - fy)}, {0, 1, (1 - fx) * fy}, {1, 1, fx * fy}} {
+		i := img.PixOffset(min(max(x0+s.dx, 0), w-1), min(max(y0+s.dy, 0), h-1))
+		for ch := range c {
+			c[ch] += s.wt * float64(img.Pix[i+ch])
+		}
+	}
+	return color.NRGBA{uint8(math.Round(c[0])), uint8(math.Round(c[1])), uint8(math.Round(c[2])), uint8(math.Round(c[3]))}
+}
+
+// removeShadows divides img, in place, by its estimated paper brightness.
+func removeShadows(img *image.NRGBA) {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	block := max(max(w, h)/40, 8)
+	gw, gh := (w+block-1)/block, (h+block-1)/block
+	lum := lumaPlane(img)
+
+	// 90th percentile brightness of each block
+	grid := make([]float64, gw*gh)
+	var hist [256]int
+	for gy := 0; gy < gh; gy++ {
+		for gx := 0; gx < gw; gx++ {
+			hist = [256]int{}
+			n := 0
+			for y := gy * block; y < min((gy+1)*block, h); y++ {
+				for x := gx * block; x < min((gx+1)*block, w); x++ {
+					hist[lum[y*w+x]]++
+					n++
+				}
+			}
+			v, count := 255, hist[255]
+			for count < n/10 && v > 0 {
+				v--
+				count += hist[v]
+			}
+			grid[gy*gw+gx] = float64(v)
+		}
+	}
+
+	// Smooth over each block's neighbors, then interpolate between block
+	// centers
+	smooth := make([]float64, len(grid))
+	for gy := 0; gy < gh; gy++ {
+		for gx := 0; gx < gw; gx++ {
+			sum, n := 0.0, 0
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					if x, y := gx+dx, gy+dy; x >= 0 && x < gw && y >= 0 && y < gh {
+						sum += grid[y*gw+x]
+						n++
+					}
+				}
+			}
+			smooth[gy*gw+gx] = sum / float64(n)
+		}
+	}
+	for y := 0; y < h; y++ {
+		fy := min(max((float64(y)+0.5)/float64(block)-0.5, 0), float64(gh-1))
+		y0 := min(int(fy), max(gh-2, 0))
+		ty := fy - float64(y0)
+		y1 := min(y0+1, gh-1)
+		for x := 0; x < w; x++ {
+			fx := min(max((float64(x)+0.5)/float64(block)-0.5, 0), float64(gw-1))
+			x0 := min(int(fx), max(gw-2, 0))
+			tx := fx - float64(x0)
+			x1 := min(x0+1, gw-1)
+			bg := (1-ty)*((1-tx)*smooth[y0*gw+x0]+tx*smooth[y0*gw+x1]) + ty*((1-tx)*smooth[y1*gw+x0]+tx*smooth[y1*gw+x1])
+			scale := 255 / max(bg, 16)
+			i := img.PixOffset(x, y)
+			for ch := 0; ch < 3; ch++ {
+				img.Pix[i+ch] = uint8(math.Round(min(float64(img.Pix[i+ch])*scale, 255)))
+			}
+		}
+	}
+}
+
+// skewAngle returns the angle in degrees at which the dark pixels of img
+// line up in rows best, or a reason the page cannot be deskewed.
+func skewAngle(img *image.NRGBA) (float64, string) {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	lum := lumaPlane(img)
+	t := uint8(otsuThreshold(lum))
+	var dark [][2]float64
+	for i, v := range lum {
+		if v < t {
+			dark = append(dark, [2]float64{float64(i%w) - float64(w)/2, float64(i/w) - float64(h)/2})
+		}
+	}
+	if len(dark) < 50 || len(dark) > len(lum)/2 {
+		return 0, "no lines of text found"
+	}
+	// At most 40000 dark pixels, evenly picked, are enough
+	if step := (len(dark) + 39999) / 40000; step > 1 {
+		picked := dark[:0]
+		for i := 0; i < len(dark); i += step {
+			picked = append(picked, dark[i])
+		}
+		dark = picked
+	}
+
+	span := int(math.Hypot(float64(w), float64(h))) + 2
+	bins := make([]int, span)
+	score := func(deg float64) float64 {
+		clear(bins)
+		sin, cos := math.Sincos(deg * math.Pi / 180)
+		for _, p := range dark {
+			bins[int(p[1]*cos-p[0]*sin+float64(span)/2)]++
+		}
+		s := 0.0
+		for _, n := range bins {
+			s += float64(n) * float64(n)
+		}
+		return s
+	}
+	best, bestScore := 0.0, score(0)
+	for deg := -scanMaxSkew; deg <= scanMaxSkew; deg++ {
+		if s := score(float64(deg)); s > bestScore {
+			best, bestScore = float64(deg), s
+		}
+	}
+	coarse := best
+	for tenth := -10; tenth <= 10; tenth++ {
+		deg := coarse + float64(tenth)/10
+		if s := score(deg); s > bestScore {
+			best, bestScore = deg, s
+		}
+	}
+	return math.Round(best*10) / 10, ""
+}
+
+// rotatePage returns img rotated about its center so lines at angle
+// degrees become horizontal, with the uncovered corners white.
+func rotatePage(img *image.NRGBA, angle float64) *image.NRGBA {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	cx, cy := float64(w)/2, float64(h)/2
+	out := image.NewNRGBA(img.Rect)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dx, dy := float64(x)+0.5-cx, float64(y)+0.5-cy
+			sx := cx + dx*cos - dy*sin
+			sy := cy + dx*sin + dy*cos
+			out.SetNRGBA(x, y, bilinearAt(img, sx-0.5, sy-0.5, color.NRGBA{255, 255, 255, 255}))
+		}
+	}
+	return out
+}
+
+// sauvolaBinarize returns img as black ink on white, thresholding each
+// pixel by Sauvola's method over a window x window neighborhood.
+func sauvolaBinarize(img *image.NRGBA, window int) *image.Gray {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	lum := lumaPlane(img)
+
+	// Integral images of brightness and squared brightness, with a zero
+	// row and column in front
+	sum := make([]float64, (w+1)*(h+1))
+	sq := make([]float64, (w+1)*(h+1))
+	for y := 0; y < h; y++ {
+		var rowSum, rowSq float64
+		for x := 0; x < w; x++ {
+			v := float64(lum[y*w+x])
+			rowSum += v
+			rowSq += v * v
+			i := (y+1)*(w+1) + x + 1
+			sum[i] = sum[i-w-1] + rowSum
+			sq[i] = sq[i-w-1] + rowSq
+		}
+	}
+
+	out := image.NewGray(image.Rect(0, 0, w, h))
+	r := window / 2
+	for y := 0; y < h; y++ {
+		y0, y1 := max(y-r, 0), min(y+r+1, h)
+		for x := 0; x < w; x++ {
+			x0, x1 := max(x-r, 0), min(x+r+1, w)
+			n := float64((x1 - x0) * (y1 - y0))
+			a, b, c, d := y0*(w+1)+x0, y0*(w+1)+x1, y1*(w+1)+x0, y1*(w+1)+x1
+			mean := (sum[d] - sum[b] - sum[c] + sum[a]) / n
+			variance := (sq[d]-sq[b]-sq[c]+sq[a])/n - mean*mean
+			threshold := mean * (1 + scanSauvolaK*(math.Sqrt(max(variance, 0))/scanSauvolaR-1))
+			if float64(lum[y*w+x]) > threshold {
+				out.Pix[y*out.Stride+x] = 255
+			}
+		}
+	}
+	return out
+}
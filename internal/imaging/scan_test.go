@@ -0,0 +1,122 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"slices"
+	"testing"
+)
+
+// pagePhoto returns a 400x400 photo of a 240x320 page of text turned by
+// turn degrees on a dark desk, shaded from left to right. Lines of text
+// are 6 pixels high, every 24 pixels from y = 40 of the page.
+func pagePhoto(turn float64) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 400, 400))
+	sin, cos := math.Sincos(turn * math.Pi / 180)
+	for y := 0; y < 400; y++ {
+		for x := 0; x < 400; x++ {
+			// Page coordinates of the photo pixel
+			dx, dy := float64(x)+0.5-200, float64(y)+0.5-200
+			px := dx*cos + dy*sin + 120
+			py := -dx*sin + dy*cos + 160
+			c := color.NRGBA{50, 45, 40, 255}
+			if px >= 0 && px < 240 && py >= 0 && py < 320 {
+				c = color.NRGBA{235, 230, 220, 255}
+				if px >= 20 && px < 220 && py >= 40 && py < 280 && int(py-40)%24 < 6 {
+					c = color.NRGBA{30, 30, 40, 255}
+				}
+			}
+			shade := 1 - 0.45*float64(x)/400
+			c.R, c.G, c.B = uint8(float64(c.R)*shade), uint8(float64(c.G)*shade), uint8(float64(c.B)*shade)
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// skewedText returns a 300x300 page of text, filling the image, whose
+// lines run down to the right at angle degrees.
+func skewedText(angle float64) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 300, 300))
+	tan := math.Tan(angle * math.Pi / 180)
+	for y := 0; y < 300; y++ {
+		for x := 0; x < 300; x++ {
+			c := color.NRGBA{240, 240, 240, 255}
+			v := float64(y) - (float64(x)-150)*tan
+			if x >= 30 && x < 270 && v >= 40 && v < 260 && int(v-40)%20 < 5 {
+				c = color.NRGBA{20, 20, 20, 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+var allScanSteps = ScanCleanupOptions{Perspective: true, RemoveShadows: true, Deskew: true, Binarize: true, Window: 31}
+
+func TestScanCleanup(t *testing.T) {
+	result, err := ScanCleanup(pagePhoto(6), allScanSteps)
+	if err != nil {
+		t.Fatalf("ScanCleanup failed: %v", err)
+	}
+	if !slices.Contains(result.Steps, ScanPerspective) || !slices.Contains(result.Steps, ScanBinarize) || len(result.Corners) != 4 || result.ImageBase64 == "" {
+		t.Fatalf("got %+v", result)
+	}
+	if math.Abs(float64(result.Width-240)) > 6 || math.Abs(float64(result.Height-320)) > 6 {
+		t.Errorf("got %dx%d, want about 240x320", result.Width, result.Height)
+	}
+	// The page's top-left corner, turned 6 degrees clockwise about the
+	// photo's center
+	if c := result.Corners[0]; math.Abs(float64(c.X)-97) > 4 || math.Abs(float64(c.Y)-28) > 4 {
+		t.Errorf("top-left corner: got %v", c)
+	}
+
+	// Lines of text are black and the gaps white, in the light and in the
+	// shade
+	out, _, _ := ScanCleanupImage(pagePhoto(6), allScanSteps)
+	page := out.(*image.Gray)
+	for _, x := range []int{40, 120, 200} {
+		for line := 0; line < 10; line++ {
+			if v := page.GrayAt(x, 43+24*line).Y; v != 0 {
+				t.Errorf("line %d at x %d: got %d, want black", line, x, v)
+			}
+			if v := page.GrayAt(x, 55+24*line).Y; v != 255 {
+				t.Errorf("gap %d at x %d: got %d, want white", line, x, v)
+			}
+		}
+	}
+}
+
+func TestScanCleanup_Deskew(t *testing.T) {
+	out, result, err := ScanCleanupImage(skewedText(4), allScanSteps)
+	if err != nil {
+		t.Fatalf("ScanCleanupImage failed: %v", err)
+	}
+	// The page fills the photo: no perspective to correct
+	if result.Corners != nil || len(result.Notes) != 1 || math.Abs(result.SkewAngle-4) > 0.3 {
+		t.Fatalf("got %+v", result)
+	}
+	if !slices.Equal(result.Steps, []string{ScanShadowRemoval, ScanDeskew, ScanBinarize}) {
+		t.Errorf("steps: got %v", result.Steps)
+	}
+
+	// The middle line of text is level again
+	page := out.(*image.Gray)
+	for _, x := range []int{60, 150, 240} {
+		if v := page.GrayAt(x, 142).Y; v != 0 {
+			t.Errorf("x %d: got %d, want black", x, v)
+		}
+	}
+
+	// Without binarization, the page stays in color
+	opts := allScanSteps
+	opts.Binarize = false
+	if out, _, _ := ScanCleanupImage(skewedText(4), opts); out.ColorModel() != color.NRGBAModel {
+		t.Errorf("got %T", out)
+	}
+	opts.Binarize, opts.Window = true, 30
+	if _, err := ScanCleanup(skewedText(4), opts); err == nil {
+		t.Error("expected error for an even window")
+	}
+}
@@ -0,0 +1,336 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"sort"
+)
+
+// maxSegmentRegions limits how many regions SegmentRegions will return.
+const maxSegmentRegions = 500
+
+// maxColorDistance is the largest RGB distance between two 8-bit colors,
+// rounded up: √(3 × 255²).
+const maxColorDistance = 442
+
+// Segment is one region found by SegmentRegions.
+type Segment struct {
+	// ID is the region's index in SegmentResult.Regions.
+	ID int `json:"id"`
+
+	// Bounds is the region's bounding box.
+	Bounds Region `json:"bounds"`
+
+	// Area is the number of pixels in the region.
+	Area int `json:"area"`
+
+	// AreaPercent is the area as a percentage of the image.
+	AreaPercent float64 `json:"area_percent"`
+
+	// Centroid is the mean position of the region's pixels. It can fall
+	// outside a region that is not convex.
+	Centroid Point `json:"centroid"`
+
+	// Color is the region's average color as hex (#RRGGBB).
+	Color string `json:"color"`
+
+	// RGB is the region's average color.
+	RGB RGBColor `json:"rgb"`
+
+	// Neighbors lists the IDs of the returned regions that share a border
+	// with this one.
+	Neighbors []int `json:"neighbors"`
+}
+
+// SegmentResult contains the regions an image was segmented into.
+type SegmentResult struct {
+	// Regions lists the largest regions, largest first.
+	Regions []Segment `json:"regions"`
+
+	// Count is the number of regions returned.
+	Count int `json:"count"`
+
+	// TotalRegions is the number of regions found, including those beyond
+	// the requested maximum.
+	TotalRegions int `json:"total_regions"`
+
+	// ImageBase64 shows every region filled with its average color, as
+	// base64 PNG. Only set when requested.
+	ImageBase64 string `json:"image_base64,omitempty"`
+
+	// MimeType is "image/png" when ImageBase64 is set.
+	MimeType string `json:"mime_type,omitempty"`
+}
+
+// SegmentRegions divides an image into regions of similar color, such as
+// the slices of a pie chart or the areas of a map.
+//
+// Parameters:
+//   - img: Source image.
+//   - scale: How large regions tend to be. Higher values merge more
+//     aggressively across weak color boundaries. Typical value: 300.
+//   - minSize: Regions smaller than this many pixels are merged into a
+//     neighbor.
+//   - maxRegions: Maximum number of regions to return (1-500).
+//   - includeImage: Also return the segmentation as an image.
+//
+// Returns:
+//   - *SegmentResult: The largest regions, with bounds, area, centroid,
+//     average color, and neighbors.
+//   - error: Non-nil if a parameter is out of range or PNG encoding fails.
+//
+// # Algorithm
+//
+// The image is segmented with the graph-based method of Felzenszwalb and
+// Huttenlocher (2004). Each pixel is a node, joined to its right and lower
+// neighbors by an edge weighted with their RGB distance. Edges are taken
+// in order of increasing weight, and the two regions an edge joins are
+// merged unless the edge is heavier than the heaviest edge already inside
+// either region plus scale divided by that region's size. Uniform areas
+// thus grow until they meet a boundary stronger than their internal
+// variation, while small regions need a strong boundary to survive.
+// Finally, regions below minSize are merged across their weakest edges.
+//
+// Regions are 4-connected. Time is O(width × height); edge weights are
+// bucket-sorted.
+func SegmentRegions(img image.Image, scale float64, minSize, maxRegions int, includeImage bool) (*SegmentResult, error) {
+	if scale <= 0 {
+		return nil, fmt.Errorf("scale must be positive, got %v", scale)
+	}
+	if minSize < 1 {
+		return nil, fmt.Errorf("min_size must be at least 1, got %d", minSize)
+	}
+	if maxRegions < 1 || maxRegions > maxSegmentRegions {
+		return nil, fmt.Errorf("max_regions must be between 1 and %d, got %d", maxSegmentRegions, maxRegions)
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	n := w * h
+	pix := make([]color.RGBA, n)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, bl, _ := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			pix[y*w+x] = color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(bl >> 8), 255}
+		}
+	}
+
+	edges := sortedEdges(pix, w, h)
+	uf := newSegmentForest(n)
+	for _, e := range edges {
+		a, c := uf.find(int(e.a)), uf.find(int(e.b))
+		if a == c {
+			continue
+		}
+		weight := float64(e.weight)
+		if weight <= uf.internal[a]+scale/float64(uf.size[a]) && weight <= uf.internal[c]+scale/float64(uf.size[c]) {
+			uf.union(a, c, weight)
+		}
+	}
+	for _, e := range edges {
+		a, c := uf.find(int(e.a)), uf.find(int(e.b))
+		if a != c && (uf.size[a] < int32(minSize) || uf.size[c] < int32(minSize)) {
+			uf.union(a, c, float64(e.weight))
+		}
+	}
+
+	// Gather the regions in raster order of their first pixel
+	type stats struct {
+		area                int
+		r, g, b, sumX, sumY int
+		bounds              Region
+	}
+	index := make([]int32, n)
+	for i := range index {
+		index[i] = -1
+	}
+	label := make([]int32, n)
+	var regions []stats
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*w + x
+			root := uf.find(i)
+			if index[root] < 0 {
+				index[root] = int32(len(regions))
+				regions = append(regions, stats{bounds: Region{X1: x, Y1: y, X2: x + 1, Y2: y + 1}})
+			}
+			k := index[root]
+			label[i] = k
+			s := &regions[k]
+			p := pix[i]
+			s.area++
+			s.r, s.g, s.b = s.r+int(p.R), s.g+int(p.G), s.b+int(p.B)
+			s.sumX, s.sumY = s.sumX+x, s.sumY+y
+			s.bounds.X1, s.bounds.X2 = min(s.bounds.X1, x), max(s.bounds.X2, x+1)
+			s.bounds.Y2 = y + 1
+		}
+	}
+
+	// Largest first; the sort is stable, so ties stay in raster order
+	order := make([]int, len(regions))
+	for k := range order {
+		order[k] = k
+	}
+	sort.SliceStable(order, func(i, j int) bool { return regions[order[i]].area > regions[order[j]].area })
+	rank := make([]int, len(regions))
+	for id, k := range order {
+		rank[k] = id
+	}
+
+	kept := min(len(regions), maxRegions)
+	result := &SegmentResult{
+		Regions:      make([]Segment, kept),
+		Count:        kept,
+		TotalRegions: len(regions),
+	}
+	colors := make([]color.RGBA, len(regions))
+	for k, s := range regions {
+		c := color.RGBA{uint8(s.r / s.area), uint8(s.g / s.area), uint8(s.b / s.area), 255}
+		colors[k] = c
+		id := rank[k]
+		if id >= kept {
+			continue
+		}
+		result.Regions[id] = Segment{
+			ID: id,
+			Bounds: Region{
+				X1: b.Min.X + s.bounds.X1, Y1: b.Min.Y + s.bounds.Y1,
+				X2: b.Min.X + s.bounds.X2, Y2: b.Min.Y + s.bounds.Y2,
+			},
+			Area:        s.area,
+			AreaPercent: math.Round(float64(s.area)/float64(n)*10000) / 100,
+			Centroid: Point{
+				X: b.Min.X + int(math.Round(float64(s.sumX)/float64(s.area))),
+				Y: b.Min.Y + int(math.Round(float64(s.sumY)/float64(s.area))),
+			},
+			Color:     fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B),
+			RGB:       RGBColor{R: c.R, G: c.G, B: c.B},
+			Neighbors: []int{},
+		}
+	}
+
+	adjacent := make(map[[2]int]bool)
+	for _, e := range edges {
+		p, q := rank[label[e.a]], rank[label[e.b]]
+		if p != q && p < kept && q < kept {
+			adjacent[[2]int{min(p, q), max(p, q)}] = true
+		}
+	}
+	for pair := range adjacent {
+		result.Regions[pair[0]].Neighbors = append(result.Regions[pair[0]].Neighbors, pair[1])
+		result.Regions[pair[1]].Neighbors = append(result.Regions[pair[1]].Neighbors, pair[0])
+	}
+	for i := range result.Regions {
+		sort.Ints(result.Regions[i].Neighbors)
+	}
+
+	if includeImage {
+		out := image.NewRGBA(image.Rect(0, 0, w, h))
+		for i, k := range label {
+			out.SetRGBA(i%w, i/w, colors[k])
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, out); err != nil {
+			return nil, fmt.Errorf("failed to encode segmentation image: %w", err)
+		}
+		result.ImageBase64 = base64.StdEncoding.EncodeToString(buf.Bytes())
+		result.MimeType = "image/png"
+	}
+
+	return result, nil
+}
+
+// segmentEdge joins two 4-adjacent pixels.
+type segmentEdge struct {
+	a, b   int32
+	weight uint16
+}
+
+// sortedEdges returns the edges between each pixel and its right and lower
+// neighbors, ordered by increasing RGB distance rounded to an integer.
+func sortedEdges(pix []color.RGBA, w, h int) []segmentEdge {
+	var counts [maxColorDistance + 2]int
+	var edges []segmentEdge
+	add := func(i, j int) {
+		p, q := pix[i], pix[j]
+		dr, dg, db := float64(p.R)-float64(q.R), float64(p.G)-float64(q.G), float64(p.B)-float64(q.B)
+		d := uint16(math.Round(math.Sqrt(dr*dr + dg*dg + db*db)))
+		edges = append(edges, segmentEdge{a: int32(i), b: int32(j), weight: d})
+		counts[d+1]++
+	}
+	edges = make([]segmentEdge, 0, 2*w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*w + x
+			if x+1 < w {
+				add(i, i+1)
+			}
+			if y+1 < h {
+				add(i, i+w)
+			}
+		}
+	}
+
+	// Counting sort by weight
+	for d := 1; d < len(counts); d++ {
+		counts[d] += counts[d-1]
+	}
+	sorted := make([]segmentEdge, len(edges))
+	for _, e := range edges {
+		sorted[counts[e.weight]] = e
+		counts[e.weight]++
+	}
+	return sorted
+}
+
+// segmentForest is a union-find forest over pixels that tracks each
+// region's size and heaviest internal edge.
+type segmentForest struct {
+	parent   []int32
+	size     []int32
+	internal []float64
+}
+
+func newSegmentForest(n int) *segmentForest {
+	f := &segmentForest{
+		parent:   make([]int32, n),
+		size:     make([]int32, n),
+		internal: make([]float64, n),
+	}
+	for i := range f.parent {
+		f.parent[i] = int32(i)
+		f.size[i] = 1
+	}
+	return f
+}
+
+// find returns the root of i's region, compressing the path.
+func (f *segmentForest) find(i int) int {
+	root := i
+	for int(f.parent[root]) != root {
+		root = int(f.parent[root])
+	}
+	for int(f.parent[i]) != root {
+		next := int(f.parent[i])
+		f.parent[i] = int32(root)
+		i = next
+	}
+	return root
+}
+
+// union merges the regions rooted at a and c across an edge of the given
+// weight. Edges arrive in increasing weight, so it is the heaviest edge
+// inside the merged region.
+func (f *segmentForest) union(a, c int, weight float64) {
+	if f.size[a] < f.size[c] {
+		a, c = c, a
+	}
+	f.parent[c] = int32(a)
+	f.size[a] += f.size[c]
+	f.internal[a] = weight
+}
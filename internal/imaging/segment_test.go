@@ -0,0 +1,86 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+func TestSegmentRegions(t *testing.T) {
+	// A map of three countries; the red one has a small town in it
+	img := image.NewRGBA(image.Rect(0, 0, 100, 60))
+	fillRect(img, 0, 0, 50, 60, color.RGBA{200, 30, 30, 255})
+	fillRect(img, 50, 0, 100, 20, color.RGBA{30, 30, 200, 255})
+	fillRect(img, 50, 20, 100, 60, color.RGBA{30, 160, 30, 255})
+	fillRect(img, 20, 20, 23, 23, color.Black)
+
+	result, err := SegmentRegions(img, 300, 20, 10, true)
+	if err != nil {
+		t.Fatalf("SegmentRegions failed: %v", err)
+	}
+	if result.Count != 3 || result.TotalRegions != 3 || result.ImageBase64 == "" || result.MimeType != "image/png" {
+		t.Fatalf("got %d of %d regions: %+v", result.Count, result.TotalRegions, result.Regions)
+	}
+
+	red, green, blue := result.Regions[0], result.Regions[1], result.Regions[2]
+	if red.Area != 3000 || red.Bounds != (Region{X1: 0, Y1: 0, X2: 50, Y2: 60}) || red.AreaPercent != 50 {
+		t.Errorf("red: got %+v", red)
+	}
+	// The town is merged into the red region and darkens its average
+	if red.RGB.R >= 200 || red.RGB.R < 190 || red.Centroid.X < 23 || red.Centroid.X > 25 {
+		t.Errorf("red color and centroid: got %+v", red)
+	}
+	if green.Color != "#1EA01E" || green.Area != 2000 || green.Centroid != (Point{X: 75, Y: 40}) {
+		t.Errorf("green: got %+v", green)
+	}
+	if blue.Color != "#1E1EC8" || blue.Bounds != (Region{X1: 50, Y1: 0, X2: 100, Y2: 20}) {
+		t.Errorf("blue: got %+v", blue)
+	}
+	for _, r := range result.Regions {
+		want := []int{}
+		for id := 0; id < 3; id++ {
+			if id != r.ID {
+				want = append(want, id)
+			}
+		}
+		if !reflect.DeepEqual(r.Neighbors, want) {
+			t.Errorf("region %d neighbors: got %v, want %v", r.ID, r.Neighbors, want)
+		}
+	}
+
+	// With a small minimum the town is its own region
+	result, err = SegmentRegions(img, 300, 1, 2, false)
+	if err != nil {
+		t.Fatalf("SegmentRegions failed: %v", err)
+	}
+	if result.TotalRegions != 4 || result.Count != 2 || result.ImageBase64 != "" {
+		t.Errorf("got %d of %d regions", result.Count, result.TotalRegions)
+	}
+	if !reflect.DeepEqual(result.Regions[0].Neighbors, []int{1}) {
+		t.Errorf("neighbors should only name returned regions: got %v", result.Regions[0].Neighbors)
+	}
+
+	// A gentle gradient is one region
+	gradient := image.NewRGBA(image.Rect(0, 0, 128, 20))
+	for x := 0; x < 128; x++ {
+		fillRect(gradient, x, 0, x+1, 20, color.Gray{uint8(64 + x)})
+	}
+	result, err = SegmentRegions(gradient, 300, 20, 10, false)
+	if err != nil {
+		t.Fatalf("SegmentRegions failed: %v", err)
+	}
+	if result.TotalRegions != 1 {
+		t.Errorf("gradient: got %d regions, want 1", result.TotalRegions)
+	}
+
+	for name, args := range map[string][3]float64{
+		"scale":       {0, 20, 10},
+		"min size":    {300, 0, 10},
+		"max regions": {300, 20, 501},
+	} {
+		if _, err := SegmentRegions(img, args[0], int(args[1]), int(args[2]), false); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,611 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+)
+
+// Node types in a SkeletonGraph.
+const (
+	SkeletonEndpoint = "endpoint" // The free end of a stroke
+	SkeletonJunction = "junction" // Where three or more strokes meet
+	SkeletonLoop     = "loop"     // An arbitrary point on a closed stroke with no other nodes
+)
+
+// maxSkeletonGraphNodes caps the nodes of a SkeletonGraph. Line art has far
+// fewer; more usually means a photograph or a bad threshold.
+const maxSkeletonGraphNodes = 5000
+
+// skeletonPathTolerance is how far, in pixels, a simplified edge path may
+// stray from the traced skeleton.
+const skeletonPathTolerance = 1.0
+
+// SkeletonResult contains the 1-pixel-wide skeleton of an image's strokes.
+type SkeletonResult struct {
+	// Width of the output image in pixels (same as input).
+	Width int `json:"width"`
+
+	// Height of the output image in pixels (same as input).
+	Height int `json:"height"`
+
+	// Threshold is the luminance (0-255) that separated strokes from the
+	// background: pixels darker than it are strokes, or, when inverted,
+	// pixels at least as bright.
+	Threshold int `json:"threshold"`
+
+	// StrokePixels is the number of pixels classified as strokes.
+	StrokePixels int `json:"stroke_pixels"`
+
+	// SkeletonPixels is the number of pixels left after thinning.
+	SkeletonPixels int `json:"skeleton_pixels"`
+
+	// Endpoints are the skeleton pixels with exactly one neighbor, in
+	// raster order.
+	Endpoints []Point `json:"endpoints"`
+
+	// Junctions are the points where three or more strokes meet, one per
+	// cluster of adjacent branching pixels, in raster order.
+	Junctions []Point `json:"junctions"`
+
+	// Graph connects the endpoints and junctions along the skeleton. Only
+	// set when requested.
+	Graph *SkeletonGraph `json:"graph,omitempty"`
+
+	// ImageBase64 is the skeleton encoded as base64 PNG: white (255) on
+	// black.
+	ImageBase64 string `json:"image_base64"`
+
+	// MimeType is always "image/png".
+	MimeType string `json:"mime_type"`
+}
+
+// SkeletonGraph is the skeleton as a graph of strokes between nodes.
+type SkeletonGraph struct {
+	// Nodes are the endpoints and junctions, plus one node on each closed
+	// stroke that has neither.
+	Nodes []SkeletonNode `json:"nodes"`
+
+	// Edges are the strokes between nodes.
+	Edges []SkeletonEdge `json:"edges"`
+}
+
+// SkeletonNode is an endpoint, junction, or loop node of a SkeletonGraph.
+type SkeletonNode struct {
+	// ID is the node's index in SkeletonGraph.Nodes.
+	ID int `json:"id"`
+
+	// Type is "endpoint", "junction", or "loop".
+	Type string `json:"type"`
+
+	// Point is the node's position; for a junction, the branching pixel
+	// nearest the center of its cluster.
+	Point Point `json:"point"`
+
+	// Degree is the number of edge ends at the node; a loop edge counts
+	// twice.
+	Degree int `json:"degree"`
+}
+
+// SkeletonEdge is one stroke of a SkeletonGraph.
+type SkeletonEdge struct {
+	// From and To are node IDs. They are equal for a closed stroke.
+	From int `json:"from"`
+	To   int `json:"to"`
+
+	// Length is the length of the traced stroke in pixels, counting
+	// diagonal steps as √2.
+	Length float64 `json:"length"`
+
+	// Path is the stroke as a polyline from node From to node To,
+	// simplified to within 1 pixel of the skeleton.
+	Path []Point `json:"path"`
+}
+
+// Skeletonize reduces the strokes of line art to 1-pixel-wide skeletons
+// and finds their endpoints and junctions, as a basis for tracing
+// connectors between the shapes of a diagram.
+//
+// Parameters:
+//   - img: Source image.
+//   - threshold: Luminance (1-255) below which pixels are strokes, or 0 to
+//     choose it with Otsu's method.
+//   - invert: Treat bright pixels as strokes, for light drawings on dark
+//     backgrounds.
+//   - graph: Also trace the skeleton into a graph of nodes and edges.
+//
+// Returns:
+//   - *SkeletonResult: The skeleton image, endpoints, junctions, and
+//     optional graph.
+//   - error: Non-nil if threshold is out of range, if the graph would
+//     have more than 5000 nodes, or if PNG encoding fails.
+//
+// # Algorithm
+//
+//  1. Binarize: luminance (ITU-R BT.601 weights) against the threshold.
+//
+//  2. Thin: Zhang-Suen thinning, then removal of the remaining pixels
+//     whose deletion changes neither connectivity nor stroke ends, which
+//     leaves strokes exactly 1 pixel wide with 8-connected steps.
+//
+//  3. Classify: a skeleton pixel with one neighbor is an endpoint; one
+//     with three or more is branching, and adjacent branching pixels form
+//     one junction.
+//
+//  4. Trace (graph only): follow the strokes from every node to the next,
+//     then add a loop node on each closed stroke not yet visited.
+//
+// Isolated single pixels, such as dots, remain in the skeleton image but
+// are neither endpoints nor graph nodes.
+func Skeletonize(img image.Image, threshold int, invert, graph bool) (*SkeletonResult, error) {
+	if threshold < 0 || threshold > 255 {
+		return nil, fmt.Errorf("threshold must be 0 (automatic) to 255, got %d", threshold)
+	}
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	lum := make([]uint8, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			lum[y*w+x] = uint8(math.Round((0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 257))
+		}
+	}
+	if threshold == 0 {
+		threshold = otsuThreshold(lum)
+	}
+
+	sk := newSkeleton(w, h)
+	strokes := 0
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if (lum[y*w+x] < uint8(threshold)) != invert {
+				sk.pix[sk.index(x, y)] = 1
+				strokes++
+			}
+		}
+	}
+	sk.thin()
+
+	result := &SkeletonResult{
+		Width:        w,
+		Height:       h,
+		Threshold:    threshold,
+		StrokePixels: strokes,
+		Endpoints:    []Point{},
+		Junctions:    []Point{},
+		MimeType:     "image/png",
+	}
+
+	out := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if sk.pix[sk.index(x, y)] != 0 {
+				out.SetGray(x, y, color.Gray{255})
+				result.SkeletonPixels++
+			}
+		}
+	}
+
+	nodes := sk.findNodes()
+	for _, n := range nodes {
+		p := Point{X: bounds.Min.X + n.Point.X, Y: bounds.Min.Y + n.Point.Y}
+		if n.Type == SkeletonEndpoint {
+			result.Endpoints = append(result.Endpoints, p)
+		} else {
+			result.Junctions = append(result.Junctions, p)
+		}
+	}
+
+	if graph {
+		if len(nodes) > maxSkeletonGraphNodes {
+			return nil, fmt.Errorf("skeleton has %d endpoints and junctions, more than the %d a graph can hold; crop to the drawing or adjust the threshold", len(nodes), maxSkeletonGraphNodes)
+		}
+		result.Graph = sk.trace(nodes)
+		for i := range result.Graph.Nodes {
+			n := &result.Graph.Nodes[i]
+			n.Point.X, n.Point.Y = n.Point.X+bounds.Min.X, n.Point.Y+bounds.Min.Y
+		}
+		for _, e := range result.Graph.Edges {
+			for i := range e.Path {
+				e.Path[i].X, e.Path[i].Y = e.Path[i].X+bounds.Min.X, e.Path[i].Y+bounds.Min.Y
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, out); err != nil {
+		return nil, fmt.Errorf("failed to encode skeleton image: %w", err)
+	}
+	result.ImageBase64 = base64.StdEncoding.EncodeToString(buf.Bytes())
+	return result, nil
+}
+
+// otsuThreshold returns the threshold t that best splits the luminance
+// histogram into pixels below t and pixels at or above it, by Otsu's
+// method. A single-valued image gives 128.
+func otsuThreshold(lum []uint8) int {
+	var hist [256]int
+	var sum float64
+	for _, v := range lum {
+		hist[v]++
+		sum += float64(v)
+	}
+	total := float64(len(lum))
+
+	first, last, bestVar := 128, 128, -1.0
+	var n0 int
+	var sum0 float64
+	for t := 1; t < 256; t++ {
+		n0 += hist[t-1]
+		sum0 += float64(t-1) * float64(hist[t-1])
+		n1 := total - float64(n0)
+		if n0 == 0 || n1 == 0 {
+			continue
+		}
+		m0, m1 := sum0/float64(n0), (sum-sum0)/n1
+		v := float64(n0) * n1 * (m0 - m1) * (m0 - m1)
+		if v > bestVar {
+			first, last, bestVar = t, t, v
+		} else if v == bestVar {
+			last = t
+		}
+	}
+	// Empty histogram bins between the classes leave a range of equally
+	// good thresholds; take the middle one
+	return (first + last + 1) / 2
+}
+
+// skeleton is a binary image with a 1-pixel border of background, so
+// every pixel has eight neighbors to inspect.
+type skeleton struct {
+	w, h, stride int
+	pix          []uint8
+	offsets      [8]int // Neighbor offsets: N, NE, E, SE, S, SW, W, NW
+}
+
+func newSkeleton(w, h int) *skeleton {
+	s := w + 2
+	return &skeleton{
+		w: w, h: h, stride: s,
+		pix:     make([]uint8, s*(h+2)),
+		offsets: [8]int{-s, -s + 1, 1, s + 1, s, s - 1, -1, -s - 1},
+	}
+}
+
+// index returns the position of pixel (x, y) in pix.
+func (sk *skeleton) index(x, y int) int {
+	return (y+1)*sk.stride + x + 1
+}
+
+// point returns the coordinates of position i in pix.
+func (sk *skeleton) point(i int) Point {
+	return Point{X: i%sk.stride - 1, Y: i/sk.stride - 1}
+}
+
+// neighbors returns the eight neighbors of position i, N first and
+// clockwise.
+func (sk *skeleton) neighbors(i int) (n [8]uint8) {
+	for k, off := range sk.offsets {
+		n[k] = sk.pix[i+off]
+	}
+	return n
+}
+
+// degree returns the number of set neighbors of position i.
+func (sk *skeleton) degree(i int) int {
+	d := 0
+	for _, off := range sk.offsets {
+		d += int(sk.pix[i+off])
+	}
+	return d
+}
+
+// thin reduces the strokes to 1-pixel-wide skeletons in place.
+func (sk *skeleton) thin() {
+	// Zhang-Suen: alternate sub-iterations peel the south-east and
+	// north-west boundaries until nothing changes
+	var remove []int
+	for changed := true; changed; {
+		changed = false
+		for step := 0; step < 2; step++ {
+			remove = remove[:0]
+			for y := 0; y < sk.h; y++ {
+				for x := 0; x < sk.w; x++ {
+					i := sk.index(x, y)
+					if sk.pix[i] == 0 {
+						continue
+					}
+					p := sk.neighbors(i)
+					b := 0
+					for _, v := range p {
+						b += int(v)
+					}
+					if b < 2 || b > 6 || crossings(p) != 1 {
+						continue
+					}
+					n, e, s, w := p[0], p[2], p[4], p[6]
+					if step == 0 && n*e*s == 0 && e*s*w == 0 || step == 1 && n*e*w == 0 && n*s*w == 0 {
+						remove = append(remove, i)
+					}
+				}
+			}
+			for _, i := range remove {
+				sk.pix[i] = 0
+			}
+			changed = changed || len(remove) > 0
+		}
+	}
+
+	// Zhang-Suen leaves staircase corners 2 pixels thick; remove every
+	// pixel that is not a stroke end and whose removal keeps its
+	// neighbors 8-connected
+	for changed := true; changed; {
+		changed = false
+		for y := 0; y < sk.h; y++ {
+			for x := 0; x < sk.w; x++ {
+				i := sk.index(x, y)
+				if sk.pix[i] != 0 && sk.degree(i) >= 2 && connectivity8(sk.neighbors(i)) == 1 {
+					sk.pix[i] = 0
+					changed = true
+				}
+			}
+		}
+	}
+}
+
+// crossings counts the background-to-stroke transitions around the
+// neighbors p, in order.
+func crossings(p [8]uint8) int {
+	a := 0
+	for k := range p {
+		if p[k] == 0 && p[(k+1)%8] != 0 {
+			a++
+		}
+	}
+	return a
+}
+
+// connectivity8 returns the Yokoi 8-connectivity number of a pixel with
+// neighbors p: the number of stroke components its removal would leave
+// among them. A pixel with value 1 is a simple point.
+func connectivity8(p [8]uint8) int {
+	// Yokoi's ring runs E, NE, N, NW, W, SW, S, SE
+	order := [8]int{2, 1, 0, 7, 6, 5, 4, 3}
+	var bg [8]int
+	for k, j := range order {
+		bg[k] = 1 - int(p[j])
+	}
+	n := 0
+	for k := 0; k < 8; k += 2 {
+		n += bg[k] - bg[k]*bg[(k+1)%8]*bg[(k+2)%8]
+	}
+	return n
+}
+
+// skeletonNode is a node found on the skeleton, with the positions of its
+// pixels.
+type skeletonNode struct {
+	SkeletonNode
+	pixels []int
+}
+
+// findNodes returns the endpoints and junctions of the skeleton in raster
+// order of their first pixel.
+func (sk *skeleton) findNodes() []*skeletonNode {
+	var nodes []*skeletonNode
+	seen := make([]bool, len(sk.pix))
+	for y := 0; y < sk.h; y++ {
+		for x := 0; x < sk.w; x++ {
+			i := sk.index(x, y)
+			if sk.pix[i] == 0 || seen[i] {
+				continue
+			}
+			switch d := sk.degree(i); {
+			case d == 1:
+				nodes = append(nodes, &skeletonNode{
+					SkeletonNode: SkeletonNode{Type: SkeletonEndpoint, Point: sk.point(i)},
+					pixels:       []int{i},
+				})
+			case d >= 3:
+				// Gather the adjacent branching pixels into one junction
+				cluster := []int{i}
+				seen[i] = true
+				for k := 0; k < len(cluster); k++ {
+					for _, off := range sk.offsets {
+						j := cluster[k] + off
+						if sk.pix[j] != 0 && !seen[j] && sk.degree(j) >= 3 {
+							seen[j] = true
+							cluster = append(cluster, j)
+						}
+					}
+				}
+				nodes = append(nodes, &skeletonNode{
+					SkeletonNode: SkeletonNode{Type: SkeletonJunction, Point: sk.clusterCenter(cluster)},
+					pixels:       cluster,
+				})
+			}
+		}
+	}
+	return nodes
+}
+
+// clusterCenter returns the pixel of cluster nearest its centroid.
+func (sk *skeleton) clusterCenter(cluster []int) Point {
+	var cx, cy float64
+	for _, i := range cluster {
+		p := sk.point(i)
+		cx += float64(p.X)
+		cy += float64(p.Y)
+	}
+	cx /= float64(len(cluster))
+	cy /= float64(len(cluster))
+
+	best, bestDist := sk.point(cluster[0]), math.Inf(1)
+	for _, i := range cluster {
+		p := sk.point(i)
+		if d := math.Hypot(float64(p.X)-cx, float64(p.Y)-cy); d < bestDist {
+			best, bestDist = p, d
+		}
+	}
+	return best
+}
+
+// trace follows the skeleton's strokes between nodes. Node and path
+// coordinates are relative to the skeleton.
+func (sk *skeleton) trace(found []*skeletonNode) *SkeletonGraph {
+	g := &SkeletonGraph{Nodes: []SkeletonNode{}, Edges: []SkeletonEdge{}}
+	label := make([]int, len(sk.pix))
+	for i := range label {
+		label[i] = -1
+	}
+	addNode := func(n SkeletonNode, pixels []int) int {
+		n.ID = len(g.Nodes)
+		g.Nodes = append(g.Nodes, n)
+		for _, i := range pixels {
+			label[i] = n.ID
+		}
+		return n.ID
+	}
+	for _, n := range found {
+		addNode(n.SkeletonNode, n.pixels)
+	}
+
+	visited := make([]bool, len(sk.pix))
+	addEdge := func(from int, path []int) {
+		to := label[path[len(path)-1]]
+		if from == to && len(path) <= 3 {
+			// A stroke that leaves a junction and immediately returns
+			return
+		}
+		pts := make([]Point, len(path))
+		length := 0.0
+		for k, i := range path {
+			pts[k] = sk.point(i)
+			if k > 0 {
+				length += math.Hypot(float64(pts[k].X-pts[k-1].X), float64(pts[k].Y-pts[k-1].Y))
+			}
+		}
+		g.Edges = append(g.Edges, SkeletonEdge{
+			From:   from,
+			To:     to,
+			Length: math.Round(length*100) / 100,
+			Path:   simplifyPath(pts, skeletonPathTolerance),
+		})
+		g.Nodes[from].Degree++
+		g.Nodes[to].Degree++
+	}
+
+	// follow walks from start through stroke pixels, each with exactly
+	// two neighbors, until it reaches a node pixel.
+	follow := func(start, next int) []int {
+		path := []int{start, next}
+		prev, cur := start, next
+		for label[cur] < 0 {
+			visited[cur] = true
+			step := -1
+			for _, off := range sk.offsets {
+				if j := cur + off; j != prev && sk.pix[j] != 0 && (label[j] >= 0 || !visited[j]) {
+					step = j
+					break
+				}
+			}
+			if step < 0 {
+				break
+			}
+			path = append(path, step)
+			prev, cur = cur, step
+		}
+		return path
+	}
+
+	for id := range found {
+		for _, i := range found[id].pixels {
+			for _, off := range sk.offsets {
+				j := i + off
+				switch {
+				case sk.pix[j] == 0 || label[j] == id:
+				case label[j] >= 0:
+					// Adjacent nodes; record the edge from one side only
+					if i < j {
+						addEdge(id, []int{i, j})
+					}
+				case !visited[j]:
+					path := follow(i, j)
+					if label[path[len(path)-1]] >= 0 {
+						addEdge(id, path)
+					}
+				}
+			}
+		}
+	}
+
+	// What remains unvisited are closed strokes without nodes
+	for y := 0; y < sk.h; y++ {
+		for x := 0; x < sk.w; x++ {
+			i := sk.index(x, y)
+			if sk.pix[i] == 0 || visited[i] || label[i] >= 0 || sk.degree(i) != 2 {
+				continue
+			}
+			id := addNode(SkeletonNode{Type: SkeletonLoop, Point: sk.point(i)}, []int{i})
+			for _, off := range sk.offsets {
+				if j := i + off; sk.pix[j] != 0 {
+					path := follow(i, j)
+					if label[path[len(path)-1]] >= 0 {
+						addEdge(id, path)
+					}
+					break
+				}
+			}
+		}
+	}
+	return g
+}
+
+// simplifyPath reduces a polyline with the Ramer-Douglas-Peucker
+// algorithm, keeping every point farther than tolerance from the
+// simplified line.
+func simplifyPath(pts []Point, tolerance float64) []Point {
+	if len(pts) <= 2 {
+		return pts
+	}
+	keep := make([]bool, len(pts))
+	keep[0], keep[len(pts)-1] = true, true
+	var simplify func(lo, hi int)
+	simplify = func(lo, hi int) {
+		a, b := pts[lo], pts[hi]
+		dx, dy := float64(b.X-a.X), float64(b.Y-a.Y)
+		norm := math.Hypot(dx, dy)
+		far, farDist := -1, tolerance
+		for k := lo + 1; k < hi; k++ {
+			px, py := float64(pts[k].X-a.X), float64(pts[k].Y-a.Y)
+			var d float64
+			if norm == 0 {
+				d = math.Hypot(px, py)
+			} else {
+				d = math.Abs(px*dy-py*dx) / norm
+			}
+			if d > farDist {
+				far, farDist = k, d
+			}
+		}
+		if far >= 0 {
+			keep[far] = true
+			simplify(lo, far)
+			simplify(far, hi)
+		}
+	}
+	simplify(0, len(pts)-1)
+
+	out := make([]Point, 0, len(pts))
+	for k, p := range pts {
+		if keep[k] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,125 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestSkeletonize(t *testing.T) {
+	// A thick T: a bar with a stem hanging from its middle
+	tee := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	fillRect(tee, 0, 0, 100, 100, color.White)
+	fillRect(tee, 10, 20, 90, 26, color.Black)
+	fillRect(tee, 47, 20, 53, 85, color.Black)
+
+	result, err := Skeletonize(tee, 0, false, true)
+	if err != nil {
+		t.Fatalf("Skeletonize failed: %v", err)
+	}
+	if result.Threshold != 128 || result.StrokePixels != 80*6+6*59 {
+		t.Errorf("threshold %d, strokes %d", result.Threshold, result.StrokePixels)
+	}
+	if result.SkeletonPixels == 0 || result.SkeletonPixels > 80+65 {
+		t.Errorf("skeleton pixels: got %d", result.SkeletonPixels)
+	}
+	if len(result.Endpoints) != 3 || len(result.Junctions) != 1 {
+		t.Fatalf("got endpoints %v, junctions %v; want 3 and 1", result.Endpoints, result.Junctions)
+	}
+	if j := result.Junctions[0]; abs(j.X-50) > 3 || abs(j.Y-23) > 3 {
+		t.Errorf("junction: got %v, want near (50, 23)", j)
+	}
+
+	g := result.Graph
+	if g == nil || len(g.Nodes) != 4 || len(g.Edges) != 3 {
+		t.Fatalf("graph: got %+v", g)
+	}
+	junction := -1
+	for _, n := range g.Nodes {
+		if n.Type == SkeletonJunction {
+			junction = n.ID
+			if n.Degree != 3 {
+				t.Errorf("junction degree: got %d, want 3", n.Degree)
+			}
+		} else if n.Type != SkeletonEndpoint || n.Degree != 1 {
+			t.Errorf("node: got %+v, want an endpoint of degree 1", n)
+		}
+	}
+	for _, e := range g.Edges {
+		if e.From != junction && e.To != junction {
+			t.Errorf("edge %+v does not touch the junction", e)
+		}
+		if len(e.Path) < 2 || len(e.Path) > 4 || e.Length < 30 {
+			t.Errorf("edge path: got %d points, length %v", len(e.Path), e.Length)
+		}
+	}
+
+	// White strokes on black need invert
+	ring := image.NewRGBA(image.Rect(0, 0, 60, 60))
+	fillRect(ring, 0, 0, 60, 60, color.Black)
+	for y := 0; y < 60; y++ {
+		for x := 0; x < 60; x++ {
+			if r := math.Hypot(float64(x)+0.5-30, float64(y)+0.5-30); r >= 17 && r < 21 {
+				ring.Set(x, y, color.White)
+			}
+		}
+	}
+	result, err = Skeletonize(ring, 128, true, true)
+	if err != nil {
+		t.Fatalf("Skeletonize failed: %v", err)
+	}
+	if len(result.Endpoints) != 0 || len(result.Junctions) != 0 {
+		t.Errorf("ring: got endpoints %v, junctions %v", result.Endpoints, result.Junctions)
+	}
+	g = result.Graph
+	if len(g.Nodes) != 1 || g.Nodes[0].Type != SkeletonLoop || len(g.Edges) != 1 || g.Edges[0].From != 0 || g.Edges[0].To != 0 {
+		t.Fatalf("ring graph: got %+v", g)
+	}
+	if l := g.Edges[0].Length; math.Abs(l-2*math.Pi*19) > 15 {
+		t.Errorf("ring length: got %v, want about %v", l, 2*math.Pi*19)
+	}
+
+	// Without invert the ring's background is the stroke
+	result, err = Skeletonize(ring, 128, false, false)
+	if err != nil {
+		t.Fatalf("Skeletonize failed: %v", err)
+	}
+	if result.Graph != nil || result.StrokePixels == 0 {
+		t.Errorf("uninverted: got %+v", result)
+	}
+
+	if _, err := Skeletonize(tee, 256, false, false); err == nil {
+		t.Error("expected error for threshold above 255")
+	}
+}
+
+func TestOtsuThreshold(t *testing.T) {
+	lum := make([]uint8, 100)
+	for i := range lum {
+		lum[i] = 200
+		if i < 30 {
+			lum[i] = 50
+		}
+	}
+	if got := otsuThreshold(lum); got != 126 {
+		t.Errorf("bimodal: got %d, want 126, midway between the modes", got)
+	}
+	if got := otsuThreshold([]uint8{7, 7, 7}); got != 128 {
+		t.Errorf("uniform: got %d, want 128", got)
+	}
+}
+
+func TestSimplifyPath(t *testing.T) {
+	var pts []Point
+	for x := 0; x <= 10; x++ {
+		pts = append(pts, Point{X: x, Y: 0})
+	}
+	for y := 1; y <= 10; y++ {
+		pts = append(pts, Point{X: 10, Y: y})
+	}
+	got := simplifyPath(pts, 1)
+	if len(got) != 3 || got[1] != (Point{X: 10, Y: 0}) {
+		t.Errorf("got %v, want the corner kept", got)
+	}
+}
@@ -0,0 +1,366 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+// Tunables for SmartCrop.
+const (
+	// smartCropMinAspect and smartCropMaxAspect bound the target aspect
+	// ratio (width / height).
+	smartCropMinAspect = 0.1
+	smartCropMaxAspect = 10
+
+	// maxSeamShare is the largest share of a dimension seam carving may
+	// remove; beyond it seams cut through content.
+	maxSeamShare = 0.5
+
+	// maxSmartCropSize bounds the max_size parameter.
+	maxSmartCropSize = 4096
+)
+
+// SmartCropResult contains a content-aware crop encoded as base64 PNG.
+type SmartCropResult struct {
+	// Width and Height of the output image in pixels.
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// Method is the method used: "crop" or "seam".
+	Method string `json:"method"`
+
+	// Crop is the source area kept by the "crop" method; nil for "seam".
+	Crop *Region `json:"crop,omitempty"`
+
+	// SeamsRemoved is the number of columns or rows removed by the "seam"
+	// method.
+	SeamsRemoved int `json:"seams_removed,omitempty"`
+
+	// SeamDirection is "vertical" when columns were removed (narrowing the
+	// image) or "horizontal" when rows were removed; empty for "crop".
+	SeamDirection string `json:"seam_direction,omitempty"`
+
+	// RetainedEnergy is the share (0-100) of the image's edge energy kept
+	// in the output. For "seam" it is estimated from the energy of the
+	// removed seams.
+	RetainedEnergy float64 `json:"retained_energy"`
+
+	// ImageBase64 is the output image encoded as base64 PNG.
+	ImageBase64 string `json:"image_base64"`
+
+	// MimeType is always "image/png".
+	MimeType string `json:"mime_type"`
+}
+
+// SmartCrop reduces an image to a target aspect ratio while keeping its
+// salient content, for thumbnails of diagrams and screenshots that do not
+// cut off the interesting parts.
+//
+// Parameters:
+//   - img: Source image.
+//   - aspectRatio: Target width / height, from 0.1 to 10 (1 for square,
+//     1.78 for 16:9).
+//   - method: "crop" to cut out the best window, or "seam" to remove
+//     low-energy seams (seam carving).
+//   - maxSize: If > 0, the output is scaled down so neither side exceeds
+//     it (at most 4096). 0 keeps the cropped size.
+//
+// Returns:
+//   - *SmartCropResult: The output image with the area kept.
+//   - error: Non-nil if a parameter is out of range, or "seam" would have
+//     to remove more than half of a dimension.
+//
+// # Energy
+//
+// Salience is edge energy: the luminance gradient |dL/dx| + |dL/dy| at
+// each pixel, from central differences. Flat backgrounds have none, while
+// text, lines, and shape outlines have a lot.
+//
+// # Methods
+//
+// "crop" keeps the full extent of one dimension and slides a window of the
+// target aspect ratio along the other, keeping the window with the most
+// energy. When several windows keep all the content, the one that best
+// centers it is chosen.
+//
+// "seam" removes connected one-pixel paths of least total energy, running
+// top to bottom to narrow the image or left to right to shorten it, one at
+// a time, until the aspect ratio is reached. Unlike "crop" it keeps content
+// near both edges, at the cost of moving it closer together; it suits
+// diagrams with empty space between elements. It is slower, and limited to
+// removing half of a dimension.
+//
+// The output size is rounded to whole pixels, so its aspect ratio may
+// differ slightly from the target.
+func SmartCrop(img image.Image, aspectRatio float64, method string, maxSize int) (*SmartCropResult, error) {
+	if aspectRatio < smartCropMinAspect || aspectRatio > smartCropMaxAspect {
+		return nil, fmt.Errorf("aspect ratio must be between %v and %v, got %v", smartCropMinAspect, smartCropMaxAspect, aspectRatio)
+	}
+	if method != "crop" && method != "seam" {
+		return nil, fmt.Errorf("invalid method %q: must be crop or seam", method)
+	}
+	if maxSize < 0 || maxSize > maxSmartCropSize {
+		return nil, fmt.Errorf("max size must be between 0 and %d, got %d", maxSmartCropSize, maxSize)
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	tw, th := w, h
+	if float64(w)/float64(h) > aspectRatio {
+		tw = max(1, int(math.Round(float64(h)*aspectRatio)))
+	} else {
+		th = max(1, int(math.Round(float64(w)/aspectRatio)))
+	}
+
+	src := imaging.Clone(img)
+	lum := make([]int, w*h)
+	for i := range lum {
+		p := src.Pix[i*4 : i*4+3]
+		lum[i] = int(math.Round(0.299*float64(p[0]) + 0.587*float64(p[1]) + 0.114*float64(p[2])))
+	}
+	energy := edgeEnergy(lum, w, h)
+	total := int64(0)
+	for _, e := range energy {
+		total += int64(e)
+	}
+
+	result := &SmartCropResult{Method: method, MimeType: "image/png"}
+	var out image.Image
+	var kept int64
+	if method == "crop" {
+		r := bestCropWindow(energy, w, h, tw, th)
+		for y := r.Y1; y < r.Y2; y++ {
+			for x := r.X1; x < r.X2; x++ {
+				kept += int64(energy[y*w+x])
+			}
+		}
+		out = imaging.Crop(src, image.Rect(r.X1, r.Y1, r.X2, r.Y2))
+		result.Crop = &Region{X1: b.Min.X + r.X1, Y1: b.Min.Y + r.Y1, X2: b.Min.X + r.X2, Y2: b.Min.Y + r.Y2}
+	} else {
+		vertical := tw < w
+		seams, side := w-tw, w
+		if !vertical {
+			seams, side = h-th, h
+		}
+		if float64(seams) > float64(side)*maxSeamShare {
+			return nil, fmt.Errorf("seam carving %dx%d to %dx%d would remove more than half of the image; use method crop for aspect ratios this far from the image's", w, h, tw, th)
+		}
+		c := newSeamCarver(src, lum, energy, !vertical)
+		removed := int64(0)
+		for i := 0; i < seams; i++ {
+			removed += c.removeSeam()
+		}
+		kept = max(0, total-removed)
+		out = c.image(!vertical)
+		result.SeamsRemoved = seams
+		result.SeamDirection = "vertical"
+		if !vertical {
+			result.SeamDirection = "horizontal"
+		}
+	}
+
+	result.RetainedEnergy = 100
+	if total > 0 {
+		result.RetainedEnergy = math.Round(float64(kept)/float64(total)*10000) / 100
+	}
+	if ob := out.Bounds(); maxSize > 0 && (ob.Dx() > maxSize || ob.Dy() > maxSize) {
+		out = imaging.Fit(out, maxSize, maxSize, imaging.Lanczos)
+	}
+	encoded, err := encodePNGBase64(out)
+	if err != nil {
+		return nil, err
+	}
+	result.Width, result.Height = out.Bounds().Dx(), out.Bounds().Dy()
+	result.ImageBase64 = encoded
+	return result, nil
+}
+
+// edgeEnergy returns the edge energy |dL/dx| + |dL/dy| of each pixel of a
+// w×h luminance image, from central differences clamped at the borders.
+func edgeEnergy(lum []int, w, h int) []int {
+	energy := make([]int, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			energy[y*w+x] = pixelEnergy(lum, w, h, w, x, y)
+		}
+	}
+	return energy
+}
+
+// pixelEnergy returns the edge energy of pixel (x, y) of a w×h luminance
+// image stored with the given row stride.
+func pixelEnergy(lum []int, w, h, stride, x, y int) int {
+	dx := lum[y*stride+min(x+1, w-1)] - lum[y*stride+max(x-1, 0)]
+	dy := lum[min(y+1, h-1)*stride+x] - lum[max(y-1, 0)*stride+x]
+	return absInt(dx) + absInt(dy)
+}
+
+// bestCropWindow returns the tw×th window of a w×h energy map with the
+// most energy. One of tw, th equals the image side, so the window slides
+// along a single axis; among equally good positions the one that best
+// centers the energy is taken.
+func bestCropWindow(energy []int, w, h, tw, th int) Region {
+	// Energy per column or row along the sliding axis
+	n, size := w, tw
+	if tw == w {
+		n, size = h, th
+	}
+	line := make([]int64, n+1)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if tw == w {
+				line[y+1] += int64(energy[y*w+x])
+			} else {
+				line[x+1] += int64(energy[y*w+x])
+			}
+		}
+	}
+	center, total := float64(n)/2, 0.0
+	moment := 0.0
+	for i := 0; i < n; i++ {
+		total += float64(line[i+1])
+		moment += (float64(i) + 0.5) * float64(line[i+1])
+	}
+	if total > 0 {
+		center = moment / total
+	}
+	for i := 1; i <= n; i++ {
+		line[i] += line[i-1]
+	}
+
+	best, pos := int64(-1), 0
+	target := center - float64(size)/2
+	for p := 0; p+size <= n; p++ {
+		e := line[p+size] - line[p]
+		if e > best || e == best && math.Abs(float64(p)-target) < math.Abs(float64(pos)-target) {
+			best, pos = e, p
+		}
+	}
+	if tw == w {
+		return Region{X1: 0, Y1: pos, X2: w, Y2: pos + th}
+	}
+	return Region{X1: pos, Y1: 0, X2: pos + tw, Y2: h}
+}
+
+// seamCarver removes vertical seams from an image, keeping its pixels,
+// luminance, and energy in rows of stride stride whose first w entries
+// are live. Horizontal seams are removed by carving the transposed image.
+type seamCarver struct {
+	w, h, stride int
+	pix          []color.NRGBA
+	lum, energy  []int
+	cost         []int64
+	seam         []int
+}
+
+// newSeamCarver prepares src, with its luminance and energy maps, for
+// carving; transpose carves rows instead of columns.
+func newSeamCarver(src *image.NRGBA, lum, energy []int, transpose bool) *seamCarver {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	cw, ch := w, h
+	if transpose {
+		cw, ch = h, w
+	}
+	c := &seamCarver{
+		w: cw, h: ch, stride: cw,
+		pix:    make([]color.NRGBA, cw*ch),
+		lum:    make([]int, cw*ch),
+		energy: make([]int, cw*ch),
+		cost:   make([]int64, cw*ch),
+		seam:   make([]int, ch),
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i, j := y*w+x, y*cw+x
+			if transpose {
+				j = x*cw + y
+			}
+			p := src.Pix[i*4 : i*4+4]
+			c.pix[j] = color.NRGBA{R: p[0], G: p[1], B: p[2], A: p[3]}
+			c.lum[j], c.energy[j] = lum[i], energy[i]
+		}
+	}
+	return c
+}
+
+// removeSeam removes the top-to-bottom 8-connected path of least energy
+// and returns its energy. Only the energy next to the seam is recomputed,
+// as no other pixel's neighbors change.
+func (c *seamCarver) removeSeam() int64 {
+	w, h, s := c.w, c.h, c.stride
+	for x := 0; x < w; x++ {
+		c.cost[x] = int64(c.energy[x])
+	}
+	for y := 1; y < h; y++ {
+		for x := 0; x < w; x++ {
+			best := c.cost[(y-1)*s+x]
+			if x > 0 && c.cost[(y-1)*s+x-1] < best {
+				best = c.cost[(y-1)*s+x-1]
+			}
+			if x < w-1 && c.cost[(y-1)*s+x+1] < best {
+				best = c.cost[(y-1)*s+x+1]
+			}
+			c.cost[y*s+x] = best + int64(c.energy[y*s+x])
+		}
+	}
+
+	// Trace back from the cheapest end, preferring the straight path
+	x := 0
+	for i := 1; i < w; i++ {
+		if c.cost[(h-1)*s+i] < c.cost[(h-1)*s+x] {
+			x = i
+		}
+	}
+	removed := int64(0)
+	for y := h - 1; y >= 0; y-- {
+		c.seam[y] = x
+		removed += int64(c.energy[y*s+x])
+		if y > 0 {
+			next := x
+			for _, nx := range []int{x - 1, x + 1} {
+				if nx >= 0 && nx < w && c.cost[(y-1)*s+nx] < c.cost[(y-1)*s+next] {
+					next = nx
+				}
+			}
+			x = next
+		}
+	}
+
+	for y := 0; y < h; y++ {
+		row, x := y*s, c.seam[y]
+		copy(c.pix[row+x:row+w-1], c.pix[row+x+1:row+w])
+		copy(c.lum[row+x:row+w-1], c.lum[row+x+1:row+w])
+		copy(c.energy[row+x:row+w-1], c.energy[row+x+1:row+w])
+	}
+	c.w--
+	for y := 0; y < h; y++ {
+		for x := max(c.seam[y]-2, 0); x <= min(c.seam[y]+1, c.w-1); x++ {
+			c.energy[y*s+x] = pixelEnergy(c.lum, c.w, h, s, x, y)
+		}
+	}
+	return removed
+}
+
+// image returns the carved pixels as an image, transposed back if the
+// carver works on the transposed image.
+func (c *seamCarver) image(transpose bool) *image.NRGBA {
+	ow, oh := c.w, c.h
+	if transpose {
+		ow, oh = c.h, c.w
+	}
+	out := image.NewNRGBA(image.Rect(0, 0, ow, oh))
+	for y := 0; y < c.h; y++ {
+		for x := 0; x < c.w; x++ {
+			if transpose {
+				out.SetNRGBA(y, x, c.pix[y*c.stride+x])
+			} else {
+				out.SetNRGBA(x, y, c.pix[y*c.stride+x])
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,196 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// decodeSmartCrop decodes the PNG of a smart crop result.
+func decodeSmartCrop(t *testing.T, r *SmartCropResult) image.Image {
+	t.Helper()
+	data, err := base64.StdEncoding.DecodeString(r.ImageBase64)
+	if err != nil {
+		t.Fatalf("failed to decode base64: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to decode PNG: %v", err)
+	}
+	if img.Bounds().Dx() != r.Width || img.Bounds().Dy() != r.Height {
+		t.Errorf("PNG is %dx%d, result says %dx%d", img.Bounds().Dx(), img.Bounds().Dy(), r.Width, r.Height)
+	}
+	return img
+}
+
+// countDark counts the pixels with a red channel below 128.
+func countDark(img image.Image) int {
+	n := 0
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if r, _, _, _ := img.At(x, y).RGBA(); r>>8 < 128 {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestSmartCrop_Crop(t *testing.T) {
+	// A box off to the right of a wide image is kept, centered
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	fillRect(img, 0, 0, 400, 200, color.White)
+	fillRect(img, 250, 50, 330, 150, color.Black)
+
+	result, err := SmartCrop(img, 1, "crop", 0)
+	if err != nil {
+		t.Fatalf("SmartCrop failed: %v", err)
+	}
+	if result.Width != 200 || result.Height != 200 {
+		t.Errorf("size: got %dx%d, want 200x200", result.Width, result.Height)
+	}
+	c := result.Crop
+	if c == nil || c.Y1 != 0 || c.Y2 != 200 || c.X2-c.X1 != 200 {
+		t.Fatalf("crop: got %+v, want a 200-wide full-height window", c)
+	}
+	// Content spans columns 249-330, so the centered window starts at 190
+	if c.X1 < 189 || c.X1 > 191 {
+		t.Errorf("crop X1: got %d, want about 190", c.X1)
+	}
+	if result.RetainedEnergy != 100 {
+		t.Errorf("retained energy: got %v, want 100", result.RetainedEnergy)
+	}
+	if got := countDark(decodeSmartCrop(t, result)); got != 80*100 {
+		t.Errorf("dark pixels: got %d, want %d", got, 80*100)
+	}
+}
+
+func TestSmartCrop_CropTall(t *testing.T) {
+	// A tall image is cropped vertically, toward its content
+	img := image.NewRGBA(image.Rect(0, 0, 100, 300))
+	fillRect(img, 0, 0, 100, 300, color.White)
+	fillRect(img, 20, 10, 80, 60, color.Black)
+
+	result, err := SmartCrop(img, 2, "crop", 0)
+	if err != nil {
+		t.Fatalf("SmartCrop failed: %v", err)
+	}
+	c := result.Crop
+	if result.Width != 100 || result.Height != 50 || c.X1 != 0 || c.X2 != 100 {
+		t.Fatalf("got %dx%d crop %+v, want a 100x50 full-width window", result.Width, result.Height, c)
+	}
+	if c.Y1 > 10 || c.Y2 < 60 {
+		t.Errorf("crop %+v cuts the box at rows 10-60 more than necessary", c)
+	}
+	if result.RetainedEnergy >= 100 || result.RetainedEnergy < 50 {
+		t.Errorf("retained energy: got %v, want most but not all", result.RetainedEnergy)
+	}
+}
+
+func TestSmartCrop_Seam(t *testing.T) {
+	// Boxes near both edges survive seam carving, where cropping loses one
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	fillRect(img, 0, 0, 400, 200, color.White)
+	fillRect(img, 20, 50, 80, 150, color.Black)
+	fillRect(img, 320, 50, 380, 150, color.Black)
+
+	result, err := SmartCrop(img, 1.5, "seam", 0)
+	if err != nil {
+		t.Fatalf("SmartCrop failed: %v", err)
+	}
+	if result.Width != 300 || result.Height != 200 {
+		t.Errorf("size: got %dx%d, want 300x200", result.Width, result.Height)
+	}
+	if result.SeamsRemoved != 100 || result.SeamDirection != "vertical" || result.Crop != nil {
+		t.Errorf("got %d %s seams, crop %+v; want 100 vertical, no crop", result.SeamsRemoved, result.SeamDirection, result.Crop)
+	}
+	if result.RetainedEnergy != 100 {
+		t.Errorf("retained energy: got %v, want 100", result.RetainedEnergy)
+	}
+	if got := countDark(decodeSmartCrop(t, result)); got != 2*60*100 {
+		t.Errorf("dark pixels: got %d, want %d", got, 2*60*100)
+	}
+
+	cropped, err := SmartCrop(img, 1.5, "crop", 0)
+	if err != nil {
+		t.Fatalf("SmartCrop crop failed: %v", err)
+	}
+	if cropped.RetainedEnergy >= 100 {
+		t.Errorf("crop retained energy: got %v, want less than 100", cropped.RetainedEnergy)
+	}
+}
+
+func TestSmartCrop_SeamHorizontal(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 200, 400))
+	fillRect(img, 0, 0, 200, 400, color.White)
+	fillRect(img, 50, 20, 150, 80, color.Black)
+	fillRect(img, 50, 320, 150, 380, color.Black)
+
+	result, err := SmartCrop(img, 200.0/300, "seam", 0)
+	if err != nil {
+		t.Fatalf("SmartCrop failed: %v", err)
+	}
+	if result.Width != 200 || result.Height != 300 || result.SeamDirection != "horizontal" {
+		t.Errorf("got %dx%d %s, want 200x300 horizontal", result.Width, result.Height, result.SeamDirection)
+	}
+	if got := countDark(decodeSmartCrop(t, result)); got != 2*60*100 {
+		t.Errorf("dark pixels: got %d, want %d", got, 2*60*100)
+	}
+}
+
+func TestSmartCrop_MaxSize(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	fillRect(img, 0, 0, 400, 200, color.White)
+	fillRect(img, 100, 50, 200, 150, color.Black)
+
+	result, err := SmartCrop(img, 1, "crop", 64)
+	if err != nil {
+		t.Fatalf("SmartCrop failed: %v", err)
+	}
+	if result.Width != 64 || result.Height != 64 {
+		t.Errorf("size: got %dx%d, want 64x64", result.Width, result.Height)
+	}
+	if result.Crop.X2-result.Crop.X1 != 200 {
+		t.Errorf("crop should be in source pixels, got %+v", result.Crop)
+	}
+}
+
+func TestSmartCrop_Blank(t *testing.T) {
+	// With no content, the crop is centered
+	img := createInMemoryImage(300, 100, color.White)
+	result, err := SmartCrop(img, 1, "crop", 0)
+	if err != nil {
+		t.Fatalf("SmartCrop failed: %v", err)
+	}
+	if *result.Crop != (Region{X1: 100, Y1: 0, X2: 200, Y2: 100}) || result.RetainedEnergy != 100 {
+		t.Errorf("got crop %+v retained %v, want centered and 100", *result.Crop, result.RetainedEnergy)
+	}
+}
+
+func TestSmartCrop_Errors(t *testing.T) {
+	img := createInMemoryImage(400, 200, color.White)
+	tests := []struct {
+		name    string
+		aspect  float64
+		method  string
+		maxSize int
+	}{
+		{"aspect too small", 0.05, "crop", 0},
+		{"aspect too large", 11, "crop", 0},
+		{"bad method", 1, "resize", 0},
+		{"negative max size", 1, "crop", -1},
+		{"max size too large", 1, "crop", 5000},
+		{"too many seams", 0.5, "seam", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := SmartCrop(img, tt.aspect, tt.method, tt.maxSize); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,473 @@
+package imaging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strings"
+)
+
+// maxSpecImages is the most images CheckSpecs checks in one call.
+const maxSpecImages = 500
+
+// specFormats are the formats ImageSpecs.Formats may allow, as reported
+// in ImageInfo.Format, and specFormatAliases other names for them.
+var (
+	specFormats       = []string{"png", "jpeg", "gif", "tiff", "svg", "ico", "icns"}
+	specFormatAliases = map[string]string{"jpg": "jpeg", "tif": "tiff"}
+)
+
+// ImageSpecs are the constraints CheckSpecs validates images against,
+// such as the asset requirements of an app store listing. Zero fields
+// are not checked.
+type ImageSpecs struct {
+	// Width and Height are the exact dimensions required, in pixels.
+	Width  int
+	Height int
+
+	// MinWidth, MaxWidth, MinHeight, and MaxHeight bound the dimensions,
+	// inclusive.
+	MinWidth  int
+	MaxWidth  int
+	MinHeight int
+	MaxHeight int
+
+	// AspectRatio is the width / height required, within
+	// AspectTolerance percent of it.
+	AspectRatio     float64
+	AspectTolerance float64
+
+	// MaxFileSize is the largest file size allowed, in bytes.
+	MaxFileSize int64
+
+	// Formats are the formats allowed, such as "png" and "jpeg" ("jpg"
+	// and "tif" are accepted too).
+	Formats []string
+
+	// DPI is the resolution required, and MinDPI the least allowed, in
+	// dots per inch, both compared after rounding to whole DPI.
+	DPI    float64
+	MinDPI float64
+}
+
+// Resolution is the physical resolution stored in an image file.
+type Resolution struct {
+	// X and Y are the horizontal and vertical resolution in dots per
+	// inch, rounded to 2 decimal places.
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// SpecFailure is a constraint an image does not meet.
+type SpecFailure struct {
+	// Check is the constraint: "load", "width", "height", "aspect_ratio",
+	// "file_size", "format", or "dpi".
+	Check string `json:"check"`
+
+	// Message describes the failure, with the image's value and the one
+	// required.
+	Message string `json:"message"`
+}
+
+// SpecCheck is the result of checking one image against ImageSpecs.
+type SpecCheck struct {
+	// Path is the image file.
+	Path string `json:"path"`
+
+	// Passed is true if the image meets every constraint.
+	Passed bool `json:"passed"`
+
+	// Width, Height, AspectRatio (width / height, rounded to 4 decimal
+	// places), Format, and FileSizeBytes describe the image; they are
+	// zero if it could not be loaded.
+	Width         int     `json:"width"`
+	Height        int     `json:"height"`
+	AspectRatio   float64 `json:"aspect_ratio"`
+	Format        string  `json:"format"`
+	FileSizeBytes int64   `json:"file_size_bytes"`
+
+	// DPI is the resolution stored in the file; nil if it has none.
+	DPI *Resolution `json:"dpi,omitempty"`
+
+	// Failures are the constraints not met, in the order checked.
+	Failures []SpecFailure `json:"failures"`
+}
+
+// SpecsResult is the result of checking a batch of images.
+type SpecsResult struct {
+	// Passed is true if every image meets every constraint.
+	Passed bool `json:"passed"`
+
+	// Checked and Failed count the images checked and those that failed.
+	Checked int `json:"checked"`
+	Failed  int `json:"failed"`
+
+	// Images holds the result for each image, in the order given.
+	Images []SpecCheck `json:"images"`
+}
+
+// CheckSpecs validates images against constraints on their dimensions,
+// aspect ratio, file size, format, and resolution, and reports for each
+// whether it passes and why not, as when auditing store listing or
+// marketing assets.
+//
+// Parameters:
+//   - cache: The image cache to load the images with.
+//   - paths: The image files to check, 1 to 500 of them.
+//   - specs: The constraints; at least one must be set.
+//
+// Returns:
+//   - *SpecsResult: The result for each image, and for the batch.
+//   - error: Non-nil if the number of paths is out of range, no
+//     constraints are given, or a constraint is invalid. An image that cannot be loaded fails its
+//     "load" check instead.
+//
+// The format is the one LoadImageInfo reports from the file extension.
+// The resolution is read from a PNG pHYs chunk, the JFIF or Exif header
+// of a JPEG, or the tags of a TIFF file; a DPI constraint fails for files
+// without one, which viewers commonly show at 72 DPI.
+func CheckSpecs(cache *ImageCache, paths []string, specs ImageSpecs) (*SpecsResult, error) {
+	if len(paths) == 0 || len(paths) > maxSpecImages {
+		return nil, fmt.Errorf("paths must hold 1 to %d images, got %d", maxSpecImages, len(paths))
+	}
+	allowed, err := validateSpecs(specs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SpecsResult{Checked: len(paths), Images: make([]SpecCheck, 0, len(paths))}
+	for _, path := range paths {
+		check := checkImageSpecs(cache, path, specs, allowed)
+		if !check.Passed {
+			result.Failed++
+		}
+		result.Images = append(result.Images, check)
+	}
+	result.Passed = result.Failed == 0
+	return result, nil
+}
+
+// validateSpecs checks that specs sets valid constraints, and returns the
+// allowed formats, with aliases resolved.
+func validateSpecs(specs ImageSpecs) (map[string]bool, error) {
+	for _, v := range []struct {
+		name  string
+		value float64
+	}{
+		{"width", float64(specs.Width)}, {"height", float64(specs.Height)},
+		{"min_width", float64(specs.MinWidth)}, {"max_width", float64(specs.MaxWidth)},
+		{"min_height", float64(specs.MinHeight)}, {"max_height", float64(specs.MaxHeight)},
+		{"aspect_ratio", specs.AspectRatio}, {"aspect_tolerance", specs.AspectTolerance},
+		{"max_file_size", float64(specs.MaxFileSize)}, {"dpi", specs.DPI}, {"min_dpi", specs.MinDPI},
+	} {
+		if v.value < 0 {
+			return nil, fmt.Errorf("%s must not be negative, got %g", v.name, v.value)
+		}
+	}
+	if specs.MaxWidth > 0 && specs.MinWidth > specs.MaxWidth {
+		return nil, fmt.Errorf("min_width %d exceeds max_width %d", specs.MinWidth, specs.MaxWidth)
+	}
+	if specs.MaxHeight > 0 && specs.MinHeight > specs.MaxHeight {
+		return nil, fmt.Errorf("min_height %d exceeds max_height %d", specs.MinHeight, specs.MaxHeight)
+	}
+	var allowed map[string]bool
+	for _, f := range specs.Formats {
+		name := strings.ToLower(strings.TrimPrefix(f, "."))
+		if alias, ok := specFormatAliases[name]; ok {
+			name = alias
+		}
+		if !slices.Contains(specFormats, name) {
+			return nil, fmt.Errorf("unknown format: %s (use %s)", f, strings.Join(specFormats, ", "))
+		}
+		if allowed == nil {
+			allowed = map[string]bool{}
+		}
+		allowed[name] = true
+	}
+	if specs.Width == 0 && specs.Height == 0 && specs.MinWidth == 0 && specs.MaxWidth == 0 &&
+		specs.MinHeight == 0 && specs.MaxHeight == 0 && specs.AspectRatio == 0 &&
+		specs.MaxFileSize == 0 && allowed == nil && specs.DPI == 0 && specs.MinDPI == 0 {
+		return nil, fmt.Errorf("no specs to check against")
+	}
+	return allowed, nil
+}
+
+// checkImageSpecs checks one image against specs.
+func checkImageSpecs(cache *ImageCache, path string, specs ImageSpecs, allowed map[string]bool) SpecCheck {
+	check := SpecCheck{Path: path, Failures: []SpecFailure{}}
+	fail := func(name, format string, args ...interface{}) {
+		check.Failures = append(check.Failures, SpecFailure{Check: name, Message: fmt.Sprintf(format, args...)})
+	}
+	info, err := LoadImageInfo(cache, path)
+	if err != nil {
+		fail("load", "cannot load image: %v", err)
+		return check
+	}
+	check.Width, check.Height, check.Format, check.FileSizeBytes = info.Width, info.Height, info.Format, info.FileSizeBytes
+	aspect := float64(info.Width) / float64(info.Height)
+	check.AspectRatio = math.Round(aspect*10000) / 10000
+
+	if specs.Width > 0 && info.Width != specs.Width {
+		fail("width", "width is %d px, must be %d px", info.Width, specs.Width)
+	}
+	if specs.MinWidth > 0 && info.Width < specs.MinWidth {
+		fail("width", "width is %d px, must be at least %d px", info.Width, specs.MinWidth)
+	}
+	if specs.MaxWidth > 0 && info.Width > specs.MaxWidth {
+		fail("width", "width is %d px, must be at most %d px", info.Width, specs.MaxWidth)
+	}
+	if specs.Height > 0 && info.Height != specs.Height {
+		fail("height", "height is %d px, must be %d px", info.Height, specs.Height)
+	}
+	if specs.MinHeight > 0 && info.Height < specs.MinHeight {
+		fail("height", "height is %d px, must be at least %d px", info.Height, specs.MinHeight)
+	}
+	if specs.MaxHeight > 0 && info.Height > specs.MaxHeight {
+		fail("height", "height is %d px, must be at most %d px", info.Height, specs.MaxHeight)
+	}
+	if specs.AspectRatio > 0 && math.Abs(aspect-specs.AspectRatio) > specs.AspectRatio*specs.AspectTolerance/100 {
+		fail("aspect_ratio", "aspect ratio is %.4g, must be %.4g (within %g%%)", aspect, specs.AspectRatio, specs.AspectTolerance)
+	}
+	if specs.MaxFileSize > 0 && info.FileSizeBytes > specs.MaxFileSize {
+		fail("file_size", "file size is %d bytes, must be at most %d bytes", info.FileSizeBytes, specs.MaxFileSize)
+	}
+	if allowed != nil && !allowed[info.Format] {
+		fail("format", "format is %s, must be one of %s", info.Format, strings.Join(formatList(allowed), ", "))
+	}
+
+	// A resolution that cannot be read fails only a DPI constraint
+	res, err := ReadResolution(path)
+	check.DPI = res
+	if specs.DPI > 0 || specs.MinDPI > 0 {
+		switch {
+		case err != nil:
+			fail("dpi", "cannot read resolution: %v", err)
+		case res == nil:
+			fail("dpi", "file has no resolution (shown at 72 DPI), must be %s", dpiRequirement(specs))
+		case specs.DPI > 0 && (math.Round(res.X) != math.Round(specs.DPI) || math.Round(res.Y) != math.Round(specs.DPI)):
+			fail("dpi", "resolution is %s DPI, must be %g DPI", formatResolution(res), specs.DPI)
+		case specs.MinDPI > 0 && (math.Round(res.X) < math.Round(specs.MinDPI) || math.Round(res.Y) < math.Round(specs.MinDPI)):
+			fail("dpi", "resolution is %s DPI, must be at least %g DPI", formatResolution(res), specs.MinDPI)
+		}
+	}
+	check.Passed = len(check.Failures) == 0
+	return check
+}
+
+// dpiRequirement describes the DPI constraints of specs.
+func dpiRequirement(specs ImageSpecs) string {
+	if specs.DPI > 0 {
+		return fmt.Sprintf("%g DPI", specs.DPI)
+	}
+	return fmt.Sprintf("at least %g DPI", specs.MinDPI)
+}
+
+// formatResolution formats r as "300" or, if its axes differ, "300×150".
+func formatResolution(r *Resolution) string {
+	if r.X == r.Y {
+		return fmt.Sprintf("%g", r.X)
+	}
+	return fmt.Sprintf("%g×%g", r.X, r.Y)
+}
+
+// formatList returns the formats of set in the order of specFormats.
+func formatList(set map[string]bool) []string {
+	var keys []string
+	for _, f := range specFormats {
+		if set[f] {
+			keys = append(keys, f)
+		}
+	}
+	return keys
+}
+
+// ReadResolution returns the resolution stored in a PNG, JPEG, or TIFF
+// file; nil for other formats and files without one.
+func ReadResolution(path string) (*Resolution, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	magic := make([]byte, 8)
+	if n, _ := f.ReadAt(magic, 0); n < len(magic) {
+		return nil, nil
+	}
+	switch {
+	case bytes.HasPrefix(magic, []byte("\x89PNG\r\n\x1a\n")):
+		return readPNGResolution(bufio.NewReader(f))
+	case bytes.HasPrefix(magic, []byte{0xff, 0xd8}):
+		return readJPEGResolution(bufio.NewReader(f))
+	case bytes.HasPrefix(magic, []byte("II*\x00")), bytes.HasPrefix(magic, []byte("MM\x00*")):
+		return readTIFFResolution(f, magic)
+	}
+	return nil, nil
+}
+
+// newResolution returns the resolution of x and y dots per unit, where
+// unitsPerInch converts to DPI; nil if either is zero.
+func newResolution(x, y, unitsPerInch float64) *Resolution {
+	if x <= 0 || y <= 0 {
+		return nil
+	}
+	return &Resolution{X: math.Round(x*unitsPerInch*100) / 100, Y: math.Round(y*unitsPerInch*100) / 100}
+}
+
+// readPNGResolution reads the pHYs chunk of a PNG file, stopping at the
+// first IDAT chunk, after which it may not appear. A pHYs chunk without
+// a unit gives only the pixels' aspect ratio, and no resolution.
+func readPNGResolution(r io.Reader) (*Resolution, error) {
+	if _, err := io.CopyN(io.Discard, r, 8); err != nil {
+		return nil, err
+	}
+	var header [8]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return nil, err
+		}
+		length := binary.BigEndian.Uint32(header[:4])
+		switch string(header[4:]) {
+		case "IDAT", "IEND":
+			return nil, nil
+		case "pHYs":
+			if length != 9 {
+				return nil, fmt.Errorf("malformed pHYs chunk")
+			}
+			var data [9]byte
+			if _, err := io.ReadFull(r, data[:]); err != nil {
+				return nil, err
+			}
+			if data[8] != 1 { // Unit: 1 = meter
+				return nil, nil
+			}
+			x, y := binary.BigEndian.Uint32(data[0:4]), binary.BigEndian.Uint32(data[4:8])
+			return newResolution(float64(x), float64(y), 0.0254), nil
+		}
+		if _, err := io.CopyN(io.Discard, r, int64(length)+4); err != nil { // Data and CRC
+			return nil, err
+		}
+	}
+}
+
+// readJPEGResolution reads the resolution of a JPEG file from its JFIF
+// (APP0) header or, if that has no unit, its Exif (APP1) header, stopping
+// at the start of scan.
+func readJPEGResolution(br *bufio.Reader) (*Resolution, error) {
+	if _, err := br.Discard(2); err != nil {
+		return nil, err
+	}
+	var exif *Resolution
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b != 0xff {
+			return nil, fmt.Errorf("malformed JPEG marker")
+		}
+		marker, err := br.ReadByte()
+		for err == nil && marker == 0xff { // Fill bytes
+			marker, err = br.ReadByte()
+		}
+		if err != nil {
+			return nil, err
+		}
+		if marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7) { // No length
+			continue
+		}
+		if marker == 0xda || marker == 0xd9 { // Start of scan, end of image
+			return exif, nil
+		}
+		var length [2]byte
+		if _, err := io.ReadFull(br, length[:]); err != nil {
+			return nil, err
+		}
+		n := int(binary.BigEndian.Uint16(length[:])) - 2
+		if n < 0 {
+			return nil, fmt.Errorf("malformed JPEG segment")
+		}
+		data := make([]byte, n)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return nil, err
+		}
+		switch {
+		case marker == 0xe0 && len(data) >= 12 && string(data[:5]) == "JFIF\x00":
+			// Version, unit (1 = inch, 2 = centimeter), X and Y density
+			x, y := float64(binary.BigEndian.Uint16(data[8:10])), float64(binary.BigEndian.Uint16(data[10:12]))
+			switch data[7] {
+			case 1:
+				return newResolution(x, y, 1), nil
+			case 2:
+				return newResolution(x, y, 2.54), nil
+			}
+		case marker == 0xe1 && len(data) > 14 && string(data[:6]) == "Exif\x00\x00":
+			if res, err := readTIFFResolution(bytes.NewReader(data[6:]), data[6:14]); err == nil {
+				exif = res
+			}
+		}
+	}
+}
+
+// TIFF tags of the resolution.
+const (
+	tiffXResolution    = 282
+	tiffYResolution    = 283
+	tiffResolutionUnit = 296
+)
+
+// readTIFFResolution reads the resolution tags of the first image of a
+// TIFF file, or of an Exif header, given its 8-byte header. The unit is
+// inches unless the ResolutionUnit tag says otherwise.
+func readTIFFResolution(r io.ReaderAt, header []byte) (*Resolution, error) {
+	var order binary.ByteOrder = binary.LittleEndian
+	if header[0] == 'M' {
+		order = binary.BigEndian
+	}
+	ifd := int64(order.Uint32(header[4:8]))
+	var count [2]byte
+	if _, err := r.ReadAt(count[:], ifd); err != nil {
+		return nil, err
+	}
+	entries := make([]byte, 12*int(order.Uint16(count[:])))
+	if _, err := r.ReadAt(entries, ifd+2); err != nil {
+		return nil, err
+	}
+	var x, y float64
+	unit := uint16(2) // 1 = none, 2 = inch, 3 = centimeter
+	for e := entries; len(e) >= 12; e = e[12:] {
+		switch order.Uint16(e) {
+		case tiffXResolution, tiffYResolution:
+			// A RATIONAL (5): numerator and denominator
+			if order.Uint16(e[2:4]) != 5 {
+				return nil, fmt.Errorf("malformed TIFF resolution tag")
+			}
+			var v [8]byte
+			if _, err := r.ReadAt(v[:], int64(order.Uint32(e[8:12]))); err != nil {
+				return nil, err
+			}
+			num, den := order.Uint32(v[0:4]), order.Uint32(v[4:8])
+			if den == 0 {
+				return nil, fmt.Errorf("malformed TIFF resolution tag")
+			}
+			if order.Uint16(e) == tiffXResolution {
+				x = float64(num) / float64(den)
+			} else {
+				y = float64(num) / float64(den)
+			}
+		case tiffResolutionUnit:
+			unit = order.Uint16(e[8:10])
+		}
+	}
+	switch unit {
+	case 2:
+		return newResolution(x, y, 1), nil
+	case 3:
+		return newResolution(x, y, 2.54), nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,134 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeJPEGWithSegment encodes img as a JPEG with an extra segment after
+// the start of image marker.
+func writeJPEGWithSegment(t *testing.T, img image.Image, marker byte, data []byte) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+	segment := binary.BigEndian.AppendUint16([]byte{0xff, marker}, uint16(len(data)+2))
+	out := append(append(append([]byte(nil), encoded[:2]...), append(segment, data...)...), encoded[2:]...)
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	if err := os.WriteFile(path, out, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadResolution(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 40, 30))
+
+	// 11811 pixels per meter is 300 DPI
+	phys := binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, 11811), 11811)
+	png300 := writePNGWithChunk(t, img, "pHYs", append(phys, 1))
+	pngAspect := writePNGWithChunk(t, img, "pHYs", append(phys, 0))
+
+	// JFIF 1.01 at 72×144 DPI
+	jfif := writeJPEGWithSegment(t, img, 0xe0, []byte{'J', 'F', 'I', 'F', 0, 1, 1, 1, 0, 72, 0, 144, 0, 0})
+
+	// Exif with a big-endian TIFF header and XResolution, YResolution of
+	// 118/1 per centimeter (299.72 DPI)
+	tiff := []byte("MM\x00*\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	for _, e := range [][3]uint32{{282, 5, 50}, {283, 5, 50}, {296, 3, 3 << 16}} {
+		tiff = binary.BigEndian.AppendUint16(tiff, uint16(e[0]))
+		tiff = binary.BigEndian.AppendUint16(tiff, uint16(e[1]))
+		tiff = binary.BigEndian.AppendUint32(tiff, 1)
+		tiff = binary.BigEndian.AppendUint32(tiff, e[2])
+	}
+	tiff = binary.BigEndian.AppendUint32(tiff, 0) // No next IFD
+	tiff = binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(tiff, 118), 1)
+	exif := writeJPEGWithSegment(t, img, 0xe1, append([]byte("Exif\x00\x00"), tiff...))
+
+	for _, tt := range []struct {
+		name string
+		path string
+		want *Resolution
+	}{
+		{"png", png300, &Resolution{X: 299.999, Y: 299.999}},
+		{"png aspect only", pngAspect, nil},
+		{"jfif", jfif, &Resolution{X: 72, Y: 144}},
+		{"exif", exif, &Resolution{X: 299.72, Y: 299.72}},
+	} {
+		got, err := ReadResolution(tt.path)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if (got == nil) != (tt.want == nil) || (got != nil && (absFloat(got.X-tt.want.X) > 0.01 || absFloat(got.Y-tt.want.Y) > 0.01)) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckSpecs(t *testing.T) {
+	dir := t.TempDir()
+	good := createTestImage(t, 1920, 1080, color.White)
+	defer os.Remove(good)
+	small := createTestImage(t, 800, 800, color.White)
+	defer os.Remove(small)
+	cache := NewImageCache()
+	specs := ImageSpecs{MinWidth: 1280, AspectRatio: 1.78, AspectTolerance: 1, Formats: []string{"PNG", "jpg"}, MaxFileSize: 1 << 20}
+
+	result, err := CheckSpecs(cache, []string{good, small, filepath.Join(dir, "missing.png")}, specs)
+	if err != nil {
+		t.Fatalf("CheckSpecs failed: %v", err)
+	}
+	if result.Passed || result.Checked != 3 || result.Failed != 2 {
+		t.Errorf("got passed %v, %d checked, %d failed; want false, 3, 2", result.Passed, result.Checked, result.Failed)
+	}
+	if g := result.Images[0]; !g.Passed || g.AspectRatio != 1.7778 || g.Format != "png" || len(g.Failures) != 0 {
+		t.Errorf("1920×1080: got %+v", g)
+	}
+	var checks []string
+	for _, f := range result.Images[1].Failures {
+		checks = append(checks, f.Check)
+	}
+	if len(checks) != 2 || checks[0] != "width" || checks[1] != "aspect_ratio" {
+		t.Errorf("800×800: got failures %v, want width and aspect_ratio", result.Images[1].Failures)
+	}
+	if f := result.Images[2].Failures; len(f) != 1 || f[0].Check != "load" {
+		t.Errorf("missing file: got failures %v, want load", f)
+	}
+
+	// Encoded without a pHYs chunk, the image has no resolution
+	result, err = CheckSpecs(cache, []string{good}, ImageSpecs{MinDPI: 144})
+	if err != nil {
+		t.Fatalf("CheckSpecs failed: %v", err)
+	}
+	if f := result.Images[0].Failures; result.Passed || len(f) != 1 || f[0].Check != "dpi" || result.Images[0].DPI != nil {
+		t.Errorf("min DPI: got %+v", result.Images[0])
+	}
+}
+
+func TestCheckSpecs_Errors(t *testing.T) {
+	cache := NewImageCache()
+	for _, tt := range []struct {
+		paths []string
+		specs ImageSpecs
+	}{
+		{nil, ImageSpecs{Width: 100}},
+		{[]string{"a.png"}, ImageSpecs{}},
+		{[]string{"a.png"}, ImageSpecs{MaxFileSize: -1}},
+		{[]string{"a.png"}, ImageSpecs{MinWidth: 200, MaxWidth: 100}},
+		{[]string{"a.png"}, ImageSpecs{Formats: []string{"bmp"}}},
+	} {
+		if _, err := CheckSpecs(cache, tt.paths, tt.specs); err == nil {
+			t.Errorf("%v %+v: expected an error", tt.paths, tt.specs)
+		}
+	}
+}
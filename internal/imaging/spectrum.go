@@ -0,0 +1,87 @@
+package imaging
+
+import (
+	"image"
+	"math"
+
+	"github.com/ironsheep/image-tools-mcp/internal/fft"
+)
+
+// grayWindow extracts an n×n grayscale window starting at (x0, y0),
+// mean-subtracted and tapered with a 2D Hann window, as a row-major
+// complex slice ready for fft.Transform2D.
+func grayWindow(img image.Image, x0, y0, n int) []complex128 {
+	data := make([]float64, n*n)
+	mean := 0.0
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			r, g, b, _ := img.At(x0+x, y0+y).RGBA()
+			v := (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 257
+			data[y*n+x] = v
+			mean += v
+		}
+	}
+	mean /= float64(n * n)
+
+	hann := fft.Hann(n)
+	out := make([]complex128, n*n)
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			out[y*n+x] = complex((data[y*n+x]-mean)*hann[x]*hann[y], 0)
+		}
+	}
+	return out
+}
+
+// windowSpectrum returns the power spectrum of the n×n grayscale window at
+// (x0, y0), and its total power without the DC term.
+func windowSpectrum(img image.Image, x0, y0, n int) ([]float64, float64) {
+	data := grayWindow(img, x0, y0, n)
+	fft.Transform2D(data, n, false)
+	power := fft.Power(data)
+	total := 0.0
+	for _, p := range power[1:] {
+		total += p
+	}
+	return power, total
+}
+
+// spectrumRMS converts the total power of an n×n window's spectrum to the
+// RMS of the tapered window (Parseval's theorem).
+func spectrumRMS(total float64, n int) float64 {
+	return math.Sqrt(total) / float64(n*n)
+}
+
+// peakShare returns the share (0-1) of total power in the 3×3
+// neighborhoods of spectrum index (u, v) and its mirror image (-u, -v),
+// which together hold the energy of one tapered sinusoid.
+func peakShare(power []float64, n, u, v int, total float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	near := make(map[int]bool, 18)
+	for _, c := range [2][2]int{{u, v}, {-u, -v}} {
+		for dv := -1; dv <= 1; dv++ {
+			for du := -1; du <= 1; du++ {
+				near[fft.Index(c[1]+dv, n)*n+fft.Index(c[0]+du, n)] = true
+			}
+		}
+	}
+	sum := 0.0
+	for i := range near {
+		sum += power[i]
+	}
+	return math.Min(sum/total, 1)
+}
+
+// peakFrequency refines the frequency of the spectrum peak at index
+// (u, v), returning it in cycles per pixel along x and y.
+func peakFrequency(power []float64, n, u, v int) (float64, float64) {
+	at := func(du, dv int) float64 {
+		return power[fft.Index(v+dv, n)*n+fft.Index(u+du, n)]
+	}
+	mid := at(0, 0)
+	fx := (float64(fft.Freq(u, n)) + fft.RefinePeak(at(-1, 0), mid, at(1, 0))) / float64(n)
+	fy := (float64(fft.Freq(v, n)) + fft.RefinePeak(at(0, -1), mid, at(0, 1))) / float64(n)
+	return fx, fy
+}
@@ -0,0 +1,261 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/disintegration/imaging"
+)
+
+// maxSpriteThumbnails limits how many cells may be returned with thumbnails,
+// keeping responses to a manageable size.
+const maxSpriteThumbnails = 100
+
+// SpriteGrid specifies an explicit layout for SplitSprites. Along each axis,
+// either the count (Columns/Rows) or the size (CellWidth/CellHeight) may be
+// given; an axis with neither is detected automatically.
+type SpriteGrid struct {
+	Columns    int
+	Rows       int
+	CellWidth  int
+	CellHeight int
+}
+
+// SpriteCell describes one cell of a sprite sheet.
+type SpriteCell struct {
+	// Index is the cell's position in reading order (left-to-right, top-to-bottom).
+	Index int `json:"index"`
+
+	// Row and Column are the cell's grid position (0-based).
+	Row    int `json:"row"`
+	Column int `json:"column"`
+
+	// Bounds is the cell's rectangle within the sheet.
+	Bounds Region `json:"bounds"`
+
+	// ContentBounds is the tight box around non-background pixels in sheet
+	// coordinates. Nil if the cell is empty.
+	ContentBounds *Region `json:"content_bounds,omitempty"`
+
+	// Empty is true if the cell contains only background pixels.
+	Empty bool `json:"empty"`
+
+	// ThumbnailBase64 is a PNG thumbnail of the cell, if requested.
+	ThumbnailBase64 string `json:"thumbnail_base64,omitempty"`
+}
+
+// SpriteSheetResult contains the cells found in a sprite sheet.
+type SpriteSheetResult struct {
+	// Width and Height are the sheet dimensions.
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// Columns and Rows are the grid dimensions.
+	Columns int `json:"columns"`
+	Rows    int `json:"rows"`
+
+	// Uniform is true if all cells share the same size, given in CellWidth
+	// and CellHeight. Those fields are 0 when the spacing is irregular.
+	Uniform    bool `json:"uniform"`
+	CellWidth  int  `json:"cell_width"`
+	CellHeight int  `json:"cell_height"`
+
+	// Background is the color treated as empty space, as hex ("#RRGGBB"),
+	// or "transparent".
+	Background string `json:"background"`
+
+	// Cells lists every cell in reading order.
+	Cells []SpriteCell `json:"cells"`
+}
+
+// SplitSprites divides a sprite sheet into cells.
+//
+// Parameters:
+//   - img: The sprite sheet.
+//   - grid: Explicit layout; zero fields are detected automatically.
+//   - thumbSize: If > 0, each cell is returned with a PNG thumbnail fitting
+//     within thumbSize×thumbSize.
+//
+// Returns:
+//   - *SpriteSheetResult: Grid layout and per-cell bounds.
+//   - error: Non-nil if the grid parameters are invalid, no sprites are
+//     found, or thumbnails were requested for more than 100 cells.
+//
+// # Automatic Detection
+//
+// The background color is taken from the top-left pixel (or treated as
+// transparent if that pixel is fully transparent). Columns and rows made up
+// entirely of background act as gutters; the spans between gutters become
+// cells. Sheets whose sprites touch with no gutter cannot be split this way
+// and need an explicit grid.
+func SplitSprites(img image.Image, grid SpriteGrid, thumbSize int) (*SpriteSheetResult, error) {
+	if grid.Columns < 0 || grid.Rows < 0 || grid.CellWidth < 0 || grid.CellHeight < 0 {
+		return nil, fmt.Errorf("grid parameters must be non-negative")
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	isBackground, bgName := spriteBackground(img)
+
+	xSpans, err := spriteAxis(w, grid.Columns, grid.CellWidth, func(x int) bool {
+		for y := 0; y < h; y++ {
+			if !isBackground(img.At(b.Min.X+x, b.Min.Y+y)) {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("columns: %w", err)
+	}
+	ySpans, err := spriteAxis(h, grid.Rows, grid.CellHeight, func(y int) bool {
+		for x := 0; x < w; x++ {
+			if !isBackground(img.At(b.Min.X+x, b.Min.Y+y)) {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+
+	count := len(xSpans) * len(ySpans)
+	if thumbSize > 0 && count > maxSpriteThumbnails {
+		return nil, fmt.Errorf("too many cells for thumbnails: %d (max %d)", count, maxSpriteThumbnails)
+	}
+
+	result := &SpriteSheetResult{
+		Width:      w,
+		Height:     h,
+		Columns:    len(xSpans),
+		Rows:       len(ySpans),
+		Background: bgName,
+		Cells:      make([]SpriteCell, 0, count),
+	}
+	if cw, ok := uniformSpan(xSpans); ok {
+		if ch, ok := uniformSpan(ySpans); ok {
+			result.Uniform = true
+			result.CellWidth = cw
+			result.CellHeight = ch
+		}
+	}
+
+	for row, ys := range ySpans {
+		for col, xs := range xSpans {
+			cell := SpriteCell{
+				Index:  len(result.Cells),
+				Row:    row,
+				Column: col,
+				Bounds: Region{X1: xs[0], Y1: ys[0], X2: xs[1], Y2: ys[1]},
+			}
+
+			for y := ys[0]; y < ys[1]; y++ {
+				for x := xs[0]; x < xs[1]; x++ {
+					if isBackground(img.At(b.Min.X+x, b.Min.Y+y)) {
+						continue
+					}
+					if cell.ContentBounds == nil {
+						cell.ContentBounds = &Region{X1: x, Y1: y, X2: x + 1, Y2: y + 1}
+						continue
+					}
+					cb := cell.ContentBounds
+					cb.X1 = minInt(cb.X1, x)
+					cb.Y1 = minInt(cb.Y1, y)
+					cb.X2 = maxInt(cb.X2, x+1)
+					cb.Y2 = maxInt(cb.Y2, y+1)
+				}
+			}
+			cell.Empty = cell.ContentBounds == nil
+
+			if thumbSize > 0 {
+				crop := imaging.Crop(img, image.Rect(b.Min.X+xs[0], b.Min.Y+ys[0], b.Min.X+xs[1], b.Min.Y+ys[1]))
+				encoded, err := encodePNGBase64(imaging.Fit(crop, thumbSize, thumbSize, imaging.Lanczos))
+				if err != nil {
+					return nil, err
+				}
+				cell.ThumbnailBase64 = encoded
+			}
+
+			result.Cells = append(result.Cells, cell)
+		}
+	}
+
+	return result, nil
+}
+
+// spriteBackground returns a predicate matching background pixels, based on
+// the top-left pixel, and a description of the background color.
+func spriteBackground(img image.Image) (func(color.Color) bool, string) {
+	b := img.Bounds()
+	ref := img.At(b.Min.X, b.Min.Y)
+	if _, _, _, a := ref.RGBA(); a == 0 {
+		return func(c color.Color) bool {
+			_, _, _, a := c.RGBA()
+			return a == 0
+		}, "transparent"
+	}
+
+	r, g, bl, _ := ref.RGBA()
+	name := fmt.Sprintf("#%02X%02X%02X", r>>8, g>>8, bl>>8)
+	return func(c color.Color) bool {
+		return pixelDiff(c, ref) <= pixelDiffThreshold
+	}, name
+}
+
+// spriteAxis splits one axis of length n into [start, end) spans. If count
+// or size is given, the axis is divided evenly (a partial trailing cell is
+// dropped); otherwise spans are the runs of positions where empty is false.
+func spriteAxis(n, count, size int, empty func(int) bool) ([][2]int, error) {
+	if count > 0 && size > 0 {
+		return nil, fmt.Errorf("specify either a count or a cell size, not both")
+	}
+	if count > 0 {
+		if count > n {
+			return nil, fmt.Errorf("count %d exceeds image size %d", count, n)
+		}
+		size = n / count
+	}
+	if size > 0 {
+		if size > n {
+			return nil, fmt.Errorf("cell size %d exceeds image size %d", size, n)
+		}
+		spans := make([][2]int, 0, n/size)
+		for start := 0; start+size <= n; start += size {
+			spans = append(spans, [2]int{start, start + size})
+		}
+		return spans, nil
+	}
+
+	var spans [][2]int
+	start := -1
+	for i := 0; i < n; i++ {
+		if empty(i) {
+			if start >= 0 {
+				spans = append(spans, [2]int{start, i})
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, n})
+	}
+	if len(spans) == 0 {
+		return nil, fmt.Errorf("no sprites found (image is entirely background)")
+	}
+	return spans, nil
+}
+
+// uniformSpan reports whether all spans have the same length, and that length.
+func uniformSpan(spans [][2]int) (int, bool) {
+	size := spans[0][1] - spans[0][0]
+	for _, s := range spans[1:] {
+		if s[1]-s[0] != size {
+			return 0, false
+		}
+	}
+	return size, true
+}
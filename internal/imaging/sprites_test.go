@@ -0,0 +1,142 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// createSpriteSheet draws cols×rows solid 10×10 sprites separated by 4px
+// gutters (and a 2px outer margin) on a white background. Sprites listed in
+// skip are left blank.
+func createSpriteSheet(cols, rows int, skip map[int]bool) *image.RGBA {
+	w, h := 2+cols*14, 2+rows*14
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{255, 255, 255, 255})
+		}
+	}
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			if skip[r*cols+c] {
+				continue
+			}
+			for y := 0; y < 10; y++ {
+				for x := 0; x < 10; x++ {
+					img.Set(2+c*14+x, 2+r*14+y, color.RGBA{uint8(40 * c), 0, uint8(80 * r), 255})
+				}
+			}
+		}
+	}
+	return img
+}
+
+func TestSplitSprites_Auto(t *testing.T) {
+	img := createSpriteSheet(4, 2, nil)
+
+	result, err := SplitSprites(img, SpriteGrid{}, 0)
+	if err != nil {
+		t.Fatalf("SplitSprites failed: %v", err)
+	}
+
+	if result.Columns != 4 || result.Rows != 2 {
+		t.Errorf("grid: got %dx%d, want 4x2", result.Columns, result.Rows)
+	}
+	if !result.Uniform || result.CellWidth != 10 || result.CellHeight != 10 {
+		t.Errorf("uniform: got %v %dx%d, want true 10x10", result.Uniform, result.CellWidth, result.CellHeight)
+	}
+	if result.Background != "#FFFFFF" {
+		t.Errorf("Background: got %s, want #FFFFFF", result.Background)
+	}
+	if len(result.Cells) != 8 {
+		t.Fatalf("cells: got %d, want 8", len(result.Cells))
+	}
+
+	c := result.Cells[5] // row 1, column 1
+	if c.Row != 1 || c.Column != 1 {
+		t.Errorf("cell 5 position: got row %d col %d", c.Row, c.Column)
+	}
+	if c.Bounds != (Region{X1: 16, Y1: 16, X2: 26, Y2: 26}) {
+		t.Errorf("cell 5 bounds: got %+v", c.Bounds)
+	}
+	if c.ThumbnailBase64 != "" {
+		t.Error("thumbnails should be omitted when thumbSize is 0")
+	}
+}
+
+func TestSplitSprites_ExplicitGrid(t *testing.T) {
+	img := createSpriteSheet(3, 3, map[int]bool{4: true})
+
+	result, err := SplitSprites(img, SpriteGrid{Columns: 3, CellHeight: 14}, 16)
+	if err != nil {
+		t.Fatalf("SplitSprites failed: %v", err)
+	}
+
+	if result.Columns != 3 || result.Rows != 3 {
+		t.Errorf("grid: got %dx%d, want 3x3", result.Columns, result.Rows)
+	}
+	if result.CellWidth != 14 || result.CellHeight != 14 {
+		t.Errorf("cell size: got %dx%d, want 14x14", result.CellWidth, result.CellHeight)
+	}
+
+	if !result.Cells[4].Empty || result.Cells[4].ContentBounds != nil {
+		t.Error("center cell should be empty")
+	}
+	cb := result.Cells[0].ContentBounds
+	if cb == nil || *cb != (Region{X1: 2, Y1: 2, X2: 12, Y2: 12}) {
+		t.Errorf("cell 0 content bounds: got %+v, want {2 2 12 12}", cb)
+	}
+	if result.Cells[0].ThumbnailBase64 == "" {
+		t.Error("cell 0 should have a thumbnail")
+	}
+}
+
+func TestSplitSprites_Transparent(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 30, 10))
+	for y := 2; y < 8; y++ {
+		for x := 2; x < 8; x++ {
+			img.Set(x, y, color.NRGBA{255, 0, 0, 255})
+			img.Set(x+20, y, color.NRGBA{255, 0, 0, 255})
+		}
+	}
+
+	result, err := SplitSprites(img, SpriteGrid{}, 0)
+	if err != nil {
+		t.Fatalf("SplitSprites failed: %v", err)
+	}
+
+	if result.Background != "transparent" {
+		t.Errorf("Background: got %s, want transparent", result.Background)
+	}
+	if result.Columns != 2 || result.Rows != 1 {
+		t.Errorf("grid: got %dx%d, want 2x1", result.Columns, result.Rows)
+	}
+}
+
+func TestSplitSprites_Errors(t *testing.T) {
+	img := createSpriteSheet(2, 2, nil)
+	blank := createInMemoryImage(20, 20, color.RGBA{255, 255, 255, 255})
+
+	tests := []struct {
+		name  string
+		img   image.Image
+		grid  SpriteGrid
+		thumb int
+	}{
+		{"count and size", img, SpriteGrid{Columns: 2, CellWidth: 10}, 0},
+		{"negative", img, SpriteGrid{Rows: -1}, 0},
+		{"count too large", img, SpriteGrid{Columns: 1000}, 0},
+		{"size too large", img, SpriteGrid{CellHeight: 1000}, 0},
+		{"blank sheet", blank, SpriteGrid{}, 0},
+		{"too many thumbnails", img, SpriteGrid{CellWidth: 1, CellHeight: 1}, 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := SplitSprites(tt.img, tt.grid, tt.thumb); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
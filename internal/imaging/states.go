@@ -0,0 +1,389 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+)
+
+// State comparison: at most maxStateChanges changed elements are listed,
+// each with up to stateSummaryColors colors per state. Colors are grouped
+// for summaries in buckets of stateColorBits bits per channel, and an
+// element is recolored if at least stateRecolorShare of its changed pixels
+// follow one mapping of before colors to after colors.
+const (
+	maxStateChanges    = 50
+	stateSummaryColors = 3
+	stateColorBits     = 4
+	stateRecolorShare  = 0.9
+	stateViewGap       = 8
+)
+
+// Kinds of StateChange.
+const (
+	StateRecolored = "recolored"
+	StateChanged   = "changed"
+)
+
+// stateMark is the color changed elements are outlined in.
+var stateMark = color.NRGBA{R: 255, G: 40, B: 40, A: 255}
+
+// StateCompareOptions configures CompareStates.
+type StateCompareOptions struct {
+	// Threshold is the smallest difference in any channel (1-255) for a
+	// pixel to have changed.
+	Threshold int
+
+	// MinArea is the fewest changed pixels an element needs to be
+	// reported; smaller clusters are noise.
+	MinArea int
+
+	// MergeDistance is the largest gap, in pixels, between changed pixels
+	// of one element, such as the letters of a label that changed color.
+	MergeDistance int
+
+	// IncludeImage adds the two states side by side with the changed
+	// elements outlined.
+	IncludeImage bool
+}
+
+// StateColor is one of the most common colors of an element in one state.
+type StateColor struct {
+	// Hex is the mean color (#RRGGBB) of the element's pixels of this
+	// color, grouped at 16 levels per channel.
+	Hex string `json:"hex"`
+
+	// Percent is the share of the element's pixels of this color.
+	Percent float64 `json:"percent"`
+}
+
+// StateChange is one UI element that differs between two states.
+type StateChange struct {
+	// Bounds is the element's box: the changed pixels' bounding box.
+	Bounds Region `json:"bounds"`
+
+	// ChangedPixels is the number of changed pixels in the box, and
+	// ChangedPercent their share of it.
+	ChangedPixels  int     `json:"changed_pixels"`
+	ChangedPercent float64 `json:"changed_percent"`
+
+	// Before and After are the box's most common colors in each state,
+	// most common first.
+	Before []StateColor `json:"before"`
+	After  []StateColor `json:"after"`
+
+	// Change is "recolored" if the element keeps its shape and only its
+	// colors change, as a button's background on hover, or "changed" if
+	// its content differs, as a focus ring or underline appearing.
+	Change string `json:"change"`
+}
+
+// StateCompareResult lists the elements that differ between two
+// screenshots of one UI in different states.
+type StateCompareResult struct {
+	// Width and Height are the size of the screenshots.
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// Changes are the changed elements, the most changed pixels first, up
+	// to 50.
+	Changes []StateChange `json:"changes"`
+
+	// TotalChanges counts all changed elements, including any beyond the
+	// 50 listed.
+	TotalChanges int `json:"total_changes"`
+
+	// ChangedPixels is the number of changed pixels in the elements.
+	ChangedPixels int `json:"changed_pixels"`
+
+	// NoisePixels is the number of pixels over the threshold that were
+	// ignored: antialiasing at edges that moved by a pixel, and clusters
+	// smaller than MinArea.
+	NoisePixels int `json:"noise_pixels"`
+
+	// ImageBase64 is the before and after screenshots side by side, with
+	// the changed elements outlined in red, as base64 PNG, if requested.
+	ImageBase64 string `json:"image_base64,omitempty"`
+
+	// MimeType is "image/png" when ImageBase64 is set.
+	MimeType string `json:"mime_type,omitempty"`
+}
+
+// CompareStates compares two screenshots of the same UI in different
+// states, such as normal and hover or active, and returns only the
+// elements that changed, with their colors in each state.
+//
+// Parameters:
+//   - before, after: The screenshots, of the same size.
+//   - opts: The noise thresholds, and whether to draw the changes.
+//
+// Returns:
+//   - *StateCompareResult: The changed elements' boxes and before and
+//     after colors.
+//   - error: Non-nil if the sizes differ, an option is out of range, or
+//     PNG encoding fails.
+//
+// # Noise
+//
+// A pixel has changed if some channel differs by at least Threshold.
+// Antialiased edges that shift by a subpixel amount change many pixels
+// slightly and some a lot; a changed pixel is antialiasing noise, as in
+// pixelmatch, if its after color appears among the before pixels around
+// it and its before color among the after pixels around it (3×3), so that
+// the edge only moved. Changed pixels within MergeDistance of each other
+// form an element, and elements with fewer than MinArea changed pixels
+// are dropped.
+//
+// # Change Kind
+//
+// An element is recolored if at least 90% of its changed pixels follow a
+// single mapping from before colors to after colors, each before color
+// (grouped at 16 levels per channel) becoming the after color its pixels
+// in and around the box (by MergeDistance, at least 2 pixels) most often
+// do: a hover background or a label turning blue. Otherwise its content
+// changed, as when text or an underline is drawn over a background whose
+// other pixels keep their color. A filled shape appearing on a plain
+// background, much larger than the margin around it, is recolored.
+func CompareStates(before, after image.Image, opts StateCompareOptions) (*StateCompareResult, error) {
+	bb, ab := before.Bounds(), after.Bounds()
+	if bb.Size() != ab.Size() {
+		return nil, fmt.Errorf("state screenshots differ in size: %dx%d and %dx%d", bb.Dx(), bb.Dy(), ab.Dx(), ab.Dy())
+	}
+	if opts.Threshold < 1 || opts.Threshold > 255 {
+		return nil, fmt.Errorf("threshold must be between 1 and 255, got %d", opts.Threshold)
+	}
+	if opts.MinArea < 1 {
+		return nil, fmt.Errorf("min_area must be positive, got %d", opts.MinArea)
+	}
+	if opts.MergeDistance < 0 || opts.MergeDistance > 64 {
+		return nil, fmt.Errorf("merge_distance must be between 0 and 64, got %d", opts.MergeDistance)
+	}
+	w, h := bb.Dx(), bb.Dy()
+	pa := make([]color.NRGBA, w*h)
+	pb := make([]color.NRGBA, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			pa[y*w+x] = nrgbaAt(before, bb.Min.X+x, bb.Min.Y+y)
+			pb[y*w+x] = nrgbaAt(after, ab.Min.X+x, ab.Min.Y+y)
+		}
+	}
+	differs := func(c, d color.NRGBA) bool {
+		return max(absInt(int(c.R)-int(d.R)), absInt(int(c.G)-int(d.G)), absInt(int(c.B)-int(d.B)), absInt(int(c.A)-int(d.A))) >= opts.Threshold
+	}
+	// near reports whether c appears in pix around (x, y)
+	near := func(pix []color.NRGBA, x, y int, c color.NRGBA) bool {
+		for ny := max(0, y-1); ny <= min(h-1, y+1); ny++ {
+			for nx := max(0, x-1); nx <= min(w-1, x+1); nx++ {
+				if (nx != x || ny != y) && !differs(pix[ny*w+nx], c) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	result := &StateCompareResult{Width: w, Height: h, Changes: []StateChange{}}
+	changed := make([]bool, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*w + x
+			if !differs(pa[i], pb[i]) {
+				continue
+			}
+			if near(pa, x, y, pb[i]) && near(pb, x, y, pa[i]) {
+				result.NoisePixels++
+				continue
+			}
+			changed[i] = true
+		}
+	}
+
+	var all []StateChange
+	for _, c := range stateClusters(changed, w, h, opts.MergeDistance) {
+		if c.pixels < opts.MinArea {
+			result.NoisePixels += c.pixels
+			continue
+		}
+		all = append(all, stateChange(c, changed, pa, pb, w, h, max(2, opts.MergeDistance)))
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].ChangedPixels > all[j].ChangedPixels })
+	result.TotalChanges = len(all)
+	for _, c := range all {
+		result.ChangedPixels += c.ChangedPixels
+	}
+	if len(all) > maxStateChanges {
+		all = all[:maxStateChanges]
+	}
+	result.Changes = append(result.Changes, all...)
+
+	if opts.IncludeImage {
+		view := image.NewNRGBA(image.Rect(0, 0, 2*w+stateViewGap, h))
+		for y := 0; y < h; y++ {
+			for x := 0; x < 2*w+stateViewGap; x++ {
+				view.SetNRGBA(x, y, color.NRGBA{R: 240, G: 240, B: 240, A: 255})
+			}
+			for x := 0; x < w; x++ {
+				view.SetNRGBA(x, y, blendNRGBA(color.NRGBA{R: 255, G: 255, B: 255, A: 255}, pa[y*w+x]))
+				view.SetNRGBA(w+stateViewGap+x, y, blendNRGBA(color.NRGBA{R: 255, G: 255, B: 255, A: 255}, pb[y*w+x]))
+			}
+		}
+		for _, c := range result.Changes {
+			r := Region{X1: c.Bounds.X1 - bb.Min.X - 1, Y1: c.Bounds.Y1 - bb.Min.Y - 1, X2: c.Bounds.X2 - bb.Min.X + 1, Y2: c.Bounds.Y2 - bb.Min.Y + 1}
+			outlineRegion(view, r, 1, stateMark)
+			r.X1, r.X2 = r.X1+w+stateViewGap, r.X2+w+stateViewGap
+			outlineRegion(view, r, 1, stateMark)
+		}
+		encoded, err := encodePNGBase64(view)
+		if err != nil {
+			return nil, err
+		}
+		result.ImageBase64, result.MimeType = encoded, "image/png"
+	}
+	for i := range result.Changes {
+		r := &result.Changes[i].Bounds
+		r.X1, r.Y1, r.X2, r.Y2 = r.X1+bb.Min.X, r.Y1+bb.Min.Y, r.X2+bb.Min.X, r.Y2+bb.Min.Y
+	}
+	return result, nil
+}
+
+// stateCluster is a group of changed pixels: its bounding box and size.
+type stateCluster struct {
+	bounds Region
+	pixels int
+}
+
+// stateClusters groups the set pixels of mask, a w×h image, that are
+// within gap pixels of each other (in both directions).
+func stateClusters(mask []bool, w, h, gap int) []stateCluster {
+	seen := make([]bool, w*h)
+	var clusters []stateCluster
+	var stack []int
+	for start := range mask {
+		if !mask[start] || seen[start] {
+			continue
+		}
+		c := stateCluster{bounds: Region{X1: w, Y1: h}}
+		seen[start] = true
+		stack = append(stack[:0], start)
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x, y := i%w, i/w
+			c.pixels++
+			c.bounds.X1, c.bounds.Y1 = min(c.bounds.X1, x), min(c.bounds.Y1, y)
+			c.bounds.X2, c.bounds.Y2 = max(c.bounds.X2, x+1), max(c.bounds.Y2, y+1)
+			for ny := max(0, y-gap-1); ny <= min(h-1, y+gap+1); ny++ {
+				for nx := max(0, x-gap-1); nx <= min(w-1, x+gap+1); nx++ {
+					if j := ny*w + nx; mask[j] && !seen[j] {
+						seen[j] = true
+						stack = append(stack, j)
+					}
+				}
+			}
+		}
+		clusters = append(clusters, c)
+	}
+	return clusters
+}
+
+// stateChange describes cluster c of the changed pixels between the
+// before pixels pa and after pixels pb, w×h, judging the change on the
+// cluster's box grown by margin pixels.
+func stateChange(c stateCluster, changed []bool, pa, pb []color.NRGBA, w, h, margin int) StateChange {
+	r := c.bounds
+	area := (r.X2 - r.X1) * (r.Y2 - r.Y1)
+	change := StateChange{
+		Bounds:         r,
+		ChangedPixels:  c.pixels,
+		ChangedPercent: float64(c.pixels) / float64(area) * 100,
+	}
+	// Map each before color around the box to the after color it most
+	// often becomes, so unchanged pixels hold their colors in the mapping
+	// and content drawn over them breaks it
+	g := Region{X1: max(0, r.X1-margin), Y1: max(0, r.Y1-margin), X2: min(w, r.X2+margin), Y2: min(h, r.Y2+margin)}
+	mapping := make(map[uint16]map[uint16]int)
+	for y := g.Y1; y < g.Y2; y++ {
+		for x := g.X1; x < g.X2; x++ {
+			from, to := stateBucket(pa[y*w+x]), stateBucket(pb[y*w+x])
+			if mapping[from] == nil {
+				mapping[from] = make(map[uint16]int)
+			}
+			mapping[from][to]++
+		}
+	}
+	mapped := make(map[uint16]uint16, len(mapping))
+	for from, to := range mapping {
+		best := -1
+		for k, n := range to {
+			if n > best || (n == best && k < mapped[from]) {
+				best, mapped[from] = n, k
+			}
+		}
+	}
+	followed, total := 0, 0
+	for y := r.Y1; y < r.Y2; y++ {
+		for x := r.X1; x < r.X2; x++ {
+			if i := y*w + x; changed[i] {
+				total++
+				if mapped[stateBucket(pa[i])] == stateBucket(pb[i]) {
+					followed++
+				}
+			}
+		}
+	}
+	change.Change = StateChanged
+	if float64(followed) >= stateRecolorShare*float64(total) {
+		change.Change = StateRecolored
+	}
+	change.Before = stateColors(pa, r, w)
+	change.After = stateColors(pb, r, w)
+	return change
+}
+
+// stateBucket groups a color at stateColorBits bits per channel.
+func stateBucket(c color.NRGBA) uint16 {
+	const shift = 8 - stateColorBits
+	return uint16(c.R>>shift)<<(2*stateColorBits) | uint16(c.G>>shift)<<stateColorBits | uint16(c.B>>shift)
+}
+
+// stateColors returns the most common colors of pix in r, w pixels wide,
+// grouped by stateBucket, with the mean color of each group.
+func stateColors(pix []color.NRGBA, r Region, w int) []StateColor {
+	type group struct {
+		key           uint16
+		n, sr, sg, sb int
+	}
+	groups := make(map[uint16]*group)
+	for y := r.Y1; y < r.Y2; y++ {
+		for x := r.X1; x < r.X2; x++ {
+			c := pix[y*w+x]
+			k := stateBucket(c)
+			g := groups[k]
+			if g == nil {
+				g = &group{key: k}
+				groups[k] = g
+			}
+			g.n, g.sr, g.sg, g.sb = g.n+1, g.sr+int(c.R), g.sg+int(c.G), g.sb+int(c.B)
+		}
+	}
+	sorted := make([]*group, 0, len(groups))
+	for _, g := range groups {
+		sorted = append(sorted, g)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].n != sorted[j].n {
+			return sorted[i].n > sorted[j].n
+		}
+		return sorted[i].key < sorted[j].key
+	})
+	total := (r.X2 - r.X1) * (r.Y2 - r.Y1)
+	colors := make([]StateColor, 0, stateSummaryColors)
+	for _, g := range sorted[:min(len(sorted), stateSummaryColors)] {
+		colors = append(colors, StateColor{
+			Hex:     fmt.Sprintf("#%02X%02X%02X", (g.sr+g.n/2)/g.n, (g.sg+g.n/2)/g.n, (g.sb+g.n/2)/g.n),
+			Percent: float64(g.n) / float64(total) * 100,
+		})
+	}
+	return colors
+}
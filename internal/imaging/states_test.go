@@ -0,0 +1,83 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// stateScene draws a 200×120 UI on white: a button with a black "OK"
+// label, filled with fill, a link underlined if underline is set, and
+// the antialiased left edge of a black bar starting at barX.
+func stateScene(fill color.RGBA, underline bool, barX int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 200, 120))
+	box := func(x1, y1, x2, y2 int, c color.Color) {
+		draw.Draw(img, image.Rect(x1, y1, x2, y2), image.NewUniform(c), image.Point{}, draw.Src)
+	}
+	box(0, 0, 200, 120, color.White)
+	box(20, 20, 100, 50, fill)
+	drawText(img, 50, 28, 0, "OK", color.Black)
+	drawText(img, 20, 80, 0, "More info", color.RGBA{0, 0, 200, 255})
+	if underline {
+		box(20, 95, 83, 96, color.RGBA{0, 0, 200, 255})
+	}
+	box(barX, 20, barX+1, 60, color.RGBA{128, 128, 128, 255})
+	box(barX+1, 20, 180, 60, color.Black)
+	return img
+}
+
+func TestCompareStates(t *testing.T) {
+	before := stateScene(color.RGBA{220, 220, 220, 255}, false, 150)
+	after := stateScene(color.RGBA{180, 200, 255, 255}, true, 151)
+	result, err := CompareStates(before, after, StateCompareOptions{Threshold: 24, MinArea: 16, MergeDistance: 4, IncludeImage: true})
+	if err != nil {
+		t.Fatalf("CompareStates failed: %v", err)
+	}
+	if result.TotalChanges != 2 || len(result.Changes) != 2 {
+		t.Fatalf("got %d changes, want the button and the underline: %+v", result.TotalChanges, result.Changes)
+	}
+	button, link := result.Changes[0], result.Changes[1]
+	if button.Bounds != (Region{X1: 20, Y1: 20, X2: 100, Y2: 50}) || button.Change != StateRecolored {
+		t.Errorf("button = %+v", button)
+	}
+	if button.Before[0].Hex != "#DCDCDC" || button.After[0].Hex != "#B4C8FF" {
+		t.Errorf("button colors: before %+v, after %+v", button.Before, button.After)
+	}
+	if link.Bounds != (Region{X1: 20, Y1: 95, X2: 83, Y2: 96}) || link.Change != StateChanged || link.ChangedPixels != 63 {
+		t.Errorf("link = %+v", link)
+	}
+	if result.NoisePixels != 80 {
+		t.Errorf("noise = %d pixels, want the bar's shifted edge (80)", result.NoisePixels)
+	}
+	if result.ImageBase64 == "" || result.MimeType != "image/png" {
+		t.Error("expected the side-by-side image")
+	}
+}
+
+func TestCompareStates_Identical(t *testing.T) {
+	img := stateScene(color.RGBA{220, 220, 220, 255}, false, 150)
+	result, err := CompareStates(img, img, StateCompareOptions{Threshold: 24, MinArea: 16})
+	if err != nil {
+		t.Fatalf("CompareStates failed: %v", err)
+	}
+	if result.TotalChanges != 0 || result.ChangedPixels != 0 || result.NoisePixels != 0 || result.ImageBase64 != "" {
+		t.Errorf("got %+v", result)
+	}
+}
+
+func TestCompareStates_Invalid(t *testing.T) {
+	img := stateScene(color.RGBA{220, 220, 220, 255}, false, 150)
+	if _, err := CompareStates(img, img.SubImage(image.Rect(0, 0, 100, 100)), StateCompareOptions{Threshold: 24, MinArea: 16}); err == nil {
+		t.Error("expected error for screenshots of different sizes")
+	}
+	for _, opts := range []StateCompareOptions{
+		{Threshold: 0, MinArea: 16},
+		{Threshold: 24, MinArea: 0},
+		{Threshold: 24, MinArea: 16, MergeDistance: 65},
+	} {
+		if _, err := CompareStates(img, img, opts); err == nil {
+			t.Errorf("expected error for %+v", opts)
+		}
+	}
+}
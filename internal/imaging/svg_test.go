@@ -0,0 +1,119 @@
+package imaging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSVG = `<svg xmlns="http://www.w3.org/2000/svg" width="100" height="50" viewBox="0 0 100 50">
+  <rect x="10" y="10" width="30" height="20" fill="#FF0000"/>
+</svg>`
+
+func TestIsSVG(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"diagram.svg", true},
+		{"/tmp/Diagram.SVG", true},
+		{"diagram.png", false},
+		{"svg", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsSVG(tt.path); got != tt.want {
+			t.Errorf("IsSVG(%q): got %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRasterizeSVG(t *testing.T) {
+	img, err := RasterizeSVG(strings.NewReader(testSVG), 1.0)
+	if err != nil {
+		t.Fatalf("RasterizeSVG failed: %v", err)
+	}
+
+	if img.Bounds().Dx() != 100 || img.Bounds().Dy() != 50 {
+		t.Errorf("size: got %dx%d, want 100x50", img.Bounds().Dx(), img.Bounds().Dy())
+	}
+
+	// Inside the rect: red
+	r, g, b, _ := img.At(25, 20).RGBA()
+	if r>>8 != 255 || g>>8 != 0 || b>>8 != 0 {
+		t.Errorf("rect color: got (%d,%d,%d), want (255,0,0)", r>>8, g>>8, b>>8)
+	}
+
+	// Outside the rect: white background
+	r, g, b, a := img.At(80, 40).RGBA()
+	if r>>8 != 255 || g>>8 != 255 || b>>8 != 255 || a>>8 != 255 {
+		t.Errorf("background: got (%d,%d,%d,%d), want opaque white", r>>8, g>>8, b>>8, a>>8)
+	}
+}
+
+func TestRasterizeSVG_Scale(t *testing.T) {
+	img, err := RasterizeSVG(strings.NewReader(testSVG), 2.0)
+	if err != nil {
+		t.Fatalf("RasterizeSVG failed: %v", err)
+	}
+
+	if img.Bounds().Dx() != 200 || img.Bounds().Dy() != 100 {
+		t.Errorf("size: got %dx%d, want 200x100", img.Bounds().Dx(), img.Bounds().Dy())
+	}
+
+	// Rect now spans (20,20)-(80,60)
+	r, g, _, _ := img.At(75, 55).RGBA()
+	if r>>8 != 255 || g>>8 != 0 {
+		t.Errorf("scaled rect color: got r=%d g=%d, want red", r>>8, g>>8)
+	}
+}
+
+func TestRasterizeSVG_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		svg   string
+		scale float64
+	}{
+		{"zero scale", testSVG, 0},
+		{"negative scale", testSVG, -1},
+		{"not xml", "this is not svg <<<", 1},
+		{"no size", `<svg xmlns="http://www.w3.org/2000/svg"></svg>`, 1},
+		{"too large", testSVG, 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := RasterizeSVG(strings.NewReader(tt.svg), tt.scale); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestImageCache_LoadSVG(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "diagram.svg")
+	if err := os.WriteFile(path, []byte(testSVG), 0644); err != nil {
+		t.Fatalf("failed to write SVG: %v", err)
+	}
+
+	cache := NewImageCache()
+	cache.SetSVGScale(1.5)
+	cache.SetSVGScale(0) // ignored
+
+	img, err := cache.Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if img.Bounds().Dx() != 150 || img.Bounds().Dy() != 75 {
+		t.Errorf("size: got %dx%d, want 150x75", img.Bounds().Dx(), img.Bounds().Dy())
+	}
+
+	info, err := LoadImageInfo(cache, path)
+	if err != nil {
+		t.Fatalf("LoadImageInfo failed: %v", err)
+	}
+	if info.Format != "svg" {
+		t.Errorf("Format: got %s, want svg", info.Format)
+	}
+}
@@ -0,0 +1,403 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/ironsheep/image-tools-mcp/internal/accel"
+)
+
+// Template matching constants.
+const (
+	// templateCoarseSide is the shortest side, in pixels, a template is
+	// shrunk to for the coarse search; templates under twice that are
+	// searched at full size.
+	templateCoarseSide = 8
+
+	// templateCoarseSlack is how far below the threshold a coarse score
+	// may fall for its position to be rescored at full size, since
+	// shrinking both images blurs detail that scores at full size.
+	templateCoarseSlack = 0.25
+
+	// maxTemplateCandidates is the most coarse positions rescored.
+	maxTemplateCandidates = 500
+
+	// maxTemplateMatches limits FindTemplate's maxMatches.
+	maxTemplateMatches = 100
+
+	// maxTemplatePixels is the largest template, in pixels, which keeps
+	// the exact window variance within an int64.
+	maxTemplatePixels = 1 << 20
+
+	// maxTemplateWork is the most pixel comparisons a pure-Go search may
+	// make, a few seconds' work.
+	maxTemplateWork = 4e9
+)
+
+// TemplateMatch is one place a template was found.
+type TemplateMatch struct {
+	// Bounds is where the template lies in the image.
+	Bounds Region `json:"bounds"`
+
+	// Score is the normalized cross-correlation (-1 to 1) of the template
+	// with the image there; 1 is an exact match up to brightness and
+	// contrast.
+	Score float64 `json:"score"`
+}
+
+// TemplateMatchResult is the result of FindTemplate.
+type TemplateMatchResult struct {
+	TemplateWidth  int `json:"template_width"`
+	TemplateHeight int `json:"template_height"`
+
+	// Matches are the places the template scored at least the threshold,
+	// best first, none overlapping a better one by more than half.
+	Matches []TemplateMatch `json:"matches"`
+	Count   int             `json:"count"`
+
+	// BestScore is the highest score found, even below the threshold, so
+	// a near miss shows how near it came.
+	BestScore float64 `json:"best_score"`
+
+	// Warnings note a blank template, which matches nothing.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// templateCandidate is a placement of the template, by its top-left
+// corner, and its score.
+type templateCandidate struct {
+	x, y  int
+	score float64
+}
+
+// FindTemplate finds every place a template appears in an image at the
+// same scale and orientation, such as each copy of an icon in a
+// screenshot.
+//
+// Parameters:
+//   - img: The image to search.
+//   - tmpl: The template to find, no larger than img and at most 2^20
+//     pixels.
+//   - threshold: The lowest score (0-1) that counts as a match.
+//   - maxMatches: Most matches to return (1-100).
+//
+// Returns:
+//   - *TemplateMatchResult: The matches, best first.
+//   - error: Non-nil if an argument is out of range, the template is
+//     larger than the image, or the search would take too long.
+//
+// # Method
+//
+// Both images are compared in luma. A placement's score is the zero-mean
+// normalized cross-correlation of the template with the pixels under it
+// (OpenCV's TM_CCOEFF_NORMED), so matches survive changes of brightness
+// and contrast; flat areas score 0. Built with OpenCV (see package accel),
+// every placement is scored by matchTemplate. Otherwise both images are
+// shrunk by the power of two that leaves the template's shorter side at
+// least 8 pixels, every placement is scored there (at each alignment of
+// the template to the shrunk pixels), and the best coarse positions (local
+// maxima within 0.25 of the threshold, up to 500) are rescored at full
+// size. Matches are then taken
+// best first, skipping any that overlaps a better one by more than half
+// the template. A template of one shade scores 0 everywhere, so it matches
+// nothing, with a warning.
+func FindTemplate(img, tmpl image.Image, threshold float64, maxMatches int) (*TemplateMatchResult, error) {
+	if threshold <= 0 || threshold > 1 {
+		return nil, fmt.Errorf("threshold must be between 0 and 1, got %v", threshold)
+	}
+	if maxMatches < 1 || maxMatches > maxTemplateMatches {
+		return nil, fmt.Errorf("max matches must be between 1 and %d, got %d", maxTemplateMatches, maxMatches)
+	}
+	ib, tb := img.Bounds(), tmpl.Bounds()
+	w, h, tw, th := ib.Dx(), ib.Dy(), tb.Dx(), tb.Dy()
+	if tw > w || th > h {
+		return nil, fmt.Errorf("template (%dx%d) is larger than the image (%dx%d)", tw, th, w, h)
+	}
+	if tw*th > maxTemplatePixels {
+		return nil, fmt.Errorf("template is too large: %dx%d (max %d pixels)", tw, th, maxTemplatePixels)
+	}
+
+	result := &TemplateMatchResult{TemplateWidth: tw, TemplateHeight: th, Matches: []TemplateMatch{}}
+	gray, tgray := grayBytes(img), grayBytes(tmpl)
+	full := newTemplateScorer(gray, w, h, tgray, tw, th)
+	if full.norm == 0 {
+		result.Warnings = append(result.Warnings, "template is blank: every pixel is the same shade")
+		return result, nil
+	}
+	var cands []templateCandidate
+	var best float64
+	if scores, ok := accel.MatchTemplate(gray, w, h, tgray, tw, th); ok {
+		pw := w - tw + 1
+		cands, best = templatePeaks(func(x, y int) float64 { return float64(scores[y*pw+x]) }, pw, h-th+1, threshold, len(scores))
+	} else {
+		var err error
+		if cands, best, err = full.search(tgray, threshold); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(cands, func(i, j int) bool {
+		a, b := cands[i], cands[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.y != b.y {
+			return a.y < b.y
+		}
+		return a.x < b.x
+	})
+	result.BestScore = math.Round(best*1000) / 1000
+	for _, c := range cands {
+		if c.score < threshold || len(result.Matches) == maxMatches {
+			break
+		}
+		r := Region{X1: ib.Min.X + c.x, Y1: ib.Min.Y + c.y, X2: ib.Min.X + c.x + tw, Y2: ib.Min.Y + c.y + th}
+		overlaps := false
+		for _, m := range result.Matches {
+			ix := min(r.X2, m.Bounds.X2) - max(r.X1, m.Bounds.X1)
+			iy := min(r.Y2, m.Bounds.Y2) - max(r.Y1, m.Bounds.Y1)
+			if ix > 0 && iy > 0 && 2*ix*iy > tw*th {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			result.Matches = append(result.Matches, TemplateMatch{Bounds: r, Score: math.Round(c.score*1000) / 1000})
+		}
+	}
+	result.Count = len(result.Matches)
+	return result, nil
+}
+
+// grayBytes returns the luma of img, row-major.
+func grayBytes(img image.Image) []uint8 {
+	b := img.Bounds()
+	gray := make([]uint8, b.Dx()*b.Dy())
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			gray[y*b.Dx()+x] = color.GrayModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.Gray).Y
+		}
+	}
+	return gray
+}
+
+// templateScorer scores placements of a template over an image, both
+// grayscale, by zero-mean normalized cross-correlation.
+type templateScorer struct {
+	gray   []uint8
+	w, h   int
+	tw, th int
+
+	// tmpl is the template less its mean, and norm the square root of its
+	// sum of squares; 0 if the template is flat.
+	tmpl []float64
+	norm float64
+
+	// sum and sumSq are integral images of the pixels and their squares,
+	// (w+1)×(h+1), for each window's variance.
+	sum, sumSq []int64
+}
+
+func newTemplateScorer(gray []uint8, w, h int, tgray []uint8, tw, th int) *templateScorer {
+	s := &templateScorer{gray: gray, w: w, h: h, tw: tw, th: th, tmpl: make([]float64, len(tgray))}
+	var sum, sumSq int64
+	for _, v := range tgray {
+		sum, sumSq = sum+int64(v), sumSq+int64(v)*int64(v)
+	}
+	n := int64(len(tgray))
+	if n*sumSq-sum*sum > 0 {
+		mean := float64(sum) / float64(n)
+		ss := 0.0
+		for i, v := range tgray {
+			d := float64(v) - mean
+			s.tmpl[i], ss = d, ss+d*d
+		}
+		s.norm = math.Sqrt(ss)
+	}
+
+	s.sum, s.sumSq = make([]int64, (w+1)*(h+1)), make([]int64, (w+1)*(h+1))
+	for y := 0; y < h; y++ {
+		var row, rowSq int64
+		for x := 0; x < w; x++ {
+			v := int64(gray[y*w+x])
+			row, rowSq = row+v, rowSq+v*v
+			i := (y+1)*(w+1) + x + 1
+			s.sum[i] = s.sum[i-w-1] + row
+			s.sumSq[i] = s.sumSq[i-w-1] + rowSq
+		}
+	}
+	return s
+}
+
+// score returns the score of the template with its top-left corner at
+// (x, y), or 0 where the image is flat.
+func (s *templateScorer) score(x, y int) float64 {
+	window := func(t []int64) int64 {
+		w := s.w + 1
+		return t[(y+s.th)*w+x+s.tw] - t[y*w+x+s.tw] - t[(y+s.th)*w+x] + t[y*w+x]
+	}
+	n := int64(s.tw * s.th)
+	sum := window(s.sum)
+	v := n*window(s.sumSq) - sum*sum // n² times the variance, exactly
+	if v <= 0 || s.norm == 0 {
+		return 0
+	}
+	dot := 0.0
+	for row := 0; row < s.th; row++ {
+		pixels := s.gray[(y+row)*s.w+x:][:s.tw]
+		t := s.tmpl[row*s.tw:][:s.tw]
+		for i, p := range pixels {
+			dot += t[i] * float64(p)
+		}
+	}
+	score := dot / (s.norm * math.Sqrt(float64(v)/float64(n)))
+	return math.Max(-1, math.Min(1, score))
+}
+
+// search returns the placements worth keeping at threshold and the best
+// score found, searching coarse-to-fine when the template (tgray) is large
+// enough to shrink.
+//
+// The image is shrunk once, by f, and the template once for each of the
+// f×f ways its pixels can fall into the image's blocks, so that every
+// placement is scored coarsely with the template aligned to the blocks.
+func (s *templateScorer) search(tgray []uint8, threshold float64) ([]templateCandidate, float64, error) {
+	pw, ph := s.w-s.tw+1, s.h-s.th+1
+	f := 1
+	for min(s.tw, s.th)/(2*f) >= templateCoarseSide {
+		f *= 2
+	}
+	var phases []*templateScorer
+	if f > 1 {
+		cw, ch := s.w/f, s.h/f
+		img := shrinkGray(s.gray, s.w, cw, ch, f)
+		for py := 0; py < f; py++ {
+			for px := 0; px < f; px++ {
+				ctw, cth := (s.tw-px)/f, (s.th-py)/f
+				coarse := newTemplateScorer(img, cw, ch, shrinkGray(tgray[py*s.tw+px:], s.tw, ctw, cth, f), ctw, cth)
+				if coarse.norm == 0 {
+					// Only fine detail, which shrinking erases
+					phases = nil
+					break
+				}
+				phases = append(phases, coarse)
+			}
+			if phases == nil {
+				break
+			}
+		}
+	}
+	if phases == nil {
+		if float64(pw)*float64(ph)*float64(s.tw*s.th) > maxTemplateWork {
+			return nil, 0, fmt.Errorf("search is too large: give a smaller target region")
+		}
+		cands, best := templatePeaks(s.score, pw, ph, threshold, pw*ph)
+		return cands, best, nil
+	}
+
+	work := float64(maxTemplateCandidates*9) * float64(s.tw*s.th)
+	for _, c := range phases {
+		work += float64(c.w-c.tw+1) * float64(c.h-c.th+1) * float64(c.tw*c.th)
+	}
+	if work > maxTemplateWork {
+		return nil, 0, fmt.Errorf("search is too large: give a smaller target region")
+	}
+	var peaks []templateCandidate
+	for i, c := range phases {
+		px, py := i%f, i/f
+		found, _ := templatePeaks(c.score, c.w-c.tw+1, c.h-c.th+1, threshold-templateCoarseSlack, maxTemplateCandidates)
+		if len(found) == 0 {
+			// Rescore the best coarse position anyway, for BestScore
+			found, _ = templatePeaks(c.score, c.w-c.tw+1, c.h-c.th+1, math.Inf(-1), 1)
+		}
+		for _, p := range found {
+			p.x, p.y = p.x*f-px, p.y*f-py
+			if p.x >= 0 && p.y >= 0 && p.x < pw && p.y < ph {
+				peaks = append(peaks, p)
+			}
+		}
+	}
+	sort.Slice(peaks, func(i, j int) bool { return peaks[i].score > peaks[j].score })
+	if len(peaks) > maxTemplateCandidates {
+		peaks = peaks[:maxTemplateCandidates]
+	}
+
+	// Rescore each at full size, a pixel either way for anti-aliasing
+	cands := make([]templateCandidate, 0, len(peaks))
+	best := math.Inf(-1)
+	for _, p := range peaks {
+		c := templateCandidate{score: math.Inf(-1)}
+		for y := max(0, p.y-1); y <= min(ph-1, p.y+1); y++ {
+			for x := max(0, p.x-1); x <= min(pw-1, p.x+1); x++ {
+				if v := s.score(x, y); v > c.score {
+					c = templateCandidate{x: x, y: y, score: v}
+				}
+			}
+		}
+		cands = append(cands, c)
+		best = math.Max(best, c.score)
+	}
+	return cands, best, nil
+}
+
+// shrinkGray returns a w×h image, each pixel the mean of an f×f block of
+// the image starting at src[0], stride pixels wide.
+func shrinkGray(src []uint8, stride, w, h, f int) []uint8 {
+	out := make([]uint8, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			sum := 0
+			for yy := y * f; yy < (y+1)*f; yy++ {
+				for _, v := range src[yy*stride+x*f:][:f] {
+					sum += int(v)
+				}
+			}
+			out[y*w+x] = uint8((sum + f*f/2) / (f * f))
+		}
+	}
+	return out
+}
+
+// templatePeaks scores every placement of a pw×ph grid and returns the
+// local maxima (no lower than any of their 8 neighbors) scoring at least
+// min, best first, at most limit of them, and the best score of all.
+func templatePeaks(score func(x, y int) float64, pw, ph int, min float64, limit int) ([]templateCandidate, float64) {
+	scores := make([]float64, pw*ph)
+	best := math.Inf(-1)
+	for y := 0; y < ph; y++ {
+		for x := 0; x < pw; x++ {
+			v := score(x, y)
+			scores[y*pw+x] = v
+			best = math.Max(best, v)
+		}
+	}
+	var peaks []templateCandidate
+	for y := 0; y < ph; y++ {
+		for x := 0; x < pw; x++ {
+			v := scores[y*pw+x]
+			if v < min {
+				continue
+			}
+			peak := true
+			for ny := max(0, y-1); ny <= y+1 && ny < ph && peak; ny++ {
+				for nx := max(0, x-1); nx <= x+1 && nx < pw; nx++ {
+					if scores[ny*pw+nx] > v {
+						peak = false
+						break
+					}
+				}
+			}
+			if peak {
+				peaks = append(peaks, templateCandidate{x: x, y: y, score: v})
+			}
+		}
+	}
+	sort.Slice(peaks, func(i, j int) bool { return peaks[i].score > peaks[j].score })
+	if len(peaks) > limit {
+		peaks = peaks[:limit]
+	}
+	return peaks, best
+}
@@ -0,0 +1,166 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/ironsheep/image-tools-mcp/internal/accel"
+)
+
+// templateScene returns a noisy 200x150 image with three copies of a 24x24
+// noise icon, the last at half contrast, and the icon.
+func templateScene() (*image.Gray, *image.Gray, []image.Point) {
+	rng := rand.New(rand.NewSource(7))
+	icon := image.NewGray(image.Rect(0, 0, 24, 24))
+	for i := range icon.Pix {
+		icon.Pix[i] = uint8(rng.Intn(256))
+	}
+	img := image.NewGray(image.Rect(0, 0, 200, 150))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(rng.Intn(256))
+	}
+	at := []image.Point{{10, 20}, {101, 33}, {150, 101}}
+	for n, p := range at {
+		for y := 0; y < 24; y++ {
+			for x := 0; x < 24; x++ {
+				v := icon.GrayAt(x, y).Y
+				if n == 2 {
+					v = 60 + v/2
+				}
+				img.SetGray(p.X+x, p.Y+y, color.Gray{v})
+			}
+		}
+	}
+	return img, icon, at
+}
+
+func TestFindTemplate(t *testing.T) {
+	img, icon, at := templateScene()
+
+	result, err := FindTemplate(img, icon, 0.8, 20)
+	if err != nil {
+		t.Fatalf("FindTemplate failed: %v", err)
+	}
+	if result.Count != 3 || result.TemplateWidth != 24 || result.TemplateHeight != 24 || result.BestScore != 1 {
+		t.Fatalf("got %+v", result)
+	}
+	found := make(map[image.Point]bool)
+	for _, m := range result.Matches {
+		if m.Score != 1 || m.Bounds.X2-m.Bounds.X1 != 24 || m.Bounds.Y2-m.Bounds.Y1 != 24 {
+			t.Errorf("match: got %+v", m)
+		}
+		found[image.Pt(m.Bounds.X1, m.Bounds.Y1)] = true
+	}
+	for _, p := range at {
+		if !found[p] {
+			t.Errorf("no match at %v: got %+v", p, result.Matches)
+		}
+	}
+
+	// Matches are reported in the image's coordinates
+	sub := img.SubImage(image.Rect(90, 20, 200, 150))
+	result, err = FindTemplate(sub, icon, 0.8, 1)
+	if err != nil {
+		t.Fatalf("FindTemplate failed: %v", err)
+	}
+	if result.Count != 1 || (result.Matches[0].Bounds != Region{X1: 101, Y1: 33, X2: 125, Y2: 57} &&
+		result.Matches[0].Bounds != Region{X1: 150, Y1: 101, X2: 174, Y2: 125}) {
+		t.Errorf("sub-image: got %+v", result.Matches)
+	}
+
+	// No match, but the near miss is reported
+	other := image.NewGray(image.Rect(0, 0, 24, 24))
+	rng := rand.New(rand.NewSource(99))
+	for i := range other.Pix {
+		other.Pix[i] = uint8(rng.Intn(256))
+	}
+	result, err = FindTemplate(img, other, 0.8, 20)
+	if err != nil {
+		t.Fatalf("FindTemplate failed: %v", err)
+	}
+	if result.Count != 0 || len(result.Matches) != 0 || result.BestScore <= 0 || result.BestScore >= 0.8 {
+		t.Errorf("unmatched: got %+v", result)
+	}
+}
+
+func TestFindTemplate_CoarseMatchesExhaustive(t *testing.T) {
+	img, icon, _ := templateScene()
+	gray, tgray := grayBytes(img), grayBytes(icon)
+	s := newTemplateScorer(gray, 200, 150, tgray, 24, 24)
+
+	coarse, best, err := s.search(tgray, 0.5)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	all, wantBest := templatePeaks(s.score, 177, 127, 0.5, 177*127)
+	if best != wantBest {
+		t.Errorf("best: got %v, want %v", best, wantBest)
+	}
+	got := make(map[templateCandidate]bool)
+	for _, c := range coarse {
+		got[c] = true
+	}
+	for _, c := range all {
+		if !got[c] {
+			t.Errorf("coarse search missed %+v", c)
+		}
+	}
+}
+
+func TestFindTemplate_MatchesOpenCV(t *testing.T) {
+	if !accel.Enabled {
+		t.Skip("built without OpenCV")
+	}
+	img, icon, _ := templateScene()
+	gray, tgray := grayBytes(img), grayBytes(icon)
+	scores, ok := accel.MatchTemplate(gray, 200, 150, tgray, 24, 24)
+	if !ok || len(scores) != 177*127 {
+		t.Fatalf("MatchTemplate: got %d scores, ok %v", len(scores), ok)
+	}
+	s := newTemplateScorer(gray, 200, 150, tgray, 24, 24)
+	for y := 0; y < 127; y++ {
+		for x := 0; x < 177; x++ {
+			if got, want := float64(scores[y*177+x]), s.score(x, y); math.Abs(got-want) > 1e-3 {
+				t.Fatalf("score at (%d,%d): OpenCV %v, Go %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestFindTemplate_Invalid(t *testing.T) {
+	img, icon, _ := templateScene()
+	tests := []struct {
+		name      string
+		tmpl      image.Image
+		threshold float64
+		max       int
+		want      string
+	}{
+		{"zero threshold", icon, 0, 20, "threshold"},
+		{"threshold above 1", icon, 1.5, 20, "threshold"},
+		{"no matches", icon, 0.8, 0, "max matches"},
+		{"too many matches", icon, 0.8, 101, "max matches"},
+		{"larger than image", image.NewGray(image.Rect(0, 0, 201, 10)), 0.8, 20, "larger than the image"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := FindTemplate(img, tt.tmpl, tt.threshold, tt.max)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+
+	// A blank template matches nothing
+	result, err := FindTemplate(img, image.NewGray(image.Rect(0, 0, 8, 8)), 0.8, 20)
+	if err != nil {
+		t.Fatalf("FindTemplate failed: %v", err)
+	}
+	if result.Count != 0 || result.Matches == nil || len(result.Warnings) != 1 {
+		t.Errorf("blank: got %+v", result)
+	}
+}
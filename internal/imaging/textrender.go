@@ -0,0 +1,378 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// Text rendering comparison: luma differences up to textRenderNoise are
+// ignored, and glyphs are compared at offsets of up to textRenderMaxShift
+// pixels. A region needs textRenderMinContrast levels between background
+// and ink to have text; its pixels from textRenderInk of the way from
+// background to ink are solid ink, and those from textRenderEdge
+// antialiasing.
+const (
+	textRenderNoise       = 8
+	textRenderMaxShift    = 2
+	textRenderMinContrast = 32
+	textRenderInk         = 0.8
+	textRenderEdge        = 0.15
+	maxTextRenderRegions  = 50
+)
+
+// Thresholds for classifying a text rendering change: the mean color
+// fringe of subpixel antialiasing, the similarity of glyphs that only
+// moved, and the shape overlap of glyphs only rendered differently.
+const (
+	textRenderSubpixelFringe = 24
+	textRenderShiftSimilar   = 0.98
+	textRenderShapeMatch     = 0.9
+)
+
+// Changes reported by TextRenderingDiff.
+const (
+	TextChangeNone         = "none"
+	TextChangeShifted      = "shifted"
+	TextChangeSubpixel     = "subpixel"
+	TextChangeAntialiasing = "antialiasing"
+	TextChangeGlyphs       = "glyphs"
+)
+
+// TextRendering describes how the text of a region is rendered in one
+// image.
+type TextRendering struct {
+	// HasText is false if the region has too little contrast for text;
+	// the other fields are 0 then.
+	HasText bool `json:"has_text"`
+
+	// InkPercent is the share of the region's pixels that are solid ink.
+	InkPercent float64 `json:"ink_percent"`
+
+	// AntialiasPercent is the share of the glyph pixels (ink and
+	// antialiasing) that are antialiasing, between background and ink:
+	// 0 for aliased text, higher for smoother or blurrier rendering.
+	AntialiasPercent float64 `json:"antialias_percent"`
+
+	// ColorFringe is the mean difference (0-255) of the antialiasing
+	// pixels' channels from a blend of the ink and background colors:
+	// near 0 for grayscale antialiasing, high for subpixel (ClearType)
+	// rendering, which antialiases each channel separately.
+	ColorFringe float64 `json:"color_fringe"`
+
+	// Subpixel is true if ColorFringe is at least 24.
+	Subpixel bool `json:"subpixel"`
+}
+
+// TextRegionDiff compares the rendering of one text region in two images.
+type TextRegionDiff struct {
+	// Bounds is the region, relative to the top-left of the images.
+	Bounds Region `json:"bounds"`
+
+	// Similarity (0.0 to 1.0) is the structural similarity of the
+	// region's luma in the two images, at the offset that matches best.
+	Similarity float64 `json:"similarity"`
+
+	// OffsetX and OffsetY are that offset of the second image's glyphs
+	// from the first's, up to 2 pixels.
+	OffsetX int `json:"offset_x"`
+	OffsetY int `json:"offset_y"`
+
+	// PixelsChanged is the number of pixels whose luma differs by more
+	// than 8, without an offset.
+	PixelsChanged int `json:"pixels_changed"`
+
+	// ShapeMatch (0.0 to 1.0) is the overlap, at the offset, of the glyph
+	// shapes: the pixels at least halfway from background to ink in each.
+	ShapeMatch float64 `json:"shape_match"`
+
+	// First and Second describe the rendering in each image.
+	First  TextRendering `json:"first"`
+	Second TextRendering `json:"second"`
+
+	// Change is "none"; "subpixel" if one rendering uses subpixel
+	// antialiasing and the other does not; "shifted" if the glyphs only
+	// moved; "antialiasing" if they keep their shapes but are smoothed
+	// differently, as with a hinting or gamma change; or "glyphs" if the
+	// shapes differ, as with a fallback font.
+	Change string `json:"change"`
+}
+
+// TextRenderingDiffResult compares the glyph rendering of the text
+// regions of two images.
+type TextRenderingDiffResult struct {
+	// Regions are the text regions compared, least similar first, up to
+	// 50.
+	Regions []TextRegionDiff `json:"regions"`
+
+	// Compared is the number of regions compared, and Changed the number
+	// whose Change is not "none".
+	Compared int `json:"compared"`
+	Changed  int `json:"changed"`
+
+	// Changes counts the regions of each change.
+	Changes map[string]int `json:"changes"`
+
+	// MinSimilarity is the lowest region similarity; 1 if no region was
+	// compared.
+	MinSimilarity float64 `json:"min_similarity"`
+}
+
+// TextRenderingDiff compares how the text in regions is rendered in two
+// screenshots, to catch font fallback and hinting or antialiasing
+// regressions that a plain pixel diff reports only as scattered changes.
+//
+// Parameters:
+//   - first, second: The images, aligned at their top-left corners.
+//   - regions: The text regions, relative to the top-left of the images;
+//     each is clipped to the area both images cover.
+//
+// Returns:
+//   - *TextRenderingDiffResult: Each region's similarity, offset, and
+//     rendering in both images, with the kind of change.
+//   - error: Non-nil if a region lies outside the compared area.
+//
+// # Method
+//
+// Each region's background is its most common luma and its ink the luma
+// farthest from it, in each image separately, so that light text on a
+// dark background is handled like dark on light. The structural
+// similarity (SSIM) of the two regions' luma is computed at every offset
+// up to 2 pixels, and the best is kept; text that only moved is similar
+// at its offset. The glyph shapes, thresholded halfway from background to
+// ink, are compared at that offset: shapes that overlap although the
+// pixels differ are the same glyphs rendered differently, and shapes that
+// do not are different glyphs.
+func TextRenderingDiff(first, second image.Image, regions []Region) (*TextRenderingDiffResult, error) {
+	fb, sb := first.Bounds(), second.Bounds()
+	w, h := min(fb.Dx(), sb.Dx()), min(fb.Dy(), sb.Dy())
+	result := &TextRenderingDiffResult{Regions: []TextRegionDiff{}, Changes: make(map[string]int), MinSimilarity: 1}
+	for _, r := range regions {
+		r = Region{X1: max(r.X1, 0), Y1: max(r.Y1, 0), X2: min(r.X2, w), Y2: min(r.Y2, h)}
+		if r.X2 <= r.X1 || r.Y2 <= r.Y1 {
+			return nil, fmt.Errorf("text region %+v is outside the compared %dx%d area", r, w, h)
+		}
+		diff := compareTextRegion(first, second, r)
+		result.Compared++
+		result.Changes[diff.Change]++
+		if diff.Change != TextChangeNone {
+			result.Changed++
+		}
+		result.MinSimilarity = math.Min(result.MinSimilarity, diff.Similarity)
+		result.Regions = append(result.Regions, diff)
+	}
+	sort.SliceStable(result.Regions, func(i, j int) bool { return result.Regions[i].Similarity < result.Regions[j].Similarity })
+	if len(result.Regions) > maxTextRenderRegions {
+		result.Regions = result.Regions[:maxTextRenderRegions]
+	}
+	return result, nil
+}
+
+// textPixels are the pixels of a text region of one image, with the
+// background and ink levels that place each between them.
+type textPixels struct {
+	w, h      int
+	pix       []color.NRGBA
+	luma      []float64
+	bg, ink   color.NRGBA
+	bgL, inkL float64
+}
+
+// readTextPixels reads region r of img, relative to its top-left, padded
+// by textRenderMaxShift pixels on every side with the nearest edge pixel.
+func readTextPixels(img image.Image, r Region) *textPixels {
+	b := img.Bounds()
+	pad := textRenderMaxShift
+	t := &textPixels{w: r.X2 - r.X1 + 2*pad, h: r.Y2 - r.Y1 + 2*pad}
+	t.pix = make([]color.NRGBA, t.w*t.h)
+	t.luma = make([]float64, t.w*t.h)
+	for y := 0; y < t.h; y++ {
+		for x := 0; x < t.w; x++ {
+			px := min(max(r.X1+x-pad, r.X1), r.X2-1)
+			py := min(max(r.Y1+y-pad, r.Y1), r.Y2-1)
+			c := nrgbaAt(img, b.Min.X+px, b.Min.Y+py)
+			t.pix[y*t.w+x], t.luma[y*t.w+x] = c, float64(luma(c))
+		}
+	}
+
+	// The background is the most common luma of the region itself, and
+	// the ink the luma farthest from it
+	var hist [256]int
+	for y := pad; y < t.h-pad; y++ {
+		for x := pad; x < t.w-pad; x++ {
+			hist[int(t.luma[y*t.w+x])]++
+		}
+	}
+	mode := 0
+	for l := range hist {
+		if hist[l] > hist[mode] {
+			mode = l
+		}
+	}
+	t.bgL, t.inkL = float64(mode), float64(mode)
+	bgFound := false
+	for y := pad; y < t.h-pad; y++ {
+		for x := pad; x < t.w-pad; x++ {
+			i := y*t.w + x
+			if !bgFound && t.luma[i] == t.bgL {
+				t.bg, bgFound = t.pix[i], true
+			}
+			if math.Abs(t.luma[i]-t.bgL) > math.Abs(t.inkL-t.bgL) {
+				t.ink, t.inkL = t.pix[i], t.luma[i]
+			}
+		}
+	}
+	return t
+}
+
+// level returns how far pixel i is from background (0) to ink (1).
+func (t *textPixels) level(i int) float64 {
+	return math.Abs(t.luma[i]-t.bgL) / math.Abs(t.inkL-t.bgL)
+}
+
+// hasText reports whether the region has enough contrast for text.
+func (t *textPixels) hasText() bool {
+	return math.Abs(t.inkL-t.bgL) >= textRenderMinContrast
+}
+
+// rendering describes the rendering of the region's text.
+func (t *textPixels) rendering() TextRendering {
+	var r TextRendering
+	if !t.hasText() {
+		return r
+	}
+	r.HasText = true
+	pad := textRenderMaxShift
+	ink, edge, pixels := 0, 0, 0
+	var fringe float64
+	for y := pad; y < t.h-pad; y++ {
+		for x := pad; x < t.w-pad; x++ {
+			i := y*t.w + x
+			pixels++
+			v := t.level(i)
+			if v >= textRenderInk {
+				ink++
+				continue
+			}
+			if v < textRenderEdge {
+				continue
+			}
+			edge++
+			// The color a grayscale blend of ink over background would have
+			c := t.pix[i]
+			fringe += max(
+				math.Abs(float64(c.R)-(float64(t.bg.R)+v*(float64(t.ink.R)-float64(t.bg.R)))),
+				math.Abs(float64(c.G)-(float64(t.bg.G)+v*(float64(t.ink.G)-float64(t.bg.G)))),
+				math.Abs(float64(c.B)-(float64(t.bg.B)+v*(float64(t.ink.B)-float64(t.bg.B)))),
+			)
+		}
+	}
+	r.InkPercent = math.Round(float64(ink)/float64(pixels)*1000) / 10
+	if ink+edge > 0 {
+		r.AntialiasPercent = math.Round(float64(edge)/float64(ink+edge)*1000) / 10
+	}
+	if edge > 0 {
+		r.ColorFringe = math.Round(fringe/float64(edge)*10) / 10
+	}
+	r.Subpixel = r.ColorFringe >= textRenderSubpixelFringe
+	return r
+}
+
+// compareTextRegion compares region r of two images.
+func compareTextRegion(first, second image.Image, r Region) TextRegionDiff {
+	a, b := readTextPixels(first, r), readTextPixels(second, r)
+	diff := TextRegionDiff{Bounds: r, First: a.rendering(), Second: b.rendering()}
+	pad := textRenderMaxShift
+	w, h := r.X2-r.X1, r.Y2-r.Y1
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := (y+pad)*a.w + x + pad
+			if math.Abs(a.luma[i]-b.luma[i]) > textRenderNoise {
+				diff.PixelsChanged++
+			}
+		}
+	}
+
+	// Offsets nearest first, so a tie keeps the smaller shift
+	diff.Similarity = -1
+	for _, d := range textRenderOffsets() {
+		if s := lumaSSIM(a, b, d.X, d.Y); s > diff.Similarity+1e-9 {
+			diff.Similarity, diff.OffsetX, diff.OffsetY = s, d.X, d.Y
+		}
+	}
+	diff.Similarity = math.Round(math.Max(0, diff.Similarity)*1000) / 1000
+
+	diff.ShapeMatch = 1
+	if diff.First.HasText || diff.Second.HasText {
+		both, either := 0, 0
+		for y := pad; y < pad+h; y++ {
+			for x := pad; x < pad+w; x++ {
+				inA := a.hasText() && a.level(y*a.w+x) >= 0.5
+				inB := b.hasText() && b.level((y+diff.OffsetY)*b.w+x+diff.OffsetX) >= 0.5
+				if inA && inB {
+					both++
+				}
+				if inA || inB {
+					either++
+				}
+			}
+		}
+		if either > 0 {
+			diff.ShapeMatch = math.Round(float64(both)/float64(either)*1000) / 1000
+		}
+	}
+
+	switch {
+	case diff.PixelsChanged == 0:
+		diff.Change = TextChangeNone
+	case diff.First.Subpixel != diff.Second.Subpixel:
+		diff.Change = TextChangeSubpixel
+	case (diff.OffsetX != 0 || diff.OffsetY != 0) && diff.Similarity >= textRenderShiftSimilar:
+		diff.Change = TextChangeShifted
+	case diff.ShapeMatch >= textRenderShapeMatch:
+		diff.Change = TextChangeAntialiasing
+	default:
+		diff.Change = TextChangeGlyphs
+	}
+	return diff
+}
+
+// textRenderOffsets returns the offsets up to textRenderMaxShift in each
+// direction, nearest first.
+func textRenderOffsets() []image.Point {
+	var offsets []image.Point
+	for dy := -textRenderMaxShift; dy <= textRenderMaxShift; dy++ {
+		for dx := -textRenderMaxShift; dx <= textRenderMaxShift; dx++ {
+			offsets = append(offsets, image.Pt(dx, dy))
+		}
+	}
+	sort.SliceStable(offsets, func(i, j int) bool {
+		return offsets[i].X*offsets[i].X+offsets[i].Y*offsets[i].Y < offsets[j].X*offsets[j].X+offsets[j].Y*offsets[j].Y
+	})
+	return offsets
+}
+
+// lumaSSIM returns the structural similarity (-1 to 1) of the luma of
+// the unpadded area of a and that of b offset by (dx, dy), computed over
+// the whole area as one window.
+func lumaSSIM(a, b *textPixels, dx, dy int) float64 {
+	const c1, c2 = (0.01 * 255) * (0.01 * 255), (0.03 * 255) * (0.03 * 255)
+	pad := textRenderMaxShift
+	var sumA, sumB, sumAA, sumBB, sumAB float64
+	n := 0
+	for y := pad; y < a.h-pad; y++ {
+		for x := pad; x < a.w-pad; x++ {
+			va, vb := a.luma[y*a.w+x], b.luma[(y+dy)*b.w+x+dx]
+			sumA, sumB = sumA+va, sumB+vb
+			sumAA, sumBB, sumAB = sumAA+va*va, sumBB+vb*vb, sumAB+va*vb
+			n++
+		}
+	}
+	fn := float64(n)
+	meanA, meanB := sumA/fn, sumB/fn
+	varA, varB := sumAA/fn-meanA*meanA, sumBB/fn-meanB*meanB
+	cov := sumAB/fn - meanA*meanB
+	return (2*meanA*meanB + c1) * (2*cov + c2) / ((meanA*meanA + meanB*meanB + c1) * (varA + varB + c2))
+}
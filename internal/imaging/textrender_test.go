@@ -0,0 +1,89 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// textLayer is a copy of a text line's glyphs, drawn dx pixels across in
+// color c beneath it.
+type textLayer struct {
+	dx int
+	c  color.Color
+}
+
+// textLine renders text at (x, 8) on a 120×30 white image, over layers
+// that add antialiasing or color fringes.
+func textLine(text string, x int, layers ...textLayer) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 120, 30))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	for _, l := range layers {
+		drawText(img, x+l.dx, 8, 0, text, l.c)
+	}
+	drawText(img, x, 8, 0, text, color.Black)
+	return img
+}
+
+func TestTextRenderingDiff(t *testing.T) {
+	base := textLine("Hello World", 4)
+	region := []Region{{X1: 0, Y1: 4, X2: 100, Y2: 26}}
+	for _, tc := range []struct {
+		name   string
+		second *image.RGBA
+		change string
+	}{
+		{"identical", textLine("Hello World", 4), TextChangeNone},
+		{"shifted", textLine("Hello World", 5), TextChangeShifted},
+		{"glyphs", textLine("Hxllq Wqrld", 4), TextChangeGlyphs},
+		{"antialiasing", textLine("Hello World", 4, textLayer{1, color.RGBA{170, 170, 170, 255}}), TextChangeAntialiasing},
+		{"subpixel", textLine("Hello World", 4, textLayer{-1, color.RGBA{255, 140, 140, 255}}, textLayer{1, color.RGBA{140, 140, 255, 255}}), TextChangeSubpixel},
+	} {
+		result, err := TextRenderingDiff(base, tc.second, region)
+		if err != nil {
+			t.Fatalf("%s: TextRenderingDiff failed: %v", tc.name, err)
+		}
+		d := result.Regions[0]
+		if d.Change != tc.change {
+			t.Errorf("%s: change = %s, want %s (%+v)", tc.name, d.Change, tc.change, d)
+		}
+		if changed := tc.change != TextChangeNone; (result.Changed == 1) != changed || result.Changes[tc.change] != 1 {
+			t.Errorf("%s: got %d changed, changes %v", tc.name, result.Changed, result.Changes)
+		}
+	}
+}
+
+func TestTextRenderingDiff_Rendering(t *testing.T) {
+	plain := textLine("Hello World", 4)
+	fringed := textLine("Hello World", 4, textLayer{-1, color.RGBA{255, 140, 140, 255}}, textLayer{1, color.RGBA{140, 140, 255, 255}})
+	result, err := TextRenderingDiff(plain, fringed, []Region{{X1: 0, Y1: 4, X2: 100, Y2: 26}})
+	if err != nil {
+		t.Fatalf("TextRenderingDiff failed: %v", err)
+	}
+	d := result.Regions[0]
+	if !d.First.HasText || d.First.AntialiasPercent != 0 || d.First.Subpixel {
+		t.Errorf("aliased text: got %+v", d.First)
+	}
+	if d.Second.AntialiasPercent == 0 || !d.Second.Subpixel || d.Second.InkPercent != d.First.InkPercent {
+		t.Errorf("fringed text: got %+v, first %+v", d.Second, d.First)
+	}
+	if d.OffsetX != 0 || d.OffsetY != 0 || d.ShapeMatch != 1 {
+		t.Errorf("got offset %d,%d and shape match %g", d.OffsetX, d.OffsetY, d.ShapeMatch)
+	}
+}
+
+func TestTextRenderingDiff_Blank(t *testing.T) {
+	blank := image.NewRGBA(image.Rect(0, 0, 40, 40))
+	result, err := TextRenderingDiff(blank, blank, []Region{{X1: 5, Y1: 5, X2: 30, Y2: 20}})
+	if err != nil {
+		t.Fatalf("TextRenderingDiff failed: %v", err)
+	}
+	d := result.Regions[0]
+	if d.First.HasText || d.Change != TextChangeNone || d.Similarity != 1 || d.ShapeMatch != 1 {
+		t.Errorf("got %+v", d)
+	}
+	if _, err := TextRenderingDiff(blank, blank, []Region{{X1: 50, Y1: 50, X2: 60, Y2: 60}}); err == nil {
+		t.Error("expected error for a region outside the images")
+	}
+}
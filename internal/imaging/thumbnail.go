@@ -0,0 +1,223 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"sync"
+
+	"github.com/disintegration/imaging"
+)
+
+// Limits for thumbnails and the ThumbnailCache.
+const (
+	// minThumbnailSize and maxThumbnailSize bound the longest side of a
+	// thumbnail, in pixels.
+	minThumbnailSize = 16
+	maxThumbnailSize = 1024
+
+	// thumbnailJPEGQuality is the quality of JPEG thumbnails.
+	thumbnailJPEGQuality = 85
+
+	// maxCachedThumbnails and maxCachedThumbnailBytes bound the
+	// ThumbnailCache; the oldest thumbnails are dropped beyond either.
+	maxCachedThumbnails     = 1024
+	maxCachedThumbnailBytes = 32 << 20
+)
+
+// ThumbnailResult contains a small preview of an image.
+type ThumbnailResult struct {
+	// Width and Height of the thumbnail in pixels.
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// OriginalWidth and OriginalHeight are the dimensions of the source
+	// image in pixels.
+	OriginalWidth  int `json:"original_width"`
+	OriginalHeight int `json:"original_height"`
+
+	// ImageBase64 is the thumbnail encoded as base64 PNG or JPEG.
+	ImageBase64 string `json:"image_base64"`
+
+	// MimeType is "image/png" or "image/jpeg".
+	MimeType string `json:"mime_type"`
+
+	// ToneMapped reports whether a dim 16-bit image was brightened to fill
+	// the 8-bit range of the thumbnail (see ToneMap).
+	ToneMapped bool `json:"tone_mapped,omitempty"`
+}
+
+// Thumbnail scales an image down so its longest side is at most maxSize
+// pixels, preserving the aspect ratio, and encodes it. Images already
+// within maxSize are encoded at their own size, never enlarged.
+//
+// Parameters:
+//   - img: Source image.
+//   - maxSize: Largest width or height of the thumbnail (16-1024).
+//   - format: "png", or "jpeg" for smaller photographs. JPEG has no
+//     transparency, so transparent areas are flattened onto white.
+//
+// Returns:
+//   - *ThumbnailResult: The encoded thumbnail with both sizes.
+//   - error: Non-nil if maxSize or format is invalid, or encoding fails.
+//
+// Downscaling uses Lanczos interpolation, like Crop, and 16-bit images
+// are tone-mapped to 8 bits first.
+func Thumbnail(img image.Image, maxSize int, format string) (*ThumbnailResult, error) {
+	if err := checkThumbnailParams(maxSize, format); err != nil {
+		return nil, err
+	}
+
+	b := img.Bounds()
+	src, toneMapped := previewSource(img, b)
+	thumb := imaging.Fit(src, maxSize, maxSize, imaging.Lanczos)
+	result := &ThumbnailResult{
+		Width:          thumb.Bounds().Dx(),
+		Height:         thumb.Bounds().Dy(),
+		OriginalWidth:  b.Dx(),
+		OriginalHeight: b.Dy(),
+		ToneMapped:     toneMapped,
+	}
+
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		flat := image.NewRGBA(thumb.Bounds())
+		draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.Draw(flat, flat.Bounds(), thumb, thumb.Bounds().Min, draw.Over)
+		if err := jpeg.Encode(&buf, flat, &jpeg.Options{Quality: thumbnailJPEGQuality}); err != nil {
+			return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+		}
+		result.MimeType = "image/jpeg"
+	} else {
+		if err := png.Encode(&buf, thumb); err != nil {
+			return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+		}
+		result.MimeType = "image/png"
+	}
+	result.ImageBase64 = base64.StdEncoding.EncodeToString(buf.Bytes())
+	return result, nil
+}
+
+// checkThumbnailParams validates the size and format of a thumbnail.
+func checkThumbnailParams(maxSize int, format string) error {
+	if maxSize < minThumbnailSize || maxSize > maxThumbnailSize {
+		return fmt.Errorf("max size must be between %d and %d, got %d", minThumbnailSize, maxThumbnailSize, maxSize)
+	}
+	if format != "png" && format != "jpeg" {
+		return fmt.Errorf("invalid format %q: must be png or jpeg", format)
+	}
+	return nil
+}
+
+// thumbnailKey identifies a cached thumbnail.
+type thumbnailKey struct {
+	path   string
+	size   int
+	format string
+}
+
+// ThumbnailCache keeps recently generated thumbnails keyed by path, size,
+// and format, so a client browsing a gallery of many files gets repeated
+// previews without decoding the full images again.
+//
+// Source images are read with ImageCache.Decode, so generating thumbnails
+// does not keep full-size images in memory. Like ImageCache, the cache
+// assumes files do not change while it is in use; Evict or Clear it when
+// they do.
+//
+// It holds at most 1024 thumbnails and 32 MB of encoded data, dropping
+// the oldest beyond either. ThumbnailCache is safe for concurrent use.
+type ThumbnailCache struct {
+	mu      sync.Mutex
+	entries map[thumbnailKey]*ThumbnailResult
+	order   []thumbnailKey // keys, oldest first
+	size    int            // total bytes of encoded thumbnails
+}
+
+// NewThumbnailCache creates an empty thumbnail cache.
+func NewThumbnailCache() *ThumbnailCache {
+	return &ThumbnailCache{entries: make(map[thumbnailKey]*ThumbnailResult)}
+}
+
+// Get returns the thumbnail of the image at path, generating it with
+// Thumbnail and caching it on first use.
+//
+// Parameters:
+//   - images: Cache the source image is read through.
+//   - path: Path to the image file.
+//   - maxSize, format: As for Thumbnail.
+//
+// Returns:
+//   - *ThumbnailResult: The thumbnail, shared with the cache; callers must
+//     not modify it.
+//   - error: Non-nil if the image cannot be loaded or the parameters are
+//     invalid.
+func (c *ThumbnailCache) Get(images *ImageCache, path string, maxSize int, format string) (*ThumbnailResult, error) {
+	key := thumbnailKey{path: path, size: maxSize, format: format}
+	c.mu.Lock()
+	cached, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	// Validate before decoding, so bad parameters do not cost a load
+	if err := checkThumbnailParams(maxSize, format); err != nil {
+		return nil, err
+	}
+	img, err := images.Decode(path)
+	if err != nil {
+		return nil, err
+	}
+	result, err := Thumbnail(img, maxSize, format)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if existing, ok := c.entries[key]; ok {
+		// Generated concurrently by another caller
+		return existing, nil
+	}
+	c.entries[key] = result
+	c.order = append(c.order, key)
+	c.size += len(result.ImageBase64)
+	for len(c.order) > maxCachedThumbnails || c.size > maxCachedThumbnailBytes {
+		oldest := c.order[0]
+		c.size -= len(c.entries[oldest].ImageBase64)
+		delete(c.entries, oldest)
+		c.order = c.order[1:]
+	}
+	return result, nil
+}
+
+// Evict removes every cached thumbnail of the image at path.
+func (c *ThumbnailCache) Evict(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	kept := c.order[:0]
+	for _, key := range c.order {
+		if key.path == path {
+			c.size -= len(c.entries[key].ImageBase64)
+			delete(c.entries, key)
+			continue
+		}
+		kept = append(kept, key)
+	}
+	c.order = kept
+}
+
+// Clear removes all cached thumbnails.
+func (c *ThumbnailCache) Clear() {
+	c.mu.Lock()
+	c.entries = make(map[thumbnailKey]*ThumbnailResult)
+	c.order = nil
+	c.size = 0
+	c.mu.Unlock()
+}
@@ -0,0 +1,148 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"testing"
+)
+
+func TestThumbnail(t *testing.T) {
+	img := createPatternImage(400, 200)
+
+	result, err := Thumbnail(img, 100, "png")
+	if err != nil {
+		t.Fatalf("Thumbnail failed: %v", err)
+	}
+	if result.Width != 100 || result.Height != 50 {
+		t.Errorf("size: got %dx%d, want 100x50", result.Width, result.Height)
+	}
+	if result.OriginalWidth != 400 || result.OriginalHeight != 200 || result.MimeType != "image/png" {
+		t.Errorf("got %+v", result)
+	}
+	data, err := base64.StdEncoding.DecodeString(result.ImageBase64)
+	if err != nil {
+		t.Fatalf("failed to decode base64: %v", err)
+	}
+	decoded, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to decode PNG: %v", err)
+	}
+	if decoded.Bounds().Dx() != 100 || decoded.Bounds().Dy() != 50 {
+		t.Errorf("PNG size: got %v", decoded.Bounds())
+	}
+}
+
+func TestThumbnail_NoEnlarge(t *testing.T) {
+	img := createInMemoryImage(30, 20, color.Black)
+	result, err := Thumbnail(img, 128, "png")
+	if err != nil {
+		t.Fatalf("Thumbnail failed: %v", err)
+	}
+	if result.Width != 30 || result.Height != 20 {
+		t.Errorf("size: got %dx%d, want 30x20", result.Width, result.Height)
+	}
+}
+
+func TestThumbnail_JPEGFlattensTransparency(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	result, err := Thumbnail(img, 32, "jpeg")
+	if err != nil {
+		t.Fatalf("Thumbnail failed: %v", err)
+	}
+	if result.MimeType != "image/jpeg" {
+		t.Errorf("MimeType: got %s, want image/jpeg", result.MimeType)
+	}
+	data, _ := base64.StdEncoding.DecodeString(result.ImageBase64)
+	decoded, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to decode JPEG: %v", err)
+	}
+	if r, g, b, _ := decoded.At(16, 16).RGBA(); r>>8 < 250 || g>>8 < 250 || b>>8 < 250 {
+		t.Errorf("transparent pixel: got (%d, %d, %d), want white", r>>8, g>>8, b>>8)
+	}
+}
+
+func TestThumbnail_Errors(t *testing.T) {
+	img := createInMemoryImage(50, 50, color.White)
+	for _, tt := range []struct {
+		size   int
+		format string
+	}{{8, "png"}, {2048, "png"}, {64, "gif"}} {
+		if _, err := Thumbnail(img, tt.size, tt.format); err == nil {
+			t.Errorf("size %d format %q: expected error", tt.size, tt.format)
+		}
+	}
+}
+
+func TestThumbnailCache(t *testing.T) {
+	images := NewImageCache()
+	thumbs := NewThumbnailCache()
+	imgPath := createTestImage(t, 200, 100, color.RGBA{0, 128, 255, 255})
+	defer os.Remove(imgPath)
+
+	first, err := thumbs.Get(images, imgPath, 64, "png")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if first.Width != 64 || first.Height != 32 {
+		t.Errorf("size: got %dx%d, want 64x32", first.Width, first.Height)
+	}
+
+	// The full-size image is not kept
+	images.mu.RLock()
+	loaded := len(images.images)
+	images.mu.RUnlock()
+	if loaded != 0 {
+		t.Errorf("image cache holds %d images, want 0", loaded)
+	}
+
+	// Cached by path and size, even once the file is gone
+	os.Remove(imgPath)
+	again, err := thumbs.Get(images, imgPath, 64, "png")
+	if err != nil || again != first {
+		t.Errorf("second Get: got %p, %v; want the cached thumbnail", again, err)
+	}
+	if _, err := thumbs.Get(images, imgPath, 32, "png"); err == nil {
+		t.Error("a different size should not be served from the cache")
+	}
+
+	thumbs.Evict(imgPath)
+	if _, err := thumbs.Get(images, imgPath, 64, "png"); err == nil {
+		t.Error("Evict should drop the cached thumbnail")
+	}
+	if thumbs.size != 0 || len(thumbs.order) != 0 {
+		t.Errorf("after Evict: size %d, %d keys", thumbs.size, len(thumbs.order))
+	}
+}
+
+func TestThumbnailCache_Bounded(t *testing.T) {
+	images := NewImageCache()
+	thumbs := NewThumbnailCache()
+	imgPath := createTestImage(t, 40, 40, color.White)
+	defer os.Remove(imgPath)
+
+	// Every size and format is a separate entry; only the newest are kept
+	for _, format := range []string{"png", "jpeg"} {
+		for size := minThumbnailSize; size <= maxThumbnailSize; size++ {
+			if _, err := thumbs.Get(images, imgPath, size, format); err != nil {
+				t.Fatalf("Get %s size %d failed: %v", format, size, err)
+			}
+		}
+	}
+	if got := len(thumbs.entries); got != maxCachedThumbnails || len(thumbs.order) != got {
+		t.Errorf("entries: got %d (%d keys), want %d", got, len(thumbs.order), maxCachedThumbnails)
+	}
+	if _, ok := thumbs.entries[thumbnailKey{imgPath, minThumbnailSize, "png"}]; ok {
+		t.Error("the oldest thumbnail should have been dropped")
+	}
+
+	thumbs.Clear()
+	if len(thumbs.entries) != 0 || thumbs.size != 0 {
+		t.Error("Clear did not empty the cache")
+	}
+}
@@ -0,0 +1,108 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+)
+
+// toneMapFullRange is the fraction of full scale at or above which a
+// 16-bit image's white point counts as using the whole range. Such images
+// are converted to 8 bits without being brightened.
+const toneMapFullRange = 0.9
+
+// toneMapMaxGain caps the brightening ToneMap applies: 16 maps 12-bit
+// data stored in 16-bit samples, as from many cameras and scientific
+// instruments, to the full range.
+const toneMapMaxGain = 16
+
+// is16Bit reports whether img holds 16 bits per channel.
+func is16Bit(img image.Image) bool {
+	switch img.(type) {
+	case *image.RGBA64, *image.NRGBA64, *image.Gray16:
+		return true
+	}
+	return false
+}
+
+// ToneMap converts a 16-bit image to 8 bits per channel for a preview.
+//
+// Channel values are rounded to the nearest 8-bit level, rather than
+// truncated as the generic conversions do. An image whose white point (the
+// brightest channel of the 99.9th-percentile visible pixel) is below 90%
+// of full scale, such as linear or 12-bit data stored in 16 bits, is also
+// brightened so the white point becomes full scale, by at most 16 times;
+// the brightest 0.1% of pixels clip. stretched reports whether it was.
+//
+// The result has img's bounds. 8-bit images are converted without
+// brightening.
+func ToneMap(img image.Image) (out *image.NRGBA, stretched bool) {
+	b := img.Bounds()
+
+	// Histogram of each visible pixel's brightest channel, in 4096 bins
+	var hist [4096]int
+	visible := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := nrgba64At(img, x, y)
+			if c.A == 0 {
+				continue
+			}
+			hist[max(c.R, c.G, c.B)>>4]++
+			visible++
+		}
+	}
+	gain := 1.0
+	if visible > 0 && is16Bit(img) {
+		remaining := visible / 1000
+		bin := len(hist) - 1
+		for ; bin > 0 && remaining >= hist[bin]; bin-- {
+			remaining -= hist[bin]
+		}
+		white := float64(bin+1) * 16
+		if white < toneMapFullRange*65535 {
+			gain = min(65535/white, toneMapMaxGain)
+		}
+	}
+
+	out = image.NewNRGBA(b)
+	scale := func(v uint16) uint8 {
+		return to8(uint16(min(float64(v)*gain+0.5, 65535)))
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := nrgba64At(img, x, y)
+			out.SetNRGBA(x, y, color.NRGBA{scale(c.R), scale(c.G), scale(c.B), to8(c.A)})
+		}
+	}
+	return out, gain != 1
+}
+
+// nrgba64At returns the non-premultiplied 16-bit color of a pixel,
+// reading the 16-bit image types directly.
+func nrgba64At(img image.Image, x, y int) color.NRGBA64 {
+	switch src := img.(type) {
+	case *image.NRGBA64:
+		return src.NRGBA64At(x, y)
+	case *image.Gray16:
+		v := src.Gray16At(x, y).Y
+		return color.NRGBA64{v, v, v, 0xffff}
+	case *image.RGBA64:
+		if c := src.RGBA64At(x, y); c.A == 0xffff {
+			return color.NRGBA64{c.R, c.G, c.B, c.A}
+		}
+	}
+	return color.NRGBA64Model.Convert(img.At(x, y)).(color.NRGBA64)
+}
+
+// previewSource returns the image a preview of r should be made from: a
+// 16-bit image's pixels in r, tone-mapped (see ToneMap), or img itself.
+// toneMapped reports whether the pixels were brightened.
+func previewSource(img image.Image, r image.Rectangle) (src image.Image, toneMapped bool) {
+	sub, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	})
+	if !is16Bit(img) || !ok || r.Empty() || !r.In(img.Bounds()) {
+		return img, false
+	}
+	return ToneMap(sub.SubImage(r))
+}
@@ -0,0 +1,67 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// gray16Ramp returns a 16-bit gray image whose pixels run from 0 to peak.
+func gray16Ramp(peak int) *image.Gray16 {
+	img := image.NewGray16(image.Rect(0, 0, 64, 64))
+	for i := 0; i < 64*64; i++ {
+		img.SetGray16(i%64, i/64, color.Gray16{uint16(i * peak / (64*64 - 1))})
+	}
+	return img
+}
+
+func TestToneMap(t *testing.T) {
+	// 12-bit data in 16-bit samples is brightened 16 times
+	out, stretched := ToneMap(gray16Ramp(4095))
+	if !stretched {
+		t.Error("12-bit data was not stretched")
+	}
+	if got := out.NRGBAAt(63, 63); got.R < 254 || got.A != 255 {
+		t.Errorf("brightest pixel: got %v", got)
+	}
+	if got := out.NRGBAAt(0, 32); got.R != 128 {
+		t.Errorf("mid pixel: got %v, want 128", got)
+	}
+
+	// Full-range data is only rounded
+	img := gray16Ramp(65535)
+	img.SetGray16(5, 0, color.Gray16{25829}) // 100.502 levels
+	out, stretched = ToneMap(img)
+	if stretched {
+		t.Error("full-range data was stretched")
+	}
+	if got := out.NRGBAAt(5, 0); got.R != 101 {
+		t.Errorf("got %v, want 101 (rounded, not truncated)", got)
+	}
+
+	// 8-bit images are never stretched
+	if _, stretched := ToneMap(createInMemoryImage(8, 8, color.RGBA{10, 10, 10, 255})); stretched {
+		t.Error("8-bit image was stretched")
+	}
+}
+
+func TestPreviews_ToneMap16Bit(t *testing.T) {
+	img := gray16Ramp(4095)
+	crop, err := Crop(img, 0, 32, 64, 64, 1)
+	if err != nil {
+		t.Fatalf("Crop failed: %v", err)
+	}
+	if !crop.ToneMapped || crop.Width != 64 || crop.Height != 32 {
+		t.Errorf("crop: got %dx%d, tone_mapped=%v", crop.Width, crop.Height, crop.ToneMapped)
+	}
+	thumb, err := Thumbnail(img, 32, "png")
+	if err != nil {
+		t.Fatalf("Thumbnail failed: %v", err)
+	}
+	if !thumb.ToneMapped {
+		t.Error("thumbnail was not tone-mapped")
+	}
+	if crop, _ := Crop(createInMemoryImage(8, 8, color.White), 0, 0, 4, 4, 1); crop.ToneMapped {
+		t.Error("8-bit crop was tone-mapped")
+	}
+}
@@ -0,0 +1,527 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+)
+
+// Kinds of network node recognized by their glyph's silhouette (see
+// NetworkNode).
+const (
+	NetworkRouter = "router"
+	NetworkSwitch = "switch"
+	NetworkServer = "server"
+	NetworkCloud  = "cloud"
+	NetworkDevice = "device"
+)
+
+// Topology extraction constants.
+const (
+	// maxNetworkTemplates limits the templates ExtractTopology accepts.
+	maxNetworkTemplates = 32
+
+	// topologyMaxHole is the largest hole filled in a glyph, as a share of
+	// the image's shorter side, so the area enclosed by a ring of links
+	// is not taken for a glyph.
+	topologyMaxHole = 0.4
+
+	// topologyCloudSolidity is the solidity below which a glyph's
+	// silhouette is bumpy enough to be a cloud.
+	topologyCloudSolidity = 0.93
+
+	// topologyBoxFill is the share of its bounding box a server's or
+	// switch's silhouette fills, and a router's rounded silhouette fills
+	// from topologyRoundMinFill to under topologyRoundMaxFill of it.
+	topologyBoxFill      = 0.8
+	topologyRoundMinFill = 0.7
+	topologyRoundMaxFill = 0.92
+
+	// topologyPathTolerance is how far, in pixels, a link's pixels may
+	// stray from its simplified path.
+	topologyPathTolerance = 2
+)
+
+// NetworkTemplate is an image of one kind of network icon, such as a
+// firewall, on a plain background.
+type NetworkTemplate struct {
+	Label string
+	Image image.Image
+}
+
+// TopologyOptions configures ExtractTopology.
+type TopologyOptions struct {
+	// Threshold is the luminance (1-255) below which pixels are ink, or 0
+	// to choose it with Otsu's method.
+	Threshold int
+
+	// MinNodeSize is the smallest width and height of a node's glyph, in
+	// pixels: 8 to 1000. Lines and text thinner than about a quarter of it
+	// are taken for links and labels.
+	MinNodeSize int
+
+	// Templates, if any, name each node by the template whose shape its
+	// glyph matches, before the built-in kinds are tried.
+	Templates []NetworkTemplate
+}
+
+// NetworkNode is one device or network of a topology diagram.
+type NetworkNode struct {
+	// ID is the node's number (1-based), in reading order: top to bottom,
+	// and left to right within a row.
+	ID int `json:"id"`
+
+	// Kind is the label of the template the glyph matched, or else
+	// "router" for a round glyph, "switch" for a wide box, "server" for a
+	// tall box, "cloud" for a bumpy outline, or "device" for any other.
+	Kind string `json:"kind"`
+
+	// Match is the overlap (0-1) of the glyph with the template it
+	// matched; omitted for the built-in kinds.
+	Match float64 `json:"match,omitempty"`
+
+	// Bounds is the glyph's box, and Center its middle.
+	Bounds Region `json:"bounds"`
+	Center Point  `json:"center"`
+}
+
+// NetworkLink is a line between two nodes.
+type NetworkLink struct {
+	// From and To are node IDs, From the first in reading order.
+	From int `json:"from"`
+	To   int `json:"to"`
+
+	// Shared is true if the line joins more than two nodes, such as a
+	// shared segment; it is then a link from the first node it joins to
+	// each of the others.
+	Shared bool `json:"shared,omitempty"`
+
+	// Path is the line's course from From to To, simplified to its
+	// corners.
+	Path []Point `json:"path"`
+}
+
+// TopologyResult is the graph of a network diagram.
+type TopologyResult struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// Nodes are the glyphs, in reading order.
+	Nodes []NetworkNode `json:"nodes"`
+
+	// Links are the lines between them, by From and then To.
+	Links []NetworkLink `json:"links"`
+}
+
+// ExtractTopology finds the device glyphs of a network diagram, such as
+// routers, switches, servers, and clouds, and the links between them.
+//
+// Returns an error if Threshold is not 0-255, MinNodeSize is not 8-1000,
+// there are more than 32 templates, or a template is unlabeled or blank.
+//
+// # Method
+//
+// Ink is the pixels darker than the threshold. Holes in it no larger than
+// 0.4 of the image's shorter side are filled, so outlined glyphs
+// become solid, and the ink is opened by a square a quarter of
+// MinNodeSize across (at least 3 pixels), which removes the links and
+// text. What remains, in pieces at least MinNodeSize wide and high, is
+// the glyphs.
+//
+// A glyph takes the label of the template whose mask, scaled to its
+// bounding box, overlaps its own by 0.6 (intersection over union) or
+// more, the most. Otherwise its silhouette decides: solidity under 0.93
+// with a wider than tall box is a cloud; a box filled by 0.8 or more is a
+// server if 1.3 times as tall as wide, a switch if 1.8 times as wide as
+// tall; a rounded glyph filling 0.7 to under 0.92 of its box, and no
+// more than 1.8 times as long as wide, is a router; anything else is a device.
+// These follow the common icon sets, drawn upright; templates cut from
+// the diagram itself match any other.
+//
+// Links are the connected ink outside the glyphs, each grown by the
+// opening's size, that touches two or more of them.
+func ExtractTopology(img image.Image, opts TopologyOptions) (*TopologyResult, error) {
+	if opts.Threshold < 0 || opts.Threshold > 255 {
+		return nil, fmt.Errorf("threshold must be 0 (automatic) to 255, got %d", opts.Threshold)
+	}
+	if opts.MinNodeSize < 8 || opts.MinNodeSize > 1000 {
+		return nil, fmt.Errorf("min node size must be 8 to 1000 pixels, got %d", opts.MinNodeSize)
+	}
+	if len(opts.Templates) > maxNetworkTemplates {
+		return nil, fmt.Errorf("need at most %d templates, got %d", maxNetworkTemplates, len(opts.Templates))
+	}
+	templates := make([]boardMask, len(opts.Templates))
+	for i, t := range opts.Templates {
+		if t.Label == "" {
+			return nil, fmt.Errorf("template %d needs a label", i)
+		}
+		tb := t.Image.Bounds()
+		r := Region{X1: 0, Y1: 0, X2: tb.Dx(), Y2: tb.Dy()}
+		mask, _, n := pieceMask(t.Image, r, outlineColor(t.Image, r))
+		if n == 0 {
+			return nil, fmt.Errorf("template %q is blank", t.Label)
+		}
+		templates[i] = mask
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	result := &TopologyResult{Width: w, Height: h, Nodes: []NetworkNode{}, Links: []NetworkLink{}}
+	if w == 0 || h == 0 {
+		return result, nil
+	}
+	lum := make([]uint8, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			lum[y*w+x] = luma(nrgbaAt(img, b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	threshold := opts.Threshold
+	if threshold == 0 {
+		threshold = otsuThreshold(lum)
+	}
+	ink := make([]bool, w*h)
+	for i, v := range lum {
+		ink[i] = int(v) < threshold
+	}
+
+	r := max(1, opts.MinNodeSize/8)
+	glyphs := openMask(fillHoles(ink, w, h, int(topologyMaxHole*float64(min(w, h)))), w, h, r)
+	nodes := make([]*blobStats, 0)
+	for _, s := range labelStrokes(glyphs, w, h) {
+		if s.x2-s.x1 >= opts.MinNodeSize && s.y2-s.y1 >= opts.MinNodeSize {
+			nodes = append(nodes, s)
+		}
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		a, c := nodes[i], nodes[j]
+		if overlap := min(a.y2, c.y2) - max(a.y1, c.y1); 2*overlap >= min(a.y2-a.y1, c.y2-c.y1) {
+			return a.x1 < c.x1
+		}
+		return a.y1 < c.y1
+	})
+
+	for i, s := range nodes {
+		n := NetworkNode{
+			ID:     i + 1,
+			Bounds: Region{X1: b.Min.X + s.x1, Y1: b.Min.Y + s.y1, X2: b.Min.X + s.x2, Y2: b.Min.Y + s.y2},
+			Center: Point{X: b.Min.X + (s.x1+s.x2)/2, Y: b.Min.Y + (s.y1+s.y2)/2},
+		}
+		if len(templates) > 0 {
+			// The background from just outside the glyph, its mask from within
+			pad := Region{X1: max(s.x1-r-2, 0), Y1: max(s.y1-r-2, 0), X2: min(s.x2+r+2, w), Y2: min(s.y2+r+2, h)}
+			mask, _, _ := pieceMask(img, Region{X1: s.x1, Y1: s.y1, X2: s.x2, Y2: s.y2}, outlineColor(img, pad))
+			for k, t := range templates {
+				if m := maskOverlap(mask, t); m >= minTemplateMatch && m > n.Match {
+					n.Kind, n.Match = opts.Templates[k].Label, math.Round(m*1000)/1000
+				}
+			}
+		}
+		if n.Kind == "" {
+			n.Kind = glyphKind(s)
+		}
+		result.Nodes = append(result.Nodes, n)
+	}
+
+	result.Links = topologyLinks(ink, w, h, nodes, 2*r+2)
+	for i := range result.Links {
+		for j := range result.Links[i].Path {
+			result.Links[i].Path[j].X += b.Min.X
+			result.Links[i].Path[j].Y += b.Min.Y
+		}
+	}
+	return result, nil
+}
+
+// glyphKind returns the built-in kind of the glyph s from its silhouette.
+func glyphKind(s *blobStats) string {
+	gw, gh := float64(s.x2-s.x1), float64(s.y2-s.y1)
+	fill := float64(s.area) / (gw * gh)
+	shape := blobShape(s)
+	switch {
+	case shape.Solidity < topologyCloudSolidity:
+		if gw >= gh {
+			return NetworkCloud
+		}
+		return NetworkDevice
+	case fill >= topologyBoxFill && gh >= 1.3*gw:
+		return NetworkServer
+	case fill >= topologyBoxFill && gw >= 1.8*gh:
+		return NetworkSwitch
+	case fill >= topologyRoundMinFill && fill < topologyRoundMaxFill && max(gw, gh) <= 1.8*min(gw, gh):
+		return NetworkRouter
+	}
+	return NetworkDevice
+}
+
+// fillHoles returns mask, an image w pixels wide, with its holes filled:
+// 4-connected runs of unset pixels not touching the image's edge, no more
+// than maxSize pixels wide and high.
+func fillHoles(mask []bool, w, h, maxSize int) []bool {
+	out := append([]bool(nil), mask...)
+	seen := make([]bool, len(mask))
+	var run, stack []int
+	for start, set := range mask {
+		if set || seen[start] {
+			continue
+		}
+		seen[start] = true
+		run, stack = run[:0], append(stack[:0], start)
+		edge := false
+		x1, y1, x2, y2 := w, h, 0, 0
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			run = append(run, i)
+			x, y := i%w, i/w
+			x1, y1, x2, y2 = min(x1, x), min(y1, y), max(x2, x+1), max(y2, y+1)
+			if x == 0 || y == 0 || x == w-1 || y == h-1 {
+				edge = true
+			}
+			for _, j := range [4]int{i - w, i + w, i - 1, i + 1} {
+				if j < 0 || j >= len(mask) || (j == i-1 && x == 0) || (j == i+1 && x == w-1) {
+					continue
+				}
+				if !mask[j] && !seen[j] {
+					seen[j] = true
+					stack = append(stack, j)
+				}
+			}
+		}
+		if !edge && x2-x1 <= maxSize && y2-y1 <= maxSize {
+			for _, i := range run {
+				out[i] = true
+			}
+		}
+	}
+	return out
+}
+
+// openMask returns mask, an image w pixels wide, opened by a square 2r+1
+// pixels across: the pixels of the squares that fit wholly within it.
+func openMask(mask []bool, w, h, r int) []bool {
+	integral := func(m []bool) []int32 {
+		sum := make([]int32, (w+1)*(h+1))
+		for y := 0; y < h; y++ {
+			var row int32
+			for x := 0; x < w; x++ {
+				if m[y*w+x] {
+					row++
+				}
+				sum[(y+1)*(w+1)+x+1] = sum[y*(w+1)+x+1] + row
+			}
+		}
+		return sum
+	}
+	count := func(sum []int32, x1, y1, x2, y2 int) int32 {
+		x1, y1, x2, y2 = max(x1, 0), max(y1, 0), min(x2, w), min(y2, h)
+		return sum[y2*(w+1)+x2] - sum[y1*(w+1)+x2] - sum[y2*(w+1)+x1] + sum[y1*(w+1)+x1]
+	}
+	k := int32(2*r + 1)
+	full := integral(mask)
+	eroded := make([]bool, len(mask))
+	for y := r; y < h-r; y++ {
+		for x := r; x < w-r; x++ {
+			eroded[y*w+x] = count(full, x-r, y-r, x+r+1, y+r+1) == k*k
+		}
+	}
+	centers := integral(eroded)
+	out := make([]bool, len(mask))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			out[y*w+x] = count(centers, x-r, y-r, x+r+1, y+r+1) > 0
+		}
+	}
+	return out
+}
+
+// labelStrokes returns the 8-connected components of mask, an image w
+// pixels wide, with their holes left open.
+func labelStrokes(mask []bool, w, h int) []*blobStats {
+	seen := make([]bool, len(mask))
+	var stats []*blobStats
+	var stack []int
+	for start, set := range mask {
+		if !set || seen[start] {
+			continue
+		}
+		s := &blobStats{x1: w, y1: h}
+		seen[start] = true
+		stack = append(stack[:0], start)
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x, y := i%w, i/w
+			s.add(x, y)
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := x+dx, y+dy
+					if nx < 0 || ny < 0 || nx >= w || ny >= h {
+						continue
+					}
+					if j := ny*w + nx; mask[j] && !seen[j] {
+						seen[j] = true
+						stack = append(stack, j)
+					}
+				}
+			}
+		}
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+// topologyLinks returns the links between nodes: the 8-connected ink of
+// an image w pixels wide outside the nodes, each grown by margin pixels,
+// that touches two or more of them.
+func topologyLinks(ink []bool, w, h int, nodes []*blobStats, margin int) []NetworkLink {
+	// Each node's pixels, grown by margin, by a search out from them
+	owner := make([]int32, w*h)
+	dist := make([]int32, w*h)
+	for i := range owner {
+		owner[i] = -1
+	}
+	var queue []int
+	for n, s := range nodes {
+		for k, row := range s.rows {
+			y := s.y1 + k
+			for x := row[0]; x < row[1]; x++ {
+				// Rows span the node's pixels, and its hull between them
+				owner[y*w+x] = int32(n)
+				queue = append(queue, y*w+x)
+			}
+		}
+	}
+	for q := 0; q < len(queue); q++ {
+		i := queue[q]
+		if dist[i] >= int32(margin) {
+			continue
+		}
+		x, y := i%w, i/w
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				nx, ny := x+dx, y+dy
+				if nx < 0 || ny < 0 || nx >= w || ny >= h {
+					continue
+				}
+				if j := ny*w + nx; owner[j] < 0 {
+					owner[j], dist[j] = owner[i], dist[i]+1
+					queue = append(queue, j)
+				}
+			}
+		}
+	}
+
+	var links []NetworkLink
+	comp := make([]bool, w*h)
+	var stack []int
+	for start, set := range ink {
+		if !set || owner[start] >= 0 || comp[start] {
+			continue
+		}
+		var pixels []int
+		contacts := make(map[int][]int)
+		comp[start] = true
+		stack = append(stack[:0], start)
+		for len(stack) > 0 {
+			p := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			pixels = append(pixels, p)
+			x, y := p%w, p/w
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := x+dx, y+dy
+					if nx < 0 || ny < 0 || nx >= w || ny >= h {
+						continue
+					}
+					q := ny*w + nx
+					if o := owner[q]; o >= 0 {
+						if c := contacts[int(o)]; len(c) == 0 || c[len(c)-1] != p {
+							contacts[int(o)] = append(c, p)
+						}
+					} else if ink[q] && !comp[q] {
+						comp[q] = true
+						stack = append(stack, q)
+					}
+				}
+			}
+		}
+		if len(contacts) < 2 {
+			continue
+		}
+		touching := make([]int, 0, len(contacts))
+		for n := range contacts {
+			touching = append(touching, n)
+		}
+		sort.Ints(touching)
+		in := make(map[int]bool, len(pixels))
+		for _, p := range pixels {
+			in[p] = true
+		}
+		for _, n := range touching[1:] {
+			links = append(links, NetworkLink{
+				From:   touching[0] + 1,
+				To:     n + 1,
+				Shared: len(touching) > 2,
+				Path:   linkPath(in, w, h, contacts[touching[0]], contacts[n]),
+			})
+		}
+	}
+	sort.SliceStable(links, func(i, j int) bool {
+		if links[i].From != links[j].From {
+			return links[i].From < links[j].From
+		}
+		return links[i].To < links[j].To
+	})
+	return links
+}
+
+// linkPath returns the shortest course through the pixels in, of an image
+// w pixels wide, from those of from to those of to, simplified to within
+// topologyPathTolerance pixels.
+func linkPath(in map[int]bool, w, h int, from, to []int) []Point {
+	target := make(map[int]bool, len(to))
+	for _, p := range to {
+		target[p] = true
+	}
+	prev := make(map[int]int, len(in))
+	queue := make([]int, 0, len(in))
+	for _, p := range from {
+		if _, ok := prev[p]; !ok {
+			prev[p] = -1
+			queue = append(queue, p)
+		}
+	}
+	end := -1
+	for q := 0; q < len(queue) && end < 0; q++ {
+		p := queue[q]
+		if target[p] {
+			end = p
+			break
+		}
+		x, y := p%w, p/w
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				nx, ny := x+dx, y+dy
+				if nx < 0 || ny < 0 || nx >= w || ny >= h {
+					continue
+				}
+				if j := ny*w + nx; in[j] {
+					if _, ok := prev[j]; !ok {
+						prev[j] = p
+						queue = append(queue, j)
+					}
+				}
+			}
+		}
+	}
+	var course []Point
+	for p := end; p >= 0; p = prev[p] {
+		course = append(course, Point{X: p % w, Y: p / w})
+	}
+	for i, j := 0, len(course)-1; i < j; i, j = i+1, j-1 {
+		course[i], course[j] = course[j], course[i]
+	}
+	return simplifyPath(course, topologyPathTolerance)
+}
@@ -0,0 +1,141 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+)
+
+// networkDiagram returns a 420x320 network diagram: a router (an outlined
+// circle), a switch (a wide outlined box), a server (a tall outlined box),
+// a cloud (three overlapping discs), and a triangle, with lines from the
+// router to the switch and the server, from the server to the cloud, and
+// an elbowed one from the switch to the cloud, and a label beside the
+// router's line to the server.
+func networkDiagram() *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, 420, 320))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	ink := func(x1, y1, x2, y2 int) {
+		draw.Draw(img, image.Rect(x1, y1, x2, y2), &image.Uniform{color.Gray{}}, image.Point{}, draw.Src)
+	}
+	box := func(x1, y1, x2, y2 int) {
+		ink(x1, y1, x2, y1+3)
+		ink(x1, y2-3, x2, y2)
+		ink(x1, y1, x1+3, y2)
+		ink(x2-3, y1, x2, y2)
+	}
+	for y := 0; y < 320; y++ {
+		for x := 0; x < 420; x++ {
+			px, py := float64(x)+0.5, float64(y)+0.5
+			if d := math.Hypot(px-80, py-60); d >= 22 && d <= 25 {
+				img.SetGray(x, y, color.Gray{})
+			}
+			if math.Hypot(px-220, py-205) <= 25 || math.Hypot(px-260, py-195) <= 32 || math.Hypot(px-300, py-205) <= 25 {
+				img.SetGray(x, y, color.Gray{})
+			}
+			if py >= 250 && py <= 290 && math.Abs(px-370) <= (py-250)/2 {
+				img.SetGray(x, y, color.Gray{})
+			}
+		}
+	}
+	box(200, 40, 320, 80)
+	box(60, 170, 100, 240)
+	ink(105, 59, 200, 62)
+	ink(79, 85, 82, 170)
+	ink(100, 204, 195, 207)
+	ink(320, 59, 380, 62)
+	ink(377, 59, 380, 160)
+	ink(290, 157, 380, 160)
+	ink(290, 157, 293, 180)
+	// A label: letters too thin to be a glyph
+	for x := 90; x < 130; x += 6 {
+		ink(x, 100, x+2, 110)
+	}
+	return img
+}
+
+func TestExtractTopology(t *testing.T) {
+	result, err := ExtractTopology(networkDiagram(), TopologyOptions{MinNodeSize: 16})
+	if err != nil {
+		t.Fatalf("ExtractTopology failed: %v", err)
+	}
+	kinds := []string{NetworkRouter, NetworkSwitch, NetworkServer, NetworkCloud, NetworkDevice}
+	if len(result.Nodes) != len(kinds) {
+		t.Fatalf("got %d nodes, want %d: %+v", len(result.Nodes), len(kinds), result.Nodes)
+	}
+	for i, n := range result.Nodes {
+		if n.ID != i+1 || n.Kind != kinds[i] {
+			t.Errorf("node %d: got %+v, want %s", i+1, n, kinds[i])
+		}
+	}
+	if b := result.Nodes[1].Bounds; b != (Region{X1: 200, Y1: 40, X2: 320, Y2: 80}) {
+		t.Errorf("switch bounds: got %+v", b)
+	}
+
+	want := [][2]int{{1, 2}, {1, 3}, {2, 4}, {3, 4}}
+	if len(result.Links) != len(want) {
+		t.Fatalf("got %d links, want %d: %+v", len(result.Links), len(want), result.Links)
+	}
+	for i, l := range result.Links {
+		if l.From != want[i][0] || l.To != want[i][1] || l.Shared {
+			t.Errorf("link %d: got %+v, want %v", i, l, want[i])
+		}
+	}
+	// The elbowed link turns at its corners
+	path := result.Links[2].Path
+	for _, corner := range []Point{{X: 378, Y: 60}, {X: 378, Y: 158}} {
+		near := false
+		for _, p := range path {
+			near = near || math.Hypot(float64(p.X-corner.X), float64(p.Y-corner.Y)) <= 4
+		}
+		if !near {
+			t.Errorf("elbowed path: got %+v, want a corner at %v", path, corner)
+		}
+	}
+	if len(path) > 7 || path[0].X < 320 || path[len(path)-1].Y < 160 {
+		t.Errorf("elbowed path: got %+v", path)
+	}
+
+	// A template names the triangle
+	tmpl := image.NewGray(image.Rect(0, 0, 30, 30))
+	draw.Draw(tmpl, tmpl.Bounds(), image.White, image.Point{}, draw.Src)
+	for y := 5; y < 25; y++ {
+		for x := 5; x < 25; x++ {
+			if math.Abs(float64(x)-14.5) <= float64(y-5)/2 {
+				tmpl.SetGray(x, y, color.Gray{})
+			}
+		}
+	}
+	result, err = ExtractTopology(networkDiagram(), TopologyOptions{
+		MinNodeSize: 16,
+		Templates:   []NetworkTemplate{{Label: "firewall", Image: tmpl}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := result.Nodes[4]; n.Kind != "firewall" || n.Match < 0.8 {
+		t.Errorf("templated node: got %+v", n)
+	}
+	if n := result.Nodes[0]; n.Kind != NetworkRouter || n.Match != 0 {
+		t.Errorf("untemplated node: got %+v", n)
+	}
+}
+
+func TestExtractTopology_Invalid(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 10, 10))
+	for _, opts := range []TopologyOptions{
+		{Threshold: -1, MinNodeSize: 16},
+		{Threshold: 256, MinNodeSize: 16},
+		{MinNodeSize: 7},
+		{MinNodeSize: 1001},
+		{MinNodeSize: 16, Templates: []NetworkTemplate{{Image: img}}},
+		{MinNodeSize: 16, Templates: []NetworkTemplate{{Label: "blank", Image: &image.Gray{Rect: image.Rect(0, 0, 4, 4), Stride: 4, Pix: make([]uint8, 16)}}}},
+		{MinNodeSize: 16, Templates: make([]NetworkTemplate, 33)},
+	} {
+		if _, err := ExtractTopology(img, opts); err == nil {
+			t.Errorf("%+v: expected an error", opts)
+		}
+	}
+}
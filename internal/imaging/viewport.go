@@ -0,0 +1,279 @@
+package imaging
+
+import (
+	"image"
+	"math"
+)
+
+// Viewport detection thresholds: neighboring pixels differ if their luma
+// differs by more than viewportEdgeDiff; a separator line is at most
+// viewportMaxLine pixels thick and at least a quarter of the image long
+// (and at least viewportMinLine pixels); it splits an area if it spans
+// at least viewportSplitShare of it; and a viewport has at least
+// viewportMinBackground of its pixels within viewportEdgeDiff of its
+// background luma. Panes under viewportMinPane pixels across are left
+// out, and lines bridge gaps of up to viewportLineGap pixels.
+const (
+	viewportEdgeDiff      = 8
+	viewportMaxLine       = 2
+	viewportMinLine       = 100
+	viewportSplitShare    = 0.98
+	viewportMinBackground = 0.4
+	viewportMinPane       = 32
+	viewportLineGap       = 4
+)
+
+// ViewportResult is the browser viewport found in a screenshot.
+type ViewportResult struct {
+	// Found is true if a viewport was found.
+	Found bool `json:"found"`
+
+	// Viewport is the area the page is shown in, below the browser's
+	// toolbar and beside any docked developer tools; nil if not found.
+	Viewport *Region `json:"viewport,omitempty"`
+
+	// BackgroundPercent is the share of the viewport, in percent, within
+	// a small luma difference of its most common luma: the page
+	// background.
+	BackgroundPercent float64 `json:"background_percent,omitempty"`
+
+	// Panels are the other panes of the browser window below the
+	// toolbar, split from the viewport by divider lines, such as docked
+	// developer tools.
+	Panels []Region `json:"panels"`
+}
+
+// separator is a thin straight line: rows [at, at+thick) from start to
+// end for a horizontal line, columns for a vertical one.
+type separator struct {
+	at, thick  int
+	start, end int
+}
+
+// DetectViewport finds the viewport of a browser in a screenshot, such as
+// a full-desktop screenshot: the area the page is shown in, below the
+// browser's toolbar, without docked developer tools.
+//
+// Parameters:
+//   - img: The screenshot.
+//
+// Returns:
+//   - *ViewportResult: The viewport and the panels beside it; Found is
+//     false if none was found.
+//
+// # Method
+//
+// Browsers separate their toolbar from the page with a thin line (1-2
+// pixels) across the window, which a page's own header, set off by a
+// change of color, rarely has. Each horizontal line at least a quarter
+// of the image wide, with room above it for a toolbar, is a candidate
+// top of the viewport, spanning the line's width. The area below it
+// extends down as far as the window's sides (edges running down from
+// the ends of the line) do, or, for a window as wide as the screenshot,
+// to the bottom, above any taskbar or dock along it. Divider lines
+// spanning the area split it into panes, and the largest pane whose
+// background is uniform (at least 40% of its pixels within a small luma
+// difference of its most common luma) is the viewport of the candidate.
+// Of all candidates, the largest viewport wins.
+func DetectViewport(img image.Image) *ViewportResult {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	lum := make([]uint8, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			lum[y*w+x] = luma(nrgbaAt(img, b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	at := func(x, y int) int { return int(lum[y*w+x]) }
+	// rowEdge and colEdge report whether pixel (x, y) differs from the
+	// pixel above it, or left of it
+	rowEdge := func(x, y int) bool { return y > 0 && y < h && absInt(at(x, y)-at(x, y-1)) > viewportEdgeDiff }
+	colEdge := func(x, y int) bool { return x > 0 && x < w && absInt(at(x, y)-at(x-1, y)) > viewportEdgeDiff }
+
+	hLines := findSeparators(w, h, max(viewportMinLine, w/4), func(along, across, thick int) bool {
+		return rowEdge(along, across) && rowEdge(along, across+thick) &&
+			absInt(at(along, across)-at(along, across+thick-1)) <= viewportEdgeDiff
+	})
+	vLines := findSeparators(h, w, max(viewportMinLine, h/4), func(along, across, thick int) bool {
+		return colEdge(across, along) && colEdge(across+thick, along) &&
+			absInt(at(across, along)-at(across+thick-1, along)) <= viewportEdgeDiff
+	})
+
+	result := &ViewportResult{Panels: []Region{}}
+	bestArea := 0
+	for _, top := range hLines {
+		if top.at < 16 {
+			continue
+		}
+		area := Region{X1: top.start, Y1: top.at + top.thick, X2: top.end, Y2: windowBottom(w, h, top, rowEdge, colEdge)}
+		if area.Y2-area.Y1 < viewportMinPane {
+			continue
+		}
+		panes := splitPanes(area, hLines, vLines)
+		best, share := -1, 0.0
+		for i, p := range panes {
+			if best >= 0 && regionArea(p) <= regionArea(panes[best]) {
+				continue
+			}
+			if s := backgroundShare(lum, w, p); s >= viewportMinBackground {
+				best, share = i, s
+			}
+		}
+		if best < 0 || regionArea(panes[best]) <= bestArea {
+			continue
+		}
+		bestArea = regionArea(panes[best])
+		viewport := panes[best]
+		result.Found = true
+		result.Viewport = &viewport
+		result.BackgroundPercent = math.Round(share*1000) / 10
+		result.Panels = []Region{}
+		for i, p := range panes {
+			if i != best {
+				result.Panels = append(result.Panels, p)
+			}
+		}
+	}
+	return result
+}
+
+// findSeparators finds the thin lines of an image along one axis: for
+// each position across the axis and each thickness up to
+// viewportMaxLine, the runs of at least minLen positions along it where
+// isLine holds, bridging gaps of up to viewportLineGap positions where
+// other lines cross it, such as a divider meeting the toolbar's line. n
+// is the length along the axis and m across it.
+func findSeparators(n, m, minLen int, isLine func(along, across, thick int) bool) []separator {
+	var lines []separator
+	for across := 1; across < m; across++ {
+		for thick := 1; thick <= viewportMaxLine && across+thick < m; thick++ {
+			start, last := -1, -1
+			for along := 0; along <= n; along++ {
+				if along < n && isLine(along, across, thick) {
+					if start < 0 {
+						start = along
+					}
+					last = along
+					continue
+				}
+				if start >= 0 && along < n && along-last <= viewportLineGap {
+					continue
+				}
+				if start >= 0 && last+1-start >= minLen {
+					lines = append(lines, separator{at: across, thick: thick, start: start, end: last + 1})
+				}
+				start = -1
+			}
+		}
+	}
+	return lines
+}
+
+// windowBottom returns the bottom of the browser window whose toolbar
+// ends at line top: the last row at which the window's sides, edges at
+// the ends of the line, still run down, allowing gaps of up to 16 rows
+// where the window meets a background of its own color. A window as wide
+// as the image has no sides, and ends at the bottom of the image, or at
+// the last edge across it in its bottom tenth, above a taskbar or dock.
+func windowBottom(w, h int, top separator, rowEdge, colEdge func(x, y int) bool) int {
+	left, right := top.start > 0, top.end < w
+	if !left && !right {
+		for y := h - 1; y > h-h/10; y-- {
+			edges := 0
+			for x := 0; x < w; x++ {
+				if rowEdge(x, y) {
+					edges++
+				}
+			}
+			if float64(edges) >= viewportSplitShare*float64(w) {
+				return y
+			}
+		}
+		return h
+	}
+	bottom := top.at + top.thick
+	for y := bottom; y < h && y-bottom <= 16; y++ {
+		if (!left || colEdge(top.start, y)) && (!right || colEdge(top.end, y)) {
+			bottom = y + 1
+		}
+	}
+	return bottom
+}
+
+// splitPanes splits area into panes at the horizontal lines spanning it,
+// then each strip at the vertical lines spanning the strip.
+func splitPanes(area Region, hLines, vLines []separator) []Region {
+	var panes []Region
+	for _, strip := range splitAt(area, hLines, true) {
+		panes = append(panes, splitAt(strip, vLines, false)...)
+	}
+	return panes
+}
+
+// splitAt splits area at the lines that span at least viewportSplitShare
+// of it, across it (horizontal lines if horizontal is set), leaving out
+// the lines and panes under viewportMinPane pixels thick.
+func splitAt(area Region, lines []separator, horizontal bool) []Region {
+	lo, hi, from, to := area.Y1, area.Y2, area.X1, area.X2
+	if !horizontal {
+		lo, hi, from, to = area.X1, area.X2, area.Y1, area.Y2
+	}
+	var panes []Region
+	add := func(a, b int) {
+		if b-a < viewportMinPane {
+			return
+		}
+		if horizontal {
+			panes = append(panes, Region{X1: area.X1, Y1: a, X2: area.X2, Y2: b})
+		} else {
+			panes = append(panes, Region{X1: a, Y1: area.Y1, X2: b, Y2: area.Y2})
+		}
+	}
+	start := lo
+	for _, l := range lines {
+		if l.at <= start || l.at+l.thick > hi {
+			continue
+		}
+		overlap := min(l.end, to) - max(l.start, from)
+		if float64(overlap) < viewportSplitShare*float64(to-from) {
+			continue
+		}
+		add(start, l.at)
+		start = l.at + l.thick
+	}
+	add(start, hi)
+	return panes
+}
+
+// backgroundShare returns the share of the pixels of r within
+// viewportEdgeDiff of their most common luma, sampling large regions.
+func backgroundShare(lum []uint8, w int, r Region) float64 {
+	step := max(1, int(math.Sqrt(float64(regionArea(r))/250000)))
+	var counts [256]int
+	total := 0
+	for y := r.Y1; y < r.Y2; y += step {
+		for x := r.X1; x < r.X2; x += step {
+			counts[lum[y*w+x]]++
+			total++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	mode := 0
+	for l := range counts {
+		if counts[l] > counts[mode] {
+			mode = l
+		}
+	}
+	near := 0
+	for l := max(0, mode-viewportEdgeDiff); l <= min(255, mode+viewportEdgeDiff); l++ {
+		near += counts[l]
+	}
+	return float64(near) / float64(total)
+}
+
+// regionArea returns the area of r in pixels.
+func regionArea(r Region) int {
+	return (r.X2 - r.X1) * (r.Y2 - r.Y1)
+}
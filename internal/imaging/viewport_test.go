@@ -0,0 +1,70 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// browserShot draws an 800×600 desktop screenshot with a browser window
+// at window: a 70 px toolbar, a separator line, and a white page with
+// text, and developer tools docked right of a divider at devtoolsX, if
+// set. A full-width window sits above a 40 px taskbar.
+func browserShot(window image.Rectangle, devtoolsX int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 800, 600))
+	fill := func(r image.Rectangle, c uint8) {
+		draw.Draw(img, r, image.NewUniform(color.NRGBA{c, c, c, 255}), image.Point{}, draw.Src)
+	}
+	fill(img.Bounds(), 70)
+	fill(image.Rect(0, 560, 800, 600), 30)
+	fill(window, 230)
+	fill(image.Rect(window.Min.X+100, window.Min.Y+40, window.Min.X+400, window.Min.Y+60), 255)
+	fill(image.Rect(window.Min.X, window.Min.Y+70, window.Max.X, window.Min.Y+71), 200)
+	fill(image.Rect(window.Min.X, window.Min.Y+71, window.Max.X, window.Max.Y), 255)
+	for i := 0; i < 8; i++ {
+		fill(image.Rect(window.Min.X+30, window.Min.Y+100+20*i, window.Min.X+150, window.Min.Y+110+20*i), 40)
+	}
+	if devtoolsX > 0 {
+		fill(image.Rect(devtoolsX, window.Min.Y+71, devtoolsX+1, window.Max.Y), 200)
+		fill(image.Rect(devtoolsX+1, window.Min.Y+71, window.Max.X, window.Max.Y), 245)
+		fill(image.Rect(devtoolsX+20, window.Min.Y+90, devtoolsX+120, window.Min.Y+100), 90)
+	}
+	return img
+}
+
+func TestDetectViewport_Window(t *testing.T) {
+	result := DetectViewport(browserShot(image.Rect(50, 40, 750, 520), 500))
+	if !result.Found || result.Viewport == nil || *result.Viewport != (Region{X1: 50, Y1: 111, X2: 500, Y2: 520}) {
+		t.Fatalf("got found %v, viewport %+v; want 50,111-500,520", result.Found, result.Viewport)
+	}
+	if len(result.Panels) != 1 || result.Panels[0] != (Region{X1: 501, Y1: 111, X2: 750, Y2: 520}) {
+		t.Errorf("got panels %+v, want the developer tools at 501,111-750,520", result.Panels)
+	}
+	if result.BackgroundPercent < 90 {
+		t.Errorf("got background %.1f%%, want at least 90%%", result.BackgroundPercent)
+	}
+}
+
+func TestDetectViewport_FullWidth(t *testing.T) {
+	result := DetectViewport(browserShot(image.Rect(0, 0, 800, 560), 0))
+	if !result.Found || result.Viewport == nil || *result.Viewport != (Region{X1: 0, Y1: 71, X2: 800, Y2: 560}) {
+		t.Fatalf("got found %v, viewport %+v; want 0,71-800,560 above the taskbar", result.Found, result.Viewport)
+	}
+	if len(result.Panels) != 0 {
+		t.Errorf("got panels %+v, want none", result.Panels)
+	}
+}
+
+func TestDetectViewport_NotFound(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 400, 300))
+	for y := 0; y < 300; y++ {
+		for x := 0; x < 400; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{uint8(x), uint8(y), uint8(x + y), 255})
+		}
+	}
+	result := DetectViewport(img)
+	if result.Found || result.Viewport != nil || result.Panels == nil {
+		t.Errorf("got found %v, viewport %+v, panels %v; want nothing found", result.Found, result.Viewport, result.Panels)
+	}
+}
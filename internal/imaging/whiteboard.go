@@ -0,0 +1,461 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// Whiteboard cleanup constants.
+const (
+	// whiteboardMaxThreshold caps the automatic ink threshold, so the
+	// evened-out board of a photo with little ink on it is not split in
+	// two.
+	whiteboardMaxThreshold = 224
+
+	// whiteboardMinSpeck is the fewest pixels a stroke needs to be kept;
+	// smaller specks are dust, noise, and reflections.
+	whiteboardMinSpeck = 8
+
+	// whiteboardStrokeGain deepens the ink: the darkness of each channel
+	// (255 minus its value) is multiplied by it.
+	whiteboardStrokeGain = 1.5
+
+	// whiteboardMinPrimitive is the shortest line, and the shortest
+	// rectangle side, in pixels, that strokes are taken for.
+	whiteboardMinPrimitive = 20
+
+	// whiteboardRectCoverage is the fraction of each side of a rectangle
+	// its stroke must cover, leaving room for a gap where the marker
+	// closed the shape.
+	whiteboardRectCoverage = 0.9
+
+	// whiteboardSquareAngle is how many degrees from horizontal or
+	// vertical a line or rectangle may be drawn and still be squared to it.
+	whiteboardSquareAngle = 5
+)
+
+// WhiteboardOptions configures CleanWhiteboard.
+type WhiteboardOptions struct {
+	// Threshold is the brightness (1-255) below which pixels of the
+	// evened-out board are ink, or 0 to choose it with Otsu's method.
+	Threshold int
+
+	// Snap redraws the strokes taken for lines and rectangles as exact
+	// ones in the cleaned image. They are reported either way.
+	Snap bool
+
+	// Tolerance is how far, in pixels, a stroke may stray from a straight
+	// line or a rectangle's side and still be taken for one: 0.5 to 50.
+	Tolerance float64
+}
+
+// WhiteboardLine is a stroke taken for a straight line.
+type WhiteboardLine struct {
+	// Start and End are the line's ends, along the middle of the stroke:
+	// Start the left end, or the top end of a vertical line.
+	Start PointF `json:"start"`
+	End   PointF `json:"end"`
+
+	// Length is the distance between the ends, in pixels.
+	Length float64 `json:"length"`
+
+	// Angle is the line's direction, in degrees clockwise from horizontal
+	// (0-180).
+	Angle float64 `json:"angle"`
+
+	// StrokeWidth is the mean width of the stroke, in pixels.
+	StrokeWidth float64 `json:"stroke_width"`
+
+	// Color is the marker's color as hex (#RRGGBB), after enhancement.
+	Color string `json:"color"`
+}
+
+// WhiteboardRectangle is a stroke taken for a rectangle. Its size and
+// corners are those of the middle of the stroke.
+type WhiteboardRectangle struct {
+	RotatedRectangle
+
+	// StrokeWidth is the mean width of the stroke, in pixels.
+	StrokeWidth float64 `json:"stroke_width"`
+
+	// Color is the marker's color as hex (#RRGGBB), after enhancement.
+	Color string `json:"color"`
+}
+
+// WhiteboardResult contains a cleaned whiteboard photo encoded as base64
+// PNG and the lines and rectangles drawn on it.
+type WhiteboardResult struct {
+	// Width and Height of the cleaned image in pixels (same as input).
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// ImageBase64 is the cleaned board encoded as base64 PNG: the strokes
+	// in their marker colors on white.
+	ImageBase64 string `json:"image_base64"`
+
+	// MimeType is always "image/png".
+	MimeType string `json:"mime_type"`
+
+	// Threshold is the brightness (0-255) that separated ink from the
+	// evened-out board.
+	Threshold int `json:"threshold"`
+
+	// InkPixels is the number of pixels kept as ink.
+	InkPixels int `json:"ink_pixels"`
+
+	// Lines and Rectangles are the strokes taken for straight lines and
+	// rectangles, in the order of their topmost pixels.
+	Lines      []WhiteboardLine      `json:"lines"`
+	Rectangles []WhiteboardRectangle `json:"rectangles"`
+
+	// Strokes is the number of other strokes, such as writing, arrows,
+	// and curves, kept as drawn.
+	Strokes int `json:"strokes"`
+
+	// Snapped is true if the lines and rectangles were redrawn exactly in
+	// the image.
+	Snapped bool `json:"snapped"`
+}
+
+// whiteboardStroke is one 8-connected component of ink: its pixel
+// indices, and its bounds.
+type whiteboardStroke struct {
+	pixels         []int
+	x1, y1, x2, y2 int
+}
+
+// CleanWhiteboard turns a photo of a whiteboard into a clean diagram: it
+// evens out glare and shadows so the board is white, deepens the marker
+// strokes, and finds the strokes that are nearly straight lines or
+// rectangles, optionally redrawing them exactly.
+//
+// Parameters:
+//   - img: Source photo. It is not modified.
+//   - opts: Threshold, snapping, and tolerance.
+//
+// Returns:
+//   - *WhiteboardResult: The cleaned board and the lines and rectangles
+//     found on it.
+//   - error: Non-nil if an option is out of range or PNG encoding fails.
+//
+// # Algorithm
+//
+//  1. Even out: each pixel is divided by the board's brightness around
+//     it, as image_scan_cleanup removes shadows, so shadows and glare
+//     become white board.
+//
+//  2. Ink: pixels darker than the threshold are ink, joined into
+//     8-connected strokes. Strokes smaller than 8 pixels are dropped, and
+//     the rest deepened in their own colors on white.
+//
+//  3. Lines: a stroke at least 20 pixels long whose smallest enclosing
+//     rectangle, at any angle, is at least three times as long as wide
+//     and no wider than its mean width plus twice the tolerance is a
+//     line, through the middle of that rectangle.
+//
+//  4. Rectangles: a stroke whose smallest enclosing rectangle has sides
+//     of at least 20 pixels, all its pixels within its mean width plus
+//     twice the tolerance of the rectangle's sides, and at least 90% of
+//     each side covered is a rectangle.
+//
+//  5. Snap: lines and rectangles within 5 degrees of horizontal or
+//     vertical are squared to it, and with opts.Snap the strokes are
+//     erased and redrawn as exact lines and rectangles of their mean
+//     width and color.
+//
+// Each stroke is taken whole, so a line touching a rectangle, such as a
+// connector drawn into a box, leaves both as drawn.
+func CleanWhiteboard(img image.Image, opts WhiteboardOptions) (*WhiteboardResult, error) {
+	if opts.Threshold < 0 || opts.Threshold > 255 {
+		return nil, fmt.Errorf("threshold must be 0 (automatic) to 255, got %d", opts.Threshold)
+	}
+	if opts.Tolerance < 0.5 || opts.Tolerance > 50 {
+		return nil, fmt.Errorf("tolerance must be 0.5 to 50 pixels, got %v", opts.Tolerance)
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	page := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			page.SetNRGBA(x, y, nrgbaAt(img, b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	removeShadows(page)
+	lum := lumaPlane(page)
+	threshold := opts.Threshold
+	if threshold == 0 {
+		threshold = min(otsuThreshold(lum), whiteboardMaxThreshold)
+	}
+
+	result := &WhiteboardResult{
+		Width:      w,
+		Height:     h,
+		MimeType:   "image/png",
+		Threshold:  threshold,
+		Lines:      []WhiteboardLine{},
+		Rectangles: []WhiteboardRectangle{},
+		Snapped:    opts.Snap,
+	}
+	out := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for i := range out.Pix {
+		out.Pix[i] = 255
+	}
+	var redraw []func()
+	for _, s := range inkStrokes(lum, w, h, uint8(threshold)) {
+		if len(s.pixels) < whiteboardMinSpeck {
+			continue
+		}
+		result.InkPixels += len(s.pixels)
+		var sum [3]int
+		for _, i := range s.pixels {
+			for ch := 0; ch < 3; ch++ {
+				v := 255 - min(float64(255-page.Pix[4*i+ch])*whiteboardStrokeGain, 255)
+				out.Pix[4*i+ch] = uint8(math.Round(v))
+				sum[ch] += int(out.Pix[4*i+ch])
+			}
+		}
+		n := len(s.pixels)
+		ink := color.NRGBA{uint8(sum[0] / n), uint8(sum[1] / n), uint8(sum[2] / n), 255}
+		hex := fmt.Sprintf("#%02X%02X%02X", ink.R, ink.G, ink.B)
+
+		rect, stroke := s.enclosingRect(w)
+		var segments [][2]PointF
+		if line, ok := whiteboardLine(rect, stroke, opts.Tolerance); ok {
+			line.Color = hex
+			result.Lines = append(result.Lines, line)
+			segments = [][2]PointF{{line.Start, line.End}}
+		} else if r, ok := s.whiteboardRectangle(rect, w, opts.Tolerance); ok {
+			r.Color = hex
+			result.Rectangles = append(result.Rectangles, r)
+			for k := range r.Corners {
+				segments = append(segments, [2]PointF{r.Corners[k], r.Corners[(k+1)%4]})
+			}
+			stroke = r.StrokeWidth
+		} else {
+			result.Strokes++
+			continue
+		}
+		if opts.Snap {
+			for _, i := range s.pixels {
+				copy(out.Pix[4*i:4*i+3], []uint8{255, 255, 255})
+			}
+			// Drawn once every stroke is placed, so erasing one does not
+			// cut into another's redrawing
+			redraw = append(redraw, func() {
+				for _, seg := range segments {
+					drawSegment(out, seg[0], seg[1], stroke, ink)
+				}
+			})
+		}
+	}
+	for _, draw := range redraw {
+		draw()
+	}
+
+	encoded, err := encodePNGBase64(out)
+	if err != nil {
+		return nil, err
+	}
+	result.ImageBase64 = encoded
+	return result, nil
+}
+
+// inkStrokes returns the 8-connected components of the pixels of lum, an
+// image w pixels wide, darker than threshold, in raster order of their
+// first pixels.
+func inkStrokes(lum []uint8, w, h int, threshold uint8) []*whiteboardStroke {
+	seen := make([]bool, len(lum))
+	var strokes []*whiteboardStroke
+	var stack []int
+	for start, v := range lum {
+		if v >= threshold || seen[start] {
+			continue
+		}
+		s := &whiteboardStroke{x1: w, y1: h}
+		seen[start] = true
+		stack = append(stack[:0], start)
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			s.pixels = append(s.pixels, i)
+			x, y := i%w, i/w
+			s.x1, s.y1, s.x2, s.y2 = min(s.x1, x), min(s.y1, y), max(s.x2, x+1), max(s.y2, y+1)
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := x+dx, y+dy
+					if nx < 0 || ny < 0 || nx >= w || ny >= h {
+						continue
+					}
+					if j := ny*w + nx; lum[j] < threshold && !seen[j] {
+						seen[j] = true
+						stack = append(stack, j)
+					}
+				}
+			}
+		}
+		strokes = append(strokes, s)
+	}
+	return strokes
+}
+
+// enclosingRect returns the smallest rectangle, at any angle, around the
+// pixels of s, whole, in an image w pixels wide, and the stroke's mean
+// width as if it were a line: its area over the rectangle's length.
+func (s *whiteboardStroke) enclosingRect(w int) (RotatedRectangle, float64) {
+	rows := make([][2]int, s.y2-s.y1)
+	for k := range rows {
+		rows[k] = [2]int{s.x2, s.x1}
+	}
+	for _, i := range s.pixels {
+		r := &rows[i/w-s.y1]
+		r[0], r[1] = min(r[0], i%w), max(r[1], i%w+1)
+	}
+	var pts [][2]float64
+	for k, r := range rows {
+		if r[0] >= r[1] {
+			continue
+		}
+		y := float64(s.y1 + k)
+		for _, x := range []float64{float64(r[0]), float64(r[1])} {
+			pts = append(pts, [2]float64{x, y}, [2]float64{x, y + 1})
+		}
+	}
+	rect := minAreaRect(convexHull(pts), 0)
+	return rect, float64(len(s.pixels)) / rect.Width
+}
+
+// whiteboardLine returns the line through the middle of rect, the
+// smallest rectangle around a stroke of mean width stroke, if the stroke
+// is straight to within tolerance.
+func whiteboardLine(rect RotatedRectangle, stroke, tolerance float64) (WhiteboardLine, bool) {
+	if rect.Width < whiteboardMinPrimitive || rect.Width < 3*rect.Height || rect.Height > stroke+2*tolerance {
+		return WhiteboardLine{}, false
+	}
+	// The stroke's ends are rounded off by half its width
+	rect.Angle = squareAngle(rect.Angle)
+	length := max(rect.Width-stroke, 1)
+	sin, cos := math.Sincos(rect.Angle * math.Pi / 180)
+	start := PointF{X: rect.Center.X - cos*length/2, Y: rect.Center.Y - sin*length/2}
+	end := PointF{X: rect.Center.X + cos*length/2, Y: rect.Center.Y + sin*length/2}
+	if end.X < start.X || (end.X == start.X && end.Y < start.Y) {
+		start, end = end, start
+	}
+	return WhiteboardLine{
+		Start:       roundPointF(start),
+		End:         roundPointF(end),
+		Length:      math.Round(length*100) / 100,
+		Angle:       math.Round(rect.Angle*10) / 10,
+		StrokeWidth: math.Round(stroke*100) / 100,
+	}, true
+}
+
+// whiteboardRectangle returns the rectangle through the middle of the
+// stroke s, in an image w pixels wide, if it follows the sides of rect,
+// its smallest enclosing rectangle, to within tolerance.
+func (s *whiteboardStroke) whiteboardRectangle(rect RotatedRectangle, w int, tolerance float64) (WhiteboardRectangle, bool) {
+	W, H := rect.Width, rect.Height
+	if H < whiteboardMinPrimitive {
+		return WhiteboardRectangle{}, false
+	}
+	// The area of an outline of width t is 2(W+H)t - 4t²
+	area := float64(len(s.pixels))
+	disc := (W+H)*(W+H) - 4*area
+	if disc < 0 {
+		return WhiteboardRectangle{}, false
+	}
+	stroke := ((W + H) - math.Sqrt(disc)) / 4
+	band := stroke + 2*tolerance
+	if band > H/3 {
+		return WhiteboardRectangle{}, false
+	}
+
+	// Each pixel must be near a side, and each side covered, by the
+	// pixels near it projected onto it
+	sin, cos := math.Sincos(rect.Angle * math.Pi / 180)
+	across, down := make([][2]bool, int(W)+1), make([][2]bool, int(H)+1)
+	for _, i := range s.pixels {
+		dx, dy := float64(i%w)+0.5-rect.Center.X, float64(i/w)+0.5-rect.Center.Y
+		u, v := dx*cos+dy*sin+W/2, dy*cos-dx*sin+H/2
+		if min(u, W-u, v, H-v) > band {
+			return WhiteboardRectangle{}, false
+		}
+		uk, vk := min(max(int(u), 0), len(across)-1), min(max(int(v), 0), len(down)-1)
+		if v <= band {
+			across[uk][0] = true
+		}
+		if H-v <= band {
+			across[uk][1] = true
+		}
+		if u <= band {
+			down[vk][0] = true
+		}
+		if W-u <= band {
+			down[vk][1] = true
+		}
+	}
+	for _, side := range [][][2]bool{across, down} {
+		for end := 0; end < 2; end++ {
+			covered := 0
+			for _, bin := range side {
+				if bin[end] {
+					covered++
+				}
+			}
+			if float64(covered) < whiteboardRectCoverage*float64(len(side)-1) {
+				return WhiteboardRectangle{}, false
+			}
+		}
+	}
+
+	mid := RotatedRectangle{
+		Center: roundPointF(rect.Center),
+		Width:  math.Round((W-stroke)*100) / 100,
+		Height: math.Round((H-stroke)*100) / 100,
+		Angle:  math.Round(squareAngle(rect.Angle)*10) / 10,
+	}
+	mid.setCorners()
+	for k := range mid.Corners {
+		mid.Corners[k] = roundPointF(mid.Corners[k])
+	}
+	return WhiteboardRectangle{RotatedRectangle: mid, StrokeWidth: math.Round(stroke*100) / 100}, true
+}
+
+// squareAngle returns angle (0-180 degrees), made horizontal or vertical
+// if it is within whiteboardSquareAngle degrees of either.
+func squareAngle(angle float64) float64 {
+	for _, square := range []float64{0, 90, 180} {
+		if math.Abs(angle-square) <= whiteboardSquareAngle {
+			return math.Mod(square, 180)
+		}
+	}
+	return angle
+}
+
+// roundPointF returns p rounded to hundredths of a pixel.
+func roundPointF(p PointF) PointF {
+	return PointF{X: math.Round(p.X*100) / 100, Y: math.Round(p.Y*100) / 100}
+}
+
+// drawSegment paints the pixels of img whose centers are within half of
+// width of the segment from a to b in c.
+func drawSegment(img *image.NRGBA, a, b PointF, width float64, c color.NRGBA) {
+	r := max(width/2, 0.5)
+	x1, x2 := int(math.Floor(min(a.X, b.X)-r)), int(math.Ceil(max(a.X, b.X)+r))
+	y1, y2 := int(math.Floor(min(a.Y, b.Y)-r)), int(math.Ceil(max(a.Y, b.Y)+r))
+	vx, vy := b.X-a.X, b.Y-a.Y
+	l2 := vx*vx + vy*vy
+	for y := max(y1, img.Rect.Min.Y); y < min(y2, img.Rect.Max.Y); y++ {
+		for x := max(x1, img.Rect.Min.X); x < min(x2, img.Rect.Max.X); x++ {
+			px, py := float64(x)+0.5-a.X, float64(y)+0.5-a.Y
+			t := 0.0
+			if l2 > 0 {
+				t = min(max((px*vx+py*vy)/l2, 0), 1)
+			}
+			if math.Hypot(px-t*vx, py-t*vy) <= r {
+				img.SetNRGBA(x, y, c)
+			}
+		}
+	}
+}
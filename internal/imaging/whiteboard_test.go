@@ -0,0 +1,108 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"testing"
+)
+
+// boardPhoto returns a 400x300 photo of a whiteboard, shaded from left to
+// right, with a black line sloping 2 degrees and wobbling a pixel from
+// (40, 50) to (240, 57), a blue box a degree off square around
+// (60, 120)-(220, 240) whose stroke stops short of closing its top-left
+// corner, and a red circle of radius 40 at (320, 180). Strokes are 4
+// pixels wide.
+func boardPhoto() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 400, 300))
+	sin, cos := math.Sincos(math.Pi / 180)
+	for y := 0; y < 300; y++ {
+		for x := 0; x < 400; x++ {
+			px, py := float64(x)+0.5, float64(y)+0.5
+			c := color.NRGBA{225, 228, 230, 255}
+			wobble := math.Sin(px / 15)
+			if px >= 40 && px < 240 && math.Abs(py-50-(px-40)*0.035-wobble) <= 2 {
+				c = color.NRGBA{30, 30, 35, 255}
+			}
+			// The box, turned a degree about its center
+			u := (px-140)*cos + (py-180)*sin
+			v := (py-180)*cos - (px-140)*sin
+			if math.Abs(u) <= 80 && math.Abs(v) <= 60 && (math.Abs(u) >= 76 || math.Abs(v) >= 56) && !(u < -70 && v < -50) {
+				c = color.NRGBA{40, 70, 170, 255}
+			}
+			if d := math.Hypot(px-320, py-180); d >= 38 && d <= 42 {
+				c = color.NRGBA{190, 40, 40, 255}
+			}
+			shade := 1 - 0.35*px/400
+			c.R, c.G, c.B = uint8(float64(c.R)*shade), uint8(float64(c.G)*shade), uint8(float64(c.B)*shade)
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestCleanWhiteboard(t *testing.T) {
+	result, err := CleanWhiteboard(boardPhoto(), WhiteboardOptions{Snap: true, Tolerance: 3})
+	if err != nil {
+		t.Fatalf("CleanWhiteboard failed: %v", err)
+	}
+	if len(result.Lines) != 1 || len(result.Rectangles) != 1 || result.Strokes != 1 || !result.Snapped {
+		t.Fatalf("got %d lines, %d rectangles, %d strokes: %+v", len(result.Lines), len(result.Rectangles), result.Strokes, result)
+	}
+
+	// The line is squared about its middle
+	line := result.Lines[0]
+	if line.Angle != 0 || line.Start.Y != line.End.Y || math.Abs(line.Start.Y-53.5) > 1 ||
+		math.Abs(line.Start.X-42) > 3 || math.Abs(line.End.X-238) > 3 || math.Abs(line.StrokeWidth-4) > 1 {
+		t.Errorf("line: got %+v", line)
+	}
+
+	// The box is squared, through the middle of its stroke
+	rect := result.Rectangles[0]
+	if rect.Angle != 0 || math.Abs(rect.Width-156) > 3 || math.Abs(rect.Height-116) > 3 ||
+		math.Abs(rect.Center.X-140) > 1 || math.Abs(rect.Center.Y-180) > 1 || rect.Color[5:] <= rect.Color[1:3] {
+		t.Errorf("rectangle: got %+v", rect)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(result.ImageBase64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The board is white in the light and the shade; the snapped box is
+	// closed and straight, and the circle kept as drawn
+	for _, p := range []image.Point{{10, 10}, {390, 290}, {140, 180}, {320, 180}} {
+		if r, g, b, _ := img.At(p.X, p.Y).RGBA(); r>>8 != 255 || g>>8 != 255 || b>>8 != 255 {
+			t.Errorf("board at %v: got %v", p, img.At(p.X, p.Y))
+		}
+	}
+	for _, p := range []image.Point{{61, 121}, {140, 122}, {218, 238}} {
+		if r, _, _, _ := img.At(p.X, p.Y).RGBA(); r>>8 > 128 {
+			t.Errorf("box at %v: got %v", p, img.At(p.X, p.Y))
+		}
+	}
+	if r, g, _, _ := img.At(320, 141).RGBA(); r>>8 < 128 || g>>8 > 64 {
+		t.Errorf("circle: got %v", img.At(320, 141))
+	}
+
+	// Without snapping, the same shapes are found but drawn as they were
+	plain, err := CleanWhiteboard(boardPhoto(), WhiteboardOptions{Tolerance: 3})
+	if err != nil || len(plain.Lines) != 1 || len(plain.Rectangles) != 1 || plain.Snapped || plain.ImageBase64 == result.ImageBase64 {
+		t.Errorf("without snapping: got %+v, %v", plain, err)
+	}
+}
+
+func TestCleanWhiteboard_Invalid(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	for _, opts := range []WhiteboardOptions{{Threshold: -1, Tolerance: 3}, {Threshold: 256, Tolerance: 3}, {Tolerance: 0}, {Tolerance: 51}} {
+		if _, err := CleanWhiteboard(img, opts); err == nil {
+			t.Errorf("%+v: expected an error", opts)
+		}
+	}
+}
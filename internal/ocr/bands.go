@@ -0,0 +1,160 @@
+package ocr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Bands of tall pages: a page is read in bands, one per CPU, if each
+// would be at least bandMinHeight pixels tall. Each cut is made at the
+// blankest row within bandSearch of a band's height of an even cut.
+const (
+	bandMinHeight = 800
+	bandSearch    = 0.25
+)
+
+// extractUpright reads an upright image, in bands read in parallel if it
+// is tall enough (see bandCuts), and returns word bounds relative to its
+// top left corner. Vertical text is read in one piece, as bands would cut
+// through its columns.
+func extractUpright(img image.Image, opts Options) (*OCRResult, error) {
+	b := img.Bounds()
+	cuts := []int{b.Min.Y, b.Max.Y}
+	if !opts.Vertical {
+		cuts = bandCuts(img, runtime.NumCPU())
+	}
+	if len(cuts) == 2 {
+		return extractTemp(img, opts)
+	}
+	return extractBands(img, cuts, opts)
+}
+
+// extractBands reads the bands of img between consecutive cuts (rows,
+// top and bottom included) in parallel, and returns word bounds relative
+// to its top left corner.
+func extractBands(img image.Image, cuts []int, opts Options) (*OCRResult, error) {
+	b := img.Bounds()
+	results := make([]*OCRResult, len(cuts)-1)
+	errs := make([]error, len(cuts)-1)
+	var wg sync.WaitGroup
+	for i := range results {
+		band := image.NewRGBA(image.Rect(0, 0, b.Dx(), cuts[i+1]-cuts[i]))
+		draw.Draw(band, band.Bounds(), img, image.Pt(b.Min.X, cuts[i]), draw.Src)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = extractTemp(band, opts)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	offsets := make([]int, len(results))
+	for i := range offsets {
+		offsets[i] = cuts[i] - b.Min.Y
+	}
+	return stitchBands(results, offsets), nil
+}
+
+// extractTemp reads img through a temporary PNG file, and returns word
+// bounds relative to its top left corner.
+func extractTemp(img image.Image, opts Options) (*OCRResult, error) {
+	tmpFile, err := os.CreateTemp("", "ocr-region-*.png")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
+
+	if err := png.Encode(tmpFile, img); err != nil {
+		tmpFile.Close()
+		return nil, fmt.Errorf("failed to encode temp image: %w", err)
+	}
+	tmpFile.Close()
+
+	return extractFile(tmpPath, opts)
+}
+
+// stitchBands joins the results of reading the bands of an image, top to
+// bottom, into one: word bounds are moved down by each band's offset
+// from the top of the image, and the bands' text, trimmed as extractFile
+// trims a whole page's, is joined by line breaks.
+func stitchBands(results []*OCRResult, offsets []int) *OCRResult {
+	stitched := &OCRResult{Regions: []TextRegion{}}
+	texts := make([]string, 0, len(results))
+	for i, result := range results {
+		for _, r := range result.Regions {
+			r.Bounds.Y1 += offsets[i]
+			r.Bounds.Y2 += offsets[i]
+			stitched.Regions = append(stitched.Regions, r)
+		}
+		if text := strings.TrimSpace(result.FullText); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	stitched.FullText = strings.Join(texts, "\n")
+	return stitched
+}
+
+// bandCuts returns the rows at which to cut img into bands to be read in
+// parallel, its top and bottom included: up to n bands, none much
+// shorter than bandMinHeight, cut at the blankest rows, where neighboring
+// pixels differ least, so that no line of text is cut through. An image
+// too short for two bands is one band.
+func bandCuts(img image.Image, n int) []int {
+	b := img.Bounds()
+	n = min(n, b.Dy()/bandMinHeight)
+	if n < 2 {
+		return []int{b.Min.Y, b.Max.Y}
+	}
+
+	// Busyness of each row: the summed luminance differences between
+	// horizontal neighbors, 0 for a blank row of any shade
+	busy := make([]int, b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		prev := luminance(img.At(b.Min.X, y))
+		for x := b.Min.X + 1; x < b.Max.X; x++ {
+			l := luminance(img.At(x, y))
+			busy[y-b.Min.Y] += abs(l - prev)
+			prev = l
+		}
+	}
+
+	height := b.Dy() / n
+	search := int(float64(height) * bandSearch)
+	cuts := []int{b.Min.Y}
+	for i := 1; i < n; i++ {
+		even := i * height
+		best := even
+		for y := even - search; y <= even+search; y++ {
+			if busy[y] < busy[best] || busy[y] == busy[best] && abs(y-even) < abs(best-even) {
+				best = y
+			}
+		}
+		cuts = append(cuts, b.Min.Y+best)
+	}
+	return append(cuts, b.Max.Y)
+}
+
+// luminance returns the 8-bit luminance of c.
+func luminance(c color.Color) int {
+	return int(color.GrayModel.Convert(c).(color.Gray).Y)
+}
+
+// abs returns the absolute value of x.
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,92 @@
+package ocr
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"testing"
+)
+
+func TestBandCuts(t *testing.T) {
+	// A page of "text lines": 20 rows of alternating pixels every 30
+	// rows, on a gray background, offset from the origin
+	page := image.NewGray(image.Rect(0, 100, 200, 100+4*bandMinHeight))
+	draw.Draw(page, page.Rect, image.NewUniform(color.Gray{200}), image.Point{}, draw.Src)
+	lineOf := func(y int) bool { return (y-100)%30 < 20 }
+	for y := page.Rect.Min.Y; y < page.Rect.Max.Y; y++ {
+		if lineOf(y) {
+			for x := 0; x < 200; x += 2 {
+				page.SetGray(x, y, color.Gray{0})
+			}
+		}
+	}
+
+	cuts := bandCuts(page, 4)
+	if len(cuts) != 5 || cuts[0] != 100 || cuts[4] != 100+4*bandMinHeight {
+		t.Fatalf("got cuts %v", cuts)
+	}
+	for i, y := range cuts[1:4] {
+		even := 100 + (i+1)*bandMinHeight
+		if lineOf(y) || abs(y-even) > 30 {
+			t.Errorf("cut %d at %d: through a line or far from %d", i+1, y, even)
+		}
+	}
+
+	// Bands no shorter than bandMinHeight, whatever the CPUs
+	if cuts := bandCuts(page, 16); len(cuts) != 5 {
+		t.Errorf("16 CPUs: got %d bands", len(cuts)-1)
+	}
+	if cuts := bandCuts(page, 1); len(cuts) != 2 {
+		t.Errorf("1 CPU: got %d bands", len(cuts)-1)
+	}
+	short := image.NewGray(image.Rect(0, 0, 200, 2*bandMinHeight-1))
+	if cuts := bandCuts(short, 8); len(cuts) != 2 {
+		t.Errorf("short page: got %d bands", len(cuts)-1)
+	}
+}
+
+func TestStitchBands(t *testing.T) {
+	word := func(text string, y int) TextRegion {
+		return TextRegion{Text: text, Confidence: 0.9, Bounds: Bounds{X1: 10, Y1: y, X2: 60, Y2: y + 14}}
+	}
+	result := stitchBands([]*OCRResult{
+		{FullText: "one two\n\f", Regions: []TextRegion{word("one", 5), word("two", 25)}},
+		{FullText: "", Regions: []TextRegion{}},
+		{FullText: "three\n", Regions: []TextRegion{word("three", 0)}},
+	}, []int{0, 800, 1600})
+
+	if result.FullText != "one two\nthree" {
+		t.Errorf("got full text %q", result.FullText)
+	}
+	if len(result.Regions) != 3 || result.Regions[1].Bounds.Y1 != 25 || result.Regions[2].Bounds != (Bounds{X1: 10, Y1: 1600, X2: 60, Y2: 1614}) {
+		t.Errorf("got regions %+v", result.Regions)
+	}
+}
+
+func TestExtractBands_MatchesWholePage(t *testing.T) {
+	// Four lines of one paragraph, cut into two bands between the second
+	// and third
+	img := image.NewRGBA(image.Rect(0, 0, 320, 110))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	for i, line := range []string{"The quick brown fox", "jumps over the lazy", "dog while the cat", "sleeps in the sun"} {
+		drawText(img, 10, 25+i*20, line, color.Black)
+	}
+	opts := Options{Language: "eng"}
+
+	whole, err := extractTemp(img, opts)
+	if err != nil {
+		if strings.Contains(err.Error(), "tesseract") ||
+			strings.Contains(err.Error(), "library") {
+			t.Skip("Tesseract not available")
+		}
+		t.Fatalf("extractTemp failed: %v", err)
+	}
+	banded, err := extractBands(img, []int{0, 50, 110}, opts)
+	if err != nil {
+		t.Fatalf("extractBands failed: %v", err)
+	}
+	if whole.FullText == "" || banded.FullText != whole.FullText {
+		t.Errorf("banded full text %q, want %q", banded.FullText, whole.FullText)
+	}
+}
@@ -0,0 +1,178 @@
+package ocr
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// quoteFolder replaces curly quotes, low quotes, and primes with straight
+// quotes (see Options.FoldQuotes).
+var quoteFolder = strings.NewReplacer(
+	"‘", "'", "’", "'", "‚", "'", "‛", "'", "′", "'",
+	"“", `"`, "”", `"`, "„", `"`, "‟", `"`, "″", `"`,
+)
+
+// lineHyphens are the characters Tesseract reads at the end of a line
+// for a word broken across lines: hyphen-minus, hyphen, soft hyphen, and
+// the not sign that broken hyphens are often read as.
+const lineHyphens = "-\u2010\u00ad\u00ac"
+
+// textPiece is a word of an OCR result with the text before it in
+// FullText (see splitFullText).
+type textPiece struct {
+	before string
+	region TextRegion
+	found  bool // Whether the word is in FullText
+}
+
+// cleanResult applies the text cleanup settings of opts to an OCR
+// result: Unicode normalization and quote folding, then dropping
+// unconfident words, joining hyphenated words, and collapsing
+// whitespace, in Regions and FullText alike. Dropped counts the words
+// dropped.
+func cleanResult(result *OCRResult, opts Options) {
+	if opts.MinConfidence == 0 && opts.Unicode == "" && !opts.FoldQuotes && !opts.Dehyphenate && !opts.CollapseWhitespace {
+		return
+	}
+	normalize := func(s string) string {
+		switch opts.Unicode {
+		case "nfc":
+			s = norm.NFC.String(s)
+		case "nfkc":
+			s = norm.NFKC.String(s)
+		}
+		if opts.FoldQuotes {
+			s = quoteFolder.Replace(s)
+		}
+		return s
+	}
+	result.FullText = normalize(result.FullText)
+	for i := range result.Regions {
+		r := &result.Regions[i]
+		r.Text = normalize(r.Text)
+		for j := range r.Alternatives {
+			r.Alternatives[j].Text = normalize(r.Alternatives[j].Text)
+		}
+	}
+	pieces, tail := splitFullText(result)
+
+	if opts.MinConfidence > 0 {
+		// A dropped word's break replaces a weaker one after it, so
+		// dropping the last word of a line keeps the line break
+		kept := pieces[:0]
+		pending := ""
+		for _, p := range pieces {
+			if p.region.Confidence < opts.MinConfidence {
+				result.Dropped++
+				if lineBreaks(p.before) > lineBreaks(pending) {
+					pending = p.before
+				}
+				continue
+			}
+			if len(kept) > 0 && lineBreaks(pending) > lineBreaks(p.before) {
+				p.before = pending
+			}
+			pending = ""
+			kept = append(kept, p)
+		}
+		pieces = kept
+	}
+
+	if opts.Dehyphenate {
+		joined := pieces[:0]
+		for _, p := range pieces {
+			if n := len(joined); n > 0 && joined[n-1].found && p.found && hyphenatedBreak(joined[n-1].region.Text, p.before, p.region.Text) {
+				prev := &joined[n-1].region
+				_, size := utf8.DecodeLastRuneInString(prev.Text)
+				prev.Text = prev.Text[:len(prev.Text)-size] + p.region.Text
+				prev.Confidence = min(prev.Confidence, p.region.Confidence)
+				prev.Alternatives, prev.Characters = nil, nil
+				continue
+			}
+			joined = append(joined, p)
+		}
+		pieces = joined
+	}
+
+	if opts.CollapseWhitespace {
+		for i := range pieces {
+			pieces[i].before = collapseSpace(pieces[i].before, i == 0)
+		}
+		tail = strings.TrimRightFunc(tail, unicode.IsSpace)
+	}
+
+	var b strings.Builder
+	result.Regions = make([]TextRegion, len(pieces))
+	for i, p := range pieces {
+		b.WriteString(p.before)
+		if p.found {
+			b.WriteString(p.region.Text)
+		}
+		result.Regions[i] = p.region
+	}
+	b.WriteString(tail)
+	result.FullText = b.String()
+}
+
+// splitFullText splits the full text of an OCR result into its words,
+// found in it in order, each with the text before it, and the text after
+// the last word. Words not found in the full text get no text before
+// them.
+func splitFullText(result *OCRResult) ([]textPiece, string) {
+	pieces := make([]textPiece, 0, len(result.Regions))
+	rest := result.FullText
+	for _, r := range result.Regions {
+		at := strings.Index(rest, r.Text)
+		if at < 0 || r.Text == "" {
+			pieces = append(pieces, textPiece{region: r})
+			continue
+		}
+		pieces = append(pieces, textPiece{before: rest[:at], region: r, found: true})
+		rest = rest[at+len(r.Text):]
+	}
+	return pieces, rest
+}
+
+// lineBreaks returns the number of line breaks in s.
+func lineBreaks(s string) int {
+	return strings.Count(s, "\n")
+}
+
+// hyphenatedBreak reports whether a word ending with a hyphen, followed
+// across one line break (before) by next, is a word broken across lines:
+// the hyphen follows a letter and next starts with a lower case letter.
+func hyphenatedBreak(word, before, next string) bool {
+	if lineBreaks(before) != 1 || strings.TrimSpace(before) != "" {
+		return false
+	}
+	last, size := utf8.DecodeLastRuneInString(word)
+	if size == 0 || !strings.ContainsRune(lineHyphens, last) {
+		return false
+	}
+	prev, _ := utf8.DecodeLastRuneInString(word[:len(word)-size])
+	first, _ := utf8.DecodeRuneInString(next)
+	return unicode.IsLetter(prev) && unicode.IsLower(first)
+}
+
+// collapseSpace collapses the whitespace between two words: to one line
+// break, or two for a paragraph break, if it has line breaks, and to one
+// space otherwise. Whitespace before the first word is removed.
+func collapseSpace(s string, first bool) string {
+	if strings.TrimSpace(s) != "" {
+		return s
+	}
+	switch {
+	case first:
+		return ""
+	case lineBreaks(s) >= 2:
+		return "\n\n"
+	case lineBreaks(s) == 1:
+		return "\n"
+	case s == "":
+		return ""
+	}
+	return " "
+}
@@ -0,0 +1,91 @@
+package ocr
+
+import "testing"
+
+func TestCleanResult(t *testing.T) {
+	// Words as Tesseract lists them, with their confidences, and the full
+	// text they were read from, with a decomposed é
+	ocrResult := func() *OCRResult {
+		words := []struct {
+			text       string
+			confidence float64
+		}{
+			{"“Eﬃcient”", 0.9}, {"recog-", 0.9}, {"nition", 0.8}, {"~", 0.2}, {"of", 0.9},
+			{"text.", 0.9}, {"Cafe\u0301", 0.9}, {"it’s", 0.9}, {"\\", 0.1},
+		}
+		regions := make([]TextRegion, len(words))
+		for i, w := range words {
+			regions[i] = TextRegion{Text: w.text, Confidence: w.confidence, Bounds: Bounds{X1: 10 * i, X2: 10*i + 8, Y2: 14}}
+		}
+		return &OCRResult{
+			FullText: "  “Eﬃcient”  recog-\nnition ~ of\ntext.\n\n\n\nCafe\u0301 it’s \\\n\f",
+			Regions:  regions,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		opts    Options
+		want    string
+		words   int
+		dropped int
+	}{
+		{
+			name:  "no cleanup",
+			want:  "  “Eﬃcient”  recog-\nnition ~ of\ntext.\n\n\n\nCafe\u0301 it’s \\\n\f",
+			words: 9,
+		},
+		{
+			name:  "nfkc and quotes",
+			opts:  Options{Unicode: "nfkc", FoldQuotes: true},
+			want:  "  \"Efficient\"  recog-\nnition ~ of\ntext.\n\n\n\nCaf\u00e9 it's \\\n\f",
+			words: 9,
+		},
+		{
+			name:    "min confidence",
+			opts:    Options{MinConfidence: 0.5},
+			want:    "  “Eﬃcient”  recog-\nnition of\ntext.\n\n\n\nCafe\u0301 it’s\n\f",
+			words:   7,
+			dropped: 2,
+		},
+		{
+			name:    "everything",
+			opts:    Options{MinConfidence: 0.5, Unicode: "nfc", FoldQuotes: true, Dehyphenate: true, CollapseWhitespace: true},
+			want:    "\"Eﬃcient\" recognition of\ntext.\n\nCaf\u00e9 it's",
+			words:   6,
+			dropped: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ocrResult()
+			cleanResult(result, tt.opts)
+			if result.FullText != tt.want {
+				t.Errorf("got full text %q, want %q", result.FullText, tt.want)
+			}
+			if len(result.Regions) != tt.words || result.Dropped != tt.dropped {
+				t.Errorf("got %d words, %d dropped, want %d, %d", len(result.Regions), result.Dropped, tt.words, tt.dropped)
+			}
+		})
+	}
+}
+
+func TestCleanResult_Dehyphenate(t *testing.T) {
+	result := &OCRResult{
+		FullText: "self-\nmade recog-\nnition well-\nKnown",
+		Regions: []TextRegion{
+			{Text: "self-", Confidence: 0.9}, {Text: "made", Confidence: 0.9},
+			{Text: "recog-", Confidence: 0.9, Bounds: Bounds{X1: 50, Y1: 0, X2: 110, Y2: 14}}, {Text: "nition", Confidence: 0.7},
+			{Text: "well-", Confidence: 0.9}, {Text: "Known", Confidence: 0.9},
+		},
+	}
+	cleanResult(result, Options{Dehyphenate: true})
+	// A hyphen before an upper case word is kept; self-made is joined, as
+	// line-end hyphens cannot tell compounds from broken words
+	if result.FullText != "selfmade recognition well-\nKnown" {
+		t.Errorf("got full text %q", result.FullText)
+	}
+	if r := result.Regions[1]; r.Text != "recognition" || r.Confidence != 0.7 || r.Bounds.X1 != 50 {
+		t.Errorf("got joined word %+v", r)
+	}
+}
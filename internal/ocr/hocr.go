@@ -0,0 +1,214 @@
+package ocr
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MaxAlternatives is the largest number of alternative readings that can
+// be requested per word.
+const MaxAlternatives = 10
+
+// lstmChoiceMode is the Tesseract variable that adds the LSTM
+// recognizer's character choices to hOCR output; "2" lists them per
+// character position.
+const lstmChoiceMode = "lstm_choice_mode"
+
+// Alternative is one reading of a word or a character, with its
+// confidence.
+type Alternative struct {
+	// Text is the reading.
+	Text string `json:"text"`
+
+	// Confidence (0.0 to 1.0) is the recognizer's confidence in it: for a
+	// word, the product of the confidences of its characters.
+	Confidence float64 `json:"confidence"`
+}
+
+// checkAlternatives returns an error if n alternatives cannot be
+// requested.
+func checkAlternatives(n int) error {
+	if n < 0 || n > MaxAlternatives {
+		return fmt.Errorf("alternatives must be between 0 and %d, got %d", MaxAlternatives, n)
+	}
+	return nil
+}
+
+// parseHOCRWords returns the words of Tesseract hOCR output produced with
+// lstm_choice_mode=2, each with up to n alternative readings and, per
+// character position, up to n character choices.
+//
+// Words come from ocrx_word spans, with their bounding box and x_wconf
+// confidence. Each character position is an ocrx_cinfo span whose id
+// starts with "lstm_choices", holding one span per choice with its
+// x_confs confidence. Words without choices, as from the legacy engine,
+// get none.
+func parseHOCRWords(r io.Reader, n int) ([]TextRegion, error) {
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+
+	regions := []TextRegion{}
+	var (
+		word      *TextRegion
+		text      strings.Builder
+		positions [][]Alternative
+		depth     int // Open elements inside the current word
+		choices   int // Depth of the open lstm_choices span, or 0
+		choice    *Alternative
+	)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid hOCR output: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			class, id, title := hocrAttrs(t)
+			if word == nil {
+				if class == "ocrx_word" {
+					word = &TextRegion{}
+					text.Reset()
+					positions = nil
+					depth = 0
+					props := hocrTitle(title)
+					if bbox := strings.Fields(props["bbox"]); len(bbox) == 4 {
+						word.Bounds.X1, _ = strconv.Atoi(bbox[0])
+						word.Bounds.Y1, _ = strconv.Atoi(bbox[1])
+						word.Bounds.X2, _ = strconv.Atoi(bbox[2])
+						word.Bounds.Y2, _ = strconv.Atoi(bbox[3])
+					}
+					conf, _ := strconv.ParseFloat(props["x_wconf"], 64)
+					word.Confidence = conf / 100
+				}
+				continue
+			}
+			depth++
+			switch {
+			case strings.HasPrefix(id, "lstm_choices"):
+				choices = depth
+				positions = append(positions, nil)
+			case choices > 0 && strings.HasPrefix(id, "choice"):
+				conf, _ := strconv.ParseFloat(hocrTitle(title)["x_confs"], 64)
+				choice = &Alternative{Confidence: conf / 100}
+			}
+		case xml.CharData:
+			switch {
+			case choice != nil:
+				choice.Text += string(t)
+			case word != nil && choices == 0:
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if word == nil {
+				continue
+			}
+			switch {
+			case choice != nil:
+				positions[len(positions)-1] = append(positions[len(positions)-1], *choice)
+				choice = nil
+			case depth == choices:
+				choices = 0
+			}
+			if depth > 0 {
+				depth--
+				continue
+			}
+			word.Text = strings.TrimSpace(text.String())
+			if word.Text != "" && word.Confidence >= 0 {
+				if n > 0 && len(positions) > 0 {
+					word.Alternatives, word.Characters = wordAlternatives(word.Text, positions, n)
+				}
+				regions = append(regions, *word)
+			}
+			word = nil
+		}
+	}
+	return regions, nil
+}
+
+// hocrAttrs returns the class, id, and title attributes of an element.
+func hocrAttrs(e xml.StartElement) (class, id, title string) {
+	for _, a := range e.Attr {
+		switch a.Name.Local {
+		case "class":
+			class = a.Value
+		case "id":
+			id = a.Value
+		case "title":
+			title = a.Value
+		}
+	}
+	return class, id, title
+}
+
+// hocrTitle splits an hOCR title ("bbox 36 92 96 116; x_wconf 95") into
+// its properties.
+func hocrTitle(title string) map[string]string {
+	props := map[string]string{}
+	for _, prop := range strings.Split(title, ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(prop), " ")
+		props[name] = strings.TrimSpace(value)
+	}
+	return props
+}
+
+// wordAlternatives returns the n most likely readings of a word other
+// than text, from the character choices at each position, and the top n
+// choices per position, most likely first.
+//
+// A reading's confidence is the product of its characters' confidences;
+// since positions are independent, keeping the n+1 best prefixes at each
+// position finds the n+1 best readings exactly.
+func wordAlternatives(text string, positions [][]Alternative, n int) ([]Alternative, [][]Alternative) {
+	byConfidence := func(alts []Alternative) {
+		sort.SliceStable(alts, func(i, j int) bool { return alts[i].Confidence > alts[j].Confidence })
+	}
+	chars := [][]Alternative{}
+	beam := []Alternative{{Confidence: 1}}
+	for _, choices := range positions {
+		if len(choices) == 0 {
+			continue
+		}
+		choices = append([]Alternative(nil), choices...)
+		byConfidence(choices)
+		top := append([]Alternative(nil), choices[:min(n, len(choices))]...)
+		for j := range top {
+			top[j].Confidence = math.Round(top[j].Confidence*1000) / 1000
+		}
+		chars = append(chars, top)
+
+		var next []Alternative
+		for _, prefix := range beam {
+			for _, c := range choices {
+				next = append(next, Alternative{Text: prefix.Text + c.Text, Confidence: prefix.Confidence * c.Confidence})
+			}
+		}
+		byConfidence(next)
+		beam = beam[:0]
+		seen := map[string]bool{}
+		for _, alt := range next {
+			if !seen[alt.Text] && len(beam) <= n {
+				seen[alt.Text] = true
+				beam = append(beam, alt)
+			}
+		}
+	}
+
+	alts := []Alternative{}
+	for _, alt := range beam {
+		if alt.Text != text && alt.Text != "" && len(alts) < n {
+			alts = append(alts, Alternative{Text: alt.Text, Confidence: math.Round(alt.Confidence*1000) / 1000})
+		}
+	}
+	return alts, chars
+}
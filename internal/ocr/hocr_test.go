@@ -0,0 +1,102 @@
+package ocr
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// hocrChoices is Tesseract 5 hOCR output with lstm_choice_mode=2 for the
+// words "ID" and "5O1", whose characters were each weighed against
+// lookalikes, and a bold word without choices.
+const hocrChoices = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN"
+    "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">
+<html xmlns="http://www.w3.org/1999/xhtml" xml:lang="en" lang="en">
+ <head>
+  <title></title>
+  <meta http-equiv="Content-Type" content="text/html;charset=utf-8"/>
+  <meta name='ocr-system' content='tesseract 5.3.0' />
+ </head>
+ <body>
+  <div class='ocr_page' id='page_1' title='image "id.png"; bbox 0 0 200 40; ppageno 0'>
+   <div class='ocr_carea' id='block_1_1' title="bbox 4 6 120 30">
+    <p class='ocr_par' id='par_1_1' lang='eng' title="bbox 4 6 120 30">
+     <span class='ocr_line' id='line_1_1' title="bbox 4 6 120 30; baseline 0 0; x_size 24">
+      <span class='ocrx_word' id='word_1_1' title='bbox 4 6 30 30; x_wconf 91'>ID
+       <span class='ocrx_cinfo' id='lstm_choices_1_1_0'>
+        <span class='ocrx_cinfo' id='choice_1_1_0' title='x_confs 97.5'>I</span>
+        <span class='ocrx_cinfo' id='choice_1_1_1' title='x_confs 2.5'>l</span></span>
+       <span class='ocrx_cinfo' id='lstm_choices_1_1_1'>
+        <span class='ocrx_cinfo' id='choice_1_1_2' title='x_confs 99'>D</span></span></span>
+      <span class='ocrx_word' id='word_1_2' title='bbox 40 6 90 30; x_wconf 62'>5O1
+       <span class='ocrx_cinfo' id='lstm_choices_1_2_0'>
+        <span class='ocrx_cinfo' id='choice_1_2_3' title='x_confs 90'>5</span>
+        <span class='ocrx_cinfo' id='choice_1_2_4' title='x_confs 10'>S</span></span>
+       <span class='ocrx_cinfo' id='lstm_choices_1_2_1'>
+        <span class='ocrx_cinfo' id='choice_1_2_5' title='x_confs 60'>O</span>
+        <span class='ocrx_cinfo' id='choice_1_2_6' title='x_confs 40'>0</span></span>
+       <span class='ocrx_cinfo' id='lstm_choices_1_2_2'>
+        <span class='ocrx_cinfo' id='choice_1_2_7' title='x_confs 80'>1</span>
+        <span class='ocrx_cinfo' id='choice_1_2_8' title='x_confs 15'>l</span>
+        <span class='ocrx_cinfo' id='choice_1_2_9' title='x_confs 5'>I</span></span></span>
+      <span class='ocrx_word' id='word_1_3' title='bbox 96 6 120 30; x_wconf 88'><strong>&amp;c</strong></span>
+     </span>
+    </p>
+   </div>
+  </div>
+ </body>
+</html>
+`
+
+func TestParseHOCRWords(t *testing.T) {
+	words, err := parseHOCRWords(strings.NewReader(hocrChoices), 2)
+	if err != nil {
+		t.Fatalf("parseHOCRWords() error = %v", err)
+	}
+	if len(words) != 3 {
+		t.Fatalf("got %d words, want 3: %+v", len(words), words)
+	}
+
+	id := words[0]
+	if id.Text != "ID" || id.Confidence != 0.91 || id.Bounds != (Bounds{X1: 4, Y1: 6, X2: 30, Y2: 30}) {
+		t.Errorf("word 1 = %+v", id)
+	}
+	if want := []Alternative{{Text: "lD", Confidence: 0.025}}; !reflect.DeepEqual(id.Alternatives, want) {
+		t.Errorf("word 1 alternatives = %+v, want %+v", id.Alternatives, want)
+	}
+
+	// 5O1 0.432, 501 0.288, 5Ol 0.081: the best reading other than the
+	// word comes first
+	number := words[1]
+	if want := []Alternative{{Text: "501", Confidence: 0.288}, {Text: "5Ol", Confidence: 0.081}}; !reflect.DeepEqual(number.Alternatives, want) {
+		t.Errorf("word 2 alternatives = %+v, want %+v", number.Alternatives, want)
+	}
+	if len(number.Characters) != 3 || len(number.Characters[2]) != 2 || number.Characters[1][1] != (Alternative{Text: "0", Confidence: 0.4}) {
+		t.Errorf("word 2 characters = %+v", number.Characters)
+	}
+
+	if words[2].Text != "&c" || words[2].Alternatives != nil || words[2].Characters != nil {
+		t.Errorf("word 3 = %+v, want no alternatives", words[2])
+	}
+
+	// Without alternatives requested, the words are read alone
+	words, err = parseHOCRWords(strings.NewReader(hocrChoices), 0)
+	if err != nil {
+		t.Fatalf("parseHOCRWords() error = %v", err)
+	}
+	if len(words) != 3 || words[1].Text != "5O1" || words[1].Alternatives != nil {
+		t.Errorf("got %+v", words)
+	}
+}
+
+func TestCheckAlternatives(t *testing.T) {
+	for _, n := range []int{-1, MaxAlternatives + 1} {
+		if err := checkAlternatives(n); err == nil {
+			t.Errorf("checkAlternatives(%d) = nil, want error", n)
+		}
+		if _, err := ExtractTextWithAlternatives("testdata/none.png", "eng", n); err == nil {
+			t.Errorf("ExtractTextWithAlternatives(%d) = nil error", n)
+		}
+	}
+}
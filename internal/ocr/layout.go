@@ -0,0 +1,252 @@
+package ocr
+
+import (
+	"sort"
+	"strings"
+)
+
+// Layout is the block structure of a page of text, in reading order (see
+// ApplyReadingOrder).
+type Layout struct {
+	// Columns is the largest number of columns found side by side.
+	Columns int `json:"columns"`
+
+	// Blocks lists the blocks of text in reading order.
+	Blocks []LayoutBlock `json:"blocks"`
+}
+
+// LayoutBlock is a block of text: a paragraph, a heading, or a cell.
+type LayoutBlock struct {
+	// Bounds is the bounding box of the block's words.
+	Bounds Bounds `json:"bounds"`
+
+	// Column is the block's column, counting from 0 at the left, in the
+	// multi-column part of the page it is in; 0 elsewhere.
+	Column int `json:"column"`
+
+	// Text is the block's lines, separated by newlines.
+	Text string `json:"text"`
+
+	// Lines lists the block's lines, top to bottom.
+	Lines []LayoutLine `json:"lines"`
+}
+
+// LayoutLine is a line of a LayoutBlock.
+type LayoutLine struct {
+	// Bounds is the bounding box of the line's words.
+	Bounds Bounds `json:"bounds"`
+
+	// Text is the line's words, left to right, separated by spaces.
+	Text string `json:"text"`
+}
+
+// ApplyReadingOrder puts the words of an OCR result in reading order,
+// for pages whose columns Tesseract's top-to-bottom order interleaves.
+// It sets result.Layout to the blocks found, and rewrites Regions and
+// FullText in reading order, with blocks separated by blank lines.
+//
+// # Algorithm
+//
+// The words are split recursively, XY-cut style, with gaps measured in
+// text heights (the median height of the words):
+//
+//  1. Columns: where a vertical gap of at least 1 text height runs
+//     through all the words, they are split into columns, read left to
+//     right.
+//  2. Bands: otherwise, where a horizontal gap of at least 1 text height
+//     runs across all the words, they are split into bands, read top to
+//     bottom. Consecutive bands that are each split into columns at the
+//     same gutters are kept together, so two columns with paragraph
+//     breaks at the same height are still read one column at a time.
+//  3. Blocks: words that cannot be split form a block, read line by
+//     line.
+//
+// A heading spanning two columns ends up in a band of its own above
+// them. Tables are read cell by cell, row by row where rows are farther
+// apart than their cells.
+//
+// Rotated text (result.Rotation) and vertical text (result.Vertical) are
+// ordered as if turned upright; the bounds of the layout stay in the
+// image's coordinates.
+func ApplyReadingOrder(result *OCRResult) {
+	quarters := result.Rotation / 90
+	if result.Vertical {
+		quarters++
+	}
+	words := make([]TextRegion, len(result.Regions))
+	heights := make([]int, 0, len(result.Regions))
+	for i, r := range result.Regions {
+		words[i] = r
+		words[i].Bounds = uprightBounds(r.Bounds, quarters)
+		heights = append(heights, words[i].Bounds.Y2-words[i].Bounds.Y1)
+	}
+	l := &layoutBuilder{words: words, layout: &Layout{Columns: 1, Blocks: []LayoutBlock{}}}
+	if len(heights) > 0 {
+		sort.Ints(heights)
+		l.gap = max(1, heights[len(heights)/2])
+		ids := make([]int, len(result.Regions))
+		for i := range ids {
+			ids[i] = i
+		}
+		l.cut(ids, -1)
+	}
+
+	ordered := make([]TextRegion, 0, len(result.Regions))
+	texts := make([]string, len(l.layout.Blocks))
+	for i, ids := range l.order {
+		for _, id := range ids {
+			ordered = append(ordered, result.Regions[id])
+		}
+		texts[i] = l.layout.Blocks[i].Text
+	}
+	if back := (4 - quarters%4) % 4; back != 0 {
+		for i := range l.layout.Blocks {
+			b := &l.layout.Blocks[i]
+			b.Bounds = uprightBounds(b.Bounds, back)
+			for j := range b.Lines {
+				b.Lines[j].Bounds = uprightBounds(b.Lines[j].Bounds, back)
+			}
+		}
+	}
+	result.Regions = ordered
+	result.FullText = strings.Join(texts, "\n\n")
+	result.Layout = l.layout
+}
+
+// layoutBuilder holds the state of ApplyReadingOrder.
+type layoutBuilder struct {
+	words  []TextRegion
+	gap    int     // Smallest gap, in pixels, between columns or bands
+	layout *Layout // Blocks found so far
+	order  [][]int // Word indexes of each block, in reading order
+}
+
+// cut splits the words ids into columns, then bands, then blocks (see
+// ApplyReadingOrder). column is the column of the words, or -1 if they
+// are not in one yet.
+func (l *layoutBuilder) cut(ids []int, column int) {
+	if columns := l.split(ids, true); len(columns) > 1 {
+		l.layout.Columns = max(l.layout.Columns, len(columns))
+		for i, c := range columns {
+			if column < 0 {
+				l.cut(c, i)
+			} else {
+				l.cut(c, column)
+			}
+		}
+		return
+	}
+	if bands := l.split(ids, false); len(bands) > 1 {
+		group := bands[0]
+		for _, band := range bands[1:] {
+			merged := append(append([]int(nil), group...), band...)
+			if len(l.split(group, true)) > 1 && len(l.split(band, true)) > 1 && len(l.split(merged, true)) > 1 {
+				group = merged
+				continue
+			}
+			l.cut(group, column)
+			group = band
+		}
+		l.cut(group, column)
+		return
+	}
+	l.block(ids, max(column, 0))
+}
+
+// split splits the words ids at the gaps of at least l.gap between them,
+// vertical gaps if across is true, horizontal gaps otherwise, and returns
+// the parts, left to right or top to bottom.
+func (l *layoutBuilder) split(ids []int, across bool) [][]int {
+	span := func(id int) (int, int) {
+		b := l.words[id].Bounds
+		if across {
+			return b.X1, b.X2
+		}
+		return b.Y1, b.Y2
+	}
+	sorted := append([]int(nil), ids...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, _ := span(sorted[i])
+		b, _ := span(sorted[j])
+		return a < b
+	})
+	var parts [][]int
+	end := 0
+	for i, id := range sorted {
+		start, stop := span(id)
+		if i == 0 || start-end >= l.gap {
+			parts = append(parts, nil)
+		}
+		parts[len(parts)-1] = append(parts[len(parts)-1], id)
+		if i == 0 || stop > end {
+			end = stop
+		}
+	}
+	return parts
+}
+
+// block adds the words ids as a block, grouping them into lines (see
+// groupLines).
+func (l *layoutBuilder) block(ids []int, column int) {
+	lines := groupLines(l.words, ids)
+	block := LayoutBlock{Column: column, Bounds: l.words[lines[0][0]].Bounds}
+	var order []int
+	var texts []string
+	for _, line := range lines {
+		words := make([]string, len(line))
+		bounds := l.words[line[0]].Bounds
+		for i, id := range line {
+			w := l.words[id]
+			words[i] = w.Text
+			bounds = unionBounds(bounds, w.Bounds)
+		}
+		text := strings.Join(words, " ")
+		block.Lines = append(block.Lines, LayoutLine{Bounds: bounds, Text: text})
+		block.Bounds = unionBounds(block.Bounds, bounds)
+		texts = append(texts, text)
+		order = append(order, line...)
+	}
+	block.Text = strings.Join(texts, "\n")
+	l.layout.Blocks = append(l.layout.Blocks, block)
+	l.order = append(l.order, order)
+}
+
+// groupLines groups the words ids into lines, top to bottom, each left to
+// right: a word joins the line it overlaps vertically by at least half
+// the height of the shorter of it and the line's last word.
+func groupLines(words []TextRegion, ids []int) [][]int {
+	sorted := append([]int(nil), ids...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := words[sorted[i]].Bounds, words[sorted[j]].Bounds
+		if a.Y1 != b.Y1 {
+			return a.Y1 < b.Y1
+		}
+		return a.X1 < b.X1
+	})
+	var lines [][]int
+	for _, id := range sorted {
+		w := words[id].Bounds
+		joined := false
+		for i, line := range lines {
+			last := words[line[len(line)-1]].Bounds
+			overlap := min(last.Y2, w.Y2) - max(last.Y1, w.Y1)
+			if overlap > 0 && overlap*2 >= min(last.Y2-last.Y1, w.Y2-w.Y1) {
+				lines[i] = append(line, id)
+				joined = true
+				break
+			}
+		}
+		if !joined {
+			lines = append(lines, []int{id})
+		}
+	}
+	for _, line := range lines {
+		sort.SliceStable(line, func(i, j int) bool { return words[line[i]].Bounds.X1 < words[line[j]].Bounds.X1 })
+	}
+	return lines
+}
+
+// unionBounds returns the bounding box of a and b.
+func unionBounds(a, b Bounds) Bounds {
+	return Bounds{X1: min(a.X1, b.X1), Y1: min(a.Y1, b.Y1), X2: max(a.X2, b.X2), Y2: max(a.Y2, b.Y2)}
+}
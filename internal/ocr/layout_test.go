@@ -0,0 +1,86 @@
+package ocr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyReadingOrder(t *testing.T) {
+	word := func(text string, x, y int) TextRegion {
+		return TextRegion{Text: text, Confidence: 0.9, Bounds: Bounds{X1: x, Y1: y, X2: x + 10*len(text), Y2: y + 14}}
+	}
+	// A heading across two columns whose paragraphs break at the same
+	// height, and a footer; listed row by row across the columns, as
+	// Tesseract can read them
+	result := &OCRResult{Regions: []TextRegion{
+		word("Annual", 100, 0), word("Report", 170, 0),
+		word("Alpha", 0, 30), word("beta", 60, 30), word("epsilon", 110, 30), word("One", 200, 30), word("two", 240, 30),
+		word("gamma", 0, 48), word("delta", 70, 48), word("three", 200, 48),
+		word("Zeta", 0, 80), word("eta", 50, 80), word("Four", 200, 80), word("five", 250, 80),
+		word("theta", 0, 98), word("six", 200, 98),
+		word("Page", 150, 130), word("1", 200, 130),
+	}}
+	ApplyReadingOrder(result)
+
+	want := []string{
+		"Annual Report",
+		"Alpha beta epsilon\ngamma delta",
+		"Zeta eta\ntheta",
+		"One two\nthree",
+		"Four five\nsix",
+		"Page 1",
+	}
+	if result.FullText != strings.Join(want, "\n\n") {
+		t.Errorf("got full text %q", result.FullText)
+	}
+	l := result.Layout
+	if l == nil || l.Columns != 2 || len(l.Blocks) != len(want) {
+		t.Fatalf("got layout %+v", l)
+	}
+	columns := []int{0, 0, 0, 1, 1, 0}
+	for i, b := range l.Blocks {
+		if b.Text != want[i] || b.Column != columns[i] {
+			t.Errorf("block %d: got %q in column %d, want %q in column %d", i, b.Text, b.Column, want[i], columns[i])
+		}
+	}
+	if b := l.Blocks[3]; b.Bounds != (Bounds{X1: 200, Y1: 30, X2: 270, Y2: 62}) || len(b.Lines) != 2 ||
+		b.Lines[0].Bounds != (Bounds{X1: 200, Y1: 30, X2: 270, Y2: 44}) {
+		t.Errorf("got right column block %+v", b)
+	}
+	if result.Regions[3].Text != "beta" || result.Regions[10].Text != "One" || result.Regions[len(result.Regions)-1].Text != "1" || len(result.Regions) != 18 {
+		t.Errorf("regions not in reading order: %+v", result.Regions)
+	}
+
+	empty := &OCRResult{Regions: []TextRegion{}}
+	ApplyReadingOrder(empty)
+	if empty.FullText != "" || empty.Layout == nil || len(empty.Layout.Blocks) != 0 {
+		t.Errorf("empty: got %+v", empty)
+	}
+}
+
+func TestApplyReadingOrder_Rotated(t *testing.T) {
+	// Two lines of text turned a quarter clockwise: the lines run down
+	// the image, the second to the left of the first
+	var regions []TextRegion
+	for _, w := range []struct {
+		text string
+		x, y int
+	}{{"three", 0, 20}, {"one", 0, 0}, {"two", 40, 0}} {
+		upright := Bounds{X1: w.x, Y1: w.y, X2: w.x + 10*len(w.text), Y2: w.y + 14}
+		b := uprightBounds(upright, 3)
+		b.X1, b.X2 = b.X1+100, b.X2+100
+		regions = append(regions, TextRegion{Text: w.text, Confidence: 0.9, Bounds: b})
+	}
+	for _, result := range []*OCRResult{
+		{Regions: regions, Rotation: 90},
+		{Regions: append([]TextRegion(nil), regions...), Vertical: true},
+	} {
+		ApplyReadingOrder(result)
+		if result.FullText != "one two\nthree" {
+			t.Errorf("got full text %q", result.FullText)
+		}
+		if b := result.Layout.Blocks[0].Bounds; b != (Bounds{X1: 66, Y1: 0, X2: 100, Y2: 70}) {
+			t.Errorf("got block bounds %+v", b)
+		}
+	}
+}
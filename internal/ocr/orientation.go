@@ -0,0 +1,293 @@
+package ocr
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"os"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+)
+
+// Options are the settings of an OCR run (see ExtractTextWithOptions).
+type Options struct {
+	// Language is the Tesseract language code, such as "eng" or
+	// "jpn+eng"; "eng" if empty.
+	Language string
+
+	// Alternatives is the number (0 to MaxAlternatives) of other
+	// readings to return for each word (see ExtractTextWithAlternatives).
+	Alternatives int
+
+	// Rotation is how far, in degrees clockwise, the text is turned from
+	// upright: 0, 90 (reading top to bottom), 180 (upside down), or 270
+	// (reading bottom to top). The image is turned back before
+	// recognition.
+	Rotation int
+
+	// AutoRotate reads the text at each of the four rotations and keeps
+	// the reading with the most confident text, ignoring Rotation.
+	AutoRotate bool
+
+	// Vertical reads vertical CJK text, in columns from right to left,
+	// with Tesseract's vertical models (see verticalLanguages) and page
+	// segmentation mode 5.
+	Vertical bool
+
+	// MinConfidence (0 to 1) drops the words read with a lower
+	// confidence, counting them in OCRResult.Dropped.
+	MinConfidence float64
+
+	// Unicode is the Unicode normalization form of the text: "nfc"
+	// composes accents with their letters, and "nfkc" also replaces
+	// compatibility characters such as ligatures and full-width forms
+	// with their plain equivalents; the text is left as read if empty.
+	Unicode string
+
+	// FoldQuotes replaces curly quotes and primes with straight quotes.
+	FoldQuotes bool
+
+	// Dehyphenate joins words hyphenated across line breaks.
+	Dehyphenate bool
+
+	// CollapseWhitespace collapses the whitespace between words to one
+	// space, one line break, or one blank line between paragraphs, and
+	// trims the text.
+	CollapseWhitespace bool
+}
+
+// verticalLanguages maps the languages written vertically to their
+// Tesseract models for vertical text.
+var verticalLanguages = map[string]string{
+	"chi_sim": "chi_sim_vert",
+	"chi_tra": "chi_tra_vert",
+	"jpn":     "jpn_vert",
+	"kor":     "kor_vert",
+}
+
+// resolve checks opts and fills in their defaults, switching the
+// languages to their vertical models for vertical text.
+func (opts Options) resolve() (Options, error) {
+	if err := checkAlternatives(opts.Alternatives); err != nil {
+		return opts, err
+	}
+	switch opts.Rotation {
+	case 0, 90, 180, 270:
+	default:
+		return opts, fmt.Errorf("rotation must be 0, 90, 180, or 270, got %d", opts.Rotation)
+	}
+	if opts.MinConfidence < 0 || opts.MinConfidence > 1 {
+		return opts, fmt.Errorf("min confidence must be between 0 and 1, got %g", opts.MinConfidence)
+	}
+	switch opts.Unicode {
+	case "", "nfc", "nfkc":
+	default:
+		return opts, fmt.Errorf("unknown unicode normalization: %s (must be nfc or nfkc)", opts.Unicode)
+	}
+	if opts.Language == "" {
+		opts.Language = "eng"
+	}
+	if opts.Vertical {
+		langs := strings.Split(opts.Language, "+")
+		vertical := false
+		for i, lang := range langs {
+			if v, ok := verticalLanguages[lang]; ok {
+				langs[i] = v
+			}
+			vertical = vertical || strings.HasSuffix(langs[i], "_vert")
+		}
+		if !vertical {
+			return opts, fmt.Errorf("vertical text needs a Chinese, Japanese, or Korean language (chi_sim, chi_tra, jpn, kor), got %q", opts.Language)
+		}
+		opts.Language = strings.Join(langs, "+")
+	}
+	return opts, nil
+}
+
+// ExtractTextWithOptions is ExtractText with the settings of opts: word
+// alternatives, rotated text, vertical text, and text cleanup. Bounds are
+// in the coordinates of the image as given, whatever its rotation, and
+// OCRResult.Rotation and Vertical record how the text was read. Tall
+// pages are cut into bands read in parallel (see bandCuts).
+func ExtractTextWithOptions(imagePath string, opts Options) (*OCRResult, error) {
+	opts, err := opts.resolve()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Rotation == 0 && !opts.AutoRotate && !tallImage(imagePath, opts) {
+		result, err := extractFile(imagePath, opts)
+		if err != nil {
+			return nil, err
+		}
+		result.Vertical = opts.Vertical
+		cleanResult(result, opts)
+		return result, nil
+	}
+
+	f, err := os.Open(imagePath)
+	if err != nil {
+		return nil, fmt.Errorf("image file not found: %w", err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	result, err := extractImage(img, opts)
+	if err != nil {
+		return nil, err
+	}
+	cleanResult(result, opts)
+	return result, nil
+}
+
+// tallImage reports whether the image file at imagePath is tall enough
+// to be read in bands (see bandCuts).
+func tallImage(imagePath string, opts Options) bool {
+	if opts.Vertical || runtime.NumCPU() < 2 {
+		return false
+	}
+	f, err := os.Open(imagePath)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	config, _, err := image.DecodeConfig(f)
+	return err == nil && config.Height >= 2*bandMinHeight
+}
+
+// ExtractTextFromRegionWithOptions is ExtractTextFromRegion with the
+// settings of opts, as in ExtractTextWithOptions.
+func ExtractTextFromRegionWithOptions(img image.Image, x1, y1, x2, y2 int, opts Options) (*OCRResult, error) {
+	opts, err := opts.resolve()
+	if err != nil {
+		return nil, err
+	}
+	region := image.Rect(x1, y1, x2, y2).Intersect(img.Bounds())
+	cropped := image.NewRGBA(image.Rect(0, 0, region.Dx(), region.Dy()))
+	draw.Draw(cropped, cropped.Bounds(), img, region.Min, draw.Src)
+
+	result, err := extractImage(cropped, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Adjust bounds to be relative to original image
+	for i := range result.Regions {
+		result.Regions[i].Bounds.X1 += region.Min.X
+		result.Regions[i].Bounds.Y1 += region.Min.Y
+		result.Regions[i].Bounds.X2 += region.Min.X
+		result.Regions[i].Bounds.Y2 += region.Min.Y
+	}
+	cleanResult(result, opts)
+	return result, nil
+}
+
+// extractImage reads the text of img at opts.Rotation, or at the rotation
+// with the highest readingScore if opts.AutoRotate is set.
+func extractImage(img image.Image, opts Options) (*OCRResult, error) {
+	rotations := []int{opts.Rotation}
+	if opts.AutoRotate {
+		rotations = []int{0, 90, 180, 270}
+	}
+	var best *OCRResult
+	bestScore := -1.0
+	for _, rotation := range rotations {
+		result, err := extractRotated(img, rotation, opts)
+		if err != nil {
+			return nil, err
+		}
+		if score := readingScore(result); score > bestScore {
+			best, bestScore = result, score
+		}
+	}
+	return best, nil
+}
+
+// extractRotated turns img upright from text rotated clockwise by
+// rotation degrees, reads it, and maps the word bounds back to img.
+func extractRotated(img image.Image, rotation int, opts Options) (*OCRResult, error) {
+	upright := turnImage(img, (4-rotation/90)%4)
+	result, err := extractUpright(upright, opts)
+	if err != nil {
+		return nil, err
+	}
+	b := img.Bounds()
+	for i := range result.Regions {
+		r := &result.Regions[i]
+		r.Bounds = unturnBounds(r.Bounds, rotation/90, b.Dx(), b.Dy())
+		r.Bounds.X1, r.Bounds.X2 = r.Bounds.X1+b.Min.X, r.Bounds.X2+b.Min.X
+		r.Bounds.Y1, r.Bounds.Y2 = r.Bounds.Y1+b.Min.Y, r.Bounds.Y2+b.Min.Y
+	}
+	result.Rotation = rotation
+	result.Vertical = opts.Vertical
+	return result, nil
+}
+
+// readingScore rates how well text was read: the sum over its words of
+// their confidence times their length, so that long, confident words,
+// which text read at the wrong rotation lacks, count most.
+func readingScore(result *OCRResult) float64 {
+	score := 0.0
+	for _, r := range result.Regions {
+		score += r.Confidence * float64(utf8.RuneCountInString(r.Text))
+	}
+	return score
+}
+
+// turnImage returns img turned clockwise by quarters quarter turns, with
+// its origin at (0, 0).
+func turnImage(img image.Image, quarters int) *image.RGBA {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if quarters%2 == 1 {
+		w, h = h, w
+	}
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			c := img.At(b.Min.X+x, b.Min.Y+y)
+			switch quarters {
+			case 1:
+				out.Set(b.Dy()-1-y, x, c)
+			case 2:
+				out.Set(b.Dx()-1-x, b.Dy()-1-y, c)
+			case 3:
+				out.Set(y, b.Dx()-1-x, c)
+			default:
+				out.Set(x, y, c)
+			}
+		}
+	}
+	return out
+}
+
+// unturnBounds maps a box in an image turned upright from text rotated
+// clockwise by quarters quarter turns back to the w by h image it was
+// turned from.
+func unturnBounds(b Bounds, quarters, w, h int) Bounds {
+	switch quarters {
+	case 1:
+		// Turned counterclockwise: upright (u, v) was (w-v, u)
+		return Bounds{X1: w - b.Y2, Y1: b.X1, X2: w - b.Y1, Y2: b.X2}
+	case 2:
+		return Bounds{X1: w - b.X2, Y1: h - b.Y2, X2: w - b.X1, Y2: h - b.Y1}
+	case 3:
+		// Turned clockwise: upright (u, v) was (v, h-u)
+		return Bounds{X1: b.Y1, Y1: h - b.X2, X2: b.Y2, Y2: h - b.X1}
+	}
+	return b
+}
+
+// uprightBounds turns a box of text rotated clockwise by quarters quarter
+// turns so that its text reads left to right, top to bottom, for
+// ordering: the result keeps relative positions but not the image's
+// coordinates. Vertical text counts as one quarter turn, its columns
+// running top to bottom and following each other to the left.
+func uprightBounds(b Bounds, quarters int) Bounds {
+	for i := 0; i < quarters%4; i++ {
+		b = Bounds{X1: b.Y1, Y1: -b.X2, X2: b.Y2, Y2: -b.X1}
+	}
+	return b
+}
@@ -0,0 +1,94 @@
+package ocr
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestOptionsResolve(t *testing.T) {
+	opts, err := Options{Vertical: true, Language: "jpn+eng"}.resolve()
+	if err != nil || opts.Language != "jpn_vert+eng" {
+		t.Errorf("got %+v, %v; want jpn_vert+eng", opts, err)
+	}
+	if opts, err := (Options{}).resolve(); err != nil || opts.Language != "eng" {
+		t.Errorf("got %+v, %v; want eng", opts, err)
+	}
+	for _, bad := range []Options{
+		{Rotation: 45},
+		{Rotation: -90},
+		{Alternatives: MaxAlternatives + 1},
+		{Vertical: true},
+		{Vertical: true, Language: "eng+deu"},
+	} {
+		if _, err := bad.resolve(); err == nil {
+			t.Errorf("expected error for %+v", bad)
+		}
+	}
+}
+
+func TestTurnImage(t *testing.T) {
+	// A 3x2 image, offset from the origin, with one marked pixel at its
+	// top left
+	img := image.NewRGBA(image.Rect(10, 20, 13, 22))
+	img.Set(10, 20, color.RGBA{255, 0, 0, 255})
+
+	for quarters, want := range []image.Point{{0, 0}, {1, 0}, {2, 1}, {0, 2}} {
+		out := turnImage(img, quarters)
+		w, h := 3, 2
+		if quarters%2 == 1 {
+			w, h = h, w
+		}
+		if out.Bounds() != image.Rect(0, 0, w, h) {
+			t.Errorf("quarters %d: got bounds %v", quarters, out.Bounds())
+		}
+		if c := out.RGBAAt(want.X, want.Y); c.R != 255 {
+			t.Errorf("quarters %d: marked pixel not at %v", quarters, want)
+		}
+	}
+}
+
+func TestUnturnBounds(t *testing.T) {
+	// A 40x20 image with a word box in it, turned upright for each
+	// rotation: the box found in the turned image maps back to it
+	w, h := 40, 20
+	box := Bounds{X1: 5, Y1: 2, X2: 15, Y2: 8}
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := box.Y1; y < box.Y2; y++ {
+		for x := box.X1; x < box.X2; x++ {
+			img.Set(x, y, color.Black)
+		}
+	}
+	for rotation := 0; rotation < 4; rotation++ {
+		turned := turnImage(img, (4-rotation)%4)
+		found := inkBounds(turned)
+		if got := unturnBounds(found, rotation, w, h); got != box {
+			t.Errorf("rotation %d: got %+v, want %+v", rotation*90, got, box)
+		}
+	}
+}
+
+// inkBounds returns the bounding box of the opaque pixels of img.
+func inkBounds(img *image.RGBA) Bounds {
+	b := Bounds{X1: img.Rect.Dx(), Y1: img.Rect.Dy()}
+	for y := 0; y < img.Rect.Dy(); y++ {
+		for x := 0; x < img.Rect.Dx(); x++ {
+			if img.RGBAAt(x, y).A != 0 {
+				b = Bounds{X1: min(b.X1, x), Y1: min(b.Y1, y), X2: max(b.X2, x+1), Y2: max(b.Y2, y+1)}
+			}
+		}
+	}
+	return b
+}
+
+func TestUprightBounds(t *testing.T) {
+	b := Bounds{X1: 5, Y1: 2, X2: 15, Y2: 8}
+	if got := uprightBounds(b, 1); got != (Bounds{X1: 2, Y1: -15, X2: 8, Y2: -5}) {
+		t.Errorf("got %+v", got)
+	}
+	for quarters := 0; quarters < 4; quarters++ {
+		if got := uprightBounds(uprightBounds(b, quarters), 4-quarters); got != b {
+			t.Errorf("quarters %d: got %+v back", quarters, got)
+		}
+	}
+}
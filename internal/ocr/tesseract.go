@@ -54,6 +54,21 @@ type TextRegion struct {
 
 	// Bounds is the bounding box around this text in the image.
 	Bounds Bounds `json:"bounds"`
+
+	// Alternatives are other readings of the word, most likely first, up
+	// to the number requested. Omitted unless alternatives were requested.
+	Alternatives []Alternative `json:"alternatives,omitempty"`
+
+	// Characters holds, for each character position of the word, the
+	// recognizer's top choices, most likely first, up to the number of
+	// alternatives requested. Omitted unless alternatives were requested.
+	Characters [][]Alternative `json:"characters,omitempty"`
+
+	// Original is the recognized text of a word corrected to a vocabulary
+	// entry (see ApplyVocabulary), and EditDistance the edits between
+	// them. Omitted for words that were not corrected.
+	Original     string `json:"original,omitempty"`
+	EditDistance int    `json:"edit_distance,omitempty"`
 }
 
 // OCRResult contains the complete results of text extraction from an image.
@@ -64,6 +79,29 @@ type OCRResult struct {
 	// Regions contains individual words with their bounding boxes and confidence scores.
 	// May be empty if bounding box extraction fails (text will still be in FullText).
 	Regions []TextRegion `json:"regions"`
+
+	// Corrections is the number of words corrected to a vocabulary entry
+	// (see ApplyVocabulary).
+	Corrections int `json:"corrections,omitempty"`
+
+	// Dropped is the number of words dropped for their low confidence
+	// (see Options.MinConfidence).
+	Dropped int `json:"dropped,omitempty"`
+
+	// Rotation is how far, in degrees clockwise, the text was turned from
+	// upright, as given or found (see Options); 0 if upright.
+	Rotation int `json:"rotation,omitempty"`
+
+	// Vertical is true if the text was read as vertical CJK text.
+	Vertical bool `json:"vertical,omitempty"`
+
+	// Values lists the numbers, amounts, percentages, and dates in the
+	// text, if requested (see ExtractValues).
+	Values []Value `json:"values,omitempty"`
+
+	// Layout is the page's blocks of text in reading order, if requested
+	// (see ApplyReadingOrder).
+	Layout *Layout `json:"layout,omitempty"`
 }
 
 // DetectTextRegionsResult contains text region locations without the actual text content.
@@ -82,6 +120,12 @@ type TextRegionBox struct {
 
 	// Confidence is Tesseract's confidence score for this being a text region (0.0 to 1.0).
 	Confidence float64 `json:"confidence"`
+
+	// Style is "printed", "handwritten", or "unknown", and HandwritingScore
+	// (0.0 to 1.0) how much the region looks handwritten. Set by the
+	// server, which classifies each region's strokes; empty here.
+	Style            string  `json:"style,omitempty"`
+	HandwritingScore float64 `json:"handwriting_score"`
 }
 
 // ErrTesseractNotFound is returned when the tesseract CLI is not installed.
@@ -143,6 +187,24 @@ func findTesseract() (string, error) {
 //     (individual words with bounding boxes and confidence).
 //   - error: Non-nil if tesseract is not installed, the image cannot be loaded, or OCR fails.
 func ExtractText(imagePath string, language string) (*OCRResult, error) {
+	return ExtractTextWithAlternatives(imagePath, language, 0)
+}
+
+// ExtractTextWithAlternatives is ExtractText with up to alternatives
+// (0 to MaxAlternatives) other readings of each word and the top choices
+// for each of its characters, from the LSTM recognizer's character
+// choices, so a caller can correct words such as IDs and amounts against
+// what they should look like.
+//
+// Word boxes and confidences then come from Tesseract's hOCR output
+// instead of its TSV output; they are the same.
+func ExtractTextWithAlternatives(imagePath string, language string, alternatives int) (*OCRResult, error) {
+	return ExtractTextWithOptions(imagePath, Options{Language: language, Alternatives: alternatives})
+}
+
+// extractFile runs the Tesseract CLI on an image file with resolved
+// options (see Options.resolve), ignoring their rotation.
+func extractFile(imagePath string, opts Options) (*OCRResult, error) {
 	tesseract, err := findTesseract()
 	if err != nil {
 		return nil, err
@@ -153,9 +215,15 @@ func ExtractText(imagePath string, language string) (*OCRResult, error) {
 		return nil, fmt.Errorf("image file not found: %w", err)
 	}
 
+	// Vertical text is one block of columns
+	args := []string{imagePath, "stdout", "-l", opts.Language}
+	if opts.Vertical {
+		args = append(args, "--psm", "5")
+	}
+
 	// Get full text
 	var stdout, stderr bytes.Buffer
-	cmd := exec.Command(tesseract, imagePath, "stdout", "-l", language)
+	cmd := exec.Command(tesseract, args...)
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
@@ -165,8 +233,14 @@ func ExtractText(imagePath string, language string) (*OCRResult, error) {
 
 	fullText := strings.TrimSpace(stdout.String())
 
-	// Get word-level bounding boxes using TSV output
-	regions, _ := extractRegionsWithTSV(tesseract, imagePath, language)
+	// Get word-level bounding boxes using TSV output, or hOCR output for
+	// alternatives
+	var regions []TextRegion
+	if opts.Alternatives > 0 {
+		regions, _ = extractRegionsWithHOCR(tesseract, args, opts.Alternatives)
+	} else {
+		regions, _ = extractRegionsWithTSV(tesseract, args)
+	}
 
 	return &OCRResult{
 		FullText: fullText,
@@ -174,10 +248,11 @@ func ExtractText(imagePath string, language string) (*OCRResult, error) {
 	}, nil
 }
 
-// extractRegionsWithTSV gets word-level bounding boxes using tesseract's TSV output.
-func extractRegionsWithTSV(tesseract, imagePath, language string) ([]TextRegion, error) {
+// extractRegionsWithTSV gets word-level bounding boxes using tesseract's
+// TSV output, running it with args as for the full text.
+func extractRegionsWithTSV(tesseract string, args []string) ([]TextRegion, error) {
 	var stdout, stderr bytes.Buffer
-	cmd := exec.Command(tesseract, imagePath, "stdout", "-l", language, "tsv")
+	cmd := exec.Command(tesseract, append(args[:len(args):len(args)], "tsv")...)
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
@@ -231,6 +306,21 @@ func extractRegionsWithTSV(tesseract, imagePath, language string) ([]TextRegion,
 	return regions, nil
 }
 
+// extractRegionsWithHOCR gets word-level bounding boxes with up to n
+// alternatives using tesseract's hOCR output with LSTM character choices,
+// running it with args as for the full text.
+func extractRegionsWithHOCR(tesseract string, args []string, n int) ([]TextRegion, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(tesseract, append(args[:len(args):len(args)], "-c", lstmChoiceMode+"=2", "hocr")...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("tesseract hOCR failed: %v", err)
+	}
+	return parseHOCRWords(&stdout, n)
+}
+
 // ExtractTextFromRegion performs OCR on a specific rectangular region of an image.
 //
 // This function extracts text only from the specified region, useful when you
@@ -248,58 +338,13 @@ func extractRegionsWithTSV(tesseract, imagePath, language string) ([]TextRegion,
 //     adjusted to be relative to the original image (not the cropped region).
 //   - error: Non-nil if cropping, temporary file creation, or OCR fails.
 func ExtractTextFromRegion(img image.Image, x1, y1, x2, y2 int, language string) (*OCRResult, error) {
-	// Crop the region
-	bounds := img.Bounds()
-	if x1 < bounds.Min.X {
-		x1 = bounds.Min.X
-	}
-	if y1 < bounds.Min.Y {
-		y1 = bounds.Min.Y
-	}
-	if x2 > bounds.Max.X {
-		x2 = bounds.Max.X
-	}
-	if y2 > bounds.Max.Y {
-		y2 = bounds.Max.Y
-	}
-
-	// Create cropped image
-	cropped := image.NewRGBA(image.Rect(0, 0, x2-x1, y2-y1))
-	for cy := y1; cy < y2; cy++ {
-		for cx := x1; cx < x2; cx++ {
-			cropped.Set(cx-x1, cy-y1, img.At(cx, cy))
-		}
-	}
-
-	// Save to temporary file
-	tmpFile, err := os.CreateTemp("", "ocr-region-*.png")
-	if err != nil {
-		return nil, fmt.Errorf("failed to create temp file: %w", err)
-	}
-	tmpPath := tmpFile.Name()
-	defer os.Remove(tmpPath)
-
-	if err := png.Encode(tmpFile, cropped); err != nil {
-		tmpFile.Close()
-		return nil, fmt.Errorf("failed to encode temp image: %w", err)
-	}
-	tmpFile.Close()
-
-	// Perform OCR
-	result, err := ExtractText(tmpPath, language)
-	if err != nil {
-		return nil, err
-	}
-
-	// Adjust bounds to be relative to original image
-	for i := range result.Regions {
-		result.Regions[i].Bounds.X1 += x1
-		result.Regions[i].Bounds.Y1 += y1
-		result.Regions[i].Bounds.X2 += x1
-		result.Regions[i].Bounds.Y2 += y1
-	}
+	return ExtractTextFromRegionWithAlternatives(img, x1, y1, x2, y2, language, 0)
+}
 
-	return result, nil
+// ExtractTextFromRegionWithAlternatives is ExtractTextFromRegion with
+// word alternatives, as in ExtractTextWithAlternatives.
+func ExtractTextFromRegionWithAlternatives(img image.Image, x1, y1, x2, y2 int, language string, alternatives int) (*OCRResult, error) {
+	return ExtractTextFromRegionWithOptions(img, x1, y1, x2, y2, Options{Language: language, Alternatives: alternatives})
 }
 
 // DetectTextRegions finds text regions in an image without performing full OCR.
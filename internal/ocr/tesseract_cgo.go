@@ -15,6 +15,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/otiai10/gosseract/v2"
@@ -121,15 +122,25 @@ type Bounds struct {
 
 // TextRegion represents a word or text block with its location and OCR confidence.
 type TextRegion struct {
-	Text       string  `json:"text"`
-	Confidence float64 `json:"confidence"`
-	Bounds     Bounds  `json:"bounds"`
+	Text         string          `json:"text"`
+	Confidence   float64         `json:"confidence"`
+	Bounds       Bounds          `json:"bounds"`
+	Alternatives []Alternative   `json:"alternatives,omitempty"`
+	Characters   [][]Alternative `json:"characters,omitempty"`
+	Original     string          `json:"original,omitempty"`
+	EditDistance int             `json:"edit_distance,omitempty"`
 }
 
 // OCRResult contains the complete results of text extraction from an image.
 type OCRResult struct {
-	FullText string       `json:"full_text"`
-	Regions  []TextRegion `json:"regions"`
+	FullText    string       `json:"full_text"`
+	Regions     []TextRegion `json:"regions"`
+	Corrections int          `json:"corrections,omitempty"`
+	Dropped     int          `json:"dropped,omitempty"`
+	Rotation    int          `json:"rotation,omitempty"`
+	Vertical    bool         `json:"vertical,omitempty"`
+	Values      []Value      `json:"values,omitempty"`
+	Layout      *Layout      `json:"layout,omitempty"`
 }
 
 // DetectTextRegionsResult contains text region locations without the actual text content.
@@ -140,12 +151,27 @@ type DetectTextRegionsResult struct {
 
 // TextRegionBox represents a detected text region's location without its content.
 type TextRegionBox struct {
-	Bounds     Bounds  `json:"bounds"`
-	Confidence float64 `json:"confidence"`
+	Bounds           Bounds  `json:"bounds"`
+	Confidence       float64 `json:"confidence"`
+	Style            string  `json:"style,omitempty"`
+	HandwritingScore float64 `json:"handwriting_score"`
 }
 
 // ExtractText performs OCR on an entire image file and returns recognized text.
 func ExtractText(imagePath string, language string) (*OCRResult, error) {
+	return ExtractTextWithAlternatives(imagePath, language, 0)
+}
+
+// ExtractTextWithAlternatives is ExtractText with up to alternatives
+// other readings of each word, from hOCR output with LSTM character
+// choices.
+func ExtractTextWithAlternatives(imagePath string, language string, alternatives int) (*OCRResult, error) {
+	return ExtractTextWithOptions(imagePath, Options{Language: language, Alternatives: alternatives})
+}
+
+// extractFile runs Tesseract on an image file with resolved options (see
+// Options.resolve), ignoring their rotation.
+func extractFile(imagePath string, opts Options) (*OCRResult, error) {
 	tessdataPath, err := ensureTessdata()
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize tessdata: %w", err)
@@ -163,14 +189,38 @@ func ExtractText(imagePath string, language string) (*OCRResult, error) {
 		return nil, fmt.Errorf("failed to set image: %w", err)
 	}
 
-	if err := client.SetLanguage(language); err != nil {
+	if err := client.SetLanguage(opts.Language); err != nil {
 		return nil, fmt.Errorf("failed to set language: %w", err)
 	}
 
+	if opts.Vertical {
+		if err := client.SetPageSegMode(gosseract.PSM_SINGLE_BLOCK_VERT_TEXT); err != nil {
+			return nil, fmt.Errorf("failed to set page segmentation mode: %w", err)
+		}
+	}
+
+	if opts.Alternatives > 0 {
+		if err := client.SetVariable(lstmChoiceMode, "2"); err != nil {
+			return nil, fmt.Errorf("failed to set %s: %w", lstmChoiceMode, err)
+		}
+	}
+
 	text, err := client.Text()
 	if err != nil {
 		return nil, fmt.Errorf("OCR failed: %w", err)
 	}
+	// Trimmed as by the CLI build, so FullText does not end in a line break
+	text = strings.TrimSpace(text)
+
+	if opts.Alternatives > 0 {
+		regions := []TextRegion{}
+		if hocr, err := client.HOCRText(); err == nil {
+			if words, err := parseHOCRWords(strings.NewReader(hocr), opts.Alternatives); err == nil {
+				regions = words
+			}
+		}
+		return &OCRResult{FullText: text, Regions: regions}, nil
+	}
 
 	// Get word-level bounding boxes
 	boxes, err := client.GetBoundingBoxes(gosseract.RIL_WORD)
@@ -198,57 +248,13 @@ func ExtractText(imagePath string, language string) (*OCRResult, error) {
 
 // ExtractTextFromRegion performs OCR on a specific rectangular region of an image.
 func ExtractTextFromRegion(img image.Image, x1, y1, x2, y2 int, language string) (*OCRResult, error) {
-	// Clamp bounds
-	bounds := img.Bounds()
-	if x1 < bounds.Min.X {
-		x1 = bounds.Min.X
-	}
-	if y1 < bounds.Min.Y {
-		y1 = bounds.Min.Y
-	}
-	if x2 > bounds.Max.X {
-		x2 = bounds.Max.X
-	}
-	if y2 > bounds.Max.Y {
-		y2 = bounds.Max.Y
-	}
-
-	// Create cropped image
-	cropped := image.NewRGBA(image.Rect(0, 0, x2-x1, y2-y1))
-	for cy := y1; cy < y2; cy++ {
-		for cx := x1; cx < x2; cx++ {
-			cropped.Set(cx-x1, cy-y1, img.At(cx, cy))
-		}
-	}
-
-	// Save to temp file
-	tmpFile, err := os.CreateTemp("", "ocr-region-*.png")
-	if err != nil {
-		return nil, fmt.Errorf("failed to create temp file: %w", err)
-	}
-	tmpPath := tmpFile.Name()
-	defer os.Remove(tmpPath)
-
-	if err := png.Encode(tmpFile, cropped); err != nil {
-		tmpFile.Close()
-		return nil, fmt.Errorf("failed to encode temp image: %w", err)
-	}
-	tmpFile.Close()
-
-	result, err := ExtractText(tmpPath, language)
-	if err != nil {
-		return nil, err
-	}
-
-	// Adjust bounds to be relative to original image
-	for i := range result.Regions {
-		result.Regions[i].Bounds.X1 += x1
-		result.Regions[i].Bounds.Y1 += y1
-		result.Regions[i].Bounds.X2 += x1
-		result.Regions[i].Bounds.Y2 += y1
-	}
+	return ExtractTextFromRegionWithAlternatives(img, x1, y1, x2, y2, language, 0)
+}
 
-	return result, nil
+// ExtractTextFromRegionWithAlternatives is ExtractTextFromRegion with
+// word alternatives, as in ExtractTextWithAlternatives.
+func ExtractTextFromRegionWithAlternatives(img image.Image, x1, y1, x2, y2 int, language string, alternatives int) (*OCRResult, error) {
+	return ExtractTextFromRegionWithOptions(img, x1, y1, x2, y2, Options{Language: language, Alternatives: alternatives})
 }
 
 // DetectTextRegions finds text regions in an image without performing full OCR.
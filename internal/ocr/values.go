@@ -0,0 +1,445 @@
+package ocr
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Value types (see Value).
+const (
+	ValueNumber     = "number"
+	ValueCurrency   = "currency"
+	ValuePercentage = "percentage"
+	ValueDate       = "date"
+)
+
+// Value is a number, amount of money, percentage, or date found in OCR
+// text (see ExtractValues).
+type Value struct {
+	// Type is ValueNumber, ValueCurrency, ValuePercentage, or ValueDate.
+	Type string `json:"type"`
+
+	// Text is the value as read, such as "1.250,00 €" or "15/03/2024".
+	Text string `json:"text"`
+
+	// Number is the value of a number, the amount of a currency value,
+	// or the percentage of a percentage value (12.5 for "12.5%").
+	Number *float64 `json:"number,omitempty"`
+
+	// Currency is the ISO 4217 code of a currency value, such as "EUR".
+	Currency string `json:"currency,omitempty"`
+
+	// Date is a date as YYYY-MM-DD.
+	Date string `json:"date,omitempty"`
+
+	// Ambiguous is true if the text has another reading without a
+	// locale: a lone separator before 3 digits, as in "1,250", or a
+	// numeric date whose day and month could be swapped.
+	Ambiguous bool `json:"ambiguous,omitempty"`
+
+	// Bounds is the bounding box of the words of the value.
+	Bounds Bounds `json:"bounds"`
+
+	// Confidence is the lowest OCR confidence of the words of the value.
+	Confidence float64 `json:"confidence"`
+}
+
+// Locale is how numbers, dates, and currencies are written (see
+// ParseLocale). The zero Locale guesses them from the text.
+type Locale struct {
+	// Decimal is the decimal separator, '.' or ','; 0 to guess each
+	// number's (see ParseNumber).
+	Decimal byte
+
+	// DateOrder is the order of the parts of numeric dates that do not
+	// start with the year: "dmy", "mdy", or "ymd"; empty to use the
+	// order the parts allow, month first if both do.
+	DateOrder string
+
+	// spaceGroups is true if spaces group thousands, as in "1 250,00".
+	spaceGroups bool
+
+	language, region string
+}
+
+// localeLanguages are the languages ParseLocale knows: their decimal
+// separator, whether they group thousands with spaces, and their date
+// order.
+var localeLanguages = map[string]struct {
+	decimal     byte
+	spaceGroups bool
+	dateOrder   string
+}{
+	"cs": {',', true, "dmy"},
+	"da": {',', false, "dmy"},
+	"de": {',', false, "dmy"},
+	"en": {'.', false, "dmy"},
+	"es": {',', false, "dmy"},
+	"fi": {',', true, "dmy"},
+	"fr": {',', true, "dmy"},
+	"hi": {'.', false, "dmy"},
+	"it": {',', false, "dmy"},
+	"ja": {'.', false, "ymd"},
+	"ko": {'.', false, "ymd"},
+	"nb": {',', true, "dmy"},
+	"nl": {',', false, "dmy"},
+	"pl": {',', true, "dmy"},
+	"pt": {',', false, "dmy"},
+	"ru": {',', true, "dmy"},
+	"sv": {',', true, "dmy"},
+	"tr": {',', false, "dmy"},
+	"zh": {'.', false, "ymd"},
+}
+
+// dollarRegions are the currencies written "$" outside the United States.
+var dollarRegions = map[string]string{
+	"AU": "AUD", "CA": "CAD", "HK": "HKD", "MX": "MXN", "NZ": "NZD", "SG": "SGD",
+}
+
+// currencySymbols are the currencies of the symbols other than "$" and
+// "¥" (see Locale.currency).
+var currencySymbols = map[string]string{
+	"€": "EUR", "£": "GBP", "₹": "INR", "₩": "KRW", "₽": "RUB", "₺": "TRY", "R$": "BRL", "US$": "USD",
+}
+
+// currencyCodes are the ISO 4217 codes recognized next to an amount.
+const currencyCodes = "USD|EUR|GBP|JPY|CNY|INR|KRW|RUB|TRY|BRL|CAD|AUD|NZD|CHF|SEK|NOK|DKK|PLN|CZK|MXN|SGD|HKD"
+
+// months are the English month names, by their first three letters.
+var months = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April,
+	"may": time.May, "jun": time.June, "jul": time.July, "aug": time.August,
+	"sep": time.September, "oct": time.October, "nov": time.November, "dec": time.December,
+}
+
+// ParseLocale parses a locale tag, such as "de-DE", "en_US", or "fr", for
+// ExtractValues: its language sets the decimal separator and date order,
+// and its region the currency of "$" and the United States' month-first
+// dates. An empty tag is the zero Locale. "en" without a region is taken
+// as American English, and Swiss locales use a decimal point.
+func ParseLocale(tag string) (Locale, error) {
+	if tag == "" {
+		return Locale{}, nil
+	}
+	language, region, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	language, region = strings.ToLower(language), strings.ToUpper(region)
+	lang, ok := localeLanguages[language]
+	if !ok {
+		return Locale{}, fmt.Errorf("unsupported locale: %s (languages: cs, da, de, en, es, fi, fr, hi, it, ja, ko, nb, nl, pl, pt, ru, sv, tr, zh)", tag)
+	}
+	l := Locale{Decimal: lang.decimal, DateOrder: lang.dateOrder, spaceGroups: lang.spaceGroups, language: language, region: region}
+	if region == "US" || language == "en" && region == "" {
+		l.DateOrder = "mdy"
+	}
+	if region == "CH" {
+		l.Decimal = '.'
+	}
+	return l, nil
+}
+
+// ParseNumber parses a number or amount as read in locale. Currency
+// symbols and codes are dropped, and a minus sign or parentheses make it
+// negative. With a locale's decimal separator, the last one is the
+// decimal point and other separators group thousands. Without, the last
+// "." or "," is the decimal separator if it is the only one and does not
+// have exactly 3 digits after it, as in "12,50", or if it differs from
+// the one before, as in "1.250,000"; otherwise separators group
+// thousands, as in "1,250".
+func ParseNumber(s string, locale Locale) (float64, bool) {
+	negative := strings.ContainsAny(s, "-\u2212") || (strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")"))
+	var digits strings.Builder
+	var seps []byte
+	decimal := -1 // Digits before the decimal separator
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+			digits.WriteByte(c)
+		case c == '.' || c == ',':
+			if locale.Decimal == 0 || c == locale.Decimal {
+				seps = append(seps, c)
+				decimal = digits.Len()
+			}
+		}
+	}
+	d := digits.String()
+	if d == "" {
+		return 0, false
+	}
+	if n := len(seps); n > 0 {
+		last := seps[n-1]
+		single := n == 1 && len(d)-decimal != 3
+		differs := n > 1 && seps[n-2] != last
+		if locale.Decimal != 0 || single || differs {
+			d = d[:decimal] + "." + d[decimal:]
+		}
+	}
+	n, err := strconv.ParseFloat(d, 64)
+	if err != nil {
+		return 0, false
+	}
+	if negative {
+		n = -n
+	}
+	return n, true
+}
+
+// valuePattern returns the regular expression matching the values of
+// locale, with a named group for each value type.
+func (l Locale) valuePattern() *regexp.Regexp {
+	group, decimal := `[,.']`, `[.,]`
+	switch l.Decimal {
+	case '.':
+		group, decimal = `[,']`, `\.`
+	case ',':
+		group, decimal = `[.']`, `,`
+		if l.spaceGroups {
+			group = `[. '\x{a0}\x{202f}]`
+		}
+	}
+	number := `[-+\x{2212}]?(?:\d{1,3}(?:` + group + `\d{3})+|\d+)(?:` + decimal + `\d+)?`
+	symbol := `(?:R\$|US\$|[$€£¥₹₩₽₺]|\b(?:` + currencyCodes + `)\b)`
+	month := `(?:jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?`
+	date := `\d{4}[-/.]\d{1,2}[-/.]\d{1,2}|` +
+		`(?i:\d{1,2}\.?\s` + month + `,?\s\d{4}|` + month + `\s\d{1,2}(?:st|nd|rd|th)?,?\s\d{4})|` +
+		`\d{1,2}[-/.]\d{1,2}[-/.](?:\d{4}|\d{2})\b`
+	return regexp.MustCompile(`(?P<date>` + date + `)|` +
+		`(?P<currency>-?` + symbol + `\s?` + number + `|` + number + `\s?` + symbol + `)|` +
+		`(?P<percentage>` + number + `\s?%)|` +
+		`(?P<number>` + number + `)`)
+}
+
+// ExtractValues finds the numbers, amounts of money, percentages, and
+// dates in the words of an OCR result, as written in locale, and sets
+// result.Values to them, line by line, top to bottom, with their typed
+// values next to their text.
+//
+// # Matching
+//
+// The words of each line are joined by single spaces, so a value can
+// span words, as in "1 250,00 €" or "March 15, 2024". Values touching
+// letters, digits, or colons, such as parts of IDs and times, or numbers
+// written for another locale, are skipped.
+//
+//   - Dates are ISO dates ("2024-03-15"), numeric dates in the locale's
+//     order ("15/03/2024", "03.15.24"), or dates with an English month
+//     name ("15 Mar 2024", "March 15th, 2024"). Two-digit years are
+//     taken as 1970 to 2069.
+//   - Currency values are numbers with a currency symbol or ISO 4217
+//     code before or after them; parentheses around them make them
+//     negative, as in accounting. "$" is the dollar of the locale's
+//     region, US dollars by default, and "¥" is Chinese yuan for Chinese
+//     and Japanese yen otherwise.
+//   - Percentages are numbers followed by "%".
+//   - Numbers are parsed with ParseNumber; thousands are grouped with
+//     spaces only in languages that do so, such as French.
+func ExtractValues(result *OCRResult, locale Locale) {
+	result.Values = []Value{}
+	pattern := locale.valuePattern()
+	names := pattern.SubexpNames()
+	all := make([]int, len(result.Regions))
+	for i := range all {
+		all[i] = i
+	}
+	for _, ids := range groupLines(result.Regions, all) {
+		line := make([]TextRegion, len(ids))
+		texts := make([]string, len(ids))
+		for i, id := range ids {
+			line[i] = result.Regions[id]
+			texts[i] = line[i].Text
+		}
+		text := strings.Join(texts, " ")
+		for _, m := range pattern.FindAllStringSubmatchIndex(text, -1) {
+			start, end := m[0], m[1]
+			if inToken(text, start, end) {
+				continue
+			}
+			kind := ""
+			for g := 1; g < len(names); g++ {
+				if m[2*g] >= 0 {
+					kind = names[g]
+				}
+			}
+			v := Value{Type: kind, Text: text[start:end]}
+			before, _ := utf8.DecodeLastRuneInString(text[:start])
+			after, _ := utf8.DecodeRuneInString(text[end:])
+			if kind == ValueCurrency && before == '(' && after == ')' {
+				start, end = start-1, end+1
+				v.Text = text[start:end]
+			}
+			if !locale.parseValue(&v) {
+				continue
+			}
+			v.Bounds, v.Confidence = spanOf(line, start, end)
+			result.Values = append(result.Values, v)
+		}
+	}
+}
+
+// inToken reports whether the match of text from start to end is part of
+// a longer token: it touches a letter, a digit, or a colon, or a "." or
+// "," with a digit on its other side, as in a number written for another
+// locale.
+func inToken(text string, start, end int) bool {
+	before, size := utf8.DecodeLastRuneInString(text[:start])
+	if valueRune(before) {
+		return true
+	}
+	if before == '.' || before == ',' {
+		if r, _ := utf8.DecodeLastRuneInString(text[:start-size]); unicode.IsDigit(r) {
+			return true
+		}
+	}
+	after, size := utf8.DecodeRuneInString(text[end:])
+	if valueRune(after) {
+		return true
+	}
+	if after == '.' || after == ',' {
+		if r, _ := utf8.DecodeRuneInString(text[end+size:]); unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// valueRune reports whether r, next to a value, makes it part of a
+// longer token: a letter, a digit, or a colon.
+func valueRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == ':'
+}
+
+// parseValue sets the typed value of v from its text, and reports
+// whether it is valid.
+func (l Locale) parseValue(v *Value) bool {
+	if v.Type == ValueDate {
+		date, ambiguous, ok := l.parseDate(v.Text)
+		v.Date, v.Ambiguous = date, ambiguous
+		return ok
+	}
+	text := v.Text
+	if v.Type == ValueCurrency {
+		code := strings.TrimFunc(text, func(r rune) bool { return unicode.IsDigit(r) || strings.ContainsRune(" .,'-+()\u2212\u00a0\u202f", r) })
+		v.Currency = l.currency(code)
+		text = strings.ReplaceAll(text, code, "")
+	}
+	n, ok := ParseNumber(text, l)
+	if !ok {
+		return false
+	}
+	v.Number = &n
+	if l.Decimal == 0 {
+		seps := strings.Count(text, ".") + strings.Count(text, ",")
+		i := strings.LastIndexAny(text, ".,")
+		v.Ambiguous = seps == 1 && len(strings.TrimRightFunc(text[i+1:], func(r rune) bool { return !unicode.IsDigit(r) })) == 3
+	}
+	return true
+}
+
+// currency returns the ISO 4217 code of a currency symbol or code.
+func (l Locale) currency(symbol string) string {
+	switch symbol {
+	case "$":
+		if code, ok := dollarRegions[l.region]; ok {
+			return code
+		}
+		return "USD"
+	case "¥":
+		if l.language == "zh" {
+			return "CNY"
+		}
+		return "JPY"
+	}
+	if code, ok := currencySymbols[symbol]; ok {
+		return code
+	}
+	return symbol
+}
+
+// parseDate parses a date as read, returning it as YYYY-MM-DD, and
+// whether its day and month could be swapped without a date order.
+func (l Locale) parseDate(s string) (date string, ambiguous, ok bool) {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(fields) != 3 {
+		return "", false, false
+	}
+	nums := make([]int, 3)
+	month := time.Month(0)
+	for i, f := range fields {
+		if m, ok := months[f[:min(3, len(f))]]; ok && unicode.IsLetter(rune(f[0])) {
+			month, nums[i] = m, -1
+			continue
+		}
+		f = strings.TrimRight(f, "stndrh")
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return "", false, false
+		}
+		nums[i] = n
+	}
+
+	var y, m, d int
+	switch {
+	case month != 0 && nums[0] == -1: // March 15, 2024
+		y, m, d = nums[2], int(month), nums[1]
+	case month != 0: // 15 March 2024
+		y, m, d = nums[2], int(month), nums[0]
+	case len(fields[0]) == 4: // 2024-03-15
+		y, m, d = nums[0], nums[1], nums[2]
+	default:
+		order := l.DateOrder
+		if order == "" {
+			order = "mdy"
+			if nums[0] > 12 {
+				order = "dmy"
+			}
+			ambiguous = nums[0] <= 12 && nums[1] <= 12 && nums[0] != nums[1]
+		}
+		switch order {
+		case "dmy":
+			d, m, y = nums[0], nums[1], nums[2]
+		case "ymd":
+			y, m, d = nums[0], nums[1], nums[2]
+		default:
+			m, d, y = nums[0], nums[1], nums[2]
+		}
+		if len(fields[2]) == 2 && order != "ymd" || len(fields[0]) == 2 && order == "ymd" {
+			y += 1900
+			if y < 1970 {
+				y += 100
+			}
+		}
+	}
+	t := time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC)
+	if m < 1 || m > 12 || t.Day() != d || int(t.Month()) != m {
+		return "", false, false
+	}
+	return t.Format("2006-01-02"), ambiguous, true
+}
+
+// spanOf returns the bounding box and lowest confidence of the words of a
+// line, joined by single spaces, that the text from start to end
+// overlaps.
+func spanOf(line []TextRegion, start, end int) (Bounds, float64) {
+	var box Bounds
+	confidence := 1.0
+	found := false
+	pos := 0
+	for _, w := range line {
+		wEnd := pos + len(w.Text)
+		if pos < end && wEnd > start {
+			if !found {
+				box, found = w.Bounds, true
+			}
+			box = unionBounds(box, w.Bounds)
+			confidence = min(confidence, w.Confidence)
+		}
+		pos = wEnd + 1
+	}
+	return box, confidence
+}
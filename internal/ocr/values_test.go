@@ -0,0 +1,158 @@
+package ocr
+
+import (
+	"strings"
+	"testing"
+)
+
+// valueLine returns the words of text, read on one line at y.
+func valueLine(text string, y int) []TextRegion {
+	var words []TextRegion
+	x := 0
+	for _, w := range strings.Fields(text) {
+		words = append(words, TextRegion{Text: w, Confidence: 0.9, Bounds: Bounds{X1: x, Y1: y, X2: x + 10*len(w), Y2: y + 14}})
+		x += 10*len(w) + 10
+	}
+	return words
+}
+
+func TestExtractValues(t *testing.T) {
+	type want struct {
+		kind, text string
+		number     float64
+		currency   string
+		date       string
+		ambiguous  bool
+	}
+	tests := []struct {
+		locale string
+		lines  []string
+		want   []want
+	}{
+		{
+			locale: "",
+			lines:  []string{"Total: $1,250.00 due March 15th, 2024", "Tax 8.5% on 03/04/24 at 10:30, ref INV-4021"},
+			want: []want{
+				{kind: ValueCurrency, text: "$1,250.00", number: 1250, currency: "USD"},
+				{kind: ValueDate, text: "March 15th, 2024", date: "2024-03-15"},
+				{kind: ValuePercentage, text: "8.5%", number: 8.5},
+				{kind: ValueDate, text: "03/04/24", date: "2024-03-04", ambiguous: true},
+			},
+		},
+		{
+			locale: "de-DE",
+			lines:  []string{"Summe 1.250,00 € am 15.03.2024", "Rabatt (12,50 EUR) und 1,250 kg, nicht 45.00 USD"},
+			want: []want{
+				{kind: ValueCurrency, text: "1.250,00 €", number: 1250, currency: "EUR"},
+				{kind: ValueDate, text: "15.03.2024", date: "2024-03-15"},
+				{kind: ValueCurrency, text: "(12,50 EUR)", number: -12.5, currency: "EUR"},
+				{kind: ValueNumber, text: "1,250", number: 1.25},
+			},
+		},
+		{
+			locale: "fr_FR",
+			lines:  []string{"Total 1 250,50 € le 03/04/2024"},
+			want: []want{
+				{kind: ValueCurrency, text: "1 250,50 €", number: 1250.5, currency: "EUR"},
+				{kind: ValueDate, text: "03/04/2024", date: "2024-04-03"},
+			},
+		},
+		{
+			locale: "en-CA",
+			lines:  []string{"Price $19.99 or 1,250 points"},
+			want: []want{
+				{kind: ValueCurrency, text: "$19.99", number: 19.99, currency: "CAD"},
+				{kind: ValueNumber, text: "1,250", number: 1250},
+			},
+		},
+	}
+	for _, tt := range tests {
+		locale, err := ParseLocale(tt.locale)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.locale, err)
+		}
+		result := &OCRResult{}
+		for i, line := range tt.lines {
+			result.Regions = append(result.Regions, valueLine(line, 30*i)...)
+		}
+		ExtractValues(result, locale)
+		if len(result.Values) != len(tt.want) {
+			t.Errorf("%q: got values %+v", tt.locale, result.Values)
+			continue
+		}
+		for i, v := range result.Values {
+			w := tt.want[i]
+			if v.Type != w.kind || v.Text != w.text || v.Currency != w.currency || v.Date != w.date || v.Ambiguous != w.ambiguous ||
+				(v.Number == nil) != (w.date != "") || v.Number != nil && *v.Number != w.number {
+				t.Errorf("%q value %d: got %+v (number %v), want %+v", tt.locale, i, v, v.Number, w)
+			}
+		}
+	}
+}
+
+func TestExtractValues_Bounds(t *testing.T) {
+	// "Total", "1", "250,00", "€": the amount spans the last three words
+	result := &OCRResult{Regions: valueLine("Total 1 250,00 €", 0)}
+	result.Regions[2].Confidence = 0.6
+	locale, _ := ParseLocale("fr")
+	ExtractValues(result, locale)
+	if len(result.Values) != 1 {
+		t.Fatalf("got values %+v", result.Values)
+	}
+	v := result.Values[0]
+	if v.Bounds != (Bounds{X1: 60, Y1: 0, X2: 180, Y2: 14}) || v.Confidence != 0.6 {
+		t.Errorf("got %+v", v)
+	}
+
+	empty := &OCRResult{}
+	ExtractValues(empty, Locale{})
+	if empty.Values == nil || len(empty.Values) != 0 {
+		t.Errorf("no words: got %+v", empty.Values)
+	}
+}
+
+func TestParseLocale(t *testing.T) {
+	tests := []struct {
+		tag       string
+		decimal   byte
+		dateOrder string
+	}{
+		{"en", '.', "mdy"},
+		{"en-GB", '.', "dmy"},
+		{"EN_us", '.', "mdy"},
+		{"de-CH", '.', "dmy"},
+		{"ja-JP", '.', "ymd"},
+		{"pt-BR", ',', "dmy"},
+	}
+	for _, tt := range tests {
+		l, err := ParseLocale(tt.tag)
+		if err != nil || l.Decimal != tt.decimal || l.DateOrder != tt.dateOrder {
+			t.Errorf("ParseLocale(%q) = %+v, %v", tt.tag, l, err)
+		}
+	}
+	if _, err := ParseLocale("xx-YY"); err == nil {
+		t.Error("expected error for an unknown language")
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		locale, in, want string
+	}{
+		{"", "2024-03-15", "2024-03-15"},
+		{"", "15/03/2024", "2024-03-15"},
+		{"", "15 Sept. 2024", "2024-09-15"},
+		{"", "1st Feb, 1999", "1999-02-01"},
+		{"ja", "24/03/15", "2024-03-15"},
+		{"en-GB", "31/12/69", "2069-12-31"},
+		{"en-GB", "1/1/70", "1970-01-01"},
+		{"", "02/30/2024", ""},
+	}
+	for _, tt := range tests {
+		l, _ := ParseLocale(tt.locale)
+		got, _, ok := l.parseDate(tt.in)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("%q parseDate(%q) = %q, %v; want %q", tt.locale, tt.in, got, ok, tt.want)
+		}
+	}
+}
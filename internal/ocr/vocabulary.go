@@ -0,0 +1,176 @@
+package ocr
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Vocabulary limits: the most entries, and the largest edit distance a
+// correction may have.
+const (
+	MaxVocabulary   = 10000
+	MaxEditDistance = 3
+)
+
+// vocabularyMinLength is the shortest word, in characters, that is
+// corrected; shorter words are too easily turned into other words.
+const vocabularyMinLength = 3
+
+// ApplyVocabulary corrects the words of an OCR result to the closest
+// entry of a vocabulary, such as product names or menu items, so words
+// misread by a character or two match what the image can contain.
+//
+// Parameters:
+//   - result: OCR result to correct in place. Corrected regions keep
+//     their recognized text in Original, with the EditDistance to the
+//     correction, and FullText has the corrected words.
+//   - vocabulary: Up to MaxVocabulary expected words. Entries of several
+//     words are split into their words.
+//   - maxDistance: Largest edit distance (1 to MaxEditDistance) to
+//     correct.
+//
+// Returns:
+//   - error: Non-nil if the vocabulary is empty or too large, or
+//     maxDistance is out of range.
+//
+// # Matching
+//
+// Words are compared without case, and without leading and trailing
+// punctuation, which is kept. A word of at least 3 characters is
+// corrected to the entry with the smallest edit distance (insertions,
+// deletions, substitutions, and transpositions of adjacent characters)
+// of at most maxDistance and a third of its length; words that already
+// match an entry, and words closest to two entries, are left as they are.
+// An alternative reading of the word (see ExtractTextWithAlternatives)
+// that matches an entry exactly is preferred to edit distance.
+func ApplyVocabulary(result *OCRResult, vocabulary []string, maxDistance int) error {
+	if maxDistance < 1 || maxDistance > MaxEditDistance {
+		return fmt.Errorf("max distance must be between 1 and %d, got %d", MaxEditDistance, maxDistance)
+	}
+	if len(vocabulary) == 0 || len(vocabulary) > MaxVocabulary {
+		return fmt.Errorf("vocabulary must have 1 to %d entries, got %d", MaxVocabulary, len(vocabulary))
+	}
+
+	// Entries by lower case form, and by length for the distance search
+	entries := map[string]string{}
+	byLength := map[int][]string{}
+	for _, entry := range vocabulary {
+		for _, word := range strings.Fields(entry) {
+			key := strings.ToLower(word)
+			if _, ok := entries[key]; !ok {
+				entries[key] = word
+				n := utf8.RuneCountInString(key)
+				byLength[n] = append(byLength[n], key)
+			}
+		}
+	}
+
+	corrected := make([]string, len(result.Regions))
+	for i := range result.Regions {
+		region := &result.Regions[i]
+		prefix, core, suffix := splitPunctuation(region.Text)
+		key := strings.ToLower(core)
+		n := utf8.RuneCountInString(key)
+		if _, ok := entries[key]; ok || n < vocabularyMinLength {
+			continue
+		}
+
+		match, distance := "", 0
+		for _, alt := range region.Alternatives {
+			_, altCore, _ := splitPunctuation(alt.Text)
+			if entry, ok := entries[strings.ToLower(altCore)]; ok {
+				match, distance = entry, editDistance(key, strings.ToLower(altCore))
+				break
+			}
+		}
+		if match == "" {
+			limit := min(maxDistance, n/3)
+			best, ties := limit+1, 0
+			for length := n - limit; length <= n+limit; length++ {
+				for _, candidate := range byLength[length] {
+					d := editDistance(key, candidate)
+					switch {
+					case d < best:
+						best, ties, match = d, 1, entries[candidate]
+					case d == best:
+						ties++
+					}
+				}
+			}
+			if best > limit || ties > 1 {
+				continue
+			}
+			distance = best
+		}
+
+		region.Original, region.EditDistance = region.Text, distance
+		region.Text = prefix + match + suffix
+		corrected[i] = region.Text
+		result.Corrections++
+	}
+
+	// Replace the corrected words in the full text, in reading order
+	if result.Corrections > 0 {
+		var b strings.Builder
+		rest := result.FullText
+		for i, text := range corrected {
+			if text == "" {
+				continue
+			}
+			at := strings.Index(rest, result.Regions[i].Original)
+			if at < 0 {
+				continue
+			}
+			b.WriteString(rest[:at])
+			b.WriteString(text)
+			rest = rest[at+len(result.Regions[i].Original):]
+		}
+		b.WriteString(rest)
+		result.FullText = b.String()
+	}
+	return nil
+}
+
+// splitPunctuation splits a word into its leading punctuation, its core,
+// and its trailing punctuation.
+func splitPunctuation(word string) (prefix, core, suffix string) {
+	isWordRune := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+	start := strings.IndexFunc(word, isWordRune)
+	if start < 0 {
+		return word, "", ""
+	}
+	end := strings.LastIndexFunc(word, isWordRune)
+	_, size := utf8.DecodeRuneInString(word[end:])
+	return word[:start], word[start : end+size], word[end+size:]
+}
+
+// editDistance returns the optimal string alignment distance between a
+// and b: the fewest insertions, deletions, substitutions, and
+// transpositions of adjacent characters turning one into the other.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// Three rows of the distance table: two rows back, previous, current
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,93 @@
+package ocr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyVocabulary(t *testing.T) {
+	menu := []string{"Cappuccino", "Latte", "Espresso", "Flat White", "Mocha", "Macchiato", "Matcha"}
+	words := func(text string) []TextRegion {
+		var regions []TextRegion
+		for _, w := range strings.Fields(text) {
+			regions = append(regions, TextRegion{Text: w, Confidence: 0.8})
+		}
+		return regions
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		distance int
+		want     string
+		fixes    int
+	}{
+		{"substitutions", "Cappucino 4.50\nLatle, Espreso", 2, "Cappuccino 4.50\nLatte, Espresso", 3},
+		{"transposition", "Fiat Whtie", 2, "Flat White", 2},
+		{"case only", "LATTE mocha", 2, "LATTE mocha", 0},
+		{"too far", "Lemonade Tea", 2, "Lemonade Tea", 0},
+		{"limited distance", "Expreso", 1, "Expreso", 0},
+		{"ambiguous", "Mtcha", 2, "Mtcha", 0},
+		{"short words", "La Lat", 2, "La Flat", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &OCRResult{FullText: tt.text, Regions: words(tt.text)}
+			if err := ApplyVocabulary(result, menu, tt.distance); err != nil {
+				t.Fatalf("ApplyVocabulary() error = %v", err)
+			}
+			if result.FullText != tt.want || result.Corrections != tt.fixes {
+				t.Errorf("got %q with %d corrections, want %q with %d", result.FullText, result.Corrections, tt.want, tt.fixes)
+			}
+			for _, region := range result.Regions {
+				if (region.Original != "") != (region.EditDistance > 0) {
+					t.Errorf("inconsistent correction: %+v", region)
+				}
+			}
+		})
+	}
+
+	// The recognizer's own alternative wins over the closest entry
+	result := &OCRResult{FullText: "Macha", Regions: []TextRegion{{
+		Text:         "Macha",
+		Alternatives: []Alternative{{Text: "Mocha", Confidence: 0.2}, {Text: "Matcha", Confidence: 0.1}},
+	}}}
+	if err := ApplyVocabulary(result, menu, 2); err != nil {
+		t.Fatalf("ApplyVocabulary() error = %v", err)
+	}
+	if r := result.Regions[0]; r.Text != "Mocha" || r.Original != "Macha" || r.EditDistance != 1 || result.FullText != "Mocha" {
+		t.Errorf("got %+v, full text %q", r, result.FullText)
+	}
+}
+
+func TestApplyVocabulary_Errors(t *testing.T) {
+	result := &OCRResult{}
+	if err := ApplyVocabulary(result, nil, 2); err == nil {
+		t.Error("expected error for an empty vocabulary")
+	}
+	for _, d := range []int{0, MaxEditDistance + 1} {
+		if err := ApplyVocabulary(result, []string{"word"}, d); err == nil {
+			t.Errorf("expected error for max distance %d", d)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"latte", "latte", 0},
+		{"latte", "latle", 1},
+		{"white", "whtie", 1},
+		{"espreso", "espresso", 1},
+		{"café", "cafe", 1},
+		{"ca", "abc", 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package server
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+
+	"github.com/ironsheep/image-tools-mcp/internal/detection"
+	"github.com/ironsheep/image-tools-mcp/internal/imaging"
+)
+
+// Fixed thresholds of the accessibility audit.
+const (
+	// inkHeightPerFontSize is the typical ratio of a text line's inked
+	// height (ascenders to descenders) to its font size, used to estimate
+	// font sizes from pixels.
+	inkHeightPerFontSize = 0.85
+
+	// largeTextSize is the font size, in CSS pixels, from which WCAG
+	// counts text as large (18pt) and lowers the contrast it requires.
+	largeTextSize = 24
+
+	// minTargetSizeAA is the smallest touch target, in CSS pixels, that
+	// WCAG 2.2 success criterion 2.5.8 (level AA) allows.
+	minTargetSizeAA = 24
+
+	// minTargetCandidate is the smallest side, in CSS pixels, of a
+	// rectangle treated as a button; smaller ones are glyphs or rules.
+	minTargetCandidate = 10
+
+	// maxTargetWidth and maxTargetHeight bound, in CSS pixels, the
+	// rectangles treated as buttons; larger ones are panels or cards.
+	maxTargetWidth  = 400
+	maxTargetHeight = 100
+
+	// minVisionColorPercentage is the share of the image a color must
+	// cover for the color vision check (see imaging.ColorVisionConflicts).
+	minVisionColorPercentage = 0.5
+)
+
+// AccessibilityAudit is the result of image_accessibility_audit: the
+// accessibility problems found in a screenshot of a user interface, each
+// located by a bounding box.
+//
+// A check that cannot run (an image too small for text detection, say)
+// is left out and the reason recorded in Skipped.
+type AccessibilityAudit struct {
+	// Passed is true if no issue has severity "error".
+	Passed bool `json:"passed"`
+
+	// Level is the WCAG conformance level audited: "AA" or "AAA".
+	Level string `json:"level"`
+
+	// Scale is the number of image pixels per CSS pixel. Sizes in the
+	// issues are in CSS pixels.
+	Scale float64 `json:"scale"`
+
+	// Errors and Warnings count the issues of each severity, including
+	// any left out of Issues by max_issues.
+	Errors   int `json:"errors"`
+	Warnings int `json:"warnings"`
+
+	// Checked counts the elements examined by each check.
+	Checked AccessibilityChecked `json:"checked"`
+
+	// Issues lists the problems found, errors first, then by check and
+	// by position from the top left.
+	Issues []AccessibilityIssue `json:"issues"`
+
+	// Skipped maps each check that could not run to the reason.
+	Skipped map[string]string `json:"skipped,omitempty"`
+}
+
+// AccessibilityChecked counts the elements an AccessibilityAudit
+// examined.
+type AccessibilityChecked struct {
+	// TextRegions is the number of detected text regions whose colors
+	// and size could be measured.
+	TextRegions int `json:"text_regions"`
+
+	// Targets is the number of detected button-sized rectangles.
+	Targets int `json:"targets"`
+}
+
+// AccessibilityIssue is one problem found by an accessibility audit. The
+// measurement fields present depend on Check.
+type AccessibilityIssue struct {
+	// Check is "contrast", "text_size", "touch_target", or "color_vision".
+	Check string `json:"check"`
+
+	// Severity is "error" for a failure of the audited WCAG level, or
+	// "warning" for a likely problem that WCAG does not strictly require
+	// or that cannot be confirmed from pixels alone.
+	Severity string `json:"severity"`
+
+	// Bounds locates the problem in image pixels.
+	Bounds detection.Bounds `json:"bounds"`
+
+	// Message describes the problem.
+	Message string `json:"message"`
+
+	// ContrastRatio and RequiredRatio are the measured and required WCAG
+	// contrast ratios, and Foreground and Background the measured colors
+	// (contrast).
+	ContrastRatio float64 `json:"contrast_ratio,omitempty"`
+	RequiredRatio float64 `json:"required_ratio,omitempty"`
+	Foreground    string  `json:"foreground,omitempty"`
+	Background    string  `json:"background,omitempty"`
+
+	// TextSize is the estimated font size in CSS pixels (contrast,
+	// text_size).
+	TextSize float64 `json:"text_size,omitempty"`
+
+	// TargetWidth and TargetHeight are the target's size in CSS pixels
+	// (touch_target).
+	TargetWidth  float64 `json:"target_width,omitempty"`
+	TargetHeight float64 `json:"target_height,omitempty"`
+
+	// Deficiency, Colors, and RelatedBounds give the color vision
+	// deficiency, the two colors it confuses, and where the second color
+	// is; Bounds locates the first (color_vision).
+	Deficiency    string            `json:"deficiency,omitempty"`
+	Colors        []string          `json:"colors,omitempty"`
+	RelatedBounds *detection.Bounds `json:"related_bounds,omitempty"`
+}
+
+// accessibilityOptions are the parameters of an accessibility audit.
+type accessibilityOptions struct {
+	level         string  // "AA" or "AAA"
+	scale         float64 // Image pixels per CSS pixel
+	minTextSize   float64 // Smallest font size without a warning, in CSS pixels
+	minTargetSize float64 // Smallest target side without a warning, in CSS pixels
+	maxIssues     int     // Issues listed; the counts include the rest
+}
+
+// checkOrder ranks the checks for sorting issues.
+var checkOrder = map[string]int{"contrast": 0, "text_size": 1, "touch_target": 2, "color_vision": 3}
+
+// buildAccessibilityAudit runs the checks of an AccessibilityAudit on img.
+func buildAccessibilityAudit(img image.Image, opts accessibilityOptions) *AccessibilityAudit {
+	audit := &AccessibilityAudit{Level: opts.level, Scale: opts.scale}
+	skip := func(check string, err error) {
+		if audit.Skipped == nil {
+			audit.Skipped = make(map[string]string)
+		}
+		audit.Skipped[check] = err.Error()
+	}
+	var issues []AccessibilityIssue
+	var textBounds []detection.Bounds
+
+	if text, err := detection.DetectTextRegions(img, 0.5); err != nil {
+		skip("text", err)
+	} else {
+		for _, region := range text.Regions {
+			textBounds = append(textBounds, region.Bounds)
+			found, ok := auditText(img, region.Bounds, opts)
+			if ok {
+				audit.Checked.TextRegions++
+				issues = append(issues, found...)
+			}
+		}
+	}
+
+	minArea := int(math.Ceil(64 * opts.scale * opts.scale))
+	if rects, err := detection.DetectRectangles(img, minArea, 0.9); err != nil {
+		skip("touch_target", err)
+	} else {
+		found, targets := auditTargets(img, rects.Rectangles, textBounds, opts)
+		audit.Checked.Targets = targets
+		issues = append(issues, found...)
+	}
+
+	if conflicts, err := imaging.ColorVisionConflicts(img, minVisionColorPercentage); err != nil {
+		skip("color_vision", err)
+	} else {
+		for _, c := range conflicts {
+			a, b := c.Colors[0].Hex, c.Colors[1].Hex
+			related := regionBounds(c.Bounds[1])
+			issues = append(issues, AccessibilityIssue{
+				Check:         "color_vision",
+				Severity:      "warning",
+				Bounds:        regionBounds(c.Bounds[0]),
+				Message:       fmt.Sprintf("%s and %s look alike with %s; do not rely on color alone to tell them apart", a, b, c.Deficiency),
+				Deficiency:    c.Deficiency,
+				Colors:        []string{a, b},
+				RelatedBounds: &related,
+			})
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if a.Severity != b.Severity {
+			return a.Severity == "error"
+		}
+		if a.Check != b.Check {
+			return checkOrder[a.Check] < checkOrder[b.Check]
+		}
+		if a.Bounds.Y1 != b.Bounds.Y1 {
+			return a.Bounds.Y1 < b.Bounds.Y1
+		}
+		return a.Bounds.X1 < b.Bounds.X1
+	})
+	for _, issue := range issues {
+		if issue.Severity == "error" {
+			audit.Errors++
+		} else {
+			audit.Warnings++
+		}
+	}
+	audit.Passed = audit.Errors == 0
+	audit.Issues = truncate(issues, opts.maxIssues)
+	if audit.Issues == nil {
+		audit.Issues = []AccessibilityIssue{}
+	}
+	return audit
+}
+
+// auditText checks the contrast and size of the text in one detected
+// region. ok is false if the region holds nothing measurable as text.
+func auditText(img image.Image, b detection.Bounds, opts accessibilityOptions) (issues []AccessibilityIssue, ok bool) {
+	tc, err := imaging.TextContrast(img, imaging.Region{X1: b.X1, Y1: b.Y1, X2: b.X2, Y2: b.Y2})
+	if err != nil || tc.Lines == 0 || tc.InkShare < 0.02 {
+		return nil, false
+	}
+	size := math.Round(float64(tc.LineHeight)/opts.scale/inkHeightPerFontSize*2) / 2
+
+	large := size >= largeTextSize
+	required := 4.5
+	switch {
+	case opts.level == "AAA" && !large:
+		required = 7
+	case opts.level == "AA" && large:
+		required = 3
+	}
+	if tc.Ratio < required {
+		kind := "normal"
+		if large {
+			kind = "large"
+		}
+		issues = append(issues, AccessibilityIssue{
+			Check:         "contrast",
+			Severity:      "error",
+			Bounds:        b,
+			Message:       fmt.Sprintf("Contrast %.2f:1 of %s on %s is below the %v:1 WCAG %s minimum for %s text", tc.Ratio, tc.Foreground, tc.Background, required, opts.level, kind),
+			ContrastRatio: tc.Ratio,
+			RequiredRatio: required,
+			Foreground:    tc.Foreground,
+			Background:    tc.Background,
+			TextSize:      size,
+		})
+	}
+	if size < opts.minTextSize {
+		issues = append(issues, AccessibilityIssue{
+			Check:    "text_size",
+			Severity: "warning",
+			Bounds:   b,
+			Message:  fmt.Sprintf("Text is about %vpx, smaller than %vpx", size, opts.minTextSize),
+			TextSize: size,
+		})
+	}
+	return issues, true
+}
+
+// auditTargets checks the size of the touch targets among rects, largest
+// first, and returns the issues and the number of targets checked.
+//
+// A target is a button-sized rectangle with something inside it other
+// than one flat color: a label, an icon, or its own outline. Solid blocks
+// such as color swatches, rectangles inside a text region (glyphs), and
+// rectangles inside another target (the inner edge of a button's border)
+// are not targets.
+func auditTargets(img image.Image, rects []detection.Rectangle, text []detection.Bounds, opts accessibilityOptions) ([]AccessibilityIssue, int) {
+	minError := float64(minTargetSizeAA)
+	if opts.level == "AAA" {
+		minError = opts.minTargetSize
+	}
+	var issues []AccessibilityIssue
+	var targets []detection.Bounds
+	for _, r := range rects {
+		w, h := float64(r.Width)/opts.scale, float64(r.Height)/opts.scale
+		if w > maxTargetWidth || h > maxTargetHeight {
+			continue
+		}
+		if w < minTargetCandidate || h < minTargetCandidate ||
+			containedIn(r.Bounds, targets) || containedIn(r.Bounds, text) || flatInside(img, r.Bounds) {
+			continue
+		}
+		targets = append(targets, r.Bounds)
+
+		side := math.Min(w, h)
+		if side >= opts.minTargetSize && side >= minError {
+			continue
+		}
+		issue := AccessibilityIssue{
+			Check:        "touch_target",
+			Severity:     "warning",
+			Bounds:       r.Bounds,
+			TargetWidth:  math.Round(w*10) / 10,
+			TargetHeight: math.Round(h*10) / 10,
+		}
+		limit := opts.minTargetSize
+		if side < minError {
+			issue.Severity = "error"
+			limit = minError
+		}
+		issue.Message = fmt.Sprintf("Target is %vx%vpx, smaller than %vx%vpx", issue.TargetWidth, issue.TargetHeight, limit, limit)
+		issues = append(issues, issue)
+	}
+	return issues, len(targets)
+}
+
+// containedIn reports whether b lies entirely inside any of boxes.
+func containedIn(b detection.Bounds, boxes []detection.Bounds) bool {
+	for _, o := range boxes {
+		if b.X1 >= o.X1 && b.Y1 >= o.Y1 && b.X2 <= o.X2 && b.Y2 <= o.Y2 {
+			return true
+		}
+	}
+	return false
+}
+
+// flatInside reports whether the inside of b, 2 pixels in from its
+// edges, is a single flat color.
+func flatInside(img image.Image, b detection.Bounds) bool {
+	inner, err := imaging.CropRegion(img, imaging.Region{X1: b.X1 + 2, Y1: b.Y1 + 2, X2: b.X2 - 2, Y2: b.Y2 - 2})
+	if err != nil {
+		return true
+	}
+	blank, err := imaging.IsBlank(inner, 3)
+	return err == nil && blank.IsBlank
+}
+
+// regionBounds converts an imaging.Region to detection.Bounds.
+func regionBounds(r imaging.Region) detection.Bounds {
+	return detection.Bounds{X1: r.X1, Y1: r.Y1, X2: r.X2, Y2: r.Y2}
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// fill paints a rectangle of img.
+func fill(img *image.RGBA, x1, y1, x2, y2 int, c color.RGBA) {
+	for y := y1; y < y2; y++ {
+		for x := x1; x < x2; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+// auditScene draws a small UI on white: three lines of 13-pixel text in
+// fg, a 120x48 button and a 16x16 icon button (both outlined in dark
+// gray), and red and green status dots.
+func auditScene(fg color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 300))
+	fill(img, 0, 0, 400, 300, color.RGBA{255, 255, 255, 255})
+	d := &font.Drawer{Dst: img, Src: image.NewUniform(fg), Face: basicfont.Face7x13}
+	for i, line := range []string{"The quick brown fox jumps over", "the lazy dog. Pack my box with", "five dozen liquor jugs, quickly."} {
+		d.Dot = fixed.P(20, 40+i*18)
+		d.DrawString(line)
+	}
+	outline := func(x1, y1, x2, y2 int) {
+		gray := color.RGBA{60, 60, 60, 255}
+		fill(img, x1, y1, x2, y1+2, gray)
+		fill(img, x1, y2-2, x2, y2, gray)
+		fill(img, x1, y1, x1+2, y2, gray)
+		fill(img, x2-2, y1, x2, y2, gray)
+	}
+	outline(20, 120, 140, 168)
+	outline(200, 130, 216, 146)
+	fill(img, 40, 220, 70, 250, color.RGBA{208, 48, 48, 255})
+	fill(img, 120, 220, 150, 250, color.RGBA{48, 160, 48, 255})
+	return img
+}
+
+// auditOptions are the tool's default audit options.
+var auditOptions = accessibilityOptions{level: "AA", scale: 1, minTextSize: 12, minTargetSize: 44, maxIssues: 50}
+
+// issuesOf returns the issues of an audit for one check.
+func issuesOf(a *AccessibilityAudit, check string) []AccessibilityIssue {
+	var found []AccessibilityIssue
+	for _, issue := range a.Issues {
+		if issue.Check == check {
+			found = append(found, issue)
+		}
+	}
+	return found
+}
+
+func TestBuildAccessibilityAudit(t *testing.T) {
+	audit := buildAccessibilityAudit(auditScene(color.RGBA{150, 150, 150, 255}), auditOptions)
+	if audit.Passed || audit.Checked.TextRegions == 0 || audit.Checked.Targets != 2 {
+		t.Fatalf("got passed=%v checked=%+v", audit.Passed, audit.Checked)
+	}
+	if audit.Errors+audit.Warnings != len(audit.Issues) {
+		t.Errorf("counts %d+%d, but %d issues", audit.Errors, audit.Warnings, len(audit.Issues))
+	}
+	if audit.Issues[0].Severity != "error" || audit.Issues[len(audit.Issues)-1].Severity != "warning" {
+		t.Error("errors should be listed before warnings")
+	}
+
+	// Gray text on white fails AA contrast
+	contrast := issuesOf(audit, "contrast")
+	if len(contrast) == 0 || contrast[0].Foreground != "#969696" || contrast[0].Background != "#FFFFFF" ||
+		contrast[0].ContrastRatio != 2.96 || contrast[0].RequiredRatio != 4.5 {
+		t.Errorf("contrast issues: got %+v", contrast)
+	}
+	// 13-pixel bitmap text is below 12 CSS pixels
+	if size := issuesOf(audit, "text_size"); len(size) == 0 || size[0].TextSize >= 12 {
+		t.Errorf("text size issues: got %+v", size)
+	}
+	// Only the 16x16 icon button is too small; the swatches are not buttons
+	target := issuesOf(audit, "touch_target")
+	if len(target) != 1 || target[0].Severity != "error" || target[0].TargetWidth != 16 {
+		t.Errorf("touch target issues: got %+v", target)
+	}
+	vision := issuesOf(audit, "color_vision")
+	if len(vision) != 1 || vision[0].Deficiency != "deuteranopia" || vision[0].RelatedBounds == nil {
+		t.Errorf("color vision issues: got %+v", vision)
+	}
+
+	// Dark text passes contrast
+	audit = buildAccessibilityAudit(auditScene(color.RGBA{30, 30, 30, 255}), auditOptions)
+	if c := issuesOf(audit, "contrast"); len(c) != 0 {
+		t.Errorf("dark text: got contrast issues %+v", c)
+	}
+
+	// Truncating the issues keeps the counts of all of them
+	opts := auditOptions
+	opts.level, opts.maxIssues = "AAA", 1
+	audit = buildAccessibilityAudit(auditScene(color.RGBA{30, 30, 30, 255}), opts)
+	if audit.Level != "AAA" || len(audit.Issues) != 1 || audit.Issues[0].Check != "touch_target" {
+		t.Errorf("AAA with max 1 issue: got %+v", audit.Issues)
+	}
+	if audit.Errors != 1 || audit.Warnings != 2 {
+		t.Errorf("counts: got %d errors %d warnings, want 1 and 2", audit.Errors, audit.Warnings)
+	}
+}
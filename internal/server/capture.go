@@ -0,0 +1,169 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// CaptureRecord is one line of a capture file: a request as received, the
+// response the server sent (absent for notifications and unparsable
+// lines), and hashes of the image files the request named.
+//
+// Responses are stored in capture form (see captureForm), so captures stay
+// small and can be compared across runs.
+type CaptureRecord struct {
+	Time     string            `json:"time"`               // RFC 3339, when the request was handled
+	Request  json.RawMessage   `json:"request"`            // Request line as received
+	Response json.RawMessage   `json:"response,omitempty"` // Response in capture form
+	Inputs   map[string]string `json:"inputs,omitempty"`   // Image path -> SHA-256 of its contents, "" if unreadable
+}
+
+// SetCapture makes the server append a CaptureRecord for every request it
+// reads in Run to w, one JSON object per line. Pass nil to stop capturing.
+// It should be called before Run.
+func (s *Server) SetCapture(w io.Writer) {
+	s.captureMu.Lock()
+	s.capture = w
+	s.captureMu.Unlock()
+}
+
+// captureRequest writes the capture record for one request line and the
+// response to it (nil if none was sent). Failures are logged; capturing
+// never interrupts the session.
+func (s *Server) captureRequest(line []byte, resp *MCPResponse) {
+	s.captureMu.Lock()
+	defer s.captureMu.Unlock()
+	if s.capture == nil {
+		return
+	}
+
+	rec := CaptureRecord{
+		Time:    time.Now().UTC().Format(time.RFC3339),
+		Request: json.RawMessage(line),
+		Inputs:  hashInputs(line),
+	}
+	if !json.Valid(line) {
+		// Keep the line, quoted, so the record itself stays valid JSON
+		quoted, _ := json.Marshal(string(line))
+		rec.Request = quoted
+	}
+	if resp != nil {
+		form, err := captureForm(resp)
+		if err != nil {
+			log.Printf("Failed to capture response: %v", err)
+		}
+		rec.Response = form
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		log.Printf("Failed to capture request: %v", err)
+		return
+	}
+	if _, err := s.capture.Write(append(data, '\n')); err != nil {
+		log.Printf("Failed to write capture: %v", err)
+	}
+}
+
+// captureForm returns a response as stored in capture files: encoded
+// images (any "*_base64" field) are replaced by their SHA-256 hashes, and
+// the JSON text content of tool results is dropped, since it repeats
+// structuredContent.
+func captureForm(resp *MCPResponse) (json.RawMessage, error) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	if result, ok := v.(map[string]interface{})["result"].(map[string]interface{}); ok {
+		if _, ok := result["structuredContent"]; ok {
+			delete(result, "content")
+		}
+	}
+	return json.Marshal(hashImages(v))
+}
+
+// hashImages replaces the string value of every "*_base64" field in the
+// decoded JSON value v with "sha256:" and the hash of the string.
+func hashImages(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if s, ok := e.(string); ok && strings.HasSuffix(k, "_base64") {
+				sum := sha256.Sum256([]byte(s))
+				v[k] = "sha256:" + hex.EncodeToString(sum[:])
+			} else {
+				v[k] = hashImages(e)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = hashImages(v[i])
+		}
+	}
+	return v
+}
+
+// hashInputs returns the SHA-256 of every file named by a path argument
+// ("path", "path_a", "previous_path", ...) in a tools/call request line,
+// or nil if there are none. Path arguments may be nested, as in the
+// images of image_contact_sheet, and may hold arrays, as does the paths
+// of image_check_specs; every string under one is a file path.
+func hashInputs(line []byte) map[string]string {
+	var req struct {
+		Params struct {
+			Arguments interface{} `json:"arguments"`
+		} `json:"params"`
+	}
+	if json.Unmarshal(line, &req) != nil {
+		return nil
+	}
+
+	hashes := make(map[string]string)
+	var walk func(v interface{}, isPath bool)
+	walk = func(v interface{}, isPath bool) {
+		switch v := v.(type) {
+		case string:
+			if isPath {
+				hashes[v] = hashFile(v)
+			}
+		case map[string]interface{}:
+			for k, e := range v {
+				walk(e, isPath || strings.Contains(k, "path"))
+			}
+		case []interface{}:
+			for _, e := range v {
+				walk(e, isPath)
+			}
+		}
+	}
+	walk(req.Params.Arguments, false)
+	if len(hashes) == 0 {
+		return nil
+	}
+	return hashes
+}
+
+// hashFile returns the hex SHA-256 of the file at path, or "" if it cannot
+// be read.
+func hashFile(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
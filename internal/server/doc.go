@@ -16,41 +16,121 @@
 //   - tools/call: Execute a tool with arguments
 //   - ping: Health check
 //
+// Each tool advertises an outputSchema generated from its result type, and
+// tools/call returns the result both as structuredContent and as JSON text.
+//
 // # Available Tools
 //
-// The server provides 19 image analysis tools organized into categories:
+// The server provides 90 image analysis tools organized into categories:
 //
 // Basic Image Information:
 //   - image_load: Load image and get metadata
 //   - image_dimensions: Get width and height
+//   - image_extract_icon: Extract one size from an ICO/ICNS icon
+//   - image_thumbnail: Small cached preview of an image
+//   - image_from_clipboard: Save the clipboard image as a file (where supported)
 //
 // Region Operations:
 //   - image_crop: Extract rectangular region
 //   - image_crop_quadrant: Extract named region (top-left, center, etc.)
+//   - image_split_sprites: Split a sprite sheet into cells
+//   - image_smart_crop: Content-aware crop to an aspect ratio
 //
 // Color Operations:
 //   - image_sample_color: Get color at pixel
 //   - image_sample_colors_multi: Sample multiple points
 //   - image_dominant_colors: Extract color palette
+//   - image_check_palette: Check colors against an allowed palette
+//   - image_compare_palettes: Compare color consistency across images
+//   - image_count_colors: Count exact distinct colors against a palette limit
+//   - image_read_indicators: Classify indicator lights into states by color
 //
 // Measurement Operations:
 //   - image_measure_distance: Measure between points
 //   - image_grid_overlay: Add coordinate grid
+//   - image_georeference: Convert between pixels and latitude/longitude on maps
+//   - image_detect_scale_bar: Find a scale bar and calibrate measurements
+//   - image_count_blobs: Count particles or cells with their sizes
+//   - image_line_profile: Sample and plot intensity along a line
+//   - image_measure_angle: Measure the angle at a vertex
+//   - image_measure_polygon: Measure the perimeter and area of a polygon
+//   - image_check_parallel: Check whether two lines are parallel
+//   - image_shape_descriptors: Find the convex hull and smallest enclosing circle and rectangle
 //
 // OCR Operations:
 //   - image_ocr_full: Extract all text
 //   - image_ocr_region: Extract text from region
 //   - image_detect_text_regions: Find text bounding boxes
+//   - image_redact: Black out or pixelate boxes and OCR matches of patterns
+//   - image_detect_pii: Find personal data and secrets in OCR text
+//   - image_extract_fields: Read labeled values such as totals with OCR
+//   - image_analyze_form: List a form's fields with labels, types, and values
+//   - image_to_mermaid: Convert a flowchart to Mermaid text
+//   - image_extract_org_chart: Extract the reporting hierarchy of an org chart
+//   - image_extract_topology: Extract the devices and links of a network diagram
+//   - image_text_diff: Compare the text of two images word by word
+//   - image_read_display: Read seven-segment and dot-matrix digits
 //
 // Shape Detection:
 //   - image_detect_rectangles: Find rectangular shapes
 //   - image_detect_lines: Find line segments
 //   - image_detect_circles: Find circular shapes
 //   - image_edge_detect: Canny edge detection
+//   - image_skeletonize: Thin strokes to skeletons and trace them as a graph
+//   - image_detect_incremental: Re-detect only what changed since a previous image
+//   - image_line_intersections: Find the junctions where lines meet
 //
 // Analysis Helpers:
 //   - image_check_alignment: Check point alignment
 //   - image_compare_regions: Compare two regions
+//   - image_align: Estimate offset between two images and report residual differences
+//   - image_infer_nine_patch: Infer nine-patch stretch regions and padding
+//   - image_check_centering: Check element centering within a container
+//   - image_find_empty_regions: Find largest background-only rectangles
+//   - image_check_overlaps: Find overlapping elements with IoU and z-order hints
+//   - image_find_repeats: Find clusters of repeated elements
+//   - image_detect_periodicity: Find dominant spatial frequencies
+//   - image_detect_rows: Split lists and menus into rows or columns
+//   - image_segment_regions: Segment into regions of similar color
+//   - image_classify_content: Classify as photo, diagram, text, or screenshot
+//   - image_is_blank: Check whether an image is a single flat color
+//   - image_detect_artifacts: Detect compression blockiness, banding, and ringing
+//   - image_detect_moire: Find moiré from resampled fine patterns
+//   - image_inspect_channels: Extract bit planes and check for hidden data
+//   - image_near_duplicate: Check whether two images are near-duplicates
+//   - image_generate_report: Summarize an image in one structured report
+//   - image_accessibility_audit: Audit a UI screenshot for accessibility issues
+//   - image_denoise: Estimate noise and denoise with bilateral or non-local means filters
+//   - image_normalize: White balance and normalize exposure of photos of documents
+//   - image_scan_cleanup: Clean up photographed documents into upright binarized pages
+//   - image_clean_whiteboard: Clean up whiteboard photos and snap strokes to lines and rectangles
+//   - image_detect_formula_regions: Find regions that probably hold math formulas
+//   - image_detect_window_chrome: Find the shadow and title bar of a window screenshot
+//   - image_detect_viewport: Find the browser viewport in a desktop screenshot
+//   - image_strip_device_chrome: Remove the status and navigation bars of a mobile screenshot
+//   - image_check_specs: Validate images against dimension, format, size, and DPI specs
+//   - image_safe_area: Overlay platform safe-area guides and flag content outside them
+//   - image_detect_print_marks: Find the trim and bleed boxes of print exports and check them
+//   - image_detect_halo: Find matte halos around the opaque content of icons and sprites
+//   - image_stroke_width_audit: Check that lines and rectangle borders share a stroke width
+//   - image_compare_states: Find the elements that changed between two UI states
+//   - image_detect_loading: Detect spinners and skeleton screens of unfinished pages
+//   - image_grow_regions: Grow and measure regions from several seeds
+//   - image_detect_grid: Find the rows and columns of a board or table grid
+//   - image_read_heatmap: Read heatmap cell values from a color scale
+//   - image_read_board: Read a game board's marks and pieces as a matrix
+//   - image_read_gauge: Read an analog gauge's needle and value
+//   - image_read_clock: Read the time from an analog clock's hands
+//   - image_match_features: Find a rotated or scaled region by matching keypoints
+//   - image_find_template: Find each copy of a template image by cross-correlation
+//
+// Composition:
+//   - image_contact_sheet: Compose thumbnails into a labeled grid
+//   - image_side_by_side: Compose two images side by side with optional diff highlighting and font rendering comparison
+//
+// Visual Regression:
+//   - image_baseline_store: Store an image as a named baseline
+//   - image_baseline_compare: Compare a new capture with a stored baseline
 //
 // # Image Caching
 //
@@ -58,6 +138,20 @@
 // by path and reused across multiple tool calls, avoiding redundant disk I/O.
 // The cache persists for the lifetime of the server process.
 //
+// Tools are annotated as read-only, except image_baseline_store, and all but
+// image_detect_incremental, the baseline tools, image_from_clipboard,
+// image_detect_scale_bar, image_measure_distance, image_count_blobs,
+// image_measure_polygon, and image_check_parallel as idempotent. The
+// encoded results of recent idempotent calls are kept too, so a repeated
+// call with the same arguments is answered without re-running the
+// analysis.
+//
+// # Capture and Replay
+//
+// SetCapture records every request and response of a session, with images
+// replaced by hashes, and Replay re-runs a recorded session and reports
+// responses that differ. Together they reproduce user-reported problems.
+//
 // # Error Handling
 //
 // Tool execution errors are returned as JSON-RPC error responses with:
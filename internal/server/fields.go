@@ -0,0 +1,352 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ironsheep/image-tools-mcp/internal/imaging"
+	"github.com/ironsheep/image-tools-mcp/internal/ocr"
+)
+
+// Field extraction limits: fields per request, and labels per field.
+const (
+	maxFields      = 50
+	maxFieldLabels = 10
+)
+
+// Where a field's value was found relative to its label.
+const (
+	FieldRight = "right"
+	FieldBelow = "below"
+)
+
+// fieldTypes are the value types image_extract_fields accepts in place
+// of a regular expression.
+var fieldTypes = map[string]string{
+	// text is the words after the label up to a wide gap.
+	"text": "",
+
+	// number matches integers and decimals with thousands separators:
+	// "42", "-3.5", "1,250".
+	"number": `[-+]?\d{1,3}(?:[,.' ]\d{3})+(?:[.,]\d+)?|[-+]?\d+(?:[.,]\d+)?`,
+
+	// amount matches money: "$1,250.00", "1.250,00 €", "(12.50)",
+	// "USD 99".
+	"amount": `\(?-?\s?(?:[$€£¥₹]|[A-Z]{3}\s)?\s?(?:\d{1,3}(?:[,.' ]\d{3})+|\d+)(?:[.,]\d{1,2})?\s?(?:[$€£¥₹]|\s[A-Z]{3})?\)?`,
+
+	// date matches numeric dates ("2024-03-15", "15/03/2024") and dates
+	// with a month name ("15 Mar 2024", "March 15, 2024").
+	"date": `\d{4}[-/.]\d{1,2}[-/.]\d{1,2}|\d{1,2}[-/.]\d{1,2}[-/.]\d{2,4}|(?i:\d{1,2}\s+(?:jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?,?\s+\d{4}|(?:jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?\s+\d{1,2},?\s+\d{4})`,
+
+	// id matches codes with at least one digit: "INV-4021", "A1B2C3".
+	"id": `[A-Za-z0-9][A-Za-z0-9/_.-]*\d[A-Za-z0-9/_.-]*|\d`,
+}
+
+// fieldSeparators are the characters between a label and its value, such
+// as the colon of "Total:" and the leader dots of "Total .... 12.50".
+const fieldSeparators = " :#=.-–_*|"
+
+// FieldRequest is one field to extract: the labels that can introduce it
+// and the type of its value.
+type FieldRequest struct {
+	// Name identifies the field in the result; the first label if empty.
+	Name string `json:"name"`
+
+	// Labels are the texts that can introduce the value, such as
+	// "Total" and "Amount due", matched without case and with any
+	// spacing between their words.
+	Labels []string `json:"labels"`
+
+	// Type is "text" (the default), "number", "amount", "date", "id", or
+	// a regular expression in Go syntax the value must match.
+	Type string `json:"type"`
+}
+
+// FieldsResult is the result of image_extract_fields.
+type FieldsResult struct {
+	// Fields lists one entry per requested field, in request order,
+	// found or not.
+	Fields []ExtractedField `json:"fields"`
+
+	// Found is the number of fields with a value.
+	Found int `json:"found"`
+
+	// WordsScanned is the number of words OCR recognized. When it is
+	// zero, no text was read, so nothing could be found.
+	WordsScanned int `json:"words_scanned"`
+}
+
+// ExtractedField is a label and the value next to it.
+type ExtractedField struct {
+	// Name is the requested field's name.
+	Name string `json:"name"`
+
+	// Found reports whether a label with a value was found. The other
+	// fields are empty if not.
+	Found bool `json:"found"`
+
+	// Label is the label as read, and LabelBounds its bounding box.
+	Label       string          `json:"label,omitempty"`
+	LabelBounds *imaging.Region `json:"label_bounds,omitempty"`
+
+	// Value is the value as read, and ValueBounds the bounding box of
+	// the words holding it.
+	Value       string          `json:"value,omitempty"`
+	ValueBounds *imaging.Region `json:"value_bounds,omitempty"`
+
+	// Number is the value as a number, for the number and amount types:
+	// separators are told apart by position, so "1,250.00" and
+	// "1.250,00" are both 1250, and an amount in parentheses is
+	// negative.
+	Number *float64 `json:"number,omitempty"`
+
+	// Position is "right" if the value follows the label on its line, or
+	// "below" if it is under the label on the next line.
+	Position string `json:"position,omitempty"`
+
+	// Confidence is the lowest OCR confidence (0.0-1.0) of the words of
+	// the label and the value.
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+// fieldMatcher is a compiled FieldRequest.
+type fieldMatcher struct {
+	name   string
+	kind   string           // The value type, or "" for a regular expression
+	labels []*regexp.Regexp // Alternatives, each with its edges checked separately
+	value  *regexp.Regexp   // Nil for text values
+}
+
+// compileFields compiles field requests, checking their labels and types.
+func compileFields(fields []FieldRequest) ([]fieldMatcher, error) {
+	if len(fields) == 0 || len(fields) > maxFields {
+		return nil, fmt.Errorf("fields must have 1 to %d entries, got %d", maxFields, len(fields))
+	}
+	matchers := make([]fieldMatcher, 0, len(fields))
+	for _, f := range fields {
+		if len(f.Labels) == 0 || len(f.Labels) > maxFieldLabels {
+			return nil, fmt.Errorf("field %q: labels must have 1 to %d entries, got %d", f.Name, maxFieldLabels, len(f.Labels))
+		}
+		m := fieldMatcher{name: f.Name, kind: f.Type}
+		if m.name == "" {
+			m.name = f.Labels[0]
+		}
+		for _, label := range f.Labels {
+			words := strings.Fields(label)
+			if len(words) == 0 {
+				return nil, fmt.Errorf("field %q: empty label", m.name)
+			}
+			for i, w := range words {
+				words[i] = regexp.QuoteMeta(w)
+			}
+			m.labels = append(m.labels, regexp.MustCompile(`(?i)`+strings.Join(words, `\s*`)))
+		}
+		if m.kind == "" {
+			m.kind = "text"
+		}
+		expr, ok := fieldTypes[m.kind]
+		if !ok {
+			expr, m.kind = m.kind, ""
+		}
+		if expr != "" {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("field %q: invalid type %q: %w", m.name, f.Type, err)
+			}
+			m.value = re
+		}
+		matchers = append(matchers, m)
+	}
+	return matchers, nil
+}
+
+// labelSpan is a label found in a line's text.
+type labelSpan struct {
+	field      int
+	start, end int
+}
+
+// findLabels returns the labels of all fields in a line's text, in text
+// order. A label starting or ending with a letter or digit must not run
+// into another one there, so "Total" is not found in "Subtotal".
+func findLabels(text string, fields []fieldMatcher) []labelSpan {
+	var spans []labelSpan
+	var taken [][2]int
+	for i, f := range fields {
+		for _, re := range f.labels {
+			for _, m := range re.FindAllStringIndex(text, -1) {
+				if m[0] > 0 && wordRune(lastRune(text[:m[0]])) && wordRune(firstRune(text[m[0]:])) {
+					continue
+				}
+				if m[1] < len(text) && wordRune(lastRune(text[:m[1]])) && wordRune(firstRune(text[m[1]:])) {
+					continue
+				}
+				if !overlapsAny(m[0], m[1], taken) {
+					spans = append(spans, labelSpan{field: i, start: m[0], end: m[1]})
+					taken = append(taken, [2]int{m[0], m[1]})
+				}
+			}
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	return spans
+}
+
+// extractFields finds each field's label in OCR words and the value next
+// to it (see image_extract_fields).
+func extractFields(words []ocr.TextRegion, fields []fieldMatcher) *FieldsResult {
+	result := &FieldsResult{Fields: make([]ExtractedField, len(fields)), WordsScanned: len(words)}
+	for i, f := range fields {
+		result.Fields[i].Name = f.name
+	}
+	lines := ocrLines(words)
+	for li, line := range lines {
+		text, starts := ocrLineText(line)
+		labels := findLabels(text, fields)
+		for k, label := range labels {
+			field := &result.Fields[label.field]
+			if field.Found {
+				continue
+			}
+			labelBox, labelConf, ok := ocrSpan(line, starts, label.start, label.end)
+			if !ok {
+				continue
+			}
+
+			// To the right, up to the next label
+			stop := len(text)
+			if k+1 < len(labels) {
+				stop = labels[k+1].start
+			}
+			start := label.end
+			for start < stop && strings.ContainsRune(fieldSeparators, rune(text[start])) {
+				start++
+			}
+			f := fields[label.field]
+			value, valueBox, valueConf, position := f.findValue(line, text, starts, start, stop), imaging.Region{}, 0.0, FieldRight
+			if value.text != "" {
+				valueBox, valueConf, _ = ocrSpan(line, starts, value.start, value.end)
+			} else if li+1 < len(lines) {
+				// Under the label, on the next line if it is close
+				below := lines[li+1]
+				if gap := below[0].Bounds.Y1 - labelBox.Y2; gap <= (labelBox.Y2-labelBox.Y1)*3/2 {
+					belowText, belowStarts := ocrLineText(below)
+					for _, seg := range lineSegments(below, belowStarts) {
+						segBox, _, _ := ocrSpan(below, belowStarts, seg[0], seg[1])
+						if segBox.X1 < labelBox.X2 && labelBox.X1 < segBox.X2 {
+							value = f.findValue(below, belowText, belowStarts, seg[0], seg[1])
+							valueBox, valueConf, _ = ocrSpan(below, belowStarts, value.start, value.end)
+							position = FieldBelow
+							break
+						}
+					}
+				}
+			}
+			if value.text == "" {
+				continue
+			}
+			field.Found = true
+			field.Label = text[label.start:label.end]
+			field.LabelBounds = &labelBox
+			field.Value = value.text
+			field.ValueBounds = &valueBox
+			field.Position = position
+			field.Confidence = min(labelConf, valueConf)
+			if f.kind == "number" || f.kind == "amount" {
+				if n, ok := parseFieldNumber(value.text); ok {
+					field.Number = &n
+				}
+			}
+			result.Found++
+		}
+	}
+	return result
+}
+
+// fieldValue is a value found in a line's text, at bytes start to end.
+type fieldValue struct {
+	text       string
+	start, end int
+}
+
+// findValue returns the field's value in bytes start to stop of a line's
+// text: the first match of its type that does not run into other letters
+// or digits, or for text values, the words up to the first wide gap. The
+// value's text is empty if there is none.
+func (f fieldMatcher) findValue(line []ocr.TextRegion, text string, starts []int, start, stop int) fieldValue {
+	if start >= stop {
+		return fieldValue{}
+	}
+	if f.value == nil {
+		end := stop
+		for _, seg := range lineSegments(line, starts) {
+			if seg[1] > start {
+				end = min(end, seg[1])
+				break
+			}
+		}
+		value := strings.TrimRight(text[start:end], fieldSeparators)
+		return fieldValue{text: value, start: start, end: start + len(value)}
+	}
+	for _, m := range f.value.FindAllStringIndex(text[start:stop], -1) {
+		a, b := start+m[0], start+m[1]
+		for a < b && text[a] == ' ' {
+			a++
+		}
+		for b > a && text[b-1] == ' ' {
+			b--
+		}
+		if a < b && isolated(text, a, b) {
+			return fieldValue{text: text[a:b], start: a, end: b}
+		}
+	}
+	return fieldValue{}
+}
+
+// lineSegments splits a line's text (see ocrLineText) at gaps between
+// words wider than 1.5 times the line's height, as between a label and a
+// value in another column, and returns the byte range of each segment.
+func lineSegments(line []ocr.TextRegion, starts []int) [][2]int {
+	height := 0
+	for _, w := range line {
+		height = max(height, w.Bounds.Y2-w.Bounds.Y1)
+	}
+	var segs [][2]int
+	segStart := 0
+	for i := 1; i < len(line); i++ {
+		if 2*(line[i].Bounds.X1-line[i-1].Bounds.X2) > 3*height {
+			segs = append(segs, [2]int{segStart, starts[i-1] + len(line[i-1].Text)})
+			segStart = starts[i]
+		}
+	}
+	if len(line) > 0 {
+		segs = append(segs, [2]int{segStart, starts[len(line)-1] + len(line[len(line)-1].Text)})
+	}
+	return segs
+}
+
+// parseFieldNumber parses a number or amount as read, guessing its
+// decimal separator (see ocr.ParseNumber).
+func parseFieldNumber(s string) (float64, bool) {
+	return ocr.ParseNumber(s, ocr.Locale{})
+}
+
+// wordRune reports whether r is a letter or digit.
+func wordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// firstRune and lastRune return the first and last rune of s.
+func firstRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
+
+func lastRune(s string) rune {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/ironsheep/image-tools-mcp/internal/imaging"
+	"github.com/ironsheep/image-tools-mcp/internal/ocr"
+)
+
+func TestExtractFields(t *testing.T) {
+	words := []ocr.TextRegion{
+		word("Invoice", 0, 0), word("#:", 75, 0), word("INV-4021", 100, 0), word("Date:", 260, 0), word("2024-03-15", 320, 0),
+		word("Subtotal", 0, 20), word("12.50", 300, 20),
+		word("Total", 0, 40), word("........", 55, 40), word("$1,250.00", 300, 40),
+		word("Ship", 0, 60), word("to:", 45, 60),
+		word("Jane", 0, 78), word("Doe", 50, 78),
+		word("Balance", 0, 100), word("(1.250,00)", 100, 100),
+		word("Cashier:", 0, 120), word("Sam", 90, 120), word("Lee", 130, 120), word("Store", 300, 120), word("12", 360, 120),
+	}
+	fields, err := compileFields([]FieldRequest{
+		{Name: "invoice", Labels: []string{"Invoice No", "Invoice #"}, Type: "id"},
+		{Labels: []string{"Date"}, Type: "date"},
+		{Name: "total", Labels: []string{"Total"}, Type: "amount"},
+		{Labels: []string{"Subtotal"}, Type: "number"},
+		{Name: "ship_to", Labels: []string{"Ship to"}},
+		{Name: "balance", Labels: []string{"Balance"}, Type: "amount"},
+		{Name: "cashier", Labels: []string{"cashier"}},
+		{Name: "tip", Labels: []string{"Tip"}, Type: "amount"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := extractFields(words, fields)
+	if got.Found != 7 || got.WordsScanned != len(words) || len(got.Fields) != 8 {
+		t.Fatalf("got found=%d words=%d fields=%d", got.Found, got.WordsScanned, len(got.Fields))
+	}
+
+	want := []struct {
+		name, label, value, position string
+		labelBounds, valueBounds     imaging.Region
+		number                       float64
+	}{
+		{"invoice", "Invoice #", "INV-4021", FieldRight, imaging.Region{X1: 0, Y1: 0, X2: 95, Y2: 14}, imaging.Region{X1: 100, Y1: 0, X2: 180, Y2: 14}, 0},
+		{"Date", "Date", "2024-03-15", FieldRight, imaging.Region{X1: 260, Y1: 0, X2: 310, Y2: 14}, imaging.Region{X1: 320, Y1: 0, X2: 420, Y2: 14}, 0},
+		{"total", "Total", "$1,250.00", FieldRight, imaging.Region{X1: 0, Y1: 40, X2: 50, Y2: 54}, imaging.Region{X1: 300, Y1: 40, X2: 390, Y2: 54}, 1250},
+		{"Subtotal", "Subtotal", "12.50", FieldRight, imaging.Region{X1: 0, Y1: 20, X2: 80, Y2: 34}, imaging.Region{X1: 300, Y1: 20, X2: 350, Y2: 34}, 12.5},
+		{"ship_to", "Ship to", "Jane Doe", FieldBelow, imaging.Region{X1: 0, Y1: 60, X2: 75, Y2: 74}, imaging.Region{X1: 0, Y1: 78, X2: 80, Y2: 92}, 0},
+		{"balance", "Balance", "(1.250,00)", FieldRight, imaging.Region{X1: 0, Y1: 100, X2: 70, Y2: 114}, imaging.Region{X1: 100, Y1: 100, X2: 200, Y2: 114}, -1250},
+		{"cashier", "Cashier", "Sam Lee", FieldRight, imaging.Region{X1: 0, Y1: 120, X2: 80, Y2: 134}, imaging.Region{X1: 90, Y1: 120, X2: 160, Y2: 134}, 0},
+	}
+	for i, w := range want {
+		f := got.Fields[i]
+		if !f.Found || f.Name != w.name || f.Label != w.label || f.Value != w.value || f.Position != w.position || f.Confidence != 0.9 {
+			t.Errorf("field %d: got %+v", i, f)
+			continue
+		}
+		if *f.LabelBounds != w.labelBounds || *f.ValueBounds != w.valueBounds {
+			t.Errorf("%s: got label %+v value %+v, want %+v %+v", w.name, *f.LabelBounds, *f.ValueBounds, w.labelBounds, w.valueBounds)
+		}
+		if (f.Number != nil) != (w.number != 0) || (f.Number != nil && *f.Number != w.number) {
+			t.Errorf("%s: got number %v, want %v", w.name, f.Number, w.number)
+		}
+	}
+	if tip := got.Fields[7]; tip.Name != "tip" || tip.Found || tip.ValueBounds != nil {
+		t.Errorf("tip: got %+v, want not found", tip)
+	}
+}
+
+func TestParseFieldNumber(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"42", 42},
+		{"-3.5", -3.5},
+		{"1,250", 1250},
+		{"1,250,000", 1250000},
+		{"$1,250.00", 1250},
+		{"1.250,00 €", 1250},
+		{"12,50", 12.5},
+		{"3.14159", 3.14159},
+		{"(12.50)", -12.5},
+		{"USD 99", 99},
+	}
+	for _, tt := range tests {
+		if got, ok := parseFieldNumber(tt.in); !ok || got != tt.want {
+			t.Errorf("parseFieldNumber(%q) = %v, %v; want %v", tt.in, got, ok, tt.want)
+		}
+	}
+	if _, ok := parseFieldNumber("$"); ok {
+		t.Error("parseFieldNumber(\"$\") should fail")
+	}
+}
+
+func TestCompileFields_Errors(t *testing.T) {
+	for _, fields := range [][]FieldRequest{
+		nil,
+		{{Name: "total"}},
+		{{Labels: []string{" "}}},
+		{{Labels: []string{"Total"}, Type: "("}},
+	} {
+		if _, err := compileFields(fields); err == nil {
+			t.Errorf("compileFields(%+v) should fail", fields)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"image"
+
+	"github.com/ironsheep/image-tools-mcp/internal/detection"
+	"github.com/ironsheep/image-tools-mcp/internal/imaging"
+)
+
+// fontDiffRegions returns the text regions to compare for the font_diff
+// mode of image_side_by_side, relative to the images' top-left corners:
+// those of first, and those of second overlapping none of them, so text
+// only one image has is compared too. Regions outside the area both
+// images cover are left out.
+func fontDiffRegions(first, second image.Image) ([]imaging.Region, error) {
+	fb, sb := first.Bounds(), second.Bounds()
+	overlap := image.Rect(0, 0, min(fb.Dx(), sb.Dx()), min(fb.Dy(), sb.Dy()))
+	var regions []image.Rectangle
+	for i, img := range []image.Image{first, second} {
+		text, err := detection.DetectTextRegions(img, 0.5)
+		if err != nil {
+			return nil, err
+		}
+		origin := img.Bounds().Min
+		found := len(regions)
+		for _, t := range text.Regions {
+			r := image.Rect(t.Bounds.X1, t.Bounds.Y1, t.Bounds.X2, t.Bounds.Y2).Sub(origin).Intersect(overlap)
+			if r.Empty() {
+				continue
+			}
+			seen := false
+			for _, prev := range regions[:found] {
+				if i > 0 && prev.Overlaps(r) {
+					seen = true
+					break
+				}
+			}
+			if !seen {
+				regions = append(regions, r)
+			}
+		}
+	}
+	result := make([]imaging.Region, len(regions))
+	for i, r := range regions {
+		result[i] = imaging.Region{X1: r.Min.X, Y1: r.Min.Y, X2: r.Max.X, Y2: r.Max.Y}
+	}
+	return result, nil
+}
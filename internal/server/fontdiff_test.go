@@ -0,0 +1,62 @@
+package server
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/ironsheep/image-tools-mcp/internal/imaging"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// paragraph is three lines of 13-pixel text, dense enough for text
+// region detection.
+var paragraph = []string{"The quick brown fox jumps over", "the lazy dog. Pack my box with", "five dozen liquor jugs, quickly."}
+
+// fontScene draws a 300×200 white image with the paragraph at the top,
+// and again 100 pixels lower if second is set.
+func fontScene(second bool) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 300, 200))
+	fill(img, 0, 0, 300, 200, color.RGBA{255, 255, 255, 255})
+	d := &font.Drawer{Dst: img, Src: image.NewUniform(color.RGBA{0, 0, 0, 255}), Face: basicfont.Face7x13}
+	for i, line := range paragraph {
+		d.Dot = fixed.P(20, 30+i*18)
+		d.DrawString(line)
+		if second {
+			d.Dot = fixed.P(20, 130+i*18)
+			d.DrawString(line)
+		}
+	}
+	return img
+}
+
+func TestFontDiffRegions(t *testing.T) {
+	first, second := fontScene(false), fontScene(true)
+	regions, err := fontDiffRegions(first, second)
+	if err != nil {
+		t.Fatalf("fontDiffRegions failed: %v", err)
+	}
+	if len(regions) < 2 {
+		t.Fatalf("got regions %+v, want the shared paragraph and the second image's own", regions)
+	}
+	result, err := imaging.TextRenderingDiff(first, second, regions)
+	if err != nil {
+		t.Fatalf("TextRenderingDiff failed: %v", err)
+	}
+	if result.Changes[imaging.TextChangeNone] == 0 || result.Changes[imaging.TextChangeGlyphs] == 0 {
+		t.Errorf("want the shared paragraph unchanged and the new one as glyphs, got %v", result.Changes)
+	}
+
+	// Text beyond the smaller image is not compared
+	regions, err = fontDiffRegions(second, fontScene(true).SubImage(image.Rect(0, 0, 300, 100)))
+	if err != nil {
+		t.Fatalf("fontDiffRegions failed: %v", err)
+	}
+	for _, r := range regions {
+		if r.Y2 > 100 {
+			t.Errorf("region %+v extends beyond the 100-pixel overlap", r)
+		}
+	}
+}
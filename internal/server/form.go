@@ -0,0 +1,410 @@
+package server
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/ironsheep/image-tools-mcp/internal/detection"
+	"github.com/ironsheep/image-tools-mcp/internal/ocr"
+)
+
+// Form field types (see FormField).
+const (
+	FormText     = "text"
+	FormTextarea = "textarea"
+	FormCheckbox = "checkbox"
+	FormDropdown = "dropdown"
+	FormButton   = "button"
+)
+
+// Thresholds of the form analysis, in text heights (the median height of
+// the OCR words) unless noted.
+const (
+	// formMinBoxSide is the smallest side, in pixels, of an input box.
+	formMinBoxSide = 8
+
+	// formCheckboxSide is the largest side of a checkbox.
+	formCheckboxSide = 2.0
+
+	// formTextareaHeight is the smallest height of a multi-line box.
+	formTextareaHeight = 2.5
+
+	// formLabelGap, formLabelAbove, and formLabelRight are the widest
+	// gaps between a box and a label to its left, above it, and (for
+	// checkboxes) to its right.
+	formLabelGap   = 10.0
+	formLabelAbove = 2.0
+	formLabelRight = 3.0
+
+	// formInkShare is the share of a checkbox's inside, or of the right
+	// end of a box, that must be ink for a check mark or a dropdown
+	// arrow.
+	formInkShare = 0.08
+
+	// formFillContrast is the smallest difference in gray level between
+	// a box's inside and its surroundings for the box to count as filled.
+	formFillContrast = 24
+
+	// formDefaultTextHeight is the text height, in pixels, used when OCR
+	// read no words.
+	formDefaultTextHeight = 16
+)
+
+// FormResult is the result of image_analyze_form: the input fields of a
+// form screenshot, each with its label and current value.
+type FormResult struct {
+	// Fields lists the fields in reading order: top to bottom, and left
+	// to right within a row.
+	Fields []FormField `json:"fields"`
+
+	// Count is the number of fields.
+	Count int `json:"count"`
+
+	// Labeled is the number of fields with a label.
+	Labeled int `json:"labeled"`
+
+	// WordsScanned is the number of words OCR recognized. When it is
+	// zero, no text was read, so no labels or values could be found.
+	WordsScanned int `json:"words_scanned"`
+}
+
+// FormField is one input of a form: a detected box, its type, and the
+// text next to it and in it.
+type FormField struct {
+	// Type is the guessed kind of input: "text", "textarea", "checkbox",
+	// "dropdown", or "button".
+	Type string `json:"type"`
+
+	// Bounds is the input's box.
+	Bounds detection.Bounds `json:"bounds"`
+
+	// Label is the text naming the field, without a trailing colon or
+	// required marker; a button's caption. Empty if no text was found
+	// near the box.
+	Label string `json:"label,omitempty"`
+
+	// LabelBounds is the bounding box of the label's words.
+	LabelBounds *detection.Bounds `json:"label_bounds,omitempty"`
+
+	// LabelPosition is where the label is relative to the box: "left",
+	// "above", "right", or "inside" for a button.
+	LabelPosition string `json:"label_position,omitempty"`
+
+	// Required is true if the label is marked with an asterisk.
+	Required bool `json:"required,omitempty"`
+
+	// Value is the text inside the box, such as what was typed in it or
+	// the option a dropdown shows. Lines of a textarea are separated by
+	// newlines. Placeholder text cannot be told apart from a value.
+	Value string `json:"value,omitempty"`
+
+	// Checked is whether a checkbox holds a mark.
+	Checked *bool `json:"checked,omitempty"`
+
+	// Confidence is the lowest OCR confidence (0.0-1.0) of the words of
+	// the label and the value.
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+// formPhrase is a run of words on one line outside every box, a
+// candidate label.
+type formPhrase struct {
+	text       string
+	bounds     detection.Bounds
+	confidence float64
+}
+
+// analyzeForm finds the input fields of a form in img, with the OCR words
+// read from it (see image_analyze_form).
+//
+// Input boxes are the rectangles of rects that are at least
+// formMinBoxSide on each side, not inside an OCR word (glyphs) or another
+// box (the inner edge of a border), and not holding two or more other
+// boxes (panels and the form's frame). Each box's type is guessed from
+// its shape and contents, and it is paired with the nearest phrase
+// outside every box, to its left or above it, or to its right for a
+// checkbox; nearer pairs are made first, so a phrase labels one box.
+func analyzeForm(img image.Image, rects []detection.Rectangle, words []ocr.TextRegion) *FormResult {
+	result := &FormResult{Fields: []FormField{}, WordsScanned: len(words)}
+	// Word boxes grown by 2 pixels, as edges are found just outside ink
+	glyphs := wordBoundsOf(words)
+	heights := make([]int, len(words))
+	for i, b := range glyphs {
+		heights[i] = b.Y2 - b.Y1
+		glyphs[i] = detection.Bounds{X1: b.X1 - 2, Y1: b.Y1 - 2, X2: b.X2 + 2, Y2: b.Y2 + 2}
+	}
+	th := float64(formDefaultTextHeight)
+	if len(heights) > 0 {
+		sort.Ints(heights)
+		th = float64(heights[len(heights)/2])
+	}
+
+	// Boxes, largest first as rects are sorted
+	var candidates []detection.Bounds
+	for _, r := range rects {
+		if r.Width >= formMinBoxSide && r.Height >= formMinBoxSide && !containedIn(r.Bounds, glyphs) {
+			candidates = append(candidates, r.Bounds)
+		}
+	}
+	var boxes []detection.Bounds
+	for i, b := range candidates {
+		inside := 0
+		for j, o := range candidates {
+			if j != i && containedIn(o, []detection.Bounds{b}) && !sameBox(o, b) {
+				inside++
+			}
+		}
+		if inside < 2 && !containedIn(b, boxes) {
+			boxes = append(boxes, b)
+		}
+	}
+
+	// Words inside a box are its value; the others are candidate labels
+	inBox := make([]int, len(words))
+	for i, w := range words {
+		inBox[i] = -1
+		cx, cy := (w.Bounds.X1+w.Bounds.X2)/2, (w.Bounds.Y1+w.Bounds.Y2)/2
+		for j, b := range boxes {
+			if cx >= b.X1 && cx < b.X2 && cy >= b.Y1 && cy < b.Y2 {
+				inBox[i] = j
+				break
+			}
+		}
+	}
+	var outside []ocr.TextRegion
+	contents := make([][]ocr.TextRegion, len(boxes))
+	for i, w := range words {
+		if inBox[i] < 0 {
+			outside = append(outside, w)
+		} else {
+			contents[inBox[i]] = append(contents[inBox[i]], w)
+		}
+	}
+
+	for i, b := range boxes {
+		field := FormField{Type: FormText, Bounds: b}
+		value, valueBounds, confidence := formValue(contents[i], b.Y2-b.Y1 >= int(formTextareaHeight*th))
+		field.Value, field.Confidence = value, confidence
+		w, h := float64(b.X2-b.X1), float64(b.Y2-b.Y1)
+		switch {
+		case w <= formCheckboxSide*th && h <= formCheckboxSide*th && 4*min(w, h) >= 3*max(w, h) && value == "":
+			field.Type = FormCheckbox
+			inset := max(4, int(min(w, h))/5)
+			checked := inkShare(img, detection.Bounds{X1: b.X1 + inset, Y1: b.Y1 + inset, X2: b.X2 - inset, Y2: b.Y2 - inset}) >= formInkShare
+			field.Checked = &checked
+		case value != "" && (filledBox(img, b) || centeredIn(valueBounds, b)):
+			field.Type = FormButton
+			field.Label, field.LabelBounds, field.LabelPosition = value, &valueBounds, "inside"
+			field.Value = ""
+		case h >= formTextareaHeight*th:
+			field.Type = FormTextarea
+		default:
+			arrow := detection.Bounds{X1: b.X2 - (b.Y2 - b.Y1), Y1: b.Y1 + 3, X2: b.X2 - 3, Y2: b.Y2 - 3}
+			if arrow.X1 > b.X1 && !overlapsAnyBounds(arrow, wordBoundsOf(contents[i])) && inkShare(img, arrow) >= formInkShare {
+				field.Type = FormDropdown
+			}
+		}
+		result.Fields = append(result.Fields, field)
+	}
+
+	// Labels, nearest pairs first
+	phrases := formPhrases(outside)
+	type pair struct {
+		field, phrase int
+		position      string
+		distance      float64
+	}
+	var pairs []pair
+	for i, f := range result.Fields {
+		if f.Type == FormButton {
+			continue
+		}
+		b := f.Bounds
+		for j, p := range phrases {
+			l := p.bounds
+			midY := float64(l.Y1+l.Y2) / 2
+			switch {
+			case f.Type == FormCheckbox && float64(l.X1) >= float64(b.X2)-th/2 && math.Abs(midY-float64(b.Y1+b.Y2)/2) <= th &&
+				float64(l.X1-b.X2) <= formLabelRight*th:
+				pairs = append(pairs, pair{i, j, "right", float64(l.X1 - b.X2)})
+			case float64(l.X2) <= float64(b.X1)+th/2 && midY >= float64(b.Y1) && midY <= float64(b.Y2) &&
+				float64(b.X1-l.X2) <= formLabelGap*th:
+				d := float64(b.X1 - l.X2)
+				if f.Type == FormCheckbox {
+					d *= 2
+				}
+				pairs = append(pairs, pair{i, j, "left", d})
+			case float64(l.Y2) <= float64(b.Y1)+th/2 && float64(l.X1) >= float64(b.X1)-th && l.X1 < b.X2 &&
+				float64(b.Y1-l.Y2) <= formLabelAbove*th:
+				pairs = append(pairs, pair{i, j, "above", float64(b.Y1 - l.Y2)})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].distance < pairs[j].distance })
+	used := make([]bool, len(phrases))
+	for _, p := range pairs {
+		f := &result.Fields[p.field]
+		if f.Label != "" || used[p.phrase] {
+			continue
+		}
+		used[p.phrase] = true
+		phrase := phrases[p.phrase]
+		f.Label, f.Required = formLabel(phrase.text)
+		f.LabelBounds, f.LabelPosition = &phrase.bounds, p.position
+		if f.Confidence == 0 || phrase.confidence < f.Confidence {
+			f.Confidence = phrase.confidence
+		}
+	}
+
+	sort.SliceStable(result.Fields, func(i, j int) bool {
+		a, b := result.Fields[i].Bounds, result.Fields[j].Bounds
+		if overlap := min(a.Y2, b.Y2) - max(a.Y1, b.Y1); 2*overlap >= min(a.Y2-a.Y1, b.Y2-b.Y1) {
+			return a.X1 < b.X1
+		}
+		return a.Y1 < b.Y1
+	})
+	result.Count = len(result.Fields)
+	for _, f := range result.Fields {
+		if f.Label != "" {
+			result.Labeled++
+		}
+	}
+	return result
+}
+
+// formValue returns the text of the words inside a box, its bounding box,
+// and its lowest OCR confidence. Lines are joined with newlines if
+// multiline, otherwise with spaces.
+func formValue(words []ocr.TextRegion, multiline bool) (string, detection.Bounds, float64) {
+	if len(words) == 0 {
+		return "", detection.Bounds{}, 0
+	}
+	var lines []string
+	var bounds detection.Bounds
+	confidence := 1.0
+	for i, line := range ocrLines(words) {
+		text, starts := ocrLineText(line)
+		box, conf, _ := ocrSpan(line, starts, 0, len(text))
+		lines = append(lines, text)
+		if i == 0 {
+			bounds = regionBounds(box)
+		}
+		bounds = detection.Bounds{X1: min(bounds.X1, box.X1), Y1: min(bounds.Y1, box.Y1), X2: max(bounds.X2, box.X2), Y2: max(bounds.Y2, box.Y2)}
+		confidence = min(confidence, conf)
+	}
+	sep := " "
+	if multiline {
+		sep = "\n"
+	}
+	return strings.Join(lines, sep), bounds, confidence
+}
+
+// formPhrases splits words into lines, and lines at wide gaps (see
+// lineSegments), into candidate labels.
+func formPhrases(words []ocr.TextRegion) []formPhrase {
+	var phrases []formPhrase
+	for _, line := range ocrLines(words) {
+		text, starts := ocrLineText(line)
+		for _, seg := range lineSegments(line, starts) {
+			box, conf, ok := ocrSpan(line, starts, seg[0], seg[1])
+			if ok {
+				phrases = append(phrases, formPhrase{text: text[seg[0]:seg[1]], bounds: regionBounds(box), confidence: conf})
+			}
+		}
+	}
+	return phrases
+}
+
+// formLabel strips a label's trailing colon and its required marker, an
+// asterisk before or after it, reporting whether there was one.
+func formLabel(text string) (string, bool) {
+	label := strings.TrimRight(strings.TrimSpace(text), ":")
+	required := strings.HasPrefix(label, "*") || strings.HasSuffix(label, "*")
+	label = strings.TrimRight(strings.Trim(label, "* "), ":")
+	return strings.TrimSpace(label), required
+}
+
+// sameBox reports whether o is b or the inner edge of b's border: inside
+// b by at most 4 pixels on every side.
+func sameBox(o, b detection.Bounds) bool {
+	return o.X1-b.X1 <= 4 && o.Y1-b.Y1 <= 4 && b.X2-o.X2 <= 4 && b.Y2-o.Y2 <= 4
+}
+
+// centeredIn reports whether text is centered horizontally in b, well in
+// from its left edge, as a button's caption is.
+func centeredIn(text, b detection.Bounds) bool {
+	w := b.X2 - b.X1
+	return 10*math.Abs(float64(text.X1+text.X2-b.X1-b.X2)/2) <= float64(w) && 5*(text.X1-b.X1) >= w
+}
+
+// filledBox reports whether the inside of b differs in gray level from the
+// pixels just outside it by formFillContrast or more.
+func filledBox(img image.Image, b detection.Bounds) bool {
+	inner := grayLevels(img, detection.Bounds{X1: b.X1 + 3, Y1: b.Y1 + 3, X2: b.X2 - 3, Y2: b.Y2 - 3}, detection.Bounds{})
+	outer := grayLevels(img, detection.Bounds{X1: b.X1 - 3, Y1: b.Y1 - 3, X2: b.X2 + 3, Y2: b.Y2 + 3}, b)
+	if len(inner) == 0 || len(outer) == 0 {
+		return false
+	}
+	return math.Abs(float64(median(inner))-float64(median(outer))) >= formFillContrast
+}
+
+// inkShare returns the share of the pixels in b that differ in gray level
+// from b's median by more than 64.
+func inkShare(img image.Image, b detection.Bounds) float64 {
+	levels := grayLevels(img, b, detection.Bounds{})
+	if len(levels) == 0 {
+		return 0
+	}
+	m := int(median(levels))
+	ink := 0
+	for _, l := range levels {
+		if d := int(l) - m; d > 64 || d < -64 {
+			ink++
+		}
+	}
+	return float64(ink) / float64(len(levels))
+}
+
+// grayLevels returns the gray levels of the pixels of img in b, clipped
+// to the image, except those in hole.
+func grayLevels(img image.Image, b, hole detection.Bounds) []uint8 {
+	r := image.Rect(b.X1, b.Y1, b.X2, b.Y2).Intersect(img.Bounds())
+	var levels []uint8
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if x >= hole.X1 && x < hole.X2 && y >= hole.Y1 && y < hole.Y2 {
+				continue
+			}
+			levels = append(levels, color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y)
+		}
+	}
+	return levels
+}
+
+// median returns the median of levels, which it sorts.
+func median(levels []uint8) uint8 {
+	sort.Slice(levels, func(i, j int) bool { return levels[i] < levels[j] })
+	return levels[len(levels)/2]
+}
+
+// wordBoundsOf returns the bounding boxes of OCR words.
+func wordBoundsOf(words []ocr.TextRegion) []detection.Bounds {
+	bounds := make([]detection.Bounds, len(words))
+	for i, w := range words {
+		bounds[i] = detection.Bounds{X1: w.Bounds.X1, Y1: w.Bounds.Y1, X2: w.Bounds.X2, Y2: w.Bounds.Y2}
+	}
+	return bounds
+}
+
+// overlapsAnyBounds reports whether b overlaps any of boxes.
+func overlapsAnyBounds(b detection.Bounds, boxes []detection.Bounds) bool {
+	for _, o := range boxes {
+		if b.X1 < o.X2 && o.X1 < b.X2 && b.Y1 < o.Y2 && o.Y1 < b.Y2 {
+			return true
+		}
+	}
+	return false
+}
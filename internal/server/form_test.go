@@ -0,0 +1,119 @@
+package server
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/ironsheep/image-tools-mcp/internal/detection"
+	"github.com/ironsheep/image-tools-mcp/internal/ocr"
+)
+
+// formPage draws a form on white: outlined boxes, solid blocks where the
+// words are, a check mark, a dropdown arrow, and a filled button. It
+// returns the image and the OCR words for it.
+func formPage() (*image.RGBA, []ocr.TextRegion) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 350))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+	fill := func(x1, y1, x2, y2 int, c color.Color) {
+		draw.Draw(img, image.Rect(x1, y1, x2, y2), &image.Uniform{c}, image.Point{}, draw.Src)
+	}
+	outline := func(x1, y1, x2, y2 int) {
+		dark := color.Gray{Y: 60}
+		fill(x1, y1, x2, y1+2, dark)
+		fill(x1, y2-2, x2, y2, dark)
+		fill(x1, y1, x1+2, y2, dark)
+		fill(x2-2, y1, x2, y2, dark)
+	}
+
+	outline(100, 20, 300, 44) // Name, with a value
+	outline(20, 80, 300, 104) // Email, labeled above
+	outline(20, 130, 36, 146) // Subscribe, checked
+	fill(25, 135, 31, 141, color.Black)
+	outline(20, 160, 36, 176)   // Terms, unchecked
+	outline(100, 192, 300, 216) // Country dropdown
+	fill(282, 200, 292, 208, color.Black)
+	fill(100, 226, 180, 252, color.Gray{Y: 200}) // Submit button
+	outline(100, 266, 300, 330)                  // Notes textarea
+
+	var words []ocr.TextRegion
+	word := func(text string, x1, y1, x2, y2 int) {
+		fill(x1, y1, x2, y2, color.Black)
+		words = append(words, ocr.TextRegion{Text: text, Confidence: 0.9, Bounds: ocr.Bounds{X1: x1, Y1: y1, X2: x2, Y2: y2}})
+	}
+	word("Name:", 20, 24, 70, 38)
+	word("Jane", 106, 26, 146, 40)
+	word("Email", 20, 60, 70, 74)
+	word("Subscribe", 44, 131, 134, 145)
+	word("Accept", 44, 161, 104, 175)
+	word("terms*", 110, 161, 170, 175)
+	word("Country", 20, 196, 90, 210)
+	word("Canada", 106, 197, 166, 211)
+	word("Submit", 116, 232, 166, 246)
+	word("Notes", 20, 270, 70, 284)
+	return img, words
+}
+
+func TestAnalyzeForm(t *testing.T) {
+	img, words := formPage()
+	rects, err := detection.DetectRectangles(img, 64, 0.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := analyzeForm(img, rects.Rectangles, words)
+
+	checked, unchecked := true, false
+	want := []FormField{
+		{Type: FormText, Bounds: detection.Bounds{X1: 100, Y1: 20, X2: 300, Y2: 44}, Label: "Name", LabelPosition: "left", Value: "Jane"},
+		{Type: FormText, Bounds: detection.Bounds{X1: 20, Y1: 80, X2: 300, Y2: 104}, Label: "Email", LabelPosition: "above"},
+		{Type: FormCheckbox, Bounds: detection.Bounds{X1: 20, Y1: 130, X2: 36, Y2: 146}, Label: "Subscribe", LabelPosition: "right", Checked: &checked},
+		{Type: FormCheckbox, Bounds: detection.Bounds{X1: 20, Y1: 160, X2: 36, Y2: 176}, Label: "Accept terms", LabelPosition: "right", Required: true, Checked: &unchecked},
+		{Type: FormDropdown, Bounds: detection.Bounds{X1: 100, Y1: 192, X2: 300, Y2: 216}, Label: "Country", LabelPosition: "left", Value: "Canada"},
+		{Type: FormButton, Bounds: detection.Bounds{X1: 100, Y1: 226, X2: 180, Y2: 252}, Label: "Submit", LabelPosition: "inside"},
+		{Type: FormTextarea, Bounds: detection.Bounds{X1: 100, Y1: 266, X2: 300, Y2: 330}, Label: "Notes", LabelPosition: "left"},
+	}
+	if got.Count != len(want) || len(got.Fields) != len(want) || got.Labeled != len(want) || got.WordsScanned != len(words) {
+		t.Fatalf("got count=%d labeled=%d words=%d: %+v", got.Count, got.Labeled, got.WordsScanned, got.Fields)
+	}
+	for i, w := range want {
+		f := got.Fields[i]
+		if f.Type != w.Type || !nearBounds(f.Bounds, w.Bounds) || f.Label != w.Label || f.LabelPosition != w.LabelPosition ||
+			f.Required != w.Required || f.Value != w.Value || f.Confidence != 0.9 || (f.Checked == nil) != (w.Checked == nil) ||
+			(f.Checked != nil && *f.Checked != *w.Checked) || f.LabelBounds == nil {
+			t.Errorf("field %d: got %+v (checked %v), want %+v", i, f, f.Checked, w)
+		}
+	}
+
+	// Without OCR words, the boxes are still found, unlabeled
+	bare := analyzeForm(img, rects.Rectangles, nil)
+	if bare.Count == 0 || bare.Labeled != 0 || bare.WordsScanned != 0 {
+		t.Errorf("without words: got count=%d labeled=%d", bare.Count, bare.Labeled)
+	}
+}
+
+func TestFormLabel(t *testing.T) {
+	tests := []struct {
+		in       string
+		want     string
+		required bool
+	}{
+		{"Name:", "Name", false},
+		{"Email*:", "Email", true},
+		{"* Phone", "Phone", true},
+		{"Last name *", "Last name", true},
+		{"Notes", "Notes", false},
+	}
+	for _, tt := range tests {
+		if got, required := formLabel(tt.in); got != tt.want || required != tt.required {
+			t.Errorf("formLabel(%q) = %q, %v; want %q, %v", tt.in, got, required, tt.want, tt.required)
+		}
+	}
+}
+
+// nearBounds reports whether a and b differ by at most a pixel per edge,
+// as edge detection may place a box's outline.
+func nearBounds(a, b detection.Bounds) bool {
+	d := func(x, y int) bool { return x-y <= 1 && y-x <= 1 }
+	return d(a.X1, b.X1) && d(a.Y1, b.Y1) && d(a.X2, b.X2) && d(a.Y2, b.Y2)
+}
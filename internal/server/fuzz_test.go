@@ -0,0 +1,246 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fuzzImagePlaceholder in a fuzz input is replaced with the path of a small
+// test image, so seeds reach the image handlers on any machine.
+const fuzzImagePlaceholder = "@img"
+
+// writeFuzzImage writes a small diagram (a filled box and a line) to dir
+// and returns its path.
+func writeFuzzImage(tb testing.TB, dir string) string {
+	tb.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			c := color.RGBA{255, 255, 255, 255}
+			if x >= 8 && x < 30 && y >= 8 && y < 24 {
+				c = color.RGBA{40, 90, 200, 255}
+			} else if y == 36 && x >= 4 && x < 60 {
+				c = color.RGBA{0, 0, 0, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	path := filepath.Join(dir, "fuzz.png")
+	out, err := os.Create(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer out.Close()
+	if err := png.Encode(out, img); err != nil {
+		tb.Fatal(err)
+	}
+	return path
+}
+
+// fuzzPathsAllowed reports whether every string in the JSON document data
+// that contains a slash lies inside dir. Fuzzed arguments are otherwise
+// free to name any file, including devices that block on read.
+func fuzzPathsAllowed(data []byte, dir string) bool {
+	var doc interface{}
+	if json.Unmarshal(data, &doc) != nil {
+		return true // rejected before any path is used
+	}
+	var walk func(v interface{}) bool
+	walk = func(v interface{}) bool {
+		switch v := v.(type) {
+		case string:
+			return !strings.Contains(v, "/") || strings.HasPrefix(v, dir+string(filepath.Separator))
+		case []interface{}:
+			for _, e := range v {
+				if !walk(e) {
+					return false
+				}
+			}
+		case map[string]interface{}:
+			for k, e := range v {
+				if !walk(k) || !walk(e) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return walk(doc)
+}
+
+// fuzzToolArguments are seed arguments for tools that need more than a
+// path; every tool is also seeded with the path alone.
+var fuzzToolArguments = map[string]string{
+	"image_thumbnail":              `{"path":"@img","max_size":32,"format":"jpeg"}`,
+	"image_crop":                   `{"path":"@img","x1":4,"y1":4,"x2":40,"y2":30,"scale":2}`,
+	"image_crop_quadrant":          `{"path":"@img","region":"bottom-right","scale":0.5}`,
+	"image_split_sprites":          `{"path":"@img","columns":4,"rows":2,"include_thumbnails":true}`,
+	"image_smart_crop":             `{"path":"@img","aspect_ratio":1.5,"method":"seam","max_size":32}`,
+	"image_sample_color":           `{"path":"@img","x":10,"y":10}`,
+	"image_sample_colors_multi":    `{"path":"@img","points":[{"x":1,"y":2,"label":"a"},{"x":63,"y":47}]}`,
+	"image_dominant_colors":        `{"path":"@img","count":3,"region":{"x1":0,"y1":0,"x2":32,"y2":24},"mask":{"polygon":[{"x":4,"y":4},{"x":30,"y":4},{"x":16,"y":22}]}}`,
+	"image_check_palette":          `{"path":"@img","palette":["#FFFFFF","#285AC8"],"tolerance":2,"ignore_edges":false,"points":[{"x":10,"y":10}]}`,
+	"image_compare_palettes":       `{"images":[{"path":"@img","label":"a"},{"path":"@img","region":{"x1":0,"y1":0,"x2":32,"y2":24}}],"count":4,"threshold":5}`,
+	"image_count_colors":           `{"path":"@img","region":{"x1":0,"y1":0,"x2":32,"y2":24},"limit":8,"max_colors":16,"ignore_transparent":true,"near_distance":4}`,
+	"image_read_indicators":        `{"path":"@img","indicators":[{"name":"a","x":10,"y":10,"radius":3},{"name":"b","region":{"x1":0,"y1":0,"x2":8,"y2":8}}],"states":[{"name":"off","lightness":[0,20]},{"name":"red","hue":[340,20],"saturation":[50,100]},{"name":"blue","color":"#285AC8","tolerance":40}],"sample":"brightest"}`,
+	"image_measure_distance":       `{"path":"@img","x1":0,"y1":0,"x2":63,"y2":47,"relative_to":"image"}`,
+	"image_grid_overlay":           `{"path":"@img","grid_spacing":8,"show_coordinates":true}`,
+	"image_georeference":           `{"path":"@img","reference_points":[{"x":0,"y":0,"lat":10,"lon":20},{"x":16,"y":16,"lat":9,"lon":21}],"projection":"equirectangular","pixels":[{"x":4,"y":4},{"x":8,"y":8}],"coordinates":[{"lat":9.5,"lon":20.5}]}`,
+	"image_detect_scale_bar":       `{"path":"@img","label":"10 µm","language":"eng","calibrate":false}`,
+	"image_count_blobs":            `{"path":"@img","threshold":100,"polarity":"dark","min_area":2,"max_area":500,"min_roundness":0.3,"exclude_edges":false,"shape_descriptors":true}`,
+	"image_line_profile":           `{"path":"@img","x1":0,"y1":0,"x2":63,"y2":47,"width":3,"rgb":true,"edge_threshold":10,"include_plot":true}`,
+	"image_measure_angle":          `{"path":"@img","x1":63,"y1":0,"vertex_x":0,"vertex_y":0,"x2":0,"y2":47}`,
+	"image_measure_polygon":        `{"path":"@img","points":[{"x":0,"y":0},{"x":63,"y":0},{"x":63,"y":47},{"x":0,"y":47}]}`,
+	"image_check_parallel":         `{"path":"@img","line1":{"x1":0,"y1":0,"x2":63,"y2":0},"line2":{"x1":0,"y1":47,"x2":63,"y2":46},"tolerance":2}`,
+	"image_shape_descriptors":      `{"path":"@img","points":[{"x":0,"y":0},{"x":63,"y":5},{"x":30,"y":47},{"x":20,"y":20}]}`,
+	"image_ocr_region":             `{"path":"@img","x1":0,"y1":0,"x2":32,"y2":24,"alternatives":2,"vocabulary":["latte"],"max_distance":1,"layout":true,"rotation":90,"min_confidence":0.5,"unicode":"nfkc","dehyphenate":true,"extract_values":true,"locale":"de-DE"}`,
+	"image_redact":                 `{"path":"@img","regions":[{"x1":4,"y1":4,"x2":30,"y2":20},{"x1":60,"y1":40,"x2":90,"y2":70}],"style":"pixelate","block_size":5,"padding":0}`,
+	"image_detect_pii":             `{"path":"@img","categories":["email","credit_card"],"language":"eng"}`,
+	"image_extract_fields":         `{"path":"@img","fields":[{"labels":["Total"],"type":"amount"}]}`,
+	"image_analyze_form":           `{"path":"@img","min_area":16}`,
+	"image_to_mermaid":             `{"path":"@img","threshold":128,"min_node_area":50,"direction":"LR"}`,
+	"image_extract_org_chart":      `{"path":"@img","threshold":128,"min_node_area":50,"title_first":true}`,
+	"image_extract_topology":       `{"path":"@img","threshold":128,"min_node_size":8}`,
+	"image_text_diff":              `{"path_a":"@img","path_b":"@img","min_confidence":0.5,"ignore_case":true}`,
+	"image_read_display":           `{"path":"@img","region":{"x1":0,"y1":0,"x2":60,"y2":30},"kind":"seven_segment","polarity":"dark","letters":true}`,
+	"image_detect_rectangles":      `{"path":"@img","min_area":10,"export_format":"coco"}`,
+	"image_detect_lines":           `{"path":"@img","export_format":"yolo"}`,
+	"image_detect_circles":         `{"path":"@img","max_radius":20,"export_format":"voc"}`,
+	"image_skeletonize":            `{"path":"@img","invert":true,"graph":true}`,
+	"image_check_alignment":        `{"points":[{"x":1,"y":2},{"x":3,"y":2}],"tolerance":1}`,
+	"image_compare_regions":        `{"path":"@img","region1":{"x1":0,"y1":0,"x2":16,"y2":16},"region2":{"x1":8,"y1":8,"x2":24,"y2":24},"mask":{"rle":{"size":[16,16],"counts":[20,200,36]}}}`,
+	"image_align":                  `{"reference":{"path":"@img"},"target":{"path":"@img","region":{"x1":2,"y1":2,"x2":50,"y2":40}},"max_offset":4}`,
+	"image_check_centering":        `{"path":"@img","container":{"x1":0,"y1":0,"x2":64,"y2":48},"element":{"x1":8,"y1":8,"x2":30,"y2":24}}`,
+	"image_find_empty_regions":     `{"path":"@img","count":2,"min_width":4,"min_height":4}`,
+	"image_check_overlaps":         `{"path":"@img","boxes":[{"x1":0,"y1":0,"x2":20,"y2":20,"label":"a"},{"x1":10,"y1":10,"x2":40,"y2":30}]}`,
+	"image_detect_periodicity":     `{"path":"@img","region":{"x1":0,"y1":0,"x2":48,"y2":32},"max_peaks":2}`,
+	"image_detect_rows":            `{"path":"@img","region":{"x1":0,"y1":0,"x2":64,"y2":48},"direction":"columns","min_gap":1}`,
+	"image_segment_regions":        `{"path":"@img","scale":100,"min_size":4,"max_regions":3,"include_image":true}`,
+	"image_detect_incremental":     `{"path":"@img","previous_path":"@img","min_area":10}`,
+	"image_line_intersections":     `{"path":"@img","lines":[{"x1":0,"y1":10,"x2":63,"y2":10},{"x1":30,"y1":0,"x2":30,"y2":47}],"min_length":10,"tolerance":2,"min_angle":15}`,
+	"image_detect_artifacts":       `{"path":"@img"}`,
+	"image_detect_moire":           `{"path":"@img","tile_size":32,"min_strength":0.1}`,
+	"image_inspect_channels":       `{"path":"@img","channels":["a","r"],"bits":[0,3,7],"include_images":false}`,
+	"image_near_duplicate":         `{"path_a":"@img","path_b":"@img"}`,
+	"image_accessibility_audit":    `{"path":"@img","level":"AAA","scale":2,"min_text_size":4,"max_issues":3}`,
+	"image_denoise":                `{"path":"@img","method":"nlm","strength":2}`,
+	"image_normalize":              `{"path":"@img","white_balance":false,"clip_percent":5}`,
+	"image_scan_cleanup":           `{"path":"@img","deskew":false,"window":15}`,
+	"image_clean_whiteboard":       `{"path":"@img","snap":false,"tolerance":2}`,
+	"image_detect_formula_regions": `{"path":"@img","min_confidence":0.1,"check_words":false}`,
+	"image_detect_window_chrome":   `{"path":"@img","platform":"macos","scale":2,"include_image":true}`,
+	"image_detect_viewport":        `{"path":"@img"}`,
+	"image_strip_device_chrome":    `{"path":"@img","platform":"ios","scale":2,"status_bar":true,"navigation_bar":false}`,
+	"image_check_specs":            `{"paths":["@img","@img"],"min_width":16,"aspect_ratio":1.33,"aspect_tolerance":2,"max_file_size":100000,"formats":["png","jpg"],"min_dpi":72}`,
+	"image_safe_area":              `{"path":"@img","preset":"tiktok","margins":{"top":5,"left":5,"bottom":5,"right":5},"checks":["text","content"],"include_image":false}`,
+	"image_detect_print_marks":     `{"path":"@img","dpi":150,"min_bleed_mm":3,"safe_margin_mm":5,"checks":["content"],"include_image":false}`,
+	"image_detect_halo":            `{"path":"@img","threshold":32,"min_alpha":8,"include_image":true}`,
+	"image_stroke_width_audit":     `{"path":"@img","min_length":10,"min_area":50,"expected_width":2,"tolerance":1}`,
+	"image_compare_states":         `{"path_a":"@img","path_b":"@img","threshold":16,"min_area":4,"merge_distance":2,"include_image":true}`,
+	"image_detect_loading":         `{"path":"@img","min_skeleton_blocks":2}`,
+	"image_grow_regions":           `{"path":"@img","seeds":[{"x":2,"y":2,"label":"a"},{"x":40,"y":30,"tolerance":60}],"tolerance":20,"include_image":true}`,
+	"image_detect_grid":            `{"path":"@img","region":{"x1":0,"y1":0,"x2":64,"y2":48},"min_coverage":0.5,"cells":[{"row":0,"col":0}]}`,
+	"image_read_heatmap":           `{"path":"@img","preset":"github","scale":[{"color":"#000000","value":0},{"color":"#FFFFFF","value":1}],"discrete":true,"rows":2,"columns":3,"tolerance":60}`,
+	"image_read_board":             `{"path":"@img","rows":3,"columns":3,"piece_colors":[{"label":"red","color":"#FF0000"}],"templates":[{"path":"@img","label":"king","region":{"x1":0,"y1":0,"x2":16,"y2":16}}]}`,
+	"image_read_gauge":             `{"path":"@img","center":{"x":50,"y":50},"radius":40,"min_angle":225,"max_angle":135,"min_value":0,"max_value":100,"needle_color":"#000000"}`,
+	"image_read_clock":             `{"path":"@img","center":{"x":50,"y":50},"radius":40}`,
+	"image_match_features":         `{"reference":{"path":"@img","region":{"x1":0,"y1":0,"x2":40,"y2":40}},"target":{"path":"@img"},"max_features":50}`,
+	"image_find_template":          `{"template":{"path":"@img","region":{"x1":0,"y1":0,"x2":16,"y2":16}},"target":{"path":"@img","region":{"x1":0,"y1":0,"x2":64,"y2":48}},"threshold":0.9,"max_matches":5}`,
+	"image_contact_sheet":          `{"images":[{"path":"@img","label":"a"},{"path":"@img","region":{"x1":0,"y1":0,"x2":32,"y2":24}}],"columns":2,"thumb_size":16}`,
+	"image_side_by_side":           `{"left":{"path":"@img"},"right":{"path":"@img","region":{"x1":0,"y1":0,"x2":32,"y2":24}},"highlight_diff":true,"font_diff":true}`,
+	"image_baseline_store":         `{"path":"@img","name":"fuzz"}`,
+	"image_baseline_compare":       `{"path":"@img","name":"fuzz","max_diff_percent":0.5}`,
+}
+
+func FuzzHandleRequest(f *testing.F) {
+	dir := f.TempDir()
+	path := writeFuzzImage(f, dir)
+
+	f.Add([]byte(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`))
+	f.Add([]byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}`))
+	f.Add([]byte(`{"jsonrpc":"2.0","id":"list","method":"tools/list"}`))
+	f.Add([]byte(`{"jsonrpc":"2.0","id":null,"method":"ping"}`))
+	f.Add([]byte(`{"jsonrpc":"2.0","id":2,"method":"unknown/method"}`))
+	f.Add([]byte(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":[1,2]}`))
+	f.Add([]byte(`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"image_dimensions","arguments":{"path":"@img"}}}`))
+	f.Add([]byte(`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"image_crop","arguments":{"path":"@img","x1":4,"y1":4,"x2":40,"y2":30,"scale":1.5}}}`))
+	f.Add([]byte(`{"jsonrpc":"2.0","id":6,"method":"tools/call","params":{"name":"image_detect_rectangles","arguments":{"path":"@img","min_area":10,"debug":true}}}`))
+	f.Add([]byte(`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"image_sample_color","arguments":"@img"}}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		data = bytes.ReplaceAll(data, []byte(fuzzImagePlaceholder), []byte(path))
+		if !fuzzPathsAllowed(data, dir) {
+			t.Skip("input names a file outside the test directory")
+		}
+
+		// As in Run: lines that are not valid requests are dropped
+		var req MCPRequest
+		if json.Unmarshal(data, &req) != nil {
+			return
+		}
+
+		srv := New()
+		srv.SetCacheDir(dir)
+		resp := srv.handleRequest(&req)
+		if req.Method == "notifications/initialized" {
+			if resp != nil {
+				t.Fatalf("notification got a response: %+v", resp)
+			}
+			return
+		}
+		if resp == nil {
+			t.Fatalf("method %q got no response", req.Method)
+		}
+		if (resp.Result == nil) == (resp.Error == nil) {
+			t.Fatalf("response must have exactly one of result and error: %+v", resp)
+		}
+		if resp.JSONRPC != "2.0" || !reflect.DeepEqual(resp.ID, req.ID) {
+			t.Fatalf("response header %q/%v does not match request id %v", resp.JSONRPC, resp.ID, req.ID)
+		}
+		if _, err := json.Marshal(resp); err != nil {
+			t.Fatalf("response cannot be encoded: %v", err)
+		}
+	})
+}
+
+func FuzzExecuteTool(f *testing.F) {
+	dir := f.TempDir()
+	path := writeFuzzImage(f, dir)
+
+	for _, tool := range GetToolDefinitions() {
+		f.Add(tool.Name, []byte(`{"path":"@img"}`))
+		if args, ok := fuzzToolArguments[tool.Name]; ok {
+			f.Add(tool.Name, []byte(args))
+		}
+	}
+	f.Add("image_crop", []byte(`{"path":"@img","x1":40,"y1":30,"x2":4,"y2":4,"scale":-1}`))
+	f.Add("image_detect_circles", []byte(`{"path":"@img","min_radius":30,"max_radius":2,"auto_tune":true}`))
+	f.Add("image_load", []byte(`null`))
+
+	f.Fuzz(func(t *testing.T, name string, args []byte) {
+		args = bytes.ReplaceAll(args, []byte(fuzzImagePlaceholder), []byte(path))
+		if !fuzzPathsAllowed(args, dir) {
+			t.Skip("input names a file outside the test directory")
+		}
+
+		srv := New()
+		srv.SetCacheDir(dir)
+		result, err := srv.executeTool(name, args)
+		if err != nil {
+			return
+		}
+		// A result that cannot be encoded reaches the client as an
+		// internal error instead of the tool's output
+		if _, err := json.Marshal(result); err != nil {
+			t.Fatalf("%s: result cannot be encoded: %v", name, err)
+		}
+	})
+}
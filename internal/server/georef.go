@@ -0,0 +1,154 @@
+package server
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/ironsheep/image-tools-mcp/internal/geo"
+)
+
+// maxGeoPoints caps the pixels and the coordinates image_georeference
+// converts in one call, and maxGeoResidual is the misfit, in pixels, of
+// control points above which it warns.
+const (
+	maxGeoPoints   = 1000
+	maxGeoResidual = 2.0
+)
+
+// GeoPoint is a pixel of a map image and its geographic coordinates.
+type GeoPoint struct {
+	X   float64 `json:"x"`
+	Y   float64 `json:"y"`
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+
+	// Inside is true if the pixel lies within the image.
+	Inside bool `json:"inside"`
+}
+
+// GeoreferenceResult is the result of image_georeference: the map's
+// scale and orientation, and pixels converted to geographic coordinates
+// and back.
+type GeoreferenceResult struct {
+	// Projection is the projection the map was taken to be drawn in.
+	Projection string `json:"projection"`
+
+	// Width and Height are the image's dimensions in pixels.
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// MetersPerPixel is the ground distance one pixel spans at the
+	// image's center.
+	MetersPerPixel float64 `json:"meters_per_pixel"`
+
+	// NorthDegrees is the direction of north in the image, in degrees
+	// clockwise from up: 0 for a north-up map.
+	NorthDegrees float64 `json:"north_degrees"`
+
+	// ResidualPixels is the root-mean-square distance between the control
+	// points and where their coordinates map to; 0 for two points.
+	ResidualPixels float64 `json:"residual_pixels"`
+
+	// Corners are the coordinates of the image's outer corners: top left,
+	// top right, bottom right, and bottom left.
+	Corners []GeoPoint `json:"corners"`
+
+	// Pixels are the requested pixels with their coordinates.
+	Pixels []GeoPoint `json:"pixels"`
+
+	// Coordinates are the requested coordinates with their pixels.
+	Coordinates []GeoPoint `json:"coordinates"`
+
+	// SegmentMeters are the ground distances between consecutive
+	// requested pixels, and PathMeters their sum; empty for fewer than
+	// two pixels.
+	SegmentMeters []float64 `json:"segment_meters,omitempty"`
+	PathMeters    float64   `json:"path_meters,omitempty"`
+
+	// Warnings note control points that disagree with each other.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// geoCoordinate is a latitude and longitude to convert to a pixel.
+type geoCoordinate struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// geoPixel is a pixel to convert to a latitude and longitude.
+type geoPixel struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// buildGeoreference fits a transform to the control points of a map
+// image with the given bounds and converts pixels and coordinates with
+// it.
+func buildGeoreference(bounds image.Rectangle, points []geo.ControlPoint, projection string, pixels []geoPixel, coords []geoCoordinate) (*GeoreferenceResult, error) {
+	if len(pixels) > maxGeoPoints || len(coords) > maxGeoPoints {
+		return nil, fmt.Errorf("at most %d pixels and %d coordinates, got %d and %d", maxGeoPoints, maxGeoPoints, len(pixels), len(coords))
+	}
+	t, err := geo.NewTransform(points, projection)
+	if err != nil {
+		return nil, err
+	}
+	w, h := bounds.Dx(), bounds.Dy()
+	result := &GeoreferenceResult{
+		Projection:     projection,
+		Width:          w,
+		Height:         h,
+		MetersPerPixel: roundTo(t.MetersPerPixel(float64(w)/2, float64(h)/2), 4),
+		NorthDegrees:   roundTo(t.NorthDegrees(), 2),
+		ResidualPixels: roundTo(t.Residual(), 2),
+		Corners:        []GeoPoint{},
+		Pixels:         []GeoPoint{},
+		Coordinates:    []GeoPoint{},
+	}
+	if t.Residual() > maxGeoResidual {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("control points disagree by %.1f pixels on average; check them and the projection", t.Residual()))
+	}
+
+	point := func(x, y, lat, lon float64) GeoPoint {
+		return GeoPoint{
+			X:      roundTo(x, 2),
+			Y:      roundTo(y, 2),
+			Lat:    roundTo(lat, 7),
+			Lon:    roundTo(lon, 7),
+			Inside: x >= float64(bounds.Min.X) && y >= float64(bounds.Min.Y) && x < float64(bounds.Max.X) && y < float64(bounds.Max.Y),
+		}
+	}
+	fx1, fy1, fx2, fy2 := float64(bounds.Min.X), float64(bounds.Min.Y), float64(bounds.Max.X), float64(bounds.Max.Y)
+	for _, c := range [][2]float64{{fx1, fy1}, {fx2, fy1}, {fx2, fy2}, {fx1, fy2}} {
+		lat, lon := t.ToGeo(c[0], c[1])
+		corner := point(c[0], c[1], lat, lon)
+		corner.Inside = true
+		result.Corners = append(result.Corners, corner)
+	}
+	var prevLat, prevLon float64
+	for i, p := range pixels {
+		lat, lon := t.ToGeo(p.X, p.Y)
+		result.Pixels = append(result.Pixels, point(p.X, p.Y, lat, lon))
+		if i > 0 {
+			d := geo.Distance(prevLat, prevLon, lat, lon)
+			result.SegmentMeters = append(result.SegmentMeters, roundTo(d, 2))
+			result.PathMeters += d
+		}
+		prevLat, prevLon = lat, lon
+	}
+	result.PathMeters = roundTo(result.PathMeters, 2)
+	for _, c := range coords {
+		if math.Abs(c.Lat) > 90 || math.Abs(c.Lon) > 180 {
+			return nil, fmt.Errorf("coordinates must have latitude within ±90 and longitude within ±180, got %g, %g", c.Lat, c.Lon)
+		}
+		x, y := t.ToPixel(c.Lat, c.Lon)
+		result.Coordinates = append(result.Coordinates, point(x, y, c.Lat, c.Lon))
+	}
+	return result, nil
+}
+
+// roundTo rounds v to the given number of decimal places.
+func roundTo(v float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(v*scale) / scale
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	"github.com/ironsheep/image-tools-mcp/internal/geo"
+)
+
+// worldMap are the control points of a 720x360 equirectangular world
+// map, half a degree per pixel.
+var worldMap = []geo.ControlPoint{
+	{X: 360, Y: 180, Lat: 0, Lon: 0},
+	{X: 720, Y: 0, Lat: 90, Lon: 180},
+}
+
+func TestBuildGeoreference(t *testing.T) {
+	result, err := buildGeoreference(image.Rect(0, 0, 720, 360), worldMap, geo.Equirectangular,
+		[]geoPixel{{X: 360, Y: 180}, {X: 360, Y: 178}, {X: 362, Y: 178}},
+		[]geoCoordinate{{Lat: 51.5, Lon: -0.1}, {Lat: -45, Lon: 170}})
+	if err != nil {
+		t.Fatalf("buildGeoreference failed: %v", err)
+	}
+	if result.NorthDegrees != 0 || result.ResidualPixels != 0 || len(result.Warnings) != 0 {
+		t.Errorf("got north %g, residual %g, warnings %v", result.NorthDegrees, result.ResidualPixels, result.Warnings)
+	}
+	wantCorners := []GeoPoint{
+		{X: 0, Y: 0, Lat: 90, Lon: -180, Inside: true},
+		{X: 720, Y: 0, Lat: 90, Lon: -180, Inside: true},
+		{X: 720, Y: 360, Lat: -90, Lon: -180, Inside: true},
+		{X: 0, Y: 360, Lat: -90, Lon: -180, Inside: true},
+	}
+	for i, c := range result.Corners {
+		if c != wantCorners[i] {
+			t.Errorf("corner %d = %+v, want %+v", i, c, wantCorners[i])
+		}
+	}
+
+	// Two steps of one degree: north along a meridian, then east along
+	// the first parallel
+	if len(result.SegmentMeters) != 2 || math.Abs(result.SegmentMeters[0]-111195) > 1 || math.Abs(result.SegmentMeters[1]-111178) > 1 {
+		t.Errorf("segments = %v", result.SegmentMeters)
+	}
+	if math.Abs(result.PathMeters-result.SegmentMeters[0]-result.SegmentMeters[1]) > 0.02 {
+		t.Errorf("path = %g m", result.PathMeters)
+	}
+
+	london, south := result.Coordinates[0], result.Coordinates[1]
+	if london.X != 359.8 || london.Y != 77 || !london.Inside {
+		t.Errorf("London = %+v", london)
+	}
+	if south.X != 700 || south.Y != 270 {
+		t.Errorf("45°S 170°E = %+v", south)
+	}
+}
+
+func TestBuildGeoreference_Invalid(t *testing.T) {
+	bounds := image.Rect(0, 0, 720, 360)
+	if _, err := buildGeoreference(bounds, worldMap[:1], geo.Equirectangular, nil, nil); err == nil {
+		t.Error("expected error for a single control point")
+	}
+	if _, err := buildGeoreference(bounds, worldMap, geo.Equirectangular, nil, []geoCoordinate{{Lat: 95}}); err == nil {
+		t.Error("expected error for latitude 95")
+	}
+	if _, err := buildGeoreference(bounds, worldMap, geo.Equirectangular, make([]geoPixel, maxGeoPoints+1), nil); err == nil {
+		t.Error("expected error for too many pixels")
+	}
+}
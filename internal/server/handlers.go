@@ -1,10 +1,20 @@
 package server
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 
+	"github.com/ironsheep/image-tools-mcp/internal/baseline"
+	"github.com/ironsheep/image-tools-mcp/internal/clipboard"
 	"github.com/ironsheep/image-tools-mcp/internal/detection"
+	"github.com/ironsheep/image-tools-mcp/internal/geo"
 	"github.com/ironsheep/image-tools-mcp/internal/imaging"
 	"github.com/ironsheep/image-tools-mcp/internal/ocr"
 )
@@ -20,12 +30,18 @@ type ToolCallParams struct {
 
 // handleToolsCall processes a tools/call request and executes the specified tool.
 //
-// The response wraps the tool result in MCP's content format:
+// The response carries the tool result twice: as a JSON object in
+// structuredContent, matching the tool's outputSchema, and as JSON text
+// for clients that predate structured output:
 //
 //	{
-//	  "content": [{"type": "text", "text": "<JSON result>"}]
+//	  "content": [{"type": "text", "text": "<JSON result>"}],
+//	  "structuredContent": {<JSON result>}
 //	}
 //
+// Results of tools annotated as idempotent are cached, and a repeated call
+// with the same arguments is answered from the cache.
+//
 // Tool execution errors return a JSON-RPC error response with code -32000.
 func (s *Server) handleToolsCall(req *MCPRequest) *MCPResponse {
 	var params ToolCallParams
@@ -33,21 +49,45 @@ func (s *Server) handleToolsCall(req *MCPRequest) *MCPResponse {
 		return s.errorResponse(req.ID, -32602, "Invalid params", err.Error())
 	}
 
+	var key string
+	if toolAnnotations(params.Name).IdempotentHint {
+		key = resultKey(params.Name, params.Arguments)
+		if data, ok := s.results.get(key); ok {
+			return s.toolResponse(req.ID, data)
+		}
+	}
+
 	result, err := s.executeTool(params.Name, params.Arguments)
 	if err != nil {
 		return s.errorResponse(req.ID, -32000, "Tool execution failed", err.Error())
 	}
 
+	data, err := json.Marshal(result)
+	if err != nil {
+		return s.errorResponse(req.ID, -32603, "Internal error", err.Error())
+	}
+	s.results.put(key, data)
+	return s.toolResponse(req.ID, data)
+}
+
+// toolResponse wraps an encoded tool result in a tools/call response.
+func (s *Server) toolResponse(id interface{}, data []byte) *MCPResponse {
+	var text bytes.Buffer
+	if err := json.Indent(&text, data, "", "  "); err != nil {
+		return s.errorResponse(id, -32603, "Internal error", err.Error())
+	}
+
 	return &MCPResponse{
 		JSONRPC: "2.0",
-		ID:      req.ID,
+		ID:      id,
 		Result: map[string]interface{}{
 			"content": []map[string]interface{}{
 				{
 					"type": "text",
-					"text": mustMarshalJSON(result),
+					"text": text.String(),
 				},
 			},
+			"structuredContent": json.RawMessage(data),
 		},
 	}
 }
@@ -65,14 +105,24 @@ func (s *Server) executeTool(name string, args json.RawMessage) (interface{}, er
 	// Basic Image Information
 	case "image_load":
 		return s.handleImageLoad(args)
+	case "image_from_clipboard":
+		return s.handleImageFromClipboard(args)
 	case "image_dimensions":
 		return s.handleImageDimensions(args)
+	case "image_extract_icon":
+		return s.handleImageExtractIcon(args)
+	case "image_thumbnail":
+		return s.handleImageThumbnail(args)
 
 	// Region Operations
 	case "image_crop":
 		return s.handleImageCrop(args)
 	case "image_crop_quadrant":
 		return s.handleImageCropQuadrant(args)
+	case "image_split_sprites":
+		return s.handleImageSplitSprites(args)
+	case "image_smart_crop":
+		return s.handleImageSmartCrop(args)
 
 	// Color Operations
 	case "image_sample_color":
@@ -81,12 +131,36 @@ func (s *Server) executeTool(name string, args json.RawMessage) (interface{}, er
 		return s.handleImageSampleColorsMulti(args)
 	case "image_dominant_colors":
 		return s.handleImageDominantColors(args)
+	case "image_check_palette":
+		return s.handleImageCheckPalette(args)
+	case "image_compare_palettes":
+		return s.handleImageComparePalettes(args)
+	case "image_count_colors":
+		return s.handleImageCountColors(args)
+	case "image_read_indicators":
+		return s.handleImageReadIndicators(args)
 
 	// Measurement Operations
 	case "image_measure_distance":
 		return s.handleImageMeasureDistance(args)
 	case "image_grid_overlay":
 		return s.handleImageGridOverlay(args)
+	case "image_georeference":
+		return s.handleImageGeoreference(args)
+	case "image_detect_scale_bar":
+		return s.handleImageDetectScaleBar(args)
+	case "image_count_blobs":
+		return s.handleImageCountBlobs(args)
+	case "image_line_profile":
+		return s.handleImageLineProfile(args)
+	case "image_measure_angle":
+		return s.handleImageMeasureAngle(args)
+	case "image_measure_polygon":
+		return s.handleImageMeasurePolygon(args)
+	case "image_check_parallel":
+		return s.handleImageCheckParallel(args)
+	case "image_shape_descriptors":
+		return s.handleImageShapeDescriptors(args)
 
 	// OCR Operations
 	case "image_ocr_full":
@@ -95,6 +169,24 @@ func (s *Server) executeTool(name string, args json.RawMessage) (interface{}, er
 		return s.handleImageOCRRegion(args)
 	case "image_detect_text_regions":
 		return s.handleImageDetectTextRegions(args)
+	case "image_redact":
+		return s.handleImageRedact(args)
+	case "image_detect_pii":
+		return s.handleImageDetectPII(args)
+	case "image_extract_fields":
+		return s.handleImageExtractFields(args)
+	case "image_analyze_form":
+		return s.handleImageAnalyzeForm(args)
+	case "image_to_mermaid":
+		return s.handleImageToMermaid(args)
+	case "image_extract_org_chart":
+		return s.handleImageExtractOrgChart(args)
+	case "image_extract_topology":
+		return s.handleImageExtractTopology(args)
+	case "image_text_diff":
+		return s.handleImageTextDiff(args)
+	case "image_read_display":
+		return s.handleImageReadDisplay(args)
 
 	// Shape Detection
 	case "image_detect_rectangles":
@@ -105,12 +197,110 @@ func (s *Server) executeTool(name string, args json.RawMessage) (interface{}, er
 		return s.handleImageDetectCircles(args)
 	case "image_edge_detect":
 		return s.handleImageEdgeDetect(args)
+	case "image_skeletonize":
+		return s.handleImageSkeletonize(args)
+	case "image_detect_incremental":
+		return s.handleImageDetectIncremental(args)
+	case "image_line_intersections":
+		return s.handleImageLineIntersections(args)
 
 	// Analysis Helpers
 	case "image_check_alignment":
 		return s.handleImageCheckAlignment(args)
 	case "image_compare_regions":
 		return s.handleImageCompareRegions(args)
+	case "image_align":
+		return s.handleImageAlign(args)
+	case "image_infer_nine_patch":
+		return s.handleImageInferNinePatch(args)
+	case "image_check_centering":
+		return s.handleImageCheckCentering(args)
+	case "image_find_empty_regions":
+		return s.handleImageFindEmptyRegions(args)
+	case "image_check_overlaps":
+		return s.handleImageCheckOverlaps(args)
+	case "image_find_repeats":
+		return s.handleImageFindRepeats(args)
+	case "image_detect_periodicity":
+		return s.handleImageDetectPeriodicity(args)
+	case "image_detect_rows":
+		return s.handleImageDetectRows(args)
+	case "image_segment_regions":
+		return s.handleImageSegmentRegions(args)
+	case "image_classify_content":
+		return s.handleImageClassifyContent(args)
+	case "image_is_blank":
+		return s.handleImageIsBlank(args)
+	case "image_detect_artifacts":
+		return s.handleImageDetectArtifacts(args)
+	case "image_detect_moire":
+		return s.handleImageDetectMoire(args)
+	case "image_inspect_channels":
+		return s.handleImageInspectChannels(args)
+	case "image_near_duplicate":
+		return s.handleImageNearDuplicate(args)
+	case "image_generate_report":
+		return s.handleImageGenerateReport(args)
+	case "image_accessibility_audit":
+		return s.handleImageAccessibilityAudit(args)
+	case "image_denoise":
+		return s.handleImageDenoise(args)
+	case "image_normalize":
+		return s.handleImageNormalize(args)
+	case "image_scan_cleanup":
+		return s.handleImageScanCleanup(args)
+	case "image_clean_whiteboard":
+		return s.handleImageCleanWhiteboard(args)
+	case "image_detect_formula_regions":
+		return s.handleImageDetectFormulaRegions(args)
+	case "image_detect_window_chrome":
+		return s.handleImageDetectWindowChrome(args)
+	case "image_detect_viewport":
+		return s.handleImageDetectViewport(args)
+	case "image_strip_device_chrome":
+		return s.handleImageStripDeviceChrome(args)
+	case "image_check_specs":
+		return s.handleImageCheckSpecs(args)
+	case "image_safe_area":
+		return s.handleImageSafeArea(args)
+	case "image_detect_print_marks":
+		return s.handleImageDetectPrintMarks(args)
+	case "image_detect_halo":
+		return s.handleImageDetectHalo(args)
+	case "image_stroke_width_audit":
+		return s.handleImageStrokeWidthAudit(args)
+	case "image_compare_states":
+		return s.handleImageCompareStates(args)
+	case "image_detect_loading":
+		return s.handleImageDetectLoading(args)
+	case "image_grow_regions":
+		return s.handleImageGrowRegions(args)
+	case "image_detect_grid":
+		return s.handleImageDetectGrid(args)
+	case "image_read_heatmap":
+		return s.handleImageReadHeatmap(args)
+	case "image_read_board":
+		return s.handleImageReadBoard(args)
+	case "image_read_gauge":
+		return s.handleImageReadGauge(args)
+	case "image_read_clock":
+		return s.handleImageReadClock(args)
+	case "image_match_features":
+		return s.handleImageMatchFeatures(args)
+	case "image_find_template":
+		return s.handleImageFindTemplate(args)
+
+	// Composition
+	case "image_contact_sheet":
+		return s.handleImageContactSheet(args)
+	case "image_side_by_side":
+		return s.handleImageSideBySide(args)
+
+	// Visual Regression
+	case "image_baseline_store":
+		return s.handleImageBaselineStore(args)
+	case "image_baseline_compare":
+		return s.handleImageBaselineCompare(args)
 
 	default:
 		return nil, fmt.Errorf("unknown tool: %s", name)
@@ -130,13 +320,6 @@ func (s *Server) errorResponse(id interface{}, code int, message, data string) *
 	}
 }
 
-// mustMarshalJSON converts a value to pretty-printed JSON string.
-// Panics are suppressed; on marshal failure, returns an empty string.
-func mustMarshalJSON(v interface{}) string {
-	b, _ := json.MarshalIndent(v, "", "  ")
-	return string(b)
-}
-
 // === Basic Image Information Handlers ===
 
 type imageLoadArgs struct {
@@ -151,6 +334,35 @@ func (s *Server) handleImageLoad(args json.RawMessage) (interface{}, error) {
 	return imaging.LoadImageInfo(s.cache, a.Path)
 }
 
+// ClipboardResult is the result of image_from_clipboard: the file the
+// clipboard image was saved to, and its information as from image_load.
+type ClipboardResult struct {
+	// Path is the saved image, to pass to other tools.
+	Path string `json:"path"`
+
+	// Unchanged is true if the same image was read from the clipboard
+	// before, and its file reused.
+	Unchanged bool `json:"unchanged"`
+
+	imaging.ImageInfo
+}
+
+func (s *Server) handleImageFromClipboard(args json.RawMessage) (interface{}, error) {
+	data, err := s.readClipboard()
+	if err != nil {
+		return nil, err
+	}
+	path, existed, err := clipboard.Save(data, s.clipboardDir)
+	if err != nil {
+		return nil, err
+	}
+	info, err := imaging.LoadImageInfo(s.cache, path)
+	if err != nil {
+		return nil, err
+	}
+	return &ClipboardResult{Path: path, Unchanged: existed, ImageInfo: *info}, nil
+}
+
 func (s *Server) handleImageDimensions(args json.RawMessage) (interface{}, error) {
 	var a imageLoadArgs
 	if err := json.Unmarshal(args, &a); err != nil {
@@ -159,6 +371,39 @@ func (s *Server) handleImageDimensions(args json.RawMessage) (interface{}, error
 	return imaging.GetDimensions(s.cache, a.Path)
 }
 
+type imageExtractIconArgs struct {
+	Path string `json:"path"`
+	Size int    `json:"size"`
+}
+
+func (s *Server) handleImageExtractIcon(args json.RawMessage) (interface{}, error) {
+	var a imageExtractIconArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	return imaging.ExtractIcon(a.Path, a.Size)
+}
+
+type imageThumbnailArgs struct {
+	Path    string `json:"path"`
+	MaxSize int    `json:"max_size"`
+	Format  string `json:"format"`
+}
+
+func (s *Server) handleImageThumbnail(args json.RawMessage) (interface{}, error) {
+	var a imageThumbnailArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.MaxSize == 0 {
+		a.MaxSize = 128
+	}
+	if a.Format == "" {
+		a.Format = "png"
+	}
+	return s.thumbs.Get(s.cache, a.Path, a.MaxSize, a.Format)
+}
+
 // === Region Operation Handlers ===
 
 type imageCropArgs struct {
@@ -206,12 +451,66 @@ func (s *Server) handleImageCropQuadrant(args json.RawMessage) (interface{}, err
 	return imaging.CropQuadrant(img, a.Region, a.Scale)
 }
 
+type imageSplitSpritesArgs struct {
+	Path              string `json:"path"`
+	Columns           int    `json:"columns"`
+	Rows              int    `json:"rows"`
+	CellWidth         int    `json:"cell_width"`
+	CellHeight        int    `json:"cell_height"`
+	IncludeThumbnails bool   `json:"include_thumbnails"`
+	ThumbSize         int    `json:"thumb_size"`
+}
+
+func (s *Server) handleImageSplitSprites(args json.RawMessage) (interface{}, error) {
+	var a imageSplitSpritesArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.ThumbSize == 0 {
+		a.ThumbSize = 64
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	thumbSize := 0
+	if a.IncludeThumbnails {
+		thumbSize = a.ThumbSize
+	}
+	grid := imaging.SpriteGrid{Columns: a.Columns, Rows: a.Rows, CellWidth: a.CellWidth, CellHeight: a.CellHeight}
+	return imaging.SplitSprites(img, grid, thumbSize)
+}
+
+type imageSmartCropArgs struct {
+	Path        string  `json:"path"`
+	AspectRatio float64 `json:"aspect_ratio"`
+	Method      string  `json:"method"`
+	MaxSize     int     `json:"max_size"`
+}
+
+func (s *Server) handleImageSmartCrop(args json.RawMessage) (interface{}, error) {
+	var a imageSmartCropArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.Method == "" {
+		a.Method = "crop"
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.SmartCrop(img, a.AspectRatio, a.Method, a.MaxSize)
+}
+
 // === Color Operation Handlers ===
 
 type imageSampleColorArgs struct {
-	Path string `json:"path"`
-	X    int    `json:"x"`
-	Y    int    `json:"y"`
+	Path     string `json:"path"`
+	X        int    `json:"x"`
+	Y        int    `json:"y"`
+	BitDepth int    `json:"bit_depth"`
 }
 
 func (s *Server) handleImageSampleColor(args json.RawMessage) (interface{}, error) {
@@ -223,7 +522,10 @@ func (s *Server) handleImageSampleColor(args json.RawMessage) (interface{}, erro
 	if err != nil {
 		return nil, err
 	}
-	return imaging.SampleColor(img, a.X, a.Y)
+	if a.BitDepth == 0 {
+		a.BitDepth = 8
+	}
+	return imaging.SampleColorDepth(img, a.X, a.Y, a.BitDepth)
 }
 
 type imageSampleColorsMultiArgs struct {
@@ -233,6 +535,7 @@ type imageSampleColorsMultiArgs struct {
 		Y     int    `json:"y"`
 		Label string `json:"label,omitempty"`
 	} `json:"points"`
+	BitDepth int `json:"bit_depth"`
 }
 
 func (s *Server) handleImageSampleColorsMulti(args json.RawMessage) (interface{}, error) {
@@ -249,7 +552,10 @@ func (s *Server) handleImageSampleColorsMulti(args json.RawMessage) (interface{}
 	for i, p := range a.Points {
 		points[i] = imaging.LabeledPoint{X: p.X, Y: p.Y, Label: p.Label}
 	}
-	return imaging.SampleColorsMulti(img, points)
+	if a.BitDepth == 0 {
+		a.BitDepth = 8
+	}
+	return imaging.SampleColorsMultiDepth(img, points, a.BitDepth)
 }
 
 type imageDominantColorsArgs struct {
@@ -261,6 +567,10 @@ type imageDominantColorsArgs struct {
 		X2 int `json:"x2"`
 		Y2 int `json:"y2"`
 	} `json:"region,omitempty"`
+	Mask       *imaging.Mask `json:"mask"`
+	BitDepth   int           `json:"bit_depth"`
+	AlphaMode  string        `json:"alpha_mode"`
+	Background string        `json:"background"`
 }
 
 func (s *Server) handleImageDominantColors(args json.RawMessage) (interface{}, error) {
@@ -271,6 +581,9 @@ func (s *Server) handleImageDominantColors(args json.RawMessage) (interface{}, e
 	if a.Count == 0 {
 		a.Count = 5
 	}
+	if a.BitDepth == 0 {
+		a.BitDepth = 8
+	}
 	img, err := s.cache.Load(a.Path)
 	if err != nil {
 		return nil, err
@@ -280,17 +593,154 @@ func (s *Server) handleImageDominantColors(args json.RawMessage) (interface{}, e
 	if a.Region != nil {
 		region = &imaging.Region{X1: a.Region.X1, Y1: a.Region.Y1, X2: a.Region.X2, Y2: a.Region.Y2}
 	}
-	return imaging.DominantColors(img, a.Count, region)
+	mask, err := rasterizeMask(a.Mask, img.Bounds())
+	if err != nil {
+		return nil, err
+	}
+	alpha := imaging.AlphaOptions{Mode: a.AlphaMode, Background: a.Background}
+	return imaging.DominantColorsAlpha(img, a.Count, region, mask, a.BitDepth, alpha)
+}
+
+type imageCheckPaletteArgs struct {
+	Path        string          `json:"path"`
+	Palette     []string        `json:"palette"`
+	Tolerance   *float64        `json:"tolerance"`
+	MinPercent  *float64        `json:"min_percent"`
+	IgnoreEdges *bool           `json:"ignore_edges"`
+	Region      *imaging.Region `json:"region,omitempty"`
+	Mask        *imaging.Mask   `json:"mask"`
+	Points      []imaging.Point `json:"points"`
+}
+
+func (s *Server) handleImageCheckPalette(args json.RawMessage) (interface{}, error) {
+	var a imageCheckPaletteArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	opts := imaging.PaletteOptions{
+		Tolerance:   3,
+		MinPercent:  0.1,
+		IgnoreEdges: true,
+		Region:      a.Region,
+		Points:      a.Points,
+	}
+	if a.Tolerance != nil {
+		opts.Tolerance = *a.Tolerance
+	}
+	if a.MinPercent != nil {
+		opts.MinPercent = *a.MinPercent
+	}
+	if a.IgnoreEdges != nil {
+		opts.IgnoreEdges = *a.IgnoreEdges
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Mask, err = rasterizeMask(a.Mask, img.Bounds()); err != nil {
+		return nil, err
+	}
+	return imaging.CheckPalette(img, a.Palette, opts)
+}
+
+type imageComparePalettesArgs struct {
+	Images    []imageSourceArgs `json:"images"`
+	Count     int               `json:"count"`
+	Threshold float64           `json:"threshold"`
+}
+
+func (s *Server) handleImageComparePalettes(args json.RawMessage) (interface{}, error) {
+	var a imageComparePalettesArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.Count == 0 {
+		a.Count = 8
+	}
+	if a.Threshold == 0 {
+		a.Threshold = 15
+	}
+
+	sources := make([]imaging.PaletteSource, 0, len(a.Images))
+	for _, src := range a.Images {
+		img, label, err := s.loadImageSource(src)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, imaging.PaletteSource{Image: img, Label: label})
+	}
+	return imaging.ComparePalettes(sources, a.Count, a.Threshold)
+}
+
+type imageCountColorsArgs struct {
+	Path              string          `json:"path"`
+	Region            *imaging.Region `json:"region,omitempty"`
+	Mask              *imaging.Mask   `json:"mask"`
+	Limit             int             `json:"limit"`
+	MaxColors         int             `json:"max_colors"`
+	IgnoreTransparent bool            `json:"ignore_transparent"`
+	NearDistance      *int            `json:"near_distance"`
+}
+
+func (s *Server) handleImageCountColors(args json.RawMessage) (interface{}, error) {
+	var a imageCountColorsArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	opts := imaging.ColorCountOptions{
+		Region:            a.Region,
+		Limit:             256,
+		MaxColors:         a.MaxColors,
+		IgnoreTransparent: a.IgnoreTransparent,
+		NearDistance:      2,
+	}
+	if a.Limit != 0 {
+		opts.Limit = a.Limit
+	}
+	if a.NearDistance != nil {
+		opts.NearDistance = *a.NearDistance
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Mask, err = rasterizeMask(a.Mask, img.Bounds()); err != nil {
+		return nil, err
+	}
+	return imaging.CountColors(img, opts)
+}
+
+type imageReadIndicatorsArgs struct {
+	Path       string                   `json:"path"`
+	Indicators []imaging.Indicator      `json:"indicators"`
+	States     []imaging.IndicatorState `json:"states"`
+	Sample     string                   `json:"sample"`
+}
+
+func (s *Server) handleImageReadIndicators(args json.RawMessage) (interface{}, error) {
+	var a imageReadIndicatorsArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.Sample == "" {
+		a.Sample = "median"
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.ReadIndicators(img, a.Indicators, a.States, a.Sample)
 }
 
 // === Measurement Operation Handlers ===
 
 type imageMeasureDistanceArgs struct {
-	Path string `json:"path"`
-	X1   int    `json:"x1"`
-	Y1   int    `json:"y1"`
-	X2   int    `json:"x2"`
-	Y2   int    `json:"y2"`
+	Path       string `json:"path"`
+	X1         int    `json:"x1"`
+	Y1         int    `json:"y1"`
+	X2         int    `json:"x2"`
+	Y2         int    `json:"y2"`
+	RelativeTo string `json:"relative_to"`
 }
 
 func (s *Server) handleImageMeasureDistance(args json.RawMessage) (interface{}, error) {
@@ -298,11 +748,19 @@ func (s *Server) handleImageMeasureDistance(args json.RawMessage) (interface{},
 	if err := json.Unmarshal(args, &a); err != nil {
 		return nil, err
 	}
-	img, err := s.cache.Load(a.Path)
+	img, viewport, err := s.loadRelative(a.Path, a.RelativeTo)
+	if err != nil {
+		return nil, err
+	}
+	result, err := imaging.MeasureDistance(img, a.X1, a.Y1, a.X2, a.Y2)
 	if err != nil {
 		return nil, err
 	}
-	return imaging.MeasureDistance(img, a.X1, a.Y1, a.X2, a.Y2)
+	result.Viewport = viewport
+	if c := s.calibration(a.Path); c != nil {
+		result.Calibrated = c.Measure(result.DeltaX, result.DeltaY)
+	}
+	return result, nil
 }
 
 type imageGridOverlayArgs struct {
@@ -310,6 +768,7 @@ type imageGridOverlayArgs struct {
 	GridSpacing     int    `json:"grid_spacing"`
 	ShowCoordinates bool   `json:"show_coordinates"`
 	GridColor       string `json:"grid_color"`
+	RelativeTo      string `json:"relative_to"`
 }
 
 func (s *Server) handleImageGridOverlay(args json.RawMessage) (interface{}, error) {
@@ -323,42 +782,50 @@ func (s *Server) handleImageGridOverlay(args json.RawMessage) (interface{}, erro
 	if a.GridColor == "" {
 		a.GridColor = "#FF000080"
 	}
-	img, err := s.cache.Load(a.Path)
+	img, viewport, err := s.loadRelative(a.Path, a.RelativeTo)
+	if err != nil {
+		return nil, err
+	}
+	result, err := imaging.GridOverlay(img, a.GridSpacing, a.ShowCoordinates, a.GridColor)
 	if err != nil {
 		return nil, err
 	}
-	return imaging.GridOverlay(img, a.GridSpacing, a.ShowCoordinates, a.GridColor)
+	result.Viewport = viewport
+	return result, nil
 }
 
-// === OCR Operation Handlers ===
-
-type imageOCRFullArgs struct {
-	Path     string `json:"path"`
-	Language string `json:"language"`
+type imageGeoreferenceArgs struct {
+	Path            string             `json:"path"`
+	ReferencePoints []geo.ControlPoint `json:"reference_points"`
+	Projection      string             `json:"projection"`
+	Pixels          []geoPixel         `json:"pixels"`
+	Coordinates     []geoCoordinate    `json:"coordinates"`
 }
 
-func (s *Server) handleImageOCRFull(args json.RawMessage) (interface{}, error) {
-	var a imageOCRFullArgs
+func (s *Server) handleImageGeoreference(args json.RawMessage) (interface{}, error) {
+	var a imageGeoreferenceArgs
 	if err := json.Unmarshal(args, &a); err != nil {
 		return nil, err
 	}
-	if a.Language == "" {
-		a.Language = "eng"
+	if a.Projection == "" {
+		a.Projection = geo.Mercator
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
 	}
-	return ocr.ExtractText(a.Path, a.Language)
+	return buildGeoreference(img.Bounds(), a.ReferencePoints, a.Projection, a.Pixels, a.Coordinates)
 }
 
-type imageOCRRegionArgs struct {
-	Path     string `json:"path"`
-	X1       int    `json:"x1"`
-	Y1       int    `json:"y1"`
-	X2       int    `json:"x2"`
-	Y2       int    `json:"y2"`
-	Language string `json:"language"`
+type imageDetectScaleBarArgs struct {
+	Path      string `json:"path"`
+	Label     string `json:"label"`
+	Language  string `json:"language"`
+	Calibrate *bool  `json:"calibrate"`
 }
 
-func (s *Server) handleImageOCRRegion(args json.RawMessage) (interface{}, error) {
-	var a imageOCRRegionArgs
+func (s *Server) handleImageDetectScaleBar(args json.RawMessage) (interface{}, error) {
+	var a imageDetectScaleBarArgs
 	if err := json.Unmarshal(args, &a); err != nil {
 		return nil, err
 	}
@@ -369,174 +836,2842 @@ func (s *Server) handleImageOCRRegion(args json.RawMessage) (interface{}, error)
 	if err != nil {
 		return nil, err
 	}
-	return ocr.ExtractTextFromRegion(img, a.X1, a.Y1, a.X2, a.Y2, a.Language)
+	opts := imaging.ScaleBarOptions{Label: a.Label}
+	// Without Tesseract, labels are only known when given
+	if a.Label == "" && ocr.GetOCRInfo().Available {
+		opts.Read = func(r imaging.Region) string {
+			result, err := ocr.ExtractTextFromRegion(img, r.X1, r.Y1, r.X2, r.Y2, a.Language)
+			if err != nil {
+				return ""
+			}
+			return result.FullText
+		}
+	}
+	result, err := imaging.DetectScaleBars(img, opts)
+	if err != nil {
+		return nil, err
+	}
+	if result.Calibration != nil && (a.Calibrate == nil || *a.Calibrate) {
+		s.storeCalibration(a.Path, result.Calibration)
+	}
+	return result, nil
 }
 
-type imageDetectTextRegionsArgs struct {
-	Path          string  `json:"path"`
-	MinConfidence float64 `json:"min_confidence"`
+type imageCountBlobsArgs struct {
+	Path         string  `json:"path"`
+	Threshold    int     `json:"threshold"`
+	Polarity     string  `json:"polarity"`
+	MinArea      *int    `json:"min_area"`
+	MaxArea      int     `json:"max_area"`
+	MinRoundness float64 `json:"min_roundness"`
+	ExcludeEdges *bool   `json:"exclude_edges"`
+	Shapes       bool    `json:"shape_descriptors"`
 }
 
-func (s *Server) handleImageDetectTextRegions(args json.RawMessage) (interface{}, error) {
-	var a imageDetectTextRegionsArgs
+func (s *Server) handleImageCountBlobs(args json.RawMessage) (interface{}, error) {
+	var a imageCountBlobsArgs
 	if err := json.Unmarshal(args, &a); err != nil {
 		return nil, err
 	}
-	if a.MinConfidence == 0 {
-		a.MinConfidence = 0.5
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	result, err := imaging.CountBlobs(img, a.options())
+	if err != nil {
+		return nil, err
 	}
-	return ocr.DetectTextRegions(a.Path, a.MinConfidence)
+	result.Calibrate(s.calibration(a.Path))
+	return result, nil
 }
 
-// === Shape Detection Handlers ===
+// options returns the blob options of a, with their defaults.
+func (a imageCountBlobsArgs) options() imaging.BlobOptions {
+	opts := imaging.BlobOptions{
+		Threshold:    a.Threshold,
+		Polarity:     a.Polarity,
+		MinArea:      10,
+		MaxArea:      a.MaxArea,
+		MinRoundness: a.MinRoundness,
+		ExcludeEdges: a.ExcludeEdges == nil || *a.ExcludeEdges,
+		Shapes:       a.Shapes,
+	}
+	if a.MinArea != nil {
+		opts.MinArea = *a.MinArea
+	}
+	return opts
+}
 
-type imageDetectRectanglesArgs struct {
-	Path      string  `json:"path"`
-	MinArea   int     `json:"min_area"`
-	Tolerance float64 `json:"tolerance"`
+type imageLineProfileArgs struct {
+	Path          string  `json:"path"`
+	X1            int     `json:"x1"`
+	Y1            int     `json:"y1"`
+	X2            int     `json:"x2"`
+	Y2            int     `json:"y2"`
+	Width         int     `json:"width"`
+	RGB           bool    `json:"rgb"`
+	EdgeThreshold float64 `json:"edge_threshold"`
+	IncludePlot   *bool   `json:"include_plot"`
 }
 
-func (s *Server) handleImageDetectRectangles(args json.RawMessage) (interface{}, error) {
-	var a imageDetectRectanglesArgs
+func (s *Server) handleImageLineProfile(args json.RawMessage) (interface{}, error) {
+	var a imageLineProfileArgs
 	if err := json.Unmarshal(args, &a); err != nil {
 		return nil, err
 	}
-	if a.MinArea == 0 {
-		a.MinArea = 100
+	if a.Width == 0 {
+		a.Width = 1
 	}
-	if a.Tolerance == 0 {
-		a.Tolerance = 0.9
+	if a.EdgeThreshold == 0 {
+		a.EdgeThreshold = 20
 	}
 	img, err := s.cache.Load(a.Path)
 	if err != nil {
 		return nil, err
 	}
-	return detection.DetectRectangles(img, a.MinArea, a.Tolerance)
+	return imaging.LineProfile(img, a.X1, a.Y1, a.X2, a.Y2, imaging.ProfileOptions{
+		Width:         a.Width,
+		RGB:           a.RGB,
+		EdgeThreshold: a.EdgeThreshold,
+		IncludePlot:   a.IncludePlot == nil || *a.IncludePlot,
+	})
 }
 
-type imageDetectLinesArgs struct {
-	Path         string `json:"path"`
-	MinLength    int    `json:"min_length"`
-	DetectArrows bool   `json:"detect_arrows"`
+type imageMeasureAngleArgs struct {
+	Path    string `json:"path"`
+	X1      int    `json:"x1"`
+	Y1      int    `json:"y1"`
+	VertexX int    `json:"vertex_x"`
+	VertexY int    `json:"vertex_y"`
+	X2      int    `json:"x2"`
+	Y2      int    `json:"y2"`
 }
 
-func (s *Server) handleImageDetectLines(args json.RawMessage) (interface{}, error) {
-	var a imageDetectLinesArgs
+func (s *Server) handleImageMeasureAngle(args json.RawMessage) (interface{}, error) {
+	var a imageMeasureAngleArgs
 	if err := json.Unmarshal(args, &a); err != nil {
 		return nil, err
 	}
-	if a.MinLength == 0 {
-		a.MinLength = 20
+	return imaging.MeasureAngle(imaging.Point{X: a.X1, Y: a.Y1}, imaging.Point{X: a.VertexX, Y: a.VertexY}, imaging.Point{X: a.X2, Y: a.Y2})
+}
+
+type imageMeasurePolygonArgs struct {
+	Path   string          `json:"path"`
+	Points []imaging.Point `json:"points"`
+}
+
+func (s *Server) handleImageMeasurePolygon(args json.RawMessage) (interface{}, error) {
+	var a imageMeasurePolygonArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
 	}
-	img, err := s.cache.Load(a.Path)
+	result, err := imaging.MeasurePolygon(a.Points)
 	if err != nil {
 		return nil, err
 	}
-	return detection.DetectLines(img, a.MinLength, a.DetectArrows)
+	result.Calibrate(s.calibration(a.Path))
+	return result, nil
 }
 
-type imageDetectCirclesArgs struct {
-	Path      string `json:"path"`
-	MinRadius int    `json:"min_radius"`
-	MaxRadius int    `json:"max_radius"`
+type imageCheckParallelArgs struct {
+	Path      string              `json:"path"`
+	Line1     imaging.LineSegment `json:"line1"`
+	Line2     imaging.LineSegment `json:"line2"`
+	Tolerance float64             `json:"tolerance"`
 }
 
-func (s *Server) handleImageDetectCircles(args json.RawMessage) (interface{}, error) {
-	var a imageDetectCirclesArgs
+func (s *Server) handleImageCheckParallel(args json.RawMessage) (interface{}, error) {
+	var a imageCheckParallelArgs
 	if err := json.Unmarshal(args, &a); err != nil {
 		return nil, err
 	}
-	if a.MinRadius == 0 {
-		a.MinRadius = 5
-	}
-	if a.MaxRadius == 0 {
-		a.MaxRadius = 500
+	if a.Tolerance == 0 {
+		a.Tolerance = 1
 	}
-	img, err := s.cache.Load(a.Path)
+	result, err := imaging.CheckParallel(a.Line1, a.Line2, a.Tolerance)
 	if err != nil {
 		return nil, err
 	}
-	return detection.DetectCircles(img, a.MinRadius, a.MaxRadius)
+	result.Calibrate(s.calibration(a.Path))
+	return result, nil
 }
 
-type imageEdgeDetectArgs struct {
-	Path          string `json:"path"`
-	ThresholdLow  int    `json:"threshold_low"`
-	ThresholdHigh int    `json:"threshold_high"`
+type imageShapeDescriptorsArgs struct {
+	imageCountBlobsArgs
+	Points []imaging.Point `json:"points"`
+	Blob   int             `json:"blob"`
 }
 
-func (s *Server) handleImageEdgeDetect(args json.RawMessage) (interface{}, error) {
-	var a imageEdgeDetectArgs
+func (s *Server) handleImageShapeDescriptors(args json.RawMessage) (interface{}, error) {
+	var a imageShapeDescriptorsArgs
 	if err := json.Unmarshal(args, &a); err != nil {
 		return nil, err
 	}
-	if a.ThresholdLow == 0 {
-		a.ThresholdLow = 50
-	}
-	if a.ThresholdHigh == 0 {
-		a.ThresholdHigh = 150
+	switch {
+	case a.Points != nil && a.Blob != 0:
+		return nil, fmt.Errorf("pass points or a blob ID, not both")
+	case a.Points != nil:
+		return imaging.EnclosePoints(a.Points)
+	case a.Blob == 0:
+		return nil, fmt.Errorf("pass points or a blob ID from image_count_blobs")
 	}
 	img, err := s.cache.Load(a.Path)
 	if err != nil {
 		return nil, err
 	}
-	return imaging.EdgeDetect(img, a.ThresholdLow, a.ThresholdHigh)
+	return imaging.EncloseBlob(img, a.options(), a.Blob)
 }
 
-// === Analysis Helper Handlers ===
+// calibration returns the scale bar calibration stored for path, or nil.
+func (s *Server) calibration(path string) *imaging.ScaleCalibration {
+	s.calibrationMu.Lock()
+	defer s.calibrationMu.Unlock()
+	return s.calibrations[path]
+}
 
-type imageCheckAlignmentArgs struct {
-	Path      string `json:"path"`
-	Points    []struct {
-		X int `json:"x"`
-		Y int `json:"y"`
-	} `json:"points"`
-	Tolerance int `json:"tolerance"`
+// storeCalibration stores the scale bar calibration for path, replacing
+// any earlier one.
+func (s *Server) storeCalibration(path string, c *imaging.ScaleCalibration) {
+	s.calibrationMu.Lock()
+	defer s.calibrationMu.Unlock()
+	s.calibrations[path] = c
 }
 
-func (s *Server) handleImageCheckAlignment(args json.RawMessage) (interface{}, error) {
-	var a imageCheckAlignmentArgs
-	if err := json.Unmarshal(args, &a); err != nil {
-		return nil, err
+// loadRelative loads the image at path for a measurement tool in the
+// coordinate system relativeTo: the whole image for "image" (or none), or
+// the browser viewport found in it for "viewport", returned with its
+// bounds.
+func (s *Server) loadRelative(path, relativeTo string) (image.Image, *imaging.Region, error) {
+	img, err := s.cache.Load(path)
+	if err != nil {
+		return nil, nil, err
 	}
-	if a.Tolerance == 0 {
-		a.Tolerance = 5
+	switch relativeTo {
+	case "", "image":
+		return img, nil, nil
+	case "viewport":
+	default:
+		return nil, nil, fmt.Errorf("invalid relative_to %q: must be 'image' or 'viewport'", relativeTo)
 	}
-
-	points := make([]imaging.Point, len(a.Points))
+	found := imaging.DetectViewport(img)
+	if !found.Found {
+		return nil, nil, fmt.Errorf("no browser viewport found in %s", path)
+	}
+	viewport, err := imaging.CropRegion(img, *found.Viewport)
+	if err != nil {
+		return nil, nil, err
+	}
+	return viewport, found.Viewport, nil
+}
+
+// === OCR Operation Handlers ===
+
+type imageOCRFullArgs struct {
+	Path               string   `json:"path"`
+	Language           string   `json:"language"`
+	Denoise            string   `json:"denoise"`
+	Normalize          bool     `json:"normalize"`
+	ScanCleanup        bool     `json:"scan_cleanup"`
+	Alternatives       int      `json:"alternatives"`
+	Vocabulary         []string `json:"vocabulary"`
+	MaxDistance        int      `json:"max_distance"`
+	Layout             bool     `json:"layout"`
+	Rotation           int      `json:"rotation"`
+	AutoRotate         bool     `json:"auto_rotate"`
+	Vertical           bool     `json:"vertical"`
+	MinConfidence      float64  `json:"min_confidence"`
+	Unicode            string   `json:"unicode"`
+	FoldQuotes         bool     `json:"fold_quotes"`
+	Dehyphenate        bool     `json:"dehyphenate"`
+	CollapseWhitespace bool     `json:"collapse_whitespace"`
+	ExtractValues      bool     `json:"extract_values"`
+	Locale             string   `json:"locale"`
+}
+
+func (s *Server) handleImageOCRFull(args json.RawMessage) (interface{}, error) {
+	var a imageOCRFullArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.Language == "" {
+		a.Language = "eng"
+	}
+	locale, err := ocr.ParseLocale(a.Locale)
+	if err != nil {
+		return nil, err
+	}
+	path, cleanup, err := s.ocrInputPath(a.Path, a.Denoise, a.Normalize, a.ScanCleanup)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	result, err := ocr.ExtractTextWithOptions(path, ocr.Options{
+		Language:           a.Language,
+		Alternatives:       a.Alternatives,
+		Rotation:           a.Rotation,
+		AutoRotate:         a.AutoRotate,
+		Vertical:           a.Vertical,
+		MinConfidence:      a.MinConfidence,
+		Unicode:            a.Unicode,
+		FoldQuotes:         a.FoldQuotes,
+		Dehyphenate:        a.Dehyphenate,
+		CollapseWhitespace: a.CollapseWhitespace,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result, err = applyVocabulary(result, a.Vocabulary, a.MaxDistance); err != nil {
+		return nil, err
+	}
+	if a.ExtractValues {
+		ocr.ExtractValues(result, locale)
+	}
+	if a.Layout {
+		ocr.ApplyReadingOrder(result)
+	}
+	return result, nil
+}
+
+// applyVocabulary corrects the words of an OCR result to the vocabulary,
+// if one was given (see ocr.ApplyVocabulary). maxDistance defaults to 2.
+func applyVocabulary(result *ocr.OCRResult, vocabulary []string, maxDistance int) (*ocr.OCRResult, error) {
+	if len(vocabulary) == 0 {
+		return result, nil
+	}
+	if maxDistance == 0 {
+		maxDistance = 2
+	}
+	if err := ocr.ApplyVocabulary(result, vocabulary, maxDistance); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type imageOCRRegionArgs struct {
+	Path               string        `json:"path"`
+	X1                 int           `json:"x1"`
+	Y1                 int           `json:"y1"`
+	X2                 int           `json:"x2"`
+	Y2                 int           `json:"y2"`
+	Language           string        `json:"language"`
+	Mask               *imaging.Mask `json:"mask"`
+	Denoise            string        `json:"denoise"`
+	Normalize          bool          `json:"normalize"`
+	Alternatives       int           `json:"alternatives"`
+	Vocabulary         []string      `json:"vocabulary"`
+	MaxDistance        int           `json:"max_distance"`
+	Layout             bool          `json:"layout"`
+	Rotation           int           `json:"rotation"`
+	AutoRotate         bool          `json:"auto_rotate"`
+	Vertical           bool          `json:"vertical"`
+	MinConfidence      float64       `json:"min_confidence"`
+	Unicode            string        `json:"unicode"`
+	FoldQuotes         bool          `json:"fold_quotes"`
+	Dehyphenate        bool          `json:"dehyphenate"`
+	CollapseWhitespace bool          `json:"collapse_whitespace"`
+	ExtractValues      bool          `json:"extract_values"`
+	Locale             string        `json:"locale"`
+}
+
+func (s *Server) handleImageOCRRegion(args json.RawMessage) (interface{}, error) {
+	var a imageOCRRegionArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.Language == "" {
+		a.Language = "eng"
+	}
+	locale, err := ocr.ParseLocale(a.Locale)
+	if err != nil {
+		return nil, err
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	if img, err = preprocessInput(img, a.Denoise, a.Normalize); err != nil {
+		return nil, err
+	}
+	if a.Mask != nil {
+		// OCR sees only the masked pixels; the rest is filled with the
+		// most common masked color so it reads as background
+		mask, err := a.Mask.Rasterize(img.Bounds())
+		if err != nil {
+			return nil, err
+		}
+		region := &imaging.Region{X1: a.X1, Y1: a.Y1, X2: a.X2, Y2: a.Y2}
+		colors, err := imaging.DominantColorsMasked(img, 1, region, mask)
+		if err != nil {
+			return nil, err
+		}
+		fill := colors.Colors[0].RGB
+		img = imaging.MaskImage(img, mask, color.RGBA{fill.R, fill.G, fill.B, 255})
+	}
+	result, err := ocr.ExtractTextFromRegionWithOptions(img, a.X1, a.Y1, a.X2, a.Y2, ocr.Options{
+		Language:           a.Language,
+		Alternatives:       a.Alternatives,
+		Rotation:           a.Rotation,
+		AutoRotate:         a.AutoRotate,
+		Vertical:           a.Vertical,
+		MinConfidence:      a.MinConfidence,
+		Unicode:            a.Unicode,
+		FoldQuotes:         a.FoldQuotes,
+		Dehyphenate:        a.Dehyphenate,
+		CollapseWhitespace: a.CollapseWhitespace,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result, err = applyVocabulary(result, a.Vocabulary, a.MaxDistance); err != nil {
+		return nil, err
+	}
+	if a.ExtractValues {
+		ocr.ExtractValues(result, locale)
+	}
+	if a.Layout {
+		ocr.ApplyReadingOrder(result)
+	}
+	return result, nil
+}
+
+type imageDetectTextRegionsArgs struct {
+	Path          string        `json:"path"`
+	MinConfidence float64       `json:"min_confidence"`
+	Mask          *imaging.Mask `json:"mask"`
+	Denoise       string        `json:"denoise"`
+	Normalize     bool          `json:"normalize"`
+}
+
+func (s *Server) handleImageDetectTextRegions(args json.RawMessage) (interface{}, error) {
+	var a imageDetectTextRegionsArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.MinConfidence == 0 {
+		a.MinConfidence = 0.5
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	var mask *image.Alpha
+	if a.Mask != nil {
+		if mask, err = a.Mask.Rasterize(img.Bounds()); err != nil {
+			return nil, err
+		}
+	}
+	path, cleanup, err := s.ocrInputPath(a.Path, a.Denoise, a.Normalize, false)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	result, err := ocr.DetectTextRegions(path, a.MinConfidence)
+	if err != nil {
+		return nil, err
+	}
+	tagTextStyles(img, result.Regions)
+	if mask == nil {
+		return result, nil
+	}
+
+	// Keep the regions centered in the mask, like the shape detectors
+	kept := result.Regions[:0]
+	for _, region := range result.Regions {
+		b := region.Bounds
+		if mask.AlphaAt((b.X1+b.X2)/2, (b.Y1+b.Y2)/2).A != 0 {
+			kept = append(kept, region)
+		}
+	}
+	result.Regions, result.Count = kept, len(kept)
+	return result, nil
+}
+
+// tagTextStyles classifies each text region of img as printed or
+// handwritten (see detection.ClassifyTextStyle), so callers know where
+// OCR will struggle.
+func tagTextStyles(img image.Image, regions []ocr.TextRegionBox) {
+	for i := range regions {
+		b := regions[i].Bounds
+		style := detection.ClassifyTextStyle(img, detection.Bounds{X1: b.X1, Y1: b.Y1, X2: b.X2, Y2: b.Y2})
+		regions[i].Style, regions[i].HandwritingScore = style.Style, style.HandwritingScore
+	}
+}
+
+// ocrInputPath returns a path that the OCR engine can read for the given
+// image. Raster formats are passed through unchanged; SVG files are
+// rasterized via the cache and written to a temporary PNG, as are images
+// denoised or normalized first (see preprocessInput), and pages cleaned
+// up with the image_scan_cleanup defaults after that if scanCleanup is
+// set. The returned cleanup function removes any temporary file and must
+// always be called.
+func (s *Server) ocrInputPath(path, denoise string, normalize, scanCleanup bool) (string, func(), error) {
+	if !imaging.IsSVG(path) && denoise == "" && !normalize && !scanCleanup {
+		return path, func() {}, nil
+	}
+	img, err := s.cache.Load(path)
+	if err != nil {
+		return "", nil, err
+	}
+	if img, err = preprocessInput(img, denoise, normalize); err != nil {
+		return "", nil, err
+	}
+	if scanCleanup {
+		if img, _, err = imaging.ScanCleanupImage(img, defaultScanCleanupOptions); err != nil {
+			return "", nil, err
+		}
+	}
+	tmp, err := ocr.SaveImageToTemp(img, "ocr-input")
+	if err != nil {
+		return "", nil, err
+	}
+	return tmp, func() { os.Remove(tmp) }, nil
+}
+
+// preprocessInput prepares img for the tools that take denoise and
+// normalize arguments: it is denoised with the denoise method at strength
+// 1 (see imaging.DenoiseImage), if any, then white balanced and its
+// exposure normalized with the image_normalize defaults, if normalize is
+// set. Denoising comes first, so the stretch does not amplify the noise
+// it estimates. img itself is returned if neither is requested.
+func preprocessInput(img image.Image, denoise string, normalize bool) (image.Image, error) {
+	if denoise != "" {
+		out, _, err := imaging.DenoiseImage(img, denoise, 1)
+		if err != nil {
+			return nil, err
+		}
+		img = out
+	}
+	if normalize {
+		out, _, err := imaging.NormalizeImage(img, defaultNormalizeOptions)
+		if err != nil {
+			return nil, err
+		}
+		img = out
+	}
+	return img, nil
+}
+
+type imageRedactArgs struct {
+	Path      string           `json:"path"`
+	Regions   []imaging.Region `json:"regions"`
+	Patterns  []string         `json:"patterns"`
+	Style     string           `json:"style"`
+	BlockSize int              `json:"block_size"`
+	Padding   *int             `json:"padding"`
+	Language  string           `json:"language"`
+}
+
+func (s *Server) handleImageRedact(args json.RawMessage) (interface{}, error) {
+	var a imageRedactArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if len(a.Regions) == 0 && len(a.Patterns) == 0 {
+		return nil, fmt.Errorf("at least one of regions or patterns is required")
+	}
+	if a.Style == "" {
+		a.Style = imaging.RedactBlack
+	}
+	if a.BlockSize == 0 {
+		a.BlockSize = 12
+	}
+	padding := 2
+	if a.Padding != nil {
+		padding = *a.Padding
+	}
+	if a.Language == "" {
+		a.Language = "eng"
+	}
+	// Compile the patterns before running OCR, so a bad one fails fast
+	patterns, err := compileRedactPatterns(a.Patterns)
+	if err != nil {
+		return nil, err
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	redactions := make([]imaging.Redaction, 0, len(a.Regions))
+	for _, r := range a.Regions {
+		redactions = append(redactions, imaging.Redaction{Bounds: r, Source: "region"})
+	}
+	if len(patterns) > 0 {
+		// An OCR failure is an error rather than an image with only the
+		// explicit regions hidden, which could be mistaken as safe to share
+		path, cleanup, err := s.ocrInputPath(a.Path, "", false, false)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		text, err := ocr.ExtractText(path, a.Language)
+		if err != nil {
+			return nil, fmt.Errorf("OCR for patterns failed: %w", err)
+		}
+		redactions = append(redactions, matchRedactPatterns(text.Regions, patterns)...)
+	}
+	return imaging.Redact(img, redactions, a.Style, a.BlockSize, padding)
+}
+
+type imageDetectPIIArgs struct {
+	Path       string   `json:"path"`
+	Categories []string `json:"categories"`
+	Language   string   `json:"language"`
+}
+
+func (s *Server) handleImageDetectPII(args json.RawMessage) (interface{}, error) {
+	var a imageDetectPIIArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.Language == "" {
+		a.Language = "eng"
+	}
+	categories, err := parsePIICategories(a.Categories)
+	if err != nil {
+		return nil, err
+	}
+	path, cleanup, err := s.ocrInputPath(a.Path, "", false, false)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	text, err := ocr.ExtractText(path, a.Language)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := findPII(text.Regions, categories)
+	result := &PIIResult{
+		Matches:      matches,
+		Count:        len(matches),
+		ByCategory:   make(map[string]int, len(categories)),
+		WordsScanned: len(text.Regions),
+	}
+	if result.Matches == nil {
+		result.Matches = []PIIMatch{}
+	}
+	for c := range categories {
+		result.ByCategory[c] = 0
+	}
+	for _, m := range matches {
+		result.ByCategory[m.Category]++
+	}
+	return result, nil
+}
+
+type imageExtractFieldsArgs struct {
+	Path     string         `json:"path"`
+	Fields   []FieldRequest `json:"fields"`
+	Language string         `json:"language"`
+}
+
+func (s *Server) handleImageExtractFields(args json.RawMessage) (interface{}, error) {
+	var a imageExtractFieldsArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.Language == "" {
+		a.Language = "eng"
+	}
+	fields, err := compileFields(a.Fields)
+	if err != nil {
+		return nil, err
+	}
+	path, cleanup, err := s.ocrInputPath(a.Path, "", false, false)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	text, err := ocr.ExtractText(path, a.Language)
+	if err != nil {
+		return nil, err
+	}
+	return extractFields(text.Regions, fields), nil
+}
+
+type imageAnalyzeFormArgs struct {
+	Path     string `json:"path"`
+	MinArea  int    `json:"min_area"`
+	Language string `json:"language"`
+}
+
+func (s *Server) handleImageAnalyzeForm(args json.RawMessage) (interface{}, error) {
+	var a imageAnalyzeFormArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.MinArea == 0 {
+		a.MinArea = 64
+	}
+	if a.Language == "" {
+		a.Language = "eng"
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	rects, err := detection.DetectRectangles(img, a.MinArea, 0.9)
+	if err != nil {
+		return nil, err
+	}
+	path, cleanup, err := s.ocrInputPath(a.Path, "", false, false)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	text, err := ocr.ExtractText(path, a.Language)
+	if err != nil {
+		return nil, err
+	}
+	return analyzeForm(img, rects.Rectangles, text.Regions), nil
+}
+
+type imageToMermaidArgs struct {
+	Path        string `json:"path"`
+	Threshold   int    `json:"threshold"`
+	MinNodeArea int    `json:"min_node_area"`
+	Direction   string `json:"direction"`
+	Language    string `json:"language"`
+}
+
+func (s *Server) handleImageToMermaid(args json.RawMessage) (interface{}, error) {
+	var a imageToMermaidArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	switch a.Direction {
+	case "", "auto", "TD", "LR":
+	default:
+		return nil, fmt.Errorf("direction must be \"auto\", \"TD\", or \"LR\", got %q", a.Direction)
+	}
+	if a.MinNodeArea == 0 {
+		a.MinNodeArea = 200
+	}
+	if a.Language == "" {
+		a.Language = "eng"
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	chart, err := detection.ExtractFlowchart(img, detection.FlowchartOptions{Threshold: a.Threshold, MinNodeArea: a.MinNodeArea})
+	if err != nil {
+		return nil, err
+	}
+	path, cleanup, err := s.ocrInputPath(a.Path, "", false, false)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	text, err := ocr.ExtractText(path, a.Language)
+	if err != nil {
+		return nil, err
+	}
+	return flowchartToMermaid(chart, text.Regions, a.Direction), nil
+}
+
+type imageExtractOrgChartArgs struct {
+	Path        string `json:"path"`
+	Threshold   int    `json:"threshold"`
+	MinNodeArea int    `json:"min_node_area"`
+	TitleFirst  bool   `json:"title_first"`
+	Language    string `json:"language"`
+}
+
+func (s *Server) handleImageExtractOrgChart(args json.RawMessage) (interface{}, error) {
+	var a imageExtractOrgChartArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.MinNodeArea == 0 {
+		a.MinNodeArea = 200
+	}
+	if a.Language == "" {
+		a.Language = "eng"
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	chart, err := detection.ExtractFlowchart(img, detection.FlowchartOptions{Threshold: a.Threshold, MinNodeArea: a.MinNodeArea})
+	if err != nil {
+		return nil, err
+	}
+	// Each box is read on its own, so names in neighboring boxes are not
+	// run together
+	words := make([][]ocr.TextRegion, len(chart.Nodes))
+	for i, n := range chart.Nodes {
+		text, err := ocr.ExtractTextFromRegion(img, n.Inner.X1, n.Inner.Y1, n.Inner.X2, n.Inner.Y2, a.Language)
+		if err != nil {
+			return nil, err
+		}
+		words[i] = text.Regions
+	}
+	return orgChart(chart, words, a.TitleFirst), nil
+}
+
+type imageExtractTopologyArgs struct {
+	Path        string            `json:"path"`
+	Threshold   int               `json:"threshold"`
+	MinNodeSize int               `json:"min_node_size"`
+	Templates   []imageSourceArgs `json:"templates"`
+	Language    string            `json:"language"`
+}
+
+func (s *Server) handleImageExtractTopology(args json.RawMessage) (interface{}, error) {
+	var a imageExtractTopologyArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.MinNodeSize == 0 {
+		a.MinNodeSize = 16
+	}
+	if a.Language == "" {
+		a.Language = "eng"
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Templates are named by their labels, not their file names
+	templates := make([]imaging.NetworkTemplate, len(a.Templates))
+	for i, src := range a.Templates {
+		if src.Label == "" {
+			return nil, fmt.Errorf("template %d needs a label", i)
+		}
+		t, label, err := s.loadImageSource(src)
+		if err != nil {
+			return nil, err
+		}
+		templates[i] = imaging.NetworkTemplate{Label: label, Image: t}
+	}
+	graph, err := imaging.ExtractTopology(img, imaging.TopologyOptions{
+		Threshold:   a.Threshold,
+		MinNodeSize: a.MinNodeSize,
+		Templates:   templates,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// A diagram without devices has nothing to label
+	if len(graph.Nodes) == 0 {
+		return networkTopology(graph, nil), nil
+	}
+	path, cleanup, err := s.ocrInputPath(a.Path, "", false, false)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	text, err := ocr.ExtractText(path, a.Language)
+	if err != nil {
+		return nil, err
+	}
+	return networkTopology(graph, text.Regions), nil
+}
+
+type imageTextDiffArgs struct {
+	PathA         string  `json:"path_a"`
+	PathB         string  `json:"path_b"`
+	Language      string  `json:"language"`
+	MinConfidence float64 `json:"min_confidence"`
+	IgnoreCase    bool    `json:"ignore_case"`
+}
+
+func (s *Server) handleImageTextDiff(args json.RawMessage) (interface{}, error) {
+	var a imageTextDiffArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.Language == "" {
+		a.Language = "eng"
+	}
+	// The images are read one at a time, as ocrInputPath reuses its
+	// temporary file
+	words := func(path string) ([]ocr.TextRegion, error) {
+		path, cleanup, err := s.ocrInputPath(path, "", false, false)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		result, err := ocr.ExtractTextWithOptions(path, ocr.Options{Language: a.Language, MinConfidence: a.MinConfidence})
+		if err != nil {
+			return nil, err
+		}
+		ocr.ApplyReadingOrder(result)
+		return result.Regions, nil
+	}
+	wordsA, err := words(a.PathA)
+	if err != nil {
+		return nil, err
+	}
+	wordsB, err := words(a.PathB)
+	if err != nil {
+		return nil, err
+	}
+	return diffText(wordsA, wordsB, a.IgnoreCase)
+}
+
+type imageReadDisplayArgs struct {
+	Path     string          `json:"path"`
+	Region   *imaging.Region `json:"region,omitempty"`
+	Kind     string          `json:"kind"`
+	Polarity string          `json:"polarity"`
+	Letters  bool            `json:"letters"`
+}
+
+func (s *Server) handleImageReadDisplay(args json.RawMessage) (interface{}, error) {
+	var a imageReadDisplayArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.ReadDisplay(img, imaging.DisplayOptions{
+		Region:   a.Region,
+		Kind:     a.Kind,
+		Polarity: a.Polarity,
+		Letters:  a.Letters,
+	})
+}
+
+// === Shape Detection Handlers ===
+
+type imageDetectRectanglesArgs struct {
+	Path         string        `json:"path"`
+	MinArea      int           `json:"min_area"`
+	Tolerance    float64       `json:"tolerance"`
+	AutoTune     bool          `json:"auto_tune"`
+	Debug        bool          `json:"debug"`
+	ExportFormat string        `json:"export_format"`
+	Mask         *imaging.Mask `json:"mask"`
+	Denoise      string        `json:"denoise"`
+	Normalize    bool          `json:"normalize"`
+}
+
+func (s *Server) handleImageDetectRectangles(args json.RawMessage) (interface{}, error) {
+	var a imageDetectRectanglesArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.MinArea == 0 {
+		a.MinArea = 100
+	}
+	if a.Tolerance == 0 {
+		a.Tolerance = 0.9
+	}
+	if err := checkExportFormat(a.ExportFormat); err != nil {
+		return nil, err
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	if img, err = preprocessInput(img, a.Denoise, a.Normalize); err != nil {
+		return nil, err
+	}
+	mask, err := rasterizeMask(a.Mask, img.Bounds())
+	if err != nil {
+		return nil, err
+	}
+	detect := detection.DetectRectangles
+	if a.Debug {
+		detect = detection.DetectRectanglesDebug
+	}
+	var tuning *detection.TuningReport
+	if a.AutoTune {
+		var params detection.RectangleParams
+		params, tuning = detection.TuneRectangles(img)
+		if tuning.Tuned {
+			a.MinArea, a.Tolerance = params.MinArea, params.Tolerance
+		}
+	}
+	result, err := detect(img, a.MinArea, a.Tolerance)
+	if err != nil {
+		return nil, err
+	}
+	result.Tuning = tuning
+	if mask != nil {
+		result.Restrict(mask)
+	}
+	result.Export, err = exportDetections(a.ExportFormat, a.Path, img, detection.CategoryRectangle, result.Primitives())
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type imageDetectLinesArgs struct {
+	Path         string        `json:"path"`
+	MinLength    int           `json:"min_length"`
+	DetectArrows bool          `json:"detect_arrows"`
+	AutoTune     bool          `json:"auto_tune"`
+	Debug        bool          `json:"debug"`
+	ExportFormat string        `json:"export_format"`
+	Mask         *imaging.Mask `json:"mask"`
+	Denoise      string        `json:"denoise"`
+	Normalize    bool          `json:"normalize"`
+}
+
+func (s *Server) handleImageDetectLines(args json.RawMessage) (interface{}, error) {
+	var a imageDetectLinesArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.MinLength == 0 {
+		a.MinLength = 20
+	}
+	if err := checkExportFormat(a.ExportFormat); err != nil {
+		return nil, err
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	if img, err = preprocessInput(img, a.Denoise, a.Normalize); err != nil {
+		return nil, err
+	}
+	mask, err := rasterizeMask(a.Mask, img.Bounds())
+	if err != nil {
+		return nil, err
+	}
+	detect := detection.DetectLines
+	if a.Debug {
+		detect = detection.DetectLinesDebug
+	}
+	var tuning *detection.TuningReport
+	if a.AutoTune {
+		var params detection.LineParams
+		params, tuning = detection.TuneLines(img)
+		if tuning.Tuned {
+			a.MinLength = params.MinLength
+		}
+	}
+	result, err := detect(img, a.MinLength, a.DetectArrows)
+	if err != nil {
+		return nil, err
+	}
+	result.Tuning = tuning
+	if mask != nil {
+		result.Restrict(mask)
+	}
+	result.Export, err = exportDetections(a.ExportFormat, a.Path, img, detection.CategoryLine, result.Primitives())
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type imageDetectCirclesArgs struct {
+	Path         string        `json:"path"`
+	MinRadius    int           `json:"min_radius"`
+	MaxRadius    int           `json:"max_radius"`
+	AutoTune     bool          `json:"auto_tune"`
+	Debug        bool          `json:"debug"`
+	ExportFormat string        `json:"export_format"`
+	Mask         *imaging.Mask `json:"mask"`
+	Denoise      string        `json:"denoise"`
+	Normalize    bool          `json:"normalize"`
+}
+
+func (s *Server) handleImageDetectCircles(args json.RawMessage) (interface{}, error) {
+	var a imageDetectCirclesArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.MinRadius == 0 {
+		a.MinRadius = 5
+	}
+	if a.MaxRadius == 0 {
+		a.MaxRadius = 500
+	}
+	if err := checkExportFormat(a.ExportFormat); err != nil {
+		return nil, err
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	if img, err = preprocessInput(img, a.Denoise, a.Normalize); err != nil {
+		return nil, err
+	}
+	mask, err := rasterizeMask(a.Mask, img.Bounds())
+	if err != nil {
+		return nil, err
+	}
+	detect := detection.DetectCircles
+	if a.Debug {
+		detect = detection.DetectCirclesDebug
+	}
+	var tuning *detection.TuningReport
+	if a.AutoTune {
+		var params detection.CircleParams
+		params, tuning = detection.TuneCircles(img)
+		if tuning.Tuned {
+			a.MinRadius, a.MaxRadius = params.MinRadius, params.MaxRadius
+		}
+	}
+	result, err := detect(img, a.MinRadius, a.MaxRadius)
+	if err != nil {
+		return nil, err
+	}
+	result.Tuning = tuning
+	if mask != nil {
+		result.Restrict(mask)
+	}
+	result.Export, err = exportDetections(a.ExportFormat, a.Path, img, detection.CategoryCircle, result.Primitives())
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// rasterizeMask renders an optional mask argument over bounds; without a
+// mask it returns nil, which selects everything.
+func rasterizeMask(m *imaging.Mask, bounds image.Rectangle) (*image.Alpha, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return m.Rasterize(bounds)
+}
+
+// checkExportFormat validates an export_format argument; "" asks for no
+// export.
+func checkExportFormat(format string) error {
+	if format == "" || slices.Contains(detection.ExportFormats, format) {
+		return nil
+	}
+	return fmt.Errorf("export_format must be one of %s, got %q", strings.Join(detection.ExportFormats, ", "), format)
+}
+
+// exportDetections returns the detections, as primitives of one category,
+// in the annotation format requested for the image at path, or nil if no
+// format was requested.
+func exportDetections(format, path string, img image.Image, category string, prims []detection.Primitive) (*detection.AnnotationExport, error) {
+	if format == "" {
+		return nil, nil
+	}
+	b := img.Bounds()
+	return detection.ExportAnnotations(format, filepath.Base(path), b.Dx(), b.Dy(), detection.Annotations(category, prims))
+}
+
+type imageEdgeDetectArgs struct {
+	Path          string `json:"path"`
+	ThresholdLow  int    `json:"threshold_low"`
+	ThresholdHigh int    `json:"threshold_high"`
+}
+
+func (s *Server) handleImageEdgeDetect(args json.RawMessage) (interface{}, error) {
+	var a imageEdgeDetectArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.ThresholdLow == 0 {
+		a.ThresholdLow = 50
+	}
+	if a.ThresholdHigh == 0 {
+		a.ThresholdHigh = 150
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.EdgeDetect(img, a.ThresholdLow, a.ThresholdHigh)
+}
+
+type imageSkeletonizeArgs struct {
+	Path      string `json:"path"`
+	Threshold int    `json:"threshold"`
+	Invert    bool   `json:"invert"`
+	Graph     bool   `json:"graph"`
+}
+
+func (s *Server) handleImageSkeletonize(args json.RawMessage) (interface{}, error) {
+	var a imageSkeletonizeArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.Skeletonize(img, a.Threshold, a.Invert, a.Graph)
+}
+
+type imageDetectIncrementalArgs struct {
+	Path          string  `json:"path"`
+	PreviousPath  string  `json:"previous_path"`
+	MinArea       int     `json:"min_area"`
+	Tolerance     float64 `json:"tolerance"`
+	MinConfidence float64 `json:"min_confidence"`
+}
+
+func (s *Server) handleImageDetectIncremental(args json.RawMessage) (interface{}, error) {
+	var a imageDetectIncrementalArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.MinArea == 0 {
+		a.MinArea = 100
+	}
+	if a.Tolerance == 0 {
+		a.Tolerance = 0.9
+	}
+	if a.MinConfidence == 0 {
+		a.MinConfidence = 0.5
+	}
+	opts := detection.IncrementalOptions{
+		MinArea:       a.MinArea,
+		Tolerance:     a.Tolerance,
+		MinConfidence: a.MinConfidence,
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update the previous image's snapshot if there is a usable one;
+	// otherwise analyze the whole image
+	if a.PreviousPath != "" {
+		prev := s.snapshot(a.PreviousPath, opts)
+		if prev != nil {
+			prevImg, err := s.cache.Load(a.PreviousPath)
+			if err != nil {
+				return nil, err
+			}
+			if prevImg.Bounds().Size() == img.Bounds().Size() {
+				dirty, err := detection.ChangedRegions(prevImg, img)
+				if err != nil {
+					return nil, err
+				}
+				snap, stats, err := prev.Update(img, dirty)
+				if err != nil {
+					return nil, err
+				}
+				s.storeSnapshot(a.Path, snap)
+				return &detection.IncrementalResult{
+					Rectangles:  snap.Rectangles(),
+					TextRegions: snap.TextRegions(),
+					Incremental: true,
+					Stats:       stats,
+				}, nil
+			}
+		}
+	}
+
+	snap := detection.NewSnapshot(img, opts)
+	s.storeSnapshot(a.Path, snap)
+	return &detection.IncrementalResult{
+		Rectangles:  snap.Rectangles(),
+		TextRegions: snap.TextRegions(),
+	}, nil
+}
+
+// snapshot returns the cached detection snapshot for path if it was built
+// with the same options, or nil.
+func (s *Server) snapshot(path string, opts detection.IncrementalOptions) *detection.Snapshot {
+	s.snapshotMu.Lock()
+	defer s.snapshotMu.Unlock()
+	snap := s.snapshots[path]
+	if snap == nil || snap.Options() != opts {
+		return nil
+	}
+	return snap
+}
+
+// storeSnapshot caches the detection snapshot for path, dropping the
+// oldest snapshot once more than maxSnapshots are held.
+func (s *Server) storeSnapshot(path string, snap *detection.Snapshot) {
+	s.snapshotMu.Lock()
+	defer s.snapshotMu.Unlock()
+	if _, ok := s.snapshots[path]; ok {
+		for i, p := range s.snapshotOrder {
+			if p == path {
+				s.snapshotOrder = append(s.snapshotOrder[:i], s.snapshotOrder[i+1:]...)
+				break
+			}
+		}
+	}
+	s.snapshots[path] = snap
+	s.snapshotOrder = append(s.snapshotOrder, path)
+	if len(s.snapshotOrder) > maxSnapshots {
+		delete(s.snapshots, s.snapshotOrder[0])
+		s.snapshotOrder = s.snapshotOrder[1:]
+	}
+}
+
+type imageLineIntersectionsArgs struct {
+	Path      string                `json:"path"`
+	Lines     []imaging.LineSegment `json:"lines"`
+	MinLength int                   `json:"min_length"`
+	Tolerance *float64              `json:"tolerance"`
+	MinAngle  float64               `json:"min_angle"`
+}
+
+func (s *Server) handleImageLineIntersections(args json.RawMessage) (interface{}, error) {
+	var a imageLineIntersectionsArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.MinLength == 0 {
+		a.MinLength = 20
+	}
+	if a.MinAngle == 0 {
+		a.MinAngle = 10
+	}
+	opts := detection.IntersectionOptions{Tolerance: 5, MinAngle: a.MinAngle}
+	if a.Tolerance != nil {
+		opts.Tolerance = *a.Tolerance
+	}
+
+	var lines []detection.Line
+	if len(a.Lines) > 0 {
+		for _, l := range a.Lines {
+			lines = append(lines, detection.Line{
+				Start: detection.Point{X: l.X1, Y: l.Y1},
+				End:   detection.Point{X: l.X2, Y: l.Y2},
+			})
+		}
+	} else {
+		img, err := s.cache.Load(a.Path)
+		if err != nil {
+			return nil, err
+		}
+		detected, err := detection.DetectLines(img, a.MinLength, false)
+		if err != nil {
+			return nil, err
+		}
+		lines = detected.Lines
+	}
+	return detection.FindIntersections(lines, opts)
+}
+
+// === Analysis Helper Handlers ===
+
+type imageCheckAlignmentArgs struct {
+	Path      string `json:"path"`
+	Points    []struct {
+		X int `json:"x"`
+		Y int `json:"y"`
+	} `json:"points"`
+	Tolerance int `json:"tolerance"`
+}
+
+func (s *Server) handleImageCheckAlignment(args json.RawMessage) (interface{}, error) {
+	var a imageCheckAlignmentArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.Tolerance == 0 {
+		a.Tolerance = 5
+	}
+
+	points := make([]imaging.Point, len(a.Points))
 	for i, p := range a.Points {
 		points[i] = imaging.Point{X: p.X, Y: p.Y}
 	}
-	return imaging.CheckAlignment(points, a.Tolerance)
+	return imaging.CheckAlignment(points, a.Tolerance)
+}
+
+type imageCompareRegionsArgs struct {
+	Path    string `json:"path"`
+	Region1 struct {
+		X1 int `json:"x1"`
+		Y1 int `json:"y1"`
+		X2 int `json:"x2"`
+		Y2 int `json:"y2"`
+	} `json:"region1"`
+	Region2 struct {
+		X1 int `json:"x1"`
+		Y1 int `json:"y1"`
+		X2 int `json:"x2"`
+		Y2 int `json:"y2"`
+	} `json:"region2"`
+	Mask       *imaging.Mask `json:"mask"`
+	AlphaMode  string        `json:"alpha_mode"`
+	Background string        `json:"background"`
+	Method     string        `json:"method"`
+}
+
+func (s *Server) handleImageCompareRegions(args json.RawMessage) (interface{}, error) {
+	var a imageCompareRegionsArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	r1 := imaging.Region{X1: a.Region1.X1, Y1: a.Region1.Y1, X2: a.Region1.X2, Y2: a.Region1.Y2}
+	r2 := imaging.Region{X1: a.Region2.X1, Y1: a.Region2.Y1, X2: a.Region2.X2, Y2: a.Region2.Y2}
+	alpha := imaging.AlphaOptions{Mode: a.AlphaMode, Background: a.Background}
+
+	// The mask is relative to the regions' top-left corners and covers
+	// the compared area
+	var mask *image.Alpha
+	w := min(r1.X2-r1.X1, r2.X2-r2.X1)
+	h := min(r1.Y2-r1.Y1, r2.Y2-r2.Y1)
+	if a.Mask != nil && w > 0 && h > 0 {
+		mask, err = a.Mask.Rasterize(image.Rect(0, 0, w, h))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return imaging.CompareRegionsMethod(img, r1, r2, mask, alpha, a.Method)
+}
+
+type imageAlignArgs struct {
+	Reference imageSourceArgs `json:"reference"`
+	Target    imageSourceArgs `json:"target"`
+	MaxOffset int             `json:"max_offset"`
+}
+
+func (s *Server) handleImageAlign(args json.RawMessage) (interface{}, error) {
+	var a imageAlignArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.MaxOffset == 0 {
+		a.MaxOffset = 32
+	}
+
+	reference, _, err := s.loadImageSource(a.Reference)
+	if err != nil {
+		return nil, err
+	}
+	target, _, err := s.loadImageSource(a.Target)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.Align(reference, target, a.MaxOffset)
+}
+
+type imageInferNinePatchArgs struct {
+	Path      string `json:"path"`
+	Tolerance int    `json:"tolerance"`
+}
+
+func (s *Server) handleImageInferNinePatch(args json.RawMessage) (interface{}, error) {
+	var a imageInferNinePatchArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.InferNinePatch(img, a.Tolerance)
+}
+
+type imageCheckCenteringArgs struct {
+	Path      string          `json:"path"`
+	Container *imaging.Region `json:"container,omitempty"`
+	Element   *imaging.Region `json:"element,omitempty"`
+	Tolerance *float64        `json:"tolerance"`
+}
+
+func (s *Server) handleImageCheckCentering(args json.RawMessage) (interface{}, error) {
+	var a imageCheckCenteringArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	tolerance := 1.0
+	if a.Tolerance != nil {
+		tolerance = *a.Tolerance
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.CheckCentering(img, a.Container, a.Element, tolerance)
+}
+
+type imageFindEmptyRegionsArgs struct {
+	Path       string   `json:"path"`
+	Background string   `json:"background"`
+	Tolerance  *float64 `json:"tolerance"`
+	Count      int      `json:"count"`
+	MinWidth   int      `json:"min_width"`
+	MinHeight  int      `json:"min_height"`
+}
+
+func (s *Server) handleImageFindEmptyRegions(args json.RawMessage) (interface{}, error) {
+	var a imageFindEmptyRegionsArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	tolerance := 10.0
+	if a.Tolerance != nil {
+		tolerance = *a.Tolerance
+	}
+	if a.Count == 0 {
+		a.Count = 5
+	}
+	if a.MinWidth == 0 {
+		a.MinWidth = 20
+	}
+	if a.MinHeight == 0 {
+		a.MinHeight = 20
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.FindEmptyRegions(img, a.Background, tolerance, a.Count, a.MinWidth, a.MinHeight)
+}
+
+type imageCheckOverlapsArgs struct {
+	Path  string `json:"path"`
+	Boxes []struct {
+		X1    int    `json:"x1"`
+		Y1    int    `json:"y1"`
+		X2    int    `json:"x2"`
+		Y2    int    `json:"y2"`
+		Label string `json:"label"`
+	} `json:"boxes"`
+	MinArea int `json:"min_area"`
+}
+
+func (s *Server) handleImageCheckOverlaps(args json.RawMessage) (interface{}, error) {
+	var a imageCheckOverlapsArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.MinArea == 0 {
+		a.MinArea = 100
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var boxes []detection.LabeledBox
+	if len(a.Boxes) > 0 {
+		for _, b := range a.Boxes {
+			boxes = append(boxes, detection.LabeledBox{
+				Bounds: detection.Bounds{X1: b.X1, Y1: b.Y1, X2: b.X2, Y2: b.Y2},
+				Label:  b.Label,
+			})
+		}
+	} else {
+		rects, err := detection.DetectRectangles(img, a.MinArea, 0.9)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rects.Rectangles {
+			boxes = append(boxes, detection.LabeledBox{Bounds: r.Bounds})
+		}
+	}
+	return detection.AnalyzeOverlaps(img, boxes)
+}
+
+type imageFindRepeatsArgs struct {
+	Path          string   `json:"path"`
+	MinSize       int      `json:"min_size"`
+	MergeDistance *int     `json:"merge_distance"`
+	Similarity    *float64 `json:"similarity"`
+}
+
+func (s *Server) handleImageFindRepeats(args json.RawMessage) (interface{}, error) {
+	var a imageFindRepeatsArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.MinSize == 0 {
+		a.MinSize = 8
+	}
+	mergeDistance := 4
+	if a.MergeDistance != nil {
+		mergeDistance = *a.MergeDistance
+	}
+	similarity := 0.9
+	if a.Similarity != nil {
+		similarity = *a.Similarity
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return detection.FindRepeats(img, a.MinSize, mergeDistance, similarity)
+}
+
+type imageDetectPeriodicityArgs struct {
+	Path     string          `json:"path"`
+	Region   *imaging.Region `json:"region,omitempty"`
+	MaxPeaks int             `json:"max_peaks"`
+}
+
+func (s *Server) handleImageDetectPeriodicity(args json.RawMessage) (interface{}, error) {
+	var a imageDetectPeriodicityArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.MaxPeaks == 0 {
+		a.MaxPeaks = 5
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.DetectPeriodicity(img, a.Region, a.MaxPeaks)
+}
+
+type imageDetectRowsArgs struct {
+	Path      string          `json:"path"`
+	Region    *imaging.Region `json:"region,omitempty"`
+	Direction string          `json:"direction"`
+	MinGap    int             `json:"min_gap"`
+}
+
+func (s *Server) handleImageDetectRows(args json.RawMessage) (interface{}, error) {
+	var a imageDetectRowsArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.Direction == "" {
+		a.Direction = "rows"
+	}
+	if a.MinGap == 0 {
+		a.MinGap = 3
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.DetectRows(img, a.Region, a.Direction, a.MinGap)
+}
+
+type imageSegmentRegionsArgs struct {
+	Path         string  `json:"path"`
+	Scale        float64 `json:"scale"`
+	MinSize      int     `json:"min_size"`
+	MaxRegions   int     `json:"max_regions"`
+	IncludeImage bool    `json:"include_image"`
+}
+
+func (s *Server) handleImageSegmentRegions(args json.RawMessage) (interface{}, error) {
+	var a imageSegmentRegionsArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.Scale == 0 {
+		a.Scale = 300
+	}
+	if a.MinSize == 0 {
+		a.MinSize = 50
+	}
+	if a.MaxRegions == 0 {
+		a.MaxRegions = 50
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.SegmentRegions(img, a.Scale, a.MinSize, a.MaxRegions, a.IncludeImage)
+}
+
+type imageClassifyContentArgs struct {
+	Path      string `json:"path"`
+	Quadrants bool   `json:"quadrants"`
+}
+
+func (s *Server) handleImageClassifyContent(args json.RawMessage) (interface{}, error) {
+	var a imageClassifyContentArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.ClassifyContent(img, a.Quadrants)
+}
+
+type imageIsBlankArgs struct {
+	Path      string   `json:"path"`
+	MaxStdDev *float64 `json:"max_std_dev"`
+}
+
+func (s *Server) handleImageIsBlank(args json.RawMessage) (interface{}, error) {
+	var a imageIsBlankArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	maxStdDev := 3.0
+	if a.MaxStdDev != nil {
+		maxStdDev = *a.MaxStdDev
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.IsBlank(img, maxStdDev)
+}
+
+type imageDetectArtifactsArgs struct {
+	Path string `json:"path"`
+}
+
+func (s *Server) handleImageDetectArtifacts(args json.RawMessage) (interface{}, error) {
+	var a imageDetectArtifactsArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.DetectArtifacts(img)
+}
+
+type imageDetectMoireArgs struct {
+	Path        string  `json:"path"`
+	TileSize    int     `json:"tile_size"`
+	MinStrength float64 `json:"min_strength"`
+}
+
+func (s *Server) handleImageDetectMoire(args json.RawMessage) (interface{}, error) {
+	var a imageDetectMoireArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.TileSize == 0 {
+		a.TileSize = 64
+	}
+	if a.MinStrength == 0 {
+		a.MinStrength = 0.25
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.DetectMoire(img, a.TileSize, a.MinStrength)
+}
+
+type imageInspectChannelsArgs struct {
+	Path          string   `json:"path"`
+	Channels      []string `json:"channels"`
+	Bits          []int    `json:"bits"`
+	IncludeImages *bool    `json:"include_images"`
+}
+
+func (s *Server) handleImageInspectChannels(args json.RawMessage) (interface{}, error) {
+	var a imageInspectChannelsArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.Bits == nil {
+		a.Bits = []int{0}
+	}
+	includeImages := true
+	if a.IncludeImages != nil {
+		includeImages = *a.IncludeImages
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.InspectChannels(img, a.Channels, a.Bits, includeImages)
+}
+
+type imageNearDuplicateArgs struct {
+	PathA       string   `json:"path_a"`
+	PathB       string   `json:"path_b"`
+	MaxDistance *int     `json:"max_distance"`
+	MaxMeanDiff *float64 `json:"max_mean_diff"`
+}
+
+func (s *Server) handleImageNearDuplicate(args json.RawMessage) (interface{}, error) {
+	var a imageNearDuplicateArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	maxDistance := 5
+	if a.MaxDistance != nil {
+		maxDistance = *a.MaxDistance
+	}
+	maxMeanDiff := 8.0
+	if a.MaxMeanDiff != nil {
+		maxMeanDiff = *a.MaxMeanDiff
+	}
+	imgA, err := s.cache.Load(a.PathA)
+	if err != nil {
+		return nil, err
+	}
+	imgB, err := s.cache.Load(a.PathB)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.NearDuplicate(imgA, imgB, maxDistance, maxMeanDiff)
+}
+
+type imageGenerateReportArgs struct {
+	Path       string `json:"path"`
+	ColorCount int    `json:"color_count"`
+	MaxItems   *int   `json:"max_items"`
+	Markdown   bool   `json:"markdown"`
+}
+
+func (s *Server) handleImageGenerateReport(args json.RawMessage) (interface{}, error) {
+	var a imageGenerateReportArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.ColorCount == 0 {
+		a.ColorCount = 5
+	}
+	maxItems := 10
+	if a.MaxItems != nil {
+		maxItems = *a.MaxItems
+	}
+	if maxItems < 0 {
+		return nil, fmt.Errorf("max_items must be non-negative, got %d", maxItems)
+	}
+	info, err := imaging.LoadImageInfo(s.cache, a.Path)
+	if err != nil {
+		return nil, err
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	report := buildReport(img, info, a.ColorCount, maxItems)
+	if a.Markdown {
+		report.Markdown = renderReportMarkdown(filepath.Base(a.Path), report)
+	}
+	return report, nil
+}
+
+type imageAccessibilityAuditArgs struct {
+	Path          string  `json:"path"`
+	Level         string  `json:"level"`
+	Scale         float64 `json:"scale"`
+	MinTextSize   float64 `json:"min_text_size"`
+	MinTargetSize float64 `json:"min_target_size"`
+	MaxIssues     *int    `json:"max_issues"`
+}
+
+func (s *Server) handleImageAccessibilityAudit(args json.RawMessage) (interface{}, error) {
+	var a imageAccessibilityAuditArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.Level == "" {
+		a.Level = "AA"
+	}
+	if a.Level != "AA" && a.Level != "AAA" {
+		return nil, fmt.Errorf("invalid level %q: must be AA or AAA", a.Level)
+	}
+	if a.Scale == 0 {
+		a.Scale = 1
+	}
+	if a.MinTextSize == 0 {
+		a.MinTextSize = 12
+	}
+	if a.MinTargetSize == 0 {
+		a.MinTargetSize = 44
+	}
+	if a.Scale < 0 || a.MinTextSize < 0 || a.MinTargetSize < 0 {
+		return nil, fmt.Errorf("scale, min_text_size, and min_target_size must be positive")
+	}
+	maxIssues := 50
+	if a.MaxIssues != nil {
+		maxIssues = *a.MaxIssues
+	}
+	if maxIssues < 0 {
+		return nil, fmt.Errorf("max_issues must be non-negative, got %d", maxIssues)
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return buildAccessibilityAudit(img, accessibilityOptions{
+		level:         a.Level,
+		scale:         a.Scale,
+		minTextSize:   a.MinTextSize,
+		minTargetSize: a.MinTargetSize,
+		maxIssues:     maxIssues,
+	}), nil
+}
+
+type imageDenoiseArgs struct {
+	Path     string  `json:"path"`
+	Method   string  `json:"method"`
+	Strength float64 `json:"strength"`
+}
+
+func (s *Server) handleImageDenoise(args json.RawMessage) (interface{}, error) {
+	var a imageDenoiseArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.Method == "" {
+		a.Method = imaging.DenoiseBilateral
+	}
+	if a.Strength == 0 {
+		a.Strength = 1
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.Denoise(img, a.Method, a.Strength)
+}
+
+type imageNormalizeArgs struct {
+	Path         string   `json:"path"`
+	WhiteBalance *bool    `json:"white_balance"`
+	Exposure     *bool    `json:"exposure"`
+	ClipPercent  *float64 `json:"clip_percent"`
+}
+
+// defaultNormalizeOptions are the image_normalize defaults, also used by
+// the normalize argument of the OCR and shape detection tools.
+var defaultNormalizeOptions = imaging.NormalizeOptions{
+	WhiteBalance: true,
+	Exposure:     true,
+	ClipPercent:  1,
+}
+
+func (s *Server) handleImageNormalize(args json.RawMessage) (interface{}, error) {
+	var a imageNormalizeArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	opts := defaultNormalizeOptions
+	if a.WhiteBalance != nil {
+		opts.WhiteBalance = *a.WhiteBalance
+	}
+	if a.Exposure != nil {
+		opts.Exposure = *a.Exposure
+	}
+	if a.ClipPercent != nil {
+		opts.ClipPercent = *a.ClipPercent
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.Normalize(img, opts)
+}
+
+type imageScanCleanupArgs struct {
+	Path          string `json:"path"`
+	Perspective   *bool  `json:"perspective"`
+	RemoveShadows *bool  `json:"remove_shadows"`
+	Deskew        *bool  `json:"deskew"`
+	Binarize      *bool  `json:"binarize"`
+	Window        int    `json:"window"`
+}
+
+// defaultScanCleanupOptions are the image_scan_cleanup defaults, also used
+// by the scan_cleanup argument of image_ocr_full.
+var defaultScanCleanupOptions = imaging.ScanCleanupOptions{
+	Perspective:   true,
+	RemoveShadows: true,
+	Deskew:        true,
+	Binarize:      true,
+	Window:        31,
+}
+
+func (s *Server) handleImageScanCleanup(args json.RawMessage) (interface{}, error) {
+	var a imageScanCleanupArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	opts := defaultScanCleanupOptions
+	if a.Perspective != nil {
+		opts.Perspective = *a.Perspective
+	}
+	if a.RemoveShadows != nil {
+		opts.RemoveShadows = *a.RemoveShadows
+	}
+	if a.Deskew != nil {
+		opts.Deskew = *a.Deskew
+	}
+	if a.Binarize != nil {
+		opts.Binarize = *a.Binarize
+	}
+	if a.Window != 0 {
+		opts.Window = a.Window
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.ScanCleanup(img, opts)
+}
+
+type imageCleanWhiteboardArgs struct {
+	Path      string  `json:"path"`
+	Threshold int     `json:"threshold"`
+	Snap      *bool   `json:"snap"`
+	Tolerance float64 `json:"tolerance"`
+}
+
+func (s *Server) handleImageCleanWhiteboard(args json.RawMessage) (interface{}, error) {
+	var a imageCleanWhiteboardArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	opts := imaging.WhiteboardOptions{Threshold: a.Threshold, Snap: a.Snap == nil || *a.Snap, Tolerance: a.Tolerance}
+	if opts.Tolerance == 0 {
+		opts.Tolerance = 3
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.CleanWhiteboard(img, opts)
+}
+
+type imageDetectFormulaRegionsArgs struct {
+	Path          string  `json:"path"`
+	MinConfidence float64 `json:"min_confidence"`
+	CheckWords    *bool   `json:"check_words"`
+	Language      string  `json:"language"`
+}
+
+func (s *Server) handleImageDetectFormulaRegions(args json.RawMessage) (interface{}, error) {
+	var a imageDetectFormulaRegionsArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.MinConfidence == 0 {
+		a.MinConfidence = 0.5
+	}
+	if a.Language == "" {
+		a.Language = "eng"
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	opts := detection.FormulaOptions{MinConfidence: a.MinConfidence}
+	// Without Tesseract the regions are scored on their shapes alone
+	if (a.CheckWords == nil || *a.CheckWords) && ocr.GetOCRInfo().Available {
+		opts.Words = func(b detection.Bounds) []string {
+			result, err := ocr.ExtractTextFromRegion(img, b.X1, b.Y1, b.X2, b.Y2, a.Language)
+			if err != nil {
+				return nil
+			}
+			return strings.Fields(result.FullText)
+		}
+	}
+	return detection.DetectFormulaRegions(img, opts)
+}
+
+type imageDetectWindowChromeArgs struct {
+	Path         string  `json:"path"`
+	Platform     string  `json:"platform"`
+	Scale        float64 `json:"scale"`
+	IncludeImage bool    `json:"include_image"`
+}
+
+func (s *Server) handleImageDetectWindowChrome(args json.RawMessage) (interface{}, error) {
+	var a imageDetectWindowChromeArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.DetectWindowChrome(img, imaging.WindowChromeOptions{
+		Platform:     a.Platform,
+		Scale:        a.Scale,
+		IncludeImage: a.IncludeImage,
+	})
+}
+
+type imageDetectViewportArgs struct {
+	Path string `json:"path"`
+}
+
+func (s *Server) handleImageDetectViewport(args json.RawMessage) (interface{}, error) {
+	var a imageDetectViewportArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.DetectViewport(img), nil
+}
+
+type imageStripDeviceChromeArgs struct {
+	Path          string  `json:"path"`
+	Platform      string  `json:"platform"`
+	Scale         float64 `json:"scale"`
+	StatusBar     *bool   `json:"status_bar"`
+	NavigationBar *bool   `json:"navigation_bar"`
+}
+
+func (s *Server) handleImageStripDeviceChrome(args json.RawMessage) (interface{}, error) {
+	var a imageStripDeviceChromeArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	opts := imaging.DeviceChromeOptions{
+		Platform:      a.Platform,
+		Scale:         a.Scale,
+		StatusBar:     true,
+		NavigationBar: true,
+	}
+	if a.StatusBar != nil {
+		opts.StatusBar = *a.StatusBar
+	}
+	if a.NavigationBar != nil {
+		opts.NavigationBar = *a.NavigationBar
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.StripDeviceChrome(img, opts)
+}
+
+type imageCheckSpecsArgs struct {
+	Paths           []string `json:"paths"`
+	Width           int      `json:"width"`
+	Height          int      `json:"height"`
+	MinWidth        int      `json:"min_width"`
+	MaxWidth        int      `json:"max_width"`
+	MinHeight       int      `json:"min_height"`
+	MaxHeight       int      `json:"max_height"`
+	AspectRatio     float64  `json:"aspect_ratio"`
+	AspectTolerance *float64 `json:"aspect_tolerance"`
+	MaxFileSize     int64    `json:"max_file_size"`
+	Formats         []string `json:"formats"`
+	DPI             float64  `json:"dpi"`
+	MinDPI          float64  `json:"min_dpi"`
+}
+
+func (s *Server) handleImageCheckSpecs(args json.RawMessage) (interface{}, error) {
+	var a imageCheckSpecsArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	specs := imaging.ImageSpecs{
+		Width:           a.Width,
+		Height:          a.Height,
+		MinWidth:        a.MinWidth,
+		MaxWidth:        a.MaxWidth,
+		MinHeight:       a.MinHeight,
+		MaxHeight:       a.MaxHeight,
+		AspectRatio:     a.AspectRatio,
+		AspectTolerance: 1,
+		MaxFileSize:     a.MaxFileSize,
+		Formats:         a.Formats,
+		DPI:             a.DPI,
+		MinDPI:          a.MinDPI,
+	}
+	if a.AspectTolerance != nil {
+		specs.AspectTolerance = *a.AspectTolerance
+	}
+	return imaging.CheckSpecs(s.cache, a.Paths, specs)
+}
+
+type imageSafeAreaArgs struct {
+	Path         string          `json:"path"`
+	Preset       string          `json:"preset"`
+	Margins      *imaging.Insets `json:"margins"`
+	Checks       []string        `json:"checks"`
+	IncludeImage *bool           `json:"include_image"`
+}
+
+func (s *Server) handleImageSafeArea(args json.RawMessage) (interface{}, error) {
+	var a imageSafeAreaArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.Checks == nil {
+		a.Checks = []string{"text", "content"}
+	}
+	opts := safeAreaOptions{preset: a.Preset, margins: a.Margins, includeImage: true}
+	for _, check := range a.Checks {
+		switch check {
+		case "text":
+			opts.checkText = true
+		case "content":
+			opts.checkContent = true
+		default:
+			return nil, fmt.Errorf("unknown check: %s (use text or content)", check)
+		}
+	}
+	if a.IncludeImage != nil {
+		opts.includeImage = *a.IncludeImage
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return buildSafeArea(img, opts)
+}
+
+type imageDetectPrintMarksArgs struct {
+	Path         string   `json:"path"`
+	DPI          float64  `json:"dpi"`
+	MinBleedMM   *float64 `json:"min_bleed_mm"`
+	SafeMarginMM *float64 `json:"safe_margin_mm"`
+	Checks       []string `json:"checks"`
+	IncludeImage *bool    `json:"include_image"`
+}
+
+func (s *Server) handleImageDetectPrintMarks(args json.RawMessage) (interface{}, error) {
+	var a imageDetectPrintMarksArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.Checks == nil {
+		a.Checks = []string{"text", "content"}
+	}
+	opts := printCheckOptions{dpi: a.DPI, dpiSource: "argument", minBleedMM: 3, safeMarginMM: 3, includeImage: true}
+	if a.MinBleedMM != nil {
+		opts.minBleedMM = *a.MinBleedMM
+	}
+	if a.SafeMarginMM != nil {
+		opts.safeMarginMM = *a.SafeMarginMM
+	}
+	if a.IncludeImage != nil {
+		opts.includeImage = *a.IncludeImage
+	}
+	if a.DPI < 0 || opts.minBleedMM < 0 || opts.safeMarginMM < 0 {
+		return nil, fmt.Errorf("dpi, min_bleed_mm, and safe_margin_mm must be non-negative")
+	}
+	for _, check := range a.Checks {
+		switch check {
+		case "text":
+			opts.checkText = true
+		case "content":
+			opts.checkContent = true
+		default:
+			return nil, fmt.Errorf("unknown check: %s (use text or content)", check)
+		}
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	if opts.dpi == 0 {
+		opts.dpi, opts.dpiSource = 300, "default"
+		// A resolution that cannot be read is as good as none
+		if res, _ := imaging.ReadResolution(a.Path); res != nil {
+			opts.dpi, opts.dpiSource = (res.X+res.Y)/2, "file"
+		}
+	}
+	return buildPrintCheck(img, opts)
+}
+
+type imageDetectHaloArgs struct {
+	Path         string `json:"path"`
+	Threshold    int    `json:"threshold"`
+	MinAlpha     int    `json:"min_alpha"`
+	IncludeImage *bool  `json:"include_image"`
+}
+
+func (s *Server) handleImageDetectHalo(args json.RawMessage) (interface{}, error) {
+	var a imageDetectHaloArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	opts := imaging.HaloOptions{Threshold: 48, MinAlpha: 16, IncludeImage: true}
+	if a.Threshold != 0 {
+		opts.Threshold = a.Threshold
+	}
+	if a.MinAlpha != 0 {
+		opts.MinAlpha = a.MinAlpha
+	}
+	if a.IncludeImage != nil {
+		opts.IncludeImage = *a.IncludeImage
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.DetectHalo(img, opts)
 }
 
-type imageCompareRegionsArgs struct {
-	Path    string `json:"path"`
-	Region1 struct {
-		X1 int `json:"x1"`
-		Y1 int `json:"y1"`
-		X2 int `json:"x2"`
-		Y2 int `json:"y2"`
-	} `json:"region1"`
-	Region2 struct {
+type imageStrokeWidthAuditArgs struct {
+	Path          string   `json:"path"`
+	MinLength     int      `json:"min_length"`
+	MinArea       int      `json:"min_area"`
+	ExpectedWidth float64  `json:"expected_width"`
+	Tolerance     *float64 `json:"tolerance"`
+}
+
+func (s *Server) handleImageStrokeWidthAudit(args json.RawMessage) (interface{}, error) {
+	var a imageStrokeWidthAuditArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	opts := detection.StrokeAuditOptions{MinLength: 20, MinArea: 100, ExpectedWidth: a.ExpectedWidth, Tolerance: 0.5}
+	if a.MinLength != 0 {
+		opts.MinLength = a.MinLength
+	}
+	if a.MinArea != 0 {
+		opts.MinArea = a.MinArea
+	}
+	if a.Tolerance != nil {
+		opts.Tolerance = *a.Tolerance
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return detection.AuditStrokeWidths(img, opts)
+}
+
+type imageCompareStatesArgs struct {
+	PathA         string `json:"path_a"`
+	PathB         string `json:"path_b"`
+	Threshold     int    `json:"threshold"`
+	MinArea       int    `json:"min_area"`
+	MergeDistance *int   `json:"merge_distance"`
+	IncludeImage  *bool  `json:"include_image"`
+}
+
+func (s *Server) handleImageCompareStates(args json.RawMessage) (interface{}, error) {
+	var a imageCompareStatesArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	opts := imaging.StateCompareOptions{Threshold: 24, MinArea: 16, MergeDistance: 4, IncludeImage: true}
+	if a.Threshold != 0 {
+		opts.Threshold = a.Threshold
+	}
+	if a.MinArea != 0 {
+		opts.MinArea = a.MinArea
+	}
+	if a.MergeDistance != nil {
+		opts.MergeDistance = *a.MergeDistance
+	}
+	if a.IncludeImage != nil {
+		opts.IncludeImage = *a.IncludeImage
+	}
+	before, err := s.cache.Load(a.PathA)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.cache.Load(a.PathB)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.CompareStates(before, after, opts)
+}
+
+type imageDetectLoadingArgs struct {
+	Path              string `json:"path"`
+	MinSkeletonBlocks int    `json:"min_skeleton_blocks"`
+}
+
+func (s *Server) handleImageDetectLoading(args json.RawMessage) (interface{}, error) {
+	var a imageDetectLoadingArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.MinSkeletonBlocks == 0 {
+		a.MinSkeletonBlocks = 3
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.DetectLoading(img, a.MinSkeletonBlocks)
+}
+
+type imageGrowRegionsArgs struct {
+	Path  string `json:"path"`
+	Seeds []struct {
+		X         int     `json:"x"`
+		Y         int     `json:"y"`
+		Label     string  `json:"label"`
+		Tolerance float64 `json:"tolerance"`
+	} `json:"seeds"`
+	Tolerance    float64 `json:"tolerance"`
+	IncludeImage bool    `json:"include_image"`
+}
+
+func (s *Server) handleImageGrowRegions(args json.RawMessage) (interface{}, error) {
+	var a imageGrowRegionsArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	seeds := make([]imaging.GrowSeed, len(a.Seeds))
+	for i, p := range a.Seeds {
+		seeds[i] = imaging.GrowSeed{X: p.X, Y: p.Y, Label: p.Label, Tolerance: p.Tolerance}
+	}
+	if a.Tolerance == 0 {
+		a.Tolerance = 30
+	}
+	return imaging.GrowRegions(img, seeds, a.Tolerance, a.IncludeImage)
+}
+
+type imageDetectGridArgs struct {
+	Path        string          `json:"path"`
+	Region      *imaging.Region `json:"region,omitempty"`
+	MinCoverage float64         `json:"min_coverage"`
+	Cells       []struct {
+		Row int `json:"row"`
+		Col int `json:"col"`
+	} `json:"cells"`
+}
+
+func (s *Server) handleImageDetectGrid(args json.RawMessage) (interface{}, error) {
+	var a imageDetectGridArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.MinCoverage == 0 {
+		a.MinCoverage = 0.6
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	result, err := imaging.DetectGrid(img, a.Region, a.MinCoverage)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range a.Cells {
+		cell, err := result.Cell(c.Row, c.Col)
+		if err != nil {
+			return nil, err
+		}
+		result.Cells = append(result.Cells, cell)
+	}
+	return result, nil
+}
+
+type imageReadHeatmapArgs struct {
+	Path      string              `json:"path"`
+	Region    *imaging.Region     `json:"region,omitempty"`
+	Preset    string              `json:"preset"`
+	Scale     []imaging.ScaleStop `json:"scale"`
+	Discrete  *bool               `json:"discrete"`
+	Rows      int                 `json:"rows"`
+	Columns   int                 `json:"columns"`
+	Tolerance float64             `json:"tolerance"`
+}
+
+func (s *Server) handleImageReadHeatmap(args json.RawMessage) (interface{}, error) {
+	var a imageReadHeatmapArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+
+	// A custom scale overrides the preset's
+	var scale imaging.HeatmapScale
+	switch {
+	case len(a.Scale) > 0:
+		scale.Stops = a.Scale
+	case a.Preset == "":
+		return nil, fmt.Errorf("preset or scale is required")
+	default:
+		p, ok := imaging.HeatmapPreset(a.Preset)
+		if !ok {
+			return nil, fmt.Errorf("unknown preset: %s (use %s)", a.Preset, strings.Join(imaging.HeatmapPresetNames(), ", "))
+		}
+		scale = p
+	}
+	if a.Discrete != nil {
+		scale.Discrete = *a.Discrete
+	}
+	if a.Tolerance == 0 {
+		a.Tolerance = 40
+	}
+
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.ReadHeatmap(img, imaging.HeatmapOptions{
+		Region:    a.Region,
+		Scale:     scale,
+		Rows:      a.Rows,
+		Columns:   a.Columns,
+		Tolerance: a.Tolerance,
+	})
+}
+
+type imageReadBoardArgs struct {
+	Path        string               `json:"path"`
+	Region      *imaging.Region      `json:"region,omitempty"`
+	Rows        int                  `json:"rows"`
+	Columns     int                  `json:"columns"`
+	PieceColors []imaging.PieceColor `json:"piece_colors"`
+	Templates   []imageSourceArgs    `json:"templates"`
+}
+
+func (s *Server) handleImageReadBoard(args json.RawMessage) (interface{}, error) {
+	var a imageReadBoardArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Templates are named by their labels, not their file names
+	templates := make([]imaging.BoardTemplate, len(a.Templates))
+	for i, src := range a.Templates {
+		if src.Label == "" {
+			return nil, fmt.Errorf("template %d needs a label", i)
+		}
+		t, label, err := s.loadImageSource(src)
+		if err != nil {
+			return nil, err
+		}
+		templates[i] = imaging.BoardTemplate{Label: label, Image: t}
+	}
+	return imaging.ReadBoard(img, imaging.BoardOptions{
+		Region:      a.Region,
+		Rows:        a.Rows,
+		Columns:     a.Columns,
+		PieceColors: a.PieceColors,
+		Templates:   templates,
+	})
+}
+
+type imageReadGaugeArgs struct {
+	Path             string              `json:"path"`
+	Region           *imaging.Region     `json:"region,omitempty"`
+	Center           *imaging.Point      `json:"center,omitempty"`
+	Radius           int                 `json:"radius"`
+	MinAngle         *float64            `json:"min_angle"`
+	MaxAngle         *float64            `json:"max_angle"`
+	MinValue         *float64            `json:"min_value"`
+	MaxValue         *float64            `json:"max_value"`
+	Marks            []imaging.GaugeMark `json:"marks"`
+	Counterclockwise bool                `json:"counterclockwise"`
+	NeedleColor      string              `json:"needle_color"`
+	Language         string              `json:"language"`
+}
+
+func (s *Server) handleImageReadGauge(args json.RawMessage) (interface{}, error) {
+	var a imageReadGaugeArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.Language == "" {
+		a.Language = "eng"
+	}
+
+	// The scale's ends are shorthand for two marks
+	marks := a.Marks
+	if a.MinAngle != nil || a.MaxAngle != nil || a.MinValue != nil || a.MaxValue != nil {
+		if a.MinAngle == nil || a.MaxAngle == nil || a.MinValue == nil || a.MaxValue == nil || len(marks) > 0 {
+			return nil, fmt.Errorf("min_angle, max_angle, min_value, and max_value go together, instead of marks")
+		}
+		marks = []imaging.GaugeMark{{Angle: *a.MinAngle, Value: *a.MinValue}, {Angle: *a.MaxAngle, Value: *a.MaxValue}}
+	}
+
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	opts := imaging.GaugeOptions{
+		Region:           a.Region,
+		Center:           a.Center,
+		Radius:           a.Radius,
+		Marks:            marks,
+		Counterclockwise: a.Counterclockwise,
+		NeedleColor:      a.NeedleColor,
+	}
+	// Without Tesseract, the scale is only known when given
+	if len(marks) == 0 && ocr.GetOCRInfo().Available {
+		opts.ReadLabels = func(r imaging.Region) []imaging.GaugeLabel {
+			result, err := ocr.ExtractTextFromRegion(img, r.X1, r.Y1, r.X2, r.Y2, a.Language)
+			if err != nil {
+				return nil
+			}
+			labels := make([]imaging.GaugeLabel, len(result.Regions))
+			for i, w := range result.Regions {
+				labels[i] = imaging.GaugeLabel{
+					Text:   w.Text,
+					Bounds: imaging.Region{X1: w.Bounds.X1, Y1: w.Bounds.Y1, X2: w.Bounds.X2, Y2: w.Bounds.Y2},
+				}
+			}
+			return labels
+		}
+	}
+	return imaging.ReadGauge(img, opts)
+}
+
+type imageReadClockArgs struct {
+	Path   string          `json:"path"`
+	Region *imaging.Region `json:"region,omitempty"`
+	Center *imaging.Point  `json:"center,omitempty"`
+	Radius int             `json:"radius"`
+}
+
+func (s *Server) handleImageReadClock(args json.RawMessage) (interface{}, error) {
+	var a imageReadClockArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.ReadClock(img, imaging.ClockOptions{
+		Region: a.Region,
+		Center: a.Center,
+		Radius: a.Radius,
+	})
+}
+
+type imageMatchFeaturesArgs struct {
+	Reference   imageSourceArgs `json:"reference"`
+	Target      imageSourceArgs `json:"target"`
+	MaxFeatures int             `json:"max_features"`
+}
+
+func (s *Server) handleImageMatchFeatures(args json.RawMessage) (interface{}, error) {
+	var a imageMatchFeaturesArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.MaxFeatures == 0 {
+		a.MaxFeatures = 500
+	}
+
+	reference, _, err := s.loadImageSource(a.Reference)
+	if err != nil {
+		return nil, err
+	}
+	target, _, err := s.loadImageSource(a.Target)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.MatchFeatures(reference, target, a.MaxFeatures)
+}
+
+type imageFindTemplateArgs struct {
+	Template   imageSourceArgs `json:"template"`
+	Target     imageSourceArgs `json:"target"`
+	Threshold  float64         `json:"threshold"`
+	MaxMatches int             `json:"max_matches"`
+}
+
+func (s *Server) handleImageFindTemplate(args json.RawMessage) (interface{}, error) {
+	var a imageFindTemplateArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.Threshold == 0 {
+		a.Threshold = 0.8
+	}
+	if a.MaxMatches == 0 {
+		a.MaxMatches = 20
+	}
+
+	tmpl, _, err := s.loadImageSource(a.Template)
+	if err != nil {
+		return nil, err
+	}
+	target, _, err := s.loadImageSource(a.Target)
+	if err != nil {
+		return nil, err
+	}
+	result, err := imaging.FindTemplate(target, tmpl, a.Threshold, a.MaxMatches)
+	if err != nil {
+		return nil, err
+	}
+	// Matches are reported in the target image's coordinates, not its
+	// region's
+	if r := a.Target.Region; r != nil {
+		for i := range result.Matches {
+			b := &result.Matches[i].Bounds
+			b.X1, b.Y1, b.X2, b.Y2 = b.X1+r.X1, b.Y1+r.Y1, b.X2+r.X1, b.Y2+r.Y1
+		}
+	}
+	return result, nil
+}
+
+// === Composition Handlers ===
+
+// imageSourceArgs identifies an image, or a region of one, used as an input
+// to tools that operate on several images at once.
+type imageSourceArgs struct {
+	Path   string `json:"path"`
+	Label  string `json:"label,omitempty"`
+	Region *struct {
 		X1 int `json:"x1"`
 		Y1 int `json:"y1"`
 		X2 int `json:"x2"`
 		Y2 int `json:"y2"`
-	} `json:"region2"`
+	} `json:"region,omitempty"`
 }
 
-func (s *Server) handleImageCompareRegions(args json.RawMessage) (interface{}, error) {
-	var a imageCompareRegionsArgs
+// loadImageSource loads the image referenced by src, cropping it to the
+// requested region if one was given. The returned label defaults to the
+// file name when src.Label is empty.
+func (s *Server) loadImageSource(src imageSourceArgs) (image.Image, string, error) {
+	img, err := s.cache.Load(src.Path)
+	if err != nil {
+		return nil, "", err
+	}
+	label := src.Label
+	if label == "" {
+		label = filepath.Base(src.Path)
+	}
+	if src.Region != nil {
+		r := imaging.Region{X1: src.Region.X1, Y1: src.Region.Y1, X2: src.Region.X2, Y2: src.Region.Y2}
+		cropped, err := imaging.CropRegion(img, r)
+		if err != nil {
+			return nil, "", err
+		}
+		img = cropped
+	}
+	return img, label, nil
+}
+
+type imageContactSheetArgs struct {
+	Images     []imageSourceArgs `json:"images"`
+	Columns    int               `json:"columns"`
+	ThumbSize  int               `json:"thumb_size"`
+	ShowLabels *bool             `json:"show_labels"`
+}
+
+func (s *Server) handleImageContactSheet(args json.RawMessage) (interface{}, error) {
+	var a imageContactSheetArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.ThumbSize == 0 {
+		a.ThumbSize = 200
+	}
+	showLabels := true
+	if a.ShowLabels != nil {
+		showLabels = *a.ShowLabels
+	}
+
+	items := make([]imaging.ContactSheetItem, 0, len(a.Images))
+	for _, src := range a.Images {
+		img, label, err := s.loadImageSource(src)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, imaging.ContactSheetItem{Image: img, Label: label})
+	}
+	return imaging.ContactSheet(items, a.Columns, a.ThumbSize, showLabels)
+}
+
+type imageSideBySideArgs struct {
+	Left          imageSourceArgs `json:"left"`
+	Right         imageSourceArgs `json:"right"`
+	Layout        string          `json:"layout"`
+	HighlightDiff bool            `json:"highlight_diff"`
+	ChannelDiff   bool            `json:"channel_diff"`
+	FontDiff      bool            `json:"font_diff"`
+	ShowLabels    *bool           `json:"show_labels"`
+}
+
+func (s *Server) handleImageSideBySide(args json.RawMessage) (interface{}, error) {
+	var a imageSideBySideArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if a.Layout == "" {
+		a.Layout = "horizontal"
+	}
+	if a.Layout != "horizontal" && a.Layout != "vertical" {
+		return nil, fmt.Errorf("unknown layout: %s", a.Layout)
+	}
+
+	left, leftLabel, err := s.loadImageSource(a.Left)
+	if err != nil {
+		return nil, err
+	}
+	right, rightLabel, err := s.loadImageSource(a.Right)
+	if err != nil {
+		return nil, err
+	}
+	if a.ShowLabels != nil && !*a.ShowLabels {
+		leftLabel, rightLabel = "", ""
+	}
+	result, err := imaging.SideBySide(left, right, leftLabel, rightLabel, a.Layout == "vertical", a.HighlightDiff)
+	if err != nil {
+		return nil, err
+	}
+	if a.ChannelDiff {
+		result.ChannelDiff, err = imaging.ChannelDiff(left, right)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if a.FontDiff {
+		regions, err := fontDiffRegions(left, right)
+		if err != nil {
+			return nil, err
+		}
+		result.FontDiff, err = imaging.TextRenderingDiff(left, right, regions)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// === Visual Regression Handlers ===
+
+type imageBaselineStoreArgs struct {
+	Path string `json:"path"`
+	Name string `json:"name"`
+}
+
+func (s *Server) handleImageBaselineStore(args json.RawMessage) (interface{}, error) {
+	var a imageBaselineStoreArgs
 	if err := json.Unmarshal(args, &a); err != nil {
 		return nil, err
 	}
+	// Screenshots are often recaptured to the same path, so read the
+	// file as it is now rather than from the cache
+	s.cache.Evict(a.Path)
 	img, err := s.cache.Load(a.Path)
 	if err != nil {
 		return nil, err
 	}
+	return s.baselines.Save(a.Name, img, a.Path)
+}
 
-	r1 := imaging.Region{X1: a.Region1.X1, Y1: a.Region1.Y1, X2: a.Region1.X2, Y2: a.Region1.Y2}
-	r2 := imaging.Region{X1: a.Region2.X1, Y1: a.Region2.Y1, X2: a.Region2.X2, Y2: a.Region2.Y2}
-	return imaging.CompareRegions(img, r1, r2)
+type imageBaselineCompareArgs struct {
+	Path           string   `json:"path"`
+	Name           string   `json:"name"`
+	MaxDiffPercent *float64 `json:"max_diff_percent"`
+	IncludeDiff    *bool    `json:"include_diff"`
+}
+
+func (s *Server) handleImageBaselineCompare(args json.RawMessage) (interface{}, error) {
+	var a imageBaselineCompareArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	opts := baseline.CompareOptions{MaxDiffPercent: baseline.DefaultMaxDiffPercent, IncludeDiff: true}
+	if a.MaxDiffPercent != nil {
+		opts.MaxDiffPercent = *a.MaxDiffPercent
+	}
+	if a.IncludeDiff != nil {
+		opts.IncludeDiff = *a.IncludeDiff
+	}
+	s.cache.Evict(a.Path)
+	img, err := s.cache.Load(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	return s.baselines.Compare(a.Name, img, opts)
 }
@@ -1,12 +1,25 @@
 package server
 
 import (
+	"bytes"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"image"
 	"image/color"
+	"image/draw"
+	"image/jpeg"
 	"image/png"
+	"math"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/ironsheep/image-tools-mcp/internal/clipboard"
+	"github.com/ironsheep/image-tools-mcp/internal/detection"
+	"github.com/ironsheep/image-tools-mcp/internal/imaging"
+	"github.com/ironsheep/image-tools-mcp/internal/ocr"
 )
 
 // createTestImageFile creates a test image file and returns its path
@@ -395,6 +408,92 @@ func TestHandleToolsCall_DetectCircles(t *testing.T) {
 	}
 }
 
+func TestHandleToolsCall_DetectAutoTune(t *testing.T) {
+	s := New()
+	img := image.NewRGBA(image.Rect(0, 0, 300, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 300; x++ {
+			c := color.RGBA{255, 255, 255, 255}
+			if (x >= 20 && x < 120 && y >= 30 && y < 100) || (x >= 170 && x < 280 && y >= 90 && y < 170) {
+				c = color.RGBA{40, 90, 160, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	boxes := filepath.Join(t.TempDir(), "boxes.png")
+	f, err := os.Create(boxes)
+	if err != nil {
+		t.Fatalf("failed to create image: %v", err)
+	}
+	png.Encode(f, img)
+	f.Close()
+
+	blank := createTestImageFile(t, 100, 100, color.RGBA{255, 255, 255, 255})
+	defer os.Remove(blank)
+
+	call := func(tool, path string) interface{} {
+		t.Helper()
+		raw, _ := json.Marshal(map[string]interface{}{"path": path, "auto_tune": true})
+		result, err := s.executeTool(tool, raw)
+		if err != nil {
+			t.Fatalf("%s failed: %v", tool, err)
+		}
+		return result
+	}
+
+	rects, ok := call("image_detect_rectangles", boxes).(*detection.RectanglesResult)
+	if !ok {
+		t.Fatal("unexpected result type for image_detect_rectangles")
+	}
+	if rects.Tuning == nil || !rects.Tuning.Tuned || rects.Count != 2 {
+		t.Errorf("rectangles: got %d with tuning %+v, want 2 with tuned parameters", rects.Count, rects.Tuning)
+	}
+
+	// Nothing to tune on a blank image: the defaults are used and the
+	// report says so
+	lines, ok := call("image_detect_lines", blank).(*detection.LinesResult)
+	if !ok || lines.Tuning == nil || lines.Tuning.Tuned {
+		t.Errorf("lines: expected an untuned report, got %+v", lines)
+	}
+	circles, ok := call("image_detect_circles", blank).(*detection.CirclesResult)
+	if !ok || circles.Tuning == nil || circles.Tuning.Tuned {
+		t.Errorf("circles: expected an untuned report, got %+v", circles)
+	}
+}
+
+func TestHandleToolsCall_DetectDebug(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 100, 100, color.RGBA{255, 255, 255, 255})
+	defer os.Remove(imgPath)
+
+	for _, tool := range []string{"image_detect_rectangles", "image_detect_lines", "image_detect_circles"} {
+		for _, debug := range []bool{false, true} {
+			raw, _ := json.Marshal(map[string]interface{}{"path": imgPath, "debug": debug})
+			result, err := s.executeTool(tool, raw)
+			if err != nil {
+				t.Fatalf("%s failed: %v", tool, err)
+			}
+			var overlay *detection.DebugOverlay
+			switch r := result.(type) {
+			case *detection.RectanglesResult:
+				overlay = r.Debug
+			case *detection.LinesResult:
+				overlay = r.Debug
+			case *detection.CirclesResult:
+				overlay = r.Debug
+			default:
+				t.Fatalf("%s: unexpected result type %T", tool, result)
+			}
+			if (overlay != nil) != debug {
+				t.Errorf("%s with debug=%v: got overlay %v", tool, debug, overlay != nil)
+			}
+			if overlay != nil && overlay.ImageBase64 == "" {
+				t.Errorf("%s: overlay has no image", tool)
+			}
+		}
+	}
+}
+
 func TestHandleToolsCall_DominantColors(t *testing.T) {
 	s := New()
 	imgPath := createTestImageFile(t, 100, 100, color.RGBA{255, 0, 0, 255})
@@ -963,6 +1062,7 @@ func TestHandleToolsCall_Crop_WithScale(t *testing.T) {
 
 func TestExecuteTool_AllTools(t *testing.T) {
 	s := New()
+	s.SetCacheDir(t.TempDir())
 	imgPath := createTestImageFile(t, 100, 100, color.RGBA{128, 128, 128, 255})
 	defer os.Remove(imgPath)
 
@@ -973,19 +1073,81 @@ func TestExecuteTool_AllTools(t *testing.T) {
 	}{
 		{"image_load", map[string]interface{}{"path": imgPath}},
 		{"image_dimensions", map[string]interface{}{"path": imgPath}},
+		{"image_thumbnail", map[string]interface{}{"path": imgPath, "max_size": 32}},
 		{"image_crop", map[string]interface{}{"path": imgPath, "x1": 0, "y1": 0, "x2": 50, "y2": 50}},
 		{"image_crop_quadrant", map[string]interface{}{"path": imgPath, "region": "center"}},
 		{"image_sample_color", map[string]interface{}{"path": imgPath, "x": 50, "y": 50}},
 		{"image_sample_colors_multi", map[string]interface{}{"path": imgPath, "points": []map[string]interface{}{{"x": 25, "y": 25}}}},
 		{"image_dominant_colors", map[string]interface{}{"path": imgPath}},
+		{"image_check_palette", map[string]interface{}{"path": imgPath, "palette": []string{"#FFFFFF"}}},
+		{"image_compare_palettes", map[string]interface{}{"images": []map[string]interface{}{{"path": imgPath}, {"path": imgPath, "region": map[string]interface{}{"x1": 0, "y1": 0, "x2": 50, "y2": 50}}}}},
+		{"image_count_colors", map[string]interface{}{"path": imgPath, "max_colors": 16}},
+		{"image_read_indicators", map[string]interface{}{"path": imgPath, "indicators": []map[string]interface{}{{"name": "a", "x": 10, "y": 10}}, "states": []map[string]interface{}{{"name": "white", "color": "#FFFFFF"}}}},
 		{"image_measure_distance", map[string]interface{}{"path": imgPath, "x1": 0, "y1": 0, "x2": 50, "y2": 50}},
 		{"image_grid_overlay", map[string]interface{}{"path": imgPath}},
+		{"image_georeference", map[string]interface{}{"path": imgPath, "reference_points": []map[string]float64{{"x": 0, "y": 0, "lat": 10, "lon": 20}, {"x": 50, "y": 50, "lat": 9, "lon": 21}}}},
+		{"image_detect_scale_bar", map[string]interface{}{"path": imgPath, "calibrate": false}},
+		{"image_count_blobs", map[string]interface{}{"path": imgPath}},
+		{"image_line_profile", map[string]interface{}{"path": imgPath, "x1": 0, "y1": 0, "x2": 50, "y2": 50}},
+		{"image_measure_angle", map[string]interface{}{"path": imgPath, "x1": 50, "y1": 0, "vertex_x": 0, "vertex_y": 0, "x2": 0, "y2": 50}},
+		{"image_measure_polygon", map[string]interface{}{"path": imgPath, "points": []map[string]int{{"x": 0, "y": 0}, {"x": 50, "y": 0}, {"x": 0, "y": 50}}}},
+		{"image_check_parallel", map[string]interface{}{"path": imgPath, "line1": map[string]int{"x1": 0, "y1": 0, "x2": 50, "y2": 0}, "line2": map[string]int{"x1": 0, "y1": 20, "x2": 50, "y2": 20}}},
+		{"image_shape_descriptors", map[string]interface{}{"path": imgPath, "points": []map[string]int{{"x": 0, "y": 0}, {"x": 50, "y": 0}, {"x": 0, "y": 50}}}},
+		{"image_redact", map[string]interface{}{"path": imgPath, "regions": []map[string]interface{}{{"x1": 10, "y1": 10, "x2": 40, "y2": 20}}}},
+		{"image_read_display", map[string]interface{}{"path": imgPath}},
 		{"image_detect_rectangles", map[string]interface{}{"path": imgPath}},
 		{"image_detect_lines", map[string]interface{}{"path": imgPath}},
 		{"image_detect_circles", map[string]interface{}{"path": imgPath}},
 		{"image_edge_detect", map[string]interface{}{"path": imgPath}},
+		{"image_skeletonize", map[string]interface{}{"path": imgPath, "graph": true}},
+		{"image_line_intersections", map[string]interface{}{"path": imgPath}},
 		{"image_check_alignment", map[string]interface{}{"path": imgPath, "points": []map[string]interface{}{{"x": 10, "y": 50}, {"x": 50, "y": 50}}}},
 		{"image_compare_regions", map[string]interface{}{"path": imgPath, "region1": map[string]interface{}{"x1": 0, "y1": 0, "x2": 50, "y2": 50}, "region2": map[string]interface{}{"x1": 50, "y1": 50, "x2": 100, "y2": 100}}},
+		{"image_contact_sheet", map[string]interface{}{"images": []map[string]interface{}{{"path": imgPath}, {"path": imgPath, "region": map[string]interface{}{"x1": 0, "y1": 0, "x2": 50, "y2": 50}}}}},
+		{"image_side_by_side", map[string]interface{}{"left": map[string]interface{}{"path": imgPath}, "right": map[string]interface{}{"path": imgPath}}},
+		{"image_align", map[string]interface{}{"reference": map[string]interface{}{"path": imgPath}, "target": map[string]interface{}{"path": imgPath}}},
+		{"image_split_sprites", map[string]interface{}{"path": imgPath, "columns": 2, "rows": 2}},
+		{"image_smart_crop", map[string]interface{}{"path": imgPath, "aspect_ratio": 1.5}},
+		{"image_infer_nine_patch", map[string]interface{}{"path": imgPath}},
+		{"image_check_centering", map[string]interface{}{"path": imgPath, "element": map[string]interface{}{"x1": 25, "y1": 25, "x2": 75, "y2": 75}}},
+		{"image_find_empty_regions", map[string]interface{}{"path": imgPath}},
+		{"image_check_overlaps", map[string]interface{}{"path": imgPath}},
+		{"image_find_repeats", map[string]interface{}{"path": imgPath}},
+		{"image_detect_periodicity", map[string]interface{}{"path": imgPath}},
+		{"image_detect_rows", map[string]interface{}{"path": imgPath}},
+		{"image_segment_regions", map[string]interface{}{"path": imgPath}},
+		{"image_classify_content", map[string]interface{}{"path": imgPath, "quadrants": true}},
+		{"image_is_blank", map[string]interface{}{"path": imgPath}},
+		{"image_detect_artifacts", map[string]interface{}{"path": imgPath}},
+		{"image_detect_moire", map[string]interface{}{"path": imgPath}},
+		{"image_inspect_channels", map[string]interface{}{"path": imgPath}},
+		{"image_near_duplicate", map[string]interface{}{"path_a": imgPath, "path_b": imgPath}},
+		{"image_accessibility_audit", map[string]interface{}{"path": imgPath}},
+		{"image_denoise", map[string]interface{}{"path": imgPath}},
+		{"image_normalize", map[string]interface{}{"path": imgPath}},
+		{"image_scan_cleanup", map[string]interface{}{"path": imgPath}},
+		{"image_clean_whiteboard", map[string]interface{}{"path": imgPath}},
+		{"image_detect_formula_regions", map[string]interface{}{"path": imgPath}},
+		{"image_detect_window_chrome", map[string]interface{}{"path": imgPath, "platform": "windows"}},
+		{"image_detect_viewport", map[string]interface{}{"path": imgPath}},
+		{"image_strip_device_chrome", map[string]interface{}{"path": imgPath, "platform": "android"}},
+		{"image_check_specs", map[string]interface{}{"paths": []string{imgPath}, "width": 100, "formats": []string{"png"}}},
+		{"image_safe_area", map[string]interface{}{"path": imgPath, "preset": "app_store_screenshot"}},
+		{"image_detect_print_marks", map[string]interface{}{"path": imgPath}},
+		{"image_detect_halo", map[string]interface{}{"path": imgPath}},
+		{"image_stroke_width_audit", map[string]interface{}{"path": imgPath}},
+		{"image_compare_states", map[string]interface{}{"path_a": imgPath, "path_b": imgPath}},
+		{"image_detect_loading", map[string]interface{}{"path": imgPath}},
+		{"image_grow_regions", map[string]interface{}{"path": imgPath, "seeds": []map[string]int{{"x": 10, "y": 10}}}},
+		{"image_detect_grid", map[string]interface{}{"path": imgPath}},
+		{"image_read_heatmap", map[string]interface{}{"path": imgPath, "preset": "viridis", "rows": 2, "columns": 2}},
+		{"image_read_board", map[string]interface{}{"path": imgPath, "rows": 3, "columns": 3}},
+		{"image_read_gauge", map[string]interface{}{"path": imgPath}},
+		{"image_read_clock", map[string]interface{}{"path": imgPath}},
+		{"image_match_features", map[string]interface{}{"reference": map[string]interface{}{"path": imgPath}, "target": map[string]interface{}{"path": imgPath}}},
+		{"image_find_template", map[string]interface{}{"template": map[string]interface{}{"path": imgPath, "region": map[string]int{"x1": 0, "y1": 0, "x2": 20, "y2": 20}}, "target": map[string]interface{}{"path": imgPath}}},
+		{"image_baseline_store", map[string]interface{}{"path": imgPath, "name": "all-tools"}},
+		{"image_baseline_compare", map[string]interface{}{"path": imgPath, "name": "all-tools"}},
 	}
 
 	for _, tt := range toolTests {
@@ -1019,3 +1181,3057 @@ func TestExecuteTool_InvalidJSON(t *testing.T) {
 		t.Error("executeTool should fail for invalid JSON")
 	}
 }
+
+func TestHandleToolsCall_ContactSheet(t *testing.T) {
+	s := New()
+	redPath := createTestImageFile(t, 100, 80, color.RGBA{255, 0, 0, 255})
+	defer os.Remove(redPath)
+	bluePath := createTestImageFile(t, 60, 60, color.RGBA{0, 0, 255, 255})
+	defer os.Remove(bluePath)
+
+	args, _ := json.Marshal(map[string]interface{}{
+		"images": []map[string]interface{}{
+			{"path": redPath, "label": "red"},
+			{"path": bluePath},
+			{"path": redPath, "region": map[string]interface{}{"x1": 0, "y1": 0, "x2": 40, "y2": 40}},
+		},
+		"thumb_size": 50,
+	})
+
+	result, err := s.executeTool("image_contact_sheet", args)
+	if err != nil {
+		t.Fatalf("executeTool failed: %v", err)
+	}
+
+	sheet, ok := result.(*imaging.ContactSheetResult)
+	if !ok {
+		t.Fatalf("unexpected result type %T", result)
+	}
+	if len(sheet.Cells) != 3 {
+		t.Fatalf("cells: got %d, want 3", len(sheet.Cells))
+	}
+	if sheet.Cells[0].Label != "red" {
+		t.Errorf("cell 0 label: got %q, want %q", sheet.Cells[0].Label, "red")
+	}
+	if sheet.Cells[1].Label != filepath.Base(bluePath) {
+		t.Errorf("cell 1 label: got %q, want file name %q", sheet.Cells[1].Label, filepath.Base(bluePath))
+	}
+	if sheet.Cells[2].SourceWidth != 40 || sheet.Cells[2].SourceHeight != 40 {
+		t.Errorf("cell 2 source: got %dx%d, want 40x40", sheet.Cells[2].SourceWidth, sheet.Cells[2].SourceHeight)
+	}
+}
+
+func TestHandleToolsCall_ContactSheet_InvalidRegion(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 50, 50, color.RGBA{255, 0, 0, 255})
+	defer os.Remove(imgPath)
+
+	args, _ := json.Marshal(map[string]interface{}{
+		"images": []map[string]interface{}{
+			{"path": imgPath, "region": map[string]interface{}{"x1": 0, "y1": 0, "x2": 100, "y2": 100}},
+		},
+	})
+
+	if _, err := s.executeTool("image_contact_sheet", args); err == nil {
+		t.Error("image_contact_sheet should fail for out-of-bounds region")
+	}
+}
+
+func TestHandleToolsCall_SideBySide(t *testing.T) {
+	s := New()
+	leftPath := createTestImageFile(t, 40, 30, color.RGBA{0, 0, 0, 255})
+	defer os.Remove(leftPath)
+	rightPath := createTestImageFile(t, 40, 30, color.RGBA{255, 255, 255, 255})
+	defer os.Remove(rightPath)
+
+	args, _ := json.Marshal(map[string]interface{}{
+		"left":           map[string]interface{}{"path": leftPath, "label": "before"},
+		"right":          map[string]interface{}{"path": rightPath},
+		"highlight_diff": true,
+	})
+
+	result, err := s.executeTool("image_side_by_side", args)
+	if err != nil {
+		t.Fatalf("image_side_by_side failed: %v", err)
+	}
+
+	sbs, ok := result.(*imaging.SideBySideResult)
+	if !ok {
+		t.Fatalf("unexpected result type: %T", result)
+	}
+	if sbs.PixelsDifferent != 40*30 {
+		t.Errorf("PixelsDifferent: got %d, want %d", sbs.PixelsDifferent, 40*30)
+	}
+	if sbs.RightBounds.X1 <= sbs.LeftBounds.X2 {
+		t.Errorf("right image should be to the right of left image: %+v vs %+v", sbs.RightBounds, sbs.LeftBounds)
+	}
+}
+
+func TestHandleToolsCall_SideBySide_InvalidLayout(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 20, 20, color.RGBA{0, 0, 0, 255})
+	defer os.Remove(imgPath)
+
+	args, _ := json.Marshal(map[string]interface{}{
+		"left":   map[string]interface{}{"path": imgPath},
+		"right":  map[string]interface{}{"path": imgPath},
+		"layout": "diagonal",
+	})
+
+	if _, err := s.executeTool("image_side_by_side", args); err == nil {
+		t.Error("image_side_by_side should fail for unknown layout")
+	}
+}
+
+func TestHandleToolsCall_Align(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 64, 64, color.RGBA{0, 0, 255, 255})
+	defer os.Remove(imgPath)
+
+	args, _ := json.Marshal(map[string]interface{}{
+		"reference": map[string]interface{}{"path": imgPath},
+		"target":    map[string]interface{}{"path": imgPath, "region": map[string]interface{}{"x1": 0, "y1": 0, "x2": 48, "y2": 48}},
+	})
+
+	result, err := s.executeTool("image_align", args)
+	if err != nil {
+		t.Fatalf("image_align failed: %v", err)
+	}
+
+	aligned, ok := result.(*imaging.AlignResult)
+	if !ok {
+		t.Fatalf("unexpected result type: %T", result)
+	}
+	if aligned.PixelsDifferent != 0 {
+		t.Errorf("PixelsDifferent: got %d, want 0", aligned.PixelsDifferent)
+	}
+}
+
+func TestHandleToolsCall_SVGInput(t *testing.T) {
+	s := New()
+	s.SetSVGScale(2.0)
+
+	svgPath := filepath.Join(t.TempDir(), "diagram.svg")
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" width="60" height="40"><rect x="5" y="5" width="20" height="10" fill="#0000FF"/></svg>`
+	if err := os.WriteFile(svgPath, []byte(svg), 0644); err != nil {
+		t.Fatalf("failed to write SVG: %v", err)
+	}
+
+	args, _ := json.Marshal(map[string]interface{}{"path": svgPath})
+	result, err := s.executeTool("image_dimensions", args)
+	if err != nil {
+		t.Fatalf("image_dimensions failed: %v", err)
+	}
+
+	dims, ok := result.(*imaging.DimensionsResult)
+	if !ok {
+		t.Fatalf("unexpected result type: %T", result)
+	}
+	if dims.Width != 120 || dims.Height != 80 {
+		t.Errorf("dimensions: got %dx%d, want 120x80", dims.Width, dims.Height)
+	}
+
+	// OCR tools receive a rasterized temporary PNG for SVG input
+	path, cleanup, err := s.ocrInputPath(svgPath, "", false, false)
+	if err != nil {
+		t.Fatalf("ocrInputPath failed: %v", err)
+	}
+	if filepath.Ext(path) != ".png" {
+		t.Errorf("ocrInputPath: got %s, want a .png file", path)
+	}
+	cleanup()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("cleanup should remove the temporary file")
+	}
+}
+
+func TestHandleToolsCall_ExtractIcon(t *testing.T) {
+	s := New()
+
+	// Minimal ICO with a single 24x24 PNG entry
+	var pngBuf bytes.Buffer
+	if err := png.Encode(&pngBuf, image.NewRGBA(image.Rect(0, 0, 24, 24))); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+	ico := []byte{0, 0, 1, 0, 1, 0, 24, 24, 0, 0, 1, 0, 32, 0}
+	ico = binary.LittleEndian.AppendUint32(ico, uint32(pngBuf.Len()))
+	ico = binary.LittleEndian.AppendUint32(ico, 22)
+	ico = append(ico, pngBuf.Bytes()...)
+
+	icoPath := filepath.Join(t.TempDir(), "app.ico")
+	if err := os.WriteFile(icoPath, ico, 0644); err != nil {
+		t.Fatalf("failed to write icon: %v", err)
+	}
+
+	args, _ := json.Marshal(map[string]interface{}{"path": icoPath, "size": 24})
+	result, err := s.executeTool("image_extract_icon", args)
+	if err != nil {
+		t.Fatalf("image_extract_icon failed: %v", err)
+	}
+
+	icon, ok := result.(*imaging.IconExtractResult)
+	if !ok {
+		t.Fatalf("unexpected result type: %T", result)
+	}
+	if icon.Selected.Width != 24 || icon.Format != "ico" {
+		t.Errorf("got %s %dpx, want ico 24px", icon.Format, icon.Selected.Width)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": icoPath, "size": 256})
+	if _, err := s.executeTool("image_extract_icon", args); err == nil {
+		t.Error("image_extract_icon should fail for a missing size")
+	}
+}
+
+func TestHandleToolsCall_SplitSprites(t *testing.T) {
+	s := New()
+
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			img.Set(x, y, color.RGBA{255, 255, 255, 255})
+		}
+	}
+	for y := 5; y < 15; y++ {
+		for x := 5; x < 15; x++ {
+			img.Set(x, y, color.RGBA{255, 0, 0, 255})
+			img.Set(x+20, y, color.RGBA{0, 0, 255, 255})
+		}
+	}
+	sheetPath := filepath.Join(t.TempDir(), "sheet.png")
+	f, err := os.Create(sheetPath)
+	if err != nil {
+		t.Fatalf("failed to create sheet: %v", err)
+	}
+	png.Encode(f, img)
+	f.Close()
+
+	args, _ := json.Marshal(map[string]interface{}{"path": sheetPath, "include_thumbnails": true})
+	result, err := s.executeTool("image_split_sprites", args)
+	if err != nil {
+		t.Fatalf("image_split_sprites failed: %v", err)
+	}
+
+	sheet, ok := result.(*imaging.SpriteSheetResult)
+	if !ok {
+		t.Fatalf("unexpected result type: %T", result)
+	}
+	if sheet.Columns != 2 || sheet.Rows != 1 {
+		t.Errorf("grid: got %dx%d, want 2x1", sheet.Columns, sheet.Rows)
+	}
+	for _, c := range sheet.Cells {
+		if c.ThumbnailBase64 == "" {
+			t.Errorf("cell %d missing thumbnail", c.Index)
+		}
+	}
+}
+
+func TestHandleToolsCall_InferNinePatch(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 30, 20, color.RGBA{200, 200, 200, 255})
+	defer os.Remove(imgPath)
+
+	args, _ := json.Marshal(map[string]interface{}{"path": imgPath})
+	result, err := s.executeTool("image_infer_nine_patch", args)
+	if err != nil {
+		t.Fatalf("image_infer_nine_patch failed: %v", err)
+	}
+
+	np, ok := result.(*imaging.NinePatchResult)
+	if !ok {
+		t.Fatalf("unexpected result type: %T", result)
+	}
+	// A solid image stretches everywhere and has no fixed border
+	if np.StretchX == nil || np.StretchX.End-np.StretchX.Start != 30 {
+		t.Errorf("StretchX: got %+v, want full width", np.StretchX)
+	}
+	if np.Padding != (imaging.Insets{}) {
+		t.Errorf("Padding: got %+v, want zero", np.Padding)
+	}
+}
+
+func TestHandleToolsCall_CheckCentering(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 100, 100, color.RGBA{255, 255, 255, 255})
+	defer os.Remove(imgPath)
+
+	args, _ := json.Marshal(map[string]interface{}{
+		"path":      imgPath,
+		"container": map[string]interface{}{"x1": 0, "y1": 0, "x2": 100, "y2": 40},
+		"element":   map[string]interface{}{"x1": 20, "y1": 10, "x2": 70, "y2": 30},
+		"tolerance": 0,
+	})
+
+	result, err := s.executeTool("image_check_centering", args)
+	if err != nil {
+		t.Fatalf("image_check_centering failed: %v", err)
+	}
+
+	c, ok := result.(*imaging.CenteringResult)
+	if !ok {
+		t.Fatalf("unexpected result type: %T", result)
+	}
+	if c.OffsetX != -5 || c.OffsetY != 0 {
+		t.Errorf("offset: got (%v,%v), want (-5,0)", c.OffsetX, c.OffsetY)
+	}
+	if c.Tolerance != 0 || c.Centered {
+		t.Errorf("explicit zero tolerance should be honored: tolerance=%v centered=%v", c.Tolerance, c.Centered)
+	}
+}
+
+func TestHandleToolsCall_FindEmptyRegions(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 80, 40, color.RGBA{255, 255, 255, 255})
+	defer os.Remove(imgPath)
+
+	args, _ := json.Marshal(map[string]interface{}{"path": imgPath, "count": 1})
+	result, err := s.executeTool("image_find_empty_regions", args)
+	if err != nil {
+		t.Fatalf("image_find_empty_regions failed: %v", err)
+	}
+
+	empty, ok := result.(*imaging.EmptyRegionsResult)
+	if !ok {
+		t.Fatalf("unexpected result type: %T", result)
+	}
+	if empty.Count != 1 || empty.Regions[0].AreaPercent != 100 {
+		t.Errorf("blank image should be one full-size region, got %+v", empty.Regions)
+	}
+}
+
+func TestHandleToolsCall_CheckOverlaps(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 100, 100, color.RGBA{255, 255, 255, 255})
+	defer os.Remove(imgPath)
+
+	args, _ := json.Marshal(map[string]interface{}{
+		"path": imgPath,
+		"boxes": []map[string]interface{}{
+			{"x1": 10, "y1": 10, "x2": 50, "y2": 50, "label": "left"},
+			{"x1": 40, "y1": 40, "x2": 90, "y2": 90, "label": "right"},
+			{"x1": 60, "y1": 0, "x2": 70, "y2": 5},
+		},
+	})
+
+	result, err := s.executeTool("image_check_overlaps", args)
+	if err != nil {
+		t.Fatalf("image_check_overlaps failed: %v", err)
+	}
+
+	overlaps, ok := result.(*detection.OverlapResult)
+	if !ok {
+		t.Fatalf("unexpected result type: %T", result)
+	}
+	if overlaps.Count != 1 || overlaps.Overlaps[0].A != 0 || overlaps.Overlaps[0].B != 1 {
+		t.Errorf("overlaps: got %+v, want only boxes 0 and 1", overlaps.Overlaps)
+	}
+	if overlaps.Boxes[0].Label != "left" {
+		t.Errorf("label: got %q, want left", overlaps.Boxes[0].Label)
+	}
+}
+
+func TestHandleToolsCall_CheckOverlaps_OutOfRange(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 100, 100, color.RGBA{255, 255, 255, 255})
+	defer os.Remove(imgPath)
+
+	// A box a billion pixels wide is clipped to the image, not walked
+	args, _ := json.Marshal(map[string]interface{}{
+		"path": imgPath,
+		"boxes": []map[string]interface{}{
+			{"x1": -1e9, "y1": 0, "x2": 1e9, "y2": 10},
+			{"x1": 20, "y1": -50, "x2": 30, "y2": 1e9},
+		},
+	})
+	result, err := s.executeTool("image_check_overlaps", args)
+	if err != nil {
+		t.Fatalf("image_check_overlaps failed: %v", err)
+	}
+	overlaps := result.(*detection.OverlapResult)
+	if got := overlaps.Boxes[0].Bounds; got != (detection.Bounds{X1: 0, Y1: 0, X2: 100, Y2: 10}) {
+		t.Errorf("clipped box: got %+v", got)
+	}
+	if overlaps.Count != 1 || overlaps.Overlaps[0].Intersection != (detection.Bounds{X1: 20, Y1: 0, X2: 30, Y2: 10}) {
+		t.Errorf("overlaps: got %+v", overlaps.Overlaps)
+	}
+
+	// A box wholly outside the image is rejected
+	args, _ = json.Marshal(map[string]interface{}{
+		"path":  imgPath,
+		"boxes": []map[string]interface{}{{"x1": 200, "y1": 0, "x2": 300, "y2": 10}},
+	})
+	if _, err := s.executeTool("image_check_overlaps", args); err == nil || !strings.Contains(err.Error(), "outside") {
+		t.Errorf("box outside the image: got %v", err)
+	}
+}
+
+func TestHandleToolsCall_DetectIncremental(t *testing.T) {
+	s := New()
+	dir := t.TempDir()
+
+	// Two frames of a UI: a panel and a button that changes color
+	frame := func(name string, button color.RGBA) string {
+		img := image.NewRGBA(image.Rect(0, 0, 200, 120))
+		for y := 0; y < 120; y++ {
+			for x := 0; x < 200; x++ {
+				c := color.RGBA{255, 255, 255, 255}
+				if x >= 10 && x < 110 && y >= 10 && y < 90 {
+					c = color.RGBA{200, 200, 220, 255}
+				}
+				if x >= 130 && x < 180 && y >= 40 && y < 70 {
+					c = button
+				}
+				img.Set(x, y, c)
+			}
+		}
+		path := filepath.Join(dir, name)
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatalf("failed to create image: %v", err)
+		}
+		png.Encode(f, img)
+		f.Close()
+		return path
+	}
+	first := frame("first.png", color.RGBA{0, 0, 200, 255})
+	second := frame("second.png", color.RGBA{0, 160, 0, 255})
+
+	call := func(args map[string]interface{}) *detection.IncrementalResult {
+		t.Helper()
+		raw, _ := json.Marshal(args)
+		result, err := s.executeTool("image_detect_incremental", raw)
+		if err != nil {
+			t.Fatalf("image_detect_incremental failed: %v", err)
+		}
+		r, ok := result.(*detection.IncrementalResult)
+		if !ok {
+			t.Fatalf("unexpected result type: %T", result)
+		}
+		return r
+	}
+
+	r := call(map[string]interface{}{"path": first})
+	if r.Incremental || r.Rectangles.Count != 2 {
+		t.Fatalf("first frame: got incremental %v with %d rectangles, want a full analysis with 2",
+			r.Incremental, r.Rectangles.Count)
+	}
+
+	r = call(map[string]interface{}{"path": second, "previous_path": first})
+	if !r.Incremental || r.Stats == nil || len(r.Stats.DirtyRegions) != 1 {
+		t.Fatalf("second frame: got %+v, want an incremental update with one dirty region", r)
+	}
+	if r.Stats.RectanglesReused != 1 {
+		t.Errorf("RectanglesReused: got %d, want 1 (the panel)", r.Stats.RectanglesReused)
+	}
+	if r.Rectangles.Count != 2 || r.Rectangles.Rectangles[1].FillColor != "#00A000" {
+		t.Errorf("second frame rectangles: got %+v, want the panel and a green button", r.Rectangles.Rectangles)
+	}
+
+	// Different parameters cannot reuse the snapshot
+	r = call(map[string]interface{}{"path": second, "previous_path": first, "min_area": 50})
+	if r.Incremental {
+		t.Error("changed parameters should force a full analysis")
+	}
+}
+
+func TestHandleToolsCall_FindRepeats(t *testing.T) {
+	s := New()
+
+	img := image.NewRGBA(image.Rect(0, 0, 60, 60))
+	for y := 0; y < 60; y++ {
+		for x := 0; x < 60; x++ {
+			img.Set(x, y, color.RGBA{255, 255, 255, 255})
+		}
+	}
+	// Three identical 10x10 icons with a dark center
+	for _, ox := range []int{5, 25, 45} {
+		for y := 20; y < 30; y++ {
+			for x := ox; x < ox+10; x++ {
+				c := color.RGBA{0, 128, 0, 255}
+				if x >= ox+3 && x < ox+7 && y >= 23 && y < 27 {
+					c = color.RGBA{0, 0, 0, 255}
+				}
+				img.Set(x, y, c)
+			}
+		}
+	}
+	imgPath := filepath.Join(t.TempDir(), "icons.png")
+	f, err := os.Create(imgPath)
+	if err != nil {
+		t.Fatalf("failed to create image: %v", err)
+	}
+	png.Encode(f, img)
+	f.Close()
+
+	args, _ := json.Marshal(map[string]interface{}{"path": imgPath, "merge_distance": 0})
+	result, err := s.executeTool("image_find_repeats", args)
+	if err != nil {
+		t.Fatalf("image_find_repeats failed: %v", err)
+	}
+
+	repeats, ok := result.(*detection.RepeatsResult)
+	if !ok {
+		t.Fatalf("unexpected result type: %T", result)
+	}
+	if repeats.Count != 1 || repeats.Clusters[0].Count != 3 {
+		t.Fatalf("clusters: got %+v, want one cluster of 3", repeats.Clusters)
+	}
+	if c := repeats.Clusters[0]; c.Width != 10 || c.Height != 10 {
+		t.Errorf("exemplar size: got %dx%d, want 10x10", c.Width, c.Height)
+	}
+}
+
+func TestHandleToolsCall_DetectRows(t *testing.T) {
+	s := New()
+
+	img := image.NewRGBA(image.Rect(0, 0, 50, 50))
+	for y := 0; y < 50; y++ {
+		for x := 0; x < 50; x++ {
+			c := color.RGBA{255, 255, 255, 255}
+			if x >= 5 && x < 45 && y%10 >= 2 && y%10 < 6 {
+				c = color.RGBA{0, 0, 0, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	imgPath := filepath.Join(t.TempDir(), "list.png")
+	f, err := os.Create(imgPath)
+	if err != nil {
+		t.Fatalf("failed to create image: %v", err)
+	}
+	png.Encode(f, img)
+	f.Close()
+
+	args, _ := json.Marshal(map[string]interface{}{
+		"path":   imgPath,
+		"region": map[string]interface{}{"x1": 0, "y1": 0, "x2": 50, "y2": 30},
+	})
+	result, err := s.executeTool("image_detect_rows", args)
+	if err != nil {
+		t.Fatalf("image_detect_rows failed: %v", err)
+	}
+
+	rows, ok := result.(*imaging.RowsResult)
+	if !ok {
+		t.Fatalf("unexpected result type: %T", result)
+	}
+	if rows.Direction != "rows" || rows.Count != 3 || rows.Pitch != 10 || !rows.Regular {
+		t.Errorf("got direction %s, %d rows, pitch %v, regular %v; want rows, 3, 10, true",
+			rows.Direction, rows.Count, rows.Pitch, rows.Regular)
+	}
+}
+
+func TestHandleToolsCall_ClassifyContent(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 40, 40, color.RGBA{255, 255, 255, 255})
+	defer os.Remove(imgPath)
+
+	args, _ := json.Marshal(map[string]interface{}{"path": imgPath, "quadrants": true})
+	result, err := s.executeTool("image_classify_content", args)
+	if err != nil {
+		t.Fatalf("image_classify_content failed: %v", err)
+	}
+
+	classified, ok := result.(*imaging.ClassifyContentResult)
+	if !ok {
+		t.Fatalf("unexpected result type: %T", result)
+	}
+	if classified.Class != imaging.ContentBlank || len(classified.Quadrants) != 4 {
+		t.Errorf("got class %s with %d quadrants, want blank with 4",
+			classified.Class, len(classified.Quadrants))
+	}
+}
+
+func TestHandleToolsCall_IsBlankAndNearDuplicate(t *testing.T) {
+	s := New()
+	whitePath := createTestImageFile(t, 40, 40, color.RGBA{255, 255, 255, 255})
+	defer os.Remove(whitePath)
+	blackPath := createTestImageFile(t, 40, 40, color.RGBA{0, 0, 0, 255})
+	defer os.Remove(blackPath)
+
+	args, _ := json.Marshal(map[string]interface{}{"path": whitePath})
+	result, err := s.executeTool("image_is_blank", args)
+	if err != nil {
+		t.Fatalf("image_is_blank failed: %v", err)
+	}
+	blank, ok := result.(*imaging.BlankResult)
+	if !ok {
+		t.Fatalf("unexpected result type: %T", result)
+	}
+	if !blank.IsBlank || blank.MaxStdDev != 3 {
+		t.Errorf("got %+v, want blank with default threshold 3", blank)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path_a": whitePath, "path_b": blackPath})
+	result, err = s.executeTool("image_near_duplicate", args)
+	if err != nil {
+		t.Fatalf("image_near_duplicate failed: %v", err)
+	}
+	dup, ok := result.(*imaging.NearDuplicateResult)
+	if !ok {
+		t.Fatalf("unexpected result type: %T", result)
+	}
+	if dup.IsDuplicate || dup.MeanDiff != 255 {
+		t.Errorf("white vs black: got %+v, want not duplicate with mean diff 255", dup)
+	}
+}
+
+func TestHandleToolsCall_GenerateReport(t *testing.T) {
+	s := New()
+	imgPath := writeFuzzImage(t, t.TempDir())
+
+	args, _ := json.Marshal(map[string]interface{}{"path": imgPath, "markdown": true, "color_count": 2})
+	result, err := s.executeTool("image_generate_report", args)
+	if err != nil {
+		t.Fatalf("image_generate_report failed: %v", err)
+	}
+	report, ok := result.(*ImageReport)
+	if !ok {
+		t.Fatalf("unexpected result type: %T", result)
+	}
+	if report.Image.Width != 64 || report.Image.Height != 48 {
+		t.Errorf("image: got %dx%d, want 64x48", report.Image.Width, report.Image.Height)
+	}
+	if report.Content == nil || report.Quality == nil || report.TextRegions == nil || report.Shapes == nil {
+		t.Fatalf("missing sections, skipped: %v", report.Skipped)
+	}
+	if len(report.DominantColors) != 2 || report.DominantColors[0].Hex != "#F0F0F0" {
+		t.Errorf("dominant colors: got %+v, want 2 led by the white background", report.DominantColors)
+	}
+	if report.Shapes.Rectangles.Count == 0 {
+		t.Error("expected the box to be detected as a rectangle")
+	}
+	if !strings.HasPrefix(report.Markdown, "# Image Report: "+filepath.Base(imgPath)) {
+		t.Errorf("markdown: got %q", report.Markdown)
+	}
+
+	// Lists can be left out entirely, keeping the counts
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath, "max_items": 0})
+	result, err = s.executeTool("image_generate_report", args)
+	if err != nil {
+		t.Fatalf("image_generate_report failed: %v", err)
+	}
+	report = result.(*ImageReport)
+	if len(report.Shapes.Rectangles.Rectangles) != 0 || report.Shapes.Rectangles.Count == 0 || report.Markdown != "" {
+		t.Errorf("max_items 0: got %+v", report.Shapes.Rectangles)
+	}
+
+	// Analyses that cannot run on a tiny image are skipped, not fatal
+	tinyPath := createTestImageFile(t, 2, 2, color.RGBA{0, 0, 0, 255})
+	defer os.Remove(tinyPath)
+	args, _ = json.Marshal(map[string]interface{}{"path": tinyPath})
+	result, err = s.executeTool("image_generate_report", args)
+	if err != nil {
+		t.Fatalf("image_generate_report failed on a tiny image: %v", err)
+	}
+	report = result.(*ImageReport)
+	if report.Quality != nil || report.Skipped["quality"] == "" || len(report.DominantColors) != 1 {
+		t.Errorf("tiny image: got %+v", report)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath, "max_items": -1})
+	if _, err := s.executeTool("image_generate_report", args); err == nil {
+		t.Error("expected error for negative max_items")
+	}
+}
+
+func TestHandleToolsCall_DetectExport(t *testing.T) {
+	s := New()
+	imgPath := writeFuzzImage(t, t.TempDir())
+
+	args, _ := json.Marshal(map[string]interface{}{"path": imgPath, "export_format": "yolo"})
+	result, err := s.executeTool("image_detect_rectangles", args)
+	if err != nil {
+		t.Fatalf("image_detect_rectangles failed: %v", err)
+	}
+	rects := result.(*detection.RectanglesResult)
+	if rects.Export == nil || rects.Export.FileName != "fuzz.txt" || rects.Export.Count != rects.Count {
+		t.Fatalf("export: got %+v for %d rectangles", rects.Export, rects.Count)
+	}
+	if lines := strings.Count(rects.Export.Data, "\n"); lines != rects.Count {
+		t.Errorf("YOLO data has %d lines, want %d", lines, rects.Count)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath, "export_format": "voc", "auto_tune": true})
+	result, err = s.executeTool("image_detect_lines", args)
+	if err != nil {
+		t.Fatalf("image_detect_lines failed: %v", err)
+	}
+	if lines := result.(*detection.LinesResult); lines.Export == nil || lines.Export.Format != "voc" || lines.Tuning == nil {
+		t.Errorf("lines: got export %+v, tuning %+v", lines.Export, lines.Tuning)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath})
+	result, _ = s.executeTool("image_detect_circles", args)
+	if circles := result.(*detection.CirclesResult); circles.Export != nil {
+		t.Error("export should be absent unless requested")
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath, "export_format": "csv"})
+	if _, err := s.executeTool("image_detect_circles", args); err == nil {
+		t.Error("expected error for unknown export format")
+	}
+}
+
+func TestHandleToolsCall_Mask(t *testing.T) {
+	s := New()
+	imgPath := writeFuzzImage(t, t.TempDir())
+
+	// Inside the blue rectangle only
+	inner := map[string]interface{}{"polygon": []map[string]int{{"x": 10, "y": 10}, {"x": 28, "y": 10}, {"x": 28, "y": 22}, {"x": 10, "y": 22}}}
+	args, _ := json.Marshal(map[string]interface{}{"path": imgPath, "count": 5, "mask": inner})
+	result, err := s.executeTool("image_dominant_colors", args)
+	if err != nil {
+		t.Fatalf("image_dominant_colors failed: %v", err)
+	}
+	if colors := result.(*imaging.DominantColorsResult).Colors; len(colors) != 1 || colors[0].Percentage != 100 {
+		t.Errorf("masked colors: got %+v, want only the rectangle's blue", colors)
+	}
+
+	// The bottom strip holds the line but not the rectangle
+	bottom := map[string]interface{}{"polygon": []map[string]int{{"x": 0, "y": 30}, {"x": 64, "y": 30}, {"x": 64, "y": 48}, {"x": 0, "y": 48}}}
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath, "mask": bottom})
+	result, err = s.executeTool("image_detect_rectangles", args)
+	if err != nil {
+		t.Fatalf("image_detect_rectangles failed: %v", err)
+	}
+	if rects := result.(*detection.RectanglesResult); rects.Count != 0 || len(rects.Rectangles) != 0 {
+		t.Errorf("masked rectangles: got %+v", rects.Rectangles)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{
+		"path":    imgPath,
+		"region1": map[string]int{"x1": 0, "y1": 0, "x2": 20, "y2": 20},
+		"region2": map[string]int{"x1": 30, "y1": 0, "x2": 50, "y2": 20},
+		"mask":    map[string]interface{}{"rle": map[string]interface{}{"size": []int{20, 20}, "counts": []int{0, 4, 396}}},
+	})
+	result, err = s.executeTool("image_compare_regions", args)
+	if err != nil {
+		t.Fatalf("image_compare_regions failed: %v", err)
+	}
+	if cmp := result.(*imaging.CompareRegionsResult); cmp.TotalPixels != 4 || cmp.SimilarityScore != 1 {
+		t.Errorf("masked comparison: got %+v", cmp)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath, "mask": map[string]interface{}{"polygon": []map[string]int{{"x": 0, "y": 0}, {"x": 5, "y": 5}}}})
+	if _, err := s.executeTool("image_detect_circles", args); err == nil {
+		t.Error("expected error for a two-vertex mask")
+	}
+}
+
+func TestHandleToolsCall_Skeletonize(t *testing.T) {
+	s := New()
+	imgPath := writeFuzzImage(t, t.TempDir())
+
+	args, _ := json.Marshal(map[string]interface{}{"path": imgPath, "graph": true})
+	result, err := s.executeTool("image_skeletonize", args)
+	if err != nil {
+		t.Fatalf("image_skeletonize failed: %v", err)
+	}
+	// The horizontal line thins to a stroke with two ends; the filled
+	// rectangle to a short spine
+	sk := result.(*imaging.SkeletonResult)
+	if sk.Width != 64 || sk.Height != 48 || sk.ImageBase64 == "" || sk.Graph == nil {
+		t.Fatalf("got %+v", sk)
+	}
+	line := false
+	for _, e := range sk.Graph.Edges {
+		from, to := sk.Graph.Nodes[e.From].Point, sk.Graph.Nodes[e.To].Point
+		if from.Y == 36 && to.Y == 36 && e.Length >= 50 {
+			line = true
+		}
+	}
+	if !line {
+		t.Errorf("no edge along the line at y=36: %+v", sk.Graph)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath})
+	result, _ = s.executeTool("image_skeletonize", args)
+	if sk := result.(*imaging.SkeletonResult); sk.Graph != nil {
+		t.Error("graph should be absent unless requested")
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath, "threshold": 300})
+	if _, err := s.executeTool("image_skeletonize", args); err == nil {
+		t.Error("expected error for threshold above 255")
+	}
+}
+
+func TestHandleToolsCall_SegmentRegions(t *testing.T) {
+	s := New()
+	imgPath := writeFuzzImage(t, t.TempDir())
+
+	args, _ := json.Marshal(map[string]interface{}{"path": imgPath, "include_image": true})
+	result, err := s.executeTool("image_segment_regions", args)
+	if err != nil {
+		t.Fatalf("image_segment_regions failed: %v", err)
+	}
+	// White background, blue rectangle, and the black line
+	seg := result.(*imaging.SegmentResult)
+	if seg.TotalRegions != 3 || seg.Count != 3 || seg.ImageBase64 == "" {
+		t.Fatalf("got %+v", seg)
+	}
+	if seg.Regions[0].Color != "#FFFFFF" || seg.Regions[1].Color != "#285AC8" || seg.Regions[1].Bounds != (imaging.Region{X1: 8, Y1: 8, X2: 30, Y2: 24}) {
+		t.Errorf("regions: got %+v", seg.Regions)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath, "max_regions": 1000})
+	if _, err := s.executeTool("image_segment_regions", args); err == nil {
+		t.Error("expected error for max_regions above 500")
+	}
+}
+
+func TestHandleToolsCall_CheckPalette(t *testing.T) {
+	s := New()
+	imgPath := writeFuzzImage(t, t.TempDir())
+
+	// The blue rectangle is on brand; the black line is not, but it is
+	// only one pixel thick
+	args, _ := json.Marshal(map[string]interface{}{"path": imgPath, "palette": []string{"#FFFFFF", "#285AC8"}})
+	result, err := s.executeTool("image_check_palette", args)
+	if err != nil {
+		t.Fatalf("image_check_palette failed: %v", err)
+	}
+	if p := result.(*imaging.PaletteResult); !p.Compliant || p.Tolerance != 3 || p.CompliantPercent != 100 {
+		t.Errorf("default check: got %+v", p)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{
+		"path":         imgPath,
+		"palette":      []string{"#FFFFFF", "#285AC8"},
+		"ignore_edges": false,
+		"min_percent":  0,
+		"points":       []map[string]int{{"x": 10, "y": 36}},
+	})
+	result, err = s.executeTool("image_check_palette", args)
+	if err != nil {
+		t.Fatalf("image_check_palette failed: %v", err)
+	}
+	p := result.(*imaging.PaletteResult)
+	if p.Compliant || len(p.Violations) != 1 || p.Violations[0].Hex != "#000000" || p.Violations[0].Pixels != 56 {
+		t.Errorf("violations: got %+v", p.Violations)
+	}
+	if len(p.Samples) != 1 || p.Samples[0].InPalette {
+		t.Errorf("samples: got %+v", p.Samples)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath})
+	if _, err := s.executeTool("image_check_palette", args); err == nil {
+		t.Error("expected error without a palette")
+	}
+}
+
+func TestHandleToolsCall_DetectArtifacts(t *testing.T) {
+	s := New()
+	dir := t.TempDir()
+	imgPath := writeFuzzImage(t, dir)
+
+	args, _ := json.Marshal(map[string]interface{}{"path": imgPath})
+	result, err := s.executeTool("image_detect_artifacts", args)
+	if err != nil {
+		t.Fatalf("image_detect_artifacts failed: %v", err)
+	}
+	if a := result.(*imaging.ArtifactsResult); a.Level != "none" || a.Reexport {
+		t.Errorf("PNG: got %+v", a)
+	}
+
+	// The same image saved as a low-quality JPEG
+	src, err := s.cache.Load(imgPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, src, &jpeg.Options{Quality: 10}); err != nil {
+		t.Fatalf("jpeg.Encode failed: %v", err)
+	}
+	jpgPath := filepath.Join(dir, "lossy.jpg")
+	if err := os.WriteFile(jpgPath, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	args, _ = json.Marshal(map[string]interface{}{"path": jpgPath})
+	result, err = s.executeTool("image_detect_artifacts", args)
+	if err != nil {
+		t.Fatalf("image_detect_artifacts failed: %v", err)
+	}
+	if a := result.(*imaging.ArtifactsResult); a.Level == "none" || a.Ringing.Severity == 0 || len(a.Advice) < 2 {
+		t.Errorf("JPEG: got %+v", a)
+	}
+}
+
+func TestHandleToolsCall_DetectMoire(t *testing.T) {
+	s := New()
+	dir := t.TempDir()
+
+	// Stripes every 2.5 pixels, as 1-pixel lines look after zooming out
+	img := image.NewRGBA(image.Rect(0, 0, 128, 96))
+	for y := 0; y < 96; y++ {
+		for x := 0; x < 128; x++ {
+			v := uint8(128 + 100*math.Cos(2*math.Pi*float64(y)/2.5))
+			img.Set(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+	path := filepath.Join(dir, "zoomed.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	args, _ := json.Marshal(map[string]interface{}{"path": path})
+	result, err := s.executeTool("image_detect_moire", args)
+	if err != nil {
+		t.Fatalf("image_detect_moire failed: %v", err)
+	}
+	m := result.(*imaging.MoireResult)
+	if !m.Detected || m.TileSize != 64 || m.AffectedPercent != 100 {
+		t.Fatalf("got %+v", m)
+	}
+	if r := m.Regions[0]; r.Bounds != (imaging.Region{X1: 0, Y1: 0, X2: 128, Y2: 96}) || r.Angle != 0 {
+		t.Errorf("region: got %+v", r)
+	}
+
+	// The 64x48 test image is smaller than a default tile
+	args, _ = json.Marshal(map[string]interface{}{"path": writeFuzzImage(t, dir)})
+	if _, err := s.executeTool("image_detect_moire", args); err == nil {
+		t.Error("expected error for an image smaller than one tile")
+	}
+	args, _ = json.Marshal(map[string]interface{}{"path": path, "tile_size": 100})
+	if _, err := s.executeTool("image_detect_moire", args); err == nil {
+		t.Error("expected error for tile_size 100")
+	}
+}
+
+func TestHandleToolsCall_DetectPeriodicity(t *testing.T) {
+	s := New()
+	dir := t.TempDir()
+
+	// Table rules every 12 pixels
+	img := image.NewRGBA(image.Rect(0, 0, 160, 140))
+	for y := 0; y < 140; y++ {
+		for x := 0; x < 160; x++ {
+			c := color.RGBA{255, 255, 255, 255}
+			if y%12 == 0 {
+				c = color.RGBA{90, 90, 90, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	path := filepath.Join(dir, "table.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	args, _ := json.Marshal(map[string]interface{}{"path": path, "max_peaks": 3})
+	result, err := s.executeTool("image_detect_periodicity", args)
+	if err != nil {
+		t.Fatalf("image_detect_periodicity failed: %v", err)
+	}
+	p := result.(*imaging.PeriodicityResult)
+	if !p.Periodic || p.Count < 1 || p.Window != (imaging.Region{X1: 16, Y1: 6, X2: 144, Y2: 134}) {
+		t.Fatalf("got %+v", p)
+	}
+	if peak := p.Peaks[0]; math.Abs(peak.PeriodY-12) > 0.2 || peak.PeriodX != 0 || peak.Angle != 90 {
+		t.Errorf("peak: got %+v", peak)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": path, "max_peaks": 50})
+	if _, err := s.executeTool("image_detect_periodicity", args); err == nil {
+		t.Error("expected error for max_peaks above 20")
+	}
+}
+
+func TestHandleToolsCall_SmartCrop(t *testing.T) {
+	s := New()
+	dir := t.TempDir()
+
+	// A diagram box near the right of a wide canvas
+	img := image.NewRGBA(image.Rect(0, 0, 300, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 300; x++ {
+			c := color.RGBA{255, 255, 255, 255}
+			if x >= 200 && x < 260 && y >= 20 && y < 80 {
+				c = color.RGBA{30, 60, 200, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	path := filepath.Join(dir, "diagram.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	// Default method is crop
+	args, _ := json.Marshal(map[string]interface{}{"path": path, "aspect_ratio": 1})
+	result, err := s.executeTool("image_smart_crop", args)
+	if err != nil {
+		t.Fatalf("image_smart_crop failed: %v", err)
+	}
+	r := result.(*imaging.SmartCropResult)
+	if r.Method != "crop" || r.Width != 100 || r.Height != 100 || r.Crop == nil {
+		t.Fatalf("got %+v", r)
+	}
+	if r.Crop.X1 > 199 || r.Crop.X2 < 261 || r.RetainedEnergy != 100 {
+		t.Errorf("crop %+v (retained %v) should keep the whole box", r.Crop, r.RetainedEnergy)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": path, "aspect_ratio": 2, "method": "seam", "max_size": 50})
+	result, err = s.executeTool("image_smart_crop", args)
+	if err != nil {
+		t.Fatalf("image_smart_crop seam failed: %v", err)
+	}
+	if r := result.(*imaging.SmartCropResult); r.SeamsRemoved != 100 || r.Width != 50 || r.Height != 25 {
+		t.Errorf("seam: got %d seams, %dx%d", r.SeamsRemoved, r.Width, r.Height)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": path})
+	if _, err := s.executeTool("image_smart_crop", args); err == nil {
+		t.Error("expected error without aspect_ratio")
+	}
+}
+
+func TestHandleToolsCall_Thumbnail(t *testing.T) {
+	s := New()
+	path := writeFuzzImage(t, t.TempDir())
+
+	// Defaults: 128 pixels, PNG; a smaller image keeps its size
+	args, _ := json.Marshal(map[string]interface{}{"path": path})
+	result, err := s.executeTool("image_thumbnail", args)
+	if err != nil {
+		t.Fatalf("image_thumbnail failed: %v", err)
+	}
+	th := result.(*imaging.ThumbnailResult)
+	if th.Width != 64 || th.Height != 48 || th.MimeType != "image/png" {
+		t.Errorf("got %dx%d %s, want 64x48 image/png", th.Width, th.Height, th.MimeType)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": path, "max_size": 32, "format": "jpeg"})
+	result, err = s.executeTool("image_thumbnail", args)
+	if err != nil {
+		t.Fatalf("image_thumbnail jpeg failed: %v", err)
+	}
+	th = result.(*imaging.ThumbnailResult)
+	if th.Width != 32 || th.Height != 24 || th.OriginalWidth != 64 || th.MimeType != "image/jpeg" {
+		t.Errorf("got %+v", th)
+	}
+
+	// Served from the thumbnail cache once generated
+	again, err := s.executeTool("image_thumbnail", args)
+	if err != nil || again != result {
+		t.Errorf("repeated call: got %p, %v; want the cached thumbnail", again, err)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": path, "max_size": 4096})
+	if _, err := s.executeTool("image_thumbnail", args); err == nil {
+		t.Error("expected error for max_size above 1024")
+	}
+}
+
+func TestHandleToolsCall_ComparePalettes(t *testing.T) {
+	s := New()
+	dir := t.TempDir()
+
+	// Three assets in the same colors and one with a warm cast
+	write := func(name string, bg, panel color.RGBA) string {
+		img := image.NewRGBA(image.Rect(0, 0, 80, 60))
+		for y := 0; y < 60; y++ {
+			for x := 0; x < 80; x++ {
+				c := bg
+				if x >= 10 && x < 50 && y >= 10 && y < 40 {
+					c = panel
+				}
+				img.Set(x, y, c)
+			}
+		}
+		path := filepath.Join(dir, name)
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := png.Encode(f, img); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+		return path
+	}
+	white, navy := color.RGBA{250, 250, 250, 255}, color.RGBA{20, 40, 110, 255}
+	images := []map[string]interface{}{
+		{"path": write("a.png", white, navy)},
+		{"path": write("b.png", white, navy), "label": "second"},
+		{"path": write("c.png", white, navy), "region": map[string]interface{}{"x1": 0, "y1": 0, "x2": 60, "y2": 50}},
+		{"path": write("warm.png", color.RGBA{255, 235, 190, 255}, color.RGBA{80, 60, 50, 255})},
+	}
+
+	args, _ := json.Marshal(map[string]interface{}{"images": images})
+	result, err := s.executeTool("image_compare_palettes", args)
+	if err != nil {
+		t.Fatalf("image_compare_palettes failed: %v", err)
+	}
+	r := result.(*imaging.PaletteComparisonResult)
+	if r.Threshold != 15 || r.Consistent || len(r.Pairs) != 6 {
+		t.Fatalf("got %+v", r)
+	}
+	if len(r.Outliers) != 1 || r.Outliers[0] != 3 {
+		t.Errorf("outliers: got %v, want [3]", r.Outliers)
+	}
+	if r.Images[0].Label != "a.png" || r.Images[1].Label != "second" {
+		t.Errorf("labels: got %q, %q", r.Images[0].Label, r.Images[1].Label)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"images": images[:1]})
+	if _, err := s.executeTool("image_compare_palettes", args); err == nil {
+		t.Error("expected error for a single image")
+	}
+}
+
+func TestHandleToolsCall_CountColors(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 40, 30, color.RGBA{200, 30, 30, 255})
+	defer os.Remove(imgPath)
+
+	args, _ := json.Marshal(map[string]interface{}{"path": imgPath, "max_colors": 1})
+	result, err := s.executeTool("image_count_colors", args)
+	if err != nil {
+		t.Fatalf("image_count_colors failed: %v", err)
+	}
+	r := result.(*imaging.ColorCountResult)
+	if r.DistinctColors != 1 || r.Pixels != 1200 || r.Colors[0].Hex != "#C81E1E" || r.WithinLimit == nil || !*r.WithinLimit {
+		t.Errorf("got %+v", r)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath, "limit": 10000})
+	if _, err := s.executeTool("image_count_colors", args); err == nil {
+		t.Error("expected error for a limit over 4096")
+	}
+}
+
+func TestHandleToolsCall_Baseline(t *testing.T) {
+	s := New()
+	s.SetCacheDir(t.TempDir())
+	path := filepath.Join(t.TempDir(), "capture.png")
+	capture := func(c color.RGBA) {
+		img := image.NewRGBA(image.Rect(0, 0, 40, 30))
+		for y := 0; y < 30; y++ {
+			for x := 0; x < 40; x++ {
+				img.Set(x, y, c)
+			}
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := png.Encode(f, img); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+	call := func(tool string, args map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		params, _ := json.Marshal(map[string]interface{}{"name": tool, "arguments": args})
+		resp := s.handleToolsCall(&MCPRequest{JSONRPC: "2.0", ID: 1, Params: params})
+		if resp.Error != nil {
+			t.Fatalf("%s failed: %v", tool, resp.Error)
+		}
+		var result map[string]interface{}
+		if err := json.Unmarshal(resp.Result.(map[string]interface{})["structuredContent"].(json.RawMessage), &result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	capture(color.RGBA{200, 200, 200, 255})
+	stored := call("image_baseline_store", map[string]interface{}{"path": path, "name": "panel"})
+	if stored["replaced"] != false || stored["source"] != path {
+		t.Errorf("store: got %v", stored)
+	}
+	if r := call("image_baseline_compare", map[string]interface{}{"path": path, "name": "panel"}); r["pass"] != true || r["identical"] != true {
+		t.Errorf("unchanged capture: got pass=%v identical=%v", r["pass"], r["identical"])
+	}
+
+	// A new capture at the same path is read afresh, not from a cache
+	capture(color.RGBA{40, 40, 40, 255})
+	r := call("image_baseline_compare", map[string]interface{}{"path": path, "name": "panel", "include_diff": false})
+	if r["pass"] != false || r["diff_percent"] != 100.0 || r["diff"] != nil {
+		t.Errorf("changed capture: got pass=%v diff_percent=%v diff=%v", r["pass"], r["diff_percent"], r["diff"] != nil)
+	}
+
+	args, _ := json.Marshal(map[string]interface{}{"path": path, "name": "missing"})
+	if _, err := s.executeTool("image_baseline_compare", args); err == nil {
+		t.Error("expected error for a missing baseline")
+	}
+	args, _ = json.Marshal(map[string]interface{}{"path": path, "name": "../escape"})
+	if _, err := s.executeTool("image_baseline_store", args); err == nil {
+		t.Error("expected error for an invalid name")
+	}
+}
+
+func TestHandleToolsCall_AccessibilityAudit(t *testing.T) {
+	s := New()
+	path := filepath.Join(t.TempDir(), "ui.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, auditScene(color.RGBA{150, 150, 150, 255})); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	args, _ := json.Marshal(map[string]interface{}{"path": path})
+	result, err := s.executeTool("image_accessibility_audit", args)
+	if err != nil {
+		t.Fatalf("image_accessibility_audit failed: %v", err)
+	}
+	audit := result.(*AccessibilityAudit)
+	if audit.Passed || audit.Level != "AA" || audit.Scale != 1 || audit.Errors == 0 {
+		t.Errorf("got passed=%v level=%s scale=%v errors=%d", audit.Passed, audit.Level, audit.Scale, audit.Errors)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": path, "max_issues": 0})
+	result, err = s.executeTool("image_accessibility_audit", args)
+	if err != nil {
+		t.Fatalf("image_accessibility_audit failed: %v", err)
+	}
+	if audit := result.(*AccessibilityAudit); len(audit.Issues) != 0 || audit.Errors == 0 {
+		t.Errorf("max_issues 0: got %d issues, %d errors", len(audit.Issues), audit.Errors)
+	}
+
+	for _, bad := range []map[string]interface{}{
+		{"path": path, "level": "A"},
+		{"path": path, "scale": -2},
+		{"path": path, "min_text_size": -1},
+		{"path": path, "max_issues": -1},
+	} {
+		args, _ = json.Marshal(bad)
+		if _, err := s.executeTool("image_accessibility_audit", args); err == nil {
+			t.Errorf("expected error for %v", bad)
+		}
+	}
+}
+
+func TestHandleToolsCall_Redact(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 100, 60, color.RGBA{255, 255, 255, 255})
+	defer os.Remove(imgPath)
+
+	args, _ := json.Marshal(map[string]interface{}{
+		"path":    imgPath,
+		"regions": []map[string]interface{}{{"x1": 10, "y1": 10, "x2": 40, "y2": 20}},
+	})
+	result, err := s.executeTool("image_redact", args)
+	if err != nil {
+		t.Fatalf("image_redact failed: %v", err)
+	}
+	r := result.(*imaging.RedactResult)
+	if r.Style != "black" || r.Count != 1 || r.ImageBase64 == "" {
+		t.Errorf("got style=%s count=%d", r.Style, r.Count)
+	}
+	if got := r.Redactions[0]; got.Source != "region" || got.Bounds != (imaging.Region{X1: 8, Y1: 8, X2: 42, Y2: 22}) {
+		t.Errorf("default padding: got %+v", got)
+	}
+
+	for _, bad := range []map[string]interface{}{
+		{"path": imgPath},
+		{"path": imgPath, "patterns": []string{"(unclosed"}},
+		{"path": imgPath, "regions": []map[string]interface{}{{"x1": 10, "y1": 10, "x2": 40, "y2": 20}}, "style": "blur"},
+		{"path": imgPath, "regions": []map[string]interface{}{{"x1": 200, "y1": 10, "x2": 240, "y2": 20}}},
+	} {
+		args, _ = json.Marshal(bad)
+		if _, err := s.executeTool("image_redact", args); err == nil {
+			t.Errorf("expected error for %v", bad)
+		}
+	}
+}
+
+func TestHandleToolsCall_DetectPII_InvalidCategory(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 50, 50, color.RGBA{255, 255, 255, 255})
+	defer os.Remove(imgPath)
+
+	args, _ := json.Marshal(map[string]interface{}{"path": imgPath, "categories": []string{"email", "ssn"}})
+	if _, err := s.executeTool("image_detect_pii", args); err == nil {
+		t.Error("expected error for an unknown category")
+	}
+}
+
+func TestHandleToolsCall_InspectChannels(t *testing.T) {
+	s := New()
+	// A transparent PNG whose invisible pixels keep a color
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{255, 255, 255, 255})
+			if y >= 32 {
+				img.SetNRGBA(x, y, color.NRGBA{200, 0, 0, 0})
+			}
+		}
+	}
+	path := filepath.Join(t.TempDir(), "hidden.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	args, _ := json.Marshal(map[string]interface{}{"path": path})
+	result, err := s.executeTool("image_inspect_channels", args)
+	if err != nil {
+		t.Fatalf("image_inspect_channels failed: %v", err)
+	}
+	r := result.(*imaging.ChannelInspection)
+	if !r.Suspicious || r.Alpha == nil || r.Alpha.HiddenColorPixels != 64*32 || r.Alpha.RevealedBase64 == "" {
+		t.Errorf("got suspicious=%v alpha=%+v", r.Suspicious, r.Alpha)
+	}
+	if len(r.Channels) != 4 || len(r.Planes) != 4 || r.Planes[0].Bit != 0 {
+		t.Errorf("default bits: got %d channels, %d planes", len(r.Channels), len(r.Planes))
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": path, "channels": []string{"g"}, "include_images": false})
+	result, err = s.executeTool("image_inspect_channels", args)
+	if err != nil {
+		t.Fatalf("image_inspect_channels failed: %v", err)
+	}
+	if r := result.(*imaging.ChannelInspection); len(r.Planes) != 0 || r.Alpha.ImageBase64 != "" || len(r.Channels) != 1 {
+		t.Errorf("without images: got %d planes, alpha image %v", len(r.Planes), r.Alpha.ImageBase64 != "")
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": path, "bits": []int{9}})
+	if _, err := s.executeTool("image_inspect_channels", args); err == nil {
+		t.Error("expected error for bit 9")
+	}
+}
+
+func TestHandleToolsCall_SampleColor_BitDepth(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 20, 20, color.RGBA{255, 128, 64, 255})
+	defer os.Remove(imgPath)
+
+	args, _ := json.Marshal(map[string]interface{}{"path": imgPath, "x": 5, "y": 5, "bit_depth": 16})
+	result, err := s.executeTool("image_sample_color", args)
+	if err != nil {
+		t.Fatalf("image_sample_color failed: %v", err)
+	}
+	if r := result.(*imaging.ColorResult); r.Hex16 != "#FFFF80804040" || r.RGBA16 == nil || r.RGBA16.A != 0xFFFF {
+		t.Errorf("got hex16 %s, rgba16 %+v", r.Hex16, r.RGBA16)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath, "bit_depth": 16})
+	result, err = s.executeTool("image_dominant_colors", args)
+	if err != nil {
+		t.Fatalf("image_dominant_colors failed: %v", err)
+	}
+	if c := result.(*imaging.DominantColorsResult).Colors[0]; c.RGB16 == nil || c.RGB16.G != 0x8080 {
+		t.Errorf("got %+v", c)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath, "points": []map[string]int{{"x": 1, "y": 1}}, "bit_depth": 10})
+	if _, err := s.executeTool("image_sample_colors_multi", args); err == nil {
+		t.Error("expected error for bit depth 10")
+	}
+}
+
+func TestHandleToolsCall_AlphaMode(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 20, 20, color.RGBA{0, 0, 0, 0})
+	defer os.Remove(imgPath)
+
+	args, _ := json.Marshal(map[string]interface{}{"path": imgPath, "alpha_mode": "content"})
+	result, err := s.executeTool("image_dominant_colors", args)
+	if err != nil {
+		t.Fatalf("image_dominant_colors failed: %v", err)
+	}
+	if r := result.(*imaging.DominantColorsResult); len(r.Colors) != 0 || r.Alpha == nil || r.Alpha.TransparentPixels != 400 {
+		t.Errorf("got %+v", r)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath, "alpha_mode": "composite", "background": "#336699"})
+	result, err = s.executeTool("image_dominant_colors", args)
+	if err != nil {
+		t.Fatalf("image_dominant_colors failed: %v", err)
+	}
+	if c := result.(*imaging.DominantColorsResult).Colors; len(c) != 1 || c[0].Hex != "#306090" {
+		t.Errorf("got %+v", c)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{
+		"path":       imgPath,
+		"region1":    map[string]int{"x1": 0, "y1": 0, "x2": 10, "y2": 10},
+		"region2":    map[string]int{"x1": 10, "y1": 10, "x2": 20, "y2": 20},
+		"alpha_mode": "content",
+	})
+	result, err = s.executeTool("image_compare_regions", args)
+	if err != nil {
+		t.Fatalf("image_compare_regions failed: %v", err)
+	}
+	if r := result.(*imaging.CompareRegionsResult); r.SimilarityScore != 1 || r.TotalPixels != 0 || r.Region1Alpha == nil {
+		t.Errorf("got %+v", r)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{
+		"path":       imgPath,
+		"region1":    map[string]int{"x1": 0, "y1": 0, "x2": 10, "y2": 10},
+		"region2":    map[string]int{"x1": 10, "y1": 10, "x2": 20, "y2": 20},
+		"alpha_mode": "blend",
+	})
+	if _, err := s.executeTool("image_compare_regions", args); err == nil {
+		t.Error("expected error for an unknown alpha mode")
+	}
+}
+
+func TestHandleToolsCall_SideBySide_ChannelDiff(t *testing.T) {
+	s := New()
+	leftPath := createTestImageFile(t, 40, 30, color.RGBA{100, 100, 100, 255})
+	defer os.Remove(leftPath)
+	rightPath := createTestImageFile(t, 40, 30, color.RGBA{110, 100, 90, 255})
+	defer os.Remove(rightPath)
+
+	args, _ := json.Marshal(map[string]interface{}{
+		"left":         map[string]interface{}{"path": leftPath},
+		"right":        map[string]interface{}{"path": rightPath},
+		"channel_diff": true,
+	})
+	result, err := s.executeTool("image_side_by_side", args)
+	if err != nil {
+		t.Fatalf("image_side_by_side failed: %v", err)
+	}
+	cd := result.(*imaging.SideBySideResult).ChannelDiff
+	if cd == nil || cd.ChangeType != imaging.ChangeColorShift || len(cd.Channels) != 5 {
+		t.Fatalf("got %+v", cd)
+	}
+	if r, b := cd.Channels[0], cd.Channels[2]; r.MeanSignedDiff != 10 || b.MeanSignedDiff != -10 || r.PixelsChanged != 40*30 {
+		t.Errorf("got r %+v, b %+v", r, b)
+	}
+}
+
+func TestHandleToolsCall_SideBySide_FontDiff(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 40, 30, color.RGBA{100, 100, 100, 255})
+	defer os.Remove(imgPath)
+
+	args, _ := json.Marshal(map[string]interface{}{
+		"left":      map[string]interface{}{"path": imgPath},
+		"right":     map[string]interface{}{"path": imgPath},
+		"font_diff": true,
+	})
+	result, err := s.executeTool("image_side_by_side", args)
+	if err != nil {
+		t.Fatalf("image_side_by_side failed: %v", err)
+	}
+	r := result.(*imaging.SideBySideResult)
+	if r.FontDiff == nil || r.FontDiff.Compared != 0 || r.FontDiff.MinSimilarity != 1 || r.ChannelDiff != nil {
+		t.Errorf("no text: got %+v", r.FontDiff)
+	}
+}
+
+func TestHandleToolsCall_Denoise(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 40, 30, color.RGBA{200, 200, 200, 255})
+	defer os.Remove(imgPath)
+
+	args, _ := json.Marshal(map[string]interface{}{"path": imgPath, "method": "nlm"})
+	result, err := s.executeTool("image_denoise", args)
+	if err != nil {
+		t.Fatalf("image_denoise failed: %v", err)
+	}
+	if r := result.(*imaging.DenoiseResult); r.Width != 40 || r.Strength != 1 || r.NoiseSigma != 0 || r.ImageBase64 == "" {
+		t.Errorf("got %+v", r)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath, "denoise": "bilateral"})
+	if _, err := s.executeTool("image_detect_rectangles", args); err != nil {
+		t.Errorf("image_detect_rectangles with denoise failed: %v", err)
+	}
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath, "denoise": "median"})
+	if _, err := s.executeTool("image_detect_lines", args); err == nil {
+		t.Error("expected error for an unknown denoise method")
+	}
+}
+
+func TestHandleToolsCall_Normalize(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 40, 30, color.RGBA{200, 180, 140, 255})
+	defer os.Remove(imgPath)
+
+	args, _ := json.Marshal(map[string]interface{}{"path": imgPath})
+	result, err := s.executeTool("image_normalize", args)
+	if err != nil {
+		t.Fatalf("image_normalize failed: %v", err)
+	}
+	// A flat image is white balanced but too flat to stretch
+	r := result.(*imaging.NormalizeResult)
+	if r.Gains.R >= 1 || r.Gains.B <= 1 || r.MeanAfter != "#ADADAD" || r.WhitePoint != 255 || r.ImageBase64 == "" {
+		t.Errorf("got %+v", r)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath, "white_balance": false})
+	result, err = s.executeTool("image_normalize", args)
+	if err != nil {
+		t.Fatalf("image_normalize failed: %v", err)
+	}
+	if r := result.(*imaging.NormalizeResult); r.Gains != (imaging.ChannelGains{R: 1, G: 1, B: 1}) || r.MeanAfter != r.MeanBefore {
+		t.Errorf("got %+v", r)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath, "clip_percent": 20})
+	if _, err := s.executeTool("image_normalize", args); err == nil {
+		t.Error("expected error for clip_percent above 10")
+	}
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath, "normalize": true, "denoise": "bilateral"})
+	if _, err := s.executeTool("image_detect_rectangles", args); err != nil {
+		t.Errorf("image_detect_rectangles with normalize failed: %v", err)
+	}
+}
+
+func TestHandleToolsCall_ScanCleanup(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 40, 30, color.RGBA{200, 180, 140, 255})
+	defer os.Remove(imgPath)
+
+	args, _ := json.Marshal(map[string]interface{}{"path": imgPath})
+	result, err := s.executeTool("image_scan_cleanup", args)
+	if err != nil {
+		t.Fatalf("image_scan_cleanup failed: %v", err)
+	}
+	// A blank page has no edges to find and no text to straighten
+	r := result.(*imaging.ScanCleanupResult)
+	if r.Width != 40 || len(r.Notes) != 2 || r.Steps[len(r.Steps)-1] != imaging.ScanBinarize || r.ImageBase64 == "" {
+		t.Errorf("got %+v", r)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath, "perspective": false, "deskew": false, "binarize": false})
+	result, err = s.executeTool("image_scan_cleanup", args)
+	if err != nil {
+		t.Fatalf("image_scan_cleanup failed: %v", err)
+	}
+	if r := result.(*imaging.ScanCleanupResult); len(r.Steps) != 1 || r.Steps[0] != imaging.ScanShadowRemoval || r.Notes != nil {
+		t.Errorf("got %+v", r)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath, "window": 32})
+	if _, err := s.executeTool("image_scan_cleanup", args); err == nil {
+		t.Error("expected error for an even window")
+	}
+}
+
+func TestHandleToolsCall_CleanWhiteboard(t *testing.T) {
+	s := New()
+	img := image.NewRGBA(image.Rect(0, 0, 120, 80))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.RGBA{210, 215, 220, 255}}, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(10, 10, 110, 14), image.Black, image.Point{}, draw.Src)
+	imgPath := filepath.Join(t.TempDir(), "board.png")
+	f, err := os.Create(imgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	args, _ := json.Marshal(map[string]interface{}{"path": imgPath})
+	result, err := s.executeTool("image_clean_whiteboard", args)
+	if err != nil {
+		t.Fatalf("image_clean_whiteboard failed: %v", err)
+	}
+	r := result.(*imaging.WhiteboardResult)
+	if len(r.Lines) != 1 || r.Lines[0].Angle != 0 || r.Lines[0].StrokeWidth != 4 || !r.Snapped || r.ImageBase64 == "" {
+		t.Errorf("got %+v", r)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath, "snap": false})
+	result, err = s.executeTool("image_clean_whiteboard", args)
+	if err != nil {
+		t.Fatalf("image_clean_whiteboard failed: %v", err)
+	}
+	if r := result.(*imaging.WhiteboardResult); r.Snapped || len(r.Lines) != 1 {
+		t.Errorf("got %+v", r)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath, "tolerance": 60})
+	if _, err := s.executeTool("image_clean_whiteboard", args); err == nil {
+		t.Error("expected error for tolerance above 50")
+	}
+}
+
+func TestTagTextStyles(t *testing.T) {
+	// A row of upright glyph-like bars on a common baseline, and a blank
+	// area
+	img := image.NewRGBA(image.Rect(0, 0, 200, 60))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	for x := 10; x < 100; x += 9 {
+		draw.Draw(img, image.Rect(x, 10, x+3, 24), image.Black, image.Point{}, draw.Src)
+	}
+	regions := []ocr.TextRegionBox{
+		{Bounds: ocr.Bounds{X1: 5, Y1: 5, X2: 105, Y2: 30}, Confidence: 0.9},
+		{Bounds: ocr.Bounds{X1: 120, Y1: 5, X2: 190, Y2: 30}, Confidence: 0.6},
+	}
+	tagTextStyles(img, regions)
+	if regions[0].Style != detection.TextPrinted || regions[1].Style != detection.TextUnknown {
+		t.Errorf("got %+v", regions)
+	}
+}
+
+func TestHandleToolsCall_DetectFormulaRegions(t *testing.T) {
+	s := New()
+
+	// x = (three glyphs over three glyphs) + x², in blocks and bars
+	img := image.NewRGBA(image.Rect(0, 0, 120, 80))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	for _, r := range []image.Rectangle{
+		image.Rect(10, 40, 18, 52),                             // x
+		image.Rect(24, 43, 36, 45), image.Rect(24, 48, 36, 50), // =
+		image.Rect(42, 46, 80, 48), // Fraction bar
+		image.Rect(46, 30, 54, 42), image.Rect(58, 30, 66, 42), image.Rect(70, 30, 78, 42),
+		image.Rect(46, 52, 54, 64), image.Rect(58, 52, 66, 64), image.Rect(70, 52, 78, 64),
+		image.Rect(86, 43, 92, 49),                                // +
+		image.Rect(98, 40, 106, 52), image.Rect(108, 34, 114, 42), // x²
+	} {
+		draw.Draw(img, r, image.Black, image.Point{}, draw.Src)
+	}
+	formulaPath := filepath.Join(t.TempDir(), "formula.png")
+	f, err := os.Create(formulaPath)
+	if err != nil {
+		t.Fatalf("failed to create image: %v", err)
+	}
+	png.Encode(f, img)
+	f.Close()
+
+	args, _ := json.Marshal(map[string]interface{}{"path": formulaPath, "check_words": false})
+	result, err := s.executeTool("image_detect_formula_regions", args)
+	if err != nil {
+		t.Fatalf("image_detect_formula_regions failed: %v", err)
+	}
+	r := result.(*detection.FormulaRegionsResult)
+	if r.Count != 1 || r.WordsChecked || r.Regions[0].Symbols != 13 || r.Regions[0].Bounds != (detection.Bounds{X1: 10, Y1: 30, X2: 114, Y2: 64}) {
+		t.Errorf("got %+v", r)
+	}
+
+	imgPath := createTestImageFile(t, 40, 30, color.White)
+	defer os.Remove(imgPath)
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath, "min_confidence": 0.2})
+	result, err = s.executeTool("image_detect_formula_regions", args)
+	if err != nil {
+		t.Fatalf("image_detect_formula_regions failed: %v", err)
+	}
+	if r := result.(*detection.FormulaRegionsResult); r.Count != 0 || r.Regions == nil {
+		t.Errorf("blank image: got %+v", r)
+	}
+}
+
+func TestHandleToolsCall_OCRAlternatives(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 40, 30, color.White)
+	defer os.Remove(imgPath)
+
+	for _, tool := range []string{"image_ocr_full", "image_ocr_region"} {
+		args, _ := json.Marshal(map[string]interface{}{"path": imgPath, "x1": 0, "y1": 0, "x2": 40, "y2": 30, "alternatives": 11})
+		_, err := s.executeTool(tool, args)
+		if err == nil || !strings.Contains(err.Error(), "alternatives") {
+			t.Errorf("%s: expected error for 11 alternatives, got %v", tool, err)
+		}
+	}
+
+	if !ocr.GetOCRInfo().Available {
+		t.Skip("tesseract not available")
+	}
+	args, _ := json.Marshal(map[string]interface{}{"path": imgPath, "alternatives": 3})
+	result, err := s.executeTool("image_ocr_full", args)
+	if err != nil {
+		t.Fatalf("image_ocr_full failed: %v", err)
+	}
+	for _, region := range result.(*ocr.OCRResult).Regions {
+		if len(region.Alternatives) > 3 {
+			t.Errorf("got %d alternatives, want at most 3: %+v", len(region.Alternatives), region)
+		}
+	}
+}
+
+func TestApplyVocabulary(t *testing.T) {
+	result := &ocr.OCRResult{
+		FullText: "Cappucino 4.50",
+		Regions:  []ocr.TextRegion{{Text: "Cappucino", Confidence: 0.7}, {Text: "4.50", Confidence: 0.9}},
+	}
+	got, err := applyVocabulary(result, nil, 0)
+	if err != nil || got.Corrections != 0 || got.FullText != "Cappucino 4.50" {
+		t.Errorf("no vocabulary: got %+v, %v", got, err)
+	}
+
+	got, err = applyVocabulary(result, []string{"Cappuccino", "Latte"}, 0)
+	if err != nil {
+		t.Fatalf("applyVocabulary failed: %v", err)
+	}
+	if r := got.Regions[0]; got.Corrections != 1 || got.FullText != "Cappuccino 4.50" || r.Original != "Cappucino" || r.EditDistance != 1 {
+		t.Errorf("got %+v", got)
+	}
+
+	if _, err := applyVocabulary(result, []string{"Latte"}, 4); err == nil {
+		t.Error("expected error for max_distance above 3")
+	}
+}
+
+func TestHandleToolsCall_ExtractFields_InvalidFields(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 50, 50, color.RGBA{255, 255, 255, 255})
+	defer os.Remove(imgPath)
+
+	for _, fields := range []interface{}{
+		[]interface{}{},
+		[]interface{}{map[string]interface{}{"name": "total"}},
+		[]interface{}{map[string]interface{}{"labels": []string{"Total"}, "type": "[0-9"}},
+	} {
+		args, _ := json.Marshal(map[string]interface{}{"path": imgPath, "fields": fields})
+		if _, err := s.executeTool("image_extract_fields", args); err == nil {
+			t.Errorf("expected error for fields %v", fields)
+		}
+	}
+}
+
+func TestHandleToolsCall_AnalyzeForm_MissingFile(t *testing.T) {
+	s := New()
+	args, _ := json.Marshal(map[string]interface{}{"path": filepath.Join(t.TempDir(), "missing.png")})
+	if _, err := s.executeTool("image_analyze_form", args); err == nil {
+		t.Error("expected error for a missing file")
+	}
+}
+
+func TestHandleToolsCall_OCR_InvalidOptions(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 50, 50, color.RGBA{255, 255, 255, 255})
+	defer os.Remove(imgPath)
+
+	for _, opts := range []map[string]interface{}{
+		{"rotation": 45},
+		{"vertical": true},
+		{"min_confidence": 1.5},
+		{"unicode": "nfd"},
+		{"locale": "xx"},
+	} {
+		for _, tool := range []string{"image_ocr_full", "image_ocr_region"} {
+			args := map[string]interface{}{"path": imgPath, "x1": 0, "y1": 0, "x2": 50, "y2": 50}
+			for k, v := range opts {
+				args[k] = v
+			}
+			raw, _ := json.Marshal(args)
+			if _, err := s.executeTool(tool, raw); err == nil {
+				t.Errorf("%s: expected error for %v", tool, args)
+			}
+		}
+	}
+}
+
+func TestHandleToolsCall_TextDiff_MissingFile(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 50, 50, color.RGBA{255, 255, 255, 255})
+	defer os.Remove(imgPath)
+
+	args, _ := json.Marshal(map[string]interface{}{"path_a": filepath.Join(t.TempDir(), "missing.png"), "path_b": imgPath})
+	if _, err := s.executeTool("image_text_diff", args); err == nil {
+		t.Error("expected error for a missing file")
+	}
+}
+
+func TestHandleToolsCall_FromClipboard(t *testing.T) {
+	s := New()
+	s.SetCacheDir(t.TempDir())
+	imgPath := createTestImageFile(t, 40, 30, color.RGBA{0, 0, 255, 255})
+	defer os.Remove(imgPath)
+	data, err := os.ReadFile(imgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.readClipboard = func() ([]byte, error) { return data, nil }
+
+	result, err := s.executeTool("image_from_clipboard", json.RawMessage(`{}`))
+	if err != nil {
+		t.Fatalf("image_from_clipboard failed: %v", err)
+	}
+	r := result.(*ClipboardResult)
+	if r.Unchanged || r.Width != 40 || r.Height != 30 || r.Format != "png" || filepath.Dir(r.Path) != s.clipboardDir {
+		t.Errorf("got %+v", r)
+	}
+
+	// The saved image can be passed to other tools
+	args, _ := json.Marshal(map[string]interface{}{"path": r.Path, "x": 5, "y": 5})
+	if _, err := s.executeTool("image_sample_color", args); err != nil {
+		t.Errorf("image_sample_color on the clipboard image failed: %v", err)
+	}
+
+	again, err := s.executeTool("image_from_clipboard", json.RawMessage(`{}`))
+	if err != nil || !again.(*ClipboardResult).Unchanged || again.(*ClipboardResult).Path != r.Path {
+		t.Errorf("reading the same image again: got %+v, %v", again, err)
+	}
+
+	s.readClipboard = func() ([]byte, error) { return nil, clipboard.ErrNoImage }
+	if _, err := s.executeTool("image_from_clipboard", json.RawMessage(`{}`)); !errors.Is(err, clipboard.ErrNoImage) {
+		t.Errorf("empty clipboard: got %v, want ErrNoImage", err)
+	}
+}
+
+func TestHandleToolsCall_RelativeToViewport(t *testing.T) {
+	s := New()
+	// A browser as wide as the screenshot: a 40 px toolbar over a line
+	// and a white page
+	img := image.NewRGBA(image.Rect(0, 0, 400, 300))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{255, 255, 255, 255}), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 0, 400, 40), image.NewUniform(color.RGBA{230, 230, 230, 255}), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 40, 400, 41), image.NewUniform(color.RGBA{200, 200, 200, 255}), image.Point{}, draw.Src)
+	path := filepath.Join(t.TempDir(), "browser.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	want := imaging.Region{X1: 0, Y1: 41, X2: 400, Y2: 300}
+
+	args, _ := json.Marshal(map[string]interface{}{"path": path})
+	result, err := s.executeTool("image_detect_viewport", args)
+	if err != nil {
+		t.Fatalf("image_detect_viewport failed: %v", err)
+	}
+	if r := result.(*imaging.ViewportResult); !r.Found || *r.Viewport != want {
+		t.Errorf("got viewport %+v, want %+v", r.Viewport, want)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": path, "x1": 0, "y1": 0, "x2": 40, "y2": 0, "relative_to": "viewport"})
+	result, err = s.executeTool("image_measure_distance", args)
+	if err != nil {
+		t.Fatalf("image_measure_distance failed: %v", err)
+	}
+	if d := result.(*imaging.DistanceResult); d.Viewport == nil || *d.Viewport != want || d.DistancePercentWidth != 10 {
+		t.Errorf("got viewport %+v, %.1f%% of the width; want %+v, 10%%", d.Viewport, d.DistancePercentWidth, want)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": path, "relative_to": "viewport"})
+	result, err = s.executeTool("image_grid_overlay", args)
+	if err != nil {
+		t.Fatalf("image_grid_overlay failed: %v", err)
+	}
+	if g := result.(*imaging.GridOverlayResult); g.Width != 400 || g.Height != 259 || g.Viewport == nil {
+		t.Errorf("got a %d×%d grid, viewport %+v; want the 400×259 viewport", g.Width, g.Height, g.Viewport)
+	}
+
+	blank := createTestImageFile(t, 200, 200, color.RGBA{255, 255, 255, 255})
+	defer os.Remove(blank)
+	for _, bad := range []map[string]interface{}{
+		{"path": path, "relative_to": "page"},
+		{"path": blank, "relative_to": "viewport"},
+	} {
+		args, _ = json.Marshal(bad)
+		if _, err := s.executeTool("image_grid_overlay", args); err == nil {
+			t.Errorf("expected error for %v", bad)
+		}
+	}
+}
+
+func TestHandleToolsCall_StripDeviceChrome(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 200, 400, color.RGBA{255, 255, 255, 255})
+	defer os.Remove(imgPath)
+
+	// At density 1, the status bar is assumed 24 pixels tall
+	args, _ := json.Marshal(map[string]interface{}{"path": imgPath, "platform": "android", "scale": 1})
+	result, err := s.executeTool("image_strip_device_chrome", args)
+	if err != nil {
+		t.Fatalf("image_strip_device_chrome failed: %v", err)
+	}
+	if r := result.(*imaging.DeviceChromeResult); r.Removed.Top != 24 || r.Height != 376 || r.ImageBase64 == "" {
+		t.Errorf("got removed %+v, height %d", r.Removed, r.Height)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath, "platform": "android", "scale": 1, "status_bar": false})
+	result, err = s.executeTool("image_strip_device_chrome", args)
+	if err != nil {
+		t.Fatalf("image_strip_device_chrome failed: %v", err)
+	}
+	if r := result.(*imaging.DeviceChromeResult); r.Removed != (imaging.Insets{}) || r.StatusBar == nil || r.Height != 400 {
+		t.Errorf("status_bar false: got removed %+v, status bar %+v", r.Removed, r.StatusBar)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath, "platform": "windows"})
+	if _, err := s.executeTool("image_strip_device_chrome", args); err == nil {
+		t.Error("expected error for an invalid platform")
+	}
+}
+
+func TestHandleToolsCall_CheckSpecs(t *testing.T) {
+	s := New()
+	square := createTestImageFile(t, 100, 100, color.RGBA{255, 255, 255, 255})
+	defer os.Remove(square)
+	wide := createTestImageFile(t, 160, 90, color.RGBA{255, 255, 255, 255})
+	defer os.Remove(wide)
+
+	// 160×90 is 1.778, within the default 1% of 1.78
+	args, _ := json.Marshal(map[string]interface{}{"paths": []string{square, wide}, "aspect_ratio": 1.78, "formats": []string{"png"}})
+	result, err := s.executeTool("image_check_specs", args)
+	if err != nil {
+		t.Fatalf("image_check_specs failed: %v", err)
+	}
+	r := result.(*imaging.SpecsResult)
+	if r.Passed || r.Failed != 1 || r.Images[0].Passed || !r.Images[1].Passed {
+		t.Errorf("got passed %v, %d failed, images %+v", r.Passed, r.Failed, r.Images)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"paths": []string{wide}, "aspect_ratio": 1.78, "aspect_tolerance": 0})
+	result, err = s.executeTool("image_check_specs", args)
+	if err != nil {
+		t.Fatalf("image_check_specs failed: %v", err)
+	}
+	if r := result.(*imaging.SpecsResult); r.Passed {
+		t.Error("aspect_tolerance 0: expected 160×90 to fail 1.78")
+	}
+
+	for _, bad := range []map[string]interface{}{
+		{"paths": []string{}, "width": 100},
+		{"paths": []string{square}},
+		{"paths": []string{square}, "formats": []string{"webm"}},
+	} {
+		args, _ = json.Marshal(bad)
+		if _, err := s.executeTool("image_check_specs", args); err == nil {
+			t.Errorf("expected error for %v", bad)
+		}
+	}
+}
+
+func TestHandleToolsCall_SafeArea(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 1024, 500, color.RGBA{255, 255, 255, 255})
+	defer os.Remove(imgPath)
+
+	args, _ := json.Marshal(map[string]interface{}{"path": imgPath, "preset": "google_play_feature", "include_image": false})
+	result, err := s.executeTool("image_safe_area", args)
+	if err != nil {
+		t.Fatalf("image_safe_area failed: %v", err)
+	}
+	r := result.(*SafeAreaResult)
+	if !r.Passed || r.SafeArea != (imaging.Region{X1: 100, Y1: 50, X2: 924, Y2: 450}) || r.ImageBase64 != "" {
+		t.Errorf("got passed %v, safe area %+v, image %d bytes", r.Passed, r.SafeArea, len(r.ImageBase64))
+	}
+
+	for _, bad := range []map[string]interface{}{
+		{"path": imgPath},
+		{"path": imgPath, "preset": "myspace"},
+		{"path": imgPath, "preset": "tiktok", "checks": []string{"faces"}},
+	} {
+		args, _ = json.Marshal(bad)
+		if _, err := s.executeTool("image_safe_area", args); err == nil {
+			t.Errorf("expected error for %v", bad)
+		}
+	}
+}
+
+func TestHandleToolsCall_DetectPrintMarks(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 200, 200, color.RGBA{255, 255, 255, 255})
+	defer os.Remove(imgPath)
+
+	args, _ := json.Marshal(map[string]interface{}{"path": imgPath})
+	result, err := s.executeTool("image_detect_print_marks", args)
+	if err != nil {
+		t.Fatalf("image_detect_print_marks failed: %v", err)
+	}
+	r := result.(*PrintCheckResult)
+	if r.Found || r.DPI != 300 || r.DPISource != "default" {
+		t.Errorf("got found %v, %g DPI from %s", r.Found, r.DPI, r.DPISource)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath, "dpi": 150})
+	result, err = s.executeTool("image_detect_print_marks", args)
+	if err != nil {
+		t.Fatalf("image_detect_print_marks failed: %v", err)
+	}
+	if r := result.(*PrintCheckResult); r.DPI != 150 || r.DPISource != "argument" {
+		t.Errorf("got %g DPI from %s, want 150 from argument", r.DPI, r.DPISource)
+	}
+
+	for _, bad := range []map[string]interface{}{
+		{"path": imgPath, "dpi": -1},
+		{"path": imgPath, "safe_margin_mm": -3},
+		{"path": imgPath, "checks": []string{"barcodes"}},
+	} {
+		args, _ = json.Marshal(bad)
+		if _, err := s.executeTool("image_detect_print_marks", args); err == nil {
+			t.Errorf("expected error for %v", bad)
+		}
+	}
+}
+
+func TestHandleToolsCall_DetectHalo(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 40, 30, color.RGBA{200, 30, 30, 255})
+	defer os.Remove(imgPath)
+
+	args, _ := json.Marshal(map[string]interface{}{"path": imgPath, "include_image": false})
+	result, err := s.executeTool("image_detect_halo", args)
+	if err != nil {
+		t.Fatalf("image_detect_halo failed: %v", err)
+	}
+	r := result.(*imaging.HaloResult)
+	if r.HasAlpha || !r.Passed || r.ImageBase64 != "" {
+		t.Errorf("opaque image: got %+v", r)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath, "min_alpha": 300})
+	if _, err := s.executeTool("image_detect_halo", args); err == nil {
+		t.Error("expected error for min_alpha over 249")
+	}
+}
+
+func TestHandleToolsCall_StrokeWidthAudit(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 40, 30, color.RGBA{255, 255, 255, 255})
+	defer os.Remove(imgPath)
+
+	args, _ := json.Marshal(map[string]interface{}{"path": imgPath, "expected_width": 2})
+	result, err := s.executeTool("image_stroke_width_audit", args)
+	if err != nil {
+		t.Fatalf("image_stroke_width_audit failed: %v", err)
+	}
+	r := result.(*detection.StrokeAuditResult)
+	if r.Count != 0 || !r.Passed || r.ReferenceWidth != 0 || r.Tolerance != 0.5 {
+		t.Errorf("blank image: got %+v", r)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath, "tolerance": -1})
+	if _, err := s.executeTool("image_stroke_width_audit", args); err == nil {
+		t.Error("expected error for a negative tolerance")
+	}
+}
+
+func TestHandleToolsCall_CompareStates(t *testing.T) {
+	s := New()
+	normalPath := createTestImageFile(t, 40, 30, color.RGBA{220, 220, 220, 255})
+	defer os.Remove(normalPath)
+	hoverPath := createTestImageFile(t, 40, 30, color.RGBA{180, 200, 255, 255})
+	defer os.Remove(hoverPath)
+
+	args, _ := json.Marshal(map[string]interface{}{"path_a": normalPath, "path_b": hoverPath, "include_image": false})
+	result, err := s.executeTool("image_compare_states", args)
+	if err != nil {
+		t.Fatalf("image_compare_states failed: %v", err)
+	}
+	r := result.(*imaging.StateCompareResult)
+	if r.TotalChanges != 1 || r.Changes[0].Change != imaging.StateRecolored || r.ChangedPixels != 40*30 || r.ImageBase64 != "" {
+		t.Errorf("got %+v", r)
+	}
+
+	smallPath := createTestImageFile(t, 20, 30, color.RGBA{220, 220, 220, 255})
+	defer os.Remove(smallPath)
+	args, _ = json.Marshal(map[string]interface{}{"path_a": normalPath, "path_b": smallPath})
+	if _, err := s.executeTool("image_compare_states", args); err == nil {
+		t.Error("expected error for screenshots of different sizes")
+	}
+}
+
+func TestHandleToolsCall_DetectLoading(t *testing.T) {
+	s := New()
+	dir := t.TempDir()
+
+	// A skeleton list: three gray text bars on white
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	fill(img, 0, 0, 200, 100, color.RGBA{255, 255, 255, 255})
+	for i := 0; i < 3; i++ {
+		fill(img, 20, 20+25*i, 180, 32+25*i, color.RGBA{224, 224, 224, 255})
+	}
+	path := filepath.Join(dir, "skeleton.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	args, _ := json.Marshal(map[string]interface{}{"path": path})
+	result, err := s.executeTool("image_detect_loading", args)
+	if err != nil {
+		t.Fatalf("image_detect_loading failed: %v", err)
+	}
+	r := result.(*imaging.LoadingResult)
+	if !r.Loading || r.SkeletonBlocks != 3 || r.Indicators[0].Shape != imaging.PlaceholderBar {
+		t.Errorf("got %+v", r)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": path, "min_skeleton_blocks": 4})
+	result, err = s.executeTool("image_detect_loading", args)
+	if err != nil {
+		t.Fatalf("image_detect_loading failed: %v", err)
+	}
+	if result.(*imaging.LoadingResult).Loading {
+		t.Error("three placeholders should not make a skeleton screen at min_skeleton_blocks 4")
+	}
+}
+
+func TestHandleToolsCall_Georeference(t *testing.T) {
+	s := New()
+	// The single 256-pixel Web Mercator tile of the world at zoom 0
+	imgPath := createTestImageFile(t, 256, 256, color.RGBA{170, 211, 223, 255})
+	defer os.Remove(imgPath)
+
+	args, _ := json.Marshal(map[string]interface{}{
+		"path": imgPath,
+		"reference_points": []map[string]float64{
+			{"x": 128, "y": 128, "lat": 0, "lon": 0},
+			{"x": 256, "y": 0, "lat": 85.05112878, "lon": 179.9999999},
+		},
+		"pixels":      []map[string]float64{{"x": 192, "y": 128}},
+		"coordinates": []map[string]float64{{"lat": 0, "lon": -90}},
+	})
+	result, err := s.executeTool("image_georeference", args)
+	if err != nil {
+		t.Fatalf("image_georeference failed: %v", err)
+	}
+	r := result.(*GeoreferenceResult)
+	// 40,030 km of equator over 256 pixels, on the mean-radius sphere
+	if r.Projection != "mercator" || math.Abs(r.MetersPerPixel-156368) > 20 {
+		t.Errorf("got projection %q, %g m per pixel", r.Projection, r.MetersPerPixel)
+	}
+	if p := r.Pixels[0]; math.Abs(p.Lat) > 1e-6 || math.Abs(p.Lon-90) > 1e-6 {
+		t.Errorf("pixel = %+v, want 0°, 90°E", p)
+	}
+	if c := r.Coordinates[0]; math.Abs(c.X-64) > 0.01 || math.Abs(c.Y-128) > 0.01 {
+		t.Errorf("coordinate = %+v, want pixel (64, 128)", c)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": imgPath, "reference_points": []map[string]float64{{"x": 0, "y": 0, "lat": 0, "lon": 0}}})
+	if _, err := s.executeTool("image_georeference", args); err == nil {
+		t.Error("expected error for a single reference point")
+	}
+}
+
+func TestHandleToolsCall_DetectScaleBar(t *testing.T) {
+	s := New()
+
+	// A figure with a black scale bar 100 pixels long
+	img := image.NewRGBA(image.Rect(0, 0, 300, 200))
+	fill(img, 0, 0, 300, 200, color.RGBA{255, 255, 255, 255})
+	fill(img, 40, 160, 140, 164, color.RGBA{0, 0, 0, 255})
+	path := filepath.Join(t.TempDir(), "figure.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	call := func(tool string, args map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		params, _ := json.Marshal(map[string]interface{}{"name": tool, "arguments": args})
+		resp := s.handleToolsCall(&MCPRequest{JSONRPC: "2.0", ID: 1, Params: params})
+		if resp.Error != nil {
+			t.Fatalf("%s failed: %v", tool, resp.Error)
+		}
+		var result map[string]interface{}
+		if err := json.Unmarshal(resp.Result.(map[string]interface{})["structuredContent"].(json.RawMessage), &result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	measure := map[string]interface{}{"path": path, "x1": 10, "y1": 10, "x2": 40, "y2": 50}
+	if r := call("image_measure_distance", measure); r["calibrated"] != nil {
+		t.Errorf("uncalibrated image: got %v", r["calibrated"])
+	}
+
+	r := call("image_detect_scale_bar", map[string]interface{}{"path": path, "label": "2 mm"})
+	c, _ := r["calibration"].(map[string]interface{})
+	if r["found"] != true || c == nil || c["unit"] != "mm" || c["units_per_pixel"] != 0.02 {
+		t.Fatalf("got %v", r)
+	}
+
+	// The same measurement is now also in millimeters, not answered from
+	// the result cache
+	d, _ := call("image_measure_distance", measure)["calibrated"].(map[string]interface{})
+	if d == nil || d["distance"] != 1.0 || d["unit"] != "mm" {
+		t.Errorf("calibrated = %v, want 1 mm", d)
+	}
+}
+
+func TestHandleToolsCall_CountBlobs(t *testing.T) {
+	s := New()
+
+	// Six dark cells of radius 6 on a light background, with a 100-pixel
+	// scale bar below them
+	img := image.NewRGBA(image.Rect(0, 0, 240, 160))
+	fill(img, 0, 0, 240, 160, color.RGBA{230, 230, 230, 255})
+	for i := 0; i < 6; i++ {
+		cx, cy := 30+35*i, 50
+		for y := cy - 6; y <= cy+6; y++ {
+			for x := cx - 6; x <= cx+6; x++ {
+				if (x-cx)*(x-cx)+(y-cy)*(y-cy) <= 36 {
+					img.SetRGBA(x, y, color.RGBA{50, 50, 80, 255})
+				}
+			}
+		}
+	}
+	fill(img, 120, 130, 220, 134, color.RGBA{0, 0, 0, 255})
+	path := filepath.Join(t.TempDir(), "cells.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	args, _ := json.Marshal(map[string]interface{}{"path": path, "min_roundness": 0.5, "shape_descriptors": true})
+	result, err := s.executeTool("image_count_blobs", args)
+	if err != nil {
+		t.Fatalf("image_count_blobs failed: %v", err)
+	}
+	r := result.(*imaging.BlobResult)
+	if r.Count != 6 || r.EstimatedCount != 6 || r.Rejected.NotRound != 1 || r.Calibrated != nil {
+		t.Fatalf("got count %d, estimated %d, rejected %+v", r.Count, r.EstimatedCount, r.Rejected)
+	}
+	if b := r.Blobs[2]; b.CentroidX != 100 || b.CentroidY != 50 {
+		t.Errorf("third blob = %+v", b)
+	}
+	if sh := r.Blobs[2].Shape; sh == nil || sh.Eccentricity > 0.3 || sh.Solidity < 0.95 || sh.AspectRatio > 1.1 {
+		t.Errorf("third blob's shape = %+v", sh)
+	}
+
+	// After calibration, sizes are also in micrometers
+	args, _ = json.Marshal(map[string]interface{}{"path": path, "label": "50 µm"})
+	if _, err := s.executeTool("image_detect_scale_bar", args); err != nil {
+		t.Fatalf("image_detect_scale_bar failed: %v", err)
+	}
+	args, _ = json.Marshal(map[string]interface{}{"path": path, "min_roundness": 0.5})
+	result, err = s.executeTool("image_count_blobs", args)
+	if err != nil {
+		t.Fatalf("image_count_blobs failed: %v", err)
+	}
+	c := result.(*imaging.BlobResult).Calibrated
+	if c == nil || c.Unit != "µm" || c.UnitsPerPixel != 0.5 || math.Abs(c.MedianDiameter-r.Sizes.MedianDiameter/2) > 0.01 {
+		t.Errorf("calibrated = %+v", c)
+	}
+}
+
+func TestHandleToolsCall_GrowRegions(t *testing.T) {
+	s := New()
+
+	// Two gel bands, the second twice as wide, on a light background
+	img := image.NewRGBA(image.Rect(0, 0, 160, 80))
+	fill(img, 0, 0, 160, 80, color.RGBA{240, 240, 240, 255})
+	fill(img, 20, 30, 60, 40, color.RGBA{40, 40, 60, 255})
+	fill(img, 80, 30, 160, 40, color.RGBA{70, 70, 90, 255})
+	path := filepath.Join(t.TempDir(), "gel.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	args, _ := json.Marshal(map[string]interface{}{
+		"path":          path,
+		"seeds":         []map[string]interface{}{{"x": 40, "y": 35, "label": "lane 1"}, {"x": 120, "y": 35, "label": "lane 2"}},
+		"include_image": true,
+	})
+	result, err := s.executeTool("image_grow_regions", args)
+	if err != nil {
+		t.Fatalf("image_grow_regions failed: %v", err)
+	}
+	r := result.(*imaging.GrowResult)
+	one, two := r.Regions[0], r.Regions[1]
+	if one.Label != "lane 1" || one.Area != 400 || one.Perimeter != 100 || one.Color != "#28283C" {
+		t.Errorf("lane 1 = %+v", one)
+	}
+	if two.Area != 2*one.Area || two.Bounds != (imaging.Region{X1: 80, Y1: 30, X2: 160, Y2: 40}) || r.ImageBase64 == "" {
+		t.Errorf("lane 2 = %+v", two)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": path, "seeds": []map[string]int{{"x": 400, "y": 35}}})
+	if _, err := s.executeTool("image_grow_regions", args); err == nil {
+		t.Error("expected error for a seed outside the image")
+	}
+}
+
+func TestHandleToolsCall_LineProfile(t *testing.T) {
+	s := New()
+
+	// A white band between x 40 and 59 on black
+	img := image.NewRGBA(image.Rect(0, 0, 100, 30))
+	fill(img, 0, 0, 100, 30, color.RGBA{0, 0, 0, 255})
+	fill(img, 40, 0, 60, 30, color.RGBA{255, 255, 255, 255})
+	path := filepath.Join(t.TempDir(), "band.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	args, _ := json.Marshal(map[string]interface{}{"path": path, "x1": 10, "y1": 15, "x2": 90, "y2": 15, "width": 5})
+	result, err := s.executeTool("image_line_profile", args)
+	if err != nil {
+		t.Fatalf("image_line_profile failed: %v", err)
+	}
+	r := result.(*imaging.LineProfileResult)
+	if len(r.Luma) != 81 || r.Width != 5 || r.ImageBase64 == "" || r.Red != nil {
+		t.Fatalf("got %d samples, width %d", len(r.Luma), r.Width)
+	}
+	if len(r.Edges) != 2 || r.Edges[0].Direction != "rising" || r.Edges[0].X != 39.5 || r.Edges[1].Direction != "falling" || r.Edges[1].X != 59.5 {
+		t.Errorf("edges = %+v", r.Edges)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": path, "x1": 10, "y1": 15, "x2": 10, "y2": 15})
+	if _, err := s.executeTool("image_line_profile", args); err == nil {
+		t.Error("expected error for a zero-length line")
+	}
+}
+
+func TestHandleToolsCall_Geometry(t *testing.T) {
+	s := New()
+
+	// A floor plan with a 100-pixel scale bar
+	img := image.NewRGBA(image.Rect(0, 0, 200, 160))
+	fill(img, 0, 0, 200, 160, color.RGBA{255, 255, 255, 255})
+	fill(img, 50, 140, 150, 144, color.RGBA{0, 0, 0, 255})
+	path := filepath.Join(t.TempDir(), "plan.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	args, _ := json.Marshal(map[string]interface{}{"path": path, "x1": 100, "y1": 20, "vertex_x": 20, "vertex_y": 20, "x2": 100, "y2": 100})
+	result, err := s.executeTool("image_measure_angle", args)
+	if err != nil {
+		t.Fatalf("image_measure_angle failed: %v", err)
+	}
+	if a := result.(*imaging.AngleResult); a.AngleDegrees != 45 || a.Turn != "clockwise" {
+		t.Errorf("angle = %+v", a)
+	}
+
+	room := map[string]interface{}{"path": path, "points": []map[string]int{{"x": 20, "y": 20}, {"x": 120, "y": 20}, {"x": 120, "y": 70}, {"x": 20, "y": 70}}}
+	walls := map[string]interface{}{
+		"path":  path,
+		"line1": map[string]int{"x1": 20, "y1": 20, "x2": 120, "y2": 20},
+		"line2": map[string]int{"x1": 20, "y1": 70, "x2": 120, "y2": 70},
+	}
+	args, _ = json.Marshal(room)
+	result, err = s.executeTool("image_measure_polygon", args)
+	if err != nil {
+		t.Fatalf("image_measure_polygon failed: %v", err)
+	}
+	if p := result.(*imaging.PolygonResult); p.AreaPixels != 5000 || p.PerimeterPixels != 300 || !p.Convex || p.Calibrated != nil {
+		t.Errorf("polygon = %+v", p)
+	}
+
+	// After calibration, the room is also measured in meters
+	args, _ = json.Marshal(map[string]interface{}{"path": path, "label": "10 m"})
+	if _, err := s.executeTool("image_detect_scale_bar", args); err != nil {
+		t.Fatalf("image_detect_scale_bar failed: %v", err)
+	}
+	args, _ = json.Marshal(room)
+	result, err = s.executeTool("image_measure_polygon", args)
+	if err != nil {
+		t.Fatalf("image_measure_polygon failed: %v", err)
+	}
+	if c := result.(*imaging.PolygonResult).Calibrated; c == nil || c.Unit != "m" || c.Area != 50 || c.Perimeter != 30 {
+		t.Errorf("calibrated polygon = %+v", c)
+	}
+	args, _ = json.Marshal(walls)
+	result, err = s.executeTool("image_check_parallel", args)
+	if err != nil {
+		t.Fatalf("image_check_parallel failed: %v", err)
+	}
+	r := result.(*imaging.ParallelResult)
+	if !r.Parallel || r.Calibrated == nil || r.Calibrated.Separation != 5 || *r.OverlapPercent != 100 {
+		t.Errorf("parallel = %+v, calibrated %+v", r, r.Calibrated)
+	}
+
+	walls["tolerance"] = 45
+	args, _ = json.Marshal(walls)
+	if _, err := s.executeTool("image_check_parallel", args); err == nil {
+		t.Error("expected error for a 45° tolerance")
+	}
+}
+
+func TestHandleToolsCall_LineIntersections(t *testing.T) {
+	s := New()
+
+	// A box with a divider: two tees and four corners
+	img := image.NewRGBA(image.Rect(0, 0, 160, 120))
+	fill(img, 0, 0, 160, 120, color.RGBA{255, 255, 255, 255})
+	path := filepath.Join(t.TempDir(), "box.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	line := func(x1, y1, x2, y2 int) map[string]int {
+		return map[string]int{"x1": x1, "y1": y1, "x2": x2, "y2": y2}
+	}
+	args, _ := json.Marshal(map[string]interface{}{
+		"path": path,
+		"lines": []map[string]int{
+			line(10, 10, 150, 10), line(150, 10, 150, 110), line(150, 110, 10, 110), line(10, 110, 10, 10),
+			line(80, 12, 80, 108),
+		},
+	})
+	result, err := s.executeTool("image_line_intersections", args)
+	if err != nil {
+		t.Fatalf("image_line_intersections failed: %v", err)
+	}
+	r := result.(*detection.IntersectionsResult)
+	if r.Count != 6 || len(r.Lines) != 5 {
+		t.Fatalf("got %d junctions on %d lines: %+v", r.Count, len(r.Lines), r.Junctions)
+	}
+	kinds := map[string]int{}
+	for _, j := range r.Junctions {
+		kinds[j.Kind]++
+	}
+	if kinds["corner"] != 4 || kinds["tee"] != 2 {
+		t.Errorf("kinds = %v", kinds)
+	}
+	if tee := r.Junctions[1]; tee.X != 80 || tee.Y != 10 || tee.Kind != "tee" {
+		t.Errorf("top tee = %+v", tee)
+	}
+	if got := r.Lines[0].Junctions; len(got) != 3 {
+		t.Errorf("top line junctions = %v", got)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": path, "lines": []map[string]int{line(0, 0, 10, 10)}, "tolerance": 60})
+	if _, err := s.executeTool("image_line_intersections", args); err == nil {
+		t.Error("expected error for a tolerance of 60")
+	}
+}
+
+func TestHandleToolsCall_DetectGrid(t *testing.T) {
+	s := New()
+
+	// A 3x3 board ruled by 2-pixel lines every 40 pixels from 10 to 130
+	img := image.NewRGBA(image.Rect(0, 0, 140, 140))
+	fill(img, 0, 0, 140, 140, color.RGBA{255, 255, 255, 255})
+	for p := 10; p <= 130; p += 40 {
+		fill(img, 10, p, 132, p+2, color.RGBA{0, 0, 0, 255})
+		fill(img, p, 10, p+2, 132, color.RGBA{0, 0, 0, 255})
+	}
+	path := filepath.Join(t.TempDir(), "board.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	args, _ := json.Marshal(map[string]interface{}{"path": path, "cells": []map[string]int{{"row": 1, "col": 2}}})
+	result, err := s.executeTool("image_detect_grid", args)
+	if err != nil {
+		t.Fatalf("image_detect_grid failed: %v", err)
+	}
+	r := result.(*imaging.GridResult)
+	if r.RowCount != 3 || r.ColumnCount != 3 || !r.RegularRows || !r.RegularColumns || r.RowPitch != 40 {
+		t.Errorf("got %dx%d, pitch %v, regular %v/%v", r.RowCount, r.ColumnCount, r.RowPitch, r.RegularRows, r.RegularColumns)
+	}
+	if len(r.Cells) != 1 || r.Cells[0].Bounds != (imaging.Region{X1: 92, Y1: 52, X2: 130, Y2: 90}) {
+		t.Errorf("cells = %+v", r.Cells)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": path, "cells": []map[string]int{{"row": 3, "col": 0}}})
+	if _, err := s.executeTool("image_detect_grid", args); err == nil {
+		t.Error("expected error for a cell outside the grid")
+	}
+}
+
+func TestHandleToolsCall_ReadHeatmap(t *testing.T) {
+	s := New()
+
+	// Two weeks of a contribution graph on white: levels 0-4 and 0-1, with
+	// the last five days missing
+	levels := []color.RGBA{
+		{0xEB, 0xED, 0xF0, 255}, {0x9B, 0xE9, 0xA8, 255}, {0x40, 0xC4, 0x63, 255},
+		{0x30, 0xA1, 0x4E, 255}, {0x21, 0x6E, 0x39, 255},
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 40, 100))
+	fill(img, 0, 0, 40, 100, color.RGBA{255, 255, 255, 255})
+	for day := 0; day < 7; day++ {
+		fill(img, 5, 5+day*13, 15, 15+day*13, levels[day%5])
+	}
+	for day := 0; day < 2; day++ {
+		fill(img, 18, 5+day*13, 28, 15+day*13, levels[(7+day)%5])
+	}
+	path := filepath.Join(t.TempDir(), "contributions.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	args, _ := json.Marshal(map[string]interface{}{"path": path, "preset": "github"})
+	result, err := s.executeTool("image_read_heatmap", args)
+	if err != nil {
+		t.Fatalf("image_read_heatmap failed: %v", err)
+	}
+	r := result.(*imaging.HeatmapResult)
+	if r.Layout != "gaps" || r.RowCount != 7 || r.ColumnCount != 2 {
+		t.Fatalf("got %s layout, %dx%d", r.Layout, r.RowCount, r.ColumnCount)
+	}
+	if sum := r.Summary; sum.Valued != 9 || sum.Empty != 5 || sum.Sum != 16 || sum.Max != 4 {
+		t.Errorf("summary = %+v", sum)
+	}
+	if v := r.Values[1][1]; v == nil || *v != 3 {
+		t.Errorf("values = %v", r.Cells)
+	}
+
+	for _, a := range []map[string]interface{}{
+		{"path": path},
+		{"path": path, "preset": "plasma"},
+	} {
+		args, _ = json.Marshal(a)
+		if _, err := s.executeTool("image_read_heatmap", args); err == nil {
+			t.Errorf("%v: expected error", a)
+		}
+	}
+}
+
+func TestHandleToolsCall_ReadBoard(t *testing.T) {
+	s := New()
+
+	// A 3x3 board of 30-pixel cells with a black block at the top left and
+	// a red one at the bottom middle
+	img := image.NewRGBA(image.Rect(0, 0, 90, 90))
+	fill(img, 0, 0, 90, 90, color.RGBA{255, 255, 255, 255})
+	fill(img, 8, 8, 22, 22, color.RGBA{0, 0, 0, 255})
+	fill(img, 38, 68, 52, 82, color.RGBA{220, 0, 0, 255})
+	path := filepath.Join(t.TempDir(), "board.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	args, _ := json.Marshal(map[string]interface{}{
+		"path":         path,
+		"rows":         3,
+		"columns":      3,
+		"piece_colors": []map[string]string{{"label": "black", "color": "#000000"}, {"label": "red", "color": "#DC0000"}},
+		"templates":    []map[string]interface{}{{"path": path, "label": "block", "region": map[string]int{"x1": 0, "y1": 0, "x2": 30, "y2": 30}}},
+	})
+	result, err := s.executeTool("image_read_board", args)
+	if err != nil {
+		t.Fatalf("image_read_board failed: %v", err)
+	}
+	r := result.(*imaging.BoardResult)
+	if r.Board[0][0] != "black block" || r.Board[2][1] != "red block" || r.Occupied != 2 || r.Empty != 7 {
+		t.Errorf("board = %q, occupied %d", r.Board, r.Occupied)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": path, "rows": 3, "columns": 3, "templates": []map[string]string{{"path": path}}})
+	if _, err := s.executeTool("image_read_board", args); err == nil {
+		t.Error("expected error for a template without a label")
+	}
+}
+
+func TestHandleToolsCall_ReadIndicators(t *testing.T) {
+	s := New()
+
+	// A panel with a green LED, a red one, and one off
+	img := image.NewRGBA(image.Rect(0, 0, 60, 20))
+	fill(img, 0, 0, 60, 20, color.RGBA{200, 200, 200, 255})
+	fill(img, 6, 6, 14, 14, color.RGBA{30, 210, 60, 255})
+	fill(img, 26, 6, 34, 14, color.RGBA{230, 30, 30, 255})
+	fill(img, 46, 6, 54, 14, color.RGBA{40, 40, 40, 255})
+	path := filepath.Join(t.TempDir(), "panel.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	args, _ := json.Marshal(map[string]interface{}{
+		"path": path,
+		"indicators": []map[string]interface{}{
+			{"name": "power", "x": 10, "y": 10, "radius": 2},
+			{"name": "alarm", "x": 30, "y": 10, "radius": 2},
+			{"name": "standby", "region": map[string]int{"x1": 46, "y1": 6, "x2": 54, "y2": 14}},
+		},
+		"states": []map[string]interface{}{
+			{"name": "off", "lightness": []int{0, 25}},
+			{"name": "red", "hue": []int{340, 20}, "saturation": []int{50, 100}},
+			{"name": "green", "color": "#20D040"},
+		},
+	})
+	result, err := s.executeTool("image_read_indicators", args)
+	if err != nil {
+		t.Fatalf("image_read_indicators failed: %v", err)
+	}
+	r := result.(*imaging.IndicatorsResult)
+	if r.States["power"] != "green" || r.States["alarm"] != "red" || r.States["standby"] != "off" {
+		t.Errorf("states = %v", r.States)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": path, "indicators": []map[string]interface{}{{"name": "power", "x": 10, "y": 10}}, "states": []map[string]interface{}{{"name": "on"}}})
+	if _, err := s.executeTool("image_read_indicators", args); err == nil {
+		t.Error("expected error for a state without conditions")
+	}
+}
+
+func TestHandleToolsCall_ReadDisplay(t *testing.T) {
+	s := New()
+
+	// A black-on-white seven-segment reading of 42
+	img := image.NewRGBA(image.Rect(0, 0, 80, 60))
+	fill(img, 0, 0, 80, 60, color.RGBA{255, 255, 255, 255})
+	ink := color.RGBA{0, 0, 0, 255}
+	for _, r := range [][4]int{
+		{10, 16, 15, 29}, {16, 28, 28, 33}, {29, 16, 34, 29}, {29, 31, 34, 44},
+		{50, 10, 62, 15}, {63, 16, 68, 29}, {50, 28, 62, 33}, {44, 31, 49, 44}, {50, 45, 62, 50},
+	} {
+		fill(img, r[0], r[1], r[2], r[3], ink)
+	}
+	path := filepath.Join(t.TempDir(), "meter.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	args, _ := json.Marshal(map[string]interface{}{"path": path})
+	result, err := s.executeTool("image_read_display", args)
+	if err != nil {
+		t.Fatalf("image_read_display failed: %v", err)
+	}
+	r := result.(*imaging.DisplayResult)
+	if r.Text != "42" || r.Value == nil || *r.Value != 42 || r.Polarity != "dark" {
+		t.Errorf("read %q (%v), polarity %s", r.Text, r.Value, r.Polarity)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": path, "kind": "nixie"})
+	if _, err := s.executeTool("image_read_display", args); err == nil {
+		t.Error("expected error for an unknown kind")
+	}
+}
+
+func TestHandleToolsCall_ReadGauge(t *testing.T) {
+	s := New()
+
+	// A white face with a black needle pointing at 3 o'clock
+	img := image.NewRGBA(image.Rect(0, 0, 120, 120))
+	fill(img, 0, 0, 120, 120, color.RGBA{255, 255, 255, 255})
+	fill(img, 56, 56, 64, 64, color.RGBA{0, 0, 0, 255})
+	fill(img, 60, 59, 100, 62, color.RGBA{0, 0, 0, 255})
+	path := filepath.Join(t.TempDir(), "gauge.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	args, _ := json.Marshal(map[string]interface{}{
+		"path":      path,
+		"center":    map[string]int{"x": 60, "y": 60},
+		"radius":    50,
+		"min_angle": 225,
+		"max_angle": 135,
+		"min_value": 0,
+		"max_value": 100,
+	})
+	result, err := s.executeTool("image_read_gauge", args)
+	if err != nil {
+		t.Fatalf("image_read_gauge failed: %v", err)
+	}
+	r := result.(*imaging.GaugeResult)
+	if !r.Found || r.Value == nil || *r.Value < 82.5 || *r.Value > 84 || r.Calibration != "marks" {
+		t.Errorf("needle at %g, value %v, calibration %q", r.NeedleAngle, r.Value, r.Calibration)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": path, "min_angle": 225, "max_value": 100})
+	if _, err := s.executeTool("image_read_gauge", args); err == nil {
+		t.Error("expected error for a partial scale")
+	}
+}
+
+func TestHandleToolsCall_ReadClock(t *testing.T) {
+	s := New()
+
+	// A white face at 3:00: the hour hand to 3 o'clock, the minute hand to 12
+	img := image.NewRGBA(image.Rect(0, 0, 120, 120))
+	fill(img, 0, 0, 120, 120, color.RGBA{255, 255, 255, 255})
+	fill(img, 57, 57, 90, 64, color.RGBA{0, 0, 0, 255})
+	fill(img, 58, 15, 63, 62, color.RGBA{0, 0, 0, 255})
+	path := filepath.Join(t.TempDir(), "clock.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	args, _ := json.Marshal(map[string]interface{}{
+		"path":   path,
+		"center": map[string]int{"x": 60, "y": 60},
+		"radius": 50,
+	})
+	result, err := s.executeTool("image_read_clock", args)
+	if err != nil {
+		t.Fatalf("image_read_clock failed: %v", err)
+	}
+	r := result.(*imaging.ClockResult)
+	if !r.Found || r.Time != "3:00" || len(r.Hands) != 2 {
+		t.Errorf("read %q: %+v %v", r.Time, r.Hands, r.Warnings)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"path": path, "center": map[string]int{"x": 60, "y": 60}})
+	if _, err := s.executeTool("image_read_clock", args); err == nil {
+		t.Error("expected error for a center without a radius")
+	}
+}
+
+func TestHandleToolsCall_CompareRegionsHistogram(t *testing.T) {
+	s := New()
+
+	// Two copies of a half-black block, the second shifted 4 pixels
+	img := image.NewRGBA(image.Rect(0, 0, 80, 20))
+	fill(img, 0, 0, 80, 20, color.RGBA{255, 255, 255, 255})
+	fill(img, 5, 0, 25, 20, color.RGBA{0, 0, 0, 255})
+	fill(img, 49, 0, 69, 20, color.RGBA{0, 0, 0, 255})
+	path := filepath.Join(t.TempDir(), "shifted.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	args, _ := json.Marshal(map[string]interface{}{
+		"path":    path,
+		"region1": map[string]int{"x1": 0, "y1": 0, "x2": 40, "y2": 20},
+		"region2": map[string]int{"x1": 40, "y1": 0, "x2": 80, "y2": 20},
+		"method":  "histogram",
+	})
+	result, err := s.executeTool("image_compare_regions", args)
+	if err != nil {
+		t.Fatalf("image_compare_regions failed: %v", err)
+	}
+	r := result.(*imaging.CompareRegionsResult)
+	if r.SimilarityScore != 0.8 || r.Distribution == nil || r.Distribution.Similarity != 1 || r.Distribution.EarthMoversDistance != 0 {
+		t.Errorf("pixel %g, distribution %+v", r.SimilarityScore, r.Distribution)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{
+		"path":    path,
+		"region1": map[string]int{"x1": 0, "y1": 0, "x2": 40, "y2": 20},
+		"region2": map[string]int{"x1": 40, "y1": 0, "x2": 80, "y2": 20},
+		"method":  "emd",
+	})
+	if _, err := s.executeTool("image_compare_regions", args); err == nil {
+		t.Error("expected error for an unknown method")
+	}
+}
+
+func TestHandleToolsCall_CompareRegionsEdges(t *testing.T) {
+	s := New()
+
+	// The same bordered box in a light and a dark theme
+	img := image.NewRGBA(image.Rect(0, 0, 80, 40))
+	fill(img, 0, 0, 40, 40, color.RGBA{255, 255, 255, 255})
+	fill(img, 10, 10, 30, 30, color.RGBA{0, 0, 200, 255})
+	fill(img, 40, 0, 80, 40, color.RGBA{20, 20, 20, 255})
+	fill(img, 50, 10, 70, 30, color.RGBA{255, 200, 0, 255})
+	path := filepath.Join(t.TempDir(), "themes.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	args, _ := json.Marshal(map[string]interface{}{
+		"path":    path,
+		"region1": map[string]int{"x1": 0, "y1": 0, "x2": 40, "y2": 40},
+		"region2": map[string]int{"x1": 40, "y1": 0, "x2": 80, "y2": 40},
+		"method":  "edges",
+	})
+	result, err := s.executeTool("image_compare_regions", args)
+	if err != nil {
+		t.Fatalf("image_compare_regions failed: %v", err)
+	}
+	r := result.(*imaging.CompareRegionsResult)
+	if r.SimilarityScore != 0 || r.Edges == nil || r.Edges.Similarity < 0.9 || r.Distribution != nil {
+		t.Errorf("pixel %g, edges %+v", r.SimilarityScore, r.Edges)
+	}
+}
+
+func TestHandleToolsCall_MatchFeatures(t *testing.T) {
+	s := New()
+
+	// Overlapping boxes in many colors; the reference is a region of the
+	// whole
+	img := image.NewRGBA(image.Rect(0, 0, 200, 160))
+	fill(img, 0, 0, 200, 160, color.RGBA{128, 128, 128, 255})
+	for i := 0; i < 30; i++ {
+		x, y := (i*53)%180, (i*37)%140
+		c := color.RGBA{uint8(i * 83), uint8(i * 41), uint8(255 - i*29), 255}
+		fill(img, x, y, min(200, x+12+i%5*6), min(160, y+10+i%4*7), c)
+	}
+	path := filepath.Join(t.TempDir(), "scene.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	args, _ := json.Marshal(map[string]interface{}{
+		"reference": map[string]interface{}{"path": path, "region": map[string]int{"x1": 40, "y1": 30, "x2": 160, "y2": 130}},
+		"target":    map[string]interface{}{"path": path},
+	})
+	result, err := s.executeTool("image_match_features", args)
+	if err != nil {
+		t.Fatalf("image_match_features failed: %v", err)
+	}
+	r := result.(*imaging.FeatureMatchResult)
+	if !r.Found {
+		t.Fatalf("not found: %+v", r)
+	}
+	if tr := r.Transform; math.Abs(tr.Scale-1) > 0.02 || math.Abs(tr.Rotation) > 1 || math.Abs(tr.TranslateX-40) > 2 || math.Abs(tr.TranslateY-30) > 2 {
+		t.Errorf("transform: got %+v, want a move by (40,30)", tr)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{
+		"reference":    map[string]interface{}{"path": path},
+		"target":       map[string]interface{}{"path": path},
+		"max_features": -1,
+	})
+	if _, err := s.executeTool("image_match_features", args); err == nil {
+		t.Error("expected error for negative max_features")
+	}
+}
+
+func TestHandleToolsCall_FindTemplate(t *testing.T) {
+	s := New()
+
+	// Three copies of a small icon; the template is cut from the first
+	img := image.NewRGBA(image.Rect(0, 0, 160, 120))
+	fill(img, 0, 0, 160, 120, color.RGBA{255, 255, 255, 255})
+	for _, p := range []image.Point{{10, 10}, {90, 15}, {40, 80}} {
+		fill(img, p.X, p.Y, p.X+20, p.Y+20, color.RGBA{30, 90, 200, 255})
+		fill(img, p.X+5, p.Y+5, p.X+15, p.Y+10, color.RGBA{255, 255, 255, 255})
+		fill(img, p.X+8, p.Y+12, p.X+12, p.Y+18, color.RGBA{240, 180, 0, 255})
+	}
+	path := filepath.Join(t.TempDir(), "icons.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	// Searching a region still reports the image's coordinates
+	args, _ := json.Marshal(map[string]interface{}{
+		"template": map[string]interface{}{"path": path, "region": map[string]int{"x1": 8, "y1": 8, "x2": 32, "y2": 32}},
+		"target":   map[string]interface{}{"path": path, "region": map[string]int{"x1": 30, "y1": 0, "x2": 160, "y2": 120}},
+	})
+	result, err := s.executeTool("image_find_template", args)
+	if err != nil {
+		t.Fatalf("image_find_template failed: %v", err)
+	}
+	r := result.(*imaging.TemplateMatchResult)
+	if r.Count != 2 || r.TemplateWidth != 24 || r.BestScore != 1 {
+		t.Fatalf("got %+v", r)
+	}
+	got := map[imaging.Region]bool{r.Matches[0].Bounds: true, r.Matches[1].Bounds: true}
+	for _, want := range []imaging.Region{{X1: 88, Y1: 13, X2: 112, Y2: 37}, {X1: 38, Y1: 78, X2: 62, Y2: 102}} {
+		if !got[want] {
+			t.Errorf("no match at %+v: got %+v", want, r.Matches)
+		}
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{
+		"template":  map[string]interface{}{"path": path, "region": map[string]int{"x1": 8, "y1": 8, "x2": 32, "y2": 32}},
+		"target":    map[string]interface{}{"path": path},
+		"threshold": 2,
+	})
+	if _, err := s.executeTool("image_find_template", args); err == nil {
+		t.Error("expected error for threshold above 1")
+	}
+}
+
+func TestHandleToolsCall_ShapeDescriptors(t *testing.T) {
+	s := New()
+
+	// A dark 40x10 bar on white
+	img := image.NewRGBA(image.Rect(0, 0, 80, 60))
+	fill(img, 0, 0, 80, 60, color.RGBA{255, 255, 255, 255})
+	fill(img, 20, 25, 60, 35, color.RGBA{0, 0, 0, 255})
+	path := filepath.Join(t.TempDir(), "bar.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	args, _ := json.Marshal(map[string]interface{}{"path": path, "blob": 1})
+	result, err := s.executeTool("image_shape_descriptors", args)
+	if err != nil {
+		t.Fatalf("image_shape_descriptors failed: %v", err)
+	}
+	r := result.(*imaging.EnclosingShapes)
+	// Smoothing rounds off the bar's corners, but not its sides
+	if r.Blob == nil || r.Blob.Shape == nil || r.Points != r.Blob.Area {
+		t.Fatalf("blob: got %+v", r)
+	}
+	if r.Rectangle.Width != 40 || r.Rectangle.Height != 10 || r.Rectangle.Center != (imaging.PointF{X: 40, Y: 30}) {
+		t.Errorf("rectangle: got %+v, want 40x10 around (40,30)", r.Rectangle)
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{
+		"path":   path,
+		"points": []map[string]int{{"x": 0, "y": 0}, {"x": 10, "y": 0}, {"x": 10, "y": 10}, {"x": 0, "y": 10}, {"x": 5, "y": 5}},
+	})
+	result, err = s.executeTool("image_shape_descriptors", args)
+	if err != nil {
+		t.Fatalf("image_shape_descriptors failed: %v", err)
+	}
+	r = result.(*imaging.EnclosingShapes)
+	if len(r.Hull) != 4 || r.Circle.Center != (imaging.PointF{X: 5, Y: 5}) {
+		t.Errorf("points: got hull %v, circle %+v", r.Hull, r.Circle)
+	}
+
+	for _, bad := range []map[string]interface{}{
+		{"path": path},
+		{"path": path, "blob": 2},
+		{"path": path, "blob": 1, "points": []map[string]int{{"x": 0, "y": 0}}},
+	} {
+		args, _ = json.Marshal(bad)
+		if _, err := s.executeTool("image_shape_descriptors", args); err == nil {
+			t.Errorf("%v: expected an error", bad)
+		}
+	}
+}
+
+func TestHandleToolsCall_ToMermaid_InvalidOptions(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 50, 50, color.RGBA{255, 255, 255, 255})
+	defer os.Remove(imgPath)
+
+	for _, bad := range []map[string]interface{}{
+		{"path": imgPath, "direction": "RL"},
+		{"path": imgPath, "threshold": 300},
+		{"path": imgPath, "min_node_area": -5},
+		{"path": filepath.Join(t.TempDir(), "missing.png")},
+	} {
+		args, _ := json.Marshal(bad)
+		if _, err := s.executeTool("image_to_mermaid", args); err == nil {
+			t.Errorf("%v: expected an error", bad)
+		}
+	}
+}
+
+func TestHandleToolsCall_ExtractOrgChart(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 50, 50, color.RGBA{255, 255, 255, 255})
+	defer os.Remove(imgPath)
+
+	for _, bad := range []map[string]interface{}{
+		{"path": imgPath, "threshold": 300},
+		{"path": imgPath, "min_node_area": -5},
+		{"path": filepath.Join(t.TempDir(), "missing.png")},
+	} {
+		args, _ := json.Marshal(bad)
+		if _, err := s.executeTool("image_extract_org_chart", args); err == nil {
+			t.Errorf("%v: expected an error", bad)
+		}
+	}
+
+	// A blank page has no boxes to read
+	args, _ := json.Marshal(map[string]interface{}{"path": imgPath})
+	result, err := s.executeTool("image_extract_org_chart", args)
+	if err != nil {
+		t.Fatalf("image_extract_org_chart failed: %v", err)
+	}
+	if r := result.(*OrgChartResult); len(r.People) != 0 || r.Outline != "" {
+		t.Errorf("got %+v", r)
+	}
+}
+
+func TestHandleToolsCall_ExtractTopology(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 50, 50, color.RGBA{255, 255, 255, 255})
+	defer os.Remove(imgPath)
+
+	for _, bad := range []map[string]interface{}{
+		{"path": imgPath, "threshold": 300},
+		{"path": imgPath, "min_node_size": 4},
+		{"path": imgPath, "templates": []map[string]interface{}{{"path": imgPath}}},
+		{"path": filepath.Join(t.TempDir(), "missing.png")},
+	} {
+		args, _ := json.Marshal(bad)
+		if _, err := s.executeTool("image_extract_topology", args); err == nil {
+			t.Errorf("%v: expected an error", bad)
+		}
+	}
+
+	// A blank page has no devices to read
+	args, _ := json.Marshal(map[string]interface{}{"path": imgPath})
+	result, err := s.executeTool("image_extract_topology", args)
+	if err != nil {
+		t.Fatalf("image_extract_topology failed: %v", err)
+	}
+	if r := result.(*TopologyResult); len(r.Nodes) != 0 || len(r.Links) != 0 || r.Width != 50 {
+		t.Errorf("got %+v", r)
+	}
+}
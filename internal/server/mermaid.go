@@ -0,0 +1,212 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/ironsheep/image-tools-mcp/internal/detection"
+	"github.com/ironsheep/image-tools-mcp/internal/ocr"
+)
+
+// mermaidLabelReach is the farthest an edge label may be from its
+// connector, in text heights (the median height of the OCR words).
+const mermaidLabelReach = 3.0
+
+// MermaidResult is the result of image_to_mermaid: a flowchart as Mermaid
+// text, with the nodes and edges it was written from.
+type MermaidResult struct {
+	// Mermaid is the flowchart in Mermaid syntax.
+	Mermaid string `json:"mermaid"`
+
+	// Direction is the flowchart's direction: "TD" (top down) or "LR"
+	// (left to right).
+	Direction string `json:"direction"`
+
+	// Nodes are the flowchart's shapes, in reading order.
+	Nodes []MermaidNode `json:"nodes"`
+
+	// Edges are the connectors between the nodes.
+	Edges []MermaidEdge `json:"edges"`
+
+	// WordsScanned is the number of words OCR recognized. When it is
+	// zero, no text was read, so nodes and edges have no labels.
+	WordsScanned int `json:"words_scanned"`
+}
+
+// MermaidNode is one shape of the flowchart.
+type MermaidNode struct {
+	// ID is the node's ID in the Mermaid text: N1, N2, and so on.
+	ID string `json:"id"`
+
+	// Shape is "process", "decision", or "terminal", and Outline the
+	// drawn shape (see detection.FlowchartNode).
+	Shape   string `json:"shape"`
+	Outline string `json:"outline"`
+
+	// Label is the text inside the node.
+	Label string `json:"label,omitempty"`
+
+	// Bounds is the node's box, border included.
+	Bounds detection.Bounds `json:"bounds"`
+
+	// Confidence is the lowest OCR confidence (0.0-1.0) of the label's
+	// words.
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+// MermaidEdge is a connector between two nodes.
+type MermaidEdge struct {
+	// From and To are node IDs.
+	From string `json:"from"`
+	To   string `json:"to"`
+
+	// Arrow is where the connector has arrowheads: "end" at To, "both",
+	// or "none".
+	Arrow string `json:"arrow"`
+
+	// Label is the text beside the connector, such as "Yes" at a
+	// decision's exit.
+	Label string `json:"label,omitempty"`
+}
+
+// flowchartToMermaid labels the nodes and edges of chart with the OCR
+// words read from its image and writes it as a Mermaid flowchart in
+// direction: "TD", "LR", or "auto" (or empty) for the way most
+// connectors run.
+//
+// A node's label is the words whose centers are inside it. The other
+// words, split into phrases at wide gaps, each label the edge whose path
+// passes nearest, within mermaidLabelReach text heights.
+func flowchartToMermaid(chart *detection.FlowchartResult, words []ocr.TextRegion, direction string) *MermaidResult {
+	result := &MermaidResult{Nodes: []MermaidNode{}, Edges: []MermaidEdge{}, WordsScanned: len(words)}
+	contents := make([][]ocr.TextRegion, len(chart.Nodes))
+	var outside []ocr.TextRegion
+	heights := make([]int, 0, len(words))
+	for _, w := range words {
+		heights = append(heights, w.Bounds.Y2-w.Bounds.Y1)
+		cx, cy := (w.Bounds.X1+w.Bounds.X2)/2, (w.Bounds.Y1+w.Bounds.Y2)/2
+		in := -1
+		for i, n := range chart.Nodes {
+			if b := n.Inner; cx >= b.X1 && cx < b.X2 && cy >= b.Y1 && cy < b.Y2 {
+				in = i
+				break
+			}
+		}
+		if in < 0 {
+			outside = append(outside, w)
+		} else {
+			contents[in] = append(contents[in], w)
+		}
+	}
+	th := float64(formDefaultTextHeight)
+	if len(heights) > 0 {
+		sort.Ints(heights)
+		th = float64(heights[len(heights)/2])
+	}
+
+	for i, n := range chart.Nodes {
+		label, _, confidence := formValue(contents[i], false)
+		result.Nodes = append(result.Nodes, MermaidNode{
+			ID:         mermaidID(n.ID),
+			Shape:      n.Shape,
+			Outline:    n.Outline,
+			Label:      label,
+			Bounds:     n.Bounds,
+			Confidence: confidence,
+		})
+	}
+	labels := make([][]string, len(chart.Edges))
+	for _, p := range formPhrases(outside) {
+		cx, cy := float64(p.bounds.X1+p.bounds.X2)/2, float64(p.bounds.Y1+p.bounds.Y2)/2
+		nearest, distance := -1, mermaidLabelReach*th
+		for i, e := range chart.Edges {
+			if d := pathDistance(cx, cy, e.Path); d <= distance {
+				nearest, distance = i, d
+			}
+		}
+		if nearest >= 0 {
+			labels[nearest] = append(labels[nearest], p.text)
+		}
+	}
+
+	// Direction, from the runs of the connectors
+	if direction == "" || direction == "auto" {
+		var across, down float64
+		for _, e := range chart.Edges {
+			for k := 1; k < len(e.Path); k++ {
+				across += math.Abs(float64(e.Path[k].X - e.Path[k-1].X))
+				down += math.Abs(float64(e.Path[k].Y - e.Path[k-1].Y))
+			}
+		}
+		direction = "TD"
+		if across > down {
+			direction = "LR"
+		}
+	}
+	result.Direction = direction
+
+	var sb strings.Builder
+	sb.WriteString("flowchart " + direction + "\n")
+	for _, n := range result.Nodes {
+		left, right := "[", "]"
+		switch n.Outline {
+		case detection.OutlineDiamond:
+			left, right = "{", "}"
+		case detection.OutlineCircle:
+			left, right = "((", "))"
+		case detection.OutlineEllipse, detection.OutlineRounded:
+			left, right = "([", "])"
+		}
+		fmt.Fprintf(&sb, "    %s%s%s%s\n", n.ID, left, mermaidText(n.Label), right)
+	}
+	for i, e := range chart.Edges {
+		edge := MermaidEdge{From: mermaidID(e.From), To: mermaidID(e.To), Arrow: e.Arrow, Label: strings.Join(labels[i], " ")}
+		result.Edges = append(result.Edges, edge)
+		link := "-->"
+		switch e.Arrow {
+		case detection.ArrowBoth:
+			link = "<-->"
+		case detection.ArrowNone:
+			link = "---"
+		}
+		if edge.Label != "" {
+			link += "|" + mermaidText(edge.Label) + "|"
+		}
+		fmt.Fprintf(&sb, "    %s %s %s\n", edge.From, link, edge.To)
+	}
+	result.Mermaid = sb.String()
+	return result
+}
+
+// mermaidID returns the Mermaid ID of the node numbered id.
+func mermaidID(id int) string {
+	return fmt.Sprintf("N%d", id)
+}
+
+// mermaidText quotes text for a Mermaid label, escaping its quotes; empty
+// text is a space, so a node keeps its shape.
+func mermaidText(text string) string {
+	if text == "" {
+		text = " "
+	}
+	return `"` + strings.ReplaceAll(text, `"`, "#quot;") + `"`
+}
+
+// pathDistance returns the distance from x,y to the nearest point of the
+// polyline path.
+func pathDistance(x, y float64, path []detection.Point) float64 {
+	best := math.Inf(1)
+	for k := range path {
+		a := path[max(0, k-1)]
+		b := path[k]
+		vx, vy := float64(b.X-a.X), float64(b.Y-a.Y)
+		t := 0.0
+		if l2 := vx*vx + vy*vy; l2 > 0 {
+			t = math.Max(0, math.Min(1, ((x-float64(a.X))*vx+(y-float64(a.Y))*vy)/l2))
+		}
+		best = math.Min(best, math.Hypot(x-float64(a.X)-t*vx, y-float64(a.Y)-t*vy))
+	}
+	return best
+}
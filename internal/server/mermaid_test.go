@@ -0,0 +1,82 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/ironsheep/image-tools-mcp/internal/detection"
+	"github.com/ironsheep/image-tools-mcp/internal/ocr"
+)
+
+// chartWords returns OCR words at the given boxes.
+func chartWords(words map[string][4]int) []ocr.TextRegion {
+	var regions []ocr.TextRegion
+	for text, b := range words {
+		regions = append(regions, ocr.TextRegion{Text: text, Confidence: 0.9, Bounds: ocr.Bounds{X1: b[0], Y1: b[1], X2: b[2], Y2: b[3]}})
+	}
+	return regions
+}
+
+func TestFlowchartToMermaid(t *testing.T) {
+	chart := &detection.FlowchartResult{
+		Nodes: []detection.FlowchartNode{
+			{ID: 1, Shape: detection.FlowchartTerminal, Outline: detection.OutlineEllipse, Inner: detection.Bounds{X1: 142, Y1: 20, X2: 258, Y2: 60}},
+			{ID: 2, Shape: detection.FlowchartProcess, Outline: detection.OutlineRectangle, Inner: detection.Bounds{X1: 142, Y1: 102, X2: 258, Y2: 148}},
+			{ID: 3, Shape: detection.FlowchartDecision, Outline: detection.OutlineDiamond, Inner: detection.Bounds{X1: 133, Y1: 203, X2: 267, Y2: 297}},
+			{ID: 4, Shape: detection.FlowchartTerminal, Outline: detection.OutlineCircle, Inner: detection.Bounds{X1: 172, Y1: 372, X2: 228, Y2: 428}},
+		},
+		Edges: []detection.FlowchartEdge{
+			{From: 1, To: 2, Arrow: detection.ArrowEnd, Path: []detection.Point{{X: 200, Y: 63}, {X: 200, Y: 97}}},
+			{From: 2, To: 3, Arrow: detection.ArrowEnd, Path: []detection.Point{{X: 200, Y: 151}, {X: 200, Y: 197}}},
+			{From: 3, To: 2, Arrow: detection.ArrowEnd, Path: []detection.Point{{X: 271, Y: 250}, {X: 329, Y: 250}, {X: 329, Y: 125}, {X: 263, Y: 125}}},
+			{From: 3, To: 4, Arrow: detection.ArrowEnd, Path: []detection.Point{{X: 200, Y: 301}, {X: 200, Y: 367}}},
+		},
+	}
+	words := chartWords(map[string][4]int{
+		"Start":  {180, 34, 220, 46},
+		"Read":   {160, 118, 195, 132},
+		"\"id\"": {200, 118, 230, 132},
+		"Valid?": {180, 244, 220, 256},
+		"End":    {188, 394, 212, 406},
+		"No":     {290, 236, 310, 246},
+		"Yes":    {206, 325, 230, 337},
+		"Page":   {10, 440, 50, 452}, // far from everything
+	})
+
+	got := flowchartToMermaid(chart, words, "auto")
+	want := `flowchart TD
+    N1(["Start"])
+    N2["Read #quot;id#quot;"]
+    N3{"Valid?"}
+    N4(("End"))
+    N1 --> N2
+    N2 --> N3
+    N3 -->|"No"| N2
+    N3 -->|"Yes"| N4
+`
+	if got.Mermaid != want {
+		t.Errorf("got\n%s\nwant\n%s", got.Mermaid, want)
+	}
+	if got.Direction != "TD" || got.WordsScanned != len(words) || len(got.Nodes) != 4 || len(got.Edges) != 4 {
+		t.Errorf("got %+v", got)
+	}
+	if n := got.Nodes[2]; n.ID != "N3" || n.Shape != detection.FlowchartDecision || n.Label != "Valid?" || n.Confidence != 0.9 {
+		t.Errorf("node 3: got %+v", n)
+	}
+
+	// Unlabeled, left to right, undirected
+	chart = &detection.FlowchartResult{
+		Nodes: []detection.FlowchartNode{
+			{ID: 1, Outline: detection.OutlineRounded, Inner: detection.Bounds{X1: 0, Y1: 0, X2: 50, Y2: 30}},
+			{ID: 2, Outline: detection.OutlineRectangle, Inner: detection.Bounds{X1: 150, Y1: 0, X2: 200, Y2: 30}},
+		},
+		Edges: []detection.FlowchartEdge{{From: 1, To: 2, Arrow: detection.ArrowNone, Path: []detection.Point{{X: 52, Y: 15}, {X: 148, Y: 15}}}},
+	}
+	got = flowchartToMermaid(chart, nil, "")
+	want = "flowchart LR\n    N1([\" \"])\n    N2[\" \"]\n    N1 --- N2\n"
+	if got.Mermaid != want || got.Direction != "LR" {
+		t.Errorf("got %q, want %q", got.Mermaid, want)
+	}
+	if got = flowchartToMermaid(chart, nil, "TD"); got.Direction != "TD" {
+		t.Errorf("direction: got %s, want TD", got.Direction)
+	}
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ironsheep/image-tools-mcp/internal/detection"
+	"github.com/ironsheep/image-tools-mcp/internal/ocr"
+)
+
+// OrgChartResult is the result of image_extract_org_chart: the people of
+// an organizational chart and who reports to whom.
+type OrgChartResult struct {
+	// Outline is the hierarchy as an indented list, one person to a line
+	// under their manager, two spaces deeper per level.
+	Outline string `json:"outline"`
+
+	// People are the chart's boxes, in reading order.
+	People []OrgChartPerson `json:"people"`
+
+	// Links are the connectors that are not reporting lines: between
+	// boxes side by side, or to a second manager.
+	Links []OrgChartLink `json:"links,omitempty"`
+}
+
+// OrgChartPerson is one box of an org chart.
+type OrgChartPerson struct {
+	// ID is the box's number (1-based), in reading order.
+	ID int `json:"id"`
+
+	// Name is the box's first line of text, and Title the rest, or the
+	// other way around when the titles come first.
+	Name  string `json:"name,omitempty"`
+	Title string `json:"title,omitempty"`
+
+	// Bounds is the box, border included.
+	Bounds detection.Bounds `json:"bounds"`
+
+	// Parent is the ID of the person's manager: the box above, at the
+	// other end of a connector. 0 at the top of the chart.
+	Parent int `json:"parent,omitempty"`
+
+	// Children are the IDs of the people reporting to this one, left to
+	// right.
+	Children []int `json:"children"`
+
+	// Level is the depth in the hierarchy: 0 at the top.
+	Level int `json:"level"`
+
+	// Confidence is the lowest OCR confidence (0.0-1.0) of the box's
+	// words.
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+// OrgChartLink is a connector between two boxes that is not a reporting
+// line.
+type OrgChartLink struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// orgChart reads the hierarchy of an org chart from the boxes and
+// connectors of chart, with words[i] the OCR words read in its ith box.
+// The first line of a box's text is the person's name and the rest their
+// title, or the other way around if titleFirst is set.
+//
+// Each connector between a box and one wholly above its middle is a
+// reporting line, up to the box above; a box reached from several
+// above reports to the nearest, and the other connectors, like those
+// between boxes side by side, are links.
+func orgChart(chart *detection.FlowchartResult, words [][]ocr.TextRegion, titleFirst bool) *OrgChartResult {
+	result := &OrgChartResult{People: make([]OrgChartPerson, len(chart.Nodes))}
+	for i, n := range chart.Nodes {
+		p := OrgChartPerson{ID: n.ID, Bounds: n.Bounds, Children: []int{}}
+		var text string
+		text, _, p.Confidence = formValue(words[i], true)
+		if text != "" {
+			lines := strings.Split(text, "\n")
+			first, rest := lines[0], strings.Join(lines[1:], " ")
+			if titleFirst {
+				p.Title, p.Name = first, rest
+			} else {
+				p.Name, p.Title = first, rest
+			}
+		}
+		result.People[i] = p
+	}
+
+	// above reports whether box a is wholly above the middle of box b
+	above := func(a, b int) bool {
+		ba, bb := chart.Nodes[a-1].Bounds, chart.Nodes[b-1].Bounds
+		return 2*ba.Y2 <= bb.Y1+bb.Y2
+	}
+	var links []OrgChartLink
+	for _, e := range chart.Edges {
+		upper, lower := e.From, e.To
+		if above(lower, upper) {
+			upper, lower = lower, upper
+		}
+		if !above(upper, lower) {
+			links = append(links, OrgChartLink{From: e.From, To: e.To})
+			continue
+		}
+		p := &result.People[lower-1]
+		switch {
+		case p.Parent == upper:
+			// A second connector between the same boxes
+		case p.Parent == 0:
+			p.Parent = upper
+		case chart.Nodes[upper-1].Bounds.Y2 > chart.Nodes[p.Parent-1].Bounds.Y2:
+			links = append(links, OrgChartLink{From: p.Parent, To: lower})
+			p.Parent = upper
+		default:
+			links = append(links, OrgChartLink{From: upper, To: lower})
+		}
+	}
+	sort.SliceStable(links, func(i, j int) bool {
+		if links[i].From != links[j].From {
+			return links[i].From < links[j].From
+		}
+		return links[i].To < links[j].To
+	})
+	result.Links = links
+
+	for _, p := range result.People {
+		if p.Parent > 0 {
+			parent := &result.People[p.Parent-1]
+			parent.Children = append(parent.Children, p.ID)
+		}
+	}
+	for i := range result.People {
+		children := result.People[i].Children
+		sort.SliceStable(children, func(a, b int) bool {
+			return chart.Nodes[children[a]-1].Bounds.X1 < chart.Nodes[children[b]-1].Bounds.X1
+		})
+	}
+
+	var sb strings.Builder
+	var walk func(id, level int)
+	walk = func(id, level int) {
+		p := &result.People[id-1]
+		p.Level = level
+		label := p.Name
+		switch {
+		case label != "" && p.Title != "":
+			label += ", " + p.Title
+		case label == "" && p.Title != "":
+			label = p.Title
+		case label == "":
+			label = fmt.Sprintf("Box %d", p.ID)
+		}
+		sb.WriteString(strings.Repeat("  ", level) + label + "\n")
+		for _, c := range p.Children {
+			walk(c, level+1)
+		}
+	}
+	for _, p := range result.People {
+		if p.Parent == 0 {
+			walk(p.ID, 0)
+		}
+	}
+	result.Outline = sb.String()
+	return result
+}
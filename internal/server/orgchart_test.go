@@ -0,0 +1,94 @@
+package server
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/ironsheep/image-tools-mcp/internal/detection"
+	"github.com/ironsheep/image-tools-mcp/internal/ocr"
+)
+
+func TestOrgChart(t *testing.T) {
+	// A CEO over three reports joined by one elbowed bus, a report under
+	// the first, and a line between the second and third
+	img := image.NewGray(image.Rect(0, 0, 400, 330))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	ink := func(x1, y1, x2, y2 int) {
+		draw.Draw(img, image.Rect(x1, y1, x2, y2), &image.Uniform{color.Gray{}}, image.Point{}, draw.Src)
+	}
+	box := func(x1, y1, x2, y2 int) {
+		ink(x1, y1, x2, y1+2)
+		ink(x1, y2-2, x2, y2)
+		ink(x1, y1, x1+2, y2)
+		ink(x2-2, y1, x2, y2)
+	}
+	box(150, 20, 250, 70)
+	box(20, 150, 120, 200)
+	box(150, 150, 250, 200)
+	box(280, 150, 380, 200)
+	box(20, 260, 120, 310)
+	ink(199, 70, 201, 110)
+	ink(69, 109, 331, 111)
+	for _, x := range []int{69, 199, 329} {
+		ink(x, 110, x+2, 150)
+	}
+	ink(69, 200, 71, 260)
+	ink(250, 174, 280, 176)
+
+	chart, err := detection.ExtractFlowchart(img, detection.FlowchartOptions{MinNodeArea: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chart.Nodes) != 5 {
+		t.Fatalf("got %d boxes, want 5: %+v", len(chart.Nodes), chart.Nodes)
+	}
+	word := func(text string, x1, y1, x2 int) ocr.TextRegion {
+		return ocr.TextRegion{Text: text, Confidence: 0.9, Bounds: ocr.Bounds{X1: x1, Y1: y1, X2: x2, Y2: y1 + 10}}
+	}
+	words := [][]ocr.TextRegion{
+		{word("Ada", 160, 30, 185), word("Lovelace", 190, 30, 240), word("CEO", 185, 48, 215)},
+		{word("Grace", 30, 160, 70), word("Hopper", 75, 160, 110), word("VP", 30, 178, 45), word("Engineering", 50, 178, 110)},
+		{word("Alan", 160, 160, 190), word("Turing", 195, 160, 240)},
+		nil,
+		{word("Tim", 30, 270, 55), word("Berners-Lee", 60, 270, 115), word("Architect", 40, 288, 100)},
+	}
+
+	got := orgChart(chart, words, false)
+	want := `Ada Lovelace, CEO
+  Grace Hopper, VP Engineering
+    Tim Berners-Lee, Architect
+  Alan Turing
+  Box 4
+`
+	if got.Outline != want {
+		t.Errorf("got\n%s\nwant\n%s", got.Outline, want)
+	}
+	parents := []int{0, 1, 1, 1, 2}
+	levels := []int{0, 1, 1, 1, 2}
+	for i, p := range got.People {
+		if p.ID != i+1 || p.Parent != parents[i] || p.Level != levels[i] {
+			t.Errorf("person %d: got %+v, want parent %d at level %d", i+1, p, parents[i], levels[i])
+		}
+	}
+	if c := got.People[0].Children; len(c) != 3 || c[0] != 2 || c[1] != 3 || c[2] != 4 {
+		t.Errorf("children of 1: got %v", c)
+	}
+	if p := got.People[1]; p.Name != "Grace Hopper" || p.Title != "VP Engineering" || p.Confidence != 0.9 {
+		t.Errorf("person 2: got %+v", p)
+	}
+	if len(got.Links) != 1 || got.Links[0] != (OrgChartLink{From: 3, To: 4}) {
+		t.Errorf("links: got %+v", got.Links)
+	}
+
+	// Titles first, and a second manager above
+	chart.Edges = append(chart.Edges, detection.FlowchartEdge{From: 3, To: 5, Arrow: detection.ArrowNone})
+	got = orgChart(chart, words, true)
+	if p := got.People[0]; p.Name != "CEO" || p.Title != "Ada Lovelace" {
+		t.Errorf("titles first: got %+v", p)
+	}
+	if got.People[4].Parent != 2 || len(got.Links) != 2 || got.Links[1] != (OrgChartLink{From: 3, To: 5}) {
+		t.Errorf("second manager: got parent %d, links %+v", got.People[4].Parent, got.Links)
+	}
+}
@@ -0,0 +1,238 @@
+package server
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/ironsheep/image-tools-mcp/internal/baseline"
+	"github.com/ironsheep/image-tools-mcp/internal/detection"
+	"github.com/ironsheep/image-tools-mcp/internal/imaging"
+	"github.com/ironsheep/image-tools-mcp/internal/ocr"
+)
+
+// toolResults maps each tool to the type its handler returns (through a
+// pointer). The tool's outputSchema is generated from this type, so the
+// schema follows the result structs as they change.
+var toolResults = map[string]reflect.Type{
+	// Basic Image Information
+	"image_load":           reflect.TypeOf(imaging.ImageInfo{}),
+	"image_from_clipboard": reflect.TypeOf(ClipboardResult{}),
+	"image_dimensions":     reflect.TypeOf(imaging.DimensionsResult{}),
+	"image_extract_icon":   reflect.TypeOf(imaging.IconExtractResult{}),
+	"image_thumbnail":      reflect.TypeOf(imaging.ThumbnailResult{}),
+
+	// Region Operations
+	"image_crop":          reflect.TypeOf(imaging.CropResult{}),
+	"image_crop_quadrant": reflect.TypeOf(imaging.CropResult{}),
+	"image_split_sprites": reflect.TypeOf(imaging.SpriteSheetResult{}),
+	"image_smart_crop":    reflect.TypeOf(imaging.SmartCropResult{}),
+
+	// Color Operations
+	"image_sample_color":        reflect.TypeOf(imaging.ColorResult{}),
+	"image_sample_colors_multi": reflect.TypeOf(imaging.MultiColorResult{}),
+	"image_dominant_colors":     reflect.TypeOf(imaging.DominantColorsResult{}),
+	"image_check_palette":       reflect.TypeOf(imaging.PaletteResult{}),
+	"image_compare_palettes":    reflect.TypeOf(imaging.PaletteComparisonResult{}),
+	"image_count_colors":        reflect.TypeOf(imaging.ColorCountResult{}),
+	"image_read_indicators":     reflect.TypeOf(imaging.IndicatorsResult{}),
+
+	// Measurement Operations
+	"image_measure_distance":  reflect.TypeOf(imaging.DistanceResult{}),
+	"image_grid_overlay":      reflect.TypeOf(imaging.GridOverlayResult{}),
+	"image_georeference":      reflect.TypeOf(GeoreferenceResult{}),
+	"image_detect_scale_bar":  reflect.TypeOf(imaging.ScaleBarResult{}),
+	"image_count_blobs":       reflect.TypeOf(imaging.BlobResult{}),
+	"image_line_profile":      reflect.TypeOf(imaging.LineProfileResult{}),
+	"image_measure_angle":     reflect.TypeOf(imaging.AngleResult{}),
+	"image_measure_polygon":   reflect.TypeOf(imaging.PolygonResult{}),
+	"image_check_parallel":    reflect.TypeOf(imaging.ParallelResult{}),
+	"image_shape_descriptors": reflect.TypeOf(imaging.EnclosingShapes{}),
+
+	// OCR Operations
+	"image_ocr_full":            reflect.TypeOf(ocr.OCRResult{}),
+	"image_ocr_region":          reflect.TypeOf(ocr.OCRResult{}),
+	"image_detect_text_regions": reflect.TypeOf(ocr.DetectTextRegionsResult{}),
+	"image_redact":              reflect.TypeOf(imaging.RedactResult{}),
+	"image_detect_pii":          reflect.TypeOf(PIIResult{}),
+	"image_extract_fields":      reflect.TypeOf(FieldsResult{}),
+	"image_analyze_form":        reflect.TypeOf(FormResult{}),
+	"image_to_mermaid":          reflect.TypeOf(MermaidResult{}),
+	"image_extract_org_chart":   reflect.TypeOf(OrgChartResult{}),
+	"image_extract_topology":    reflect.TypeOf(TopologyResult{}),
+	"image_text_diff":           reflect.TypeOf(TextDiffResult{}),
+	"image_read_display":        reflect.TypeOf(imaging.DisplayResult{}),
+
+	// Shape Detection
+	"image_detect_rectangles":  reflect.TypeOf(detection.RectanglesResult{}),
+	"image_detect_lines":       reflect.TypeOf(detection.LinesResult{}),
+	"image_detect_circles":     reflect.TypeOf(detection.CirclesResult{}),
+	"image_edge_detect":        reflect.TypeOf(imaging.EdgeDetectResult{}),
+	"image_skeletonize":        reflect.TypeOf(imaging.SkeletonResult{}),
+	"image_detect_incremental": reflect.TypeOf(detection.IncrementalResult{}),
+	"image_line_intersections": reflect.TypeOf(detection.IntersectionsResult{}),
+
+	// Analysis Helpers
+	"image_check_alignment":        reflect.TypeOf(imaging.AlignmentResult{}),
+	"image_compare_regions":        reflect.TypeOf(imaging.CompareRegionsResult{}),
+	"image_align":                  reflect.TypeOf(imaging.AlignResult{}),
+	"image_infer_nine_patch":       reflect.TypeOf(imaging.NinePatchResult{}),
+	"image_check_centering":        reflect.TypeOf(imaging.CenteringResult{}),
+	"image_find_empty_regions":     reflect.TypeOf(imaging.EmptyRegionsResult{}),
+	"image_check_overlaps":         reflect.TypeOf(detection.OverlapResult{}),
+	"image_find_repeats":           reflect.TypeOf(detection.RepeatsResult{}),
+	"image_detect_periodicity":     reflect.TypeOf(imaging.PeriodicityResult{}),
+	"image_detect_rows":            reflect.TypeOf(imaging.RowsResult{}),
+	"image_segment_regions":        reflect.TypeOf(imaging.SegmentResult{}),
+	"image_classify_content":       reflect.TypeOf(imaging.ClassifyContentResult{}),
+	"image_is_blank":               reflect.TypeOf(imaging.BlankResult{}),
+	"image_detect_artifacts":       reflect.TypeOf(imaging.ArtifactsResult{}),
+	"image_detect_moire":           reflect.TypeOf(imaging.MoireResult{}),
+	"image_inspect_channels":       reflect.TypeOf(imaging.ChannelInspection{}),
+	"image_near_duplicate":         reflect.TypeOf(imaging.NearDuplicateResult{}),
+	"image_generate_report":        reflect.TypeOf(ImageReport{}),
+	"image_accessibility_audit":    reflect.TypeOf(AccessibilityAudit{}),
+	"image_denoise":                reflect.TypeOf(imaging.DenoiseResult{}),
+	"image_normalize":              reflect.TypeOf(imaging.NormalizeResult{}),
+	"image_scan_cleanup":           reflect.TypeOf(imaging.ScanCleanupResult{}),
+	"image_clean_whiteboard":       reflect.TypeOf(imaging.WhiteboardResult{}),
+	"image_detect_formula_regions": reflect.TypeOf(detection.FormulaRegionsResult{}),
+	"image_detect_window_chrome":   reflect.TypeOf(imaging.WindowChromeResult{}),
+	"image_detect_viewport":        reflect.TypeOf(imaging.ViewportResult{}),
+	"image_strip_device_chrome":    reflect.TypeOf(imaging.DeviceChromeResult{}),
+	"image_check_specs":            reflect.TypeOf(imaging.SpecsResult{}),
+	"image_safe_area":              reflect.TypeOf(SafeAreaResult{}),
+	"image_detect_print_marks":     reflect.TypeOf(PrintCheckResult{}),
+	"image_detect_halo":            reflect.TypeOf(imaging.HaloResult{}),
+	"image_stroke_width_audit":     reflect.TypeOf(detection.StrokeAuditResult{}),
+	"image_compare_states":         reflect.TypeOf(imaging.StateCompareResult{}),
+	"image_detect_loading":         reflect.TypeOf(imaging.LoadingResult{}),
+	"image_grow_regions":           reflect.TypeOf(imaging.GrowResult{}),
+	"image_detect_grid":            reflect.TypeOf(imaging.GridResult{}),
+	"image_read_heatmap":           reflect.TypeOf(imaging.HeatmapResult{}),
+	"image_read_board":             reflect.TypeOf(imaging.BoardResult{}),
+	"image_read_gauge":             reflect.TypeOf(imaging.GaugeResult{}),
+	"image_read_clock":             reflect.TypeOf(imaging.ClockResult{}),
+	"image_match_features":         reflect.TypeOf(imaging.FeatureMatchResult{}),
+	"image_find_template":          reflect.TypeOf(imaging.TemplateMatchResult{}),
+
+	// Composition
+	"image_contact_sheet": reflect.TypeOf(imaging.ContactSheetResult{}),
+	"image_side_by_side":  reflect.TypeOf(imaging.SideBySideResult{}),
+
+	// Visual Regression
+	"image_baseline_store":   reflect.TypeOf(baseline.SaveResult{}),
+	"image_baseline_compare": reflect.TypeOf(baseline.CompareResult{}),
+}
+
+var rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+
+// outputSchema returns a JSON Schema describing values of type t as
+// encoding/json marshals them.
+//
+// Struct fields are named by their json tags; fields without omitempty are
+// required, and embedded structs are flattened. Nil slices, maps, and
+// pointers marshal as null, so those fields also allow null unless they
+// are omitted when empty. json.RawMessage and interfaces accept anything.
+func outputSchema(t reflect.Type) map[string]interface{} {
+	if t == rawMessageType {
+		return map[string]interface{}{}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return nullable(outputSchema(t.Elem()))
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		required := []string{}
+		addStructFields(t, properties, &required)
+		schema := map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+		}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return nullable(map[string]interface{}{"type": "string"}) // base64
+		}
+		return nullable(map[string]interface{}{
+			"type":  "array",
+			"items": outputSchema(t.Elem()),
+		})
+	case reflect.Array:
+		return map[string]interface{}{
+			"type":     "array",
+			"items":    outputSchema(t.Elem()),
+			"minItems": t.Len(),
+			"maxItems": t.Len(),
+		}
+	case reflect.Map:
+		return nullable(map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": outputSchema(t.Elem()),
+		})
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// addStructFields adds the JSON fields of struct type t to properties,
+// recording those that are always present in required.
+func addStructFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			addStructFields(field.Type, properties, required)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		omitEmpty := strings.Contains(opts, "omitempty")
+		schema := outputSchema(field.Type)
+		if omitEmpty {
+			// An omitted field is absent rather than null
+			schema = nonNullable(schema)
+		} else {
+			*required = append(*required, name)
+		}
+		properties[name] = schema
+	}
+}
+
+// nullable widens schema to also accept null.
+func nullable(schema map[string]interface{}) map[string]interface{} {
+	if typ, ok := schema["type"].(string); ok {
+		schema["type"] = []string{typ, "null"}
+	}
+	return schema
+}
+
+// nonNullable undoes nullable.
+func nonNullable(schema map[string]interface{}) map[string]interface{} {
+	if types, ok := schema["type"].([]string); ok && len(types) == 2 && types[1] == "null" {
+		schema["type"] = types[0]
+	}
+	return schema
+}
@@ -0,0 +1,233 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestOutputSchema(t *testing.T) {
+	type inner struct {
+		X int `json:"x"`
+	}
+	type embedded struct {
+		Shared string `json:"shared"`
+	}
+	type result struct {
+		embedded
+		Name     string            `json:"name"`
+		Count    uint8             `json:"count"`
+		Ratio    float64           `json:"ratio,omitempty"`
+		Point    *inner            `json:"point,omitempty"`
+		Parent   *inner            `json:"parent"`
+		Items    []inner           `json:"items"`
+		Tags     map[string]string `json:"tags,omitempty"`
+		Triple   [3]int            `json:"triple"`
+		Raw      json.RawMessage   `json:"raw"`
+		Hidden   string            `json:"-"`
+		internal int
+	}
+
+	got := outputSchema(reflect.TypeOf(result{}))
+	want := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"shared": map[string]interface{}{"type": "string"},
+			"name":   map[string]interface{}{"type": "string"},
+			"count":  map[string]interface{}{"type": "integer", "minimum": 0},
+			"ratio":  map[string]interface{}{"type": "number"},
+			"point": map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"x": map[string]interface{}{"type": "integer"}},
+				"required":   []string{"x"},
+			},
+			"parent": map[string]interface{}{
+				"type":       []string{"object", "null"},
+				"properties": map[string]interface{}{"x": map[string]interface{}{"type": "integer"}},
+				"required":   []string{"x"},
+			},
+			"items": map[string]interface{}{
+				"type": []string{"array", "null"},
+				"items": map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{"x": map[string]interface{}{"type": "integer"}},
+					"required":   []string{"x"},
+				},
+			},
+			"tags": map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": map[string]interface{}{"type": "string"},
+			},
+			"triple": map[string]interface{}{
+				"type":     "array",
+				"items":    map[string]interface{}{"type": "integer"},
+				"minItems": 3,
+				"maxItems": 3,
+			},
+			"raw": map[string]interface{}{},
+		},
+		"required": []string{"shared", "name", "count", "parent", "items", "triple", "raw"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		gotJSON, _ := json.MarshalIndent(got, "", "  ")
+		t.Errorf("schema mismatch, got:\n%s", gotJSON)
+	}
+}
+
+func TestToolDefinitions_OutputSchema(t *testing.T) {
+	names := make(map[string]bool)
+	for _, tool := range GetToolDefinitions() {
+		names[tool.Name] = true
+		if tool.OutputSchema == nil {
+			t.Errorf("%s: no outputSchema", tool.Name)
+			continue
+		}
+		if tool.OutputSchema["type"] != "object" {
+			t.Errorf("%s: outputSchema type is %v, want object", tool.Name, tool.OutputSchema["type"])
+		}
+	}
+	for name := range toolResults {
+		if !names[name] {
+			t.Errorf("toolResults names unknown tool %s", name)
+		}
+	}
+}
+
+// TestHandleToolsCall_StructuredContent calls every tool that can run here
+// and checks that its structured result matches the text result, the
+// registered result type, and the advertised outputSchema.
+func TestHandleToolsCall_StructuredContent(t *testing.T) {
+	imgPath := writeFuzzImage(t, t.TempDir())
+
+	s := New()
+	s.SetCacheDir(t.TempDir())
+	for _, tool := range GetToolDefinitions() {
+		args, ok := fuzzToolArguments[tool.Name]
+		if !ok {
+			args = `{"path":"@img"}`
+		}
+		args = string(bytes.ReplaceAll([]byte(args), []byte(fuzzImagePlaceholder), []byte(imgPath)))
+
+		t.Run(tool.Name, func(t *testing.T) {
+			result, err := s.executeTool(tool.Name, json.RawMessage(args))
+			if err != nil {
+				t.Skipf("tool unavailable: %v", err)
+			}
+			if got, want := reflect.TypeOf(result), reflect.PtrTo(toolResults[tool.Name]); got != want {
+				t.Errorf("result type %v, toolResults says %v", got, want)
+			}
+
+			paramsJSON, _ := json.Marshal(map[string]interface{}{
+				"name":      tool.Name,
+				"arguments": json.RawMessage(args),
+			})
+			resp := s.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 1, Method: "tools/call", Params: paramsJSON})
+			if resp.Error != nil {
+				t.Fatalf("unexpected error: %v", resp.Error)
+			}
+
+			// Decode the response as a client would
+			respJSON, err := json.Marshal(resp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var decoded struct {
+				Result struct {
+					Content []struct {
+						Text string `json:"text"`
+					} `json:"content"`
+					StructuredContent map[string]interface{} `json:"structuredContent"`
+				} `json:"result"`
+			}
+			if err := json.Unmarshal(respJSON, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			var fromText map[string]interface{}
+			if err := json.Unmarshal([]byte(decoded.Result.Content[0].Text), &fromText); err != nil {
+				t.Fatalf("text content is not JSON: %v", err)
+			}
+			if !reflect.DeepEqual(fromText, decoded.Result.StructuredContent) {
+				t.Error("structuredContent differs from text content")
+			}
+
+			var schema map[string]interface{}
+			schemaJSON, _ := json.Marshal(tool.OutputSchema)
+			json.Unmarshal(schemaJSON, &schema)
+			validateSchema(t, "result", schema, decoded.Result.StructuredContent)
+		})
+	}
+}
+
+// validateSchema checks the decoded JSON value v against the subset of
+// JSON Schema that outputSchema generates.
+func validateSchema(t *testing.T, path string, schema map[string]interface{}, v interface{}) {
+	t.Helper()
+	if typ, ok := schema["type"]; ok {
+		var allowed []interface{}
+		if list, ok := typ.([]interface{}); ok {
+			allowed = list
+		} else {
+			allowed = []interface{}{typ}
+		}
+		got := jsonType(v)
+		match := false
+		for _, a := range allowed {
+			if a == got || (a == "number" && got == "integer") {
+				match = true
+			}
+		}
+		if !match {
+			t.Errorf("%s: got %s, schema allows %v", path, got, allowed)
+			return
+		}
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := v[name.(string)]; !ok {
+				t.Errorf("%s: missing required field %s", path, name)
+			}
+		}
+		for name, field := range v {
+			if fieldSchema, ok := properties[name].(map[string]interface{}); ok {
+				validateSchema(t, path+"."+name, fieldSchema, field)
+			} else if extra, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+				validateSchema(t, path+"."+name, extra, field)
+			} else if properties != nil {
+				t.Errorf("%s: field %s is not in the schema", path, name)
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for _, item := range v {
+				validateSchema(t, path+"[]", items, item)
+			}
+		}
+	}
+}
+
+// jsonType returns the JSON Schema type name of a decoded JSON value.
+func jsonType(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
@@ -0,0 +1,236 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ironsheep/image-tools-mcp/internal/imaging"
+	"github.com/ironsheep/image-tools-mcp/internal/ocr"
+)
+
+// PIIResult is the result of image_detect_pii: the personal data and
+// secrets found in an image's text.
+type PIIResult struct {
+	// Matches lists what was found, top to bottom and left to right.
+	Matches []PIIMatch `json:"matches"`
+
+	// Count is the number of matches.
+	Count int `json:"count"`
+
+	// ByCategory counts the matches of each category searched, including
+	// those with none.
+	ByCategory map[string]int `json:"by_category"`
+
+	// WordsScanned is the number of words OCR recognized. When it is
+	// zero, no text was read, so nothing could be found.
+	WordsScanned int `json:"words_scanned"`
+}
+
+// PIIMatch is one piece of personal data or one secret found by OCR.
+type PIIMatch struct {
+	// Category is "email", "phone", "credit_card", or "api_key".
+	Category string `json:"category"`
+
+	// Kind names the type of API key or token, such as "aws_access_key"
+	// or "github_token" (api_key only).
+	Kind string `json:"kind,omitempty"`
+
+	// Bounds is the bounding box of the words holding the match. Pass it
+	// to image_redact as a region to hide it.
+	Bounds imaging.Region `json:"bounds"`
+
+	// Masked is the matched text with all but its last 4 letters and
+	// digits replaced by "*"; for emails, all but the first character and
+	// the domain. The full text is never returned.
+	Masked string `json:"masked"`
+
+	// Confidence is the lowest OCR confidence (0.0-1.0) of the words
+	// holding the match.
+	Confidence float64 `json:"confidence"`
+}
+
+// piiCategories are the categories image_detect_pii searches, in the
+// order a piece of text is claimed: text matched as one category is not
+// matched again as a later one, so a card number is not also a phone
+// number.
+var piiCategories = []string{"email", "api_key", "credit_card", "phone"}
+
+// piiDetector finds one kind of PII in a line of OCR text.
+type piiDetector struct {
+	category string
+	kind     string
+	re       *regexp.Regexp
+	valid    func(match string) bool // Optional check of each match
+}
+
+// piiDetectors are the detectors for each category, in piiCategories
+// order. Within api_key, the specific token formats come before the
+// generic "label: value" pattern.
+var piiDetectors = []piiDetector{
+	{category: "email", re: regexp.MustCompile(redactPresets["email"])},
+
+	{category: "api_key", kind: "private_key", re: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+	{category: "api_key", kind: "aws_access_key", re: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{category: "api_key", kind: "github_token", re: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`)},
+	{category: "api_key", kind: "slack_token", re: regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+	{category: "api_key", kind: "stripe_key", re: regexp.MustCompile(`\b[rs]k_(?:live|test)_[A-Za-z0-9]{16,}`)},
+	{category: "api_key", kind: "google_api_key", re: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`)},
+	{category: "api_key", kind: "secret_key", re: regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`)},
+	{category: "api_key", kind: "jwt", re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+	{category: "api_key", kind: "labeled_secret", re: regexp.MustCompile(`(?i)\b(?:api[_ -]?key|secret(?:[_ -]?key)?|(?:access|auth)[_ -]?token|password|passwd)\s*[:=]\s*\S{6,}`)},
+
+	{category: "credit_card", re: regexp.MustCompile(`\d(?:[ -]?\d){12,18}`), valid: validCardNumber},
+
+	{category: "phone", re: regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{1,4}\)[ .-]?)?\d{2,4}(?:[ .-]?\d{2,4}){1,4}`), valid: validPhoneNumber},
+}
+
+// datePattern matches numeric dates, which look like phone numbers.
+var datePattern = regexp.MustCompile(`^(?:\d{4}[.-]\d{1,2}[.-]\d{1,2}|\d{1,2}[.-]\d{1,2}[.-]\d{2,4})$`)
+
+// findPII searches OCR words for the given categories. Matches that run
+// into a letter or digit on either side, such as digits inside a longer
+// number, are ignored.
+func findPII(words []ocr.TextRegion, categories map[string]bool) []PIIMatch {
+	var matches []PIIMatch
+	for _, line := range ocrLines(words) {
+		text, starts := ocrLineText(line)
+		var claimed [][2]int
+		for _, d := range piiDetectors {
+			if !categories[d.category] {
+				continue
+			}
+			for _, m := range d.re.FindAllStringIndex(text, -1) {
+				match := text[m[0]:m[1]]
+				if !isolated(text, m[0], m[1]) || overlapsAny(m[0], m[1], claimed) || (d.valid != nil && !d.valid(match)) {
+					continue
+				}
+				box, confidence, ok := ocrSpan(line, starts, m[0], m[1])
+				if !ok {
+					continue
+				}
+				claimed = append(claimed, [2]int{m[0], m[1]})
+				matches = append(matches, PIIMatch{
+					Category:   d.category,
+					Kind:       d.kind,
+					Bounds:     box,
+					Masked:     maskPII(d.category, match),
+					Confidence: confidence,
+				})
+			}
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i].Bounds, matches[j].Bounds
+		if a.Y1 != b.Y1 {
+			return a.Y1 < b.Y1
+		}
+		return a.X1 < b.X1
+	})
+	return matches
+}
+
+// isolated reports whether text[start:end] is neither preceded nor
+// followed by a letter or digit.
+func isolated(text string, start, end int) bool {
+	alnum := func(b byte) bool { return b < 0x80 && (unicode.IsLetter(rune(b)) || unicode.IsDigit(rune(b))) }
+	return (start == 0 || !alnum(text[start-1])) && (end == len(text) || !alnum(text[end]))
+}
+
+// overlapsAny reports whether bytes start to end overlap any of spans.
+func overlapsAny(start, end int, spans [][2]int) bool {
+	for _, s := range spans {
+		if start < s[1] && s[0] < end {
+			return true
+		}
+	}
+	return false
+}
+
+// digitsOf returns the digits of s.
+func digitsOf(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// validCardNumber reports whether s holds 13 to 19 digits that pass the
+// Luhn checksum, as payment card numbers do.
+func validCardNumber(s string) bool {
+	digits := digitsOf(s)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (len(digits)-1-i)%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// validPhoneNumber reports whether s looks like a phone number: 7 to 15
+// digits, not a date, and either written with a separator, country code,
+// or area code in parentheses, or at least 10 digits long, so plain
+// counts and IDs are not flagged.
+func validPhoneNumber(s string) bool {
+	digits := digitsOf(s)
+	if len(digits) < 7 || len(digits) > 15 || datePattern.MatchString(s) {
+		return false
+	}
+	return len(digits) >= 10 || strings.ContainsAny(s, " .-(+")
+}
+
+// maskPII hides a match for display: an email keeps its first character
+// and domain; anything else keeps its last 4 letters and digits.
+func maskPII(category, s string) string {
+	if category == "email" {
+		if at := strings.LastIndexByte(s, '@'); at > 0 {
+			return s[:1] + strings.Repeat("*", at-1) + s[at:]
+		}
+	}
+	keep := 4
+	runes := []rune(s)
+	for i := len(runes) - 1; i >= 0; i-- {
+		if !unicode.IsLetter(runes[i]) && !unicode.IsDigit(runes[i]) {
+			continue
+		}
+		if keep > 0 {
+			keep--
+			continue
+		}
+		runes[i] = '*'
+	}
+	return string(runes)
+}
+
+// parsePIICategories returns the set of categories to search: all of
+// piiCategories if none are given.
+func parsePIICategories(names []string) (map[string]bool, error) {
+	set := make(map[string]bool)
+	if len(names) == 0 {
+		names = piiCategories
+	}
+	for _, name := range names {
+		known := false
+		for _, c := range piiCategories {
+			known = known || c == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown category %q: must be one of %s", name, strings.Join(piiCategories, ", "))
+		}
+		set[name] = true
+	}
+	return set, nil
+}
@@ -0,0 +1,175 @@
+package server
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/ironsheep/image-tools-mcp/internal/imaging"
+)
+
+// PrintMargins are widths around each side of a box, in millimeters.
+type PrintMargins struct {
+	Top    float64 `json:"top"`
+	Left   float64 `json:"left"`
+	Bottom float64 `json:"bottom"`
+	Right  float64 `json:"right"`
+}
+
+// PrintCheckResult is the result of image_detect_print_marks: the trim
+// and bleed boxes of a print-ready export, whether the bleed is wide
+// enough, and the text and content within the unsafe margin inside the
+// trim box.
+//
+// A check that cannot run is left out and the reason recorded in
+// Skipped.
+type PrintCheckResult struct {
+	// Found is true if crop marks were found; the other fields but DPI
+	// and Warnings are empty otherwise.
+	Found bool `json:"found"`
+
+	// DPI is the resolution used to convert millimeters, and DPISource
+	// where it came from: "argument", "file", or "default" (300).
+	DPI       float64 `json:"dpi"`
+	DPISource string  `json:"dpi_source"`
+
+	// TrimBox is the finished page, where the paper is cut.
+	TrimBox *imaging.Region `json:"trim_box,omitempty"`
+
+	// BleedBox is the artwork's extent beyond the trim box.
+	BleedBox *imaging.Region `json:"bleed_box,omitempty"`
+
+	// Bleed is the bleed beyond each side of the trim box in pixels, and
+	// BleedMM in millimeters.
+	Bleed   imaging.Insets `json:"bleed"`
+	BleedMM PrintMargins   `json:"bleed_mm"`
+
+	// MissingBleed are the sides whose bleed is narrower than required:
+	// "top", "left", "bottom", and "right".
+	MissingBleed []string `json:"missing_bleed"`
+
+	// SafeBox is the trim box inset by the safe margin; text and content
+	// between it and the trim box risk being cut.
+	SafeBox *imaging.Region `json:"safe_box,omitempty"`
+
+	// Violations are the text and content inside the trim box crossing
+	// into the safe margin, in image coordinates, the most outside first.
+	Violations []SafeAreaViolation `json:"violations"`
+
+	// TotalViolations counts all violations, including any beyond the 50
+	// listed.
+	TotalViolations int `json:"total_violations"`
+
+	// Marks are the crop marks found.
+	Marks []imaging.Region `json:"marks"`
+
+	// Passed is true if marks were found, the bleed is wide enough on
+	// every side, and no text or content is within the safe margin.
+	Passed bool `json:"passed"`
+
+	// Warnings note problems with the check itself.
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Skipped maps each check left out to the reason.
+	Skipped map[string]string `json:"skipped,omitempty"`
+
+	// ImageBase64 is the export with the area outside the safe box shaded
+	// and violations outlined, as base64 PNG, if requested.
+	ImageBase64 string `json:"image_base64,omitempty"`
+
+	// MimeType is "image/png" when ImageBase64 is set.
+	MimeType string `json:"mime_type,omitempty"`
+}
+
+// printCheckOptions are the parameters of buildPrintCheck.
+type printCheckOptions struct {
+	dpi          float64
+	dpiSource    string
+	minBleedMM   float64
+	safeMarginMM float64
+	checkText    bool
+	checkContent bool
+	includeImage bool
+}
+
+// buildPrintCheck finds the trim and bleed boxes of a print-ready export
+// and checks them against opts.
+func buildPrintCheck(img image.Image, opts printCheckOptions) (*PrintCheckResult, error) {
+	marks := imaging.DetectPrintMarks(img)
+	result := &PrintCheckResult{
+		DPI:          opts.dpi,
+		DPISource:    opts.dpiSource,
+		MissingBleed: []string{},
+		Violations:   []SafeAreaViolation{},
+		Marks:        marks.Marks,
+	}
+	if opts.dpiSource == "default" {
+		result.Warnings = append(result.Warnings, "the file has no resolution; millimeters assume 300 DPI (set dpi to override)")
+	}
+	if !marks.Found {
+		result.Warnings = append(result.Warnings, "no crop marks found at three or more corners of a trim box")
+		return result, nil
+	}
+	result.Found = true
+	result.TrimBox, result.BleedBox, result.Bleed = marks.TrimBox, marks.BleedBox, marks.Bleed
+
+	toMM := func(px int) float64 { return math.Round(float64(px)/opts.dpi*25.4*10) / 10 }
+	toPx := func(mm float64) int { return int(math.Round(mm / 25.4 * opts.dpi)) }
+	b := marks.Bleed
+	result.BleedMM = PrintMargins{Top: toMM(b.Top), Left: toMM(b.Left), Bottom: toMM(b.Bottom), Right: toMM(b.Right)}
+	// Allow a pixel for antialiasing at the artwork's edge
+	minBleed := toPx(opts.minBleedMM) - 1
+	for _, side := range []struct {
+		name  string
+		bleed int
+	}{{"top", b.Top}, {"left", b.Left}, {"bottom", b.Bottom}, {"right", b.Right}} {
+		if side.bleed < minBleed {
+			result.MissingBleed = append(result.MissingBleed, side.name)
+		}
+	}
+
+	trim := *marks.TrimBox
+	margin := toPx(opts.safeMarginMM)
+	safe := imaging.Region{X1: trim.X1 + margin, Y1: trim.Y1 + margin, X2: trim.X2 - margin, Y2: trim.Y2 - margin}
+	if safe.X2 <= safe.X1 || safe.Y2 <= safe.Y1 {
+		return nil, fmt.Errorf("a safe margin of %gmm leaves no safe area in the %dx%d trim box", opts.safeMarginMM, trim.X2-trim.X1, trim.Y2-trim.Y1)
+	}
+	result.SafeBox = &safe
+
+	// Only the trim box is printed; the bleed is cut off
+	page, err := imaging.CropRegion(img, trim)
+	if err != nil {
+		return nil, err
+	}
+	inner := imaging.Region{X1: margin, Y1: margin, X2: safe.X2 - trim.X1, Y2: safe.Y2 - trim.Y1}
+	skip := func(check string, err error) {
+		if result.Skipped == nil {
+			result.Skipped = make(map[string]string)
+		}
+		result.Skipped[check] = err.Error()
+	}
+	violations := findSafeAreaViolations(page, inner, opts.checkText, opts.checkContent, skip)
+	for i := range violations {
+		r := &violations[i].Bounds
+		r.X1, r.Y1, r.X2, r.Y2 = r.X1+trim.X1, r.Y1+trim.Y1, r.X2+trim.X1, r.Y2+trim.Y1
+	}
+	result.TotalViolations = len(violations)
+	if len(violations) > maxSafeAreaViolations {
+		violations = violations[:maxSafeAreaViolations]
+	}
+	result.Violations = append(result.Violations, violations...)
+	result.Passed = len(result.MissingBleed) == 0 && result.TotalViolations == 0
+
+	if opts.includeImage {
+		flagged := make([]imaging.Region, len(result.Violations))
+		for i, v := range result.Violations {
+			flagged[i] = v.Bounds
+		}
+		encoded, err := imaging.SafeAreaOverlay(img, safe, flagged)
+		if err != nil {
+			return nil, err
+		}
+		result.ImageBase64, result.MimeType = encoded, "image/png"
+	}
+	return result, nil
+}
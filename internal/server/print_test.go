@@ -0,0 +1,78 @@
+package server
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/ironsheep/image-tools-mcp/internal/imaging"
+)
+
+// printScene draws a 600×800 print export at 100 DPI on white paper: a
+// 480×680 trim box at 60,60 with crop marks at its corners, blue artwork
+// bleeding 18 pixels (4.6 mm) beyond it but only 4 pixels (1 mm) at the
+// right, a white panel in the middle, and a red badge 10 pixels inside
+// the left edge of the trim box.
+func printScene() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 600, 800))
+	fill(img, 0, 0, 600, 800, color.RGBA{255, 255, 255, 255})
+	fill(img, 42, 42, 544, 758, color.RGBA{40, 90, 200, 255})
+	fill(img, 150, 250, 450, 550, color.RGBA{250, 250, 250, 255})
+	fill(img, 70, 300, 100, 340, color.RGBA{208, 48, 48, 255})
+	black := color.RGBA{0, 0, 0, 255}
+	for _, y := range []int{60, 740} {
+		fill(img, 6, y, 36, y+1, black)
+		fill(img, 564, y, 594, y+1, black)
+	}
+	for _, x := range []int{60, 540} {
+		fill(img, x, 6, x+1, 36, black)
+		fill(img, x, 764, x+1, 794, black)
+	}
+	return img
+}
+
+func TestBuildPrintCheck(t *testing.T) {
+	opts := printCheckOptions{dpi: 100, dpiSource: "argument", minBleedMM: 3, safeMarginMM: 3, checkContent: true, includeImage: true}
+	result, err := buildPrintCheck(printScene(), opts)
+	if err != nil {
+		t.Fatalf("buildPrintCheck failed: %v", err)
+	}
+	if !result.Found || *result.TrimBox != (imaging.Region{X1: 60, Y1: 60, X2: 540, Y2: 740}) {
+		t.Fatalf("got found %v, trim box %+v", result.Found, result.TrimBox)
+	}
+	if result.BleedMM != (PrintMargins{Top: 4.6, Left: 4.6, Bottom: 4.6, Right: 1}) {
+		t.Errorf("bleed = %+v mm", result.BleedMM)
+	}
+	if len(result.MissingBleed) != 1 || result.MissingBleed[0] != "right" {
+		t.Errorf("missing bleed = %v, want [right]", result.MissingBleed)
+	}
+	if *result.SafeBox != (imaging.Region{X1: 72, Y1: 72, X2: 528, Y2: 728}) {
+		t.Errorf("safe box = %+v", *result.SafeBox)
+	}
+	if result.TotalViolations != 1 || result.Violations[0].Bounds != (imaging.Region{X1: 70, Y1: 300, X2: 100, Y2: 340}) || result.Violations[0].Sides[0] != "left" {
+		t.Errorf("violations = %+v, want the badge at the left", result.Violations)
+	}
+	if result.Passed || result.ImageBase64 == "" {
+		t.Errorf("got passed %v, image %d bytes", result.Passed, len(result.ImageBase64))
+	}
+
+	opts.minBleedMM, opts.safeMarginMM = 1, 0
+	result, err = buildPrintCheck(printScene(), opts)
+	if err != nil {
+		t.Fatalf("buildPrintCheck failed: %v", err)
+	}
+	if !result.Passed {
+		t.Errorf("1 mm bleed, no safe margin: expected a pass, got %+v", result)
+	}
+}
+
+func TestBuildPrintCheck_NoMarks(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 200, 200))
+	result, err := buildPrintCheck(img, printCheckOptions{dpi: 300, dpiSource: "default", checkText: true})
+	if err != nil {
+		t.Fatalf("buildPrintCheck failed: %v", err)
+	}
+	if result.Found || result.Passed || result.TrimBox != nil || len(result.Warnings) != 2 {
+		t.Errorf("got %+v", result)
+	}
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ironsheep/image-tools-mcp/internal/imaging"
+	"github.com/ironsheep/image-tools-mcp/internal/ocr"
+)
+
+// redactPresets are the named patterns image_redact accepts in place of a
+// regular expression.
+var redactPresets = map[string]string{
+	// email matches addresses such as jane.doe+tag@example.co.uk.
+	"email": `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+
+	// number matches runs of at least 3 digits, with the separators of
+	// phone, card, and account numbers: "555-0100", "(555) 010 0199",
+	// "4111 1111 1111 1111", "+44 20 7946 0958".
+	"number": `\+?\(?\d[\d ().-]*\d[\d ().-]*\d\)?`,
+
+	// url matches web addresses with a scheme or starting with "www.".
+	"url": `(?i)(?:https?://|www\.)\S+`,
+}
+
+// redactPattern is a compiled image_redact pattern.
+type redactPattern struct {
+	source string // The preset name or expression, as given
+	re     *regexp.Regexp
+}
+
+// compileRedactPatterns compiles image_redact patterns: preset names
+// (see redactPresets) or regular expressions in Go syntax.
+func compileRedactPatterns(patterns []string) ([]redactPattern, error) {
+	compiled := make([]redactPattern, 0, len(patterns))
+	for _, p := range patterns {
+		expr, ok := redactPresets[p]
+		if !ok {
+			expr = p
+		}
+		if expr == "" {
+			return nil, fmt.Errorf("empty pattern")
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+		compiled = append(compiled, redactPattern{source: p, re: re})
+	}
+	return compiled, nil
+}
+
+// matchRedactPatterns returns the boxes of the OCR words that each
+// pattern's matches cover, one per match.
+//
+// The words are grouped into lines and each line is searched as its words
+// joined by single spaces, so a pattern can span words, as in
+// "(555) 010 0199". A match covering any part of a word hides the whole
+// word.
+func matchRedactPatterns(words []ocr.TextRegion, patterns []redactPattern) []imaging.Redaction {
+	var redactions []imaging.Redaction
+	for _, line := range ocrLines(words) {
+		text, starts := ocrLineText(line)
+		for _, p := range patterns {
+			for _, m := range p.re.FindAllStringIndex(text, -1) {
+				if box, _, ok := ocrSpan(line, starts, m[0], m[1]); ok {
+					redactions = append(redactions, imaging.Redaction{Bounds: box, Source: p.source})
+				}
+			}
+		}
+	}
+	return redactions
+}
+
+// ocrLineText joins the words of a line with single spaces, and returns
+// the text and the offset of each word in it.
+func ocrLineText(line []ocr.TextRegion) (string, []int) {
+	var text strings.Builder
+	starts := make([]int, len(line))
+	for i, w := range line {
+		if i > 0 {
+			text.WriteByte(' ')
+		}
+		starts[i] = text.Len()
+		text.WriteString(w.Text)
+	}
+	return text.String(), starts
+}
+
+// ocrSpan returns the bounding box and lowest confidence of the words of
+// a line that overlap bytes start to end of its text (see ocrLineText).
+// ok is false if the span covers only spaces between words.
+func ocrSpan(line []ocr.TextRegion, starts []int, start, end int) (box imaging.Region, confidence float64, ok bool) {
+	for i, w := range line {
+		if starts[i] >= end || starts[i]+len(w.Text) <= start {
+			continue
+		}
+		b := imaging.Region{X1: w.Bounds.X1, Y1: w.Bounds.Y1, X2: w.Bounds.X2, Y2: w.Bounds.Y2}
+		if !ok {
+			box, confidence, ok = b, w.Confidence, true
+			continue
+		}
+		box.X1, box.Y1 = min(box.X1, b.X1), min(box.Y1, b.Y1)
+		box.X2, box.Y2 = max(box.X2, b.X2), max(box.Y2, b.Y2)
+		confidence = min(confidence, w.Confidence)
+	}
+	return box, confidence, ok
+}
+
+// ocrLines groups OCR words into lines, top to bottom, each ordered left
+// to right. A word joins the line it overlaps vertically by at least half
+// the height of the shorter of it and the line's last word.
+func ocrLines(words []ocr.TextRegion) [][]ocr.TextRegion {
+	sorted := append([]ocr.TextRegion(nil), words...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Bounds.Y1 != sorted[j].Bounds.Y1 {
+			return sorted[i].Bounds.Y1 < sorted[j].Bounds.Y1
+		}
+		return sorted[i].Bounds.X1 < sorted[j].Bounds.X1
+	})
+	var lines [][]ocr.TextRegion
+	for _, w := range sorted {
+		joined := false
+		for i, line := range lines {
+			last := line[len(line)-1].Bounds
+			overlap := min(last.Y2, w.Bounds.Y2) - max(last.Y1, w.Bounds.Y1)
+			shorter := min(last.Y2-last.Y1, w.Bounds.Y2-w.Bounds.Y1)
+			if overlap*2 >= shorter && overlap > 0 {
+				lines[i] = append(line, w)
+				joined = true
+				break
+			}
+		}
+		if !joined {
+			lines = append(lines, []ocr.TextRegion{w})
+		}
+	}
+	for _, line := range lines {
+		sort.SliceStable(line, func(i, j int) bool { return line[i].Bounds.X1 < line[j].Bounds.X1 })
+	}
+	return lines
+}
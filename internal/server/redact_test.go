@@ -0,0 +1,62 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/ironsheep/image-tools-mcp/internal/imaging"
+	"github.com/ironsheep/image-tools-mcp/internal/ocr"
+)
+
+// word returns an OCR word at x on the line at y, 10 pixels per character.
+func word(text string, x, y int) ocr.TextRegion {
+	return ocr.TextRegion{Text: text, Confidence: 0.9, Bounds: ocr.Bounds{X1: x, Y1: y, X2: x + 10*len(text), Y2: y + 14}}
+}
+
+func TestMatchRedactPatterns(t *testing.T) {
+	// Two lines, the second slightly lower on the right and out of order
+	words := []ocr.TextRegion{
+		word("0199", 150, 42),
+		word("Contact:", 0, 0),
+		word("jane.doe@example.com", 90, 1),
+		word("Call", 0, 40),
+		word("(555)", 50, 40),
+		word("010", 110, 41),
+		word("or", 200, 40),
+		word("visit", 230, 40),
+		word("www.example.com/help", 300, 40),
+	}
+	patterns, err := compileRedactPatterns([]string{"email", "number", "url", `(?i)contact`})
+	if err != nil {
+		t.Fatalf("compileRedactPatterns failed: %v", err)
+	}
+	got := matchRedactPatterns(words, patterns)
+	want := []imaging.Redaction{
+		{Bounds: imaging.Region{X1: 90, Y1: 1, X2: 290, Y2: 15}, Source: "email"},
+		{Bounds: imaging.Region{X1: 0, Y1: 0, X2: 80, Y2: 14}, Source: `(?i)contact`},
+		{Bounds: imaging.Region{X1: 50, Y1: 40, X2: 190, Y2: 56}, Source: "number"},
+		{Bounds: imaging.Region{X1: 300, Y1: 40, X2: 500, Y2: 54}, Source: "url"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d redactions, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("redaction %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestOCRLines(t *testing.T) {
+	lines := ocrLines([]ocr.TextRegion{word("b", 50, 3), word("c", 0, 30), word("a", 0, 0)})
+	if len(lines) != 2 || len(lines[0]) != 2 || lines[0][0].Text != "a" || lines[0][1].Text != "b" || lines[1][0].Text != "c" {
+		t.Errorf("got %+v", lines)
+	}
+}
+
+func TestCompileRedactPatterns_Errors(t *testing.T) {
+	for _, p := range []string{"", "(unclosed", "a{2,1}"} {
+		if _, err := compileRedactPatterns([]string{p}); err == nil {
+			t.Errorf("pattern %q: expected error", p)
+		}
+	}
+}
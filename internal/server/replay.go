@@ -0,0 +1,168 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// maxReplayDiffs is the number of differing fields reported per response.
+const maxReplayDiffs = 5
+
+// ReplaySummary counts the outcome of a Replay.
+type ReplaySummary struct {
+	Requests      int // Requests re-executed
+	Differed      int // Requests whose response differed from the capture
+	InputsChanged int // Image files that are missing or changed since the capture
+}
+
+// Replay re-executes the requests of a capture file (see SetCapture) in
+// order and compares each response, in capture form, with the captured
+// one. Differences and changed input files are reported to out, one line
+// each, prefixed by the request's line number in the capture.
+//
+// Requests run on s, so state such as incremental detection snapshots
+// builds up as it did in the captured session; use a new Server. Only
+// reading the capture can fail; differences are reported in the summary.
+func (s *Server) Replay(in io.Reader, out io.Writer) (ReplaySummary, error) {
+	var summary ReplaySummary
+	r := bufio.NewReader(in)
+	for lineNo := 1; ; lineNo++ {
+		line, err := r.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var rec CaptureRecord
+			if err := json.Unmarshal(line, &rec); err != nil {
+				return summary, fmt.Errorf("capture line %d: %w", lineNo, err)
+			}
+			s.replayRecord(lineNo, &rec, out, &summary)
+		}
+		if err == io.EOF {
+			return summary, nil
+		}
+		if err != nil {
+			return summary, fmt.Errorf("reading capture: %w", err)
+		}
+	}
+}
+
+// replayRecord re-executes one captured request and reports how its
+// response and inputs differ from the capture.
+func (s *Server) replayRecord(lineNo int, rec *CaptureRecord, out io.Writer, summary *ReplaySummary) {
+	for _, path := range sortedKeys(rec.Inputs, nil) {
+		if hash := hashFile(path); hash != rec.Inputs[path] {
+			summary.InputsChanged++
+			if hash == "" {
+				fmt.Fprintf(out, "%d: input %s is missing\n", lineNo, path)
+			} else {
+				fmt.Fprintf(out, "%d: input %s has changed since the capture\n", lineNo, path)
+			}
+		}
+	}
+
+	// Lines that were not valid requests were captured as JSON strings and
+	// got no response; they are dropped again here
+	var req MCPRequest
+	if json.Unmarshal(rec.Request, &req) != nil {
+		return
+	}
+	summary.Requests++
+
+	var got json.RawMessage
+	if resp := s.handleRequest(&req); resp != nil {
+		form, err := captureForm(resp)
+		if err != nil {
+			summary.Differed++
+			fmt.Fprintf(out, "%d: %s: response cannot be encoded: %v\n", lineNo, req.Method, err)
+			return
+		}
+		got = form
+	}
+
+	var want, have interface{}
+	json.Unmarshal(rec.Response, &want)
+	json.Unmarshal(got, &have)
+	diffs := describeDiff("response", want, have, maxReplayDiffs)
+	if len(diffs) == 0 {
+		return
+	}
+	summary.Differed++
+	fmt.Fprintf(out, "%d: %s differs:\n", lineNo, requestLabel(&req))
+	for _, d := range diffs {
+		fmt.Fprintf(out, "    %s\n", d)
+	}
+}
+
+// requestLabel names a request for reports: its method, and the tool for
+// tools/call.
+func requestLabel(req *MCPRequest) string {
+	if req.Method == "tools/call" {
+		var params ToolCallParams
+		if json.Unmarshal(req.Params, &params) == nil && params.Name != "" {
+			return req.Method + " " + params.Name
+		}
+	}
+	return req.Method
+}
+
+// describeDiff returns up to max paths at which the decoded JSON values a
+// (captured) and b (replayed) differ, as "path: a != b" lines.
+func describeDiff(path string, a, b interface{}, max int) []string {
+	var diffs []string
+	var walk func(path string, a, b interface{})
+	walk = func(path string, a, b interface{}) {
+		if len(diffs) >= max {
+			return
+		}
+		switch a := a.(type) {
+		case map[string]interface{}:
+			if b, ok := b.(map[string]interface{}); ok {
+				for _, k := range sortedKeys(a, b) {
+					walk(path+"."+k, a[k], b[k])
+				}
+				return
+			}
+		case []interface{}:
+			if b, ok := b.([]interface{}); ok && len(a) == len(b) {
+				for i := range a {
+					walk(fmt.Sprintf("%s[%d]", path, i), a[i], b[i])
+				}
+				return
+			}
+		}
+		ja, _ := json.Marshal(a)
+		jb, _ := json.Marshal(b)
+		if !bytes.Equal(ja, jb) {
+			diffs = append(diffs, fmt.Sprintf("%s: %s != %s", path, abbreviate(ja), abbreviate(jb)))
+		}
+	}
+	walk(path, a, b)
+	return diffs
+}
+
+// sortedKeys returns the keys of a and b, merged and sorted.
+func sortedKeys[V any](a, b map[string]V) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var keys []string
+	for _, m := range []map[string]V{a, b} {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// abbreviate shortens encoded JSON for a one-line report.
+func abbreviate(data []byte) string {
+	const max = 80
+	if len(data) > max {
+		return string(data[:max-3]) + "..."
+	}
+	return string(data)
+}
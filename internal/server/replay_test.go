@@ -0,0 +1,176 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"os"
+	"strings"
+	"testing"
+)
+
+// captureSession serves the request lines on a capturing server and
+// returns the capture.
+func captureSession(t *testing.T, lines ...string) []byte {
+	t.Helper()
+	var capture, out bytes.Buffer
+	s := New()
+	s.SetCapture(&capture)
+	if err := s.serve(strings.NewReader(strings.Join(lines, "\n")), &out); err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+	return capture.Bytes()
+}
+
+func TestCapture(t *testing.T) {
+	imgPath := createTestImageFile(t, 40, 30, color.RGBA{255, 0, 0, 255})
+	defer os.Remove(imgPath)
+
+	capture := captureSession(t,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		fmt.Sprintf(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"image_crop","arguments":{"path":%q,"x1":0,"y1":0,"x2":10,"y2":10}}}`, imgPath),
+		`not json`,
+	)
+
+	var records []CaptureRecord
+	scanner := bufio.NewScanner(bytes.NewReader(capture))
+	for scanner.Scan() {
+		var rec CaptureRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("capture line is not a record: %v", err)
+		}
+		records = append(records, rec)
+	}
+	if len(records) != 4 {
+		t.Fatalf("records: got %d, want 4", len(records))
+	}
+
+	if records[1].Response != nil {
+		t.Errorf("notification captured a response: %s", records[1].Response)
+	}
+
+	crop := records[2]
+	if crop.Inputs[imgPath] != hashFile(imgPath) || crop.Inputs[imgPath] == "" {
+		t.Errorf("input hash: got %v", crop.Inputs)
+	}
+	var resp struct {
+		Result map[string]json.RawMessage `json:"result"`
+	}
+	json.Unmarshal(crop.Response, &resp)
+	if _, ok := resp.Result["content"]; ok {
+		t.Error("text content should be dropped from captured tool results")
+	}
+	var result struct {
+		ImageBase64 string `json:"image_base64"`
+		Width       int    `json:"width"`
+	}
+	json.Unmarshal(resp.Result["structuredContent"], &result)
+	if !strings.HasPrefix(result.ImageBase64, "sha256:") || result.Width != 10 {
+		t.Errorf("captured crop: got %+v, want a hashed image 10 wide", result)
+	}
+
+	var line string
+	if err := json.Unmarshal(records[3].Request, &line); err != nil || line != "not json" {
+		t.Errorf("invalid line: got request %s", records[3].Request)
+	}
+}
+
+func TestHashInputs(t *testing.T) {
+	a := createTestImageFile(t, 4, 4, color.RGBA{255, 0, 0, 255})
+	defer os.Remove(a)
+	b := createTestImageFile(t, 4, 4, color.RGBA{0, 0, 255, 255})
+	defer os.Remove(b)
+
+	for _, tt := range []struct {
+		name, args string
+		want       []string
+	}{
+		{"top level", fmt.Sprintf(`{"path":%q,"x1":0}`, a), []string{a}},
+		{"array", fmt.Sprintf(`{"paths":[%q,%q],"formats":["png"]}`, a, b), []string{a, b}},
+		{"nested sources", fmt.Sprintf(`{"images":[{"path":%q,"label":"x"},{"path":%q}]}`, a, b), []string{a, b}},
+		{"none", `{"text":"hello","label":"path"}`, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			line := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"x","arguments":%s}}`, tt.args)
+			got := hashInputs([]byte(line))
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want hashes of %v", got, tt.want)
+			}
+			for _, p := range tt.want {
+				if got[p] == "" || got[p] != hashFile(p) {
+					t.Errorf("%s: got %q", p, got[p])
+				}
+			}
+		})
+	}
+}
+
+func TestReplay(t *testing.T) {
+	imgPath := createTestImageFile(t, 60, 40, color.RGBA{255, 255, 255, 255})
+	defer os.Remove(imgPath)
+
+	capture := captureSession(t,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}`,
+		fmt.Sprintf(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"image_detect_incremental","arguments":{"path":%q}}}`, imgPath),
+		fmt.Sprintf(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"image_detect_incremental","arguments":{"path":%q,"previous_path":%q}}}`, imgPath, imgPath),
+		fmt.Sprintf(`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"image_grid_overlay","arguments":{"path":%q}}}`, imgPath),
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"image_dimensions","arguments":{"path":"/nonexistent.png"}}}`,
+		`garbage`,
+	)
+
+	// An unchanged session replays identically, including state carried
+	// between the incremental calls and the error for a file that was
+	// missing all along
+	var report bytes.Buffer
+	summary, err := New().Replay(bytes.NewReader(capture), &report)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if summary.Requests != 5 || summary.Differed != 0 || summary.InputsChanged != 0 {
+		t.Errorf("summary: got %+v, want 5 requests, none differing\n%s", summary, report.String())
+	}
+
+	// A changed result is reported with the field that differs
+	altered := bytes.Replace(capture, []byte(`"width":60`), []byte(`"width":61`), 1)
+	report.Reset()
+	summary, _ = New().Replay(bytes.NewReader(altered), &report)
+	if summary.Differed != 1 || !strings.Contains(report.String(), "image_grid_overlay differs") ||
+		!strings.Contains(report.String(), "structuredContent.width: 61 != 60") {
+		t.Errorf("altered capture: got %+v\n%s", summary, report.String())
+	}
+
+	// So is an input that changed since the capture
+	os.WriteFile(imgPath, []byte("not an image"), 0o644)
+	report.Reset()
+	summary, _ = New().Replay(bytes.NewReader(capture), &report)
+	if summary.InputsChanged != 3 || summary.Differed != 3 || !strings.Contains(report.String(), "has changed since the capture") {
+		t.Errorf("changed input: got %+v\n%s", summary, report.String())
+	}
+
+	if _, err := New().Replay(strings.NewReader("{\n"), &report); err == nil {
+		t.Error("expected error for a malformed capture")
+	}
+}
+
+func TestDescribeDiff(t *testing.T) {
+	var a, b interface{}
+	json.Unmarshal([]byte(`{"x":1,"list":[1,2,3],"obj":{"k":"v"},"gone":true}`), &a)
+	json.Unmarshal([]byte(`{"x":2,"list":[1,2],"obj":{"k":"w"}}`), &b)
+
+	got := describeDiff("r", a, b, 10)
+	want := []string{
+		"r.gone: true != null",
+		"r.list: [1,2,3] != [1,2]",
+		"r.obj.k: \"v\" != \"w\"",
+		"r.x: 1 != 2",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if got := describeDiff("r", a, b, 2); len(got) != 2 {
+		t.Errorf("max 2: got %d diffs", len(got))
+	}
+}
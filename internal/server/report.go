@@ -0,0 +1,225 @@
+package server
+
+import (
+	"fmt"
+	"image"
+	"strings"
+
+	"github.com/ironsheep/image-tools-mcp/internal/detection"
+	"github.com/ironsheep/image-tools-mcp/internal/imaging"
+)
+
+// ImageReport is the result of image_generate_report: the outputs of the
+// basic analysis tools for one image, gathered in a single call.
+//
+// A section whose analysis fails (an image too small to classify, say) is
+// left out and the reason recorded in Skipped; the report as a whole only
+// fails if the image cannot be loaded.
+type ImageReport struct {
+	// Image holds the dimensions, format, and file metadata.
+	Image *imaging.ImageInfo `json:"image"`
+
+	// Content is the whole-image classification (see image_classify_content).
+	Content *imaging.ContentClassification `json:"content,omitempty"`
+
+	// Quality holds brightness, contrast, and sharpness metrics.
+	Quality *imaging.QualityResult `json:"quality,omitempty"`
+
+	// DominantColors lists the most common colors, most frequent first.
+	DominantColors []imaging.ColorFrequency `json:"dominant_colors,omitempty"`
+
+	// TextRegions are the heuristically detected text areas (no OCR).
+	TextRegions *detection.TextRegionsResult `json:"text_regions,omitempty"`
+
+	// Shapes are the detected rectangles, lines, and circles.
+	Shapes *ReportShapes `json:"shapes,omitempty"`
+
+	// Skipped maps each section left out of the report to the reason.
+	Skipped map[string]string `json:"skipped,omitempty"`
+
+	// Markdown is the report rendered for reading, if requested.
+	Markdown string `json:"markdown,omitempty"`
+}
+
+// ReportShapes groups the shape detections of an ImageReport. Each list is
+// truncated to the report's max_items; Count is always the full total.
+type ReportShapes struct {
+	Rectangles *detection.RectanglesResult `json:"rectangles"`
+	Lines      *detection.LinesResult      `json:"lines"`
+	Circles    *detection.CirclesResult    `json:"circles"`
+}
+
+// buildReport runs the analyses of an ImageReport on img, whose metadata
+// is info. Lists in the report are truncated to maxItems entries.
+func buildReport(img image.Image, info *imaging.ImageInfo, colorCount, maxItems int) *ImageReport {
+	report := &ImageReport{Image: info}
+	skip := func(section string, err error) {
+		if report.Skipped == nil {
+			report.Skipped = make(map[string]string)
+		}
+		report.Skipped[section] = err.Error()
+	}
+
+	if content, err := imaging.ClassifyContent(img, false); err != nil {
+		skip("content", err)
+	} else {
+		report.Content = &content.ContentClassification
+	}
+	if quality, err := imaging.MeasureQuality(img); err != nil {
+		skip("quality", err)
+	} else {
+		report.Quality = quality
+	}
+	if colors, err := imaging.DominantColors(img, colorCount, nil); err != nil {
+		skip("dominant_colors", err)
+	} else {
+		report.DominantColors = colors.Colors
+	}
+	if text, err := detection.DetectTextRegions(img, 0.5); err != nil {
+		skip("text_regions", err)
+	} else {
+		text.Regions = truncate(text.Regions, maxItems)
+		report.TextRegions = text
+	}
+	if shapes, err := detectReportShapes(img, maxItems); err != nil {
+		skip("shapes", err)
+	} else {
+		report.Shapes = shapes
+	}
+	return report
+}
+
+// detectReportShapes runs the shape detectors with the defaults of the
+// individual tools, except that circles larger than the image can hold are
+// not searched for.
+func detectReportShapes(img image.Image, maxItems int) (*ReportShapes, error) {
+	rects, err := detection.DetectRectangles(img, 100, 0.9)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := detection.DetectLines(img, 20, false)
+	if err != nil {
+		return nil, err
+	}
+	b := img.Bounds()
+	maxRadius := max(5, min(500, min(b.Dx(), b.Dy())/2))
+	circles, err := detection.DetectCircles(img, 5, maxRadius)
+	if err != nil {
+		return nil, err
+	}
+
+	rects.Rectangles = truncate(rects.Rectangles, maxItems)
+	lines.Lines = truncate(lines.Lines, maxItems)
+	circles.Circles = truncate(circles.Circles, maxItems)
+	return &ReportShapes{Rectangles: rects, Lines: lines, Circles: circles}, nil
+}
+
+// truncate returns the first n elements of list, or all of it if shorter.
+func truncate[T any](list []T, n int) []T {
+	if len(list) > n {
+		return list[:n]
+	}
+	return list
+}
+
+// renderReportMarkdown renders a report as a Markdown document titled
+// with the image's name.
+func renderReportMarkdown(name string, r *ImageReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Image Report: %s\n\n", name)
+
+	alpha := "no alpha"
+	if r.Image.HasAlpha {
+		alpha = "alpha"
+	}
+	b.WriteString("| Property | Value |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Dimensions | %d x %d |\n", r.Image.Width, r.Image.Height)
+	fmt.Fprintf(&b, "| Format | %s (%s, %s) |\n", r.Image.Format, r.Image.ColorDepth, alpha)
+	fmt.Fprintf(&b, "| File size | %d bytes |\n", r.Image.FileSizeBytes)
+
+	if r.Content != nil {
+		fmt.Fprintf(&b, "\n## Content\n\n**%s**: %s\n", r.Content.Class, r.Content.Reason)
+		if len(r.Content.SuggestedTools) > 0 {
+			fmt.Fprintf(&b, "\nSuggested tools: %s\n", strings.Join(r.Content.SuggestedTools, ", "))
+		}
+	}
+
+	if q := r.Quality; q != nil {
+		issues := "none"
+		if len(q.Issues) > 0 {
+			issues = strings.Join(q.Issues, ", ")
+		}
+		b.WriteString("\n## Quality\n\n")
+		fmt.Fprintf(&b, "- Brightness: %.1f\n", q.Brightness)
+		fmt.Fprintf(&b, "- Contrast: %.1f\n", q.Contrast)
+		fmt.Fprintf(&b, "- Sharpness: %.1f\n", q.Sharpness)
+		fmt.Fprintf(&b, "- Clipped: %.1f%% shadows, %.1f%% highlights\n", q.ShadowsClipped*100, q.HighlightsClipped*100)
+		fmt.Fprintf(&b, "- Issues: %s\n", issues)
+	}
+
+	if len(r.DominantColors) > 0 {
+		b.WriteString("\n## Dominant Colors\n\n| Color | Share |\n|---|---|\n")
+		for _, c := range r.DominantColors {
+			fmt.Fprintf(&b, "| %s | %.1f%% |\n", c.Hex, c.Percentage)
+		}
+	}
+
+	if t := r.TextRegions; t != nil {
+		fmt.Fprintf(&b, "\n## Text Regions\n\n%s\n", countLine(t.Count, len(t.Regions), "text region"))
+		if len(t.Regions) > 0 {
+			b.WriteString("\n| Bounds | Confidence |\n|---|---|\n")
+			for _, region := range t.Regions {
+				fmt.Fprintf(&b, "| %s | %.2f |\n", boundsText(region.Bounds), region.Confidence)
+			}
+		}
+	}
+
+	if sh := r.Shapes; sh != nil {
+		b.WriteString("\n## Shapes\n")
+		fmt.Fprintf(&b, "\n### Rectangles\n\n%s\n", countLine(sh.Rectangles.Count, len(sh.Rectangles.Rectangles), "rectangle"))
+		if len(sh.Rectangles.Rectangles) > 0 {
+			b.WriteString("\n| Bounds | Size | Fill |\n|---|---|---|\n")
+			for _, rect := range sh.Rectangles.Rectangles {
+				fmt.Fprintf(&b, "| %s | %d x %d | %s |\n", boundsText(rect.Bounds), rect.Width, rect.Height, rect.FillColor)
+			}
+		}
+		fmt.Fprintf(&b, "\n### Lines\n\n%s\n", countLine(sh.Lines.Count, len(sh.Lines.Lines), "line"))
+		if len(sh.Lines.Lines) > 0 {
+			b.WriteString("\n| From | To | Length | Angle |\n|---|---|---|---|\n")
+			for _, l := range sh.Lines.Lines {
+				fmt.Fprintf(&b, "| (%d, %d) | (%d, %d) | %.1f | %.1f° |\n", l.Start.X, l.Start.Y, l.End.X, l.End.Y, l.Length, l.AngleDegrees)
+			}
+		}
+		fmt.Fprintf(&b, "\n### Circles\n\n%s\n", countLine(sh.Circles.Count, len(sh.Circles.Circles), "circle"))
+		if len(sh.Circles.Circles) > 0 {
+			b.WriteString("\n| Center | Radius | Fill |\n|---|---|---|\n")
+			for _, c := range sh.Circles.Circles {
+				fmt.Fprintf(&b, "| (%d, %d) | %d | %s |\n", c.Center.X, c.Center.Y, c.Radius, c.FillColor)
+			}
+		}
+	}
+
+	if len(r.Skipped) > 0 {
+		b.WriteString("\n## Skipped\n\n")
+		for _, section := range sortedKeys(r.Skipped, nil) {
+			fmt.Fprintf(&b, "- %s: %s\n", section, r.Skipped[section])
+		}
+	}
+	return b.String()
+}
+
+// countLine describes how many items were found and how many are listed.
+func countLine(total, listed int, noun string) string {
+	if total != 1 {
+		noun += "s"
+	}
+	if listed < total {
+		return fmt.Sprintf("%d %s found, first %d listed.", total, noun, listed)
+	}
+	return fmt.Sprintf("%d %s found.", total, noun)
+}
+
+// boundsText formats a bounding box as "(x1, y1)-(x2, y2)".
+func boundsText(b detection.Bounds) string {
+	return fmt.Sprintf("(%d, %d)-(%d, %d)", b.X1, b.Y1, b.X2, b.Y2)
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ironsheep/image-tools-mcp/internal/detection"
+	"github.com/ironsheep/image-tools-mcp/internal/imaging"
+)
+
+func TestRenderReportMarkdown(t *testing.T) {
+	report := &ImageReport{
+		Image:          &imaging.ImageInfo{Width: 800, Height: 600, Format: "png", ColorDepth: "8-bit", FileSizeBytes: 1234},
+		Quality:        &imaging.QualityResult{Brightness: 240, Contrast: 30, Sharpness: 900, Issues: []string{}},
+		DominantColors: []imaging.ColorFrequency{{Hex: "#F0F0F0", Percentage: 91.5}},
+		TextRegions: &detection.TextRegionsResult{
+			Regions: []detection.TextRegion{{Bounds: detection.Bounds{X1: 10, Y1: 20, X2: 110, Y2: 50}, Confidence: 0.8}},
+			Count:   3,
+		},
+		Skipped: map[string]string{"content": "image too small to classify"},
+	}
+
+	md := renderReportMarkdown("diagram.png", report)
+	for _, want := range []string{
+		"# Image Report: diagram.png\n",
+		"| Dimensions | 800 x 600 |\n",
+		"| Format | png (8-bit, no alpha) |\n",
+		"- Issues: none\n",
+		"| #F0F0F0 | 91.5% |\n",
+		"3 text regions found, first 1 listed.\n",
+		"| (10, 20)-(110, 50) | 0.80 |\n",
+		"- content: image too small to classify\n",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown lacks %q:\n%s", want, md)
+		}
+	}
+	for _, absent := range []string{"## Content", "## Shapes"} {
+		if strings.Contains(md, absent) {
+			t.Errorf("markdown has %q for a section not in the report", absent)
+		}
+	}
+}
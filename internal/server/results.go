@@ -0,0 +1,85 @@
+package server
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// Limits on the results kept by resultCache. Results that carry images
+// can be large, so the cache is bounded by size as well as count, and a
+// single result over maxCachedResultBytes/4 is not cached at all.
+const (
+	maxCachedResults     = 64
+	maxCachedResultBytes = 64 << 20
+)
+
+// resultCache holds the encoded results of recent idempotent tool calls,
+// keyed by tool name and arguments, so a repeated call is answered without
+// running the analysis again. Like the image cache, it assumes files do
+// not change while the server runs.
+//
+// The oldest entry is dropped once either limit is exceeded. A resultCache
+// is safe for concurrent use.
+type resultCache struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+	order   []string // keys, oldest first
+	size    int      // total bytes of entries
+}
+
+func newResultCache() *resultCache {
+	return &resultCache{entries: make(map[string][]byte)}
+}
+
+// resultKey returns the cache key for a call, or "" if the arguments are
+// not valid JSON. Arguments are re-encoded so that key order and spacing
+// do not matter.
+func resultKey(name string, args json.RawMessage) string {
+	var v interface{}
+	if len(args) > 0 {
+		if err := json.Unmarshal(args, &v); err != nil {
+			return ""
+		}
+	}
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return name + "\x00" + string(canonical)
+}
+
+// get returns the encoded result stored under key.
+func (c *resultCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, ok := c.entries[key]
+	return data, ok
+}
+
+// put stores an encoded result under key, evicting the oldest entries to
+// stay within the limits. data must not be modified afterwards.
+func (c *resultCache) put(key string, data []byte) {
+	if key == "" || len(data) > maxCachedResultBytes/4 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.entries[key]; ok {
+		c.size -= len(old)
+		for i, k := range c.order {
+			if k == key {
+				c.order = append(c.order[:i], c.order[i+1:]...)
+				break
+			}
+		}
+	}
+	c.entries[key] = data
+	c.order = append(c.order, key)
+	c.size += len(data)
+	for len(c.order) > maxCachedResults || c.size > maxCachedResultBytes {
+		oldest := c.order[0]
+		c.size -= len(c.entries[oldest])
+		delete(c.entries, oldest)
+		c.order = c.order[1:]
+	}
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"os"
+	"testing"
+)
+
+func TestResultKey(t *testing.T) {
+	a := resultKey("image_crop", json.RawMessage(`{"path":"/a.png","x1":1,"y1":2}`))
+	b := resultKey("image_crop", json.RawMessage(`{ "y1": 2, "x1": 1, "path": "/a.png" }`))
+	if a == "" || a != b {
+		t.Errorf("equivalent arguments gave keys %q and %q", a, b)
+	}
+	if c := resultKey("image_dimensions", json.RawMessage(`{"path":"/a.png","x1":1,"y1":2}`)); c == a {
+		t.Error("different tools share a key")
+	}
+	if k := resultKey("image_crop", json.RawMessage(`{"path":`)); k != "" {
+		t.Errorf("invalid arguments: got key %q, want none", k)
+	}
+}
+
+func TestResultCache_Limits(t *testing.T) {
+	c := newResultCache()
+	for i := 0; i < maxCachedResults+5; i++ {
+		c.put(fmt.Sprintf("k%d", i), []byte("{}"))
+	}
+	if len(c.entries) != maxCachedResults {
+		t.Errorf("entries: got %d, want %d", len(c.entries), maxCachedResults)
+	}
+	if _, ok := c.get("k0"); ok {
+		t.Error("oldest entry should have been evicted")
+	}
+	if _, ok := c.get(fmt.Sprintf("k%d", maxCachedResults+4)); !ok {
+		t.Error("newest entry missing")
+	}
+
+	// Large results push out older ones by size; oversized ones are skipped
+	big := make([]byte, maxCachedResultBytes/4)
+	for i := 0; i < 5; i++ {
+		c.put(fmt.Sprintf("big%d", i), big)
+	}
+	if c.size > maxCachedResultBytes {
+		t.Errorf("size %d exceeds limit %d", c.size, maxCachedResultBytes)
+	}
+	if _, ok := c.get("big0"); ok {
+		t.Error("big0 should have been evicted by size")
+	}
+	c.put("huge", make([]byte, maxCachedResultBytes/4+1))
+	if _, ok := c.get("huge"); ok {
+		t.Error("oversized result should not be cached")
+	}
+
+	// Replacing an entry keeps the size accounting exact
+	c = newResultCache()
+	c.put("k", []byte("12345"))
+	c.put("k", []byte("12"))
+	if c.size != 2 || len(c.order) != 1 {
+		t.Errorf("after replace: size %d, order %v", c.size, c.order)
+	}
+}
+
+func TestHandleToolsCall_ResultCache(t *testing.T) {
+	s := New()
+	imgPath := createTestImageFile(t, 40, 30, color.RGBA{255, 0, 0, 255})
+	defer os.Remove(imgPath)
+
+	call := func(name string, args map[string]interface{}) *MCPResponse {
+		paramsJSON, _ := json.Marshal(map[string]interface{}{"name": name, "arguments": args})
+		return s.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 1, Method: "tools/call", Params: paramsJSON})
+	}
+
+	if resp := call("image_dimensions", map[string]interface{}{"path": imgPath}); resp.Error != nil {
+		t.Fatalf("unexpected error: %v", resp.Error)
+	}
+	if len(s.results.entries) != 1 {
+		t.Fatalf("cached results: got %d, want 1", len(s.results.entries))
+	}
+
+	// With the file and the decoded image gone, only the cache can answer
+	os.Remove(imgPath)
+	s.cache.Evict(imgPath)
+	resp := call("image_dimensions", map[string]interface{}{"path": imgPath})
+	if resp.Error != nil {
+		t.Fatalf("repeated call not served from cache: %v", resp.Error)
+	}
+	var dims struct{ Width, Height int }
+	result := resp.Result.(map[string]interface{})
+	json.Unmarshal(result["structuredContent"].(json.RawMessage), &dims)
+	if dims.Width != 40 || dims.Height != 30 {
+		t.Errorf("cached dimensions: got %dx%d, want 40x30", dims.Width, dims.Height)
+	}
+
+	// Errors and stateful tools are not cached
+	if resp := call("image_detect_incremental", map[string]interface{}{"path": imgPath}); resp.Error == nil {
+		t.Fatal("expected error for missing file")
+	}
+	imgPath2 := createTestImageFile(t, 40, 30, color.RGBA{0, 255, 0, 255})
+	defer os.Remove(imgPath2)
+	if resp := call("image_detect_incremental", map[string]interface{}{"path": imgPath2}); resp.Error != nil {
+		t.Fatalf("unexpected error: %v", resp.Error)
+	}
+	if len(s.results.entries) != 1 {
+		t.Errorf("cached results: got %d, want 1", len(s.results.entries))
+	}
+}
@@ -0,0 +1,305 @@
+package server
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/ironsheep/image-tools-mcp/internal/detection"
+	"github.com/ironsheep/image-tools-mcp/internal/imaging"
+)
+
+// safeAreaPreset is the canvas of a marketing or social format, and the
+// insets, in canvas pixels, of its safe area: the part no device crops
+// and no platform interface covers.
+type safeAreaPreset struct {
+	width, height int
+	insets        imaging.Insets
+}
+
+// safeAreaPresets are the formats image_safe_area accepts, from the
+// platforms' published guidelines and common templates.
+var safeAreaPresets = map[string]safeAreaPreset{
+	// App Store screenshots (6.7" iPhone): 5% margins for captions and
+	// key content, clear of rounded corners and device frames.
+	"app_store_screenshot": {1290, 2796, imaging.Insets{Top: 140, Left: 65, Bottom: 140, Right: 65}},
+
+	// Google Play feature graphic: content centered, clear of the edges
+	// cropped on some surfaces.
+	"google_play_feature": {1024, 500, imaging.Insets{Top: 50, Left: 100, Bottom: 50, Right: 100}},
+
+	// YouTube channel banner: the 1546×423 area shown on every device.
+	"youtube_banner": {2560, 1440, imaging.Insets{Top: 508, Left: 507, Bottom: 509, Right: 507}},
+
+	// X (Twitter) header: the band left when mobile apps crop the top
+	// and bottom.
+	"x_header": {1500, 500, imaging.Insets{Top: 60, Bottom: 60}},
+
+	// Facebook cover photo: the center mobile apps show of the desktop
+	// cover.
+	"facebook_cover": {1640, 624, imaging.Insets{Left: 180, Right: 180}},
+
+	// Instagram story: clear of the profile header and the reply bar.
+	"instagram_story": {1080, 1920, imaging.Insets{Top: 250, Left: 60, Bottom: 340, Right: 60}},
+
+	// Instagram reel: clear of the header, the caption, and the action
+	// buttons at the right.
+	"instagram_reel": {1080, 1920, imaging.Insets{Top: 220, Left: 60, Bottom: 420, Right: 120}},
+
+	// TikTok video: clear of the tabs, the caption, and the action
+	// buttons at the right.
+	"tiktok": {1080, 1920, imaging.Insets{Top: 130, Left: 60, Bottom: 484, Right: 140}},
+}
+
+// safeAreaPresetNames returns the names of safeAreaPresets, sorted.
+func safeAreaPresetNames() []string {
+	names := make([]string, 0, len(safeAreaPresets))
+	for name := range safeAreaPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Safe area checks: content segments are at least minSafeAreaContent of
+// the image (in percent) and at most maxSafeAreaContent, above which they
+// are background; at most maxSafeAreaViolations are listed.
+const (
+	minSafeAreaContent    = 0.1
+	maxSafeAreaContent    = 25
+	maxSafeAreaViolations = 50
+)
+
+// SafeAreaResult is the result of image_safe_area: the safe area of a
+// format on an image, and the text and content found outside it.
+//
+// A check that cannot run (an image too small for text detection, say)
+// is left out and the reason recorded in Skipped.
+type SafeAreaResult struct {
+	// Preset is the format, if one was given.
+	Preset string `json:"preset,omitempty"`
+
+	// Width and Height of the image in pixels.
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// SafeArea is the safe area in image coordinates, and Margins the
+	// widths around it, in pixels.
+	SafeArea imaging.Region `json:"safe_area"`
+	Margins  imaging.Insets `json:"margins"`
+
+	// Passed is true if no text or content was found outside the safe
+	// area.
+	Passed bool `json:"passed"`
+
+	// Violations are the text and content crossing or outside the safe
+	// area, the most outside first.
+	Violations []SafeAreaViolation `json:"violations"`
+
+	// TotalViolations counts all violations, including any beyond the 50
+	// listed.
+	TotalViolations int `json:"total_violations"`
+
+	// Warnings note problems with the check itself, such as an image
+	// whose aspect ratio differs from the preset's canvas.
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Skipped maps each check left out to the reason.
+	Skipped map[string]string `json:"skipped,omitempty"`
+
+	// ImageBase64 is the image with the safe area guides and the
+	// violations outlined, as base64 PNG, if requested.
+	ImageBase64 string `json:"image_base64,omitempty"`
+
+	// MimeType is "image/png" when ImageBase64 is set.
+	MimeType string `json:"mime_type,omitempty"`
+}
+
+// SafeAreaViolation is text or content crossing or outside the safe area.
+type SafeAreaViolation struct {
+	// Kind is "text" (heuristic text regions) or "content" (regions of
+	// color distinct from their surroundings, such as logos and product
+	// shots).
+	Kind string `json:"kind"`
+
+	// Bounds is the region's bounding box.
+	Bounds imaging.Region `json:"bounds"`
+
+	// OutsidePercent is the share of the bounding box outside the safe
+	// area, in percent.
+	OutsidePercent float64 `json:"outside_percent"`
+
+	// Sides are the margins the region extends into: "top", "left",
+	// "bottom", and "right".
+	Sides []string `json:"sides"`
+}
+
+// safeAreaOptions are the parameters of buildSafeArea.
+type safeAreaOptions struct {
+	preset       string          // Name in safeAreaPresets, or empty
+	margins      *imaging.Insets // Custom margins in percent, overriding the preset's
+	checkText    bool
+	checkContent bool
+	includeImage bool
+}
+
+// safeAreaMargins returns the margins, in pixels, of the safe area of a
+// w×h image for opts, and a warning if the image's aspect ratio differs
+// from the preset's canvas by over 2%.
+func safeAreaMargins(w, h int, opts safeAreaOptions) (imaging.Insets, string, error) {
+	if opts.margins != nil {
+		m := *opts.margins
+		for _, v := range []int{m.Top, m.Left, m.Bottom, m.Right} {
+			if v < 0 || v > 49 {
+				return imaging.Insets{}, "", fmt.Errorf("margins must be between 0 and 49 percent, got %d", v)
+			}
+		}
+		pct := func(v, size int) int { return int(math.Round(float64(v) * float64(size) / 100)) }
+		return imaging.Insets{Top: pct(m.Top, h), Left: pct(m.Left, w), Bottom: pct(m.Bottom, h), Right: pct(m.Right, w)}, "", nil
+	}
+	if opts.preset == "" {
+		return imaging.Insets{}, "", fmt.Errorf("preset or margins is required")
+	}
+	p, ok := safeAreaPresets[opts.preset]
+	if !ok {
+		return imaging.Insets{}, "", fmt.Errorf("unknown preset: %s (use %s)", opts.preset, strings.Join(safeAreaPresetNames(), ", "))
+	}
+	sx, sy := float64(w)/float64(p.width), float64(h)/float64(p.height)
+	m := imaging.Insets{
+		Top:    int(math.Round(float64(p.insets.Top) * sy)),
+		Left:   int(math.Round(float64(p.insets.Left) * sx)),
+		Bottom: int(math.Round(float64(p.insets.Bottom) * sy)),
+		Right:  int(math.Round(float64(p.insets.Right) * sx)),
+	}
+	var warning string
+	if want := float64(p.width) / float64(p.height); math.Abs(float64(w)/float64(h)-want) > 0.02*want {
+		warning = fmt.Sprintf("the image is %d×%d, but %s is %d×%d; the safe area was scaled to the image on each axis", w, h, opts.preset, p.width, p.height)
+	}
+	return m, warning, nil
+}
+
+// buildSafeArea finds the safe area of opts on img and the text and
+// content outside it.
+func buildSafeArea(img image.Image, opts safeAreaOptions) (*SafeAreaResult, error) {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	margins, warning, err := safeAreaMargins(w, h, opts)
+	if err != nil {
+		return nil, err
+	}
+	safe := imaging.Region{X1: margins.Left, Y1: margins.Top, X2: w - margins.Right, Y2: h - margins.Bottom}
+	if safe.X2 <= safe.X1 || safe.Y2 <= safe.Y1 {
+		return nil, fmt.Errorf("the margins leave no safe area in a %dx%d image", w, h)
+	}
+	result := &SafeAreaResult{Preset: opts.preset, Width: w, Height: h, SafeArea: safe, Margins: margins, Violations: []SafeAreaViolation{}}
+	if opts.margins != nil {
+		result.Preset = ""
+	}
+	if warning != "" {
+		result.Warnings = append(result.Warnings, warning)
+	}
+	skip := func(check string, err error) {
+		if result.Skipped == nil {
+			result.Skipped = make(map[string]string)
+		}
+		result.Skipped[check] = err.Error()
+	}
+	violations := findSafeAreaViolations(img, safe, opts.checkText, opts.checkContent, skip)
+	result.TotalViolations = len(violations)
+	result.Passed = len(violations) == 0
+	if len(violations) > maxSafeAreaViolations {
+		violations = violations[:maxSafeAreaViolations]
+	}
+	result.Violations = append(result.Violations, violations...)
+
+	if opts.includeImage {
+		flagged := make([]imaging.Region, len(result.Violations))
+		for i, v := range result.Violations {
+			flagged[i] = v.Bounds
+		}
+		encoded, err := imaging.SafeAreaOverlay(img, safe, flagged)
+		if err != nil {
+			return nil, err
+		}
+		result.ImageBase64, result.MimeType = encoded, "image/png"
+	}
+	return result, nil
+}
+
+// findSafeAreaViolations returns the text and content of img crossing or
+// outside the safe area, the most outside first, calling skip for each
+// check that cannot run.
+func findSafeAreaViolations(img image.Image, safe imaging.Region, checkText, checkContent bool, skip func(check string, err error)) []SafeAreaViolation {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	var violations []SafeAreaViolation
+	var textBounds []detection.Bounds
+	if checkText {
+		if text, err := detection.DetectTextRegions(img, 0.5); err != nil {
+			skip("text", err)
+		} else {
+			for _, region := range text.Regions {
+				textBounds = append(textBounds, region.Bounds)
+				r := imaging.Region{X1: region.Bounds.X1 - b.Min.X, Y1: region.Bounds.Y1 - b.Min.Y, X2: region.Bounds.X2 - b.Min.X, Y2: region.Bounds.Y2 - b.Min.Y}
+				if v, ok := safeAreaViolation("text", r, safe); ok {
+					violations = append(violations, v)
+				}
+			}
+		}
+	}
+	if checkContent {
+		minSize := max(16, w*h/1000)
+		if segments, err := imaging.SegmentRegions(img, 300, minSize, 500, false); err != nil {
+			skip("content", err)
+		} else {
+			for _, s := range segments.Regions {
+				r := s.Bounds
+				edges := 0
+				for _, touches := range []bool{r.X1 == 0, r.Y1 == 0, r.X2 == w, r.Y2 == h} {
+					if touches {
+						edges++
+					}
+				}
+				// Backgrounds are large or span the image; glyphs are
+				// reported with their text
+				if s.AreaPercent < minSafeAreaContent || s.AreaPercent > maxSafeAreaContent || edges >= 3 ||
+					containedIn(detection.Bounds{X1: r.X1 + b.Min.X, Y1: r.Y1 + b.Min.Y, X2: r.X2 + b.Min.X, Y2: r.Y2 + b.Min.Y}, textBounds) {
+					continue
+				}
+				if v, ok := safeAreaViolation("content", r, safe); ok {
+					violations = append(violations, v)
+				}
+			}
+		}
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].OutsidePercent > violations[j].OutsidePercent
+	})
+	return violations
+}
+
+// safeAreaViolation returns the violation of region r, of kind, against
+// the safe area, and false if r lies within it.
+func safeAreaViolation(kind string, r, safe imaging.Region) (SafeAreaViolation, bool) {
+	v := SafeAreaViolation{Kind: kind, Bounds: r, Sides: []string{}}
+	for _, side := range []struct {
+		name    string
+		crosses bool
+	}{
+		{"top", r.Y1 < safe.Y1}, {"left", r.X1 < safe.X1}, {"bottom", r.Y2 > safe.Y2}, {"right", r.X2 > safe.X2},
+	} {
+		if side.crosses {
+			v.Sides = append(v.Sides, side.name)
+		}
+	}
+	if len(v.Sides) == 0 {
+		return v, false
+	}
+	area := (r.X2 - r.X1) * (r.Y2 - r.Y1)
+	inside := max(0, min(r.X2, safe.X2)-max(r.X1, safe.X1)) * max(0, min(r.Y2, safe.Y2)-max(r.Y1, safe.Y1))
+	if area > 0 {
+		v.OutsidePercent = math.Round(float64(area-inside)/float64(area)*1000) / 10
+	}
+	return v, true
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/ironsheep/image-tools-mcp/internal/imaging"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// storyScene draws a 540×960 story (half of 1080×1920) on white: a
+// caption at the top, a red logo in the middle, and a blue badge at the
+// bottom, under TikTok's caption area.
+func storyScene() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 540, 960))
+	fill(img, 0, 0, 540, 960, color.RGBA{255, 255, 255, 255})
+	d := &font.Drawer{Dst: img, Src: image.NewUniform(color.RGBA{0, 0, 0, 255}), Face: basicfont.Face7x13}
+	for i, line := range []string{"NEW SEASON SALE - UP TO", "HALF OFF EVERYTHING IN STORE"} {
+		d.Dot = fixed.P(150, 30+i*18)
+		d.DrawString(line)
+	}
+	fill(img, 240, 400, 300, 460, color.RGBA{208, 48, 48, 255})
+	fill(img, 200, 800, 280, 860, color.RGBA{48, 48, 208, 255})
+	return img
+}
+
+func TestBuildSafeArea_Preset(t *testing.T) {
+	result, err := buildSafeArea(storyScene(), safeAreaOptions{preset: "tiktok", checkText: true, checkContent: true, includeImage: true})
+	if err != nil {
+		t.Fatalf("buildSafeArea failed: %v", err)
+	}
+	want := imaging.Insets{Top: 65, Left: 30, Bottom: 242, Right: 70}
+	if result.Margins != want {
+		t.Errorf("margins = %+v, want %+v", result.Margins, want)
+	}
+	if result.SafeArea != (imaging.Region{X1: 30, Y1: 65, X2: 470, Y2: 718}) {
+		t.Errorf("safe area = %+v", result.SafeArea)
+	}
+	if len(result.Warnings) != 0 {
+		t.Errorf("unexpected warnings for a 9:16 image: %v", result.Warnings)
+	}
+	if result.Passed || result.TotalViolations != len(result.Violations) {
+		t.Errorf("passed = %v, %d of %d violations listed", result.Passed, len(result.Violations), result.TotalViolations)
+	}
+
+	var badge, caption bool
+	for _, v := range result.Violations {
+		if v.Bounds.Y1 >= 380 && v.Bounds.Y2 <= 480 {
+			t.Errorf("logo inside the safe area reported: %+v", v)
+		}
+		if v.Kind == "content" && v.Bounds == (imaging.Region{X1: 200, Y1: 800, X2: 280, Y2: 860}) {
+			badge = true
+			if v.OutsidePercent != 100 || len(v.Sides) != 1 || v.Sides[0] != "bottom" {
+				t.Errorf("badge violation = %+v", v)
+			}
+		}
+		if v.Kind == "text" && v.Bounds.Y1 < 65 && v.Sides[0] == "top" {
+			caption = true
+		}
+	}
+	if !badge {
+		t.Errorf("badge under the caption area not reported: %+v", result.Violations)
+	}
+	if !caption {
+		t.Errorf("caption across the top margin not reported: %+v", result.Violations)
+	}
+	if result.ImageBase64 == "" || result.MimeType != "image/png" {
+		t.Error("expected an overlay image")
+	}
+}
+
+func TestBuildSafeArea_Margins(t *testing.T) {
+	margins := imaging.Insets{Top: 5, Left: 10, Bottom: 5, Right: 10}
+	result, err := buildSafeArea(storyScene(), safeAreaOptions{preset: "tiktok", margins: &margins, checkContent: true})
+	if err != nil {
+		t.Fatalf("buildSafeArea failed: %v", err)
+	}
+	if result.Preset != "" || result.Margins != (imaging.Insets{Top: 48, Left: 54, Bottom: 48, Right: 54}) {
+		t.Errorf("preset %q, margins %+v", result.Preset, result.Margins)
+	}
+	if !result.Passed || result.Skipped != nil || result.ImageBase64 != "" {
+		t.Errorf("expected a pass without an image, got %+v", result)
+	}
+}
+
+func TestBuildSafeArea_AspectWarning(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 400))
+	result, err := buildSafeArea(img, safeAreaOptions{preset: "youtube_banner"})
+	if err != nil {
+		t.Fatalf("buildSafeArea failed: %v", err)
+	}
+	if len(result.Warnings) != 1 {
+		t.Errorf("expected an aspect ratio warning, got %v", result.Warnings)
+	}
+}
+
+func TestBuildSafeArea_Errors(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	for _, opts := range []safeAreaOptions{
+		{},
+		{preset: "myspace"},
+		{margins: &imaging.Insets{Top: 50}},
+		{margins: &imaging.Insets{Left: -1}},
+	} {
+		if _, err := buildSafeArea(img, opts); err == nil {
+			t.Errorf("expected error for %+v", opts)
+		}
+	}
+}
+
+func TestSafeAreaViolation(t *testing.T) {
+	safe := imaging.Region{X1: 10, Y1: 10, X2: 90, Y2: 90}
+	if _, ok := safeAreaViolation("text", imaging.Region{X1: 10, Y1: 10, X2: 90, Y2: 90}, safe); ok {
+		t.Error("region filling the safe area reported")
+	}
+	v, ok := safeAreaViolation("text", imaging.Region{X1: 0, Y1: 50, X2: 20, Y2: 60}, safe)
+	if !ok || v.OutsidePercent != 50 || len(v.Sides) != 1 || v.Sides[0] != "left" {
+		t.Errorf("got %+v, %v", v, ok)
+	}
+	v, _ = safeAreaViolation("content", imaging.Region{X1: 85, Y1: 85, X2: 95, Y2: 95}, safe)
+	if v.OutsidePercent != 75 || len(v.Sides) != 2 {
+		t.Errorf("corner region = %+v", v)
+	}
+}
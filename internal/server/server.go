@@ -4,18 +4,66 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"sync"
 
+	"github.com/ironsheep/image-tools-mcp/internal/baseline"
+	"github.com/ironsheep/image-tools-mcp/internal/clipboard"
+	"github.com/ironsheep/image-tools-mcp/internal/detection"
 	"github.com/ironsheep/image-tools-mcp/internal/imaging"
 )
 
+// protocolVersions lists the MCP protocol versions the server speaks,
+// newest first. Tool output schemas and structured results are part of
+// 2025-06-18; clients on older versions ignore them.
+var protocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// maxSnapshots is the number of detection snapshots kept for
+// image_detect_incremental. The oldest is dropped when a new one is stored.
+const maxSnapshots = 8
+
 // Server handles MCP protocol communication over stdio.
 //
 // The server maintains an image cache for efficient repeated access to images
 // and processes JSON-RPC requests to execute image analysis tools.
 type Server struct {
 	cache *imaging.ImageCache
+
+	// thumbs caches image_thumbnail previews by path, size, and format.
+	thumbs *imaging.ThumbnailCache
+
+	// results caches the encoded results of idempotent tool calls.
+	results *resultCache
+
+	// baselines holds the images stored by image_baseline_store, in the
+	// "baselines" directory under the cache directory.
+	baselines *baseline.Store
+
+	// clipboardDir holds the images read by image_from_clipboard, the
+	// "clipboard" directory under the cache directory. readClipboard
+	// reads the clipboard image (clipboard.ReadImage, replaced in tests).
+	clipboardDir  string
+	readClipboard func() ([]byte, error)
+
+	// snapshots holds the detection state of recently analyzed images,
+	// keyed by path, so image_detect_incremental can update rather than
+	// redo it. snapshotOrder lists the paths oldest first.
+	snapshotMu    sync.Mutex
+	snapshots     map[string]*detection.Snapshot
+	snapshotOrder []string
+
+	// calibrations holds the scale bar calibrations found by
+	// image_detect_scale_bar, keyed by path, for image_measure_distance.
+	calibrationMu sync.Mutex
+	calibrations  map[string]*imaging.ScaleCalibration
+
+	// capture, if set, receives a CaptureRecord per request (see
+	// SetCapture).
+	captureMu sync.Mutex
+	capture   io.Writer
 }
 
 // MCPRequest represents an incoming JSON-RPC 2.0 request.
@@ -70,10 +118,54 @@ type MCPNotification struct {
 // It maintains an internal image cache that persists for the server's lifetime.
 func New() *Server {
 	return &Server{
-		cache: imaging.NewImageCache(),
+		cache:         imaging.NewImageCache(),
+		thumbs:        imaging.NewThumbnailCache(),
+		results:       newResultCache(),
+		baselines:     baseline.NewStore(filepath.Join(DefaultCacheDir(), "baselines")),
+		clipboardDir:  filepath.Join(DefaultCacheDir(), "clipboard"),
+		readClipboard: clipboard.ReadImage,
+		snapshots:     make(map[string]*detection.Snapshot),
+		calibrations:  make(map[string]*imaging.ScaleCalibration),
 	}
 }
 
+// DefaultCacheDir returns the directory the server keeps persistent data
+// in unless SetCacheDir is called: "image-tools-mcp" under the user's
+// cache directory, or under the temporary directory if there is none.
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "image-tools-mcp")
+}
+
+// SetCacheDir sets the directory the server keeps persistent data, such
+// as visual regression baselines and clipboard images, in. It is created
+// when first written. It should be called before Run.
+func (s *Server) SetCacheDir(dir string) {
+	s.baselines = baseline.NewStore(filepath.Join(dir, "baselines"))
+	s.clipboardDir = filepath.Join(dir, "clipboard")
+}
+
+// SetSVGScale sets the render scale used when SVG files are loaded
+// (output pixels per SVG user unit). It should be called before Run.
+func (s *Server) SetSVGScale(scale float64) {
+	s.cache.SetSVGScale(scale)
+}
+
+// SetMaxPixels sets the largest image, in pixels, that tools will load;
+// zero or a negative value removes the limit. It should be called before Run.
+func (s *Server) SetMaxPixels(n int64) {
+	s.cache.SetMaxPixels(n)
+}
+
+// SetColorConversion sets whether images with an embedded color profile are
+// converted to sRGB on load (the default). It should be called before Run.
+func (s *Server) SetColorConversion(enabled bool) {
+	s.cache.SetColorConversion(enabled)
+}
+
 // Run starts the MCP server's main loop, processing requests from stdin.
 //
 // The server reads JSON-RPC requests line-by-line from stdin and writes
@@ -87,12 +179,17 @@ func New() *Server {
 // Individual request parsing or handling errors are logged and don't
 // terminate the server.
 func (s *Server) Run() error {
-	scanner := bufio.NewScanner(os.Stdin)
+	return s.serve(os.Stdin, os.Stdout)
+}
+
+// serve is Run reading requests from in and writing responses to out.
+func (s *Server) serve(in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
 	// Increase buffer size for large requests
 	buf := make([]byte, 0, 64*1024)
 	scanner.Buffer(buf, 1024*1024)
 
-	encoder := json.NewEncoder(os.Stdout)
+	encoder := json.NewEncoder(out)
 
 	for scanner.Scan() {
 		line := scanner.Bytes()
@@ -103,6 +200,7 @@ func (s *Server) Run() error {
 		var req MCPRequest
 		if err := json.Unmarshal(line, &req); err != nil {
 			log.Printf("Failed to parse request: %v", err)
+			s.captureRequest(line, nil)
 			continue
 		}
 
@@ -112,6 +210,7 @@ func (s *Server) Run() error {
 				log.Printf("Failed to encode response: %v", err)
 			}
 		}
+		s.captureRequest(line, resp)
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -156,13 +255,28 @@ func (s *Server) handleRequest(req *MCPRequest) *MCPResponse {
 // handleInitialize responds to the MCP initialize request with server capabilities.
 //
 // This is the first request in the MCP handshake, establishing protocol version
-// and advertising available capabilities.
+// and advertising available capabilities. The client's requested version is
+// accepted if the server speaks it; otherwise the server offers its newest.
 func (s *Server) handleInitialize(req *MCPRequest) *MCPResponse {
+	var params struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	// Missing or malformed params just mean no version was requested
+	_ = json.Unmarshal(req.Params, &params)
+
+	version := protocolVersions[0]
+	for _, v := range protocolVersions {
+		if v == params.ProtocolVersion {
+			version = v
+			break
+		}
+	}
+
 	return &MCPResponse{
 		JSONRPC: "2.0",
 		ID:      req.ID,
 		Result: map[string]interface{}{
-			"protocolVersion": "2024-11-05",
+			"protocolVersion": version,
 			"capabilities": map[string]interface{}{
 				"tools": map[string]interface{}{},
 			},
@@ -186,8 +186,32 @@ func TestHandleRequest_Initialize(t *testing.T) {
 		t.Fatal("Result should be a map")
 	}
 
-	if result["protocolVersion"] != "2024-11-05" {
-		t.Errorf("protocolVersion: got %v", result["protocolVersion"])
+	// Without a requested version the newest is offered
+	if result["protocolVersion"] != protocolVersions[0] {
+		t.Errorf("protocolVersion: got %v, want %s", result["protocolVersion"], protocolVersions[0])
+	}
+}
+
+func TestHandleRequest_InitializeVersion(t *testing.T) {
+	tests := []struct {
+		requested, want string
+	}{
+		{"2024-11-05", "2024-11-05"},
+		{"2025-06-18", "2025-06-18"},
+		{"1999-01-01", protocolVersions[0]},
+	}
+	for _, tt := range tests {
+		s := New()
+		resp := s.handleRequest(&MCPRequest{
+			JSONRPC: "2.0",
+			ID:      1,
+			Method:  "initialize",
+			Params:  json.RawMessage(`{"protocolVersion":"` + tt.requested + `","capabilities":{}}`),
+		})
+		result := resp.Result.(map[string]interface{})
+		if result["protocolVersion"] != tt.want {
+			t.Errorf("requested %s: got %v, want %s", tt.requested, result["protocolVersion"], tt.want)
+		}
 	}
 }
 
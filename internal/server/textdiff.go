@@ -0,0 +1,186 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ironsheep/image-tools-mcp/internal/ocr"
+)
+
+// Text change types (see TextChange).
+const (
+	TextAdded   = "added"
+	TextRemoved = "removed"
+	TextChanged = "changed"
+)
+
+// textDiffMaxWords is the most words of each image image_text_diff
+// compares, bounding the memory of the word alignment.
+const textDiffMaxWords = 4000
+
+// TextDiffResult is the result of image_text_diff: how the text of image
+// B differs from the text of image A, word by word, in reading order.
+type TextDiffResult struct {
+	// Identical is true if the texts have the same words.
+	Identical bool `json:"identical"`
+
+	// Changes lists the runs of differing words, in reading order.
+	Changes []TextChange `json:"changes"`
+
+	// Added, Removed, and Changed count the changes of each type.
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+	Changed int `json:"changed"`
+
+	// WordsA and WordsB are the number of words read in each image, and
+	// UnchangedWords the number of them in both.
+	WordsA         int `json:"words_a"`
+	WordsB         int `json:"words_b"`
+	UnchangedWords int `json:"unchanged_words"`
+}
+
+// TextChange is a run of words of image A replaced by a run of words of
+// image B: text added to B, removed from A, or changed.
+type TextChange struct {
+	// Type is TextAdded, TextRemoved, or TextChanged.
+	Type string `json:"type"`
+
+	// TextA and TextB are the words of the run in each image, separated
+	// by spaces; empty for added and removed text.
+	TextA string `json:"text_a,omitempty"`
+	TextB string `json:"text_b,omitempty"`
+
+	// BoundsA and BoundsB are the bounding boxes of the run in each
+	// image, and WordBoundsA and WordBoundsB the boxes of its words, as
+	// a run can span lines.
+	BoundsA     *ocr.Bounds  `json:"bounds_a,omitempty"`
+	BoundsB     *ocr.Bounds  `json:"bounds_b,omitempty"`
+	WordBoundsA []ocr.Bounds `json:"word_bounds_a,omitempty"`
+	WordBoundsB []ocr.Bounds `json:"word_bounds_b,omitempty"`
+}
+
+// diffText compares the words of two images, each in reading order, and
+// returns the runs of words that differ, from the longest common
+// subsequence of the words. Words are compared without case if
+// ignoreCase is set.
+func diffText(wordsA, wordsB []ocr.TextRegion, ignoreCase bool) (*TextDiffResult, error) {
+	if len(wordsA) > textDiffMaxWords || len(wordsB) > textDiffMaxWords {
+		return nil, fmt.Errorf("too many words to compare: %d and %d (at most %d each)", len(wordsA), len(wordsB), textDiffMaxWords)
+	}
+	key := func(w ocr.TextRegion) string {
+		if ignoreCase {
+			return strings.ToLower(w.Text)
+		}
+		return w.Text
+	}
+	a := make([]string, len(wordsA))
+	for i, w := range wordsA {
+		a[i] = key(w)
+	}
+	b := make([]string, len(wordsB))
+	for i, w := range wordsB {
+		b[i] = key(w)
+	}
+
+	result := &TextDiffResult{Changes: []TextChange{}, WordsA: len(a), WordsB: len(b)}
+	i, j := 0, 0
+	for _, m := range commonWords(a, b) {
+		if m[0] > i || m[1] > j {
+			result.addChange(wordsA[i:m[0]], wordsB[j:m[1]])
+		}
+		result.UnchangedWords++
+		i, j = m[0]+1, m[1]+1
+	}
+	if i < len(a) || j < len(b) {
+		result.addChange(wordsA[i:], wordsB[j:])
+	}
+	result.Identical = len(result.Changes) == 0
+	return result, nil
+}
+
+// addChange adds the change replacing the words removed of image A with
+// the words added of image B.
+func (r *TextDiffResult) addChange(removed, added []ocr.TextRegion) {
+	c := TextChange{Type: TextChanged}
+	switch {
+	case len(removed) == 0:
+		c.Type = TextAdded
+		r.Added++
+	case len(added) == 0:
+		c.Type = TextRemoved
+		r.Removed++
+	default:
+		r.Changed++
+	}
+	c.TextA, c.BoundsA, c.WordBoundsA = textRun(removed)
+	c.TextB, c.BoundsB, c.WordBoundsB = textRun(added)
+	r.Changes = append(r.Changes, c)
+}
+
+// textRun returns the text, the bounding box, and the word boxes of a run
+// of words; nil boxes for no words.
+func textRun(words []ocr.TextRegion) (string, *ocr.Bounds, []ocr.Bounds) {
+	if len(words) == 0 {
+		return "", nil, nil
+	}
+	texts := make([]string, len(words))
+	boxes := make([]ocr.Bounds, len(words))
+	box := words[0].Bounds
+	for i, w := range words {
+		texts[i] = w.Text
+		boxes[i] = w.Bounds
+		box = ocr.Bounds{X1: min(box.X1, w.Bounds.X1), Y1: min(box.Y1, w.Bounds.Y1), X2: max(box.X2, w.Bounds.X2), Y2: max(box.Y2, w.Bounds.Y2)}
+	}
+	return strings.Join(texts, " "), &box, boxes
+}
+
+// commonWords returns the index pairs of a longest common subsequence of
+// a and b, in order. The common prefix and suffix are matched first, and
+// the rest by dynamic programming.
+func commonWords(a, b []string) [][2]int {
+	var pairs [][2]int
+	start := 0
+	for start < len(a) && start < len(b) && a[start] == b[start] {
+		pairs = append(pairs, [2]int{start, start})
+		start++
+	}
+	endA, endB := len(a), len(b)
+	for endA > start && endB > start && a[endA-1] == b[endB-1] {
+		endA--
+		endB--
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of
+	// a[start+i:endA] and b[start+j:endB]
+	n, m := endA-start, endB-start
+	lcs := make([][]uint16, n+1)
+	for i := range lcs {
+		lcs[i] = make([]uint16, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[start+i] == b[start+j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	for i, j := 0, 0; i < n && j < m; {
+		switch {
+		case a[start+i] == b[start+j]:
+			pairs = append(pairs, [2]int{start + i, start + j})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	for k := 0; endA+k < len(a); k++ {
+		pairs = append(pairs, [2]int{endA + k, endB + k})
+	}
+	return pairs
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/ironsheep/image-tools-mcp/internal/ocr"
+)
+
+func TestDiffText(t *testing.T) {
+	before := []ocr.TextRegion{
+		word("Welcome", 0, 0), word("back", 80, 0),
+		word("Save", 0, 30), word("changes", 50, 30),
+		word("Delete", 0, 60), word("account", 70, 60),
+		word("Help", 0, 90),
+	}
+	after := []ocr.TextRegion{
+		word("Welcome", 0, 0), word("back,", 80, 0), word("Ada", 140, 0),
+		word("Save", 0, 30), word("changes", 50, 30),
+		word("Help", 0, 60), word("center", 50, 60),
+	}
+	result, err := diffText(before, after, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []TextChange{
+		{Type: TextChanged, TextA: "back", TextB: "back, Ada"},
+		{Type: TextRemoved, TextA: "Delete account"},
+		{Type: TextAdded, TextB: "center"},
+	}
+	if len(result.Changes) != len(want) {
+		t.Fatalf("got changes %+v", result.Changes)
+	}
+	for i, c := range result.Changes {
+		if c.Type != want[i].Type || c.TextA != want[i].TextA || c.TextB != want[i].TextB {
+			t.Errorf("change %d: got %+v, want %+v", i, c, want[i])
+		}
+	}
+	if c := result.Changes[0]; *c.BoundsB != (ocr.Bounds{X1: 80, Y1: 0, X2: 170, Y2: 14}) || len(c.WordBoundsB) != 2 || len(c.WordBoundsA) != 1 {
+		t.Errorf("got changed bounds %+v", c)
+	}
+	if c := result.Changes[1]; c.BoundsB != nil || *c.BoundsA != (ocr.Bounds{X1: 0, Y1: 60, X2: 140, Y2: 74}) {
+		t.Errorf("got removed bounds %+v", c)
+	}
+	if result.Identical || result.Added != 1 || result.Removed != 1 || result.Changed != 1 ||
+		result.WordsA != 7 || result.WordsB != 7 || result.UnchangedWords != 4 {
+		t.Errorf("got counts %+v", result)
+	}
+}
+
+func TestDiffText_IgnoreCase(t *testing.T) {
+	a := []ocr.TextRegion{word("Sign", 0, 0), word("In", 50, 0)}
+	b := []ocr.TextRegion{word("Sign", 0, 0), word("in", 50, 0)}
+	if result, _ := diffText(a, b, false); result.Identical || result.Changed != 1 {
+		t.Errorf("case sensitive: got %+v", result)
+	}
+	if result, _ := diffText(a, b, true); !result.Identical || result.UnchangedWords != 2 {
+		t.Errorf("ignoring case: got %+v", result)
+	}
+	if result, _ := diffText(nil, nil, false); !result.Identical || result.Changes == nil {
+		t.Errorf("no words: got %+v", result)
+	}
+	if _, err := diffText(make([]ocr.TextRegion, textDiffMaxWords+1), nil, false); err == nil {
+		t.Error("expected error for too many words")
+	}
+}
+
+func TestCommonWords(t *testing.T) {
+	a := []string{"a", "b", "c", "d", "e", "f"}
+	b := []string{"a", "x", "c", "e", "d", "f"}
+	pairs := commonWords(a, b)
+	if len(pairs) != 4 || pairs[0] != [2]int{0, 0} || pairs[1] != [2]int{2, 2} || pairs[3] != [2]int{5, 5} {
+		t.Errorf("got %v", pairs)
+	}
+}
@@ -1,5 +1,12 @@
 package server
 
+import (
+	"slices"
+
+	"github.com/ironsheep/image-tools-mcp/internal/clipboard"
+	"github.com/ironsheep/image-tools-mcp/internal/imaging"
+)
+
 // Tool represents an MCP tool definition with JSON Schema for input validation.
 //
 // This struct is serialized directly to JSON for the tools/list response,
@@ -14,6 +21,339 @@ type Tool struct {
 	// InputSchema is a JSON Schema object describing the tool's parameters.
 	// Includes properties, required fields, types, and defaults.
 	InputSchema map[string]interface{} `json:"inputSchema"`
+
+	// OutputSchema is a JSON Schema object describing the structured
+	// result, generated from the tool's result type (see toolResults).
+	OutputSchema map[string]interface{} `json:"outputSchema,omitempty"`
+
+	// Annotations are hints about the tool's behavior, so clients can
+	// decide which calls are safe to repeat or run in parallel.
+	Annotations *ToolAnnotations `json:"annotations,omitempty"`
+}
+
+// ToolAnnotations are the MCP behavior hints for a tool. Clients treat
+// them as hints, not guarantees.
+type ToolAnnotations struct {
+	// ReadOnlyHint is true if the tool does not modify its environment.
+	ReadOnlyHint bool `json:"readOnlyHint"`
+
+	// DestructiveHint is true if the tool may delete or overwrite data.
+	DestructiveHint bool `json:"destructiveHint"`
+
+	// IdempotentHint is true if repeating a call with the same arguments
+	// returns the same result and has no further effect. The server
+	// caches the results of such calls (see resultCache).
+	IdempotentHint bool `json:"idempotentHint"`
+
+	// OpenWorldHint is true if the tool reaches outside the local
+	// machine (e.g., the network).
+	OpenWorldHint bool `json:"openWorldHint"`
+}
+
+// statefulTools are tools whose result depends on earlier calls, so they
+// are not idempotent. image_detect_incremental updates the previous
+// image's stored detections; the baseline tools read the current file and
+// the stored baselines; image_from_clipboard reads whatever was copied
+// last. image_detect_scale_bar stores the image's calibration, which
+// image_measure_distance, image_count_blobs, image_measure_polygon, and
+// image_check_parallel then report sizes in.
+var statefulTools = map[string]bool{
+	"image_detect_incremental": true,
+	"image_baseline_store":     true,
+	"image_baseline_compare":   true,
+	"image_from_clipboard":     true,
+	"image_detect_scale_bar":   true,
+	"image_measure_distance":   true,
+	"image_count_blobs":        true,
+	"image_measure_polygon":    true,
+	"image_check_parallel":     true,
+}
+
+// writingTools are tools that write files, in the cache directory, and
+// whether they may overwrite data. image_baseline_store replaces any
+// baseline of the same name; image_from_clipboard only adds files.
+var writingTools = map[string]bool{
+	"image_baseline_store": true,
+	"image_from_clipboard": false,
+}
+
+// toolAnnotations returns the annotations for the named tool. Every tool
+// reads local image files; only writingTools change anything.
+func toolAnnotations(name string) *ToolAnnotations {
+	destructive, writes := writingTools[name]
+	return &ToolAnnotations{
+		ReadOnlyHint:    !writes,
+		DestructiveHint: destructive,
+		IdempotentHint:  !statefulTools[name],
+	}
+}
+
+// maskSchema returns the schema of a mask argument (see imaging.Mask),
+// shared by the tools that can be restricted to an irregular area.
+// usage completes the description: what the mask selects, and in which
+// coordinates.
+func maskSchema(usage string) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"polygon": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"x": map[string]interface{}{"type": "integer"},
+						"y": map[string]interface{}{"type": "integer"},
+					},
+					"required": []string{"x", "y"},
+				},
+				"minItems":    3,
+				"description": "Polygon vertices in order (3-10000); pixels whose centers are inside, by the even-odd rule, are selected",
+			},
+			"rle": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"size": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "integer"},
+						"description": "[height, width] of the bitmap",
+					},
+					"counts": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "integer"},
+						"description": "Run lengths alternating unselected and selected pixels, starting with unselected, column by column (COCO uncompressed RLE)",
+					},
+				},
+				"required": []string{"size", "counts"},
+			},
+		},
+		"description": "Irregular area to analyze, as a polygon or a run-length encoded bitmap (exactly one). " + usage,
+	}
+}
+
+// bitDepthSchema returns the schema of the bit_depth argument of the color
+// sampling tools. fields names the result fields added at depth 16.
+func bitDepthSchema(fields string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "integer",
+		"enum":        []int{8, 16},
+		"description": "Bits per channel to report: 8, or 16 to also return " + fields + ", keeping the precision of 16-bit PNG and TIFF images (default 8)",
+		"default":     8,
+	}
+}
+
+// alphaModeSchema returns the schema of the alpha_mode property of tools
+// that can treat transparency as content rather than color. Its
+// background color is given by backgroundSchema.
+func alphaModeSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "string",
+		"enum":        []string{"ignore", "content", "composite"},
+		"description": "How transparency is treated: ignore (color channels only, as stored), content (fully transparent pixels are no content and are left out; other pixels count by their color without alpha applied), or composite (each pixel is composited over background first, as the image would appear on it). Default ignore.",
+		"default":     "ignore",
+	}
+}
+
+// backgroundSchema returns the schema of the background property used by
+// alpha_mode composite.
+func backgroundSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "string",
+		"description": "Background color in hex (#RRGGBB) for alpha_mode composite (default #FFFFFF)",
+		"default":     "#FFFFFF",
+	}
+}
+
+// denoiseSchema returns the schema of the denoise property of the OCR and
+// shape detection tools.
+func denoiseSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "string",
+		"enum":        []string{"bilateral", "nlm"},
+		"description": "Denoise the image first (see image_denoise), for photos of screens and heavily compressed images: bilateral, or nlm (slower, keeps fine strokes). Default none.",
+	}
+}
+
+// normalizeSchema returns the schema of the normalize property of the OCR
+// and shape detection tools.
+func normalizeSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "boolean",
+		"description": "White balance the image and normalize its exposure first (see image_normalize), for photos of whiteboards and documents. Applied after denoise (default false)",
+		"default":     false,
+	}
+}
+
+// alternativesSchema returns the schema of the alternatives property of
+// the OCR tools.
+func alternativesSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "integer",
+		"description": "Number (0-10) of alternative readings to return for each word, most likely first, with confidences and the top choices for each character, for correcting IDs, amounts, and other critical fields (default 0)",
+		"default":     0,
+		"minimum":     0,
+		"maximum":     10,
+	}
+}
+
+// vocabularySchema returns the schema of the vocabulary property of the
+// OCR tools.
+func vocabularySchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "array",
+		"items":       map[string]interface{}{"type": "string"},
+		"description": "Expected words, such as product names or menu items (up to 10000). Recognized words within max_distance edits of one entry are corrected to it, keeping their recognized text in original with the edit_distance; words already matching an entry, words under 3 characters, and words equally close to two entries are left as read",
+	}
+}
+
+// maxDistanceSchema returns the schema of the max_distance property of
+// the OCR tools.
+func maxDistanceSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "integer",
+		"description": "Largest edit distance (1-3) of a vocabulary correction, and never more than a third of the word's length (default 2)",
+		"default":     2,
+		"minimum":     1,
+		"maximum":     3,
+	}
+}
+
+// layoutSchema returns the schema of the layout property of the OCR
+// tools.
+func layoutSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "boolean",
+		"description": "Group the words into columns and blocks and return them in reading order: adds layout with the blocks, and rewrites full_text (blocks separated by blank lines) and regions in that order, so multi-column pages are not read across the columns (default false)",
+		"default":     false,
+	}
+}
+
+// rotationSchema returns the schema of the rotation property of the OCR
+// tools.
+func rotationSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "integer",
+		"description": "How far the text is turned clockwise from upright, in degrees: 90 reads top to bottom, 180 is upside down, 270 reads bottom to top. The image is turned back before recognition and bounds are mapped back to it (default 0)",
+		"enum":        []int{0, 90, 180, 270},
+		"default":     0,
+	}
+}
+
+// autoRotateSchema returns the schema of the auto_rotate property of the
+// OCR tools.
+func autoRotateSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "boolean",
+		"description": "Read the text at all four rotations and keep the most confident reading, returned in rotation; overrides rotation and takes four times as long (default false)",
+		"default":     false,
+	}
+}
+
+// verticalSchema returns the schema of the vertical property of the OCR
+// tools.
+func verticalSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "boolean",
+		"description": "Read vertical CJK text, in columns from right to left, with the vertical models of chi_sim, chi_tra, jpn, or kor, one of which language must include (default false)",
+		"default":     false,
+	}
+}
+
+// minConfidenceSchema returns the schema of the min_confidence property
+// of the OCR tools.
+func minConfidenceSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "number",
+		"description": "Drop the words read with a lower confidence (0-1), from regions and full_text alike, counting them in dropped (default 0, keep all)",
+		"default":     0,
+		"minimum":     0,
+		"maximum":     1,
+	}
+}
+
+// unicodeSchema returns the schema of the unicode property of the OCR
+// tools.
+func unicodeSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "string",
+		"description": "Unicode normalization of the text: nfc composes accents with their letters; nfkc also replaces ligatures, full-width forms, and other compatibility characters with plain ones (default: as read)",
+		"enum":        []string{"nfc", "nfkc"},
+	}
+}
+
+// foldQuotesSchema returns the schema of the fold_quotes property of the
+// OCR tools.
+func foldQuotesSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "boolean",
+		"description": "Replace curly quotes and primes with straight quotes (default false)",
+		"default":     false,
+	}
+}
+
+// dehyphenateSchema returns the schema of the dehyphenate property of
+// the OCR tools.
+func dehyphenateSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "boolean",
+		"description": "Join words hyphenated across line breaks, such as 'recog-' and 'nition', into one word with the bounds of its first part (default false)",
+		"default":     false,
+	}
+}
+
+// collapseWhitespaceSchema returns the schema of the collapse_whitespace
+// property of the OCR tools.
+func collapseWhitespaceSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "boolean",
+		"description": "Collapse the whitespace between words to one space, one line break, or one blank line between paragraphs, and trim the text (default false)",
+		"default":     false,
+	}
+}
+
+// extractValuesSchema returns the schema of the extract_values property
+// of the OCR tools.
+func extractValuesSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "boolean",
+		"description": "Find the numbers, amounts of money, percentages, and dates in the text and return them in values, each with its text as read, its typed value (number, ISO 4217 currency, or YYYY-MM-DD date), and its bounds (default false)",
+		"default":     false,
+	}
+}
+
+// localeSchema returns the schema of the locale property of the OCR
+// tools.
+func localeSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "string",
+		"description": "Locale of the values, such as 'en-US', 'de-DE', or 'fr': sets the decimal separator, the order of numeric dates, and the currency of '$'. Without one, each number's decimal separator is guessed and values with another reading are flagged ambiguous",
+	}
+}
+
+// relativeToSchema returns the schema of the relative_to property of the
+// measurement tools.
+func relativeToSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "string",
+		"enum":        []string{"image", "viewport"},
+		"description": "Coordinate system: 'image' (default) or 'viewport', relative to the top left corner of the browser viewport found in the screenshot (see image_detect_viewport), which is returned in viewport",
+		"default":     "image",
+	}
+}
+
+// lineSchema returns the schema of a line between two points, described
+// by description.
+func lineSchema(description string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "object",
+		"description": description,
+		"properties": map[string]interface{}{
+			"x1": map[string]interface{}{"type": "integer", "description": "Start point X"},
+			"y1": map[string]interface{}{"type": "integer", "description": "Start point Y"},
+			"x2": map[string]interface{}{"type": "integer", "description": "End point X"},
+			"y2": map[string]interface{}{"type": "integer", "description": "End point Y"},
+		},
+		"required": []string{"x1", "y1", "x2", "y2"},
+	}
 }
 
 // GetToolDefinitions returns the complete list of available image analysis tools.
@@ -22,21 +362,26 @@ type Tool struct {
 //   - A unique name for invocation
 //   - A description explaining its purpose and use cases
 //   - A JSON Schema defining its input parameters
+//   - A JSON Schema describing its structured result
+//   - Behavior annotations (read-only, destructive, idempotent)
 //
 // The tools are organized into categories:
-//   - Basic Image Information (2 tools)
-//   - Region Operations (2 tools)
-//   - Color Operations (3 tools)
-//   - Measurement Operations (2 tools)
-//   - OCR Operations (3 tools)
-//   - Shape Detection (4 tools)
-//   - Analysis Helpers (2 tools)
+//   - Basic Image Information (5 tools, image_from_clipboard only where
+//     clipboard.Supported)
+//   - Region Operations (4 tools)
+//   - Color Operations (7 tools)
+//   - Measurement Operations (10 tools)
+//   - OCR Operations (12 tools)
+//   - Shape Detection (7 tools)
+//   - Analysis Helpers (41 tools)
+//   - Composition (2 tools)
+//   - Visual Regression (2 tools)
 func GetToolDefinitions() []Tool {
-	return []Tool{
+	tools := []Tool{
 		// Basic Image Information
 		{
 			Name:        "image_load",
-			Description: "Load an image file and return its dimensions and format. Sets this as the active image for subsequent operations.",
+			Description: "Load an image file and return its dimensions, format, and embedded color profile, if any. Sets this as the active image for subsequent operations.",
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -48,6 +393,14 @@ func GetToolDefinitions() []Tool {
 				"required": []string{"path"},
 			},
 		},
+		{
+			Name:        "image_from_clipboard",
+			Description: "Read the image on the system clipboard, such as a screenshot just taken, save it as a PNG file in the server's cache directory, and return its path, for the other tools, with its dimensions and format as from image_load. Only offered on platforms with clipboard support (macOS, Linux with wl-paste or xclip, Windows).",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
 		{
 			Name:        "image_dimensions",
 			Description: "Get the width and height of an image file.",
@@ -62,6 +415,49 @@ func GetToolDefinitions() []Tool {
 				"required": []string{"path"},
 			},
 		},
+		{
+			Name:        "image_extract_icon",
+			Description: "Extract one size from an .ico or .icns icon container as PNG, listing all available sizes and the bounds of the non-transparent content (to check padding).",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the .ico or .icns file",
+					},
+					"size": map[string]interface{}{
+						"type":        "integer",
+						"description": "Width in pixels of the entry to extract (default: largest)",
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_thumbnail",
+			Description: "Return a small preview of an image as base64 PNG or JPEG, scaled to fit a maximum width/height. Thumbnails are cached by path and size, and full-size images are not kept in memory, so galleries of many files stay quick.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"max_size": map[string]interface{}{
+						"type":        "integer",
+						"description": "Largest thumbnail width or height in pixels, 16-1024 (default 128); smaller images are not enlarged",
+						"default":     128,
+					},
+					"format": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"png", "jpeg"},
+						"description": "png, or jpeg for smaller photo previews (transparency is flattened onto white)",
+						"default":     "png",
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
 
 		// Region Operations
 		{
@@ -123,6 +519,75 @@ func GetToolDefinitions() []Tool {
 				"required": []string{"path", "region"},
 			},
 		},
+		{
+			Name:        "image_split_sprites",
+			Description: "Split a sprite sheet or icon set into cells. Detects sprites separated by background gutters automatically, or uses a given grid. Returns each cell's bounds, content bounds, and optional thumbnails.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the sprite sheet",
+					},
+					"columns": map[string]interface{}{
+						"type":        "integer",
+						"description": "Number of columns (omit with cell_width to auto-detect)",
+					},
+					"rows": map[string]interface{}{
+						"type":        "integer",
+						"description": "Number of rows (omit with cell_height to auto-detect)",
+					},
+					"cell_width": map[string]interface{}{
+						"type":        "integer",
+						"description": "Cell width in pixels (alternative to columns)",
+					},
+					"cell_height": map[string]interface{}{
+						"type":        "integer",
+						"description": "Cell height in pixels (alternative to rows)",
+					},
+					"include_thumbnails": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Return a PNG thumbnail for each cell (max 100 cells)",
+						"default":     false,
+					},
+					"thumb_size": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum thumbnail width/height in pixels (default 64)",
+						"default":     64,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_smart_crop",
+			Description: "Content-aware crop to a target aspect ratio. Keeps the window with the most edge energy (text, lines, outlines), or removes low-energy seams (seam carving) to keep content near both edges. Returns the cropped image as base64 PNG, useful for thumbnails of diagrams.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"aspect_ratio": map[string]interface{}{
+						"type":        "number",
+						"description": "Target width / height, 0.1-10 (e.g., 1 for square, 1.78 for 16:9)",
+					},
+					"method": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"crop", "seam"},
+						"description": "crop: cut out the most salient window. seam: remove low-energy seams, moving content closer together (slower; removes at most half a dimension)",
+						"default":     "crop",
+					},
+					"max_size": map[string]interface{}{
+						"type":        "integer",
+						"description": "Scale the result down so neither side exceeds this many pixels (max 4096); 0 keeps the cropped size",
+						"default":     0,
+					},
+				},
+				"required": []string{"path", "aspect_ratio"},
+			},
+		},
 
 		// Color Operations
 		{
@@ -143,6 +608,7 @@ func GetToolDefinitions() []Tool {
 						"type":        "integer",
 						"description": "Y coordinate (0-based, from top)",
 					},
+					"bit_depth": bitDepthSchema("hex16 and rgba16"),
 				},
 				"required": []string{"path", "x", "y"},
 			},
@@ -170,6 +636,7 @@ func GetToolDefinitions() []Tool {
 						},
 						"description": "Array of points to sample",
 					},
+					"bit_depth": bitDepthSchema("hex16 and rgba16 for each sample"),
 				},
 				"required": []string{"path", "points"},
 			},
@@ -199,15 +666,17 @@ func GetToolDefinitions() []Tool {
 						},
 						"description": "Optional region to analyze. If omitted, analyzes entire image.",
 					},
+					"mask":       maskSchema("Polygon vertices are in image coordinates, and an RLE bitmap covers the whole image. Combined with region, only masked pixels inside the region count."),
+					"bit_depth":  bitDepthSchema("rgb16, the mean unquantized color of each group"),
+					"alpha_mode": alphaModeSchema(),
+					"background": backgroundSchema(),
 				},
 				"required": []string{"path"},
 			},
 		},
-
-		// Measurement Operations
 		{
-			Name:        "image_measure_distance",
-			Description: "Measure the distance in pixels between two points.",
+			Name:        "image_check_palette",
+			Description: "Check an image against an allowed color palette, such as a design system's brand colors. Reports every significant off-palette color with its nearest palette color, CIEDE2000 difference, share of the image, and where it occurs, plus each palette color's usage. Optionally checks sampled points.",
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -215,48 +684,98 @@ func GetToolDefinitions() []Tool {
 						"type":        "string",
 						"description": "Absolute path to the image file",
 					},
-					"x1": map[string]interface{}{"type": "integer", "description": "First point X"},
-					"y1": map[string]interface{}{"type": "integer", "description": "First point Y"},
-					"x2": map[string]interface{}{"type": "integer", "description": "Second point X"},
-					"y2": map[string]interface{}{"type": "integer", "description": "Second point Y"},
+					"palette": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Allowed colors as hex (#RRGGBB), 1 to 64",
+					},
+					"tolerance": map[string]interface{}{
+						"type":        "number",
+						"description": "Largest CIEDE2000 difference (Delta-E) from a palette color that still matches it; about 1 is just noticeable (default 3)",
+						"default":     3,
+					},
+					"min_percent": map[string]interface{}{
+						"type":        "number",
+						"description": "Smallest share of the analyzed pixels, in percent, an off-palette color must cover to be reported (default 0.1)",
+						"default":     0.1,
+					},
+					"ignore_edges": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Skip pixels that are not in a flat area, so anti-aliased edges and text do not count as off-palette; check those with points (default true)",
+						"default":     true,
+					},
+					"region": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"x1": map[string]interface{}{"type": "integer"},
+							"y1": map[string]interface{}{"type": "integer"},
+							"x2": map[string]interface{}{"type": "integer"},
+							"y2": map[string]interface{}{"type": "integer"},
+						},
+						"description": "Area to check (default: whole image)",
+					},
+					"mask": maskSchema("Polygon vertices are in image coordinates, and an RLE bitmap covers the whole image."),
+					"points": map[string]interface{}{
+						"type": "array",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"x": map[string]interface{}{"type": "integer", "description": "X coordinate (0-based, from left)"},
+								"y": map[string]interface{}{"type": "integer", "description": "Y coordinate (0-based, from top)"},
+							},
+							"required": []string{"x", "y"},
+						},
+						"description": "Pixels to sample and check individually, such as text or icon colors",
+					},
 				},
-				"required": []string{"path", "x1", "y1", "x2", "y2"},
+				"required": []string{"path", "palette"},
 			},
 		},
 		{
-			Name:        "image_grid_overlay",
-			Description: "Return a version of the image with a coordinate grid overlay for precise positioning reference.",
+			Name:        "image_compare_palettes",
+			Description: "Compare the dominant colors of several images to check a set of assets for consistent color grading. Returns each image's palette, the CIEDE2000 palette distance between every pair, and the images that stand out from the rest.",
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
-					"path": map[string]interface{}{
-						"type":        "string",
-						"description": "Absolute path to the image file",
+					"images": map[string]interface{}{
+						"type": "array",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"path":  map[string]interface{}{"type": "string", "description": "Absolute path to the image file"},
+								"label": map[string]interface{}{"type": "string", "description": "Optional name (default: file name)"},
+								"region": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"x1": map[string]interface{}{"type": "integer", "description": "Left edge X coordinate (0-based)"},
+										"y1": map[string]interface{}{"type": "integer", "description": "Top edge Y coordinate (0-based)"},
+										"x2": map[string]interface{}{"type": "integer", "description": "Right edge X coordinate (exclusive)"},
+										"y2": map[string]interface{}{"type": "integer", "description": "Bottom edge Y coordinate (exclusive)"},
+									},
+									"description": "Optional region to compare, such as a shared background or brand area. If omitted, the whole image is used.",
+								},
+							},
+							"required": []string{"path"},
+						},
+						"description": "Images or regions to compare (2 to 32)",
 					},
-					"grid_spacing": map[string]interface{}{
+					"count": map[string]interface{}{
 						"type":        "integer",
-						"description": "Pixels between grid lines (default 50)",
-						"default":     50,
-					},
-					"show_coordinates": map[string]interface{}{
-						"type":        "boolean",
-						"description": "Whether to label grid intersections with coordinates",
-						"default":     true,
+						"description": "Dominant colors extracted per image, 1-32 (default 8)",
+						"default":     8,
 					},
-					"grid_color": map[string]interface{}{
-						"type":        "string",
-						"description": "Grid line color as hex (default #FF000080 - semi-transparent red)",
-						"default":     "#FF000080",
+					"threshold": map[string]interface{}{
+						"type":        "number",
+						"description": "Palette distance (CIEDE2000) above which images count as differently graded (default 15)",
+						"default":     15,
 					},
 				},
-				"required": []string{"path"},
+				"required": []string{"images"},
 			},
 		},
-
-		// OCR Operations
 		{
-			Name:        "image_ocr_full",
-			Description: "Extract all text from the image using OCR. Returns text with approximate bounding boxes.",
+			Name:        "image_count_colors",
+			Description: "Count the exact distinct colors of an image, without quantization, with the pixels of each, and check the count against a maximum palette size. Use this to validate pixel art and icons; near-duplicate colors point to the stray shades left by smoothing, compression, or dithering.",
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -264,18 +783,43 @@ func GetToolDefinitions() []Tool {
 						"type":        "string",
 						"description": "Absolute path to the image file",
 					},
-					"language": map[string]interface{}{
-						"type":        "string",
-						"description": "OCR language hint (default 'eng')",
-						"default":     "eng",
+					"region": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"x1": map[string]interface{}{"type": "integer", "description": "Left edge X coordinate (0-based)"},
+							"y1": map[string]interface{}{"type": "integer", "description": "Top edge Y coordinate (0-based)"},
+							"x2": map[string]interface{}{"type": "integer", "description": "Right edge X coordinate (exclusive)"},
+							"y2": map[string]interface{}{"type": "integer", "description": "Bottom edge Y coordinate (exclusive)"},
+						},
+						"description": "Optional region to count, such as one sprite of a sheet. If omitted, counts the entire image.",
+					},
+					"mask": maskSchema("Polygon vertices are in image coordinates, and an RLE bitmap covers the whole image. Combined with region, only masked pixels inside the region count."),
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "Colors listed, most common first, 1-4096 (default 256)",
+						"default":     256,
+					},
+					"max_colors": map[string]interface{}{
+						"type":        "integer",
+						"description": "Largest palette allowed; the result reports whether the image is within it",
+					},
+					"ignore_transparent": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Leave fully transparent pixels out of the count, such as an icon's background (default false: they count as one color)",
+						"default":     false,
+					},
+					"near_distance": map[string]interface{}{
+						"type":        "integer",
+						"description": "Largest channel difference of colors reported as near duplicates, 0-32; 0 skips the search (default 2)",
+						"default":     2,
 					},
 				},
 				"required": []string{"path"},
 			},
 		},
 		{
-			Name:        "image_ocr_region",
-			Description: "Extract text from a specific rectangular region of the image.",
+			Name:        "image_read_indicators",
+			Description: "Read the states of status LEDs, lamps, or other indicators, such as on a device or dashboard photo: sample the color at named points or regions and classify each into user-defined states by reference color or HSL ranges (e.g., off, red, amber, green). Returns each indicator's color and state, a map of names to states, and counts per state.",
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -283,22 +827,65 @@ func GetToolDefinitions() []Tool {
 						"type":        "string",
 						"description": "Absolute path to the image file",
 					},
-					"x1": map[string]interface{}{"type": "integer", "description": "Left edge X coordinate (0-based)"},
-					"y1": map[string]interface{}{"type": "integer", "description": "Top edge Y coordinate (0-based)"},
-					"x2": map[string]interface{}{"type": "integer", "description": "Right edge X coordinate (exclusive)"},
-					"y2": map[string]interface{}{"type": "integer", "description": "Bottom edge Y coordinate (exclusive)"},
-					"language": map[string]interface{}{
-						"type":        "string",
-						"description": "OCR language code (default 'eng')",
-						"default":     "eng",
-					},
+					"indicators": map[string]interface{}{
+						"type":        "array",
+						"description": "Indicators to read (1-200): a disc around a point, or a region",
+						"minItems":    1,
+						"maxItems":    200,
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"name":   map[string]interface{}{"type": "string", "description": "Unique name of the indicator"},
+								"x":      map[string]interface{}{"type": "integer", "description": "Center X"},
+								"y":      map[string]interface{}{"type": "integer", "description": "Center Y"},
+								"radius": map[string]interface{}{"type": "integer", "description": "Radius of the disc sampled, 0-100 (default 0: the pixel alone)"},
+								"region": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"x1": map[string]interface{}{"type": "integer", "description": "Left edge X coordinate (0-based)"},
+										"y1": map[string]interface{}{"type": "integer", "description": "Top edge Y coordinate (0-based)"},
+										"x2": map[string]interface{}{"type": "integer", "description": "Right edge X coordinate (exclusive)"},
+										"y2": map[string]interface{}{"type": "integer", "description": "Bottom edge Y coordinate (exclusive)"},
+									},
+									"description": "Area to sample instead of a disc",
+								},
+							},
+							"required": []string{"name"},
+						},
+					},
+					"states": map[string]interface{}{
+						"type":        "array",
+						"description": "States in order (1-32); each indicator takes the first whose conditions its color meets, or \"unknown\". List narrower states first, such as off before the colors.",
+						"minItems":    1,
+						"maxItems":    32,
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"name":       map[string]interface{}{"type": "string", "description": "Unique name of the state"},
+								"color":      map[string]interface{}{"type": "string", "description": "Reference color (#RRGGBB)"},
+								"tolerance":  map[string]interface{}{"type": "number", "description": "Largest RGB distance (1-442) from color (default 60)"},
+								"hue":        map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}, "description": "[min, max] hue in degrees (0-360); wraps when min > max, as in [340, 20] for red"},
+								"saturation": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}, "description": "[min, max] HSL saturation in percent (0-100)"},
+								"lightness":  map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}, "description": "[min, max] HSL lightness in percent (0-100)"},
+							},
+							"required": []string{"name"},
+						},
+					},
+					"sample": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"median", "brightest"},
+						"description": "Color of the area: its median, or the median of its brightest quarter, which finds the lit part of an LED in a photo (default median)",
+						"default":     "median",
+					},
 				},
-				"required": []string{"path", "x1", "y1", "x2", "y2"},
+				"required": []string{"path", "indicators", "states"},
 			},
 		},
+
+		// Measurement Operations
 		{
-			Name:        "image_detect_text_regions",
-			Description: "Detect all regions in the image that contain text. Returns bounding boxes without performing full OCR.",
+			Name:        "image_measure_distance",
+			Description: "Measure the distance in pixels between two points. Once image_detect_scale_bar has calibrated the image, the distance is also given in the scale bar's units.",
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -306,20 +893,18 @@ func GetToolDefinitions() []Tool {
 						"type":        "string",
 						"description": "Absolute path to the image file",
 					},
-					"min_confidence": map[string]interface{}{
-						"type":        "number",
-						"description": "Minimum confidence threshold (0-1, default 0.5)",
-						"default":     0.5,
-					},
+					"x1":          map[string]interface{}{"type": "integer", "description": "First point X"},
+					"y1":          map[string]interface{}{"type": "integer", "description": "First point Y"},
+					"x2":          map[string]interface{}{"type": "integer", "description": "Second point X"},
+					"y2":          map[string]interface{}{"type": "integer", "description": "Second point Y"},
+					"relative_to": relativeToSchema(),
 				},
-				"required": []string{"path"},
+				"required": []string{"path", "x1", "y1", "x2", "y2"},
 			},
 		},
-
-		// Shape Detection
 		{
-			Name:        "image_detect_rectangles",
-			Description: "Detect rectangular shapes in the image. Useful for finding boxes in diagrams.",
+			Name:        "image_grid_overlay",
+			Description: "Return a version of the image with a coordinate grid overlay for precise positioning reference.",
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -327,23 +912,88 @@ func GetToolDefinitions() []Tool {
 						"type":        "string",
 						"description": "Absolute path to the image file",
 					},
-					"min_area": map[string]interface{}{
+					"grid_spacing": map[string]interface{}{
 						"type":        "integer",
-						"description": "Minimum area in pixels to consider (default 100)",
-						"default":     100,
+						"description": "Pixels between grid lines (default 50)",
+						"default":     50,
 					},
-					"tolerance": map[string]interface{}{
-						"type":        "number",
-						"description": "How close to rectangular a shape must be (0-1, default 0.9)",
-						"default":     0.9,
+					"show_coordinates": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Whether to label grid intersections with coordinates",
+						"default":     true,
+					},
+					"grid_color": map[string]interface{}{
+						"type":        "string",
+						"description": "Grid line color as hex (default #FF000080 - semi-transparent red)",
+						"default":     "#FF000080",
 					},
+					"relative_to": relativeToSchema(),
 				},
 				"required": []string{"path"},
 			},
 		},
 		{
-			Name:        "image_detect_lines",
-			Description: "Detect line segments in the image. Useful for finding connections between elements.",
+			Name:        "image_georeference",
+			Description: "Convert between pixel coordinates and latitude/longitude on a map screenshot or scanned map, given two or more reference points whose coordinates are known. Returns the map's scale (meters per pixel) and orientation, the coordinates of its corners, the requested pixels (such as detections) as latitude/longitude with the ground distances between consecutive ones, and the requested coordinates as pixels.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the map image",
+					},
+					"reference_points": map[string]interface{}{
+						"type":        "array",
+						"description": "At least two pixels with known coordinates, far apart; more are fitted by least squares",
+						"minItems":    2,
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"x":   map[string]interface{}{"type": "number", "description": "Pixel X"},
+								"y":   map[string]interface{}{"type": "number", "description": "Pixel Y"},
+								"lat": map[string]interface{}{"type": "number", "description": "Latitude in degrees"},
+								"lon": map[string]interface{}{"type": "number", "description": "Longitude in degrees"},
+							},
+							"required": []string{"x", "y", "lat", "lon"},
+						},
+					},
+					"projection": map[string]interface{}{
+						"type":        "string",
+						"description": "Projection of the map: 'mercator' for web map tiles and screenshots of online maps, 'equirectangular' for plate carrée world and scanned maps (default 'mercator')",
+						"enum":        []string{"mercator", "equirectangular"},
+						"default":     "mercator",
+					},
+					"pixels": map[string]interface{}{
+						"type":        "array",
+						"description": "Pixels to convert to latitude/longitude, in path order for distances (max 1000)",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"x": map[string]interface{}{"type": "number"},
+								"y": map[string]interface{}{"type": "number"},
+							},
+							"required": []string{"x", "y"},
+						},
+					},
+					"coordinates": map[string]interface{}{
+						"type":        "array",
+						"description": "Latitude/longitude pairs to convert to pixels (max 1000)",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"lat": map[string]interface{}{"type": "number"},
+								"lon": map[string]interface{}{"type": "number"},
+							},
+							"required": []string{"lat", "lon"},
+						},
+					},
+				},
+				"required": []string{"path", "reference_points"},
+			},
+		},
+		{
+			Name:        "image_detect_scale_bar",
+			Description: "Find the scale bar of a micrograph, figure, or map: a straight bar, with or without end ticks, beside a label such as '10 µm' read by OCR. Returns the bar's position and length in pixels, its label, and the calibration it implies (units and meters per pixel), which image_measure_distance then applies to later measurements on the same image. Without OCR, give the label's text.",
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -351,14 +1001,18 @@ func GetToolDefinitions() []Tool {
 						"type":        "string",
 						"description": "Absolute path to the image file",
 					},
-					"min_length": map[string]interface{}{
-						"type":        "integer",
-						"description": "Minimum line length in pixels (default 20)",
-						"default":     20,
+					"label": map[string]interface{}{
+						"type":        "string",
+						"description": "The scale bar's label, such as '500 nm' or '2 km', if known; it applies to the lowest bar found and skips OCR",
 					},
-					"detect_arrows": map[string]interface{}{
+					"language": map[string]interface{}{
+						"type":        "string",
+						"description": "OCR language hint for reading labels (default 'eng')",
+						"default":     "eng",
+					},
+					"calibrate": map[string]interface{}{
 						"type":        "boolean",
-						"description": "Whether to detect arrow heads at line endpoints",
+						"description": "Store the calibration for later image_measure_distance calls on this image (default true)",
 						"default":     true,
 					},
 				},
@@ -366,8 +1020,8 @@ func GetToolDefinitions() []Tool {
 			},
 		},
 		{
-			Name:        "image_detect_circles",
-			Description: "Detect circular shapes in the image. Useful for finding nodes, connectors, or bullets.",
+			Name:        "image_count_blobs",
+			Description: "Count roughly circular blobs, such as particles or cells in a micrograph, on a uniform background: threshold, connected components with holes filled, and size and roundness filters. Returns the count, an estimate counting clumps of touching blobs by their area, the size distribution with a histogram of diameters, and each blob's centroid, bounds, area, and diameter, with optional shape descriptors (Hu moments, eccentricity, solidity, aspect ratio, orientation) to classify shapes and match them across images. Sizes are also given in the scale bar's units once image_detect_scale_bar has calibrated the image.",
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -375,23 +1029,53 @@ func GetToolDefinitions() []Tool {
 						"type":        "string",
 						"description": "Absolute path to the image file",
 					},
-					"min_radius": map[string]interface{}{
+					"threshold": map[string]interface{}{
 						"type":        "integer",
-						"description": "Minimum radius in pixels (default 5)",
-						"default":     5,
+						"description": "Luma (1-255) separating blobs from the background, or 0 to choose it automatically (Otsu) (default 0)",
+						"minimum":     0,
+						"maximum":     255,
+						"default":     0,
 					},
-					"max_radius": map[string]interface{}{
+					"polarity": map[string]interface{}{
+						"type":        "string",
+						"description": "'dark' blobs on a light background, 'light' blobs on a dark one as in fluorescence, or 'auto' for whichever covers less of the image (default 'auto')",
+						"enum":        []string{"auto", "dark", "light"},
+						"default":     "auto",
+					},
+					"min_area": map[string]interface{}{
 						"type":        "integer",
-						"description": "Maximum radius in pixels (default 500)",
-						"default":     500,
+						"description": "Smallest blob counted, in pixels, to ignore noise and debris (default 10)",
+						"default":     10,
+					},
+					"max_area": map[string]interface{}{
+						"type":        "integer",
+						"description": "Largest blob counted, in pixels, or 0 for no limit (default 0)",
+						"default":     0,
+					},
+					"min_roundness": map[string]interface{}{
+						"type":        "number",
+						"description": "Smallest ratio (0-1) of a blob's minor to major axis counted; 0 counts every shape (default 0)",
+						"minimum":     0,
+						"maximum":     1,
+						"default":     0,
+					},
+					"exclude_edges": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Leave out blobs cut off by the image's edge (default true)",
+						"default":     true,
+					},
+					"shape_descriptors": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also return each blob's shape: the seven Hu moments, eccentricity, solidity, aspect ratio of its smallest rotated bounding rectangle, and orientation, which do not change with position, scale, or rotation (default false)",
+						"default":     false,
 					},
 				},
 				"required": []string{"path"},
 			},
 		},
 		{
-			Name:        "image_edge_detect",
-			Description: "Return an edge-detected version of the image, showing only structural lines. Useful for understanding diagram structure without color fills.",
+			Name:        "image_line_profile",
+			Description: "Sample intensity along a line to inspect edges, gradients, and banding quantitatively. Returns the luma (and optionally red, green, and blue) at 1-pixel steps, its statistics, each edge's position, contrast, and 10-90% rise distance (a measure of sharpness), and a plot of the profile.",
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -399,25 +1083,59 @@ func GetToolDefinitions() []Tool {
 						"type":        "string",
 						"description": "Absolute path to the image file",
 					},
-					"threshold_low": map[string]interface{}{
+					"x1": map[string]interface{}{"type": "integer", "description": "Start point X"},
+					"y1": map[string]interface{}{"type": "integer", "description": "Start point Y"},
+					"x2": map[string]interface{}{"type": "integer", "description": "End point X"},
+					"y2": map[string]interface{}{"type": "integer", "description": "End point Y"},
+					"width": map[string]interface{}{
 						"type":        "integer",
-						"description": "Low threshold for Canny edge detection (default 50)",
-						"default":     50,
+						"description": "Number of parallel lines averaged, odd, to smooth noise and texture (1-51, default 1)",
+						"minimum":     1,
+						"maximum":     51,
+						"default":     1,
 					},
-					"threshold_high": map[string]interface{}{
-						"type":        "integer",
-						"description": "High threshold for Canny edge detection (default 150)",
-						"default":     150,
+					"rgb": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also return the red, green, and blue profiles (default false)",
+						"default":     false,
+					},
+					"edge_threshold": map[string]interface{}{
+						"type":        "number",
+						"description": "Smallest change of luma (1-255) reported as an edge (default 20)",
+						"default":     20,
+					},
+					"include_plot": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Return a plot of the profile with edges marked (default true)",
+						"default":     true,
 					},
 				},
-				"required": []string{"path"},
+				"required": []string{"path", "x1", "y1", "x2", "y2"},
 			},
 		},
-
-		// Analysis Helpers
 		{
-			Name:        "image_check_alignment",
-			Description: "Check if multiple points or regions are horizontally or vertically aligned.",
+			Name:        "image_measure_angle",
+			Description: "Measure the angle at a vertex between the lines to two other points, such as a joint, a corner of a shape, or a slope. Returns the angle (0-180°) and its reflex, the direction on screen from the first line to the second, and each line's direction and length.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"x1":       map[string]interface{}{"type": "integer", "description": "First point X"},
+					"y1":       map[string]interface{}{"type": "integer", "description": "First point Y"},
+					"vertex_x": map[string]interface{}{"type": "integer", "description": "Vertex X"},
+					"vertex_y": map[string]interface{}{"type": "integer", "description": "Vertex Y"},
+					"x2":       map[string]interface{}{"type": "integer", "description": "Second point X"},
+					"y2":       map[string]interface{}{"type": "integer", "description": "Second point Y"},
+				},
+				"required": []string{"path", "x1", "y1", "vertex_x", "vertex_y", "x2", "y2"},
+			},
+		},
+		{
+			Name:        "image_measure_polygon",
+			Description: "Measure the polygon through a list of points, such as a room on a floor plan, a plot on a map, or a region in a micrograph. Returns its perimeter, area, centroid, bounds, orientation, interior angles, and whether it is convex or self-intersecting. Once image_detect_scale_bar has calibrated the image, the perimeter and area are also given in the scale bar's units.",
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -426,7 +1144,9 @@ func GetToolDefinitions() []Tool {
 						"description": "Absolute path to the image file",
 					},
 					"points": map[string]interface{}{
-						"type": "array",
+						"type":        "array",
+						"description": "Vertices in order around the outline (3-1000); the polygon closes back to the first",
+						"minItems":    3,
 						"items": map[string]interface{}{
 							"type": "object",
 							"properties": map[string]interface{}{
@@ -435,20 +1155,35 @@ func GetToolDefinitions() []Tool {
 							},
 							"required": []string{"x", "y"},
 						},
-						"description": "Points to check for alignment",
 					},
+				},
+				"required": []string{"path", "points"},
+			},
+		},
+		{
+			Name:        "image_check_parallel",
+			Description: "Check whether two lines, such as edges of a drawing or sides of a photographed document, are parallel or perpendicular within a tolerance. Returns each line's direction, the angle between them, the gap and overlap between parallel lines, and where lines that are not parallel cross. Once image_detect_scale_bar has calibrated the image, the gap is also given in the scale bar's units.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"line1": lineSchema("First line"),
+					"line2": lineSchema("Second line"),
 					"tolerance": map[string]interface{}{
-						"type":        "integer",
-						"description": "Pixel tolerance for alignment (default 5)",
-						"default":     5,
+						"type":        "number",
+						"description": "Largest difference in degrees from parallel or perpendicular allowed (above 0, below 45, default 1)",
+						"default":     1,
 					},
 				},
-				"required": []string{"path", "points"},
+				"required": []string{"path", "line1", "line2"},
 			},
 		},
 		{
-			Name:        "image_compare_regions",
-			Description: "Compare two regions of an image to determine if they contain similar content (useful for detecting repeated elements).",
+			Name:        "image_shape_descriptors",
+			Description: "Find the convex hull, smallest enclosing circle, and smallest-area rotated rectangle of a set of points, such as the corners of a detected shape, or of a blob found by image_count_blobs. Returns the hull's vertices, area, and perimeter, the circle's center and radius, and the rectangle's center, size, angle, and corners; for a blob, also its shape descriptors (Hu moments, eccentricity, solidity, aspect ratio, orientation).",
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -456,33 +1191,2454 @@ func GetToolDefinitions() []Tool {
 						"type":        "string",
 						"description": "Absolute path to the image file",
 					},
-					"region1": map[string]interface{}{
-						"type":        "object",
-						"description": "First region to compare",
-						"properties": map[string]interface{}{
-							"x1": map[string]interface{}{"type": "integer", "description": "Left edge X coordinate (0-based)"},
-							"y1": map[string]interface{}{"type": "integer", "description": "Top edge Y coordinate (0-based)"},
-							"x2": map[string]interface{}{"type": "integer", "description": "Right edge X coordinate (exclusive)"},
-							"y2": map[string]interface{}{"type": "integer", "description": "Bottom edge Y coordinate (exclusive)"},
+					"points": map[string]interface{}{
+						"type":        "array",
+						"description": "Points to enclose (1-10000), in any order; pass these or blob",
+						"minItems":    1,
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"x": map[string]interface{}{"type": "integer", "description": "X coordinate (0-based, from left)"},
+								"y": map[string]interface{}{"type": "integer", "description": "Y coordinate (0-based, from top)"},
+							},
+							"required": []string{"x", "y"},
 						},
-						"required": []string{"x1", "y1", "x2", "y2"},
 					},
-					"region2": map[string]interface{}{
-						"type":        "object",
-						"description": "Second region to compare",
-						"properties": map[string]interface{}{
-							"x1": map[string]interface{}{"type": "integer", "description": "Left edge X coordinate (0-based)"},
-							"y1": map[string]interface{}{"type": "integer", "description": "Top edge Y coordinate (0-based)"},
-							"x2": map[string]interface{}{"type": "integer", "description": "Right edge X coordinate (exclusive)"},
-							"y2": map[string]interface{}{"type": "integer", "description": "Bottom edge Y coordinate (exclusive)"},
-						},
-						"required": []string{"x1", "y1", "x2", "y2"},
+					"blob": map[string]interface{}{
+						"type":        "integer",
+						"description": "ID of a blob from image_count_blobs to enclose, counted with the same threshold, polarity, min_area, max_area, min_roundness, and exclude_edges; pass this or points",
+						"minimum":     1,
+					},
+					"threshold": map[string]interface{}{
+						"type":        "integer",
+						"description": "As for image_count_blobs: luma (1-255) separating blobs from the background, or 0 for automatic (default 0)",
+						"minimum":     0,
+						"maximum":     255,
+						"default":     0,
+					},
+					"polarity": map[string]interface{}{
+						"type":        "string",
+						"description": "As for image_count_blobs: 'dark', 'light', or 'auto' blobs (default 'auto')",
+						"enum":        []string{"auto", "dark", "light"},
+						"default":     "auto",
+					},
+					"min_area": map[string]interface{}{
+						"type":        "integer",
+						"description": "As for image_count_blobs: smallest blob counted, in pixels (default 10)",
+						"default":     10,
+					},
+					"max_area": map[string]interface{}{
+						"type":        "integer",
+						"description": "As for image_count_blobs: largest blob counted, in pixels, or 0 for no limit (default 0)",
+						"default":     0,
+					},
+					"min_roundness": map[string]interface{}{
+						"type":        "number",
+						"description": "As for image_count_blobs: smallest ratio (0-1) of minor to major axis counted (default 0)",
+						"minimum":     0,
+						"maximum":     1,
+						"default":     0,
+					},
+					"exclude_edges": map[string]interface{}{
+						"type":        "boolean",
+						"description": "As for image_count_blobs: leave out blobs cut off by the image's edge (default true)",
+						"default":     true,
 					},
 				},
-				"required": []string{"path", "region1", "region2"},
+				"required": []string{"path"},
+			},
+		},
+
+		// OCR Operations
+		{
+			Name:        "image_ocr_full",
+			Description: "Extract all text from the image using OCR. Returns text with approximate bounding boxes.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"language": map[string]interface{}{
+						"type":        "string",
+						"description": "OCR language hint (default 'eng')",
+						"default":     "eng",
+					},
+					"denoise":   denoiseSchema(),
+					"normalize": normalizeSchema(),
+					"scan_cleanup": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Clean up a photographed page first, as image_scan_cleanup does by default: perspective correction, shadow removal, deskew, and binarization. Applied after denoise and normalize (default false)",
+						"default":     false,
+					},
+					"alternatives":        alternativesSchema(),
+					"vocabulary":          vocabularySchema(),
+					"max_distance":        maxDistanceSchema(),
+					"layout":              layoutSchema(),
+					"rotation":            rotationSchema(),
+					"auto_rotate":         autoRotateSchema(),
+					"vertical":            verticalSchema(),
+					"min_confidence":      minConfidenceSchema(),
+					"unicode":             unicodeSchema(),
+					"fold_quotes":         foldQuotesSchema(),
+					"dehyphenate":         dehyphenateSchema(),
+					"collapse_whitespace": collapseWhitespaceSchema(),
+					"extract_values":      extractValuesSchema(),
+					"locale":              localeSchema(),
+				},
+				"required": []string{"path"},
 			},
 		},
+		{
+			Name:        "image_ocr_region",
+			Description: "Extract text from a specific rectangular region of the image.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"x1": map[string]interface{}{"type": "integer", "description": "Left edge X coordinate (0-based)"},
+					"y1": map[string]interface{}{"type": "integer", "description": "Top edge Y coordinate (0-based)"},
+					"x2": map[string]interface{}{"type": "integer", "description": "Right edge X coordinate (exclusive)"},
+					"y2": map[string]interface{}{"type": "integer", "description": "Bottom edge Y coordinate (exclusive)"},
+					"language": map[string]interface{}{
+						"type":        "string",
+						"description": "OCR language code (default 'eng')",
+						"default":     "eng",
+					},
+					"mask":                maskSchema("Polygon vertices are in image coordinates, and an RLE bitmap covers the whole image. Pixels inside the region but outside the mask are painted over with the most common masked color before OCR."),
+					"denoise":             denoiseSchema(),
+					"normalize":           normalizeSchema(),
+					"alternatives":        alternativesSchema(),
+					"vocabulary":          vocabularySchema(),
+					"max_distance":        maxDistanceSchema(),
+					"layout":              layoutSchema(),
+					"rotation":            rotationSchema(),
+					"auto_rotate":         autoRotateSchema(),
+					"vertical":            verticalSchema(),
+					"min_confidence":      minConfidenceSchema(),
+					"unicode":             unicodeSchema(),
+					"fold_quotes":         foldQuotesSchema(),
+					"dehyphenate":         dehyphenateSchema(),
+					"collapse_whitespace": collapseWhitespaceSchema(),
+					"extract_values":      extractValuesSchema(),
+					"locale":              localeSchema(),
+				},
+				"required": []string{"path", "x1", "y1", "x2", "y2"},
+			},
+		},
+		{
+			Name:        "image_detect_text_regions",
+			Description: "Detect all regions in the image that contain text. Returns bounding boxes without performing full OCR, each tagged printed, handwritten, or unknown from the regularity of its strokes: expect low OCR confidence on handwritten regions.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"min_confidence": map[string]interface{}{
+						"type":        "number",
+						"description": "Minimum confidence threshold (0-1, default 0.5)",
+						"default":     0.5,
+					},
+					"mask":      maskSchema("Polygon vertices are in image coordinates, and an RLE bitmap covers the whole image. Only regions centered inside the mask are returned."),
+					"denoise":   denoiseSchema(),
+					"normalize": normalizeSchema(),
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_redact",
+			Description: "Black out or pixelate sensitive content so a screenshot can be shared: explicit boxes, and/or every OCR match of patterns such as emails, numbers, URLs, or regular expressions. Returns the redacted image and the boxes hidden; the source file is not changed. Matched text is never returned.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"regions": map[string]interface{}{
+						"type": "array",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"x1": map[string]interface{}{"type": "integer"},
+								"y1": map[string]interface{}{"type": "integer"},
+								"x2": map[string]interface{}{"type": "integer"},
+								"y2": map[string]interface{}{"type": "integer"},
+							},
+							"required": []string{"x1", "y1", "x2", "y2"},
+						},
+						"description": "Boxes to hide",
+					},
+					"patterns": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Text to find with OCR and hide: 'email', 'number' (3+ digits, as in phone, card, and account numbers), 'url', or a regular expression (Go syntax, matched against each line of text)",
+					},
+					"style": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"black", "pixelate"},
+						"description": "How to hide each box (default black); pixelation can leave short text guessable",
+						"default":     "black",
+					},
+					"block_size": map[string]interface{}{
+						"type":        "integer",
+						"description": "Pixelation block size in pixels, at least 2 (default 12)",
+						"default":     12,
+					},
+					"padding": map[string]interface{}{
+						"type":        "integer",
+						"description": "Pixels added around each box (default 2)",
+						"default":     2,
+					},
+					"language": map[string]interface{}{
+						"type":        "string",
+						"description": "OCR language code for patterns (default 'eng')",
+						"default":     "eng",
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_detect_pii",
+			Description: "Scan an image's text with OCR for personal data and secrets before sharing it: email addresses, phone numbers, credit card numbers (Luhn-checked), and API keys or tokens. Returns each match with its category, bounding box, and a masked preview; pass the boxes to image_redact to hide them.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"categories": map[string]interface{}{
+						"type": "array",
+						"items": map[string]interface{}{
+							"type": "string",
+							"enum": []string{"email", "phone", "credit_card", "api_key"},
+						},
+						"description": "Categories to search for (default all)",
+					},
+					"language": map[string]interface{}{
+						"type":        "string",
+						"description": "OCR language code (default 'eng')",
+						"default":     "eng",
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_extract_fields",
+			Description: "Read labeled values from a receipt, invoice, or form with OCR: for each field, find one of its labels (such as 'Total' or 'Invoice #') and the value next to it, to the right on the same line or below it on the next. Values can be typed (number, amount, date, id, or a regular expression) to skip leader dots and other text. Returns each field's label and value with their bounding boxes, and amounts and numbers parsed.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"fields": map[string]interface{}{
+						"type":     "array",
+						"minItems": 1,
+						"maxItems": 50,
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"name": map[string]interface{}{
+									"type":        "string",
+									"description": "Name of the field in the result (default: its first label)",
+								},
+								"labels": map[string]interface{}{
+									"type":        "array",
+									"items":       map[string]interface{}{"type": "string"},
+									"minItems":    1,
+									"maxItems":    10,
+									"description": "Texts that can introduce the value, e.g. ['Total', 'Amount due'], matched without case",
+								},
+								"type": map[string]interface{}{
+									"type":        "string",
+									"description": "Value type: 'text' (the words up to a wide gap), 'number', 'amount', 'date', 'id' (a code with a digit), or a regular expression in Go syntax (default 'text')",
+									"default":     "text",
+								},
+							},
+							"required": []string{"labels"},
+						},
+						"description": "Fields to extract, reported in this order",
+					},
+					"language": map[string]interface{}{
+						"type":        "string",
+						"description": "OCR language code (default 'eng')",
+						"default":     "eng",
+					},
+				},
+				"required": []string{"path", "fields"},
+			},
+		},
+		{
+			Name:        "image_analyze_form",
+			Description: "List the input fields of a form screenshot: finds input boxes as rectangles, guesses each one's type (text, textarea, checkbox, dropdown, or button), pairs it with the nearest label text read by OCR (to its left or above it, or to the right of a checkbox), and reads its current value from the text inside it and, for checkboxes, whether it is checked. Returns the fields in reading order with their bounds.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"min_area": map[string]interface{}{
+						"type":        "integer",
+						"description": "Minimum area in pixels of an input box (default 64)",
+						"default":     64,
+					},
+					"language": map[string]interface{}{
+						"type":        "string",
+						"description": "OCR language code (default 'eng')",
+						"default":     "eng",
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_to_mermaid",
+			Description: "Convert a flowchart image to Mermaid flowchart text: finds the nodes as closed outlines and classifies them (rectangle = process, diamond = decision, circle, ellipse, or rounded rectangle = start/end), traces the connectors between them with their arrowheads, and reads the node labels and the labels beside connectors, such as Yes and No, with OCR. Returns the Mermaid text and the nodes and edges it was written from, with their bounds.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"threshold": map[string]interface{}{
+						"type":        "integer",
+						"description": "Gray level (1-255) below which a pixel is ink, or 0 to choose it automatically (default 0)",
+						"minimum":     0,
+						"maximum":     255,
+						"default":     0,
+					},
+					"min_node_area": map[string]interface{}{
+						"type":        "integer",
+						"description": "Smallest inside of a node, in pixels (default 200)",
+						"default":     200,
+					},
+					"direction": map[string]interface{}{
+						"type":        "string",
+						"description": "Direction of the Mermaid flowchart: 'TD' (top down), 'LR' (left to right), or 'auto' for the way most connectors run (default 'auto')",
+						"enum":        []string{"auto", "TD", "LR"},
+						"default":     "auto",
+					},
+					"language": map[string]interface{}{
+						"type":        "string",
+						"description": "OCR language code (default 'eng')",
+						"default":     "eng",
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_extract_org_chart",
+			Description: "Extract the hierarchy of an organizational chart: finds the boxes as closed outlines, traces the vertical and elbowed connectors between them, and reads each box's name and title with OCR. A box reports to the box above it at the other end of a connector. Returns the people with their names, titles, bounds, managers, direct reports, and levels, an indented outline of the hierarchy, and the connectors that are not reporting lines.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"threshold": map[string]interface{}{
+						"type":        "integer",
+						"description": "Gray level (1-255) below which a pixel is ink, or 0 to choose it automatically (default 0)",
+						"minimum":     0,
+						"maximum":     255,
+						"default":     0,
+					},
+					"min_node_area": map[string]interface{}{
+						"type":        "integer",
+						"description": "Smallest inside of a box, in pixels (default 200)",
+						"default":     200,
+					},
+					"title_first": map[string]interface{}{
+						"type":        "boolean",
+						"description": "The boxes give the title on the first line and the name below it, instead of the name first (default false)",
+						"default":     false,
+					},
+					"language": map[string]interface{}{
+						"type":        "string",
+						"description": "OCR language code (default 'eng')",
+						"default":     "eng",
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_extract_topology",
+			Description: "Extract the topology of a network diagram: finds the device glyphs, classifies them as router (round), switch (wide box), server (tall box), cloud (bumpy outline), or device, or by the templates given, traces the links between them, and reads the labels with OCR. Each label goes to the nearest node or link; a link's label gives its bandwidth (such as '10G' or '1 Gbps') and VLAN IDs. Returns the nodes with their kinds, labels, and bounds, and the links with their paths, labels, bandwidths, and VLANs, as topology JSON.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"threshold": map[string]interface{}{
+						"type":        "integer",
+						"description": "Gray level (1-255) below which a pixel is ink, or 0 to choose it automatically (default 0)",
+						"minimum":     0,
+						"maximum":     255,
+						"default":     0,
+					},
+					"min_node_size": map[string]interface{}{
+						"type":        "integer",
+						"description": "Smallest width and height of a device glyph, in pixels (8-1000); lines and text thinner than about a quarter of it are links and labels (default 16)",
+						"minimum":     8,
+						"maximum":     1000,
+						"default":     16,
+					},
+					"templates": map[string]interface{}{
+						"type":        "array",
+						"description": "Images of kinds of device icon on a plain background, such as a firewall cropped from the diagram; each glyph takes the label of the template whose shape it matches, before the built-in kinds are tried",
+						"maxItems":    32,
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"path":  map[string]interface{}{"type": "string", "description": "Absolute path to the image file"},
+								"label": map[string]interface{}{"type": "string", "description": "Kind of device, such as firewall"},
+								"region": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"x1": map[string]interface{}{"type": "integer", "description": "Left edge X coordinate (0-based)"},
+										"y1": map[string]interface{}{"type": "integer", "description": "Top edge Y coordinate (0-based)"},
+										"x2": map[string]interface{}{"type": "integer", "description": "Right edge X coordinate (exclusive)"},
+										"y2": map[string]interface{}{"type": "integer", "description": "Bottom edge Y coordinate (exclusive)"},
+									},
+									"description": "Optional area of the image holding the icon",
+								},
+							},
+							"required": []string{"path", "label"},
+						},
+					},
+					"language": map[string]interface{}{
+						"type":        "string",
+						"description": "OCR language code (default 'eng')",
+						"default":     "eng",
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_text_diff",
+			Description: "Compare the text of two images, such as two versions of an app screen, word by word: reads both with OCR in reading order and returns the text added, removed, or changed, with the bounding boxes of the words in each image. Use this to verify copy changes between versions.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path_a": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the first image (the old version)",
+					},
+					"path_b": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the second image (the new version)",
+					},
+					"language": map[string]interface{}{
+						"type":        "string",
+						"description": "OCR language code (default 'eng')",
+						"default":     "eng",
+					},
+					"min_confidence": map[string]interface{}{
+						"type":        "number",
+						"description": "Ignore the words read with a lower confidence (0-1) in either image, such as OCR noise from icons (default 0, compare all)",
+						"default":     0,
+						"minimum":     0,
+						"maximum":     1,
+					},
+					"ignore_case": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Compare words without case, so a change of case alone is not a change (default false)",
+						"default":     false,
+					},
+				},
+				"required": []string{"path_a", "path_b"},
+			},
+		},
+		{
+			Name:        "image_read_display",
+			Description: "Read the digits of a seven-segment or dot-matrix display, such as on a photo of a multimeter, oven, scale, or clock, which general OCR reads poorly. Finds the digit cells, classifies their lit segments or dots, and returns the text and numeric value with each character's bounds and confidence. Handles LED and LCD polarity, italic digits, decimal points, colons, and minus signs.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"region": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"x1": map[string]interface{}{"type": "integer", "description": "Left edge X coordinate (0-based)"},
+							"y1": map[string]interface{}{"type": "integer", "description": "Top edge Y coordinate (0-based)"},
+							"x2": map[string]interface{}{"type": "integer", "description": "Right edge X coordinate (exclusive)"},
+							"y2": map[string]interface{}{"type": "integer", "description": "Bottom edge Y coordinate (exclusive)"},
+						},
+						"description": "Area of the digits, inside the display's glass and without labels or units (default: whole image)",
+					},
+					"kind": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"auto", "seven_segment", "dot_matrix"},
+						"description": "Kind of display: seven-segment digits, 5x7 dot-matrix characters, or auto to tell them apart (default auto)",
+						"default":     "auto",
+					},
+					"polarity": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"auto", "light", "dark"},
+						"description": "Whether lit segments are lighter than the background (LED, backlit) or darker (reflective LCD); auto takes the smaller class as lit (default auto)",
+						"default":     "auto",
+					},
+					"letters": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also read the letters seven-segment displays show, as in hexadecimal values and error codes like E01 or Err (default false)",
+						"default":     false,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+
+		// Shape Detection
+		{
+			Name:        "image_detect_rectangles",
+			Description: "Detect rectangular shapes in the image. Useful for finding boxes in diagrams.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"min_area": map[string]interface{}{
+						"type":        "integer",
+						"description": "Minimum area in pixels to consider (default 100)",
+						"default":     100,
+					},
+					"tolerance": map[string]interface{}{
+						"type":        "number",
+						"description": "How close to rectangular a shape must be (0-1, default 0.9)",
+						"default":     0.9,
+					},
+					"auto_tune": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Pick min_area and tolerance automatically by sweeping a small grid on a downscaled copy and keeping the most stable result (overrides min_area and tolerance; the choice is reported under tuning)",
+						"default":     false,
+					},
+					"debug": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also return an overlay image (debug.image_base64) of the edge map with found rectangles in green and rejected contours in red or orange, plus the reason each was rejected",
+						"default":     false,
+					},
+					"export_format": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"coco", "yolo", "voc"},
+						"description": "Also return the detections as a dataset annotation file (export.data) in COCO JSON, YOLO txt, or Pascal VOC XML, for bootstrapping labeled training data",
+					},
+					"mask":      maskSchema("Polygon vertices are in image coordinates, and an RLE bitmap covers the whole image. Only rectangles centered inside the mask are returned."),
+					"denoise":   denoiseSchema(),
+					"normalize": normalizeSchema(),
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_detect_lines",
+			Description: "Detect line segments in the image. Useful for finding connections between elements.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"min_length": map[string]interface{}{
+						"type":        "integer",
+						"description": "Minimum line length in pixels (default 20)",
+						"default":     20,
+					},
+					"detect_arrows": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Whether to detect arrow heads at line endpoints",
+						"default":     true,
+					},
+					"auto_tune": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Pick min_length automatically by sweeping a small grid on a downscaled copy and keeping the most stable result (overrides min_length; the choice is reported under tuning)",
+						"default":     false,
+					},
+					"debug": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also return an overlay image (debug.image_base64) of the edge map with found lines in green and rejected Hough peaks in red or orange, plus the reason each was rejected",
+						"default":     false,
+					},
+					"export_format": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"coco", "yolo", "voc"},
+						"description": "Also return the detections as a dataset annotation file (export.data) in COCO JSON, YOLO txt, or Pascal VOC XML, for bootstrapping labeled training data",
+					},
+					"mask":      maskSchema("Polygon vertices are in image coordinates, and an RLE bitmap covers the whole image. Only lines whose midpoint is inside the mask are returned."),
+					"denoise":   denoiseSchema(),
+					"normalize": normalizeSchema(),
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_detect_circles",
+			Description: "Detect circular shapes in the image. Useful for finding nodes, connectors, or bullets.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"min_radius": map[string]interface{}{
+						"type":        "integer",
+						"description": "Minimum radius in pixels (default 5)",
+						"default":     5,
+					},
+					"max_radius": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum radius in pixels (default 500)",
+						"default":     500,
+					},
+					"auto_tune": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Pick the radius range automatically by sweeping a small grid on a downscaled copy and keeping the most stable result (overrides min_radius and max_radius; the choice is reported under tuning)",
+						"default":     false,
+					},
+					"debug": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also return an overlay image (debug.image_base64) of the edge map with accumulator peaks, found circles in green, and rejected candidates in red or orange, plus the reason each was rejected",
+						"default":     false,
+					},
+					"export_format": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"coco", "yolo", "voc"},
+						"description": "Also return the detections as a dataset annotation file (export.data) in COCO JSON, YOLO txt, or Pascal VOC XML, for bootstrapping labeled training data",
+					},
+					"mask":      maskSchema("Polygon vertices are in image coordinates, and an RLE bitmap covers the whole image. Only circles centered inside the mask are returned."),
+					"denoise":   denoiseSchema(),
+					"normalize": normalizeSchema(),
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_edge_detect",
+			Description: "Return an edge-detected version of the image, showing only structural lines. Useful for understanding diagram structure without color fills.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"threshold_low": map[string]interface{}{
+						"type":        "integer",
+						"description": "Low threshold for Canny edge detection (default 50)",
+						"default":     50,
+					},
+					"threshold_high": map[string]interface{}{
+						"type":        "integer",
+						"description": "High threshold for Canny edge detection (default 150)",
+						"default":     150,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_skeletonize",
+			Description: "Thin the strokes of line art to 1-pixel-wide skeletons and find their endpoints and junctions. Optionally traces the skeleton into a graph of nodes and edges, a basis for following connectors between the shapes of a diagram.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"threshold": map[string]interface{}{
+						"type":        "integer",
+						"description": "Luminance (1-255) below which pixels are strokes; 0 chooses it automatically with Otsu's method (default 0)",
+						"default":     0,
+					},
+					"invert": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Treat bright pixels as strokes, for light drawings on dark backgrounds (default false)",
+						"default":     false,
+					},
+					"graph": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also return the skeleton as a graph: endpoint, junction, and loop nodes connected by edges with their length and simplified path (default false)",
+						"default":     false,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_detect_incremental",
+			Description: "Detect rectangles and text regions, re-analyzing only what changed since a previous image of the same size analyzed by this tool. Useful for watching a live UI frame by frame. Without a usable previous snapshot, the whole image is analyzed.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the current image file",
+					},
+					"previous_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the previous image, analyzed earlier by this tool with the same parameters (optional)",
+					},
+					"min_area": map[string]interface{}{
+						"type":        "integer",
+						"description": "Minimum rectangle area in pixels (default 100)",
+						"default":     100,
+					},
+					"tolerance": map[string]interface{}{
+						"type":        "number",
+						"description": "How close to rectangular a shape must be (0-1, default 0.9)",
+						"default":     0.9,
+					},
+					"min_confidence": map[string]interface{}{
+						"type":        "number",
+						"description": "Minimum text region confidence (0-1, default 0.5)",
+						"default":     0.5,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_line_intersections",
+			Description: "Find where lines meet: the junctions of the given line segments, or of the lines detected as by image_detect_lines. Returns each junction's position, its kind (corner, tee, or cross), and the lines meeting there, whether each ends there or passes through, and the junctions along each line in order. Useful for reconstructing tables and grids and the connections of diagrams.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"lines": map[string]interface{}{
+						"type":        "array",
+						"description": "Line segments to intersect (max 500); if omitted, lines are detected in the image",
+						"items":       lineSchema("A line segment"),
+					},
+					"min_length": map[string]interface{}{
+						"type":        "integer",
+						"description": "Minimum length in pixels of detected lines, when lines are not given (default 20)",
+						"default":     20,
+					},
+					"tolerance": map[string]interface{}{
+						"type":        "number",
+						"description": "Pixels (0-50) an intersection may lie beyond a segment's end, within which a line ends at a junction, and within which intersections merge (default 5)",
+						"default":     5,
+					},
+					"min_angle": map[string]interface{}{
+						"type":        "number",
+						"description": "Smallest angle in degrees (above 0, at most 90) between lines that are intersected; nearly parallel lines, such as the two edges of one stroke, are not (default 10)",
+						"default":     10,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+
+		// Analysis Helpers
+		{
+			Name:        "image_check_alignment",
+			Description: "Check if multiple points or regions are horizontally or vertically aligned.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"points": map[string]interface{}{
+						"type": "array",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"x": map[string]interface{}{"type": "integer", "description": "X coordinate (0-based, from left)"},
+								"y": map[string]interface{}{"type": "integer", "description": "Y coordinate (0-based, from top)"},
+							},
+							"required": []string{"x", "y"},
+						},
+						"description": "Points to check for alignment",
+					},
+					"tolerance": map[string]interface{}{
+						"type":        "integer",
+						"description": "Pixel tolerance for alignment (default 5)",
+						"default":     5,
+					},
+				},
+				"required": []string{"path", "points"},
+			},
+		},
+		{
+			Name:        "image_compare_regions",
+			Description: "Compare two regions of an image to determine if they contain similar content (useful for detecting repeated elements). With method 'histogram', also compares their color distributions, which stay similar when content is slightly shifted or noisy; with method 'edges', also compares their edge maps, which stay similar when only colors change, as between light and dark themes of a layout.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"region1": map[string]interface{}{
+						"type":        "object",
+						"description": "First region to compare",
+						"properties": map[string]interface{}{
+							"x1": map[string]interface{}{"type": "integer", "description": "Left edge X coordinate (0-based)"},
+							"y1": map[string]interface{}{"type": "integer", "description": "Top edge Y coordinate (0-based)"},
+							"x2": map[string]interface{}{"type": "integer", "description": "Right edge X coordinate (exclusive)"},
+							"y2": map[string]interface{}{"type": "integer", "description": "Bottom edge Y coordinate (exclusive)"},
+						},
+						"required": []string{"x1", "y1", "x2", "y2"},
+					},
+					"region2": map[string]interface{}{
+						"type":        "object",
+						"description": "Second region to compare",
+						"properties": map[string]interface{}{
+							"x1": map[string]interface{}{"type": "integer", "description": "Left edge X coordinate (0-based)"},
+							"y1": map[string]interface{}{"type": "integer", "description": "Top edge Y coordinate (0-based)"},
+							"x2": map[string]interface{}{"type": "integer", "description": "Right edge X coordinate (exclusive)"},
+							"y2": map[string]interface{}{"type": "integer", "description": "Bottom edge Y coordinate (exclusive)"},
+						},
+						"required": []string{"x1", "y1", "x2", "y2"},
+					},
+					"mask":       maskSchema("Coordinates are relative to the top-left of each region, so the same shape is compared in both, and an RLE bitmap covers the compared area (the smaller width and height). Only masked pixels are compared."),
+					"alpha_mode": alphaModeSchema(),
+					"background": backgroundSchema(),
+					"method": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"pixel", "histogram", "edges"},
+						"description": "'pixel' compares pixel by pixel; 'histogram' also reports histogram intersection and Earth Mover's Distance of the regions' colors; 'edges' also reports the chamfer distance between their edge maps (default 'pixel')",
+						"default":     "pixel",
+					},
+				},
+				"required": []string{"path", "region1", "region2"},
+			},
+		},
+		{
+			Name:        "image_align",
+			Description: "Estimate the pixel offset between two nearly identical images (e.g. screenshots scrolled by a few pixels) using phase correlation, then report the differences that remain after aligning them.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"reference": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"path": map[string]interface{}{"type": "string", "description": "Absolute path to the image file"},
+							"region": map[string]interface{}{
+								"type": "object",
+								"properties": map[string]interface{}{
+									"x1": map[string]interface{}{"type": "integer", "description": "Left edge X coordinate (0-based)"},
+									"y1": map[string]interface{}{"type": "integer", "description": "Top edge Y coordinate (0-based)"},
+									"x2": map[string]interface{}{"type": "integer", "description": "Right edge X coordinate (exclusive)"},
+									"y2": map[string]interface{}{"type": "integer", "description": "Bottom edge Y coordinate (exclusive)"},
+								},
+								"description": "Optional region to use instead of the whole image",
+							},
+						},
+						"required":    []string{"path"},
+						"description": "Baseline image",
+					},
+					"target": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"path": map[string]interface{}{"type": "string", "description": "Absolute path to the image file"},
+							"region": map[string]interface{}{
+								"type": "object",
+								"properties": map[string]interface{}{
+									"x1": map[string]interface{}{"type": "integer", "description": "Left edge X coordinate (0-based)"},
+									"y1": map[string]interface{}{"type": "integer", "description": "Top edge Y coordinate (0-based)"},
+									"x2": map[string]interface{}{"type": "integer", "description": "Right edge X coordinate (exclusive)"},
+									"y2": map[string]interface{}{"type": "integer", "description": "Bottom edge Y coordinate (exclusive)"},
+								},
+								"description": "Optional region to use instead of the whole image",
+							},
+						},
+						"required":    []string{"path"},
+						"description": "Image to align against the reference",
+					},
+					"max_offset": map[string]interface{}{
+						"type":        "integer",
+						"description": "Largest shift in pixels to search for on each axis (default 32)",
+						"default":     32,
+					},
+				},
+				"required": []string{"reference", "target"},
+			},
+		},
+		{
+			Name:        "image_infer_nine_patch",
+			Description: "Infer the stretchable regions and content padding of a button or panel asset (like an Android 9-patch or iOS cap insets) by finding runs of identical rows and columns.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the asset image",
+					},
+					"tolerance": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum per-channel difference (0-255) for pixels to count as equal (default 0, exact)",
+						"default":     0,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_check_centering",
+			Description: "Check whether an element is centered within a container box. Reports horizontal/vertical offsets from perfect centering in pixels and percent, edge margins, and pass/fail against a tolerance. The element can be auto-detected.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"container": map[string]interface{}{
+						"type":        "object",
+						"description": "Container box (default: whole image)",
+						"properties": map[string]interface{}{
+							"x1": map[string]interface{}{"type": "integer", "description": "Left edge X coordinate (0-based)"},
+							"y1": map[string]interface{}{"type": "integer", "description": "Top edge Y coordinate (0-based)"},
+							"x2": map[string]interface{}{"type": "integer", "description": "Right edge X coordinate (exclusive)"},
+							"y2": map[string]interface{}{"type": "integer", "description": "Bottom edge Y coordinate (exclusive)"},
+						},
+						"required": []string{"x1", "y1", "x2", "y2"},
+					},
+					"element": map[string]interface{}{
+						"type":        "object",
+						"description": "Element box (default: auto-detect the content inside the container, ignoring borders)",
+						"properties": map[string]interface{}{
+							"x1": map[string]interface{}{"type": "integer", "description": "Left edge X coordinate (0-based)"},
+							"y1": map[string]interface{}{"type": "integer", "description": "Top edge Y coordinate (0-based)"},
+							"x2": map[string]interface{}{"type": "integer", "description": "Right edge X coordinate (exclusive)"},
+							"y2": map[string]interface{}{"type": "integer", "description": "Bottom edge Y coordinate (exclusive)"},
+						},
+						"required": []string{"x1", "y1", "x2", "y2"},
+					},
+					"tolerance": map[string]interface{}{
+						"type":        "number",
+						"description": "Maximum offset in pixels that still counts as centered (default 1)",
+						"default":     1,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_find_empty_regions",
+			Description: "Find the largest rectangles containing only background color, e.g. to decide where callouts or overlays can be placed without covering content. Returns non-overlapping rectangles, largest first.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"background": map[string]interface{}{
+						"type":        "string",
+						"description": "Background color as hex (e.g. '#FFFFFF'). Default: the image's most common color",
+					},
+					"tolerance": map[string]interface{}{
+						"type":        "number",
+						"description": "Maximum mean per-channel difference (0-255) from the background (default 10)",
+						"default":     10,
+					},
+					"count": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum number of regions to return (default 5, max 50)",
+						"default":     5,
+					},
+					"min_width": map[string]interface{}{
+						"type":        "integer",
+						"description": "Minimum region width in pixels (default 20)",
+						"default":     20,
+					},
+					"min_height": map[string]interface{}{
+						"type":        "integer",
+						"description": "Minimum region height in pixels (default 20)",
+						"default":     20,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_check_overlaps",
+			Description: "Find UI elements that overlap or cover each other. Given bounding boxes (or auto-detected rectangles), reports every overlapping pair with its IoU, containment, and a z-order hint based on which element's border stays continuous through the overlap.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"boxes": map[string]interface{}{
+						"type": "array",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"x1":    map[string]interface{}{"type": "integer", "description": "Left border X coordinate"},
+								"y1":    map[string]interface{}{"type": "integer", "description": "Top border Y coordinate"},
+								"x2":    map[string]interface{}{"type": "integer", "description": "Right border X coordinate"},
+								"y2":    map[string]interface{}{"type": "integer", "description": "Bottom border Y coordinate"},
+								"label": map[string]interface{}{"type": "string", "description": "Optional name for the element"},
+							},
+							"required": []string{"x1", "y1", "x2", "y2"},
+						},
+						"description": "Element boxes whose edges lie on their drawn borders, as returned by image_detect_rectangles (max 200). Boxes are clipped to the image; one wholly outside it is an error. If omitted, rectangles are detected automatically.",
+					},
+					"min_area": map[string]interface{}{
+						"type":        "integer",
+						"description": "Minimum area for auto-detected rectangles (default 100)",
+						"default":     100,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_find_repeats",
+			Description: "Discover repeated visual elements such as list rows, cards, or icons. Segments the foreground, compares elements by size and appearance, and returns clusters of matching bounding boxes with a representative exemplar for each cluster.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"min_size": map[string]interface{}{
+						"type":        "integer",
+						"description": "Minimum element width and height in pixels (default 8)",
+						"default":     8,
+					},
+					"merge_distance": map[string]interface{}{
+						"type":        "integer",
+						"description": "Shapes closer than this many pixels are merged into one element, e.g. an icon and its label (default 4, 0 keeps every shape separate)",
+						"default":     4,
+					},
+					"similarity": map[string]interface{}{
+						"type":        "number",
+						"description": "Minimum similarity (0.0-1.0) for two elements to match (default 0.9)",
+						"default":     0.9,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_detect_periodicity",
+			Description: "Find the dominant spatial frequencies of an image with a 2D FFT: the spacing and direction of repeated elements such as list rows, grid cells, table rules, or hatching. Returns each repeating pattern's period (overall and per axis), frequency, orientation, and strength, with harmonics folded into their fundamental. Use it to measure the pitch of a list or grid before locating individual elements.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"region": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"x1": map[string]interface{}{"type": "integer"},
+							"y1": map[string]interface{}{"type": "integer"},
+							"x2": map[string]interface{}{"type": "integer"},
+							"y2": map[string]interface{}{"type": "integer"},
+						},
+						"description": "Area to analyze (default: whole image); its largest centered power-of-two square, up to 512x512, is used",
+					},
+					"max_peaks": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum number of repeating patterns to return, strongest first (1-20, default 5)",
+						"default":     5,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_detect_rows",
+			Description: "Split a list, menu, or table without ruling lines into rows (or columns) separated by background gaps. Returns each row's tight bounds and a padded slot for per-row OCR, plus the row pitch and whether the rhythm is regular.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"region": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"x1": map[string]interface{}{"type": "integer"},
+							"y1": map[string]interface{}{"type": "integer"},
+							"x2": map[string]interface{}{"type": "integer"},
+							"y2": map[string]interface{}{"type": "integer"},
+						},
+						"description": "Area to analyze (default: whole image)",
+					},
+					"direction": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"rows", "columns"},
+						"description": "Split top to bottom (rows) or left to right (columns)",
+						"default":     "rows",
+					},
+					"min_gap": map[string]interface{}{
+						"type":        "integer",
+						"description": "Minimum gap in pixels between rows; smaller gaps, such as between wrapped lines, are merged (default 3)",
+						"default":     3,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_segment_regions",
+			Description: "Divide an image into regions of similar color, such as the slices of a pie chart or the areas of a map, using graph-based (Felzenszwalb) segmentation. Returns the largest regions with bounds, area, centroid, average color, and neighboring regions, optionally with an image of the segmentation.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"scale": map[string]interface{}{
+						"type":        "number",
+						"description": "How large regions tend to be; higher values merge more across weak color boundaries (default 300)",
+						"default":     300,
+					},
+					"min_size": map[string]interface{}{
+						"type":        "integer",
+						"description": "Regions smaller than this many pixels are merged into a neighbor (default 50)",
+						"default":     50,
+					},
+					"max_regions": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum number of regions to return, largest first (1-500, default 50)",
+						"default":     50,
+					},
+					"include_image": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also return an image with every region filled with its average color (default false)",
+						"default":     false,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_classify_content",
+			Description: "Classify an image as photograph, line-art diagram, dense text, UI screenshot, or blank using edge statistics, color count, and text density. Returns the class, the features behind it, and suggested follow-up tools. Optionally classifies each quadrant for mixed content.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"quadrants": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also classify each quadrant separately (default false)",
+						"default":     false,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_is_blank",
+			Description: "Fast check whether an image is a single flat color (an empty or unrendered frame), based on brightness variance. Use it to skip uninteresting frames before running OCR or detection.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"max_std_dev": map[string]interface{}{
+						"type":        "number",
+						"description": "Largest brightness standard deviation (0-255) that counts as blank (default 3)",
+						"default":     3,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_detect_artifacts",
+			Description: "Check an image for compression artifacts: JPEG blockiness, color banding in gradients, and ringing (mosquito noise) near sharp edges. Returns a severity score and example regions for each, and advises whether the asset should be re-exported at higher quality or as PNG.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_detect_moire",
+			Description: "Find moiré and aliasing: fine periodic patterns (hatching, dense lines, dithers) resampled off the pixel grid, as in screenshots taken at a non-100% browser zoom or display scale. These corrupt OCR and line detection. Returns the affected regions with the pattern's period and angle; recapture at 100% zoom when any are found.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"tile_size": map[string]interface{}{
+						"type":        "integer",
+						"description": "Side of the analyzed tiles in pixels: 32, 64, or 128; smaller tiles localize better but miss weaker patterns (default 64)",
+						"default":     64,
+					},
+					"min_strength": map[string]interface{}{
+						"type":        "number",
+						"description": "Smallest share (0-1) of a tile's brightness variation the aliased pattern must carry to be reported (default 0.25)",
+						"default":     0.25,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_inspect_channels",
+			Description: "Check an image for hidden data: extracts bit planes of the color channels and the alpha channel as images, and tests the least significant bits for the statistical traces of LSB steganography (pairs-of-values chi-square test, and noise LSBs under structured content) and for color hidden under transparent pixels. Returns per-channel statistics, suspicious regions, and findings.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"channels": map[string]interface{}{
+						"type": "array",
+						"items": map[string]interface{}{
+							"type": "string",
+							"enum": []string{"r", "g", "b", "a"},
+						},
+						"description": "Channels to inspect (default r, g, b, plus a if the image has transparency)",
+					},
+					"bits": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "integer"},
+						"description": "Bit planes (0 = least significant, to 7) of each channel to return as images (default [0])",
+					},
+					"include_images": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Return the bit planes and alpha channel as images (default true)",
+						"default":     true,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_near_duplicate",
+			Description: "Fast check whether two images show practically the same content, using a perceptual difference hash plus a thumbnail brightness comparison. Use it to skip repeated frames before running OCR or detection. Images may differ in size.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path_a": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the first image file",
+					},
+					"path_b": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the second image file",
+					},
+					"max_distance": map[string]interface{}{
+						"type":        "integer",
+						"description": "Largest hash Hamming distance (0-64) that counts as a duplicate (default 5)",
+						"default":     5,
+					},
+					"max_mean_diff": map[string]interface{}{
+						"type":        "number",
+						"description": "Largest mean thumbnail brightness difference (0-255) that counts as a duplicate (default 8)",
+						"default":     8,
+					},
+				},
+				"required": []string{"path_a", "path_b"},
+			},
+		},
+		{
+			Name:        "image_generate_report",
+			Description: "One-call summary of an image: dimensions and format, content class, quality metrics (brightness, contrast, sharpness, issues), dominant colors, heuristic text regions, and detected rectangles, lines, and circles. Optionally includes the report rendered as Markdown. Use it as a first look before drilling in with the individual tools.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"color_count": map[string]interface{}{
+						"type":        "integer",
+						"description": "Number of dominant colors to report (default 5)",
+						"default":     5,
+					},
+					"max_items": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum text regions, rectangles, lines, and circles listed each; counts always give the full totals (default 10)",
+						"default":     10,
+					},
+					"markdown": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also return the report rendered as a Markdown document (default false)",
+						"default":     false,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_accessibility_audit",
+			Description: "Audit a UI screenshot for accessibility in one call: WCAG text contrast, estimated text size, touch-target size of detected buttons, and color pairs that look alike with color blindness. Returns pass/fail and a list of issues, each with a severity (error or warning), a bounding box, and the measurements behind it.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"level": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"AA", "AAA"},
+						"description": "WCAG conformance level whose contrast and target-size minimums are errors (default AA)",
+						"default":     "AA",
+					},
+					"scale": map[string]interface{}{
+						"type":        "number",
+						"description": "Image pixels per CSS pixel, e.g. 2 for a Retina screenshot (default 1)",
+						"default":     1,
+					},
+					"min_text_size": map[string]interface{}{
+						"type":        "number",
+						"description": "Estimated font size in CSS pixels below which text is flagged (default 12)",
+						"default":     12,
+					},
+					"min_target_size": map[string]interface{}{
+						"type":        "number",
+						"description": "Smallest button side in CSS pixels without a warning (default 44); under AA, targets below 24 are errors",
+						"default":     44,
+					},
+					"max_issues": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum issues listed; the error and warning counts include the rest (default 50)",
+						"default":     50,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_denoise",
+			Description: "Remove noise from an image, such as sensor noise in a photo of a screen or compression ringing, and estimate how noisy it was. Returns the denoised image as PNG with the estimated noise standard deviation before and after. The OCR and shape detection tools accept the same denoising through their denoise parameter.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"method": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"bilateral", "nlm"},
+						"description": "bilateral (fast, edge-preserving) or nlm (non-local means: slower, keeps fine texture and text strokes better) (default bilateral)",
+						"default":     "bilateral",
+					},
+					"strength": map[string]interface{}{
+						"type":        "number",
+						"description": "How strongly to smooth, as a multiple of the estimated noise (greater than 0, at most 10; default 1)",
+						"default":     1,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_normalize",
+			Description: "Correct the color cast and exposure of a photo, such as a whiteboard or document shot under warm or dim light, so the other tools see white paper and dark ink. Applies gray-world white balance and a histogram-based levels stretch. Returns the normalized image as PNG with the channel gains and the black and white points used. The OCR and shape detection tools accept the same correction through their normalize parameter.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"white_balance": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Remove the color cast by scaling each channel so the image averages to gray, by a factor from 0.5 to 2 (default true)",
+						"default":     true,
+					},
+					"exposure": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Stretch brightness so the black point becomes black and the white point white (default true)",
+						"default":     true,
+					},
+					"clip_percent": map[string]interface{}{
+						"type":        "number",
+						"description": "Percentage of the darkest and of the brightest pixels clipped when finding the black and white points, 0-10 (default 1)",
+						"default":     1,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_scan_cleanup",
+			Description: "Turn a photo of a document into a clean, upright page for OCR: finds the page against the background and corrects its perspective, removes shadows, straightens the text, and binarizes it with a locally adaptive threshold. Returns the page as PNG with the corners found, the skew corrected, and the steps applied. image_ocr_full accepts the same cleanup through its scan_cleanup parameter.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"perspective": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Find the page against the background and warp it to a rectangle (default true)",
+						"default":     true,
+					},
+					"remove_shadows": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Even out the lighting so shadows and shading become white paper (default true)",
+						"default":     true,
+					},
+					"deskew": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Rotate the page so lines of text are horizontal, for skews up to 15 degrees (default true)",
+						"default":     true,
+					},
+					"binarize": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Turn the page into black ink on white with a Sauvola threshold adapted to each neighborhood (default true)",
+						"default":     true,
+					},
+					"window": map[string]interface{}{
+						"type":        "integer",
+						"description": "Side in pixels of the neighborhood the binarization adapts to: an odd number from 3 to 201, larger than the text strokes (default 31)",
+						"default":     31,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_clean_whiteboard",
+			Description: "Turn a photo of a whiteboard into a clean diagram: evens out glare and shadows so the board is white, deepens the marker strokes in their own colors, and finds the strokes that are nearly straight lines or rectangles, optionally redrawing them exactly (squared to horizontal and vertical within 5 degrees). Returns the cleaned board as PNG with the lines and rectangles found, their stroke widths and colors. A stroke touching another, such as a connector drawn into a box, is left as drawn.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"threshold": map[string]interface{}{
+						"type":        "integer",
+						"description": "Brightness (1-255) below which pixels of the evened-out board are ink, or 0 to choose it automatically (default 0)",
+						"default":     0,
+					},
+					"snap": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Redraw the lines and rectangles found as exact ones in the cleaned image; they are reported either way (default true)",
+						"default":     true,
+					},
+					"tolerance": map[string]interface{}{
+						"type":        "number",
+						"description": "How far in pixels a stroke may stray from a straight line or a rectangle's side and still be taken for one: 0.5 to 50 (default 3)",
+						"default":     3,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+
+		{
+			Name:        "image_detect_formula_regions",
+			Description: "Find regions that probably hold mathematical formulas, so they can be sent to a math recognizer instead of regular OCR, which garbles them. Scores groups of symbols on their vertical spread (fractions, limits), symbols off the baseline (superscripts, subscripts), thin operator bars (=, −, fraction bars), and, when Tesseract is available, how few common English words OCR reads in them. Returns the regions with their confidence and features, highest confidence first.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"min_confidence": map[string]interface{}{
+						"type":        "number",
+						"description": "Minimum confidence (0.0-1.0) for a region to be reported (default 0.5)",
+						"default":     0.5,
+					},
+					"check_words": map[string]interface{}{
+						"type":        "boolean",
+						"description": "OCR the candidate regions and lower the confidence of those reading as prose. Skipped if Tesseract is not installed (default true)",
+						"default":     true,
+					},
+					"language": map[string]interface{}{
+						"type":        "string",
+						"description": "OCR language code for check_words (default 'eng'). The common words are English.",
+						"default":     "eng",
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_detect_window_chrome",
+			Description: "Find the window chrome of a screenshot of a window: the drop shadow (transparent margin) around it and the title bar, from standard macOS and Windows title bar heights and row edges. Returns the content area and the offset to subtract from image coordinates so measurements refer to app content coordinates, and optionally the content area as an image. Identifies macOS screenshots and their scale by the traffic light buttons.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the screenshot",
+					},
+					"platform": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"macos", "windows"},
+						"description": "Platform of the screenshot, if known: only its title bar heights are tried, and its default height is assumed if no title bar edge is found",
+					},
+					"scale": map[string]interface{}{
+						"type":        "number",
+						"description": "Display scale of the screenshot in pixels per point (1-4, e.g. 2 for Retina), if known",
+					},
+					"include_image": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Return the content area, without shadow and title bar, as a base64 PNG",
+						"default":     false,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_detect_viewport",
+			Description: "Find the viewport of a browser in a screenshot, such as a full-desktop screenshot: the largest area of uniform background below the browser's toolbar line, split from docked developer tools and other panes by divider lines. Returns the viewport's bounds and the other panes. Measurement tools take relative_to: 'viewport' to use coordinates relative to it.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the screenshot",
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_strip_device_chrome",
+			Description: "Remove the system chrome of an iOS or Android screenshot: the status bar, with the notch or Dynamic Island, and the home indicator area or navigation bar. Returns clean app content as a base64 PNG, for consistent comparisons across devices, with the chrome found and the offset to subtract from image coordinates. iOS chrome is sized from the known iPhone and iPad screen sizes; Android chrome from edges at the standard heights.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the screenshot, of the whole screen",
+					},
+					"platform": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"ios", "android"},
+						"description": "Platform of the screenshot, if known (default: iOS if the image is the size of an iPhone or iPad screen, Android otherwise)",
+					},
+					"scale": map[string]interface{}{
+						"type":        "number",
+						"description": "Scale of the screenshot in pixels per point or dp (1-4, e.g. 3 for an iPhone Pro), if known",
+					},
+					"status_bar": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Remove the status bar with the notch or Dynamic Island, and in landscape the sides an iPhone's notch covers",
+						"default":     true,
+					},
+					"navigation_bar": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Remove the home indicator area or the navigation bar",
+						"default":     true,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_check_specs",
+			Description: "Validate a batch of images against asset specs, such as store listing or marketing requirements: exact or bounded dimensions, aspect ratio, maximum file size, allowed formats, and DPI. Returns pass/fail for the batch and each image, with the image's measured values and the reasons it fails.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"paths": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Absolute paths to the image files to check (1 to 500)",
+					},
+					"width":      map[string]interface{}{"type": "integer", "description": "Exact width required in pixels"},
+					"height":     map[string]interface{}{"type": "integer", "description": "Exact height required in pixels"},
+					"min_width":  map[string]interface{}{"type": "integer", "description": "Minimum width in pixels"},
+					"max_width":  map[string]interface{}{"type": "integer", "description": "Maximum width in pixels"},
+					"min_height": map[string]interface{}{"type": "integer", "description": "Minimum height in pixels"},
+					"max_height": map[string]interface{}{"type": "integer", "description": "Maximum height in pixels"},
+					"aspect_ratio": map[string]interface{}{
+						"type":        "number",
+						"description": "Width / height required (e.g., 1 for square, 1.78 for 16:9)",
+					},
+					"aspect_tolerance": map[string]interface{}{
+						"type":        "number",
+						"description": "Allowed difference from aspect_ratio, in percent of it (default 1)",
+						"default":     1,
+					},
+					"max_file_size": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum file size in bytes",
+					},
+					"formats": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Allowed formats by file extension: png, jpeg (or jpg), gif, tiff (or tif), svg, ico, icns",
+					},
+					"dpi": map[string]interface{}{
+						"type":        "number",
+						"description": "Resolution required in DPI, as stored in the file (PNG pHYs, JPEG JFIF or Exif, TIFF tags)",
+					},
+					"min_dpi": map[string]interface{}{
+						"type":        "number",
+						"description": "Minimum resolution in DPI, as stored in the file",
+					},
+				},
+				"required": []string{"paths"},
+			},
+		},
+		{
+			Name:        "image_safe_area",
+			Description: "Overlay a platform's safe-area guides on a marketing or social image and report text and key content outside them, where devices crop or platform interfaces cover it. Presets scale to the image; margins sets a custom safe area. Returns the safe area, the violations with the margins they cross, and the image with the area outside the safe area shaded and violations outlined in red. The content check suits graphics on plain backgrounds; use checks [\"text\"] for photos.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"preset": map[string]interface{}{
+						"type":        "string",
+						"enum":        safeAreaPresetNames(),
+						"description": "Platform format whose safe area to use",
+					},
+					"margins": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"top":    map[string]interface{}{"type": "integer", "description": "Top margin in percent of the height"},
+							"left":   map[string]interface{}{"type": "integer", "description": "Left margin in percent of the width"},
+							"bottom": map[string]interface{}{"type": "integer", "description": "Bottom margin in percent of the height"},
+							"right":  map[string]interface{}{"type": "integer", "description": "Right margin in percent of the width"},
+						},
+						"description": "Custom safe area margins, 0-49 percent each, instead of a preset",
+					},
+					"checks": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string", "enum": []string{"text", "content"}},
+						"description": "What to look for outside the safe area: text (heuristic text regions) and content (logos, products, and other distinct regions) (default: both)",
+					},
+					"include_image": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Include the image with the guides drawn",
+						"default":     true,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_detect_print_marks",
+			Description: "Find the crop marks of a print-ready export (a page rasterized with marks and bleed) and report the trim box (where the paper is cut) and the bleed box (artwork beyond it), the bleed on each side in pixels and millimeters, the sides with too little bleed, and text and content inside the trim box but within the safe margin of its edge. Returns pass/fail and the export with the area outside the safe box shaded and violations outlined in red.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"dpi": map[string]interface{}{
+						"type":        "number",
+						"description": "Resolution of the export, to convert millimeters (default: the file's resolution, or 300)",
+					},
+					"min_bleed_mm": map[string]interface{}{
+						"type":        "number",
+						"description": "Bleed required beyond each side of the trim box, in millimeters",
+						"default":     3,
+					},
+					"safe_margin_mm": map[string]interface{}{
+						"type":        "number",
+						"description": "Margin inside the trim box to keep clear of text and content, in millimeters",
+						"default":     3,
+					},
+					"checks": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string", "enum": []string{"text", "content"}},
+						"description": "What to look for in the safe margin: text (heuristic text regions) and content (logos and other distinct regions) (default: both)",
+					},
+					"include_image": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Include the export with the safe box drawn",
+						"default":     true,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_detect_halo",
+			Description: "Check an icon or sprite with transparency for matte halos: semi-transparent edge pixels whose color is not that of the opaque content they fringe, left by exporting against a white or black matte or by premultiplied alpha saved as straight. Returns the halo pixel counts (light and dark), the halo's color, its clusters, and the image over a contrasting background with halo pixels in magenta.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"threshold": map[string]interface{}{
+						"type":        "integer",
+						"description": "Smallest difference in any channel (1-255) from all opaque content within 2 pixels for an edge pixel to count as halo (default 48)",
+						"default":     48,
+					},
+					"min_alpha": map[string]interface{}{
+						"type":        "integer",
+						"description": "Lowest alpha (1-249) of the edge pixels checked; fainter pixels are barely visible (default 16)",
+						"default":     16,
+					},
+					"include_image": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Include the visualization of the halo pixels",
+						"default":     true,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_stroke_width_audit",
+			Description: "Audit the stroke widths of a diagram against a style guide: measures the border width of every detected rectangle and the width of every detected line across the stroke, and reports the width histogram, the most common width, and the strokes deviating from it (or from an expected width) by more than a tolerance. Filled shapes without a border are counted as unmeasured.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"min_length": map[string]interface{}{
+						"type":        "integer",
+						"description": "Shortest line measured, in pixels (default 20)",
+						"default":     20,
+					},
+					"min_area": map[string]interface{}{
+						"type":        "integer",
+						"description": "Smallest rectangle measured, in square pixels (default 100)",
+						"default":     100,
+					},
+					"expected_width": map[string]interface{}{
+						"type":        "number",
+						"description": "Width every stroke should have, in pixels; if unset, strokes are compared with the most common width",
+					},
+					"tolerance": map[string]interface{}{
+						"type":        "number",
+						"description": "Largest difference from the expected or most common width, in pixels, of a stroke that is not an outlier (default 0.5)",
+						"default":     0.5,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_compare_states",
+			Description: "Compare two screenshots of the same UI in different states (e.g. normal and hover, active, or focused) and return only the elements that changed: their boxes, the most common colors of each box before and after, and whether the element was only recolored or its content changed. Antialiasing at edges that moved by a pixel and tiny clusters are filtered out as noise. The screenshots must be the same size.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path_a": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the screenshot of the first state (e.g. normal)",
+					},
+					"path_b": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the screenshot of the second state (e.g. hover)",
+					},
+					"threshold": map[string]interface{}{
+						"type":        "integer",
+						"description": "Smallest difference in any channel (1-255) for a pixel to have changed (default 24)",
+						"default":     24,
+					},
+					"min_area": map[string]interface{}{
+						"type":        "integer",
+						"description": "Fewest changed pixels an element needs to be reported (default 16)",
+						"default":     16,
+					},
+					"merge_distance": map[string]interface{}{
+						"type":        "integer",
+						"description": "Largest gap in pixels (0-64) between changed pixels of one element (default 4)",
+						"default":     4,
+					},
+					"include_image": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Include both states side by side with the changed elements outlined",
+						"default":     true,
+					},
+				},
+				"required": []string{"path_a", "path_b"},
+			},
+		},
+		{
+			Name:        "image_detect_loading",
+			Description: "Detect loading indicators so a pipeline can tell that a page had not finished rendering before analyzing its content: spinners (a rotating arc, or a ring of dots or petals) and skeleton screens (flat gray placeholder bars, blocks, and avatar circles, with any shimmer highlight). Returns whether the screen is loading, and each indicator's box, color, and kind.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the screenshot",
+					},
+					"min_skeleton_blocks": map[string]interface{}{
+						"type":        "integer",
+						"description": "Fewest skeleton placeholders that make a skeleton screen (default 3); a single gray block is as likely to be an empty field",
+						"default":     3,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_grow_regions",
+			Description: "Grow regions of similar color from several seed pixels at once, such as samples in a gel, wells of a plate, or parts of an object, for quantitative comparison within one image. Where regions meet, each pixel goes to the region it matches better. Returns each region's area, perimeter, bounds, centroid, mean color and its spread, and neighboring regions, optionally with an image of the regions.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"seeds": map[string]interface{}{
+						"type":        "array",
+						"description": "Pixels to grow regions from, one per region (1-50)",
+						"minItems":    1,
+						"maxItems":    50,
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"x":         map[string]interface{}{"type": "integer", "description": "Seed X"},
+								"y":         map[string]interface{}{"type": "integer", "description": "Seed Y"},
+								"label":     map[string]interface{}{"type": "string", "description": "Optional name for the region"},
+								"tolerance": map[string]interface{}{"type": "number", "description": "This region's tolerance, replacing the shared one"},
+							},
+							"required": []string{"x", "y"},
+						},
+					},
+					"tolerance": map[string]interface{}{
+						"type":        "number",
+						"description": "Largest RGB distance (1-442) of a pixel from a region's mean color for it to join the region (default 30)",
+						"default":     30,
+					},
+					"include_image": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also return the image with every region tinted and outlined in its own color (default false)",
+						"default":     false,
+					},
+				},
+				"required": []string{"path", "seeds"},
+			},
+		},
+		{
+			Name:        "image_detect_grid",
+			Description: "Find the grid of a game board, calendar, spreadsheet, or ruled table: its horizontal and vertical lines, or the edges between filled squares, and the rows and columns between them. Returns row and column boundaries, the pitch and whether it is regular, and the pixel bounds of requested cells by (row, col).",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"region": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"x1": map[string]interface{}{"type": "integer"},
+							"y1": map[string]interface{}{"type": "integer"},
+							"x2": map[string]interface{}{"type": "integer"},
+							"y2": map[string]interface{}{"type": "integer"},
+						},
+						"description": "Area to analyze, such as the board or table alone (default: whole image)",
+					},
+					"min_coverage": map[string]interface{}{
+						"type":        "number",
+						"description": "Fraction (0-1) of the region's width a horizontal line must span, or of its height a vertical one (default 0.6)",
+						"default":     0.6,
+					},
+					"cells": map[string]interface{}{
+						"type":        "array",
+						"description": "Cells to return the pixel bounds and center of, counted from 0 at the top left",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"row": map[string]interface{}{"type": "integer", "description": "Row index"},
+								"col": map[string]interface{}{"type": "integer", "description": "Column index"},
+							},
+							"required": []string{"row", "col"},
+						},
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_read_heatmap",
+			Description: "Recover approximate values from a heatmap, such as a GitHub contribution graph, a calendar heatmap, or a correlation matrix: find its cells, sample each cell's fill color, and map it onto a preset or custom color scale. Returns each cell's color and value, a row-by-column matrix of values, and their count, range, mean, and sum.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"region": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"x1": map[string]interface{}{"type": "integer"},
+							"y1": map[string]interface{}{"type": "integer"},
+							"x2": map[string]interface{}{"type": "integer"},
+							"y2": map[string]interface{}{"type": "integer"},
+						},
+						"description": "Area holding only the cells, without labels or legend (default: whole image)",
+					},
+					"preset": map[string]interface{}{
+						"type":        "string",
+						"enum":        imaging.HeatmapPresetNames(),
+						"description": "Named color scale: github and github_dark map the contribution levels to 0-4, viridis maps to 0-1",
+					},
+					"scale": map[string]interface{}{
+						"type":        "array",
+						"description": "Custom color scale, 2-32 stops in order from one end to the other, instead of a preset",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"color": map[string]interface{}{"type": "string", "description": "Hex color (#RRGGBB)"},
+								"value": map[string]interface{}{"type": "number", "description": "Value the color stands for"},
+							},
+							"required": []string{"color", "value"},
+						},
+					},
+					"discrete": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Snap each cell to its nearest stop's value instead of interpolating between stops (default: true for the github presets, false otherwise)",
+					},
+					"rows": map[string]interface{}{
+						"type":        "integer",
+						"description": "Rows to split the region into evenly, with columns, for heatmaps whose cells touch (default: find the cells)",
+					},
+					"columns": map[string]interface{}{
+						"type":        "integer",
+						"description": "Columns to split the region into evenly, with rows",
+					},
+					"tolerance": map[string]interface{}{
+						"type":        "number",
+						"description": "Largest RGB distance (1-442) of a cell's color from the scale for it to get a value (default 40)",
+						"default":     40,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_read_board",
+			Description: "Read the state of a game board, such as tic-tac-toe, checkers, chess, or go, from a screenshot: find its grid and classify each cell as empty, an X or O mark, or a piece with its color and shape (disc, piece, or a matching template). Returns a row-by-column matrix of cell labels, each cell's details, and counts per label.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"region": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"x1": map[string]interface{}{"type": "integer"},
+							"y1": map[string]interface{}{"type": "integer"},
+							"x2": map[string]interface{}{"type": "integer"},
+							"y2": map[string]interface{}{"type": "integer"},
+						},
+						"description": "The board's area, without coordinates or other interface (default: whole image)",
+					},
+					"rows": map[string]interface{}{
+						"type":        "integer",
+						"description": "Rows to split the region into evenly, with columns, such as 8 for a chess board (default: find the grid)",
+					},
+					"columns": map[string]interface{}{
+						"type":        "integer",
+						"description": "Columns to split the region into evenly, with rows",
+					},
+					"piece_colors": map[string]interface{}{
+						"type":        "array",
+						"description": "Named piece colors, such as white and black; each piece takes the nearest (default: light or dark by brightness)",
+						"maxItems":    16,
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"label": map[string]interface{}{"type": "string", "description": "Name of the color"},
+								"color": map[string]interface{}{"type": "string", "description": "Hex color (#RRGGBB)"},
+							},
+							"required": []string{"label", "color"},
+						},
+					},
+					"templates": map[string]interface{}{
+						"type":        "array",
+						"description": "Images of kinds of piece on a plain background, such as one square of each chess piece cropped from the screenshot; each piece takes the label of the template whose shape it matches",
+						"maxItems":    32,
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"path":  map[string]interface{}{"type": "string", "description": "Absolute path to the image file"},
+								"label": map[string]interface{}{"type": "string", "description": "Name of the shape, such as knight"},
+								"region": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"x1": map[string]interface{}{"type": "integer", "description": "Left edge X coordinate (0-based)"},
+										"y1": map[string]interface{}{"type": "integer", "description": "Top edge Y coordinate (0-based)"},
+										"x2": map[string]interface{}{"type": "integer", "description": "Right edge X coordinate (exclusive)"},
+										"y2": map[string]interface{}{"type": "integer", "description": "Bottom edge Y coordinate (exclusive)"},
+									},
+									"description": "Optional area of the image holding the piece",
+								},
+							},
+							"required": []string{"path", "label"},
+						},
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_read_gauge",
+			Description: "Read an analog gauge or dial with a needle, such as a pressure gauge, thermometer, or meter photo: finds the dial (or takes its center and radius), measures the needle's angle, and returns the value it points to, given the scale's end angles and values or marks, or calibrated from the scale numbers read by OCR when Tesseract is available. Angles are in degrees clockwise from 12 o'clock.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"region": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"x1": map[string]interface{}{"type": "integer", "description": "Left edge X coordinate (0-based)"},
+							"y1": map[string]interface{}{"type": "integer", "description": "Top edge Y coordinate (0-based)"},
+							"x2": map[string]interface{}{"type": "integer", "description": "Right edge X coordinate (exclusive)"},
+							"y2": map[string]interface{}{"type": "integer", "description": "Bottom edge Y coordinate (exclusive)"},
+						},
+						"description": "Area to search for the dial (default: whole image)",
+					},
+					"center": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"x": map[string]interface{}{"type": "integer", "description": "X coordinate"},
+							"y": map[string]interface{}{"type": "integer", "description": "Y coordinate"},
+						},
+						"description": "The needle's pivot, if known; requires radius (default: find the dial)",
+					},
+					"radius": map[string]interface{}{
+						"type":        "integer",
+						"description": "Radius of the dial's face in pixels, with center",
+						"minimum":     8,
+					},
+					"min_angle": map[string]interface{}{
+						"type":        "number",
+						"description": "Angle of the scale's start, in degrees clockwise from 12 o'clock (e.g., 225 for lower left)",
+					},
+					"max_angle": map[string]interface{}{
+						"type":        "number",
+						"description": "Angle of the scale's end (e.g., 135 for lower right)",
+					},
+					"min_value": map[string]interface{}{
+						"type":        "number",
+						"description": "Value at min_angle",
+					},
+					"max_value": map[string]interface{}{
+						"type":        "number",
+						"description": "Value at max_angle",
+					},
+					"marks": map[string]interface{}{
+						"type":        "array",
+						"description": "Instead of the min and max, points of a nonlinear scale in order from its start: angle and value of each",
+						"minItems":    2,
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"angle": map[string]interface{}{"type": "number", "description": "Degrees clockwise from 12 o'clock"},
+								"value": map[string]interface{}{"type": "number", "description": "Value at the angle"},
+							},
+							"required": []string{"angle", "value"},
+						},
+					},
+					"counterclockwise": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Values increase counterclockwise (default false)",
+						"default":     false,
+					},
+					"needle_color": map[string]interface{}{
+						"type":        "string",
+						"description": "Needle color (#RRGGBB), to tell it from printed markings (default: anything differing from the face)",
+					},
+					"language": map[string]interface{}{
+						"type":        "string",
+						"description": "OCR language code for reading scale numbers when no scale is given (default 'eng')",
+						"default":     "eng",
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "image_read_clock",
+			Description: "Read the time from an analog clock: finds the face (or takes its center and radius), the angles of the hour, minute, and second hands, and returns the time shown as H:MM or H:MM:SS on a 12-hour clock. Hands are told apart by length and thickness; overlapping hands are reported with a warning.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file",
+					},
+					"region": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"x1": map[string]interface{}{"type": "integer", "description": "Left edge X coordinate (0-based)"},
+							"y1": map[string]interface{}{"type": "integer", "description": "Top edge Y coordinate (0-based)"},
+							"x2": map[string]interface{}{"type": "integer", "description": "Right edge X coordinate (exclusive)"},
+							"y2": map[string]interface{}{"type": "integer", "description": "Bottom edge Y coordinate (exclusive)"},
+						},
+						"description": "Area to search for the clock face (default: whole image)",
+					},
+					"center": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"x": map[string]interface{}{"type": "integer", "description": "X coordinate"},
+							"y": map[string]interface{}{"type": "integer", "description": "Y coordinate"},
+						},
+						"description": "The hands' pivot, if known; requires radius (default: find the face)",
+					},
+					"radius": map[string]interface{}{
+						"type":        "integer",
+						"description": "Radius of the clock face in pixels, with center",
+						"minimum":     8,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+
+		{
+			Name:        "image_match_features",
+			Description: "Find a region in another image even if it is rotated or scaled: matches ORB-style keypoints (FAST corners with rotated BRIEF descriptors over an image pyramid) and fits a similarity transform with RANSAC. Returns the scale, rotation, and translation carrying reference points into the target, the reference's corners in the target, and the inlier and match counts. Use image_align for pure translations.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"reference": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"path": map[string]interface{}{"type": "string", "description": "Absolute path to the image file"},
+							"region": map[string]interface{}{
+								"type": "object",
+								"properties": map[string]interface{}{
+									"x1": map[string]interface{}{"type": "integer", "description": "Left edge X coordinate (0-based)"},
+									"y1": map[string]interface{}{"type": "integer", "description": "Top edge Y coordinate (0-based)"},
+									"x2": map[string]interface{}{"type": "integer", "description": "Right edge X coordinate (exclusive)"},
+									"y2": map[string]interface{}{"type": "integer", "description": "Bottom edge Y coordinate (exclusive)"},
+								},
+								"description": "Optional region to use instead of the whole image",
+							},
+						},
+						"required":    []string{"path"},
+						"description": "Image or region to find",
+					},
+					"target": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"path": map[string]interface{}{"type": "string", "description": "Absolute path to the image file"},
+							"region": map[string]interface{}{
+								"type": "object",
+								"properties": map[string]interface{}{
+									"x1": map[string]interface{}{"type": "integer", "description": "Left edge X coordinate (0-based)"},
+									"y1": map[string]interface{}{"type": "integer", "description": "Top edge Y coordinate (0-based)"},
+									"x2": map[string]interface{}{"type": "integer", "description": "Right edge X coordinate (exclusive)"},
+									"y2": map[string]interface{}{"type": "integer", "description": "Bottom edge Y coordinate (exclusive)"},
+								},
+								"description": "Optional region to use instead of the whole image",
+							},
+						},
+						"required":    []string{"path"},
+						"description": "Image to find the reference in",
+					},
+					"max_features": map[string]interface{}{
+						"type":        "integer",
+						"description": "Most keypoints to take from each image (default 500)",
+						"default":     500,
+						"minimum":     1,
+					},
+				},
+				"required": []string{"reference", "target"},
+			},
+		},
+
+		{
+			Name:        "image_find_template",
+			Description: "Find every place a template image appears in another at the same scale and orientation, such as each copy of an icon or button in a screenshot. Scores placements by normalized cross-correlation, so matches hold through changes of brightness and contrast, and returns the matches best first with their bounds and scores, and the best score found. Use image_match_features for rotated or scaled content.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"template": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"path": map[string]interface{}{"type": "string", "description": "Absolute path to the image file"},
+							"region": map[string]interface{}{
+								"type": "object",
+								"properties": map[string]interface{}{
+									"x1": map[string]interface{}{"type": "integer", "description": "Left edge X coordinate (0-based)"},
+									"y1": map[string]interface{}{"type": "integer", "description": "Top edge Y coordinate (0-based)"},
+									"x2": map[string]interface{}{"type": "integer", "description": "Right edge X coordinate (exclusive)"},
+									"y2": map[string]interface{}{"type": "integer", "description": "Bottom edge Y coordinate (exclusive)"},
+								},
+								"description": "Optional region to use instead of the whole image",
+							},
+						},
+						"required":    []string{"path"},
+						"description": "Image or region to find",
+					},
+					"target": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"path": map[string]interface{}{"type": "string", "description": "Absolute path to the image file"},
+							"region": map[string]interface{}{
+								"type": "object",
+								"properties": map[string]interface{}{
+									"x1": map[string]interface{}{"type": "integer", "description": "Left edge X coordinate (0-based)"},
+									"y1": map[string]interface{}{"type": "integer", "description": "Top edge Y coordinate (0-based)"},
+									"x2": map[string]interface{}{"type": "integer", "description": "Right edge X coordinate (exclusive)"},
+									"y2": map[string]interface{}{"type": "integer", "description": "Bottom edge Y coordinate (exclusive)"},
+								},
+								"description": "Optional region to use instead of the whole image",
+							},
+						},
+						"required":    []string{"path"},
+						"description": "Image to search",
+					},
+					"threshold": map[string]interface{}{
+						"type":        "number",
+						"description": "Lowest score (0-1) that counts as a match (default 0.8)",
+						"default":     0.8,
+						"minimum":     0,
+						"maximum":     1,
+					},
+					"max_matches": map[string]interface{}{
+						"type":        "integer",
+						"description": "Most matches to return (default 20)",
+						"default":     20,
+						"minimum":     1,
+						"maximum":     100,
+					},
+				},
+				"required": []string{"template", "target"},
+			},
+		},
+
+		// Composition
+		{
+			Name:        "image_contact_sheet",
+			Description: "Compose thumbnails of multiple images (or multiple regions of one image) into a single labeled grid returned as one base64 PNG. Use this to review many regions at once.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"images": map[string]interface{}{
+						"type": "array",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"path":  map[string]interface{}{"type": "string", "description": "Absolute path to the image file"},
+								"label": map[string]interface{}{"type": "string", "description": "Optional caption (default: file name)"},
+								"region": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"x1": map[string]interface{}{"type": "integer", "description": "Left edge X coordinate (0-based)"},
+										"y1": map[string]interface{}{"type": "integer", "description": "Top edge Y coordinate (0-based)"},
+										"x2": map[string]interface{}{"type": "integer", "description": "Right edge X coordinate (exclusive)"},
+										"y2": map[string]interface{}{"type": "integer", "description": "Bottom edge Y coordinate (exclusive)"},
+									},
+									"description": "Optional region to crop before thumbnailing. If omitted, the whole image is used.",
+								},
+							},
+							"required": []string{"path"},
+						},
+						"description": "Images or regions to place on the sheet, in reading order (max 100)",
+					},
+					"columns": map[string]interface{}{
+						"type":        "integer",
+						"description": "Thumbnails per row (default: near-square grid)",
+					},
+					"thumb_size": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum thumbnail width/height in pixels (default 200)",
+						"default":     200,
+					},
+					"show_labels": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Whether to draw captions under each thumbnail",
+						"default":     true,
+					},
+				},
+				"required": []string{"images"},
+			},
+		},
+		{
+			Name:        "image_side_by_side",
+			Description: "Place two images (or two regions) next to each other in one composite PNG, with optional labels, red highlighting of differing pixels, per-channel difference statistics that tell color shifts from layout changes, and a glyph rendering comparison of text regions that catches font fallback and hinting regressions. Useful for before/after regression reports.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"left": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"path":  map[string]interface{}{"type": "string", "description": "Absolute path to the image file"},
+							"label": map[string]interface{}{"type": "string", "description": "Optional caption (default: file name)"},
+							"region": map[string]interface{}{
+								"type": "object",
+								"properties": map[string]interface{}{
+									"x1": map[string]interface{}{"type": "integer", "description": "Left edge X coordinate (0-based)"},
+									"y1": map[string]interface{}{"type": "integer", "description": "Top edge Y coordinate (0-based)"},
+									"x2": map[string]interface{}{"type": "integer", "description": "Right edge X coordinate (exclusive)"},
+									"y2": map[string]interface{}{"type": "integer", "description": "Bottom edge Y coordinate (exclusive)"},
+								},
+								"description": "Optional region to use instead of the whole image",
+							},
+						},
+						"required":    []string{"path"},
+						"description": "First image (left, or top when vertical)",
+					},
+					"right": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"path":  map[string]interface{}{"type": "string", "description": "Absolute path to the image file"},
+							"label": map[string]interface{}{"type": "string", "description": "Optional caption (default: file name)"},
+							"region": map[string]interface{}{
+								"type": "object",
+								"properties": map[string]interface{}{
+									"x1": map[string]interface{}{"type": "integer", "description": "Left edge X coordinate (0-based)"},
+									"y1": map[string]interface{}{"type": "integer", "description": "Top edge Y coordinate (0-based)"},
+									"x2": map[string]interface{}{"type": "integer", "description": "Right edge X coordinate (exclusive)"},
+									"y2": map[string]interface{}{"type": "integer", "description": "Bottom edge Y coordinate (exclusive)"},
+								},
+								"description": "Optional region to use instead of the whole image",
+							},
+						},
+						"required":    []string{"path"},
+						"description": "Second image (right, or bottom when vertical)",
+					},
+					"layout": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"horizontal", "vertical"},
+						"description": "Place images left-to-right or top-to-bottom (default horizontal)",
+						"default":     "horizontal",
+					},
+					"highlight_diff": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Tint pixels that differ between the two images red",
+						"default":     false,
+					},
+					"channel_diff": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also return per-channel (r, g, b, a, luminance) difference statistics and heat maps, and whether the change is a color shift (e.g. a gamma change), a layout change, or both",
+						"default":     false,
+					},
+					"font_diff": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also compare the glyph rendering of each text region: similarity, offset, antialiasing and subpixel (ClearType) rendering in each image, and whether the text only shifted, is smoothed differently (hinting, gamma), or has different glyphs (font fallback)",
+						"default":     false,
+					},
+					"show_labels": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Whether to draw captions above each image",
+						"default":     true,
+					},
+				},
+				"required": []string{"left", "right"},
+			},
+		},
+
+		// Visual Regression
+		{
+			Name:        "image_baseline_store",
+			Description: "Store an image as a named baseline for visual regression checks, replacing any baseline of the same name. Saves the pixels, a hash of them, and quality metrics in the server's cache directory, for later comparison with image_baseline_compare.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the image file (read afresh, not from the cache)",
+					},
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Baseline name: 1-128 letters, digits, '.', '_', or '-', not starting with '.' (e.g., 'login-page')",
+					},
+				},
+				"required": []string{"path", "name"},
+			},
+		},
+		{
+			Name:        "image_baseline_compare",
+			Description: "Compare a new capture with a baseline stored by image_baseline_store. Passes if the size is unchanged and few enough pixels differ; returns the differing pixel count, changed regions, failure reasons, and a side-by-side diff image with changes tinted red.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the new capture (read afresh, not from the cache)",
+					},
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the baseline to compare against",
+					},
+					"max_diff_percent": map[string]interface{}{
+						"type":        "number",
+						"description": "Largest percentage of differing pixels that still passes (0-100)",
+						"default":     0.1,
+					},
+					"include_diff": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Return the side-by-side diff image when the images differ",
+						"default":     true,
+					},
+				},
+				"required": []string{"path", "name"},
+			},
+		},
+	}
+	if !clipboard.Supported() {
+		tools = slices.DeleteFunc(tools, func(t Tool) bool { return t.Name == "image_from_clipboard" })
+	}
+	for i := range tools {
+		if t, ok := toolResults[tools[i].Name]; ok {
+			tools[i].OutputSchema = outputSchema(t)
+		}
+		tools[i].Annotations = toolAnnotations(tools[i].Name)
 	}
+	return tools
 }
 
 // handleToolsList returns the list of available tools in MCP format.
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -31,6 +32,21 @@ func TestGetToolDefinitions(t *testing.T) {
 		"image_edge_detect",
 		"image_check_alignment",
 		"image_compare_regions",
+		"image_contact_sheet",
+		"image_side_by_side",
+		"image_align",
+		"image_extract_icon",
+		"image_split_sprites",
+		"image_infer_nine_patch",
+		"image_check_centering",
+		"image_find_empty_regions",
+		"image_check_overlaps",
+		"image_find_repeats",
+		"image_detect_rows",
+		"image_classify_content",
+		"image_is_blank",
+		"image_near_duplicate",
+		"image_detect_incremental",
 	}
 
 	toolMap := make(map[string]Tool)
@@ -356,6 +372,27 @@ func TestHandleToolsList(t *testing.T) {
 	}
 }
 
+func TestToolDefinitions_Annotations(t *testing.T) {
+	for _, tool := range GetToolDefinitions() {
+		a := tool.Annotations
+		if a == nil {
+			t.Errorf("%s: no annotations", tool.Name)
+			continue
+		}
+		destructive := tool.Name == "image_baseline_store"
+		writes := destructive || tool.Name == "image_from_clipboard"
+		if a.ReadOnlyHint == writes || a.DestructiveHint != destructive || a.OpenWorldHint {
+			t.Errorf("%s: got %+v, want read-only (unless it writes baselines or clipboard images), closed-world", tool.Name, *a)
+		}
+		wantIdempotent := tool.Name != "image_detect_incremental" && tool.Name != "image_from_clipboard" && !strings.HasPrefix(tool.Name, "image_baseline_") &&
+			tool.Name != "image_detect_scale_bar" && tool.Name != "image_measure_distance" && tool.Name != "image_count_blobs" &&
+			tool.Name != "image_measure_polygon" && tool.Name != "image_check_parallel"
+		if a.IdempotentHint != wantIdempotent {
+			t.Errorf("%s: idempotentHint %v, want %v", tool.Name, a.IdempotentHint, wantIdempotent)
+		}
+	}
+}
+
 func TestToolStruct(t *testing.T) {
 	tool := Tool{
 		Name:        "test_tool",
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ironsheep/image-tools-mcp/internal/detection"
+	"github.com/ironsheep/image-tools-mcp/internal/imaging"
+	"github.com/ironsheep/image-tools-mcp/internal/ocr"
+)
+
+// topologyLabelReach is the farthest a label may be from its node or
+// link, in text heights (the median height of the OCR words).
+const topologyLabelReach = 3.0
+
+var (
+	// topologyBandwidth matches a link speed: "10G", "1 Gbps", "100Mb/s",
+	// "10GE".
+	topologyBandwidth = regexp.MustCompile(`(?i)\b\d+(?:\.\d+)?\s?[kmgt](?:bps|b/s|bit/s|bits?|b|e)?\b`)
+
+	// topologyVLAN matches a VLAN tag and its IDs: "VLAN 10",
+	// "vlan:20,30".
+	topologyVLAN = regexp.MustCompile(`(?i)\bvlans?\s*[-:#]?\s*(\d+(?:\s*[,/]\s*\d+)*)`)
+)
+
+// TopologyResult is the result of image_extract_topology: the devices of
+// a network diagram and the links between them, with their labels.
+type TopologyResult struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// Nodes are the device glyphs, in reading order.
+	Nodes []TopologyNode `json:"nodes"`
+
+	// Links are the lines between them, by From and then To.
+	Links []TopologyLink `json:"links"`
+
+	// WordsScanned is the number of words OCR recognized. When it is
+	// zero, no text was read, so nodes and links have no labels.
+	WordsScanned int `json:"words_scanned"`
+}
+
+// TopologyNode is one device or network of the diagram.
+type TopologyNode struct {
+	imaging.NetworkNode
+
+	// Label is the text beside or inside the glyph, such as its host name.
+	Label string `json:"label,omitempty"`
+
+	// Confidence is the lowest OCR confidence (0.0-1.0) of the label's
+	// words.
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+// TopologyLink is a line between two nodes.
+type TopologyLink struct {
+	imaging.NetworkLink
+
+	// Label is the text along the line.
+	Label string `json:"label,omitempty"`
+
+	// Bandwidth is the link speed read from the label, as written, such
+	// as "10G" or "1 Gbps".
+	Bandwidth string `json:"bandwidth,omitempty"`
+
+	// VLANs are the VLAN IDs read from the label, such as 10 and 20 from
+	// "VLAN 10,20".
+	VLANs []int `json:"vlans,omitempty"`
+}
+
+// networkTopology labels the nodes and links of graph with the words
+// read from its image.
+//
+// The words, split into phrases at wide gaps, each label the node or
+// link nearest their middle, within topologyLabelReach text heights: a
+// node by its box, a link by its path. A link's label gives its
+// bandwidth and VLANs.
+func networkTopology(graph *imaging.TopologyResult, words []ocr.TextRegion) *TopologyResult {
+	result := &TopologyResult{
+		Width:        graph.Width,
+		Height:       graph.Height,
+		Nodes:        make([]TopologyNode, len(graph.Nodes)),
+		Links:        make([]TopologyLink, len(graph.Links)),
+		WordsScanned: len(words),
+	}
+	heights := make([]int, 0, len(words))
+	for _, w := range words {
+		heights = append(heights, w.Bounds.Y2-w.Bounds.Y1)
+	}
+	th := float64(formDefaultTextHeight)
+	if len(heights) > 0 {
+		sort.Ints(heights)
+		th = float64(heights[len(heights)/2])
+	}
+	paths := make([][]detection.Point, len(graph.Links))
+	for i, l := range graph.Links {
+		for _, p := range l.Path {
+			paths[i] = append(paths[i], detection.Point{X: p.X, Y: p.Y})
+		}
+	}
+
+	nodeLabels := make([][]formPhrase, len(graph.Nodes))
+	linkLabels := make([][]formPhrase, len(graph.Links))
+	for _, p := range formPhrases(words) {
+		cx, cy := float64(p.bounds.X1+p.bounds.X2)/2, float64(p.bounds.Y1+p.bounds.Y2)/2
+		node, link, distance := -1, -1, topologyLabelReach*th
+		for i, n := range graph.Nodes {
+			b := n.Bounds
+			dx := math.Max(0, math.Max(float64(b.X1)-cx, cx-float64(b.X2)))
+			dy := math.Max(0, math.Max(float64(b.Y1)-cy, cy-float64(b.Y2)))
+			if d := math.Hypot(dx, dy); d <= distance {
+				node, distance = i, d
+			}
+		}
+		for i := range graph.Links {
+			if d := pathDistance(cx, cy, paths[i]); d < distance {
+				node, link, distance = -1, i, d
+			}
+		}
+		switch {
+		case link >= 0:
+			linkLabels[link] = append(linkLabels[link], p)
+		case node >= 0:
+			nodeLabels[node] = append(nodeLabels[node], p)
+		}
+	}
+
+	for i, n := range graph.Nodes {
+		node := TopologyNode{NetworkNode: n}
+		node.Label, node.Confidence = topologyLabel(nodeLabels[i])
+		result.Nodes[i] = node
+	}
+	for i, l := range graph.Links {
+		link := TopologyLink{NetworkLink: l}
+		link.Label, _ = topologyLabel(linkLabels[i])
+		link.Bandwidth = topologyBandwidth.FindString(link.Label)
+		for _, m := range topologyVLAN.FindAllStringSubmatch(link.Label, -1) {
+			for _, id := range strings.FieldsFunc(m[1], func(r rune) bool { return r < '0' || r > '9' }) {
+				if v, err := strconv.Atoi(id); err == nil && v >= 1 && v <= 4094 {
+					link.VLANs = append(link.VLANs, v)
+				}
+			}
+		}
+		result.Links[i] = link
+	}
+	return result
+}
+
+// topologyLabel joins phrases, in reading order, into one label, with
+// the lowest confidence of their words.
+func topologyLabel(phrases []formPhrase) (string, float64) {
+	texts := make([]string, len(phrases))
+	confidence := 0.0
+	for i, p := range phrases {
+		texts[i] = p.text
+		if i == 0 || p.confidence < confidence {
+			confidence = p.confidence
+		}
+	}
+	return strings.Join(texts, " "), confidence
+}
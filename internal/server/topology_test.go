@@ -0,0 +1,61 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/ironsheep/image-tools-mcp/internal/imaging"
+	"github.com/ironsheep/image-tools-mcp/internal/ocr"
+)
+
+func TestNetworkTopology(t *testing.T) {
+	// A router over a switch, with a line down between them, and a
+	// server to the switch's right
+	graph := &imaging.TopologyResult{
+		Width: 400, Height: 300,
+		Nodes: []imaging.NetworkNode{
+			{ID: 1, Kind: imaging.NetworkRouter, Bounds: imaging.Region{X1: 50, Y1: 20, X2: 100, Y2: 70}},
+			{ID: 2, Kind: imaging.NetworkSwitch, Bounds: imaging.Region{X1: 20, Y1: 200, X2: 140, Y2: 240}},
+			{ID: 3, Kind: imaging.NetworkServer, Bounds: imaging.Region{X1: 300, Y1: 190, X2: 340, Y2: 260}},
+		},
+		Links: []imaging.NetworkLink{
+			{From: 1, To: 2, Path: []imaging.Point{{X: 75, Y: 76}, {X: 75, Y: 194}}},
+			{From: 2, To: 3, Path: []imaging.Point{{X: 146, Y: 220}, {X: 294, Y: 220}}},
+		},
+	}
+	word := func(text string, x1, y1, x2 int) ocr.TextRegion {
+		return ocr.TextRegion{Text: text, Confidence: 0.9, Bounds: ocr.Bounds{X1: x1, Y1: y1, X2: x2, Y2: y1 + 10}}
+	}
+	words := []ocr.TextRegion{
+		word("core-rtr1", 104, 40, 144),
+		word("10G", 82, 120, 102),
+		word("VLAN", 80, 135, 100),
+		word("10,20", 104, 135, 126),
+		word("sw-access", 40, 245, 100),
+		word("1", 200, 205, 206),
+		word("Gbps", 209, 205, 235),
+		word("db01", 305, 265, 335),
+		word("Legend", 300, 10, 350),
+	}
+
+	got := networkTopology(graph, words)
+	if got.WordsScanned != len(words) || got.Width != 400 || len(got.Nodes) != 3 || len(got.Links) != 2 {
+		t.Fatalf("got %+v", got)
+	}
+	labels := []string{"core-rtr1", "sw-access", "db01"}
+	for i, n := range got.Nodes {
+		if n.ID != i+1 || n.Label != labels[i] || n.Kind != graph.Nodes[i].Kind {
+			t.Errorf("node %d: got %+v, want label %q", i+1, n, labels[i])
+		}
+	}
+	if got.Nodes[0].Confidence != 0.9 {
+		t.Errorf("confidence: got %v", got.Nodes[0].Confidence)
+	}
+
+	uplink := got.Links[0]
+	if uplink.Label != "10G VLAN 10,20" || uplink.Bandwidth != "10G" || len(uplink.VLANs) != 2 || uplink.VLANs[0] != 10 || uplink.VLANs[1] != 20 {
+		t.Errorf("uplink: got %+v", uplink)
+	}
+	if access := got.Links[1]; access.Label != "1 Gbps" || access.Bandwidth != "1 Gbps" || access.VLANs != nil || access.To != 3 {
+		t.Errorf("access link: got %+v", access)
+	}
+}